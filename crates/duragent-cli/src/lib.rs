@@ -116,7 +116,7 @@ pub async fn stream_response(
             Ok(ClientStreamEvent::ApprovalRequired { call_id, command }) => {
                 return Ok(StreamResult::ApprovalRequired { call_id, command });
             }
-            Ok(ClientStreamEvent::Start) => {}
+            Ok(ClientStreamEvent::Start) | Ok(ClientStreamEvent::Reasoning { .. }) => {}
             Err(e) => {
                 eprintln!("\nStream error: {}", e);
                 return Ok(StreamResult::Done);
@@ -10,6 +10,15 @@ use serde::{Deserialize, Serialize};
 // SessionStatus is defined in duragent-types::session; re-exported here for API compatibility.
 pub use duragent_types::session::SessionStatus;
 
+// Project settings are shared with the on-disk project format.
+pub use duragent_types::project::{ProjectBudget, ProjectModel};
+
+// Provenance records are shared with the on-disk agent format.
+pub use duragent_types::provenance::{ChangeSource, Provenance};
+
+// Provider error classes are reported on failed runs.
+pub use crate::llm::ProviderErrorClass;
+
 // ============================================================================
 // ID Prefixes
 // ============================================================================
@@ -20,6 +29,9 @@ pub const SESSION_ID_PREFIX: &str = "session_";
 /// ID prefix for messages.
 pub const MESSAGE_ID_PREFIX: &str = "msg_";
 
+/// ID prefix for runs started with a `callback_url`.
+pub const RUN_ID_PREFIX: &str = "run_";
+
 // ============================================================================
 // SSE Event Names
 // ============================================================================
@@ -28,6 +40,7 @@ pub const MESSAGE_ID_PREFIX: &str = "msg_";
 pub mod sse {
     pub const START: &str = "start";
     pub const TOKEN: &str = "token";
+    pub const REASONING: &str = "reasoning";
     pub const DONE: &str = "done";
     pub const ERROR: &str = "error";
     pub const CANCELLED: &str = "cancelled";
@@ -48,6 +61,17 @@ pub struct AgentSummary {
     pub description: Option<String>,
     #[serde(default, skip_serializing_if = "Option::is_none")]
     pub version: Option<String>,
+    #[serde(default = "default_true")]
+    pub enabled: bool,
+    #[serde(default, skip_serializing_if = "HashMap::is_empty")]
+    pub labels: HashMap<String, String>,
+    #[serde(default, skip_serializing_if = "Vec::is_empty")]
+    pub tags: Vec<String>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub project: Option<String>,
+    /// Who created and last changed the agent.
+    #[serde(default)]
+    pub provenance: Provenance,
 }
 
 /// Detailed agent information.
@@ -55,8 +79,13 @@ pub struct AgentSummary {
 pub struct AgentDetailResponse {
     pub api_version: String,
     pub kind: String,
+    #[serde(default = "default_true")]
+    pub enabled: bool,
     pub metadata: AgentMetadataResponse,
     pub spec: AgentSpecResponse,
+    /// Who created and last changed the agent.
+    #[serde(default)]
+    pub provenance: Provenance,
 }
 
 /// Agent metadata in responses.
@@ -69,6 +98,10 @@ pub struct AgentMetadataResponse {
     pub version: Option<String>,
     #[serde(default, skip_serializing_if = "HashMap::is_empty")]
     pub labels: HashMap<String, String>,
+    #[serde(default, skip_serializing_if = "Vec::is_empty")]
+    pub tags: Vec<String>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub project: Option<String>,
 }
 
 /// Agent spec in responses.
@@ -79,6 +112,9 @@ pub struct AgentSpecResponse {
     pub system_prompt: Option<String>,
     #[serde(default, skip_serializing_if = "Option::is_none")]
     pub instructions: Option<String>,
+    /// JSON Schema that message input must validate against.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub input_schema: Option<serde_json::Value>,
 }
 
 /// Agent model configuration in responses.
@@ -102,6 +138,653 @@ pub struct ListAgentsResponse {
     pub agents: Vec<AgentSummary>,
 }
 
+/// Request body for `POST /api/v1/agents`.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct CreateAgentRequest {
+    pub name: String,
+    /// Contents of `agent.yaml`.
+    pub manifest: String,
+    /// Additional files (e.g. `SOUL.md`), keyed by path relative to the agent directory.
+    #[serde(default, skip_serializing_if = "HashMap::is_empty")]
+    pub files: HashMap<String, String>,
+}
+
+/// Request body for `POST /api/v1/agents/bulk`.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct BulkAgentsRequest {
+    pub operations: Vec<BulkAgentOperation>,
+}
+
+/// A single operation in a bulk agent request.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+#[serde(tag = "op", rename_all = "snake_case")]
+pub enum BulkAgentOperation {
+    /// Create a new agent. Fails if the agent already exists.
+    Create {
+        name: String,
+        /// Contents of `agent.yaml`.
+        manifest: String,
+        /// Additional files (e.g. `SOUL.md`), keyed by path relative to the agent directory.
+        #[serde(default, skip_serializing_if = "HashMap::is_empty")]
+        files: HashMap<String, String>,
+    },
+    /// Replace an existing agent's manifest and the given files.
+    Update {
+        name: String,
+        manifest: String,
+        #[serde(default, skip_serializing_if = "HashMap::is_empty")]
+        files: HashMap<String, String>,
+    },
+    /// Delete an existing agent directory.
+    Delete { name: String },
+}
+
+impl BulkAgentOperation {
+    pub fn name(&self) -> &str {
+        match self {
+            Self::Create { name, .. } | Self::Update { name, .. } | Self::Delete { name } => name,
+        }
+    }
+
+    pub fn op(&self) -> &'static str {
+        match self {
+            Self::Create { .. } => "create",
+            Self::Update { .. } => "update",
+            Self::Delete { .. } => "delete",
+        }
+    }
+}
+
+/// Response for `POST /api/v1/agents/bulk`.
+///
+/// Operations are all-or-nothing: `applied` is false when any operation
+/// failed, in which case no changes were made.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct BulkAgentsResponse {
+    pub applied: bool,
+    pub results: Vec<BulkAgentResult>,
+}
+
+/// Outcome of one bulk operation, in request order.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct BulkAgentResult {
+    pub index: usize,
+    pub op: String,
+    pub name: String,
+    pub status: BulkAgentStatus,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub error: Option<String>,
+}
+
+/// Status of a bulk operation.
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "snake_case")]
+pub enum BulkAgentStatus {
+    Created,
+    Updated,
+    Deleted,
+    /// The operation was invalid; the batch was not applied.
+    Failed,
+    /// The operation was valid but not applied because another one failed.
+    Skipped,
+}
+
+/// A deleted agent awaiting purge.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct TrashedAgentSummary {
+    pub name: String,
+    pub deleted_at: String,
+    /// When the agent will be purged automatically; absent if retention is disabled.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub purge_at: Option<String>,
+}
+
+/// Response for listing trashed agents.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct ListTrashedAgentsResponse {
+    pub agents: Vec<TrashedAgentSummary>,
+}
+
+fn default_true() -> bool {
+    true
+}
+
+/// How a loaded agent differs from the agents directory.
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "snake_case")]
+pub enum AgentDriftStatus {
+    /// On disk and valid, but not loaded.
+    NotLoaded,
+    /// Loaded, but no longer on disk.
+    MissingOnDisk,
+    /// Loaded, but the files on disk have changed since.
+    Modified,
+    /// On disk, but fails to load.
+    Invalid,
+}
+
+/// One agent that is out of sync with the agents directory.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct AgentDriftEntry {
+    pub name: String,
+    pub status: AgentDriftStatus,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub detail: Option<String>,
+}
+
+/// Response for `GET /api/v1/admin/drift` and its reconcile action.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct DriftResponse {
+    pub in_sync: bool,
+    pub agents: Vec<AgentDriftEntry>,
+    /// Agents updated, loaded, or unloaded by a reconcile.
+    #[serde(default, skip_serializing_if = "Vec::is_empty")]
+    pub reconciled: Vec<String>,
+}
+
+// ============================================================================
+// Health History Types
+// ============================================================================
+
+/// Current state of a dependency, from its latest check.
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "snake_case")]
+pub enum DependencyStatus {
+    Up,
+    Down,
+    /// Not checked yet.
+    Unknown,
+}
+
+/// One dependency check.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct HealthCheckEntry {
+    pub checked_at: String,
+    pub ok: bool,
+    pub latency_ms: u64,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub error: Option<String>,
+}
+
+/// A run of consecutive failed checks.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct HealthIncident {
+    pub started_at: String,
+    /// Absent while the incident is ongoing.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub ended_at: Option<String>,
+    pub failed_checks: usize,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub last_error: Option<String>,
+}
+
+/// Health of one dependency over the retained checks.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct DependencyHealthEntry {
+    pub name: String,
+    pub status: DependencyStatus,
+    pub checks: usize,
+    pub failures: usize,
+    /// Fraction of checks that passed.
+    pub availability: f64,
+    /// Fraction of the error budget left. Negative once overspent.
+    pub error_budget_remaining: f64,
+    /// Changes between up and down.
+    pub transitions: usize,
+    pub incidents: Vec<HealthIncident>,
+    /// Most recent checks, oldest first.
+    pub history: Vec<HealthCheckEntry>,
+}
+
+/// Response for `GET /api/v1/admin/health-history`.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct HealthHistoryResponse {
+    pub slo_target: f64,
+    pub dependencies: Vec<DependencyHealthEntry>,
+}
+
+// ============================================================================
+// Template Types
+// ============================================================================
+
+/// Request for `POST /api/v1/templates/render`.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct RenderTemplateRequest {
+    pub template: String,
+    /// Agent whose variables, files, and memory the template can use.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub agent: Option<String>,
+    /// Extra variables. They take precedence over built-ins.
+    #[serde(default, skip_serializing_if = "serde_json::Map::is_empty")]
+    pub vars: serde_json::Map<String, serde_json::Value>,
+}
+
+/// Response for `POST /api/v1/templates/render`.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct RenderTemplateResponse {
+    pub rendered: String,
+    pub estimated_tokens: u32,
+    /// Tags left unrendered and malformed sections.
+    pub warnings: Vec<String>,
+}
+
+// ============================================================================
+// Example Types
+// ============================================================================
+
+/// A few-shot example in an agent's pool.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct ExampleResponse {
+    pub id: String,
+    pub input: String,
+    pub output: String,
+    #[serde(default)]
+    pub tags: Vec<String>,
+    pub created_at: String,
+    pub updated_at: String,
+    /// Model of the cached embedding, if the example has been embedded.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub embedding_model: Option<String>,
+}
+
+/// Response for `GET /api/v1/agents/{name}/examples`.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct ListExamplesResponse {
+    pub examples: Vec<ExampleResponse>,
+}
+
+/// Request for `POST /api/v1/agents/{name}/examples` and
+/// `PUT /api/v1/agents/{name}/examples/{id}`.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct PutExampleRequest {
+    pub input: String,
+    pub output: String,
+    #[serde(default, skip_serializing_if = "Vec::is_empty")]
+    pub tags: Vec<String>,
+}
+
+/// Request for `POST /api/v1/agents/{name}/examples/select`.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct SelectExamplesRequest {
+    pub input: String,
+}
+
+// ============================================================================
+// Prompt Library Types
+// ============================================================================
+
+/// A prompt in `GET /api/v1/prompts`.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct PromptSummary {
+    pub name: String,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub description: Option<String>,
+    pub latest_version: u32,
+    pub updated_at: String,
+}
+
+/// Response for `GET /api/v1/prompts`.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct ListPromptsResponse {
+    pub prompts: Vec<PromptSummary>,
+}
+
+/// A prompt with its latest content.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct PromptResponse {
+    pub name: String,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub description: Option<String>,
+    pub version: u32,
+    pub content: String,
+    /// Loaded agents that include this prompt.
+    #[serde(default)]
+    pub used_by: Vec<String>,
+    pub created_at: String,
+    pub updated_at: String,
+}
+
+/// One version of a prompt.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct PromptVersionResponse {
+    pub version: u32,
+    pub content: String,
+    pub created_at: String,
+}
+
+/// Response for `GET /api/v1/prompts/{name}/versions`.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct ListPromptVersionsResponse {
+    pub versions: Vec<PromptVersionResponse>,
+}
+
+/// Request for `PUT /api/v1/prompts/{name}`.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct PutPromptRequest {
+    pub content: String,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub description: Option<String>,
+}
+
+// ============================================================================
+// Model Registry Types
+// ============================================================================
+
+/// Price of a model in US dollars per million tokens.
+#[derive(Debug, Clone, Copy, PartialEq, Serialize, Deserialize)]
+pub struct ModelPricing {
+    pub input_per_mtok: f64,
+    pub output_per_mtok: f64,
+}
+
+/// What a model supports.
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct ModelCapabilities {
+    /// Input tokens the model accepts.
+    pub context_window: u32,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub max_output_tokens: Option<u32>,
+    pub vision: bool,
+    pub tool_calling: bool,
+    pub json_mode: bool,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub pricing: Option<ModelPricing>,
+}
+
+/// A registry entry in `GET /api/v1/models`.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct ModelEntry {
+    /// Matched case-insensitively against model names as a substring.
+    pub name: String,
+    /// `builtin` or `override`.
+    pub source: String,
+    #[serde(flatten)]
+    pub capabilities: ModelCapabilities,
+}
+
+/// Response for `GET /api/v1/models`.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct ListModelsResponse {
+    pub models: Vec<ModelEntry>,
+}
+
+/// Response for `GET /api/v1/models/{model}`: the capabilities a model name
+/// resolves to.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct ModelResponse {
+    pub model: String,
+    /// Registry entries that matched, most specific first. Empty when the
+    /// model is unknown and defaults apply.
+    #[serde(default)]
+    pub matched: Vec<String>,
+    #[serde(flatten)]
+    pub capabilities: ModelCapabilities,
+}
+
+/// Request for `PUT /api/v1/models/{name}`. Unset fields fall through to
+/// less specific entries.
+#[derive(Debug, Clone, Default, Serialize, Deserialize)]
+pub struct PutModelRequest {
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub context_window: Option<u32>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub max_output_tokens: Option<u32>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub vision: Option<bool>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub tool_calling: Option<bool>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub json_mode: Option<bool>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub pricing: Option<ModelPricing>,
+}
+
+// ============================================================================
+// Provider Types
+// ============================================================================
+
+/// Latency and outcomes of a provider's most recent requests (up to 100).
+#[derive(Debug, Clone, Default, Serialize, Deserialize)]
+pub struct ProviderLatency {
+    pub requests: usize,
+    pub errors: usize,
+    /// Latency of successful requests; streams are timed to the first byte.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub p50_ms: Option<u64>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub p95_ms: Option<u64>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub last_request_at: Option<String>,
+}
+
+/// Rate-limit headroom from the provider's latest response headers. Fields
+/// the provider didn't report are omitted.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct RateLimitHeadroom {
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub requests_limit: Option<u64>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub requests_remaining: Option<u64>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub tokens_limit: Option<u64>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub tokens_remaining: Option<u64>,
+    pub observed_at: String,
+}
+
+/// A model that loaded agents use with a provider.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct ProviderModel {
+    pub name: String,
+    /// Agents using the model, as their model or in a routing rule.
+    pub agents: Vec<String>,
+    #[serde(flatten)]
+    pub capabilities: ModelCapabilities,
+}
+
+/// A configured provider in `GET /api/v1/providers`.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct ProviderSummary {
+    pub name: String,
+    /// From the latest health check.
+    pub health: DependencyStatus,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub last_checked_at: Option<String>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub last_error: Option<String>,
+    /// Fraction of retained health checks that passed.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub availability: Option<f64>,
+    pub latency: ProviderLatency,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub rate_limit: Option<RateLimitHeadroom>,
+    pub models: Vec<ProviderModel>,
+}
+
+/// Response for `GET /api/v1/providers`.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct ListProvidersResponse {
+    pub providers: Vec<ProviderSummary>,
+}
+
+/// Response for `GET /api/v1/providers/{name}/models`: the model IDs the
+/// provider itself reports.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct ProviderModelsResponse {
+    pub provider: String,
+    pub models: Vec<String>,
+}
+
+// ============================================================================
+// User Memory Types
+// ============================================================================
+
+/// A fact remembered about an end user.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct UserFactResponse {
+    pub id: String,
+    pub content: String,
+    /// Agent the fact was learned by, if it was learned from a conversation.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub agent: Option<String>,
+    pub created_at: String,
+    pub updated_at: String,
+}
+
+/// Response for `GET /api/v1/users/{user}/facts`.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct ListUserFactsResponse {
+    pub facts: Vec<UserFactResponse>,
+}
+
+/// Request for `POST /api/v1/users/{user}/facts` and
+/// `PUT /api/v1/users/{user}/facts/{id}`.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct PutUserFactRequest {
+    pub content: String,
+}
+
+// ============================================================================
+// Status Page Types
+// ============================================================================
+
+/// Overall status shown on the status page.
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "snake_case")]
+pub enum OverallStatus {
+    Operational,
+    /// Some components are down.
+    Degraded,
+    /// Every checked component is down.
+    Outage,
+}
+
+/// One component on the status page.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct StatusComponent {
+    pub name: String,
+    pub status: DependencyStatus,
+    /// Fraction of checks passed in the last hour. Absent without checks.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub uptime_1h: Option<f64>,
+    /// Fraction of checks passed in the last 24 hours.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub uptime_24h: Option<f64>,
+}
+
+/// An incident on the status page. Error details are left out.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct StatusIncident {
+    pub component: String,
+    pub started_at: String,
+    /// Absent while the incident is ongoing.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub ended_at: Option<String>,
+}
+
+/// Response for `GET /status` with `Accept: application/json`.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct StatusPageResponse {
+    pub title: String,
+    pub status: OverallStatus,
+    pub components: Vec<StatusComponent>,
+    /// Ongoing incidents, newest first.
+    pub active_incidents: Vec<StatusIncident>,
+    /// Resolved incidents in the last 24 hours, newest first.
+    pub recent_incidents: Vec<StatusIncident>,
+    pub updated_at: String,
+}
+
+// ============================================================================
+// SLO Types
+// ============================================================================
+
+/// Burn rate of one provider objective over its window.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct SloStatusEntry {
+    pub provider: String,
+    /// `latency` or `errors`.
+    pub objective: String,
+    pub target: f64,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub latency_ms: Option<u64>,
+    pub window_minutes: u64,
+    pub requests: usize,
+    pub bad: usize,
+    pub burn_rate: f64,
+    pub burn_rate_threshold: f64,
+    pub alerting: bool,
+}
+
+/// Response for `GET /api/v1/admin/slos`.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct SloResponse {
+    pub objectives: Vec<SloStatusEntry>,
+}
+
+// ============================================================================
+// Project Types
+// ============================================================================
+
+/// A project and its member agents.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct ProjectResponse {
+    pub name: String,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub description: Option<String>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub default_model: Option<ProjectModel>,
+    #[serde(default, skip_serializing_if = "ProjectBudget::is_empty")]
+    pub budget: ProjectBudget,
+    /// Loaded agents whose `metadata.project` names this project.
+    #[serde(default)]
+    pub agents: Vec<String>,
+}
+
+/// Response for listing projects.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct ListProjectsResponse {
+    pub projects: Vec<ProjectResponse>,
+}
+
+/// Request body for `PUT /api/v1/projects/{name}`.
+#[derive(Debug, Clone, Default, Serialize, Deserialize)]
+pub struct PutProjectRequest {
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub description: Option<String>,
+    /// Model for member agents whose manifest omits `spec.model`.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub default_model: Option<ProjectModel>,
+    /// Run budgets for member agents that don't set their own.
+    #[serde(default, skip_serializing_if = "ProjectBudget::is_empty")]
+    pub budget: ProjectBudget,
+}
+
+// ============================================================================
+// Problem Types
+// ============================================================================
+
+/// A problem type from the error catalog.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct ProblemTypeInfo {
+    /// Stable machine-readable code (e.g. `session-not-found`).
+    pub code: String,
+    /// RFC 7807 `type` URI.
+    #[serde(rename = "type")]
+    pub type_uri: String,
+    pub title: String,
+    pub status: u16,
+    /// Whether retrying the same request later may succeed.
+    pub retryable: bool,
+    pub docs_url: String,
+}
+
+/// Response for GET /api/v1/problems.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct ListProblemsResponse {
+    pub problems: Vec<ProblemTypeInfo>,
+}
+
 // ============================================================================
 // Session Types
 // ============================================================================
@@ -110,6 +793,10 @@ pub struct ListAgentsResponse {
 #[derive(Debug, Clone, Serialize, Deserialize)]
 pub struct CreateSessionRequest {
     pub agent: String,
+    /// End user the session is with. Agents with user memory remember facts
+    /// about them across sessions.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub user: Option<String>,
 }
 
 /// Response for session creation.
@@ -119,6 +806,15 @@ pub struct CreateSessionResponse {
     pub agent: String,
     pub status: SessionStatus,
     pub created_at: String,
+    /// Channel the session was started through.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub source: Option<ChangeSource>,
+    /// Principal that started the session.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub created_by: Option<String>,
+    /// End user the session is with.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub user: Option<String>,
 }
 
 /// Response for getting a single session.
@@ -130,6 +826,15 @@ pub struct GetSessionResponse {
     pub created_at: String,
     #[serde(default, skip_serializing_if = "Option::is_none")]
     pub updated_at: Option<String>,
+    /// Channel the session was started through.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub source: Option<ChangeSource>,
+    /// Principal that started the session.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub created_by: Option<String>,
+    /// End user the session is with.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub user: Option<String>,
 }
 
 /// Summary of a session in list responses.
@@ -139,6 +844,15 @@ pub struct SessionSummary {
     pub agent: String,
     pub status: SessionStatus,
     pub created_at: String,
+    /// Channel the session was started through.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub source: Option<ChangeSource>,
+    /// Principal that started the session.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub created_by: Option<String>,
+    /// End user the session is with.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub user: Option<String>,
 }
 
 /// Response for listing sessions.
@@ -147,6 +861,107 @@ pub struct ListSessionsResponse {
     pub sessions: Vec<SessionSummary>,
 }
 
+// ============================================================================
+// Artifact Types
+// ============================================================================
+
+/// A file attached to a session message.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct ArtifactResponse {
+    pub id: String,
+    pub name: String,
+    pub content_type: String,
+    /// Size in bytes.
+    pub size: u64,
+    pub created_at: String,
+}
+
+/// Response for `GET /api/v1/sessions/{session_id}/artifacts`.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct ListArtifactsResponse {
+    pub artifacts: Vec<ArtifactResponse>,
+}
+
+// ============================================================================
+// Share Types
+// ============================================================================
+
+/// Request to share a session's transcript.
+#[derive(Debug, Clone, Default, Serialize, Deserialize)]
+pub struct CreateShareRequest {
+    /// Seconds until the link stops working; omit for a link that lasts
+    /// until revoked.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub expires_in_seconds: Option<u64>,
+}
+
+/// A read-only share link for a session transcript.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct ShareResponse {
+    pub id: String,
+    pub session_id: String,
+    pub created_at: String,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub expires_at: Option<String>,
+}
+
+/// Response for `GET /api/v1/sessions/{session_id}/shares`.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct ListSharesResponse {
+    pub shares: Vec<ShareResponse>,
+}
+
+/// A new share with its token and link.
+///
+/// The token is only ever returned here; anyone with the link can read the
+/// transcript.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct ShareTokenResponse {
+    pub share: ShareResponse,
+    pub token: String,
+    pub url: String,
+}
+
+/// Response for `GET /shared/{token}`.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct SharedTranscriptResponse {
+    pub session_id: String,
+    pub agent: String,
+    pub created_at: String,
+    pub messages: Vec<MessageResponse>,
+}
+
+// ============================================================================
+// Public Agent Types
+// ============================================================================
+
+/// Request to start a conversation with a public agent.
+#[derive(Debug, Clone, Default, Serialize, Deserialize)]
+pub struct CreatePublicSessionRequest {
+    /// Token from the CAPTCHA widget, for agents that require one.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub captcha_token: Option<String>,
+}
+
+/// A conversation with a public agent.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct PublicSessionResponse {
+    pub session_id: String,
+    pub agent: String,
+    pub created_at: String,
+}
+
+/// Request to send a message in a conversation with a public agent.
+#[derive(Debug, Clone, Default, Serialize, Deserialize)]
+pub struct PublicMessageRequest {
+    /// Message text. May be empty when `input` is set.
+    #[serde(default)]
+    pub content: String,
+    /// Structured input for agents that declare an `input_schema`.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub input: Option<serde_json::Value>,
+}
+
 // ============================================================================
 // Message Types
 // ============================================================================
@@ -154,7 +969,57 @@ pub struct ListSessionsResponse {
 /// Request to send a message to a session.
 #[derive(Debug, Clone, Serialize, Deserialize)]
 pub struct SendMessageRequest {
+    /// Message text. May be empty when `input` is set.
+    #[serde(default)]
     pub content: String,
+    /// Structured input for agents that declare an `input_schema`.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub input: Option<serde_json::Value>,
+    /// Scheduling priority when the server is at its run limit.
+    #[serde(default, skip_serializing_if = "RunPriority::is_normal")]
+    pub priority: RunPriority,
+    /// Labels stored with the run, e.g. your own order or ticket ID. Runs
+    /// can be listed by label with `GET /api/v1/runs?selector=`.
+    #[serde(default, skip_serializing_if = "HashMap::is_empty")]
+    pub labels: HashMap<String, String>,
+    /// Run in the background: respond `202` with a run ID to fetch the
+    /// result from `GET /api/v1/runs/{id}`.
+    #[serde(default, skip_serializing_if = "std::ops::Not::not")]
+    pub background: bool,
+    /// Run in the background and POST the result here when it finishes.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub callback_url: Option<String>,
+    /// Restrict this run to a subset of the agent's tools.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub tools: Option<ToolRestriction>,
+}
+
+/// Which of the agent's tools a run may use. A run can only lose tools:
+/// names the agent doesn't have are ignored.
+#[derive(Debug, Clone, Default, PartialEq, Eq, Serialize, Deserialize)]
+pub struct ToolRestriction {
+    /// Only these tools may be used. All of the agent's tools when unset.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub allow: Option<Vec<String>>,
+    /// These tools may not be used, even if allowed.
+    #[serde(default, skip_serializing_if = "Vec::is_empty")]
+    pub deny: Vec<String>,
+}
+
+/// Priority of a run waiting for a slot in the server's run pool.
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "snake_case")]
+pub enum RunPriority {
+    Low,
+    #[default]
+    Normal,
+    High,
+}
+
+impl RunPriority {
+    pub fn is_normal(&self) -> bool {
+        *self == Self::Normal
+    }
 }
 
 /// A message in a session.
@@ -176,6 +1041,24 @@ pub struct SendMessageResponse {
     pub message_id: String,
     pub role: String,
     pub content: String,
+    /// Model reasoning behind the answer (agents without tools only). Only
+    /// returned with `sessions.expose_reasoning` to callers with admin access
+    /// or a `runs:reasoning` scope.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub reasoning: Option<String>,
+    /// Resource usage of the run that produced this response (agents with tools only).
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub stats: Option<RunStatsResponse>,
+}
+
+/// Time and memory used by an agentic run.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct RunStatsResponse {
+    pub wall_time_ms: u64,
+    pub provider_time_ms: u64,
+    pub tool_time_ms: u64,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub peak_memory_bytes: Option<u64>,
 }
 
 // ============================================================================
@@ -248,6 +1131,79 @@ pub struct PendingApprovalResponse {
     pub command: String,
 }
 
+/// Response when a message is accepted to run in the background.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct AcceptedRunResponse {
+    pub run_id: String,
+    pub session_id: String,
+    pub status: RunStatus,
+}
+
+/// State of a background run.
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "snake_case")]
+pub enum RunStatus {
+    /// Accepted to run after the request returns.
+    Accepted,
+    /// Waiting for a run slot or in progress.
+    Running,
+    /// Finished with an assistant message.
+    Completed,
+    /// Paused until a tool call is approved.
+    AwaitingApproval,
+    /// Finished with an error.
+    Failed,
+}
+
+/// Body POSTed to a message's `callback_url` when its run finishes.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct RunCallback {
+    pub run_id: String,
+    pub session_id: String,
+    #[serde(default, skip_serializing_if = "HashMap::is_empty")]
+    pub labels: HashMap<String, String>,
+    pub status: RunStatus,
+    /// Status code the synchronous request would have returned.
+    pub http_status: u16,
+    /// Why the provider request failed, for failed runs.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub error_class: Option<ProviderErrorClass>,
+    /// Body the synchronous request would have returned: a
+    /// `SendMessageResponse`, `PendingApprovalResponse`, or problem details.
+    pub result: serde_json::Value,
+}
+
+/// A message run and, once finished, its outcome.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct RunResponse {
+    pub run_id: String,
+    pub session_id: String,
+    #[serde(default, skip_serializing_if = "HashMap::is_empty")]
+    pub labels: HashMap<String, String>,
+    pub status: RunStatus,
+    /// Status code the request would have returned without running in the
+    /// background. Set once the run finishes.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub http_status: Option<u16>,
+    /// Why the provider request failed, for runs that failed on one.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub error_class: Option<ProviderErrorClass>,
+    /// Body the request would have returned: a `SendMessageResponse`,
+    /// `PendingApprovalResponse`, or problem details. Set once a background
+    /// run finishes.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub result: Option<serde_json::Value>,
+    pub created_at: String,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub finished_at: Option<String>,
+}
+
+/// Response for listing runs.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct ListRunsResponse {
+    pub runs: Vec<RunResponse>,
+}
+
 /// Response for getting a single session (extended with pending_approval).
 #[derive(Debug, Clone, Serialize, Deserialize)]
 pub struct GetSessionDetailResponse {
@@ -269,3 +1225,297 @@ pub struct PendingApprovalInfo {
     pub command: String,
     pub expires_at: String,
 }
+
+// ============================================================================
+// Run Types
+// ============================================================================
+
+/// A scheduled run that failed after exhausting its retries.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct DeadLetterSummary {
+    pub id: String,
+    pub schedule_id: String,
+    pub agent: String,
+    pub gateway: String,
+    pub chat_id: String,
+    /// Payload kind: `message` or `task`.
+    pub kind: String,
+    /// The message to send or the task to run.
+    pub content: String,
+    pub attempts: u8,
+    pub error: String,
+    /// Coarse cause of `error`: `rate_limit`, `provider`, `gateway`,
+    /// `agent`, or `other`.
+    pub error_class: String,
+    pub started_at: String,
+    pub failed_at: String,
+}
+
+/// Response for listing dead-lettered runs.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct ListDeadLettersResponse {
+    pub dead_letters: Vec<DeadLetterSummary>,
+}
+
+/// Response for requeueing a dead-lettered run.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct RequeueDeadLetterResponse {
+    /// The one-shot schedule created to rerun the payload.
+    pub schedule_id: String,
+}
+
+/// Request to requeue every dead letter matching a filter.
+#[derive(Debug, Clone, Default, Serialize, Deserialize)]
+pub struct BulkRequeueRequest {
+    /// Only runs that failed at or after this RFC 3339 time.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub since: Option<String>,
+    /// Only runs that failed before this RFC 3339 time.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub until: Option<String>,
+    /// Only runs whose error has this class, e.g. `rate_limit`.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub error_class: Option<String>,
+    /// Only runs of this agent.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub agent: Option<String>,
+    /// Most requeues per second (default 2), so a recovering provider is
+    /// not hit by every failed run at once.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub rate_per_second: Option<u32>,
+    /// Report what would be requeued without requeueing anything.
+    #[serde(default)]
+    pub dry_run: bool,
+}
+
+/// Response for a bulk requeue.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct BulkRequeueResponse {
+    /// The matching dead letters, in the order they are requeued.
+    pub dead_letter_ids: Vec<String>,
+    pub dry_run: bool,
+}
+
+// ============================================================================
+// Meta Types
+// ============================================================================
+
+/// Response for `GET /api/v1/meta`: build info and server capabilities.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct MetaResponse {
+    pub version: String,
+    pub commit: String,
+    pub build_date: String,
+    pub variant: String,
+    pub features: MetaFeatures,
+    /// LLM providers with credentials available, sorted.
+    pub providers: Vec<String>,
+    pub auth: MetaAuth,
+    pub limits: MetaLimits,
+}
+
+/// Optional server features and how they are configured.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct MetaFeatures {
+    /// Chat gateways compiled into this build.
+    pub gateways: Vec<String>,
+    pub scheduler: bool,
+    pub background_processes: bool,
+    /// Storage backend for agent memory.
+    pub memory_backend: String,
+    /// Media types list and run endpoints can respond with.
+    pub response_formats: Vec<String>,
+    pub request_validation: bool,
+    /// Only list and get requests are accepted (`server.read_only`).
+    #[serde(default)]
+    pub read_only: bool,
+    /// Experimental subsystems currently switched on.
+    pub experimental: Vec<String>,
+}
+
+/// How a group of endpoints is authenticated.
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "snake_case")]
+pub enum AuthMode {
+    /// `Authorization: Bearer <token>` is required.
+    Token,
+    /// No token is configured; only loopback clients are accepted.
+    Loopback,
+}
+
+/// Authentication for the public and admin endpoints.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct MetaAuth {
+    pub api: AuthMode,
+    pub admin: AuthMode,
+}
+
+/// Server limits clients may need to respect.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct MetaLimits {
+    pub max_connections: usize,
+    pub max_request_body_bytes: usize,
+    /// Concurrent run limit; absent when unlimited.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub max_concurrent_runs: Option<usize>,
+    pub idle_timeout_seconds: u64,
+    pub keep_alive_interval_seconds: u64,
+    pub agent_trash_retention_hours: u64,
+}
+
+// ============================================================================
+// Feature Flag Types
+// ============================================================================
+
+/// State of one feature flag.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct FeatureResponse {
+    pub name: String,
+    pub description: String,
+    pub enabled: bool,
+}
+
+/// Response for listing feature flags.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct ListFeaturesResponse {
+    pub features: Vec<FeatureResponse>,
+}
+
+/// Request to turn a feature flag on or off.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct SetFeatureRequest {
+    pub enabled: bool,
+}
+
+// ============================================================================
+// Usage Types
+// ============================================================================
+
+/// Token usage for one agent over one bucket.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct UsageBucketResponse {
+    /// Start of the bucket (RFC 3339, UTC).
+    pub start: String,
+    pub agent: String,
+    /// Assistant responses that reported usage.
+    pub responses: u64,
+    pub prompt_tokens: u64,
+    pub completion_tokens: u64,
+    pub total_tokens: u64,
+    /// Cost at the pricing of the agent's model, when the registry has it.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub estimated_cost_usd: Option<f64>,
+}
+
+/// Summed token usage across all returned buckets.
+#[derive(Debug, Clone, Default, Serialize, Deserialize)]
+pub struct UsageTotalsResponse {
+    pub responses: u64,
+    pub prompt_tokens: u64,
+    pub completion_tokens: u64,
+    pub total_tokens: u64,
+    /// Sum of the bucket costs that could be estimated.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub estimated_cost_usd: Option<f64>,
+}
+
+/// Response for querying token usage.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct UsageResponse {
+    /// Bucket width: `hour` or `day`.
+    pub granularity: String,
+    pub from: String,
+    pub to: String,
+    pub buckets: Vec<UsageBucketResponse>,
+    pub totals: UsageTotalsResponse,
+}
+
+// ============================================================================
+// Service Account Types
+// ============================================================================
+
+/// A service account. Never includes its token.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct ServiceAccountResponse {
+    pub id: String,
+    pub name: String,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub description: Option<String>,
+    /// `resource:verb` scopes, optionally limited to a project with `@project`.
+    pub scopes: Vec<String>,
+    pub created_at: String,
+    pub rotated_at: String,
+    /// When the token replaced by the last rotation stops working.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub previous_token_expires_at: Option<String>,
+}
+
+/// Response for listing service accounts.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct ListServiceAccountsResponse {
+    pub service_accounts: Vec<ServiceAccountResponse>,
+}
+
+/// Request to create a service account.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct CreateServiceAccountRequest {
+    pub name: String,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub description: Option<String>,
+    pub scopes: Vec<String>,
+}
+
+/// Request to replace a service account's description and scopes.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct UpdateServiceAccountRequest {
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub description: Option<String>,
+    pub scopes: Vec<String>,
+}
+
+/// A service account with a newly issued token.
+///
+/// The token is only ever returned here; store it securely.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct ServiceAccountTokenResponse {
+    pub service_account: ServiceAccountResponse,
+    pub token: String,
+}
+
+// ============================================================================
+// Debug Capture Types
+// ============================================================================
+
+/// A request that was answered with a 4xx status, captured for debugging.
+///
+/// Credential headers and sensitive body fields are redacted.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct CapturedRequestResponse {
+    pub request_id: String,
+    pub captured_at: String,
+    pub method: String,
+    /// Path and query, with share tokens and query values redacted.
+    pub path: String,
+    pub status: u16,
+    pub headers: HashMap<String, String>,
+    /// Redacted JSON body, with long strings and arrays shortened.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub body: Option<serde_json::Value>,
+    /// Redacted raw body, when it was not valid JSON.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub body_text: Option<String>,
+    /// Size of the original body in bytes.
+    pub body_bytes: usize,
+    /// Whether the captured body is incomplete.
+    #[serde(default)]
+    pub truncated: bool,
+    /// The response body, usually a problem document.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub response: Option<serde_json::Value>,
+}
+
+/// Response for listing captured requests, newest first.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct ListCapturedRequestsResponse {
+    pub requests: Vec<CapturedRequestResponse>,
+}
@@ -10,8 +10,8 @@ pub use crate::api::{
     AgentDetailResponse, AgentMetadataResponse, AgentModelResponse, AgentSpecResponse,
     AgentSummary, ApprovalDecision, ApproveCommandRequest, ApproveCommandResponse,
     CreateSessionRequest, GetMessagesResponse, GetSessionResponse, ListAgentsResponse,
-    ListSessionsResponse, MessageResponse, SendMessageRequest, SendMessageResponse, SessionStatus,
-    SessionSummary,
+    ListSessionsResponse, MessageResponse, RunPriority, RunStatsResponse, SendMessageRequest,
+    SendMessageResponse, SessionStatus, SessionSummary,
 };
 pub use error::{ClientError, Result};
 pub use stream::ClientStreamEvent;
@@ -108,6 +108,7 @@ impl AgentClient {
         let url = format!("{}/api/v1/sessions", self.base_url);
         let body = CreateSessionRequest {
             agent: agent.to_string(),
+            user: None,
         };
 
         let response = self.http.post(&url).json(&body).send().await?;
@@ -163,6 +164,12 @@ impl AgentClient {
         let url = format!("{}/api/v1/sessions/{}/messages", self.base_url, session_id);
         let body = SendMessageRequest {
             content: content.to_string(),
+            input: None,
+            priority: RunPriority::default(),
+            labels: Default::default(),
+            background: false,
+            callback_url: None,
+            tools: None,
         };
 
         let response = self.http.post(&url).json(&body).send().await?;
@@ -178,6 +185,12 @@ impl AgentClient {
         let url = format!("{}/api/v1/sessions/{}/stream", self.base_url, session_id);
         let body = SendMessageRequest {
             content: content.to_string(),
+            input: None,
+            priority: RunPriority::default(),
+            labels: Default::default(),
+            background: false,
+            callback_url: None,
+            tools: None,
         };
 
         let response = self.http.post(&url).json(&body).send().await?;
@@ -27,6 +27,8 @@ pub enum ClientStreamEvent {
     Start,
     /// A token of content.
     Token { content: String },
+    /// A chunk of model reasoning (only sent to privileged callers).
+    Reasoning { content: String },
     /// Stream completed successfully.
     Done {
         message_id: String,
@@ -110,6 +112,13 @@ fn parse_event(event_type: &str, data: &str) -> Result<ClientStreamEvent> {
                 content: parsed.content,
             })
         }
+        sse_events::REASONING => {
+            let parsed: TokenData = serde_json::from_str(data)
+                .map_err(|e| ClientError::SseParseError(e.to_string()))?;
+            Ok(ClientStreamEvent::Reasoning {
+                content: parsed.content,
+            })
+        }
         sse_events::DONE => {
             let parsed: DoneData = serde_json::from_str(data)
                 .map_err(|e| ClientError::SseParseError(e.to_string()))?;
@@ -186,6 +195,17 @@ mod tests {
         );
     }
 
+    #[test]
+    fn parse_event_reasoning() {
+        let event = parse_event(sse_events::REASONING, r#"{"content":"Hmm"}"#).unwrap();
+        assert_eq!(
+            event,
+            ClientStreamEvent::Reasoning {
+                content: "Hmm".to_string()
+            }
+        );
+    }
+
     #[test]
     fn parse_event_done() {
         let event = parse_event(
@@ -1,5 +1,6 @@
 //! LLM error types.
 
+use serde::{Deserialize, Serialize};
 use thiserror::Error;
 
 /// Errors that can occur when making LLM API calls.
@@ -16,6 +17,85 @@ pub enum LLMError {
     /// Rate limited (429)
     #[error("rate limited (retry after {retry_after:?}s)")]
     RateLimit { retry_after: Option<u64> },
+
+    /// The provider does not offer this capability
+    #[error("not supported by this provider: {0}")]
+    Unsupported(&'static str),
+}
+
+/// Why a provider request failed, for retries, metrics, and alerts.
+#[derive(Debug, Clone, Copy, PartialEq, Eq, PartialOrd, Ord, Hash, Serialize, Deserialize)]
+#[serde(rename_all = "snake_case")]
+pub enum ProviderErrorClass {
+    /// The provider rate limited the request.
+    RateLimited,
+    /// The request exceeded the model's context window.
+    ContextTooLong,
+    /// The provider's content filter blocked the request or response.
+    ContentFiltered,
+    /// The credentials were missing, invalid, or not allowed.
+    Auth,
+    /// The provider could not be reached, or the request timed out.
+    Network,
+    /// The provider failed or was overloaded.
+    Server,
+}
+
+impl ProviderErrorClass {
+    pub fn as_str(self) -> &'static str {
+        match self {
+            Self::RateLimited => "rate_limited",
+            Self::ContextTooLong => "context_too_long",
+            Self::ContentFiltered => "content_filtered",
+            Self::Auth => "auth",
+            Self::Network => "network",
+            Self::Server => "server",
+        }
+    }
+}
+
+/// Error message fragments providers use for oversized requests.
+const CONTEXT_TOO_LONG_MARKERS: &[&str] = &[
+    "context_length_exceeded",
+    "maximum context length",
+    "context window",
+    "prompt is too long",
+    "too many tokens",
+    "input is too long",
+];
+
+/// Error message fragments providers use for filtered content.
+const CONTENT_FILTERED_MARKERS: &[&str] = &[
+    "content_filter",
+    "content management policy",
+    "content_policy_violation",
+];
+
+impl LLMError {
+    /// The error's class, or `None` for errors that are the caller's fault
+    /// or not about the provider at all, e.g. an invalid request.
+    pub fn class(&self) -> Option<ProviderErrorClass> {
+        match self {
+            Self::Request(_) => Some(ProviderErrorClass::Network),
+            Self::RateLimit { .. } => Some(ProviderErrorClass::RateLimited),
+            Self::Api { status, message } => classify_api_error(*status, message),
+            Self::Unsupported(_) => None,
+        }
+    }
+}
+
+fn classify_api_error(status: u16, message: &str) -> Option<ProviderErrorClass> {
+    let message = message.to_ascii_lowercase();
+    let mentions = |markers: &[&str]| markers.iter().any(|m| message.contains(m));
+    match status {
+        429 => Some(ProviderErrorClass::RateLimited),
+        401 | 403 => Some(ProviderErrorClass::Auth),
+        _ if mentions(CONTEXT_TOO_LONG_MARKERS) => Some(ProviderErrorClass::ContextTooLong),
+        _ if mentions(CONTENT_FILTERED_MARKERS) => Some(ProviderErrorClass::ContentFiltered),
+        413 => Some(ProviderErrorClass::ContextTooLong),
+        408 | 500..=599 => Some(ProviderErrorClass::Server),
+        _ => None,
+    }
 }
 
 /// Check an HTTP response for rate-limit errors, returning `RateLimit` for 429.
@@ -33,3 +113,69 @@ pub fn check_response_error(response: &reqwest::Response) -> Option<LLMError> {
     }
     None
 }
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn api_error(status: u16, message: &str) -> LLMError {
+        LLMError::Api {
+            status,
+            message: message.to_string(),
+        }
+    }
+
+    #[test]
+    fn classifies_provider_errors() {
+        let cases = [
+            (
+                LLMError::RateLimit { retry_after: None },
+                Some(ProviderErrorClass::RateLimited),
+            ),
+            (
+                api_error(
+                    400,
+                    r#"{"error":{"code":"context_length_exceeded","message":"This model's maximum context length is 8192 tokens"}}"#,
+                ),
+                Some(ProviderErrorClass::ContextTooLong),
+            ),
+            (
+                api_error(400, "prompt is too long: 210000 tokens > 200000 maximum"),
+                Some(ProviderErrorClass::ContextTooLong),
+            ),
+            (
+                api_error(
+                    400,
+                    r#"{"message":"Input is too long for requested model."}"#,
+                ),
+                Some(ProviderErrorClass::ContextTooLong),
+            ),
+            (
+                api_error(400, r#"{"error":{"code":"content_filter"}}"#),
+                Some(ProviderErrorClass::ContentFiltered),
+            ),
+            (
+                api_error(401, "invalid x-api-key"),
+                Some(ProviderErrorClass::Auth),
+            ),
+            (
+                api_error(529, "overloaded"),
+                Some(ProviderErrorClass::Server),
+            ),
+            (api_error(400, "invalid model"), None),
+            (LLMError::Unsupported("embeddings"), None),
+        ];
+        for (error, class) in cases {
+            assert_eq!(error.class(), class, "{error}");
+        }
+    }
+
+    #[test]
+    fn class_names_match_serde() {
+        let class = ProviderErrorClass::ContextTooLong;
+        assert_eq!(
+            serde_json::to_value(class).unwrap(),
+            serde_json::json!(class.as_str())
+        );
+    }
+}
@@ -2,7 +2,7 @@
 
 mod error;
 
-pub use error::{LLMError, check_response_error};
+pub use error::{LLMError, ProviderErrorClass, check_response_error};
 
 // Data types are defined in duragent-types; re-exported here for compatibility.
 pub use duragent_types::llm::*;
@@ -1,6 +1,7 @@
 //! Access control types for agent-level message filtering.
 //!
-//! Types: `AccessConfig`, `DmAccessConfig`, `GroupAccessConfig`, queue/activation config.
+//! Types: `AccessConfig`, `DmAccessConfig`, `GroupAccessConfig`,
+//! `PublicAccessConfig`, queue/activation config.
 //!
 //! Evaluation functions (`check_access`, `resolve_sender_disposition`) live in
 //! `duragent::agent::access_eval`.
@@ -20,6 +21,10 @@ pub struct AccessConfig {
     pub dm: DmAccessConfig,
     #[serde(default)]
     pub groups: GroupAccessConfig,
+    /// Anonymous invocation over the public API. Agents without it can't be
+    /// reached there.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub public: Option<PublicAccessConfig>,
 }
 
 /// DM access policy configuration.
@@ -128,6 +133,34 @@ pub enum ActivationMode {
     Always,
 }
 
+// ============================================================================
+// Public Access Types
+// ============================================================================
+
+/// Invocation without credentials, e.g. from a chat widget on a website.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct PublicAccessConfig {
+    /// Requests each client IP may make to the agent per minute.
+    #[serde(default = "default_public_requests_per_minute")]
+    pub requests_per_minute: u32,
+    /// Require a verified CAPTCHA token to start a conversation.
+    #[serde(default)]
+    pub captcha: bool,
+}
+
+impl Default for PublicAccessConfig {
+    fn default() -> Self {
+        Self {
+            requests_per_minute: default_public_requests_per_minute(),
+            captcha: false,
+        }
+    }
+}
+
+fn default_public_requests_per_minute() -> u32 {
+    10
+}
+
 // ============================================================================
 // Context Buffer Types
 // ============================================================================
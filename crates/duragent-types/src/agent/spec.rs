@@ -11,6 +11,7 @@ use serde_json::Value;
 
 use super::access::AccessConfig;
 use super::policy::ToolPolicy;
+use crate::provenance::Provenance;
 use crate::provider::Provider;
 use crate::session::CompactionMode;
 
@@ -20,6 +21,12 @@ pub const DEFAULT_MAX_TOOL_ITERATIONS: u32 = 10;
 /// Default timeout for a single LLM call (connect + stream), in seconds.
 pub const DEFAULT_LLM_TIMEOUT_SECONDS: u64 = 300;
 
+/// Most candidates best-of sampling draws per model turn.
+pub const MAX_BEST_OF_SAMPLES: u32 = 8;
+
+/// Default for the most steps a plan may have.
+pub const DEFAULT_MAX_PLAN_STEPS: u32 = 8;
+
 /// Parsed skill metadata from a SKILL.md file.
 #[derive(Debug, Clone)]
 pub struct SkillMetadata {
@@ -42,6 +49,16 @@ pub struct AgentSpec {
     pub kind: String,
     pub metadata: AgentMetadata,
     pub model: ModelConfig,
+    /// Rules that pick a different model per message.
+    pub model_routing: Option<ModelRoutingConfig>,
+    /// Sample each model turn several times and keep the best candidate.
+    pub best_of: Option<BestOfConfig>,
+    /// Answer each model turn with a consensus of several agents or models.
+    pub ensemble: Option<EnsembleConfig>,
+    /// Plan each run before executing it, one plan step at a time.
+    pub planning: Option<PlanningConfig>,
+    /// Rules that hand each message to a downstream agent.
+    pub router: Option<RouterConfig>,
     /// Agent personality and character (who the agent IS).
     pub soul: Option<String>,
     /// Core system prompt (what the agent DOES).
@@ -56,6 +73,19 @@ pub struct AgentSpec {
     pub access: Option<AccessConfig>,
     /// Memory configuration.
     pub memory: Option<AgentMemoryConfig>,
+    /// Shared prompts from the prompt library, in order.
+    pub prompts: Vec<PromptRef>,
+    /// Few-shot examples added to the prompt.
+    pub examples: Option<AgentExamplesConfig>,
+    /// Input language detection and reply language.
+    pub language: Option<AgentLanguageConfig>,
+    /// JSON Schema for structured invoke input. When set, message content
+    /// must be a JSON value that validates against it.
+    pub input_schema: Option<Value>,
+    /// Steps applied to the agent's output as it streams, in order.
+    pub stream_processors: Vec<StreamProcessor>,
+    /// Steps applied to the agent's final output, in order.
+    pub post_processors: Vec<PostProcessor>,
     /// Tool configurations for agentic capabilities.
     pub tools: Vec<ToolConfig>,
     /// Tool execution policy.
@@ -64,6 +94,14 @@ pub struct AgentSpec {
     pub hooks: HooksConfig,
     /// Directory containing the agent's configuration files.
     pub agent_dir: PathBuf,
+    /// Whether the agent accepts new work. Disabled agents stay loaded but
+    /// cannot be invoked.
+    pub enabled: bool,
+    /// Who created and last changed the agent.
+    pub provenance: Provenance,
+    /// SHA-256 over the agent directory's files when the agent was loaded.
+    /// Compared with the files on disk to detect drift.
+    pub digest: String,
 }
 
 /// Agent metadata from the Duragent Format spec.
@@ -74,6 +112,12 @@ pub struct AgentMetadata {
     pub version: Option<String>,
     #[serde(default)]
     pub labels: HashMap<String, String>,
+    /// Free-form tags, matched by agent search.
+    #[serde(default)]
+    pub tags: Vec<String>,
+    /// Project this agent belongs to, if any.
+    #[serde(default)]
+    pub project: Option<String>,
 }
 
 /// Model configuration from the Duragent Format spec.
@@ -90,6 +134,170 @@ pub struct ModelConfig {
     pub base_url: Option<String>,
 }
 
+/// Per-message model routing: the first rule whose conditions all hold picks
+/// the model; when none does, `model` is used.
+#[derive(Debug, Clone, Deserialize)]
+pub struct ModelRoutingConfig {
+    /// Model that labels each message for `when.classifier` conditions.
+    #[serde(default)]
+    pub classifier: Option<RoutingClassifierConfig>,
+    pub rules: Vec<ModelRoute>,
+}
+
+/// Labels each message with one of a fixed set of labels.
+#[derive(Debug, Clone, Deserialize)]
+pub struct RoutingClassifierConfig {
+    /// Model that classifies, served by the agent's provider. Defaults to
+    /// the agent's model.
+    #[serde(default)]
+    pub model: Option<String>,
+    /// Labels the classifier chooses from, e.g. `[simple, complex]`.
+    pub labels: Vec<String>,
+    /// What the labels mean, added to the classifier prompt.
+    #[serde(default)]
+    pub instructions: Option<String>,
+}
+
+/// A routing rule: messages matching `when` go to `model`.
+#[derive(Debug, Clone, Deserialize)]
+pub struct ModelRoute {
+    #[serde(default)]
+    pub when: RouteConditions,
+    pub model: ModelConfig,
+}
+
+/// Router: each message is handed to the downstream agent picked by the
+/// first rule whose conditions all hold, which answers it in the router's
+/// session; when none does, `default` answers, or the router itself.
+#[derive(Debug, Clone, Deserialize)]
+pub struct RouterConfig {
+    /// Model that labels each message with an intent for `when.classifier`
+    /// conditions.
+    #[serde(default)]
+    pub classifier: Option<RoutingClassifierConfig>,
+    pub rules: Vec<AgentRoute>,
+    /// Agent that answers messages no rule matches.
+    #[serde(default)]
+    pub default: Option<String>,
+}
+
+/// A router rule: messages matching `when` go to `agent`.
+#[derive(Debug, Clone, Deserialize)]
+pub struct AgentRoute {
+    #[serde(default)]
+    pub when: RouteConditions,
+    pub agent: String,
+}
+
+/// Conditions on the incoming message. Unset conditions always hold, so a
+/// rule without any matches every message.
+#[derive(Debug, Clone, Default, Deserialize)]
+pub struct RouteConditions {
+    /// Message is at least this many characters long.
+    #[serde(default)]
+    pub min_input_chars: Option<usize>,
+    /// Message is at most this many characters long.
+    #[serde(default)]
+    pub max_input_chars: Option<usize>,
+    /// Message is detected as one of these languages (ISO 639-1 codes).
+    #[serde(default)]
+    pub languages: Vec<String>,
+    /// Classifier labeled the message with one of these labels.
+    #[serde(default)]
+    pub classifier: Vec<String>,
+}
+
+/// Best-of sampling: each model turn is sampled `samples` times
+/// concurrently, and one candidate is selected to continue the run.
+#[derive(Debug, Clone, Deserialize)]
+pub struct BestOfConfig {
+    /// Candidates per model turn.
+    pub samples: u32,
+    /// Models to sample, taken in turn. Defaults to the agent's model.
+    #[serde(default)]
+    pub models: Vec<ModelConfig>,
+    /// How the winning candidate is picked.
+    #[serde(default)]
+    pub select: BestOfSelect,
+    /// Model that judges candidates for `select: judge`. Defaults to the
+    /// agent's model.
+    #[serde(default)]
+    pub judge: Option<ModelConfig>,
+    /// What makes a candidate best, added to the judge prompt.
+    #[serde(default)]
+    pub criteria: Option<String>,
+}
+
+/// How best-of sampling picks its winner.
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "snake_case")]
+pub enum BestOfSelect {
+    /// A judge model picks the best candidate.
+    #[default]
+    Judge,
+    /// The candidate with the longest answer.
+    Longest,
+    /// The candidate with the shortest answer.
+    Shortest,
+}
+
+impl BestOfSelect {
+    pub fn as_str(self) -> &'static str {
+        match self {
+            Self::Judge => "judge",
+            Self::Longest => "longest",
+            Self::Shortest => "shortest",
+        }
+    }
+}
+
+/// Ensemble: every member answers each model turn, and a synthesizer model
+/// merges the answers into a consensus, reporting where they disagree.
+#[derive(Debug, Clone, Deserialize)]
+pub struct EnsembleConfig {
+    pub members: Vec<EnsembleMember>,
+    /// Model that synthesizes the consensus. Defaults to the agent's model.
+    #[serde(default)]
+    pub synthesizer: Option<ModelConfig>,
+    /// How to weigh the answers, added to the synthesizer prompt.
+    #[serde(default)]
+    pub instructions: Option<String>,
+    /// Append the points the members disagree on to the answer.
+    #[serde(default)]
+    pub report_disagreements: bool,
+}
+
+/// An ensemble member: another agent, answering with its own model and
+/// prompts, or a model answering with this agent's prompts. Exactly one of
+/// `agent` and `model` is set.
+#[derive(Debug, Clone, Deserialize)]
+pub struct EnsembleMember {
+    #[serde(default)]
+    pub agent: Option<String>,
+    #[serde(default)]
+    pub model: Option<ModelConfig>,
+}
+
+/// Planner-executor: each run starts with a planning step in which the model
+/// records a structured plan, then works through the plan one step at a
+/// time, with only the tools each step names.
+#[derive(Debug, Clone, Deserialize)]
+pub struct PlanningConfig {
+    /// Most steps a plan may have.
+    #[serde(default = "default_max_plan_steps")]
+    pub max_steps: u32,
+    /// Pause for approval of each plan before executing it.
+    #[serde(default)]
+    pub require_approval: bool,
+    /// How to plan, added to the planning prompt.
+    #[serde(default)]
+    pub instructions: Option<String>,
+}
+
+fn default_max_plan_steps() -> u32 {
+    DEFAULT_MAX_PLAN_STEPS
+}
+
 /// Session behavior configuration for an agent.
 #[derive(Debug, Clone, Default, Serialize, Deserialize)]
 pub struct AgentSessionConfig {
@@ -102,6 +310,18 @@ pub struct AgentSessionConfig {
     /// Timeout for a single LLM call (connect + full stream consumption), in seconds.
     #[serde(default = "default_llm_timeout_seconds")]
     pub llm_timeout_seconds: u64,
+    /// Wall-clock budget for a whole run (all LLM calls and tools), in seconds.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub max_wall_time_seconds: Option<u64>,
+    /// Budget for total tool execution time within a run, in seconds.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub max_tool_time_seconds: Option<u64>,
+    /// Most tool calls within a run.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub max_tool_calls: Option<u32>,
+    /// Most calls of each listed tool within a run, by tool name.
+    #[serde(default, skip_serializing_if = "HashMap::is_empty")]
+    pub max_tool_calls_per_tool: HashMap<String, u32>,
     /// Context window management configuration.
     #[serde(default)]
     pub context: ContextConfig,
@@ -201,12 +421,214 @@ pub struct AgentMemoryConfig {
     /// Currently only "filesystem" is supported.
     #[serde(default = "default_memory_backend")]
     pub backend: String,
+    /// Long-term memory of each end user, kept across sessions.
+    #[serde(default)]
+    pub user: Option<UserMemoryConfig>,
+    /// Entities and relations extracted from conversations, queryable with
+    /// the `entities` tool.
+    #[serde(default)]
+    pub entities: Option<EntityMemoryConfig>,
 }
 
 fn default_memory_backend() -> String {
     "filesystem".to_string()
 }
 
+/// Per-user memory: facts extracted from what users say, recalled in later
+/// sessions with the same user.
+#[derive(Debug, Clone, Deserialize)]
+pub struct UserMemoryConfig {
+    /// Facts added to the prompt per request.
+    #[serde(default = "default_user_memory_recall")]
+    pub recall: usize,
+    /// Embedding model for recalling the facts most similar to the user's
+    /// message. Without one, the most recent facts are recalled.
+    #[serde(default)]
+    pub embedding_model: Option<String>,
+    /// Model that extracts facts, served by the agent's provider. Defaults to
+    /// the agent's model.
+    #[serde(default)]
+    pub extraction_model: Option<String>,
+}
+
+fn default_user_memory_recall() -> usize {
+    5
+}
+
+/// Structured memory: who and what the agent's conversations mention, and
+/// how they relate ("Apollo uses Postgres").
+#[derive(Debug, Clone, Default, Deserialize)]
+pub struct EntityMemoryConfig {
+    /// Model that extracts entities and relations, served by the agent's
+    /// provider. Defaults to the agent's model.
+    #[serde(default)]
+    pub extraction_model: Option<String>,
+}
+
+/// Reference to a shared prompt: `name` follows the latest version, and
+/// `name@N` pins version `N`.
+#[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(try_from = "String", into = "String")]
+pub struct PromptRef {
+    pub name: String,
+    pub version: Option<u32>,
+}
+
+impl std::str::FromStr for PromptRef {
+    type Err = String;
+
+    fn from_str(s: &str) -> Result<Self, Self::Err> {
+        let (name, version) = match s.split_once('@') {
+            Some((name, version)) => {
+                let version = version
+                    .parse::<u32>()
+                    .ok()
+                    .filter(|v| *v > 0)
+                    .ok_or_else(|| format!("invalid prompt version in '{s}'"))?;
+                (name, Some(version))
+            }
+            None => (s, None),
+        };
+        if name.is_empty() {
+            return Err(format!("invalid prompt reference '{s}'"));
+        }
+        Ok(Self {
+            name: name.to_string(),
+            version,
+        })
+    }
+}
+
+impl TryFrom<String> for PromptRef {
+    type Error = String;
+
+    fn try_from(s: String) -> Result<Self, Self::Error> {
+        s.parse()
+    }
+}
+
+impl From<PromptRef> for String {
+    fn from(r: PromptRef) -> Self {
+        r.to_string()
+    }
+}
+
+impl std::fmt::Display for PromptRef {
+    fn fmt(&self, f: &mut std::fmt::Formatter<'_>) -> std::fmt::Result {
+        match self.version {
+            Some(version) => write!(f, "{}@{version}", self.name),
+            None => f.write_str(&self.name),
+        }
+    }
+}
+
+/// Few-shot example configuration for an agent.
+#[derive(Debug, Clone, Deserialize)]
+pub struct AgentExamplesConfig {
+    /// Example pools to draw from, named after the agents that own them.
+    /// Defaults to the agent's own pool.
+    #[serde(default)]
+    pub pools: Vec<String>,
+    /// Examples added to the prompt.
+    #[serde(default = "default_example_count")]
+    pub count: usize,
+    #[serde(default)]
+    pub selection: ExampleSelection,
+    /// Embedding model for `similarity` selection, served by the agent's
+    /// provider.
+    #[serde(default)]
+    pub embedding_model: Option<String>,
+}
+
+impl AgentExamplesConfig {
+    /// Pools to draw from, falling back to the agent's own.
+    pub fn pools_for<'a>(&'a self, agent: &'a str) -> Vec<&'a str> {
+        if self.pools.is_empty() {
+            return vec![agent];
+        }
+        self.pools.iter().map(String::as_str).collect()
+    }
+}
+
+fn default_example_count() -> usize {
+    3
+}
+
+/// How few-shot examples are picked for a request.
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Default, Serialize, Deserialize)]
+#[serde(rename_all = "snake_case")]
+pub enum ExampleSelection {
+    /// The oldest examples, in the order they were added (default).
+    #[default]
+    First,
+    /// The examples most similar to the user's message, by embedding.
+    Similarity,
+}
+
+/// Input language detection and the language replies are in. Setting it
+/// records the detected language of each message in the session event log.
+#[derive(Debug, Clone, Default, Deserialize)]
+pub struct AgentLanguageConfig {
+    /// Language to reply in: `input` for the language the user wrote in, or
+    /// an ISO 639-1 code. Unset leaves it to the model.
+    #[serde(default)]
+    pub reply: Option<String>,
+    /// Reply language for `reply: input` when the input's language can't be
+    /// detected. Unset leaves it to the model.
+    #[serde(default)]
+    pub fallback: Option<String>,
+}
+
+/// Reply in the language the user wrote in (`language.reply`).
+pub const REPLY_IN_INPUT_LANGUAGE: &str = "input";
+
+/// A step applied to an agent's final output before it is returned.
+#[derive(Debug, Clone, PartialEq, Eq, Deserialize)]
+#[serde(tag = "type", rename_all = "snake_case")]
+pub enum PostProcessor {
+    /// Render Markdown as HTML.
+    MarkdownHtml,
+    /// Replace the output with the first JSON object or array in it.
+    JsonExtract,
+    /// Number inline Markdown links and list their URLs at the end.
+    Citations {
+        /// Heading above the source list.
+        #[serde(default = "default_citations_heading")]
+        heading: String,
+    },
+    /// Mask profane words.
+    ProfanityFilter {
+        /// Words masked in addition to the built-in list.
+        #[serde(default)]
+        words: Vec<String>,
+        /// Text that replaces each word. Defaults to one `*` per character.
+        #[serde(default)]
+        replacement: Option<String>,
+    },
+}
+
+fn default_citations_heading() -> String {
+    "Sources".to_string()
+}
+
+/// A step applied to an agent's output as it streams, without waiting for
+/// the whole response.
+#[derive(Debug, Clone, PartialEq, Eq, Deserialize)]
+#[serde(tag = "type", rename_all = "snake_case")]
+pub enum StreamProcessor {
+    /// Replace terms, ignoring ASCII case.
+    Redact {
+        terms: Vec<String>,
+        /// Text that replaces each term. Defaults to `[redacted]`.
+        #[serde(default)]
+        replacement: Option<String>,
+    },
+    /// End the response before the first of these sequences.
+    StopSequences { sequences: Vec<String> },
+    /// Close a Markdown code fence left open at the end of the response.
+    CloseFences,
+}
+
 /// Tool configuration from the Duragent Format spec.
 #[derive(Debug, Clone, Deserialize)]
 #[serde(tag = "type", rename_all = "snake_case")]
@@ -290,6 +712,7 @@ pub struct LoadedAgentFiles {
 #[derive(Debug, Clone)]
 pub struct AgentFileRefs {
     pub name: String,
+    pub project: Option<String>,
     pub soul: Option<String>,
     pub system_prompt: Option<String>,
     pub instructions: Option<String>,
@@ -6,6 +6,8 @@
 
 pub mod agent;
 pub mod llm;
+pub mod project;
+pub mod provenance;
 pub mod provider;
 pub mod scheduler;
 pub mod session;
@@ -69,6 +69,10 @@ pub struct Message {
     /// Tool call ID (when role is tool).
     #[serde(default, skip_serializing_if = "Option::is_none")]
     pub tool_call_id: Option<String>,
+    /// Reasoning the model returned apart from its answer, if any. Providers
+    /// fill this in; it is never sent back to a model.
+    #[serde(skip)]
+    pub reasoning: Option<String>,
 }
 
 impl Message {
@@ -79,6 +83,7 @@ impl Message {
             content: Some(content.into()),
             tool_calls: None,
             tool_call_id: None,
+            reasoning: None,
         }
     }
 
@@ -89,6 +94,7 @@ impl Message {
             content: Some(content.into()),
             tool_calls: None,
             tool_call_id: Some(tool_call_id.into()),
+            reasoning: None,
         }
     }
 
@@ -99,6 +105,7 @@ impl Message {
             content: Some(content.into()),
             tool_calls: None,
             tool_call_id: None,
+            reasoning: None,
         }
     }
 
@@ -109,6 +116,7 @@ impl Message {
             content: None,
             tool_calls: Some(tool_calls),
             tool_call_id: None,
+            reasoning: None,
         }
     }
 
@@ -226,6 +234,9 @@ pub struct Usage {
 pub enum StreamEvent {
     /// A content token from the assistant.
     Token(String),
+    /// A chunk of reasoning, streamed apart from the answer by models that
+    /// expose it.
+    Reasoning(String),
     /// Tool calls from the assistant.
     ToolCalls(Vec<ToolCall>),
     /// The stream is complete with optional usage stats.
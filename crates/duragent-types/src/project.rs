@@ -0,0 +1,68 @@
+//! Project types.
+//!
+//! A project groups agents under shared settings. Agents join a project by
+//! naming it in `metadata.project`; the project's defaults fill in whatever
+//! the agent's manifest leaves unset.
+
+use serde::{Deserialize, Serialize};
+
+use crate::agent::ModelConfig;
+use crate::provider::Provider;
+
+/// A project definition.
+#[derive(Debug, Clone, Default, Serialize, Deserialize)]
+pub struct Project {
+    /// Project name. Taken from the storage key, not the document.
+    #[serde(skip)]
+    pub name: String,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub description: Option<String>,
+    /// Model for member agents whose manifest omits `spec.model`.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub default_model: Option<ProjectModel>,
+    /// Run budgets for member agents that don't set their own.
+    #[serde(default, skip_serializing_if = "ProjectBudget::is_empty")]
+    pub budget: ProjectBudget,
+}
+
+/// Default model settings of a project.
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct ProjectModel {
+    pub provider: String,
+    pub name: String,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub temperature: Option<f32>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub max_output_tokens: Option<u32>,
+}
+
+impl ProjectModel {
+    /// Model configuration for an agent that inherits this default.
+    #[must_use]
+    pub fn to_model_config(&self) -> ModelConfig {
+        ModelConfig {
+            provider: Provider::from(self.provider.clone()),
+            name: self.name.clone(),
+            temperature: self.temperature,
+            max_input_tokens: None,
+            max_output_tokens: self.max_output_tokens,
+            base_url: None,
+        }
+    }
+}
+
+/// Run budgets shared by a project's agents, in seconds.
+#[derive(Debug, Clone, Default, PartialEq, Eq, Serialize, Deserialize)]
+pub struct ProjectBudget {
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub max_wall_time_seconds: Option<u64>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub max_tool_time_seconds: Option<u64>,
+}
+
+impl ProjectBudget {
+    #[must_use]
+    pub fn is_empty(&self) -> bool {
+        self.max_wall_time_seconds.is_none() && self.max_tool_time_seconds.is_none()
+    }
+}
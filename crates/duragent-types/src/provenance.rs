@@ -0,0 +1,56 @@
+//! Provenance types.
+//!
+//! Records who created and last changed an agent, who started a session,
+//! and through which channel, so changes can be traced back to a person
+//! or a system.
+
+use chrono::{DateTime, Utc};
+use serde::{Deserialize, Serialize};
+
+/// Channel a change or run came through.
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "snake_case")]
+pub enum ChangeSource {
+    /// The HTTP API.
+    Api,
+    /// The `duragent` command line.
+    Cli,
+    /// Files written to disk directly, e.g. by a GitOps sync. Assumed when
+    /// an agent has no provenance record.
+    #[default]
+    Gitops,
+    /// A chat gateway (sessions only).
+    Gateway,
+    /// A schedule firing (sessions only).
+    Scheduler,
+    /// An anonymous caller of a public agent (sessions only).
+    Public,
+}
+
+/// Who created and last changed an agent.
+#[derive(Debug, Clone, Default, PartialEq, Serialize, Deserialize)]
+pub struct Provenance {
+    /// Channel of the most recent change.
+    #[serde(default)]
+    pub source: ChangeSource,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub created_by: Option<String>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub created_at: Option<DateTime<Utc>>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub updated_by: Option<String>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub updated_at: Option<DateTime<Utc>>,
+}
+
+/// Who started a session and through which channel. Every run in the
+/// session is attributed to this origin.
+#[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
+pub struct RunOrigin {
+    pub source: ChangeSource,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub created_by: Option<String>,
+    /// End user the session is with, which keys their long-term memory.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub user: Option<String>,
+}
@@ -9,9 +9,23 @@ use serde::Deserialize;
 #[serde(try_from = "String")]
 pub enum Provider {
     Anthropic,
+    /// Azure OpenAI; the model name is the deployment name.
+    Azure,
+    /// AWS Bedrock via the Converse API.
+    Bedrock,
+    /// Google Gemini API.
+    Gemini,
+    /// Self-hosted llama.cpp server.
+    LlamaCpp,
+    /// Self-hosted LM Studio server.
+    LmStudio,
+    /// Offline echo provider for benchmarks and local testing.
+    Mock,
     Ollama,
     OpenAI,
     OpenRouter,
+    /// Self-hosted vLLM server.
+    Vllm,
     Other(String),
 }
 
@@ -20,9 +34,16 @@ impl Provider {
     pub fn as_str(&self) -> &str {
         match self {
             Provider::Anthropic => "anthropic",
+            Provider::Azure => "azure",
+            Provider::Bedrock => "bedrock",
+            Provider::Gemini => "gemini",
+            Provider::LlamaCpp => "llamacpp",
+            Provider::LmStudio => "lmstudio",
+            Provider::Mock => "mock",
             Provider::Ollama => "ollama",
             Provider::OpenAI => "openai",
             Provider::OpenRouter => "openrouter",
+            Provider::Vllm => "vllm",
             Provider::Other(s) => s.as_str(),
         }
     }
@@ -40,9 +61,16 @@ impl FromStr for Provider {
     fn from_str(s: &str) -> Result<Self, Self::Err> {
         Ok(match s {
             "anthropic" => Provider::Anthropic,
+            "azure" => Provider::Azure,
+            "bedrock" => Provider::Bedrock,
+            "gemini" => Provider::Gemini,
+            "llamacpp" => Provider::LlamaCpp,
+            "lmstudio" => Provider::LmStudio,
+            "mock" => Provider::Mock,
             "ollama" => Provider::Ollama,
             "openai" => Provider::OpenAI,
             "openrouter" => Provider::OpenRouter,
+            "vllm" => Provider::Vllm,
             other => Provider::Other(other.to_string()),
         })
     }
@@ -225,6 +225,36 @@ pub struct RunLogEntry {
     pub next_run_at: Option<i64>,
 }
 
+/// A scheduled run that failed after exhausting its retries.
+///
+/// Kept until it is requeued, so failures are not lost in the run log.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct DeadLetter {
+    /// Unique identifier.
+    pub id: String,
+    /// Schedule whose run failed.
+    pub schedule_id: ScheduleId,
+    /// Agent that owns the schedule.
+    pub agent: String,
+    /// Session that created the schedule.
+    pub created_by_session: String,
+    /// Where results would have been delivered.
+    pub destination: ScheduleDestination,
+    /// What the run was doing.
+    pub payload: SchedulePayload,
+    /// Retry configuration of the failed run, reused on requeue.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub retry: Option<RetryConfig>,
+    /// Attempts made, including the first.
+    pub attempts: u8,
+    /// Error from the last attempt.
+    pub error: String,
+    /// When the first attempt started.
+    pub started_at: DateTime<Utc>,
+    /// When the last attempt failed.
+    pub failed_at: DateTime<Utc>,
+}
+
 // ============================================================================
 // Tests
 // ============================================================================
@@ -60,6 +60,12 @@ pub enum SessionEventPayload {
         #[serde(skip_serializing_if = "Option::is_none")]
         usage: Option<Usage>,
     },
+    /// Checkpoint of assistant content streamed so far (cumulative).
+    ///
+    /// Written periodically while a response streams. Superseded by the final
+    /// `AssistantMessage`; if none follows (server crashed mid-stream), replay
+    /// recovers the partial content as the assistant message.
+    AssistantPartial { message_id: String, content: String },
     /// Composite assistant response with optional tool calls (preferred for new events).
     ///
     /// Folds content + tool_calls into a single atomic event, eliminating the
@@ -115,6 +121,125 @@ pub enum SessionEventPayload {
     },
     /// An error occurred (recoverable).
     Error { code: String, message: String },
+    /// Language detected for the latest user message (agents with
+    /// `language`), and the language the agent was told to reply in.
+    LanguageDetected {
+        #[serde(default, skip_serializing_if = "Option::is_none")]
+        language: Option<String>,
+        #[serde(default, skip_serializing_if = "Option::is_none")]
+        reply_language: Option<String>,
+    },
+    /// Reasoning the model returned apart from its answer.
+    ///
+    /// Kept for the run trace only; `to_message()` returns `None`, so it is
+    /// never sent back to a model.
+    Reasoning { agent: String, content: String },
+    /// The provider rejected a request as too long for the model's context,
+    /// and it was retried once with a shortened conversation.
+    ///
+    /// Kept for the run trace only; `to_message()` returns `None`.
+    ContextRecovered {
+        agent: String,
+        /// Oldest messages dropped from the request.
+        dropped_messages: u32,
+        /// Estimated request tokens before and after shortening.
+        tokens_before: u32,
+        tokens_after: u32,
+    },
+    /// A model turn was sampled several times and one candidate selected
+    /// (agents with `best_of`).
+    ///
+    /// Kept for the run trace only; `to_message()` returns `None`.
+    SamplesSelected {
+        agent: String,
+        /// How the winner was picked: `judge`, `longest`, or `shortest`.
+        select: String,
+        /// Index of the winning candidate in `candidates`.
+        winner: usize,
+        candidates: Vec<SampleCandidate>,
+        /// Why the judge gave no answer, in which case the first candidate
+        /// that didn't fail won.
+        #[serde(default, skip_serializing_if = "Option::is_none")]
+        judge_error: Option<String>,
+    },
+    /// An ensemble's members answered a model turn and their answers were
+    /// merged into a consensus (agents with `ensemble`).
+    ///
+    /// Kept for the run trace only; `to_message()` returns `None`.
+    ConsensusReached {
+        agent: String,
+        members: Vec<MemberAnswer>,
+        /// Points the members disagree on; empty when they agree.
+        #[serde(default, skip_serializing_if = "Vec::is_empty")]
+        disagreements: Vec<String>,
+        /// Why no consensus was synthesized, in which case the first member
+        /// answer that didn't fail was used.
+        #[serde(default, skip_serializing_if = "Option::is_none")]
+        synthesis_error: Option<String>,
+    },
+    /// The planning step of a run produced a plan (agents with `planning`).
+    ///
+    /// Kept for the run trace only; `to_message()` returns `None`.
+    PlanCreated {
+        /// The `plan` tool call that recorded the plan.
+        call_id: String,
+        steps: Vec<PlanStep>,
+        /// Whether the plan waits for approval before it is executed.
+        #[serde(default)]
+        requires_approval: bool,
+    },
+    /// The model finished a step of the run's plan.
+    ///
+    /// Kept for the run trace only; `to_message()` returns `None`.
+    PlanStepCompleted {
+        /// The `plan` tool call that recorded the plan.
+        call_id: String,
+        /// Index of the step in the plan.
+        step: usize,
+        /// The model's account of the step.
+        content: String,
+    },
+}
+
+/// One step of a plan.
+#[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
+pub struct PlanStep {
+    /// What the step does.
+    pub description: String,
+    /// Tools the step uses; every tool when empty.
+    #[serde(default, skip_serializing_if = "Vec::is_empty")]
+    pub tools: Vec<String>,
+}
+
+/// One ensemble member's answer to a model turn.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct MemberAnswer {
+    /// `agent:<name>` or `provider/model`.
+    pub member: String,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub content: Option<String>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub usage: Option<Usage>,
+    /// Why the member failed to answer, if it did.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub error: Option<String>,
+}
+
+/// One candidate of a best-of sampled model turn.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct SampleCandidate {
+    /// `provider/model` that produced the candidate.
+    pub model: String,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub content: Option<String>,
+    /// Names of the tools the candidate called.
+    #[serde(default, skip_serializing_if = "Vec::is_empty")]
+    pub tool_calls: Vec<String>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub usage: Option<Usage>,
+    /// Why the sample failed, if it did.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub error: Option<String>,
 }
 
 /// Type of approval decision.
@@ -209,6 +334,14 @@ pub struct PendingApproval {
     /// Used in group chats to ensure only the requester can approve.
     #[serde(default, skip_serializing_if = "Option::is_none")]
     pub requester_id: Option<String>,
+    /// Agent whose run is paused, when a router handed the message to an
+    /// agent other than the session's. The run resumes with that agent.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub agent: Option<String>,
+    /// Tools the paused run was restricted to, e.g. by its invoke request.
+    /// The run resumes with the same restriction.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub tools: Option<Vec<String>>,
 }
 
 /// Default tool type for backwards compatibility with persisted approvals.
@@ -234,6 +367,8 @@ impl PendingApproval {
             tool_type,
             messages,
             requester_id: None,
+            agent: None,
+            tools: None,
         }
     }
 }
@@ -356,6 +491,32 @@ mod tests {
         }
     }
 
+    #[test]
+    fn assistant_partial_serialization_roundtrip() {
+        let event = SessionEvent::new(
+            7,
+            SessionEventPayload::AssistantPartial {
+                message_id: "msg_01".to_string(),
+                content: "Hello, wor".to_string(),
+            },
+        );
+
+        let json = serde_json::to_string(&event).unwrap();
+        assert!(json.contains("\"type\":\"assistant_partial\""));
+
+        let parsed: SessionEvent = serde_json::from_str(&json).unwrap();
+        match parsed.payload {
+            SessionEventPayload::AssistantPartial {
+                message_id,
+                content,
+            } => {
+                assert_eq!(message_id, "msg_01");
+                assert_eq!(content, "Hello, wor");
+            }
+            _ => panic!("Wrong event type"),
+        }
+    }
+
     #[test]
     fn serialize_assistant_response_with_tool_calls() {
         let event = SessionEvent::new(
@@ -8,6 +8,7 @@ use serde::{Deserialize, Serialize};
 
 use crate::agent::OnDisconnect;
 use crate::llm::Message;
+use crate::provenance::RunOrigin;
 
 use super::SessionStatus;
 
@@ -67,6 +68,10 @@ pub struct SessionConfig {
     #[serde(default, skip_serializing_if = "Option::is_none")]
     pub gateway_chat_id: Option<String>,
 
+    /// Who started the session.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub origin: Option<RunOrigin>,
+
     /// Pending approval waiting for user decision.
     #[serde(default, skip_serializing_if = "Option::is_none")]
     pub pending_approval: Option<PendingApproval>,
@@ -0,0 +1,126 @@
+//! Drift between loaded agents and the agents directory.
+//!
+//! Agents are loaded into memory at startup and on reload; files edited on
+//! the server afterwards are not picked up until the next reload, unless
+//! `agents.watch_interval_seconds` is set, in which case the server
+//! reconciles drift on that interval. Drift detection compares the loaded
+//! agents with a fresh scan of the directory.
+
+use std::collections::BTreeMap;
+
+use super::store::AgentStore;
+use crate::store::{AgentScanResult, ScanWarning};
+
+/// How a loaded agent differs from the agents directory.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum DriftKind {
+    /// On disk and valid, but not loaded.
+    NotLoaded,
+    /// Loaded, but no longer on disk.
+    MissingOnDisk,
+    /// Loaded, but the files on disk have changed since.
+    Modified,
+    /// On disk, but fails to load. A loaded copy, if any, is the old version.
+    Invalid,
+}
+
+/// One agent that is out of sync.
+#[derive(Debug, Clone)]
+pub struct AgentDrift {
+    pub name: String,
+    pub kind: DriftKind,
+    /// What differs, or why the agent on disk fails to load.
+    pub detail: Option<String>,
+}
+
+/// Compare `loaded` agents with a fresh scan of the agents directory.
+///
+/// Results are sorted by agent name.
+pub fn detect_drift(loaded: &AgentStore, disk: &AgentScanResult) -> Vec<AgentDrift> {
+    let mut drift = BTreeMap::new();
+    let on_disk: BTreeMap<&str, _> = disk
+        .agents
+        .iter()
+        .map(|spec| (spec.metadata.name.as_str(), spec))
+        .collect();
+
+    for spec in on_disk.values() {
+        let name = spec.metadata.name.clone();
+        let entry = match loaded.get(&name) {
+            None => Some((DriftKind::NotLoaded, None)),
+            Some(current) if current.digest != spec.digest => {
+                Some((DriftKind::Modified, Some("files changed".to_string())))
+            }
+            Some(current) if current.enabled != spec.enabled => Some((
+                DriftKind::Modified,
+                Some(format!("enabled is {} on disk", spec.enabled)),
+            )),
+            Some(_) => None,
+        };
+        if let Some((kind, detail)) = entry {
+            drift.insert(name.clone(), AgentDrift { name, kind, detail });
+        }
+    }
+
+    for warning in &disk.warnings {
+        if let ScanWarning::InvalidAgent { name, error } = warning {
+            drift.insert(
+                name.clone(),
+                AgentDrift {
+                    name: name.clone(),
+                    kind: DriftKind::Invalid,
+                    detail: Some(error.clone()),
+                },
+            );
+        }
+    }
+
+    for (name, _) in loaded.snapshot() {
+        if !on_disk.contains_key(name.as_str()) && !drift.contains_key(&name) {
+            drift.insert(
+                name.clone(),
+                AgentDrift {
+                    name,
+                    kind: DriftKind::MissingOnDisk,
+                    detail: None,
+                },
+            );
+        }
+    }
+
+    drift.into_values().collect()
+}
+
+/// Bring `loaded` in line with the agents directory, which wins.
+///
+/// Agents that fail to load on disk are left as they are. Returns the names
+/// of the agents that were updated, loaded, or unloaded.
+pub fn reconcile_agents(
+    loaded: &AgentStore,
+    disk: AgentScanResult,
+    drift: &[AgentDrift],
+) -> Vec<String> {
+    let mut on_disk: BTreeMap<String, _> = disk
+        .agents
+        .into_iter()
+        .map(|spec| (spec.metadata.name.clone(), spec))
+        .collect();
+
+    let mut reconciled = Vec::new();
+    for entry in drift {
+        match entry.kind {
+            DriftKind::NotLoaded | DriftKind::Modified => {
+                if let Some(spec) = on_disk.remove(&entry.name) {
+                    loaded.upsert(spec);
+                    reconciled.push(entry.name.clone());
+                }
+            }
+            DriftKind::MissingOnDisk => {
+                loaded.remove(&entry.name);
+                reconciled.push(entry.name.clone());
+            }
+            DriftKind::Invalid => {}
+        }
+    }
+    reconciled
+}
@@ -8,22 +8,30 @@ pub const KIND_AGENT: &str = "Agent";
 
 // Re-export all domain types from duragent-types
 pub use duragent_types::agent::*;
+pub use duragent_types::project::{Project, ProjectBudget, ProjectModel};
+pub use duragent_types::provenance::{ChangeSource, Provenance, RunOrigin};
 
 // Local modules (server-only logic that can't move to duragent-types)
 mod access_eval;
+mod drift;
 mod error;
 mod parsing;
 mod policy_eval;
 mod policy_ext;
+mod selector;
 pub mod skill;
 mod spec_eval;
 mod store;
 
 pub use access_eval::{check_access, resolve_sender_disposition};
+pub use drift::{AgentDrift, DriftKind, detect_drift, reconcile_agents};
 pub use error::{AgentLoadError, AgentLoadWarning};
+#[cfg(test)]
+pub(crate) use parsing::{RawAgentSpec, RawAgentSpecBody};
 pub use parsing::{parse_agent_file_refs, parse_agent_yaml, validate_builtin_tools};
 pub use policy_eval::ToolPolicyEval;
 pub use policy_ext::{PolicyLocks, add_policy_pattern_and_save};
+pub use selector::{LabelSelector, SelectorParseError};
 pub use skill::SkillParseError;
 pub use spec_eval::{HooksConfigEval, ModelConfigEval};
-pub use store::{AgentStore, log_scan_warnings};
+pub use store::{AgentQuery, AgentStore, log_scan_warnings};
@@ -10,20 +10,26 @@ use serde::Deserialize;
 use super::error::{AgentLoadError, AgentLoadWarning};
 use super::{API_VERSION_V1ALPHA1, KIND_AGENT};
 use crate::agent::{
-    AccessConfig, AgentFileRefs, AgentMemoryConfig, AgentMetadata, AgentSessionConfig, AgentSpec,
-    HooksConfig, HooksConfigEval, LoadedAgentFiles, ModelConfig, SkillMetadata, ToolConfig,
-    ToolPolicy,
+    AccessConfig, AgentExamplesConfig, AgentFileRefs, AgentLanguageConfig, AgentMemoryConfig,
+    AgentMetadata, AgentSessionConfig, AgentSpec, BestOfConfig, EnsembleConfig, ExampleSelection,
+    HooksConfig, HooksConfigEval, LoadedAgentFiles, MAX_BEST_OF_SAMPLES, ModelConfig,
+    ModelRoutingConfig, PlanningConfig, PostProcessor, Project, PromptRef, REPLY_IN_INPUT_LANGUAGE,
+    RouteConditions, RouterConfig, RoutingClassifierConfig, SkillMetadata, StreamProcessor,
+    ToolConfig, ToolPolicy,
 };
 /// Create an agent from YAML content and pre-loaded files.
 ///
 /// This function performs no file I/O - all content should be loaded by the caller
-/// (e.g., `FileAgentCatalog`).
+/// (e.g., `FileAgentCatalog`). `project` is the project named by
+/// `metadata.project`, if the manifest names one; its defaults fill in the
+/// model and run budgets the manifest leaves unset.
 pub fn parse_agent_yaml(
     yaml_content: &str,
     files: LoadedAgentFiles,
     skills: Vec<SkillMetadata>,
     policy: ToolPolicy,
     agent_dir: PathBuf,
+    project: Option<&Project>,
 ) -> Result<AgentSpec, AgentLoadError> {
     let raw: RawAgentSpec = serde_saphyr::from_str(yaml_content).map_err(AgentLoadError::Yaml)?;
 
@@ -50,6 +56,162 @@ pub fn parse_agent_yaml(
         ));
     }
 
+    // Validate prompt references
+    if let Some(prompt) = raw
+        .spec
+        .prompts
+        .iter()
+        .find(|p| !crate::store::file::is_valid_agent_name(&p.name))
+    {
+        return Err(AgentLoadError::Validation(format!(
+            "invalid prompt name '{}'",
+            prompt.name
+        )));
+    }
+
+    // Validate examples config
+    if let Some(examples) = &raw.spec.examples {
+        if examples.count == 0 {
+            return Err(AgentLoadError::Validation(
+                "examples.count must be > 0".to_string(),
+            ));
+        }
+        if examples.selection == ExampleSelection::Similarity && examples.embedding_model.is_none()
+        {
+            return Err(AgentLoadError::Validation(
+                "examples.embedding_model is required for similarity selection".to_string(),
+            ));
+        }
+        if let Some(pool) = examples
+            .pools
+            .iter()
+            .find(|p| !crate::store::file::is_valid_agent_name(p))
+        {
+            return Err(AgentLoadError::Validation(format!(
+                "invalid example pool name '{pool}'"
+            )));
+        }
+    }
+
+    // Validate user memory config
+    if let Some(user) = raw.spec.memory.as_ref().and_then(|m| m.user.as_ref())
+        && user.recall == 0
+    {
+        return Err(AgentLoadError::Validation(
+            "memory.user.recall must be > 0".to_string(),
+        ));
+    }
+
+    // Validate model routing
+    if let Some(routing) = &raw.spec.model_routing {
+        validate_model_routing(routing)?;
+    }
+
+    // Validate router
+    if let Some(router) = &raw.spec.router {
+        validate_router(&raw.metadata.name, router, &raw.spec)?;
+    }
+
+    // Validate public access
+    if let Some(public) = raw.spec.access.as_ref().and_then(|a| a.public.as_ref())
+        && public.requests_per_minute == 0
+    {
+        return Err(AgentLoadError::Validation(
+            "access.public.requests_per_minute must be > 0".to_string(),
+        ));
+    }
+
+    // Validate best-of sampling
+    if let Some(best_of) = &raw.spec.best_of
+        && !(2..=MAX_BEST_OF_SAMPLES).contains(&best_of.samples)
+    {
+        return Err(AgentLoadError::Validation(format!(
+            "best_of.samples must be between 2 and {MAX_BEST_OF_SAMPLES}"
+        )));
+    }
+
+    // Validate ensemble
+    if let Some(ensemble) = &raw.spec.ensemble {
+        validate_ensemble(&raw.metadata.name, ensemble, &raw.spec)?;
+    }
+
+    // Validate planning
+    if let Some(planning) = &raw.spec.planning {
+        if planning.max_steps == 0 {
+            return Err(AgentLoadError::Validation(
+                "planning.max_steps must be > 0".to_string(),
+            ));
+        }
+        if raw.spec.ensemble.is_some() {
+            return Err(AgentLoadError::Validation(
+                "planning and ensemble can't be used together".to_string(),
+            ));
+        }
+    }
+
+    // Validate language config
+    if let Some(language) = &raw.spec.language {
+        if let Some(reply) = &language.reply
+            && reply != REPLY_IN_INPUT_LANGUAGE
+            && !is_language_code(reply)
+        {
+            return Err(AgentLoadError::Validation(format!(
+                "language.reply must be 'input' or a two-letter language code, got '{reply}'"
+            )));
+        }
+        if let Some(fallback) = &language.fallback
+            && !is_language_code(fallback)
+        {
+            return Err(AgentLoadError::Validation(format!(
+                "language.fallback must be a two-letter language code, got '{fallback}'"
+            )));
+        }
+    }
+
+    // Validate tool call limits
+    if raw
+        .spec
+        .session
+        .max_tool_calls_per_tool
+        .keys()
+        .any(|tool| tool.trim().is_empty())
+    {
+        return Err(AgentLoadError::Validation(
+            "session.max_tool_calls_per_tool tool names must not be blank".to_string(),
+        ));
+    }
+
+    // Validate input schema
+    if let Some(schema) = &raw.spec.input_schema {
+        crate::input_schema::check_schema(schema)
+            .map_err(|e| AgentLoadError::Validation(format!("invalid input_schema: {e}")))?;
+    }
+
+    // Validate stream processors
+    for processor in &raw.spec.stream_processors {
+        let (field, values) = match processor {
+            StreamProcessor::Redact { terms, .. } => ("redact terms", terms),
+            StreamProcessor::StopSequences { sequences } => ("stop_sequences sequences", sequences),
+            StreamProcessor::CloseFences => continue,
+        };
+        if values.is_empty() || values.iter().any(|v| v.is_empty()) {
+            return Err(AgentLoadError::Validation(format!(
+                "{field} must be a non-empty list of non-empty strings"
+            )));
+        }
+    }
+
+    // Validate post-processors
+    for processor in &raw.spec.post_processors {
+        if let PostProcessor::ProfanityFilter { words, .. } = processor
+            && words.iter().any(|w| w.trim().is_empty())
+        {
+            return Err(AgentLoadError::Validation(
+                "profanity_filter words must not be blank".to_string(),
+            ));
+        }
+    }
+
     // Merge agent-configured hooks with defaults from enabled tools.
     let tool_names: Vec<&str> = raw
         .spec
@@ -63,22 +225,68 @@ pub fn parse_agent_yaml(
     let defaults = crate::tools::hooks::default_hooks(&tool_names);
     let hooks = raw.spec.hooks.with_defaults(defaults);
 
+    // Resolve project defaults
+    let project = match &raw.metadata.project {
+        Some(name) => Some(
+            project
+                .filter(|p| &p.name == name)
+                .ok_or_else(|| AgentLoadError::Validation(format!("unknown project '{name}'")))?,
+        ),
+        None => None,
+    };
+    let model = raw
+        .spec
+        .model
+        .or_else(|| {
+            project
+                .and_then(|p| p.default_model.as_ref())
+                .map(|m| m.to_model_config())
+        })
+        .ok_or_else(|| {
+            AgentLoadError::Validation(
+                "spec.model is required unless the agent's project sets default_model".to_string(),
+            )
+        })?;
+    let mut session = raw.spec.session;
+    if let Some(project) = project {
+        session.max_wall_time_seconds = session
+            .max_wall_time_seconds
+            .or(project.budget.max_wall_time_seconds);
+        session.max_tool_time_seconds = session
+            .max_tool_time_seconds
+            .or(project.budget.max_tool_time_seconds);
+    }
+
     Ok(AgentSpec {
         api_version: raw.api_version,
         kind: raw.kind,
         metadata: raw.metadata,
-        model: raw.spec.model,
+        model,
+        model_routing: raw.spec.model_routing,
+        best_of: raw.spec.best_of,
+        ensemble: raw.spec.ensemble,
+        planning: raw.spec.planning,
+        router: raw.spec.router,
         soul: files.soul,
         system_prompt: files.system_prompt,
         instructions: files.instructions,
         skills,
-        session: raw.spec.session,
+        session,
         access: raw.spec.access,
         memory: raw.spec.memory,
+        prompts: raw.spec.prompts,
+        examples: raw.spec.examples,
+        language: raw.spec.language,
+        input_schema: raw.spec.input_schema,
+        stream_processors: raw.spec.stream_processors,
+        post_processors: raw.spec.post_processors,
         tools: raw.spec.tools,
         policy,
         hooks,
         agent_dir,
+        enabled: true,
+        provenance: Default::default(),
+        digest: String::new(),
     })
 }
 
@@ -90,6 +298,7 @@ pub fn parse_agent_file_refs(yaml_content: &str) -> Result<AgentFileRefs, AgentL
 
     Ok(AgentFileRefs {
         name: raw.metadata.name,
+        project: raw.metadata.project,
         soul: raw.spec.soul,
         system_prompt: raw.spec.system_prompt,
         instructions: raw.spec.instructions,
@@ -121,10 +330,129 @@ pub fn validate_builtin_tools(agent_name: &str, tools: &[ToolConfig]) -> Vec<Age
 // Implementation Details
 // ============================================================================
 
+fn validate_model_routing(routing: &ModelRoutingConfig) -> Result<(), AgentLoadError> {
+    if routing.rules.is_empty() {
+        return Err(AgentLoadError::Validation(
+            "model_routing.rules must not be empty".to_string(),
+        ));
+    }
+    validate_route_conditions(
+        "model_routing",
+        routing.classifier.as_ref(),
+        routing.rules.iter().map(|rule| &rule.when),
+    )
+}
+
+fn validate_router(
+    name: &str,
+    router: &RouterConfig,
+    spec: &RawAgentSpecBody,
+) -> Result<(), AgentLoadError> {
+    let invalid = |message: String| Err(AgentLoadError::Validation(message));
+    if router.rules.is_empty() {
+        return invalid("router.rules must not be empty".to_string());
+    }
+    validate_route_conditions(
+        "router",
+        router.classifier.as_ref(),
+        router.rules.iter().map(|rule| &rule.when),
+    )?;
+    for (i, rule) in router.rules.iter().enumerate() {
+        if rule.agent == name {
+            return invalid(format!("router.rules[{i}]: a router can't route to itself"));
+        }
+    }
+    if router.default.as_deref() == Some(name) {
+        return invalid("router.default: a router can't route to itself".to_string());
+    }
+    if !spec.tools.is_empty() {
+        return invalid("router agents can't have tools".to_string());
+    }
+    Ok(())
+}
+
+/// Checks the `when` conditions of `section`'s rules, in order.
+fn validate_route_conditions<'a>(
+    section: &str,
+    classifier: Option<&RoutingClassifierConfig>,
+    conditions: impl Iterator<Item = &'a RouteConditions>,
+) -> Result<(), AgentLoadError> {
+    let invalid = |message: String| Err(AgentLoadError::Validation(message));
+    let labels = match classifier {
+        Some(classifier) => {
+            if classifier.labels.is_empty() || classifier.labels.iter().any(|l| l.trim().is_empty())
+            {
+                return invalid(format!(
+                    "{section}.classifier.labels must be non-empty and not blank"
+                ));
+            }
+            classifier.labels.as_slice()
+        }
+        None => &[],
+    };
+    for (i, when) in conditions.enumerate() {
+        if let (Some(min), Some(max)) = (when.min_input_chars, when.max_input_chars)
+            && min > max
+        {
+            return invalid(format!(
+                "{section}.rules[{i}]: min_input_chars is greater than max_input_chars"
+            ));
+        }
+        if let Some(language) = when.languages.iter().find(|l| !is_language_code(l)) {
+            return invalid(format!(
+                "{section}.rules[{i}]: '{language}' is not a two-letter language code"
+            ));
+        }
+        if let Some(label) = when.classifier.iter().find(|l| !labels.contains(l)) {
+            return invalid(format!(
+                "{section}.rules[{i}]: classifier label '{label}' is not one of {section}.classifier.labels"
+            ));
+        }
+    }
+    Ok(())
+}
+
+fn validate_ensemble(
+    name: &str,
+    ensemble: &EnsembleConfig,
+    spec: &RawAgentSpecBody,
+) -> Result<(), AgentLoadError> {
+    let invalid = |message: String| Err(AgentLoadError::Validation(message));
+    if ensemble.members.len() < 2 {
+        return invalid("ensemble.members must have at least 2 members".to_string());
+    }
+    for (i, member) in ensemble.members.iter().enumerate() {
+        match (&member.agent, &member.model) {
+            (Some(agent), None) if agent == name => {
+                return invalid(format!(
+                    "ensemble.members[{i}]: an agent can't be a member of its own ensemble"
+                ));
+            }
+            (Some(_), None) | (None, Some(_)) => {}
+            _ => {
+                return invalid(format!(
+                    "ensemble.members[{i}]: exactly one of agent and model must be set"
+                ));
+            }
+        }
+    }
+    if spec.best_of.is_some() {
+        return invalid("ensemble and best_of can't be used together".to_string());
+    }
+    if !spec.tools.is_empty() {
+        return invalid("ensemble agents can't have tools".to_string());
+    }
+    Ok(())
+}
+
+fn is_language_code(code: &str) -> bool {
+    code.len() == 2 && code.bytes().all(|b| b.is_ascii_lowercase())
+}
+
 /// Raw YAML structure for parsing agent.yaml files.
 #[derive(Debug, Deserialize)]
 #[serde(rename_all = "camelCase")]
-struct RawAgentSpec {
+pub(crate) struct RawAgentSpec {
     api_version: String,
     kind: String,
     metadata: AgentMetadata,
@@ -132,8 +460,20 @@ struct RawAgentSpec {
 }
 
 #[derive(Debug, Deserialize)]
-struct RawAgentSpecBody {
-    model: ModelConfig,
+pub(crate) struct RawAgentSpecBody {
+    /// Optional when the agent's project provides a default model.
+    #[serde(default)]
+    model: Option<ModelConfig>,
+    #[serde(default)]
+    model_routing: Option<ModelRoutingConfig>,
+    #[serde(default)]
+    best_of: Option<BestOfConfig>,
+    #[serde(default)]
+    ensemble: Option<EnsembleConfig>,
+    #[serde(default)]
+    planning: Option<PlanningConfig>,
+    #[serde(default)]
+    router: Option<RouterConfig>,
     soul: Option<String>,
     system_prompt: Option<String>,
     instructions: Option<String>,
@@ -146,6 +486,18 @@ struct RawAgentSpecBody {
     #[serde(default)]
     memory: Option<AgentMemoryConfig>,
     #[serde(default)]
+    prompts: Vec<PromptRef>,
+    #[serde(default)]
+    examples: Option<AgentExamplesConfig>,
+    #[serde(default)]
+    language: Option<AgentLanguageConfig>,
+    #[serde(default)]
+    input_schema: Option<serde_json::Value>,
+    #[serde(default)]
+    stream_processors: Vec<StreamProcessor>,
+    #[serde(default)]
+    post_processors: Vec<PostProcessor>,
+    #[serde(default)]
     tools: Vec<ToolConfig>,
     #[serde(default)]
     hooks: HooksConfig,
@@ -396,6 +748,559 @@ spec:
         );
     }
 
+    #[tokio::test]
+    async fn load_agent_uses_project_defaults() {
+        let tmp = TempDir::new().unwrap();
+        let agents_dir = tmp.path().join("agents");
+        let projects_dir = tmp.path().join("projects");
+        std::fs::create_dir(&agents_dir).unwrap();
+        std::fs::create_dir(&projects_dir).unwrap();
+        std::fs::write(
+            projects_dir.join("support.yaml"),
+            r#"default_model:
+  provider: anthropic
+  name: claude-sonnet-4
+budget:
+  max_wall_time_seconds: 600
+  max_tool_time_seconds: 120
+"#,
+        )
+        .unwrap();
+
+        let agent_dir = agents_dir.join("triage");
+        std::fs::create_dir(&agent_dir).unwrap();
+        write_yaml(
+            &agent_dir,
+            r#"apiVersion: duragent/v1alpha1
+kind: Agent
+metadata:
+  name: triage
+  project: support
+spec:
+  session:
+    max_tool_time_seconds: 30
+"#,
+        );
+
+        let agent = load_agent(&agents_dir, "triage").await.unwrap();
+        assert_eq!(agent.metadata.project.as_deref(), Some("support"));
+        assert_eq!(agent.model.provider, Provider::Anthropic);
+        assert_eq!(agent.model.name, "claude-sonnet-4");
+        assert_eq!(agent.session.max_wall_time_seconds, Some(600));
+        assert_eq!(agent.session.max_tool_time_seconds, Some(30));
+    }
+
+    #[tokio::test]
+    async fn load_agent_with_tool_call_limits() {
+        let tmp = TempDir::new().unwrap();
+        let agents_dir = tmp.path().join("agents");
+        std::fs::create_dir(&agents_dir).unwrap();
+
+        let agent_dir = agents_dir.join("ops");
+        std::fs::create_dir(&agent_dir).unwrap();
+        write_yaml(
+            &agent_dir,
+            r#"apiVersion: duragent/v1alpha1
+kind: Agent
+metadata:
+  name: ops
+spec:
+  model:
+    provider: openai
+    name: gpt-4o-mini
+  session:
+    max_tool_calls: 20
+    max_tool_calls_per_tool:
+      bash: 5
+"#,
+        );
+
+        let agent = load_agent(&agents_dir, "ops").await.unwrap();
+        assert_eq!(agent.session.max_tool_calls, Some(20));
+        assert_eq!(agent.session.max_tool_calls_per_tool["bash"], 5);
+
+        write_yaml(
+            &agent_dir,
+            r#"apiVersion: duragent/v1alpha1
+kind: Agent
+metadata:
+  name: ops
+spec:
+  model:
+    provider: openai
+    name: gpt-4o-mini
+  session:
+    max_tool_calls_per_tool:
+      " ": 5
+"#,
+        );
+        assert!(load_agent(&agents_dir, "ops").await.is_err());
+    }
+
+    #[tokio::test]
+    async fn load_agent_with_unknown_project_fails() {
+        let tmp = TempDir::new().unwrap();
+        let agents_dir = tmp.path().join("agents");
+        std::fs::create_dir(&agents_dir).unwrap();
+
+        let agent_dir = agents_dir.join("triage");
+        std::fs::create_dir(&agent_dir).unwrap();
+        write_yaml(
+            &agent_dir,
+            r#"apiVersion: duragent/v1alpha1
+kind: Agent
+metadata:
+  name: triage
+  project: missing
+spec:
+  model:
+    provider: openrouter
+    name: anthropic/claude-sonnet-4
+"#,
+        );
+
+        let result = scan_agents(&agents_dir).await;
+        assert!(result.agents.is_empty());
+        assert_eq!(result.warnings.len(), 1);
+    }
+
+    #[tokio::test]
+    async fn load_agent_with_prompt_refs() {
+        let tmp = TempDir::new().unwrap();
+        let agents_dir = tmp.path().join("agents");
+        std::fs::create_dir(&agents_dir).unwrap();
+
+        let agent_dir = agents_dir.join("support");
+        std::fs::create_dir(&agent_dir).unwrap();
+        write_yaml(
+            &agent_dir,
+            r#"apiVersion: duragent/v1alpha1
+kind: Agent
+metadata:
+  name: support
+spec:
+  model:
+    provider: openrouter
+    name: anthropic/claude-sonnet-4
+  prompts:
+    - safety-policy
+    - friendly-persona@2
+"#,
+        );
+
+        let agent = load_agent(&agents_dir, "support").await.unwrap();
+        assert_eq!(
+            agent.prompts,
+            vec![
+                PromptRef {
+                    name: "safety-policy".to_string(),
+                    version: None,
+                },
+                PromptRef {
+                    name: "friendly-persona".to_string(),
+                    version: Some(2),
+                },
+            ]
+        );
+
+        write_yaml(
+            &agent_dir,
+            r#"apiVersion: duragent/v1alpha1
+kind: Agent
+metadata:
+  name: support
+spec:
+  model:
+    provider: openrouter
+    name: anthropic/claude-sonnet-4
+  prompts:
+    - safety-policy@latest
+"#,
+        );
+        assert!(load_agent(&agents_dir, "support").await.is_err());
+    }
+
+    #[tokio::test]
+    async fn load_agent_with_post_processors() {
+        let tmp = TempDir::new().unwrap();
+        let agents_dir = tmp.path().join("agents");
+        std::fs::create_dir(&agents_dir).unwrap();
+
+        let agent_dir = agents_dir.join("support");
+        std::fs::create_dir(&agent_dir).unwrap();
+        write_yaml(
+            &agent_dir,
+            r#"apiVersion: duragent/v1alpha1
+kind: Agent
+metadata:
+  name: support
+spec:
+  model:
+    provider: openrouter
+    name: anthropic/claude-sonnet-4
+  post_processors:
+    - type: profanity_filter
+      words: [darn]
+    - type: citations
+    - type: markdown_html
+"#,
+        );
+
+        let agent = load_agent(&agents_dir, "support").await.unwrap();
+        assert_eq!(
+            agent.post_processors,
+            vec![
+                PostProcessor::ProfanityFilter {
+                    words: vec!["darn".to_string()],
+                    replacement: None,
+                },
+                PostProcessor::Citations {
+                    heading: "Sources".to_string(),
+                },
+                PostProcessor::MarkdownHtml,
+            ]
+        );
+
+        write_yaml(
+            &agent_dir,
+            r#"apiVersion: duragent/v1alpha1
+kind: Agent
+metadata:
+  name: support
+spec:
+  model:
+    provider: openrouter
+    name: anthropic/claude-sonnet-4
+  post_processors:
+    - type: translate
+"#,
+        );
+        assert!(load_agent(&agents_dir, "support").await.is_err());
+    }
+
+    #[tokio::test]
+    async fn load_agent_with_stream_processors() {
+        let tmp = TempDir::new().unwrap();
+        let agents_dir = tmp.path().join("agents");
+        std::fs::create_dir(&agents_dir).unwrap();
+
+        let agent_dir = agents_dir.join("support");
+        std::fs::create_dir(&agent_dir).unwrap();
+        write_yaml(
+            &agent_dir,
+            r#"apiVersion: duragent/v1alpha1
+kind: Agent
+metadata:
+  name: support
+spec:
+  model:
+    provider: openrouter
+    name: anthropic/claude-sonnet-4
+  stream_processors:
+    - type: redact
+      terms: [sk-live-]
+    - type: stop_sequences
+      sequences: ["END"]
+    - type: close_fences
+"#,
+        );
+
+        let agent = load_agent(&agents_dir, "support").await.unwrap();
+        assert_eq!(
+            agent.stream_processors,
+            vec![
+                StreamProcessor::Redact {
+                    terms: vec!["sk-live-".to_string()],
+                    replacement: None,
+                },
+                StreamProcessor::StopSequences {
+                    sequences: vec!["END".to_string()],
+                },
+                StreamProcessor::CloseFences,
+            ]
+        );
+
+        write_yaml(
+            &agent_dir,
+            r#"apiVersion: duragent/v1alpha1
+kind: Agent
+metadata:
+  name: support
+spec:
+  model:
+    provider: openrouter
+    name: anthropic/claude-sonnet-4
+  stream_processors:
+    - type: stop_sequences
+      sequences: []
+"#,
+        );
+        assert!(load_agent(&agents_dir, "support").await.is_err());
+    }
+
+    #[tokio::test]
+    async fn load_agent_with_model_routing() {
+        let tmp = TempDir::new().unwrap();
+        let agents_dir = tmp.path().join("agents");
+        std::fs::create_dir(&agents_dir).unwrap();
+
+        let agent_dir = agents_dir.join("support");
+        std::fs::create_dir(&agent_dir).unwrap();
+        write_yaml(
+            &agent_dir,
+            r#"apiVersion: duragent/v1alpha1
+kind: Agent
+metadata:
+  name: support
+spec:
+  model:
+    provider: openai
+    name: gpt-4o
+  model_routing:
+    classifier:
+      labels: [simple, complex]
+    rules:
+      - when: { max_input_chars: 200, classifier: [simple] }
+        model: { provider: openai, name: gpt-4o-mini }
+      - when: { languages: [ja] }
+        model: { provider: anthropic, name: claude-sonnet-4-5 }
+"#,
+        );
+
+        let agent = load_agent(&agents_dir, "support").await.unwrap();
+        let routing = agent.model_routing.unwrap();
+        assert_eq!(routing.rules.len(), 2);
+        assert_eq!(routing.rules[0].when.max_input_chars, Some(200));
+        assert_eq!(routing.rules[0].model.name, "gpt-4o-mini");
+        assert_eq!(routing.rules[1].when.languages, vec!["ja".to_string()]);
+
+        // Labels must come from the classifier
+        write_yaml(
+            &agent_dir,
+            r#"apiVersion: duragent/v1alpha1
+kind: Agent
+metadata:
+  name: support
+spec:
+  model:
+    provider: openai
+    name: gpt-4o
+  model_routing:
+    rules:
+      - when: { classifier: [simple] }
+        model: { provider: openai, name: gpt-4o-mini }
+"#,
+        );
+        assert!(load_agent(&agents_dir, "support").await.is_err());
+    }
+
+    #[tokio::test]
+    async fn load_agent_with_router() {
+        let tmp = TempDir::new().unwrap();
+        let agents_dir = tmp.path().join("agents");
+        std::fs::create_dir(&agents_dir).unwrap();
+
+        let agent_dir = agents_dir.join("front-desk");
+        std::fs::create_dir(&agent_dir).unwrap();
+        write_yaml(
+            &agent_dir,
+            r#"apiVersion: duragent/v1alpha1
+kind: Agent
+metadata:
+  name: front-desk
+spec:
+  model:
+    provider: openai
+    name: gpt-4o-mini
+  router:
+    classifier:
+      labels: [billing, support]
+    rules:
+      - when: { classifier: [billing] }
+        agent: billing
+      - when: { classifier: [support] }
+        agent: support
+    default: general
+"#,
+        );
+
+        let agent = load_agent(&agents_dir, "front-desk").await.unwrap();
+        let router = agent.router.unwrap();
+        assert_eq!(router.rules.len(), 2);
+        assert_eq!(router.rules[0].when.classifier, vec!["billing".to_string()]);
+        assert_eq!(router.rules[1].agent, "support");
+        assert_eq!(router.default.as_deref(), Some("general"));
+
+        // A router can't route to itself
+        write_yaml(
+            &agent_dir,
+            r#"apiVersion: duragent/v1alpha1
+kind: Agent
+metadata:
+  name: front-desk
+spec:
+  model:
+    provider: openai
+    name: gpt-4o-mini
+  router:
+    rules:
+      - when: { languages: [ja] }
+        agent: front-desk
+"#,
+        );
+        assert!(load_agent(&agents_dir, "front-desk").await.is_err());
+    }
+
+    #[tokio::test]
+    async fn load_agent_with_best_of() {
+        let tmp = TempDir::new().unwrap();
+        let agents_dir = tmp.path().join("agents");
+        std::fs::create_dir(&agents_dir).unwrap();
+
+        let agent_dir = agents_dir.join("support");
+        std::fs::create_dir(&agent_dir).unwrap();
+        write_yaml(
+            &agent_dir,
+            r#"apiVersion: duragent/v1alpha1
+kind: Agent
+metadata:
+  name: support
+spec:
+  model:
+    provider: openai
+    name: gpt-4o
+  best_of:
+    samples: 3
+    models:
+      - { provider: openai, name: gpt-4o }
+      - { provider: anthropic, name: claude-sonnet-4-5 }
+    select: longest
+"#,
+        );
+
+        let agent = load_agent(&agents_dir, "support").await.unwrap();
+        let best_of = agent.best_of.unwrap();
+        assert_eq!(best_of.samples, 3);
+        assert_eq!(best_of.models.len(), 2);
+        assert_eq!(best_of.select, crate::agent::BestOfSelect::Longest);
+
+        // One sample is no choice at all
+        write_yaml(
+            &agent_dir,
+            r#"apiVersion: duragent/v1alpha1
+kind: Agent
+metadata:
+  name: support
+spec:
+  model:
+    provider: openai
+    name: gpt-4o
+  best_of:
+    samples: 1
+"#,
+        );
+        assert!(load_agent(&agents_dir, "support").await.is_err());
+    }
+
+    #[tokio::test]
+    async fn load_agent_with_ensemble() {
+        let tmp = TempDir::new().unwrap();
+        let agents_dir = tmp.path().join("agents");
+        std::fs::create_dir(&agents_dir).unwrap();
+
+        let agent_dir = agents_dir.join("panel");
+        std::fs::create_dir(&agent_dir).unwrap();
+        write_yaml(
+            &agent_dir,
+            r#"apiVersion: duragent/v1alpha1
+kind: Agent
+metadata:
+  name: panel
+spec:
+  model:
+    provider: openai
+    name: gpt-4o
+  ensemble:
+    members:
+      - agent: researcher
+      - model: { provider: anthropic, name: claude-sonnet-4-5 }
+    report_disagreements: true
+"#,
+        );
+
+        let agent = load_agent(&agents_dir, "panel").await.unwrap();
+        let ensemble = agent.ensemble.unwrap();
+        assert_eq!(ensemble.members.len(), 2);
+        assert_eq!(ensemble.members[0].agent.as_deref(), Some("researcher"));
+        assert!(ensemble.members[1].model.is_some());
+        assert!(ensemble.synthesizer.is_none());
+        assert!(ensemble.report_disagreements);
+
+        // An agent can't answer in its own ensemble
+        write_yaml(
+            &agent_dir,
+            r#"apiVersion: duragent/v1alpha1
+kind: Agent
+metadata:
+  name: panel
+spec:
+  model:
+    provider: openai
+    name: gpt-4o
+  ensemble:
+    members:
+      - agent: panel
+      - model: { provider: anthropic, name: claude-sonnet-4-5 }
+"#,
+        );
+        assert!(load_agent(&agents_dir, "panel").await.is_err());
+    }
+
+    #[tokio::test]
+    async fn load_agent_with_planning() {
+        let tmp = TempDir::new().unwrap();
+        let agents_dir = tmp.path().join("agents");
+        std::fs::create_dir(&agents_dir).unwrap();
+
+        let agent_dir = agents_dir.join("ops");
+        std::fs::create_dir(&agent_dir).unwrap();
+        write_yaml(
+            &agent_dir,
+            r#"apiVersion: duragent/v1alpha1
+kind: Agent
+metadata:
+  name: ops
+spec:
+  model:
+    provider: openai
+    name: gpt-4o
+  planning:
+    require_approval: true
+"#,
+        );
+
+        let agent = load_agent(&agents_dir, "ops").await.unwrap();
+        let planning = agent.planning.unwrap();
+        assert_eq!(planning.max_steps, crate::agent::DEFAULT_MAX_PLAN_STEPS);
+        assert!(planning.require_approval);
+
+        write_yaml(
+            &agent_dir,
+            r#"apiVersion: duragent/v1alpha1
+kind: Agent
+metadata:
+  name: ops
+spec:
+  model:
+    provider: openai
+    name: gpt-4o
+  planning:
+    max_steps: 0
+"#,
+        );
+        assert!(load_agent(&agents_dir, "ops").await.is_err());
+    }
+
     #[tokio::test]
     async fn load_agent_session_defaults_to_pause() {
         let tmp = TempDir::new().unwrap();
@@ -470,6 +1375,54 @@ spec:
             access.groups.sender_overrides.get("99999"),
             Some(&SenderDisposition::Block)
         );
+        assert!(access.public.is_none());
+    }
+
+    #[tokio::test]
+    async fn load_agent_with_public_access() {
+        let tmp = TempDir::new().unwrap();
+        let agents_dir = tmp.path().join("agents");
+        std::fs::create_dir(&agents_dir).unwrap();
+
+        let agent_dir = agents_dir.join("widget");
+        std::fs::create_dir(&agent_dir).unwrap();
+        write_yaml(
+            &agent_dir,
+            r#"apiVersion: duragent/v1alpha1
+kind: Agent
+metadata:
+  name: widget
+spec:
+  model:
+    provider: openai
+    name: gpt-4o-mini
+  access:
+    public:
+      captcha: true
+"#,
+        );
+
+        let agent = load_agent(&agents_dir, "widget").await.unwrap();
+        let public = agent.access.unwrap().public.unwrap();
+        assert_eq!(public.requests_per_minute, 10);
+        assert!(public.captcha);
+
+        write_yaml(
+            &agent_dir,
+            r#"apiVersion: duragent/v1alpha1
+kind: Agent
+metadata:
+  name: widget
+spec:
+  model:
+    provider: openai
+    name: gpt-4o-mini
+  access:
+    public:
+      requests_per_minute: 0
+"#,
+        );
+        assert!(load_agent(&agents_dir, "widget").await.is_err());
     }
 
     #[tokio::test]
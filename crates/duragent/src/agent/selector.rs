@@ -0,0 +1,165 @@
+//! Label selectors for filtering agents and runs.
+//!
+//! A selector is a comma-separated list of requirements, all of which must
+//! hold, in the style of Kubernetes equality-based selectors:
+//!
+//! | Requirement | Matches when |
+//! |-------------|--------------|
+//! | `key=value` (or `key==value`) | the label is present with that value |
+//! | `key!=value` | the label is absent or has another value |
+//! | `key` | the label is present |
+//! | `!key` | the label is absent |
+
+use std::collections::HashMap;
+use std::fmt;
+use std::str::FromStr;
+
+/// Parsed label selector. The default selector matches everything.
+#[derive(Debug, Clone, Default, PartialEq, Eq)]
+pub struct LabelSelector {
+    requirements: Vec<Requirement>,
+}
+
+#[derive(Debug, Clone, PartialEq, Eq)]
+enum Requirement {
+    Equals(String, String),
+    NotEquals(String, String),
+    Exists(String),
+    NotExists(String),
+}
+
+/// Error returned for a malformed selector.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct SelectorParseError(String);
+
+impl fmt::Display for SelectorParseError {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        write!(f, "invalid label selector requirement '{}'", self.0)
+    }
+}
+
+impl std::error::Error for SelectorParseError {}
+
+impl LabelSelector {
+    /// Whether the selector has no requirements.
+    pub fn is_empty(&self) -> bool {
+        self.requirements.is_empty()
+    }
+
+    /// Check a label set against every requirement.
+    pub fn matches(&self, labels: &HashMap<String, String>) -> bool {
+        self.requirements.iter().all(|r| match r {
+            Requirement::Equals(k, v) => labels.get(k) == Some(v),
+            Requirement::NotEquals(k, v) => labels.get(k) != Some(v),
+            Requirement::Exists(k) => labels.contains_key(k),
+            Requirement::NotExists(k) => !labels.contains_key(k),
+        })
+    }
+
+    /// `(key, value)` pairs that must be present, for index lookups.
+    pub fn equalities(&self) -> impl Iterator<Item = (&str, &str)> {
+        self.requirements.iter().filter_map(|r| match r {
+            Requirement::Equals(k, v) => Some((k.as_str(), v.as_str())),
+            _ => None,
+        })
+    }
+}
+
+impl FromStr for LabelSelector {
+    type Err = SelectorParseError;
+
+    fn from_str(s: &str) -> Result<Self, Self::Err> {
+        let requirements = s
+            .split(',')
+            .map(str::trim)
+            .filter(|part| !part.is_empty())
+            .map(parse_requirement)
+            .collect::<Result<_, _>>()?;
+        Ok(Self { requirements })
+    }
+}
+
+fn parse_requirement(part: &str) -> Result<Requirement, SelectorParseError> {
+    let invalid = || SelectorParseError(part.to_string());
+    let key = |k: &str| {
+        let k = k.trim();
+        if k.is_empty() || k.contains(['=', '!']) {
+            Err(invalid())
+        } else {
+            Ok(k.to_string())
+        }
+    };
+
+    if let Some((k, v)) = part.split_once("!=") {
+        return Ok(Requirement::NotEquals(key(k)?, v.trim().to_string()));
+    }
+    if let Some((k, v)) = part.split_once("==").or_else(|| part.split_once('=')) {
+        if v.contains('=') {
+            return Err(invalid());
+        }
+        return Ok(Requirement::Equals(key(k)?, v.trim().to_string()));
+    }
+    if let Some(k) = part.strip_prefix('!') {
+        return Ok(Requirement::NotExists(key(k)?));
+    }
+    Ok(Requirement::Exists(key(part)?))
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn labels(pairs: &[(&str, &str)]) -> HashMap<String, String> {
+        pairs
+            .iter()
+            .map(|&(k, v)| (k.to_string(), v.to_string()))
+            .collect()
+    }
+
+    #[test]
+    fn empty_selector_matches_everything() {
+        let selector: LabelSelector = "".parse().unwrap();
+        assert!(selector.is_empty());
+        assert!(selector.matches(&labels(&[])));
+    }
+
+    #[test]
+    fn requirements_are_anded() {
+        let selector: LabelSelector = "team=ml, env==prod,tier!=free,owner,!deprecated"
+            .parse()
+            .unwrap();
+        assert_eq!(
+            selector.equalities().collect::<Vec<_>>(),
+            [("team", "ml"), ("env", "prod")]
+        );
+
+        let base = [("team", "ml"), ("env", "prod"), ("owner", "ana")];
+        assert!(selector.matches(&labels(&base)));
+        assert!(selector.matches(&labels(&[
+            ("team", "ml"),
+            ("env", "prod"),
+            ("owner", "ana"),
+            ("tier", "gold"),
+        ])));
+        assert!(!selector.matches(&labels(&[("team", "ml"), ("env", "prod")])));
+        assert!(!selector.matches(&labels(&[
+            ("team", "ml"),
+            ("env", "prod"),
+            ("owner", "ana"),
+            ("tier", "free"),
+        ])));
+        assert!(!selector.matches(&labels(&[
+            ("team", "ml"),
+            ("env", "prod"),
+            ("owner", "ana"),
+            ("deprecated", "true"),
+        ])));
+    }
+
+    #[test]
+    fn rejects_malformed_requirements() {
+        for bad in ["=prod", "!=x", "a=b=c", "!", "a!b"] {
+            assert!(bad.parse::<LabelSelector>().is_err(), "{bad}");
+        }
+    }
+}
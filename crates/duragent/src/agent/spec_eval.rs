@@ -4,6 +4,7 @@
 //! The data definitions live in `duragent-types`; evaluation lives here.
 
 use crate::agent::{HooksConfig, ModelConfig};
+use crate::models;
 
 /// Extension trait for `ModelConfig` evaluation logic.
 pub trait ModelConfigEval {
@@ -54,100 +55,12 @@ impl HooksConfigEval for HooksConfig {
 
 /// Return the default context window size for a model.
 ///
-/// Matches by model name substring. More specific patterns are checked before
-/// catch-all family patterns. Returns a conservative default for unknown models.
-///
-/// Context windows sourced from OpenRouter model pages (Feb 2026).
+/// Uses the built-in entries of the model registry, which match by model
+/// name substring with the most specific pattern winning. Returns a
+/// conservative default for unknown models.
 fn default_context_window(model_name: &str) -> u32 {
-    let name = model_name.to_lowercase();
-
-    // Claude models (200K)
-    if name.contains("claude") {
-        return 200_000;
-    }
-
-    // GPT-5 / GPT-5 mini / GPT-5 nano (400K)
-    if name.contains("gpt-5") {
-        return 400_000;
-    }
-
-    // GPT-4.1 / GPT-4.1 mini / GPT-4.1 nano (1M) — before gpt-4o/gpt-4
-    if name.contains("gpt-4.1") {
-        return 1_000_000;
-    }
-
-    // GPT-4o / GPT-4o-mini (128K)
-    if name.contains("gpt-4o") {
-        return 128_000;
-    }
-
-    // GPT-4 Turbo (128K)
-    if name.contains("gpt-4-turbo") {
-        return 128_000;
-    }
-
-    // GPT-4 (128K)
-    if name.contains("gpt-4") {
-        return 128_000;
-    }
-
-    // Gemini (1M)
-    if name.contains("gemini") {
-        return 1_000_000;
-    }
-
-    // Grok 4.x (2M) — before grok catch-all
-    if name.contains("grok-4") {
-        return 2_000_000;
-    }
-
-    // Grok 3.x and older (131K)
-    if name.contains("grok") {
-        return 131_072;
-    }
-
-    // DeepSeek V3.x (163K) — before deepseek catch-all
-    if name.contains("deepseek-v3") || name.contains("deepseek-chat-v3") {
-        return 163_840;
-    }
-
-    // DeepSeek older (128K)
-    if name.contains("deepseek") {
-        return 128_000;
-    }
-
-    // Qwen 3 (131K)
-    if name.contains("qwen3") {
-        return 131_072;
-    }
-
-    // Qwen older (128K)
-    if name.contains("qwen") {
-        return 128_000;
-    }
-
-    // Llama 4 (327K — conservative; maverick is 1M, scout is 327K)
-    if name.contains("llama-4") {
-        return 327_680;
-    }
-
-    // Llama 3.x and older (128K)
-    if name.contains("llama") {
-        return 128_000;
-    }
-
-    // Mistral Large (262K) — before mistral catch-all
-    if name.contains("mistral-large") {
-        return 262_144;
-    }
-
-    // Mistral / Mixtral (128K)
-    if name.contains("mistral") || name.contains("mixtral") {
-        return 128_000;
-    }
-
-    // Conservative default
-    128_000
+    models::builtin_capabilities(model_name)
+        .map_or(models::DEFAULT_CONTEXT_WINDOW, |c| c.context_window)
 }
 
 #[cfg(test)]
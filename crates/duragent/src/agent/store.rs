@@ -1,6 +1,7 @@
-use std::collections::HashMap;
+use std::collections::{BTreeSet, HashMap};
 use std::sync::{Arc, RwLock};
 
+use super::LabelSelector;
 use super::error::{AgentLoadError, AgentLoadWarning};
 use super::spec::AgentSpec;
 use crate::store::{AgentCatalog, ScanWarning};
@@ -15,7 +16,19 @@ use crate::store::{AgentCatalog, ScanWarning};
 /// await points. Interior mutability enables hot-reloading agents at runtime.
 #[derive(Debug, Clone)]
 pub struct AgentStore {
-    agents: Arc<RwLock<HashMap<String, Arc<AgentSpec>>>>,
+    agents: Arc<RwLock<AgentIndex>>,
+}
+
+/// Filter for `AgentStore::search`.
+#[derive(Debug, Default)]
+pub struct AgentQuery {
+    /// Whitespace-separated terms; every term must appear (case-insensitive)
+    /// in the agent's name, description, tags, or a label key or value.
+    pub text: Option<String>,
+    /// Label selector the agent's labels must satisfy.
+    pub selector: LabelSelector,
+    /// Only agents whose `metadata.project` is this project.
+    pub project: Option<String>,
 }
 
 /// Result of scanning the agents directory.
@@ -45,17 +58,38 @@ pub enum AgentScanWarning {
 impl AgentStore {
     /// Get an agent by name.
     pub fn get(&self, name: &str) -> Option<Arc<AgentSpec>> {
-        self.agents.read().unwrap().get(name).cloned()
+        self.agents.read().unwrap().by_name.get(name).cloned()
+    }
+
+    /// Get an agent by name if it is enabled.
+    ///
+    /// Use this wherever an agent is about to be invoked.
+    pub fn get_enabled(&self, name: &str) -> Option<Arc<AgentSpec>> {
+        self.get(name).filter(|spec| spec.enabled)
+    }
+
+    /// Set an agent's enabled flag. Returns false if the agent is not loaded.
+    pub fn set_enabled(&self, name: &str, enabled: bool) -> bool {
+        let mut agents = self.agents.write().unwrap();
+        let Some(spec) = agents.by_name.get_mut(name) else {
+            return false;
+        };
+        if spec.enabled != enabled {
+            let mut updated = (**spec).clone();
+            updated.enabled = enabled;
+            *spec = Arc::new(updated);
+        }
+        true
     }
 
     /// Get the number of loaded agents.
     pub fn len(&self) -> usize {
-        self.agents.read().unwrap().len()
+        self.agents.read().unwrap().by_name.len()
     }
 
     /// Check if the store is empty.
     pub fn is_empty(&self) -> bool {
-        self.agents.read().unwrap().is_empty()
+        self.agents.read().unwrap().by_name.is_empty()
     }
 
     /// Snapshot all agents as a vec of (name, spec) pairs.
@@ -63,15 +97,66 @@ impl AgentStore {
         self.agents
             .read()
             .unwrap()
+            .by_name
             .iter()
             .map(|(k, v)| (k.clone(), v.clone()))
             .collect()
     }
 
+    /// Agents matching `query`, sorted by name.
+    ///
+    /// Selector equalities are answered from the label index and text terms
+    /// from precomputed lowercase search text, so no agent files are read.
+    pub fn search(&self, query: &AgentQuery) -> Vec<Arc<AgentSpec>> {
+        let terms: Vec<String> = query
+            .text
+            .as_deref()
+            .unwrap_or_default()
+            .split_whitespace()
+            .map(str::to_lowercase)
+            .collect();
+
+        let index = self.agents.read().unwrap();
+        // Seed candidates from the smallest indexed equality, then check the
+        // full selector and text terms against each candidate.
+        let seed = query
+            .selector
+            .equalities()
+            .map(|(key, value)| index.by_label.get(&label_key(key, value)))
+            .min_by_key(|names| names.map_or(0, BTreeSet::len));
+        let mut names: Vec<&String> = match seed {
+            Some(None) => return Vec::new(),
+            Some(Some(names)) => names.iter().collect(),
+            None => index.by_name.keys().collect(),
+        };
+        names.retain(|name| {
+            let text = &index.search_text[*name];
+            let metadata = &index.by_name[*name].metadata;
+            query.selector.matches(&metadata.labels)
+                && (query.project.is_none() || metadata.project == query.project)
+                && terms.iter().all(|term| text.contains(term.as_str()))
+        });
+        names.sort_unstable();
+        names
+            .into_iter()
+            .map(|name| index.by_name[name].clone())
+            .collect()
+    }
+
+    /// Insert or replace a single agent.
+    pub fn upsert(&self, spec: AgentSpec) {
+        self.agents.write().unwrap().insert(Arc::new(spec));
+    }
+
+    /// Remove an agent, returning it if it was loaded.
+    pub fn remove(&self, name: &str) -> Option<Arc<AgentSpec>> {
+        self.agents.write().unwrap().remove(name)
+    }
+
     /// Replace the contents of this store with agents from `other`.
     pub fn replace_from(&self, other: &AgentStore) {
-        let new_map = other.agents.read().unwrap().clone();
-        *self.agents.write().unwrap() = new_map;
+        let new_index = other.agents.read().unwrap().clone();
+        *self.agents.write().unwrap() = new_index;
     }
 
     /// Load agents from a catalog.
@@ -84,7 +169,7 @@ impl AgentStore {
                 // Storage error during scan (e.g., read_dir failed)
                 return AgentScanReport {
                     store: AgentStore {
-                        agents: Arc::new(RwLock::new(HashMap::new())),
+                        agents: Arc::new(RwLock::new(AgentIndex::default())),
                     },
                     warnings: vec![AgentScanWarning::CatalogError {
                         error: e.to_string(),
@@ -93,12 +178,11 @@ impl AgentStore {
             }
         };
 
-        // Convert loaded agents to HashMap by name, wrapping each in Arc
-        let agents: HashMap<String, Arc<AgentSpec>> = result
-            .agents
-            .into_iter()
-            .map(|a| (a.metadata.name.clone(), Arc::new(a)))
-            .collect();
+        // Index loaded agents by name and label, wrapping each in Arc
+        let mut agents = AgentIndex::default();
+        for agent in result.agents {
+            agents.insert(Arc::new(agent));
+        }
 
         // Convert ScanWarning to AgentScanWarning
         let warnings = result
@@ -132,6 +216,75 @@ impl AgentStore {
     }
 }
 
+// ============================================================================
+// Index
+// ============================================================================
+
+/// Loaded agents keyed by name, with secondary indexes for `search`.
+///
+/// The enabled flag is not indexed, so `set_enabled` only touches `by_name`.
+#[derive(Debug, Clone, Default)]
+struct AgentIndex {
+    by_name: HashMap<String, Arc<AgentSpec>>,
+    /// `key=value` label → names of agents carrying it.
+    by_label: HashMap<String, BTreeSet<String>>,
+    /// Lowercase name, description, tags, and labels per agent.
+    search_text: HashMap<String, String>,
+}
+
+impl AgentIndex {
+    fn insert(&mut self, spec: Arc<AgentSpec>) {
+        let name = spec.metadata.name.clone();
+        self.remove(&name);
+
+        for (key, value) in &spec.metadata.labels {
+            self.by_label
+                .entry(label_key(key, value))
+                .or_default()
+                .insert(name.clone());
+        }
+        self.search_text.insert(name.clone(), search_text(&spec));
+        self.by_name.insert(name, spec);
+    }
+
+    fn remove(&mut self, name: &str) -> Option<Arc<AgentSpec>> {
+        let spec = self.by_name.remove(name)?;
+        self.search_text.remove(name);
+        for (key, value) in &spec.metadata.labels {
+            let label = label_key(key, value);
+            if let Some(names) = self.by_label.get_mut(&label) {
+                names.remove(name);
+                if names.is_empty() {
+                    self.by_label.remove(&label);
+                }
+            }
+        }
+        Some(spec)
+    }
+}
+
+fn label_key(key: &str, value: &str) -> String {
+    format!("{key}={value}")
+}
+
+fn search_text(spec: &AgentSpec) -> String {
+    let metadata = &spec.metadata;
+    let mut text = metadata.name.to_lowercase();
+    if let Some(description) = &metadata.description {
+        text.push('\n');
+        text.push_str(&description.to_lowercase());
+    }
+    for tag in &metadata.tags {
+        text.push('\n');
+        text.push_str(&tag.to_lowercase());
+    }
+    for (key, value) in &metadata.labels {
+        text.push('\n');
+        text.push_str(&label_key(key, value).to_lowercase());
+    }
+    text
+}
+
 // ============================================================================
 // Utility Functions
 // ============================================================================
@@ -300,6 +453,134 @@ spec:
         assert!(names.iter().any(|n| n == "gamma"));
     }
 
+    #[tokio::test]
+    async fn store_upsert_and_remove() {
+        let tmp = TempDir::new().unwrap();
+        let agents_dir = tmp.path().join("agents");
+        std::fs::create_dir(&agents_dir).unwrap();
+        let agent_dir = agents_dir.join("test-agent");
+        std::fs::create_dir(&agent_dir).unwrap();
+        create_minimal_agent(&agent_dir, "test-agent");
+
+        let report = scan_agents(&agents_dir).await;
+        let spec = (*report.store.get("test-agent").unwrap()).clone();
+
+        let store = scan_agents(&tmp.path().join("empty")).await.store;
+        store.upsert(spec);
+        assert!(store.get("test-agent").is_some());
+
+        assert!(store.set_enabled("test-agent", false));
+        assert!(store.get("test-agent").is_some());
+        assert!(store.get_enabled("test-agent").is_none());
+        assert!(store.set_enabled("test-agent", true));
+        assert!(store.get_enabled("test-agent").is_some());
+        assert!(!store.set_enabled("missing", false));
+
+        assert!(store.remove("test-agent").is_some());
+        assert!(store.remove("test-agent").is_none());
+        assert!(store.is_empty());
+    }
+
+    #[tokio::test]
+    async fn store_search_uses_indexes() {
+        let tmp = TempDir::new().unwrap();
+        let agent_dir = tmp.path().join("agents").join("template");
+        std::fs::create_dir_all(&agent_dir).unwrap();
+        create_minimal_agent(&agent_dir, "template");
+        let report = scan_agents(&tmp.path().join("agents")).await;
+        let template = (*report.store.get("template").unwrap()).clone();
+
+        let store = scan_agents(&tmp.path().join("empty")).await.store;
+        for (name, description, team) in [
+            ("billing", "Answers invoice questions", "finance"),
+            ("helpdesk", "Resets passwords", "support"),
+            ("refunds", "Handles refund requests", "support"),
+        ] {
+            let mut spec = template.clone();
+            spec.metadata.name = name.to_string();
+            spec.metadata.description = Some(description.to_string());
+            spec.metadata.labels = HashMap::from([("team".to_string(), team.to_string())]);
+            spec.metadata.tags = vec![format!("{team}-tier1")];
+            store.upsert(spec);
+        }
+
+        let names = |query: AgentQuery| -> Vec<String> {
+            store
+                .search(&query)
+                .iter()
+                .map(|s| s.metadata.name.clone())
+                .collect()
+        };
+        let label = |value: &str| -> LabelSelector { format!("team={value}").parse().unwrap() };
+
+        assert_eq!(
+            names(AgentQuery::default()),
+            ["billing", "helpdesk", "refunds"]
+        );
+        assert_eq!(
+            names(AgentQuery {
+                text: Some("Invoice".to_string()),
+                ..Default::default()
+            }),
+            ["billing"]
+        );
+        assert_eq!(
+            names(AgentQuery {
+                text: Some("support refund".to_string()),
+                ..Default::default()
+            }),
+            ["refunds"]
+        );
+        assert_eq!(
+            names(AgentQuery {
+                text: Some("FINANCE-TIER1".to_string()),
+                ..Default::default()
+            }),
+            ["billing"]
+        );
+        assert_eq!(
+            names(AgentQuery {
+                selector: label("support"),
+                ..Default::default()
+            }),
+            ["helpdesk", "refunds"]
+        );
+        assert_eq!(
+            names(AgentQuery {
+                selector: "team!=support".parse().unwrap(),
+                ..Default::default()
+            }),
+            ["billing"]
+        );
+
+        // Re-indexed on update and dropped on remove
+        let mut moved = (*store.get("helpdesk").unwrap()).clone();
+        moved.metadata.labels = HashMap::from([("team".to_string(), "it".to_string())]);
+        store.upsert(moved);
+        assert_eq!(
+            names(AgentQuery {
+                selector: label("support"),
+                ..Default::default()
+            }),
+            ["refunds"]
+        );
+        store.remove("refunds");
+        assert!(
+            names(AgentQuery {
+                selector: label("support"),
+                ..Default::default()
+            })
+            .is_empty()
+        );
+        assert_eq!(
+            names(AgentQuery {
+                selector: label("it"),
+                ..Default::default()
+            }),
+            ["helpdesk"]
+        );
+    }
+
     // ==========================================================================
     // scan() - Directory handling
     // ==========================================================================
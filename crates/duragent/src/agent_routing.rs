@@ -0,0 +1,181 @@
+//! Router agents.
+//!
+//! An agent's manifest can hand each message to a downstream agent, picked
+//! by the first rule whose conditions all hold, to put one entrypoint in
+//! front of several specialists:
+//!
+//! ```yaml
+//! router:
+//!   classifier:
+//!     labels: [billing, support, other]
+//!     instructions: billing covers invoices, refunds, and payment methods
+//!   rules:
+//!     - when: { classifier: [billing] }
+//!       agent: billing
+//!     - when: { classifier: [support], languages: [ja] }
+//!       agent: support-ja
+//!     - when: { classifier: [support] }
+//!       agent: support
+//!   default: general
+//! ```
+//!
+//! Conditions are those of `model_routing`, with the classifier labeling
+//! each message's intent. The downstream agent answers in the router's
+//! session with its own model, prompts, tools, and policy, so callers get
+//! its answer as the router's. Messages no rule matches go to `default`, or
+//! are answered by the router itself. Rules naming an agent that isn't
+//! loaded or is disabled are skipped. Routing is one hop: a downstream
+//! router answers messages itself.
+
+use std::sync::Arc;
+
+use tracing::{debug, warn};
+
+use crate::agent::{AgentSpec, AgentStore};
+use crate::language;
+use crate::llm::ProviderRegistry;
+use crate::model_routing::{classify, matches_input};
+
+/// The agent to answer `input`: the downstream agent picked by `agent`'s
+/// router rules, or `agent` itself.
+pub async fn route(
+    agent: Arc<AgentSpec>,
+    agents: &AgentStore,
+    providers: &ProviderRegistry,
+    input: &str,
+) -> Arc<AgentSpec> {
+    let Some(router) = &agent.router else {
+        return agent;
+    };
+
+    let chars = input.chars().count();
+    let detected = language::detect(input);
+    // Computed on first use; `None` inside means the classifier had no answer.
+    let mut label: Option<Option<String>> = None;
+
+    for (i, rule) in router.rules.iter().enumerate() {
+        if !matches_input(&rule.when, chars, detected) {
+            continue;
+        }
+        if !rule.when.classifier.is_empty() {
+            if label.is_none() {
+                label = Some(match &router.classifier {
+                    Some(classifier) => classify(&agent, classifier, providers, input).await,
+                    None => None,
+                });
+            }
+            let Some(Some(label)) = &label else {
+                continue;
+            };
+            if !rule.when.classifier.contains(label) {
+                continue;
+            }
+        }
+        if let Some(downstream) = downstream(&agent, agents, &rule.agent) {
+            debug!(
+                agent = %agent.metadata.name,
+                rule = i,
+                to = %rule.agent,
+                "Routed message to agent"
+            );
+            return downstream;
+        }
+    }
+
+    let default = router
+        .default
+        .as_deref()
+        .and_then(|name| downstream(&agent, agents, name));
+    match default {
+        Some(downstream) => {
+            debug!(
+                agent = %agent.metadata.name,
+                to = %downstream.metadata.name,
+                "Routed message to default agent"
+            );
+            downstream
+        }
+        None => agent,
+    }
+}
+
+/// The enabled agent `name`, if loaded.
+fn downstream(router: &AgentSpec, agents: &AgentStore, name: &str) -> Option<Arc<AgentSpec>> {
+    match agents.get(name) {
+        Some(spec) if spec.enabled => Some(spec),
+        Some(_) => {
+            warn!(agent = %router.metadata.name, to = %name, "Router target agent is disabled");
+            None
+        }
+        None => {
+            warn!(agent = %router.metadata.name, to = %name, "Router target agent not found");
+            None
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::store::file::FileAgentCatalog;
+    use tempfile::TempDir;
+
+    fn agent(name: &str, router: &str) -> AgentSpec {
+        let yaml = format!(
+            "apiVersion: duragent/v1alpha1\nkind: Agent\nmetadata:\n  name: {name}\nspec:\n  model:\n    provider: openai\n    name: gpt-4o-mini\n{router}"
+        );
+        crate::agent::parse_agent_yaml(
+            &yaml,
+            Default::default(),
+            Vec::new(),
+            Default::default(),
+            std::path::PathBuf::from("/tmp/a"),
+            None,
+        )
+        .unwrap()
+    }
+
+    async fn store(tmp: &TempDir, specs: Vec<AgentSpec>) -> AgentStore {
+        let catalog = FileAgentCatalog::new(tmp.path().join("agents"), None);
+        let store = AgentStore::from_catalog(&catalog).await.store;
+        for spec in specs {
+            store.upsert(spec);
+        }
+        store
+    }
+
+    #[tokio::test]
+    async fn first_matching_rule_picks_the_agent() {
+        let tmp = TempDir::new().unwrap();
+        let router = Arc::new(agent(
+            "front-desk",
+            "  router:\n    rules:\n      - when: { max_input_chars: 10 }\n        agent: quick\n      - when: { min_input_chars: 5 }\n        agent: thorough\n",
+        ));
+        let agents = store(&tmp, vec![agent("quick", ""), agent("thorough", "")]).await;
+        let providers = ProviderRegistry::new();
+
+        let routed = route(router.clone(), &agents, &providers, "hi").await;
+        assert_eq!(routed.metadata.name, "quick");
+        let routed = route(router.clone(), &agents, &providers, "a longer message").await;
+        assert_eq!(routed.metadata.name, "thorough");
+
+        // Disabled agents are skipped; with no default the router answers
+        agents.set_enabled("thorough", false);
+        let routed = route(router, &agents, &providers, "a longer message").await;
+        assert_eq!(routed.metadata.name, "front-desk");
+    }
+
+    #[tokio::test]
+    async fn unmatched_messages_go_to_the_default() {
+        let tmp = TempDir::new().unwrap();
+        let router = Arc::new(agent(
+            "front-desk",
+            "  router:\n    rules:\n      - when: { languages: [ja] }\n        agent: support-ja\n    default: general\n",
+        ));
+        let agents = store(&tmp, vec![agent("general", "")]).await;
+        let providers = ProviderRegistry::new();
+
+        let routed = route(router, &agents, &providers, "Where is my invoice?").await;
+        assert_eq!(routed.metadata.name, "general");
+    }
+}
@@ -0,0 +1,194 @@
+//! Files attached to session messages.
+//!
+//! Clients that invoke agents with `multipart/form-data` can attach files
+//! to a message. Each file is kept as an artifact of the session, under
+//! `{workspace}/artifacts/{session_id}/`, and the message the agent sees
+//! gains a line per attachment naming it. UTF-8 text attachments are also
+//! included inline so the agent can read them; other files are referenced
+//! by ID and can be downloaded through the API.
+
+use std::sync::Arc;
+
+use chrono::{DateTime, Utc};
+use serde::{Deserialize, Serialize};
+
+use crate::store::{ArtifactStore, StorageResult};
+
+/// ID prefix for artifacts.
+pub const ARTIFACT_ID_PREFIX: &str = "art_";
+
+/// Largest text attachment included inline in the message.
+pub const MAX_INLINE_TEXT_BYTES: usize = 64 * 1024;
+
+// ============================================================================
+// Types
+// ============================================================================
+
+/// A file stored with a session.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct Artifact {
+    pub id: String,
+    pub session_id: String,
+    /// File name given by the client.
+    pub name: String,
+    pub content_type: String,
+    /// Size of the content in bytes.
+    pub size: u64,
+    pub created_at: DateTime<Utc>,
+}
+
+impl Artifact {
+    pub fn new(session_id: &str, name: String, content_type: String, size: u64) -> Self {
+        Self {
+            id: format!("{ARTIFACT_ID_PREFIX}{}", ulid::Ulid::new()),
+            session_id: session_id.to_string(),
+            name,
+            content_type,
+            size,
+            created_at: Utc::now(),
+        }
+    }
+}
+
+/// A file uploaded with a message, before it is stored.
+#[derive(Debug, Clone)]
+pub struct Attachment {
+    pub name: String,
+    pub content_type: String,
+    pub content: Vec<u8>,
+}
+
+// ============================================================================
+// Artifacts
+// ============================================================================
+
+/// Session artifacts; cheap to clone.
+#[derive(Clone)]
+pub struct Artifacts {
+    store: Arc<dyn ArtifactStore>,
+}
+
+impl Artifacts {
+    pub fn new(store: Arc<dyn ArtifactStore>) -> Self {
+        Self { store }
+    }
+
+    /// List a session's artifacts, oldest first.
+    pub async fn list(&self, session_id: &str) -> StorageResult<Vec<Artifact>> {
+        self.store.list(session_id).await
+    }
+
+    /// Load an artifact and its content.
+    pub async fn load(
+        &self,
+        session_id: &str,
+        id: &str,
+    ) -> StorageResult<Option<(Artifact, Vec<u8>)>> {
+        self.store.load(session_id, id).await
+    }
+
+    /// Store each attachment with the session and return the user message
+    /// with the attachments described after it.
+    pub async fn attach(
+        &self,
+        session_id: &str,
+        content: String,
+        attachments: Vec<Attachment>,
+    ) -> StorageResult<String> {
+        let mut message = content;
+        for attachment in attachments {
+            let artifact = Artifact::new(
+                session_id,
+                attachment.name,
+                attachment.content_type,
+                attachment.content.len() as u64,
+            );
+            self.store.save(&artifact, &attachment.content).await?;
+
+            if !message.is_empty() {
+                message.push_str("\n\n");
+            }
+            message.push_str(&describe(&artifact, &attachment.content));
+        }
+        Ok(message)
+    }
+}
+
+/// Describe an attachment to the agent, with its text when it has some.
+fn describe(artifact: &Artifact, content: &[u8]) -> String {
+    let header = format!(
+        "[Attachment {}: {} ({}, {} bytes)]",
+        artifact.id, artifact.name, artifact.content_type, artifact.size
+    );
+    let text = (content.len() <= MAX_INLINE_TEXT_BYTES)
+        .then(|| std::str::from_utf8(content).ok())
+        .flatten();
+    match text {
+        Some(text) if is_text(&artifact.content_type) => format!("{header}\n```\n{text}\n```"),
+        _ => header,
+    }
+}
+
+fn is_text(content_type: &str) -> bool {
+    let essence = content_type
+        .split(';')
+        .next()
+        .unwrap_or_default()
+        .trim()
+        .to_ascii_lowercase();
+    essence.starts_with("text/")
+        || essence == "application/json"
+        || essence == "application/xml"
+        || essence == "application/yaml"
+        || essence.ends_with("+json")
+        || essence.ends_with("+xml")
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn artifact(name: &str, content_type: &str, size: u64) -> Artifact {
+        Artifact {
+            id: "art_1".to_string(),
+            session_id: "session_1".to_string(),
+            name: name.to_string(),
+            content_type: content_type.to_string(),
+            size,
+            created_at: Utc::now(),
+        }
+    }
+
+    #[test]
+    fn text_attachments_are_inlined() {
+        let content = b"a,b\n1,2";
+        assert_eq!(
+            describe(&artifact("data.csv", "text/csv; charset=utf-8", 7), content),
+            "[Attachment art_1: data.csv (text/csv; charset=utf-8, 7 bytes)]\n```\na,b\n1,2\n```"
+        );
+    }
+
+    #[test]
+    fn binary_and_large_attachments_are_referenced() {
+        assert_eq!(
+            describe(&artifact("photo.png", "image/png", 3), &[0x89, 0x50, 0x4e]),
+            "[Attachment art_1: photo.png (image/png, 3 bytes)]"
+        );
+
+        let large = vec![b'x'; MAX_INLINE_TEXT_BYTES + 1];
+        let described = describe(
+            &artifact("log.txt", "text/plain", large.len() as u64),
+            &large,
+        );
+        assert!(!described.contains("```"));
+    }
+
+    #[test]
+    fn recognizes_text_content_types() {
+        assert!(is_text("text/plain"));
+        assert!(is_text("Application/JSON"));
+        assert!(is_text("application/ld+json"));
+        assert!(!is_text("application/pdf"));
+        assert!(!is_text("application/octet-stream"));
+    }
+}
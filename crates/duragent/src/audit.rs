@@ -0,0 +1,626 @@
+//! Security audit log.
+//!
+//! Security-relevant events (authentication failures, access denials, and
+//! admin and provisioning changes) are recorded through an [`AuditLog`] and
+//! shipped to the sinks in the `audit` config section: a local file,
+//! syslog, an OTLP logs endpoint, or a webhook. Several sinks can be active
+//! at once.
+//!
+//! Recording never blocks a request. Each sink has its own bounded buffer
+//! and a background task that delivers events in batches, retrying a failed
+//! batch with exponential backoff before dropping it. A slow or unreachable
+//! sink only affects itself.
+
+use std::collections::HashMap;
+use std::fmt;
+use std::net::SocketAddr;
+use std::path::PathBuf;
+use std::sync::Arc;
+use std::time::Duration;
+
+use async_trait::async_trait;
+use chrono::{DateTime, SecondsFormat, Utc};
+use serde::Serialize;
+use thiserror::Error;
+use tokio::io::AsyncWriteExt;
+use tokio::sync::mpsc;
+use tracing::{error, warn};
+
+use crate::config::{AuditConfig, AuditSinkConfig, SyslogProtocol};
+
+/// First delay before retrying a failed batch. Doubles on each retry.
+const INITIAL_RETRY_DELAY: Duration = Duration::from_millis(500);
+/// Longest delay between retries.
+const MAX_RETRY_DELAY: Duration = Duration::from_secs(30);
+
+// ============================================================================
+// Events
+// ============================================================================
+
+/// Result of an audited action.
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize)]
+#[serde(rename_all = "snake_case")]
+pub enum AuditOutcome {
+    Success,
+    /// Rejected by authentication or authorization.
+    Denied,
+}
+
+impl fmt::Display for AuditOutcome {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        f.write_str(match self {
+            AuditOutcome::Success => "success",
+            AuditOutcome::Denied => "denied",
+        })
+    }
+}
+
+/// One audit record.
+#[derive(Debug, Clone, PartialEq, Eq, Serialize)]
+pub struct AuditEvent {
+    pub id: String,
+    pub timestamp: DateTime<Utc>,
+    /// Dotted action name, e.g. `auth.failed` or `service_account.rotated`.
+    pub action: String,
+    pub outcome: AuditOutcome,
+    /// Who acted, when known.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub principal: Option<String>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub source_ip: Option<String>,
+    /// What was acted on, e.g. a path or resource ID.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub target: Option<String>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub detail: Option<String>,
+}
+
+impl AuditEvent {
+    pub fn new(action: impl Into<String>, outcome: AuditOutcome) -> Self {
+        Self {
+            id: ulid::Ulid::new().to_string(),
+            timestamp: Utc::now(),
+            action: action.into(),
+            outcome,
+            principal: None,
+            source_ip: None,
+            target: None,
+            detail: None,
+        }
+    }
+
+    pub fn principal(mut self, principal: impl Into<String>) -> Self {
+        self.principal = Some(principal.into());
+        self
+    }
+
+    pub fn source(mut self, addr: &SocketAddr) -> Self {
+        self.source_ip = Some(addr.ip().to_string());
+        self
+    }
+
+    pub fn target(mut self, target: impl Into<String>) -> Self {
+        self.target = Some(target.into());
+        self
+    }
+
+    pub fn detail(mut self, detail: impl Into<String>) -> Self {
+        self.detail = Some(detail.into());
+        self
+    }
+
+    /// One-line summary, used where a sink needs a message body.
+    fn summary(&self) -> String {
+        let mut summary = format!("{} {}", self.action, self.outcome);
+        if let Some(target) = &self.target {
+            summary.push_str(&format!(" target={target}"));
+        }
+        if let Some(detail) = &self.detail {
+            summary.push_str(&format!(": {detail}"));
+        }
+        summary
+    }
+}
+
+// ============================================================================
+// AuditLog
+// ============================================================================
+
+/// Handle for recording audit events. Cheap to clone.
+///
+/// The default handle has no sinks and drops every event.
+#[derive(Clone, Default)]
+pub struct AuditLog {
+    sinks: Arc<Vec<SinkQueue>>,
+}
+
+struct SinkQueue {
+    name: &'static str,
+    tx: mpsc::Sender<AuditEvent>,
+}
+
+impl AuditLog {
+    /// Start one delivery task per configured sink.
+    ///
+    /// `default_file` is used by `file` sinks without a `path`.
+    pub fn start(config: &AuditConfig, default_file: PathBuf, client: reqwest::Client) -> Self {
+        let sinks = config
+            .sinks
+            .iter()
+            .map(|sink| {
+                let sink: Box<dyn AuditSink> = match sink {
+                    AuditSinkConfig::File { path } => Box::new(FileSink {
+                        path: path.clone().unwrap_or_else(|| default_file.clone()),
+                    }),
+                    AuditSinkConfig::Syslog {
+                        address,
+                        protocol,
+                        facility,
+                    } => Box::new(SyslogSink {
+                        address: address.clone(),
+                        protocol: *protocol,
+                        facility: *facility,
+                        hostname: std::env::var("HOSTNAME").unwrap_or_else(|_| "-".to_string()),
+                    }),
+                    AuditSinkConfig::Otlp { endpoint, headers } => Box::new(OtlpSink {
+                        client: client.clone(),
+                        endpoint: endpoint.clone(),
+                        headers: headers.clone(),
+                    }),
+                    AuditSinkConfig::Webhook { url, headers } => Box::new(WebhookSink {
+                        client: client.clone(),
+                        url: url.clone(),
+                        headers: headers.clone(),
+                    }),
+                };
+                let (tx, rx) = mpsc::channel(config.buffer_size.max(1));
+                let name = sink.name();
+                tokio::spawn(run_sink(sink, rx, Batching::from_config(config)));
+                SinkQueue { name, tx }
+            })
+            .collect();
+        Self {
+            sinks: Arc::new(sinks),
+        }
+    }
+
+    pub fn is_enabled(&self) -> bool {
+        !self.sinks.is_empty()
+    }
+
+    /// Queue `event` for every sink. Drops it for sinks whose buffer is full.
+    pub fn record(&self, event: AuditEvent) {
+        for sink in self.sinks.iter() {
+            if let Err(mpsc::error::TrySendError::Full(event)) = sink.tx.try_send(event.clone()) {
+                warn!(sink = sink.name, action = %event.action, "Audit buffer full, dropping event");
+            }
+        }
+    }
+}
+
+#[derive(Debug, Clone, Copy)]
+struct Batching {
+    batch_size: usize,
+    flush_interval: Duration,
+    max_retries: u32,
+    /// Delay before the first retry.
+    retry_delay: Duration,
+}
+
+impl Batching {
+    fn from_config(config: &AuditConfig) -> Self {
+        Self {
+            batch_size: config.batch_size.max(1),
+            flush_interval: Duration::from_millis(config.flush_interval_ms),
+            max_retries: config.max_retries,
+            retry_delay: INITIAL_RETRY_DELAY,
+        }
+    }
+}
+
+/// Collect events into batches and deliver them until the log is dropped.
+async fn run_sink(
+    sink: Box<dyn AuditSink>,
+    mut rx: mpsc::Receiver<AuditEvent>,
+    batching: Batching,
+) {
+    let mut batch = Vec::with_capacity(batching.batch_size);
+    while let Some(first) = rx.recv().await {
+        batch.push(first);
+        let deadline = tokio::time::Instant::now() + batching.flush_interval;
+        while batch.len() < batching.batch_size {
+            match tokio::time::timeout_at(deadline, rx.recv()).await {
+                Ok(Some(event)) => batch.push(event),
+                Ok(None) | Err(_) => break,
+            }
+        }
+        deliver(sink.as_ref(), &batch, batching).await;
+        batch.clear();
+    }
+}
+
+async fn deliver(sink: &dyn AuditSink, batch: &[AuditEvent], batching: Batching) {
+    let mut delay = batching.retry_delay;
+    let mut attempt = 0;
+    loop {
+        match sink.send(batch).await {
+            Ok(()) => return,
+            Err(e) if attempt < batching.max_retries => {
+                attempt += 1;
+                warn!(sink = sink.name(), attempt, error = %e, "Audit delivery failed, retrying");
+                tokio::time::sleep(delay).await;
+                delay = (delay * 2).min(MAX_RETRY_DELAY);
+            }
+            Err(e) => {
+                error!(
+                    sink = sink.name(),
+                    dropped = batch.len(),
+                    error = %e,
+                    "Audit delivery failed, dropping events"
+                );
+                return;
+            }
+        }
+    }
+}
+
+// ============================================================================
+// Sinks
+// ============================================================================
+
+/// A failed delivery.
+#[derive(Debug, Error)]
+enum SinkError {
+    #[error(transparent)]
+    Io(#[from] std::io::Error),
+    #[error(transparent)]
+    Http(#[from] reqwest::Error),
+    #[error("unexpected status {0}")]
+    Status(reqwest::StatusCode),
+    #[error(transparent)]
+    Json(#[from] serde_json::Error),
+}
+
+#[async_trait]
+trait AuditSink: Send + Sync {
+    fn name(&self) -> &'static str;
+
+    async fn send(&self, batch: &[AuditEvent]) -> Result<(), SinkError>;
+}
+
+struct FileSink {
+    path: PathBuf,
+}
+
+#[async_trait]
+impl AuditSink for FileSink {
+    fn name(&self) -> &'static str {
+        "file"
+    }
+
+    async fn send(&self, batch: &[AuditEvent]) -> Result<(), SinkError> {
+        let mut lines = Vec::new();
+        for event in batch {
+            serde_json::to_writer(&mut lines, event)?;
+            lines.push(b'\n');
+        }
+        if let Some(parent) = self.path.parent() {
+            tokio::fs::create_dir_all(parent).await?;
+        }
+        let mut file = tokio::fs::OpenOptions::new()
+            .create(true)
+            .append(true)
+            .open(&self.path)
+            .await?;
+        file.write_all(&lines).await?;
+        file.flush().await?;
+        Ok(())
+    }
+}
+
+struct SyslogSink {
+    address: String,
+    protocol: SyslogProtocol,
+    facility: u8,
+    hostname: String,
+}
+
+impl SyslogSink {
+    /// Format an RFC 5424 message with the event as JSON in the body.
+    fn format(&self, event: &AuditEvent) -> Result<String, SinkError> {
+        // Severity: notice for successes, warning for denials
+        let severity = match event.outcome {
+            AuditOutcome::Success => 5,
+            AuditOutcome::Denied => 4,
+        };
+        let priority = u16::from(self.facility) * 8 + severity;
+        Ok(format!(
+            "<{priority}>1 {} {} duragent - {} - {}",
+            event.timestamp.to_rfc3339_opts(SecondsFormat::Millis, true),
+            self.hostname,
+            msgid(&event.action),
+            serde_json::to_string(event)?
+        ))
+    }
+}
+
+/// RFC 5424 MSGID: printable ASCII without spaces, at most 32 characters.
+fn msgid(action: &str) -> String {
+    let id: String = action
+        .chars()
+        .filter(|c| c.is_ascii_graphic())
+        .take(32)
+        .collect();
+    if id.is_empty() { "-".to_string() } else { id }
+}
+
+#[async_trait]
+impl AuditSink for SyslogSink {
+    fn name(&self) -> &'static str {
+        "syslog"
+    }
+
+    async fn send(&self, batch: &[AuditEvent]) -> Result<(), SinkError> {
+        let messages = batch
+            .iter()
+            .map(|e| self.format(e))
+            .collect::<Result<Vec<_>, _>>()?;
+        match self.protocol {
+            SyslogProtocol::Udp => {
+                let target = tokio::net::lookup_host(&self.address)
+                    .await?
+                    .next()
+                    .ok_or_else(|| {
+                        std::io::Error::new(
+                            std::io::ErrorKind::NotFound,
+                            format!("no address for {}", self.address),
+                        )
+                    })?;
+                let bind: SocketAddr = if target.is_ipv4() {
+                    ([0, 0, 0, 0], 0).into()
+                } else {
+                    ([0u16; 8], 0).into()
+                };
+                let socket = tokio::net::UdpSocket::bind(bind).await?;
+                for message in messages {
+                    socket.send_to(message.as_bytes(), target).await?;
+                }
+            }
+            SyslogProtocol::Tcp => {
+                let mut stream = tokio::net::TcpStream::connect(&self.address).await?;
+                let mut framed = Vec::new();
+                for message in messages {
+                    framed.extend_from_slice(format!("{} {message}", message.len()).as_bytes());
+                }
+                stream.write_all(&framed).await?;
+                stream.shutdown().await?;
+            }
+        }
+        Ok(())
+    }
+}
+
+struct OtlpSink {
+    client: reqwest::Client,
+    endpoint: String,
+    headers: HashMap<String, String>,
+}
+
+impl OtlpSink {
+    /// Build an OTLP `ExportLogsServiceRequest` in its JSON encoding.
+    fn payload(batch: &[AuditEvent]) -> serde_json::Value {
+        let attribute = |key: &str, value: &str| serde_json::json!({"key": key, "value": {"stringValue": value}});
+        let records: Vec<_> = batch
+            .iter()
+            .map(|event| {
+                let (severity_number, severity_text) = match event.outcome {
+                    AuditOutcome::Success => (10, "INFO2"),
+                    AuditOutcome::Denied => (13, "WARN"),
+                };
+                let mut attributes = vec![
+                    attribute("event.id", &event.id),
+                    attribute("event.name", &event.action),
+                    attribute("event.outcome", &event.outcome.to_string()),
+                ];
+                let optional = [
+                    ("enduser.id", &event.principal),
+                    ("client.address", &event.source_ip),
+                    ("audit.target", &event.target),
+                    ("audit.detail", &event.detail),
+                ];
+                for (key, value) in optional {
+                    if let Some(value) = value {
+                        attributes.push(attribute(key, value));
+                    }
+                }
+                let nanos = event.timestamp.timestamp_nanos_opt().unwrap_or_default();
+                serde_json::json!({
+                    "timeUnixNano": nanos.to_string(),
+                    "severityNumber": severity_number,
+                    "severityText": severity_text,
+                    "body": {"stringValue": event.summary()},
+                    "attributes": attributes,
+                })
+            })
+            .collect();
+        serde_json::json!({
+            "resourceLogs": [{
+                "resource": {"attributes": [attribute("service.name", "duragent")]},
+                "scopeLogs": [{
+                    "scope": {"name": "duragent.audit"},
+                    "logRecords": records,
+                }],
+            }],
+        })
+    }
+}
+
+#[async_trait]
+impl AuditSink for OtlpSink {
+    fn name(&self) -> &'static str {
+        "otlp"
+    }
+
+    async fn send(&self, batch: &[AuditEvent]) -> Result<(), SinkError> {
+        post_json(
+            &self.client,
+            &self.endpoint,
+            &self.headers,
+            &Self::payload(batch),
+        )
+        .await
+    }
+}
+
+struct WebhookSink {
+    client: reqwest::Client,
+    url: String,
+    headers: HashMap<String, String>,
+}
+
+#[async_trait]
+impl AuditSink for WebhookSink {
+    fn name(&self) -> &'static str {
+        "webhook"
+    }
+
+    async fn send(&self, batch: &[AuditEvent]) -> Result<(), SinkError> {
+        let payload = serde_json::json!({ "events": batch });
+        post_json(&self.client, &self.url, &self.headers, &payload).await
+    }
+}
+
+async fn post_json(
+    client: &reqwest::Client,
+    url: &str,
+    headers: &HashMap<String, String>,
+    payload: &serde_json::Value,
+) -> Result<(), SinkError> {
+    let mut request = client.post(url).json(payload);
+    for (name, value) in headers {
+        request = request.header(name, value);
+    }
+    let response = request.send().await?;
+    if response.status().is_success() {
+        Ok(())
+    } else {
+        Err(SinkError::Status(response.status()))
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use std::sync::Mutex;
+    use std::sync::atomic::{AtomicUsize, Ordering};
+
+    /// Fails the first `failures` sends, then records batches.
+    struct FlakySink {
+        failures: AtomicUsize,
+        batches: Arc<Mutex<Vec<Vec<String>>>>,
+    }
+
+    #[async_trait]
+    impl AuditSink for FlakySink {
+        fn name(&self) -> &'static str {
+            "flaky"
+        }
+
+        async fn send(&self, batch: &[AuditEvent]) -> Result<(), SinkError> {
+            if self
+                .failures
+                .fetch_update(Ordering::SeqCst, Ordering::SeqCst, |n| n.checked_sub(1))
+                .is_ok()
+            {
+                return Err(std::io::Error::other("unavailable").into());
+            }
+            let actions = batch.iter().map(|e| e.action.clone()).collect();
+            self.batches.lock().unwrap().push(actions);
+            Ok(())
+        }
+    }
+
+    #[tokio::test]
+    async fn batches_and_retries_failed_deliveries() {
+        let batches = Arc::new(Mutex::new(Vec::new()));
+        let sink = FlakySink {
+            failures: AtomicUsize::new(2),
+            batches: batches.clone(),
+        };
+        let (tx, rx) = mpsc::channel(16);
+        let batching = Batching {
+            batch_size: 2,
+            flush_interval: Duration::from_millis(10),
+            max_retries: 3,
+            retry_delay: Duration::from_millis(1),
+        };
+        for action in ["a", "b", "c"] {
+            tx.send(AuditEvent::new(action, AuditOutcome::Success))
+                .await
+                .unwrap();
+        }
+        drop(tx);
+        run_sink(Box::new(sink), rx, batching).await;
+
+        assert_eq!(
+            *batches.lock().unwrap(),
+            vec![
+                vec!["a".to_string(), "b".to_string()],
+                vec!["c".to_string()]
+            ]
+        );
+    }
+
+    #[tokio::test]
+    async fn file_sink_appends_json_lines() {
+        let tmp = tempfile::TempDir::new().unwrap();
+        let sink = FileSink {
+            path: tmp.path().join("audit/audit.jsonl"),
+        };
+        let event = AuditEvent::new("auth.failed", AuditOutcome::Denied)
+            .source(&([10, 0, 0, 1], 443).into())
+            .detail("missing or invalid api token");
+        sink.send(std::slice::from_ref(&event)).await.unwrap();
+        sink.send(&[event]).await.unwrap();
+
+        let content = std::fs::read_to_string(&sink.path).unwrap();
+        let lines: Vec<serde_json::Value> = content
+            .lines()
+            .map(|l| serde_json::from_str(l).unwrap())
+            .collect();
+        assert_eq!(lines.len(), 2);
+        assert_eq!(lines[0]["action"], "auth.failed");
+        assert_eq!(lines[0]["outcome"], "denied");
+        assert_eq!(lines[0]["source_ip"], "10.0.0.1");
+        assert!(lines[0].get("principal").is_none());
+    }
+
+    #[test]
+    fn syslog_messages_follow_rfc5424() {
+        let sink = SyslogSink {
+            address: "127.0.0.1:514".to_string(),
+            protocol: SyslogProtocol::Udp,
+            facility: 10,
+            hostname: "host1".to_string(),
+        };
+        let event =
+            AuditEvent::new("service_account.rotated", AuditOutcome::Success).principal("admin");
+        let message = sink.format(&event).unwrap();
+        assert!(message.starts_with("<85>1 "), "{message}");
+        assert!(message.contains(" host1 duragent - service_account.rotated - {"));
+    }
+
+    #[test]
+    fn otlp_payload_carries_event_attributes() {
+        let event = AuditEvent::new("access.denied", AuditOutcome::Denied).principal("ci");
+        let payload = OtlpSink::payload(&[event]);
+        let record = &payload["resourceLogs"][0]["scopeLogs"][0]["logRecords"][0];
+        assert_eq!(record["severityText"], "WARN");
+        assert_eq!(record["body"]["stringValue"], "access.denied denied");
+        assert!(
+            record["attributes"].as_array().unwrap().contains(
+                &serde_json::json!({"key": "enduser.id", "value": {"stringValue": "ci"}})
+            )
+        );
+    }
+}
@@ -0,0 +1,457 @@
+//! Best-of sampling.
+//!
+//! An agent's manifest can have each model turn sampled several times
+//! concurrently, from the same or different models, and keep the best
+//! candidate:
+//!
+//! ```yaml
+//! best_of:
+//!   samples: 3
+//!   models:
+//!     - { provider: openai, name: gpt-4o }
+//!     - { provider: anthropic, name: claude-sonnet-4-5 }
+//!   select: judge
+//!   criteria: prefer answers that cite the documentation
+//! ```
+//!
+//! Samples take `models` in turn. Failed samples are dropped, and the turn
+//! fails only when every sample does. The winner continues the run as if it
+//! were the only response; all candidates are recorded in the session's
+//! event log. Usage of every sample and of the judge counts toward the run.
+
+use std::sync::Arc;
+
+use async_trait::async_trait;
+use futures::{future, stream};
+use tracing::{debug, warn};
+
+use crate::agent::{AgentSpec, BestOfSelect, ModelConfig};
+use crate::llm::{
+    ChatRequest, ChatResponse, ChatStream, LLMError, LLMProvider, Message, ProviderRegistry, Role,
+    StreamEvent, Usage,
+};
+use crate::session::{SampleCandidate, SessionHandle};
+
+/// Candidates of one sampled turn and the winner, for the run trace.
+#[derive(Debug, Clone)]
+pub struct SampleSelection {
+    pub select: BestOfSelect,
+    pub winner: usize,
+    pub candidates: Vec<SampleCandidate>,
+    pub judge_error: Option<String>,
+}
+
+/// A model to sample and the provider serving it.
+struct Sampler {
+    model: ModelConfig,
+    provider: Arc<dyn LLMProvider>,
+}
+
+impl Sampler {
+    fn label(&self) -> String {
+        format!("{}/{}", self.model.provider, self.model.name)
+    }
+}
+
+/// `provider` sampled best-of for agents with `best_of`, or `provider`
+/// itself. Models whose provider isn't configured are left out.
+pub async fn wrap(
+    agent: &AgentSpec,
+    provider: Arc<dyn LLMProvider>,
+    providers: &ProviderRegistry,
+    handle: &SessionHandle,
+) -> Arc<dyn LLMProvider> {
+    let Some(config) = &agent.best_of else {
+        return provider;
+    };
+
+    let mut samplers = Vec::new();
+    if config.models.is_empty() {
+        samplers.push(Sampler {
+            model: agent.model.clone(),
+            provider: provider.clone(),
+        });
+    }
+    for model in &config.models {
+        match providers
+            .get(&model.provider, model.base_url.as_deref())
+            .await
+        {
+            Some(provider) => samplers.push(Sampler {
+                model: model.clone(),
+                provider,
+            }),
+            None => warn!(
+                agent = %agent.metadata.name,
+                provider = %model.provider,
+                "Best-of sampling provider not configured"
+            ),
+        }
+    }
+    if samplers.is_empty() {
+        return provider;
+    }
+
+    let judge = match config.select {
+        BestOfSelect::Judge => {
+            let model = config.judge.clone().unwrap_or_else(|| agent.model.clone());
+            let judge = providers
+                .get(&model.provider, model.base_url.as_deref())
+                .await;
+            if judge.is_none() {
+                warn!(
+                    agent = %agent.metadata.name,
+                    provider = %model.provider,
+                    "Best-of judge provider not configured"
+                );
+            }
+            judge.map(|provider| Sampler { model, provider })
+        }
+        BestOfSelect::Longest | BestOfSelect::Shortest => None,
+    };
+
+    Arc::new(BestOfProvider {
+        base: provider,
+        samples: config.samples as usize,
+        samplers,
+        select: config.select,
+        judge,
+        criteria: config.criteria.clone(),
+        handle: handle.clone(),
+    })
+}
+
+// ============================================================================
+// BestOfProvider
+// ============================================================================
+
+/// Sends each chat request to several samplers and answers with the best
+/// response.
+struct BestOfProvider {
+    base: Arc<dyn LLMProvider>,
+    samples: usize,
+    samplers: Vec<Sampler>,
+    select: BestOfSelect,
+    judge: Option<Sampler>,
+    criteria: Option<String>,
+    handle: SessionHandle,
+}
+
+impl BestOfProvider {
+    /// Ask the judge which successful candidate is best, as an index into
+    /// `candidates`, with the judge's usage.
+    async fn judge(
+        &self,
+        request: &ChatRequest,
+        candidates: &[SampleCandidate],
+    ) -> Result<(usize, Option<Usage>), String> {
+        let judge = self
+            .judge
+            .as_ref()
+            .ok_or_else(|| "judge provider not configured".to_string())?;
+
+        let mut prompt = "You compare candidate replies to the same conversation and pick the best one. Reply with the number of the best candidate only.".to_string();
+        if let Some(criteria) = &self.criteria {
+            prompt.push_str("\n\n");
+            prompt.push_str(criteria);
+        }
+        let request = ChatRequest::new(
+            &judge.model.name,
+            vec![
+                Message::text(Role::System, prompt),
+                Message::text(Role::User, judge_input(request, candidates)),
+            ],
+            Some(0.0),
+            Some(16),
+        );
+
+        let response = judge
+            .provider
+            .chat(request)
+            .await
+            .map_err(|e| e.to_string())?;
+        let reply = response
+            .choices
+            .first()
+            .and_then(|c| c.message.content.as_deref())
+            .unwrap_or_default();
+        parse_choice(reply, candidates)
+            .map(|winner| (winner, response.usage))
+            .ok_or_else(|| format!("judge named no candidate: {reply:?}"))
+    }
+}
+
+#[async_trait]
+impl LLMProvider for BestOfProvider {
+    async fn chat(&self, request: ChatRequest) -> Result<ChatResponse, LLMError> {
+        let samplers: Vec<&Sampler> = self.samplers.iter().cycle().take(self.samples).collect();
+        let results = future::join_all(samplers.iter().map(|sampler| {
+            let mut request = request.clone();
+            request.model = sampler.model.name.clone();
+            if sampler.model.temperature.is_some() {
+                request.temperature = sampler.model.temperature;
+            }
+            if sampler.model.max_output_tokens.is_some() {
+                request.max_tokens = sampler.model.max_output_tokens;
+            }
+            sampler.provider.chat(request)
+        }))
+        .await;
+
+        let mut usage = None;
+        let mut candidates = Vec::with_capacity(results.len());
+        let mut responses = Vec::with_capacity(results.len());
+        let mut first_error = None;
+        for (sampler, result) in samplers.iter().zip(results) {
+            match result {
+                Ok(response) => {
+                    usage = add_usage(usage, response.usage.clone());
+                    candidates.push(candidate(sampler.label(), &response));
+                    responses.push(Some(response));
+                }
+                Err(e) => {
+                    candidates.push(SampleCandidate {
+                        model: sampler.label(),
+                        content: None,
+                        tool_calls: Vec::new(),
+                        usage: None,
+                        error: Some(e.to_string()),
+                    });
+                    responses.push(None);
+                    first_error.get_or_insert(e);
+                }
+            }
+        }
+        let Some(first_ok) = responses.iter().position(Option::is_some) else {
+            return Err(first_error.expect("every sample failed"));
+        };
+
+        let mut judge_error = None;
+        let winner = match self.select {
+            BestOfSelect::Judge => match self.judge(&request, &candidates).await {
+                Ok((winner, judge_usage)) => {
+                    usage = add_usage(usage, judge_usage);
+                    winner
+                }
+                Err(e) => {
+                    warn!(error = %e, "Best-of judge failed, keeping the first candidate");
+                    judge_error = Some(e);
+                    first_ok
+                }
+            },
+            BestOfSelect::Longest => pick_by_length(&candidates, true).unwrap_or(first_ok),
+            BestOfSelect::Shortest => pick_by_length(&candidates, false).unwrap_or(first_ok),
+        };
+        debug!(
+            winner,
+            model = %candidates[winner].model,
+            samples = candidates.len(),
+            "Selected best-of sample"
+        );
+
+        if let Err(e) = self
+            .handle
+            .record_samples(SampleSelection {
+                select: self.select,
+                winner,
+                candidates,
+                judge_error,
+            })
+            .await
+        {
+            warn!(error = %e, "Failed to record best-of samples");
+        }
+
+        let mut response = responses
+            .swap_remove(winner)
+            .expect("winner is a successful candidate");
+        response.usage = usage;
+        Ok(response)
+    }
+
+    /// The winner is only known once every sample is done, so it is sent
+    /// as a single chunk, tool calls included.
+    async fn chat_stream(&self, request: ChatRequest) -> Result<ChatStream, LLMError> {
+        let response = self.chat(request).await?;
+        let message = response.choices.into_iter().next().map(|c| c.message);
+        let (content, reasoning, tool_calls) = message
+            .map(|m| (m.content, m.reasoning, m.tool_calls))
+            .unwrap_or_default();
+
+        let mut events = Vec::new();
+        events.extend(reasoning.map(StreamEvent::Reasoning));
+        events.extend(content.map(StreamEvent::Token));
+        events.extend(
+            tool_calls
+                .filter(|calls| !calls.is_empty())
+                .map(StreamEvent::ToolCalls),
+        );
+        events.push(StreamEvent::Done {
+            usage: response.usage,
+        });
+        Ok(Box::pin(stream::iter(events.into_iter().map(Ok))))
+    }
+
+    async fn health_check(&self) -> Result<(), LLMError> {
+        self.base.health_check().await
+    }
+
+    async fn embed(&self, model: &str, inputs: Vec<String>) -> Result<Vec<Vec<f32>>, LLMError> {
+        self.base.embed(model, inputs).await
+    }
+
+    async fn list_models(&self) -> Result<Vec<String>, LLMError> {
+        self.base.list_models().await
+    }
+}
+
+// ============================================================================
+// Helper Functions
+// ============================================================================
+
+fn candidate(model: String, response: &ChatResponse) -> SampleCandidate {
+    let message = response.choices.first().map(|c| &c.message);
+    SampleCandidate {
+        model,
+        content: message.and_then(|m| m.content.clone()),
+        tool_calls: message
+            .and_then(|m| m.tool_calls.as_ref())
+            .map(|calls| calls.iter().map(|c| c.function.name.clone()).collect())
+            .unwrap_or_default(),
+        usage: response.usage.clone(),
+        error: None,
+    }
+}
+
+/// The latest user message and each successful candidate, numbered from 1
+/// by their position among all candidates.
+fn judge_input(request: &ChatRequest, candidates: &[SampleCandidate]) -> String {
+    let latest = request
+        .messages
+        .iter()
+        .rev()
+        .find(|m| m.role == Role::User)
+        .and_then(|m| m.content.as_deref())
+        .unwrap_or_default();
+    let mut input = format!("Latest message:\n{latest}");
+    for (i, candidate) in candidates.iter().enumerate() {
+        if candidate.error.is_some() {
+            continue;
+        }
+        input.push_str(&format!(
+            "\n\nCandidate {}:\n{}",
+            i + 1,
+            candidate.content.as_deref().unwrap_or_default()
+        ));
+        if !candidate.tool_calls.is_empty() {
+            input.push_str(&format!(
+                "\n(calls tools: {})",
+                candidate.tool_calls.join(", ")
+            ));
+        }
+    }
+    input
+}
+
+/// The candidate the judge's reply names by number, if it didn't fail.
+fn parse_choice(reply: &str, candidates: &[SampleCandidate]) -> Option<usize> {
+    let digits: String = reply
+        .chars()
+        .skip_while(|c| !c.is_ascii_digit())
+        .take_while(char::is_ascii_digit)
+        .collect();
+    let index = digits.parse::<usize>().ok()?.checked_sub(1)?;
+    candidates
+        .get(index)
+        .filter(|c| c.error.is_none())
+        .map(|_| index)
+}
+
+/// The successful candidate with the longest or shortest answer, the
+/// earliest on ties.
+fn pick_by_length(candidates: &[SampleCandidate], longest: bool) -> Option<usize> {
+    let lengths = candidates
+        .iter()
+        .enumerate()
+        .filter(|(_, c)| c.error.is_none())
+        .map(|(i, c)| (i, c.content.as_deref().unwrap_or_default().chars().count()));
+    let best = if longest {
+        lengths.min_by_key(|&(i, len)| (std::cmp::Reverse(len), i))
+    } else {
+        lengths.min_by_key(|&(i, len)| (len, i))
+    };
+    best.map(|(i, _)| i)
+}
+
+fn add_usage(total: Option<Usage>, usage: Option<Usage>) -> Option<Usage> {
+    match (total, usage) {
+        (Some(t), Some(u)) => Some(Usage {
+            prompt_tokens: t.prompt_tokens + u.prompt_tokens,
+            completion_tokens: t.completion_tokens + u.completion_tokens,
+            total_tokens: t.total_tokens + u.total_tokens,
+        }),
+        (total, usage) => total.or(usage),
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn ok(content: &str) -> SampleCandidate {
+        SampleCandidate {
+            model: "mock/echo".to_string(),
+            content: Some(content.to_string()),
+            tool_calls: Vec::new(),
+            usage: None,
+            error: None,
+        }
+    }
+
+    fn failed() -> SampleCandidate {
+        SampleCandidate {
+            error: Some("boom".to_string()),
+            ..ok("")
+        }
+    }
+
+    #[test]
+    fn parse_choice_takes_the_first_number() {
+        let candidates = vec![ok("a"), failed(), ok("c")];
+        assert_eq!(parse_choice("3", &candidates), Some(2));
+        assert_eq!(parse_choice("Candidate 1 is best.", &candidates), Some(0));
+        // Failed, out of range, or no number at all
+        assert_eq!(parse_choice("2", &candidates), None);
+        assert_eq!(parse_choice("4", &candidates), None);
+        assert_eq!(parse_choice("0", &candidates), None);
+        assert_eq!(parse_choice("the first", &candidates), None);
+    }
+
+    #[test]
+    fn pick_by_length_skips_failures_and_prefers_earliest() {
+        let candidates = vec![ok("four"), failed(), ok("sixsix"), ok("ab"), ok("cd")];
+        assert_eq!(pick_by_length(&candidates, true), Some(2));
+        assert_eq!(pick_by_length(&candidates, false), Some(3));
+        assert_eq!(pick_by_length(&[failed()], true), None);
+    }
+
+    #[test]
+    fn judge_input_lists_only_successful_candidates() {
+        let request = ChatRequest::new(
+            "m",
+            vec![
+                Message::text(Role::System, "sys"),
+                Message::text(Role::User, "what is 2+2?"),
+            ],
+            None,
+            None,
+        );
+        let mut tool_user = ok("");
+        tool_user.tool_calls = vec!["calculator".to_string()];
+        let input = judge_input(&request, &[ok("4"), failed(), tool_user]);
+        assert!(input.starts_with("Latest message:\nwhat is 2+2?"));
+        assert!(input.contains("Candidate 1:\n4"));
+        assert!(!input.contains("Candidate 2"));
+        assert!(input.contains("Candidate 3:\n\n(calls tools: calculator)"));
+    }
+}
@@ -0,0 +1,490 @@
+//! Result callbacks for background runs.
+//!
+//! A message sent with a `callback_url` is answered right away with `202`
+//! and a run ID. When the run finishes, a [`RunCallback`] carrying the
+//! response the synchronous request would have returned is POSTed to the
+//! URL, so callers don't need to hold a connection open or poll.
+//!
+//! Every delivery is signed with the `callbacks.signing_secret`:
+//!
+//! - `X-Duragent-Timestamp`: Unix time of the attempt, in seconds
+//! - `X-Duragent-Signature`: `sha256=` and the hex HMAC-SHA256 of
+//!   `{timestamp}.{body}`
+//!
+//! Receivers should recompute the signature over the raw body and reject
+//! stale timestamps. Failed deliveries (network errors and non-2xx
+//! responses) are retried with exponential backoff before being dropped.
+//!
+//! Callback URLs come from API callers, so they are checked against
+//! `callbacks.allowed_hosts` and `callbacks.require_https` when the message
+//! is accepted. Unless `callbacks.allow_private_addresses` is set, URLs whose
+//! host resolves to a loopback, private, or link-local address are refused
+//! too, both then and again before each delivery. Deliveries never follow
+//! redirects, and their client resolves hosts through `PublicResolver`, so
+//! a host that starts resolving to a private address after the check still
+//! can't be reached.
+
+use std::net::{IpAddr, Ipv4Addr, Ipv6Addr, SocketAddr};
+use std::sync::Arc;
+use std::time::Duration;
+
+use reqwest::dns::{Addrs, Name, Resolve, Resolving};
+use tracing::{error, warn};
+use url::Url;
+
+use crate::api::RunCallback;
+use crate::config::CallbacksConfig;
+use crate::runs::Run;
+use crate::signing::{hex, hmac_sha256};
+
+/// Header carrying the Unix time a delivery was signed at.
+pub const TIMESTAMP_HEADER: &str = "x-duragent-timestamp";
+/// Header carrying the delivery's HMAC signature.
+pub const SIGNATURE_HEADER: &str = "x-duragent-signature";
+
+/// First delay before retrying a failed delivery. Doubles on each retry.
+const INITIAL_RETRY_DELAY: Duration = Duration::from_millis(500);
+/// Longest delay between retries.
+const MAX_RETRY_DELAY: Duration = Duration::from_secs(30);
+
+// ============================================================================
+// Callbacks
+// ============================================================================
+
+/// Delivers run results to callback URLs; cheap to clone.
+///
+/// The default is disabled: messages with a `callback_url` are refused.
+#[derive(Clone, Default)]
+pub struct Callbacks {
+    inner: Option<Arc<Inner>>,
+}
+
+struct Inner {
+    client: reqwest::Client,
+    secret: String,
+    max_retries: u32,
+    timeout: Duration,
+    allowed_hosts: Vec<String>,
+    require_https: bool,
+    allow_private_addresses: bool,
+}
+
+impl Callbacks {
+    /// Deliver with a client built from `client`, or stay disabled without a
+    /// signing secret. Redirects are turned off and, unless private addresses
+    /// are allowed, hosts resolve through `PublicResolver`.
+    pub fn new(
+        config: &CallbacksConfig,
+        client: reqwest::ClientBuilder,
+    ) -> Result<Self, reqwest::Error> {
+        let Some(secret) = &config.signing_secret else {
+            return Ok(Self::default());
+        };
+        let mut client = client.redirect(reqwest::redirect::Policy::none());
+        if !config.allow_private_addresses {
+            client = client.dns_resolver(Arc::new(PublicResolver));
+        }
+        let client = client.build()?;
+
+        let inner = Some(Arc::new(Inner {
+            client,
+            secret: secret.clone(),
+            max_retries: config.max_retries,
+            timeout: Duration::from_secs(config.timeout_seconds),
+            allowed_hosts: config
+                .allowed_hosts
+                .iter()
+                .map(|host| host.to_ascii_lowercase())
+                .collect(),
+            require_https: config.require_https,
+            allow_private_addresses: config.allow_private_addresses,
+        }));
+        Ok(Self { inner })
+    }
+
+    pub fn is_enabled(&self) -> bool {
+        self.inner.is_some()
+    }
+
+    /// Check a caller's `callback_url` before accepting the message. The
+    /// error explains why the URL is refused.
+    pub async fn check_url(&self, url: &str) -> Result<(), String> {
+        let Some(inner) = &self.inner else {
+            return Err("callbacks are not enabled on this server".to_string());
+        };
+        inner.check_url(url).await
+    }
+
+    /// POST `callback` to `url`, retrying failures. Does nothing when disabled.
+    pub async fn deliver(&self, url: &str, callback: &RunCallback) {
+        let Some(inner) = &self.inner else {
+            return;
+        };
+        // The host may resolve differently than when the message was accepted
+        if let Err(e) = inner.check_url(url).await {
+            error!(run_id = %callback.run_id, error = %e, "Run callback refused");
+            return;
+        }
+        let body = match serde_json::to_vec(callback) {
+            Ok(body) => body,
+            Err(e) => {
+                error!(run_id = %callback.run_id, error = %e, "Failed to encode run callback");
+                return;
+            }
+        };
+
+        let mut delay = INITIAL_RETRY_DELAY;
+        let mut attempt = 0;
+        loop {
+            match inner.post(url, &body).await {
+                Ok(()) => return,
+                Err(e) if attempt < inner.max_retries => {
+                    attempt += 1;
+                    warn!(run_id = %callback.run_id, attempt, error = %e, "Run callback failed, retrying");
+                    tokio::time::sleep(delay).await;
+                    delay = (delay * 2).min(MAX_RETRY_DELAY);
+                }
+                Err(e) => {
+                    error!(run_id = %callback.run_id, error = %e, "Run callback failed, giving up");
+                    return;
+                }
+            }
+        }
+    }
+}
+
+impl Inner {
+    async fn check_url(&self, url: &str) -> Result<(), String> {
+        let url = Url::parse(url).map_err(|e| format!("invalid URL: {e}"))?;
+        match url.scheme() {
+            "https" => {}
+            "http" if !self.require_https => {}
+            _ if self.require_https => return Err("must be an https URL".to_string()),
+            _ => return Err("must be an http or https URL".to_string()),
+        }
+        let Some(host) = url.host_str() else {
+            return Err("must have a host".to_string());
+        };
+        let host = host.to_ascii_lowercase();
+        if !self.allowed_hosts.is_empty()
+            && !self
+                .allowed_hosts
+                .iter()
+                .any(|pattern| host_matches(pattern, &host))
+        {
+            return Err(format!("host '{host}' is not in callbacks.allowed_hosts"));
+        }
+        if self.allow_private_addresses {
+            return Ok(());
+        }
+
+        let port = url.port_or_known_default().unwrap_or(443);
+        let addrs = match url.host() {
+            Some(url::Host::Ipv4(ip)) => vec![IpAddr::V4(ip)],
+            Some(url::Host::Ipv6(ip)) => vec![IpAddr::V6(ip)],
+            _ => tokio::net::lookup_host((host.as_str(), port))
+                .await
+                .map_err(|e| format!("cannot resolve host '{host}': {e}"))?
+                .map(|addr| addr.ip())
+                .collect(),
+        };
+        match addrs.iter().find(|ip| !is_public(**ip)) {
+            Some(ip) => Err(format!("host '{host}' resolves to non-public address {ip}")),
+            None => Ok(()),
+        }
+    }
+
+    async fn post(&self, url: &str, body: &[u8]) -> Result<(), String> {
+        let timestamp = chrono::Utc::now().timestamp().to_string();
+        let response = self
+            .client
+            .post(url)
+            .timeout(self.timeout)
+            .header(reqwest::header::CONTENT_TYPE, "application/json")
+            .header(TIMESTAMP_HEADER, &timestamp)
+            .header(SIGNATURE_HEADER, sign(&self.secret, &timestamp, body))
+            .body(body.to_vec())
+            .send()
+            .await
+            .map_err(|e| e.to_string())?;
+        if response.status().is_success() {
+            Ok(())
+        } else {
+            Err(format!("callback URL returned {}", response.status()))
+        }
+    }
+}
+
+/// DNS resolver for deliveries that drops non-public addresses.
+///
+/// `check_url` resolves the host before each delivery, but the connection
+/// resolves it again; filtering here keeps a host that changes its answer in
+/// between (DNS rebinding) from reaching an internal address. URLs with IP
+/// literals skip resolution and are covered by `check_url` alone.
+struct PublicResolver;
+
+impl Resolve for PublicResolver {
+    fn resolve(&self, name: Name) -> Resolving {
+        Box::pin(async move {
+            let host = name.as_str();
+            let addrs: Vec<SocketAddr> = tokio::net::lookup_host((host, 0))
+                .await?
+                .filter(|addr| is_public(addr.ip()))
+                .collect();
+            if addrs.is_empty() {
+                return Err(format!("host '{host}' has no public address").into());
+            }
+            let addrs: Addrs = Box::new(addrs.into_iter());
+            Ok::<_, Box<dyn std::error::Error + Send + Sync>>(addrs)
+        })
+    }
+}
+
+/// `X-Duragent-Signature` value for a body sent at `timestamp`.
+pub fn sign(secret: &str, timestamp: &str, body: &[u8]) -> String {
+    let mut signed = Vec::with_capacity(timestamp.len() + 1 + body.len());
+    signed.extend_from_slice(timestamp.as_bytes());
+    signed.push(b'.');
+    signed.extend_from_slice(body);
+    format!("sha256={}", hex(&hmac_sha256(secret.as_bytes(), &signed)))
+}
+
+/// Whether `host` matches an `allowed_hosts` entry: the same name, or for
+/// `*.example.com`, any subdomain of `example.com`.
+fn host_matches(pattern: &str, host: &str) -> bool {
+    match pattern.strip_prefix("*.") {
+        Some(domain) => host
+            .strip_suffix(domain)
+            .is_some_and(|sub| sub.len() > 1 && sub.ends_with('.')),
+        None => pattern == host,
+    }
+}
+
+/// Whether `ip` is reachable on the public internet, i.e. not loopback,
+/// private, link-local, shared (CGNAT), or otherwise reserved.
+fn is_public(ip: IpAddr) -> bool {
+    match ip {
+        IpAddr::V4(ip) => is_public_v4(ip),
+        IpAddr::V6(ip) => match ip.to_ipv4_mapped() {
+            Some(ip) => is_public_v4(ip),
+            None => is_public_v6(ip),
+        },
+    }
+}
+
+fn is_public_v4(ip: Ipv4Addr) -> bool {
+    let [a, b, ..] = ip.octets();
+    !(ip.is_unspecified()
+        || ip.is_loopback()
+        || ip.is_private()
+        || ip.is_link_local()
+        || ip.is_broadcast()
+        || ip.is_documentation()
+        || ip.is_multicast()
+        || a == 0
+        || (a == 100 && (64..128).contains(&b)))
+}
+
+fn is_public_v6(ip: Ipv6Addr) -> bool {
+    let first = ip.segments()[0];
+    !(ip.is_unspecified()
+        || ip.is_loopback()
+        || ip.is_multicast()
+        || (first & 0xfe00) == 0xfc00
+        || (first & 0xffc0) == 0xfe80)
+}
+
+// ============================================================================
+// Payloads
+// ============================================================================
+
+/// The callback for a finished run.
+pub fn run_callback(run: Run) -> RunCallback {
+    RunCallback {
+        run_id: run.run_id,
+        session_id: run.session_id,
+        labels: run.labels,
+        status: run.status,
+        http_status: run.http_status.unwrap_or_default(),
+        error_class: run.error_class,
+        result: run.result.unwrap_or_default(),
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use tokio::io::{AsyncReadExt, AsyncWriteExt};
+    use tokio::net::TcpListener;
+
+    use super::*;
+
+    #[test]
+    fn signature_covers_timestamp_and_body() {
+        let signature = sign("secret", "1700000000", br#"{"run_id":"run_1"}"#);
+        assert_eq!(
+            signature,
+            format!(
+                "sha256={}",
+                hex(&hmac_sha256(b"secret", br#"1700000000.{"run_id":"run_1"}"#))
+            )
+        );
+        assert_ne!(
+            signature,
+            sign("secret", "1700000001", br#"{"run_id":"run_1"}"#)
+        );
+    }
+
+    #[test]
+    fn disabled_without_a_secret() {
+        let callbacks =
+            Callbacks::new(&CallbacksConfig::default(), reqwest::Client::builder()).unwrap();
+        assert!(!callbacks.is_enabled());
+    }
+
+    fn callbacks(config: CallbacksConfig) -> Callbacks {
+        let config = CallbacksConfig {
+            signing_secret: Some("secret".to_string()),
+            ..config
+        };
+        Callbacks::new(&config, reqwest::Client::builder().no_proxy()).unwrap()
+    }
+
+    /// Answer one request on `listener` with `response`.
+    async fn respond_once(listener: TcpListener, response: String) {
+        let (mut stream, _) = listener.accept().await.unwrap();
+        let mut request = [0; 4096];
+        let _ = stream.read(&mut request).await.unwrap();
+        stream.write_all(response.as_bytes()).await.unwrap();
+    }
+
+    /// Whether anything connects to `listener` within a short wait.
+    async fn connected(listener: &TcpListener) -> bool {
+        tokio::time::timeout(Duration::from_millis(200), listener.accept())
+            .await
+            .is_ok()
+    }
+
+    #[tokio::test]
+    async fn deliveries_do_not_follow_redirects() {
+        let internal = TcpListener::bind("127.0.0.1:0").await.unwrap();
+        let internal_addr = internal.local_addr().unwrap();
+        let hook = TcpListener::bind("127.0.0.1:0").await.unwrap();
+        let hook_addr = hook.local_addr().unwrap();
+        tokio::spawn(respond_once(
+            hook,
+            format!(
+                "HTTP/1.1 302 Found\r\nLocation: http://{internal_addr}/admin\r\nContent-Length: 0\r\nConnection: close\r\n\r\n"
+            ),
+        ));
+
+        // Private addresses are allowed so the hook itself is reachable
+        let callbacks = callbacks(CallbacksConfig {
+            allow_private_addresses: true,
+            ..CallbacksConfig::default()
+        });
+        let inner = callbacks.inner.as_ref().unwrap();
+        let result = inner.post(&format!("http://{hook_addr}/hook"), b"{}").await;
+        assert_eq!(result.unwrap_err(), "callback URL returned 302 Found");
+        assert!(!connected(&internal).await);
+    }
+
+    #[tokio::test]
+    async fn deliveries_refuse_hosts_resolving_to_private_addresses() {
+        let listener = TcpListener::bind("127.0.0.1:0").await.unwrap();
+        let port = listener.local_addr().unwrap().port();
+
+        // `localhost` passes no check here, as if it had resolved publicly
+        // when the URL was accepted and been rebound since
+        let callbacks = callbacks(CallbacksConfig::default());
+        let inner = callbacks.inner.as_ref().unwrap();
+        let result = inner
+            .post(&format!("http://localhost:{port}/hook"), b"{}")
+            .await;
+        assert!(result.is_err());
+        assert!(!connected(&listener).await);
+    }
+
+    #[tokio::test]
+    async fn refuses_non_public_addresses() {
+        let callbacks = callbacks(CallbacksConfig::default());
+        for url in [
+            "http://127.0.0.1/hook",
+            "http://localhost:8080/hook",
+            "http://10.0.0.5/hook",
+            "http://169.254.169.254/latest/meta-data",
+            "http://[::1]/hook",
+            "http://[::ffff:192.168.1.1]/hook",
+            "http://[fd00::1]/hook",
+        ] {
+            assert!(callbacks.check_url(url).await.is_err(), "{url}");
+        }
+        assert!(
+            callbacks
+                .check_url("https://93.184.215.14/hook")
+                .await
+                .is_ok()
+        );
+        assert!(
+            callbacks
+                .check_url("ftp://93.184.215.14/hook")
+                .await
+                .is_err()
+        );
+
+        let private = callbacks(CallbacksConfig {
+            allow_private_addresses: true,
+            ..CallbacksConfig::default()
+        });
+        assert!(private.check_url("http://127.0.0.1/hook").await.is_ok());
+    }
+
+    #[tokio::test]
+    async fn enforces_allowed_hosts_and_https() {
+        let callbacks = callbacks(CallbacksConfig {
+            allowed_hosts: vec!["hooks.example.com".to_string(), "*.Example.org".to_string()],
+            require_https: true,
+            allow_private_addresses: true,
+            ..CallbacksConfig::default()
+        });
+        assert!(
+            callbacks
+                .check_url("https://hooks.example.com/runs")
+                .await
+                .is_ok()
+        );
+        assert!(
+            callbacks
+                .check_url("https://a.b.example.org/runs")
+                .await
+                .is_ok()
+        );
+        assert!(
+            callbacks
+                .check_url("http://hooks.example.com/runs")
+                .await
+                .is_err()
+        );
+        assert!(
+            callbacks
+                .check_url("https://example.org/runs")
+                .await
+                .is_err()
+        );
+        assert!(callbacks.check_url("https://evil.com/runs").await.is_err());
+        assert!(
+            callbacks
+                .check_url("https://notexample.org/runs")
+                .await
+                .is_err()
+        );
+    }
+
+    #[tokio::test]
+    async fn disabled_callbacks_refuse_urls() {
+        let result = Callbacks::default().check_url("https://example.com/").await;
+        assert_eq!(
+            result.unwrap_err(),
+            "callbacks are not enabled on this server"
+        );
+    }
+}
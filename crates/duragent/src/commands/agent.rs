@@ -4,9 +4,12 @@ use std::path::{Path, PathBuf};
 
 use anyhow::{Context, Result, bail};
 
+use duragent::agent::ChangeSource;
 use duragent::config::{self, Config, DEFAULT_AGENTS_DIR, DEFAULT_WORKSPACE};
 use duragent::launcher::{LaunchOptions, ensure_server_running};
+use duragent::store::file::{ChangeAuthor, FileAgentCatalog};
 
+use super::bundle::{self, SignatureStatus};
 use super::init::{
     DEFAULT_MODEL, DEFAULT_PROVIDER, create_agent_files, credential_hint, print_file_summary,
     prompt_with_default,
@@ -41,7 +44,11 @@ pub async fn list(
 
     for agent in &agents {
         let desc = agent.description.as_deref().unwrap_or("");
-        println!("{:<20} {}", agent.name, desc);
+        if agent.enabled {
+            println!("{:<20} {}", agent.name, desc);
+        } else {
+            println!("{:<20} {} (disabled)", agent.name, desc);
+        }
     }
 
     Ok(())
@@ -56,19 +63,8 @@ pub async fn create(
 ) -> Result<()> {
     super::check_workspace(config_path)?;
 
-    // Resolve agents dir from config
-    let config_path_ref = Path::new(config_path);
     let config = Config::load(config_path).await?;
-    let workspace_raw = config
-        .workspace
-        .as_deref()
-        .unwrap_or(Path::new(DEFAULT_WORKSPACE));
-    let workspace = config::resolve_path(config_path_ref, workspace_raw);
-    let agents_dir = config
-        .agents_dir
-        .as_ref()
-        .map(|p| config::resolve_path(config_path_ref, p))
-        .unwrap_or_else(|| workspace.join(DEFAULT_AGENTS_DIR));
+    let agents_dir = resolve_agents_dir(config_path, &config, None);
 
     // Check agent doesn't already exist
     let agent_dir = agents_dir.join(agent_name);
@@ -97,6 +93,7 @@ pub async fn create(
     };
 
     let (created, skipped) = create_agent_files(&agents_dir, agent_name, &provider, &model).await?;
+    record_cli_change(&agents_dir, agent_name).await?;
 
     // Strip to workspace-relative paths for display (same as init)
     let cwd = std::env::current_dir().unwrap_or_default();
@@ -118,6 +115,248 @@ pub async fn create(
     Ok(())
 }
 
+pub async fn export(
+    config_path: &str,
+    agent_name: &str,
+    agents_dir_override: Option<&Path>,
+    output: Option<&Path>,
+    sign_key: Option<&Path>,
+) -> Result<()> {
+    super::check_workspace(config_path)?;
+
+    let config = Config::load(config_path).await?;
+    let agents_dir = resolve_agents_dir(config_path, &config, agents_dir_override);
+    let agent_dir = agents_dir.join(agent_name);
+    if !agent_dir.is_dir() {
+        bail!(
+            "Agent '{}' not found at '{}'.",
+            agent_name,
+            agent_dir.display()
+        );
+    }
+
+    let signing_key = sign_key.map(bundle::load_secret_key).transpose()?;
+
+    let dest = output
+        .map(Path::to_path_buf)
+        .unwrap_or_else(|| bundle::default_bundle_path(agent_name));
+    let name = agent_name.to_string();
+    let dest_clone = dest.clone();
+    let key_clone = signing_key.clone();
+    let manifest = tokio::task::spawn_blocking(move || {
+        bundle::pack(&agent_dir, &name, &dest_clone, key_clone.as_ref())
+    })
+    .await
+    .map_err(|e| anyhow::anyhow!("export task failed: {}", e))??;
+
+    println!(
+        "Exported agent '{}' ({} files) to {}",
+        agent_name,
+        manifest.files.len(),
+        dest.display()
+    );
+    if let Some(key) = &signing_key {
+        println!("  Signed with key {}", bundle::key_id(&key.verifying_key()));
+    }
+
+    Ok(())
+}
+
+pub async fn import(
+    config_path: &str,
+    bundle_path: &Path,
+    name_override: Option<&str>,
+    agents_dir_override: Option<&Path>,
+    force: bool,
+    trusted_keys: &[PathBuf],
+    require_signature: bool,
+) -> Result<()> {
+    super::check_workspace(config_path)?;
+
+    let config = Config::load(config_path).await?;
+    let agents_dir = resolve_agents_dir(config_path, &config, agents_dir_override);
+
+    // Trusted keys from the CLI are used as-is; keys from config are relative
+    // to the config file.
+    let config_path_ref = Path::new(config_path);
+    let key_paths = trusted_keys.iter().cloned().chain(
+        config
+            .bundles
+            .trusted_keys
+            .iter()
+            .map(|p| config::resolve_path(config_path_ref, p)),
+    );
+    let keys = key_paths
+        .map(|p| bundle::load_public_key(&p))
+        .collect::<Result<Vec<_>>>()?;
+    let require_signature = require_signature || config.bundles.require_signature;
+
+    let path = bundle_path.to_path_buf();
+    let verified = tokio::task::spawn_blocking(move || bundle::read_verified(&path))
+        .await
+        .map_err(|e| anyhow::anyhow!("import task failed: {}", e))??;
+
+    match verified.check_signature(&keys)? {
+        SignatureStatus::Verified { key_id } => {
+            println!("Signature verified (key {key_id})");
+        }
+        SignatureStatus::Unsigned if require_signature => {
+            bail!("Bundle is not signed and a signature is required");
+        }
+        SignatureStatus::Unverified if require_signature => {
+            bail!(
+                "Bundle is signed but no trusted keys are configured.\n\
+                 Pass --trusted-key or set bundles.trusted_keys in the config."
+            );
+        }
+        SignatureStatus::Unsigned => {
+            eprintln!("warning: bundle is not signed");
+        }
+        SignatureStatus::Unverified => {
+            eprintln!("warning: bundle signature was not verified (no trusted keys configured)");
+        }
+    }
+
+    let agent_name = name_override.unwrap_or(&verified.manifest.metadata.name);
+    validate_agent_name(agent_name)?;
+
+    let agent_dir = agents_dir.join(agent_name);
+    if agent_dir.exists() {
+        if !force {
+            bail!(
+                "Agent '{}' already exists at '{}'.\n\
+                 Use --force to replace it or --name to import under a different name.",
+                agent_name,
+                agent_dir.display()
+            );
+        }
+        tokio::fs::remove_dir_all(&agent_dir)
+            .await
+            .with_context(|| format!("Failed to remove {}", agent_dir.display()))?;
+    }
+
+    let agent_name = agent_name.to_string();
+    let file_count = verified.files.len();
+    let dest = agent_dir.clone();
+    tokio::task::spawn_blocking(move || bundle::write_files(&verified, &dest))
+        .await
+        .map_err(|e| anyhow::anyhow!("import task failed: {}", e))??;
+    record_cli_change(&agents_dir, &agent_name).await?;
+
+    println!(
+        "Imported agent '{}' ({} files) into {}",
+        agent_name,
+        file_count,
+        agent_dir.display()
+    );
+    println!("  Run `duragent serve reload-agents` to load it on a running server.");
+
+    Ok(())
+}
+
+pub async fn keygen(output: &Path, force: bool) -> Result<()> {
+    let secret_path = output.with_extension("key");
+    let public_path = output.with_extension("pub");
+
+    if !force && (secret_path.exists() || public_path.exists()) {
+        bail!(
+            "Key files '{}' or '{}' already exist. Use --force to overwrite.",
+            secret_path.display(),
+            public_path.display()
+        );
+    }
+
+    let key = bundle::generate_signing_key();
+    write_secret_file(&secret_path, &bundle::encode_secret_key(&key))?;
+    tokio::fs::write(
+        &public_path,
+        bundle::encode_public_key(&key.verifying_key()),
+    )
+    .await
+    .with_context(|| format!("Failed to write {}", public_path.display()))?;
+
+    println!(
+        "Generated signing key {}",
+        bundle::key_id(&key.verifying_key())
+    );
+    println!("  Secret key: {} (keep private)", secret_path.display());
+    println!("  Public key: {}", public_path.display());
+
+    Ok(())
+}
+
+// ============================================================================
+// Private Helpers
+// ============================================================================
+
+/// Resolve the agents directory from the CLI override or the config file.
+/// Record the local user as the author of an agent written by the CLI.
+async fn record_cli_change(agents_dir: &Path, agent_name: &str) -> Result<()> {
+    let principal = std::env::var("USER")
+        .ok()
+        .filter(|u| !u.is_empty())
+        .unwrap_or_else(|| "cli".to_string());
+    let author = ChangeAuthor {
+        source: ChangeSource::Cli,
+        principal,
+    };
+    FileAgentCatalog::new(agents_dir, None)
+        .record_change(agent_name, &author)
+        .await
+        .with_context(|| format!("Failed to record provenance for agent '{agent_name}'"))
+}
+
+fn resolve_agents_dir(config_path: &str, config: &Config, override_dir: Option<&Path>) -> PathBuf {
+    if let Some(dir) = override_dir {
+        return dir.to_path_buf();
+    }
+
+    let config_path_ref = Path::new(config_path);
+    let workspace_raw = config
+        .workspace
+        .as_deref()
+        .unwrap_or(Path::new(DEFAULT_WORKSPACE));
+    let workspace = config::resolve_path(config_path_ref, workspace_raw);
+    config
+        .agents_dir
+        .as_ref()
+        .map(|p| config::resolve_path(config_path_ref, p))
+        .unwrap_or_else(|| workspace.join(DEFAULT_AGENTS_DIR))
+}
+
+/// Write a file readable only by the current user.
+fn write_secret_file(path: &Path, contents: &str) -> Result<()> {
+    #[cfg(unix)]
+    {
+        use std::io::Write;
+        use std::os::unix::fs::OpenOptionsExt;
+        let mut file = std::fs::OpenOptions::new()
+            .write(true)
+            .create(true)
+            .truncate(true)
+            .mode(0o600)
+            .open(path)
+            .with_context(|| format!("Failed to write {}", path.display()))?;
+        file.write_all(contents.as_bytes())
+            .with_context(|| format!("Failed to write {}", path.display()))?;
+    }
+    #[cfg(not(unix))]
+    {
+        std::fs::write(path, contents)
+            .with_context(|| format!("Failed to write {}", path.display()))?;
+    }
+    Ok(())
+}
+
+/// Reject agent names that would escape the agents directory.
+fn validate_agent_name(name: &str) -> Result<()> {
+    let valid = !name.is_empty() && name != "." && name != ".." && !name.contains(['/', '\\']);
+    if !valid {
+        bail!("Invalid agent name '{name}'");
+    }
+    Ok(())
+}
+
 // ============================================================================
 // Tests
 // ============================================================================
@@ -169,6 +408,9 @@ mod tests {
         assert!(agent.contains("name: new-bot"));
         assert!(agent.contains("provider: openrouter"));
         assert!(agent.contains("name: anthropic/claude-sonnet-4"));
+
+        let provenance = std::fs::read_to_string(agent_dir.join(".provenance.yaml")).unwrap();
+        assert!(provenance.contains("source: cli"));
     }
 
     #[tokio::test]
@@ -274,4 +516,161 @@ mod tests {
             std::fs::read_to_string(root.join(".duragent/agents/match-bot/agent.yaml")).unwrap();
         assert_eq!(actual, expected);
     }
+
+    #[tokio::test]
+    async fn test_export_import_roundtrip() {
+        let tmp = TempDir::new().unwrap();
+        let root = tmp.path();
+        setup_workspace(root).await;
+
+        let config_path = root.join("duragent.yaml");
+        let config = config_path.to_str().unwrap();
+        create(
+            config,
+            "src-bot",
+            Some("openrouter".to_string()),
+            Some("model".to_string()),
+            true,
+        )
+        .await
+        .unwrap();
+
+        let bundle_path = root.join("src-bot.agent.tar.gz");
+        export(config, "src-bot", None, Some(&bundle_path), None)
+            .await
+            .unwrap();
+        assert!(bundle_path.exists());
+
+        import(
+            config,
+            &bundle_path,
+            Some("copy-bot"),
+            None,
+            false,
+            &[],
+            false,
+        )
+        .await
+        .unwrap();
+
+        let agents = root.join(".duragent/agents");
+        let original = std::fs::read_to_string(agents.join("src-bot/agent.yaml")).unwrap();
+        let copied = std::fs::read_to_string(agents.join("copy-bot/agent.yaml")).unwrap();
+        assert_eq!(original, copied);
+        assert!(agents.join("copy-bot/SOUL.md").exists());
+    }
+
+    #[tokio::test]
+    async fn test_import_existing_requires_force() {
+        let tmp = TempDir::new().unwrap();
+        let root = tmp.path();
+        setup_workspace(root).await;
+
+        let config_path = root.join("duragent.yaml");
+        let config = config_path.to_str().unwrap();
+        create(
+            config,
+            "bot",
+            Some("openrouter".to_string()),
+            Some("model".to_string()),
+            true,
+        )
+        .await
+        .unwrap();
+
+        let bundle_path = root.join("bot.agent.tar.gz");
+        export(config, "bot", None, Some(&bundle_path), None)
+            .await
+            .unwrap();
+
+        let err = import(config, &bundle_path, None, None, false, &[], false)
+            .await
+            .unwrap_err();
+        assert!(err.to_string().contains("already exists"));
+
+        import(config, &bundle_path, None, None, true, &[], false)
+            .await
+            .unwrap();
+    }
+
+    #[tokio::test]
+    async fn test_export_missing_agent() {
+        let tmp = TempDir::new().unwrap();
+        let root = tmp.path();
+        setup_workspace(root).await;
+
+        let config_path = root.join("duragent.yaml");
+        let err = export(config_path.to_str().unwrap(), "ghost", None, None, None)
+            .await
+            .unwrap_err();
+        assert!(err.to_string().contains("not found"));
+    }
+
+    #[tokio::test]
+    async fn test_import_require_signature() {
+        let tmp = TempDir::new().unwrap();
+        let root = tmp.path();
+        setup_workspace(root).await;
+
+        let config_path = root.join("duragent.yaml");
+        let config = config_path.to_str().unwrap();
+        create(
+            config,
+            "bot",
+            Some("openrouter".to_string()),
+            Some("model".to_string()),
+            true,
+        )
+        .await
+        .unwrap();
+
+        let key_base = root.join("release");
+        keygen(&key_base, false).await.unwrap();
+        let secret = root.join("release.key");
+        let public = root.join("release.pub");
+
+        let unsigned = root.join("unsigned.agent.tar.gz");
+        export(config, "bot", None, Some(&unsigned), None)
+            .await
+            .unwrap();
+        let err = import(config, &unsigned, Some("b1"), None, false, &[], true)
+            .await
+            .unwrap_err();
+        assert!(err.to_string().contains("not signed"));
+
+        let signed = root.join("signed.agent.tar.gz");
+        export(config, "bot", None, Some(&signed), Some(&secret))
+            .await
+            .unwrap();
+        import(
+            config,
+            &signed,
+            Some("b2"),
+            None,
+            false,
+            std::slice::from_ref(&public),
+            true,
+        )
+        .await
+        .unwrap();
+        assert!(root.join(".duragent/agents/b2/agent.yaml").exists());
+    }
+
+    #[tokio::test]
+    async fn test_keygen_refuses_overwrite() {
+        let tmp = TempDir::new().unwrap();
+        let key_base = tmp.path().join("k");
+        keygen(&key_base, false).await.unwrap();
+        let err = keygen(&key_base, false).await.unwrap_err();
+        assert!(err.to_string().contains("already exist"));
+        keygen(&key_base, true).await.unwrap();
+    }
+
+    #[test]
+    fn test_validate_agent_name() {
+        assert!(validate_agent_name("bot").is_ok());
+        assert!(validate_agent_name("..").is_err());
+        assert!(validate_agent_name("a/b").is_err());
+        assert!(validate_agent_name("").is_err());
+    }
 }
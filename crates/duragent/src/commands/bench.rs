@@ -0,0 +1,338 @@
+//! `duragent bench` — synthetic load generator for capacity planning.
+//!
+//! Opens one session per worker against a running server and sends messages
+//! until the request budget is spent, then reports throughput and latency
+//! percentiles. Point it at an agent using `provider: mock` so the numbers
+//! measure the server, not the LLM provider.
+
+use std::path::Path;
+use std::sync::Arc;
+use std::sync::atomic::{AtomicUsize, Ordering};
+use std::time::{Duration, Instant};
+
+use anyhow::{Context, Result, bail};
+use futures::StreamExt;
+use serde::Serialize;
+use tokio::task::JoinSet;
+
+use duragent::client::{AgentClient, ClientStreamEvent};
+use duragent::config::Config;
+use duragent::launcher::{LaunchOptions, ensure_server_running};
+
+pub struct BenchOpts<'a> {
+    pub agent: &'a str,
+    pub config_path: &'a str,
+    pub agents_dir: Option<&'a Path>,
+    pub server_url: Option<&'a str>,
+    pub concurrency: usize,
+    pub requests: usize,
+    pub payload_bytes: usize,
+    pub stream: bool,
+    pub format: &'a str,
+}
+
+// ============================================================================
+// Output Types
+// ============================================================================
+
+#[derive(Debug, Serialize)]
+struct BenchReport {
+    agent: String,
+    concurrency: usize,
+    payload_bytes: usize,
+    stream: bool,
+    requests: usize,
+    failed: usize,
+    elapsed_ms: f64,
+    throughput_rps: f64,
+    latency_ms: Percentiles,
+    /// Time to first token (streaming only).
+    #[serde(skip_serializing_if = "Option::is_none")]
+    first_token_ms: Option<Percentiles>,
+    /// First few distinct errors, for diagnosis.
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    errors: Vec<String>,
+}
+
+#[derive(Debug, Default, Serialize)]
+struct Percentiles {
+    p50: f64,
+    p90: f64,
+    p99: f64,
+    max: f64,
+}
+
+impl Percentiles {
+    fn from_samples(mut samples: Vec<Duration>) -> Self {
+        if samples.is_empty() {
+            return Self::default();
+        }
+        samples.sort_unstable();
+        let at = |p: usize| ms(samples[(samples.len() * p).div_ceil(100).max(1) - 1]);
+        Self {
+            p50: at(50),
+            p90: at(90),
+            p99: at(99),
+            max: ms(samples[samples.len() - 1]),
+        }
+    }
+}
+
+/// Outcome of one worker.
+#[derive(Default)]
+struct WorkerStats {
+    latencies: Vec<Duration>,
+    first_tokens: Vec<Duration>,
+    failed: usize,
+    errors: Vec<String>,
+}
+
+/// Distinct error messages kept per worker.
+const MAX_ERRORS: usize = 5;
+
+// ============================================================================
+// Entry Point
+// ============================================================================
+
+pub async fn run(opts: BenchOpts<'_>) -> Result<()> {
+    if opts.concurrency == 0 || opts.requests == 0 {
+        bail!("--concurrency and --requests must be at least 1");
+    }
+
+    super::check_workspace(opts.config_path)?;
+    let config = Config::load(opts.config_path).await?;
+
+    let client = ensure_server_running(LaunchOptions {
+        server_url: opts.server_url,
+        config_path: Path::new(opts.config_path),
+        config: &config,
+        agents_dir: opts.agents_dir,
+    })
+    .await
+    .context("Failed to connect to server")?;
+
+    let agent = client
+        .get_agent(opts.agent)
+        .await
+        .with_context(|| format!("Failed to get agent '{}'", opts.agent))?;
+    if agent.spec.model.provider != "mock" {
+        eprintln!(
+            "warning: agent '{}' uses provider '{}'; results include provider latency and cost",
+            opts.agent, agent.spec.model.provider
+        );
+    }
+
+    // One session per worker, created up front so setup isn't timed
+    let mut sessions = Vec::with_capacity(opts.concurrency);
+    for _ in 0..opts.concurrency {
+        let session = client
+            .create_session(opts.agent)
+            .await
+            .context("Failed to create session")?;
+        sessions.push(session.session_id);
+    }
+
+    let payload = Arc::new(payload(opts.payload_bytes));
+    let issued = Arc::new(AtomicUsize::new(0));
+    let started = Instant::now();
+
+    let mut workers = JoinSet::new();
+    for session_id in sessions.iter().cloned() {
+        let client = client.clone();
+        let payload = Arc::clone(&payload);
+        let issued = Arc::clone(&issued);
+        let (total, stream) = (opts.requests, opts.stream);
+        workers.spawn(async move {
+            let mut stats = WorkerStats::default();
+            while issued.fetch_add(1, Ordering::Relaxed) < total {
+                let result = if stream {
+                    stream_once(&client, &session_id, &payload).await
+                } else {
+                    send_once(&client, &session_id, &payload).await
+                };
+                match result {
+                    Ok((latency, first_token)) => {
+                        stats.latencies.push(latency);
+                        stats.first_tokens.extend(first_token);
+                    }
+                    Err(e) => {
+                        stats.failed += 1;
+                        let message = format!("{e:#}");
+                        if stats.errors.len() < MAX_ERRORS && !stats.errors.contains(&message) {
+                            stats.errors.push(message);
+                        }
+                    }
+                }
+            }
+            stats
+        });
+    }
+
+    let mut all = WorkerStats::default();
+    while let Some(result) = workers.join_next().await {
+        let stats = result.context("bench worker panicked")?;
+        all.latencies.extend(stats.latencies);
+        all.first_tokens.extend(stats.first_tokens);
+        all.failed += stats.failed;
+        for e in stats.errors {
+            if all.errors.len() < MAX_ERRORS && !all.errors.contains(&e) {
+                all.errors.push(e);
+            }
+        }
+    }
+    let elapsed = started.elapsed();
+
+    for session_id in &sessions {
+        if let Err(e) = client.delete_session(session_id).await {
+            eprintln!("warning: failed to delete session {session_id}: {e}");
+        }
+    }
+
+    let report = BenchReport {
+        agent: opts.agent.to_string(),
+        concurrency: opts.concurrency,
+        payload_bytes: opts.payload_bytes,
+        stream: opts.stream,
+        requests: opts.requests,
+        failed: all.failed,
+        elapsed_ms: ms(elapsed),
+        throughput_rps: all.latencies.len() as f64 / elapsed.as_secs_f64(),
+        latency_ms: Percentiles::from_samples(all.latencies),
+        first_token_ms: opts
+            .stream
+            .then(|| Percentiles::from_samples(all.first_tokens)),
+        errors: all.errors,
+    };
+    report.render(opts.format)
+}
+
+// ============================================================================
+// Requests
+// ============================================================================
+
+async fn send_once(
+    client: &AgentClient,
+    session_id: &str,
+    payload: &str,
+) -> Result<(Duration, Option<Duration>)> {
+    let started = Instant::now();
+    client.send_message(session_id, payload).await?;
+    Ok((started.elapsed(), None))
+}
+
+async fn stream_once(
+    client: &AgentClient,
+    session_id: &str,
+    payload: &str,
+) -> Result<(Duration, Option<Duration>)> {
+    let started = Instant::now();
+    let mut first_token = None;
+    let mut stream = std::pin::pin!(client.stream_message(session_id, payload).await?);
+    while let Some(event) = stream.next().await {
+        match event? {
+            ClientStreamEvent::Token { .. } => {
+                first_token.get_or_insert_with(|| started.elapsed());
+            }
+            ClientStreamEvent::Done { .. } => return Ok((started.elapsed(), first_token)),
+            ClientStreamEvent::Error { message } => bail!("stream error: {message}"),
+            ClientStreamEvent::Cancelled => bail!("stream cancelled"),
+            ClientStreamEvent::ApprovalRequired { .. } => {
+                bail!("agent requested tool approval; use an agent without approval-gated tools")
+            }
+            ClientStreamEvent::Start | ClientStreamEvent::Reasoning { .. } => {}
+        }
+    }
+    bail!("stream ended without done event")
+}
+
+/// Build a message of exactly `bytes` ASCII bytes.
+fn payload(bytes: usize) -> String {
+    "lorem ipsum dolor sit amet "
+        .chars()
+        .cycle()
+        .take(bytes.max(1))
+        .collect()
+}
+
+fn ms(d: Duration) -> f64 {
+    d.as_secs_f64() * 1000.0
+}
+
+// ============================================================================
+// Rendering
+// ============================================================================
+
+impl BenchReport {
+    fn render(&self, format: &str) -> Result<()> {
+        match format {
+            "json" => {
+                println!("{}", serde_json::to_string_pretty(self)?);
+            }
+            _ => self.render_text(),
+        }
+        Ok(())
+    }
+
+    fn render_text(&self) {
+        println!("Duragent Bench");
+        println!("{}", "=".repeat(50));
+        println!("Agent:        {}", self.agent);
+        println!("Concurrency:  {}", self.concurrency);
+        println!("Payload:      {} bytes", self.payload_bytes);
+        println!("Streaming:    {}", if self.stream { "on" } else { "off" });
+        println!();
+        println!("Requests:     {} ({} failed)", self.requests, self.failed);
+        println!("Elapsed:      {:.2}s", self.elapsed_ms / 1000.0);
+        println!("Throughput:   {:.1} req/s", self.throughput_rps);
+        println!();
+        println!(
+            "{:<16} {:>9} {:>9} {:>9} {:>9}",
+            "Latency (ms)", "p50", "p90", "p99", "max"
+        );
+        print_row("request", &self.latency_ms);
+        if let Some(first_token) = &self.first_token_ms {
+            print_row("first token", first_token);
+        }
+
+        if !self.errors.is_empty() {
+            println!();
+            println!("Errors:");
+            for e in &self.errors {
+                println!("  {e}");
+            }
+        }
+    }
+}
+
+fn print_row(label: &str, p: &Percentiles) {
+    println!(
+        "{:<16} {:>9.1} {:>9.1} {:>9.1} {:>9.1}",
+        label, p.p50, p.p90, p.p99, p.max
+    );
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn payload_has_requested_size() {
+        assert_eq!(payload(0).len(), 1);
+        assert_eq!(payload(5), "lorem");
+        assert_eq!(payload(4096).len(), 4096);
+    }
+
+    #[test]
+    fn percentiles_pick_nearest_rank() {
+        let samples = (1..=100).map(Duration::from_millis).collect();
+        let p = Percentiles::from_samples(samples);
+        assert_eq!(p.p50, 50.0);
+        assert_eq!(p.p90, 90.0);
+        assert_eq!(p.p99, 99.0);
+        assert_eq!(p.max, 100.0);
+
+        let p = Percentiles::from_samples(vec![Duration::from_millis(7)]);
+        assert_eq!((p.p50, p.p99, p.max), (7.0, 7.0, 7.0));
+        assert_eq!(Percentiles::from_samples(Vec::new()).max, 0.0);
+    }
+}
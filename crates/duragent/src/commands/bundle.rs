@@ -0,0 +1,636 @@
+//! Agent bundle packing and unpacking.
+//!
+//! A bundle is a gzipped tarball holding a single agent directory plus a
+//! `MANIFEST.yaml` at the archive root. The manifest lists every packed file
+//! with its SHA-256 digest so the importing side can detect corruption or
+//! tampering before anything is written to the agents directory.
+//!
+//! Bundles may optionally be signed with an Ed25519 key. The signature covers
+//! the raw manifest bytes, which in turn pin every file by checksum, and is
+//! stored as `MANIFEST.yaml.sig` next to the manifest.
+//!
+//! ```text
+//! my-agent.agent.tar.gz
+//! ├── MANIFEST.yaml
+//! ├── MANIFEST.yaml.sig   (optional)
+//! └── agent/
+//!     ├── agent.yaml
+//!     ├── policy.yaml
+//!     ├── SOUL.md
+//!     └── skills/...
+//! ```
+
+use std::collections::BTreeMap;
+use std::io::Read;
+use std::path::{Component, Path, PathBuf};
+
+use anyhow::{Context, Result, bail};
+use base64::Engine;
+use base64::engine::general_purpose::STANDARD;
+use ed25519_dalek::{Signature, Signer, SigningKey, Verifier, VerifyingKey};
+use serde::{Deserialize, Serialize};
+use sha2::{Digest, Sha256};
+
+use duragent::agent::API_VERSION_V1ALPHA1;
+use duragent::build_info;
+use duragent::store::file::{DISABLED_FILE, PROVENANCE_FILE};
+
+/// Kind value written into bundle manifests.
+pub const KIND_AGENT_BUNDLE: &str = "AgentBundle";
+
+/// File name of the manifest at the archive root.
+pub const MANIFEST_FILE: &str = "MANIFEST.yaml";
+
+/// File name of the detached manifest signature at the archive root.
+pub const SIGNATURE_FILE: &str = "MANIFEST.yaml.sig";
+
+/// Directory inside the archive that holds the agent files.
+const AGENT_PREFIX: &str = "agent";
+
+/// Default file extension for exported bundles.
+pub const BUNDLE_EXTENSION: &str = "agent.tar.gz";
+
+// ============================================================================
+// Manifest
+// ============================================================================
+
+/// Bundle manifest describing the packed agent and its file checksums.
+#[derive(Debug, Clone, Serialize, Deserialize, PartialEq)]
+#[serde(rename_all = "camelCase")]
+pub struct BundleManifest {
+    pub api_version: String,
+    pub kind: String,
+    pub metadata: BundleMetadata,
+    /// SHA-256 hex digests keyed by path relative to the agent directory.
+    pub files: BTreeMap<String, String>,
+}
+
+#[derive(Debug, Clone, Serialize, Deserialize, PartialEq)]
+#[serde(rename_all = "camelCase")]
+pub struct BundleMetadata {
+    /// Agent name at export time.
+    pub name: String,
+    /// Duragent version that produced the bundle.
+    pub duragent_version: String,
+    /// RFC 3339 export timestamp.
+    pub exported_at: String,
+}
+
+/// A bundle that has been read into memory and verified against its manifest.
+#[derive(Debug)]
+pub struct VerifiedBundle {
+    pub manifest: BundleManifest,
+    /// Raw manifest bytes as stored in the archive (the signed payload).
+    pub manifest_raw: Vec<u8>,
+    /// Manifest signature, if the bundle was signed.
+    pub signature: Option<Signature>,
+    /// File contents keyed by path relative to the agent directory.
+    pub files: BTreeMap<String, Vec<u8>>,
+}
+
+// ============================================================================
+// Packing
+// ============================================================================
+
+/// Pack an agent directory into a bundle at `dest`.
+///
+/// Every regular file under `agent_dir` is included except the provenance
+/// record and the disabled marker, which describe this copy. Symlinks are
+/// skipped so a bundle never carries content from outside the agent
+/// directory. When `signing_key` is given, the manifest is signed and the
+/// signature stored in the archive.
+pub fn pack(
+    agent_dir: &Path,
+    agent_name: &str,
+    dest: &Path,
+    signing_key: Option<&SigningKey>,
+) -> Result<BundleManifest> {
+    if !agent_dir.join("agent.yaml").is_file() {
+        bail!("'{}' does not contain an agent.yaml", agent_dir.display());
+    }
+
+    let mut files = BTreeMap::new();
+    collect_files(agent_dir, agent_dir, &mut files)?;
+
+    let manifest = BundleManifest {
+        api_version: API_VERSION_V1ALPHA1.to_string(),
+        kind: KIND_AGENT_BUNDLE.to_string(),
+        metadata: BundleMetadata {
+            name: agent_name.to_string(),
+            duragent_version: build_info::VERSION.to_string(),
+            exported_at: chrono::Utc::now().to_rfc3339(),
+        },
+        files: files
+            .iter()
+            .map(|(path, data)| (path.clone(), sha256_hex(data)))
+            .collect(),
+    };
+    let manifest_yaml =
+        serde_saphyr::to_string(&manifest).context("Failed to serialize bundle manifest")?;
+
+    let file = std::fs::File::create(dest)
+        .with_context(|| format!("Failed to create {}", dest.display()))?;
+    let encoder = flate2::write::GzEncoder::new(file, flate2::Compression::default());
+    let mut builder = tar::Builder::new(encoder);
+
+    append_entry(&mut builder, MANIFEST_FILE, manifest_yaml.as_bytes())?;
+    if let Some(key) = signing_key {
+        let signature = key.sign(manifest_yaml.as_bytes());
+        let sig_file = encode_key_file(
+            &format!("signature from key {}", key_id(&key.verifying_key())),
+            &signature.to_bytes(),
+        );
+        append_entry(&mut builder, SIGNATURE_FILE, sig_file.as_bytes())?;
+    }
+    for (path, data) in &files {
+        append_entry(&mut builder, &format!("{AGENT_PREFIX}/{path}"), data)?;
+    }
+
+    builder
+        .into_inner()
+        .and_then(|encoder| encoder.finish())
+        .with_context(|| format!("Failed to write {}", dest.display()))?;
+
+    Ok(manifest)
+}
+
+fn collect_files(root: &Path, dir: &Path, out: &mut BTreeMap<String, Vec<u8>>) -> Result<()> {
+    let entries =
+        std::fs::read_dir(dir).with_context(|| format!("Failed to read {}", dir.display()))?;
+
+    for entry in entries {
+        let entry = entry.with_context(|| format!("Failed to read {}", dir.display()))?;
+        let path = entry.path();
+        let file_type = entry.file_type()?;
+
+        if file_type.is_dir() {
+            collect_files(root, &path, out)?;
+        } else if file_type.is_file() {
+            let rel = path
+                .strip_prefix(root)
+                .expect("walked path is under root")
+                .components()
+                .map(|c| c.as_os_str().to_string_lossy())
+                .collect::<Vec<_>>()
+                .join("/");
+            // Provenance and the disabled marker describe this copy, not the
+            // agent being shipped.
+            if rel == PROVENANCE_FILE || rel == DISABLED_FILE {
+                continue;
+            }
+            let data = std::fs::read(&path)
+                .with_context(|| format!("Failed to read {}", path.display()))?;
+            out.insert(rel, data);
+        }
+    }
+    Ok(())
+}
+
+fn append_entry<W: std::io::Write>(
+    builder: &mut tar::Builder<W>,
+    path: &str,
+    data: &[u8],
+) -> Result<()> {
+    let mut header = tar::Header::new_gnu();
+    header.set_size(data.len() as u64);
+    header.set_mode(0o644);
+    header.set_cksum();
+    builder
+        .append_data(&mut header, path, data)
+        .with_context(|| format!("Failed to add '{path}' to bundle"))
+}
+
+// ============================================================================
+// Unpacking
+// ============================================================================
+
+/// Read a bundle and verify every file against the manifest checksums.
+///
+/// Fails if the manifest is missing, a file is missing or unlisted, a
+/// checksum does not match, or an entry would escape the agent directory.
+pub fn read_verified(bundle_path: &Path) -> Result<VerifiedBundle> {
+    let file = std::fs::File::open(bundle_path)
+        .with_context(|| format!("Failed to open bundle {}", bundle_path.display()))?;
+    let decoder = flate2::read::GzDecoder::new(file);
+    let mut archive = tar::Archive::new(decoder);
+
+    let mut manifest_raw = None;
+    let mut signature_raw = None;
+    let mut files = BTreeMap::new();
+
+    for entry in archive.entries().context("Failed to read bundle entries")? {
+        let mut entry = entry.context("Failed to read bundle entry")?;
+        if !entry.header().entry_type().is_file() {
+            continue;
+        }
+        let path = entry
+            .path()
+            .context("Failed to read entry path")?
+            .into_owned();
+
+        let mut data = Vec::new();
+        entry
+            .read_to_end(&mut data)
+            .with_context(|| format!("Failed to read '{}' from bundle", path.display()))?;
+
+        if path == Path::new(MANIFEST_FILE) {
+            manifest_raw = Some(data);
+        } else if path == Path::new(SIGNATURE_FILE) {
+            signature_raw = Some(data);
+        } else if let Ok(rel) = path.strip_prefix(AGENT_PREFIX) {
+            files.insert(safe_relative_path(rel)?, data);
+        } else {
+            bail!("Unexpected entry '{}' in bundle", path.display());
+        }
+    }
+
+    let manifest_raw = manifest_raw.context("Bundle is missing MANIFEST.yaml")?;
+    let manifest_str =
+        std::str::from_utf8(&manifest_raw).context("Bundle manifest is not valid UTF-8")?;
+    let manifest: BundleManifest =
+        serde_saphyr::from_str(manifest_str).context("Failed to parse bundle manifest")?;
+
+    if manifest.kind != KIND_AGENT_BUNDLE {
+        bail!(
+            "Unsupported bundle kind '{}' (expected '{}')",
+            manifest.kind,
+            KIND_AGENT_BUNDLE
+        );
+    }
+    if manifest.api_version != API_VERSION_V1ALPHA1 {
+        bail!(
+            "Unsupported bundle apiVersion '{}' (expected '{}')",
+            manifest.api_version,
+            API_VERSION_V1ALPHA1
+        );
+    }
+
+    verify_checksums(&manifest, &files)?;
+
+    let signature = signature_raw
+        .map(|raw| {
+            let content =
+                std::str::from_utf8(&raw).context("Bundle signature is not valid UTF-8")?;
+            let bytes: [u8; 64] = decode_key_file(content, "signature")?;
+            Ok::<_, anyhow::Error>(Signature::from_bytes(&bytes))
+        })
+        .transpose()?;
+
+    Ok(VerifiedBundle {
+        manifest,
+        manifest_raw,
+        signature,
+        files,
+    })
+}
+
+fn verify_checksums(manifest: &BundleManifest, files: &BTreeMap<String, Vec<u8>>) -> Result<()> {
+    for (path, expected) in &manifest.files {
+        let data = files
+            .get(path)
+            .with_context(|| format!("Bundle is missing '{path}' listed in manifest"))?;
+        let actual = sha256_hex(data);
+        if !actual.eq_ignore_ascii_case(expected) {
+            bail!(
+                "Checksum mismatch for '{path}': expected {expected}, got {actual}. \
+                 The bundle may be corrupted or tampered with."
+            );
+        }
+    }
+    if let Some(extra) = files.keys().find(|p| !manifest.files.contains_key(*p)) {
+        bail!("Bundle contains '{extra}' which is not listed in the manifest");
+    }
+    if !files.contains_key("agent.yaml") {
+        bail!("Bundle does not contain an agent.yaml");
+    }
+    Ok(())
+}
+
+// ============================================================================
+// Signing
+// ============================================================================
+
+/// Outcome of checking a bundle signature against a set of trusted keys.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub enum SignatureStatus {
+    /// Bundle carries no signature.
+    Unsigned,
+    /// Signature is valid for the trusted key with this ID.
+    Verified { key_id: String },
+    /// Bundle is signed but no trusted keys were provided to check it.
+    Unverified,
+}
+
+impl VerifiedBundle {
+    /// Check the manifest signature against `trusted_keys`.
+    ///
+    /// Fails if the bundle is signed and trusted keys are given, but none of
+    /// them produced the signature.
+    pub fn check_signature(&self, trusted_keys: &[VerifyingKey]) -> Result<SignatureStatus> {
+        let Some(signature) = &self.signature else {
+            return Ok(SignatureStatus::Unsigned);
+        };
+        if trusted_keys.is_empty() {
+            return Ok(SignatureStatus::Unverified);
+        }
+        for key in trusted_keys {
+            if key.verify(&self.manifest_raw, signature).is_ok() {
+                return Ok(SignatureStatus::Verified {
+                    key_id: key_id(key),
+                });
+            }
+        }
+        bail!("Bundle signature does not match any trusted key")
+    }
+}
+
+/// Generate a new signing key.
+pub fn generate_signing_key() -> SigningKey {
+    SigningKey::from_bytes(&rand::random::<[u8; 32]>())
+}
+
+/// Short identifier for a public key (first 8 bytes of its SHA-256, hex).
+pub fn key_id(key: &VerifyingKey) -> String {
+    sha256_hex(key.as_bytes())[..16].to_string()
+}
+
+/// Encode a secret key file.
+pub fn encode_secret_key(key: &SigningKey) -> String {
+    encode_key_file(
+        &format!("duragent secret key {}", key_id(&key.verifying_key())),
+        &key.to_bytes(),
+    )
+}
+
+/// Encode a public key file.
+pub fn encode_public_key(key: &VerifyingKey) -> String {
+    encode_key_file(
+        &format!("duragent public key {}", key_id(key)),
+        key.as_bytes(),
+    )
+}
+
+/// Load a secret key file written by `duragent agent keygen`.
+pub fn load_secret_key(path: &Path) -> Result<SigningKey> {
+    let content = std::fs::read_to_string(path)
+        .with_context(|| format!("Failed to read secret key {}", path.display()))?;
+    let bytes: [u8; 32] = decode_key_file(&content, "secret key")
+        .with_context(|| format!("Invalid secret key {}", path.display()))?;
+    Ok(SigningKey::from_bytes(&bytes))
+}
+
+/// Load a public key file written by `duragent agent keygen`.
+pub fn load_public_key(path: &Path) -> Result<VerifyingKey> {
+    let content = std::fs::read_to_string(path)
+        .with_context(|| format!("Failed to read public key {}", path.display()))?;
+    let bytes: [u8; 32] = decode_key_file(&content, "public key")
+        .with_context(|| format!("Invalid public key {}", path.display()))?;
+    VerifyingKey::from_bytes(&bytes)
+        .with_context(|| format!("Invalid public key {}", path.display()))
+}
+
+/// Key and signature files use a minisign-like layout: an untrusted comment
+/// line followed by the base64-encoded payload.
+fn encode_key_file(comment: &str, payload: &[u8]) -> String {
+    format!(
+        "untrusted comment: {comment}\n{}\n",
+        STANDARD.encode(payload)
+    )
+}
+
+fn decode_key_file<const N: usize>(content: &str, what: &str) -> Result<[u8; N]> {
+    let encoded = content
+        .lines()
+        .map(str::trim)
+        .find(|line| !line.is_empty() && !line.starts_with("untrusted comment:"))
+        .with_context(|| format!("Missing {what} payload"))?;
+    let bytes = STANDARD
+        .decode(encoded)
+        .with_context(|| format!("Malformed {what} encoding"))?;
+    bytes
+        .try_into()
+        .map_err(|_| anyhow::anyhow!("Malformed {what}: expected {N} bytes"))
+}
+
+// ============================================================================
+// Writing
+// ============================================================================
+
+/// Write verified bundle files into `agent_dir`.
+pub fn write_files(bundle: &VerifiedBundle, agent_dir: &Path) -> Result<()> {
+    for (rel, data) in &bundle.files {
+        let dest = agent_dir.join(rel);
+        if let Some(parent) = dest.parent() {
+            std::fs::create_dir_all(parent)
+                .with_context(|| format!("Failed to create {}", parent.display()))?;
+        }
+        std::fs::write(&dest, data)
+            .with_context(|| format!("Failed to write {}", dest.display()))?;
+    }
+    Ok(())
+}
+
+/// Normalize an archive path, rejecting anything that could escape the
+/// destination directory.
+fn safe_relative_path(path: &Path) -> Result<String> {
+    let mut parts = Vec::new();
+    for component in path.components() {
+        match component {
+            Component::Normal(part) => parts.push(part.to_string_lossy().into_owned()),
+            Component::CurDir => {}
+            _ => bail!("Refusing unsafe path '{}' in bundle", path.display()),
+        }
+    }
+    if parts.is_empty() {
+        bail!("Empty path in bundle");
+    }
+    Ok(parts.join("/"))
+}
+
+/// Default bundle file name for an agent.
+pub fn default_bundle_path(agent_name: &str) -> PathBuf {
+    PathBuf::from(format!("{agent_name}.{BUNDLE_EXTENSION}"))
+}
+
+pub fn sha256_hex(data: &[u8]) -> String {
+    format!("{:x}", Sha256::digest(data))
+}
+
+// ============================================================================
+// Tests
+// ============================================================================
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use tempfile::TempDir;
+
+    fn write_agent(dir: &Path) {
+        std::fs::create_dir_all(dir.join("skills/search")).unwrap();
+        std::fs::write(dir.join("agent.yaml"), "kind: Agent\n").unwrap();
+        std::fs::write(dir.join("SOUL.md"), "Be helpful.\n").unwrap();
+        std::fs::write(dir.join("skills/search/SKILL.md"), "# Search\n").unwrap();
+    }
+
+    fn rewrite_bundle(src: &Path, dest: &Path, mutate: impl Fn(&str, Vec<u8>) -> Vec<u8>) {
+        let file = std::fs::File::open(src).unwrap();
+        let mut archive = tar::Archive::new(flate2::read::GzDecoder::new(file));
+        let out = std::fs::File::create(dest).unwrap();
+        let mut builder = tar::Builder::new(flate2::write::GzEncoder::new(
+            out,
+            flate2::Compression::default(),
+        ));
+        for entry in archive.entries().unwrap() {
+            let mut entry = entry.unwrap();
+            let path = entry.path().unwrap().to_string_lossy().into_owned();
+            let mut data = Vec::new();
+            entry.read_to_end(&mut data).unwrap();
+            append_entry(&mut builder, &path, &mutate(&path, data)).unwrap();
+        }
+        builder.into_inner().unwrap().finish().unwrap();
+    }
+
+    #[test]
+    fn pack_and_read_roundtrip() {
+        let tmp = TempDir::new().unwrap();
+        let agent_dir = tmp.path().join("my-agent");
+        write_agent(&agent_dir);
+        let bundle = tmp.path().join("my-agent.agent.tar.gz");
+
+        let manifest = pack(&agent_dir, "my-agent", &bundle, None).unwrap();
+        assert_eq!(manifest.kind, KIND_AGENT_BUNDLE);
+        assert_eq!(manifest.files.len(), 3);
+        assert!(manifest.files.contains_key("skills/search/SKILL.md"));
+
+        let verified = read_verified(&bundle).unwrap();
+        assert_eq!(verified.manifest, manifest);
+        assert_eq!(verified.files["SOUL.md"], b"Be helpful.\n");
+    }
+
+    #[test]
+    fn pack_skips_local_state_files() {
+        let tmp = TempDir::new().unwrap();
+        let agent_dir = tmp.path().join("my-agent");
+        write_agent(&agent_dir);
+        std::fs::write(agent_dir.join(DISABLED_FILE), "").unwrap();
+        std::fs::write(agent_dir.join(PROVENANCE_FILE), "created_by: admin\n").unwrap();
+        let bundle = tmp.path().join("my-agent.agent.tar.gz");
+
+        let manifest = pack(&agent_dir, "my-agent", &bundle, None).unwrap();
+        assert_eq!(manifest.files.len(), 3);
+        assert!(!manifest.files.contains_key(DISABLED_FILE));
+        assert!(!manifest.files.contains_key(PROVENANCE_FILE));
+    }
+
+    #[test]
+    fn pack_requires_agent_yaml() {
+        let tmp = TempDir::new().unwrap();
+        let bundle = tmp.path().join("x.agent.tar.gz");
+        let err = pack(tmp.path(), "x", &bundle, None).unwrap_err();
+        assert!(err.to_string().contains("agent.yaml"));
+    }
+
+    #[test]
+    fn read_rejects_tampered_file() {
+        let tmp = TempDir::new().unwrap();
+        let agent_dir = tmp.path().join("a");
+        write_agent(&agent_dir);
+        let bundle = tmp.path().join("a.agent.tar.gz");
+        pack(&agent_dir, "a", &bundle, None).unwrap();
+
+        let tampered = tmp.path().join("tampered.agent.tar.gz");
+        rewrite_bundle(&bundle, &tampered, |path, data| {
+            if path == "agent/SOUL.md" {
+                b"Be evil.\n".to_vec()
+            } else {
+                data
+            }
+        });
+
+        let err = read_verified(&tampered).unwrap_err();
+        assert!(err.to_string().contains("Checksum mismatch"));
+    }
+
+    #[test]
+    fn signed_bundle_verifies_with_trusted_key() {
+        let tmp = TempDir::new().unwrap();
+        let agent_dir = tmp.path().join("a");
+        write_agent(&agent_dir);
+        let bundle = tmp.path().join("a.agent.tar.gz");
+        let key = generate_signing_key();
+        pack(&agent_dir, "a", &bundle, Some(&key)).unwrap();
+
+        let verified = read_verified(&bundle).unwrap();
+        let status = verified.check_signature(&[key.verifying_key()]).unwrap();
+        assert_eq!(
+            status,
+            SignatureStatus::Verified {
+                key_id: key_id(&key.verifying_key())
+            }
+        );
+        assert_eq!(
+            verified.check_signature(&[]).unwrap(),
+            SignatureStatus::Unverified
+        );
+    }
+
+    #[test]
+    fn signed_bundle_rejects_untrusted_key() {
+        let tmp = TempDir::new().unwrap();
+        let agent_dir = tmp.path().join("a");
+        write_agent(&agent_dir);
+        let bundle = tmp.path().join("a.agent.tar.gz");
+        pack(&agent_dir, "a", &bundle, Some(&generate_signing_key())).unwrap();
+
+        let other = generate_signing_key().verifying_key();
+        let err = read_verified(&bundle)
+            .unwrap()
+            .check_signature(&[other])
+            .unwrap_err();
+        assert!(err.to_string().contains("does not match"));
+    }
+
+    #[test]
+    fn unsigned_bundle_reports_unsigned() {
+        let tmp = TempDir::new().unwrap();
+        let agent_dir = tmp.path().join("a");
+        write_agent(&agent_dir);
+        let bundle = tmp.path().join("a.agent.tar.gz");
+        pack(&agent_dir, "a", &bundle, None).unwrap();
+
+        let key = generate_signing_key().verifying_key();
+        let status = read_verified(&bundle)
+            .unwrap()
+            .check_signature(&[key])
+            .unwrap();
+        assert_eq!(status, SignatureStatus::Unsigned);
+    }
+
+    #[test]
+    fn key_files_roundtrip() {
+        let tmp = TempDir::new().unwrap();
+        let key = generate_signing_key();
+        let secret_path = tmp.path().join("k.key");
+        let public_path = tmp.path().join("k.pub");
+        std::fs::write(&secret_path, encode_secret_key(&key)).unwrap();
+        std::fs::write(&public_path, encode_public_key(&key.verifying_key())).unwrap();
+
+        assert_eq!(
+            load_secret_key(&secret_path).unwrap().to_bytes(),
+            key.to_bytes()
+        );
+        assert_eq!(load_public_key(&public_path).unwrap(), key.verifying_key());
+        std::fs::write(&public_path, "untrusted comment: x\nnot-base64!\n").unwrap();
+        assert!(load_public_key(&public_path).is_err());
+    }
+
+    #[test]
+    fn safe_relative_path_rejects_traversal() {
+        assert!(safe_relative_path(Path::new("../etc/passwd")).is_err());
+        assert!(safe_relative_path(Path::new("/etc/passwd")).is_err());
+        assert_eq!(
+            safe_relative_path(Path::new("./skills/a.md")).unwrap(),
+            "skills/a.md"
+        );
+    }
+}
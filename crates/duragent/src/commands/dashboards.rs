@@ -0,0 +1,403 @@
+//! `duragent dashboards` command implementations.
+//!
+//! Builds a Grafana dashboard and Prometheus alert rules from the metric
+//! names the server exposes at `/metrics`.
+
+use std::path::Path;
+
+use anyhow::{Context, Result, bail};
+use serde_json::{Value, json};
+
+use duragent::metrics;
+
+const DASHBOARD_FILE: &str = "duragent-dashboard.json";
+const ALERTS_FILE: &str = "duragent-alerts.yaml";
+
+pub async fn export(output_dir: &Path, job: &str, force: bool) -> Result<()> {
+    let dashboard_path = output_dir.join(DASHBOARD_FILE);
+    let alerts_path = output_dir.join(ALERTS_FILE);
+    if !force {
+        for path in [&dashboard_path, &alerts_path] {
+            if path.exists() {
+                bail!(
+                    "{} already exists (use --force to overwrite)",
+                    path.display()
+                );
+            }
+        }
+    }
+
+    tokio::fs::create_dir_all(output_dir)
+        .await
+        .with_context(|| format!("Failed to create {}", output_dir.display()))?;
+
+    let dashboard = serde_json::to_string_pretty(&dashboard(job))?;
+    tokio::fs::write(&dashboard_path, dashboard + "\n")
+        .await
+        .with_context(|| format!("Failed to write {}", dashboard_path.display()))?;
+
+    let alerts =
+        serde_saphyr::to_string(&alert_rules(job)).context("Failed to serialize alert rules")?;
+    tokio::fs::write(&alerts_path, alerts)
+        .await
+        .with_context(|| format!("Failed to write {}", alerts_path.display()))?;
+
+    println!("Wrote {}", dashboard_path.display());
+    println!("Wrote {}", alerts_path.display());
+    println!();
+    println!("Import the dashboard in Grafana (Dashboards > New > Import) and add the");
+    println!("alert rules to `rule_files` in your Prometheus config. Scrape `/metrics`");
+    println!("with job name '{job}', using the admin token as a bearer token if one is set.");
+    Ok(())
+}
+
+// ============================================================================
+// Grafana Dashboard
+// ============================================================================
+
+/// Grafana dashboard with request, run, dependency, and SLO panels.
+pub fn dashboard(job: &str) -> Value {
+    let sel = r#"job=~"$job""#;
+    let panels = vec![
+        panel(
+            1,
+            "Request rate",
+            "timeseries",
+            (0, 0, 12, 8),
+            "reqps",
+            vec![target(
+                format!(
+                    "sum by (route) (rate({}{{{sel}}}[5m]))",
+                    metrics::HTTP_REQUESTS_TOTAL
+                ),
+                "{{route}}",
+            )],
+        ),
+        panel(
+            2,
+            "Server error ratio",
+            "timeseries",
+            (12, 0, 12, 8),
+            "percentunit",
+            vec![target(
+                format!(
+                    r#"sum(rate({m}{{{sel},status=~"5.."}}[5m])) / sum(rate({m}{{{sel}}}[5m]))"#,
+                    m = metrics::HTTP_REQUESTS_TOTAL
+                ),
+                "5xx",
+            )],
+        ),
+        panel(
+            3,
+            "p95 request duration",
+            "timeseries",
+            (0, 8, 12, 8),
+            "s",
+            vec![target(
+                format!(
+                    "histogram_quantile(0.95, sum by (le, route) (rate({}_bucket{{{sel}}}[5m])))",
+                    metrics::HTTP_REQUEST_DURATION_SECONDS
+                ),
+                "{{route}}",
+            )],
+        ),
+        panel(
+            4,
+            "LLM runs",
+            "timeseries",
+            (12, 8, 12, 8),
+            "short",
+            vec![
+                target(
+                    format!("sum({}{{{sel}}})", metrics::RUNS_RUNNING),
+                    "running",
+                ),
+                target(format!("sum({}{{{sel}}})", metrics::RUNS_QUEUED), "queued"),
+            ],
+        ),
+        panel(
+            5,
+            "Active sessions",
+            "stat",
+            (0, 16, 6, 4),
+            "short",
+            vec![target(
+                format!("sum({}{{{sel}}})", metrics::SESSIONS_ACTIVE),
+                "sessions",
+            )],
+        ),
+        panel(
+            6,
+            "Loaded agents",
+            "stat",
+            (6, 16, 6, 4),
+            "short",
+            vec![target(
+                format!("max({}{{{sel}}})", metrics::AGENTS_LOADED),
+                "agents",
+            )],
+        ),
+        panel(
+            7,
+            "Dependencies up",
+            "state-timeline",
+            (12, 16, 12, 8),
+            "bool_on_off",
+            vec![target(
+                format!("max by (dependency) ({}{{{sel}}})", metrics::DEPENDENCY_UP),
+                "{{dependency}}",
+            )],
+        ),
+        panel(
+            8,
+            "Dependency error budget remaining",
+            "timeseries",
+            (0, 20, 12, 8),
+            "percentunit",
+            vec![target(
+                format!(
+                    "min by (dependency) ({}{{{sel}}})",
+                    metrics::DEPENDENCY_ERROR_BUDGET_REMAINING
+                ),
+                "{{dependency}}",
+            )],
+        ),
+        panel(
+            9,
+            "Provider SLO burn rate",
+            "timeseries",
+            (12, 24, 12, 8),
+            "short",
+            vec![
+                target(
+                    format!(
+                        "max by (provider, objective) ({}{{{sel}}})",
+                        metrics::SLO_BURN_RATE
+                    ),
+                    "{{provider}} {{objective}}",
+                ),
+                target(
+                    format!(
+                        "max by (provider, objective) ({}{{{sel}}})",
+                        metrics::SLO_BURN_RATE_THRESHOLD
+                    ),
+                    "{{provider}} {{objective}} threshold",
+                ),
+            ],
+        ),
+    ];
+
+    json!({
+        "title": "Duragent",
+        "uid": "duragent",
+        "tags": ["duragent"],
+        "schemaVersion": 39,
+        "editable": true,
+        "refresh": "30s",
+        "time": {"from": "now-6h", "to": "now"},
+        "templating": {
+            "list": [
+                {
+                    "name": "datasource",
+                    "label": "Data source",
+                    "type": "datasource",
+                    "query": "prometheus",
+                },
+                {
+                    "name": "job",
+                    "label": "Job",
+                    "type": "query",
+                    "datasource": datasource(),
+                    "query": format!("label_values({}, job)", metrics::BUILD_INFO),
+                    "current": {"text": job, "value": job},
+                    "refresh": 1,
+                    "includeAll": false,
+                },
+            ],
+        },
+        "panels": panels,
+    })
+}
+
+fn datasource() -> Value {
+    json!({"type": "prometheus", "uid": "${datasource}"})
+}
+
+fn target(expr: String, legend: &str) -> Value {
+    json!({"expr": expr, "legendFormat": legend})
+}
+
+fn panel(
+    id: u32,
+    title: &str,
+    kind: &str,
+    (x, y, w, h): (u32, u32, u32, u32),
+    unit: &str,
+    targets: Vec<Value>,
+) -> Value {
+    let targets: Vec<Value> = targets
+        .into_iter()
+        .zip('A'..)
+        .map(|(mut target, ref_id)| {
+            target["refId"] = json!(ref_id.to_string());
+            target
+        })
+        .collect();
+    json!({
+        "id": id,
+        "title": title,
+        "type": kind,
+        "datasource": datasource(),
+        "gridPos": {"x": x, "y": y, "w": w, "h": h},
+        "fieldConfig": {"defaults": {"unit": unit}, "overrides": []},
+        "targets": targets,
+    })
+}
+
+// ============================================================================
+// Prometheus Alert Rules
+// ============================================================================
+
+/// Prometheus rule file with alerts on the server's own metrics.
+pub fn alert_rules(job: &str) -> Value {
+    let sel = format!(r#"job="{job}""#);
+    json!({
+        "groups": [{
+            "name": "duragent",
+            "rules": [
+                rule(
+                    "DuragentDown",
+                    format!(r#"up{{{sel}}} == 0"#),
+                    "2m",
+                    "critical",
+                    "Duragent target {{ $labels.instance }} is down",
+                ),
+                rule(
+                    "DuragentHighServerErrorRate",
+                    format!(
+                        r#"sum(rate({m}{{{sel},status=~"5.."}}[5m])) / sum(rate({m}{{{sel}}}[5m])) > 0.05"#,
+                        m = metrics::HTTP_REQUESTS_TOTAL
+                    ),
+                    "10m",
+                    "warning",
+                    "More than 5% of requests are failing with 5xx",
+                ),
+                rule(
+                    "DuragentRunsQueued",
+                    format!("sum({}{{{sel}}}) > 0", metrics::RUNS_QUEUED),
+                    "15m",
+                    "warning",
+                    "LLM runs have been waiting for a run pool permit for 15 minutes",
+                ),
+                rule(
+                    "DuragentProviderAuthFailing",
+                    format!(
+                        r#"sum(increase({}{{{sel},class="auth"}}[10m])) > 0"#,
+                        metrics::RUN_ERRORS_TOTAL
+                    ),
+                    "0m",
+                    "critical",
+                    "Runs are failing because provider credentials are rejected",
+                ),
+                rule(
+                    "DuragentDependencyDown",
+                    format!("{}{{{sel}}} == 0", metrics::DEPENDENCY_UP),
+                    "5m",
+                    "critical",
+                    "Dependency {{ $labels.dependency }} is failing its health checks",
+                ),
+                rule(
+                    "DuragentDependencyErrorBudgetExhausted",
+                    format!(
+                        "{}{{{sel}}} < 0",
+                        metrics::DEPENDENCY_ERROR_BUDGET_REMAINING
+                    ),
+                    "15m",
+                    "warning",
+                    "Dependency {{ $labels.dependency }} has overspent its error budget",
+                ),
+                rule(
+                    "DuragentProviderSloBurning",
+                    format!("{}{{{sel}}} == 1", metrics::SLO_ALERTING),
+                    "5m",
+                    "warning",
+                    "Provider {{ $labels.provider }} is burning its {{ $labels.objective }} error budget",
+                ),
+            ],
+        }],
+    })
+}
+
+fn rule(name: &str, expr: String, r#for: &str, severity: &str, summary: &str) -> Value {
+    json!({
+        "alert": name,
+        "expr": expr,
+        "for": r#for,
+        "labels": {"severity": severity},
+        "annotations": {"summary": summary},
+    })
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn dashboard_queries_use_server_metric_names() {
+        let dashboard = dashboard("duragent");
+        let panels = dashboard["panels"].as_array().unwrap();
+        let exprs: Vec<&str> = panels
+            .iter()
+            .flat_map(|p| p["targets"].as_array().unwrap())
+            .map(|t| t["expr"].as_str().unwrap())
+            .collect();
+
+        for name in [
+            metrics::HTTP_REQUESTS_TOTAL,
+            metrics::HTTP_REQUEST_DURATION_SECONDS,
+            metrics::RUNS_QUEUED,
+            metrics::DEPENDENCY_UP,
+            metrics::SLO_BURN_RATE,
+        ] {
+            assert!(
+                exprs.iter().any(|e| e.contains(name)),
+                "no panel uses {name}"
+            );
+        }
+        assert!(exprs.iter().all(|e| e.contains(r#"job=~"$job""#)));
+        assert_eq!(panels[8]["targets"][1]["refId"], "B");
+    }
+
+    #[test]
+    fn alert_rules_select_the_job() {
+        let rules = alert_rules("agents");
+        let rules = rules["groups"][0]["rules"].as_array().unwrap();
+        assert!(
+            rules
+                .iter()
+                .all(|r| r["expr"].as_str().unwrap().contains(r#"job="agents""#))
+        );
+        assert!(
+            rules
+                .iter()
+                .any(|r| r["alert"] == "DuragentProviderSloBurning")
+        );
+        assert!(rules.iter().any(|r| {
+            r["expr"]
+                .as_str()
+                .unwrap()
+                .contains(metrics::RUN_ERRORS_TOTAL)
+        }));
+    }
+
+    #[tokio::test]
+    async fn export_refuses_to_overwrite() {
+        let tmp = tempfile::TempDir::new().unwrap();
+        export(tmp.path(), "duragent", false).await.unwrap();
+
+        let alerts = std::fs::read_to_string(tmp.path().join(ALERTS_FILE)).unwrap();
+        assert!(alerts.contains("DuragentDependencyDown"));
+        assert!(export(tmp.path(), "duragent", false).await.is_err());
+        export(tmp.path(), "duragent", true).await.unwrap();
+    }
+}
@@ -9,9 +9,13 @@ use serde::Serialize;
 
 use duragent::auth::AuthStorage;
 use duragent::config::{self, Config, ConfigError};
-use duragent::llm::Provider;
+use duragent::faults::FaultInjector;
+use duragent::llm::{AwsCredentials, Provider};
+use duragent::policy::Policies;
+use duragent::slo::ProviderSlos;
 use duragent::store::file::FileAgentCatalog;
 use duragent::store::{AgentCatalog, ScanWarning};
+use duragent::tools::ToolMocks;
 
 // ============================================================================
 // Report Types
@@ -243,6 +247,56 @@ async fn check_config(sections: &mut Vec<Section>, config_path: &str) -> Option<
         });
     }
 
+    // Compile authorization policies
+    if let Err(e) = Policies::from_config(&config.authorization) {
+        checks.push(CheckResult {
+            status: CheckStatus::Error,
+            message: format!("Invalid authorization policy: {e}"),
+        });
+    }
+
+    // Validate provider SLOs
+    if let Err(e) = ProviderSlos::from_config(&config.slo) {
+        checks.push(CheckResult {
+            status: CheckStatus::Error,
+            message: format!("Invalid slo config: {e}"),
+        });
+    }
+
+    // Validate fault rules, and flag fault injection left on
+    match FaultInjector::from_config(&config.faults) {
+        Err(e) => checks.push(CheckResult {
+            status: CheckStatus::Error,
+            message: format!("Invalid faults config: {e}"),
+        }),
+        Ok(faults) if !faults.is_empty() => checks.push(CheckResult {
+            status: CheckStatus::Warn,
+            message: "Fault injection is enabled (faults.enabled); disable it outside development"
+                .to_string(),
+        }),
+        Ok(_) => {}
+    }
+
+    // Validate tool mock fixtures, and flag tool mocking left on
+    let tool_mocks_dir = config
+        .tool_mocks
+        .fixtures_dir
+        .as_ref()
+        .map(|p| config::resolve_path(config_path_ref, p))
+        .unwrap_or_else(|| workspace.join(config::DEFAULT_TOOL_MOCKS_DIR));
+    match ToolMocks::from_config(&config.tool_mocks, &tool_mocks_dir) {
+        Err(e) => checks.push(CheckResult {
+            status: CheckStatus::Error,
+            message: format!("Invalid tool_mocks config: {e}"),
+        }),
+        Ok(mocks) if !mocks.is_empty() => checks.push(CheckResult {
+            status: CheckStatus::Warn,
+            message: "Tool mocking is enabled (tool_mocks.enabled); disable it outside development"
+                .to_string(),
+        }),
+        Ok(_) => {}
+    }
+
     sections.push(Section {
         name: "Configuration".to_string(),
         checks,
@@ -381,7 +435,46 @@ async fn check_provider_credentials(provider: &Provider) -> Option<CheckResult>
                 message: "OpenRouter: OPENROUTER_API_KEY not set".to_string(),
             })
         }
-        Provider::Ollama => None, // No credentials needed
+        Provider::Azure => {
+            if std::env::var("AZURE_OPENAI_API_KEY").is_err() {
+                return Some(CheckResult {
+                    status: CheckStatus::Error,
+                    message: "Azure OpenAI: AZURE_OPENAI_API_KEY not set".to_string(),
+                });
+            }
+            if std::env::var("AZURE_OPENAI_ENDPOINT").is_err() {
+                return Some(CheckResult {
+                    status: CheckStatus::Warn,
+                    message: "Azure OpenAI: AZURE_OPENAI_ENDPOINT not set; agents need a base_url"
+                        .to_string(),
+                });
+            }
+            None
+        }
+        Provider::Bedrock => {
+            if AwsCredentials::from_env().is_some() {
+                return None;
+            }
+            Some(CheckResult {
+                status: CheckStatus::Error,
+                message: "Bedrock: AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY not set".to_string(),
+            })
+        }
+        Provider::Gemini => {
+            if std::env::var("GEMINI_API_KEY").is_ok() || std::env::var("GOOGLE_API_KEY").is_ok() {
+                return None;
+            }
+            Some(CheckResult {
+                status: CheckStatus::Error,
+                message: "Gemini: GEMINI_API_KEY not set".to_string(),
+            })
+        }
+        // No credentials needed
+        Provider::Ollama
+        | Provider::Vllm
+        | Provider::LmStudio
+        | Provider::LlamaCpp
+        | Provider::Mock => None,
         Provider::Other(name) => Some(CheckResult {
             status: CheckStatus::Warn,
             message: format!("Unknown provider '{}': cannot verify credentials", name,),
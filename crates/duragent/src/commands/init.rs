@@ -8,6 +8,7 @@ use duragent::config::{
     DEFAULT_AGENTS_DIR, DEFAULT_ARTIFACTS_DIR, DEFAULT_DIRECTIVES_DIR, DEFAULT_SCHEDULES_DIR,
     DEFAULT_SESSIONS_DIR, DEFAULT_WORKSPACE, DEFAULT_WORLD_MEMORY_DIR,
 };
+use duragent::store::file::Migrator;
 
 // ============================================================================
 // Templates (compiled into binary)
@@ -126,7 +127,15 @@ pub(super) fn credential_hint(provider: &str) -> Option<&'static str> {
         "anthropic" => Some("Run: duragent login anthropic"),
         "openrouter" => Some("Run: export OPENROUTER_API_KEY=your-key"),
         "openai" => Some("Run: export OPENAI_API_KEY=your-key"),
+        "azure" => {
+            Some("Run: export AZURE_OPENAI_API_KEY=your-key AZURE_OPENAI_ENDPOINT=your-endpoint")
+        }
+        "gemini" => Some("Run: export GEMINI_API_KEY=your-key"),
+        "bedrock" => Some("Run: export AWS_ACCESS_KEY_ID=your-id AWS_SECRET_ACCESS_KEY=your-key"),
         "ollama" => Some("Ensure Ollama is running: ollama serve"),
+        "vllm" => Some("Ensure vLLM is running, or set VLLM_BASE_URL"),
+        "lmstudio" => Some("Ensure the LM Studio server is running, or set LMSTUDIO_BASE_URL"),
+        "llamacpp" => Some("Ensure llama-server is running, or set LLAMACPP_BASE_URL"),
         _ => None,
     }
 }
@@ -167,6 +176,11 @@ async fn init_at(root: &Path, agent_name: &str, provider: &str, model: &str) ->
         fs::create_dir_all(dir).await?;
     }
 
+    // Record the store layout version so later upgrades know where to start
+    Migrator::new(&workspace, workspace.join(DEFAULT_SESSIONS_DIR))
+        .up(None)
+        .await?;
+
     let agents_dir = workspace.join(DEFAULT_AGENTS_DIR);
 
     // Write workspace-level files
@@ -0,0 +1,97 @@
+//! Store migration command implementation.
+
+use std::path::Path;
+
+use anyhow::{Context, Result};
+use serde::Serialize;
+
+use duragent::config::{self, Config};
+use duragent::store::file::{MigrationStatus, Migrator};
+
+#[derive(Serialize)]
+struct MigrateResult {
+    current_version: u32,
+    latest_version: u32,
+    /// Migrations applied or reverted by this command (all known ones for `status`).
+    migrations: Vec<MigrationStatus>,
+}
+
+/// Apply pending migrations up to `target` (default: latest).
+pub async fn up(config_path: &str, target: Option<u32>, format: &str) -> Result<()> {
+    let migrator = migrator(config_path).await?;
+    let applied = migrator.up(target).await?;
+    report(&migrator, applied, "Applied", format).await
+}
+
+/// Revert applied migrations down to `target` (default: one step).
+pub async fn down(config_path: &str, target: Option<u32>, format: &str) -> Result<()> {
+    let migrator = migrator(config_path).await?;
+    let reverted = migrator.down(target).await?;
+    report(&migrator, reverted, "Reverted", format).await
+}
+
+/// List every embedded migration and whether it is applied.
+pub async fn status(config_path: &str, format: &str) -> Result<()> {
+    let migrator = migrator(config_path).await?;
+    let migrations = migrator.status().await?;
+    report(&migrator, migrations, "", format).await
+}
+
+async fn report(
+    migrator: &Migrator,
+    migrations: Vec<MigrationStatus>,
+    verb: &str,
+    format: &str,
+) -> Result<()> {
+    let result = MigrateResult {
+        current_version: migrator.current_version().await?,
+        latest_version: Migrator::latest_version(),
+        migrations,
+    };
+
+    if format == "json" {
+        println!("{}", serde_json::to_string_pretty(&result)?);
+        return Ok(());
+    }
+
+    if verb.is_empty() {
+        for m in &result.migrations {
+            let mark = if m.applied { "applied" } else { "pending" };
+            println!("{:>4}  {:<8} {}", m.version, mark, m.name);
+        }
+    } else if result.migrations.is_empty() {
+        println!("Nothing to do.");
+    } else {
+        for m in &result.migrations {
+            println!("{verb} {} {}", m.version, m.name);
+        }
+    }
+    println!(
+        "Schema version: {} (latest {})",
+        result.current_version, result.latest_version
+    );
+    Ok(())
+}
+
+/// Build a migrator for the workspace and sessions directory in the config.
+async fn migrator(config_path: &str) -> Result<Migrator> {
+    super::check_workspace(config_path)?;
+    let config = Config::load(config_path)
+        .await
+        .context("Failed to load config")?;
+    let config_path_ref = Path::new(config_path);
+    let workspace_raw = config
+        .workspace
+        .as_deref()
+        .unwrap_or(Path::new(config::DEFAULT_WORKSPACE));
+    let workspace = config::resolve_path(config_path_ref, workspace_raw);
+    let sessions_path = config
+        .services
+        .session
+        .path
+        .as_ref()
+        .map(|p| config::resolve_path(config_path_ref, p))
+        .unwrap_or_else(|| workspace.join(config::DEFAULT_SESSIONS_DIR));
+
+    Ok(Migrator::new(workspace, sessions_path))
+}
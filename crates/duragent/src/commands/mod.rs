@@ -9,11 +9,15 @@ use duragent::config::DEFAULT_WORKSPACE;
 pub mod agent;
 #[cfg(feature = "cli")]
 pub mod attach;
+pub mod bench;
+pub mod bundle;
 #[cfg(feature = "cli")]
 pub mod chat;
+pub mod dashboards;
 pub mod doctor;
 pub mod init;
 pub mod login;
+pub mod migrate;
 pub mod serve;
 pub mod session;
 pub mod upgrade;
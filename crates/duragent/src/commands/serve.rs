@@ -10,20 +10,49 @@ use tokio::sync::Mutex;
 use tracing::{info, warn};
 
 use duragent::agent::{self, AgentStore};
+use duragent::artifacts::Artifacts;
+use duragent::audit::AuditLog;
 use duragent::background::BackgroundTasks;
+use duragent::callbacks::Callbacks;
 use duragent::client::AgentClient;
 use duragent::config::{self, Config, ExternalGatewayConfig};
+use duragent::encryption::{NamespaceResolver, TenantKeys};
+use duragent::examples::ExampleLibrary;
+use duragent::faults::FaultInjector;
+use duragent::features::FeatureFlags;
 use duragent::gateway::{GatewayManager, SubprocessGateway};
+use duragent::health::{self, HealthChecker, HealthHistory};
 use duragent::llm::ProviderRegistry;
+use duragent::metrics::HttpMetrics;
+use duragent::models::ModelRegistry;
+use duragent::policy::Policies;
 use duragent::process::ProcessRegistryHandle;
 use duragent::process::registry::spawn_cleanup_task;
+use duragent::prompts::PromptLibrary;
+use duragent::public_agents::PublicAgents;
+use duragent::runs::Runs;
 use duragent::sandbox::{Sandbox, TrustSandbox};
 use duragent::scheduler::{SchedulerConfig, SchedulerService};
 use duragent::server::{self, RuntimeServices};
-use duragent::session::{ChatSessionCache, SessionRegistry};
+use duragent::service_accounts::ServiceAccounts;
+use duragent::session::{
+    ChatSessionCache, RunPool, SessionArchiver, SessionRegistry, StreamBuffers, spawn_archive_job,
+};
+use duragent::shares::Shares;
+use duragent::slo::{self, ProviderSlos};
+use duragent::store::AgentCatalog;
 use duragent::store::file::{
-    FileAgentCatalog, FilePolicyStore, FileRunLogStore, FileScheduleStore, FileSessionStore,
+    FileAgentCatalog, FileArtifactStore, FileDeadLetterStore, FileExampleStore, FileIdentityStore,
+    FileModelStore, FilePolicyStore, FilePromptStore, FileRunLogStore, FileRunStore,
+    FileScheduleStore, FileServiceAccountStore, FileSessionArchive, FileSessionStore,
+    FileShareStore, FileUsageStore, FileUserFactStore, Migrator,
 };
+use duragent::store::s3::S3SessionArchive;
+use duragent::throttle::ProviderThrottle;
+use duragent::tools::ToolMocks;
+use duragent::upgrade::{self, UpgradeTrigger};
+use duragent::usage::{self, UsageRollups};
+use duragent::user_memory::UserMemory;
 
 pub async fn run(
     config_path: &str,
@@ -78,10 +107,205 @@ pub async fn run(
     let (store, providers, policy_store) = load_agents(&agents_dir, &workspace).await;
     info!(agents = store.len(), "Loaded agents");
 
+    // Apply outbound HTTP settings (proxy, CA bundle) to provider clients
+    let mut outbound = config.outbound.clone();
+    outbound.ca_bundle = outbound
+        .ca_bundle
+        .map(|p| config::resolve_path(config_path_ref, &p));
+    let slos = ProviderSlos::from_config(&config.slo).context("Invalid slo config")?;
+    let faults = FaultInjector::from_config(&config.faults).context("Invalid faults config")?;
+    let throttle =
+        ProviderThrottle::from_config(&outbound).context("Invalid outbound provider budgets")?;
+    if !throttle.is_empty() {
+        info!("Provider rate limit budgets enabled; requests over budget are queued");
+    }
+    let tool_mocks_dir = config
+        .tool_mocks
+        .fixtures_dir
+        .as_ref()
+        .map(|p| config::resolve_path(config_path_ref, p))
+        .unwrap_or_else(|| workspace.join(config::DEFAULT_TOOL_MOCKS_DIR));
+    let tool_mocks = ToolMocks::from_config(&config.tool_mocks, &tool_mocks_dir)
+        .context("Invalid tool_mocks config")?;
+    if !tool_mocks.is_empty() {
+        warn!(
+            tools = ?config.tool_mocks.tools,
+            "Tool mocking is enabled; mocked tools are answered from fixtures instead of run"
+        );
+    }
+    if !faults.is_empty() {
+        warn!(
+            routes = config.faults.routes.len(),
+            providers = config.faults.providers.len(),
+            "Fault injection is enabled; requests will be delayed, failed, or dropped on purpose"
+        );
+    }
+    let providers = providers
+        .with_outbound(&outbound)
+        .context("Failed to configure outbound HTTP client")?
+        .with_slos(slos.clone())
+        .with_faults(faults.clone())
+        .with_throttle(throttle);
+    if !slos.is_empty() {
+        let http = duragent::llm::http::build_client(&outbound, None)
+            .context("Failed to configure SLO alert HTTP client")?;
+        info!(
+            providers = config.slo.providers.len(),
+            "Provider SLO tracking enabled"
+        );
+        slo::spawn_slo_alerts(slos.clone(), &config.slo, http);
+    }
+
+    // When started by an upgrade, let the old server drain and flush its
+    // sessions before recovering them here
+    upgrade::drain_previous().await;
+
+    // Bring the store layout up to date before anything reads it
+    let migrator = Migrator::new(&workspace, &sessions_path);
+    if config.store.auto_migrate {
+        let applied = migrator.up(None).await.context("Failed to migrate store")?;
+        for m in applied {
+            info!(
+                version = m.version,
+                name = m.name,
+                "Applied store migration"
+            );
+        }
+    } else {
+        let current = migrator.current_version().await?;
+        let latest = Migrator::latest_version();
+        if current > latest {
+            anyhow::bail!(
+                "Store schema version {current} is newer than this binary supports ({latest})"
+            );
+        }
+        if current < latest {
+            warn!(
+                current,
+                latest, "Store has pending migrations; run `duragent migrate up`"
+            );
+        }
+    }
+
+    // Token usage is counted by session actors, so set up rollups first
+    let usage_rollups = UsageRollups::new(Arc::new(FileUsageStore::new(
+        workspace.join(config::DEFAULT_USAGE_DIR),
+    )));
+    usage::spawn_rollup_job(usage_rollups.clone(), &config.usage);
+
+    let service_accounts = ServiceAccounts::load(Arc::new(FileServiceAccountStore::new(
+        workspace.join(config::DEFAULT_SERVICE_ACCOUNTS_DIR),
+    )))
+    .await
+    .context("Failed to load service accounts")?;
+    let shares = Shares::load(Arc::new(FileShareStore::new(
+        workspace.join(config::DEFAULT_SHARES_DIR),
+    )))
+    .await
+    .context("Failed to load share links")?;
+    let prompts = PromptLibrary::load(Arc::new(FilePromptStore::new(
+        workspace.join(config::DEFAULT_PROMPTS_DIR),
+    )))
+    .await
+    .context("Failed to load prompt library")?;
+    for (name, agent) in store.snapshot() {
+        for reference in &agent.prompts {
+            if prompts.resolve(reference).is_none() {
+                warn!(agent = %name, prompt = %reference, "Agent includes a prompt that does not exist");
+            }
+        }
+    }
+    let models = ModelRegistry::load(Arc::new(FileModelStore::new(
+        workspace.join(config::DEFAULT_MODELS_FILE),
+    )))
+    .await
+    .context("Failed to load model registry")?;
+    models.warn_agent_issues(&store);
+    let runs = if config.runs.persist {
+        Runs::load(
+            &config.runs,
+            Arc::new(FileRunStore::new(workspace.join(config::DEFAULT_RUNS_DIR))),
+        )
+        .await
+        .context("Failed to load run history")?
+    } else {
+        Runs::with_config(&config.runs)
+    };
+    let policies =
+        Policies::from_config(&config.authorization).context("Invalid authorization policy")?;
+
+    let audit = if config.audit.sinks.is_empty() {
+        AuditLog::default()
+    } else {
+        let http = duragent::llm::http::build_client(&outbound, None)
+            .context("Failed to configure audit HTTP client")?;
+        info!(sinks = config.audit.sinks.len(), "Audit log enabled");
+        AuditLog::start(
+            &config.audit,
+            workspace.join(config::DEFAULT_AUDIT_FILE),
+            http,
+        )
+    };
+
+    let callbacks = if config.callbacks.signing_secret.is_none() {
+        Callbacks::default()
+    } else {
+        let http = duragent::llm::http::client_builder(&outbound, None)
+            .context("Failed to configure callback HTTP client")?;
+        Callbacks::new(&config.callbacks, http)
+            .context("Failed to configure callback HTTP client")?
+    };
+
+    let public_agents = {
+        let http = duragent::llm::http::build_client(&outbound, None)
+            .context("Failed to configure CAPTCHA HTTP client")?;
+        PublicAgents::new(&config.public_agents, http).context("Invalid public_agents config")?
+    };
+
+    // Dependency checks for the health history endpoint
+    let health_history = HealthHistory::new(&config.health);
+    health::spawn_health_checks(
+        HealthChecker::new(
+            health_history.clone(),
+            providers.clone(),
+            workspace.clone(),
+            &config.health,
+        ),
+        &config.health,
+    );
+
+    // Per-tenant data keys for stored sessions, when a master key is set
+    let tenant_keys = match &config.encryption.master_key {
+        Some(master_key) => {
+            let keys_dir = config
+                .encryption
+                .keys_dir
+                .as_ref()
+                .map(|p| config::resolve_path(config_path_ref, p))
+                .unwrap_or_else(|| workspace.join(config::DEFAULT_KEYS_DIR));
+            let agents = store.clone();
+            let resolve: NamespaceResolver = Arc::new(move |agent: &str| {
+                agents
+                    .get(agent)
+                    .and_then(|spec| spec.metadata.project.clone())
+            });
+            let keys = TenantKeys::new(master_key, &keys_dir, resolve)
+                .context("Invalid encryption.master_key")?;
+            info!(keys_dir = %keys_dir.display(), "Session encryption enabled");
+            Some(keys)
+        }
+        None => None,
+    };
+
     // Initialize session store and registry, then recover persisted sessions
-    let session_store: Arc<dyn duragent::store::SessionStore> =
-        Arc::new(FileSessionStore::new(&sessions_path));
-    let session_registry = SessionRegistry::new(session_store.clone(), config.sessions.compaction);
+    let mut file_session_store = FileSessionStore::new(&sessions_path);
+    if let Some(keys) = &tenant_keys {
+        file_session_store = file_session_store.with_encryption(keys.clone());
+    }
+    let session_store: Arc<dyn duragent::store::SessionStore> = Arc::new(file_session_store);
+    let session_registry = SessionRegistry::new(session_store.clone(), config.sessions.compaction)
+        .with_event_batch(config.sessions.event_batch)
+        .with_usage(usage_rollups.clone());
     let recovery = session_registry.recover().await?;
     if recovery.recovered > 0 {
         info!(
@@ -147,6 +371,101 @@ pub async fn run(
         );
     }
 
+    // Cold archive for old completed sessions. Always set up so sessions
+    // archived earlier stay readable after archiving is turned off.
+    let archive_config = &config.sessions.archive;
+    let session_archive: Arc<dyn duragent::store::SessionArchive> = match &archive_config.s3 {
+        Some(s3) => {
+            let http = duragent::llm::http::build_client(&outbound, None)
+                .context("Failed to configure session archive HTTP client")?;
+            Arc::new(S3SessionArchive::new(http, s3).context("Invalid session archive S3 config")?)
+        }
+        None => Arc::new(FileSessionArchive::new(
+            archive_config
+                .path
+                .as_ref()
+                .map(|p| config::resolve_path(config_path_ref, p))
+                .unwrap_or_else(|| workspace.join(config::DEFAULT_SESSION_ARCHIVE_DIR)),
+        )),
+    };
+    let mut session_archiver = SessionArchiver::new(session_store.clone(), session_archive);
+    if let Some(keys) = tenant_keys {
+        session_archiver = session_archiver.with_encryption(keys);
+    }
+    if archive_config.after_days > 0 {
+        spawn_archive_job(
+            session_archiver.clone(),
+            session_registry.clone(),
+            archive_config.after_days,
+        );
+        info!(
+            after_days = archive_config.after_days,
+            "Session archiving enabled"
+        );
+    }
+
+    // Spawn agent trash purge loop
+    if config.agents.trash_retention_hours > 0 {
+        let trash_catalog = FileAgentCatalog::new(&agents_dir, Some(workspace.clone()));
+        let retention_hours = config.agents.trash_retention_hours;
+        tokio::spawn(async move {
+            let retention = chrono::Duration::hours(retention_hours as i64);
+            let mut interval = tokio::time::interval(std::time::Duration::from_secs(3600));
+            loop {
+                interval.tick().await;
+                match trash_catalog.purge_expired(retention).await {
+                    Ok(purged) if !purged.is_empty() => {
+                        info!(agents = ?purged, "Purged expired agents from trash");
+                    }
+                    Ok(_) => {}
+                    Err(e) => warn!(error = %e, "Failed to purge agent trash"),
+                }
+            }
+        });
+    }
+
+    // Spawn agents directory watcher; it stops when shutdown starts
+    let background_tasks = BackgroundTasks::new();
+    if config.agents.watch_interval_seconds > 0 {
+        let watch_catalog = FileAgentCatalog::new(&agents_dir, Some(workspace.clone()));
+        let watched = store.clone();
+        let watch_models = models.clone();
+        let watch_runs = runs.clone();
+        let every = std::time::Duration::from_secs(config.agents.watch_interval_seconds);
+        background_tasks.spawn(async move {
+            let mut interval = tokio::time::interval(every);
+            interval.tick().await; // First tick is immediate; agents were just loaded
+            loop {
+                tokio::select! {
+                    _ = interval.tick() => {}
+                    () = watch_runs.shutting_down() => break,
+                }
+                let disk = match watch_catalog.load_all().await {
+                    Ok(disk) => disk,
+                    Err(e) => {
+                        warn!(error = %e, "Failed to scan agents directory");
+                        continue;
+                    }
+                };
+                let drift = agent::detect_drift(&watched, &disk);
+                let reconciled = agent::reconcile_agents(&watched, disk, &drift);
+                if reconciled.is_empty() {
+                    continue;
+                }
+                for name in &reconciled {
+                    if let Some(spec) = watched.get(name) {
+                        watch_models.warn_issues(&spec);
+                    }
+                }
+                info!(agents = ?reconciled, "Reloaded agents changed on disk");
+            }
+        });
+        info!(
+            interval_seconds = config.agents.watch_interval_seconds,
+            "Watching agents directory for changes"
+        );
+    }
+
     // Initialize scheduler service (before gateway handler so it can be passed in)
     let schedules_path = sessions_path
         .parent()
@@ -166,16 +485,29 @@ pub async fn run(
         workspace_tools_path: workspace_tools_path.clone(),
         agentic_loop_locks: duragent::sync::KeyedLocks::with_cleanup("agentic_loop"),
         steering_channels: Arc::new(dashmap::DashMap::new()),
+        run_pool: RunPool::new(config.sessions.max_concurrent_runs)
+            .with_load_shedding(config.sessions.load_shedding.clone()),
+        examples: ExampleLibrary::new(Arc::new(FileExampleStore::new(
+            workspace.join(config::DEFAULT_EXAMPLES_DIR),
+        ))),
+        prompts,
+        user_memory: UserMemory::new(Arc::new(FileUserFactStore::new(
+            workspace.join(config::DEFAULT_USER_MEMORY_DIR),
+        ))),
+        models,
+        tool_mocks,
     };
 
     let schedule_store = Arc::new(FileScheduleStore::new(&schedules_path));
     let run_log_store = Arc::new(FileRunLogStore::new(schedules_path.join("runs")));
+    let dead_letter_store = Arc::new(FileDeadLetterStore::new(schedules_path.join("dead-letter")));
     let process_registry_slot = Arc::new(OnceLock::new());
     let scheduler_config = SchedulerConfig {
         services: services.clone(),
         gateway_sender: gateway_sender.clone(),
         schedule_store,
         run_log_store,
+        dead_letter_store,
         chat_session_cache: chat_session_cache.clone(),
         process_registry: process_registry_slot.clone(),
     };
@@ -243,9 +575,9 @@ pub async fn run(
 
     // Create shutdown channel for HTTP-triggered shutdown
     let (shutdown_tx, shutdown_rx) = server::shutdown_channel();
+    let upgrade_trigger = UpgradeTrigger::default();
 
     // Build app state
-    let background_tasks = BackgroundTasks::new();
     let state = server::AppState {
         services,
         scheduler: Some(scheduler_handle.clone()),
@@ -256,12 +588,55 @@ pub async fn run(
         idle_timeout_seconds: config.server.idle_timeout_seconds,
         keep_alive_interval_seconds: config.server.keep_alive_interval_seconds,
         max_connections: config.server.max_connections,
+        request_validation: config.server.request_validation,
+        read_only: config.server.read_only,
+        base_path: config.server.normalized_base_path(),
+        external_url: server::ExternalUrl::new(
+            &config.server.normalized_base_path(),
+            config.server.external_url.as_deref(),
+        ),
+        access_log: server::AccessLog::new(
+            &config.server.access_log,
+            &config.server.normalized_base_path(),
+        ),
+        debug_capture: server::DebugCapture::new(&config.server.debug_capture),
+        route_timeouts: server::RouteTimeouts::new(
+            &config.server.route_timeouts,
+            &config.server.normalized_base_path(),
+        ),
         background_tasks: background_tasks.clone(),
         shutdown_tx: Arc::new(Mutex::new(Some(shutdown_tx))),
         workspace_hash,
         chat_session_cache,
+        stream_buffers: StreamBuffers::new(),
         agents_dir: agents_dir.clone(),
         workspace_dir: Some(workspace.clone()),
+        agent_trash_retention_hours: config.agents.trash_retention_hours,
+        expose_reasoning: config.sessions.expose_reasoning,
+        features: FeatureFlags::from_config(&config.features),
+        upgrade: upgrade_trigger.clone(),
+        usage: usage_rollups.clone(),
+        session_archive: session_archiver,
+        artifacts: Artifacts::new(Arc::new(FileArtifactStore::new(
+            workspace.join(config::DEFAULT_ARTIFACTS_DIR),
+        ))),
+        identity: Arc::new(FileIdentityStore::new(
+            workspace.join(config::DEFAULT_IDENTITY_DIR),
+        )),
+        scim: config.scim.clone(),
+        scim_writes: Arc::new(Mutex::new(())),
+        service_accounts,
+        shares,
+        policies,
+        audit,
+        callbacks,
+        runs: runs.clone(),
+        health: health_history,
+        slos,
+        http_metrics: HttpMetrics::new(),
+        status_page: config.health.status_page.clone(),
+        faults,
+        public_agents,
     };
 
     // Spawn ephemeral idle monitor if requested
@@ -292,18 +667,41 @@ pub async fn run(
         info!(idle_timeout_secs = idle_secs, "Ephemeral mode enabled");
     }
 
+    if config.server.read_only {
+        info!("Read-only mode enabled; only list and get requests are accepted");
+    }
     let app = server::build_app(state, config.server.request_timeout_seconds);
 
     let ip: IpAddr = config.server.host.parse()?;
     let addr = SocketAddr::new(ip, config.server.port);
-    let listener = tokio::net::TcpListener::bind(addr).await?;
+    let listener = match upgrade::inherited_listener()? {
+        Some(listener) => {
+            info!("Using listening socket inherited from previous server");
+            tokio::net::TcpListener::from_std(listener)?
+        }
+        None => tokio::net::TcpListener::bind(addr).await?,
+    };
+    #[cfg(unix)]
+    upgrade::spawn_upgrade_listener(std::os::fd::AsRawFd::as_raw_fd(&listener), upgrade_trigger);
 
-    info!("Listening on http://{}", addr);
+    info!(
+        "Listening on http://{}{}",
+        addr,
+        config.server.normalized_base_path()
+    );
     axum::serve(
         listener,
         app.into_make_service_with_connect_info::<SocketAddr>(),
     )
-    .with_graceful_shutdown(shutdown_signal(shutdown_rx))
+    .with_graceful_shutdown({
+        // Stop background runs as soon as shutdown starts, so run event
+        // streams end and don't hold up the graceful shutdown
+        let runs = runs.clone();
+        async move {
+            shutdown_signal(shutdown_rx).await;
+            runs.shutdown();
+        }
+    })
     .await?;
 
     // Shutdown process registry gracefully
@@ -316,11 +714,15 @@ pub async fn run(
     // Shutdown session registry (flush all pending events and snapshots)
     session_registry.shutdown().await;
 
+    // Roll up usage counted since the last rollup
+    usage_rollups.flush().await;
+
     // Shutdown gateways gracefully
     gateways.shutdown().await;
 
     // Wait for background tasks to complete before exiting
     background_tasks.shutdown().await;
+    runs.flush().await;
 
     info!("Server stopped");
     Ok(())
@@ -331,7 +733,7 @@ pub async fn status(config_path: &str, port_override: Option<u16>) -> Result<()>
     let config = Config::load(config_path).await?;
     let port = port_override.unwrap_or(config.server.port);
 
-    let client = AgentClient::new(&format!("http://127.0.0.1:{}", port));
+    let client = AgentClient::new(&config.server.local_url(port));
 
     match client.health().await {
         Ok(readyz) => {
@@ -352,7 +754,7 @@ pub async fn stop(config_path: &str, port_override: Option<u16>) -> Result<()> {
     let config = Config::load(config_path).await?;
     let port = port_override.unwrap_or(config.server.port);
 
-    let client = AgentClient::new(&format!("http://127.0.0.1:{}", port));
+    let client = AgentClient::new(&config.server.local_url(port));
 
     // Check if server is running
     if client.health().await.is_err() {
@@ -371,7 +773,7 @@ pub async fn reload_agents(config_path: &str, port_override: Option<u16>) -> Res
     let config = Config::load(config_path).await?;
     let port = port_override.unwrap_or(config.server.port);
 
-    let client = AgentClient::new(&format!("http://127.0.0.1:{}", port));
+    let client = AgentClient::new(&config.server.local_url(port));
 
     if client.health().await.is_err() {
         anyhow::bail!("No server running on port {}", port);
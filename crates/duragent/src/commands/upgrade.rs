@@ -504,7 +504,7 @@ async fn restart_server(config_path: &str, port_override: Option<u16>) -> Result
     let config = Config::load(config_path).await?;
     let port = port_override.unwrap_or(config.server.port);
 
-    let base_url = format!("http://127.0.0.1:{port}");
+    let base_url = config.server.local_url(port);
     let client = AgentClient::new(&base_url);
 
     // Check if server is running
@@ -515,7 +515,13 @@ async fn restart_server(config_path: &str, port_override: Option<u16>) -> Result
 
     println!("Restarting server...");
 
-    // Shut down the running server
+    // Prefer a socket handoff so the port never stops accepting connections
+    if request_handoff(&base_url, config.server.admin_token.as_deref()).await? {
+        println!("Upgrade handed off to the running server.");
+        return Ok(());
+    }
+
+    // Older servers have no upgrade endpoint: shut down and exec instead
     shutdown_server(&base_url, config.server.admin_token.as_deref()).await?;
 
     // Wait for the server to fully stop
@@ -532,6 +538,28 @@ async fn restart_server(config_path: &str, port_override: Option<u16>) -> Result
     Ok(())
 }
 
+/// Ask the running server to start the new binary and hand over its socket.
+///
+/// Returns `false` if the server predates the upgrade endpoint.
+async fn request_handoff(base_url: &str, admin_token: Option<&str>) -> Result<bool> {
+    let url = format!("{base_url}/api/admin/v1/upgrade");
+    let client = reqwest::Client::new();
+    let mut req = client.post(&url);
+    if let Some(token) = admin_token {
+        req = req.header(reqwest::header::AUTHORIZATION, format!("Bearer {token}"));
+    }
+    let response = req.send().await.context("Failed to send upgrade request")?;
+    if response.status().is_success() {
+        return Ok(true);
+    }
+    let status = response.status();
+    if status == reqwest::StatusCode::NOT_FOUND {
+        return Ok(false);
+    }
+    let body = response.text().await.unwrap_or_default();
+    bail!("Failed to upgrade server: HTTP {status} {body}")
+}
+
 async fn shutdown_server(base_url: &str, admin_token: Option<&str>) -> Result<()> {
     let url = format!("{base_url}/api/admin/v1/shutdown");
     let client = reqwest::Client::new();
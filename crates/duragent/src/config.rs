@@ -4,7 +4,7 @@ use std::path::{Path, PathBuf};
 use sha2::{Digest, Sha256};
 use tokio::fs;
 
-use serde::Deserialize;
+use serde::{Deserialize, Serialize};
 use thiserror::Error;
 
 // ============================================================================
@@ -20,6 +20,8 @@ pub struct Config {
     #[serde(default)]
     pub agents_dir: Option<PathBuf>,
     #[serde(default)]
+    pub agents: AgentsConfig,
+    #[serde(default)]
     pub services: ServicesConfig,
     #[serde(default)]
     pub world_memory: WorldMemoryConfig,
@@ -32,6 +34,39 @@ pub struct Config {
     pub sandbox: SandboxConfig,
     #[serde(default)]
     pub sessions: SessionsConfig,
+    #[serde(default)]
+    pub runs: RunsConfig,
+    #[serde(default)]
+    pub bundles: BundlesConfig,
+    #[serde(default)]
+    pub outbound: OutboundConfig,
+    #[serde(default)]
+    pub store: StoreConfig,
+    #[serde(default)]
+    pub usage: UsageConfig,
+    #[serde(default)]
+    pub encryption: EncryptionConfig,
+    #[serde(default)]
+    pub scim: ScimConfig,
+    #[serde(default)]
+    pub authorization: AuthorizationConfig,
+    #[serde(default)]
+    pub audit: AuditConfig,
+    #[serde(default)]
+    pub callbacks: CallbacksConfig,
+    #[serde(default)]
+    pub health: HealthConfig,
+    #[serde(default)]
+    pub slo: SloConfig,
+    #[serde(default)]
+    pub faults: FaultsConfig,
+    #[serde(default)]
+    pub tool_mocks: ToolMocksConfig,
+    #[serde(default)]
+    pub public_agents: PublicAgentsConfig,
+    /// Feature flags for experimental subsystems, e.g. `workflows: true`.
+    #[serde(default)]
+    pub features: std::collections::BTreeMap<String, bool>,
 }
 
 #[derive(Debug, Error)]
@@ -117,6 +152,34 @@ pub const DEFAULT_SCHEDULES_DIR: &str = "schedules";
 pub const DEFAULT_PROCESSES_DIR: &str = "processes";
 /// Default artifacts directory (relative to workspace).
 pub const DEFAULT_ARTIFACTS_DIR: &str = "artifacts";
+/// Default projects directory (relative to workspace).
+pub const DEFAULT_PROJECTS_DIR: &str = "projects";
+/// Default session archive directory (relative to workspace).
+pub const DEFAULT_SESSION_ARCHIVE_DIR: &str = "archive/sessions";
+/// Default usage rollups directory (relative to workspace).
+pub const DEFAULT_USAGE_DIR: &str = "usage";
+/// Default wrapped tenant data keys directory (relative to workspace).
+pub const DEFAULT_KEYS_DIR: &str = "keys";
+/// Default provisioned users and groups directory (relative to workspace).
+pub const DEFAULT_IDENTITY_DIR: &str = "identity";
+/// Default audit log file (relative to workspace).
+pub const DEFAULT_AUDIT_FILE: &str = "audit/audit.jsonl";
+/// Default service accounts directory (relative to workspace).
+pub const DEFAULT_SERVICE_ACCOUNTS_DIR: &str = "service-accounts";
+/// Default session share links directory (relative to workspace).
+pub const DEFAULT_SHARES_DIR: &str = "shares";
+/// Default few-shot example pools directory (relative to workspace).
+pub const DEFAULT_EXAMPLES_DIR: &str = "examples";
+/// Default prompt library directory (relative to workspace).
+pub const DEFAULT_PROMPTS_DIR: &str = "prompts";
+/// Default long-term user memory directory (relative to workspace).
+pub const DEFAULT_USER_MEMORY_DIR: &str = "user-memory";
+/// Default message run history directory (relative to workspace).
+pub const DEFAULT_RUNS_DIR: &str = "runs";
+/// Default model capability overrides file (relative to workspace).
+pub const DEFAULT_MODELS_FILE: &str = "models.yaml";
+/// Default tool mock fixtures directory (relative to workspace).
+pub const DEFAULT_TOOL_MOCKS_DIR: &str = "tool-mocks";
 
 // ============================================================================
 // ServerConfig
@@ -144,6 +207,50 @@ pub struct ServerConfig {
     pub api_token: Option<String>,
     #[serde(default = "default_max_connections")]
     pub max_connections: usize,
+    /// Check JSON request bodies against the OpenAPI document's request
+    /// schemas before they reach handlers. Handlers' own checks run either way.
+    #[serde(default = "default_true")]
+    pub request_validation: bool,
+    /// Only answer list and get requests; invokes and other mutations,
+    /// including SCIM provisioning, are refused. Admin shutdown and upgrade
+    /// still work. For exposing a reporting replica to a broad audience.
+    #[serde(default)]
+    pub read_only: bool,
+    /// Path prefix to serve all routes under when running behind a reverse
+    /// proxy at a sub-path (e.g. `/duragent`). Empty serves from the root.
+    #[serde(default)]
+    pub base_path: String,
+    /// Public URL clients use to reach this server (e.g.
+    /// `https://example.com/duragent`). Generated links use it when set.
+    #[serde(default)]
+    pub external_url: Option<String>,
+    /// Sampled per-request access logging.
+    #[serde(default)]
+    pub access_log: AccessLogConfig,
+    /// Per-route `request_timeout_seconds` overrides, keyed by route template
+    /// (e.g. `/api/v1/sessions/{session_id}/messages`). `0` removes the limit.
+    #[serde(default)]
+    pub route_timeouts: std::collections::HashMap<String, u64>,
+    /// Short-lived capture of requests answered with a 4xx status.
+    #[serde(default)]
+    pub debug_capture: DebugCaptureConfig,
+}
+
+impl ServerConfig {
+    /// `base_path` in `/prefix` form, or empty when serving from the root.
+    pub fn normalized_base_path(&self) -> String {
+        let trimmed = self.base_path.trim().trim_matches('/');
+        if trimmed.is_empty() {
+            String::new()
+        } else {
+            format!("/{trimmed}")
+        }
+    }
+
+    /// URL of a server on this machine listening on `port`, including `base_path`.
+    pub fn local_url(&self, port: u16) -> String {
+        format!("http://127.0.0.1:{}{}", port, self.normalized_base_path())
+    }
 }
 
 impl Default for ServerConfig {
@@ -157,6 +264,66 @@ impl Default for ServerConfig {
             admin_token: None,
             api_token: None,
             max_connections: default_max_connections(),
+            request_validation: default_true(),
+            read_only: false,
+            base_path: String::new(),
+            external_url: None,
+            access_log: AccessLogConfig::default(),
+            route_timeouts: std::collections::HashMap::new(),
+            debug_capture: DebugCaptureConfig::default(),
+        }
+    }
+}
+
+/// Access log configuration.
+///
+/// Requests answered with 4xx/5xx are always logged; successful ones are
+/// sampled.
+#[derive(Debug, Clone, Deserialize)]
+#[serde(default)]
+pub struct AccessLogConfig {
+    pub enabled: bool,
+    /// Log one in every N successful requests. 1 logs all, 0 logs none.
+    pub sample_every: u32,
+    /// Per-route `sample_every` overrides, keyed by route template
+    /// (e.g. `/api/v1/sessions/{session_id}/messages` or `/livez`).
+    pub routes: std::collections::HashMap<String, u32>,
+}
+
+impl Default for AccessLogConfig {
+    fn default() -> Self {
+        Self {
+            enabled: false,
+            sample_every: 1,
+            routes: std::collections::HashMap::new(),
+        }
+    }
+}
+
+/// Capture of requests answered with a 4xx status, for debugging client
+/// integrations. Captured requests are kept in memory only.
+#[derive(Debug, Clone, Deserialize)]
+#[serde(default)]
+pub struct DebugCaptureConfig {
+    pub enabled: bool,
+    /// Largest captured body, after redaction and shortening.
+    pub max_body_bytes: usize,
+    /// How long a captured request can be looked up.
+    pub retention_seconds: u64,
+    /// Most captured requests kept; the oldest are dropped first.
+    pub max_entries: usize,
+    /// Body fields to redact in addition to the built-in credential names.
+    pub redact_fields: Vec<String>,
+}
+
+impl Default for DebugCaptureConfig {
+    fn default() -> Self {
+        Self {
+            enabled: false,
+            max_body_bytes: 16 * 1024,
+            retention_seconds: 900,
+            max_entries: 500,
+            redact_fields: Vec::new(),
         }
     }
 }
@@ -171,6 +338,485 @@ pub struct ServicesConfig {
     pub session: SessionServiceConfig,
 }
 
+// ============================================================================
+// StoreConfig
+// ============================================================================
+
+/// Configuration for the on-disk store layout.
+#[derive(Debug, Default, Deserialize)]
+pub struct StoreConfig {
+    /// Apply pending store migrations when the server starts.
+    #[serde(default)]
+    pub auto_migrate: bool,
+}
+
+// ============================================================================
+// UsageConfig
+// ============================================================================
+
+/// Token usage rollup configuration.
+#[derive(Debug, Clone, Deserialize)]
+#[serde(default)]
+pub struct UsageConfig {
+    /// How often pending usage is rolled up into the store, in seconds.
+    pub rollup_interval_seconds: u64,
+    /// Days to keep hourly rollups (0 = forever). Daily rollups are always kept.
+    pub hourly_retention_days: u32,
+}
+
+impl Default for UsageConfig {
+    fn default() -> Self {
+        Self {
+            rollup_interval_seconds: 60,
+            hourly_retention_days: 30,
+        }
+    }
+}
+
+// ============================================================================
+// EncryptionConfig
+// ============================================================================
+
+/// Tenant-scoped encryption of stored sessions.
+///
+/// Disabled unless `master_key` is set.
+#[derive(Debug, Clone, Default, Deserialize)]
+#[serde(default)]
+pub struct EncryptionConfig {
+    /// Base64-encoded 32-byte master key that wraps each tenant's data key.
+    pub master_key: Option<String>,
+    /// Directory for wrapped data keys (default: `{workspace}/keys`).
+    pub keys_dir: Option<PathBuf>,
+}
+
+// ============================================================================
+// ScimConfig
+// ============================================================================
+
+/// SCIM user provisioning configuration.
+#[derive(Debug, Clone, Default, Deserialize)]
+#[serde(default)]
+pub struct ScimConfig {
+    /// Bearer token the identity provider uses for `/scim/v2`.
+    /// If not set, SCIM endpoints only accept requests from localhost.
+    pub token: Option<String>,
+    /// Access granted to members of provisioned groups. When set, users
+    /// named by `authorization.user_header` need a role for each request.
+    pub group_roles: Vec<GroupRoleConfig>,
+}
+
+/// Grants a role to the members of a provisioned group.
+#[derive(Debug, Clone, Deserialize)]
+pub struct GroupRoleConfig {
+    /// Group `displayName` as sent by the identity provider.
+    pub group: String,
+    pub role: AccessRole,
+    /// Projects the role applies in. Empty applies it in every project.
+    #[serde(default)]
+    pub projects: Vec<String>,
+}
+
+/// Access role, from least to most privileged.
+#[derive(Debug, Clone, Copy, PartialEq, Eq, PartialOrd, Ord, Hash, Deserialize, Serialize)]
+#[serde(rename_all = "snake_case")]
+pub enum AccessRole {
+    /// Read agents, sessions, runs, and usage.
+    Viewer,
+    /// Viewer, plus create sessions and send messages.
+    Operator,
+    /// Every API route, including admin-guarded ones under `/api/v1`.
+    Admin,
+}
+
+// ============================================================================
+// AuthorizationConfig
+// ============================================================================
+
+/// Authorization policies evaluated on API requests.
+#[derive(Debug, Clone, Default, Deserialize)]
+#[serde(default)]
+pub struct AuthorizationConfig {
+    /// Header a trusted front end sets to the end user's SCIM `userName`.
+    /// Only read on requests already authorized with the API token.
+    pub user_header: Option<String>,
+    /// Rules, checked in order. See [`PolicyConfig`].
+    pub policies: Vec<PolicyConfig>,
+}
+
+/// One authorization rule.
+///
+/// A request the `when` expression matches is denied unless the `allow`
+/// expression holds.
+#[derive(Debug, Clone, Deserialize)]
+pub struct PolicyConfig {
+    pub name: String,
+    #[serde(default)]
+    pub description: Option<String>,
+    /// Requests the rule applies to. Applies to every request when omitted.
+    #[serde(default)]
+    pub when: Option<String>,
+    /// Condition applicable requests must meet.
+    pub allow: String,
+}
+
+// ============================================================================
+// AuditConfig
+// ============================================================================
+
+/// Security audit log.
+///
+/// Authentication failures, access denials, and admin and provisioning
+/// changes are sent to every sink. Each sink has its own buffer and
+/// delivers events in batches, retrying failed batches with backoff.
+/// Disabled when no sinks are configured.
+#[derive(Debug, Clone, Deserialize)]
+#[serde(default)]
+pub struct AuditConfig {
+    pub sinks: Vec<AuditSinkConfig>,
+    /// Events buffered per sink. New events are dropped while it is full.
+    pub buffer_size: usize,
+    /// Most events delivered in one batch.
+    pub batch_size: usize,
+    /// Longest an event waits in the buffer before its batch is sent.
+    pub flush_interval_ms: u64,
+    /// Retries for a failed batch before its events are dropped.
+    pub max_retries: u32,
+}
+
+impl Default for AuditConfig {
+    fn default() -> Self {
+        Self {
+            sinks: Vec::new(),
+            buffer_size: 10_000,
+            batch_size: 100,
+            flush_interval_ms: 1000,
+            max_retries: 5,
+        }
+    }
+}
+
+/// Destination for audit events.
+#[derive(Debug, Clone, Deserialize)]
+#[serde(tag = "type", rename_all = "snake_case")]
+pub enum AuditSinkConfig {
+    /// JSON lines appended to a local file.
+    File {
+        /// Defaults to `{workspace}/audit/audit.jsonl`.
+        #[serde(default)]
+        path: Option<PathBuf>,
+    },
+    /// RFC 5424 messages to a syslog server.
+    Syslog {
+        /// `host:port` of the syslog server.
+        address: String,
+        #[serde(default)]
+        protocol: SyslogProtocol,
+        /// Syslog facility number (default: 10, `authpriv`).
+        #[serde(default = "default_syslog_facility")]
+        facility: u8,
+    },
+    /// OTLP logs over HTTP/JSON, e.g. `http://collector:4318/v1/logs`.
+    Otlp {
+        endpoint: String,
+        #[serde(default)]
+        headers: std::collections::HashMap<String, String>,
+    },
+    /// JSON batches POSTed to a URL.
+    Webhook {
+        url: String,
+        #[serde(default)]
+        headers: std::collections::HashMap<String, String>,
+    },
+}
+
+/// Syslog transport.
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq, Deserialize)]
+#[serde(rename_all = "snake_case")]
+pub enum SyslogProtocol {
+    #[default]
+    Udp,
+    /// TCP with octet-counting framing (RFC 6587).
+    Tcp,
+}
+
+fn default_syslog_facility() -> u8 {
+    10
+}
+
+// ============================================================================
+// CallbacksConfig
+// ============================================================================
+
+/// Result callbacks for messages sent with a `callback_url`.
+///
+/// Disabled until `signing_secret` is set, so every callback can be
+/// verified by its receiver.
+#[derive(Debug, Clone, Deserialize)]
+#[serde(default)]
+pub struct CallbacksConfig {
+    /// Key for the `X-Duragent-Signature` HMAC over each callback.
+    pub signing_secret: Option<String>,
+    /// Retries for a failed delivery before it is dropped.
+    pub max_retries: u32,
+    /// Timeout for each delivery attempt.
+    pub timeout_seconds: u64,
+    /// Hosts callback URLs may point at. `*.example.com` matches any
+    /// subdomain of `example.com`. Empty allows any host.
+    pub allowed_hosts: Vec<String>,
+    /// Refuse `http://` callback URLs.
+    pub require_https: bool,
+    /// Deliver to loopback, private, and link-local addresses. Off by
+    /// default, so callers can't make the server POST to internal services.
+    pub allow_private_addresses: bool,
+}
+
+impl Default for CallbacksConfig {
+    fn default() -> Self {
+        Self {
+            signing_secret: None,
+            max_retries: 5,
+            timeout_seconds: 10,
+            allowed_hosts: Vec::new(),
+            require_https: false,
+            allow_private_addresses: false,
+        }
+    }
+}
+
+// ============================================================================
+// HealthConfig
+// ============================================================================
+
+/// Periodic dependency checks behind `GET /api/v1/admin/health-history`.
+#[derive(Debug, Clone, Deserialize)]
+#[serde(default)]
+pub struct HealthConfig {
+    /// Seconds between checks (0 = disabled).
+    pub check_interval_seconds: u64,
+    /// Seconds a single check may take before it counts as failed.
+    pub check_timeout_seconds: u64,
+    /// Results kept per dependency.
+    pub history_size: usize,
+    /// Fraction of checks that should pass, e.g. `0.99`. Sets the error budget.
+    pub slo_target: f64,
+    pub status_page: StatusPageConfig,
+}
+
+impl Default for HealthConfig {
+    fn default() -> Self {
+        Self {
+            check_interval_seconds: 60,
+            check_timeout_seconds: 10,
+            history_size: 1440,
+            slo_target: 0.99,
+            status_page: StatusPageConfig::default(),
+        }
+    }
+}
+
+/// Status page at `/status`, built from the health history.
+#[derive(Debug, Clone, Deserialize)]
+#[serde(default)]
+pub struct StatusPageConfig {
+    pub enabled: bool,
+    /// Serve without authentication. Otherwise the API token is required.
+    pub public: bool,
+    pub title: String,
+}
+
+impl Default for StatusPageConfig {
+    fn default() -> Self {
+        Self {
+            enabled: true,
+            public: false,
+            title: "Duragent Status".to_string(),
+        }
+    }
+}
+
+// ============================================================================
+// SloConfig
+// ============================================================================
+
+/// Per-provider latency and error objectives with burn rate alerts.
+///
+/// Disabled when no providers are listed.
+#[derive(Debug, Clone, Deserialize)]
+#[serde(default)]
+pub struct SloConfig {
+    /// Seconds between burn rate evaluations.
+    pub evaluation_interval_seconds: u64,
+    /// Alerts are POSTed to each of these.
+    pub webhooks: Vec<SloWebhookConfig>,
+    pub providers: Vec<ProviderSloConfig>,
+}
+
+impl Default for SloConfig {
+    fn default() -> Self {
+        Self {
+            evaluation_interval_seconds: 30,
+            webhooks: Vec::new(),
+            providers: Vec::new(),
+        }
+    }
+}
+
+/// Destination for SLO alerts.
+#[derive(Debug, Clone, Deserialize)]
+pub struct SloWebhookConfig {
+    pub url: String,
+    #[serde(default)]
+    pub headers: std::collections::HashMap<String, String>,
+}
+
+/// Objectives for one provider, e.g. `openai`.
+#[derive(Debug, Clone, Deserialize)]
+pub struct ProviderSloConfig {
+    pub provider: String,
+    /// Requests slower than this count against the latency objective.
+    /// No latency objective when unset.
+    #[serde(default)]
+    pub latency_ms: Option<u64>,
+    /// Fraction of successful requests expected within `latency_ms`.
+    #[serde(default = "default_latency_target")]
+    pub latency_target: f64,
+    /// Fraction of requests expected to succeed. No error objective when unset.
+    #[serde(default)]
+    pub error_target: Option<f64>,
+    /// Window burn rates are measured over.
+    #[serde(default = "default_slo_window_minutes")]
+    pub window_minutes: u64,
+    /// Alert once the error budget burns this many times faster than the
+    /// objective allows.
+    #[serde(default = "default_burn_rate_threshold")]
+    pub burn_rate_threshold: f64,
+    /// Requests needed in the window before an objective can alert.
+    #[serde(default = "default_slo_min_requests")]
+    pub min_requests: usize,
+}
+
+fn default_latency_target() -> f64 {
+    0.95
+}
+
+fn default_slo_window_minutes() -> u64 {
+    60
+}
+
+fn default_burn_rate_threshold() -> f64 {
+    2.0
+}
+
+fn default_slo_min_requests() -> usize {
+    10
+}
+
+// ============================================================================
+// FaultsConfig
+// ============================================================================
+
+/// Fault injection for testing client resilience. Development only: never
+/// enable this on a server real users depend on.
+#[derive(Debug, Clone, Default, Deserialize)]
+#[serde(default)]
+pub struct FaultsConfig {
+    pub enabled: bool,
+    /// Faults applied to HTTP requests. The first matching rule wins.
+    pub routes: Vec<RouteFaultConfig>,
+    /// Faults applied to LLM requests. The first matching rule wins.
+    pub providers: Vec<ProviderFaultConfig>,
+}
+
+/// Faults for requests whose path starts with `path`.
+#[derive(Debug, Clone, Deserialize)]
+pub struct RouteFaultConfig {
+    /// Path prefix relative to `server.base_path`, e.g. `/api/v1/sessions`.
+    pub path: String,
+    /// HTTP methods to match. All methods when empty.
+    #[serde(default)]
+    pub methods: Vec<String>,
+    #[serde(flatten)]
+    pub faults: FaultSpec,
+}
+
+/// Faults for requests to one LLM provider.
+#[derive(Debug, Clone, Deserialize)]
+pub struct ProviderFaultConfig {
+    pub provider: String,
+    #[serde(flatten)]
+    pub faults: FaultSpec,
+}
+
+/// What to inject. Rates are probabilities between 0 and 1.
+#[derive(Debug, Clone, Deserialize)]
+#[serde(default)]
+pub struct FaultSpec {
+    /// Delay added before the request is handled.
+    pub latency_ms: u64,
+    /// Random extra delay of up to this many milliseconds.
+    pub latency_jitter_ms: u64,
+    /// Fraction of requests that fail with `error_status`.
+    pub error_rate: f64,
+    pub error_status: u16,
+    /// Fraction of streams cut off after `drop_after_events` events.
+    pub drop_stream_rate: f64,
+    pub drop_after_events: usize,
+}
+
+impl Default for FaultSpec {
+    fn default() -> Self {
+        Self {
+            latency_ms: 0,
+            latency_jitter_ms: 0,
+            error_rate: 0.0,
+            error_status: 503,
+            drop_stream_rate: 0.0,
+            drop_after_events: 3,
+        }
+    }
+}
+
+// ============================================================================
+// ToolMocksConfig
+// ============================================================================
+
+/// Tools replaced by mocks that answer with canned data, so destructive
+/// tools can be exercised safely in development and staging.
+#[derive(Debug, Clone, Default, Deserialize)]
+#[serde(default)]
+pub struct ToolMocksConfig {
+    pub enabled: bool,
+    /// Directory of fixture files, one `{tool}.json` per mocked tool
+    /// (relative to config file). Defaults to `{workspace}/tool-mocks`.
+    pub fixtures_dir: Option<PathBuf>,
+    /// Names of the tools to mock.
+    pub tools: Vec<String>,
+}
+
+// ============================================================================
+// PublicAgentsConfig
+// ============================================================================
+
+/// Settings for agents invoked over the public API (`access.public`).
+#[derive(Debug, Clone, Default, Deserialize)]
+#[serde(default)]
+pub struct PublicAgentsConfig {
+    /// Header holding the client IP when behind a reverse proxy, e.g.
+    /// `X-Forwarded-For` (the last address is used). Rate limits apply to
+    /// the connection's address when unset.
+    pub client_ip_header: Option<String>,
+    /// CAPTCHA verification for agents with `access.public.captcha`.
+    pub captcha: Option<CaptchaConfig>,
+}
+
+/// A CAPTCHA provider's siteverify endpoint, e.g. Cloudflare Turnstile,
+/// hCaptcha, or reCAPTCHA.
+#[derive(Debug, Clone, Deserialize)]
+pub struct CaptchaConfig {
+    pub verify_url: String,
+    pub secret: String,
+}
+
 // ============================================================================
 // SessionServiceConfig
 // ============================================================================
@@ -336,6 +982,77 @@ pub enum SandboxMode {
     Docker,
 }
 
+// ============================================================================
+// AgentsConfig
+// ============================================================================
+
+fn default_trash_retention_hours() -> u64 {
+    168 // 7 days
+}
+
+fn default_watch_interval_seconds() -> u64 {
+    30
+}
+
+/// Agent lifecycle configuration.
+#[derive(Debug, Clone, Deserialize)]
+pub struct AgentsConfig {
+    /// Hours a deleted agent stays in the trash before it is purged.
+    /// 0 keeps trashed agents until purged explicitly.
+    #[serde(default = "default_trash_retention_hours")]
+    pub trash_retention_hours: u64,
+    /// Seconds between scans of the agents directory for added, changed, or
+    /// removed agents, which are then reloaded. 0 disables watching; changes
+    /// are picked up on reload or drift reconciliation only.
+    #[serde(default = "default_watch_interval_seconds")]
+    pub watch_interval_seconds: u64,
+}
+
+impl Default for AgentsConfig {
+    fn default() -> Self {
+        Self {
+            trash_retention_hours: default_trash_retention_hours(),
+            watch_interval_seconds: default_watch_interval_seconds(),
+        }
+    }
+}
+
+// ============================================================================
+// RunsConfig
+// ============================================================================
+
+fn default_persist_runs() -> bool {
+    true
+}
+
+fn default_run_retention_hours() -> u64 {
+    1
+}
+
+/// Message run history and background run limits.
+#[derive(Debug, Clone, Deserialize)]
+pub struct RunsConfig {
+    /// Keep run history in `{workspace}/runs` so it survives a restart.
+    #[serde(default = "default_persist_runs")]
+    pub persist: bool,
+    /// Hours a finished run can still be fetched.
+    #[serde(default = "default_run_retention_hours")]
+    pub retention_hours: u64,
+    /// Seconds a background run may take before it is stopped. 0 = no limit.
+    #[serde(default)]
+    pub timeout_seconds: u64,
+}
+
+impl Default for RunsConfig {
+    fn default() -> Self {
+        Self {
+            persist: default_persist_runs(),
+            retention_hours: default_run_retention_hours(),
+            timeout_seconds: 0,
+        }
+    }
+}
+
 // ============================================================================
 // SessionsConfig
 // ============================================================================
@@ -353,6 +1070,24 @@ pub struct SessionsConfig {
     /// Event log compaction mode after snapshots.
     #[serde(default)]
     pub compaction: CompactionMode,
+    /// Maximum LLM runs in flight across all sessions. 0 = unlimited.
+    #[serde(default)]
+    pub max_concurrent_runs: usize,
+    /// Overload thresholds for rejecting low-priority HTTP runs.
+    #[serde(default)]
+    pub load_shedding: LoadSheddingConfig,
+    /// How session events are batched into event log writes.
+    #[serde(default)]
+    pub event_batch: EventBatchConfig,
+    /// Moving old completed sessions out of the sessions directory.
+    #[serde(default)]
+    pub archive: SessionArchiveConfig,
+    /// Return model reasoning to callers with admin access or a
+    /// `runs:reasoning` service account scope. Reasoning is
+    /// always recorded in the session event log; by default it never
+    /// reaches clients.
+    #[serde(default)]
+    pub expose_reasoning: bool,
 }
 
 impl Default for SessionsConfig {
@@ -360,13 +1095,189 @@ impl Default for SessionsConfig {
         Self {
             ttl_hours: default_ttl_hours(),
             compaction: CompactionMode::default(),
+            max_concurrent_runs: 0,
+            load_shedding: LoadSheddingConfig::default(),
+            event_batch: EventBatchConfig::default(),
+            archive: SessionArchiveConfig::default(),
+            expose_reasoning: false,
+        }
+    }
+}
+
+/// Load shedding thresholds.
+///
+/// While either threshold is exceeded, low-priority messages sent over the
+/// HTTP API are rejected with 503 and `Retry-After`.
+#[derive(Debug, Clone, Deserialize)]
+#[serde(default)]
+pub struct LoadSheddingConfig {
+    /// Shed once this many runs are waiting for a slot. 0 = disabled.
+    pub max_queued_runs: usize,
+    /// Shed once the p95 run duration over the last minute exceeds this many
+    /// milliseconds. 0 = disabled.
+    pub max_p95_latency_ms: u64,
+    /// `Retry-After` value sent with shed responses, in seconds.
+    pub retry_after_seconds: u64,
+}
+
+impl Default for LoadSheddingConfig {
+    fn default() -> Self {
+        Self {
+            max_queued_runs: 0,
+            max_p95_latency_ms: 0,
+            retry_after_seconds: 5,
         }
     }
 }
 
+/// Event log write batching.
+///
+/// Each session queues its events (messages, tool calls, status changes) and
+/// appends them in one write once either limit is reached. Larger batches
+/// mean fewer writes during tool-heavy runs; events still queued when the
+/// process crashes are lost. Graceful shutdown always flushes.
+#[derive(Debug, Clone, Copy, Deserialize)]
+#[serde(default)]
+pub struct EventBatchConfig {
+    /// Flush once this many events are queued.
+    pub max_events: usize,
+    /// Flush queued events at least this often, in milliseconds.
+    pub flush_interval_ms: u64,
+}
+
+impl EventBatchConfig {
+    pub fn flush_interval(&self) -> std::time::Duration {
+        std::time::Duration::from_millis(self.flush_interval_ms.max(1))
+    }
+}
+
+impl Default for EventBatchConfig {
+    fn default() -> Self {
+        Self {
+            max_events: 10,
+            flush_interval_ms: 100,
+        }
+    }
+}
+
+/// Cold archive for completed sessions.
+///
+/// Completed sessions older than `after_days` are written as one compressed
+/// JSONL file each to the archive and deleted from the sessions directory.
+/// Session detail requests read them back from the archive.
+#[derive(Debug, Clone, Default, Deserialize)]
+#[serde(default)]
+pub struct SessionArchiveConfig {
+    /// Days after a session completes before it is archived. 0 = never.
+    pub after_days: u64,
+    /// Local archive directory (relative to config file). Defaults to
+    /// `{workspace}/archive/sessions`. Ignored when `s3` is set.
+    pub path: Option<PathBuf>,
+    /// Archive to an S3-compatible bucket instead of a local directory.
+    pub s3: Option<S3ArchiveConfig>,
+}
+
+/// S3-compatible bucket for the session archive.
+#[derive(Debug, Clone, Deserialize)]
+pub struct S3ArchiveConfig {
+    pub bucket: String,
+    /// Key prefix for archived sessions, e.g. `duragent/sessions/`.
+    #[serde(default)]
+    pub prefix: String,
+    #[serde(default = "default_s3_region")]
+    pub region: String,
+    /// Endpoint for S3-compatible services (MinIO, R2). Defaults to AWS.
+    #[serde(default)]
+    pub endpoint: Option<String>,
+    pub access_key_id: String,
+    pub secret_access_key: String,
+}
+
+fn default_s3_region() -> String {
+    "us-east-1".to_string()
+}
+
 // Re-export CompactionMode from duragent-types
 pub use duragent_types::session::CompactionMode;
 
+// ============================================================================
+// BundlesConfig
+// ============================================================================
+
+/// Agent bundle import configuration.
+#[derive(Debug, Clone, Default, Deserialize)]
+#[serde(default)]
+pub struct BundlesConfig {
+    /// Reject bundles that are unsigned or not signed by a trusted key.
+    pub require_signature: bool,
+    /// Public key files trusted for bundle signature verification
+    /// (relative to the config file).
+    pub trusted_keys: Vec<PathBuf>,
+}
+
+// ============================================================================
+// OutboundConfig
+// ============================================================================
+
+/// Outbound HTTP configuration for LLM provider clients.
+///
+/// `HTTPS_PROXY`, `HTTP_PROXY`, `ALL_PROXY` and `NO_PROXY` are honored when
+/// no proxy is configured here.
+#[derive(Debug, Clone, Deserialize)]
+#[serde(default)]
+pub struct OutboundConfig {
+    /// TCP connect timeout for provider requests.
+    pub connect_timeout_seconds: u64,
+    /// Total timeout for provider requests (connect + send + receive).
+    pub request_timeout_seconds: u64,
+    /// Maximum idle pooled connections kept per host (unlimited if unset).
+    pub pool_max_idle_per_host: Option<usize>,
+    /// How long an idle pooled connection is kept before closing.
+    pub pool_idle_timeout_seconds: u64,
+    /// TCP keep-alive interval for pooled connections (disabled if unset).
+    pub tcp_keepalive_seconds: Option<u64>,
+    /// Proxy URL for all provider traffic (e.g. `http://proxy.corp:3128`).
+    pub proxy: Option<String>,
+    /// Comma-separated hosts that bypass `proxy`, in `NO_PROXY` syntax.
+    pub no_proxy: Option<String>,
+    /// PEM bundle of additional CA certificates to trust
+    /// (relative to the config file).
+    pub ca_bundle: Option<PathBuf>,
+    /// Per-provider overrides, keyed by provider name (e.g. `anthropic`).
+    pub providers: std::collections::HashMap<String, ProviderOutboundConfig>,
+}
+
+impl Default for OutboundConfig {
+    fn default() -> Self {
+        Self {
+            connect_timeout_seconds: 30,
+            request_timeout_seconds: 300,
+            pool_max_idle_per_host: None,
+            pool_idle_timeout_seconds: 90,
+            tcp_keepalive_seconds: None,
+            proxy: None,
+            no_proxy: None,
+            ca_bundle: None,
+            providers: std::collections::HashMap::new(),
+        }
+    }
+}
+
+/// Per-provider outbound HTTP overrides and rate limit budgets.
+#[derive(Debug, Clone, Default, Deserialize)]
+#[serde(default)]
+pub struct ProviderOutboundConfig {
+    /// Proxy URL for this provider. An empty string connects directly.
+    pub proxy: Option<String>,
+    /// Requests sent to this provider per minute, at most.
+    pub requests_per_minute: Option<u32>,
+    /// Tokens (prompt and completion) sent to this provider per minute, at most.
+    pub tokens_per_minute: Option<u64>,
+    /// How long a request waits for budget before failing as rate limited
+    /// (default: 60).
+    pub max_queue_seconds: Option<u64>,
+}
+
 // ============================================================================
 // Private Helpers (Serde Defaults)
 // ============================================================================
@@ -562,6 +1473,9 @@ mod tests {
         assert_eq!(config.server.request_timeout_seconds, 300);
         assert_eq!(config.server.idle_timeout_seconds, 60);
         assert_eq!(config.server.keep_alive_interval_seconds, 15);
+        assert!(config.server.request_validation);
+        assert_eq!(config.server.normalized_base_path(), "");
+        assert!(config.server.external_url.is_none());
         assert!(config.workspace.is_none());
         assert!(config.agents_dir.is_none());
         assert!(config.services.session.path.is_none());
@@ -607,6 +1521,26 @@ agents_dir: ".duragent/agents-custom"
         );
     }
 
+    #[tokio::test]
+    async fn test_load_route_timeouts() {
+        let mut file = NamedTempFile::new().unwrap();
+        writeln!(
+            file,
+            r#"
+server:
+  route_timeouts:
+    "/api/v1/sessions/{{session_id}}/messages": 900
+    "/api/v1/agents/bulk": 0
+"#
+        )
+        .unwrap();
+
+        let config = Config::load(file.path().to_str().unwrap()).await.unwrap();
+        let timeouts = &config.server.route_timeouts;
+        assert_eq!(timeouts["/api/v1/sessions/{session_id}/messages"], 900);
+        assert_eq!(timeouts["/api/v1/agents/bulk"], 0);
+    }
+
     #[tokio::test]
     async fn test_load_partial_yaml_uses_defaults() {
         let mut file = NamedTempFile::new().unwrap();
@@ -844,6 +1778,194 @@ sandbox:
         assert_eq!(config.sandbox.mode, SandboxMode::Trust);
     }
 
+    #[tokio::test]
+    async fn test_request_validation_toggle_from_env() {
+        // SAFETY: Single-threaded test
+        unsafe { std::env::set_var("DURAGENT_TEST_REQUEST_VALIDATION", "false") };
+
+        let mut file = NamedTempFile::new().unwrap();
+        writeln!(
+            file,
+            r#"
+server:
+  request_validation: ${{DURAGENT_TEST_REQUEST_VALIDATION:-true}}
+"#
+        )
+        .unwrap();
+
+        let config = Config::load(file.path().to_str().unwrap()).await.unwrap();
+        assert!(!config.server.request_validation);
+
+        unsafe { std::env::remove_var("DURAGENT_TEST_REQUEST_VALIDATION") };
+    }
+
+    #[tokio::test]
+    async fn test_server_proxy_config() {
+        let mut file = NamedTempFile::new().unwrap();
+        writeln!(
+            file,
+            r#"
+server:
+  port: 9090
+  base_path: duragent/
+  external_url: https://example.com/duragent
+"#
+        )
+        .unwrap();
+
+        let config = Config::load(file.path().to_str().unwrap()).await.unwrap();
+        assert_eq!(config.server.normalized_base_path(), "/duragent");
+        assert_eq!(
+            config.server.external_url.as_deref(),
+            Some("https://example.com/duragent")
+        );
+        assert_eq!(
+            config.server.local_url(9090),
+            "http://127.0.0.1:9090/duragent"
+        );
+    }
+
+    #[test]
+    fn test_normalized_base_path_root() {
+        for base_path in ["", "/", "  "] {
+            let server = ServerConfig {
+                base_path: base_path.to_string(),
+                ..Default::default()
+            };
+            assert_eq!(server.normalized_base_path(), "");
+            assert_eq!(server.local_url(8080), "http://127.0.0.1:8080");
+        }
+    }
+
+    #[tokio::test]
+    async fn test_outbound_config() {
+        let mut file = NamedTempFile::new().unwrap();
+        writeln!(
+            file,
+            r#"
+outbound:
+  proxy: http://proxy.corp:3128
+  no_proxy: localhost,.internal
+  ca_bundle: certs/corp-ca.pem
+  providers:
+    ollama:
+      proxy: ""
+"#
+        )
+        .unwrap();
+
+        let config = Config::load(file.path().to_str().unwrap()).await.unwrap();
+        let outbound = &config.outbound;
+        assert_eq!(outbound.proxy.as_deref(), Some("http://proxy.corp:3128"));
+        assert_eq!(outbound.no_proxy.as_deref(), Some("localhost,.internal"));
+        assert_eq!(outbound.ca_bundle, Some(PathBuf::from("certs/corp-ca.pem")));
+        assert_eq!(outbound.providers["ollama"].proxy.as_deref(), Some(""));
+    }
+
+    #[tokio::test]
+    async fn test_outbound_pool_config() {
+        let mut file = NamedTempFile::new().unwrap();
+        writeln!(
+            file,
+            r#"
+outbound:
+  request_timeout_seconds: 120
+  pool_max_idle_per_host: 16
+  tcp_keepalive_seconds: 30
+"#
+        )
+        .unwrap();
+
+        let config = Config::load(file.path().to_str().unwrap()).await.unwrap();
+        let outbound = &config.outbound;
+        assert_eq!(outbound.connect_timeout_seconds, 30);
+        assert_eq!(outbound.request_timeout_seconds, 120);
+        assert_eq!(outbound.pool_max_idle_per_host, Some(16));
+        assert_eq!(outbound.pool_idle_timeout_seconds, 90);
+        assert_eq!(outbound.tcp_keepalive_seconds, Some(30));
+    }
+
+    #[tokio::test]
+    async fn test_agents_config() {
+        let mut file = NamedTempFile::new().unwrap();
+        writeln!(
+            file,
+            r#"
+agents:
+  trash_retention_hours: 24
+  watch_interval_seconds: 5
+"#
+        )
+        .unwrap();
+
+        let config = Config::load(file.path().to_str().unwrap()).await.unwrap();
+        assert_eq!(config.agents.trash_retention_hours, 24);
+        assert_eq!(config.agents.watch_interval_seconds, 5);
+        assert_eq!(Config::default().agents.trash_retention_hours, 168);
+        assert_eq!(Config::default().agents.watch_interval_seconds, 30);
+    }
+
+    #[tokio::test]
+    async fn test_runs_config() {
+        let mut file = NamedTempFile::new().unwrap();
+        writeln!(
+            file,
+            r#"
+runs:
+  persist: false
+  timeout_seconds: 600
+"#
+        )
+        .unwrap();
+
+        let config = Config::load(file.path().to_str().unwrap()).await.unwrap();
+        assert!(!config.runs.persist);
+        assert_eq!(config.runs.retention_hours, 1);
+        assert_eq!(config.runs.timeout_seconds, 600);
+        assert!(Config::default().runs.persist);
+    }
+
+    #[tokio::test]
+    async fn test_bundles_config() {
+        let mut file = NamedTempFile::new().unwrap();
+        writeln!(
+            file,
+            r#"
+bundles:
+  require_signature: true
+  trusted_keys:
+    - keys/release.pub
+"#
+        )
+        .unwrap();
+
+        let config = Config::load(file.path().to_str().unwrap()).await.unwrap();
+        assert!(config.bundles.require_signature);
+        assert_eq!(
+            config.bundles.trusted_keys,
+            vec![PathBuf::from("keys/release.pub")]
+        );
+    }
+
+    #[tokio::test]
+    async fn test_callbacks_config() {
+        let mut file = NamedTempFile::new().unwrap();
+        writeln!(
+            file,
+            r#"
+callbacks:
+  signing_secret: s3cret
+  max_retries: 2
+"#
+        )
+        .unwrap();
+
+        let config = Config::load(file.path().to_str().unwrap()).await.unwrap();
+        assert_eq!(config.callbacks.signing_secret.as_deref(), Some("s3cret"));
+        assert_eq!(config.callbacks.max_retries, 2);
+        assert_eq!(config.callbacks.timeout_seconds, 10);
+    }
+
     #[tokio::test]
     async fn test_routing_multiple_match_conditions() {
         let mut file = NamedTempFile::new().unwrap();
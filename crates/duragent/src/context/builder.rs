@@ -92,6 +92,14 @@ impl ContextBuilder {
         self
     }
 
+    /// Add custom system blocks.
+    pub fn with_blocks(mut self, blocks: Vec<SystemBlock>) -> Self {
+        for block in blocks {
+            self.context.add_block(block);
+        }
+        self
+    }
+
     /// Add pre-loaded directives to the context.
     pub fn with_directives(mut self, directives: Vec<DirectiveEntry>) -> Self {
         for directive in directives {
@@ -162,6 +170,8 @@ mod tests {
                 description: None,
                 version: None,
                 labels: HashMap::new(),
+                tags: Vec::new(),
+                project: None,
             },
             model: ModelConfig {
                 provider: Provider::Other("test".to_string()),
@@ -171,17 +181,31 @@ mod tests {
                 max_input_tokens: None,
                 max_output_tokens: None,
             },
+            model_routing: None,
+            best_of: None,
+            ensemble: None,
+            planning: None,
+            router: None,
             soul: soul.map(|s| s.to_string()),
             system_prompt: system_prompt.map(|s| s.to_string()),
             instructions: instructions.map(|s| s.to_string()),
             skills: Vec::new(),
             session: AgentSessionConfig::default(),
             memory: None,
+            prompts: Vec::new(),
+            examples: None,
+            language: None,
+            input_schema: None,
+            stream_processors: Vec::new(),
+            post_processors: Vec::new(),
             tools: Vec::new(),
             policy: ToolPolicy::default(),
             hooks: HooksConfig::default(),
             access: None,
             agent_dir: PathBuf::from("/tmp/test-agent"),
+            enabled: true,
+            provenance: Default::default(),
+            digest: String::new(),
         }
     }
 
@@ -238,6 +238,8 @@ mod tests {
     fn default_directives_returns_memory_when_enabled() {
         let agent = stub_agent(Some(AgentMemoryConfig {
             backend: "filesystem".to_string(),
+            user: None,
+            entities: None,
         }));
         let directives = default_directives(&agent);
 
@@ -318,6 +320,8 @@ mod tests {
                 description: None,
                 version: None,
                 labels: HashMap::new(),
+                tags: Vec::new(),
+                project: None,
             },
             model: ModelConfig {
                 provider: Provider::Other("test".to_string()),
@@ -327,17 +331,31 @@ mod tests {
                 max_input_tokens: None,
                 max_output_tokens: None,
             },
+            model_routing: None,
+            best_of: None,
+            ensemble: None,
+            planning: None,
+            router: None,
             soul: None,
             system_prompt: None,
             instructions: None,
             skills: Vec::new(),
             session: AgentSessionConfig::default(),
             memory,
+            prompts: Vec::new(),
+            examples: None,
+            language: None,
+            input_schema: None,
+            stream_processors: Vec::new(),
+            post_processors: Vec::new(),
             tools: Vec::new(),
             policy: ToolPolicy::default(),
             hooks: HooksConfig::default(),
             access: None,
             agent_dir: PathBuf::from("/tmp/test-agent"),
+            enabled: true,
+            provenance: Default::default(),
+            digest: String::new(),
         }
     }
 }
@@ -14,7 +14,10 @@ mod truncation;
 
 pub use builder::ContextBuilder;
 pub use directives::{load_all_directives, load_all_directives_async};
-pub use template::interpolate_template_vars;
+pub use template::{
+    FUNCTIONS as TEMPLATE_FUNCTIONS, Rendered, TemplateContext, interpolate_template_vars,
+    render_template,
+};
 pub use tokens::*;
 pub use truncation::*;
 
@@ -129,8 +132,16 @@ pub mod priority {
     pub const SOUL: i32 = 0;
     /// Core system prompt.
     pub const SYSTEM_PROMPT: i32 = 100;
+    /// Shared prompts from the prompt library, in manifest order.
+    pub const PROMPTS: i32 = 150;
     /// Agent-level instructions.
     pub const INSTRUCTIONS: i32 = 200;
+    /// Few-shot examples, after the instructions they illustrate.
+    pub const EXAMPLES: i32 = 250;
+    /// Facts remembered about the session's user.
+    pub const USER_MEMORY: i32 = 260;
+    /// Language to reply in.
+    pub const LANGUAGE: i32 = 270;
     /// Hook-injected blocks.
     pub const HOOK: i32 = 300;
     /// Runtime directives.
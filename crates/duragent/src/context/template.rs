@@ -1,13 +1,59 @@
-//! Template variable interpolation for agent spec content.
+//! Prompt templating for agent spec content.
 //!
-//! Replaces `{{var}}` placeholders in soul, system_prompt, and instructions
-//! with runtime values. Unknown variables are left as-is.
+//! Renders `{{ ... }}` tags in soul, system_prompt, and instructions. A tag
+//! is a variable (`{{agent.name}}`), a function call (`{{now "%A"}}`), or a
+//! pipeline feeding each result into the next function as its first
+//! argument (`{{memory | truncate_tokens 500}}`). `{{#if expr}}` and
+//! `{{#unless expr}}` sections, with an optional `{{else}}`, include text
+//! conditionally.
+//!
+//! Rendering never fails: a tag that can't be evaluated is left as-is and
+//! reported as a warning, so an unknown variable like `{{foo}}` passes
+//! through unchanged.
+
+use std::path::{Component, Path};
 
-use chrono::Utc;
+use chrono::{DateTime, NaiveDate, Utc};
+use serde_json::{Map, Value};
 
+use super::tokens::estimate_tokens;
 use crate::agent::AgentSpec;
 
-/// Replace `{{var}}` placeholders with runtime values.
+/// Functions available in tags, with a one-line usage each.
+pub const FUNCTIONS: &[(&str, &str)] = &[
+    (
+        "now",
+        "now [format] — current UTC time, strftime format (default RFC 3339)",
+    ),
+    (
+        "format_date",
+        "format_date value format — reformat an RFC 3339 or YYYY-MM-DD date",
+    ),
+    ("json", "json value — value as compact JSON"),
+    ("json_pretty", "json_pretty value — value as indented JSON"),
+    (
+        "truncate_tokens",
+        "truncate_tokens value n — cut text to about n tokens",
+    ),
+    ("upper", "upper value — uppercase"),
+    ("lower", "lower value — lowercase"),
+    ("trim", "trim value — strip surrounding whitespace"),
+    (
+        "default",
+        "default value fallback — fallback when value is empty or missing",
+    ),
+    ("join", "join list separator — join list items"),
+    (
+        "file",
+        "file path — contents of a file in the agent directory",
+    ),
+    (
+        "memory",
+        "memory — the agent's long-term MEMORY.md (empty if none)",
+    ),
+];
+
+/// Replace `{{ ... }}` tags with runtime values.
 ///
 /// Supported variables:
 /// - `{{date}}` — Current date (ISO 8601, e.g. `2026-02-16`)
@@ -15,40 +61,554 @@ use crate::agent::AgentSpec;
 /// - `{{agent.name}}` — Agent metadata name
 /// - `{{agent.home}}` — Agent directory path
 ///
-/// Unknown variables like `{{foo}}` are left unchanged.
+/// See [`FUNCTIONS`] for functions. Unknown variables like `{{foo}}` are
+/// left unchanged.
 pub fn interpolate_template_vars(input: &str, agent: &AgentSpec) -> String {
-    let now = Utc::now();
-    let mut result = String::with_capacity(input.len());
-    let mut rest = input;
+    render_template(input, &TemplateContext::new().with_agent(agent)).output
+}
+
+// ============================================================================
+// Context
+// ============================================================================
+
+/// Values and files a template can reach.
+#[derive(Debug, Clone)]
+pub struct TemplateContext<'a> {
+    agent: Option<&'a AgentSpec>,
+    vars: Map<String, Value>,
+    now: DateTime<Utc>,
+}
+
+impl Default for TemplateContext<'_> {
+    fn default() -> Self {
+        Self::new()
+    }
+}
+
+impl<'a> TemplateContext<'a> {
+    /// Context with the `date` and `time` variables.
+    pub fn new() -> Self {
+        let now = Utc::now();
+        let mut vars = Map::new();
+        vars.insert(
+            "date".to_string(),
+            Value::String(now.format("%Y-%m-%d").to_string()),
+        );
+        vars.insert(
+            "time".to_string(),
+            Value::String(now.format("%H:%M UTC").to_string()),
+        );
+        Self {
+            agent: None,
+            vars,
+            now,
+        }
+    }
+
+    /// Add the `agent` variables and the agent directory for `file` and
+    /// `memory`.
+    #[must_use]
+    pub fn with_agent(mut self, agent: &'a AgentSpec) -> Self {
+        let mut fields = Map::new();
+        fields.insert(
+            "name".to_string(),
+            Value::String(agent.metadata.name.clone()),
+        );
+        fields.insert(
+            "home".to_string(),
+            Value::String(agent.agent_dir.display().to_string()),
+        );
+        self.vars.insert("agent".to_string(), Value::Object(fields));
+        self.agent = Some(agent);
+        self
+    }
 
+    /// Add caller-supplied variables. They take precedence over built-ins.
+    #[must_use]
+    pub fn with_vars(mut self, vars: Map<String, Value>) -> Self {
+        self.vars.extend(vars);
+        self
+    }
+
+    fn lookup(&self, path: &str) -> Option<&Value> {
+        let mut parts = path.split('.');
+        let mut value = self.vars.get(parts.next()?)?;
+        for part in parts {
+            value = match value {
+                Value::Object(map) => map.get(part)?,
+                Value::Array(items) => items.get(part.parse::<usize>().ok()?)?,
+                _ => return None,
+            };
+        }
+        Some(value)
+    }
+}
+
+/// Output of [`render_template`].
+#[derive(Debug, Clone, PartialEq)]
+pub struct Rendered {
+    pub output: String,
+    /// Tags left unrendered and malformed sections, in template order.
+    pub warnings: Vec<String>,
+}
+
+/// Render `input`, collecting a warning for each tag that couldn't be
+/// evaluated.
+pub fn render_template(input: &str, ctx: &TemplateContext<'_>) -> Rendered {
+    let mut warnings = Vec::new();
+    let mut tokens = tokenize(input).into_iter();
+    let (nodes, _) = parse_nodes(&mut tokens, &mut warnings, None);
+    let mut output = String::with_capacity(input.len());
+    render_nodes(&nodes, ctx, &mut output, &mut warnings);
+    Rendered { output, warnings }
+}
+
+// ============================================================================
+// Parsing
+// ============================================================================
+
+enum Token<'a> {
+    Text(&'a str),
+    /// Inside of a `{{ ... }}` tag.
+    Tag(&'a str),
+}
+
+enum Node<'a> {
+    Text(&'a str),
+    Tag(&'a str),
+    Section {
+        negate: bool,
+        condition: &'a str,
+        then: Vec<Node<'a>>,
+        otherwise: Vec<Node<'a>>,
+    },
+}
+
+/// How a run of nodes ended.
+enum End {
+    Else,
+    Close,
+    Eof,
+}
+
+fn tokenize(input: &str) -> Vec<Token<'_>> {
+    let mut tokens = Vec::new();
+    let mut rest = input;
     while let Some(start) = rest.find("{{") {
-        result.push_str(&rest[..start]);
+        if start > 0 {
+            tokens.push(Token::Text(&rest[..start]));
+        }
         let after_open = &rest[start + 2..];
+        match after_open.find("}}") {
+            Some(end) => {
+                tokens.push(Token::Tag(&after_open[..end]));
+                rest = &after_open[end + 2..];
+            }
+            None => {
+                // No closing `}}` — emit the `{{` literally and move on
+                tokens.push(Token::Text("{{"));
+                rest = after_open;
+            }
+        }
+    }
+    if !rest.is_empty() {
+        tokens.push(Token::Text(rest));
+    }
+    tokens
+}
+
+fn parse_nodes<'a>(
+    tokens: &mut std::vec::IntoIter<Token<'a>>,
+    warnings: &mut Vec<String>,
+    section: Option<&str>,
+) -> (Vec<Node<'a>>, End) {
+    let mut nodes = Vec::new();
+    while let Some(token) = tokens.next() {
+        let raw = match token {
+            Token::Text(text) => {
+                nodes.push(Node::Text(text));
+                continue;
+            }
+            Token::Tag(raw) => raw,
+        };
+        let tag = raw.trim();
+
+        let opened = tag
+            .strip_prefix("#if ")
+            .map(|c| (false, c))
+            .or_else(|| tag.strip_prefix("#unless ").map(|c| (true, c)));
+        if let Some((negate, condition)) = opened {
+            let keyword = if negate { "unless" } else { "if" };
+            let (then, end) = parse_nodes(tokens, warnings, Some(keyword));
+            let otherwise = match end {
+                End::Else => parse_nodes(tokens, warnings, Some(keyword)).0,
+                _ => Vec::new(),
+            };
+            nodes.push(Node::Section {
+                negate,
+                condition: condition.trim(),
+                then,
+                otherwise,
+            });
+            continue;
+        }
+
+        match (tag, section) {
+            ("else", Some(_)) => return (nodes, End::Else),
+            ("/if" | "/unless", Some(open)) => {
+                if tag[1..] != *open {
+                    warnings.push(format!("{{{{#{open}}}}} closed by {{{{{tag}}}}}"));
+                }
+                return (nodes, End::Close);
+            }
+            ("else" | "/if" | "/unless", None) => {
+                warnings.push(format!("{{{{{tag}}}}} without an open section"));
+                nodes.push(Node::Tag(raw));
+            }
+            _ => nodes.push(Node::Tag(raw)),
+        }
+    }
+    if let Some(open) = section {
+        warnings.push(format!("unclosed {{{{#{open}}}}} section"));
+    }
+    (nodes, End::Eof)
+}
+
+// ============================================================================
+// Rendering
+// ============================================================================
 
-        if let Some(end) = after_open.find("}}") {
-            let var_name = after_open[..end].trim();
-            match var_name {
-                "date" => result.push_str(&now.format("%Y-%m-%d").to_string()),
-                "time" => result.push_str(&now.format("%H:%M UTC").to_string()),
-                "agent.name" => result.push_str(&agent.metadata.name),
-                "agent.home" => result.push_str(&agent.agent_dir.display().to_string()),
-                _ => {
-                    // Unknown variable — leave as-is
-                    result.push_str("{{");
-                    result.push_str(&after_open[..end]);
-                    result.push_str("}}");
+fn render_nodes(
+    nodes: &[Node<'_>],
+    ctx: &TemplateContext<'_>,
+    out: &mut String,
+    warnings: &mut Vec<String>,
+) {
+    for node in nodes {
+        match node {
+            Node::Text(text) => out.push_str(text),
+            Node::Tag(raw) => match evaluate(raw.trim(), ctx) {
+                Ok(value) => out.push_str(&display(&value)),
+                Err(e) => {
+                    warnings.push(format!("{{{{{}}}}}: {e}", raw.trim()));
+                    out.push_str("{{");
+                    out.push_str(raw);
+                    out.push_str("}}");
                 }
+            },
+            Node::Section {
+                negate,
+                condition,
+                then,
+                otherwise,
+            } => {
+                // A missing variable is just false in a condition
+                let value = match evaluate(condition, ctx) {
+                    Ok(value) => value,
+                    Err(EvalError::UnknownVariable(_)) => Value::Null,
+                    Err(e) => {
+                        warnings.push(format!("{{{{#if {condition}}}}}: {e}"));
+                        Value::Null
+                    }
+                };
+                let branch = if truthy(&value) != *negate {
+                    then
+                } else {
+                    otherwise
+                };
+                render_nodes(branch, ctx, out, warnings);
             }
-            rest = &after_open[end + 2..];
+        }
+    }
+}
+
+/// Text for a rendered value. Strings are inserted as-is, `null` as
+/// nothing, and everything else as JSON.
+fn display(value: &Value) -> String {
+    match value {
+        Value::String(s) => s.clone(),
+        Value::Null => String::new(),
+        other => other.to_string(),
+    }
+}
+
+fn truthy(value: &Value) -> bool {
+    match value {
+        Value::Null => false,
+        Value::Bool(b) => *b,
+        Value::Number(n) => n.as_f64() != Some(0.0),
+        Value::String(s) => !s.is_empty(),
+        Value::Array(items) => !items.is_empty(),
+        Value::Object(map) => !map.is_empty(),
+    }
+}
+
+// ============================================================================
+// Expressions
+// ============================================================================
+
+#[derive(Debug, thiserror::Error)]
+enum EvalError {
+    #[error("unknown variable '{0}'")]
+    UnknownVariable(String),
+
+    #[error("unknown function '{0}'")]
+    UnknownFunction(String),
+
+    #[error("{0}")]
+    Syntax(String),
+
+    #[error("{function}: {message}")]
+    Call { function: String, message: String },
+}
+
+#[derive(Debug, Clone, PartialEq)]
+enum Arg {
+    Literal(Value),
+    Path(String),
+}
+
+fn evaluate(expr: &str, ctx: &TemplateContext<'_>) -> Result<Value, EvalError> {
+    let stages = lex(expr)?;
+    let mut value: Option<Value> = None;
+    let piped = stages.len() > 1;
+
+    for stage in stages {
+        let mut args = stage.into_iter();
+        let Some(first) = args.next() else {
+            return Err(EvalError::Syntax("empty pipeline stage".to_string()));
+        };
+        let function = match &first {
+            Arg::Path(name) if is_function(name) => Some(name.clone()),
+            _ => None,
+        };
+
+        value = Some(match (function, value.take()) {
+            (Some(name), input) => {
+                let mut values: Vec<Value> = input.into_iter().collect();
+                values.extend(args.map(|a| resolve(&a, ctx)));
+                call(&name, values, ctx)?
+            }
+            (None, None) if args.len() == 0 => match first {
+                Arg::Literal(v) => v,
+                // A bare unknown variable is an error; feeding one into a
+                // pipeline passes null so `default` can handle it
+                Arg::Path(path) => match ctx.lookup(&path) {
+                    Some(v) => v.clone(),
+                    None if piped => Value::Null,
+                    None => return Err(EvalError::UnknownVariable(path)),
+                },
+            },
+            (None, _) => {
+                let name = match first {
+                    Arg::Path(name) => name,
+                    Arg::Literal(v) => v.to_string(),
+                };
+                return Err(EvalError::UnknownFunction(name));
+            }
+        });
+    }
+    value.ok_or_else(|| EvalError::Syntax("empty tag".to_string()))
+}
+
+fn resolve(arg: &Arg, ctx: &TemplateContext<'_>) -> Value {
+    match arg {
+        Arg::Literal(v) => v.clone(),
+        Arg::Path(path) => ctx.lookup(path).cloned().unwrap_or(Value::Null),
+    }
+}
+
+/// Split a tag into pipeline stages of whitespace-separated arguments.
+fn lex(expr: &str) -> Result<Vec<Vec<Arg>>, EvalError> {
+    let mut stages = vec![Vec::new()];
+    let mut chars = expr.chars().peekable();
+    while let Some(&c) = chars.peek() {
+        if c.is_whitespace() {
+            chars.next();
+        } else if c == '|' {
+            chars.next();
+            stages.push(Vec::new());
+        } else if c == '"' {
+            chars.next();
+            let mut s = String::new();
+            loop {
+                match chars.next() {
+                    Some('"') => break,
+                    Some('\\') => match chars.next() {
+                        Some('n') => s.push('\n'),
+                        Some('t') => s.push('\t'),
+                        Some(other) => s.push(other),
+                        None => break,
+                    },
+                    Some(other) => s.push(other),
+                    None => return Err(EvalError::Syntax("unterminated string".to_string())),
+                }
+            }
+            stages
+                .last_mut()
+                .unwrap()
+                .push(Arg::Literal(Value::String(s)));
         } else {
-            // No closing `}}` — emit the `{{` literally and move on
-            result.push_str("{{");
-            rest = after_open;
+            let mut word = String::new();
+            while let Some(&c) = chars.peek() {
+                if c.is_whitespace() || c == '|' || c == '"' {
+                    break;
+                }
+                word.push(c);
+                chars.next();
+            }
+            let arg = match word.as_str() {
+                "true" => Arg::Literal(Value::Bool(true)),
+                "false" => Arg::Literal(Value::Bool(false)),
+                "null" => Arg::Literal(Value::Null),
+                _ => match serde_json::from_str::<serde_json::Number>(&word) {
+                    Ok(n) => Arg::Literal(Value::Number(n)),
+                    Err(_) => Arg::Path(word),
+                },
+            };
+            stages.last_mut().unwrap().push(arg);
         }
     }
+    Ok(stages)
+}
+
+// ============================================================================
+// Functions
+// ============================================================================
+
+fn is_function(name: &str) -> bool {
+    FUNCTIONS.iter().any(|(f, _)| *f == name)
+}
+
+fn call(name: &str, args: Vec<Value>, ctx: &TemplateContext<'_>) -> Result<Value, EvalError> {
+    let fail = |message: &str| EvalError::Call {
+        function: name.to_string(),
+        message: message.to_string(),
+    };
+    static NULL: Value = Value::Null;
+    let arg = |i: usize| args.get(i).unwrap_or(&NULL);
+    let text = |i: usize| display(arg(i));
+    let count = |n: usize| {
+        if args.len() == n {
+            Ok(())
+        } else {
+            Err(fail(&format!(
+                "expects {n} argument(s), got {}",
+                args.len()
+            )))
+        }
+    };
+
+    let value = match name {
+        "now" => {
+            if args.len() > 1 {
+                return Err(fail("expects at most 1 argument"));
+            }
+            let format = args
+                .first()
+                .map_or("%Y-%m-%dT%H:%M:%SZ".to_string(), display);
+            Value::String(format_time(&ctx.now, &format).map_err(|e| fail(&e))?)
+        }
+        "format_date" => {
+            count(2)?;
+            let input = text(0);
+            let date = DateTime::parse_from_rfc3339(&input)
+                .map(|d| d.with_timezone(&Utc))
+                .or_else(|_| {
+                    NaiveDate::parse_from_str(&input, "%Y-%m-%d")
+                        .map(|d| d.and_hms_opt(0, 0, 0).unwrap().and_utc())
+                })
+                .map_err(|_| fail(&format!("'{input}' is not a date")))?;
+            Value::String(format_time(&date, &text(1)).map_err(|e| fail(&e))?)
+        }
+        "json" => {
+            count(1)?;
+            Value::String(arg(0).to_string())
+        }
+        "json_pretty" => {
+            count(1)?;
+            Value::String(serde_json::to_string_pretty(arg(0)).unwrap_or_default())
+        }
+        "truncate_tokens" => {
+            count(2)?;
+            let max = arg(1)
+                .as_u64()
+                .ok_or_else(|| fail("token limit must be a non-negative integer"))?;
+            Value::String(truncate_tokens(&text(0), max as usize))
+        }
+        "upper" => {
+            count(1)?;
+            Value::String(text(0).to_uppercase())
+        }
+        "lower" => {
+            count(1)?;
+            Value::String(text(0).to_lowercase())
+        }
+        "trim" => {
+            count(1)?;
+            Value::String(text(0).trim().to_string())
+        }
+        "default" => {
+            count(2)?;
+            if truthy(arg(0)) {
+                arg(0).clone()
+            } else {
+                arg(1).clone()
+            }
+        }
+        "join" => {
+            count(2)?;
+            let Value::Array(items) = arg(0) else {
+                return Err(fail("first argument must be a list"));
+            };
+            let items: Vec<String> = items.iter().map(display).collect();
+            Value::String(items.join(&text(1)))
+        }
+        "file" => {
+            count(1)?;
+            let agent = ctx.agent.ok_or_else(|| fail("needs an agent"))?;
+            let path = text(0);
+            let relative = Path::new(&path);
+            if !relative
+                .components()
+                .all(|c| matches!(c, Component::Normal(_) | Component::CurDir))
+            {
+                return Err(fail("path must be relative to the agent directory"));
+            }
+            let content = std::fs::read_to_string(agent.agent_dir.join(relative))
+                .map_err(|e| fail(&format!("{path}: {e}")))?;
+            Value::String(content)
+        }
+        "memory" => {
+            count(0)?;
+            let agent = ctx.agent.ok_or_else(|| fail("needs an agent"))?;
+            let path = agent.agent_dir.join("memory").join("MEMORY.md");
+            Value::String(std::fs::read_to_string(path).unwrap_or_default())
+        }
+        other => return Err(EvalError::UnknownFunction(other.to_string())),
+    };
+    Ok(value)
+}
+
+fn format_time(time: &DateTime<Utc>, format: &str) -> Result<String, String> {
+    use std::fmt::Write;
+
+    let mut out = String::new();
+    write!(out, "{}", time.format(format)).map_err(|_| format!("invalid format '{format}'"))?;
+    Ok(out)
+}
 
-    result.push_str(rest);
-    result
+/// Cut `text` to about `max_tokens` tokens, by the same estimate the
+/// context budget uses, marking the cut with an ellipsis.
+fn truncate_tokens(text: &str, max_tokens: usize) -> String {
+    if estimate_tokens(text) as usize <= max_tokens {
+        return text.to_string();
+    }
+    let mut end = (max_tokens * 4).min(text.len());
+    while !text.is_char_boundary(end) {
+        end -= 1;
+    }
+    format!("{}…", text[..end].trim_end())
 }
 
 #[cfg(test)]
@@ -68,6 +628,8 @@ mod tests {
                 description: None,
                 version: None,
                 labels: HashMap::new(),
+                tags: Vec::new(),
+                project: None,
             },
             model: ModelConfig {
                 provider: Provider::Other("test".to_string()),
@@ -77,20 +639,126 @@ mod tests {
                 max_input_tokens: None,
                 max_output_tokens: None,
             },
+            model_routing: None,
+            best_of: None,
+            ensemble: None,
+            planning: None,
+            router: None,
             soul: None,
             system_prompt: None,
             instructions: None,
             skills: Vec::new(),
             session: AgentSessionConfig::default(),
             memory: None,
+            prompts: Vec::new(),
+            examples: None,
+            language: None,
+            input_schema: None,
+            stream_processors: Vec::new(),
+            post_processors: Vec::new(),
             tools: Vec::new(),
             policy: ToolPolicy::default(),
             hooks: HooksConfig::default(),
             access: None,
             agent_dir: PathBuf::from("/opt/agents/iova/.duragent/agents/iova"),
+            enabled: true,
+            provenance: Default::default(),
+            digest: String::new(),
         }
     }
 
+    fn render(input: &str, agent: &AgentSpec, vars: Value) -> Rendered {
+        let Value::Object(vars) = vars else {
+            panic!("vars must be an object");
+        };
+        render_template(
+            input,
+            &TemplateContext::new().with_agent(agent).with_vars(vars),
+        )
+    }
+
+    #[test]
+    fn functions_and_pipelines() {
+        let agent = test_agent();
+        let vars = serde_json::json!({
+            "user": {"name": "ada", "tags": ["admin", "ops"]},
+            "since": "2026-01-15T10:00:00Z",
+        });
+        let result = render(
+            r#"{{user.name | upper}} ({{join user.tags ", "}}) since {{format_date since "%d %b"}}: {{json user.tags}}"#,
+            &agent,
+            vars,
+        );
+        assert_eq!(
+            result.output,
+            r#"ADA (admin, ops) since 15 Jan: ["admin","ops"]"#
+        );
+        assert!(result.warnings.is_empty());
+    }
+
+    #[test]
+    fn missing_values_fall_back_in_pipelines() {
+        let agent = test_agent();
+        let result = render(
+            r#"Hi {{nickname | default "there"}}"#,
+            &agent,
+            serde_json::json!({}),
+        );
+        assert_eq!(result.output, "Hi there");
+        assert!(result.warnings.is_empty());
+    }
+
+    #[test]
+    fn truncates_by_tokens() {
+        let agent = test_agent();
+        let vars = serde_json::json!({"doc": "one two three four five six"});
+        let result = render("{{truncate_tokens doc 2}}", &agent, vars);
+        assert_eq!(result.output, "one two…");
+    }
+
+    #[test]
+    fn conditional_sections() {
+        let agent = test_agent();
+        let template = "{{#if vip}}VIP{{else}}regular{{/if}}{{#unless quiet}}!{{/unless}}";
+        let result = render(template, &agent, serde_json::json!({"vip": true}));
+        assert_eq!(result.output, "VIP!");
+        let result = render(template, &agent, serde_json::json!({"quiet": "yes"}));
+        assert_eq!(result.output, "regular");
+        assert!(result.warnings.is_empty());
+    }
+
+    #[test]
+    fn malformed_sections_and_calls_are_reported() {
+        let agent = test_agent();
+        let result = render(
+            "{{#if a}}open {{upper}} {{nope 1}} {{/if",
+            &agent,
+            serde_json::json!({"a": 1}),
+        );
+        assert_eq!(result.output, "open {{upper}} {{nope 1}} {{/if");
+        assert_eq!(result.warnings.len(), 3);
+        assert!(result.warnings[0].contains("unclosed"));
+        assert!(result.warnings[2].contains("unknown function 'nope'"));
+    }
+
+    #[test]
+    fn reads_files_inside_agent_dir() {
+        let tmp = tempfile::TempDir::new().unwrap();
+        std::fs::create_dir_all(tmp.path().join("memory")).unwrap();
+        std::fs::write(tmp.path().join("memory/MEMORY.md"), "likes tea").unwrap();
+        std::fs::write(tmp.path().join("faq.md"), "Q&A").unwrap();
+        let mut agent = test_agent();
+        agent.agent_dir = tmp.path().to_path_buf();
+
+        let result = render(
+            r#"{{memory}} / {{file "faq.md"}} / {{file "../secret"}}"#,
+            &agent,
+            serde_json::json!({}),
+        );
+        assert_eq!(result.output, r#"likes tea / Q&A / {{file "../secret"}}"#);
+        assert_eq!(result.warnings.len(), 1);
+    }
+
     #[test]
     fn interpolates_date() {
         let agent = test_agent();
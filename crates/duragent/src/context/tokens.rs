@@ -99,6 +99,7 @@ mod tests {
                 },
             }]),
             tool_call_id: None,
+            reasoning: None,
         };
         let tokens = estimate_message_tokens(&msg);
         // Should include content + tool_calls JSON + overhead
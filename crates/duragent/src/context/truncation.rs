@@ -4,8 +4,11 @@
 //! - Layer 3a: Individual tool result truncation
 //! - Layer 3b: Observation masking (replace middle tool results with placeholders)
 //! - Layer 3c: Iteration group dropping (remove oldest iteration groups)
+//!
+//! Plus recovery for requests the provider still rejects as too long for
+//! the model's context (see [`shrink_context`]).
 
-use crate::agent::ToolResultTruncation;
+use crate::agent::{ContextConfig, ToolResultTruncation};
 use crate::llm::{Message, Role};
 
 use super::tokens::{estimate_message_tokens, estimate_tokens};
@@ -225,6 +228,75 @@ fn find_iteration_groups(messages: &[Message], conversation_end_idx: usize) -> V
     groups
 }
 
+// ============================================================================
+// Context-Too-Long Recovery
+// ============================================================================
+
+/// How a request was shortened after the provider rejected it as too long.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub struct ContextRecovery {
+    /// Oldest messages dropped.
+    pub dropped_messages: u32,
+    /// Estimated tokens before and after shortening.
+    pub tokens_before: u32,
+    pub tokens_after: u32,
+}
+
+/// Shorten a request the provider rejected as too long for the model's
+/// context, so it can be retried once.
+///
+/// Applies the agent's context policy to the whole request instead of just
+/// the current run: tool results beyond `tool_result_keep_first` and
+/// `tool_result_keep_last` are masked, then the oldest messages are dropped
+/// until the rest fits in half of what it was. Leading system messages and
+/// the newest message are always kept. Returns `None` when nothing could be
+/// removed.
+pub fn shrink_context(
+    messages: &mut Vec<Message>,
+    context: &ContextConfig,
+) -> Option<ContextRecovery> {
+    let tokens_before: u32 = messages.iter().map(estimate_message_tokens).sum();
+    let head = messages
+        .iter()
+        .take_while(|m| m.role == Role::System)
+        .count();
+    if messages.len() <= head {
+        return None;
+    }
+
+    mask_tool_results(
+        messages,
+        head,
+        context.tool_result_keep_first,
+        context.tool_result_keep_last,
+    );
+
+    let history_tokens: u32 = messages[head..].iter().map(estimate_message_tokens).sum();
+    let budget = history_tokens / 2;
+    let mut keep_from = messages.len() - 1;
+    let mut used_tokens = estimate_message_tokens(&messages[keep_from]);
+    for (i, msg) in messages.iter().enumerate().skip(head).rev().skip(1) {
+        let msg_tokens = estimate_message_tokens(msg);
+        if used_tokens + msg_tokens > budget {
+            break;
+        }
+        used_tokens += msg_tokens;
+        keep_from = i;
+    }
+    // Skip forward past tool results whose assistant message is dropped
+    while keep_from < messages.len() - 1 && messages[keep_from].role == Role::Tool {
+        keep_from += 1;
+    }
+    messages.drain(head..keep_from);
+
+    let tokens_after: u32 = messages.iter().map(estimate_message_tokens).sum();
+    (tokens_after < tokens_before).then_some(ContextRecovery {
+        dropped_messages: (keep_from - head) as u32,
+        tokens_before,
+        tokens_after,
+    })
+}
+
 // ============================================================================
 // Tests
 // ============================================================================
@@ -324,6 +396,7 @@ mod tests {
                 },
             }]),
             tool_call_id: None,
+            reasoning: None,
         }
     }
 
@@ -506,4 +579,40 @@ mod tests {
         // Single group should never be dropped
         assert_eq!(messages.len(), initial_len);
     }
+
+    // --- Context-Too-Long Recovery ---
+
+    #[test]
+    fn shrink_context_keeps_system_and_newest_messages() {
+        let mut messages = vec![
+            Message::text(Role::System, "system prompt"),
+            Message::text(Role::User, &"old question ".repeat(200)),
+            assistant_tool_call_msg(),
+            tool_result_msg(&"old result ".repeat(200)),
+            Message::text(Role::Assistant, "answer"),
+            Message::text(Role::User, "latest question"),
+        ];
+
+        let recovery = shrink_context(&mut messages, &ContextConfig::default()).unwrap();
+
+        assert_eq!(messages[0].content_str(), "system prompt");
+        assert_eq!(messages.last().unwrap().content_str(), "latest question");
+        assert!(
+            !messages
+                .iter()
+                .any(|m| m.content_str().starts_with("old question"))
+        );
+        assert_eq!(recovery.dropped_messages as usize, 6 - messages.len());
+        assert!(recovery.tokens_after < recovery.tokens_before);
+    }
+
+    #[test]
+    fn shrink_context_gives_up_on_a_single_message() {
+        let mut messages = vec![
+            Message::text(Role::System, "system prompt"),
+            Message::text(Role::User, &"huge ".repeat(1000)),
+        ];
+        assert!(shrink_context(&mut messages, &ContextConfig::default()).is_none());
+        assert_eq!(messages.len(), 2);
+    }
 }
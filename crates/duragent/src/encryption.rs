@@ -0,0 +1,381 @@
+//! Tenant-scoped envelope encryption for stored conversations.
+//!
+//! Each tenant namespace (an agent's project, or `default` for agents without
+//! one) gets its own random data key. Data keys are stored wrapped by the
+//! master key in `{keys_dir}/{namespace}.json`; the master key itself never
+//! encrypts session data. A data key only opens its own tenant's records.
+//!
+//! Records are sealed with AES-256-GCM. The namespace and session ID are bound
+//! in as associated data, so a record copied into another tenant's or
+//! session's files fails to decrypt instead of being read.
+
+use std::path::{Path, PathBuf};
+use std::sync::{Arc, Mutex};
+
+use base64::Engine;
+use base64::engine::general_purpose::STANDARD;
+use dashmap::DashMap;
+use ring::aead::{AES_256_GCM, Aad, LessSafeKey, NONCE_LEN, Nonce, UnboundKey};
+use ring::rand::{SecureRandom, SystemRandom};
+use serde::{Deserialize, Serialize};
+use thiserror::Error;
+
+/// Namespace for agents that are not in a project.
+pub const DEFAULT_NAMESPACE: &str = "default";
+
+/// Version tag written into every sealed record.
+const SEALED_VERSION: u8 = 1;
+
+/// Length of master and data keys in bytes.
+const KEY_LEN: usize = 32;
+
+/// Maps an agent name to its project, if any.
+pub type NamespaceResolver = Arc<dyn Fn(&str) -> Option<String> + Send + Sync>;
+
+#[derive(Debug, Error)]
+pub enum EncryptionError {
+    #[error("master key must be {KEY_LEN} bytes, base64-encoded")]
+    InvalidMasterKey,
+
+    #[error("invalid tenant namespace '{0}'")]
+    InvalidNamespace(String),
+
+    #[error("failed to access key file {path}: {source}")]
+    KeyFile {
+        path: PathBuf,
+        #[source]
+        source: std::io::Error,
+    },
+
+    #[error("corrupt key file {0}")]
+    CorruptKeyFile(PathBuf),
+
+    #[error("failed to encrypt record")]
+    Seal,
+
+    #[error("failed to decrypt record for namespace '{0}'")]
+    Open(String),
+}
+
+// ============================================================================
+// On-disk Formats
+// ============================================================================
+
+/// A data key encrypted with the master key.
+#[derive(Serialize, Deserialize)]
+struct WrappedKey {
+    namespace: String,
+    nonce: String,
+    wrapped_key: String,
+}
+
+/// A record encrypted with a tenant's data key.
+#[derive(Serialize, Deserialize)]
+struct SealedRecord {
+    sealed: u8,
+    namespace: String,
+    nonce: String,
+    data: String,
+}
+
+// ============================================================================
+// TenantKeys
+// ============================================================================
+
+/// Per-tenant data keys, wrapped by a master key.
+#[derive(Clone)]
+pub struct TenantKeys {
+    inner: Arc<Inner>,
+}
+
+struct Inner {
+    master: LessSafeKey,
+    keys_dir: PathBuf,
+    data_keys: DashMap<String, Arc<LessSafeKey>>,
+    /// Serializes data key creation so two writers never race to create one.
+    create_lock: Mutex<()>,
+    resolve: NamespaceResolver,
+    rng: SystemRandom,
+}
+
+impl TenantKeys {
+    /// Create from a base64-encoded 32-byte master key.
+    pub fn new(
+        master_key: &str,
+        keys_dir: impl Into<PathBuf>,
+        resolve: NamespaceResolver,
+    ) -> Result<Self, EncryptionError> {
+        let master = STANDARD
+            .decode(master_key.trim())
+            .ok()
+            .filter(|bytes| bytes.len() == KEY_LEN)
+            .ok_or(EncryptionError::InvalidMasterKey)?;
+
+        Ok(Self {
+            inner: Arc::new(Inner {
+                master: aead_key(&master)?,
+                keys_dir: keys_dir.into(),
+                data_keys: DashMap::new(),
+                create_lock: Mutex::new(()),
+                resolve,
+                rng: SystemRandom::new(),
+            }),
+        })
+    }
+
+    /// Tenant namespace for sessions of `agent`.
+    pub fn namespace_for_agent(&self, agent: &str) -> String {
+        (self.inner.resolve)(agent).unwrap_or_else(|| DEFAULT_NAMESPACE.to_string())
+    }
+
+    /// Encrypt `plaintext` for `session_id` with the tenant's data key.
+    ///
+    /// Returns a single-line JSON record.
+    pub fn seal(
+        &self,
+        namespace: &str,
+        session_id: &str,
+        plaintext: &[u8],
+    ) -> Result<String, EncryptionError> {
+        let key = self.data_key(namespace)?;
+        let nonce = self.nonce()?;
+        let mut data = plaintext.to_vec();
+        key.seal_in_place_append_tag(
+            Nonce::assume_unique_for_key(nonce),
+            Aad::from(record_aad(namespace, session_id).as_bytes()),
+            &mut data,
+        )
+        .map_err(|_| EncryptionError::Seal)?;
+
+        let record = SealedRecord {
+            sealed: SEALED_VERSION,
+            namespace: namespace.to_string(),
+            nonce: STANDARD.encode(nonce),
+            data: STANDARD.encode(data),
+        };
+        serde_json::to_string(&record).map_err(|_| EncryptionError::Seal)
+    }
+
+    /// Decrypt a record written by [`seal`](Self::seal).
+    ///
+    /// Returns `Ok(None)` if `record` is not a sealed record (data written
+    /// before encryption was turned on), or the namespace and plaintext.
+    pub fn open(
+        &self,
+        session_id: &str,
+        record: &str,
+    ) -> Result<Option<(String, Vec<u8>)>, EncryptionError> {
+        let Ok(record) = serde_json::from_str::<SealedRecord>(record) else {
+            return Ok(None);
+        };
+        if record.sealed != SEALED_VERSION {
+            return Err(EncryptionError::Open(record.namespace));
+        }
+
+        let key = self.data_key(&record.namespace)?;
+        let failed = || EncryptionError::Open(record.namespace.clone());
+        let nonce = decode_nonce(&record.nonce).ok_or_else(failed)?;
+        let mut data = STANDARD.decode(&record.data).map_err(|_| failed())?;
+        let plaintext = key
+            .open_in_place(
+                Nonce::assume_unique_for_key(nonce),
+                Aad::from(record_aad(&record.namespace, session_id).as_bytes()),
+                &mut data,
+            )
+            .map_err(|_| failed())?;
+        let plaintext = plaintext.to_vec();
+        Ok(Some((record.namespace, plaintext)))
+    }
+
+    /// Load the tenant's data key, creating it on first use.
+    ///
+    /// Uses blocking file I/O; keys are cached, so this only touches disk
+    /// once per namespace.
+    fn data_key(&self, namespace: &str) -> Result<Arc<LessSafeKey>, EncryptionError> {
+        if let Some(key) = self.inner.data_keys.get(namespace) {
+            return Ok(key.clone());
+        }
+        if namespace.is_empty()
+            || !namespace
+                .chars()
+                .all(|c| c.is_ascii_alphanumeric() || c == '-' || c == '_')
+        {
+            return Err(EncryptionError::InvalidNamespace(namespace.to_string()));
+        }
+
+        let _guard = self.inner.create_lock.lock().unwrap();
+        if let Some(key) = self.inner.data_keys.get(namespace) {
+            return Ok(key.clone());
+        }
+
+        let path = self.inner.keys_dir.join(format!("{namespace}.json"));
+        let key_bytes = match std::fs::read_to_string(&path) {
+            Ok(contents) => self.unwrap_key(namespace, &path, &contents)?,
+            Err(e) if e.kind() == std::io::ErrorKind::NotFound => {
+                self.create_key(namespace, &path)?
+            }
+            Err(e) => return Err(EncryptionError::KeyFile { path, source: e }),
+        };
+
+        let key = Arc::new(aead_key(&key_bytes)?);
+        self.inner
+            .data_keys
+            .insert(namespace.to_string(), key.clone());
+        Ok(key)
+    }
+
+    fn unwrap_key(
+        &self,
+        namespace: &str,
+        path: &Path,
+        contents: &str,
+    ) -> Result<Vec<u8>, EncryptionError> {
+        let corrupt = || EncryptionError::CorruptKeyFile(path.to_path_buf());
+        let wrapped: WrappedKey = serde_json::from_str(contents).map_err(|_| corrupt())?;
+        if wrapped.namespace != namespace {
+            return Err(corrupt());
+        }
+        let nonce = decode_nonce(&wrapped.nonce).ok_or_else(corrupt)?;
+        let mut data = STANDARD
+            .decode(&wrapped.wrapped_key)
+            .map_err(|_| corrupt())?;
+        let key = self
+            .inner
+            .master
+            .open_in_place(
+                Nonce::assume_unique_for_key(nonce),
+                Aad::from(key_aad(namespace).as_bytes()),
+                &mut data,
+            )
+            .map_err(|_| corrupt())?;
+        Ok(key.to_vec())
+    }
+
+    fn create_key(&self, namespace: &str, path: &Path) -> Result<Vec<u8>, EncryptionError> {
+        let mut key = vec![0u8; KEY_LEN];
+        self.inner
+            .rng
+            .fill(&mut key)
+            .map_err(|_| EncryptionError::Seal)?;
+
+        let nonce = self.nonce()?;
+        let mut wrapped = key.clone();
+        self.inner
+            .master
+            .seal_in_place_append_tag(
+                Nonce::assume_unique_for_key(nonce),
+                Aad::from(key_aad(namespace).as_bytes()),
+                &mut wrapped,
+            )
+            .map_err(|_| EncryptionError::Seal)?;
+
+        let contents = serde_json::to_string_pretty(&WrappedKey {
+            namespace: namespace.to_string(),
+            nonce: STANDARD.encode(nonce),
+            wrapped_key: STANDARD.encode(wrapped),
+        })
+        .map_err(|_| EncryptionError::Seal)?;
+
+        let key_file_error = |source| EncryptionError::KeyFile {
+            path: path.to_path_buf(),
+            source,
+        };
+        std::fs::create_dir_all(&self.inner.keys_dir).map_err(key_file_error)?;
+        let temp_path = path.with_extension(format!("json.{}.tmp", ulid::Ulid::new()));
+        std::fs::write(&temp_path, contents).map_err(key_file_error)?;
+        std::fs::rename(&temp_path, path).map_err(key_file_error)?;
+        Ok(key)
+    }
+
+    fn nonce(&self) -> Result<[u8; NONCE_LEN], EncryptionError> {
+        let mut nonce = [0u8; NONCE_LEN];
+        self.inner
+            .rng
+            .fill(&mut nonce)
+            .map_err(|_| EncryptionError::Seal)?;
+        Ok(nonce)
+    }
+}
+
+fn aead_key(bytes: &[u8]) -> Result<LessSafeKey, EncryptionError> {
+    UnboundKey::new(&AES_256_GCM, bytes)
+        .map(LessSafeKey::new)
+        .map_err(|_| EncryptionError::InvalidMasterKey)
+}
+
+fn decode_nonce(encoded: &str) -> Option<[u8; NONCE_LEN]> {
+    STANDARD.decode(encoded).ok()?.try_into().ok()
+}
+
+fn key_aad(namespace: &str) -> String {
+    format!("duragent-data-key:{namespace}")
+}
+
+fn record_aad(namespace: &str, session_id: &str) -> String {
+    format!("duragent-record:{namespace}:{session_id}")
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use tempfile::TempDir;
+
+    const MASTER_KEY: &str = "AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8=";
+
+    fn tenant_keys(dir: &Path) -> TenantKeys {
+        TenantKeys::new(
+            MASTER_KEY,
+            dir,
+            Arc::new(|agent: &str| (agent == "billing-bot").then(|| "acme".to_string())),
+        )
+        .unwrap()
+    }
+
+    #[test]
+    fn seal_and_open_round_trip() {
+        let tmp = TempDir::new().unwrap();
+        let keys = tenant_keys(tmp.path());
+        assert_eq!(keys.namespace_for_agent("billing-bot"), "acme");
+        assert_eq!(keys.namespace_for_agent("other"), DEFAULT_NAMESPACE);
+
+        let record = keys.seal("acme", "session_1", b"hello").unwrap();
+        assert!(!record.contains("hello"));
+        assert!(tmp.path().join("acme.json").exists());
+
+        // A fresh instance unwraps the stored data key with the master key
+        let (namespace, plaintext) = tenant_keys(tmp.path())
+            .open("session_1", &record)
+            .unwrap()
+            .unwrap();
+        assert_eq!(namespace, "acme");
+        assert_eq!(plaintext, b"hello");
+
+        // Plaintext records pass through untouched
+        assert!(keys.open("session_1", r#"{"seq":1}"#).unwrap().is_none());
+    }
+
+    #[test]
+    fn records_do_not_open_under_another_tenant_or_session() {
+        let tmp = TempDir::new().unwrap();
+        let keys = tenant_keys(tmp.path());
+        let record = keys.seal("acme", "session_1", b"secret").unwrap();
+
+        assert!(keys.open("session_2", &record).is_err());
+
+        // Relabeling the record for another tenant uses that tenant's key
+        let relabeled = record.replace("\"acme\"", "\"globex\"");
+        assert!(keys.open("session_1", &relabeled).is_err());
+    }
+
+    #[test]
+    fn rejects_bad_master_key_and_namespace() {
+        let tmp = TempDir::new().unwrap();
+        let resolve: NamespaceResolver = Arc::new(|_: &str| None);
+        assert!(TenantKeys::new("c2hvcnQ=", tmp.path(), resolve).is_err());
+        assert!(
+            tenant_keys(tmp.path())
+                .seal("../etc", "session_1", b"x")
+                .is_err()
+        );
+    }
+}
@@ -0,0 +1,465 @@
+//! Ensemble agents.
+//!
+//! An agent's manifest can answer each model turn with a consensus of
+//! several members, each another agent or a model:
+//!
+//! ```yaml
+//! ensemble:
+//!   members:
+//!     - agent: researcher
+//!     - model: { provider: openai, name: gpt-4o }
+//!     - model: { provider: anthropic, name: claude-sonnet-4-5 }
+//!   synthesizer: { provider: openai, name: gpt-4o }
+//!   report_disagreements: true
+//! ```
+//!
+//! Members answer concurrently. Agent members answer with their own model
+//! and prompts; model members with this agent's prompts. The synthesizer
+//! then writes the answer the members support and lists where they
+//! disagree. Every member answer and the disagreements are recorded in the
+//! session's event log. Usage of every member and of the synthesizer counts
+//! toward the run.
+
+use std::sync::Arc;
+
+use async_trait::async_trait;
+use futures::future;
+use serde::Deserialize;
+use tracing::{debug, warn};
+
+use crate::agent::{AgentSpec, AgentStore, ModelConfig};
+use crate::context::ContextBuilder;
+use crate::llm::{
+    ChatRequest, ChatResponse, Choice, LLMError, LLMProvider, Message, ProviderRegistry, Role,
+    Usage,
+};
+use crate::session::{MemberAnswer, SessionHandle};
+
+/// Member answers of one model turn and the consensus drawn from them, for
+/// the run trace.
+#[derive(Debug, Clone)]
+pub struct Consensus {
+    pub members: Vec<MemberAnswer>,
+    pub disagreements: Vec<String>,
+    pub synthesis_error: Option<String>,
+}
+
+/// An ensemble member ready to answer.
+struct Member {
+    label: String,
+    model: ModelConfig,
+    provider: Arc<dyn LLMProvider>,
+    /// The member agent's own system prompt, replacing this agent's.
+    system_prompt: Option<String>,
+}
+
+/// `provider` answering by ensemble for agents with `ensemble`, or
+/// `provider` itself. Members whose agent isn't loaded or whose provider
+/// isn't configured are left out.
+pub async fn wrap(
+    agent: &AgentSpec,
+    provider: Arc<dyn LLMProvider>,
+    agents: &AgentStore,
+    providers: &ProviderRegistry,
+    handle: &SessionHandle,
+) -> Arc<dyn LLMProvider> {
+    let Some(config) = &agent.ensemble else {
+        return provider;
+    };
+
+    let mut members = Vec::new();
+    for member in &config.members {
+        let (label, model, system_prompt) = match (&member.agent, &member.model) {
+            (Some(name), _) => {
+                let Some(spec) = agents.get(name) else {
+                    warn!(agent = %agent.metadata.name, member = %name, "Ensemble member agent not found");
+                    continue;
+                };
+                let system_prompt = ContextBuilder::new()
+                    .from_agent_spec(&spec)
+                    .build()
+                    .render_system_message();
+                (format!("agent:{name}"), spec.model.clone(), system_prompt)
+            }
+            (None, Some(model)) => (
+                format!("{}/{}", model.provider, model.name),
+                model.clone(),
+                None,
+            ),
+            (None, None) => continue,
+        };
+        match providers
+            .get(&model.provider, model.base_url.as_deref())
+            .await
+        {
+            Some(provider) => members.push(Member {
+                label,
+                model,
+                provider,
+                system_prompt,
+            }),
+            None => warn!(
+                agent = %agent.metadata.name,
+                member = %label,
+                "Ensemble member provider not configured"
+            ),
+        }
+    }
+    if members.is_empty() {
+        return provider;
+    }
+
+    let model = config
+        .synthesizer
+        .clone()
+        .unwrap_or_else(|| agent.model.clone());
+    let synthesizer = match providers
+        .get(&model.provider, model.base_url.as_deref())
+        .await
+    {
+        Some(provider) => Some((model, provider)),
+        None => {
+            warn!(
+                agent = %agent.metadata.name,
+                provider = %model.provider,
+                "Ensemble synthesizer provider not configured"
+            );
+            None
+        }
+    };
+
+    Arc::new(EnsembleProvider {
+        base: provider,
+        members,
+        synthesizer,
+        instructions: config.instructions.clone(),
+        report_disagreements: config.report_disagreements,
+        handle: handle.clone(),
+    })
+}
+
+// ============================================================================
+// EnsembleProvider
+// ============================================================================
+
+/// Answers each chat request with the consensus of its members.
+struct EnsembleProvider {
+    base: Arc<dyn LLMProvider>,
+    members: Vec<Member>,
+    synthesizer: Option<(ModelConfig, Arc<dyn LLMProvider>)>,
+    instructions: Option<String>,
+    report_disagreements: bool,
+    handle: SessionHandle,
+}
+
+/// What the synthesizer is asked to reply with.
+#[derive(Debug, Deserialize)]
+struct Synthesis {
+    answer: String,
+    #[serde(default)]
+    disagreements: Vec<String>,
+}
+
+impl EnsembleProvider {
+    /// Merge the members' answers, returning the synthesis and the
+    /// synthesizer's usage.
+    async fn synthesize(
+        &self,
+        request: &ChatRequest,
+        answers: &[MemberAnswer],
+    ) -> Result<(Synthesis, Option<Usage>), String> {
+        let (model, provider) = self
+            .synthesizer
+            .as_ref()
+            .ok_or_else(|| "synthesizer provider not configured".to_string())?;
+
+        let mut prompt = "Several assistants answered the same message. Write the single answer \
+            their answers best support, and list each point where they disagree. Reply with \
+            only a JSON object: {\"answer\": \"...\", \"disagreements\": [\"...\"]}, with an \
+            empty list when they agree."
+            .to_string();
+        if let Some(instructions) = &self.instructions {
+            prompt.push_str("\n\n");
+            prompt.push_str(instructions);
+        }
+        let request = ChatRequest::new(
+            &model.name,
+            vec![
+                Message::text(Role::System, prompt),
+                Message::text(Role::User, synthesis_input(request, answers)),
+            ],
+            Some(0.0),
+            model.max_output_tokens,
+        );
+
+        let response = provider.chat(request).await.map_err(|e| e.to_string())?;
+        let reply = response
+            .choices
+            .first()
+            .and_then(|c| c.message.content.as_deref())
+            .unwrap_or_default();
+        parse_synthesis(reply)
+            .map(|synthesis| (synthesis, response.usage))
+            .ok_or_else(|| "synthesizer reply was not the requested JSON".to_string())
+    }
+}
+
+#[async_trait]
+impl LLMProvider for EnsembleProvider {
+    async fn chat(&self, request: ChatRequest) -> Result<ChatResponse, LLMError> {
+        let results = future::join_all(
+            self.members
+                .iter()
+                .map(|member| member.provider.chat(member_request(member, &request))),
+        )
+        .await;
+
+        let mut usage = None;
+        let mut answers = Vec::with_capacity(results.len());
+        let mut first_error = None;
+        for (member, result) in self.members.iter().zip(results) {
+            let mut answer = MemberAnswer {
+                member: member.label.clone(),
+                content: None,
+                usage: None,
+                error: None,
+            };
+            match result {
+                Ok(response) => {
+                    usage = add_usage(usage, response.usage.clone());
+                    answer.content = response
+                        .choices
+                        .into_iter()
+                        .next()
+                        .and_then(|c| c.message.content);
+                    answer.usage = response.usage;
+                }
+                Err(e) => {
+                    answer.error = Some(e.to_string());
+                    first_error.get_or_insert(e);
+                }
+            }
+            answers.push(answer);
+        }
+        let Some(fallback) = answers.iter().find(|a| a.error.is_none()) else {
+            return Err(first_error.expect("every member failed"));
+        };
+        let fallback = fallback.content.clone().unwrap_or_default();
+
+        let (content, disagreements, synthesis_error) =
+            match self.synthesize(&request, &answers).await {
+                Ok((synthesis, synthesis_usage)) => {
+                    usage = add_usage(usage, synthesis_usage);
+                    (synthesis.answer, synthesis.disagreements, None)
+                }
+                Err(e) => {
+                    warn!(error = %e, "Ensemble synthesis failed, using the first member answer");
+                    (fallback, Vec::new(), Some(e))
+                }
+            };
+        debug!(
+            members = answers.len(),
+            disagreements = disagreements.len(),
+            "Ensemble reached consensus"
+        );
+
+        let mut content = content;
+        if self.report_disagreements && !disagreements.is_empty() {
+            content.push_str("\n\nDisagreements:");
+            for disagreement in &disagreements {
+                content.push_str("\n- ");
+                content.push_str(disagreement);
+            }
+        }
+
+        if let Err(e) = self
+            .handle
+            .record_consensus(Consensus {
+                members: answers,
+                disagreements,
+                synthesis_error,
+            })
+            .await
+        {
+            warn!(error = %e, "Failed to record ensemble consensus");
+        }
+
+        Ok(ChatResponse {
+            id: format!("ensemble-{}", ulid::Ulid::new()),
+            choices: vec![Choice {
+                index: 0,
+                message: Message::text(Role::Assistant, content),
+                finish_reason: Some("stop".to_string()),
+            }],
+            usage,
+        })
+    }
+
+    async fn health_check(&self) -> Result<(), LLMError> {
+        self.base.health_check().await
+    }
+
+    async fn embed(&self, model: &str, inputs: Vec<String>) -> Result<Vec<Vec<f32>>, LLMError> {
+        self.base.embed(model, inputs).await
+    }
+
+    async fn list_models(&self) -> Result<Vec<String>, LLMError> {
+        self.base.list_models().await
+    }
+}
+
+// ============================================================================
+// Helper Functions
+// ============================================================================
+
+/// The request a member answers: the conversation with the member's model,
+/// and an agent member's own system prompt in place of this agent's.
+fn member_request(member: &Member, request: &ChatRequest) -> ChatRequest {
+    let mut request = request.clone();
+    request.model = member.model.name.clone();
+    request.tools = None;
+    if member.model.temperature.is_some() {
+        request.temperature = member.model.temperature;
+    }
+    if member.model.max_output_tokens.is_some() {
+        request.max_tokens = member.model.max_output_tokens;
+    }
+    if let Some(system_prompt) = &member.system_prompt {
+        request.messages.retain(|m| m.role != Role::System);
+        request
+            .messages
+            .insert(0, Message::text(Role::System, system_prompt.clone()));
+    }
+    request
+}
+
+/// The latest user message and each member's answer.
+fn synthesis_input(request: &ChatRequest, answers: &[MemberAnswer]) -> String {
+    let latest = request
+        .messages
+        .iter()
+        .rev()
+        .find(|m| m.role == Role::User)
+        .and_then(|m| m.content.as_deref())
+        .unwrap_or_default();
+    let mut input = format!("Message:\n{latest}");
+    for answer in answers.iter().filter(|a| a.error.is_none()) {
+        input.push_str(&format!(
+            "\n\nAnswer from {}:\n{}",
+            answer.member,
+            answer.content.as_deref().unwrap_or_default()
+        ));
+    }
+    input
+}
+
+/// The synthesis in a reply, tolerating text or code fences around the
+/// JSON object.
+fn parse_synthesis(reply: &str) -> Option<Synthesis> {
+    let start = reply.find('{')?;
+    let end = reply.rfind('}')?;
+    serde_json::from_str(reply.get(start..=end)?).ok()
+}
+
+fn add_usage(total: Option<Usage>, usage: Option<Usage>) -> Option<Usage> {
+    match (total, usage) {
+        (Some(t), Some(u)) => Some(Usage {
+            prompt_tokens: t.prompt_tokens + u.prompt_tokens,
+            completion_tokens: t.completion_tokens + u.completion_tokens,
+            total_tokens: t.total_tokens + u.total_tokens,
+        }),
+        (total, usage) => total.or(usage),
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::llm::{MockProvider, Provider};
+
+    fn member(system_prompt: Option<&str>) -> Member {
+        Member {
+            label: "agent:researcher".to_string(),
+            model: ModelConfig {
+                provider: Provider::Mock,
+                name: "small".to_string(),
+                temperature: Some(0.2),
+                max_input_tokens: None,
+                max_output_tokens: None,
+                base_url: None,
+            },
+            provider: Arc::new(MockProvider),
+            system_prompt: system_prompt.map(str::to_string),
+        }
+    }
+
+    #[test]
+    fn parse_synthesis_tolerates_fences() {
+        let synthesis =
+            parse_synthesis("```json\n{\"answer\": \"42\", \"disagreements\": [\"units\"]}\n```")
+                .unwrap();
+        assert_eq!(synthesis.answer, "42");
+        assert_eq!(synthesis.disagreements, vec!["units".to_string()]);
+
+        let synthesis = parse_synthesis("{\"answer\": \"42\"}").unwrap();
+        assert!(synthesis.disagreements.is_empty());
+        assert!(parse_synthesis("42").is_none());
+    }
+
+    #[test]
+    fn member_request_swaps_in_the_member_prompt() {
+        let request = ChatRequest::new(
+            "big",
+            vec![
+                Message::text(Role::System, "ensemble prompt"),
+                Message::text(Role::User, "hi"),
+            ],
+            Some(0.7),
+            Some(100),
+        );
+
+        let own = member_request(&member(Some("researcher prompt")), &request);
+        assert_eq!(own.model, "small");
+        assert_eq!(own.temperature, Some(0.2));
+        assert_eq!(own.max_tokens, Some(100));
+        assert_eq!(own.messages.len(), 2);
+        assert_eq!(
+            own.messages[0].content.as_deref(),
+            Some("researcher prompt")
+        );
+
+        let shared = member_request(&member(None), &request);
+        assert_eq!(
+            shared.messages[0].content.as_deref(),
+            Some("ensemble prompt")
+        );
+    }
+
+    #[test]
+    fn synthesis_input_skips_failed_members() {
+        let request = ChatRequest::new(
+            "m",
+            vec![Message::text(Role::User, "capital of France?")],
+            None,
+            None,
+        );
+        let answers = vec![
+            MemberAnswer {
+                member: "openai/gpt-4o".to_string(),
+                content: Some("Paris".to_string()),
+                usage: None,
+                error: None,
+            },
+            MemberAnswer {
+                member: "agent:researcher".to_string(),
+                content: None,
+                usage: None,
+                error: Some("timeout".to_string()),
+            },
+        ];
+        let input = synthesis_input(&request, &answers);
+        assert!(input.starts_with("Message:\ncapital of France?"));
+        assert!(input.contains("Answer from openai/gpt-4o:\nParis"));
+        assert!(!input.contains("agent:researcher"));
+    }
+}
@@ -0,0 +1,384 @@
+//! Few-shot example pools.
+//!
+//! Examples are input/output pairs curated through the API, separately from
+//! the agent's prompt. Each agent owns a pool named after it; a manifest's
+//! `examples` section says which pools to draw from and how many examples to
+//! add to each request:
+//!
+//! ```yaml
+//! examples:
+//!   pools: [support-bot, shared-tone]
+//!   count: 3
+//!   selection: similarity
+//!   embedding_model: text-embedding-3-small
+//! ```
+//!
+//! `first` selection takes the oldest examples. `similarity` selection embeds
+//! the user's message with the agent's provider and takes the closest
+//! examples. Example embeddings are computed on first use and saved with the
+//! example, so each example is embedded once per model.
+
+use std::sync::Arc;
+
+use chrono::{DateTime, Utc};
+use serde::{Deserialize, Serialize};
+use tracing::warn;
+
+use crate::agent::{AgentSpec, ExampleSelection};
+use crate::context::{BlockSource, SystemBlock, priority};
+use crate::llm::{LLMError, LLMProvider, Message, Role};
+use crate::store::{ExampleStore, StorageResult};
+
+/// ID prefix for examples.
+pub const EXAMPLE_ID_PREFIX: &str = "ex_";
+
+// ============================================================================
+// Types
+// ============================================================================
+
+/// A few-shot example.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct Example {
+    pub id: String,
+    pub input: String,
+    pub output: String,
+    #[serde(default, skip_serializing_if = "Vec::is_empty")]
+    pub tags: Vec<String>,
+    pub created_at: DateTime<Utc>,
+    pub updated_at: DateTime<Utc>,
+    /// Cached embedding of `input`, for similarity selection.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub embedding: Option<Embedding>,
+}
+
+impl Example {
+    pub fn new(input: String, output: String, tags: Vec<String>) -> Self {
+        let now = Utc::now();
+        Self {
+            id: format!("{EXAMPLE_ID_PREFIX}{}", ulid::Ulid::new()),
+            input,
+            output,
+            tags,
+            created_at: now,
+            updated_at: now,
+            embedding: None,
+        }
+    }
+}
+
+/// A cached embedding and the model that produced it.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct Embedding {
+    pub model: String,
+    pub vector: Vec<f32>,
+}
+
+// ============================================================================
+// ExampleLibrary
+// ============================================================================
+
+/// Example pools, and selection of examples for a request.
+#[derive(Clone)]
+pub struct ExampleLibrary {
+    store: Arc<dyn ExampleStore>,
+}
+
+impl ExampleLibrary {
+    pub fn new(store: Arc<dyn ExampleStore>) -> Self {
+        Self { store }
+    }
+
+    pub async fn list(&self, pool: &str) -> StorageResult<Vec<Example>> {
+        self.store.list(pool).await
+    }
+
+    pub async fn get(&self, pool: &str, id: &str) -> StorageResult<Option<Example>> {
+        self.store.load(pool, id).await
+    }
+
+    pub async fn create(
+        &self,
+        pool: &str,
+        input: String,
+        output: String,
+        tags: Vec<String>,
+    ) -> StorageResult<Example> {
+        let example = Example::new(input, output, tags);
+        self.store.save(pool, &example).await?;
+        Ok(example)
+    }
+
+    /// Replace an example's content. Returns `None` if it doesn't exist.
+    pub async fn update(
+        &self,
+        pool: &str,
+        id: &str,
+        input: String,
+        output: String,
+        tags: Vec<String>,
+    ) -> StorageResult<Option<Example>> {
+        let Some(mut example) = self.store.load(pool, id).await? else {
+            return Ok(None);
+        };
+        if example.input != input {
+            example.embedding = None;
+        }
+        example.input = input;
+        example.output = output;
+        example.tags = tags;
+        example.updated_at = Utc::now();
+        self.store.save(pool, &example).await?;
+        Ok(Some(example))
+    }
+
+    /// Delete an example. Returns whether it existed.
+    pub async fn delete(&self, pool: &str, id: &str) -> StorageResult<bool> {
+        if self.store.load(pool, id).await?.is_none() {
+            return Ok(false);
+        }
+        self.store.delete(pool, id).await?;
+        Ok(true)
+    }
+
+    /// Pick the examples to show the agent for `input`.
+    ///
+    /// Never fails: unreadable pools are skipped, and similarity selection
+    /// falls back to the oldest examples when embedding fails.
+    pub async fn select(
+        &self,
+        agent: &AgentSpec,
+        provider: &dyn LLMProvider,
+        input: &str,
+    ) -> Vec<Example> {
+        let Some(config) = &agent.examples else {
+            return Vec::new();
+        };
+
+        let mut candidates = Vec::new();
+        for pool in config.pools_for(&agent.metadata.name) {
+            match self.store.list(pool).await {
+                Ok(examples) => candidates.extend(examples.into_iter().map(|e| (pool, e))),
+                Err(e) => {
+                    warn!(agent = %agent.metadata.name, pool, error = %e, "Failed to load example pool")
+                }
+            }
+        }
+
+        let model = config.embedding_model.as_deref();
+        if let (ExampleSelection::Similarity, Some(model)) = (config.selection, model) {
+            if let Err(e) = self
+                .rank_by_similarity(provider, model, input, &mut candidates)
+                .await
+            {
+                warn!(agent = %agent.metadata.name, error = %e, "Example similarity selection failed; using first examples");
+            }
+        }
+
+        candidates
+            .into_iter()
+            .take(config.count)
+            .map(|(_, example)| example)
+            .collect()
+    }
+
+    /// System block with the examples for the conversation's latest user
+    /// message, or `None` when the agent has no examples configured.
+    pub async fn block_for(
+        &self,
+        agent: &AgentSpec,
+        provider: &dyn LLMProvider,
+        messages: &[Message],
+    ) -> Option<SystemBlock> {
+        agent.examples.as_ref()?;
+        let input = messages
+            .iter()
+            .rev()
+            .find(|m| m.role == Role::User)
+            .and_then(|m| m.content.as_deref())
+            .unwrap_or_default();
+        examples_block(&self.select(agent, provider, input).await)
+    }
+
+    /// Sort `candidates` most similar to `input` first, embedding examples
+    /// that have no embedding for `model` yet.
+    async fn rank_by_similarity(
+        &self,
+        provider: &dyn LLMProvider,
+        model: &str,
+        input: &str,
+        candidates: &mut [(&str, Example)],
+    ) -> Result<(), LLMError> {
+        let stale: Vec<usize> = candidates
+            .iter()
+            .enumerate()
+            .filter(|(_, (_, e))| e.embedding.as_ref().is_none_or(|emb| emb.model != model))
+            .map(|(i, _)| i)
+            .collect();
+
+        // Embed the input and any stale examples in one request.
+        let mut texts = vec![input.to_string()];
+        texts.extend(stale.iter().map(|&i| candidates[i].1.input.clone()));
+        let mut vectors = provider.embed(model, texts).await?.into_iter();
+        let query = vectors.next().unwrap_or_default();
+
+        for (&i, vector) in stale.iter().zip(vectors) {
+            let (pool, example) = &mut candidates[i];
+            example.embedding = Some(Embedding {
+                model: model.to_string(),
+                vector,
+            });
+            if let Err(e) = self.store.save(*pool, example).await {
+                warn!(pool = %pool, example = %example.id, error = %e, "Failed to cache example embedding");
+            }
+        }
+
+        let score = |example: &Example| {
+            example
+                .embedding
+                .as_ref()
+                .map_or(0.0, |emb| cosine_similarity(&query, &emb.vector))
+        };
+        candidates.sort_by(|(_, a), (_, b)| score(b).total_cmp(&score(a)));
+        Ok(())
+    }
+}
+
+/// Render examples as a system block, or `None` when there are none.
+pub fn examples_block(examples: &[Example]) -> Option<SystemBlock> {
+    if examples.is_empty() {
+        return None;
+    }
+    let mut xml = String::from("<examples>\n");
+    for example in examples {
+        xml.push_str("  <example>\n");
+        xml.push_str(&format!("    <input>{}</input>\n", example.input));
+        xml.push_str(&format!("    <output>{}</output>\n", example.output));
+        xml.push_str("  </example>\n");
+    }
+    xml.push_str("</examples>");
+    Some(SystemBlock {
+        content: xml,
+        label: "examples".to_string(),
+        source: BlockSource::Runtime,
+        priority: priority::EXAMPLES,
+    })
+}
+
+/// Cosine similarity, or 0 for vectors of different lengths or zero length.
+pub(crate) fn cosine_similarity(a: &[f32], b: &[f32]) -> f32 {
+    if a.len() != b.len() {
+        return 0.0;
+    }
+    let dot: f32 = a.iter().zip(b).map(|(x, y)| x * y).sum();
+    let norm_a = a.iter().map(|x| x * x).sum::<f32>().sqrt();
+    let norm_b = b.iter().map(|x| x * x).sum::<f32>().sqrt();
+    if norm_a == 0.0 || norm_b == 0.0 {
+        return 0.0;
+    }
+    dot / (norm_a * norm_b)
+}
+
+#[cfg(test)]
+mod tests {
+    use tempfile::TempDir;
+
+    use super::*;
+    use crate::agent::AgentExamplesConfig;
+    use crate::llm::MockProvider;
+    use crate::store::file::FileExampleStore;
+
+    fn agent(selection: ExampleSelection) -> AgentSpec {
+        let yaml = r#"apiVersion: duragent/v1alpha1
+kind: Agent
+metadata:
+  name: helper
+spec:
+  model:
+    provider: mock
+    name: echo
+"#;
+        let mut spec = crate::agent::parse_agent_yaml(
+            yaml,
+            Default::default(),
+            Vec::new(),
+            Default::default(),
+            std::path::PathBuf::from("/tmp/helper"),
+            None,
+        )
+        .unwrap();
+        spec.examples = Some(AgentExamplesConfig {
+            pools: Vec::new(),
+            count: 1,
+            selection,
+            embedding_model: Some("mock-embed".to_string()),
+        });
+        spec
+    }
+
+    async fn library(tmp: &TempDir) -> ExampleLibrary {
+        let library = ExampleLibrary::new(Arc::new(FileExampleStore::new(tmp.path())));
+        for (input, output) in [
+            ("how do I reset my password", "Use the reset link."),
+            ("what are your opening hours", "9 to 5."),
+        ] {
+            library
+                .create("helper", input.into(), output.into(), Vec::new())
+                .await
+                .unwrap();
+        }
+        library
+    }
+
+    #[test]
+    fn cosine_similarity_handles_edge_cases() {
+        assert!((cosine_similarity(&[1.0, 0.0], &[2.0, 0.0]) - 1.0).abs() < 1e-6);
+        assert_eq!(cosine_similarity(&[1.0, 0.0], &[0.0, 1.0]), 0.0);
+        assert_eq!(cosine_similarity(&[1.0], &[1.0, 0.0]), 0.0);
+        assert_eq!(cosine_similarity(&[0.0, 0.0], &[1.0, 0.0]), 0.0);
+    }
+
+    #[tokio::test]
+    async fn first_selection_takes_oldest() {
+        let tmp = TempDir::new().unwrap();
+        let library = library(&tmp).await;
+        let picked = library
+            .select(
+                &agent(ExampleSelection::First),
+                &MockProvider,
+                "opening hours?",
+            )
+            .await;
+        assert_eq!(picked.len(), 1);
+        assert_eq!(picked[0].input, "how do I reset my password");
+    }
+
+    #[tokio::test]
+    async fn similarity_selection_picks_closest_and_caches_embeddings() {
+        let tmp = TempDir::new().unwrap();
+        let library = library(&tmp).await;
+        let picked = library
+            .select(
+                &agent(ExampleSelection::Similarity),
+                &MockProvider,
+                "what are the opening hours",
+            )
+            .await;
+        assert_eq!(picked[0].output, "9 to 5.");
+
+        let stored = library.list("helper").await.unwrap();
+        assert!(stored.iter().all(|e| {
+            e.embedding
+                .as_ref()
+                .is_some_and(|emb| emb.model == "mock-embed")
+        }));
+    }
+
+    #[test]
+    fn block_lists_examples() {
+        let example = Example::new("hi".into(), "hello".into(), Vec::new());
+        let block = examples_block(&[example]).unwrap();
+        assert_eq!(block.priority, priority::EXAMPLES);
+        assert!(block.content.contains("<input>hi</input>"));
+        assert!(examples_block(&[]).is_none());
+    }
+}
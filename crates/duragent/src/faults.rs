@@ -0,0 +1,325 @@
+//! Fault injection for resilience testing.
+//!
+//! With `faults.enabled`, matching HTTP requests and LLM requests are
+//! delayed, failed, or have their streams cut off part way, at the configured
+//! rates. Clients can be tested against a misbehaving server without a
+//! misbehaving provider. Injected HTTP failures carry an `x-duragent-fault`
+//! header so they can be told apart from real ones.
+
+use std::sync::Arc;
+use std::time::Duration;
+
+use async_trait::async_trait;
+use futures::StreamExt;
+use thiserror::Error;
+
+use crate::config::{FaultSpec, FaultsConfig, ProviderFaultConfig, RouteFaultConfig};
+use crate::llm::{ChatRequest, ChatResponse, ChatStream, LLMError, LLMProvider};
+
+/// Response header naming the injected fault: `error` or `dropped-stream`.
+pub const FAULT_HEADER: &str = "x-duragent-fault";
+
+// ============================================================================
+// Types
+// ============================================================================
+
+#[derive(Debug, Error)]
+pub enum FaultError {
+    #[error("fault rule '{rule}': {field} must be between 0 and 1, got {value}")]
+    InvalidRate {
+        rule: String,
+        field: &'static str,
+        value: f64,
+    },
+
+    #[error("fault rule '{rule}': error_status must be between 400 and 599, got {status}")]
+    InvalidStatus { rule: String, status: u16 },
+}
+
+/// Faults drawn for one request.
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq)]
+pub struct FaultPlan {
+    pub delay: Duration,
+    /// Fail with this status instead of handling the request.
+    pub error_status: Option<u16>,
+    /// Cut a streamed response off after this many events.
+    pub drop_after: Option<usize>,
+}
+
+impl FaultPlan {
+    /// Roll the dice for one request.
+    fn draw(spec: &FaultSpec) -> Self {
+        let jitter = match spec.latency_jitter_ms {
+            0 => 0,
+            max => rand::random_range(0..=max),
+        };
+        let error_status = (rand::random::<f64>() < spec.error_rate).then_some(spec.error_status);
+        let drop_after = (error_status.is_none() && rand::random::<f64>() < spec.drop_stream_rate)
+            .then_some(spec.drop_after_events);
+        Self {
+            delay: Duration::from_millis(spec.latency_ms + jitter),
+            error_status,
+            drop_after,
+        }
+    }
+}
+
+// ============================================================================
+// FaultInjector
+// ============================================================================
+
+/// Route and provider fault rules. Empty unless `faults.enabled` is set.
+#[derive(Debug, Clone, Default)]
+pub struct FaultInjector {
+    routes: Arc<Vec<RouteFaultConfig>>,
+    providers: Arc<Vec<ProviderFaultConfig>>,
+}
+
+impl FaultInjector {
+    /// Validate and load fault rules. Rules are checked even when disabled,
+    /// so a bad config is caught before someone turns it on.
+    pub fn from_config(config: &FaultsConfig) -> Result<Self, FaultError> {
+        for route in &config.routes {
+            validate(&route.path, &route.faults)?;
+        }
+        for provider in &config.providers {
+            validate(&provider.provider, &provider.faults)?;
+        }
+        if !config.enabled {
+            return Ok(Self::default());
+        }
+        Ok(Self {
+            routes: Arc::new(config.routes.clone()),
+            providers: Arc::new(config.providers.clone()),
+        })
+    }
+
+    pub fn is_empty(&self) -> bool {
+        self.routes.is_empty() && self.providers.is_empty()
+    }
+
+    /// Faults for an HTTP request, or `None` when no rule matches.
+    pub fn route_faults(&self, method: &str, path: &str) -> Option<FaultPlan> {
+        self.routes
+            .iter()
+            .find(|r| {
+                path.starts_with(&r.path)
+                    && (r.methods.is_empty()
+                        || r.methods.iter().any(|m| m.eq_ignore_ascii_case(method)))
+            })
+            .map(|r| FaultPlan::draw(&r.faults))
+    }
+
+    /// Wrap `inner` with the provider's fault rule, if it has one.
+    pub fn wrap_provider(
+        &self,
+        provider: &str,
+        inner: Arc<dyn LLMProvider>,
+    ) -> Arc<dyn LLMProvider> {
+        match self.providers.iter().find(|p| p.provider == provider) {
+            Some(rule) => Arc::new(FaultyProvider {
+                inner,
+                spec: rule.faults.clone(),
+            }),
+            None => inner,
+        }
+    }
+}
+
+fn validate(rule: &str, spec: &FaultSpec) -> Result<(), FaultError> {
+    for (field, value) in [
+        ("error_rate", spec.error_rate),
+        ("drop_stream_rate", spec.drop_stream_rate),
+    ] {
+        if !(0.0..=1.0).contains(&value) {
+            return Err(FaultError::InvalidRate {
+                rule: rule.to_string(),
+                field,
+                value,
+            });
+        }
+    }
+    if !(400..=599).contains(&spec.error_status) {
+        return Err(FaultError::InvalidStatus {
+            rule: rule.to_string(),
+            status: spec.error_status,
+        });
+    }
+    Ok(())
+}
+
+// ============================================================================
+// FaultyProvider
+// ============================================================================
+
+/// Injects a provider's faults into its LLM requests.
+struct FaultyProvider {
+    inner: Arc<dyn LLMProvider>,
+    spec: FaultSpec,
+}
+
+impl FaultyProvider {
+    async fn before_request(&self) -> Result<FaultPlan, LLMError> {
+        let plan = FaultPlan::draw(&self.spec);
+        if !plan.delay.is_zero() {
+            tokio::time::sleep(plan.delay).await;
+        }
+        match plan.error_status {
+            Some(status) => Err(injected_error(status, "injected fault")),
+            None => Ok(plan),
+        }
+    }
+}
+
+#[async_trait]
+impl LLMProvider for FaultyProvider {
+    async fn chat(&self, request: ChatRequest) -> Result<ChatResponse, LLMError> {
+        self.before_request().await?;
+        self.inner.chat(request).await
+    }
+
+    async fn chat_stream(&self, request: ChatRequest) -> Result<ChatStream, LLMError> {
+        let plan = self.before_request().await?;
+        let stream = self.inner.chat_stream(request).await?;
+        let Some(after) = plan.drop_after else {
+            return Ok(stream);
+        };
+        let status = self.spec.error_status;
+        let dropped = futures::stream::once(async move {
+            Err(injected_error(status, "injected fault: stream dropped"))
+        });
+        Ok(Box::pin(stream.take(after).chain(dropped)))
+    }
+
+    async fn health_check(&self) -> Result<(), LLMError> {
+        self.inner.health_check().await
+    }
+
+    async fn embed(&self, model: &str, inputs: Vec<String>) -> Result<Vec<Vec<f32>>, LLMError> {
+        self.before_request().await?;
+        self.inner.embed(model, inputs).await
+    }
+
+    async fn list_models(&self) -> Result<Vec<String>, LLMError> {
+        self.inner.list_models().await
+    }
+}
+
+fn injected_error(status: u16, message: &str) -> LLMError {
+    if status == 429 {
+        return LLMError::RateLimit { retry_after: None };
+    }
+    LLMError::Api {
+        status,
+        message: message.to_string(),
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::llm::{Message, MockProvider, Role, StreamEvent};
+
+    fn spec(error_rate: f64, drop_stream_rate: f64) -> FaultSpec {
+        FaultSpec {
+            error_rate,
+            drop_stream_rate,
+            drop_after_events: 1,
+            ..FaultSpec::default()
+        }
+    }
+
+    fn injector(
+        routes: Vec<RouteFaultConfig>,
+        providers: Vec<ProviderFaultConfig>,
+    ) -> FaultInjector {
+        FaultInjector::from_config(&FaultsConfig {
+            enabled: true,
+            routes,
+            providers,
+        })
+        .unwrap()
+    }
+
+    fn request() -> ChatRequest {
+        ChatRequest::new("mock", vec![Message::text(Role::User, "hi")], None, None)
+    }
+
+    #[test]
+    fn disabled_config_injects_nothing() {
+        let config = FaultsConfig {
+            enabled: false,
+            routes: vec![RouteFaultConfig {
+                path: "/".to_string(),
+                methods: Vec::new(),
+                faults: spec(1.0, 0.0),
+            }],
+            providers: Vec::new(),
+        };
+        let faults = FaultInjector::from_config(&config).unwrap();
+        assert!(faults.is_empty());
+        assert_eq!(faults.route_faults("GET", "/livez"), None);
+    }
+
+    #[test]
+    fn rates_are_validated() {
+        let config = FaultsConfig {
+            enabled: false,
+            routes: Vec::new(),
+            providers: vec![ProviderFaultConfig {
+                provider: "openai".to_string(),
+                faults: spec(1.5, 0.0),
+            }],
+        };
+        assert!(matches!(
+            FaultInjector::from_config(&config),
+            Err(FaultError::InvalidRate {
+                field: "error_rate",
+                ..
+            })
+        ));
+    }
+
+    #[test]
+    fn routes_match_by_prefix_and_method() {
+        let faults = injector(
+            vec![RouteFaultConfig {
+                path: "/api/v1/sessions".to_string(),
+                methods: vec!["post".to_string()],
+                faults: spec(1.0, 0.0),
+            }],
+            Vec::new(),
+        );
+        let plan = faults.route_faults("POST", "/api/v1/sessions/abc/messages");
+        assert_eq!(plan.unwrap().error_status, Some(503));
+        assert_eq!(faults.route_faults("GET", "/api/v1/sessions"), None);
+        assert_eq!(faults.route_faults("POST", "/api/v1/agents"), None);
+    }
+
+    #[tokio::test]
+    async fn provider_errors_and_dropped_streams() {
+        let rule = |faults| ProviderFaultConfig {
+            provider: "mock".to_string(),
+            faults,
+        };
+
+        let failing = injector(Vec::new(), vec![rule(spec(1.0, 0.0))])
+            .wrap_provider("mock", Arc::new(MockProvider));
+        assert!(matches!(
+            failing.chat(request()).await,
+            Err(LLMError::Api { status: 503, .. })
+        ));
+
+        let dropping = injector(Vec::new(), vec![rule(spec(0.0, 1.0))])
+            .wrap_provider("mock", Arc::new(MockProvider));
+        let events: Vec<_> = dropping
+            .chat_stream(request())
+            .await
+            .unwrap()
+            .collect()
+            .await;
+        assert_eq!(events.len(), 2);
+        assert!(matches!(events[0], Ok(StreamEvent::Token(_))));
+        assert!(events[1].is_err());
+    }
+}
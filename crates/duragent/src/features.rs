@@ -0,0 +1,122 @@
+//! Feature flags for experimental subsystems.
+//!
+//! Flags start from the `features` section of the config and can be flipped
+//! at runtime through the admin API, so operators can roll out a subsystem
+//! without a new build. Runtime changes last until the next restart.
+
+use std::collections::BTreeMap;
+use std::sync::{Arc, RwLock};
+
+use tracing::warn;
+
+/// Multi-step agent workflows.
+pub const WORKFLOWS: &str = "workflows";
+/// Agent-to-agent (A2A) protocol endpoints.
+pub const A2A: &str = "a2a";
+/// Knowledge base sync connectors.
+pub const CONNECTORS: &str = "connectors";
+
+/// Every known flag with a one-line description. All default to off.
+pub const KNOWN_FEATURES: &[(&str, &str)] = &[
+    (WORKFLOWS, "Multi-step agent workflows"),
+    (A2A, "Agent-to-agent protocol endpoints"),
+    (CONNECTORS, "Knowledge base sync connectors"),
+];
+
+/// A flag name that is not in [`KNOWN_FEATURES`].
+#[derive(Debug, thiserror::Error)]
+#[error("unknown feature '{0}'")]
+pub struct UnknownFeature(pub String);
+
+/// Current state of one flag.
+#[derive(Debug, Clone)]
+pub struct FeatureState {
+    pub name: &'static str,
+    pub description: &'static str,
+    pub enabled: bool,
+}
+
+/// Shared, runtime-adjustable feature flags.
+///
+/// Uses `std::sync::RwLock` because the lock is never held across await points.
+#[derive(Debug, Clone, Default)]
+pub struct FeatureFlags {
+    enabled: Arc<RwLock<BTreeMap<&'static str, bool>>>,
+}
+
+impl FeatureFlags {
+    /// Build flags from the config's `features` map. Unknown names are
+    /// logged and ignored.
+    pub fn from_config(features: &BTreeMap<String, bool>) -> Self {
+        let flags = Self::default();
+        for (name, &enabled) in features {
+            if flags.set(name, enabled).is_err() {
+                warn!(feature = %name, "Ignoring unknown feature flag in config");
+            }
+        }
+        flags
+    }
+
+    /// Whether `name` is enabled. Unknown flags are off.
+    pub fn is_enabled(&self, name: &str) -> bool {
+        self.enabled
+            .read()
+            .unwrap()
+            .get(name)
+            .copied()
+            .unwrap_or(false)
+    }
+
+    /// Turn a flag on or off, returning its new state.
+    pub fn set(&self, name: &str, enabled: bool) -> Result<FeatureState, UnknownFeature> {
+        let &(name, description) = KNOWN_FEATURES
+            .iter()
+            .find(|(known, _)| *known == name)
+            .ok_or_else(|| UnknownFeature(name.to_string()))?;
+        self.enabled.write().unwrap().insert(name, enabled);
+        Ok(FeatureState {
+            name,
+            description,
+            enabled,
+        })
+    }
+
+    /// State of every known flag, in declaration order.
+    pub fn list(&self) -> Vec<FeatureState> {
+        KNOWN_FEATURES
+            .iter()
+            .map(|&(name, description)| FeatureState {
+                name,
+                description,
+                enabled: self.is_enabled(name),
+            })
+            .collect()
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn flags_default_off_and_follow_config() {
+        let config = BTreeMap::from([
+            (WORKFLOWS.to_string(), true),
+            ("teleport".to_string(), true),
+        ]);
+        let flags = FeatureFlags::from_config(&config);
+
+        assert!(flags.is_enabled(WORKFLOWS));
+        assert!(!flags.is_enabled(A2A));
+        assert!(!flags.is_enabled("teleport"));
+        assert_eq!(flags.list().len(), KNOWN_FEATURES.len());
+    }
+
+    #[test]
+    fn set_rejects_unknown_flags() {
+        let flags = FeatureFlags::default();
+        flags.set(A2A, true).unwrap();
+        assert!(flags.is_enabled(A2A));
+        assert!(flags.set("teleport", true).is_err());
+    }
+}
@@ -11,7 +11,7 @@ use crate::context::ContextBuilder;
 use crate::llm::{FunctionCall, ToolCall};
 use crate::session::{AgenticResult, ApprovalDecisionType, ResumeContext, resume_agentic_loop};
 use crate::tools::{
-    ReloadDeps, ToolDependencies, ToolExecutionContext, ToolResult, build_executor_async,
+    PLAN_TOOL, ReloadDeps, ToolDependencies, ToolExecutionContext, ToolResult, build_executor_async,
 };
 
 // ============================================================================
@@ -108,20 +108,27 @@ impl GatewayMessageHandler {
             return Some("Failed to process approval".to_string());
         }
 
-        // Get agent spec for tool execution
-        let agent = match self.services.agents.get(handle.agent()) {
-            Some(a) => a,
+        // Get agent spec for tool execution: the agent whose run is paused,
+        // which a router may have handed the message to
+        let agent_name = pending
+            .agent
+            .clone()
+            .unwrap_or_else(|| handle.agent().to_string());
+        let agent = match self.services.agents.get(&agent_name) {
+            Some(a) if a.enabled => a,
+            Some(_) => return Some("Agent is disabled".to_string()),
             None => {
-                error!(agent = %handle.agent(), "Agent not found");
+                error!(agent = %agent_name, "Agent not found");
                 return Some("Agent configuration error".to_string());
             }
         };
 
-        // If allow_always, save pattern to policy
+        // If allow_always, save pattern to policy (plans are approved one by one)
         if decision_type == ApprovalDecisionType::AllowAlways
+            && pending.tool_name != PLAN_TOOL
             && let Err(e) = crate::agent::add_policy_pattern_and_save(
                 self.services.policy_store.as_ref(),
-                handle.agent(),
+                &agent_name,
                 pending.tool_type,
                 &pending.command,
                 &self.policy_locks,
@@ -137,7 +144,7 @@ impl GatewayMessageHandler {
 
         // Load policy from store (picks up runtime changes from AllowAlways)
         // This is loaded AFTER add_pattern_and_save so it includes any newly saved pattern
-        let policy = self.services.policy_store.load(handle.agent()).await;
+        let policy = self.services.policy_store.load(&agent_name).await;
 
         // Determine tool result based on decision
         let tool_result = if decision_type == ApprovalDecisionType::Deny {
@@ -164,12 +171,12 @@ impl GatewayMessageHandler {
                 workspace_tools_dir: Some(self.services.workspace_tools_path.clone()),
                 process_registry: self.process_registry.clone(),
                 session_id: Some(handle.id().to_string()),
-                agent_name: Some(handle.agent().to_string()),
+                agent_name: Some(agent_name.clone()),
                 session_registry: Some(self.services.session_registry.clone()),
             };
             let executor = match build_executor_async(
                 agent.clone(),
-                handle.agent().to_string(),
+                agent_name.clone(),
                 handle.id().to_string(),
                 policy.clone(),
                 deps,
@@ -182,7 +189,8 @@ impl GatewayMessageHandler {
                     error!(error = %e, "Failed to build tool executor");
                     return Some("Tool execution failed: executor init failed".to_string());
                 }
-            };
+            }
+            .with_mocks(self.services.tool_mocks.clone());
 
             // Build tool call from pending approval
             let tool_call = ToolCall {
@@ -237,12 +245,12 @@ impl GatewayMessageHandler {
             workspace_tools_dir: Some(self.services.workspace_tools_path.clone()),
             process_registry: self.process_registry.clone(),
             session_id: Some(handle.id().to_string()),
-            agent_name: Some(handle.agent().to_string()),
+            agent_name: Some(agent_name.clone()),
             session_registry: Some(self.services.session_registry.clone()),
         };
         let mut executor = match build_executor_async(
             agent.clone(),
-            handle.agent().to_string(),
+            agent_name.clone(),
             handle.id().to_string(),
             policy,
             deps,
@@ -261,18 +269,27 @@ impl GatewayMessageHandler {
             agent_dir: agent.agent_dir.clone(),
             workspace_tools_dir: Some(self.services.workspace_tools_path.clone()),
             agent_tool_configs: agent.tools.clone(),
-        });
+        })
+        .with_mocks(self.services.tool_mocks.clone());
 
-        // Extract tool_refs from agent spec (consistent with run path)
-        let tool_refs = ContextBuilder::new()
-            .from_agent_spec(&agent)
-            .build()
-            .tool_refs;
+        // Keep the paused run's tool restriction, else extract tool_refs from
+        // agent spec (consistent with run path)
+        let tool_refs = match &pending.tools {
+            Some(tools) => Some(tools.iter().cloned().collect()),
+            None => {
+                ContextBuilder::new()
+                    .from_agent_spec(&agent)
+                    .build()
+                    .tool_refs
+            }
+        };
 
         // Acquire per-session agentic loop lock to prevent concurrent loops
         let loop_lock = self.services.agentic_loop_locks.get(handle.id());
         let _loop_guard = loop_lock.lock().await;
 
+        let provider = self.services.run_provider(&agent, provider, &handle).await;
+
         // Resume the agentic loop
         let result = match resume_agentic_loop(
             provider,
@@ -331,6 +348,7 @@ impl GatewayMessageHandler {
                 usage: _,
                 iterations: _,
                 tool_calls_made: _,
+                stats: _,
             } => {
                 // Tag with the same requester who approved the previous command
                 new_pending.requester_id = Some(data.sender.id.clone());
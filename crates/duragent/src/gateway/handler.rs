@@ -34,13 +34,15 @@ use crate::api::SessionStatus;
 use crate::context::{
     BlockSource, ContextBuilder, SystemBlock, TokenBudget, load_all_directives_async, priority,
 };
+use crate::postprocess;
 use crate::process::ProcessRegistryHandle;
 use crate::scheduler::SchedulerHandle;
 use crate::server::RuntimeServices;
 use crate::session::{
-    AGENTIC_LOOP_LOCK_TIMEOUT, AgenticResult, ChatSessionCache, STEERING_CHANNEL_CAPACITY,
-    SessionHandle, SteeringMessage, run_agentic_loop,
+    AGENTIC_LOOP_LOCK_TIMEOUT, AgenticResult, ChatSessionCache, RunPriority,
+    STEERING_CHANNEL_CAPACITY, SessionHandle, SteeringMessage, run_agentic_loop,
 };
+use crate::stream_processors;
 use crate::sync::KeyedLocks;
 use crate::tools::{ReloadDeps, ToolDependencies, ToolExecutionContext, build_executor_async};
 
@@ -139,7 +141,7 @@ impl MessageHandler for GatewayMessageHandler {
         let handle = self.get_or_create_session(gateway, routing).await?;
 
         // Check agent-level access control
-        let agent = self.services.agents.get(handle.agent())?;
+        let agent = self.services.agents.get_enabled(handle.agent())?;
         if let Some(ref access) = agent.access {
             if !check_access(
                 access,
@@ -454,7 +456,7 @@ impl GatewayMessageHandler {
         routing: &RoutingContext,
         already_persisted: bool,
     ) -> Option<String> {
-        let agent = self.services.agents.get(handle.agent())?;
+        let agent = self.services.agents.get_enabled(handle.agent())?;
         let sender_label = resolve_sender_label(sender);
 
         // Persist user message via actor (with sender attribution for gateway messages)
@@ -463,7 +465,7 @@ impl GatewayMessageHandler {
                 .add_user_message_with_sender(
                     text.to_string(),
                     Some(sender.id.clone()),
-                    Some(sender_label.clone()),
+                    Some(sender_label),
                 )
                 .await
         {
@@ -471,21 +473,17 @@ impl GatewayMessageHandler {
             return None;
         }
 
+        let agent = self.services.route_agent(agent, text).await;
+
         // Route to agentic loop if agent has tools configured
         if !agent.tools.is_empty() {
             return self
-                .process_text_message_agentic(
-                    gateway,
-                    handle,
-                    text,
-                    &sender.id,
-                    Some(sender_label),
-                    routing,
-                )
+                .process_text_message_agentic(gateway, handle, agent, text, sender, routing)
                 .await;
         }
 
         // Simple single-turn for agents without tools
+        let agent = self.services.route_model(agent, text).await;
         let provider = self
             .services
             .providers
@@ -507,8 +505,13 @@ impl GatewayMessageHandler {
             agent.clone(),
         )
         .await;
+        let blocks = self
+            .services
+            .context_blocks(&agent, &provider, &handle, &history)
+            .await;
         let mut builder = ContextBuilder::new()
             .from_agent_spec(&agent)
+            .with_blocks(blocks)
             .with_messages(history)
             .with_directives(directives);
 
@@ -532,6 +535,8 @@ impl GatewayMessageHandler {
             &budget,
         );
 
+        let _permit = self.services.run_pool.acquire(RunPriority::Normal).await;
+        let provider = self.services.run_provider(&agent, provider, handle).await;
         let response = match provider.chat(chat_request).await {
             Ok(resp) => resp,
             Err(e) => {
@@ -540,15 +545,25 @@ impl GatewayMessageHandler {
             }
         };
 
-        let assistant_content = response
+        let (assistant_content, reasoning) = response
             .choices
             .first()
-            .and_then(|c| c.message.content.clone())
+            .map(|c| (c.message.content.clone(), c.message.reasoning.clone()))
             .unwrap_or_default();
+        let assistant_content = assistant_content.unwrap_or_default();
+
+        if let Some(reasoning) = reasoning
+            && let Err(e) = handle.record_reasoning(reasoning).await
+        {
+            error!(error = %e, "Failed to record reasoning");
+        }
 
         if assistant_content.trim().is_empty() {
             return None;
         }
+        let assistant_content =
+            stream_processors::apply(&agent.stream_processors, assistant_content);
+        let assistant_content = postprocess::apply(&agent.post_processors, assistant_content);
 
         if let Err(e) = handle
             .add_assistant_message(assistant_content.clone(), response.usage)
@@ -620,13 +635,12 @@ impl GatewayMessageHandler {
         &self,
         gateway: &str,
         handle: &SessionHandle,
+        agent: Arc<AgentSpec>,
         text: &str,
-        sender_id: &str,
-        sender_label: Option<String>,
+        sender: &Sender,
         routing: &RoutingContext,
     ) -> Option<String> {
         let chat_id = &routing.chat_id;
-        let agent = self.services.agents.get(handle.agent())?;
 
         // If a loop is already running, steer and return immediately.
         if let Some(tx_ref) = self.services.steering_channels.get(handle.id()) {
@@ -634,8 +648,8 @@ impl GatewayMessageHandler {
             drop(tx_ref);
             match tx.try_send(SteeringMessage {
                 content: text.to_string(),
-                sender_id: Some(sender_id.to_string()),
-                sender_label,
+                sender_id: Some(sender.id.clone()),
+                sender_label: Some(resolve_sender_label(sender)),
                 persisted: true,
             }) {
                 Ok(()) => return None,
@@ -649,6 +663,7 @@ impl GatewayMessageHandler {
             }
         }
 
+        let agent = self.services.route_model(agent, text).await;
         let provider = self
             .services
             .providers
@@ -656,7 +671,7 @@ impl GatewayMessageHandler {
             .await?;
 
         // Load policy from store (picks up runtime changes from AllowAlways)
-        let policy = self.services.policy_store.load(handle.agent()).await;
+        let policy = self.services.policy_store.load(&agent.metadata.name).await;
 
         // Create tool executor with execution context for schedule tools
         let execution_context = self.scheduler.as_ref().map(|_| ToolExecutionContext {
@@ -673,12 +688,12 @@ impl GatewayMessageHandler {
             workspace_tools_dir: Some(self.services.workspace_tools_path.clone()),
             process_registry: self.process_registry.clone(),
             session_id: Some(handle.id().to_string()),
-            agent_name: Some(handle.agent().to_string()),
+            agent_name: Some(agent.metadata.name.clone()),
             session_registry: Some(self.services.session_registry.clone()),
         };
         let mut executor = match build_executor_async(
             agent.clone(),
-            handle.agent().to_string(),
+            agent.metadata.name.clone(),
             handle.id().to_string(),
             policy,
             deps,
@@ -697,7 +712,8 @@ impl GatewayMessageHandler {
             agent_dir: agent.agent_dir.clone(),
             workspace_tools_dir: Some(self.services.workspace_tools_path.clone()),
             agent_tool_configs: agent.tools.clone(),
-        });
+        })
+        .with_mocks(self.services.tool_mocks.clone());
 
         // Build initial messages from history using StructuredContext
         let history = match handle.get_messages().await {
@@ -713,8 +729,13 @@ impl GatewayMessageHandler {
             agent.clone(),
         )
         .await;
+        let blocks = self
+            .services
+            .context_blocks(&agent, &provider, &handle, &history)
+            .await;
         let mut builder = ContextBuilder::new()
             .from_agent_spec(&agent)
+            .with_blocks(blocks)
             .with_messages(history)
             .with_directives(directives);
 
@@ -762,6 +783,8 @@ impl GatewayMessageHandler {
                     return Some("Session busy. Please retry in a moment.".to_string());
                 }
             };
+        let _permit = self.services.run_pool.acquire(RunPriority::Normal).await;
+        let provider = self.services.run_provider(&agent, provider, handle).await;
 
         // Run agentic loop
         let result = match run_agentic_loop(
@@ -809,6 +832,7 @@ impl GatewayMessageHandler {
                 usage: _,
                 iterations: _,
                 tool_calls_made: _,
+                stats: _,
             } => {
                 // Tag with requester so only they can approve in group chats
                 pending.requester_id = Some(sender_id.to_string());
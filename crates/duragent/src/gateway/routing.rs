@@ -4,7 +4,7 @@ use tracing::{debug, warn};
 
 use duragent_gateway_protocol::RoutingContext;
 
-use crate::agent::{ModelConfigEval, QueueConfig};
+use crate::agent::{ChangeSource, ModelConfigEval, QueueConfig, RunOrigin};
 use crate::config::{RoutingMatch, RoutingRule};
 use crate::session::SessionHandle;
 
@@ -59,7 +59,7 @@ impl GatewayMessageHandler {
         let agent_name = self.resolve_agent(gateway, routing)?;
 
         // Get the agent spec for session creation (needed if we create a new session)
-        let agent = self.services.agents.get(&agent_name)?;
+        let agent = self.services.agents.get_enabled(&agent_name)?;
 
         // Clone values needed in closures
         let registry = self.services.session_registry.clone();
@@ -75,6 +75,7 @@ impl GatewayMessageHandler {
         let msg_limit =
             crate::session::actor_message_limit(agent.model.effective_max_input_tokens());
         let compaction_override = agent.session.compaction;
+        let user = gateway_user(gateway, routing);
 
         // Use atomic get-or-insert to prevent race conditions
         let session_id = match self
@@ -94,6 +95,7 @@ impl GatewayMessageHandler {
                     let agent_name = agent_name_clone.clone();
                     let gateway = gateway_clone.clone();
                     let chat_id = chat_id_clone.clone();
+                    let user = user.clone();
                     async move {
                         let handle = registry
                             .create(
@@ -105,6 +107,11 @@ impl GatewayMessageHandler {
                                     silent_buffer_cap,
                                     actor_message_limit: msg_limit,
                                     compaction_override,
+                                    origin: Some(RunOrigin {
+                                        source: ChangeSource::Gateway,
+                                        created_by: Some(gateway.clone()),
+                                        user,
+                                    }),
                                 },
                             )
                             .await?;
@@ -257,6 +264,12 @@ pub(super) fn is_group_chat(chat_type: &str) -> bool {
     )
 }
 
+/// End user of a direct chat, as `{gateway}:{sender_id}`. Group chats have
+/// no single user, so their facts are never recalled to the whole group.
+fn gateway_user(gateway: &str, routing: &RoutingContext) -> Option<String> {
+    (!is_group_chat(&routing.chat_type)).then(|| format!("{gateway}:{}", routing.sender_id))
+}
+
 #[cfg(test)]
 mod tests {
     use std::collections::HashMap;
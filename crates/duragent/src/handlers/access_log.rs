@@ -0,0 +1,200 @@
+//! Sampled access logging.
+//!
+//! Every request answered with a 4xx or 5xx status is logged. Successful
+//! requests are logged one in every `sample_every`, with per-route overrides
+//! keyed by route template (e.g. `/api/v1/sessions/{session_id}/messages`),
+//! so busy endpoints and health probes don't dominate CPU or log volume.
+//!
+//! Samplers are built once at startup and looked up by borrowed route string;
+//! log fields borrow from the request, so the per-request path does not
+//! allocate beyond what the tracing subscriber itself does.
+
+use std::collections::HashMap;
+use std::sync::Arc;
+use std::sync::atomic::{AtomicU64, Ordering};
+use std::time::Instant;
+
+use axum::body::Body;
+use axum::extract::{MatchedPath, State};
+use axum::http::Request;
+use axum::middleware::Next;
+use axum::response::Response;
+use tracing::{info, warn};
+
+use crate::config::AccessLogConfig;
+
+/// Access log settings shared by every request; cheap to clone.
+#[derive(Clone, Default)]
+pub struct AccessLog {
+    /// `None` when access logging is disabled.
+    inner: Option<Arc<AccessLogInner>>,
+}
+
+struct AccessLogInner {
+    base_path: String,
+    default: Sampler,
+    routes: HashMap<String, Sampler>,
+}
+
+/// Logs one in every `every` successful requests (0 = none).
+struct Sampler {
+    every: u32,
+    seen: AtomicU64,
+}
+
+impl Sampler {
+    fn new(every: u32) -> Self {
+        Self {
+            every,
+            seen: AtomicU64::new(0),
+        }
+    }
+
+    fn sample(&self) -> bool {
+        match self.every {
+            0 => false,
+            1 => true,
+            n => self.seen.fetch_add(1, Ordering::Relaxed) % u64::from(n) == 0,
+        }
+    }
+}
+
+impl AccessLog {
+    /// Build from config. `base_path` is stripped before matching route overrides.
+    pub fn new(config: &AccessLogConfig, base_path: &str) -> Self {
+        if !config.enabled {
+            return Self::default();
+        }
+        let routes = config
+            .routes
+            .iter()
+            .map(|(route, &every)| (route.clone(), Sampler::new(every)))
+            .collect();
+        Self {
+            inner: Some(Arc::new(AccessLogInner {
+                base_path: base_path.to_string(),
+                default: Sampler::new(config.sample_every),
+                routes,
+            })),
+        }
+    }
+}
+
+impl AccessLogInner {
+    fn sample_success(&self, route: &str) -> bool {
+        let route = route.strip_prefix(&*self.base_path).unwrap_or(route);
+        self.routes.get(route).unwrap_or(&self.default).sample()
+    }
+}
+
+/// Middleware that writes one access log line per sampled request.
+pub async fn log_requests(
+    State(log): State<AccessLog>,
+    request: Request<Body>,
+    next: Next,
+) -> Response {
+    let Some(log) = log.inner else {
+        return next.run(request).await;
+    };
+
+    let started = Instant::now();
+    let method = request.method().clone();
+    let uri = request.uri().clone();
+    let matched = request.extensions().get::<MatchedPath>().cloned();
+
+    let response = next.run(request).await;
+
+    let status = response.status();
+    let route = matched.as_ref().map_or(uri.path(), MatchedPath::as_str);
+    let is_server_error = status.is_server_error();
+    if !status.is_client_error() && !is_server_error && !log.sample_success(route) {
+        return response;
+    }
+
+    let path = uri.path();
+    let status = status.as_u16();
+    let latency_ms = started.elapsed().as_millis() as u64;
+    if is_server_error {
+        warn!(
+            target: "duragent::access",
+            %method,
+            path,
+            route,
+            status,
+            latency_ms,
+            "request"
+        );
+    } else {
+        info!(
+            target: "duragent::access",
+            %method,
+            path,
+            route,
+            status,
+            latency_ms,
+            "request"
+        );
+    }
+    response
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn config(sample_every: u32, routes: &[(&str, u32)]) -> AccessLogConfig {
+        AccessLogConfig {
+            enabled: true,
+            sample_every,
+            routes: routes
+                .iter()
+                .map(|&(route, every)| (route.to_string(), every))
+                .collect(),
+        }
+    }
+
+    fn sampled(log: &AccessLog, route: &str, requests: usize) -> usize {
+        let inner = log.inner.as_ref().unwrap();
+        (0..requests)
+            .filter(|_| inner.sample_success(route))
+            .count()
+    }
+
+    #[test]
+    fn disabled_has_no_samplers() {
+        let log = AccessLog::new(&AccessLogConfig::default(), "");
+        assert!(log.inner.is_none());
+    }
+
+    #[test]
+    fn samples_one_in_n() {
+        let log = AccessLog::new(&config(10, &[]), "");
+        assert_eq!(sampled(&log, "/api/v1/agents", 100), 10);
+
+        let log = AccessLog::new(&config(1, &[]), "");
+        assert_eq!(sampled(&log, "/api/v1/agents", 5), 5);
+    }
+
+    #[test]
+    fn route_overrides_default() {
+        let log = AccessLog::new(
+            &config(
+                1,
+                &[("/livez", 0), ("/api/v1/sessions/{session_id}/messages", 4)],
+            ),
+            "",
+        );
+        assert_eq!(sampled(&log, "/livez", 50), 0);
+        assert_eq!(
+            sampled(&log, "/api/v1/sessions/{session_id}/messages", 8),
+            2
+        );
+        assert_eq!(sampled(&log, "/api/v1/agents", 3), 3);
+    }
+
+    #[test]
+    fn route_overrides_ignore_base_path() {
+        let log = AccessLog::new(&config(1, &[("/readyz", 0)]), "/duragent");
+        assert_eq!(sampled(&log, "/duragent/readyz", 10), 0);
+    }
+}
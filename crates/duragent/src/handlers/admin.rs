@@ -2,12 +2,18 @@
 
 use std::net::SocketAddr;
 
-use axum::extract::{ConnectInfo, State};
+use axum::Json;
+use axum::extract::{ConnectInfo, Path, State};
 use axum::http::{HeaderMap, StatusCode};
 use axum::response::IntoResponse;
+use tracing::info;
 
-use super::api_auth;
+use super::validation::ValidJson;
+use super::{api_auth, problem_details};
 use crate::agent::{AgentStore, log_scan_warnings};
+use crate::api::{FeatureResponse, ListFeaturesResponse, SetFeatureRequest};
+use crate::audit::{AuditEvent, AuditOutcome};
+use crate::features::FeatureState;
 use crate::server::AppState;
 use crate::store::file::FileAgentCatalog;
 
@@ -24,14 +30,19 @@ pub async fn shutdown(
     headers: HeaderMap,
 ) -> impl IntoResponse {
     if !api_auth::is_authorized(&state.admin_token, &addr, &headers) {
-        return (StatusCode::FORBIDDEN, "Admin access denied").into_response();
+        return api_auth::admin_denied(&state, &addr);
     }
 
     if let Some(tx) = state.shutdown_tx.lock().await.take() {
+        api_auth::audit_admin(
+            &state,
+            &addr,
+            AuditEvent::new("server.shutdown", AuditOutcome::Success),
+        );
         let _ = tx.send(());
         (StatusCode::OK, "Shutdown initiated").into_response()
     } else {
-        (StatusCode::CONFLICT, "Shutdown already in progress").into_response()
+        problem_details::conflict("shutdown already in progress").into_response()
     }
 }
 
@@ -46,15 +57,109 @@ pub async fn reload_agents(
     headers: HeaderMap,
 ) -> impl IntoResponse {
     if !api_auth::is_authorized(&state.admin_token, &addr, &headers) {
-        return (StatusCode::FORBIDDEN, "Admin access denied").into_response();
+        return api_auth::admin_denied(&state, &addr);
     }
 
     let catalog = FileAgentCatalog::new(&state.agents_dir, state.workspace_dir.clone());
     let report = AgentStore::from_catalog(&catalog).await;
     log_scan_warnings(&report.warnings);
+    state.services.models.warn_agent_issues(&report.store);
 
     let count = report.store.len();
     state.services.agents.replace_from(&report.store);
+    api_auth::audit_admin(
+        &state,
+        &addr,
+        AuditEvent::new("agents.reloaded", AuditOutcome::Success).detail(format!("{count} agents")),
+    );
 
     (StatusCode::OK, format!("Reloaded {} agents", count)).into_response()
 }
+
+/// POST /api/admin/v1/upgrade
+///
+/// Starts the binary at the server's path as a new server that takes over
+/// the listening socket, then drains this one. Same as sending `SIGUSR2`.
+///
+/// Authorization: same as shutdown.
+pub async fn upgrade(
+    State(state): State<AppState>,
+    ConnectInfo(addr): ConnectInfo<SocketAddr>,
+    headers: HeaderMap,
+) -> impl IntoResponse {
+    if !api_auth::is_authorized(&state.admin_token, &addr, &headers) {
+        return api_auth::admin_denied(&state, &addr);
+    }
+
+    if !state.upgrade.is_supported() {
+        return problem_details::conflict("upgrades are only supported on Unix").into_response();
+    }
+    if !state.upgrade.trigger() {
+        return problem_details::conflict("upgrade already in progress").into_response();
+    }
+    api_auth::audit_admin(
+        &state,
+        &addr,
+        AuditEvent::new("server.upgrade", AuditOutcome::Success),
+    );
+    (StatusCode::ACCEPTED, "Upgrade initiated").into_response()
+}
+
+/// GET /api/admin/v1/features
+///
+/// Lists every feature flag and whether it is enabled.
+///
+/// Authorization: same as shutdown.
+pub async fn list_features(
+    State(state): State<AppState>,
+    ConnectInfo(addr): ConnectInfo<SocketAddr>,
+    headers: HeaderMap,
+) -> impl IntoResponse {
+    if !api_auth::is_authorized(&state.admin_token, &addr, &headers) {
+        return api_auth::admin_denied(&state, &addr);
+    }
+
+    let features = state.features.list().into_iter().map(to_response).collect();
+    Json(ListFeaturesResponse { features }).into_response()
+}
+
+/// PUT /api/admin/v1/features/{name}
+///
+/// Turns a feature flag on or off. The change lasts until the next restart;
+/// persist it in the `features` config section to keep it.
+///
+/// Authorization: same as shutdown.
+pub async fn set_feature(
+    State(state): State<AppState>,
+    ConnectInfo(addr): ConnectInfo<SocketAddr>,
+    headers: HeaderMap,
+    Path(name): Path<String>,
+    ValidJson(req): ValidJson<SetFeatureRequest>,
+) -> impl IntoResponse {
+    if !api_auth::is_authorized(&state.admin_token, &addr, &headers) {
+        return api_auth::admin_denied(&state, &addr);
+    }
+
+    match state.features.set(&name, req.enabled) {
+        Ok(feature) => {
+            info!(feature = %name, enabled = req.enabled, "Feature flag changed");
+            api_auth::audit_admin(
+                &state,
+                &addr,
+                AuditEvent::new("feature.updated", AuditOutcome::Success)
+                    .target(&name)
+                    .detail(format!("enabled={}", req.enabled)),
+            );
+            Json(to_response(feature)).into_response()
+        }
+        Err(e) => problem_details::not_found(e.to_string()).into_response(),
+    }
+}
+
+fn to_response(feature: FeatureState) -> FeatureResponse {
+    FeatureResponse {
+        name: feature.name.to_string(),
+        description: feature.description.to_string(),
+        enabled: feature.enabled,
+    }
+}
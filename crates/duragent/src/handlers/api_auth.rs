@@ -5,17 +5,52 @@
 //! Behavior:
 //! - Token configured: requires `Authorization: Bearer <token>` header
 //! - Token not configured: only accepts requests from loopback addresses
+//! - Service account token (`dsa_...`): accepted on API routes from any
+//!   address, limited to the account's scopes
+//!
+//! API routes are then checked against the caller's `scim.group_roles`
+//! role, when a front end names a user, and against `authorization.policies`.
 
 use std::net::SocketAddr;
 
-use axum::extract::{ConnectInfo, State};
-use axum::http::{HeaderMap, Request, StatusCode};
+use axum::body::Body;
+use axum::extract::{ConnectInfo, OriginalUri, State};
+use axum::http::{HeaderMap, Method, Request};
 use axum::middleware::Next;
 use axum::response::{IntoResponse, Response};
 use sha2::{Digest, Sha256};
 use subtle::ConstantTimeEq;
+use tracing::warn;
+
+use super::problem_details;
+use crate::audit::{AuditEvent, AuditOutcome};
+use crate::encryption::DEFAULT_NAMESPACE;
+use crate::identity::{Grant, grants_for, role_in, role_permits};
+use crate::policy::{AgentTarget, Principal, RequestContext};
+use crate::server::{AppState, MAX_REQUEST_BODY_BYTES};
+use crate::service_accounts::{ServiceAccount, TOKEN_PREFIX};
+
+/// Service account that authenticated a request.
+///
+/// Inserted as a request extension by [`require_api_token`].
+#[derive(Debug, Clone)]
+pub struct ServiceAccountPrincipal {
+    pub name: String,
+}
 
-use crate::server::AppState;
+impl ServiceAccountPrincipal {
+    /// Principal recorded as the author of changes made by this account.
+    pub fn created_by(&self) -> String {
+        format!("service-account:{}", self.name)
+    }
+}
+
+fn bearer_token(headers: &HeaderMap) -> Option<&str> {
+    headers
+        .get("authorization")
+        .and_then(|v| v.to_str().ok())
+        .and_then(|v| v.strip_prefix("Bearer "))
+}
 
 /// Check if a request is authorized against an optional token.
 ///
@@ -24,32 +59,452 @@ use crate::server::AppState;
 /// - If token is `None`: only allows requests from loopback addresses
 pub fn is_authorized(token: &Option<String>, addr: &SocketAddr, headers: &HeaderMap) -> bool {
     match token {
-        Some(expected) => headers
-            .get("authorization")
-            .and_then(|v| v.to_str().ok())
-            .and_then(|v| v.strip_prefix("Bearer "))
-            .is_some_and(|provided| {
-                let a = Sha256::digest(provided.as_bytes());
-                let b = Sha256::digest(expected.as_bytes());
-                a.ct_eq(&b).into()
-            }),
+        Some(expected) => bearer_token(headers).is_some_and(|provided| {
+            let a = Sha256::digest(provided.as_bytes());
+            let b = Sha256::digest(expected.as_bytes());
+            a.ct_eq(&b).into()
+        }),
         None => addr.ip().is_loopback(),
     }
 }
 
+/// Check access to an admin-guarded API route (`/api/v1/*`) that service
+/// accounts may also call.
+///
+/// Service account tokens pass: [`require_api_token`] has already checked
+/// them against the scope the route needs. Anyone else needs admin access.
+pub fn has_scoped_access(state: &AppState, addr: &SocketAddr, headers: &HeaderMap) -> bool {
+    service_account(state, headers).is_some() || is_authorized(&state.admin_token, addr, headers)
+}
+
+/// Check whether the caller may see model reasoning from an agent in
+/// `project`: the admin token, or a service account holding an admin write
+/// scope or `runs:reasoning` there. `admin:read` alone is not enough.
+pub fn may_see_reasoning(
+    state: &AppState,
+    addr: &SocketAddr,
+    headers: &HeaderMap,
+    project: Option<&str>,
+) -> bool {
+    match service_account(state, headers) {
+        Some(account) => {
+            account.is_admin() || account.sees_reasoning(project.unwrap_or(DEFAULT_NAMESPACE))
+        }
+        None => is_authorized(&state.admin_token, addr, headers),
+    }
+}
+
+fn service_account(state: &AppState, headers: &HeaderMap) -> Option<ServiceAccount> {
+    bearer_token(headers)
+        .filter(|token| token.starts_with(TOKEN_PREFIX))
+        .and_then(|token| state.service_accounts.authenticate(token))
+}
+
+/// Audit a rejected admin request and build its `403` response.
+pub fn admin_denied(state: &AppState, addr: &SocketAddr) -> Response {
+    state.audit.record(
+        AuditEvent::new("auth.failed", AuditOutcome::Denied)
+            .source(addr)
+            .target("admin")
+            .detail("admin access denied"),
+    );
+    problem_details::forbidden("admin access denied").into_response()
+}
+
+/// Audit a successful admin action, attributed to the admin principal.
+pub fn audit_admin(state: &AppState, addr: &SocketAddr, event: AuditEvent) {
+    state.audit.record(
+        event
+            .principal(principal(&state.admin_token, "admin"))
+            .source(addr),
+    );
+}
+
+/// Audit a successful action on an admin-guarded API route, attributed to
+/// the service account that authorized it, or else the admin principal.
+pub fn audit_scoped(
+    state: &AppState,
+    addr: &SocketAddr,
+    service_account: Option<&ServiceAccountPrincipal>,
+    event: AuditEvent,
+) {
+    match service_account {
+        Some(account) => state
+            .audit
+            .record(event.principal(account.created_by()).source(addr)),
+        None => audit_admin(state, addr, event),
+    }
+}
+
+/// Full request path, including any prefix stripped by nested routers.
+pub fn original_path(request: &Request<Body>) -> String {
+    request
+        .extensions()
+        .get::<OriginalUri>()
+        .map_or_else(|| request.uri().path(), |uri| uri.path())
+        .to_string()
+}
+
+/// Principal recorded as the author of changes made by an authorized request.
+///
+/// Callers holding a configured `token` are recorded under the token's
+/// `name` (e.g. `admin`); without a token, callers are loopback clients and
+/// are recorded as `local`.
+pub fn principal(token: &Option<String>, name: &str) -> String {
+    match token {
+        Some(_) => name.to_string(),
+        None => "local".to_string(),
+    }
+}
+
 /// Middleware that guards API routes (`/api/v1/*`).
 ///
 /// Uses `api_token` from `AppState`. Always installed — falls back to
-/// localhost-only when no token is configured.
+/// localhost-only when no token is configured. Service account tokens are
+/// checked against the account's scopes, even from loopback. Authorized
+/// requests must then pass every authorization policy.
 pub async fn require_api_token(
     State(state): State<AppState>,
     ConnectInfo(addr): ConnectInfo<SocketAddr>,
-    request: Request<axum::body::Body>,
+    request: Request<Body>,
     next: Next,
 ) -> Response {
-    if is_authorized(&state.api_token, &addr, request.headers()) {
-        next.run(request).await
-    } else {
-        StatusCode::UNAUTHORIZED.into_response()
+    let account = service_account(&state, request.headers());
+    if account.is_none() && !is_authorized(&state.api_token, &addr, request.headers()) {
+        state.audit.record(
+            AuditEvent::new("auth.failed", AuditOutcome::Denied)
+                .source(&addr)
+                .target(original_path(&request))
+                .detail("missing or invalid api token"),
+        );
+        return problem_details::unauthorized("missing or invalid api token").into_response();
+    }
+    let enforce_roles = !state.scim.group_roles.is_empty();
+    if account.is_none() && state.policies.is_empty() && !enforce_roles {
+        return next.run(request).await;
+    }
+
+    let (mut request, target) = match resolve_target(&state, request).await {
+        Ok(r) => r,
+        Err(response) => return response,
+    };
+
+    if let Some(account) = &account
+        && let Some(target) = &target
+        && !account.permits(target.resource, target.verb, target.project.as_deref())
+    {
+        let scope = scope_suffix(target.project.as_deref());
+        let detail = format!(
+            "service account '{}' lacks scope {}:{}{scope}",
+            account.name, target.resource, target.verb
+        );
+        state.audit.record(
+            AuditEvent::new("access.denied", AuditOutcome::Denied)
+                .principal(format!("service-account:{}", account.name))
+                .source(&addr)
+                .target(original_path(&request))
+                .detail(&detail),
+        );
+        return problem_details::forbidden(detail).into_response();
+    }
+
+    let (principal, grants) = match &account {
+        Some(account) => (
+            Principal {
+                kind: "service_account",
+                name: account.name.clone(),
+                groups: Vec::new(),
+            },
+            None,
+        ),
+        None => caller(&state, request.headers()).await,
+    };
+
+    if enforce_roles
+        && let Some(grants) = &grants
+        && let Some(target) = &target
+        && !role_in(grants, target.project.as_deref())
+            .is_some_and(|role| role_permits(role, target.resource, target.verb))
+    {
+        let scope = scope_suffix(target.project.as_deref());
+        let detail = format!(
+            "user '{}' has no role permitting {}:{}{scope}",
+            principal.name, target.resource, target.verb
+        );
+        state.audit.record(
+            AuditEvent::new("access.denied", AuditOutcome::Denied)
+                .principal(principal.to_string())
+                .source(&addr)
+                .target(original_path(&request))
+                .detail(&detail),
+        );
+        return problem_details::forbidden(detail).into_response();
+    }
+
+    if !state.policies.is_empty() {
+        let who = principal.to_string();
+        let ctx = request_context(&state, &request, principal, target);
+        if let Err(denial) = state.policies.check(&ctx) {
+            let detail = match denial.description {
+                Some(description) => format!("denied by policy '{}': {description}", denial.policy),
+                None => format!("denied by policy '{}'", denial.policy),
+            };
+            state.audit.record(
+                AuditEvent::new("access.denied", AuditOutcome::Denied)
+                    .principal(who)
+                    .source(&addr)
+                    .target(original_path(&request))
+                    .detail(&detail),
+            );
+            return problem_details::forbidden(detail).into_response();
+        }
+    }
+
+    if let Some(account) = account {
+        request
+            .extensions_mut()
+            .insert(ServiceAccountPrincipal { name: account.name });
+    }
+    next.run(request).await
+}
+
+// ============================================================================
+// Request Targets
+// ============================================================================
+
+/// What a request acts on: a verb on a resource, in a project.
+///
+/// `project` is `None` for collections and other cross-project targets,
+/// which only unscoped (all-project) service account scopes permit.
+#[derive(Debug, PartialEq, Eq)]
+struct RequestTarget {
+    resource: &'static str,
+    verb: &'static str,
+    /// Agent the request acts on, directly or through one of its sessions.
+    agent: Option<String>,
+    project: Option<String>,
+}
+
+/// Resolve what a request to an API route acts on.
+///
+/// Returns `None` for discovery routes any authenticated caller may read.
+/// Session creation needs the target agent, so its body is buffered and
+/// put back on the request.
+async fn resolve_target(
+    state: &AppState,
+    request: Request<Body>,
+) -> Result<(Request<Body>, Option<RequestTarget>), Response> {
+    let method = request.method().clone();
+    let path = request.uri().path().trim_matches('/').to_string();
+    let segments: Vec<&str> = path.split('/').collect();
+    let verb = method_verb(&method);
+
+    let collection = |resource, verb| {
+        Some(RequestTarget {
+            resource,
+            verb,
+            agent: None,
+            project: None,
+        })
+    };
+    let on_agent = |resource, verb, agent: Option<String>| {
+        Some(RequestTarget {
+            resource,
+            verb,
+            project: agent.as_deref().map(|a| agent_project(state, a)),
+            agent,
+        })
+    };
+
+    let target = match segments.as_slice() {
+        ["meta"] | ["problems"] | ["schemas", ..] => None,
+        // Each call on the connection is authorized on its own route.
+        ["rpc"] => None,
+        ["agents"] => collection("agents", verb),
+        ["agents", "bulk"] => collection("agents", "update"),
+        ["agents", name] => on_agent("agents", verb, Some((*name).to_string())),
+        ["agents", name, "examples", "select"] => {
+            on_agent("agents", "read", Some((*name).to_string()))
+        }
+        ["agents", name, "examples", ..] => on_agent("agents", verb, Some((*name).to_string())),
+        // Starting a run opens a session for it, like sending a message
+        ["agents", name, "runs"] => on_agent("runs", "create", Some((*name).to_string())),
+        ["agents", name, _] => on_agent("agents", "update", Some((*name).to_string())),
+        ["trash", "agents", _, "restore"] => collection("agents", "update"),
+        ["trash", ..] => collection("agents", verb),
+        ["projects", name] => Some(RequestTarget {
+            resource: "projects",
+            verb,
+            agent: None,
+            project: Some((*name).to_string()),
+        }),
+        ["projects"] => collection("projects", verb),
+        ["prompts", ..] => collection("prompts", verb),
+        ["models" | "providers", ..] => collection("models", verb),
+        ["runs", ..] => collection("runs", verb),
+        ["sessions"] if method == Method::POST => {
+            let (parts, body) = request.into_parts();
+            let bytes = axum::body::to_bytes(body, MAX_REQUEST_BODY_BYTES)
+                .await
+                .map_err(|_| {
+                    problem_details::bad_request("unreadable request body").into_response()
+                })?;
+            let agent = serde_json::from_slice::<serde_json::Value>(&bytes)
+                .ok()
+                .and_then(|v| v.get("agent")?.as_str().map(str::to_string));
+            let request = Request::from_parts(parts, Body::from(bytes));
+            return Ok((request, on_agent("sessions", "create", agent)));
+        }
+        ["sessions"] => collection("sessions", verb),
+        ["sessions", id, "messages" | "stream"] if method == Method::POST => {
+            on_agent("runs", "create", session_agent(state, id).await)
+        }
+        ["sessions", id, "approve"] => {
+            on_agent("sessions", "update", session_agent(state, id).await)
+        }
+        ["sessions", id, ..] => on_agent("sessions", verb, session_agent(state, id).await),
+        ["usage"] => collection("usage", verb),
+        ["users", ..] => collection("users", verb),
+        _ => collection("admin", verb),
+    };
+    Ok((request, target))
+}
+
+/// Where a denied target applies, for denial messages.
+fn scope_suffix(project: Option<&str>) -> String {
+    match project {
+        Some(project) => format!(" in project '{project}'"),
+        None => " across projects".to_string(),
+    }
+}
+
+fn method_verb(method: &Method) -> &'static str {
+    match *method {
+        Method::GET | Method::HEAD | Method::OPTIONS => "read",
+        Method::POST => "create",
+        Method::PUT | Method::PATCH => "update",
+        Method::DELETE => "delete",
+        _ => "update",
+    }
+}
+
+/// Project an agent belongs to. Unknown agents fall into the default
+/// project; their handlers return 404 regardless.
+fn agent_project(state: &AppState, agent: &str) -> String {
+    state
+        .services
+        .agents
+        .get(agent)
+        .and_then(|spec| spec.metadata.project.clone())
+        .unwrap_or_else(|| DEFAULT_NAMESPACE.to_string())
+}
+
+/// Agent of a live or stored session.
+async fn session_agent(state: &AppState, session_id: &str) -> Option<String> {
+    match state.services.session_registry.get(session_id) {
+        Some(handle) => Some(handle.agent().to_string()),
+        None => state
+            .session_archive
+            .load(session_id)
+            .await
+            .ok()
+            .flatten()
+            .map(|stored| stored.snapshot.agent),
+    }
+}
+
+// ============================================================================
+// Policies
+// ============================================================================
+
+/// Principal of a request authorized with the API token (or from loopback).
+///
+/// With `authorization.user_header` set, a trusted front end can name the
+/// end user; the user's groups come from SCIM provisioning, along with the
+/// grants they carry under `scim.group_roles`. Grants are `None` for callers
+/// that name no user. Unknown or deactivated users have no groups or grants.
+async fn caller(state: &AppState, headers: &HeaderMap) -> (Principal, Option<Vec<Grant>>) {
+    let user_name = state
+        .policies
+        .user_header()
+        .and_then(|header| headers.get(header))
+        .and_then(|v| v.to_str().ok())
+        .filter(|v| !v.is_empty());
+    let Some(user_name) = user_name else {
+        let principal = Principal {
+            kind: if state.api_token.is_some() {
+                "token"
+            } else {
+                "local"
+            },
+            name: principal(&state.api_token, "api"),
+            groups: Vec::new(),
+        };
+        return (principal, None);
+    };
+
+    let user = match state.identity.list_users().await {
+        Ok(users) => users
+            .into_iter()
+            .find(|u| u.active && u.user_name.eq_ignore_ascii_case(user_name)),
+        Err(e) => {
+            warn!(error = %e, "failed to load users for policy check");
+            None
+        }
+    };
+    let (groups, grants) = match user {
+        Some(user) => match state.identity.list_groups().await {
+            Ok(groups) => {
+                let grants = grants_for(&user, &groups, &state.scim.group_roles);
+                let groups = groups
+                    .into_iter()
+                    .filter(|g| g.members.contains(&user.id))
+                    .map(|g| g.display_name)
+                    .collect();
+                (groups, grants)
+            }
+            Err(e) => {
+                warn!(error = %e, "failed to load groups for policy check");
+                (Vec::new(), Vec::new())
+            }
+        },
+        None => (Vec::new(), Vec::new()),
+    };
+    let principal = Principal {
+        kind: "user",
+        name: user_name.to_string(),
+        groups,
+    };
+    (principal, Some(grants))
+}
+
+fn request_context(
+    state: &AppState,
+    request: &Request<Body>,
+    principal: Principal,
+    target: Option<RequestTarget>,
+) -> RequestContext {
+    let (resource, verb, agent) = match target {
+        Some(t) => (Some(t.resource), Some(t.verb), t.agent),
+        None => (None, None, None),
+    };
+    let agent = agent.map(|name| {
+        let spec = state.services.agents.get(&name);
+        AgentTarget {
+            tags: spec
+                .as_ref()
+                .map(|s| s.metadata.tags.clone())
+                .unwrap_or_default(),
+            project: spec.and_then(|s| s.metadata.project.clone()),
+            name,
+        }
+    });
+    RequestContext {
+        principal,
+        method: request.method().to_string(),
+        path: request.uri().path().to_string(),
+        resource,
+        verb,
+        agent,
     }
 }
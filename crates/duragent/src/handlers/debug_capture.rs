@@ -0,0 +1,673 @@
+//! Capture of requests answered with a 4xx status.
+//!
+//! When `server.debug_capture` is enabled, requests answered with a 4xx
+//! status are kept in memory for `retention_seconds`, keyed by the request ID
+//! from the response's `X-Request-Id` header, so an operator can look up
+//! exactly what a client sent.
+//!
+//! Credential headers and sensitive body fields are redacted before anything
+//! is stored, as are share tokens in `/shared/{token}` paths and every query
+//! parameter value. JSON bodies keep their shape; long strings and arrays are
+//! shortened to fit `max_body_bytes`. Bodies that are not valid JSON, often
+//! the reason for the 400, are kept as text with values of sensitive
+//! `"name":` pairs masked. Multipart bodies (file uploads) are never kept;
+//! only their size and content type are.
+
+use std::collections::VecDeque;
+use std::net::SocketAddr;
+use std::sync::{Arc, Mutex};
+
+use axum::Json;
+use axum::body::Body;
+use axum::extract::{ConnectInfo, Path, State};
+use axum::http::{HeaderMap, Request, Uri, header};
+use axum::middleware::Next;
+use axum::response::{IntoResponse, Response};
+use chrono::{DateTime, TimeDelta, Utc};
+use serde_json::Value;
+
+use super::request_id::RequestId;
+use super::{api_auth, problem_details};
+use crate::api::{CapturedRequestResponse, ListCapturedRequestsResponse};
+use crate::config::DebugCaptureConfig;
+use crate::server::{AppState, MAX_REQUEST_BODY_BYTES};
+
+const REDACTED: &str = "[REDACTED]";
+
+/// Field and header names redacted by suffix, after lowercasing and dropping
+/// separators, so `access_token`, `X-Auth-Token`, and `clientSecret` match
+/// while `max_tokens` does not.
+const SENSITIVE_SUFFIXES: &[&str] = &[
+    "apikey",
+    "authorization",
+    "cookie",
+    "credential",
+    "credentials",
+    "passwd",
+    "password",
+    "privatekey",
+    "secret",
+    "signature",
+    "token",
+];
+
+/// Path prefixes whose next segment is a bearer secret.
+const SECRET_PATH_PREFIXES: &[&str] = &["/shared/"];
+
+/// Strings longer than this are cut when a body must be shortened.
+const MAX_STRING_CHARS: usize = 256;
+/// Arrays longer than this are cut when a body must be shortened.
+const MAX_ARRAY_ITEMS: usize = 20;
+
+/// Debug capture settings and buffer, shared by every request; cheap to clone.
+#[derive(Clone, Default)]
+pub struct DebugCapture {
+    /// `None` when capture is disabled.
+    inner: Option<Arc<DebugCaptureInner>>,
+}
+
+struct DebugCaptureInner {
+    max_body_bytes: usize,
+    retention: TimeDelta,
+    max_entries: usize,
+    /// Extra field names to redact, normalized.
+    redact_fields: Vec<String>,
+    /// Oldest first.
+    entries: Mutex<VecDeque<Captured>>,
+}
+
+struct Captured {
+    at: DateTime<Utc>,
+    request: CapturedRequestResponse,
+}
+
+impl DebugCapture {
+    pub fn new(config: &DebugCaptureConfig) -> Self {
+        if !config.enabled {
+            return Self::default();
+        }
+        Self {
+            inner: Some(Arc::new(DebugCaptureInner {
+                max_body_bytes: config.max_body_bytes,
+                retention: TimeDelta::seconds(config.retention_seconds as i64),
+                max_entries: config.max_entries,
+                redact_fields: config.redact_fields.iter().map(|f| normalize(f)).collect(),
+                entries: Mutex::new(VecDeque::new()),
+            })),
+        }
+    }
+
+    pub fn is_enabled(&self) -> bool {
+        self.inner.is_some()
+    }
+
+    /// A captured request that has not expired.
+    pub fn get(&self, request_id: &str) -> Option<CapturedRequestResponse> {
+        let inner = self.inner.as_ref()?;
+        let mut entries = inner.entries.lock().unwrap();
+        inner.prune(&mut entries, Utc::now());
+        entries
+            .iter()
+            .rev()
+            .find(|entry| entry.request.request_id == request_id)
+            .map(|entry| entry.request.clone())
+    }
+
+    /// Captured requests that have not expired, newest first.
+    pub fn recent(&self) -> Vec<CapturedRequestResponse> {
+        let Some(inner) = &self.inner else {
+            return Vec::new();
+        };
+        let mut entries = inner.entries.lock().unwrap();
+        inner.prune(&mut entries, Utc::now());
+        entries
+            .iter()
+            .rev()
+            .map(|entry| entry.request.clone())
+            .collect()
+    }
+}
+
+impl DebugCaptureInner {
+    fn record(&self, request: CapturedRequestResponse) {
+        let now = Utc::now();
+        let mut entries = self.entries.lock().unwrap();
+        entries.push_back(Captured { at: now, request });
+        self.prune(&mut entries, now);
+    }
+
+    fn prune(&self, entries: &mut VecDeque<Captured>, now: DateTime<Utc>) {
+        while entries.len() > self.max_entries
+            || entries
+                .front()
+                .is_some_and(|entry| now - entry.at > self.retention)
+        {
+            entries.pop_front();
+        }
+    }
+
+    fn capture(
+        &self,
+        request_id: String,
+        request: &RequestSummary,
+        body: Option<&[u8]>,
+        status: u16,
+        response: &[u8],
+    ) -> CapturedRequestResponse {
+        let headers = request
+            .headers
+            .iter()
+            .filter_map(|(name, value)| {
+                let value = if is_sensitive(name.as_str(), &self.redact_fields) {
+                    REDACTED.to_string()
+                } else {
+                    value.to_str().ok()?.to_string()
+                };
+                Some((name.to_string(), value))
+            })
+            .collect();
+
+        let (body, body_text, truncated) = match body {
+            // Only the size and content type of uploads are kept.
+            _ if is_multipart(&request.headers) => (None, None, false),
+            // Bodies of unknown or excessive size were not buffered.
+            None => (None, None, request.body_bytes > 0),
+            Some(bytes) => match serde_json::from_slice::<Value>(bytes) {
+                Ok(mut value) => {
+                    redact_json(&mut value, &self.redact_fields);
+                    let (value, truncated) = fit(value, self.max_body_bytes);
+                    (value, None, truncated)
+                }
+                Err(_) => {
+                    let text = redact_text(&String::from_utf8_lossy(bytes), &self.redact_fields);
+                    let (text, truncated) = truncate(text, self.max_body_bytes);
+                    (None, Some(text), truncated)
+                }
+            },
+        };
+
+        CapturedRequestResponse {
+            request_id,
+            captured_at: Utc::now().to_rfc3339(),
+            method: request.method.clone(),
+            path: request.path.clone(),
+            status,
+            headers,
+            body,
+            body_text,
+            body_bytes: request.body_bytes,
+            truncated,
+            response: serde_json::from_slice(response).ok(),
+        }
+    }
+}
+
+/// What is kept of a request until its response status is known.
+struct RequestSummary {
+    method: String,
+    path: String,
+    headers: HeaderMap,
+    body_bytes: usize,
+}
+
+/// Middleware that captures requests answered with a 4xx status. Runs inside
+/// `request_id::assign_request_id`.
+pub async fn capture_requests(
+    State(capture): State<DebugCapture>,
+    request: Request<Body>,
+    next: Next,
+) -> Response {
+    let Some(capture) = capture.inner else {
+        return next.run(request).await;
+    };
+
+    let request_id = request
+        .extensions()
+        .get::<RequestId>()
+        .map_or_else(String::new, |id| id.0.clone());
+    let content_length = request
+        .headers()
+        .get(header::CONTENT_LENGTH)
+        .and_then(|v| v.to_str().ok())
+        .and_then(|v| v.parse::<usize>().ok())
+        .unwrap_or(0);
+    let summary = RequestSummary {
+        method: request.method().to_string(),
+        path: redact_uri(request.uri()),
+        headers: request.headers().clone(),
+        body_bytes: content_length,
+    };
+
+    // Only bodies of known, bounded size are buffered; others stream through.
+    let buffer = content_length > 0
+        && content_length <= MAX_REQUEST_BODY_BYTES
+        && !is_multipart(request.headers());
+    let (request, body) = if buffer {
+        let (parts, body) = request.into_parts();
+        let Ok(bytes) = axum::body::to_bytes(body, content_length).await else {
+            return problem_details::bad_request("unreadable request body").into_response();
+        };
+        (
+            Request::from_parts(parts, Body::from(bytes.clone())),
+            Some(bytes),
+        )
+    } else {
+        (request, None)
+    };
+
+    let response = next.run(request).await;
+    if !response.status().is_client_error() {
+        return response;
+    }
+
+    let status = response.status().as_u16();
+    let (parts, response_body) = response.into_parts();
+    let response_bytes = axum::body::to_bytes(response_body, usize::MAX)
+        .await
+        .unwrap_or_default();
+    capture.record(capture.capture(
+        request_id,
+        &summary,
+        body.as_deref(),
+        status,
+        &response_bytes,
+    ));
+
+    Response::from_parts(parts, Body::from(response_bytes))
+}
+
+/// GET /api/admin/v1/debug/requests
+///
+/// Captured requests that have not expired, newest first.
+pub async fn list_captured_requests(
+    State(state): State<AppState>,
+    ConnectInfo(addr): ConnectInfo<SocketAddr>,
+    headers: HeaderMap,
+) -> Response {
+    if !api_auth::is_authorized(&state.admin_token, &addr, &headers) {
+        return api_auth::admin_denied(&state, &addr);
+    }
+    if !state.debug_capture.is_enabled() {
+        return problem_details::not_found("debug capture is disabled").into_response();
+    }
+
+    Json(ListCapturedRequestsResponse {
+        requests: state.debug_capture.recent(),
+    })
+    .into_response()
+}
+
+/// GET /api/admin/v1/debug/requests/{request_id}
+pub async fn get_captured_request(
+    State(state): State<AppState>,
+    ConnectInfo(addr): ConnectInfo<SocketAddr>,
+    headers: HeaderMap,
+    Path(request_id): Path<String>,
+) -> Response {
+    if !api_auth::is_authorized(&state.admin_token, &addr, &headers) {
+        return api_auth::admin_denied(&state, &addr);
+    }
+
+    match state.debug_capture.get(&request_id) {
+        Some(captured) => Json(captured).into_response(),
+        None => problem_details::not_found(format!(
+            "no captured request '{request_id}' (not a 4xx, expired, or capture is disabled)"
+        ))
+        .into_response(),
+    }
+}
+
+/// Lowercase alphanumerics only, so name variants compare equal.
+fn normalize(name: &str) -> String {
+    name.chars()
+        .filter(char::is_ascii_alphanumeric)
+        .map(|c| c.to_ascii_lowercase())
+        .collect()
+}
+
+fn is_sensitive(name: &str, extra: &[String]) -> bool {
+    let name = normalize(name);
+    SENSITIVE_SUFFIXES
+        .iter()
+        .any(|suffix| name.ends_with(suffix))
+        || extra.contains(&name)
+}
+
+fn is_multipart(headers: &HeaderMap) -> bool {
+    headers
+        .get(header::CONTENT_TYPE)
+        .and_then(|v| v.to_str().ok())
+        .is_some_and(|v| {
+            v.trim_start()
+                .get(..10)
+                .is_some_and(|t| t.eq_ignore_ascii_case("multipart/"))
+        })
+}
+
+/// Path and query of `uri` with share tokens and query values redacted.
+/// Query parameter names are kept; their values may be tokens under any name.
+fn redact_uri(uri: &Uri) -> String {
+    let path = uri.path();
+    let mut out = SECRET_PATH_PREFIXES
+        .iter()
+        .find_map(|prefix| {
+            let rest = path.strip_prefix(prefix)?;
+            let tail = rest.find('/').map_or("", |i| &rest[i..]);
+            Some(format!("{prefix}{REDACTED}{tail}"))
+        })
+        .unwrap_or_else(|| path.to_string());
+
+    if let Some(query) = uri.query() {
+        let params: Vec<String> = query
+            .split('&')
+            .filter(|param| !param.is_empty())
+            .map(|param| match param.split_once('=') {
+                Some((name, _)) => format!("{name}={REDACTED}"),
+                None => param.to_string(),
+            })
+            .collect();
+        out.push('?');
+        out.push_str(&params.join("&"));
+    }
+    out
+}
+
+fn redact_json(value: &mut Value, extra: &[String]) {
+    match value {
+        Value::Object(map) => {
+            for (key, value) in map.iter_mut() {
+                if is_sensitive(key, extra) {
+                    *value = Value::String(REDACTED.to_string());
+                } else {
+                    redact_json(value, extra);
+                }
+            }
+        }
+        Value::Array(items) => items.iter_mut().for_each(|item| redact_json(item, extra)),
+        _ => {}
+    }
+}
+
+/// Redact values of sensitive `"name": value` pairs in text that is not
+/// valid JSON. Quoted values are replaced whole; bare ones up to the next
+/// `,`, `}`, `]`, or newline.
+fn redact_text(text: &str, extra: &[String]) -> String {
+    let bytes = text.as_bytes();
+    let mut out = String::with_capacity(text.len());
+    // The last string literal, while it may still turn out to be a key.
+    let mut key: Option<&str> = None;
+    let mut redact_value = false;
+    let mut i = 0;
+
+    while i < bytes.len() {
+        match bytes[i] {
+            b'"' => {
+                let end = literal_end(bytes, i);
+                if redact_value {
+                    out.push('"');
+                    out.push_str(REDACTED);
+                    out.push('"');
+                    redact_value = false;
+                    key = None;
+                } else {
+                    out.push_str(&text[i..end]);
+                    key = Some(text[i..end].trim_matches('"'));
+                }
+                i = end;
+            }
+            b':' => {
+                out.push(':');
+                i += 1;
+                redact_value = key.take().is_some_and(|k| is_sensitive(k, extra));
+                if redact_value {
+                    while i < bytes.len() && bytes[i].is_ascii_whitespace() {
+                        out.push(bytes[i] as char);
+                        i += 1;
+                    }
+                    if i < bytes.len() && bytes[i] != b'"' {
+                        i = bytes[i..]
+                            .iter()
+                            .position(|b| matches!(b, b',' | b'}' | b']' | b'\n'))
+                            .map_or(bytes.len(), |p| i + p);
+                        out.push_str(REDACTED);
+                        redact_value = false;
+                    }
+                }
+            }
+            b if b.is_ascii_whitespace() => {
+                out.push(b as char);
+                i += 1;
+            }
+            _ => {
+                key = None;
+                let ch = text[i..].chars().next().unwrap_or_default();
+                out.push(ch);
+                i += ch.len_utf8();
+            }
+        }
+    }
+    out
+}
+
+/// Index just past the string literal opening at `start`, or the end of
+/// input when it is unterminated.
+fn literal_end(bytes: &[u8], start: usize) -> usize {
+    let mut j = start + 1;
+    while j < bytes.len() {
+        match bytes[j] {
+            b'\\' => j += 2,
+            b'"' => return j + 1,
+            _ => j += 1,
+        }
+    }
+    bytes.len()
+}
+
+/// Fit a redacted body into `max_bytes`, shortening long strings and arrays
+/// when needed. Returns `None` when even the shortened body is too large.
+fn fit(mut value: Value, max_bytes: usize) -> (Option<Value>, bool) {
+    if serialized_len(&value) <= max_bytes {
+        return (Some(value), false);
+    }
+    shorten(&mut value);
+    if serialized_len(&value) <= max_bytes {
+        (Some(value), true)
+    } else {
+        (None, true)
+    }
+}
+
+fn serialized_len(value: &Value) -> usize {
+    serde_json::to_vec(value).map_or(usize::MAX, |v| v.len())
+}
+
+fn shorten(value: &mut Value) {
+    match value {
+        Value::String(s) if s.chars().count() > MAX_STRING_CHARS => {
+            let mut cut: String = s.chars().take(MAX_STRING_CHARS).collect();
+            cut.push('…');
+            *s = cut;
+        }
+        Value::Array(items) => {
+            items.truncate(MAX_ARRAY_ITEMS);
+            items.iter_mut().for_each(shorten);
+        }
+        Value::Object(map) => map.values_mut().for_each(shorten),
+        _ => {}
+    }
+}
+
+fn truncate(mut text: String, max_bytes: usize) -> (String, bool) {
+    if text.len() <= max_bytes {
+        return (text, false);
+    }
+    let mut end = max_bytes;
+    while !text.is_char_boundary(end) {
+        end -= 1;
+    }
+    text.truncate(end);
+    (text, true)
+}
+
+#[cfg(test)]
+mod tests {
+    use serde_json::json;
+
+    use super::*;
+
+    fn capture(max_entries: usize) -> DebugCapture {
+        DebugCapture::new(&DebugCaptureConfig {
+            enabled: true,
+            max_entries,
+            redact_fields: vec!["ssn".to_string()],
+            ..DebugCaptureConfig::default()
+        })
+    }
+
+    fn summary(headers: HeaderMap) -> RequestSummary {
+        RequestSummary {
+            method: "POST".to_string(),
+            path: "/api/v1/sessions".to_string(),
+            headers,
+            body_bytes: 0,
+        }
+    }
+
+    #[test]
+    fn disabled_captures_nothing() {
+        let capture = DebugCapture::new(&DebugCaptureConfig::default());
+        assert!(!capture.is_enabled());
+        assert!(capture.recent().is_empty());
+    }
+
+    #[test]
+    fn sensitive_names_match_by_suffix() {
+        assert!(is_sensitive("access_token", &[]));
+        assert!(is_sensitive("X-Auth-Token", &[]));
+        assert!(is_sensitive("clientSecret", &[]));
+        assert!(is_sensitive("Authorization", &[]));
+        assert!(!is_sensitive("max_tokens", &[]));
+        assert!(!is_sensitive("content", &[]));
+        assert!(is_sensitive("SSN", &["ssn".to_string()]));
+    }
+
+    #[test]
+    fn json_bodies_keep_their_shape() {
+        let mut body = json!({
+            "agent": "helper",
+            "auth": { "api_key": "sk-123", "user": "ana" },
+            "items": [{ "password": 42 }]
+        });
+        redact_json(&mut body, &[]);
+        assert_eq!(
+            body,
+            json!({
+                "agent": "helper",
+                "auth": { "api_key": REDACTED, "user": "ana" },
+                "items": [{ "password": REDACTED }]
+            })
+        );
+    }
+
+    #[test]
+    fn malformed_bodies_are_redacted_as_text() {
+        let text = r#"{"agent": "helper", "token": "sk-123", "password": hunter2, "note": "a:b",}"#;
+        assert_eq!(
+            redact_text(text, &[]),
+            r#"{"agent": "helper", "token": "[REDACTED]", "password": [REDACTED], "note": "a:b",}"#
+        );
+    }
+
+    #[test]
+    fn oversized_bodies_are_shortened() {
+        let body = json!({ "content": "x".repeat(1000), "items": (0..100).collect::<Vec<_>>() });
+        let (value, truncated) = fit(body, 1024);
+        let value = value.unwrap();
+        assert!(truncated);
+        assert_eq!(value["items"].as_array().unwrap().len(), MAX_ARRAY_ITEMS);
+        assert_eq!(
+            value["content"].as_str().unwrap().chars().count(),
+            MAX_STRING_CHARS + 1
+        );
+    }
+
+    #[test]
+    fn credential_headers_are_redacted() {
+        let capture = capture(10);
+        let inner = capture.inner.as_ref().unwrap();
+        let mut headers = HeaderMap::new();
+        headers.insert(header::AUTHORIZATION, "Bearer secret".parse().unwrap());
+        headers.insert(header::CONTENT_TYPE, "application/json".parse().unwrap());
+
+        let captured = inner.capture(
+            "req_1".to_string(),
+            &summary(headers),
+            Some(&br#"{"ssn": "123"}"#[..]),
+            400,
+            br#"{"title": "Bad Request"}"#,
+        );
+        assert_eq!(captured.headers["authorization"], REDACTED);
+        assert_eq!(captured.headers["content-type"], "application/json");
+        assert_eq!(captured.body, Some(json!({ "ssn": REDACTED })));
+        assert_eq!(captured.response, Some(json!({ "title": "Bad Request" })));
+    }
+
+    #[test]
+    fn paths_hide_share_tokens_and_query_values() {
+        let uri: Uri = "/shared/abc123?token=sk-1&verbose".parse().unwrap();
+        assert_eq!(
+            redact_uri(&uri),
+            "/shared/[REDACTED]?token=[REDACTED]&verbose"
+        );
+        let uri: Uri = "/api/v1/sessions/s1?limit=5".parse().unwrap();
+        assert_eq!(redact_uri(&uri), "/api/v1/sessions/s1?limit=[REDACTED]");
+        let uri: Uri = "/api/v1/agents".parse().unwrap();
+        assert_eq!(redact_uri(&uri), "/api/v1/agents");
+    }
+
+    #[test]
+    fn multipart_bodies_keep_only_size_and_type() {
+        let capture = capture(10);
+        let inner = capture.inner.as_ref().unwrap();
+        let mut headers = HeaderMap::new();
+        headers.insert(
+            header::CONTENT_TYPE,
+            "multipart/form-data; boundary=x".parse().unwrap(),
+        );
+        let request = RequestSummary {
+            body_bytes: 42,
+            ..summary(headers)
+        };
+
+        let captured = inner.capture(
+            "req_1".to_string(),
+            &request,
+            Some(&b"--x\r\ncontent of a private file\r\n--x--"[..]),
+            413,
+            b"",
+        );
+        assert_eq!(captured.body, None);
+        assert_eq!(captured.body_text, None);
+        assert_eq!(captured.body_bytes, 42);
+        assert_eq!(
+            captured.headers["content-type"],
+            "multipart/form-data; boundary=x"
+        );
+    }
+
+    #[test]
+    fn buffer_keeps_the_newest_entries() {
+        let capture = capture(2);
+        let inner = capture.inner.as_ref().unwrap();
+        for id in ["req_1", "req_2", "req_3"] {
+            let captured =
+                inner.capture(id.to_string(), &summary(HeaderMap::new()), None, 400, b"");
+            inner.record(captured);
+        }
+
+        assert!(capture.get("req_1").is_none());
+        assert!(capture.get("req_3").is_some());
+        let ids: Vec<_> = capture.recent().into_iter().map(|r| r.request_id).collect();
+        assert_eq!(ids, ["req_3", "req_2"]);
+    }
+}
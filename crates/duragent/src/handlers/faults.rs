@@ -0,0 +1,75 @@
+//! Fault injection middleware.
+
+use axum::BoxError;
+use axum::body::Body;
+use axum::extract::State;
+use axum::http::{HeaderValue, Request, StatusCode, header};
+use axum::middleware::Next;
+use axum::response::{IntoResponse, Response};
+use futures::StreamExt;
+
+use super::format::APPLICATION_NDJSON;
+use super::problem_details::ProblemDetails;
+use crate::faults::{FAULT_HEADER, FaultInjector};
+
+/// Middleware that delays, fails, or cuts off requests matching a
+/// `faults.routes` rule. Installed only when fault injection is enabled.
+pub async fn inject_faults(
+    State(faults): State<FaultInjector>,
+    request: Request<Body>,
+    next: Next,
+) -> Response {
+    let Some(plan) = faults.route_faults(request.method().as_str(), request.uri().path()) else {
+        return next.run(request).await;
+    };
+
+    if !plan.delay.is_zero() {
+        tokio::time::sleep(plan.delay).await;
+    }
+
+    if let Some(status) = plan.error_status {
+        let status = StatusCode::from_u16(status).unwrap_or(StatusCode::SERVICE_UNAVAILABLE);
+        let mut response = ProblemDetails::new(
+            status,
+            status.canonical_reason().unwrap_or("Injected Fault"),
+        )
+        .with_detail("injected fault")
+        .into_response();
+        response
+            .headers_mut()
+            .insert(FAULT_HEADER, HeaderValue::from_static("error"));
+        return response;
+    }
+
+    let response = next.run(request).await;
+    match plan.drop_after {
+        Some(after) if is_stream(&response) => drop_stream(response, after),
+        _ => response,
+    }
+}
+
+fn is_stream(response: &Response) -> bool {
+    response
+        .headers()
+        .get(header::CONTENT_TYPE)
+        .and_then(|v| v.to_str().ok())
+        .is_some_and(|ct| ct.starts_with("text/event-stream") || ct.starts_with(APPLICATION_NDJSON))
+}
+
+/// Pass `after` body chunks through, then fail the body so the connection
+/// is cut without a clean end of stream.
+fn drop_stream(response: Response, after: usize) -> Response {
+    let (mut parts, body) = response.into_parts();
+    parts
+        .headers
+        .insert(FAULT_HEADER, HeaderValue::from_static("dropped-stream"));
+    let dropped = futures::stream::once(async {
+        Err::<bytes::Bytes, BoxError>("injected fault: stream dropped".into())
+    });
+    let stream = body
+        .into_data_stream()
+        .map(|chunk| chunk.map_err(BoxError::from))
+        .take(after)
+        .chain(dropped);
+    Response::from_parts(parts, Body::from_stream(stream))
+}
@@ -0,0 +1,319 @@
+//! Response format negotiation.
+//!
+//! List and run endpoints honor the `Accept` header:
+//!
+//! - `application/json` (default) — a single JSON document
+//! - `application/x-ndjson` — newline-delimited JSON; list endpoints stream
+//!   one item per line instead of the wrapping envelope
+//! - `application/msgpack` — the JSON document encoded as MessagePack
+//!
+//! Unsupported or missing `Accept` values fall back to JSON. Error responses
+//! are always `application/problem+json`.
+//!
+//! Bodies are encoded into a per-thread buffer that is reused across
+//! requests, and NDJSON lists are written in chunks rather than one
+//! allocation per item, to keep allocator traffic flat on hot list paths.
+
+use std::cell::RefCell;
+use std::convert::Infallible;
+
+use axum::body::Body;
+use axum::extract::FromRequestParts;
+use axum::http::request::Parts;
+use axum::http::{HeaderMap, HeaderValue, StatusCode, header};
+use axum::response::{IntoResponse, Response};
+use bytes::{BufMut, Bytes, BytesMut};
+use serde::Serialize;
+use tracing::error;
+
+use super::problem_details;
+
+pub const APPLICATION_JSON: &str = "application/json";
+pub const APPLICATION_NDJSON: &str = "application/x-ndjson";
+pub const APPLICATION_MSGPACK: &str = "application/msgpack";
+
+/// Target size of each NDJSON body chunk.
+const NDJSON_CHUNK_BYTES: usize = 16 * 1024;
+
+/// Bodies larger than this are not kept in the per-thread buffer, so one big
+/// response doesn't pin its allocation for the life of the worker.
+const MAX_POOLED_BYTES: usize = 1024 * 1024;
+
+thread_local! {
+    /// Encode buffer reused by every response built on this thread.
+    ///
+    /// Each body is split off as `Bytes`; once the response is sent and the
+    /// `Bytes` dropped, the next `reserve` reclaims the same allocation.
+    static ENCODE_BUF: RefCell<BytesMut> = RefCell::new(BytesMut::new());
+}
+
+/// Representation selected from the request's `Accept` header.
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq)]
+pub enum ResponseFormat {
+    #[default]
+    Json,
+    NdJson,
+    MsgPack,
+}
+
+impl ResponseFormat {
+    /// Pick the most preferred supported media type from `Accept`.
+    pub fn from_headers(headers: &HeaderMap) -> Self {
+        headers
+            .get(header::ACCEPT)
+            .and_then(|v| v.to_str().ok())
+            .map(Self::from_accept)
+            .unwrap_or_default()
+    }
+
+    fn from_accept(accept: &str) -> Self {
+        let mut best: Option<(Self, f32)> = None;
+        for range in accept.split(',') {
+            let mut parts = range.split(';');
+            let media_type = parts.next().unwrap_or("").trim().to_ascii_lowercase();
+            let quality = parts
+                .filter_map(|p| p.trim().strip_prefix("q="))
+                .find_map(|q| q.parse::<f32>().ok())
+                .unwrap_or(1.0);
+            let Some(format) = Self::from_media_type(&media_type) else {
+                continue;
+            };
+            if quality > 0.0 && best.is_none_or(|(_, q)| quality > q) {
+                best = Some((format, quality));
+            }
+        }
+        best.map(|(format, _)| format).unwrap_or_default()
+    }
+
+    fn from_media_type(media_type: &str) -> Option<Self> {
+        match media_type {
+            "application/json" | "application/*" | "*/*" => Some(Self::Json),
+            "application/x-ndjson" | "application/ndjson" | "application/jsonl" => {
+                Some(Self::NdJson)
+            }
+            "application/msgpack" | "application/x-msgpack" | "application/vnd.msgpack" => {
+                Some(Self::MsgPack)
+            }
+            _ => None,
+        }
+    }
+
+    pub fn content_type(self) -> &'static str {
+        match self {
+            Self::Json => APPLICATION_JSON,
+            Self::NdJson => APPLICATION_NDJSON,
+            Self::MsgPack => APPLICATION_MSGPACK,
+        }
+    }
+
+    /// Encode a single document. NDJSON emits it as one line.
+    pub fn respond<T: Serialize>(self, status: StatusCode, value: &T) -> Response {
+        let body = with_encode_buf(|buf| match self {
+            Self::Json => serde_json::to_writer(buf.writer(), value).map_err(|e| e.to_string()),
+            Self::NdJson => serde_json::to_writer((&mut *buf).writer(), value)
+                .map(|()| buf.put_u8(b'\n'))
+                .map_err(|e| e.to_string()),
+            Self::MsgPack => {
+                rmp_serde::encode::write_named(&mut buf.writer(), value).map_err(|e| e.to_string())
+            }
+        });
+
+        match body {
+            Ok(body) => self.with_headers(status, Body::from(body)),
+            Err(e) => {
+                error!(error = %e, format = self.content_type(), "failed to encode response");
+                problem_details::internal_error("failed to encode response").into_response()
+            }
+        }
+    }
+
+    /// Encode a list endpoint's response.
+    ///
+    /// JSON and MessagePack encode the full `envelope`; NDJSON streams
+    /// `items` one per line so clients can process large results
+    /// incrementally.
+    pub fn respond_list<I, T>(self, items: Vec<I>, envelope: impl FnOnce(Vec<I>) -> T) -> Response
+    where
+        I: Serialize + Send + 'static,
+        T: Serialize,
+    {
+        if self != Self::NdJson {
+            return self.respond(StatusCode::OK, &envelope(items));
+        }
+
+        let chunks = futures::stream::iter(NdJsonChunks {
+            items: items.into_iter(),
+        });
+        self.with_headers(StatusCode::OK, Body::from_stream(chunks))
+    }
+
+    fn with_headers(self, status: StatusCode, body: Body) -> Response {
+        let mut response = (status, body).into_response();
+        let headers = response.headers_mut();
+        headers.insert(
+            header::CONTENT_TYPE,
+            HeaderValue::from_static(self.content_type()),
+        );
+        headers.insert(header::VARY, HeaderValue::from_static("accept"));
+        response
+    }
+}
+
+/// Iterator of NDJSON body chunks, each holding as many lines as fit in
+/// `NDJSON_CHUNK_BYTES`. Items are encoded lazily as the body is polled.
+struct NdJsonChunks<I> {
+    items: std::vec::IntoIter<I>,
+}
+
+impl<I: Serialize> Iterator for NdJsonChunks<I> {
+    type Item = Result<Bytes, Infallible>;
+
+    fn next(&mut self) -> Option<Self::Item> {
+        if self.items.as_slice().is_empty() {
+            return None;
+        }
+        let chunk = with_encode_buf(|buf| {
+            while buf.len() < NDJSON_CHUNK_BYTES
+                && let Some(item) = self.items.next()
+            {
+                let start = buf.len();
+                match serde_json::to_writer((&mut *buf).writer(), &item) {
+                    Ok(()) => buf.put_u8(b'\n'),
+                    Err(e) => {
+                        error!(error = %e, "failed to encode NDJSON item");
+                        buf.truncate(start);
+                    }
+                }
+            }
+            Ok::<_, Infallible>(())
+        });
+        Some(chunk)
+    }
+}
+
+/// Run `encode` against this thread's pooled buffer and split off the result.
+///
+/// On error the partial output is discarded.
+fn with_encode_buf<E>(encode: impl FnOnce(&mut BytesMut) -> Result<(), E>) -> Result<Bytes, E> {
+    ENCODE_BUF.with_borrow_mut(|buf| {
+        buf.clear();
+        buf.reserve(1024);
+        let result = encode(&mut *buf);
+        let body = buf.split().freeze();
+        if body.len() > MAX_POOLED_BYTES {
+            *buf = BytesMut::new();
+        }
+        result.map(|()| body)
+    })
+}
+
+impl<S: Send + Sync> FromRequestParts<S> for ResponseFormat {
+    type Rejection = Infallible;
+
+    async fn from_request_parts(parts: &mut Parts, _state: &S) -> Result<Self, Self::Rejection> {
+        Ok(Self::from_headers(&parts.headers))
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use http_body_util::BodyExt;
+
+    #[test]
+    fn accept_defaults_to_json() {
+        assert_eq!(ResponseFormat::from_accept(""), ResponseFormat::Json);
+        assert_eq!(ResponseFormat::from_accept("*/*"), ResponseFormat::Json);
+        assert_eq!(
+            ResponseFormat::from_accept("text/html"),
+            ResponseFormat::Json
+        );
+    }
+
+    #[test]
+    fn accept_selects_supported_types() {
+        assert_eq!(
+            ResponseFormat::from_accept("application/x-ndjson"),
+            ResponseFormat::NdJson
+        );
+        assert_eq!(
+            ResponseFormat::from_accept("application/msgpack"),
+            ResponseFormat::MsgPack
+        );
+        assert_eq!(
+            ResponseFormat::from_accept("text/html, application/vnd.msgpack"),
+            ResponseFormat::MsgPack
+        );
+    }
+
+    #[test]
+    fn accept_honors_quality_values() {
+        assert_eq!(
+            ResponseFormat::from_accept("application/json;q=0.5, application/msgpack"),
+            ResponseFormat::MsgPack
+        );
+        assert_eq!(
+            ResponseFormat::from_accept("application/msgpack;q=0, application/json"),
+            ResponseFormat::Json
+        );
+        assert_eq!(
+            ResponseFormat::from_accept("application/x-ndjson, */*;q=0.1"),
+            ResponseFormat::NdJson
+        );
+    }
+
+    #[derive(Serialize)]
+    struct Envelope {
+        items: Vec<u32>,
+    }
+
+    #[tokio::test]
+    async fn ndjson_list_streams_one_item_per_line() {
+        let response =
+            ResponseFormat::NdJson.respond_list(vec![1, 2, 3], |items| Envelope { items });
+
+        assert_eq!(response.headers()[header::CONTENT_TYPE], APPLICATION_NDJSON);
+        let body = response.into_body().collect().await.unwrap().to_bytes();
+        assert_eq!(&body[..], b"1\n2\n3\n");
+    }
+
+    #[tokio::test]
+    async fn msgpack_list_encodes_envelope() {
+        let response = ResponseFormat::MsgPack.respond_list(vec![1, 2], |items| Envelope { items });
+
+        assert_eq!(
+            response.headers()[header::CONTENT_TYPE],
+            APPLICATION_MSGPACK
+        );
+        assert_eq!(response.headers()[header::VARY], "accept");
+        let body = response.into_body().collect().await.unwrap().to_bytes();
+        let decoded: serde_json::Value = rmp_serde::from_slice(&body).unwrap();
+        assert_eq!(decoded, serde_json::json!({"items": [1, 2]}));
+    }
+
+    #[test]
+    fn pooled_buffer_does_not_clobber_live_bodies() {
+        let first = with_encode_buf(|buf| serde_json::to_writer(buf.writer(), &"first")).unwrap();
+        let second = with_encode_buf(|buf| serde_json::to_writer(buf.writer(), &"second")).unwrap();
+        assert_eq!(&first[..], br#""first""#);
+        assert_eq!(&second[..], br#""second""#);
+    }
+
+    #[tokio::test]
+    async fn ndjson_large_list_is_chunked() {
+        let items: Vec<u32> = (0..20_000).collect();
+        let expected: String = items.iter().map(|i| format!("{i}\n")).collect();
+
+        let chunks: Vec<_> = NdJsonChunks {
+            items: items.clone().into_iter(),
+        }
+        .map(Result::unwrap)
+        .collect();
+        assert!(chunks.len() > 1);
+        assert!(chunks.len() < items.len() / 100);
+
+        let response = ResponseFormat::NdJson.respond_list(items, |items| Envelope { items });
+        let body = response.into_body().collect().await.unwrap().to_bytes();
+        assert_eq!(&body[..], expected.as_bytes());
+    }
+}
@@ -0,0 +1,153 @@
+//! Message bodies for the invoke endpoints.
+//!
+//! `POST /sessions/{session_id}/messages` and `/stream` take either a JSON
+//! `SendMessageRequest` or `multipart/form-data`, for HTML forms and mobile
+//! clients. Form text fields carry the same members as the JSON body
+//! (`content`, `input` and `labels` as JSON text, `priority`,
+//! `background`, `callback_url`); every part with a file name is an attachment.
+
+use std::collections::HashMap;
+
+use axum::extract::multipart::MultipartError;
+use axum::extract::{FromRequest, Multipart, Request};
+use axum::http::header;
+
+use super::problem_details::{self, FieldError, ProblemDetails};
+use super::validation::{ValidJson, Validate};
+use crate::api::{RunPriority, SendMessageRequest};
+use crate::artifacts::Attachment;
+use crate::server::AppState;
+
+/// Content type of attachments sent without one.
+const DEFAULT_ATTACHMENT_TYPE: &str = "application/octet-stream";
+
+/// A message to send, with any files attached to it.
+#[derive(Debug)]
+pub struct MessageBody {
+    pub request: SendMessageRequest,
+    pub attachments: Vec<Attachment>,
+}
+
+impl FromRequest<AppState> for MessageBody {
+    type Rejection = ProblemDetails;
+
+    async fn from_request(req: Request, state: &AppState) -> Result<Self, Self::Rejection> {
+        if !is_multipart(&req) {
+            let ValidJson(request) =
+                ValidJson::<SendMessageRequest>::from_request(req, state).await?;
+            return Ok(Self {
+                request,
+                attachments: Vec::new(),
+            });
+        }
+
+        let multipart = Multipart::from_request(req, state)
+            .await
+            .map_err(|rejection| {
+                problem_details::validation_failed(vec![FieldError::new("", rejection.body_text())])
+            })?;
+        let body = read_form(multipart).await?;
+
+        let mut errors = body.request.validate();
+        if !body.attachments.is_empty() {
+            // A message may be just its attachments
+            errors.retain(|e| e.pointer != "/content" || !body.request.content.trim().is_empty());
+        }
+        if !errors.is_empty() {
+            return Err(problem_details::validation_failed(errors));
+        }
+        Ok(body)
+    }
+}
+
+fn is_multipart(req: &Request) -> bool {
+    req.headers()
+        .get(header::CONTENT_TYPE)
+        .and_then(|value| value.to_str().ok())
+        .is_some_and(|value| {
+            value
+                .trim_start()
+                .to_ascii_lowercase()
+                .starts_with("multipart/form-data")
+        })
+}
+
+async fn read_form(mut multipart: Multipart) -> Result<MessageBody, ProblemDetails> {
+    let mut request = SendMessageRequest {
+        content: String::new(),
+        input: None,
+        priority: RunPriority::default(),
+        labels: HashMap::new(),
+        background: false,
+        callback_url: None,
+        tools: None,
+    };
+    let mut attachments = Vec::new();
+
+    while let Some(field) = multipart.next_field().await.map_err(form_error)? {
+        if let Some(name) = field.file_name().map(str::to_string) {
+            // Browsers send an empty file name for file inputs left blank
+            if name.is_empty() {
+                continue;
+            }
+            let content_type = field
+                .content_type()
+                .unwrap_or(DEFAULT_ATTACHMENT_TYPE)
+                .to_string();
+            let content = field.bytes().await.map_err(form_error)?;
+            attachments.push(Attachment {
+                name,
+                content_type,
+                content: content.to_vec(),
+            });
+            continue;
+        }
+
+        let name = field.name().unwrap_or_default().to_string();
+        let text = field.text().await.map_err(form_error)?;
+        match name.as_str() {
+            "content" => request.content = text,
+            "input" => {
+                let input = serde_json::from_str(&text)
+                    .map_err(|_| invalid_field("/input", "must be JSON"))?;
+                request.input = Some(input);
+            }
+            "priority" => {
+                request.priority = serde_json::from_value(serde_json::Value::String(text))
+                    .map_err(|_| invalid_field("/priority", "must be low, normal, or high"))?;
+            }
+            "labels" => {
+                request.labels = serde_json::from_str(&text).map_err(|_| {
+                    invalid_field("/labels", "must be a JSON object of string values")
+                })?;
+            }
+            "background" => {
+                request.background = text
+                    .parse()
+                    .map_err(|_| invalid_field("/background", "must be true or false"))?;
+            }
+            "callback_url" => request.callback_url = Some(text),
+            "tools" => {
+                let tools = serde_json::from_str(&text).map_err(|_| {
+                    invalid_field("/tools", "must be a JSON object with allow and deny lists")
+                })?;
+                request.tools = Some(tools);
+            }
+            // Unknown fields are ignored, as in JSON bodies
+            _ => {}
+        }
+    }
+
+    Ok(MessageBody {
+        request,
+        attachments,
+    })
+}
+
+fn form_error(e: MultipartError) -> ProblemDetails {
+    problem_details::validation_failed(vec![FieldError::new("", e.body_text())])
+}
+
+fn invalid_field(pointer: &str, message: &str) -> ProblemDetails {
+    problem_details::validation_failed(vec![FieldError::new(pointer, message)])
+}
@@ -0,0 +1,191 @@
+//! Prometheus metrics endpoint and request counting middleware.
+
+use std::net::SocketAddr;
+use std::time::Instant;
+
+use axum::body::Body;
+use axum::extract::{ConnectInfo, MatchedPath, State};
+use axum::http::{HeaderMap, Request, StatusCode, header};
+use axum::middleware::Next;
+use axum::response::{IntoResponse, Response};
+
+use super::api_auth;
+use crate::build_info;
+use crate::health::DependencyStatus;
+use crate::metrics::{self, Exposition, HttpMetrics};
+use crate::server::AppState;
+use crate::slo::ObjectiveKind;
+
+/// Route label for requests that matched no route.
+const UNMATCHED_ROUTE: &str = "unmatched";
+
+/// Middleware that counts every request by route template and status, and
+/// the requests in flight.
+pub async fn record_requests(
+    State(metrics): State<HttpMetrics>,
+    request: Request<Body>,
+    next: Next,
+) -> Response {
+    let _in_flight = metrics.start();
+    let started = Instant::now();
+    let method = request.method().clone();
+    let route = request
+        .extensions()
+        .get::<MatchedPath>()
+        .map_or(UNMATCHED_ROUTE.to_string(), |m| m.as_str().to_string());
+
+    let response = next.run(request).await;
+
+    metrics.record(
+        method.as_str(),
+        &route,
+        response.status().as_u16(),
+        started.elapsed(),
+    );
+    response
+}
+
+/// GET /metrics
+///
+/// Prometheus text exposition of request, run, dependency, and SLO metrics.
+///
+/// Authorization: same as the admin endpoints.
+pub async fn metrics(
+    State(state): State<AppState>,
+    ConnectInfo(addr): ConnectInfo<SocketAddr>,
+    headers: HeaderMap,
+) -> Response {
+    if !api_auth::is_authorized(&state.admin_token, &addr, &headers) {
+        return api_auth::admin_denied(&state, &addr);
+    }
+
+    let mut out = Exposition::new();
+    out.header(metrics::BUILD_INFO, "gauge", "Build information.");
+    out.sample(
+        metrics::BUILD_INFO,
+        &[
+            ("version", build_info::VERSION),
+            ("commit", build_info::COMMIT),
+        ],
+        1.0,
+    );
+
+    state.http_metrics.render(&mut out);
+
+    out.gauge(
+        metrics::AGENTS_LOADED,
+        "Agents currently loaded.",
+        state.services.agents.len() as f64,
+    );
+    out.gauge(
+        metrics::SESSIONS_ACTIVE,
+        "Sessions with a live actor.",
+        state.services.session_registry.len() as f64,
+    );
+    out.gauge(
+        metrics::RUNS_RUNNING,
+        "LLM runs holding a run pool permit.",
+        state.services.run_pool.running() as f64,
+    );
+    out.gauge(
+        metrics::RUNS_QUEUED,
+        "LLM runs waiting for a run pool permit.",
+        state.services.run_pool.queued() as f64,
+    );
+    out.header(
+        metrics::RUN_ERRORS_TOTAL,
+        "counter",
+        "Message runs that failed on a provider request, by error class.",
+    );
+    for (class, count) in state.runs.error_counts() {
+        out.sample(
+            metrics::RUN_ERRORS_TOTAL,
+            &[("class", class.as_str())],
+            count as f64,
+        );
+    }
+
+    render_dependencies(&state, &mut out);
+    render_slos(&state, &mut out);
+
+    (
+        StatusCode::OK,
+        [(header::CONTENT_TYPE, "text/plain; version=0.0.4")],
+        out.finish(),
+    )
+        .into_response()
+}
+
+fn render_dependencies(state: &AppState, out: &mut Exposition) {
+    let dependencies = state.health.summaries();
+    let series = [
+        (
+            metrics::DEPENDENCY_UP,
+            "Whether the latest dependency check passed.",
+        ),
+        (
+            metrics::DEPENDENCY_CHECK_DURATION_SECONDS,
+            "Duration of the latest dependency check.",
+        ),
+        (
+            metrics::DEPENDENCY_AVAILABILITY,
+            "Fraction of retained dependency checks that passed.",
+        ),
+        (
+            metrics::DEPENDENCY_ERROR_BUDGET_REMAINING,
+            "Fraction of the dependency error budget left; negative once overspent.",
+        ),
+    ];
+    for (name, help) in series {
+        out.header(name, "gauge", help);
+        for d in &dependencies {
+            let value = match name {
+                metrics::DEPENDENCY_UP => f64::from(u8::from(d.status == DependencyStatus::Up)),
+                metrics::DEPENDENCY_CHECK_DURATION_SECONDS => d
+                    .history
+                    .last()
+                    .map_or(0.0, |r| r.latency_ms as f64 / 1000.0),
+                metrics::DEPENDENCY_AVAILABILITY => d.availability,
+                _ => d.error_budget_remaining,
+            };
+            out.sample(name, &[("dependency", d.name.as_str())], value);
+        }
+    }
+}
+
+fn render_slos(state: &AppState, out: &mut Exposition) {
+    let statuses = state.slos.statuses();
+    let series = [
+        (
+            metrics::SLO_BURN_RATE,
+            "Error budget burn rate of a provider objective over its window.",
+        ),
+        (
+            metrics::SLO_BURN_RATE_THRESHOLD,
+            "Burn rate at which a provider objective alerts.",
+        ),
+        (
+            metrics::SLO_ALERTING,
+            "Whether a provider objective is alerting.",
+        ),
+    ];
+    for (name, help) in series {
+        out.header(name, "gauge", help);
+        for s in &statuses {
+            let objective = match s.objective {
+                ObjectiveKind::Latency => "latency",
+                ObjectiveKind::Errors => "errors",
+            };
+            let value = match name {
+                metrics::SLO_BURN_RATE => s.burn_rate,
+                metrics::SLO_BURN_RATE_THRESHOLD => s.burn_rate_threshold,
+                _ => f64::from(u8::from(s.alerting)),
+            };
+            out.sample(
+                name,
+                &[("provider", s.provider.as_str()), ("objective", objective)],
+                value,
+            );
+        }
+    }
+}
@@ -1,12 +1,33 @@
 //! HTTP request handlers.
 
+pub(crate) mod access_log;
 mod admin;
 pub(crate) mod api_auth;
+pub(crate) mod debug_capture;
+pub(crate) mod faults;
+pub mod format;
 mod health;
+pub(crate) mod message_body;
+pub(crate) mod metrics;
 pub(crate) mod problem_details;
+pub(crate) mod read_only;
+pub(crate) mod request_id;
+pub(crate) mod request_schemas;
+pub mod scim;
+mod service_accounts;
+mod status;
+pub(crate) mod timeouts;
 pub mod v1;
+pub(crate) mod validation;
 mod version;
 
-pub use admin::{reload_agents, shutdown};
+pub use admin::{list_features, reload_agents, set_feature, shutdown, upgrade};
+pub use debug_capture::{get_captured_request, list_captured_requests};
 pub use health::{livez, readyz};
+pub use metrics::metrics;
+pub use service_accounts::{
+    create_service_account, delete_service_account, get_service_account, list_service_accounts,
+    rotate_service_account, update_service_account,
+};
+pub use status::status_page;
 pub use version::version;
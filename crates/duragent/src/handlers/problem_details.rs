@@ -1,5 +1,7 @@
 //! RFC 7807 Problem Details for HTTP error responses.
 
+use std::time::Duration;
+
 use axum::Json;
 use axum::http::HeaderValue;
 use axum::http::StatusCode;
@@ -7,11 +9,166 @@ use axum::http::header;
 use axum::response::{IntoResponse, Response};
 use serde::Serialize;
 
+use super::request_id;
+use crate::api::ProviderErrorClass;
+
 /// URN-style identifiers for RFC 7807 `type`.
 pub const TYPE_BAD_REQUEST: &str = "urn:duragent:problem:bad-request";
 pub const TYPE_INTERNAL_ERROR: &str = "urn:duragent:problem:internal-error";
 pub const TYPE_NOT_FOUND: &str = "urn:duragent:problem:not-found";
 
+/// Prefix shared by all catalog problem type URNs.
+const TYPE_PREFIX: &str = "urn:duragent:problem:";
+
+/// Documentation page that describes every problem type (one anchor per code).
+pub const DOCS_BASE_URL: &str = "https://giosakti.github.io/duragent/reference/api.html";
+
+// ============================================================================
+// Problem Catalog
+// ============================================================================
+
+/// Catalog of problem types emitted by the API.
+///
+/// Every error response produced by a handler should come from this catalog
+/// so clients can branch on a stable `code` instead of parsing `detail`.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum ProblemType {
+    BadRequest,
+    Unauthorized,
+    Forbidden,
+    NotFound,
+    AgentNotFound,
+    AgentDisabled,
+    SessionNotFound,
+    Conflict,
+    ValidationFailed,
+    InternalError,
+    ProviderError,
+    ProviderNotConfigured,
+    RunBudgetExceeded,
+    RunInterrupted,
+    Overloaded,
+    ReadOnly,
+    RateLimited,
+    CaptchaRequired,
+}
+
+impl ProblemType {
+    /// All catalog entries, in documentation order.
+    pub const ALL: &'static [ProblemType] = &[
+        Self::BadRequest,
+        Self::Unauthorized,
+        Self::Forbidden,
+        Self::NotFound,
+        Self::AgentNotFound,
+        Self::AgentDisabled,
+        Self::SessionNotFound,
+        Self::Conflict,
+        Self::ValidationFailed,
+        Self::InternalError,
+        Self::ProviderError,
+        Self::ProviderNotConfigured,
+        Self::RunBudgetExceeded,
+        Self::RunInterrupted,
+        Self::Overloaded,
+        Self::ReadOnly,
+        Self::RateLimited,
+        Self::CaptchaRequired,
+    ];
+
+    /// Stable machine-readable code.
+    pub fn code(self) -> &'static str {
+        match self {
+            Self::BadRequest => "bad-request",
+            Self::Unauthorized => "unauthorized",
+            Self::Forbidden => "forbidden",
+            Self::NotFound => "not-found",
+            Self::AgentNotFound => "agent-not-found",
+            Self::AgentDisabled => "agent-disabled",
+            Self::SessionNotFound => "session-not-found",
+            Self::Conflict => "conflict",
+            Self::ValidationFailed => "validation-failed",
+            Self::InternalError => "internal-error",
+            Self::ProviderError => "provider-error",
+            Self::ProviderNotConfigured => "provider-not-configured",
+            Self::RunBudgetExceeded => "run-budget-exceeded",
+            Self::RunInterrupted => "run-interrupted",
+            Self::Overloaded => "overloaded",
+            Self::ReadOnly => "read-only",
+            Self::RateLimited => "rate-limited",
+            Self::CaptchaRequired => "captcha-required",
+        }
+    }
+
+    pub fn title(self) -> &'static str {
+        match self {
+            Self::BadRequest => "Bad Request",
+            Self::Unauthorized => "Unauthorized",
+            Self::Forbidden => "Forbidden",
+            Self::NotFound => "Not Found",
+            Self::AgentNotFound => "Agent Not Found",
+            Self::AgentDisabled => "Agent Disabled",
+            Self::SessionNotFound => "Session Not Found",
+            Self::Conflict => "Conflict",
+            Self::ValidationFailed => "Validation Failed",
+            Self::InternalError => "Internal Server Error",
+            Self::ProviderError => "LLM Provider Error",
+            Self::ProviderNotConfigured => "LLM Provider Not Configured",
+            Self::RunBudgetExceeded => "Run Budget Exceeded",
+            Self::RunInterrupted => "Run Interrupted",
+            Self::Overloaded => "Server Overloaded",
+            Self::ReadOnly => "Read-Only Server",
+            Self::RateLimited => "Too Many Requests",
+            Self::CaptchaRequired => "CAPTCHA Required",
+        }
+    }
+
+    pub fn status(self) -> StatusCode {
+        match self {
+            Self::BadRequest | Self::ValidationFailed => StatusCode::BAD_REQUEST,
+            Self::Unauthorized => StatusCode::UNAUTHORIZED,
+            Self::Forbidden | Self::ReadOnly | Self::CaptchaRequired => StatusCode::FORBIDDEN,
+            Self::NotFound | Self::AgentNotFound | Self::SessionNotFound => StatusCode::NOT_FOUND,
+            Self::Conflict | Self::AgentDisabled => StatusCode::CONFLICT,
+            Self::InternalError | Self::ProviderNotConfigured => StatusCode::INTERNAL_SERVER_ERROR,
+            Self::ProviderError => StatusCode::BAD_GATEWAY,
+            Self::RunBudgetExceeded => StatusCode::UNPROCESSABLE_ENTITY,
+            Self::RunInterrupted | Self::Overloaded => StatusCode::SERVICE_UNAVAILABLE,
+            Self::RateLimited => StatusCode::TOO_MANY_REQUESTS,
+        }
+    }
+
+    /// Whether retrying the same request later may succeed.
+    pub fn retryable(self) -> bool {
+        matches!(
+            self,
+            Self::Conflict
+                | Self::ProviderError
+                | Self::RunInterrupted
+                | Self::Overloaded
+                | Self::RateLimited
+        )
+    }
+
+    /// URN used as the RFC 7807 `type`.
+    pub fn type_uri(self) -> String {
+        format!("{TYPE_PREFIX}{}", self.code())
+    }
+
+    pub fn docs_url(self) -> String {
+        format!("{DOCS_BASE_URL}#{}", self.code())
+    }
+
+    /// Build a problem response of this type.
+    #[must_use]
+    pub fn problem(self, detail: impl Into<String>) -> ProblemDetails {
+        ProblemDetails::new(self.status(), self.title())
+            .with_type(self.type_uri())
+            .with_code(self.code(), self.retryable())
+            .with_detail(detail)
+    }
+}
+
 /// RFC 7807 Problem Details response
 #[derive(Debug, Serialize)]
 pub struct ProblemDetails {
@@ -22,6 +179,45 @@ pub struct ProblemDetails {
     pub detail: Option<String>,
     #[serde(skip_serializing_if = "Option::is_none")]
     pub instance: Option<String>,
+    /// Catalog code (extension member).
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub code: Option<String>,
+    /// Whether the request may succeed if retried (extension member).
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub retryable: Option<bool>,
+    /// Why the provider request failed (extension member). Also attached to
+    /// the response as an extension, so the run can record it.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub error_class: Option<ProviderErrorClass>,
+    /// Per-field validation errors (extension member).
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    pub errors: Vec<FieldError>,
+    /// The agent's `session` setting of the run budget that was exceeded,
+    /// e.g. `max_tool_calls` (extension member).
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub budget: Option<String>,
+    /// ID of the request that failed, as in its `X-Request-Id` response
+    /// header (extension member). Filled in when the response is built.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub request_id: Option<String>,
+}
+
+/// A single validation failure, located by JSON Pointer (RFC 6901).
+#[derive(Debug, Clone, PartialEq, Eq, Serialize)]
+pub struct FieldError {
+    /// JSON Pointer to the offending field (empty string for the whole body).
+    pub pointer: String,
+    pub message: String,
+}
+
+impl FieldError {
+    #[must_use]
+    pub fn new(pointer: impl Into<String>, message: impl Into<String>) -> Self {
+        Self {
+            pointer: pointer.into(),
+            message: message.into(),
+        }
+    }
 }
 
 impl ProblemDetails {
@@ -33,6 +229,12 @@ impl ProblemDetails {
             status: status.as_u16(),
             detail: None,
             instance: None,
+            code: None,
+            retryable: None,
+            error_class: None,
+            errors: Vec::new(),
+            budget: None,
+            request_id: None,
         }
     }
 
@@ -48,6 +250,31 @@ impl ProblemDetails {
         self
     }
 
+    #[must_use]
+    pub fn with_code(mut self, code: impl Into<String>, retryable: bool) -> Self {
+        self.code = Some(code.into());
+        self.retryable = Some(retryable);
+        self
+    }
+
+    #[must_use]
+    pub fn with_error_class(mut self, class: Option<ProviderErrorClass>) -> Self {
+        self.error_class = class;
+        self
+    }
+
+    #[must_use]
+    pub fn with_budget(mut self, budget: Option<&str>) -> Self {
+        self.budget = budget.map(str::to_string);
+        self
+    }
+
+    #[must_use]
+    pub fn with_errors(mut self, errors: Vec<FieldError>) -> Self {
+        self.errors = errors;
+        self
+    }
+
     #[must_use]
     #[allow(dead_code)] // public API, used in tests
     pub fn with_instance(mut self, instance: impl Into<String>) -> Self {
@@ -61,8 +288,12 @@ impl IntoResponse for ProblemDetails {
         let mut pd = self;
         let status = StatusCode::from_u16(pd.status).unwrap_or(StatusCode::INTERNAL_SERVER_ERROR);
         pd.status = status.as_u16();
+        if pd.request_id.is_none() {
+            pd.request_id = request_id::current();
+        }
 
-        (
+        let error_class = pd.error_class;
+        let mut response = (
             status,
             [(
                 header::CONTENT_TYPE,
@@ -70,30 +301,126 @@ impl IntoResponse for ProblemDetails {
             )],
             Json(pd),
         )
-            .into_response()
+            .into_response();
+        if let Some(class) = error_class {
+            response.extensions_mut().insert(class);
+        }
+        response
     }
 }
 
 /// Common error responses
 #[must_use]
 pub fn bad_request(detail: impl Into<String>) -> ProblemDetails {
-    ProblemDetails::new(StatusCode::BAD_REQUEST, "Bad Request")
-        .with_type(TYPE_BAD_REQUEST)
-        .with_detail(detail)
+    ProblemType::BadRequest.problem(detail)
+}
+
+#[must_use]
+pub fn unauthorized(detail: impl Into<String>) -> ProblemDetails {
+    ProblemType::Unauthorized.problem(detail)
+}
+
+#[must_use]
+pub fn forbidden(detail: impl Into<String>) -> ProblemDetails {
+    ProblemType::Forbidden.problem(detail)
+}
+
+#[must_use]
+pub fn conflict(detail: impl Into<String>) -> ProblemDetails {
+    ProblemType::Conflict.problem(detail)
+}
+
+#[must_use]
+pub fn validation_failed(errors: Vec<FieldError>) -> ProblemDetails {
+    ProblemType::ValidationFailed
+        .problem("request validation failed")
+        .with_errors(errors)
 }
 
 #[must_use]
 pub fn internal_error(detail: impl Into<String>) -> ProblemDetails {
-    ProblemDetails::new(StatusCode::INTERNAL_SERVER_ERROR, "Internal Server Error")
-        .with_type(TYPE_INTERNAL_ERROR)
-        .with_detail(detail)
+    ProblemType::InternalError.problem(detail)
 }
 
 #[must_use]
 pub fn not_found(detail: impl Into<String>) -> ProblemDetails {
-    ProblemDetails::new(StatusCode::NOT_FOUND, "Not Found")
-        .with_type(TYPE_NOT_FOUND)
-        .with_detail(detail)
+    ProblemType::NotFound.problem(detail)
+}
+
+#[must_use]
+pub fn agent_not_found(name: &str) -> ProblemDetails {
+    ProblemType::AgentNotFound.problem(format!("agent '{name}' not found"))
+}
+
+#[must_use]
+pub fn agent_disabled(name: &str) -> ProblemDetails {
+    ProblemType::AgentDisabled.problem(format!("agent '{name}' is disabled"))
+}
+
+#[must_use]
+pub fn session_not_found() -> ProblemDetails {
+    ProblemType::SessionNotFound.problem("session not found")
+}
+
+#[must_use]
+pub fn provider_error(detail: impl Into<String>) -> ProblemDetails {
+    ProblemType::ProviderError.problem(detail)
+}
+
+#[must_use]
+pub fn provider_not_configured() -> ProblemDetails {
+    ProblemType::ProviderNotConfigured.problem("provider not configured")
+}
+
+#[must_use]
+pub fn run_budget_exceeded(detail: impl Into<String>) -> ProblemDetails {
+    ProblemType::RunBudgetExceeded.problem(detail)
+}
+
+#[must_use]
+pub fn run_interrupted(detail: impl Into<String>) -> ProblemDetails {
+    ProblemType::RunInterrupted.problem(detail)
+}
+
+#[must_use]
+pub fn captcha_required(detail: impl Into<String>) -> ProblemDetails {
+    ProblemType::CaptchaRequired.problem(detail)
+}
+
+#[must_use]
+pub fn read_only(method: &str) -> ProblemDetails {
+    ProblemType::ReadOnly.problem(format!(
+        "server is read-only; {method} requests are not accepted"
+    ))
+}
+
+/// 503 response for shed load, with a `Retry-After` header.
+#[must_use]
+pub fn overloaded(retry_after: Duration) -> Response {
+    let secs = retry_after.as_secs().max(1);
+    let mut response = ProblemType::Overloaded
+        .problem(format!(
+            "server is overloaded; retry low-priority requests in {secs}s"
+        ))
+        .into_response();
+    response
+        .headers_mut()
+        .insert(header::RETRY_AFTER, HeaderValue::from(secs));
+    response
+}
+
+/// 429 response for a client over its request budget, with a `Retry-After`
+/// header.
+#[must_use]
+pub fn rate_limited(retry_after: Duration) -> Response {
+    let secs = retry_after.as_secs().max(1);
+    let mut response = ProblemType::RateLimited
+        .problem(format!("too many requests; retry in {secs}s"))
+        .into_response();
+    response
+        .headers_mut()
+        .insert(header::RETRY_AFTER, HeaderValue::from(secs));
+    response
 }
 
 #[cfg(test)]
@@ -172,6 +499,73 @@ mod tests {
         assert_eq!(pd.detail, Some("Agent not found".to_string()));
     }
 
+    #[test]
+    fn test_catalog_codes_are_unique() {
+        let mut codes: Vec<_> = ProblemType::ALL.iter().map(|t| t.code()).collect();
+        codes.sort_unstable();
+        codes.dedup();
+        assert_eq!(codes.len(), ProblemType::ALL.len());
+    }
+
+    #[test]
+    fn test_catalog_type_uris_match_legacy_constants() {
+        assert_eq!(ProblemType::BadRequest.type_uri(), TYPE_BAD_REQUEST);
+        assert_eq!(ProblemType::InternalError.type_uri(), TYPE_INTERNAL_ERROR);
+        assert_eq!(ProblemType::NotFound.type_uri(), TYPE_NOT_FOUND);
+    }
+
+    #[test]
+    fn test_catalog_problem_carries_code_and_retryable() {
+        let pd = provider_error("upstream timed out");
+        assert_eq!(pd.status, 502);
+        assert_eq!(pd.code.as_deref(), Some("provider-error"));
+        assert_eq!(pd.retryable, Some(true));
+
+        let pd = session_not_found();
+        assert_eq!(pd.r#type, "urn:duragent:problem:session-not-found");
+        assert_eq!(pd.retryable, Some(false));
+    }
+
+    #[tokio::test]
+    async fn test_validation_failed_serializes_errors() {
+        use http_body_util::BodyExt;
+
+        let resp = validation_failed(vec![FieldError::new("/content", "must not be empty")])
+            .into_response();
+        assert_eq!(resp.status(), StatusCode::BAD_REQUEST);
+
+        let bytes = resp.into_body().collect().await.unwrap().to_bytes();
+        let v: serde_json::Value = serde_json::from_slice(&bytes).unwrap();
+        assert_eq!(v["code"], "validation-failed");
+        assert_eq!(v["errors"][0]["pointer"], "/content");
+        assert_eq!(v["errors"][0]["message"], "must not be empty");
+    }
+
+    #[test]
+    fn test_overloaded_sets_retry_after() {
+        let resp = overloaded(Duration::from_secs(7));
+        assert_eq!(resp.status(), StatusCode::SERVICE_UNAVAILABLE);
+        assert_eq!(resp.headers()[header::RETRY_AFTER], "7");
+
+        // Never tell clients to retry immediately
+        let resp = overloaded(Duration::ZERO);
+        assert_eq!(resp.headers()[header::RETRY_AFTER], "1");
+    }
+
+    #[test]
+    fn test_errors_omitted_when_empty() {
+        let v = serde_json::to_value(bad_request("nope")).unwrap();
+        assert!(v.get("errors").is_none());
+    }
+
+    #[test]
+    fn test_catalog_docs_url_anchors_on_code() {
+        assert_eq!(
+            ProblemType::AgentNotFound.docs_url(),
+            format!("{DOCS_BASE_URL}#agent-not-found")
+        );
+    }
+
     #[tokio::test]
     async fn test_problem_details_into_response_contract() {
         use http_body_util::BodyExt;
@@ -194,5 +588,22 @@ mod tests {
         assert_eq!(v["status"], 400);
         assert_eq!(v["detail"], "Invalid input");
         assert_eq!(v["instance"], "/api/v1/agents");
+        assert_eq!(v["code"], "bad-request");
+        assert_eq!(v["retryable"], false);
+        assert!(v.get("request_id").is_none());
+    }
+
+    #[tokio::test]
+    async fn test_problem_details_carries_request_id() {
+        use http_body_util::BodyExt;
+
+        let resp = request_id::scope("req_abc".to_string(), async {
+            not_found("no such thing").into_response()
+        })
+        .await;
+
+        let bytes = resp.into_body().collect().await.unwrap().to_bytes();
+        let v: serde_json::Value = serde_json::from_slice(&bytes).unwrap();
+        assert_eq!(v["request_id"], "req_abc");
     }
 }
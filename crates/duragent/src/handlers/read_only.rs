@@ -0,0 +1,35 @@
+//! Read-only server mode.
+//!
+//! With `server.read_only`, the server only answers reads: list and get
+//! requests go through, while invokes and every other mutation are refused
+//! with `403` [`read-only`](super::problem_details::ProblemType::ReadOnly).
+//! That covers the API, admin, public, and SCIM routes; admin shutdown and
+//! upgrade are left out of the layer. Meant for exposing a reporting replica
+//! to a broad audience.
+
+use axum::body::Body;
+use axum::extract::State;
+use axum::http::{Method, Request};
+use axum::middleware::Next;
+use axum::response::{IntoResponse, Response};
+
+use super::problem_details;
+use crate::server::AppState;
+
+/// Middleware that refuses any request other than `GET`, `HEAD`, or
+/// `OPTIONS` when the server is read-only.
+pub async fn reject_writes(
+    State(state): State<AppState>,
+    request: Request<Body>,
+    next: Next,
+) -> Response {
+    if !state.read_only
+        || matches!(
+            *request.method(),
+            Method::GET | Method::HEAD | Method::OPTIONS
+        )
+    {
+        return next.run(request).await;
+    }
+    problem_details::read_only(request.method().as_str()).into_response()
+}
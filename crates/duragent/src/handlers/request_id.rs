@@ -0,0 +1,90 @@
+//! Request IDs.
+//!
+//! Every request gets an ID: the caller's own `X-Request-Id`, when it sends
+//! a valid one, or a fresh `req_<ulid>`. The ID is set on the response, is
+//! available to handlers as a [`RequestId`] extension, tags every log line
+//! written while the request is handled, and is echoed in problem responses,
+//! so a failure a client reports can be found in the server logs.
+
+use axum::body::Body;
+use axum::http::{HeaderName, HeaderValue, Request};
+use axum::middleware::Next;
+use axum::response::Response;
+use tracing::Instrument;
+use ulid::Ulid;
+
+/// Request ID header, honored on requests and set on every response.
+pub const X_REQUEST_ID: HeaderName = HeaderName::from_static("x-request-id");
+
+/// Longest caller-supplied request ID that is honored.
+const MAX_REQUEST_ID_LEN: usize = 128;
+
+tokio::task_local! {
+    static CURRENT: RequestId;
+}
+
+/// The ID of the request being handled.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct RequestId(pub String);
+
+/// The ID of the request being handled by this task, if any. Work spawned
+/// onto other tasks does not inherit it.
+pub fn current() -> Option<String> {
+    CURRENT.try_with(|id| id.0.clone()).ok()
+}
+
+/// Run `work` as part of the request `request_id`.
+pub async fn scope<F: Future>(request_id: String, work: F) -> F::Output {
+    CURRENT.scope(RequestId(request_id), work).await
+}
+
+/// Middleware that assigns every request an ID.
+pub async fn assign_request_id(mut request: Request<Body>, next: Next) -> Response {
+    let request_id = request
+        .headers()
+        .get(&X_REQUEST_ID)
+        .and_then(|v| v.to_str().ok())
+        .filter(|id| is_valid_request_id(id))
+        .map_or_else(|| format!("req_{}", Ulid::new()), str::to_string);
+    request
+        .extensions_mut()
+        .insert(RequestId(request_id.clone()));
+
+    let span = tracing::info_span!("request", request_id = %request_id);
+    let mut response = scope(request_id.clone(), next.run(request))
+        .instrument(span)
+        .await;
+    if let Ok(value) = HeaderValue::from_str(&request_id) {
+        response.headers_mut().insert(X_REQUEST_ID, value);
+    }
+    response
+}
+
+fn is_valid_request_id(id: &str) -> bool {
+    !id.is_empty()
+        && id.len() <= MAX_REQUEST_ID_LEN
+        && id
+            .bytes()
+            .all(|b| b.is_ascii_alphanumeric() || matches!(b, b'-' | b'_' | b'.' | b':'))
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn request_ids_are_validated() {
+        assert!(is_valid_request_id("req_01HXYZ"));
+        assert!(is_valid_request_id("trace-1.2:3"));
+        assert!(!is_valid_request_id(""));
+        assert!(!is_valid_request_id("has space"));
+        assert!(!is_valid_request_id(&"a".repeat(MAX_REQUEST_ID_LEN + 1)));
+    }
+
+    #[tokio::test]
+    async fn current_is_scoped_to_the_request() {
+        assert_eq!(current(), None);
+        let seen = scope("req_1".to_string(), async { current() }).await;
+        assert_eq!(seen.as_deref(), Some("req_1"));
+    }
+}
@@ -0,0 +1,210 @@
+//! Request body validation against the OpenAPI document.
+//!
+//! With `server.request_validation` (the default), JSON bodies sent to an
+//! operation that `schemas/openapi.json` describes are checked against its
+//! request schema before the handler runs. Every mismatch is reported at once
+//! in a `validation-failed` problem. Handlers' own `Validate` checks run
+//! either way, so turning this off never lets more through than they allow.
+
+use std::collections::HashMap;
+use std::sync::Arc;
+
+use axum::body::Body;
+use axum::extract::{MatchedPath, State};
+use axum::http::{Method, Request, header};
+use axum::middleware::Next;
+use axum::response::{IntoResponse, Response};
+use serde_json::{Map, Value};
+use tracing::warn;
+
+use super::problem_details::{self, FieldError};
+use super::v1::OPENAPI_DOCUMENT;
+use crate::input_schema;
+use crate::server::MAX_REQUEST_BODY_BYTES;
+
+/// Deepest nesting followed while resolving `$ref`s, so a cycle can't
+/// recurse forever.
+const MAX_REF_DEPTH: usize = 64;
+
+/// Request schemas by operation; cheap to clone. Empty when
+/// `server.request_validation` is off.
+#[derive(Clone, Default)]
+pub struct RequestSchemas {
+    inner: Arc<RequestSchemasInner>,
+}
+
+#[derive(Default)]
+struct RequestSchemasInner {
+    base_path: String,
+    /// Resolved JSON body schema by method and route template.
+    schemas: HashMap<(Method, String), Value>,
+}
+
+impl RequestSchemas {
+    /// The JSON body schema of every operation in the OpenAPI document, with
+    /// `$ref`s resolved. `base_path` is stripped before matching routes.
+    pub fn from_openapi(base_path: &str) -> Self {
+        let document: Value =
+            serde_json::from_str(OPENAPI_DOCUMENT).expect("OpenAPI document must be valid JSON");
+        let mut schemas = HashMap::new();
+        for (route, item) in document["paths"].as_object().into_iter().flatten() {
+            for (method, operation) in item.as_object().into_iter().flatten() {
+                let Some(schema) =
+                    operation.pointer("/requestBody/content/application~1json/schema")
+                else {
+                    continue;
+                };
+                let Ok(method) = Method::from_bytes(method.to_ascii_uppercase().as_bytes()) else {
+                    continue;
+                };
+                match resolve_refs(&document, schema, 0) {
+                    Some(schema) if input_schema::check_schema(&schema).is_ok() => {
+                        schemas.insert((method, route.clone()), schema);
+                    }
+                    _ => warn!(%method, route, "Unsupported request schema; body not checked"),
+                }
+            }
+        }
+        Self {
+            inner: Arc::new(RequestSchemasInner {
+                base_path: base_path.to_string(),
+                schemas,
+            }),
+        }
+    }
+
+    fn schema_for(&self, method: &Method, route: &str) -> Option<&Value> {
+        let route = route.strip_prefix(&*self.inner.base_path).unwrap_or(route);
+        self.inner.schemas.get(&(method.clone(), route.to_string()))
+    }
+}
+
+/// `schema` with every `$ref` replaced by the schema it points to, or `None`
+/// when a reference doesn't resolve.
+fn resolve_refs(document: &Value, schema: &Value, depth: usize) -> Option<Value> {
+    if depth > MAX_REF_DEPTH {
+        return None;
+    }
+    match schema {
+        Value::Object(object) => {
+            if let Some(target) = object.get("$ref").and_then(Value::as_str) {
+                let target = document.pointer(target.strip_prefix('#')?)?;
+                return resolve_refs(document, target, depth + 1);
+            }
+            object
+                .iter()
+                .map(|(key, value)| Some((key.clone(), resolve_refs(document, value, depth + 1)?)))
+                .collect::<Option<Map<_, _>>>()
+                .map(Value::Object)
+        }
+        Value::Array(items) => items
+            .iter()
+            .map(|item| resolve_refs(document, item, depth + 1))
+            .collect::<Option<Vec<_>>>()
+            .map(Value::Array),
+        other => Some(other.clone()),
+    }
+}
+
+fn is_json(request: &Request<Body>) -> bool {
+    request
+        .headers()
+        .get(header::CONTENT_TYPE)
+        .and_then(|value| value.to_str().ok())
+        .and_then(|value| value.split(';').next())
+        .is_some_and(|mime| mime.trim().eq_ignore_ascii_case("application/json"))
+}
+
+/// Middleware that rejects JSON bodies not matching their operation's
+/// request schema with `400 validation-failed`.
+pub async fn validate_bodies(
+    State(schemas): State<RequestSchemas>,
+    request: Request<Body>,
+    next: Next,
+) -> Response {
+    let schema = request
+        .extensions()
+        .get::<MatchedPath>()
+        .filter(|_| is_json(&request))
+        .and_then(|route| schemas.schema_for(request.method(), route.as_str()));
+    let Some(schema) = schema else {
+        return next.run(request).await;
+    };
+
+    let (parts, body) = request.into_parts();
+    let bytes = match axum::body::to_bytes(body, MAX_REQUEST_BODY_BYTES).await {
+        Ok(bytes) => bytes,
+        Err(e) => {
+            return problem_details::validation_failed(vec![FieldError::new(
+                "",
+                format!("Failed to buffer the request body: {e}"),
+            )])
+            .into_response();
+        }
+    };
+
+    // Malformed JSON is left to the handler's extractor to report
+    if let Ok(value) = serde_json::from_slice::<Value>(&bytes) {
+        let errors: Vec<FieldError> = input_schema::validate(schema, &value)
+            .into_iter()
+            .map(|e| FieldError::new(e.pointer, e.message))
+            .collect();
+        if !errors.is_empty() {
+            return problem_details::validation_failed(errors).into_response();
+        }
+    }
+    next.run(Request::from_parts(parts, Body::from(bytes)))
+        .await
+}
+
+#[cfg(test)]
+mod tests {
+    use serde_json::json;
+
+    use super::*;
+
+    #[test]
+    fn every_request_schema_is_supported() {
+        let document: Value = serde_json::from_str(OPENAPI_DOCUMENT).unwrap();
+        let operations = document["paths"]
+            .as_object()
+            .unwrap()
+            .values()
+            .flat_map(|item| item.as_object().unwrap().values())
+            .filter(|op| {
+                op.pointer("/requestBody/content/application~1json")
+                    .is_some()
+            })
+            .count();
+
+        let schemas = RequestSchemas::from_openapi("");
+        assert!(operations > 0);
+        assert_eq!(schemas.inner.schemas.len(), operations);
+    }
+
+    #[test]
+    fn schemas_resolve_refs_and_strip_base_path() {
+        let schemas = RequestSchemas::from_openapi("/duragent");
+        let schema = schemas
+            .schema_for(
+                &Method::POST,
+                "/duragent/api/v1/sessions/{session_id}/messages",
+            )
+            .unwrap();
+        assert_eq!(schema["properties"]["priority"]["type"], "string");
+
+        let errors = input_schema::validate(
+            schema,
+            &json!({"content": "hi", "priority": "urgent", "labels": {"team": 7}}),
+        );
+        let mut pointers: Vec<&str> = errors.iter().map(|e| e.pointer.as_str()).collect();
+        pointers.sort_unstable();
+        assert_eq!(pointers, ["/labels/team", "/priority"]);
+
+        assert!(
+            schemas
+                .schema_for(&Method::GET, "/api/v1/sessions")
+                .is_none()
+        );
+    }
+}
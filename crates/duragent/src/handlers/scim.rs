@@ -0,0 +1,1050 @@
+//! SCIM 2.0 user and group provisioning (`/scim/v2`).
+//!
+//! A minimal subset of RFC 7643/7644 for identity providers such as Okta and
+//! Entra ID: `Users` and `Groups` with create, read, replace, patch, and
+//! delete; list filters of the form `attr eq "value"`; and
+//! `ServiceProviderConfig`. Group membership maps onto access roles through
+//! `scim.group_roles` (see [`crate::identity`]), and each user carries its
+//! resulting grants in a `urn:duragent:...:access:1.0:User` extension.
+//!
+//! Errors use the SCIM error schema rather than problem details, since that
+//! is what provisioning clients parse.
+
+use std::net::SocketAddr;
+
+use axum::Json;
+use axum::extract::{ConnectInfo, FromRequest, Path, Query, State};
+use axum::http::{Method, Request, StatusCode, header};
+use axum::middleware::Next;
+use axum::response::{IntoResponse, Response};
+use chrono::{DateTime, Utc};
+use serde::de::DeserializeOwned;
+use serde::{Deserialize, Serialize};
+use serde_json::Value;
+use tracing::{error, info};
+
+use super::api_auth;
+use crate::audit::{AuditEvent, AuditOutcome};
+use crate::identity::{Grant, Group, User, grants_for};
+use crate::server::AppState;
+use crate::store::StorageResult;
+
+const USER_SCHEMA: &str = "urn:ietf:params:scim:schemas:core:2.0:User";
+const GROUP_SCHEMA: &str = "urn:ietf:params:scim:schemas:core:2.0:Group";
+const ACCESS_SCHEMA: &str = "urn:duragent:params:scim:schemas:extension:access:1.0:User";
+const LIST_SCHEMA: &str = "urn:ietf:params:scim:api:messages:2.0:ListResponse";
+const ERROR_SCHEMA: &str = "urn:ietf:params:scim:api:messages:2.0:Error";
+const SERVICE_PROVIDER_SCHEMA: &str = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig";
+
+/// Largest page a list request returns.
+const MAX_RESULTS: usize = 200;
+
+// ============================================================================
+// Auth and Responses
+// ============================================================================
+
+/// Middleware that guards SCIM routes with `scim.token`.
+///
+/// Falls back to localhost-only when no token is configured, like the API
+/// and admin tokens. Rejected requests and successful writes are audited.
+pub async fn require_scim_token(
+    State(state): State<AppState>,
+    ConnectInfo(addr): ConnectInfo<SocketAddr>,
+    request: Request<axum::body::Body>,
+    next: Next,
+) -> Response {
+    let path = api_auth::original_path(&request);
+    if !api_auth::is_authorized(&state.scim.token, &addr, request.headers()) {
+        state.audit.record(
+            AuditEvent::new("auth.failed", AuditOutcome::Denied)
+                .source(&addr)
+                .target(path)
+                .detail("missing or invalid scim token"),
+        );
+        return scim_error(
+            StatusCode::UNAUTHORIZED,
+            None,
+            "missing or invalid scim token",
+        );
+    }
+
+    let action = match *request.method() {
+        Method::POST => Some("scim.created"),
+        Method::PUT | Method::PATCH => Some("scim.updated"),
+        Method::DELETE => Some("scim.deleted"),
+        _ => None,
+    };
+    let response = next.run(request).await;
+    if let Some(action) = action
+        && response.status().is_success()
+    {
+        state.audit.record(
+            AuditEvent::new(action, AuditOutcome::Success)
+                .principal(api_auth::principal(&state.scim.token, "scim"))
+                .source(&addr)
+                .target(path),
+        );
+    }
+    response
+}
+
+#[derive(Serialize)]
+#[serde(rename_all = "camelCase")]
+struct ScimError {
+    schemas: [&'static str; 1],
+    status: String,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    scim_type: Option<&'static str>,
+    detail: String,
+}
+
+fn scim_error(status: StatusCode, scim_type: Option<&'static str>, detail: &str) -> Response {
+    scim_json(
+        status,
+        ScimError {
+            schemas: [ERROR_SCHEMA],
+            status: status.as_u16().to_string(),
+            scim_type,
+            detail: detail.to_string(),
+        },
+    )
+}
+
+fn scim_json(status: StatusCode, body: impl Serialize) -> Response {
+    (
+        status,
+        [(header::CONTENT_TYPE, "application/scim+json")],
+        Json(body),
+    )
+        .into_response()
+}
+
+fn invalid_value(detail: &str) -> Response {
+    scim_error(StatusCode::BAD_REQUEST, Some("invalidValue"), detail)
+}
+
+/// JSON body extractor that reports malformed bodies in the SCIM error schema.
+#[derive(Debug)]
+pub struct ScimJson<T>(pub T);
+
+impl<T, S> FromRequest<S> for ScimJson<T>
+where
+    T: DeserializeOwned,
+    S: Send + Sync,
+{
+    type Rejection = Response;
+
+    async fn from_request(req: Request<axum::body::Body>, state: &S) -> Result<Self, Response> {
+        match Json::<T>::from_request(req, state).await {
+            Ok(Json(value)) => Ok(Self(value)),
+            Err(rejection) => Err(scim_error(
+                StatusCode::BAD_REQUEST,
+                Some("invalidSyntax"),
+                &rejection.body_text(),
+            )),
+        }
+    }
+}
+
+fn storage_error(action: &str, e: impl std::fmt::Display) -> Response {
+    error!(error = %e, "failed to {action}");
+    scim_error(
+        StatusCode::INTERNAL_SERVER_ERROR,
+        None,
+        &format!("failed to {action}"),
+    )
+}
+
+// ============================================================================
+// Resources
+// ============================================================================
+
+#[derive(Serialize)]
+#[serde(rename_all = "camelCase")]
+struct Meta {
+    resource_type: &'static str,
+    created: DateTime<Utc>,
+    last_modified: DateTime<Utc>,
+    location: String,
+}
+
+#[derive(Debug, Serialize, Deserialize)]
+struct MultiValue {
+    value: String,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    display: Option<String>,
+}
+
+#[derive(Serialize)]
+struct Email {
+    value: String,
+    primary: bool,
+}
+
+#[derive(Serialize)]
+struct AccessExtension {
+    grants: Vec<Grant>,
+}
+
+#[derive(Serialize)]
+#[serde(rename_all = "camelCase")]
+struct UserResource {
+    schemas: [&'static str; 2],
+    id: String,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    external_id: Option<String>,
+    user_name: String,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    display_name: Option<String>,
+    active: bool,
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    emails: Vec<Email>,
+    groups: Vec<MultiValue>,
+    #[serde(rename = "urn:duragent:params:scim:schemas:extension:access:1.0:User")]
+    access: AccessExtension,
+    meta: Meta,
+}
+
+#[derive(Serialize)]
+#[serde(rename_all = "camelCase")]
+struct GroupResource {
+    schemas: [&'static str; 1],
+    id: String,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    external_id: Option<String>,
+    display_name: String,
+    members: Vec<MultiValue>,
+    meta: Meta,
+}
+
+#[derive(Serialize)]
+#[serde(rename_all = "camelCase")]
+struct ListResponse<T> {
+    schemas: [&'static str; 1],
+    total_results: usize,
+    start_index: usize,
+    items_per_page: usize,
+    #[serde(rename = "Resources")]
+    resources: Vec<T>,
+}
+
+fn user_resource(state: &AppState, user: &User, groups: &[Group]) -> UserResource {
+    UserResource {
+        schemas: [USER_SCHEMA, ACCESS_SCHEMA],
+        id: user.id.clone(),
+        external_id: user.external_id.clone(),
+        user_name: user.user_name.clone(),
+        display_name: user.display_name.clone(),
+        active: user.active,
+        emails: user
+            .emails
+            .iter()
+            .enumerate()
+            .map(|(i, value)| Email {
+                value: value.clone(),
+                primary: i == 0,
+            })
+            .collect(),
+        groups: groups
+            .iter()
+            .filter(|g| g.members.contains(&user.id))
+            .map(|g| MultiValue {
+                value: g.id.clone(),
+                display: Some(g.display_name.clone()),
+            })
+            .collect(),
+        access: AccessExtension {
+            grants: grants_for(user, groups, &state.scim.group_roles),
+        },
+        meta: Meta {
+            resource_type: "User",
+            created: user.created_at,
+            last_modified: user.updated_at,
+            location: state
+                .external_url
+                .url_for(&format!("/scim/v2/Users/{}", user.id)),
+        },
+    }
+}
+
+fn group_resource(state: &AppState, group: &Group, users: &[User]) -> GroupResource {
+    GroupResource {
+        schemas: [GROUP_SCHEMA],
+        id: group.id.clone(),
+        external_id: group.external_id.clone(),
+        display_name: group.display_name.clone(),
+        members: group
+            .members
+            .iter()
+            .map(|id| MultiValue {
+                value: id.clone(),
+                display: users
+                    .iter()
+                    .find(|u| u.id == *id)
+                    .map(|u| u.user_name.clone()),
+            })
+            .collect(),
+        meta: Meta {
+            resource_type: "Group",
+            created: group.created_at,
+            last_modified: group.updated_at,
+            location: state
+                .external_url
+                .url_for(&format!("/scim/v2/Groups/{}", group.id)),
+        },
+    }
+}
+
+// ============================================================================
+// Listing and Filters
+// ============================================================================
+
+#[derive(Debug, Deserialize)]
+#[serde(rename_all = "camelCase")]
+pub struct ListQuery {
+    filter: Option<String>,
+    start_index: Option<usize>,
+    count: Option<usize>,
+}
+
+/// A parsed `attr eq "value"` filter.
+struct EqFilter {
+    attr: String,
+    value: String,
+}
+
+fn parse_filter(filter: &str) -> Option<EqFilter> {
+    let mut parts = filter.trim().splitn(3, char::is_whitespace);
+    let attr = parts.next()?.to_ascii_lowercase();
+    if !parts.next()?.eq_ignore_ascii_case("eq") {
+        return None;
+    }
+    let value = parts.next()?.trim();
+    let value = value.strip_prefix('"')?.strip_suffix('"')?;
+    Some(EqFilter {
+        attr,
+        value: value.to_string(),
+    })
+}
+
+/// Apply `filter` with `matches`, then paginate.
+fn list_response<T, R>(
+    query: &ListQuery,
+    items: Vec<T>,
+    matches: impl Fn(&T, &EqFilter) -> Option<bool>,
+    to_resource: impl Fn(&T) -> R,
+) -> Response
+where
+    R: Serialize,
+{
+    let filter = match query.filter.as_deref().filter(|f| !f.trim().is_empty()) {
+        Some(raw) => match parse_filter(raw) {
+            Some(f) => Some(f),
+            None => {
+                return scim_error(
+                    StatusCode::BAD_REQUEST,
+                    Some("invalidFilter"),
+                    "only 'attribute eq \"value\"' filters are supported",
+                );
+            }
+        },
+        None => None,
+    };
+
+    let mut matched = Vec::new();
+    for item in &items {
+        match &filter {
+            Some(f) => match matches(item, f) {
+                Some(true) => matched.push(item),
+                Some(false) => {}
+                None => {
+                    return scim_error(
+                        StatusCode::BAD_REQUEST,
+                        Some("invalidFilter"),
+                        &format!("cannot filter on '{}'", f.attr),
+                    );
+                }
+            },
+            None => matched.push(item),
+        }
+    }
+
+    let start_index = query.start_index.unwrap_or(1).max(1);
+    let count = query.count.unwrap_or(MAX_RESULTS).min(MAX_RESULTS);
+    let resources: Vec<R> = matched
+        .iter()
+        .skip(start_index - 1)
+        .take(count)
+        .map(|item| to_resource(item))
+        .collect();
+
+    scim_json(
+        StatusCode::OK,
+        ListResponse {
+            schemas: [LIST_SCHEMA],
+            total_results: matched.len(),
+            start_index,
+            items_per_page: resources.len(),
+            resources,
+        },
+    )
+}
+
+fn user_matches(user: &User, filter: &EqFilter) -> Option<bool> {
+    match filter.attr.as_str() {
+        "username" => Some(user.user_name.eq_ignore_ascii_case(&filter.value)),
+        "externalid" => Some(user.external_id.as_deref() == Some(filter.value.as_str())),
+        "id" => Some(user.id == filter.value),
+        _ => None,
+    }
+}
+
+fn group_matches(group: &Group, filter: &EqFilter) -> Option<bool> {
+    match filter.attr.as_str() {
+        "displayname" => Some(group.display_name == filter.value),
+        "externalid" => Some(group.external_id.as_deref() == Some(filter.value.as_str())),
+        "id" => Some(group.id == filter.value),
+        _ => None,
+    }
+}
+
+// ============================================================================
+// Users
+// ============================================================================
+
+#[derive(Debug, Deserialize)]
+#[serde(rename_all = "camelCase")]
+pub struct UserRequest {
+    user_name: String,
+    external_id: Option<String>,
+    display_name: Option<String>,
+    name: Option<NameRequest>,
+    #[serde(default)]
+    emails: Vec<MultiValue>,
+    active: Option<bool>,
+}
+
+#[derive(Debug, Deserialize)]
+#[serde(rename_all = "camelCase")]
+struct NameRequest {
+    formatted: Option<String>,
+    given_name: Option<String>,
+    family_name: Option<String>,
+}
+
+impl NameRequest {
+    fn display(&self) -> Option<String> {
+        self.formatted.clone().or_else(|| {
+            let parts: Vec<&str> = [&self.given_name, &self.family_name]
+                .into_iter()
+                .flatten()
+                .map(String::as_str)
+                .collect();
+            (!parts.is_empty()).then(|| parts.join(" "))
+        })
+    }
+}
+
+impl UserRequest {
+    fn apply(self, user: &mut User) {
+        user.user_name = self.user_name;
+        user.external_id = self.external_id;
+        user.display_name = self
+            .display_name
+            .or_else(|| self.name.as_ref().and_then(NameRequest::display));
+        user.emails = self.emails.into_iter().map(|e| e.value).collect();
+        user.active = self.active.unwrap_or(true);
+        user.updated_at = Utc::now();
+    }
+}
+
+/// GET /scim/v2/Users
+pub async fn list_users(State(state): State<AppState>, Query(query): Query<ListQuery>) -> Response {
+    let (users, groups) = match load_all(&state).await {
+        Ok(all) => all,
+        Err(e) => return storage_error("list users", e),
+    };
+    list_response(&query, users, user_matches, |u| {
+        user_resource(&state, u, &groups)
+    })
+}
+
+/// GET /scim/v2/Users/{id}
+pub async fn get_user(State(state): State<AppState>, Path(id): Path<String>) -> Response {
+    let (users, groups) = match load_all(&state).await {
+        Ok(all) => all,
+        Err(e) => return storage_error("load user", e),
+    };
+    match users.iter().find(|u| u.id == id) {
+        Some(user) => scim_json(StatusCode::OK, user_resource(&state, user, &groups)),
+        None => user_not_found(&id),
+    }
+}
+
+/// POST /scim/v2/Users
+pub async fn create_user(
+    State(state): State<AppState>,
+    ScimJson(req): ScimJson<UserRequest>,
+) -> Response {
+    let _guard = state.scim_writes.lock().await;
+    let mut user = User::new(String::new());
+    req.apply(&mut user);
+    if let Err(response) = check_user(&state, &user).await {
+        return response;
+    }
+    if let Err(e) = state.identity.save_user(&user).await {
+        return storage_error("save user", e);
+    }
+    info!(user_id = %user.id, user_name = %user.user_name, "Provisioned user");
+    respond_user(&state, &user, StatusCode::CREATED).await
+}
+
+/// PUT /scim/v2/Users/{id}
+pub async fn replace_user(
+    State(state): State<AppState>,
+    Path(id): Path<String>,
+    ScimJson(req): ScimJson<UserRequest>,
+) -> Response {
+    let _guard = state.scim_writes.lock().await;
+    let mut user = match load_user(&state, &id).await {
+        Ok(user) => user,
+        Err(response) => return response,
+    };
+    req.apply(&mut user);
+    save_user(&state, user).await
+}
+
+/// PATCH /scim/v2/Users/{id}
+pub async fn patch_user(
+    State(state): State<AppState>,
+    Path(id): Path<String>,
+    ScimJson(req): ScimJson<PatchRequest>,
+) -> Response {
+    let _guard = state.scim_writes.lock().await;
+    let mut user = match load_user(&state, &id).await {
+        Ok(user) => user,
+        Err(response) => return response,
+    };
+    for op in req.operations {
+        if let Err(detail) = op.apply_to_user(&mut user) {
+            return invalid_value(&detail);
+        }
+    }
+    user.updated_at = Utc::now();
+    save_user(&state, user).await
+}
+
+/// DELETE /scim/v2/Users/{id}
+///
+/// Also removes the user from every group.
+pub async fn delete_user(State(state): State<AppState>, Path(id): Path<String>) -> Response {
+    let _guard = state.scim_writes.lock().await;
+    if let Err(response) = load_user(&state, &id).await {
+        return response;
+    }
+
+    let groups = match state.identity.list_groups().await {
+        Ok(groups) => groups,
+        Err(e) => return storage_error("list groups", e),
+    };
+    for mut group in groups.into_iter().filter(|g| g.members.contains(&id)) {
+        group.remove_member(&id);
+        group.updated_at = Utc::now();
+        if let Err(e) = state.identity.save_group(&group).await {
+            return storage_error("save group", e);
+        }
+    }
+    if let Err(e) = state.identity.delete_user(&id).await {
+        return storage_error("delete user", e);
+    }
+    info!(user_id = %id, "Deprovisioned user");
+    StatusCode::NO_CONTENT.into_response()
+}
+
+async fn load_all(state: &AppState) -> StorageResult<(Vec<User>, Vec<Group>)> {
+    Ok((
+        state.identity.list_users().await?,
+        state.identity.list_groups().await?,
+    ))
+}
+
+async fn load_user(state: &AppState, id: &str) -> Result<User, Response> {
+    match state.identity.load_user(id).await {
+        Ok(Some(user)) => Ok(user),
+        Ok(None) => Err(user_not_found(id)),
+        Err(e) => Err(storage_error("load user", e)),
+    }
+}
+
+fn user_not_found(id: &str) -> Response {
+    scim_error(
+        StatusCode::NOT_FOUND,
+        None,
+        &format!("user '{id}' not found"),
+    )
+}
+
+/// Reject empty or duplicate (case-insensitive) user names.
+async fn check_user(state: &AppState, user: &User) -> Result<(), Response> {
+    if user.user_name.trim().is_empty() {
+        return Err(invalid_value("userName is required"));
+    }
+    let users = state
+        .identity
+        .list_users()
+        .await
+        .map_err(|e| storage_error("list users", e))?;
+    if users
+        .iter()
+        .any(|u| u.id != user.id && u.user_name.eq_ignore_ascii_case(&user.user_name))
+    {
+        return Err(scim_error(
+            StatusCode::CONFLICT,
+            Some("uniqueness"),
+            &format!("userName '{}' is already taken", user.user_name),
+        ));
+    }
+    Ok(())
+}
+
+async fn save_user(state: &AppState, user: User) -> Response {
+    if let Err(response) = check_user(state, &user).await {
+        return response;
+    }
+    if let Err(e) = state.identity.save_user(&user).await {
+        return storage_error("save user", e);
+    }
+    respond_user(state, &user, StatusCode::OK).await
+}
+
+async fn respond_user(state: &AppState, user: &User, status: StatusCode) -> Response {
+    match state.identity.list_groups().await {
+        Ok(groups) => scim_json(status, user_resource(state, user, &groups)),
+        Err(e) => storage_error("list groups", e),
+    }
+}
+
+// ============================================================================
+// Groups
+// ============================================================================
+
+#[derive(Debug, Deserialize)]
+#[serde(rename_all = "camelCase")]
+pub struct GroupRequest {
+    display_name: String,
+    external_id: Option<String>,
+    #[serde(default)]
+    members: Vec<MultiValue>,
+}
+
+/// GET /scim/v2/Groups
+pub async fn list_groups(
+    State(state): State<AppState>,
+    Query(query): Query<ListQuery>,
+) -> Response {
+    let (users, groups) = match load_all(&state).await {
+        Ok(all) => all,
+        Err(e) => return storage_error("list groups", e),
+    };
+    list_response(&query, groups, group_matches, |g| {
+        group_resource(&state, g, &users)
+    })
+}
+
+/// GET /scim/v2/Groups/{id}
+pub async fn get_group(State(state): State<AppState>, Path(id): Path<String>) -> Response {
+    match load_group(&state, &id).await {
+        Ok(group) => respond_group(&state, &group, StatusCode::OK).await,
+        Err(response) => response,
+    }
+}
+
+/// POST /scim/v2/Groups
+pub async fn create_group(
+    State(state): State<AppState>,
+    ScimJson(req): ScimJson<GroupRequest>,
+) -> Response {
+    let _guard = state.scim_writes.lock().await;
+    let mut group = Group::new(req.display_name);
+    group.external_id = req.external_id;
+    for member in &req.members {
+        group.add_member(&member.value);
+    }
+    if let Err(response) = save_group(&state, &group).await {
+        return response;
+    }
+    info!(group_id = %group.id, group = %group.display_name, "Provisioned group");
+    respond_group(&state, &group, StatusCode::CREATED).await
+}
+
+/// PUT /scim/v2/Groups/{id}
+pub async fn replace_group(
+    State(state): State<AppState>,
+    Path(id): Path<String>,
+    ScimJson(req): ScimJson<GroupRequest>,
+) -> Response {
+    let _guard = state.scim_writes.lock().await;
+    let mut group = match load_group(&state, &id).await {
+        Ok(group) => group,
+        Err(response) => return response,
+    };
+    group.display_name = req.display_name;
+    group.external_id = req.external_id;
+    group.members.clear();
+    for member in &req.members {
+        group.add_member(&member.value);
+    }
+    group.updated_at = Utc::now();
+    if let Err(response) = save_group(&state, &group).await {
+        return response;
+    }
+    respond_group(&state, &group, StatusCode::OK).await
+}
+
+/// PATCH /scim/v2/Groups/{id}
+pub async fn patch_group(
+    State(state): State<AppState>,
+    Path(id): Path<String>,
+    ScimJson(req): ScimJson<PatchRequest>,
+) -> Response {
+    let _guard = state.scim_writes.lock().await;
+    let mut group = match load_group(&state, &id).await {
+        Ok(group) => group,
+        Err(response) => return response,
+    };
+    for op in req.operations {
+        if let Err(detail) = op.apply_to_group(&mut group) {
+            return invalid_value(&detail);
+        }
+    }
+    group.updated_at = Utc::now();
+    if let Err(response) = save_group(&state, &group).await {
+        return response;
+    }
+    respond_group(&state, &group, StatusCode::OK).await
+}
+
+/// DELETE /scim/v2/Groups/{id}
+pub async fn delete_group(State(state): State<AppState>, Path(id): Path<String>) -> Response {
+    let _guard = state.scim_writes.lock().await;
+    if let Err(response) = load_group(&state, &id).await {
+        return response;
+    }
+    if let Err(e) = state.identity.delete_group(&id).await {
+        return storage_error("delete group", e);
+    }
+    info!(group_id = %id, "Deprovisioned group");
+    StatusCode::NO_CONTENT.into_response()
+}
+
+async fn load_group(state: &AppState, id: &str) -> Result<Group, Response> {
+    match state.identity.load_group(id).await {
+        Ok(Some(group)) => Ok(group),
+        Ok(None) => Err(scim_error(
+            StatusCode::NOT_FOUND,
+            None,
+            &format!("group '{id}' not found"),
+        )),
+        Err(e) => Err(storage_error("load group", e)),
+    }
+}
+
+/// Validate and save a group: a unique display name and known members.
+async fn save_group(state: &AppState, group: &Group) -> Result<(), Response> {
+    if group.display_name.trim().is_empty() {
+        return Err(invalid_value("displayName is required"));
+    }
+    let (users, groups) = load_all(state)
+        .await
+        .map_err(|e| storage_error("list groups", e))?;
+    if groups
+        .iter()
+        .any(|g| g.id != group.id && g.display_name == group.display_name)
+    {
+        return Err(scim_error(
+            StatusCode::CONFLICT,
+            Some("uniqueness"),
+            &format!("displayName '{}' is already taken", group.display_name),
+        ));
+    }
+    if let Some(unknown) = group
+        .members
+        .iter()
+        .find(|m| !users.iter().any(|u| u.id == **m))
+    {
+        return Err(invalid_value(&format!("unknown member '{unknown}'")));
+    }
+    state
+        .identity
+        .save_group(group)
+        .await
+        .map_err(|e| storage_error("save group", e))
+}
+
+async fn respond_group(state: &AppState, group: &Group, status: StatusCode) -> Response {
+    match state.identity.list_users().await {
+        Ok(users) => scim_json(status, group_resource(state, group, &users)),
+        Err(e) => storage_error("list users", e),
+    }
+}
+
+// ============================================================================
+// PATCH
+// ============================================================================
+
+#[derive(Debug, Deserialize)]
+pub struct PatchRequest {
+    #[serde(rename = "Operations")]
+    operations: Vec<PatchOperation>,
+}
+
+#[derive(Debug, Deserialize)]
+struct PatchOperation {
+    op: String,
+    #[serde(default)]
+    path: Option<String>,
+    #[serde(default)]
+    value: Value,
+}
+
+impl PatchOperation {
+    /// Apply to a user. Attributes this server does not store are ignored,
+    /// since identity providers send many optional ones.
+    fn apply_to_user(self, user: &mut User) -> Result<(), String> {
+        let op = self.op.to_ascii_lowercase();
+        match (op.as_str(), self.path) {
+            ("add" | "replace", Some(path)) => set_user_attr(user, &path, &self.value),
+            ("add" | "replace", None) => {
+                let Value::Object(attrs) = &self.value else {
+                    return Err("value must be an object when path is omitted".to_string());
+                };
+                attrs
+                    .iter()
+                    .try_for_each(|(attr, value)| set_user_attr(user, attr, value))
+            }
+            ("remove", Some(path)) => {
+                match path.to_ascii_lowercase().as_str() {
+                    "externalid" => user.external_id = None,
+                    "displayname" => user.display_name = None,
+                    "emails" => user.emails.clear(),
+                    _ => {}
+                }
+                Ok(())
+            }
+            ("remove", None) => Err("remove requires a path".to_string()),
+            _ => Err(format!("unsupported op '{}'", self.op)),
+        }
+    }
+
+    fn apply_to_group(self, group: &mut Group) -> Result<(), String> {
+        let op = self.op.to_ascii_lowercase();
+        let path = self.path.as_deref().map(str::to_ascii_lowercase);
+        match (op.as_str(), path.as_deref()) {
+            ("add", Some("members")) => {
+                for id in member_ids(&self.value)? {
+                    group.add_member(&id);
+                }
+                Ok(())
+            }
+            ("replace", Some("members")) => {
+                group.members.clear();
+                for id in member_ids(&self.value)? {
+                    group.add_member(&id);
+                }
+                Ok(())
+            }
+            ("remove", Some("members")) => {
+                if self.value.is_null() {
+                    group.members.clear();
+                } else {
+                    for id in member_ids(&self.value)? {
+                        group.remove_member(&id);
+                    }
+                }
+                Ok(())
+            }
+            ("remove", Some(p)) if p.starts_with("members[") => {
+                // members[value eq "user_..."]
+                let id = self
+                    .path
+                    .as_deref()
+                    .and_then(|p| p.strip_prefix("members[")?.strip_suffix(']'))
+                    .and_then(parse_filter)
+                    .filter(|f| f.attr == "value")
+                    .ok_or_else(|| format!("unsupported path '{p}'"))?;
+                group.remove_member(&id.value);
+                Ok(())
+            }
+            ("add" | "replace", Some(attr)) => set_group_attr(group, attr, &self.value),
+            ("add" | "replace", None) => {
+                let Value::Object(attrs) = &self.value else {
+                    return Err("value must be an object when path is omitted".to_string());
+                };
+                for (attr, value) in attrs {
+                    let attr = attr.to_ascii_lowercase();
+                    if attr == "members" {
+                        for id in member_ids(value)? {
+                            group.add_member(&id);
+                        }
+                    } else {
+                        set_group_attr(group, &attr, value)?;
+                    }
+                }
+                Ok(())
+            }
+            ("remove", Some("externalid")) => {
+                group.external_id = None;
+                Ok(())
+            }
+            _ => Err(format!("unsupported op '{}'", self.op)),
+        }
+    }
+}
+
+fn set_user_attr(user: &mut User, attr: &str, value: &Value) -> Result<(), String> {
+    match attr.to_ascii_lowercase().as_str() {
+        "active" => user.active = bool_value(value)?,
+        "username" => user.user_name = string_value(attr, value)?,
+        "displayname" => user.display_name = Some(string_value(attr, value)?),
+        "externalid" => user.external_id = Some(string_value(attr, value)?),
+        "emails" => {
+            user.emails = serde_json::from_value::<Vec<MultiValue>>(value.clone())
+                .map_err(|_| "emails must be a list of {\"value\": ...}".to_string())?
+                .into_iter()
+                .map(|e| e.value)
+                .collect();
+        }
+        _ => {}
+    }
+    Ok(())
+}
+
+fn set_group_attr(group: &mut Group, attr: &str, value: &Value) -> Result<(), String> {
+    match attr {
+        "displayname" => group.display_name = string_value(attr, value)?,
+        "externalid" => group.external_id = Some(string_value(attr, value)?),
+        _ => {}
+    }
+    Ok(())
+}
+
+/// Accepts booleans and the `"True"`/`"False"` strings some providers send.
+fn bool_value(value: &Value) -> Result<bool, String> {
+    match value {
+        Value::Bool(b) => Ok(*b),
+        Value::String(s) if s.eq_ignore_ascii_case("true") => Ok(true),
+        Value::String(s) if s.eq_ignore_ascii_case("false") => Ok(false),
+        _ => Err("active must be a boolean".to_string()),
+    }
+}
+
+fn string_value(attr: &str, value: &Value) -> Result<String, String> {
+    value
+        .as_str()
+        .map(str::to_string)
+        .ok_or_else(|| format!("{attr} must be a string"))
+}
+
+fn member_ids(value: &Value) -> Result<Vec<String>, String> {
+    serde_json::from_value::<Vec<MultiValue>>(value.clone())
+        .map(|members| members.into_iter().map(|m| m.value).collect())
+        .map_err(|_| "members must be a list of {\"value\": ...}".to_string())
+}
+
+// ============================================================================
+// Discovery
+// ============================================================================
+
+/// GET /scim/v2/ServiceProviderConfig
+pub async fn service_provider_config() -> Response {
+    scim_json(
+        StatusCode::OK,
+        serde_json::json!({
+            "schemas": [SERVICE_PROVIDER_SCHEMA],
+            "patch": { "supported": true },
+            "bulk": { "supported": false, "maxOperations": 0, "maxPayloadSize": 0 },
+            "filter": { "supported": true, "maxResults": MAX_RESULTS },
+            "changePassword": { "supported": false },
+            "sort": { "supported": false },
+            "etag": { "supported": false },
+            "authenticationSchemes": [{
+                "type": "oauthbearertoken",
+                "name": "Bearer token",
+                "description": "The token configured as scim.token",
+            }],
+        }),
+    )
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn op(op: &str, path: Option<&str>, value: Value) -> PatchOperation {
+        PatchOperation {
+            op: op.to_string(),
+            path: path.map(str::to_string),
+            value,
+        }
+    }
+
+    #[test]
+    fn parses_eq_filters() {
+        let f = parse_filter(r#"userName eq "ada@example.com""#).unwrap();
+        assert_eq!(f.attr, "username");
+        assert_eq!(f.value, "ada@example.com");
+        assert!(parse_filter(r#"userName sw "ada""#).is_none());
+        assert!(parse_filter("userName eq ada").is_none());
+    }
+
+    #[test]
+    fn patches_users_in_provider_dialects() {
+        let mut user = User::new("ada".to_string());
+
+        // Entra ID: path and string booleans
+        op("Replace", Some("active"), Value::from("False"))
+            .apply_to_user(&mut user)
+            .unwrap();
+        assert!(!user.active);
+
+        // Okta: value object without a path
+        op(
+            "replace",
+            None,
+            serde_json::json!({"active": true, "displayName": "Ada", "title": "ignored"}),
+        )
+        .apply_to_user(&mut user)
+        .unwrap();
+        assert!(user.active);
+        assert_eq!(user.display_name.as_deref(), Some("Ada"));
+
+        assert!(
+            op("move", Some("active"), Value::Null)
+                .apply_to_user(&mut user)
+                .is_err()
+        );
+    }
+
+    #[test]
+    fn patches_group_members() {
+        let mut group = Group::new("support".to_string());
+        op(
+            "add",
+            Some("members"),
+            serde_json::json!([{"value": "user_a"}, {"value": "user_b"}]),
+        )
+        .apply_to_group(&mut group)
+        .unwrap();
+        assert_eq!(group.members, vec!["user_a", "user_b"]);
+
+        op("remove", Some(r#"members[value eq "user_a"]"#), Value::Null)
+            .apply_to_group(&mut group)
+            .unwrap();
+        assert_eq!(group.members, vec!["user_b"]);
+
+        op("replace", None, serde_json::json!({"displayName": "help"}))
+            .apply_to_group(&mut group)
+            .unwrap();
+        assert_eq!(group.display_name, "help");
+    }
+}
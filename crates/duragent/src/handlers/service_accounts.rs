@@ -0,0 +1,263 @@
+//! Service account admin handlers.
+//!
+//! Service accounts are issued, updated, rotated, and revoked here; see
+//! [`crate::service_accounts`] for scopes and token handling.
+
+use std::net::SocketAddr;
+
+use axum::Json;
+use axum::extract::{ConnectInfo, Path, Query, State};
+use axum::http::{HeaderMap, StatusCode};
+use axum::response::{IntoResponse, Response};
+use chrono::TimeDelta;
+use serde::Deserialize;
+use tracing::{error, info};
+
+use super::api_auth;
+use super::problem_details::{self, FieldError};
+use super::validation::ValidJson;
+use crate::api::{
+    CreateServiceAccountRequest, ListServiceAccountsResponse, ServiceAccountResponse,
+    ServiceAccountTokenResponse, UpdateServiceAccountRequest,
+};
+use crate::audit::{AuditEvent, AuditOutcome};
+use crate::server::AppState;
+use crate::service_accounts::{Scope, ServiceAccount};
+
+/// GET /api/admin/v1/service-accounts
+pub async fn list_service_accounts(
+    State(state): State<AppState>,
+    ConnectInfo(addr): ConnectInfo<SocketAddr>,
+    headers: HeaderMap,
+) -> Response {
+    if !api_auth::is_authorized(&state.admin_token, &addr, &headers) {
+        return api_auth::admin_denied(&state, &addr);
+    }
+
+    let service_accounts = state
+        .service_accounts
+        .list()
+        .iter()
+        .map(account_response)
+        .collect();
+    (
+        StatusCode::OK,
+        Json(ListServiceAccountsResponse { service_accounts }),
+    )
+        .into_response()
+}
+
+/// POST /api/admin/v1/service-accounts
+///
+/// Returns the new account with its token. The token is not shown again.
+pub async fn create_service_account(
+    State(state): State<AppState>,
+    ConnectInfo(addr): ConnectInfo<SocketAddr>,
+    headers: HeaderMap,
+    ValidJson(req): ValidJson<CreateServiceAccountRequest>,
+) -> Response {
+    if !api_auth::is_authorized(&state.admin_token, &addr, &headers) {
+        return api_auth::admin_denied(&state, &addr);
+    }
+    let scopes = match parse_scopes(&req.scopes) {
+        Ok(scopes) => scopes,
+        Err(problem) => return problem,
+    };
+    if state
+        .service_accounts
+        .list()
+        .iter()
+        .any(|a| a.name == req.name)
+    {
+        return problem_details::conflict(format!("service account '{}' already exists", req.name))
+            .into_response();
+    }
+
+    match state
+        .service_accounts
+        .create(req.name, req.description, scopes)
+        .await
+    {
+        Ok((account, token)) => {
+            info!(service_account = %account.name, id = %account.id, "Created service account");
+            audit(&state, &addr, "service_account.created", &account);
+            token_response(StatusCode::CREATED, &account, token)
+        }
+        Err(e) => {
+            error!(error = %e, "failed to create service account");
+            problem_details::internal_error("failed to create service account").into_response()
+        }
+    }
+}
+
+/// GET /api/admin/v1/service-accounts/{id}
+pub async fn get_service_account(
+    State(state): State<AppState>,
+    ConnectInfo(addr): ConnectInfo<SocketAddr>,
+    headers: HeaderMap,
+    Path(id): Path<String>,
+) -> Response {
+    if !api_auth::is_authorized(&state.admin_token, &addr, &headers) {
+        return api_auth::admin_denied(&state, &addr);
+    }
+    match state.service_accounts.get(&id) {
+        Some(account) => (StatusCode::OK, Json(account_response(&account))).into_response(),
+        None => not_found(&id),
+    }
+}
+
+/// PUT /api/admin/v1/service-accounts/{id}
+///
+/// Replaces the description and scopes. The token is unchanged.
+pub async fn update_service_account(
+    State(state): State<AppState>,
+    ConnectInfo(addr): ConnectInfo<SocketAddr>,
+    headers: HeaderMap,
+    Path(id): Path<String>,
+    ValidJson(req): ValidJson<UpdateServiceAccountRequest>,
+) -> Response {
+    if !api_auth::is_authorized(&state.admin_token, &addr, &headers) {
+        return api_auth::admin_denied(&state, &addr);
+    }
+    let scopes = match parse_scopes(&req.scopes) {
+        Ok(scopes) => scopes,
+        Err(problem) => return problem,
+    };
+
+    match state
+        .service_accounts
+        .update(&id, req.description, scopes)
+        .await
+    {
+        Ok(Some(account)) => {
+            audit(&state, &addr, "service_account.updated", &account);
+            (StatusCode::OK, Json(account_response(&account))).into_response()
+        }
+        Ok(None) => not_found(&id),
+        Err(e) => {
+            error!(id = %id, error = %e, "failed to update service account");
+            problem_details::internal_error("failed to update service account").into_response()
+        }
+    }
+}
+
+#[derive(Debug, Deserialize)]
+pub struct RotateQuery {
+    /// Seconds the previous token keeps working (default: 0).
+    #[serde(default)]
+    grace_seconds: u64,
+}
+
+/// POST /api/admin/v1/service-accounts/{id}/rotate
+///
+/// Issues a new token. With `?grace_seconds=N`, the previous token keeps
+/// working for N more seconds so clients can switch without downtime.
+pub async fn rotate_service_account(
+    State(state): State<AppState>,
+    ConnectInfo(addr): ConnectInfo<SocketAddr>,
+    headers: HeaderMap,
+    Path(id): Path<String>,
+    Query(query): Query<RotateQuery>,
+) -> Response {
+    if !api_auth::is_authorized(&state.admin_token, &addr, &headers) {
+        return api_auth::admin_denied(&state, &addr);
+    }
+
+    let grace = TimeDelta::seconds(query.grace_seconds.min(i64::MAX as u64) as i64);
+    match state.service_accounts.rotate(&id, grace).await {
+        Ok(Some((account, token))) => {
+            info!(service_account = %account.name, id = %account.id, "Rotated service account token");
+            audit(&state, &addr, "service_account.rotated", &account);
+            token_response(StatusCode::OK, &account, token)
+        }
+        Ok(None) => not_found(&id),
+        Err(e) => {
+            error!(id = %id, error = %e, "failed to rotate service account");
+            problem_details::internal_error("failed to rotate service account").into_response()
+        }
+    }
+}
+
+/// DELETE /api/admin/v1/service-accounts/{id}
+///
+/// Revokes the account; its tokens stop working immediately.
+pub async fn delete_service_account(
+    State(state): State<AppState>,
+    ConnectInfo(addr): ConnectInfo<SocketAddr>,
+    headers: HeaderMap,
+    Path(id): Path<String>,
+) -> Response {
+    if !api_auth::is_authorized(&state.admin_token, &addr, &headers) {
+        return api_auth::admin_denied(&state, &addr);
+    }
+
+    match state.service_accounts.delete(&id).await {
+        Ok(true) => {
+            info!(id = %id, "Deleted service account");
+            api_auth::audit_admin(
+                &state,
+                &addr,
+                AuditEvent::new("service_account.deleted", AuditOutcome::Success).target(&id),
+            );
+            StatusCode::NO_CONTENT.into_response()
+        }
+        Ok(false) => not_found(&id),
+        Err(e) => {
+            error!(id = %id, error = %e, "failed to delete service account");
+            problem_details::internal_error("failed to delete service account").into_response()
+        }
+    }
+}
+
+fn parse_scopes(scopes: &[String]) -> Result<Vec<Scope>, Response> {
+    let mut parsed = Vec::new();
+    let mut errors = Vec::new();
+    for (i, scope) in scopes.iter().enumerate() {
+        match scope.parse() {
+            Ok(scope) => parsed.push(scope),
+            Err(e) => errors.push(FieldError::new(format!("/scopes/{i}"), e)),
+        }
+    }
+    if errors.is_empty() {
+        Ok(parsed)
+    } else {
+        Err(problem_details::validation_failed(errors).into_response())
+    }
+}
+
+fn audit(state: &AppState, addr: &SocketAddr, action: &str, account: &ServiceAccount) {
+    api_auth::audit_admin(
+        state,
+        addr,
+        AuditEvent::new(action, AuditOutcome::Success)
+            .target(&account.id)
+            .detail(format!("name={}", account.name)),
+    );
+}
+
+fn not_found(id: &str) -> Response {
+    problem_details::not_found(format!("service account '{id}' not found")).into_response()
+}
+
+fn account_response(account: &ServiceAccount) -> ServiceAccountResponse {
+    ServiceAccountResponse {
+        id: account.id.clone(),
+        name: account.name.clone(),
+        description: account.description.clone(),
+        scopes: account.scopes.iter().map(Scope::to_string).collect(),
+        created_at: account.created_at.to_rfc3339(),
+        rotated_at: account.rotated_at.to_rfc3339(),
+        previous_token_expires_at: account.previous_token_expires_at.map(|at| at.to_rfc3339()),
+    }
+}
+
+fn token_response(status: StatusCode, account: &ServiceAccount, token: String) -> Response {
+    (
+        status,
+        Json(ServiceAccountTokenResponse {
+            service_account: account_response(account),
+            token,
+        }),
+    )
+        .into_response()
+}
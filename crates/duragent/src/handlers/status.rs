@@ -0,0 +1,249 @@
+//! Status page handler.
+//!
+//! Summarizes the dependency health history for people rather than tools:
+//! component status, incidents, and uptime. Error details stay on the admin
+//! health history endpoint, since the page may be public.
+
+use std::fmt::Write;
+use std::net::SocketAddr;
+
+use axum::extract::{ConnectInfo, State};
+use axum::http::{HeaderMap, StatusCode, header};
+use axum::response::{Html, IntoResponse, Response};
+use chrono::{Duration, Utc};
+
+use super::format::{APPLICATION_JSON, ResponseFormat};
+use super::{api_auth, problem_details};
+use crate::api::{
+    DependencyStatus, OverallStatus, StatusComponent, StatusIncident, StatusPageResponse,
+};
+use crate::health::{self, DependencyHealth};
+use crate::server::AppState;
+
+/// GET /status
+///
+/// HTML status page, or JSON when the client asks for `application/json`.
+///
+/// Authorization: none when `health.status_page.public` is set, otherwise
+/// the API token.
+pub async fn status_page(
+    State(state): State<AppState>,
+    ConnectInfo(addr): ConnectInfo<SocketAddr>,
+    headers: HeaderMap,
+) -> Response {
+    let config = &state.status_page;
+    if !config.enabled {
+        return problem_details::not_found("status page is disabled").into_response();
+    }
+    if !config.public && !api_auth::is_authorized(&state.api_token, &addr, &headers) {
+        return problem_details::unauthorized("invalid or missing API token").into_response();
+    }
+
+    let status = build_status(&config.title, state.health.summaries());
+    if wants_json(&headers) {
+        return ResponseFormat::Json.respond(StatusCode::OK, &status);
+    }
+    Html(render_html(&status)).into_response()
+}
+
+// ============================================================================
+// Helper Functions
+// ============================================================================
+
+/// JSON only when asked for explicitly; browsers send `*/*` and get HTML.
+fn wants_json(headers: &HeaderMap) -> bool {
+    headers
+        .get(header::ACCEPT)
+        .and_then(|v| v.to_str().ok())
+        .is_some_and(|accept| accept.contains(APPLICATION_JSON))
+}
+
+fn build_status(title: &str, dependencies: Vec<DependencyHealth>) -> StatusPageResponse {
+    let now = Utc::now();
+    let last_hour = now - Duration::hours(1);
+    let last_day = now - Duration::hours(24);
+
+    let mut components = Vec::with_capacity(dependencies.len());
+    let mut active_incidents = Vec::new();
+    let mut recent_incidents = Vec::new();
+    for dependency in &dependencies {
+        for incident in &dependency.incidents {
+            let entry = StatusIncident {
+                component: dependency.name.clone(),
+                started_at: incident.started_at.to_rfc3339(),
+                ended_at: incident.ended_at.map(|t| t.to_rfc3339()),
+            };
+            match incident.ended_at {
+                None => active_incidents.push(entry),
+                Some(ended_at) if ended_at >= last_day => recent_incidents.push(entry),
+                Some(_) => {}
+            }
+        }
+        components.push(StatusComponent {
+            name: dependency.name.clone(),
+            status: match dependency.status {
+                health::DependencyStatus::Up => DependencyStatus::Up,
+                health::DependencyStatus::Down => DependencyStatus::Down,
+                health::DependencyStatus::Unknown => DependencyStatus::Unknown,
+            },
+            uptime_1h: dependency.uptime_since(last_hour),
+            uptime_24h: dependency.uptime_since(last_day),
+        });
+    }
+    // RFC 3339 timestamps in UTC sort chronologically as strings.
+    active_incidents.sort_by(|a, b| b.started_at.cmp(&a.started_at));
+    recent_incidents.sort_by(|a, b| b.started_at.cmp(&a.started_at));
+
+    StatusPageResponse {
+        title: title.to_string(),
+        status: overall_status(&components),
+        components,
+        active_incidents,
+        recent_incidents,
+        updated_at: now.to_rfc3339(),
+    }
+}
+
+fn overall_status(components: &[StatusComponent]) -> OverallStatus {
+    let checked = components
+        .iter()
+        .filter(|c| c.status != DependencyStatus::Unknown)
+        .count();
+    let down = components
+        .iter()
+        .filter(|c| c.status == DependencyStatus::Down)
+        .count();
+    match down {
+        0 => OverallStatus::Operational,
+        n if n == checked => OverallStatus::Outage,
+        _ => OverallStatus::Degraded,
+    }
+}
+
+fn render_html(status: &StatusPageResponse) -> String {
+    let title = escape_html(&status.title);
+    let (banner, color) = match status.status {
+        OverallStatus::Operational => ("All systems operational", "#1a7f37"),
+        OverallStatus::Degraded => ("Some systems degraded", "#bf8700"),
+        OverallStatus::Outage => ("Major outage", "#cf222e"),
+    };
+
+    let mut out = String::new();
+    let _ = write!(
+        out,
+        "<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n<meta charset=\"utf-8\">\n\
+         <meta name=\"viewport\" content=\"width=device-width, initial-scale=1\">\n\
+         <meta http-equiv=\"refresh\" content=\"60\">\n<title>{title}</title>\n<style>\n\
+         body{{font-family:system-ui,sans-serif;max-width:48rem;margin:2rem auto;padding:0 1rem;color:#1f2328}}\n\
+         .banner{{padding:1rem;border-radius:6px;color:#fff;background:{color};font-weight:600}}\n\
+         table{{width:100%;border-collapse:collapse;margin:1rem 0}}\n\
+         th,td{{text-align:left;padding:.5rem;border-bottom:1px solid #d0d7de}}\n\
+         .up{{color:#1a7f37}}.down{{color:#cf222e}}.unknown{{color:#656d76}}\n\
+         footer{{color:#656d76;font-size:.875rem}}\n\
+         </style>\n</head>\n<body>\n<h1>{title}</h1>\n<div class=\"banner\">{banner}</div>\n"
+    );
+
+    out.push_str("<h2>Components</h2>\n");
+    if status.components.is_empty() {
+        out.push_str("<p>No checks have run yet.</p>\n");
+    } else {
+        out.push_str(
+            "<table>\n<tr><th>Component</th><th>Status</th><th>Uptime (1h)</th><th>Uptime (24h)</th></tr>\n",
+        );
+        for component in &status.components {
+            let (class, label) = match component.status {
+                DependencyStatus::Up => ("up", "Operational"),
+                DependencyStatus::Down => ("down", "Down"),
+                DependencyStatus::Unknown => ("unknown", "Unknown"),
+            };
+            let _ = writeln!(
+                out,
+                "<tr><td>{}</td><td class=\"{class}\">{label}</td><td>{}</td><td>{}</td></tr>",
+                escape_html(&component.name),
+                format_uptime(component.uptime_1h),
+                format_uptime(component.uptime_24h),
+            );
+        }
+        out.push_str("</table>\n");
+    }
+
+    out.push_str("<h2>Active incidents</h2>\n");
+    render_incidents(&mut out, &status.active_incidents, "No active incidents.");
+    out.push_str("<h2>Resolved in the last 24 hours</h2>\n");
+    render_incidents(&mut out, &status.recent_incidents, "No recent incidents.");
+
+    let _ = write!(
+        out,
+        "<footer>Updated {}</footer>\n</body>\n</html>\n",
+        escape_html(&status.updated_at)
+    );
+    out
+}
+
+fn render_incidents(out: &mut String, incidents: &[StatusIncident], empty: &str) {
+    if incidents.is_empty() {
+        let _ = writeln!(out, "<p>{empty}</p>");
+        return;
+    }
+    out.push_str("<ul>\n");
+    for incident in incidents {
+        let _ = write!(
+            out,
+            "<li><strong>{}</strong> down since {}",
+            escape_html(&incident.component),
+            escape_html(&incident.started_at)
+        );
+        if let Some(ended_at) = &incident.ended_at {
+            let _ = write!(out, ", resolved {}", escape_html(ended_at));
+        }
+        out.push_str("</li>\n");
+    }
+    out.push_str("</ul>\n");
+}
+
+fn format_uptime(uptime: Option<f64>) -> String {
+    uptime.map_or("—".to_string(), |u| format!("{:.2}%", u * 100.0))
+}
+
+fn escape_html(s: &str) -> String {
+    s.replace('&', "&amp;")
+        .replace('<', "&lt;")
+        .replace('>', "&gt;")
+        .replace('"', "&quot;")
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn component(status: DependencyStatus) -> StatusComponent {
+        StatusComponent {
+            name: "store".to_string(),
+            status,
+            uptime_1h: None,
+            uptime_24h: None,
+        }
+    }
+
+    #[test]
+    fn overall_status_ignores_unchecked_components() {
+        use DependencyStatus::{Down, Unknown, Up};
+        assert_eq!(overall_status(&[]), OverallStatus::Operational);
+        assert_eq!(
+            overall_status(&[component(Up), component(Down)]),
+            OverallStatus::Degraded
+        );
+        assert_eq!(
+            overall_status(&[component(Down), component(Unknown)]),
+            OverallStatus::Outage
+        );
+    }
+
+    #[test]
+    fn html_escapes_title() {
+        let status = build_status("<Acme> & Co", Vec::new());
+        let html = render_html(&status);
+        assert!(html.contains("<title>&lt;Acme&gt; &amp; Co</title>"));
+        assert!(html.contains("All systems operational"));
+    }
+}
@@ -0,0 +1,141 @@
+//! Per-route request timeouts.
+//!
+//! Regular API routes must respond within `server.request_timeout_seconds`;
+//! `server.route_timeouts` overrides that per route template (e.g.
+//! `/api/v1/sessions/{session_id}/messages`), and `0` removes the limit.
+//! Streaming routes are never wrapped: a stream runs as long as its provider
+//! keeps producing, bounded by the idle timeout instead.
+
+use std::collections::HashMap;
+use std::sync::Arc;
+use std::time::Duration;
+
+use axum::body::Body;
+use axum::extract::{MatchedPath, State};
+use axum::http::{Request, StatusCode};
+use axum::middleware::Next;
+use axum::response::{IntoResponse, Response};
+
+/// Route timeout overrides; cheap to clone.
+#[derive(Clone, Default)]
+pub struct RouteTimeouts {
+    inner: Arc<RouteTimeoutsInner>,
+    /// Limit for routes without an override (`None` = unlimited).
+    default: Option<Duration>,
+}
+
+#[derive(Default)]
+struct RouteTimeoutsInner {
+    base_path: String,
+    routes: HashMap<String, Option<Duration>>,
+}
+
+/// `0` seconds means no limit.
+fn limit(seconds: u64) -> Option<Duration> {
+    (seconds > 0).then(|| Duration::from_secs(seconds))
+}
+
+impl RouteTimeouts {
+    /// Build from `server.route_timeouts`. `base_path` is stripped before
+    /// matching route overrides.
+    pub fn new(routes: &HashMap<String, u64>, base_path: &str) -> Self {
+        Self {
+            inner: Arc::new(RouteTimeoutsInner {
+                base_path: base_path.to_string(),
+                routes: routes
+                    .iter()
+                    .map(|(route, &seconds)| (route.clone(), limit(seconds)))
+                    .collect(),
+            }),
+            default: None,
+        }
+    }
+
+    /// The same overrides, with `seconds` for every other route.
+    pub fn with_default(mut self, seconds: u64) -> Self {
+        self.default = limit(seconds);
+        self
+    }
+
+    fn timeout_for(&self, route: &str) -> Option<Duration> {
+        let route = route.strip_prefix(&*self.inner.base_path).unwrap_or(route);
+        match self.inner.routes.get(route) {
+            Some(&timeout) => timeout,
+            None => self.default,
+        }
+    }
+}
+
+/// Middleware that answers `408 Request Timeout` when a route's handler
+/// outlives its limit.
+pub async fn enforce_timeouts(
+    State(timeouts): State<RouteTimeouts>,
+    request: Request<Body>,
+    next: Next,
+) -> Response {
+    let timeout = request
+        .extensions()
+        .get::<MatchedPath>()
+        .and_then(|route| timeouts.timeout_for(route.as_str()));
+    let Some(timeout) = timeout else {
+        return next.run(request).await;
+    };
+
+    match tokio::time::timeout(timeout, next.run(request)).await {
+        Ok(response) => response,
+        Err(_elapsed) => StatusCode::REQUEST_TIMEOUT.into_response(),
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn timeouts(routes: &[(&str, u64)], base_path: &str) -> RouteTimeouts {
+        let routes = routes
+            .iter()
+            .map(|&(route, seconds)| (route.to_string(), seconds))
+            .collect();
+        RouteTimeouts::new(&routes, base_path).with_default(300)
+    }
+
+    #[test]
+    fn routes_without_override_use_default() {
+        let timeouts = timeouts(&[], "");
+        assert_eq!(
+            timeouts.timeout_for("/api/v1/agents"),
+            Some(Duration::from_secs(300))
+        );
+    }
+
+    #[test]
+    fn overrides_replace_or_remove_the_limit() {
+        let timeouts = timeouts(
+            &[
+                ("/api/v1/sessions/{session_id}/messages", 900),
+                ("/api/v1/agents/bulk", 0),
+            ],
+            "",
+        );
+        assert_eq!(
+            timeouts.timeout_for("/api/v1/sessions/{session_id}/messages"),
+            Some(Duration::from_secs(900))
+        );
+        assert_eq!(timeouts.timeout_for("/api/v1/agents/bulk"), None);
+    }
+
+    #[test]
+    fn base_path_is_stripped() {
+        let timeouts = timeouts(&[("/api/v1/usage", 10)], "/duragent");
+        assert_eq!(
+            timeouts.timeout_for("/duragent/api/v1/usage"),
+            Some(Duration::from_secs(10))
+        );
+    }
+
+    #[test]
+    fn zero_default_is_unlimited() {
+        let timeouts = RouteTimeouts::default().with_default(0);
+        assert_eq!(timeouts.timeout_for("/api/v1/agents"), None);
+    }
+}
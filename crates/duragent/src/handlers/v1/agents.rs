@@ -1,31 +1,86 @@
 //! Agent management HTTP handlers.
 
-use axum::Json;
-use axum::extract::{Path, State};
-use axum::http::StatusCode;
-use axum::response::IntoResponse;
+use std::collections::HashSet;
+use std::net::SocketAddr;
+use std::path::{Component, PathBuf};
 
+use axum::extract::{ConnectInfo, Path, Query, State};
+use axum::http::{HeaderMap, StatusCode, header};
+use axum::response::{IntoResponse, Response};
+use axum::{Extension, Json};
+use serde::Deserialize;
+use tracing::{error, warn};
+
+use crate::agent::{
+    AgentQuery, AgentSpec, ChangeSource, LabelSelector, LoadedAgentFiles, ToolPolicy,
+    parse_agent_file_refs, parse_agent_yaml,
+};
 use crate::api::{
     AgentDetailResponse, AgentMetadataResponse, AgentModelResponse, AgentSpecResponse,
-    AgentSummary, ListAgentsResponse,
+    AgentSummary, BulkAgentOperation, BulkAgentResult, BulkAgentStatus, BulkAgentsRequest,
+    BulkAgentsResponse, CreateAgentRequest, ListAgentsResponse, ListTrashedAgentsResponse,
+    TrashedAgentSummary,
 };
+use crate::handlers::api_auth::{self, ServiceAccountPrincipal};
+use crate::handlers::format::ResponseFormat;
 use crate::handlers::problem_details;
+use crate::handlers::validation::ValidJson;
 use crate::server::AppState;
+use crate::store::file::{
+    AgentChange, ChangeAuthor, FileAgentCatalog, PROVENANCE_FILE, is_valid_agent_name,
+};
+use crate::store::{AgentCatalog, ProjectStore, StorageError};
+
+/// Manifest file name inside an agent directory.
+const AGENT_YAML: &str = "agent.yaml";
+
+#[derive(Deserialize)]
+pub struct ListAgentsQuery {
+    /// Full-text search over name, description, tags, and labels.
+    q: Option<String>,
+    /// Label selector, e.g. `team=ml,env!=dev`.
+    selector: Option<String>,
+    /// Only agents in this project.
+    project: Option<String>,
+}
+
+/// GET /api/v1/agents
+///
+/// Answered from the in-memory agent index; filtering never rescans the
+/// agents directory.
+pub async fn list_agents(
+    State(state): State<AppState>,
+    Query(query): Query<ListAgentsQuery>,
+    format: ResponseFormat,
+) -> Response {
+    let selector = match parse_selector(query.selector.as_deref()) {
+        Ok(selector) => selector,
+        Err(response) => return response,
+    };
+    let query = AgentQuery {
+        text: query.q,
+        selector,
+        project: query.project,
+    };
 
-pub async fn list_agents(State(state): State<AppState>) -> Json<ListAgentsResponse> {
     let agents: Vec<AgentSummary> = state
         .services
         .agents
-        .snapshot()
+        .search(&query)
         .into_iter()
-        .map(|(_, spec)| AgentSummary {
+        .map(|spec| AgentSummary {
             name: spec.metadata.name.clone(),
             description: spec.metadata.description.clone(),
             version: spec.metadata.version.clone(),
+            enabled: spec.enabled,
+            labels: spec.metadata.labels.clone(),
+            tags: spec.metadata.tags.clone(),
+            project: spec.metadata.project.clone(),
+            provenance: spec.provenance.clone(),
         })
         .collect();
 
-    Json(ListAgentsResponse { agents })
+    format.respond_list(agents, |agents| ListAgentsResponse { agents })
 }
 
 pub async fn get_agent(
@@ -33,17 +88,371 @@ pub async fn get_agent(
     Path(name): Path<String>,
 ) -> impl IntoResponse {
     let Some(agent) = state.services.agents.get(&name) else {
-        return problem_details::not_found(format!("agent '{name}' not found")).into_response();
+        return problem_details::agent_not_found(&name)
+            .with_instance(agent_url(&state, &name))
+            .into_response();
+    };
+
+    (StatusCode::OK, Json(agent_detail(&agent))).into_response()
+}
+
+/// POST /api/v1/agents
+///
+/// Create an agent from its manifest and files. The agent directory is
+/// written the same way as a bulk `create` operation, and the agent is
+/// servable as soon as the response is returned.
+///
+/// Authorization: same as the admin endpoints, or a scoped service account.
+pub async fn create_agent(
+    State(state): State<AppState>,
+    ConnectInfo(addr): ConnectInfo<SocketAddr>,
+    headers: HeaderMap,
+    service_account: Option<Extension<ServiceAccountPrincipal>>,
+    ValidJson(req): ValidJson<CreateAgentRequest>,
+) -> Response {
+    if !api_auth::has_scoped_access(&state, &addr, &headers) {
+        return api_auth::admin_denied(&state, &addr);
+    }
+
+    let catalog = FileAgentCatalog::new(&state.agents_dir, state.workspace_dir.clone());
+    if is_valid_agent_name(&req.name) && catalog.exists(&req.name).await {
+        return problem_details::conflict(format!("agent '{}' already exists", req.name))
+            .with_instance(agent_url(&state, &req.name))
+            .into_response();
+    }
+
+    let author = api_author(&state, service_account);
+    let name = req.name.clone();
+    let op = BulkAgentOperation::Create {
+        name: req.name,
+        manifest: req.manifest,
+        files: req.files,
+    };
+    let change = match plan_operation(&state, &catalog, &op, &author, &mut HashSet::new()).await {
+        Ok(change) => change,
+        Err(e) => return problem_details::bad_request(e).into_response(),
     };
 
-    let response = AgentDetailResponse {
+    if let Err(e) = catalog.apply_changes(&[change]).await {
+        error!(agent = %name, error = %e, "failed to create agent");
+        return problem_details::internal_error("failed to create agent").into_response();
+    }
+    let spec = match catalog.load(&name).await {
+        Ok(spec) => spec,
+        Err(e) => {
+            error!(agent = %name, error = %e, "failed to load agent after create");
+            return problem_details::internal_error("failed to load created agent").into_response();
+        }
+    };
+    state.services.models.warn_issues(&spec);
+    let response = agent_detail(&spec);
+    state.services.agents.upsert(spec);
+
+    (
+        StatusCode::CREATED,
+        [(header::LOCATION, agent_url(&state, &name))],
+        Json(response),
+    )
+        .into_response()
+}
+
+/// POST /api/v1/agents/bulk
+///
+/// Create, update, and delete many agents in one request. Every operation is
+/// validated before anything is written; if any operation is invalid, no
+/// changes are made and the response (422) marks the failing operations.
+///
+/// Authorization: same as the admin endpoints, or a scoped service account.
+pub async fn bulk_agents(
+    State(state): State<AppState>,
+    ConnectInfo(addr): ConnectInfo<SocketAddr>,
+    headers: HeaderMap,
+    service_account: Option<Extension<ServiceAccountPrincipal>>,
+    ValidJson(req): ValidJson<BulkAgentsRequest>,
+) -> Response {
+    if !api_auth::has_scoped_access(&state, &addr, &headers) {
+        return api_auth::admin_denied(&state, &addr);
+    }
+
+    let catalog = FileAgentCatalog::new(&state.agents_dir, state.workspace_dir.clone());
+    let author = api_author(&state, service_account);
+
+    // Validate every operation before touching disk
+    let mut seen = HashSet::new();
+    let mut changes = Vec::with_capacity(req.operations.len());
+    let mut errors = Vec::with_capacity(req.operations.len());
+    for op in &req.operations {
+        match plan_operation(&state, &catalog, op, &author, &mut seen).await {
+            Ok(change) => {
+                changes.push(change);
+                errors.push(None);
+            }
+            Err(e) => errors.push(Some(e)),
+        }
+    }
+
+    if errors.iter().any(Option::is_some) {
+        let results = req
+            .operations
+            .iter()
+            .zip(errors)
+            .enumerate()
+            .map(|(index, (op, error))| {
+                let status = if error.is_some() {
+                    BulkAgentStatus::Failed
+                } else {
+                    BulkAgentStatus::Skipped
+                };
+                bulk_result(index, op, status, error)
+            })
+            .collect();
+        let response = BulkAgentsResponse {
+            applied: false,
+            results,
+        };
+        return (StatusCode::UNPROCESSABLE_ENTITY, Json(response)).into_response();
+    }
+
+    if let Err(e) = catalog.apply_changes(&changes).await {
+        error!(error = %e, "failed to apply bulk agent changes");
+        return problem_details::internal_error(
+            "failed to apply agent changes; no changes were made",
+        )
+        .into_response();
+    }
+
+    // Refresh the loaded agents that changed
+    for change in &changes {
+        match change {
+            AgentChange::Write { name, .. } => match catalog.load(name).await {
+                Ok(spec) => {
+                    state.services.models.warn_issues(&spec);
+                    state.services.agents.upsert(spec);
+                }
+                Err(e) => warn!(agent = %name, error = %e, "Failed to load agent after bulk write"),
+            },
+            AgentChange::Delete { name } => {
+                state.services.agents.remove(name);
+            }
+        }
+    }
+
+    let results = req
+        .operations
+        .iter()
+        .enumerate()
+        .map(|(index, op)| {
+            let status = match op {
+                BulkAgentOperation::Create { .. } => BulkAgentStatus::Created,
+                BulkAgentOperation::Update { .. } => BulkAgentStatus::Updated,
+                BulkAgentOperation::Delete { .. } => BulkAgentStatus::Deleted,
+            };
+            bulk_result(index, op, status, None)
+        })
+        .collect();
+
+    (
+        StatusCode::OK,
+        Json(BulkAgentsResponse {
+            applied: true,
+            results,
+        }),
+    )
+        .into_response()
+}
+
+/// DELETE /api/v1/agents/{name}
+///
+/// Move an agent to the trash. It is no longer listed or invocable, and can be
+/// restored until it is purged.
+///
+/// Authorization: same as the admin endpoints, or a scoped service account.
+pub async fn delete_agent(
+    State(state): State<AppState>,
+    ConnectInfo(addr): ConnectInfo<SocketAddr>,
+    headers: HeaderMap,
+    Path(name): Path<String>,
+) -> Response {
+    if !api_auth::has_scoped_access(&state, &addr, &headers) {
+        return api_auth::admin_denied(&state, &addr);
+    }
+
+    let catalog = FileAgentCatalog::new(&state.agents_dir, state.workspace_dir.clone());
+    if !is_valid_agent_name(&name) || !catalog.exists(&name).await {
+        return problem_details::agent_not_found(&name)
+            .with_instance(agent_url(&state, &name))
+            .into_response();
+    }
+
+    if let Err(e) = catalog
+        .apply_changes(&[AgentChange::Delete { name: name.clone() }])
+        .await
+    {
+        error!(agent = %name, error = %e, "failed to move agent to trash");
+        return problem_details::internal_error("failed to delete agent").into_response();
+    }
+    state.services.agents.remove(&name);
+
+    StatusCode::NO_CONTENT.into_response()
+}
+
+/// POST /api/v1/agents/{name}/disable
+///
+/// Stop an agent from accepting new sessions, messages, and scheduled runs.
+/// The agent stays loaded and listed; the flag persists across restarts.
+///
+/// Authorization: same as the admin endpoints, or a scoped service account.
+pub async fn disable_agent(
+    State(state): State<AppState>,
+    ConnectInfo(addr): ConnectInfo<SocketAddr>,
+    headers: HeaderMap,
+    Path(name): Path<String>,
+) -> Response {
+    set_agent_enabled(state, addr, headers, name, false).await
+}
+
+/// POST /api/v1/agents/{name}/enable
+///
+/// Authorization: same as the admin endpoints, or a scoped service account.
+pub async fn enable_agent(
+    State(state): State<AppState>,
+    ConnectInfo(addr): ConnectInfo<SocketAddr>,
+    headers: HeaderMap,
+    Path(name): Path<String>,
+) -> Response {
+    set_agent_enabled(state, addr, headers, name, true).await
+}
+
+/// GET /api/v1/trash/agents
+///
+/// Authorization: same as the admin endpoints, or a scoped service account.
+pub async fn list_trashed_agents(
+    State(state): State<AppState>,
+    ConnectInfo(addr): ConnectInfo<SocketAddr>,
+    headers: HeaderMap,
+) -> Response {
+    if !api_auth::has_scoped_access(&state, &addr, &headers) {
+        return api_auth::admin_denied(&state, &addr);
+    }
+
+    let catalog = FileAgentCatalog::new(&state.agents_dir, state.workspace_dir.clone());
+    let trashed = match catalog.list_trash().await {
+        Ok(t) => t,
+        Err(e) => {
+            error!(error = %e, "failed to list agent trash");
+            return problem_details::internal_error("failed to list agent trash").into_response();
+        }
+    };
+
+    let retention = state.agent_trash_retention_hours;
+    let agents = trashed
+        .into_iter()
+        .map(|t| TrashedAgentSummary {
+            name: t.name,
+            deleted_at: t.deleted_at.to_rfc3339(),
+            purge_at: (retention > 0)
+                .then(|| (t.deleted_at + chrono::Duration::hours(retention as i64)).to_rfc3339()),
+        })
+        .collect();
+
+    (StatusCode::OK, Json(ListTrashedAgentsResponse { agents })).into_response()
+}
+
+/// POST /api/v1/trash/agents/{name}/restore
+///
+/// Authorization: same as the admin endpoints, or a scoped service account.
+pub async fn restore_agent(
+    State(state): State<AppState>,
+    ConnectInfo(addr): ConnectInfo<SocketAddr>,
+    headers: HeaderMap,
+    Path(name): Path<String>,
+) -> Response {
+    if !api_auth::has_scoped_access(&state, &addr, &headers) {
+        return api_auth::admin_denied(&state, &addr);
+    }
+    if !is_valid_agent_name(&name) {
+        return trashed_agent_not_found(&name);
+    }
+
+    let catalog = FileAgentCatalog::new(&state.agents_dir, state.workspace_dir.clone());
+    if catalog.exists(&name).await {
+        return problem_details::conflict(format!("an agent named '{name}' already exists"))
+            .into_response();
+    }
+
+    match catalog.restore(&name).await {
+        Ok(()) => {}
+        Err(StorageError::NotFound { .. }) => return trashed_agent_not_found(&name),
+        Err(e) => {
+            error!(agent = %name, error = %e, "failed to restore agent");
+            return problem_details::internal_error("failed to restore agent").into_response();
+        }
+    }
+
+    match catalog.load(&name).await {
+        Ok(spec) => {
+            state.services.models.warn_issues(&spec);
+            state.services.agents.upsert(spec);
+        }
+        Err(e) => warn!(agent = %name, error = %e, "Failed to load restored agent"),
+    }
+
+    StatusCode::NO_CONTENT.into_response()
+}
+
+/// DELETE /api/v1/trash/agents/{name}
+///
+/// Permanently delete a trashed agent.
+///
+/// Authorization: same as the admin endpoints, or a scoped service account.
+pub async fn purge_agent(
+    State(state): State<AppState>,
+    ConnectInfo(addr): ConnectInfo<SocketAddr>,
+    headers: HeaderMap,
+    Path(name): Path<String>,
+) -> Response {
+    if !api_auth::has_scoped_access(&state, &addr, &headers) {
+        return api_auth::admin_denied(&state, &addr);
+    }
+    if !is_valid_agent_name(&name) {
+        return trashed_agent_not_found(&name);
+    }
+
+    let catalog = FileAgentCatalog::new(&state.agents_dir, state.workspace_dir.clone());
+    match catalog.purge(&name).await {
+        Ok(()) => StatusCode::NO_CONTENT.into_response(),
+        Err(StorageError::NotFound { .. }) => trashed_agent_not_found(&name),
+        Err(e) => {
+            error!(agent = %name, error = %e, "failed to purge agent");
+            problem_details::internal_error("failed to purge agent").into_response()
+        }
+    }
+}
+
+// ============================================================================
+// Helper Functions
+// ============================================================================
+
+/// Parse an optional `selector` query parameter; a malformed one becomes a 400.
+pub(crate) fn parse_selector(raw: Option<&str>) -> Result<LabelSelector, Response> {
+    raw.unwrap_or_default()
+        .parse::<LabelSelector>()
+        .map_err(|e| problem_details::bad_request(e.to_string()).into_response())
+}
+
+/// An agent as returned by the detail endpoints.
+fn agent_detail(agent: &AgentSpec) -> AgentDetailResponse {
+    AgentDetailResponse {
         api_version: agent.api_version.clone(),
         kind: agent.kind.clone(),
+        enabled: agent.enabled,
         metadata: AgentMetadataResponse {
             name: agent.metadata.name.clone(),
             description: agent.metadata.description.clone(),
             version: agent.metadata.version.clone(),
             labels: agent.metadata.labels.clone(),
+            tags: agent.metadata.tags.clone(),
+            project: agent.metadata.project.clone(),
         },
         spec: AgentSpecResponse {
             model: AgentModelResponse {
@@ -56,8 +465,178 @@ pub async fn get_agent(
             },
             system_prompt: agent.system_prompt.clone(),
             instructions: agent.instructions.clone(),
+            input_schema: agent.input_schema.clone(),
         },
+        provenance: agent.provenance.clone(),
+    }
+}
+
+/// Public link to an agent resource.
+fn agent_url(state: &AppState, name: &str) -> String {
+    state
+        .external_url
+        .url_for(&format!("/api/v1/agents/{name}"))
+}
+
+async fn set_agent_enabled(
+    state: AppState,
+    addr: SocketAddr,
+    headers: HeaderMap,
+    name: String,
+    enabled: bool,
+) -> Response {
+    if !api_auth::has_scoped_access(&state, &addr, &headers) {
+        return api_auth::admin_denied(&state, &addr);
+    }
+    if !is_valid_agent_name(&name) {
+        return problem_details::agent_not_found(&name)
+            .with_instance(agent_url(&state, &name))
+            .into_response();
+    }
+
+    let catalog = FileAgentCatalog::new(&state.agents_dir, state.workspace_dir.clone());
+    match catalog.set_enabled(&name, enabled).await {
+        Ok(()) => {}
+        Err(StorageError::NotFound { .. }) => {
+            return problem_details::agent_not_found(&name)
+                .with_instance(agent_url(&state, &name))
+                .into_response();
+        }
+        Err(e) => {
+            error!(agent = %name, enabled, error = %e, "failed to update agent state");
+            return problem_details::internal_error("failed to update agent state").into_response();
+        }
+    }
+    state.services.agents.set_enabled(&name, enabled);
+
+    StatusCode::NO_CONTENT.into_response()
+}
+
+fn trashed_agent_not_found(name: &str) -> Response {
+    problem_details::not_found(format!("agent '{name}' is not in the trash")).into_response()
+}
+
+/// Validate one bulk operation and turn it into a catalog change.
+async fn plan_operation(
+    state: &AppState,
+    catalog: &FileAgentCatalog,
+    op: &BulkAgentOperation,
+    author: &ChangeAuthor,
+    seen: &mut HashSet<String>,
+) -> Result<AgentChange, String> {
+    let name = op.name();
+    if !is_valid_agent_name(name) {
+        return Err(format!("invalid agent name '{name}'"));
+    }
+    if !seen.insert(name.to_string()) {
+        return Err("agent appears more than once in this request".to_string());
+    }
+
+    let exists = catalog.exists(name).await;
+    let (manifest, files) = match op {
+        BulkAgentOperation::Create {
+            manifest, files, ..
+        } => {
+            if exists {
+                return Err("agent already exists".to_string());
+            }
+            (manifest, files)
+        }
+        BulkAgentOperation::Update {
+            manifest, files, ..
+        } => {
+            if !exists {
+                return Err("agent not found".to_string());
+            }
+            (manifest, files)
+        }
+        BulkAgentOperation::Delete { .. } => {
+            if !exists {
+                return Err("agent not found".to_string());
+            }
+            return Ok(AgentChange::Delete {
+                name: name.to_string(),
+            });
+        }
     };
 
-    (StatusCode::OK, Json(response)).into_response()
+    let refs = parse_agent_file_refs(manifest).map_err(|e| format!("invalid manifest: {e}"))?;
+    let project = match &refs.project {
+        Some(project) => catalog
+            .projects()
+            .load(project)
+            .await
+            .map_err(|e| format!("failed to load project '{project}': {e}"))?,
+        None => None,
+    };
+    let spec = parse_agent_yaml(
+        manifest,
+        LoadedAgentFiles::default(),
+        Vec::new(),
+        ToolPolicy::default(),
+        state.agents_dir.join(name),
+        project.as_ref(),
+    )
+    .map_err(|e| format!("invalid manifest: {e}"))?;
+    if spec.metadata.name != name {
+        return Err(format!(
+            "metadata.name '{}' does not match '{name}'",
+            spec.metadata.name
+        ));
+    }
+
+    let mut written = vec![(PathBuf::from(AGENT_YAML), manifest.clone())];
+    let mut extra: Vec<_> = files.iter().collect();
+    extra.sort_by(|a, b| a.0.cmp(b.0));
+    for (path, contents) in extra {
+        let rel = PathBuf::from(path);
+        let is_relative =
+            !path.is_empty() && rel.components().all(|c| matches!(c, Component::Normal(_)));
+        if !is_relative {
+            return Err(format!("invalid file path '{path}'"));
+        }
+        if rel == std::path::Path::new(AGENT_YAML) {
+            return Err("agent.yaml must be provided as `manifest`".to_string());
+        }
+        if rel == std::path::Path::new(PROVENANCE_FILE) {
+            return Err(format!("'{PROVENANCE_FILE}' is maintained by the server"));
+        }
+        written.push((rel, contents.clone()));
+    }
+
+    Ok(AgentChange::Write {
+        name: name.to_string(),
+        files: written,
+        author: Some(author.clone()),
+    })
+}
+
+/// Author of an agent change made through the API: the service account that
+/// authorized the request, or the admin token.
+fn api_author(
+    state: &AppState,
+    service_account: Option<Extension<ServiceAccountPrincipal>>,
+) -> ChangeAuthor {
+    ChangeAuthor {
+        source: ChangeSource::Api,
+        principal: match service_account {
+            Some(Extension(account)) => account.created_by(),
+            None => api_auth::principal(&state.admin_token, "admin"),
+        },
+    }
+}
+
+fn bulk_result(
+    index: usize,
+    op: &BulkAgentOperation,
+    status: BulkAgentStatus,
+    error: Option<String>,
+) -> BulkAgentResult {
+    BulkAgentResult {
+        index,
+        op: op.op().to_string(),
+        name: op.name().to_string(),
+        status,
+        error,
+    }
 }
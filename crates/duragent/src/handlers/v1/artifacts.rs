@@ -0,0 +1,137 @@
+//! Session artifact HTTP handlers.
+//!
+//! Files attached to messages sent as `multipart/form-data` are kept with
+//! the session and can be listed and downloaded here.
+
+use axum::Json;
+use axum::extract::{Path, State};
+use axum::http::{HeaderValue, StatusCode, header};
+use axum::response::{IntoResponse, Response};
+use tracing::error;
+
+use crate::api::{ArtifactResponse, ListArtifactsResponse};
+use crate::artifacts::Artifact;
+use crate::handlers::problem_details;
+use crate::server::AppState;
+
+/// GET /api/v1/sessions/{session_id}/artifacts
+///
+/// The session's artifacts, oldest first.
+pub async fn list_artifacts(
+    State(state): State<AppState>,
+    Path(session_id): Path<String>,
+) -> Response {
+    if let Err(response) = require_session(&state, &session_id).await {
+        return response;
+    }
+
+    match state.artifacts.list(&session_id).await {
+        Ok(artifacts) => {
+            let artifacts = artifacts.into_iter().map(artifact_response).collect();
+            (StatusCode::OK, Json(ListArtifactsResponse { artifacts })).into_response()
+        }
+        Err(e) => {
+            error!(session_id = %session_id, error = %e, "failed to list artifacts");
+            problem_details::internal_error("failed to list artifacts").into_response()
+        }
+    }
+}
+
+/// GET /api/v1/sessions/{session_id}/artifacts/{id}
+///
+/// The artifact's content, with the content type it was uploaded with.
+pub async fn get_artifact(
+    State(state): State<AppState>,
+    Path((session_id, id)): Path<(String, String)>,
+) -> Response {
+    if let Err(response) = require_session(&state, &session_id).await {
+        return response;
+    }
+
+    let (artifact, content) = match state.artifacts.load(&session_id, &id).await {
+        Ok(Some(found)) => found,
+        Ok(None) => {
+            return problem_details::not_found(format!("artifact '{id}' not found"))
+                .into_response();
+        }
+        Err(e) => {
+            error!(session_id = %session_id, artifact = %id, error = %e, "failed to load artifact");
+            return problem_details::internal_error("failed to load artifact").into_response();
+        }
+    };
+
+    let content_type = HeaderValue::from_str(&artifact.content_type)
+        .unwrap_or(HeaderValue::from_static("application/octet-stream"));
+    let disposition = HeaderValue::from_str(&format!(
+        "attachment; filename=\"{}\"",
+        safe_file_name(&artifact.name)
+    ))
+    .unwrap_or(HeaderValue::from_static("attachment"));
+    (
+        StatusCode::OK,
+        [
+            (header::CONTENT_TYPE, content_type),
+            (header::CONTENT_DISPOSITION, disposition),
+            (
+                header::X_CONTENT_TYPE_OPTIONS,
+                HeaderValue::from_static("nosniff"),
+            ),
+        ],
+        content,
+    )
+        .into_response()
+}
+
+// ============================================================================
+// Helpers
+// ============================================================================
+
+/// Check that the session exists, live or stored.
+pub(super) async fn require_session(state: &AppState, session_id: &str) -> Result<(), Response> {
+    if state.services.session_registry.get(session_id).is_some() {
+        return Ok(());
+    }
+    match state.session_archive.load(session_id).await {
+        Ok(Some(_)) => Ok(()),
+        Ok(None) => Err(problem_details::session_not_found().into_response()),
+        Err(e) => {
+            error!(session_id = %session_id, error = %e, "failed to load stored session");
+            Err(problem_details::internal_error("failed to load session").into_response())
+        }
+    }
+}
+
+/// A file name safe to quote in `Content-Disposition`.
+fn safe_file_name(name: &str) -> String {
+    name.chars()
+        .map(|c| {
+            if c == ' ' || (c.is_ascii_graphic() && c != '"' && c != '\\') {
+                c
+            } else {
+                '_'
+            }
+        })
+        .collect()
+}
+
+fn artifact_response(artifact: Artifact) -> ArtifactResponse {
+    ArtifactResponse {
+        id: artifact.id,
+        name: artifact.name,
+        content_type: artifact.content_type,
+        size: artifact.size,
+        created_at: artifact.created_at.to_rfc3339(),
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn file_names_are_quoted_safely() {
+        assert_eq!(safe_file_name("report 2024.pdf"), "report 2024.pdf");
+        assert_eq!(safe_file_name("a\"b\\c\r\n.txt"), "a_b_c__.txt");
+        assert_eq!(safe_file_name("résumé.pdf"), "r_sum_.pdf");
+    }
+}
@@ -0,0 +1,103 @@
+//! Drift detection HTTP handlers.
+//!
+//! Report and repair differences between the loaded agents and the agents
+//! directory, e.g. after someone edited files directly on the server.
+
+use std::net::SocketAddr;
+
+use axum::Json;
+use axum::extract::{ConnectInfo, State};
+use axum::http::{HeaderMap, StatusCode};
+use axum::response::{IntoResponse, Response};
+use tracing::{error, info};
+
+use crate::agent::{AgentDrift, DriftKind, detect_drift, reconcile_agents};
+use crate::api::{AgentDriftEntry, AgentDriftStatus, DriftResponse};
+use crate::handlers::{api_auth, problem_details};
+use crate::server::AppState;
+use crate::store::file::FileAgentCatalog;
+use crate::store::{AgentCatalog, AgentScanResult};
+
+/// GET /api/v1/admin/drift
+///
+/// Authorization: same as the admin endpoints, or a scoped service account.
+pub async fn get_drift(
+    State(state): State<AppState>,
+    ConnectInfo(addr): ConnectInfo<SocketAddr>,
+    headers: HeaderMap,
+) -> Response {
+    if !api_auth::has_scoped_access(&state, &addr, &headers) {
+        return api_auth::admin_denied(&state, &addr);
+    }
+
+    let disk = match scan_agents_dir(&state).await {
+        Ok(disk) => disk,
+        Err(response) => return response,
+    };
+    let drift = detect_drift(&state.services.agents, &disk);
+    (StatusCode::OK, Json(drift_response(&drift, Vec::new()))).into_response()
+}
+
+/// POST /api/v1/admin/drift/reconcile
+///
+/// Make the loaded agents match the agents directory. Agents that fail to
+/// load from disk keep their loaded version and are still reported.
+///
+/// Authorization: same as the admin endpoints, or a scoped service account.
+pub async fn reconcile_drift(
+    State(state): State<AppState>,
+    ConnectInfo(addr): ConnectInfo<SocketAddr>,
+    headers: HeaderMap,
+) -> Response {
+    if !api_auth::has_scoped_access(&state, &addr, &headers) {
+        return api_auth::admin_denied(&state, &addr);
+    }
+
+    let disk = match scan_agents_dir(&state).await {
+        Ok(disk) => disk,
+        Err(response) => return response,
+    };
+    let drift = detect_drift(&state.services.agents, &disk);
+    let reconciled = reconcile_agents(&state.services.agents, disk, &drift);
+    if !reconciled.is_empty() {
+        info!(agents = ?reconciled, "Reconciled agents with disk");
+    }
+
+    let remaining: Vec<AgentDrift> = drift
+        .into_iter()
+        .filter(|d| !reconciled.contains(&d.name))
+        .collect();
+    (StatusCode::OK, Json(drift_response(&remaining, reconciled))).into_response()
+}
+
+// ============================================================================
+// Helper Functions
+// ============================================================================
+
+async fn scan_agents_dir(state: &AppState) -> Result<AgentScanResult, Response> {
+    let catalog = FileAgentCatalog::new(&state.agents_dir, state.workspace_dir.clone());
+    catalog.load_all().await.map_err(|e| {
+        error!(error = %e, "failed to scan agents directory");
+        problem_details::internal_error("failed to scan agents directory").into_response()
+    })
+}
+
+fn drift_response(drift: &[AgentDrift], reconciled: Vec<String>) -> DriftResponse {
+    DriftResponse {
+        in_sync: drift.is_empty(),
+        agents: drift
+            .iter()
+            .map(|d| AgentDriftEntry {
+                name: d.name.clone(),
+                status: match d.kind {
+                    DriftKind::NotLoaded => AgentDriftStatus::NotLoaded,
+                    DriftKind::MissingOnDisk => AgentDriftStatus::MissingOnDisk,
+                    DriftKind::Modified => AgentDriftStatus::Modified,
+                    DriftKind::Invalid => AgentDriftStatus::Invalid,
+                },
+                detail: d.detail.clone(),
+            })
+            .collect(),
+        reconciled,
+    }
+}
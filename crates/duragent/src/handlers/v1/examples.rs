@@ -0,0 +1,223 @@
+//! Few-shot example HTTP handlers.
+//!
+//! Each agent's pool is curated at `/api/v1/agents/{name}/examples`, apart
+//! from its manifest, so examples can change without redeploying the agent.
+
+use std::net::SocketAddr;
+use std::sync::Arc;
+
+use axum::Json;
+use axum::extract::{ConnectInfo, Path, State};
+use axum::http::{HeaderMap, StatusCode, header};
+use axum::response::{IntoResponse, Response};
+use tracing::error;
+
+use crate::agent::AgentSpec;
+use crate::api::{ExampleResponse, ListExamplesResponse, PutExampleRequest, SelectExamplesRequest};
+use crate::examples::Example;
+use crate::handlers::validation::ValidJson;
+use crate::handlers::{api_auth, problem_details};
+use crate::server::AppState;
+
+/// GET /api/v1/agents/{name}/examples
+///
+/// Examples in the agent's pool, oldest first.
+pub async fn list_examples(State(state): State<AppState>, Path(name): Path<String>) -> Response {
+    if let Err(response) = require_agent(&state, &name) {
+        return response;
+    }
+
+    match state.services.examples.list(&name).await {
+        Ok(examples) => {
+            let examples = examples.into_iter().map(example_response).collect();
+            (StatusCode::OK, Json(ListExamplesResponse { examples })).into_response()
+        }
+        Err(e) => {
+            error!(agent = %name, error = %e, "failed to list examples");
+            problem_details::internal_error("failed to list examples").into_response()
+        }
+    }
+}
+
+/// GET /api/v1/agents/{name}/examples/{id}
+pub async fn get_example(
+    State(state): State<AppState>,
+    Path((name, id)): Path<(String, String)>,
+) -> Response {
+    if let Err(response) = require_agent(&state, &name) {
+        return response;
+    }
+
+    match state.services.examples.get(&name, &id).await {
+        Ok(Some(example)) => (StatusCode::OK, Json(example_response(example))).into_response(),
+        Ok(None) => example_not_found(&id),
+        Err(e) => {
+            error!(agent = %name, example = %id, error = %e, "failed to load example");
+            problem_details::internal_error("failed to load example").into_response()
+        }
+    }
+}
+
+/// POST /api/v1/agents/{name}/examples
+///
+/// Authorization: same as the admin endpoints, or a scoped service account.
+pub async fn create_example(
+    State(state): State<AppState>,
+    ConnectInfo(addr): ConnectInfo<SocketAddr>,
+    headers: HeaderMap,
+    Path(name): Path<String>,
+    ValidJson(req): ValidJson<PutExampleRequest>,
+) -> Response {
+    if !api_auth::has_scoped_access(&state, &addr, &headers) {
+        return api_auth::admin_denied(&state, &addr);
+    }
+    if let Err(response) = require_agent(&state, &name) {
+        return response;
+    }
+
+    match state
+        .services
+        .examples
+        .create(&name, req.input, req.output, req.tags)
+        .await
+    {
+        Ok(example) => {
+            let location = state
+                .external_url
+                .url_for(&format!("/api/v1/agents/{name}/examples/{}", example.id));
+            (
+                StatusCode::CREATED,
+                [(header::LOCATION, location)],
+                Json(example_response(example)),
+            )
+                .into_response()
+        }
+        Err(e) => {
+            error!(agent = %name, error = %e, "failed to save example");
+            problem_details::internal_error("failed to save example").into_response()
+        }
+    }
+}
+
+/// PUT /api/v1/agents/{name}/examples/{id}
+///
+/// Replaces the example's input, output, and tags. Changing the input drops
+/// its cached embedding.
+///
+/// Authorization: same as the admin endpoints, or a scoped service account.
+pub async fn update_example(
+    State(state): State<AppState>,
+    ConnectInfo(addr): ConnectInfo<SocketAddr>,
+    headers: HeaderMap,
+    Path((name, id)): Path<(String, String)>,
+    ValidJson(req): ValidJson<PutExampleRequest>,
+) -> Response {
+    if !api_auth::has_scoped_access(&state, &addr, &headers) {
+        return api_auth::admin_denied(&state, &addr);
+    }
+    if let Err(response) = require_agent(&state, &name) {
+        return response;
+    }
+
+    match state
+        .services
+        .examples
+        .update(&name, &id, req.input, req.output, req.tags)
+        .await
+    {
+        Ok(Some(example)) => (StatusCode::OK, Json(example_response(example))).into_response(),
+        Ok(None) => example_not_found(&id),
+        Err(e) => {
+            error!(agent = %name, example = %id, error = %e, "failed to save example");
+            problem_details::internal_error("failed to save example").into_response()
+        }
+    }
+}
+
+/// DELETE /api/v1/agents/{name}/examples/{id}
+///
+/// Authorization: same as the admin endpoints, or a scoped service account.
+pub async fn delete_example(
+    State(state): State<AppState>,
+    ConnectInfo(addr): ConnectInfo<SocketAddr>,
+    headers: HeaderMap,
+    Path((name, id)): Path<(String, String)>,
+) -> Response {
+    if !api_auth::has_scoped_access(&state, &addr, &headers) {
+        return api_auth::admin_denied(&state, &addr);
+    }
+    if let Err(response) = require_agent(&state, &name) {
+        return response;
+    }
+
+    match state.services.examples.delete(&name, &id).await {
+        Ok(true) => StatusCode::NO_CONTENT.into_response(),
+        Ok(false) => example_not_found(&id),
+        Err(e) => {
+            error!(agent = %name, example = %id, error = %e, "failed to delete example");
+            problem_details::internal_error("failed to delete example").into_response()
+        }
+    }
+}
+
+/// POST /api/v1/agents/{name}/examples/select
+///
+/// Preview which examples the agent would be shown for `input`, using its
+/// manifest's `examples` settings. Empty when the agent has none.
+pub async fn select_examples(
+    State(state): State<AppState>,
+    Path(name): Path<String>,
+    ValidJson(req): ValidJson<SelectExamplesRequest>,
+) -> Response {
+    let agent = match require_agent(&state, &name) {
+        Ok(agent) => agent,
+        Err(response) => return response,
+    };
+    let Some(provider) = state
+        .services
+        .providers
+        .get(&agent.model.provider, agent.model.base_url.as_deref())
+        .await
+    else {
+        return problem_details::provider_not_configured().into_response();
+    };
+
+    let examples = state
+        .services
+        .examples
+        .select(&agent, provider.as_ref(), &req.input)
+        .await
+        .into_iter()
+        .map(example_response)
+        .collect();
+    (StatusCode::OK, Json(ListExamplesResponse { examples })).into_response()
+}
+
+// ============================================================================
+// Helper Functions
+// ============================================================================
+
+/// The agent owning the pool, or a 404 response.
+fn require_agent(state: &AppState, name: &str) -> Result<Arc<AgentSpec>, Response> {
+    state
+        .services
+        .agents
+        .get(name)
+        .ok_or_else(|| problem_details::agent_not_found(name).into_response())
+}
+
+fn example_not_found(id: &str) -> Response {
+    problem_details::not_found(format!("example '{id}' not found")).into_response()
+}
+
+fn example_response(example: Example) -> ExampleResponse {
+    ExampleResponse {
+        id: example.id,
+        input: example.input,
+        output: example.output,
+        tags: example.tags,
+        created_at: example.created_at.to_rfc3339(),
+        updated_at: example.updated_at.to_rfc3339(),
+        embedding_model: example.embedding.map(|e| e.model),
+    }
+}
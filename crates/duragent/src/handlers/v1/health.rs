@@ -0,0 +1,113 @@
+//! Dependency health history HTTP handlers.
+
+use std::net::SocketAddr;
+
+use axum::Json;
+use axum::extract::{ConnectInfo, Query, State};
+use axum::http::{HeaderMap, StatusCode};
+use axum::response::{IntoResponse, Response};
+use serde::Deserialize;
+
+use crate::api::{
+    DependencyHealthEntry, DependencyStatus, HealthCheckEntry, HealthHistoryResponse,
+    HealthIncident,
+};
+use crate::handlers::api_auth;
+use crate::health::{self, DependencyHealth};
+use crate::server::AppState;
+
+/// Checks returned per dependency when `limit` is omitted.
+const DEFAULT_HISTORY_LIMIT: usize = 60;
+
+// ============================================================================
+// Query Types
+// ============================================================================
+
+#[derive(Deserialize)]
+pub struct HealthHistoryQuery {
+    /// Only report this dependency, e.g. `store` or `provider:openai`.
+    dependency: Option<String>,
+    /// Most recent checks returned per dependency (default 60). Summaries
+    /// always cover every retained check.
+    limit: Option<usize>,
+}
+
+// ============================================================================
+// Handlers
+// ============================================================================
+
+/// GET /api/v1/admin/health-history
+///
+/// Recent dependency checks with availability, error budget, and incidents.
+///
+/// Authorization: same as the admin endpoints, or a scoped service account.
+pub async fn get_health_history(
+    State(state): State<AppState>,
+    ConnectInfo(addr): ConnectInfo<SocketAddr>,
+    headers: HeaderMap,
+    Query(query): Query<HealthHistoryQuery>,
+) -> Response {
+    if !api_auth::has_scoped_access(&state, &addr, &headers) {
+        return api_auth::admin_denied(&state, &addr);
+    }
+
+    let limit = query.limit.unwrap_or(DEFAULT_HISTORY_LIMIT);
+    let dependencies = state
+        .health
+        .summaries()
+        .into_iter()
+        .filter(|d| query.dependency.as_ref().is_none_or(|name| &d.name == name))
+        .map(|d| dependency_entry(d, limit))
+        .collect();
+
+    (
+        StatusCode::OK,
+        Json(HealthHistoryResponse {
+            slo_target: state.health.slo_target(),
+            dependencies,
+        }),
+    )
+        .into_response()
+}
+
+// ============================================================================
+// Helper Functions
+// ============================================================================
+
+fn dependency_entry(health: DependencyHealth, limit: usize) -> DependencyHealthEntry {
+    let skip = health.history.len().saturating_sub(limit);
+    DependencyHealthEntry {
+        name: health.name,
+        status: match health.status {
+            health::DependencyStatus::Up => DependencyStatus::Up,
+            health::DependencyStatus::Down => DependencyStatus::Down,
+            health::DependencyStatus::Unknown => DependencyStatus::Unknown,
+        },
+        checks: health.checks,
+        failures: health.failures,
+        availability: health.availability,
+        error_budget_remaining: health.error_budget_remaining,
+        transitions: health.transitions,
+        incidents: health
+            .incidents
+            .into_iter()
+            .map(|i| HealthIncident {
+                started_at: i.started_at.to_rfc3339(),
+                ended_at: i.ended_at.map(|t| t.to_rfc3339()),
+                failed_checks: i.failed_checks,
+                last_error: i.last_error,
+            })
+            .collect(),
+        history: health
+            .history
+            .into_iter()
+            .skip(skip)
+            .map(|r| HealthCheckEntry {
+                checked_at: r.checked_at.to_rfc3339(),
+                ok: r.is_ok(),
+                latency_ms: r.latency_ms,
+                error: r.error,
+            })
+            .collect(),
+    }
+}
@@ -0,0 +1,75 @@
+//! Server metadata HTTP handler.
+
+use axum::Json;
+use axum::extract::State;
+
+use crate::api::{AuthMode, MetaAuth, MetaFeatures, MetaLimits, MetaResponse};
+use crate::build_info::BuildInfo;
+use crate::handlers::format::{APPLICATION_JSON, APPLICATION_MSGPACK, APPLICATION_NDJSON};
+use crate::server::{AppState, MAX_REQUEST_BODY_BYTES};
+
+/// GET /api/v1/meta
+///
+/// Build info plus the features, providers, auth mode, and limits of this
+/// server, so clients can adapt to what it supports.
+pub async fn meta(State(state): State<AppState>) -> Json<MetaResponse> {
+    let build = BuildInfo::new();
+    let run_limit = state.services.run_pool.limit();
+
+    Json(MetaResponse {
+        version: build.version.to_string(),
+        commit: build.commit.to_string(),
+        build_date: build.build_date.to_string(),
+        variant: build.variant.to_string(),
+        features: MetaFeatures {
+            gateways: compiled_gateways(),
+            scheduler: state.scheduler.is_some(),
+            background_processes: state.process_registry.is_some(),
+            memory_backend: "files".to_string(),
+            response_formats: [APPLICATION_JSON, APPLICATION_NDJSON, APPLICATION_MSGPACK]
+                .map(String::from)
+                .to_vec(),
+            request_validation: state.request_validation,
+            read_only: state.read_only,
+            experimental: state
+                .features
+                .list()
+                .into_iter()
+                .filter(|f| f.enabled)
+                .map(|f| f.name.to_string())
+                .collect(),
+        },
+        providers: state.services.providers.available(),
+        auth: MetaAuth {
+            api: auth_mode(&state.api_token),
+            admin: auth_mode(&state.admin_token),
+        },
+        limits: MetaLimits {
+            max_connections: state.max_connections,
+            max_request_body_bytes: MAX_REQUEST_BODY_BYTES,
+            max_concurrent_runs: (run_limit > 0).then_some(run_limit),
+            idle_timeout_seconds: state.idle_timeout_seconds,
+            keep_alive_interval_seconds: state.keep_alive_interval_seconds,
+            agent_trash_retention_hours: state.agent_trash_retention_hours,
+        },
+    })
+}
+
+fn auth_mode(token: &Option<String>) -> AuthMode {
+    match token {
+        Some(_) => AuthMode::Token,
+        None => AuthMode::Loopback,
+    }
+}
+
+/// Gateway integrations built into this binary.
+fn compiled_gateways() -> Vec<String> {
+    let mut gateways = Vec::new();
+    if cfg!(feature = "gateway-discord") {
+        gateways.push("discord".to_string());
+    }
+    if cfg!(feature = "gateway-telegram") {
+        gateways.push("telegram".to_string());
+    }
+    gateways
+}
@@ -1,10 +1,60 @@
 //! V1 API handlers.
 
 mod agents;
+mod artifacts;
+mod drift;
+mod examples;
+mod health;
+mod meta;
+mod models;
+mod problems;
+mod projects;
+mod prompts;
+mod providers;
+mod rpc;
+mod runs;
+mod schemas;
 mod sessions;
+mod shares;
+mod slos;
+mod templates;
+mod usage;
+mod user_memory;
 
-pub use agents::{get_agent, list_agents};
+pub use agents::{
+    bulk_agents, create_agent, delete_agent, disable_agent, enable_agent, get_agent, list_agents,
+    list_trashed_agents, purge_agent, restore_agent,
+};
+pub use artifacts::{get_artifact, list_artifacts};
+pub use drift::{get_drift, reconcile_drift};
+pub use examples::{
+    create_example, delete_example, get_example, list_examples, select_examples, update_example,
+};
+pub use health::get_health_history;
+pub use meta::meta;
+pub use models::{delete_model, get_model, list_models, put_model};
+pub use problems::list_problems;
+pub use projects::{delete_project, get_project, list_projects, put_project};
+pub use prompts::{
+    delete_prompt, get_prompt, get_prompt_version, list_prompt_versions, list_prompts, put_prompt,
+};
+pub use providers::{list_provider_models, list_providers};
+pub use rpc::{RpcRoutes, rpc};
+pub use runs::{
+    bulk_requeue_dead_letters, get_run, list_dead_letters, list_runs, requeue_dead_letter,
+    run_events,
+};
+pub use schemas::{OPENAPI_DOCUMENT, agent_manifest_schema, openapi_document};
 pub use sessions::{
-    approve_command, create_session, delete_session, get_messages, get_session, list_sessions,
-    send_message, stream_session,
+    approve_command, create_public_session, create_session, delete_session, export_session,
+    get_messages, get_session, list_sessions, resume_stream, send_message, send_public_message,
+    start_agent_run, stream_session,
+};
+pub use shares::{create_share, delete_share, get_shared_transcript, list_shares};
+pub use slos::get_slos;
+pub use templates::render_template;
+pub use usage::get_usage;
+pub use user_memory::{
+    create_user_fact, delete_user_fact, forget_user, get_user_fact, list_user_facts,
+    update_user_fact,
 };
@@ -0,0 +1,141 @@
+//! Model registry HTTP handlers.
+//!
+//! Model capabilities and pricing used to check agents and route messages.
+//! Names are patterns matched against model names, so they may contain `/`.
+
+use std::net::SocketAddr;
+
+use axum::Json;
+use axum::extract::{ConnectInfo, Path, State};
+use axum::http::{HeaderMap, StatusCode, header};
+use axum::response::{IntoResponse, Response};
+use tracing::error;
+
+use crate::api::{ListModelsResponse, ModelEntry, ModelResponse, PutModelRequest};
+use crate::handlers::validation::ValidJson;
+use crate::handlers::{api_auth, problem_details};
+use crate::models::{ModelOverride, ModelSource};
+use crate::server::AppState;
+
+/// GET /api/v1/models
+///
+/// Built-in entries and overrides, sorted by name.
+pub async fn list_models(State(state): State<AppState>) -> Response {
+    let models = state
+        .services
+        .models
+        .list()
+        .into_iter()
+        .map(|(name, source, capabilities)| ModelEntry {
+            name,
+            source: source.as_str().to_string(),
+            capabilities,
+        })
+        .collect();
+    (StatusCode::OK, Json(ListModelsResponse { models })).into_response()
+}
+
+/// GET /api/v1/models/{model}
+///
+/// The capabilities a model name resolves to. Unknown models get defaults.
+pub async fn get_model(State(state): State<AppState>, Path(model): Path<String>) -> Response {
+    let resolved = state.services.models.resolve(&model);
+    (
+        StatusCode::OK,
+        Json(ModelResponse {
+            model,
+            matched: resolved.matched,
+            capabilities: resolved.capabilities,
+        }),
+    )
+        .into_response()
+}
+
+/// PUT /api/v1/models/{name}
+///
+/// Create or replace the override for model names containing `name`, and
+/// save it to `models.yaml`.
+///
+/// Authorization: same as the admin endpoints, or a scoped service account.
+pub async fn put_model(
+    State(state): State<AppState>,
+    ConnectInfo(addr): ConnectInfo<SocketAddr>,
+    headers: HeaderMap,
+    Path(name): Path<String>,
+    ValidJson(req): ValidJson<PutModelRequest>,
+) -> Response {
+    if !api_auth::has_scoped_access(&state, &addr, &headers) {
+        return api_auth::admin_denied(&state, &addr);
+    }
+    if name.trim().is_empty() {
+        return problem_details::bad_request("model name must not be blank").into_response();
+    }
+
+    let entry = ModelOverride {
+        context_window: req.context_window,
+        max_output_tokens: req.max_output_tokens,
+        vision: req.vision,
+        tool_calling: req.tool_calling,
+        json_mode: req.json_mode,
+        pricing: req.pricing,
+    };
+    let created = match state.services.models.put(&name, entry).await {
+        Ok(created) => created,
+        Err(e) => {
+            error!(model = %name, error = %e, "failed to save model override");
+            return problem_details::internal_error("failed to save model override")
+                .into_response();
+        }
+    };
+    state
+        .services
+        .models
+        .warn_agent_issues(&state.services.agents);
+
+    let name = name.to_lowercase();
+    let response = ModelEntry {
+        capabilities: state.services.models.capabilities(&name),
+        source: ModelSource::Override.as_str().to_string(),
+        name,
+    };
+    if created {
+        let location = state
+            .external_url
+            .url_for(&format!("/api/v1/models/{}", response.name));
+        (
+            StatusCode::CREATED,
+            [(header::LOCATION, location)],
+            Json(response),
+        )
+            .into_response()
+    } else {
+        (StatusCode::OK, Json(response)).into_response()
+    }
+}
+
+/// DELETE /api/v1/models/{name}
+///
+/// Removes an override. Built-in entries can't be deleted.
+///
+/// Authorization: same as the admin endpoints, or a scoped service account.
+pub async fn delete_model(
+    State(state): State<AppState>,
+    ConnectInfo(addr): ConnectInfo<SocketAddr>,
+    headers: HeaderMap,
+    Path(name): Path<String>,
+) -> Response {
+    if !api_auth::has_scoped_access(&state, &addr, &headers) {
+        return api_auth::admin_denied(&state, &addr);
+    }
+
+    match state.services.models.delete(&name).await {
+        Ok(true) => StatusCode::NO_CONTENT.into_response(),
+        Ok(false) => {
+            problem_details::not_found(format!("no override for model '{name}'")).into_response()
+        }
+        Err(e) => {
+            error!(model = %name, error = %e, "failed to delete model override");
+            problem_details::internal_error("failed to delete model override").into_response()
+        }
+    }
+}
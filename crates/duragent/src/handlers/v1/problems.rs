@@ -0,0 +1,23 @@
+//! Problem type catalog handler.
+
+use axum::Json;
+
+use crate::api::{ListProblemsResponse, ProblemTypeInfo};
+use crate::handlers::problem_details::ProblemType;
+
+/// GET /api/v1/problems
+pub async fn list_problems() -> Json<ListProblemsResponse> {
+    let problems = ProblemType::ALL
+        .iter()
+        .map(|t| ProblemTypeInfo {
+            code: t.code().to_string(),
+            type_uri: t.type_uri(),
+            title: t.title().to_string(),
+            status: t.status().as_u16(),
+            retryable: t.retryable(),
+            docs_url: t.docs_url(),
+        })
+        .collect();
+
+    Json(ListProblemsResponse { problems })
+}
@@ -0,0 +1,198 @@
+//! Project HTTP handlers.
+//!
+//! A project groups agents (via `metadata.project`) and supplies defaults for
+//! them: a model for agents that omit `spec.model`, and run budgets.
+
+use std::net::SocketAddr;
+
+use axum::Json;
+use axum::extract::{ConnectInfo, Path, State};
+use axum::http::{HeaderMap, StatusCode, header};
+use axum::response::{IntoResponse, Response};
+use tracing::error;
+
+use crate::agent::{AgentQuery, AgentStore, Project, log_scan_warnings};
+use crate::api::{ListProjectsResponse, ProjectResponse, PutProjectRequest};
+use crate::handlers::validation::ValidJson;
+use crate::handlers::{api_auth, problem_details};
+use crate::server::AppState;
+use crate::store::ProjectStore;
+use crate::store::file::{FileAgentCatalog, is_valid_agent_name};
+
+/// GET /api/v1/projects
+pub async fn list_projects(State(state): State<AppState>) -> Response {
+    let catalog = FileAgentCatalog::new(&state.agents_dir, state.workspace_dir.clone());
+    let projects = match catalog.projects().list().await {
+        Ok(p) => p,
+        Err(e) => {
+            error!(error = %e, "failed to list projects");
+            return problem_details::internal_error("failed to list projects").into_response();
+        }
+    };
+
+    let projects = projects
+        .into_iter()
+        .map(|p| project_response(&state, p))
+        .collect();
+    (StatusCode::OK, Json(ListProjectsResponse { projects })).into_response()
+}
+
+/// GET /api/v1/projects/{name}
+pub async fn get_project(State(state): State<AppState>, Path(name): Path<String>) -> Response {
+    let catalog = FileAgentCatalog::new(&state.agents_dir, state.workspace_dir.clone());
+    match load_project(&catalog, &name).await {
+        Ok(project) => (StatusCode::OK, Json(project_response(&state, project))).into_response(),
+        Err(response) => response,
+    }
+}
+
+/// PUT /api/v1/projects/{name}
+///
+/// Create or replace a project. Agents are reloaded from disk afterwards so
+/// member agents pick up the new defaults, including agents that previously
+/// failed to load because the project did not exist.
+///
+/// Authorization: same as the admin endpoints, or a scoped service account.
+pub async fn put_project(
+    State(state): State<AppState>,
+    ConnectInfo(addr): ConnectInfo<SocketAddr>,
+    headers: HeaderMap,
+    Path(name): Path<String>,
+    ValidJson(req): ValidJson<PutProjectRequest>,
+) -> Response {
+    if !api_auth::has_scoped_access(&state, &addr, &headers) {
+        return api_auth::admin_denied(&state, &addr);
+    }
+    if !is_valid_agent_name(&name) {
+        return problem_details::bad_request(format!("invalid project name '{name}'"))
+            .into_response();
+    }
+
+    let catalog = FileAgentCatalog::new(&state.agents_dir, state.workspace_dir.clone());
+    let store = catalog.projects();
+    let created = match store.load(&name).await {
+        Ok(existing) => existing.is_none(),
+        Err(e) => {
+            error!(project = %name, error = %e, "failed to load project");
+            return problem_details::internal_error("failed to load project").into_response();
+        }
+    };
+
+    let project = Project {
+        name: name.clone(),
+        description: req.description,
+        default_model: req.default_model,
+        budget: req.budget,
+    };
+    if let Err(e) = store.save(&project).await {
+        error!(project = %name, error = %e, "failed to save project");
+        return problem_details::internal_error("failed to save project").into_response();
+    }
+
+    let report = AgentStore::from_catalog(&catalog).await;
+    log_scan_warnings(&report.warnings);
+    state.services.models.warn_agent_issues(&report.store);
+    state.services.agents.replace_from(&report.store);
+
+    let response = project_response(&state, project);
+    if created {
+        let location = project_url(&state, &name);
+        (
+            StatusCode::CREATED,
+            [(header::LOCATION, location)],
+            Json(response),
+        )
+            .into_response()
+    } else {
+        (StatusCode::OK, Json(response)).into_response()
+    }
+}
+
+/// DELETE /api/v1/projects/{name}
+///
+/// Fails with 409 while any loaded agent still belongs to the project.
+///
+/// Authorization: same as the admin endpoints, or a scoped service account.
+pub async fn delete_project(
+    State(state): State<AppState>,
+    ConnectInfo(addr): ConnectInfo<SocketAddr>,
+    headers: HeaderMap,
+    Path(name): Path<String>,
+) -> Response {
+    if !api_auth::has_scoped_access(&state, &addr, &headers) {
+        return api_auth::admin_denied(&state, &addr);
+    }
+
+    let catalog = FileAgentCatalog::new(&state.agents_dir, state.workspace_dir.clone());
+    if let Err(response) = load_project(&catalog, &name).await {
+        return response;
+    }
+
+    let members = member_agents(&state, &name);
+    if !members.is_empty() {
+        return problem_details::conflict(format!(
+            "project '{name}' still has agents: {}",
+            members.join(", ")
+        ))
+        .into_response();
+    }
+
+    if let Err(e) = catalog.projects().delete(&name).await {
+        error!(project = %name, error = %e, "failed to delete project");
+        return problem_details::internal_error("failed to delete project").into_response();
+    }
+
+    StatusCode::NO_CONTENT.into_response()
+}
+
+// ============================================================================
+// Helper Functions
+// ============================================================================
+
+async fn load_project(catalog: &FileAgentCatalog, name: &str) -> Result<Project, Response> {
+    let not_found =
+        || problem_details::not_found(format!("project '{name}' not found")).into_response();
+    if !is_valid_agent_name(name) {
+        return Err(not_found());
+    }
+    match catalog.projects().load(name).await {
+        Ok(Some(project)) => Ok(project),
+        Ok(None) => Err(not_found()),
+        Err(e) => {
+            error!(project = %name, error = %e, "failed to load project");
+            Err(problem_details::internal_error("failed to load project").into_response())
+        }
+    }
+}
+
+/// Names of loaded agents in `project`, sorted.
+fn member_agents(state: &AppState, project: &str) -> Vec<String> {
+    let query = AgentQuery {
+        project: Some(project.to_string()),
+        ..Default::default()
+    };
+    state
+        .services
+        .agents
+        .search(&query)
+        .into_iter()
+        .map(|spec| spec.metadata.name.clone())
+        .collect()
+}
+
+fn project_response(state: &AppState, project: Project) -> ProjectResponse {
+    ProjectResponse {
+        agents: member_agents(state, &project.name),
+        name: project.name,
+        description: project.description,
+        default_model: project.default_model,
+        budget: project.budget,
+    }
+}
+
+/// Public link to a project resource.
+fn project_url(state: &AppState, name: &str) -> String {
+    state
+        .external_url
+        .url_for(&format!("/api/v1/projects/{name}"))
+}
@@ -0,0 +1,211 @@
+//! Prompt library HTTP handlers.
+//!
+//! Shared prompt fragments that agents include by reference. Changing a
+//! prompt's content adds a version; agents that pin a version keep it.
+
+use std::net::SocketAddr;
+
+use axum::Json;
+use axum::extract::{ConnectInfo, Path, State};
+use axum::http::{HeaderMap, StatusCode, header};
+use axum::response::{IntoResponse, Response};
+use tracing::error;
+
+use crate::api::{
+    ListPromptVersionsResponse, ListPromptsResponse, PromptResponse, PromptSummary,
+    PromptVersionResponse, PutPromptRequest,
+};
+use crate::handlers::validation::ValidJson;
+use crate::handlers::{api_auth, problem_details};
+use crate::prompts::{Prompt, PromptVersion, PutOutcome};
+use crate::server::AppState;
+use crate::store::file::is_valid_agent_name;
+
+/// GET /api/v1/prompts
+pub async fn list_prompts(State(state): State<AppState>) -> Response {
+    let prompts = state
+        .services
+        .prompts
+        .list()
+        .into_iter()
+        .map(|p| PromptSummary {
+            latest_version: p.latest().version,
+            name: p.name,
+            description: p.description,
+            updated_at: p.updated_at.to_rfc3339(),
+        })
+        .collect();
+    (StatusCode::OK, Json(ListPromptsResponse { prompts })).into_response()
+}
+
+/// GET /api/v1/prompts/{name}
+///
+/// The latest version, and the loaded agents that include the prompt.
+pub async fn get_prompt(State(state): State<AppState>, Path(name): Path<String>) -> Response {
+    match state.services.prompts.get(&name) {
+        Some(prompt) => (StatusCode::OK, Json(prompt_response(&state, prompt))).into_response(),
+        None => prompt_not_found(&name),
+    }
+}
+
+/// PUT /api/v1/prompts/{name}
+///
+/// Create a prompt, or add a version when the content changed. Content equal
+/// to the latest version updates only the description.
+///
+/// Authorization: same as the admin endpoints, or a scoped service account.
+pub async fn put_prompt(
+    State(state): State<AppState>,
+    ConnectInfo(addr): ConnectInfo<SocketAddr>,
+    headers: HeaderMap,
+    Path(name): Path<String>,
+    ValidJson(req): ValidJson<PutPromptRequest>,
+) -> Response {
+    if !api_auth::has_scoped_access(&state, &addr, &headers) {
+        return api_auth::admin_denied(&state, &addr);
+    }
+    if !is_valid_agent_name(&name) {
+        return problem_details::bad_request(format!("invalid prompt name '{name}'"))
+            .into_response();
+    }
+
+    let (prompt, outcome) = match state
+        .services
+        .prompts
+        .put(&name, req.description, req.content)
+        .await
+    {
+        Ok(result) => result,
+        Err(e) => {
+            error!(prompt = %name, error = %e, "failed to save prompt");
+            return problem_details::internal_error("failed to save prompt").into_response();
+        }
+    };
+
+    let response = prompt_response(&state, prompt);
+    if outcome == PutOutcome::Created {
+        let location = state
+            .external_url
+            .url_for(&format!("/api/v1/prompts/{name}"));
+        (
+            StatusCode::CREATED,
+            [(header::LOCATION, location)],
+            Json(response),
+        )
+            .into_response()
+    } else {
+        (StatusCode::OK, Json(response)).into_response()
+    }
+}
+
+/// DELETE /api/v1/prompts/{name}
+///
+/// Deletes every version. Fails with 409 while a loaded agent includes it.
+///
+/// Authorization: same as the admin endpoints, or a scoped service account.
+pub async fn delete_prompt(
+    State(state): State<AppState>,
+    ConnectInfo(addr): ConnectInfo<SocketAddr>,
+    headers: HeaderMap,
+    Path(name): Path<String>,
+) -> Response {
+    if !api_auth::has_scoped_access(&state, &addr, &headers) {
+        return api_auth::admin_denied(&state, &addr);
+    }
+    if state.services.prompts.get(&name).is_none() {
+        return prompt_not_found(&name);
+    }
+
+    let users = including_agents(&state, &name);
+    if !users.is_empty() {
+        return problem_details::conflict(format!(
+            "prompt '{name}' is included by agents: {}",
+            users.join(", ")
+        ))
+        .into_response();
+    }
+
+    match state.services.prompts.delete(&name).await {
+        Ok(_) => StatusCode::NO_CONTENT.into_response(),
+        Err(e) => {
+            error!(prompt = %name, error = %e, "failed to delete prompt");
+            problem_details::internal_error("failed to delete prompt").into_response()
+        }
+    }
+}
+
+/// GET /api/v1/prompts/{name}/versions
+///
+/// Every version, oldest first.
+pub async fn list_prompt_versions(
+    State(state): State<AppState>,
+    Path(name): Path<String>,
+) -> Response {
+    let Some(prompt) = state.services.prompts.get(&name) else {
+        return prompt_not_found(&name);
+    };
+    let versions = prompt.versions.into_iter().map(version_response).collect();
+    (
+        StatusCode::OK,
+        Json(ListPromptVersionsResponse { versions }),
+    )
+        .into_response()
+}
+
+/// GET /api/v1/prompts/{name}/versions/{version}
+pub async fn get_prompt_version(
+    State(state): State<AppState>,
+    Path((name, version)): Path<(String, u32)>,
+) -> Response {
+    let Some(prompt) = state.services.prompts.get(&name) else {
+        return prompt_not_found(&name);
+    };
+    match prompt.version(Some(version)) {
+        Some(v) => (StatusCode::OK, Json(version_response(v.clone()))).into_response(),
+        None => problem_details::not_found(format!("prompt '{name}' has no version {version}"))
+            .into_response(),
+    }
+}
+
+// ============================================================================
+// Helper Functions
+// ============================================================================
+
+fn prompt_not_found(name: &str) -> Response {
+    problem_details::not_found(format!("prompt '{name}' not found")).into_response()
+}
+
+/// Names of loaded agents that include `prompt`, sorted.
+fn including_agents(state: &AppState, prompt: &str) -> Vec<String> {
+    let mut names: Vec<String> = state
+        .services
+        .agents
+        .snapshot()
+        .into_iter()
+        .filter(|(_, spec)| spec.prompts.iter().any(|r| r.name == prompt))
+        .map(|(name, _)| name)
+        .collect();
+    names.sort();
+    names
+}
+
+fn prompt_response(state: &AppState, prompt: Prompt) -> PromptResponse {
+    let latest = prompt.latest().clone();
+    PromptResponse {
+        used_by: including_agents(state, &prompt.name),
+        name: prompt.name,
+        description: prompt.description,
+        version: latest.version,
+        content: latest.content,
+        created_at: prompt.created_at.to_rfc3339(),
+        updated_at: prompt.updated_at.to_rfc3339(),
+    }
+}
+
+fn version_response(version: PromptVersion) -> PromptVersionResponse {
+    PromptVersionResponse {
+        version: version.version,
+        content: version.content,
+        created_at: version.created_at.to_rfc3339(),
+    }
+}
@@ -0,0 +1,156 @@
+//! Provider dashboard HTTP handlers.
+
+use std::collections::{BTreeMap, BTreeSet};
+use std::net::SocketAddr;
+
+use axum::Json;
+use axum::extract::{ConnectInfo, Path, State};
+use axum::http::{HeaderMap, StatusCode};
+use axum::response::{IntoResponse, Response};
+use tracing::warn;
+
+use crate::api::{
+    DependencyStatus, ListProvidersResponse, ProviderLatency, ProviderModel,
+    ProviderModelsResponse, ProviderSummary, RateLimitHeadroom,
+};
+use crate::handlers::{api_auth, problem_details};
+use crate::health;
+use crate::llm::{LLMError, Provider};
+use crate::server::AppState;
+
+/// GET /api/v1/providers
+///
+/// Configured providers with their latest health check, recent latency,
+/// rate-limit headroom, and the models loaded agents use with them.
+///
+/// Authorization: same as the admin endpoints, or a scoped service account.
+pub async fn list_providers(
+    State(state): State<AppState>,
+    ConnectInfo(addr): ConnectInfo<SocketAddr>,
+    headers: HeaderMap,
+) -> Response {
+    if !api_auth::has_scoped_access(&state, &addr, &headers) {
+        return api_auth::admin_denied(&state, &addr);
+    }
+
+    let mut models = models_by_provider(&state);
+    let health = state.health.summaries();
+    let stats = state.services.providers.stats();
+    let providers = state
+        .services
+        .providers
+        .available()
+        .into_iter()
+        .map(|name| {
+            let dependency = format!("provider:{name}");
+            let checks = health.iter().find(|d| d.name == dependency);
+            let last_check = checks.and_then(|d| d.history.last());
+            let activity = stats.activity(&name);
+            ProviderSummary {
+                health: match checks.map(|d| d.status) {
+                    Some(health::DependencyStatus::Up) => DependencyStatus::Up,
+                    Some(health::DependencyStatus::Down) => DependencyStatus::Down,
+                    Some(health::DependencyStatus::Unknown) | None => DependencyStatus::Unknown,
+                },
+                last_checked_at: last_check.map(|c| c.checked_at.to_rfc3339()),
+                last_error: last_check.and_then(|c| c.error.clone()),
+                availability: checks.filter(|d| d.checks > 0).map(|d| d.availability),
+                latency: ProviderLatency {
+                    requests: activity.requests,
+                    errors: activity.errors,
+                    p50_ms: activity.p50_latency_ms,
+                    p95_ms: activity.p95_latency_ms,
+                    last_request_at: activity.last_request_at.map(|t| t.to_rfc3339()),
+                },
+                rate_limit: activity.rate_limit.map(|r| RateLimitHeadroom {
+                    requests_limit: r.requests_limit,
+                    requests_remaining: r.requests_remaining,
+                    tokens_limit: r.tokens_limit,
+                    tokens_remaining: r.tokens_remaining,
+                    observed_at: r.observed_at.to_rfc3339(),
+                }),
+                models: models
+                    .remove(&name)
+                    .unwrap_or_default()
+                    .into_iter()
+                    .map(|(model, agents)| ProviderModel {
+                        capabilities: state.services.models.capabilities(&model),
+                        name: model,
+                        agents: agents.into_iter().collect(),
+                    })
+                    .collect(),
+                name,
+            }
+        })
+        .collect();
+
+    (StatusCode::OK, Json(ListProvidersResponse { providers })).into_response()
+}
+
+/// GET /api/v1/providers/{name}/models
+///
+/// The models a provider serves, passed through from its own model list.
+///
+/// Authorization: same as the admin endpoints, or a scoped service account.
+pub async fn list_provider_models(
+    State(state): State<AppState>,
+    ConnectInfo(addr): ConnectInfo<SocketAddr>,
+    headers: HeaderMap,
+    Path(name): Path<String>,
+) -> Response {
+    if !api_auth::has_scoped_access(&state, &addr, &headers) {
+        return api_auth::admin_denied(&state, &addr);
+    }
+    let Some(provider) = state
+        .services
+        .providers
+        .get(&Provider::from(name.clone()), None)
+        .await
+    else {
+        return problem_details::provider_not_configured().into_response();
+    };
+
+    match provider.list_models().await {
+        Ok(models) => (
+            StatusCode::OK,
+            Json(ProviderModelsResponse {
+                provider: name,
+                models,
+            }),
+        )
+            .into_response(),
+        Err(LLMError::Unsupported(_)) => {
+            problem_details::not_found(format!("provider '{name}' can't list its models"))
+                .into_response()
+        }
+        Err(e) => {
+            warn!(provider = %name, error = %e, "failed to list provider models");
+            problem_details::provider_error("failed to list provider models").into_response()
+        }
+    }
+}
+
+// ============================================================================
+// Helper Functions
+// ============================================================================
+
+/// Provider → model → agents using it, from loaded agents' models and
+/// routing rules.
+fn models_by_provider(state: &AppState) -> BTreeMap<String, BTreeMap<String, BTreeSet<String>>> {
+    let mut models: BTreeMap<String, BTreeMap<String, BTreeSet<String>>> = BTreeMap::new();
+    for (name, agent) in state.services.agents.snapshot() {
+        let routed = agent
+            .model_routing
+            .iter()
+            .flat_map(|routing| routing.rules.iter().map(|rule| &rule.model));
+        for model in std::iter::once(&agent.model).chain(routed) {
+            models
+                .entry(model.provider.to_string())
+                .or_default()
+                .entry(model.name.clone())
+                .or_default()
+                .insert(name.clone());
+        }
+    }
+    models
+}
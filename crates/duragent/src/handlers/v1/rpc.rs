@@ -0,0 +1,747 @@
+//! WebSocket JSON-RPC 2.0 control channel.
+//!
+//! One connection carries many concurrent calls. Each method maps onto an
+//! `/api/v1` route and is dispatched through the same router, so a call is
+//! authenticated, scoped, and policy-checked exactly like its HTTP twin.
+//! Streaming invocations and session subscriptions push notifications while
+//! they run.
+
+use std::collections::HashMap;
+use std::net::SocketAddr;
+use std::sync::{Arc, Mutex};
+use std::time::Duration;
+
+use axum::body::Body;
+use axum::extract::ws::{Message, WebSocket, WebSocketUpgrade};
+use axum::extract::{ConnectInfo, OriginalUri, State};
+use axum::http::{HeaderMap, HeaderValue, Method, Request, StatusCode, header};
+use axum::response::Response;
+use axum::{Extension, Router};
+use bytes::Bytes;
+use futures::{SinkExt, StreamExt};
+use serde::{Deserialize, Serialize};
+use serde_json::{Map, Value, json};
+use tokio::sync::{broadcast, mpsc};
+use tokio::task::{AbortHandle, JoinSet};
+use tokio::time::{Instant, interval_at, sleep};
+use tower::ServiceExt;
+use tracing::debug;
+use ulid::Ulid;
+
+use crate::api::sse as sse_events;
+use crate::handlers::format::APPLICATION_JSON;
+use crate::server::AppState;
+use crate::sse_parser::SseEventStream;
+
+const JSONRPC_VERSION: &str = "2.0";
+
+const PARSE_ERROR: i64 = -32700;
+const INVALID_REQUEST: i64 = -32600;
+const METHOD_NOT_FOUND: i64 = -32601;
+const INVALID_PARAMS: i64 = -32602;
+const INTERNAL_ERROR: i64 = -32603;
+/// The dispatched HTTP call failed; `data` carries its status and problem.
+const HTTP_ERROR: i64 = -32000;
+/// A streamed run ended with an `error` or `cancelled` event.
+const STREAM_ERROR: i64 = -32001;
+
+/// Outbound frames buffered per connection before calls wait on the socket.
+const OUTBOUND_BUFFER: usize = 256;
+
+const TEXT_EVENT_STREAM: &str = "text/event-stream";
+
+// ============================================================================
+// Handler
+// ============================================================================
+
+/// Routes that RPC calls are dispatched to: `/api/v1` behind its auth
+/// middleware.
+#[derive(Clone)]
+pub struct RpcRoutes(pub Router);
+
+/// GET /api/v1/rpc
+///
+/// Upgrades to a WebSocket speaking JSON-RPC 2.0. Credentials on the
+/// upgrade request are forwarded to every call made over the connection.
+/// The server pings every `keep_alive_interval_seconds` and closes
+/// connections that send nothing, not even a pong, for
+/// `idle_timeout_seconds`.
+pub async fn rpc(
+    State(state): State<AppState>,
+    Extension(RpcRoutes(routes)): Extension<RpcRoutes>,
+    ConnectInfo(addr): ConnectInfo<SocketAddr>,
+    headers: HeaderMap,
+    ws: WebSocketUpgrade,
+) -> Response {
+    let connection = Connection {
+        state,
+        routes,
+        addr,
+        headers: forwarded_headers(&headers),
+        subscriptions: Arc::default(),
+    };
+    ws.on_upgrade(move |socket| connection.serve(socket))
+}
+
+/// Headers of the upgrade request that are replayed on dispatched calls.
+///
+/// Handshake and body headers describe the upgrade itself, not the calls.
+fn forwarded_headers(headers: &HeaderMap) -> HeaderMap {
+    let mut forwarded = headers.clone();
+    for name in [
+        header::CONNECTION,
+        header::UPGRADE,
+        header::SEC_WEBSOCKET_KEY,
+        header::SEC_WEBSOCKET_VERSION,
+        header::SEC_WEBSOCKET_EXTENSIONS,
+        header::SEC_WEBSOCKET_PROTOCOL,
+        header::ACCEPT,
+        header::CONTENT_LENGTH,
+        header::CONTENT_TYPE,
+    ] {
+        forwarded.remove(name);
+    }
+    forwarded
+}
+
+// ============================================================================
+// Protocol Types
+// ============================================================================
+
+#[derive(Debug, Deserialize)]
+struct RpcRequest {
+    jsonrpc: String,
+    /// Absent for notifications, which get no response.
+    #[serde(default)]
+    id: Option<Value>,
+    method: String,
+    #[serde(default)]
+    params: Option<Value>,
+}
+
+#[derive(Debug, PartialEq, Serialize)]
+struct RpcError {
+    code: i64,
+    message: String,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    data: Option<Value>,
+}
+
+impl RpcError {
+    fn new(code: i64, message: impl Into<String>) -> Self {
+        Self {
+            code,
+            message: message.into(),
+            data: None,
+        }
+    }
+
+    fn invalid_params(message: impl Into<String>) -> Self {
+        Self::new(INVALID_PARAMS, message)
+    }
+
+    /// Error for a non-2xx response, keeping its problem body.
+    fn http(status: StatusCode, problem: Value) -> Self {
+        let message = ["detail", "title"]
+            .iter()
+            .find_map(|field| problem.get(*field).and_then(Value::as_str))
+            .map_or_else(|| status.to_string(), str::to_string);
+        Self {
+            code: HTTP_ERROR,
+            message,
+            data: Some(json!({ "status": status.as_u16(), "problem": problem })),
+        }
+    }
+}
+
+fn response(id: Value, result: Result<Value, RpcError>) -> Value {
+    match result {
+        Ok(result) => json!({ "jsonrpc": JSONRPC_VERSION, "id": id, "result": result }),
+        Err(error) => json!({ "jsonrpc": JSONRPC_VERSION, "id": id, "error": error }),
+    }
+}
+
+fn notification(method: &str, params: Value) -> Value {
+    json!({ "jsonrpc": JSONRPC_VERSION, "method": method, "params": params })
+}
+
+// ============================================================================
+// Method Table
+// ============================================================================
+
+/// How a method is carried out.
+#[derive(Debug, PartialEq)]
+enum Call {
+    /// One HTTP call; its JSON body is the result.
+    Http(HttpCall),
+    /// A streaming invocation; events become `stream.event` notifications.
+    Stream(HttpCall),
+    /// Start forwarding a live session's events.
+    Subscribe { session_id: String },
+    /// Stop a subscription.
+    Unsubscribe { subscription: String },
+}
+
+#[derive(Debug, PartialEq)]
+struct HttpCall {
+    method: Method,
+    path: String,
+    body: Option<Value>,
+}
+
+impl HttpCall {
+    fn get(path: String) -> Self {
+        Self {
+            method: Method::GET,
+            path,
+            body: None,
+        }
+    }
+
+    fn delete(path: String) -> Self {
+        Self {
+            method: Method::DELETE,
+            path,
+            body: None,
+        }
+    }
+
+    fn post(path: String, body: Map<String, Value>) -> Self {
+        Self {
+            method: Method::POST,
+            path,
+            body: Some(Value::Object(body)),
+        }
+    }
+}
+
+/// Resolve a method and its params to the call that implements it.
+///
+/// Path and query params are taken out of `params`; what remains is the
+/// request body for methods that have one.
+fn plan(method: &str, params: Option<Value>) -> Result<Call, RpcError> {
+    let mut params = match params {
+        None | Some(Value::Null) => Map::new(),
+        Some(Value::Object(params)) => params,
+        Some(_) => return Err(RpcError::invalid_params("params must be an object")),
+    };
+    let p = &mut params;
+
+    let call = match method {
+        "agents.list" => Call::Http(HttpCall::get(with_query("/agents", p, &["selector"])?)),
+        "agents.get" => Call::Http(HttpCall::get(format!("/agents/{}", segment(p, "name")?))),
+        "sessions.list" => Call::Http(HttpCall::get(with_query("/sessions", p, &["selector"])?)),
+        "sessions.create" => return Ok(Call::Http(HttpCall::post("/sessions".into(), params))),
+        "sessions.get" => Call::Http(HttpCall::get(session_path(p, "")?)),
+        "sessions.delete" => Call::Http(HttpCall::delete(session_path(p, "")?)),
+        "sessions.messages" => {
+            let path = session_path(p, "/messages")?;
+            Call::Http(HttpCall::get(with_query(&path, p, &["limit"])?))
+        }
+        "sessions.invoke" => {
+            let path = session_path(p, "/messages")?;
+            return Ok(Call::Http(HttpCall::post(path, params)));
+        }
+        "sessions.stream" => {
+            let path = session_path(p, "/stream")?;
+            return Ok(Call::Stream(HttpCall::post(path, params)));
+        }
+        "sessions.approve" => {
+            let path = session_path(p, "/approve")?;
+            return Ok(Call::Http(HttpCall::post(path, params)));
+        }
+        "sessions.subscribe" => Call::Subscribe {
+            session_id: segment(p, "session_id")?,
+        },
+        "sessions.unsubscribe" => Call::Unsubscribe {
+            subscription: string_param(p, "subscription")?,
+        },
+        _ => {
+            return Err(RpcError::new(
+                METHOD_NOT_FOUND,
+                format!("method not found: {method}"),
+            ));
+        }
+    };
+
+    // Methods without a body take only the params they name.
+    if let Some(name) = params.keys().next() {
+        return Err(RpcError::invalid_params(format!("unknown param '{name}'")));
+    }
+    Ok(call)
+}
+
+fn string_param(params: &mut Map<String, Value>, name: &str) -> Result<String, RpcError> {
+    match params.remove(name) {
+        Some(Value::String(value)) => Ok(value),
+        Some(_) => Err(RpcError::invalid_params(format!(
+            "param '{name}' must be a string"
+        ))),
+        None => Err(RpcError::invalid_params(format!(
+            "missing required param '{name}'"
+        ))),
+    }
+}
+
+/// A string param used as one path segment.
+fn segment(params: &mut Map<String, Value>, name: &str) -> Result<String, RpcError> {
+    let value = string_param(params, name)?;
+    let valid = !value.is_empty()
+        && value
+            .chars()
+            .all(|c| c.is_ascii_alphanumeric() || matches!(c, '-' | '_' | '.'));
+    if !valid || value == "." || value == ".." {
+        return Err(RpcError::invalid_params(format!(
+            "param '{name}' is not a valid identifier"
+        )));
+    }
+    Ok(value)
+}
+
+fn session_path(params: &mut Map<String, Value>, suffix: &str) -> Result<String, RpcError> {
+    Ok(format!(
+        "/sessions/{}{suffix}",
+        segment(params, "session_id")?
+    ))
+}
+
+/// Append the named params, when present, as a query string.
+fn with_query(
+    path: &str,
+    params: &mut Map<String, Value>,
+    names: &[&str],
+) -> Result<String, RpcError> {
+    let mut query = url::form_urlencoded::Serializer::new(String::new());
+    for name in names {
+        match params.remove(*name) {
+            None | Some(Value::Null) => {}
+            Some(Value::String(value)) => {
+                query.append_pair(name, &value);
+            }
+            Some(Value::Number(value)) => {
+                query.append_pair(name, &value.to_string());
+            }
+            Some(_) => {
+                return Err(RpcError::invalid_params(format!(
+                    "param '{name}' must be a string or number"
+                )));
+            }
+        }
+    }
+    let query = query.finish();
+    if query.is_empty() {
+        Ok(path.to_string())
+    } else {
+        Ok(format!("{path}?{query}"))
+    }
+}
+
+// ============================================================================
+// Connection
+// ============================================================================
+
+#[derive(Clone)]
+struct Connection {
+    state: AppState,
+    routes: Router,
+    addr: SocketAddr,
+    headers: HeaderMap,
+    /// Live subscriptions by id.
+    subscriptions: Arc<Mutex<HashMap<String, AbortHandle>>>,
+}
+
+impl Connection {
+    async fn serve(self, socket: WebSocket) {
+        let (mut sink, mut incoming) = socket.split();
+        let (out_tx, mut out_rx) = mpsc::channel::<Value>(OUTBOUND_BUFFER);
+        let keep_alive = Duration::from_secs(self.state.keep_alive_interval_seconds);
+        let idle_timeout = Duration::from_secs(self.state.idle_timeout_seconds);
+
+        // Pings keep intermediaries from dropping a quiet connection, and
+        // their pongs show the peer is still there.
+        let writer = tokio::spawn(async move {
+            let mut heartbeat = interval_at(Instant::now() + keep_alive, keep_alive);
+            loop {
+                let message = tokio::select! {
+                    frame = out_rx.recv() => match frame {
+                        Some(frame) => Message::Text(frame.to_string().into()),
+                        None => break,
+                    },
+                    _ = heartbeat.tick() => Message::Ping(Bytes::new()),
+                };
+                if sink.send(message).await.is_err() {
+                    break;
+                }
+            }
+        });
+
+        let idle = sleep(idle_timeout);
+        tokio::pin!(idle);
+        let mut calls = JoinSet::new();
+        loop {
+            tokio::select! {
+                message = incoming.next() => {
+                    idle.as_mut().reset(Instant::now() + idle_timeout);
+                    let text = match message {
+                        Some(Ok(Message::Text(text))) => text,
+                        // Pings are answered by axum; binary frames carry no calls.
+                        Some(Ok(Message::Ping(_) | Message::Pong(_) | Message::Binary(_))) => continue,
+                        Some(Ok(Message::Close(_)) | Err(_)) | None => break,
+                    };
+                    let connection = self.clone();
+                    let out = out_tx.clone();
+                    calls.spawn(async move { connection.handle(text.as_str(), &out).await });
+                }
+                () = &mut idle => {
+                    debug!(addr = %self.addr, "Closing idle RPC connection");
+                    break;
+                }
+                Some(_) = calls.join_next(), if !calls.is_empty() => {}
+            }
+        }
+
+        // The peer is gone: stop in-flight calls as an HTTP disconnect would.
+        calls.abort_all();
+        for (_, task) in self.subscriptions.lock().unwrap().drain() {
+            task.abort();
+        }
+        writer.abort();
+    }
+
+    async fn handle(&self, text: &str, out: &mpsc::Sender<Value>) {
+        let value: Value = match serde_json::from_str(text) {
+            Ok(value) => value,
+            Err(e) => {
+                let error = RpcError::new(PARSE_ERROR, format!("parse error: {e}"));
+                let _ = out.send(response(Value::Null, Err(error))).await;
+                return;
+            }
+        };
+        let id = value.get("id").cloned().unwrap_or(Value::Null);
+        let request = match value {
+            Value::Array(_) => Err("batch requests are not supported".to_string()),
+            value => serde_json::from_value::<RpcRequest>(value)
+                .map_err(|e| e.to_string())
+                .and_then(|request| {
+                    if request.jsonrpc == JSONRPC_VERSION {
+                        Ok(request)
+                    } else {
+                        Err("jsonrpc must be \"2.0\"".to_string())
+                    }
+                }),
+        };
+        let request = match request {
+            Ok(request) => request,
+            Err(detail) => {
+                let error = RpcError::new(INVALID_REQUEST, format!("invalid request: {detail}"));
+                let _ = out.send(response(id, Err(error))).await;
+                return;
+            }
+        };
+
+        let result = self
+            .call(&request.method, request.params, request.id.as_ref(), out)
+            .await;
+        if let Some(id) = request.id {
+            let _ = out.send(response(id, result)).await;
+        }
+    }
+
+    async fn call(
+        &self,
+        method: &str,
+        params: Option<Value>,
+        id: Option<&Value>,
+        out: &mpsc::Sender<Value>,
+    ) -> Result<Value, RpcError> {
+        match plan(method, params)? {
+            Call::Http(call) => json_result(self.dispatch(call, APPLICATION_JSON).await).await,
+            Call::Stream(call) => self.stream(call, id, out).await,
+            Call::Subscribe { session_id } => self.subscribe(session_id, out).await,
+            Call::Unsubscribe { subscription } => {
+                let task = self.subscriptions.lock().unwrap().remove(&subscription);
+                match task {
+                    Some(task) => {
+                        task.abort();
+                        Ok(json!({ "subscription": subscription }))
+                    }
+                    None => Err(RpcError::invalid_params(format!(
+                        "unknown subscription '{subscription}'"
+                    ))),
+                }
+            }
+        }
+    }
+
+    /// Run a call through the `/api/v1` router as this connection's caller.
+    async fn dispatch(&self, call: HttpCall, accept: &'static str) -> Response {
+        let mut request = Request::new(match &call.body {
+            Some(body) => Body::from(body.to_string()),
+            None => Body::empty(),
+        });
+        *request.method_mut() = call.method;
+        *request.uri_mut() = call
+            .path
+            .parse()
+            .expect("paths are built from valid segments");
+        *request.headers_mut() = self.headers.clone();
+        request
+            .headers_mut()
+            .insert(header::ACCEPT, HeaderValue::from_static(accept));
+        if call.body.is_some() {
+            request.headers_mut().insert(
+                header::CONTENT_TYPE,
+                HeaderValue::from_static(APPLICATION_JSON),
+            );
+        }
+        if let Ok(uri) = format!("/api/v1{}", call.path).parse() {
+            request.extensions_mut().insert(OriginalUri(uri));
+        }
+        request.extensions_mut().insert(ConnectInfo(self.addr));
+
+        self.routes
+            .clone()
+            .oneshot(request)
+            .await
+            .unwrap_or_else(|never| match never {})
+    }
+
+    /// Forward a streaming run's events; the `done` event is the result.
+    async fn stream(
+        &self,
+        call: HttpCall,
+        id: Option<&Value>,
+        out: &mpsc::Sender<Value>,
+    ) -> Result<Value, RpcError> {
+        let response = self.dispatch(call, TEXT_EVENT_STREAM).await;
+        if !response.status().is_success() {
+            return json_result(response).await;
+        }
+
+        let mut events = SseEventStream::new(response.into_body().into_data_stream());
+        while let Some(event) = events.next().await {
+            let event =
+                event.map_err(|e| RpcError::new(INTERNAL_ERROR, format!("stream failed: {e}")))?;
+            let name = event.event.unwrap_or_else(|| "message".to_string());
+            let data = serde_json::from_str(&event.data).unwrap_or(Value::String(event.data));
+
+            match name.as_str() {
+                sse_events::DONE => return Ok(data),
+                sse_events::ERROR | sse_events::CANCELLED => {
+                    let message = data
+                        .get("message")
+                        .and_then(Value::as_str)
+                        .map_or_else(|| format!("stream {name}"), str::to_string);
+                    return Err(RpcError {
+                        code: STREAM_ERROR,
+                        message,
+                        data: Some(json!({ "event": name, "data": data })),
+                    });
+                }
+                _ => {}
+            }
+
+            // Notifications have no id to correlate events with.
+            if let Some(id) = id {
+                let params = json!({
+                    "request": id,
+                    "event": name,
+                    "event_id": event.id,
+                    "data": data,
+                });
+                if out
+                    .send(notification("stream.event", params))
+                    .await
+                    .is_err()
+                {
+                    break;
+                }
+            }
+        }
+        Err(RpcError::new(
+            STREAM_ERROR,
+            "stream ended before a terminal event",
+        ))
+    }
+
+    /// Forward a live session's events as `session.event` notifications.
+    async fn subscribe(
+        &self,
+        session_id: String,
+        out: &mpsc::Sender<Value>,
+    ) -> Result<Value, RpcError> {
+        // Subscribing needs the same access as reading the session.
+        let path = format!("/sessions/{session_id}");
+        json_result(self.dispatch(HttpCall::get(path), APPLICATION_JSON).await).await?;
+
+        let Some(handle) = self.state.services.session_registry.get(&session_id) else {
+            return Err(RpcError::invalid_params(format!(
+                "session '{session_id}' is not running"
+            )));
+        };
+        let mut events = handle
+            .subscribe_events()
+            .await
+            .map_err(|e| RpcError::new(INTERNAL_ERROR, e.to_string()))?;
+
+        let subscription = format!("sub_{}", Ulid::new());
+        let out = out.clone();
+        let id = subscription.clone();
+        let task = tokio::spawn(async move {
+            loop {
+                let frame = match events.recv().await {
+                    Ok(event) => notification(
+                        "session.event",
+                        json!({ "subscription": id, "event": event }),
+                    ),
+                    Err(broadcast::error::RecvError::Lagged(skipped)) => notification(
+                        "session.lagged",
+                        json!({ "subscription": id, "skipped": skipped }),
+                    ),
+                    Err(broadcast::error::RecvError::Closed) => {
+                        let frame = notification("session.closed", json!({ "subscription": id }));
+                        let _ = out.send(frame).await;
+                        break;
+                    }
+                };
+                if out.send(frame).await.is_err() {
+                    break;
+                }
+            }
+        });
+        self.subscriptions
+            .lock()
+            .unwrap()
+            .insert(subscription.clone(), task.abort_handle());
+
+        Ok(json!({ "subscription": subscription }))
+    }
+}
+
+/// A JSON response body as a call result, or its problem as an error.
+async fn json_result(response: Response) -> Result<Value, RpcError> {
+    let status = response.status();
+    let bytes = axum::body::to_bytes(response.into_body(), usize::MAX)
+        .await
+        .map_err(|e| RpcError::new(INTERNAL_ERROR, format!("unreadable response: {e}")))?;
+    let body = if bytes.is_empty() {
+        Value::Null
+    } else {
+        serde_json::from_slice(&bytes)
+            .unwrap_or_else(|_| Value::String(String::from_utf8_lossy(&bytes).into_owned()))
+    };
+
+    if status.is_success() {
+        Ok(body)
+    } else {
+        Err(RpcError::http(status, body))
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn params(value: Value) -> Option<Value> {
+        Some(value)
+    }
+
+    #[test]
+    fn plans_http_calls() {
+        assert_eq!(
+            plan("agents.get", params(json!({ "name": "helper" }))).unwrap(),
+            Call::Http(HttpCall::get("/agents/helper".into()))
+        );
+        assert_eq!(
+            plan(
+                "sessions.messages",
+                params(json!({ "session_id": "session_01", "limit": 10 }))
+            )
+            .unwrap(),
+            Call::Http(HttpCall::get(
+                "/sessions/session_01/messages?limit=10".into()
+            ))
+        );
+        assert_eq!(
+            plan(
+                "agents.list",
+                params(json!({ "selector": "team=ml,tier!=dev" }))
+            )
+            .unwrap(),
+            Call::Http(HttpCall::get(
+                "/agents?selector=team%3Dml%2Ctier%21%3Ddev".into()
+            ))
+        );
+        assert_eq!(
+            plan("sessions.list", None).unwrap(),
+            Call::Http(HttpCall::get("/sessions".into()))
+        );
+    }
+
+    #[test]
+    fn remaining_params_become_the_body() {
+        let call = plan(
+            "sessions.stream",
+            params(json!({ "session_id": "session_01", "content": "Hi" })),
+        )
+        .unwrap();
+        let Call::Stream(call) = call else {
+            panic!("expected a streaming call, got {call:?}");
+        };
+        assert_eq!(call.method, Method::POST);
+        assert_eq!(call.path, "/sessions/session_01/stream");
+        assert_eq!(call.body, Some(json!({ "content": "Hi" })));
+    }
+
+    #[test]
+    fn rejects_bad_params() {
+        let err = plan("sessions.get", None).unwrap_err();
+        assert_eq!(err.code, INVALID_PARAMS);
+        assert!(err.message.contains("session_id"));
+
+        let err = plan("sessions.get", params(json!({ "session_id": "../agents" }))).unwrap_err();
+        assert_eq!(err.code, INVALID_PARAMS);
+
+        let err = plan(
+            "sessions.get",
+            params(json!({ "session_id": "session_01", "extra": true })),
+        )
+        .unwrap_err();
+        assert_eq!(err.message, "unknown param 'extra'");
+
+        let err = plan("agents.list", params(json!(["positional"]))).unwrap_err();
+        assert_eq!(err.code, INVALID_PARAMS);
+    }
+
+    #[test]
+    fn unknown_methods_are_not_found() {
+        let err = plan("sessions.explode", None).unwrap_err();
+        assert_eq!(err.code, METHOD_NOT_FOUND);
+    }
+
+    #[test]
+    fn http_errors_keep_the_problem() {
+        let problem =
+            json!({ "title": "Not Found", "status": 404, "detail": "Agent not found: x" });
+        let err = RpcError::http(StatusCode::NOT_FOUND, problem.clone());
+        assert_eq!(err.code, HTTP_ERROR);
+        assert_eq!(err.message, "Agent not found: x");
+        assert_eq!(err.data, Some(json!({ "status": 404, "problem": problem })));
+    }
+
+    #[test]
+    fn handshake_headers_are_not_forwarded() {
+        let mut headers = HeaderMap::new();
+        headers.insert(header::AUTHORIZATION, "Bearer secret".parse().unwrap());
+        headers.insert(header::UPGRADE, "websocket".parse().unwrap());
+        headers.insert(header::SEC_WEBSOCKET_KEY, "abc".parse().unwrap());
+
+        let forwarded = forwarded_headers(&headers);
+        assert_eq!(
+            forwarded.get(header::AUTHORIZATION).unwrap(),
+            "Bearer secret"
+        );
+        assert!(forwarded.get(header::UPGRADE).is_none());
+        assert!(forwarded.get(header::SEC_WEBSOCKET_KEY).is_none());
+    }
+}
@@ -0,0 +1,395 @@
+//! Run management HTTP handlers.
+
+use std::convert::Infallible;
+use std::net::SocketAddr;
+use std::time::Duration;
+
+use axum::extract::{ConnectInfo, Path, Query, State};
+use axum::http::{HeaderMap, StatusCode};
+use axum::response::sse::{Event, KeepAlive, Sse};
+use axum::response::{IntoResponse, Response};
+use axum::{Extension, Json};
+use futures::StreamExt;
+use serde::Deserialize;
+use tokio_stream::wrappers::WatchStream;
+use tracing::{error, info, warn};
+
+use crate::api::{
+    BulkRequeueRequest, BulkRequeueResponse, DeadLetterSummary, ListDeadLettersResponse,
+    ListRunsResponse, RequeueDeadLetterResponse, RunResponse,
+};
+use crate::audit::{AuditEvent, AuditOutcome};
+use crate::handlers::api_auth::{self, ServiceAccountPrincipal};
+use crate::handlers::format::ResponseFormat;
+use crate::handlers::problem_details;
+use crate::handlers::validation::ValidJson;
+use crate::runs::Run;
+use crate::scheduler::{
+    DeadLetter, DeadLetterFilter, ErrorClass, SchedulePayload, SchedulerError, SchedulerHandle,
+};
+use crate::server::AppState;
+
+/// Longest a `GET /api/v1/runs/{id}` request waits for the run to finish.
+const MAX_WAIT: Duration = Duration::from_secs(60);
+
+/// Requeues per second for a bulk requeue that doesn't set a rate.
+const DEFAULT_REQUEUE_RATE_PER_SECOND: u32 = 2;
+
+#[derive(Deserialize)]
+pub struct ListRunsQuery {
+    /// Label selector, e.g. `order_id=A-1042`.
+    selector: Option<String>,
+    /// Only runs of this session.
+    session_id: Option<String>,
+}
+
+#[derive(Deserialize)]
+pub struct GetRunQuery {
+    /// How long to wait for the run to finish, e.g. `30s`, `500ms`, or `2m`.
+    wait: Option<String>,
+}
+
+/// GET /api/v1/runs
+///
+/// Recent message runs, newest first, filtered by the labels clients sent
+/// with them.
+pub async fn list_runs(
+    State(state): State<AppState>,
+    Query(query): Query<ListRunsQuery>,
+    format: ResponseFormat,
+) -> Response {
+    let selector = match super::agents::parse_selector(query.selector.as_deref()) {
+        Ok(selector) => selector,
+        Err(response) => return response,
+    };
+
+    let runs: Vec<RunResponse> = state
+        .runs
+        .list(&selector)
+        .into_iter()
+        .filter(|run| {
+            query
+                .session_id
+                .as_ref()
+                .is_none_or(|session_id| &run.session_id == session_id)
+        })
+        .map(run_response)
+        .collect();
+
+    format.respond_list(runs, |runs| ListRunsResponse { runs })
+}
+
+/// GET /api/v1/runs/{id}
+///
+/// A message run; only background runs, started with `background: true`
+/// or a `callback_url`, carry a result. With `wait`, responds as soon as the run finishes, or with the run still
+/// in progress once the wait (at most 60s) expires.
+pub async fn get_run(
+    State(state): State<AppState>,
+    Path(id): Path<String>,
+    Query(query): Query<GetRunQuery>,
+) -> Response {
+    let wait = match query.wait.as_deref().map(parse_wait) {
+        None => Duration::ZERO,
+        Some(Some(wait)) => wait.min(MAX_WAIT),
+        Some(None) => {
+            return problem_details::bad_request(
+                "wait must be a duration such as 30s, 500ms, or 2m",
+            )
+            .into_response();
+        }
+    };
+
+    let run = if wait.is_zero() {
+        state.runs.get(&id)
+    } else {
+        state.runs.wait(&id, wait).await
+    };
+    match run {
+        Some(run) => (StatusCode::OK, Json(run_response(run))).into_response(),
+        None => problem_details::not_found(format!("run '{id}' not found")).into_response(),
+    }
+}
+
+/// GET /api/v1/runs/{id}/events
+///
+/// Follow a run as Server-Sent Events.
+///
+/// Events emitted:
+/// - `status`: the run, as it is on connecting and on every change
+/// - `done`: the run once it finishes (or pauses for approval), with its
+///   result for background runs; the stream then ends
+pub async fn run_events(State(state): State<AppState>, Path(id): Path<String>) -> Response {
+    let Some(updates) = state.runs.subscribe(&id) else {
+        return problem_details::not_found(format!("run '{id}' not found")).into_response();
+    };
+
+    let events = futures::stream::unfold(
+        (WatchStream::new(updates), false),
+        |(mut updates, done)| async move {
+            if done {
+                return None;
+            }
+            let run = updates.next().await?;
+            let finished = run.is_finished();
+            let event = Event::default()
+                .event(if finished { "done" } else { "status" })
+                .json_data(run_response(run))
+                .unwrap_or_default();
+            Some((Ok::<_, Infallible>(event), (updates, finished)))
+        },
+    );
+
+    let keep_alive = KeepAlive::new()
+        .interval(Duration::from_secs(state.keep_alive_interval_seconds))
+        .text("keep-alive");
+    Sse::new(events).keep_alive(keep_alive).into_response()
+}
+
+/// GET /api/v1/runs/dead-letter
+///
+/// Scheduled runs that failed after exhausting their retries, oldest first.
+///
+/// Authorization: same as the admin endpoints, or a scoped service account.
+pub async fn list_dead_letters(
+    State(state): State<AppState>,
+    ConnectInfo(addr): ConnectInfo<SocketAddr>,
+    headers: HeaderMap,
+) -> Response {
+    if !api_auth::has_scoped_access(&state, &addr, &headers) {
+        return api_auth::admin_denied(&state, &addr);
+    }
+    let scheduler = match scheduler(&state) {
+        Ok(s) => s,
+        Err(response) => return response,
+    };
+
+    let dead_letters = match scheduler.list_dead_letters().await {
+        Ok(d) => d,
+        Err(e) => {
+            error!(error = %e, "failed to list dead letters");
+            return problem_details::internal_error("failed to list dead letters").into_response();
+        }
+    };
+
+    let dead_letters = dead_letters.into_iter().map(dead_letter_summary).collect();
+    (
+        StatusCode::OK,
+        Json(ListDeadLettersResponse { dead_letters }),
+    )
+        .into_response()
+}
+
+/// POST /api/v1/runs/dead-letter/{id}/requeue
+///
+/// Rerun a dead-lettered payload now as a new one-shot schedule and remove it
+/// from the dead letter list.
+///
+/// Authorization: same as the admin endpoints, or a scoped service account.
+pub async fn requeue_dead_letter(
+    State(state): State<AppState>,
+    ConnectInfo(addr): ConnectInfo<SocketAddr>,
+    headers: HeaderMap,
+    Path(id): Path<String>,
+) -> Response {
+    if !api_auth::has_scoped_access(&state, &addr, &headers) {
+        return api_auth::admin_denied(&state, &addr);
+    }
+    let scheduler = match scheduler(&state) {
+        Ok(s) => s,
+        Err(response) => return response,
+    };
+
+    match scheduler.requeue_dead_letter(&id).await {
+        Ok(schedule_id) => (
+            StatusCode::ACCEPTED,
+            Json(RequeueDeadLetterResponse { schedule_id }),
+        )
+            .into_response(),
+        Err(SchedulerError::DeadLetterNotFound(_)) => {
+            problem_details::not_found(format!("dead letter '{id}' not found")).into_response()
+        }
+        Err(e) => {
+            error!(dead_letter_id = %id, error = %e, "failed to requeue dead letter");
+            problem_details::internal_error("failed to requeue dead letter").into_response()
+        }
+    }
+}
+
+/// POST /api/v1/runs/dead-letter/requeue
+///
+/// Requeue every dead letter that failed in a time window, optionally only
+/// those of one error class or agent, e.g. after a provider outage. Matches
+/// are requeued oldest first in the background, at most `rate_per_second`
+/// at a time; the response lists them without waiting. Requeueing stops at
+/// server shutdown; dead letters not reached yet stay put.
+///
+/// Authorization: same as the admin endpoints, or a scoped service account.
+pub async fn bulk_requeue_dead_letters(
+    State(state): State<AppState>,
+    ConnectInfo(addr): ConnectInfo<SocketAddr>,
+    headers: HeaderMap,
+    service_account: Option<Extension<ServiceAccountPrincipal>>,
+    ValidJson(req): ValidJson<BulkRequeueRequest>,
+) -> Response {
+    if !api_auth::has_scoped_access(&state, &addr, &headers) {
+        return api_auth::admin_denied(&state, &addr);
+    }
+    let scheduler = match scheduler(&state) {
+        Ok(s) => s.clone(),
+        Err(response) => return response,
+    };
+
+    let dead_letters = match scheduler.list_dead_letters().await {
+        Ok(d) => d,
+        Err(e) => {
+            error!(error = %e, "failed to list dead letters");
+            return problem_details::internal_error("failed to list dead letters").into_response();
+        }
+    };
+
+    let filter = dead_letter_filter(&req);
+    let ids: Vec<String> = dead_letters
+        .into_iter()
+        .filter(|d| filter.matches(d))
+        .map(|d| d.id)
+        .collect();
+    let response = BulkRequeueResponse {
+        dead_letter_ids: ids.clone(),
+        dry_run: req.dry_run,
+    };
+    if req.dry_run || ids.is_empty() {
+        return (StatusCode::OK, Json(response)).into_response();
+    }
+
+    let rate = req
+        .rate_per_second
+        .unwrap_or(DEFAULT_REQUEUE_RATE_PER_SECOND);
+    let interval = Duration::from_secs(1) / rate;
+    info!(count = ids.len(), rate, "Bulk requeueing dead letters");
+    api_auth::audit_scoped(
+        &state,
+        &addr,
+        service_account.as_deref(),
+        AuditEvent::new("dead_letters.requeued", AuditOutcome::Success)
+            .detail(format!("count={}", ids.len())),
+    );
+    let runs = state.runs.clone();
+    state.background_tasks.spawn(async move {
+        let mut ticker = tokio::time::interval(interval);
+        for (done, id) in ids.iter().enumerate() {
+            tokio::select! {
+                _ = ticker.tick() => {}
+                () = runs.shutting_down() => {
+                    info!(remaining = ids.len() - done, "Bulk requeue stopped by shutdown");
+                    return;
+                }
+            }
+            match scheduler.requeue_dead_letter(id).await {
+                Ok(_) => {}
+                // Requeued or removed since it was listed
+                Err(SchedulerError::DeadLetterNotFound(_)) => {}
+                Err(e) => {
+                    warn!(dead_letter_id = %id, error = %e, "Failed to requeue dead letter");
+                }
+            }
+        }
+    });
+    (StatusCode::ACCEPTED, Json(response)).into_response()
+}
+
+// ============================================================================
+// Helper Functions
+// ============================================================================
+
+fn scheduler(state: &AppState) -> Result<&SchedulerHandle, Response> {
+    state
+        .scheduler
+        .as_ref()
+        .ok_or_else(|| problem_details::internal_error("scheduler not available").into_response())
+}
+
+/// Parse a wait like `30s`, `500ms`, or `2m`; bare numbers are seconds.
+fn parse_wait(value: &str) -> Option<Duration> {
+    let value = value.trim();
+    let split = value
+        .find(|c: char| !c.is_ascii_digit())
+        .unwrap_or(value.len());
+    let (amount, unit) = value.split_at(split);
+    let amount: u64 = amount.parse().ok()?;
+    match unit {
+        "" | "s" => Some(Duration::from_secs(amount)),
+        "ms" => Some(Duration::from_millis(amount)),
+        "m" => Some(Duration::from_secs(amount.checked_mul(60)?)),
+        _ => None,
+    }
+}
+
+fn run_response(run: Run) -> RunResponse {
+    RunResponse {
+        run_id: run.run_id,
+        session_id: run.session_id,
+        labels: run.labels,
+        status: run.status,
+        http_status: run.http_status,
+        error_class: run.error_class,
+        result: run.result,
+        created_at: run.created_at.to_rfc3339(),
+        finished_at: run.finished_at.map(|t| t.to_rfc3339()),
+    }
+}
+
+/// The filter a bulk requeue request selects with. Expects a validated request.
+fn dead_letter_filter(req: &BulkRequeueRequest) -> DeadLetterFilter {
+    let parse_time = |value: &Option<String>| {
+        value
+            .as_deref()
+            .and_then(|v| chrono::DateTime::parse_from_rfc3339(v).ok())
+            .map(|t| t.with_timezone(&chrono::Utc))
+    };
+    DeadLetterFilter {
+        since: parse_time(&req.since),
+        until: parse_time(&req.until),
+        error_class: req.error_class.as_deref().and_then(ErrorClass::parse),
+        agent: req.agent.clone(),
+    }
+}
+
+fn dead_letter_summary(dead_letter: DeadLetter) -> DeadLetterSummary {
+    let (kind, content) = match dead_letter.payload {
+        SchedulePayload::Message { message } => ("message", message),
+        SchedulePayload::Task { task } => ("task", task),
+    };
+    DeadLetterSummary {
+        id: dead_letter.id,
+        schedule_id: dead_letter.schedule_id,
+        agent: dead_letter.agent,
+        gateway: dead_letter.destination.gateway,
+        chat_id: dead_letter.destination.chat_id,
+        kind: kind.to_string(),
+        content,
+        attempts: dead_letter.attempts,
+        error_class: ErrorClass::classify(&dead_letter.error)
+            .as_str()
+            .to_string(),
+        error: dead_letter.error,
+        started_at: dead_letter.started_at.to_rfc3339(),
+        failed_at: dead_letter.failed_at.to_rfc3339(),
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn parses_waits() {
+        assert_eq!(parse_wait("30s"), Some(Duration::from_secs(30)));
+        assert_eq!(parse_wait("30"), Some(Duration::from_secs(30)));
+        assert_eq!(parse_wait("500ms"), Some(Duration::from_millis(500)));
+        assert_eq!(parse_wait("2m"), Some(Duration::from_secs(120)));
+        assert_eq!(parse_wait("1h"), None);
+        assert_eq!(parse_wait("s"), None);
+        assert_eq!(parse_wait("-5s"), None);
+    }
+}
@@ -0,0 +1,270 @@
+//! JSON Schema and OpenAPI publication handlers.
+
+use axum::Json;
+use axum::extract::State;
+use axum::http::header;
+use axum::response::IntoResponse;
+use serde_json::{Value, json};
+
+use crate::server::AppState;
+
+/// JSON Schema for `agent.yaml` manifests.
+///
+/// Maintained alongside the agent spec types in `duragent-types`. The tests
+/// compare its properties with the fields those types deserialize, so a
+/// manifest field added to one but not the other fails them.
+pub const AGENT_MANIFEST_SCHEMA: &str = include_str!("../../../schemas/agent-manifest.schema.json");
+
+/// OpenAPI document for the core agent and session API.
+///
+/// Maintained alongside the types in `duragent-client`'s `api` module; the
+/// SDKs in `sdk/` are generated from it.
+pub const OPENAPI_DOCUMENT: &str = include_str!("../../../schemas/openapi.json");
+
+/// Content type for JSON Schema documents.
+const CONTENT_TYPE_SCHEMA_JSON: &str = "application/schema+json";
+
+pub async fn agent_manifest_schema() -> impl IntoResponse {
+    (
+        [(header::CONTENT_TYPE, CONTENT_TYPE_SCHEMA_JSON)],
+        AGENT_MANIFEST_SCHEMA,
+    )
+}
+
+/// The OpenAPI document, with the input schema of every agent that declares
+/// one.
+pub async fn openapi_document(State(state): State<AppState>) -> impl IntoResponse {
+    let mut document: Value =
+        serde_json::from_str(OPENAPI_DOCUMENT).expect("OpenAPI document must be valid JSON");
+    let mut agents = state.services.agents.snapshot();
+    agents.sort_by(|(a, _), (b, _)| a.cmp(b));
+    add_agent_inputs(
+        &mut document,
+        agents
+            .iter()
+            .filter_map(|(name, spec)| Some((name.as_str(), spec.input_schema.as_ref()?))),
+    );
+    Json(document)
+}
+
+/// Add each agent's input schema as `components.schemas["AgentInput.<name>"]`,
+/// listed by agent name under the top-level `x-agent-inputs`.
+fn add_agent_inputs<'a>(document: &mut Value, inputs: impl Iterator<Item = (&'a str, &'a Value)>) {
+    let mut refs = serde_json::Map::new();
+    for (name, schema) in inputs {
+        let component = format!("AgentInput.{name}");
+        document["components"]["schemas"][&component] = schema.clone();
+        refs.insert(
+            name.to_string(),
+            json!({ "$ref": format!("#/components/schemas/{component}") }),
+        );
+    }
+    if !refs.is_empty() {
+        document["x-agent-inputs"] = Value::Object(refs);
+    }
+}
+
+// ============================================================================
+// Tests
+// ============================================================================
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn schema() -> serde_json::Value {
+        serde_json::from_str(AGENT_MANIFEST_SCHEMA).expect("schema must be valid JSON")
+    }
+
+    #[test]
+    fn schema_declares_api_version_and_kind() {
+        let schema = schema();
+        assert_eq!(
+            schema["properties"]["apiVersion"]["const"],
+            crate::agent::API_VERSION_V1ALPHA1
+        );
+        assert_eq!(
+            schema["properties"]["kind"]["const"],
+            crate::agent::KIND_AGENT
+        );
+    }
+
+    /// Field names a derived `Deserialize` accepts, aliases included, as
+    /// passed to `deserialize_struct`.
+    fn serde_fields<T: serde::de::DeserializeOwned>() -> &'static [&'static str] {
+        use serde::de::{self, Visitor};
+
+        struct Fields<'a>(&'a mut &'static [&'static str]);
+
+        impl<'de> de::Deserializer<'de> for Fields<'_> {
+            type Error = de::value::Error;
+
+            fn deserialize_any<V: Visitor<'de>>(self, _: V) -> Result<V::Value, Self::Error> {
+                Err(de::Error::custom("not a struct"))
+            }
+
+            fn deserialize_struct<V: Visitor<'de>>(
+                self,
+                _name: &'static str,
+                fields: &'static [&'static str],
+                _visitor: V,
+            ) -> Result<V::Value, Self::Error> {
+                *self.0 = fields;
+                Err(de::Error::custom("fields captured"))
+            }
+
+            serde::forward_to_deserialize_any! {
+                bool i8 i16 i32 i64 i128 u8 u16 u32 u64 u128 f32 f64 char str string
+                bytes byte_buf option unit unit_struct newtype_struct seq tuple
+                tuple_struct map enum identifier ignored_any
+            }
+        }
+
+        let mut fields: &'static [&'static str] = &[];
+        let _ = T::deserialize(Fields(&mut fields));
+        assert!(
+            !fields.is_empty(),
+            "{} is not a plain struct",
+            std::any::type_name::<T>()
+        );
+        fields
+    }
+
+    /// The schema object at `pointer`, following a `$ref` at the end.
+    fn schema_at<'a>(schema: &'a Value, pointer: &str) -> &'a Value {
+        let node = schema
+            .pointer(pointer)
+            .unwrap_or_else(|| panic!("schema has no {pointer}"));
+        match node["$ref"].as_str() {
+            Some(target) => schema_at(schema, target.trim_start_matches('#')),
+            None => node,
+        }
+    }
+
+    fn assert_schema_matches<T: serde::de::DeserializeOwned>(schema: &Value, pointer: &str) {
+        let mut fields: Vec<&str> = serde_fields::<T>().to_vec();
+        fields.sort_unstable();
+        let properties = schema_at(schema, pointer)["properties"]
+            .as_object()
+            .unwrap_or_else(|| panic!("schema {pointer} has no properties"));
+        let mut documented: Vec<&str> = properties.keys().map(String::as_str).collect();
+        documented.sort_unstable();
+        assert_eq!(
+            documented,
+            fields,
+            "schema {pointer} out of sync with {}",
+            std::any::type_name::<T>()
+        );
+    }
+
+    #[test]
+    fn schema_covers_spec_fields() {
+        use crate::agent::{
+            AccessConfig, AgentExamplesConfig, AgentLanguageConfig, AgentMemoryConfig,
+            AgentMetadata, AgentRoute, AgentSessionConfig, BestOfConfig, ContextConfig,
+            DmAccessConfig, EnsembleConfig, EnsembleMember, EntityMemoryConfig, GroupAccessConfig,
+            HooksConfig, ModelConfig, ModelRoute, ModelRoutingConfig, PlanningConfig,
+            PublicAccessConfig, RawAgentSpec, RawAgentSpecBody, RouteConditions, RouterConfig,
+            RoutingClassifierConfig, UserMemoryConfig,
+        };
+
+        let schema = schema();
+        assert_schema_matches::<RawAgentSpec>(&schema, "");
+        assert_schema_matches::<RawAgentSpecBody>(&schema, "/$defs/spec");
+        assert_schema_matches::<AgentMetadata>(&schema, "/$defs/metadata");
+        assert_schema_matches::<ModelConfig>(&schema, "/$defs/model");
+        assert_schema_matches::<ModelRoutingConfig>(&schema, "/$defs/model_routing");
+        assert_schema_matches::<ModelRoute>(&schema, "/$defs/model_routing/properties/rules/items");
+        assert_schema_matches::<RoutingClassifierConfig>(&schema, "/$defs/routing_classifier");
+        assert_schema_matches::<RouteConditions>(&schema, "/$defs/route_conditions");
+        assert_schema_matches::<RouterConfig>(&schema, "/$defs/router");
+        assert_schema_matches::<AgentRoute>(&schema, "/$defs/router/properties/rules/items");
+        assert_schema_matches::<BestOfConfig>(&schema, "/$defs/best_of");
+        assert_schema_matches::<EnsembleConfig>(&schema, "/$defs/ensemble");
+        assert_schema_matches::<EnsembleMember>(
+            &schema,
+            "/$defs/ensemble/properties/members/items",
+        );
+        assert_schema_matches::<PlanningConfig>(&schema, "/$defs/planning");
+        assert_schema_matches::<AgentSessionConfig>(&schema, "/$defs/session");
+        assert_schema_matches::<ContextConfig>(&schema, "/$defs/session/properties/context");
+        assert_schema_matches::<AccessConfig>(&schema, "/$defs/access");
+        assert_schema_matches::<DmAccessConfig>(&schema, "/$defs/access/properties/dm");
+        assert_schema_matches::<GroupAccessConfig>(&schema, "/$defs/access/properties/groups");
+        assert_schema_matches::<PublicAccessConfig>(&schema, "/$defs/access/properties/public");
+        assert_schema_matches::<AgentMemoryConfig>(&schema, "/$defs/memory");
+        assert_schema_matches::<UserMemoryConfig>(&schema, "/$defs/memory/properties/user");
+        assert_schema_matches::<EntityMemoryConfig>(&schema, "/$defs/memory/properties/entities");
+        assert_schema_matches::<AgentExamplesConfig>(&schema, "/$defs/examples");
+        assert_schema_matches::<AgentLanguageConfig>(&schema, "/$defs/spec/properties/language");
+        assert_schema_matches::<HooksConfig>(&schema, "/$defs/hooks");
+    }
+
+    fn openapi() -> serde_json::Value {
+        serde_json::from_str(OPENAPI_DOCUMENT).expect("OpenAPI document must be valid JSON")
+    }
+
+    #[test]
+    fn openapi_describes_every_stream_event() {
+        use crate::api::sse;
+
+        let openapi = openapi();
+        let events = &openapi["components"]["responses"]["EventStream"]["content"]["text/event-stream"]
+            ["x-events"];
+        for event in [
+            sse::START,
+            sse::TOKEN,
+            sse::REASONING,
+            sse::DONE,
+            sse::ERROR,
+            sse::CANCELLED,
+            sse::APPROVAL_REQUIRED,
+            sse::TOOL_CALL,
+            sse::TOOL_RESULT,
+        ] {
+            assert!(events.get(event).is_some(), "OpenAPI missing event {event}");
+        }
+    }
+
+    #[test]
+    fn openapi_publishes_agent_inputs() {
+        let mut openapi = openapi();
+        let schema = json!({
+            "type": "object",
+            "required": ["ticket_id"],
+            "properties": { "ticket_id": { "type": "string" } }
+        });
+        add_agent_inputs(&mut openapi, [("triage", &schema)].into_iter());
+
+        assert_eq!(
+            openapi["components"]["schemas"]["AgentInput.triage"],
+            schema
+        );
+        let target = openapi["x-agent-inputs"]["triage"]["$ref"]
+            .as_str()
+            .unwrap();
+        assert_eq!(
+            openapi.pointer(target.trim_start_matches('#')),
+            Some(&schema)
+        );
+    }
+
+    #[test]
+    fn openapi_refs_resolve() {
+        fn check(openapi: &serde_json::Value, value: &serde_json::Value) {
+            match value {
+                serde_json::Value::Object(map) => {
+                    if let Some(serde_json::Value::String(target)) = map.get("$ref") {
+                        let pointer = target.trim_start_matches('#');
+                        assert!(openapi.pointer(pointer).is_some(), "dangling $ref {target}");
+                    }
+                    map.values().for_each(|v| check(openapi, v));
+                }
+                serde_json::Value::Array(items) => items.iter().for_each(|v| check(openapi, v)),
+                _ => {}
+            }
+        }
+        let openapi = openapi();
+        check(&openapi, &openapi);
+    }
+}
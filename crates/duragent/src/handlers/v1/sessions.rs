@@ -1,101 +1,143 @@
 //! Session management HTTP handlers.
 
+use std::net::{IpAddr, SocketAddr};
 use std::sync::Arc;
 use std::time::Duration;
 
-use axum::Json;
-use axum::extract::{Path as PathExtract, Query, State};
-use axum::http::StatusCode;
+use axum::extract::{ConnectInfo, Path as PathExtract, Query, State};
+use axum::http::{HeaderMap, StatusCode, header};
 use axum::response::sse::{KeepAlive, Sse};
 use axum::response::{IntoResponse, Response};
+use axum::{Extension, Json};
+use chrono::{DateTime, Utc};
+use futures::StreamExt;
 use serde::Deserialize;
 use tokio_util::sync::CancellationToken;
-use tracing::{debug, error};
+use tracing::{debug, error, warn};
 use ulid::Ulid;
 
-use crate::agent::{AgentSpec, ModelConfigEval, OnDisconnect};
+use crate::agent::{
+    AgentSpec, ChangeSource, ModelConfigEval, OnDisconnect, PublicAccessConfig, RunOrigin,
+};
 use crate::api::{
-    ApprovalDecision, ApproveCommandRequest, CreateSessionRequest, CreateSessionResponse,
-    GetMessagesResponse, GetSessionResponse, ListSessionsResponse, MessageResponse,
-    PendingApprovalResponse, SendMessageRequest, SendMessageResponse, SessionStatus,
-    SessionSummary,
+    AcceptedRunResponse, ApprovalDecision, ApproveCommandRequest, CreatePublicSessionRequest,
+    CreateSessionRequest, CreateSessionResponse, GetMessagesResponse, GetSessionResponse,
+    ListSessionsResponse, MessageResponse, PendingApprovalResponse, PublicMessageRequest,
+    PublicSessionResponse, RunStatsResponse, RunStatus, SendMessageResponse, SessionStatus,
+    SessionSummary, ToolRestriction,
+};
+use crate::artifacts::Attachment;
+use crate::callbacks;
+use crate::context::{ContextBuilder, TokenBudget, load_all_directives_async, shrink_context};
+use crate::handlers::api_auth::{self, ServiceAccountPrincipal};
+use crate::handlers::format::ResponseFormat;
+use crate::handlers::message_body::MessageBody;
+use crate::handlers::problem_details::{self, FieldError};
+use crate::handlers::validation::ValidJson;
+use crate::input_schema;
+use crate::llm::{
+    ChatRequest, ChatResponse, ChatStream, LLMError, LLMProvider, Message, ProviderErrorClass, Role,
 };
-use crate::context::{ContextBuilder, TokenBudget, load_all_directives_async};
-use crate::handlers::problem_details;
-use crate::llm::{ChatRequest, LLMProvider, Role};
+use crate::postprocess;
+use crate::runs;
 use crate::server::AppState;
+use crate::session::export::{ExportFormat, Transcript};
+use crate::session::stream_buffer::{self, parse_event_id};
 use crate::session::{
-    AccumulatingStream, AgenticResult, ApprovalDecisionType, ResumeContext, SessionHandle,
-    StreamConfig, resume_agentic_loop, run_agentic_loop,
+    AccumulatingStream, AgenticError, AgenticResult, ApprovalDecisionType, ResumeContext,
+    RunPriority, RunStats, SessionHandle, StoredSession, StreamConfig, resume_agentic_loop,
+    run_agentic_loop,
 };
-use crate::tools::{ReloadDeps, ToolDependencies, ToolResult, build_executor_async};
+use crate::stream_processors::{self, StreamTransform};
+use crate::tools::{PLAN_TOOL, ReloadDeps, ToolDependencies, ToolResult, build_executor_async};
+
+use super::agents::parse_selector;
+
+/// SSE reconnection header carrying the last received event ID.
+const LAST_EVENT_ID: &str = "last-event-id";
 
 // ============================================================================
 // Query Types
 // ============================================================================
 
+#[derive(Deserialize)]
+pub struct ListSessionsQuery {
+    /// Label selector over each session's agent, e.g. `team=ml`.
+    selector: Option<String>,
+}
+
 #[derive(Deserialize)]
 pub struct GetMessagesQuery {
     limit: Option<u32>,
 }
 
+#[derive(Deserialize)]
+pub struct ExportSessionQuery {
+    /// `md` (the default), `html`, or `pdf`.
+    format: Option<String>,
+}
+
 // ============================================================================
 // Handlers
 // ============================================================================
 
 /// GET /api/v1/sessions
-pub async fn list_sessions(State(state): State<AppState>) -> Json<ListSessionsResponse> {
+///
+/// With `selector`, only sessions whose agent is loaded and matches it are listed.
+pub async fn list_sessions(
+    State(state): State<AppState>,
+    Query(query): Query<ListSessionsQuery>,
+    format: ResponseFormat,
+) -> Response {
+    let selector = match parse_selector(query.selector.as_deref()) {
+        Ok(selector) => selector,
+        Err(response) => return response,
+    };
+    let agents = &state.services.agents;
+
     let sessions: Vec<SessionSummary> = state
         .services
         .session_registry
         .list()
         .await
         .into_iter()
+        .filter(|m| {
+            selector.is_empty()
+                || agents
+                    .get(&m.agent)
+                    .is_some_and(|spec| selector.matches(&spec.metadata.labels))
+        })
         .map(|m| SessionSummary {
             session_id: m.id,
             agent: m.agent,
             status: m.status,
             created_at: m.created_at.to_rfc3339(),
+            source: m.origin.as_ref().map(|o| o.source),
+            created_by: m.origin.as_ref().and_then(|o| o.created_by.clone()),
+            user: m.origin.and_then(|o| o.user),
         })
         .collect();
 
-    Json(ListSessionsResponse { sessions })
+    format.respond_list(sessions, |sessions| ListSessionsResponse { sessions })
 }
 
 /// POST /api/v1/sessions
 pub async fn create_session(
     State(state): State<AppState>,
-    Json(req): Json<CreateSessionRequest>,
+    service_account: Option<Extension<ServiceAccountPrincipal>>,
+    ValidJson(req): ValidJson<CreateSessionRequest>,
 ) -> impl IntoResponse {
     let Some(agent_spec) = state.services.agents.get(&req.agent) else {
-        return problem_details::not_found(format!("agent '{}' not found", req.agent))
-            .into_response();
+        return problem_details::agent_not_found(&req.agent).into_response();
     };
+    if !agent_spec.enabled {
+        return problem_details::agent_disabled(&req.agent).into_response();
+    }
 
-    // Create session via registry - actor records SessionStart event automatically
-    let handle = match state
-        .services
-        .session_registry
-        .create(
-            &req.agent,
-            crate::session::CreateSessionOpts {
-                on_disconnect: agent_spec.session.on_disconnect,
-                gateway: None,
-                gateway_chat_id: None,
-                silent_buffer_cap: crate::session::DEFAULT_SILENT_BUFFER_CAP,
-                actor_message_limit: crate::session::actor_message_limit(
-                    agent_spec.model.effective_max_input_tokens(),
-                ),
-                compaction_override: agent_spec.session.compaction,
-            },
-        )
-        .await
-    {
+    let origin = api_origin(&state, service_account, req.user.clone());
+    let handle = match open_session(&state, &agent_spec, origin).await {
         Ok(h) => h,
-        Err(e) => {
-            error!(error = %e, "failed to create session");
-            return problem_details::internal_error("failed to create session").into_response();
-        }
+        Err(response) => return response,
     };
 
     // Get metadata for response
@@ -113,18 +155,95 @@ pub async fn create_session(
         agent: metadata.agent,
         status: metadata.status,
         created_at: metadata.created_at.to_rfc3339(),
+        source: metadata.origin.as_ref().map(|o| o.source),
+        created_by: metadata.origin.as_ref().and_then(|o| o.created_by.clone()),
+        user: metadata.origin.and_then(|o| o.user),
     };
 
-    (StatusCode::CREATED, Json(response)).into_response()
+    let location = session_url(&state, &response.session_id);
+    (
+        StatusCode::CREATED,
+        [(header::LOCATION, location)],
+        Json(response),
+    )
+        .into_response()
+}
+
+/// Origin of a session started over the API, attributed to the caller.
+fn api_origin(
+    state: &AppState,
+    service_account: Option<Extension<ServiceAccountPrincipal>>,
+    user: Option<String>,
+) -> RunOrigin {
+    RunOrigin {
+        source: ChangeSource::Api,
+        created_by: Some(match service_account {
+            Some(Extension(account)) => account.created_by(),
+            None => api_auth::principal(&state.api_token, "api"),
+        }),
+        user,
+    }
+}
+
+/// Create a session for an agent.
+async fn open_session(
+    state: &AppState,
+    agent_spec: &AgentSpec,
+    origin: RunOrigin,
+) -> Result<SessionHandle, Response> {
+    // Create session via registry - actor records SessionStart event automatically
+    state
+        .services
+        .session_registry
+        .create(
+            &agent_spec.metadata.name,
+            crate::session::CreateSessionOpts {
+                on_disconnect: agent_spec.session.on_disconnect,
+                gateway: None,
+                gateway_chat_id: None,
+                silent_buffer_cap: crate::session::DEFAULT_SILENT_BUFFER_CAP,
+                actor_message_limit: crate::session::actor_message_limit(
+                    agent_spec.model.effective_max_input_tokens(),
+                ),
+                compaction_override: agent_spec.session.compaction,
+                origin: Some(origin),
+            },
+        )
+        .await
+        .map_err(|e| {
+            error!(error = %e, "failed to create session");
+            problem_details::internal_error("failed to create session").into_response()
+        })
 }
 
 /// GET /api/v1/sessions/{session_id}
+///
+/// Sessions that are no longer live are read from the session store, or from
+/// the archive once they have been moved there.
 pub async fn get_session(
     State(state): State<AppState>,
     PathExtract(session_id): PathExtract<String>,
 ) -> impl IntoResponse {
     let Some(handle) = state.services.session_registry.get(&session_id) else {
-        return problem_details::not_found("session not found").into_response();
+        let snapshot = match load_stored_session(&state, &session_id).await {
+            Ok(stored) => stored.snapshot,
+            Err(response) => return response,
+        };
+        let response = GetSessionResponse {
+            session_id: snapshot.session_id,
+            agent: snapshot.agent,
+            status: snapshot.status,
+            created_at: snapshot.created_at.to_rfc3339(),
+            updated_at: Some(snapshot.snapshot_at.to_rfc3339()),
+            source: snapshot.config.origin.as_ref().map(|o| o.source),
+            created_by: snapshot
+                .config
+                .origin
+                .as_ref()
+                .and_then(|o| o.created_by.clone()),
+            user: snapshot.config.origin.and_then(|o| o.user),
+        };
+        return (StatusCode::OK, Json(response)).into_response();
     };
 
     let metadata = match handle.get_metadata().await {
@@ -142,6 +261,9 @@ pub async fn get_session(
         status: metadata.status,
         created_at: metadata.created_at.to_rfc3339(),
         updated_at: Some(metadata.updated_at.to_rfc3339()),
+        source: metadata.origin.as_ref().map(|o| o.source),
+        created_by: metadata.origin.as_ref().and_then(|o| o.created_by.clone()),
+        user: metadata.origin.and_then(|o| o.user),
     };
 
     (StatusCode::OK, Json(response)).into_response()
@@ -153,7 +275,9 @@ pub async fn delete_session(
     PathExtract(session_id): PathExtract<String>,
 ) -> Response {
     let Some(handle) = state.services.session_registry.get(&session_id) else {
-        return problem_details::not_found("session not found").into_response();
+        return problem_details::session_not_found()
+            .with_instance(session_url(&state, &session_id))
+            .into_response();
     };
 
     // Mark session as completed so it won't be recovered
@@ -178,65 +302,438 @@ pub async fn delete_session(
         return problem_details::internal_error("failed to delete session").into_response();
     }
 
+    // Share links must not outlive the session
+    if let Err(e) = state.shares.revoke_session(&session_id).await {
+        error!(session_id = %session_id, error = %e, "failed to revoke session shares");
+        return problem_details::internal_error("failed to revoke session shares").into_response();
+    }
+
     StatusCode::NO_CONTENT.into_response()
 }
 
 /// GET /api/v1/sessions/{session_id}/messages
+///
+/// Falls back to the session store and archive like `get_session`.
 pub async fn get_messages(
     State(state): State<AppState>,
     PathExtract(session_id): PathExtract<String>,
     Query(query): Query<GetMessagesQuery>,
+    format: ResponseFormat,
 ) -> impl IntoResponse {
-    let Some(handle) = state.services.session_registry.get(&session_id) else {
-        return problem_details::not_found("session not found").into_response();
-    };
-
-    let messages = match handle.get_messages().await {
-        Ok(m) => m,
-        Err(e) => {
-            error!(error = %e, "failed to get messages");
-            return problem_details::internal_error("failed to get messages").into_response();
-        }
+    let messages = match state.services.session_registry.get(&session_id) {
+        Some(handle) => match handle.get_messages().await {
+            Ok(m) => m,
+            Err(e) => {
+                error!(error = %e, "failed to get messages");
+                return problem_details::internal_error("failed to get messages").into_response();
+            }
+        },
+        None => match load_stored_session(&state, &session_id).await {
+            Ok(stored) => stored.messages(),
+            Err(response) => return response,
+        },
     };
 
-    let iter = messages
-        .into_iter()
-        .filter(|m| m.role == Role::User || (m.role == Role::Assistant && m.tool_calls.is_none()))
-        .map(|m| MessageResponse {
-            role: m.role.to_string(),
-            content: m.content.unwrap_or_default(),
-        });
+    let iter = transcript(messages);
     let messages: Vec<_> = match query.limit {
         Some(limit) => iter.take(limit as usize).collect(),
         None => iter.collect(),
     };
 
-    (StatusCode::OK, Json(GetMessagesResponse { messages })).into_response()
+    format.respond_list(messages, |messages| GetMessagesResponse { messages })
+}
+
+/// GET /api/v1/sessions/{session_id}/export
+///
+/// The conversation as a document for people, with tool calls collapsed.
+/// Falls back to the session store and archive like `get_session`.
+pub async fn export_session(
+    State(state): State<AppState>,
+    PathExtract(session_id): PathExtract<String>,
+    Query(query): Query<ExportSessionQuery>,
+) -> Response {
+    let format = match query.format.as_deref().map(ExportFormat::parse) {
+        None => ExportFormat::Markdown,
+        Some(Some(format)) => format,
+        Some(None) => {
+            return problem_details::bad_request("format must be md, html, or pdf").into_response();
+        }
+    };
+    let conversation = match load_conversation(&state, &session_id).await {
+        Ok(c) => c,
+        Err(response) => return response,
+    };
+
+    let transcript = Transcript::new(
+        &session_id,
+        conversation.agent,
+        conversation.created_at,
+        &conversation.messages,
+    );
+    let disposition = format!(
+        "attachment; filename=\"{session_id}.{}\"",
+        format.extension()
+    );
+    (
+        StatusCode::OK,
+        [
+            (header::CONTENT_TYPE, format.content_type().to_string()),
+            (header::CONTENT_DISPOSITION, disposition),
+        ],
+        format.render(&transcript),
+    )
+        .into_response()
 }
 
 /// POST /api/v1/sessions/{session_id}/messages
 pub async fn send_message(
     State(state): State<AppState>,
+    ConnectInfo(addr): ConnectInfo<SocketAddr>,
+    headers: HeaderMap,
     PathExtract(session_id): PathExtract<String>,
-    Json(req): Json<SendMessageRequest>,
+    format: ResponseFormat,
+    MessageBody {
+        request: req,
+        attachments,
+    }: MessageBody,
 ) -> impl IntoResponse {
-    let ctx = match prepare_chat_context(&state, &session_id, req.content).await {
+    // Shed before the message is persisted so a retry doesn't duplicate it
+    if let Some(retry_after) = state.services.run_pool.should_shed(req.priority) {
+        return problem_details::overloaded(retry_after);
+    }
+
+    if let Some(url) = &req.callback_url
+        && let Err(reason) = state.callbacks.check_url(url).await
+    {
+        return problem_details::validation_failed(vec![FieldError::new("/callback_url", reason)])
+            .into_response();
+    }
+
+    let ctx = match prepare_chat_context(
+        &state,
+        &session_id,
+        req.content,
+        req.input,
+        req.tools,
+        attachments,
+    )
+    .await
+    {
+        Ok(ctx) => ctx,
+        Err(e) => return e.into_response(),
+    };
+
+    let show_reasoning = exposes_reasoning(&state, &addr, &headers, &ctx.agent_spec);
+    let run_id = format!("{}{}", crate::api::RUN_ID_PREFIX, Ulid::new());
+    state.runs.start(&run_id, &session_id, req.labels);
+    if !req.background && req.callback_url.is_none() {
+        let response = run_message(&state, ctx, req.priority, show_reasoning, format).await;
+        let error_class = runs::error_class(&response);
+        state
+            .runs
+            .finish(&run_id, response.status(), error_class, None);
+        return response;
+    }
+
+    // Run in the background
+    let accepted = AcceptedRunResponse {
+        run_id: run_id.clone(),
+        session_id,
+        status: RunStatus::Accepted,
+    };
+    spawn_run(
+        &state,
+        run_id,
+        ctx,
+        req.priority,
+        show_reasoning,
+        req.callback_url,
+    );
+
+    format.respond(StatusCode::ACCEPTED, &accepted)
+}
+
+/// POST /api/v1/agents/{name}/runs
+///
+/// Start a run of an agent in a new session. Takes the same body as
+/// `POST /api/v1/sessions/{session_id}/messages`, but always runs in the
+/// background: responds `202` with the run and the session it runs in.
+pub async fn start_agent_run(
+    State(state): State<AppState>,
+    ConnectInfo(addr): ConnectInfo<SocketAddr>,
+    headers: HeaderMap,
+    service_account: Option<Extension<ServiceAccountPrincipal>>,
+    PathExtract(name): PathExtract<String>,
+    format: ResponseFormat,
+    MessageBody {
+        request: req,
+        attachments,
+    }: MessageBody,
+) -> Response {
+    if let Some(retry_after) = state.services.run_pool.should_shed(req.priority) {
+        return problem_details::overloaded(retry_after);
+    }
+    if let Some(url) = &req.callback_url
+        && let Err(reason) = state.callbacks.check_url(url).await
+    {
+        return problem_details::validation_failed(vec![FieldError::new("/callback_url", reason)])
+            .into_response();
+    }
+
+    let Some(agent_spec) = state.services.agents.get(&name) else {
+        return problem_details::agent_not_found(&name).into_response();
+    };
+    if !agent_spec.enabled {
+        return problem_details::agent_disabled(&name).into_response();
+    }
+    let origin = api_origin(&state, service_account, None);
+    let handle = match open_session(&state, &agent_spec, origin).await {
+        Ok(h) => h,
+        Err(response) => return response,
+    };
+    let session_id = handle.id().to_string();
+
+    let ctx = match prepare_chat_context(
+        &state,
+        &session_id,
+        req.content,
+        req.input,
+        req.tools,
+        attachments,
+    )
+    .await
+    {
         Ok(ctx) => ctx,
         Err(e) => return e.into_response(),
     };
 
+    let show_reasoning = exposes_reasoning(&state, &addr, &headers, &ctx.agent_spec);
+    let run_id = format!("{}{}", crate::api::RUN_ID_PREFIX, Ulid::new());
+    state.runs.start(&run_id, &session_id, req.labels);
+    let accepted = AcceptedRunResponse {
+        run_id: run_id.clone(),
+        session_id,
+        status: RunStatus::Accepted,
+    };
+    spawn_run(
+        &state,
+        run_id,
+        ctx,
+        req.priority,
+        show_reasoning,
+        req.callback_url,
+    );
+
+    format.respond(StatusCode::ACCEPTED, &accepted)
+}
+
+/// POST /public/v1/agents/{name}/sessions
+///
+/// Start a conversation with a public agent (`access.public`), without
+/// credentials. Agents with `captcha: true` need a `captcha_token` their
+/// CAPTCHA provider accepts.
+pub async fn create_public_session(
+    State(state): State<AppState>,
+    ConnectInfo(addr): ConnectInfo<SocketAddr>,
+    headers: HeaderMap,
+    PathExtract(name): PathExtract<String>,
+    ValidJson(req): ValidJson<CreatePublicSessionRequest>,
+) -> Response {
+    let (agent_spec, ip) = match admit_public(&state, &name, addr, &headers) {
+        Ok(admitted) => admitted,
+        Err(response) => return response,
+    };
+
+    if public_access(&agent_spec).is_some_and(|public| public.captcha) {
+        if !state.public_agents.captcha_configured() {
+            error!(agent = %name, "Agent requires a CAPTCHA but public_agents.captcha is not configured");
+            return problem_details::internal_error("captcha verification is not configured")
+                .into_response();
+        }
+        let verified = match req.captcha_token.as_deref() {
+            Some(token) => state.public_agents.verify_captcha(token, ip).await,
+            None => false,
+        };
+        if !verified {
+            return problem_details::captcha_required(
+                "a verified captcha_token is required to start a conversation",
+            )
+            .into_response();
+        }
+    }
+
+    let origin = RunOrigin {
+        source: ChangeSource::Public,
+        created_by: None,
+        user: None,
+    };
+    let handle = match open_session(&state, &agent_spec, origin).await {
+        Ok(h) => h,
+        Err(response) => return response,
+    };
+    let metadata = match handle.get_metadata().await {
+        Ok(m) => m,
+        Err(e) => {
+            error!(error = %e, "failed to get session metadata");
+            return problem_details::internal_error("failed to get session metadata")
+                .into_response();
+        }
+    };
+
+    let response = PublicSessionResponse {
+        session_id: metadata.id,
+        agent: metadata.agent,
+        created_at: metadata.created_at.to_rfc3339(),
+    };
+    (StatusCode::CREATED, Json(response)).into_response()
+}
+
+/// POST /public/v1/agents/{name}/sessions/{session_id}/messages
+///
+/// Send a message in a conversation started with `create_public_session`
+/// and wait for the reply. Runs at low priority, so public traffic is shed
+/// first under load.
+pub async fn send_public_message(
+    State(state): State<AppState>,
+    ConnectInfo(addr): ConnectInfo<SocketAddr>,
+    headers: HeaderMap,
+    PathExtract((name, session_id)): PathExtract<(String, String)>,
+    format: ResponseFormat,
+    ValidJson(req): ValidJson<PublicMessageRequest>,
+) -> Response {
+    if let Err(response) = admit_public(&state, &name, addr, &headers) {
+        return response;
+    }
+    if let Some(retry_after) = state.services.run_pool.should_shed(RunPriority::Low) {
+        return problem_details::overloaded(retry_after);
+    }
+
+    // Only conversations started over the public API, with this agent
+    let is_public = match state.services.session_registry.get(&session_id) {
+        Some(handle) if handle.agent() == name => handle.get_metadata().await.is_ok_and(|m| {
+            m.origin
+                .is_some_and(|origin| origin.source == ChangeSource::Public)
+        }),
+        _ => false,
+    };
+    if !is_public {
+        return problem_details::session_not_found().into_response();
+    }
+
+    let ctx = match prepare_chat_context(
+        &state,
+        &session_id,
+        req.content,
+        req.input,
+        None,
+        Vec::new(),
+    )
+    .await
+    {
+        Ok(ctx) => ctx,
+        Err(e) => return e.into_response(),
+    };
+
+    let run_id = format!("{}{}", crate::api::RUN_ID_PREFIX, Ulid::new());
+    state.runs.start(&run_id, &session_id, Default::default());
+    let response = run_message(&state, ctx, RunPriority::Low, false, format).await;
+    let error_class = runs::error_class(&response);
+    state
+        .runs
+        .finish(&run_id, response.status(), error_class, None);
+    response
+}
+
+/// The agent's `access.public` settings, if it is public.
+fn public_access(agent: &AgentSpec) -> Option<&PublicAccessConfig> {
+    agent.access.as_ref()?.public.as_ref()
+}
+
+/// Look up the public agent `name` and count the request against the
+/// caller's rate limit. Agents that aren't public are reported as missing.
+fn admit_public(
+    state: &AppState,
+    name: &str,
+    addr: SocketAddr,
+    headers: &HeaderMap,
+) -> Result<(Arc<AgentSpec>, IpAddr), Response> {
+    let Some(agent_spec) = state.services.agents.get(name) else {
+        return Err(problem_details::agent_not_found(name).into_response());
+    };
+    let Some(requests_per_minute) = public_access(&agent_spec).map(|p| p.requests_per_minute)
+    else {
+        return Err(problem_details::agent_not_found(name).into_response());
+    };
+    if !agent_spec.enabled {
+        return Err(problem_details::agent_disabled(name).into_response());
+    }
+
+    let ip = state.public_agents.client_ip(addr, headers);
+    if let Err(retry_after) = state.public_agents.admit(name, ip, requests_per_minute) {
+        return Err(problem_details::rate_limited(retry_after));
+    }
+    Ok((agent_spec, ip))
+}
+
+/// Run a prepared message in the background. The outcome is kept for
+/// GET /api/v1/runs/{id} and delivered to the callback URL, if any.
+fn spawn_run(
+    state: &AppState,
+    run_id: String,
+    ctx: ChatContext,
+    priority: RunPriority,
+    show_reasoning: bool,
+    callback_url: Option<String>,
+) {
+    let task_state = state.clone();
+    state.background_tasks.spawn(async move {
+        let response = task_state
+            .runs
+            .bounded(run_message(
+                &task_state,
+                ctx,
+                priority,
+                show_reasoning,
+                ResponseFormat::Json,
+            ))
+            .await;
+        let error_class = runs::error_class(&response);
+        let (status, result) = runs::read_result(response).await;
+        let run = task_state
+            .runs
+            .finish(&run_id, status, error_class, Some(result));
+        if let (Some(callback_url), Some(run)) = (callback_url, run) {
+            let callback = callbacks::run_callback(run);
+            task_state.callbacks.deliver(&callback_url, &callback).await;
+        }
+    });
+}
+
+/// Run a prepared message to completion and build its response.
+async fn run_message(
+    state: &AppState,
+    ctx: ChatContext,
+    priority: RunPriority,
+    show_reasoning: bool,
+    format: ResponseFormat,
+) -> Response {
+    // Wait for a run slot; held until the response is built
+    let _permit = state.services.run_pool.acquire(priority).await;
+
     // Check if agent has tools configured
     if !ctx.agent_spec.tools.is_empty() {
         // Use agentic loop for tool-using agents
-        return send_message_agentic(&state, ctx).await;
+        return send_message_agentic(state, ctx, format).await;
     }
 
     // Simple single-turn for agents without tools
-    let chat_response = match ctx.provider.chat(ctx.request).await {
+    let chat_response = match chat_with_context_recovery(&ctx).await {
         Ok(resp) => resp,
         Err(e) => {
             error!(error = %e, "llm request failed");
-            return problem_details::internal_error("llm request failed").into_response();
+            return problem_details::provider_error("llm request failed")
+                .with_error_class(e.class())
+                .into_response();
         }
     };
 
@@ -245,6 +742,19 @@ pub async fn send_message(
         .first()
         .and_then(|c| c.message.content.clone())
         .unwrap_or_default();
+    let assistant_content =
+        stream_processors::apply(&ctx.agent_spec.stream_processors, assistant_content);
+    let assistant_content = postprocess::apply(&ctx.agent_spec.post_processors, assistant_content);
+    let reasoning = chat_response
+        .choices
+        .first()
+        .and_then(|c| c.message.reasoning.clone());
+
+    if let Some(reasoning) = reasoning.clone()
+        && let Err(e) = ctx.handle.record_reasoning(reasoning).await
+    {
+        error!(error = %e, "failed to record reasoning");
+    }
 
     // Persist assistant message via actor
     if let Err(e) = ctx
@@ -263,9 +773,11 @@ pub async fn send_message(
         message_id: format!("{}{}", crate::api::MESSAGE_ID_PREFIX, Ulid::new()),
         role: "assistant".to_string(),
         content: assistant_content,
+        reasoning: reasoning.filter(|_| show_reasoning),
+        stats: None,
     };
 
-    (StatusCode::OK, Json(response)).into_response()
+    format.respond(StatusCode::OK, &response)
 }
 
 /// POST /api/v1/sessions/{session_id}/stream
@@ -278,6 +790,8 @@ pub async fn send_message(
 /// - `start`: `{}` — signals streaming has begun
 /// - `token`: `{"content": "..."}` — streamed content chunks
 /// - `done`: `{"message_id": "msg_...", "usage": {...}}` — stream complete with message ID
+/// - `reasoning`: `{"content": "..."}` — model reasoning chunks, only with
+///   `sessions.expose_reasoning` and admin access or `runs:reasoning`
 /// - `cancelled`: `{}` — stream was cancelled (client disconnected)
 /// - `error`: `{"message": "..."}` — on error (timeout, LLM failure)
 ///
@@ -288,24 +802,77 @@ pub async fn send_message(
 /// - If agent has `on_disconnect: continue`, LLM continues in background, events are logged
 pub async fn stream_session(
     State(state): State<AppState>,
+    ConnectInfo(addr): ConnectInfo<SocketAddr>,
+    headers: HeaderMap,
     PathExtract(session_id): PathExtract<String>,
-    Json(req): Json<SendMessageRequest>,
+    MessageBody {
+        request: req,
+        attachments,
+    }: MessageBody,
 ) -> impl IntoResponse {
-    let ctx = match prepare_chat_context(&state, &session_id, req.content).await {
+    // Streams are consumed as they run and aren't recorded as runs
+    let mut errors = Vec::new();
+    if req.background {
+        errors.push(FieldError::new(
+            "/background",
+            "not supported when streaming",
+        ));
+    }
+    if req.callback_url.is_some() {
+        errors.push(FieldError::new(
+            "/callback_url",
+            "not supported when streaming",
+        ));
+    }
+    if !req.labels.is_empty() {
+        errors.push(FieldError::new("/labels", "not supported when streaming"));
+    }
+    if req.tools.is_some() {
+        errors.push(FieldError::new("/tools", "not supported when streaming"));
+    }
+    if !errors.is_empty() {
+        return problem_details::validation_failed(errors).into_response();
+    }
+
+    // Shed before the message is persisted so a retry doesn't duplicate it
+    if let Some(retry_after) = state.services.run_pool.should_shed(req.priority) {
+        return problem_details::overloaded(retry_after);
+    }
+
+    let ctx = match prepare_chat_context(
+        &state,
+        &session_id,
+        req.content,
+        req.input,
+        None,
+        attachments,
+    )
+    .await
+    {
         Ok(ctx) => ctx,
         Err(e) => return e.into_response(),
     };
 
+    let permit = state.services.run_pool.acquire(req.priority).await;
     let stream = match ctx.provider.chat_stream(ctx.request).await {
         Ok(s) => s,
         Err(e) => {
             error!(error = %e, "llm request failed");
-            return problem_details::internal_error("llm request failed").into_response();
+            return problem_details::provider_error("llm request failed")
+                .with_error_class(e.class())
+                .into_response();
         }
     };
+    // Hold the run slot for as long as the LLM stream is alive, including
+    // background completion in continue mode.
+    let stream: ChatStream = Box::pin(stream.map(move |event| {
+        let _ = &permit;
+        event
+    }));
 
     let message_id = format!("{}{}", crate::api::MESSAGE_ID_PREFIX, Ulid::new());
     let cancel_token = CancellationToken::new();
+    let replay = state.stream_buffers.create(&session_id, &message_id);
 
     debug!(
         session_id = %session_id,
@@ -324,6 +891,9 @@ pub async fn stream_session(
             cancel_token,
             on_disconnect: ctx.on_disconnect,
             background_tasks: state.background_tasks.clone(),
+            replay,
+            expose_reasoning: exposes_reasoning(&state, &addr, &headers, &ctx.agent_spec),
+            transform: StreamTransform::new(&ctx.agent_spec.stream_processors),
         },
     );
 
@@ -334,6 +904,50 @@ pub async fn stream_session(
     Sse::new(sse_stream).keep_alive(keep_alive).into_response()
 }
 
+/// GET /api/v1/sessions/{session_id}/stream
+///
+/// Resume a stream after a dropped connection. The `Last-Event-ID` header
+/// (the `id` of the last event received) selects the stream; events after it
+/// are replayed, then the stream is followed live until the run finishes.
+/// Finished streams stay resumable for a few minutes.
+pub async fn resume_stream(
+    State(state): State<AppState>,
+    PathExtract(session_id): PathExtract<String>,
+    headers: HeaderMap,
+) -> Response {
+    let Some((message_id, seq)) = headers
+        .get(LAST_EVENT_ID)
+        .and_then(|v| v.to_str().ok())
+        .and_then(parse_event_id)
+    else {
+        return problem_details::bad_request("missing or invalid Last-Event-ID header")
+            .into_response();
+    };
+
+    let Some(buffer) = state
+        .stream_buffers
+        .get(message_id)
+        .filter(|b| b.session_id() == session_id)
+    else {
+        return problem_details::not_found("stream not found or expired").into_response();
+    };
+
+    debug!(
+        session_id = %session_id,
+        message_id = %message_id,
+        after = seq,
+        "Resuming SSE stream"
+    );
+
+    let keep_alive = KeepAlive::new()
+        .interval(Duration::from_secs(state.keep_alive_interval_seconds))
+        .text("keep-alive");
+
+    Sse::new(stream_buffer::replay(buffer, Some(seq)))
+        .keep_alive(keep_alive)
+        .into_response()
+}
+
 /// POST /api/v1/sessions/{session_id}/approve
 ///
 /// Approve or deny a pending tool execution.
@@ -343,14 +957,25 @@ pub async fn stream_session(
 pub async fn approve_command(
     State(state): State<AppState>,
     PathExtract(session_id): PathExtract<String>,
-    Json(req): Json<ApproveCommandRequest>,
+    format: ResponseFormat,
+    ValidJson(req): ValidJson<ApproveCommandRequest>,
 ) -> impl IntoResponse {
     // Verify session exists and get handle
     let Some(handle) = state.services.session_registry.get(&session_id) else {
-        return problem_details::not_found("session not found").into_response();
+        return problem_details::session_not_found()
+            .with_instance(session_url(&state, &session_id))
+            .into_response();
     };
 
     let agent_name = handle.agent().to_string();
+    if state
+        .services
+        .agents
+        .get(&agent_name)
+        .is_some_and(|a| !a.enabled)
+    {
+        return problem_details::agent_disabled(&agent_name).into_response();
+    }
 
     // Load pending approval from actor (actor serializes access, no external lock needed)
     let pending = match handle.get_pending_approval().await {
@@ -368,10 +993,13 @@ pub async fn approve_command(
 
     // Validate call_id matches
     if pending.call_id != req.call_id {
-        return problem_details::bad_request(format!(
-            "call_id mismatch: expected '{}', got '{}'",
-            pending.call_id, req.call_id
-        ))
+        return problem_details::validation_failed(vec![FieldError::new(
+            "/call_id",
+            format!(
+                "does not match pending approval (expected '{}', got '{}')",
+                pending.call_id, req.call_id
+            ),
+        )])
         .into_response();
     }
 
@@ -392,14 +1020,17 @@ pub async fn approve_command(
             .into_response();
     }
 
-    // Get agent spec for tool execution
+    // Get agent spec for tool execution: the agent whose run is paused,
+    // which a router may have handed the message to
+    let agent_name = pending.agent.clone().unwrap_or(agent_name);
     let Some(agent_spec) = state.services.agents.get(&agent_name) else {
         return problem_details::internal_error("session references non-existent agent")
             .into_response();
     };
 
-    // If allow_always, save pattern to policy
+    // If allow_always, save pattern to policy (plans are approved one by one)
     if req.decision == ApprovalDecision::AllowAlways
+        && pending.tool_name != PLAN_TOOL
         && let Err(e) = crate::agent::add_policy_pattern_and_save(
             state.services.policy_store.as_ref(),
             &agent_name,
@@ -458,7 +1089,8 @@ pub async fn approve_command(
                 error!(error = %e, "Failed to build tool executor");
                 return problem_details::internal_error("executor init failed").into_response();
             }
-        };
+        }
+        .with_mocks(state.services.tool_mocks.clone());
 
         // Build tool call from pending approval
         let tool_call = crate::llm::ToolCall {
@@ -495,7 +1127,7 @@ pub async fn approve_command(
         )
         .await
     else {
-        return problem_details::internal_error("provider not configured").into_response();
+        return problem_details::provider_not_configured().into_response();
     };
 
     // Create executor for resume (uses same policy loaded above)
@@ -531,22 +1163,34 @@ pub async fn approve_command(
         agent_dir: agent_spec.agent_dir.clone(),
         workspace_tools_dir: Some(state.services.workspace_tools_path.clone()),
         agent_tool_configs: agent_spec.tools.clone(),
-    });
+    })
+    .with_mocks(state.services.tool_mocks.clone());
 
     // Set session to Running before resuming (accurate status during execution)
     if let Err(e) = handle.set_status(SessionStatus::Running).await {
         debug!(error = %e, "Failed to set session status to Running");
     }
 
-    // Extract tool_refs from agent spec (consistent with run path)
-    let tool_refs = ContextBuilder::new()
-        .from_agent_spec(&agent_spec)
-        .build()
-        .tool_refs;
+    // Keep the paused run's tool restriction, else extract tool_refs from
+    // agent spec (consistent with run path)
+    let tool_refs = match &pending.tools {
+        Some(tools) => Some(tools.iter().cloned().collect()),
+        None => {
+            ContextBuilder::new()
+                .from_agent_spec(&agent_spec)
+                .build()
+                .tool_refs
+        }
+    };
 
     // Acquire per-session agentic loop lock to prevent concurrent loops
     let loop_lock = state.services.agentic_loop_locks.get(&session_id);
     let _loop_guard = loop_lock.lock().await;
+    let _permit = state.services.run_pool.acquire(RunPriority::Normal).await;
+    let provider = state
+        .services
+        .run_provider(&agent_spec, provider, &handle)
+        .await;
 
     // Resume the agentic loop
     let result = match resume_agentic_loop(
@@ -567,13 +1211,12 @@ pub async fn approve_command(
         Err(e) => {
             // Reset to Active on error
             let _ = handle.set_status(SessionStatus::Active).await;
-            error!(error = %e, "agentic loop failed");
-            return problem_details::internal_error("agentic loop failed").into_response();
+            return agentic_error_response(e);
         }
     };
 
     // Handle result using shared helper
-    handle_agentic_result(&handle, result, true).await
+    handle_agentic_result(&handle, result, true, format).await
 }
 
 // ============================================================================
@@ -585,6 +1228,8 @@ pub async fn approve_command(
 enum SendMessageError {
     SessionNotFound,
     AgentNotFound,
+    AgentDisabled(String),
+    InvalidInput(Vec<FieldError>),
     PersistFailed,
     ProviderNotConfigured,
 }
@@ -592,16 +1237,16 @@ enum SendMessageError {
 impl IntoResponse for SendMessageError {
     fn into_response(self) -> Response {
         match self {
-            Self::SessionNotFound => problem_details::not_found("session not found"),
+            Self::SessionNotFound => problem_details::session_not_found(),
             Self::AgentNotFound => {
                 problem_details::internal_error("session references non-existent agent")
             }
+            Self::AgentDisabled(name) => problem_details::agent_disabled(&name),
+            Self::InvalidInput(errors) => problem_details::validation_failed(errors),
             Self::PersistFailed => {
                 problem_details::internal_error("failed to persist session data")
             }
-            Self::ProviderNotConfigured => {
-                problem_details::internal_error("provider not configured")
-            }
+            Self::ProviderNotConfigured => problem_details::provider_not_configured(),
         }
         .into_response()
     }
@@ -616,12 +1261,43 @@ struct ChatContext {
     agent_dir: std::path::PathBuf,
     handle: SessionHandle,
     tool_refs: Option<std::collections::HashSet<String>>,
+    /// The invoke request's restriction of the agent's tools.
+    tools: Option<ToolRestriction>,
+}
+
+/// Send the chat request, retrying once with a shortened conversation if
+/// the provider rejects it as too long for the model's context.
+async fn chat_with_context_recovery(ctx: &ChatContext) -> Result<ChatResponse, LLMError> {
+    let err = match ctx.provider.chat(ctx.request.clone()).await {
+        Err(e) if e.class() == Some(ProviderErrorClass::ContextTooLong) => e,
+        result => return result,
+    };
+    let mut request = ctx.request.clone();
+    let Some(recovery) = shrink_context(&mut request.messages, &ctx.agent_spec.session.context)
+    else {
+        return Err(err);
+    };
+    warn!(
+        dropped_messages = recovery.dropped_messages,
+        tokens_before = recovery.tokens_before,
+        tokens_after = recovery.tokens_after,
+        "Context too long, retrying with a shortened conversation"
+    );
+    if let Err(e) = ctx.handle.record_context_recovery(recovery).await {
+        warn!(error = %e, "Failed to record context recovery");
+    }
+    ctx.provider.chat(request).await
 }
 
 /// Handle send_message for agents with tools using the agentic loop.
-async fn send_message_agentic(state: &AppState, ctx: ChatContext) -> Response {
+async fn send_message_agentic(
+    state: &AppState,
+    ctx: ChatContext,
+    format: ResponseFormat,
+) -> Response {
     let session_id = ctx.handle.id().to_string();
-    let agent_name = ctx.handle.agent().to_string();
+    // The routed agent, when a router handed the message on
+    let agent_name = ctx.agent_spec.metadata.name.clone();
 
     // Load policy from store (picks up runtime changes from AllowAlways)
     let policy = state.services.policy_store.load(&agent_name).await;
@@ -659,7 +1335,20 @@ async fn send_message_agentic(state: &AppState, ctx: ChatContext) -> Response {
         agent_dir: ctx.agent_dir.clone(),
         workspace_tools_dir: Some(state.services.workspace_tools_path.clone()),
         agent_tool_configs: ctx.agent_spec.tools.clone(),
-    });
+    })
+    .with_mocks(state.services.tool_mocks.clone());
+
+    // The invoke request can only narrow the tools the run may use
+    let tool_filter = match &ctx.tools {
+        Some(tools) => {
+            let mut allowed = executor.allowed_tools(tools.allow.as_deref(), &tools.deny);
+            if let Some(refs) = &ctx.tool_refs {
+                allowed.retain(|name| refs.contains(name));
+            }
+            Some(allowed)
+        }
+        None => ctx.tool_refs.clone(),
+    };
 
     // Acquire per-session agentic loop lock to prevent concurrent loops
     let loop_lock = state.services.agentic_loop_locks.get(&session_id);
@@ -672,29 +1361,30 @@ async fn send_message_agentic(state: &AppState, ctx: ChatContext) -> Response {
         &ctx.agent_spec,
         ctx.request.messages,
         &ctx.handle,
-        ctx.tool_refs.as_ref(),
+        tool_filter.as_ref(),
         None,
     )
     .await
     {
         Ok(r) => r,
-        Err(e) => {
-            error!(error = %e, "agentic loop failed");
-            return problem_details::internal_error("agentic loop failed").into_response();
-        }
+        Err(e) => return agentic_error_response(e),
     };
 
-    handle_agentic_result(&ctx.handle, result, false).await
+    handle_agentic_result(&ctx.handle, result, false, format).await
 }
 
 /// Prepare chat context for LLM request.
 ///
-/// Validates session and agent, adds user message, builds structured context,
-/// and returns the ChatRequest with the provider and agent configuration.
+/// Validates session, agent, and input, stores attachments, adds user
+/// message, builds structured context, and returns the ChatRequest with the provider and agent
+/// configuration. `tools` restricts the run to a subset of the agent's tools.
 async fn prepare_chat_context(
     state: &AppState,
     session_id: &str,
-    user_content: String,
+    content: String,
+    input: Option<serde_json::Value>,
+    tools: Option<ToolRestriction>,
+    attachments: Vec<Attachment>,
 ) -> Result<ChatContext, SendMessageError> {
     let Some(handle) = state.services.session_registry.get(session_id) else {
         return Err(SendMessageError::SessionNotFound);
@@ -704,6 +1394,23 @@ async fn prepare_chat_context(
     let Some(agent) = state.services.agents.get(&agent_name) else {
         return Err(SendMessageError::AgentNotFound);
     };
+    if !agent.enabled {
+        return Err(SendMessageError::AgentDisabled(agent_name));
+    }
+    let mut user_content = resolve_input(agent.input_schema.as_ref(), content, input)
+        .map_err(SendMessageError::InvalidInput)?;
+    if !attachments.is_empty() {
+        user_content = state
+            .artifacts
+            .attach(session_id, user_content, attachments)
+            .await
+            .map_err(|e| {
+                error!(error = %e, "failed to store attachments");
+                SendMessageError::PersistFailed
+            })?;
+    }
+    let agent = state.services.route_agent(agent, &user_content).await;
+    let agent = state.services.route_model(agent, &user_content).await;
 
     // Persist user message via actor
     if let Err(e) = handle.add_user_message(user_content).await {
@@ -735,8 +1442,13 @@ async fn prepare_chat_context(
         agent.clone(),
     )
     .await;
+    let blocks = state
+        .services
+        .context_blocks(&agent, &provider, &handle, &history)
+        .await;
     let structured_context = ContextBuilder::new()
         .from_agent_spec(&agent)
+        .with_blocks(blocks)
         .with_messages(history)
         .with_directives(directives)
         .build();
@@ -758,6 +1470,7 @@ async fn prepare_chat_context(
         &budget,
     );
 
+    let provider = state.services.run_provider(&agent, provider, &handle).await;
     let agent_dir = agent.agent_dir.clone();
     let agent_spec = agent.clone();
 
@@ -769,9 +1482,76 @@ async fn prepare_chat_context(
         agent_dir,
         handle,
         tool_refs,
+        tools,
     })
 }
 
+/// Map an agentic loop failure to an error response.
+/// Turn a message's content or structured input into the user message text.
+///
+/// Agents with an `input_schema` take a JSON value, from `input` or parsed
+/// from `content`, that must validate against it. Without a schema, `input`
+/// is passed along as JSON (strings as-is).
+fn resolve_input(
+    schema: Option<&serde_json::Value>,
+    content: String,
+    input: Option<serde_json::Value>,
+) -> Result<String, Vec<FieldError>> {
+    let Some(schema) = schema else {
+        return Ok(match input {
+            Some(serde_json::Value::String(text)) => text,
+            Some(value) => value.to_string(),
+            None => content,
+        });
+    };
+
+    let (pointer, value) = match input {
+        Some(value) => ("/input", value),
+        None => match serde_json::from_str(&content) {
+            Ok(value) => ("/content", value),
+            Err(_) => {
+                return Err(vec![FieldError::new(
+                    "/content",
+                    "must be JSON matching the agent's input schema",
+                )]);
+            }
+        },
+    };
+    let errors = input_schema::validate(schema, &value);
+    if !errors.is_empty() {
+        return Err(errors
+            .into_iter()
+            .map(|e| FieldError::new(format!("{pointer}{}", e.pointer), e.message))
+            .collect());
+    }
+    Ok(value.to_string())
+}
+
+fn agentic_error_response(e: AgenticError) -> Response {
+    if e.is_budget_exceeded() {
+        return problem_details::run_budget_exceeded(e.to_string())
+            .with_budget(e.budget())
+            .into_response();
+    }
+    if let Some(class) = e.provider_error_class() {
+        error!(error = %e, "llm request failed");
+        return problem_details::provider_error("llm request failed")
+            .with_error_class(Some(class))
+            .into_response();
+    }
+    error!(error = %e, "agentic loop failed");
+    problem_details::internal_error("agentic loop failed").into_response()
+}
+
+fn run_stats_response(stats: &RunStats) -> RunStatsResponse {
+    RunStatsResponse {
+        wall_time_ms: stats.wall_time.as_millis() as u64,
+        provider_time_ms: stats.provider_time.as_millis() as u64,
+        tool_time_ms: stats.tool_time.as_millis() as u64,
+        peak_memory_bytes: stats.peak_memory_bytes,
+    }
+}
+
 /// Handle the result from an agentic loop (initial or resume).
 ///
 /// For Complete: persists the message and returns 200 with the response.
@@ -780,11 +1560,12 @@ async fn handle_agentic_result(
     handle: &SessionHandle,
     result: AgenticResult,
     is_resume: bool,
+    format: ResponseFormat,
 ) -> Response {
     let session_id = handle.id();
 
     match result {
-        AgenticResult::Complete { content, .. } => {
+        AgenticResult::Complete { content, stats, .. } => {
             // Final response already persisted by the agentic loop — just flush.
             if let Err(e) = handle.force_flush().await {
                 error!(error = %e, "failed to flush session events");
@@ -806,15 +1587,17 @@ async fn handle_agentic_result(
                     message_id,
                     content,
                 };
-                (StatusCode::OK, Json(response)).into_response()
+                format.respond(StatusCode::OK, &response)
             } else {
                 // For send_message, return SendMessageResponse
                 let response = SendMessageResponse {
                     message_id,
                     role: "assistant".to_string(),
                     content,
+                    reasoning: None,
+                    stats: Some(run_stats_response(&stats)),
                 };
-                (StatusCode::OK, Json(response)).into_response()
+                format.respond(StatusCode::OK, &response)
             }
         }
 
@@ -836,7 +1619,7 @@ async fn handle_agentic_result(
                     call_id: pending.call_id,
                     command: pending.command,
                 };
-                (StatusCode::ACCEPTED, Json(response)).into_response()
+                format.respond(StatusCode::ACCEPTED, &response)
             } else {
                 // For send_message, return PendingApprovalResponse
                 let response = PendingApprovalResponse {
@@ -844,8 +1627,91 @@ async fn handle_agentic_result(
                     call_id: pending.call_id,
                     command: pending.command,
                 };
-                (StatusCode::ACCEPTED, Json(response)).into_response()
+                format.respond(StatusCode::ACCEPTED, &response)
             }
         }
     }
 }
+
+/// Public link to a session resource.
+/// Load a session that is no longer live, or build the error response.
+async fn load_stored_session(
+    state: &AppState,
+    session_id: &str,
+) -> Result<StoredSession, Response> {
+    match state.session_archive.load(session_id).await {
+        Ok(Some(stored)) => Ok(stored),
+        Ok(None) => Err(problem_details::session_not_found()
+            .with_instance(session_url(state, session_id))
+            .into_response()),
+        Err(e) => {
+            error!(session_id = %session_id, error = %e, "failed to load stored session");
+            Err(problem_details::internal_error("failed to load session").into_response())
+        }
+    }
+}
+
+/// A session's agent, start time, and full conversation.
+pub(super) struct Conversation {
+    pub agent: String,
+    pub created_at: DateTime<Utc>,
+    pub messages: Vec<Message>,
+}
+
+/// Load a session's conversation, live or stored, or build the error response.
+pub(super) async fn load_conversation(
+    state: &AppState,
+    session_id: &str,
+) -> Result<Conversation, Response> {
+    let Some(handle) = state.services.session_registry.get(session_id) else {
+        let stored = load_stored_session(state, session_id).await?;
+        let messages = stored.messages();
+        return Ok(Conversation {
+            agent: stored.snapshot.agent,
+            created_at: stored.snapshot.created_at,
+            messages,
+        });
+    };
+    match tokio::try_join!(handle.get_metadata(), handle.get_messages()) {
+        Ok((metadata, messages)) => Ok(Conversation {
+            agent: metadata.agent,
+            created_at: metadata.created_at,
+            messages,
+        }),
+        Err(e) => {
+            error!(session_id = %session_id, error = %e, "failed to load conversation");
+            Err(problem_details::internal_error("failed to load session").into_response())
+        }
+    }
+}
+
+/// The messages clients see: user messages and final assistant replies,
+/// without tool calls and their results.
+pub(super) fn transcript(messages: Vec<Message>) -> impl Iterator<Item = MessageResponse> {
+    messages
+        .into_iter()
+        .filter(|m| m.role == Role::User || (m.role == Role::Assistant && m.tool_calls.is_none()))
+        .map(|m| MessageResponse {
+            role: m.role.to_string(),
+            content: m.content.unwrap_or_default(),
+        })
+}
+
+/// Whether the caller may see model reasoning: the server must opt in with
+/// `sessions.expose_reasoning`, and the caller needs admin access or a
+/// `runs:reasoning` scope in the agent's project.
+fn exposes_reasoning(
+    state: &AppState,
+    addr: &SocketAddr,
+    headers: &HeaderMap,
+    agent: &AgentSpec,
+) -> bool {
+    state.expose_reasoning
+        && api_auth::may_see_reasoning(state, addr, headers, agent.metadata.project.as_deref())
+}
+
+fn session_url(state: &AppState, session_id: &str) -> String {
+    state
+        .external_url
+        .url_for(&format!("/api/v1/sessions/{session_id}"))
+}
@@ -0,0 +1,137 @@
+//! Session share link HTTP handlers.
+//!
+//! Share links are managed under a session with the usual API credentials;
+//! the transcript they point at is served at `/shared/{token}` to anyone
+//! holding the link. See [`crate::shares`].
+
+use axum::Json;
+use axum::extract::{Path, State};
+use axum::http::StatusCode;
+use axum::response::{IntoResponse, Response};
+use chrono::{TimeDelta, Utc};
+use tracing::{error, info};
+
+use super::artifacts::require_session;
+use super::sessions::{load_conversation, transcript};
+use crate::api::{
+    CreateShareRequest, ListSharesResponse, ShareResponse, ShareTokenResponse,
+    SharedTranscriptResponse,
+};
+use crate::handlers::problem_details;
+use crate::handlers::validation::ValidJson;
+use crate::server::AppState;
+use crate::shares::Share;
+
+/// POST /api/v1/sessions/{session_id}/shares
+///
+/// Returns the new share with its token and link. The token is not shown
+/// again.
+pub async fn create_share(
+    State(state): State<AppState>,
+    Path(session_id): Path<String>,
+    ValidJson(req): ValidJson<CreateShareRequest>,
+) -> Response {
+    if let Err(response) = require_session(&state, &session_id).await {
+        return response;
+    }
+
+    let expires_at = req
+        .expires_in_seconds
+        .map(|secs| Utc::now() + TimeDelta::seconds(secs as i64));
+    match state.shares.create(&session_id, expires_at).await {
+        Ok((share, token)) => {
+            info!(session_id = %session_id, id = %share.id, "Created share link");
+            let url = state.external_url.url_for(&format!("/shared/{token}"));
+            let response = ShareTokenResponse {
+                share: share_response(share),
+                token,
+                url,
+            };
+            (StatusCode::CREATED, Json(response)).into_response()
+        }
+        Err(e) => {
+            error!(session_id = %session_id, error = %e, "failed to create share");
+            problem_details::internal_error("failed to create share").into_response()
+        }
+    }
+}
+
+/// GET /api/v1/sessions/{session_id}/shares
+///
+/// The session's shares, oldest first, including expired ones.
+pub async fn list_shares(
+    State(state): State<AppState>,
+    Path(session_id): Path<String>,
+) -> Response {
+    if let Err(response) = require_session(&state, &session_id).await {
+        return response;
+    }
+
+    let shares = state
+        .shares
+        .list(&session_id)
+        .into_iter()
+        .map(share_response)
+        .collect();
+    (StatusCode::OK, Json(ListSharesResponse { shares })).into_response()
+}
+
+/// DELETE /api/v1/sessions/{session_id}/shares/{id}
+pub async fn delete_share(
+    State(state): State<AppState>,
+    Path((session_id, id)): Path<(String, String)>,
+) -> Response {
+    match state.shares.revoke(&session_id, &id).await {
+        Ok(true) => {
+            info!(session_id = %session_id, id = %id, "Revoked share link");
+            StatusCode::NO_CONTENT.into_response()
+        }
+        Ok(false) => problem_details::not_found(format!("share '{id}' not found")).into_response(),
+        Err(e) => {
+            error!(session_id = %session_id, id = %id, error = %e, "failed to revoke share");
+            problem_details::internal_error("failed to revoke share").into_response()
+        }
+    }
+}
+
+/// GET /shared/{token}
+///
+/// The shared session's transcript. Needs no credentials: the token is the
+/// credential, and it only grants reading this one transcript. Unknown,
+/// expired, and revoked tokens all get the same `404`.
+pub async fn get_shared_transcript(
+    State(state): State<AppState>,
+    Path(token): Path<String>,
+) -> Response {
+    let not_found =
+        || problem_details::not_found("share link not found or expired").into_response();
+    let Some(share) = state.shares.resolve(&token) else {
+        return not_found();
+    };
+    let conversation = match load_conversation(&state, &share.session_id).await {
+        Ok(c) => c,
+        Err(response) if response.status() == StatusCode::NOT_FOUND => return not_found(),
+        Err(response) => return response,
+    };
+
+    let response = SharedTranscriptResponse {
+        session_id: share.session_id,
+        agent: conversation.agent,
+        created_at: conversation.created_at.to_rfc3339(),
+        messages: transcript(conversation.messages).collect(),
+    };
+    (StatusCode::OK, Json(response)).into_response()
+}
+
+// ============================================================================
+// Helpers
+// ============================================================================
+
+fn share_response(share: Share) -> ShareResponse {
+    ShareResponse {
+        id: share.id,
+        session_id: share.session_id,
+        created_at: share.created_at.to_rfc3339(),
+        expires_at: share.expires_at.map(|t| t.to_rfc3339()),
+    }
+}
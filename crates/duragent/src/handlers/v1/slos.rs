@@ -0,0 +1,52 @@
+//! Provider SLO HTTP handlers.
+
+use std::net::SocketAddr;
+
+use axum::Json;
+use axum::extract::{ConnectInfo, State};
+use axum::http::{HeaderMap, StatusCode};
+use axum::response::{IntoResponse, Response};
+
+use crate::api::{SloResponse, SloStatusEntry};
+use crate::handlers::api_auth;
+use crate::server::AppState;
+use crate::slo::ObjectiveKind;
+
+/// GET /api/v1/admin/slos
+///
+/// Current burn rate of every provider objective in `slo.providers`.
+///
+/// Authorization: same as the admin endpoints, or a scoped service account.
+pub async fn get_slos(
+    State(state): State<AppState>,
+    ConnectInfo(addr): ConnectInfo<SocketAddr>,
+    headers: HeaderMap,
+) -> Response {
+    if !api_auth::has_scoped_access(&state, &addr, &headers) {
+        return api_auth::admin_denied(&state, &addr);
+    }
+
+    let objectives = state
+        .slos
+        .statuses()
+        .into_iter()
+        .map(|s| SloStatusEntry {
+            provider: s.provider,
+            objective: match s.objective {
+                ObjectiveKind::Latency => "latency",
+                ObjectiveKind::Errors => "errors",
+            }
+            .to_string(),
+            target: s.target,
+            latency_ms: s.latency_ms,
+            window_minutes: s.window_minutes,
+            requests: s.requests,
+            bad: s.bad,
+            burn_rate: s.burn_rate,
+            burn_rate_threshold: s.burn_rate_threshold,
+            alerting: s.alerting,
+        })
+        .collect();
+
+    (StatusCode::OK, Json(SloResponse { objectives })).into_response()
+}
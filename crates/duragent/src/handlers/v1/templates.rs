@@ -0,0 +1,56 @@
+//! Prompt template HTTP handlers.
+
+use std::net::SocketAddr;
+
+use axum::Json;
+use axum::extract::{ConnectInfo, State};
+use axum::http::{HeaderMap, StatusCode};
+use axum::response::{IntoResponse, Response};
+
+use crate::api::{RenderTemplateRequest, RenderTemplateResponse};
+use crate::context::{self, TemplateContext};
+use crate::handlers::validation::ValidJson;
+use crate::handlers::{api_auth, problem_details};
+use crate::server::AppState;
+
+/// POST /api/v1/templates/render
+///
+/// Render a prompt template the way agent prompts are rendered, for
+/// debugging. Returns the output, its estimated token count, and a warning
+/// for every tag that could not be rendered.
+///
+/// Authorization: same as the admin endpoints, or a scoped service account.
+pub async fn render_template(
+    State(state): State<AppState>,
+    ConnectInfo(addr): ConnectInfo<SocketAddr>,
+    headers: HeaderMap,
+    ValidJson(req): ValidJson<RenderTemplateRequest>,
+) -> Response {
+    if !api_auth::has_scoped_access(&state, &addr, &headers) {
+        return api_auth::admin_denied(&state, &addr);
+    }
+
+    let agent = match &req.agent {
+        Some(name) => match state.services.agents.get(name) {
+            Some(spec) => Some(spec),
+            None => return problem_details::agent_not_found(name).into_response(),
+        },
+        None => None,
+    };
+
+    let mut ctx = TemplateContext::new();
+    if let Some(agent) = &agent {
+        ctx = ctx.with_agent(agent);
+    }
+    let rendered = context::render_template(&req.template, &ctx.with_vars(req.vars));
+
+    (
+        StatusCode::OK,
+        Json(RenderTemplateResponse {
+            estimated_tokens: context::estimate_tokens(&rendered.output),
+            rendered: rendered.output,
+            warnings: rendered.warnings,
+        }),
+    )
+        .into_response()
+}
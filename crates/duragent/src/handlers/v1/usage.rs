@@ -0,0 +1,149 @@
+//! Token usage HTTP handlers.
+
+use std::net::SocketAddr;
+
+use axum::Json;
+use axum::extract::{ConnectInfo, Query, State};
+use axum::http::{HeaderMap, StatusCode};
+use axum::response::{IntoResponse, Response};
+use chrono::{DateTime, TimeDelta, Utc};
+use serde::Deserialize;
+use tracing::error;
+
+use crate::api::{UsageBucketResponse, UsageResponse, UsageTotalsResponse};
+use crate::handlers::{api_auth, problem_details};
+use crate::server::AppState;
+use crate::usage::Granularity;
+
+/// Window queried when `from` is omitted.
+const DEFAULT_WINDOW_DAYS: i64 = 30;
+
+// ============================================================================
+// Query Types
+// ============================================================================
+
+#[derive(Deserialize)]
+pub struct UsageQuery {
+    /// Start of the window (RFC 3339). Defaults to 30 days before `to`.
+    from: Option<String>,
+    /// End of the window, exclusive (RFC 3339). Defaults to now.
+    to: Option<String>,
+    /// Bucket width: `hour` or `day` (default).
+    granularity: Option<String>,
+    /// Only report usage for this agent.
+    agent: Option<String>,
+}
+
+// ============================================================================
+// Handlers
+// ============================================================================
+
+/// GET /api/v1/usage
+///
+/// Token usage per agent, bucketed by hour or day. Served from rollups, so
+/// hourly buckets are only available within the hourly retention period.
+/// Costs are estimated at the pricing of each agent's configured model.
+///
+/// Authorization: same as the admin endpoints, or a scoped service account.
+pub async fn get_usage(
+    State(state): State<AppState>,
+    ConnectInfo(addr): ConnectInfo<SocketAddr>,
+    headers: HeaderMap,
+    Query(query): Query<UsageQuery>,
+) -> Response {
+    if !api_auth::has_scoped_access(&state, &addr, &headers) {
+        return api_auth::admin_denied(&state, &addr);
+    }
+
+    let granularity = match query.granularity.as_deref() {
+        None | Some("day") => Granularity::Day,
+        Some("hour") => Granularity::Hour,
+        Some(other) => {
+            return problem_details::bad_request(format!(
+                "invalid granularity '{other}', expected 'hour' or 'day'"
+            ))
+            .into_response();
+        }
+    };
+    let to = match parse_time("to", query.to.as_deref()) {
+        Ok(t) => t.unwrap_or_else(Utc::now),
+        Err(response) => return response,
+    };
+    let from = match parse_time("from", query.from.as_deref()) {
+        Ok(t) => t.unwrap_or(to - TimeDelta::days(DEFAULT_WINDOW_DAYS)),
+        Err(response) => return response,
+    };
+    if from >= to {
+        return problem_details::bad_request("'from' must be before 'to'").into_response();
+    }
+
+    let buckets = match state.usage.query(granularity, from, to).await {
+        Ok(b) => b,
+        Err(e) => {
+            error!(error = %e, "failed to query usage");
+            return problem_details::internal_error("failed to query usage").into_response();
+        }
+    };
+
+    let mut totals = UsageTotalsResponse::default();
+    let buckets: Vec<UsageBucketResponse> = buckets
+        .into_iter()
+        .filter(|b| query.agent.as_ref().is_none_or(|agent| &b.agent == agent))
+        .map(|b| {
+            totals.responses += b.totals.responses;
+            totals.prompt_tokens += b.totals.prompt_tokens;
+            totals.completion_tokens += b.totals.completion_tokens;
+            totals.total_tokens += b.totals.total_tokens;
+            let estimated_cost_usd = state.services.agents.get(&b.agent).and_then(|agent| {
+                state.services.models.estimate_cost(
+                    &agent.model.name,
+                    b.totals.prompt_tokens,
+                    b.totals.completion_tokens,
+                )
+            });
+            if let Some(cost) = estimated_cost_usd {
+                *totals.estimated_cost_usd.get_or_insert(0.0) += cost;
+            }
+            UsageBucketResponse {
+                start: b.start.to_rfc3339(),
+                agent: b.agent,
+                responses: b.totals.responses,
+                prompt_tokens: b.totals.prompt_tokens,
+                completion_tokens: b.totals.completion_tokens,
+                total_tokens: b.totals.total_tokens,
+                estimated_cost_usd,
+            }
+        })
+        .collect();
+
+    let granularity = match granularity {
+        Granularity::Hour => "hour",
+        Granularity::Day => "day",
+    };
+    (
+        StatusCode::OK,
+        Json(UsageResponse {
+            granularity: granularity.to_string(),
+            from: from.to_rfc3339(),
+            to: to.to_rfc3339(),
+            buckets,
+            totals,
+        }),
+    )
+        .into_response()
+}
+
+fn parse_time(name: &str, value: Option<&str>) -> Result<Option<DateTime<Utc>>, Response> {
+    value
+        .map(|v| {
+            DateTime::parse_from_rfc3339(v)
+                .map(|t| t.with_timezone(&Utc))
+                .map_err(|_| {
+                    problem_details::bad_request(format!(
+                        "invalid '{name}': expected an RFC 3339 timestamp"
+                    ))
+                    .into_response()
+                })
+        })
+        .transpose()
+}
@@ -0,0 +1,225 @@
+//! Long-term user memory HTTP handlers.
+//!
+//! Facts remembered about an end user are kept at
+//! `/api/v1/users/{user}/facts`, so they can be reviewed, corrected, or
+//! forgotten. Facts are personal data, so every endpoint needs admin access.
+
+use std::net::SocketAddr;
+
+use axum::Json;
+use axum::extract::{ConnectInfo, Path, State};
+use axum::http::{HeaderMap, StatusCode, header};
+use axum::response::{IntoResponse, Response};
+use tracing::{error, info};
+
+use crate::api::{ListUserFactsResponse, PutUserFactRequest, UserFactResponse};
+use crate::handlers::validation::ValidJson;
+use crate::handlers::{api_auth, problem_details};
+use crate::server::AppState;
+use crate::store::file::is_valid_agent_name;
+use crate::user_memory::UserFact;
+
+/// GET /api/v1/users/{user}/facts
+///
+/// Facts remembered about the user, oldest first.
+///
+/// Authorization: same as the admin endpoints, or a scoped service account.
+pub async fn list_user_facts(
+    State(state): State<AppState>,
+    ConnectInfo(addr): ConnectInfo<SocketAddr>,
+    headers: HeaderMap,
+    Path(user): Path<String>,
+) -> Response {
+    if let Err(response) = authorize(&state, &addr, &headers, &user) {
+        return response;
+    }
+
+    match state.services.user_memory.list(&user).await {
+        Ok(facts) => {
+            let facts = facts.into_iter().map(fact_response).collect();
+            (StatusCode::OK, Json(ListUserFactsResponse { facts })).into_response()
+        }
+        Err(e) => {
+            error!(user = %user, error = %e, "failed to list user facts");
+            problem_details::internal_error("failed to list user facts").into_response()
+        }
+    }
+}
+
+/// GET /api/v1/users/{user}/facts/{id}
+///
+/// Authorization: same as the admin endpoints, or a scoped service account.
+pub async fn get_user_fact(
+    State(state): State<AppState>,
+    ConnectInfo(addr): ConnectInfo<SocketAddr>,
+    headers: HeaderMap,
+    Path((user, id)): Path<(String, String)>,
+) -> Response {
+    if let Err(response) = authorize(&state, &addr, &headers, &user) {
+        return response;
+    }
+
+    match state.services.user_memory.get(&user, &id).await {
+        Ok(Some(fact)) => (StatusCode::OK, Json(fact_response(fact))).into_response(),
+        Ok(None) => fact_not_found(&id),
+        Err(e) => {
+            error!(user = %user, fact = %id, error = %e, "failed to load user fact");
+            problem_details::internal_error("failed to load user fact").into_response()
+        }
+    }
+}
+
+/// POST /api/v1/users/{user}/facts
+///
+/// Remember a fact about the user without it coming up in conversation.
+///
+/// Authorization: same as the admin endpoints, or a scoped service account.
+pub async fn create_user_fact(
+    State(state): State<AppState>,
+    ConnectInfo(addr): ConnectInfo<SocketAddr>,
+    headers: HeaderMap,
+    Path(user): Path<String>,
+    ValidJson(req): ValidJson<PutUserFactRequest>,
+) -> Response {
+    if let Err(response) = authorize(&state, &addr, &headers, &user) {
+        return response;
+    }
+
+    match state
+        .services
+        .user_memory
+        .create(&user, req.content, None)
+        .await
+    {
+        Ok(fact) => {
+            let location = state
+                .external_url
+                .url_for(&format!("/api/v1/users/{user}/facts/{}", fact.id));
+            (
+                StatusCode::CREATED,
+                [(header::LOCATION, location)],
+                Json(fact_response(fact)),
+            )
+                .into_response()
+        }
+        Err(e) => {
+            error!(user = %user, error = %e, "failed to save user fact");
+            problem_details::internal_error("failed to save user fact").into_response()
+        }
+    }
+}
+
+/// PUT /api/v1/users/{user}/facts/{id}
+///
+/// Correct a fact's content. Changing it drops its cached embedding.
+///
+/// Authorization: same as the admin endpoints, or a scoped service account.
+pub async fn update_user_fact(
+    State(state): State<AppState>,
+    ConnectInfo(addr): ConnectInfo<SocketAddr>,
+    headers: HeaderMap,
+    Path((user, id)): Path<(String, String)>,
+    ValidJson(req): ValidJson<PutUserFactRequest>,
+) -> Response {
+    if let Err(response) = authorize(&state, &addr, &headers, &user) {
+        return response;
+    }
+
+    match state
+        .services
+        .user_memory
+        .update(&user, &id, req.content)
+        .await
+    {
+        Ok(Some(fact)) => (StatusCode::OK, Json(fact_response(fact))).into_response(),
+        Ok(None) => fact_not_found(&id),
+        Err(e) => {
+            error!(user = %user, fact = %id, error = %e, "failed to save user fact");
+            problem_details::internal_error("failed to save user fact").into_response()
+        }
+    }
+}
+
+/// DELETE /api/v1/users/{user}/facts/{id}
+///
+/// Authorization: same as the admin endpoints, or a scoped service account.
+pub async fn delete_user_fact(
+    State(state): State<AppState>,
+    ConnectInfo(addr): ConnectInfo<SocketAddr>,
+    headers: HeaderMap,
+    Path((user, id)): Path<(String, String)>,
+) -> Response {
+    if let Err(response) = authorize(&state, &addr, &headers, &user) {
+        return response;
+    }
+
+    match state.services.user_memory.delete(&user, &id).await {
+        Ok(true) => StatusCode::NO_CONTENT.into_response(),
+        Ok(false) => fact_not_found(&id),
+        Err(e) => {
+            error!(user = %user, fact = %id, error = %e, "failed to delete user fact");
+            problem_details::internal_error("failed to delete user fact").into_response()
+        }
+    }
+}
+
+/// DELETE /api/v1/users/{user}/facts
+///
+/// Forget everything remembered about the user.
+///
+/// Authorization: same as the admin endpoints, or a scoped service account.
+pub async fn forget_user(
+    State(state): State<AppState>,
+    ConnectInfo(addr): ConnectInfo<SocketAddr>,
+    headers: HeaderMap,
+    Path(user): Path<String>,
+) -> Response {
+    if let Err(response) = authorize(&state, &addr, &headers, &user) {
+        return response;
+    }
+
+    match state.services.user_memory.forget(&user).await {
+        Ok(count) => {
+            info!(user = %user, facts = count, "Forgot user");
+            StatusCode::NO_CONTENT.into_response()
+        }
+        Err(e) => {
+            error!(user = %user, error = %e, "failed to forget user");
+            problem_details::internal_error("failed to forget user").into_response()
+        }
+    }
+}
+
+// ============================================================================
+// Helper Functions
+// ============================================================================
+
+/// Require admin access and a valid user id.
+fn authorize(
+    state: &AppState,
+    addr: &SocketAddr,
+    headers: &HeaderMap,
+    user: &str,
+) -> Result<(), Response> {
+    if !api_auth::has_scoped_access(state, addr, headers) {
+        return Err(api_auth::admin_denied(state, addr));
+    }
+    if !is_valid_agent_name(user) {
+        return Err(problem_details::bad_request(format!("invalid user '{user}'")).into_response());
+    }
+    Ok(())
+}
+
+fn fact_not_found(id: &str) -> Response {
+    problem_details::not_found(format!("fact '{id}' not found")).into_response()
+}
+
+fn fact_response(fact: UserFact) -> UserFactResponse {
+    UserFactResponse {
+        id: fact.id,
+        content: fact.content,
+        agent: fact.agent,
+        created_at: fact.created_at.to_rfc3339(),
+        updated_at: fact.updated_at.to_rfc3339(),
+    }
+}
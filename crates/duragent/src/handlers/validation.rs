@@ -0,0 +1,688 @@
+//! Request body validation.
+//!
+//! `ValidJson<T>` is a drop-in replacement for `axum::Json<T>` on request
+//! bodies. Deserialization failures and `Validate` failures are both reported
+//! as a `validation-failed` problem whose `errors` array locates each failure
+//! with a JSON Pointer. These checks always run; `server.request_validation`
+//! only controls the OpenAPI schema check in front of them (see
+//! `request_schemas`).
+
+use std::collections::HashMap;
+
+use axum::Json;
+use axum::extract::rejection::JsonRejection;
+use axum::extract::{FromRequest, Request};
+use serde::de::DeserializeOwned;
+
+use super::problem_details::{self, FieldError, ProblemDetails};
+use crate::api::{
+    ApproveCommandRequest, BulkAgentOperation, BulkAgentsRequest, BulkRequeueRequest,
+    CreateAgentRequest, CreatePublicSessionRequest, CreateServiceAccountRequest,
+    CreateSessionRequest, CreateShareRequest, PublicMessageRequest, PutExampleRequest,
+    PutModelRequest, PutProjectRequest, PutPromptRequest, PutUserFactRequest,
+    RenderTemplateRequest, SelectExamplesRequest, SendMessageRequest, SetFeatureRequest,
+    UpdateServiceAccountRequest,
+};
+use crate::scheduler::ErrorClass;
+use crate::server::AppState;
+use crate::store::file::is_valid_agent_name;
+
+/// Semantic validation for a deserialized request body.
+pub trait Validate {
+    /// Return every validation failure; empty means valid.
+    fn validate(&self) -> Vec<FieldError>;
+}
+
+/// JSON body extractor that runs `Validate` after deserialization.
+#[derive(Debug)]
+pub struct ValidJson<T>(pub T);
+
+impl<T> FromRequest<AppState> for ValidJson<T>
+where
+    T: DeserializeOwned + Validate,
+{
+    type Rejection = ProblemDetails;
+
+    async fn from_request(req: Request, state: &AppState) -> Result<Self, Self::Rejection> {
+        let Json(value) = Json::<T>::from_request(req, state)
+            .await
+            .map_err(|rejection| {
+                problem_details::validation_failed(vec![rejection_error(&rejection)])
+            })?;
+
+        let errors = value.validate();
+        if !errors.is_empty() {
+            return Err(problem_details::validation_failed(errors));
+        }
+        Ok(Self(value))
+    }
+}
+
+// ============================================================================
+// Request Validators
+// ============================================================================
+
+impl Validate for CreateSessionRequest {
+    fn validate(&self) -> Vec<FieldError> {
+        let mut errors = Vec::new();
+        require_non_blank(&mut errors, "/agent", &self.agent);
+        if let Some(user) = &self.user
+            && !is_valid_agent_name(user)
+        {
+            errors.push(FieldError::new(
+                "/user",
+                "must not be empty, start with '.', or contain '/' or '\\'",
+            ));
+        }
+        errors
+    }
+}
+
+/// Message text is required, unless structured `input` replaces it.
+fn validate_message_content(
+    errors: &mut Vec<FieldError>,
+    content: &str,
+    input: Option<&serde_json::Value>,
+) {
+    if input.is_none() {
+        require_non_blank(errors, "/content", content);
+    } else if !content.is_empty() {
+        errors.push(FieldError::new(
+            "/content",
+            "must be empty when input is set",
+        ));
+    }
+}
+
+impl Validate for SendMessageRequest {
+    fn validate(&self) -> Vec<FieldError> {
+        let mut errors = Vec::new();
+        validate_message_content(&mut errors, &self.content, self.input.as_ref());
+        validate_run_labels(&mut errors, &self.labels);
+        if let Some(url) = &self.callback_url {
+            require_http_url(&mut errors, "/callback_url", url);
+        }
+        if let Some(tools) = &self.tools {
+            for (i, name) in tools.allow.iter().flatten().enumerate() {
+                require_non_blank(&mut errors, &format!("/tools/allow/{i}"), name);
+            }
+            for (i, name) in tools.deny.iter().enumerate() {
+                require_non_blank(&mut errors, &format!("/tools/deny/{i}"), name);
+            }
+        }
+        errors
+    }
+}
+
+/// Most labels on one run.
+const MAX_RUN_LABELS: usize = 16;
+/// Longest run label key, in characters.
+const MAX_RUN_LABEL_KEY_CHARS: usize = 63;
+/// Longest run label value, in characters.
+const MAX_RUN_LABEL_VALUE_CHARS: usize = 256;
+
+/// Keys and values must be usable in a label selector.
+fn validate_run_labels(errors: &mut Vec<FieldError>, labels: &HashMap<String, String>) {
+    if labels.len() > MAX_RUN_LABELS {
+        errors.push(FieldError::new(
+            "/labels",
+            format!("must have at most {MAX_RUN_LABELS} labels"),
+        ));
+    }
+    let mut keys: Vec<&String> = labels.keys().collect();
+    keys.sort();
+    for key in keys {
+        let pointer = format!("/labels/{}", escape_pointer_segment(key));
+        let valid_key = !key.is_empty()
+            && key.chars().count() <= MAX_RUN_LABEL_KEY_CHARS
+            && key
+                .chars()
+                .all(|c| c.is_ascii_alphanumeric() || matches!(c, '.' | '_' | '-' | '/'));
+        if !valid_key {
+            errors.push(FieldError::new(
+                pointer,
+                format!(
+                    "key must be 1-{MAX_RUN_LABEL_KEY_CHARS} letters, digits, '.', '_', '-', or '/'"
+                ),
+            ));
+            continue;
+        }
+        let value = &labels[key];
+        if value.chars().count() > MAX_RUN_LABEL_VALUE_CHARS || value.contains([',', '=']) {
+            errors.push(FieldError::new(
+                pointer,
+                format!(
+                    "must be at most {MAX_RUN_LABEL_VALUE_CHARS} characters, without ',' or '='"
+                ),
+            ));
+        }
+    }
+}
+
+/// Longest a share link can last, in seconds (one year).
+const MAX_SHARE_EXPIRY_SECONDS: u64 = 365 * 24 * 60 * 60;
+
+impl Validate for CreatePublicSessionRequest {
+    fn validate(&self) -> Vec<FieldError> {
+        let mut errors = Vec::new();
+        if let Some(token) = &self.captcha_token {
+            require_non_blank(&mut errors, "/captcha_token", token);
+        }
+        errors
+    }
+}
+
+impl Validate for PublicMessageRequest {
+    fn validate(&self) -> Vec<FieldError> {
+        let mut errors = Vec::new();
+        validate_message_content(&mut errors, &self.content, self.input.as_ref());
+        errors
+    }
+}
+
+impl Validate for CreateShareRequest {
+    fn validate(&self) -> Vec<FieldError> {
+        let mut errors = Vec::new();
+        if let Some(secs) = self.expires_in_seconds
+            && !(1..=MAX_SHARE_EXPIRY_SECONDS).contains(&secs)
+        {
+            errors.push(FieldError::new(
+                "/expires_in_seconds",
+                format!("must be between 1 and {MAX_SHARE_EXPIRY_SECONDS}"),
+            ));
+        }
+        errors
+    }
+}
+
+/// Most dead letters a bulk requeue may requeue per second.
+const MAX_REQUEUE_RATE_PER_SECOND: u32 = 50;
+
+impl Validate for BulkRequeueRequest {
+    fn validate(&self) -> Vec<FieldError> {
+        let mut errors = Vec::new();
+        let mut parse_time = |pointer: &str, value: &Option<String>| {
+            let parsed = value
+                .as_deref()
+                .map(chrono::DateTime::parse_from_rfc3339)
+                .transpose();
+            parsed.unwrap_or_else(|_| {
+                errors.push(FieldError::new(pointer, "must be an RFC 3339 timestamp"));
+                None
+            })
+        };
+        let since = parse_time("/since", &self.since);
+        let until = parse_time("/until", &self.until);
+        if let (Some(since), Some(until)) = (since, until)
+            && since >= until
+        {
+            errors.push(FieldError::new("/until", "must be after since"));
+        }
+        if let Some(class) = &self.error_class
+            && ErrorClass::parse(class).is_none()
+        {
+            let known: Vec<_> = ErrorClass::ALL.iter().map(|c| c.as_str()).collect();
+            errors.push(FieldError::new(
+                "/error_class",
+                format!("must be one of: {}", known.join(", ")),
+            ));
+        }
+        if let Some(rate) = self.rate_per_second
+            && !(1..=MAX_REQUEUE_RATE_PER_SECOND).contains(&rate)
+        {
+            errors.push(FieldError::new(
+                "/rate_per_second",
+                format!("must be between 1 and {MAX_REQUEUE_RATE_PER_SECOND}"),
+            ));
+        }
+        errors
+    }
+}
+
+impl Validate for RenderTemplateRequest {
+    fn validate(&self) -> Vec<FieldError> {
+        let mut errors = Vec::new();
+        if let Some(agent) = &self.agent {
+            require_non_blank(&mut errors, "/agent", agent);
+        }
+        errors
+    }
+}
+
+impl Validate for PutExampleRequest {
+    fn validate(&self) -> Vec<FieldError> {
+        let mut errors = Vec::new();
+        require_non_blank(&mut errors, "/input", &self.input);
+        require_non_blank(&mut errors, "/output", &self.output);
+        for (i, tag) in self.tags.iter().enumerate() {
+            require_non_blank(&mut errors, &format!("/tags/{i}"), tag);
+        }
+        errors
+    }
+}
+
+impl Validate for PutPromptRequest {
+    fn validate(&self) -> Vec<FieldError> {
+        let mut errors = Vec::new();
+        require_non_blank(&mut errors, "/content", &self.content);
+        errors
+    }
+}
+
+impl Validate for PutModelRequest {
+    fn validate(&self) -> Vec<FieldError> {
+        let mut errors = Vec::new();
+        for (pointer, value) in [
+            ("/context_window", self.context_window),
+            ("/max_output_tokens", self.max_output_tokens),
+        ] {
+            if value == Some(0) {
+                errors.push(FieldError::new(pointer, "must be greater than 0"));
+            }
+        }
+        if let Some(pricing) = &self.pricing {
+            for (pointer, value) in [
+                ("/pricing/input_per_mtok", pricing.input_per_mtok),
+                ("/pricing/output_per_mtok", pricing.output_per_mtok),
+            ] {
+                if !value.is_finite() || value < 0.0 {
+                    errors.push(FieldError::new(pointer, "must be a non-negative number"));
+                }
+            }
+        }
+        errors
+    }
+}
+
+impl Validate for PutUserFactRequest {
+    fn validate(&self) -> Vec<FieldError> {
+        let mut errors = Vec::new();
+        require_non_blank(&mut errors, "/content", &self.content);
+        errors
+    }
+}
+
+impl Validate for SelectExamplesRequest {
+    fn validate(&self) -> Vec<FieldError> {
+        let mut errors = Vec::new();
+        require_non_blank(&mut errors, "/input", &self.input);
+        errors
+    }
+}
+
+impl Validate for ApproveCommandRequest {
+    fn validate(&self) -> Vec<FieldError> {
+        let mut errors = Vec::new();
+        require_non_blank(&mut errors, "/call_id", &self.call_id);
+        require_non_blank(&mut errors, "/command", &self.command);
+        errors
+    }
+}
+
+impl Validate for SetFeatureRequest {
+    fn validate(&self) -> Vec<FieldError> {
+        // `enabled` is a bool, so deserialization already checked it
+        Vec::new()
+    }
+}
+
+impl Validate for CreateAgentRequest {
+    fn validate(&self) -> Vec<FieldError> {
+        let mut errors = Vec::new();
+        require_non_blank(&mut errors, "/name", &self.name);
+        require_non_blank(&mut errors, "/manifest", &self.manifest);
+        errors
+    }
+}
+
+impl Validate for BulkAgentsRequest {
+    fn validate(&self) -> Vec<FieldError> {
+        let mut errors = Vec::new();
+        if self.operations.is_empty() {
+            errors.push(FieldError::new("/operations", "must not be empty"));
+        }
+        for (i, op) in self.operations.iter().enumerate() {
+            require_non_blank(&mut errors, &format!("/operations/{i}/name"), op.name());
+            if let BulkAgentOperation::Create { manifest, .. }
+            | BulkAgentOperation::Update { manifest, .. } = op
+            {
+                require_non_blank(&mut errors, &format!("/operations/{i}/manifest"), manifest);
+            }
+        }
+        errors
+    }
+}
+
+impl Validate for PutProjectRequest {
+    fn validate(&self) -> Vec<FieldError> {
+        let mut errors = Vec::new();
+        if let Some(model) = &self.default_model {
+            require_non_blank(&mut errors, "/default_model/provider", &model.provider);
+            require_non_blank(&mut errors, "/default_model/name", &model.name);
+        }
+        errors
+    }
+}
+
+impl Validate for CreateServiceAccountRequest {
+    fn validate(&self) -> Vec<FieldError> {
+        let mut errors = Vec::new();
+        require_non_blank(&mut errors, "/name", &self.name);
+        if self.scopes.is_empty() {
+            errors.push(FieldError::new("/scopes", "must not be empty"));
+        }
+        errors
+    }
+}
+
+impl Validate for UpdateServiceAccountRequest {
+    fn validate(&self) -> Vec<FieldError> {
+        let mut errors = Vec::new();
+        if self.scopes.is_empty() {
+            errors.push(FieldError::new("/scopes", "must not be empty"));
+        }
+        errors
+    }
+}
+
+/// Push an error if `value` is empty or whitespace-only.
+pub fn require_non_blank(errors: &mut Vec<FieldError>, pointer: &str, value: &str) {
+    if value.trim().is_empty() {
+        errors.push(FieldError::new(pointer, "must not be empty"));
+    }
+}
+
+/// Push an error unless `value` is an absolute `http` or `https` URL.
+pub fn require_http_url(errors: &mut Vec<FieldError>, pointer: &str, value: &str) {
+    let valid = url::Url::parse(value)
+        .is_ok_and(|url| matches!(url.scheme(), "http" | "https") && url.has_host());
+    if !valid {
+        errors.push(FieldError::new(pointer, "must be an http or https URL"));
+    }
+}
+
+// ============================================================================
+// Rejection Mapping
+// ============================================================================
+
+/// Prefix axum puts in front of serde data errors.
+const DATA_ERROR_PREFIX: &str = "Failed to deserialize the JSON body into the target type: ";
+
+/// Convert an axum JSON rejection into a located field error.
+fn rejection_error(rejection: &JsonRejection) -> FieldError {
+    let text = rejection.body_text();
+    match rejection {
+        JsonRejection::JsonDataError(_) => {
+            let inner = text.strip_prefix(DATA_ERROR_PREFIX).unwrap_or(&text);
+            locate_data_error(inner)
+        }
+        _ => FieldError::new("", text),
+    }
+}
+
+/// Split a serde error of the form `path.to[0].field: message` into a JSON
+/// Pointer and message. `missing field `x`` errors point at the missing field.
+fn locate_data_error(inner: &str) -> FieldError {
+    let (path, message) = match inner.split_once(": ") {
+        Some((path, message)) if is_serde_path(path) => (path, message),
+        _ => ("", inner),
+    };
+
+    let mut pointer = path_to_pointer(path);
+    if let Some(field) = message
+        .strip_prefix("missing field `")
+        .and_then(|rest| rest.split('`').next())
+    {
+        pointer.push('/');
+        pointer.push_str(&escape_pointer_segment(field));
+    }
+
+    FieldError::new(pointer, strip_position(message))
+}
+
+/// Whether `s` looks like a serde_path_to_error path (`a.b[0].c`).
+fn is_serde_path(s: &str) -> bool {
+    !s.is_empty()
+        && s.chars()
+            .all(|c| c.is_alphanumeric() || matches!(c, '_' | '-' | '.' | '[' | ']'))
+}
+
+/// Convert `a.b[0].c` into `/a/b/0/c`.
+fn path_to_pointer(path: &str) -> String {
+    if path.is_empty() || path == "." {
+        return String::new();
+    }
+    path.replace('[', ".")
+        .replace(']', "")
+        .split('.')
+        .filter(|segment| !segment.is_empty())
+        .map(|segment| format!("/{}", escape_pointer_segment(segment)))
+        .collect()
+}
+
+fn escape_pointer_segment(segment: &str) -> String {
+    segment.replace('~', "~0").replace('/', "~1")
+}
+
+/// Drop serde's trailing ` at line N column M`.
+fn strip_position(message: &str) -> &str {
+    message
+        .rsplit_once(" at line ")
+        .map_or(message, |(head, _)| head)
+}
+
+// ============================================================================
+// Tests
+// ============================================================================
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::api::ToolRestriction;
+
+    #[test]
+    fn locate_missing_field() {
+        let err = locate_data_error("missing field `agent` at line 1 column 2");
+        assert_eq!(err, FieldError::new("/agent", "missing field `agent`"));
+    }
+
+    #[test]
+    fn locate_nested_path() {
+        let err = locate_data_error(
+            "decision: unknown variant `maybe`, expected one of `allow_once`, `allow_always`, `deny` at line 1 column 40",
+        );
+        assert_eq!(err.pointer, "/decision");
+        assert!(err.message.starts_with("unknown variant `maybe`"));
+    }
+
+    #[test]
+    fn path_to_pointer_handles_indices_and_escaping() {
+        assert_eq!(path_to_pointer("items[2].name"), "/items/2/name");
+        assert_eq!(path_to_pointer("."), "");
+        assert_eq!(escape_pointer_segment("a/b~c"), "a~1b~0c");
+    }
+
+    #[test]
+    fn send_message_rejects_blank_content() {
+        let req = SendMessageRequest {
+            content: "  ".to_string(),
+            input: None,
+            priority: Default::default(),
+            labels: Default::default(),
+            background: false,
+            callback_url: None,
+            tools: None,
+        };
+        assert_eq!(
+            req.validate(),
+            vec![FieldError::new("/content", "must not be empty")]
+        );
+    }
+
+    #[test]
+    fn send_message_takes_content_or_input() {
+        let mut req = SendMessageRequest {
+            content: String::new(),
+            input: Some(serde_json::json!({ "ticket_id": "T-1" })),
+            priority: Default::default(),
+            labels: Default::default(),
+            background: false,
+            callback_url: None,
+            tools: None,
+        };
+        assert!(req.validate().is_empty());
+
+        req.content = "hello".to_string();
+        assert_eq!(
+            req.validate(),
+            vec![FieldError::new(
+                "/content",
+                "must be empty when input is set"
+            )]
+        );
+    }
+
+    #[test]
+    fn send_message_requires_http_callback_url() {
+        let mut req = SendMessageRequest {
+            content: "hello".to_string(),
+            input: None,
+            priority: Default::default(),
+            labels: Default::default(),
+            background: false,
+            callback_url: Some("https://hooks.example.com/runs".to_string()),
+            tools: None,
+        };
+        assert!(req.validate().is_empty());
+
+        for url in ["ftp://hooks.example.com", "/runs", "not a url"] {
+            req.callback_url = Some(url.to_string());
+            assert_eq!(
+                req.validate(),
+                vec![FieldError::new(
+                    "/callback_url",
+                    "must be an http or https URL"
+                )]
+            );
+        }
+    }
+
+    #[test]
+    fn send_message_checks_run_labels() {
+        let mut req = SendMessageRequest {
+            content: "hello".to_string(),
+            input: None,
+            priority: Default::default(),
+            labels: [("order_id".to_string(), "A-1042".to_string())].into(),
+            background: false,
+            callback_url: None,
+            tools: None,
+        };
+        assert!(req.validate().is_empty());
+
+        req.labels = [
+            ("order id".to_string(), "A-1".to_string()),
+            ("tags".to_string(), "a,b".to_string()),
+        ]
+        .into();
+        let pointers: Vec<_> = req.validate().into_iter().map(|e| e.pointer).collect();
+        assert_eq!(pointers, ["/labels/order id", "/labels/tags"]);
+
+        req.labels = (0..=MAX_RUN_LABELS)
+            .map(|i| (format!("k{i}"), "v".to_string()))
+            .collect();
+        assert_eq!(req.validate()[0].pointer, "/labels");
+    }
+
+    #[test]
+    fn send_message_rejects_blank_tool_names() {
+        let mut req = SendMessageRequest {
+            content: "hello".to_string(),
+            input: None,
+            priority: Default::default(),
+            labels: Default::default(),
+            background: false,
+            callback_url: None,
+            tools: Some(ToolRestriction {
+                allow: Some(vec!["web".to_string()]),
+                deny: vec!["bash".to_string()],
+            }),
+        };
+        assert!(req.validate().is_empty());
+
+        req.tools = Some(ToolRestriction {
+            allow: Some(vec!["web".to_string(), " ".to_string()]),
+            deny: vec![String::new()],
+        });
+        let pointers: Vec<_> = req.validate().into_iter().map(|e| e.pointer).collect();
+        assert_eq!(pointers, ["/tools/allow/1", "/tools/deny/0"]);
+    }
+
+    #[test]
+    fn bulk_agents_rejects_blank_fields() {
+        let req = BulkAgentsRequest {
+            operations: vec![
+                BulkAgentOperation::Create {
+                    name: "a".to_string(),
+                    manifest: " ".to_string(),
+                    files: Default::default(),
+                },
+                BulkAgentOperation::Delete {
+                    name: String::new(),
+                },
+            ],
+        };
+        assert_eq!(
+            req.validate(),
+            vec![
+                FieldError::new("/operations/0/manifest", "must not be empty"),
+                FieldError::new("/operations/1/name", "must not be empty"),
+            ]
+        );
+
+        let empty = BulkAgentsRequest { operations: vec![] };
+        assert_eq!(
+            empty.validate(),
+            vec![FieldError::new("/operations", "must not be empty")]
+        );
+    }
+
+    #[test]
+    fn bulk_requeue_checks_window_class_and_rate() {
+        let req = BulkRequeueRequest {
+            since: Some("2026-10-16T10:00:00Z".to_string()),
+            until: Some("2026-10-16T09:00:00Z".to_string()),
+            error_class: Some("timeout".to_string()),
+            rate_per_second: Some(0),
+            ..Default::default()
+        };
+        let pointers: Vec<_> = req.validate().into_iter().map(|e| e.pointer).collect();
+        assert_eq!(pointers, ["/until", "/error_class", "/rate_per_second"]);
+
+        let req = BulkRequeueRequest {
+            since: Some("yesterday".to_string()),
+            error_class: Some("rate_limit".to_string()),
+            ..Default::default()
+        };
+        assert_eq!(
+            req.validate(),
+            vec![FieldError::new("/since", "must be an RFC 3339 timestamp")]
+        );
+        assert!(BulkRequeueRequest::default().validate().is_empty());
+    }
+
+    #[test]
+    fn put_model_rejects_zero_limits_and_negative_prices() {
+        let req = PutModelRequest {
+            context_window: Some(0),
+            pricing: Some(crate::api::ModelPricing {
+                input_per_mtok: -1.0,
+                output_per_mtok: 15.0,
+            }),
+            ..Default::default()
+        };
+        let pointers: Vec<_> = req.validate().into_iter().map(|e| e.pointer).collect();
+        assert_eq!(pointers, ["/context_window", "/pricing/input_per_mtok"]);
+        assert!(PutModelRequest::default().validate().is_empty());
+    }
+}
@@ -0,0 +1,447 @@
+//! Dependency health history.
+//!
+//! A background job checks each dependency — the workspace store and every
+//! LLM provider with credentials — on an interval and keeps the most recent
+//! results per dependency. From those results it derives availability, the
+//! error budget left against `health.slo_target`, how often a dependency
+//! flipped between up and down, and incidents (runs of failed checks).
+
+use std::collections::{BTreeMap, VecDeque};
+use std::path::PathBuf;
+use std::sync::{Arc, Mutex};
+use std::time::{Duration, Instant};
+
+use chrono::{DateTime, Utc};
+use tracing::{debug, warn};
+
+use crate::config::HealthConfig;
+use crate::llm::{Provider, ProviderRegistry};
+
+/// Name of the workspace store dependency.
+pub const STORE_DEPENDENCY: &str = "store";
+
+// ============================================================================
+// Types
+// ============================================================================
+
+/// Outcome of one dependency check.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct CheckResult {
+    pub checked_at: DateTime<Utc>,
+    pub latency_ms: u64,
+    /// Why the check failed; `None` when it passed.
+    pub error: Option<String>,
+}
+
+impl CheckResult {
+    pub fn is_ok(&self) -> bool {
+        self.error.is_none()
+    }
+}
+
+/// Current state of a dependency, from its latest check.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum DependencyStatus {
+    Up,
+    Down,
+    /// Not checked yet.
+    Unknown,
+}
+
+/// A run of consecutive failed checks.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct Incident {
+    pub started_at: DateTime<Utc>,
+    /// When the next check passed; `None` while the incident is ongoing.
+    pub ended_at: Option<DateTime<Utc>>,
+    pub failed_checks: usize,
+    pub last_error: Option<String>,
+}
+
+/// Health of one dependency over the retained checks.
+#[derive(Debug, Clone)]
+pub struct DependencyHealth {
+    pub name: String,
+    pub status: DependencyStatus,
+    pub checks: usize,
+    pub failures: usize,
+    /// Fraction of checks that passed (1.0 with no checks).
+    pub availability: f64,
+    /// Fraction of the error budget left. Negative once overspent.
+    pub error_budget_remaining: f64,
+    /// Changes between up and down. A high count means the dependency flaps.
+    pub transitions: usize,
+    /// Oldest first.
+    pub incidents: Vec<Incident>,
+    /// Retained checks, oldest first.
+    pub history: Vec<CheckResult>,
+}
+
+impl DependencyHealth {
+    /// Fraction of checks since `since` that passed; `None` without checks.
+    pub fn uptime_since(&self, since: DateTime<Utc>) -> Option<f64> {
+        let (checks, passed) = self
+            .history
+            .iter()
+            .filter(|r| r.checked_at >= since)
+            .fold((0, 0), |(checks, passed), r| {
+                (checks + 1, passed + usize::from(r.is_ok()))
+            });
+        (checks > 0).then(|| passed as f64 / checks as f64)
+    }
+}
+
+// ============================================================================
+// HealthHistory
+// ============================================================================
+
+/// Recent check results per dependency, shared by the checker and handlers.
+#[derive(Clone)]
+pub struct HealthHistory {
+    results: Arc<Mutex<BTreeMap<String, VecDeque<CheckResult>>>>,
+    capacity: usize,
+    slo_target: f64,
+}
+
+impl Default for HealthHistory {
+    fn default() -> Self {
+        Self::new(&HealthConfig::default())
+    }
+}
+
+impl HealthHistory {
+    #[must_use]
+    pub fn new(config: &HealthConfig) -> Self {
+        Self {
+            results: Arc::new(Mutex::new(BTreeMap::new())),
+            capacity: config.history_size.max(1),
+            slo_target: config.slo_target.clamp(0.0, 1.0),
+        }
+    }
+
+    pub fn slo_target(&self) -> f64 {
+        self.slo_target
+    }
+
+    /// Record a check result, dropping the oldest once at capacity.
+    pub fn record(&self, dependency: &str, result: CheckResult) {
+        let mut results = self.results.lock().expect("mutex poisoned");
+        let history = results.entry(dependency.to_string()).or_default();
+        if history.len() == self.capacity {
+            history.pop_front();
+        }
+        history.push_back(result);
+    }
+
+    /// Health of every checked dependency, sorted by name.
+    pub fn summaries(&self) -> Vec<DependencyHealth> {
+        let results = self.results.lock().expect("mutex poisoned");
+        results
+            .iter()
+            .map(|(name, history)| summarize(name, history, self.slo_target))
+            .collect()
+    }
+}
+
+fn summarize(name: &str, history: &VecDeque<CheckResult>, slo_target: f64) -> DependencyHealth {
+    let checks = history.len();
+    let failures = history.iter().filter(|r| !r.is_ok()).count();
+    let status = match history.back() {
+        Some(r) if r.is_ok() => DependencyStatus::Up,
+        Some(_) => DependencyStatus::Down,
+        None => DependencyStatus::Unknown,
+    };
+    let transitions = history
+        .iter()
+        .zip(history.iter().skip(1))
+        .filter(|(a, b)| a.is_ok() != b.is_ok())
+        .count();
+
+    let mut incidents: Vec<Incident> = Vec::new();
+    let mut open: Option<Incident> = None;
+    for result in history {
+        if result.is_ok() {
+            if let Some(mut incident) = open.take() {
+                incident.ended_at = Some(result.checked_at);
+                incidents.push(incident);
+            }
+        } else if let Some(incident) = &mut open {
+            incident.failed_checks += 1;
+            incident.last_error = result.error.clone();
+        } else {
+            open = Some(Incident {
+                started_at: result.checked_at,
+                ended_at: None,
+                failed_checks: 1,
+                last_error: result.error.clone(),
+            });
+        }
+    }
+    incidents.extend(open);
+
+    DependencyHealth {
+        name: name.to_string(),
+        status,
+        checks,
+        failures,
+        availability: if checks == 0 {
+            1.0
+        } else {
+            (checks - failures) as f64 / checks as f64
+        },
+        error_budget_remaining: error_budget_remaining(checks, failures, slo_target),
+        transitions,
+        incidents,
+        history: history.iter().cloned().collect(),
+    }
+}
+
+/// Fraction of the error budget left after `failures` out of `checks`.
+///
+/// The budget is the failures `slo_target` allows over `checks`. Values
+/// below zero mean the budget is overspent.
+pub fn error_budget_remaining(checks: usize, failures: usize, slo_target: f64) -> f64 {
+    let allowed = (1.0 - slo_target) * checks as f64;
+    if failures == 0 {
+        1.0
+    } else if allowed <= 0.0 {
+        0.0
+    } else {
+        1.0 - failures as f64 / allowed
+    }
+}
+
+// ============================================================================
+// HealthChecker
+// ============================================================================
+
+/// Runs one round of checks against every dependency.
+#[derive(Clone)]
+pub struct HealthChecker {
+    history: HealthHistory,
+    providers: ProviderRegistry,
+    /// Directory the store probe writes to.
+    store_dir: PathBuf,
+    timeout: Duration,
+}
+
+impl HealthChecker {
+    #[must_use]
+    pub fn new(
+        history: HealthHistory,
+        providers: ProviderRegistry,
+        store_dir: PathBuf,
+        config: &HealthConfig,
+    ) -> Self {
+        Self {
+            history,
+            providers,
+            store_dir,
+            timeout: Duration::from_secs(config.check_timeout_seconds.max(1)),
+        }
+    }
+
+    /// Check all dependencies concurrently and record the results.
+    pub async fn check_all(&self) {
+        let providers: Vec<Provider> = self
+            .providers
+            .available()
+            .into_iter()
+            .map(Provider::from)
+            .filter(|p| *p != Provider::Mock)
+            .collect();
+
+        let store = async {
+            let result = self.timed(self.check_store()).await;
+            (STORE_DEPENDENCY.to_string(), result)
+        };
+        let provider_checks = providers.iter().map(|provider| async move {
+            let result = self.timed(self.check_provider(provider)).await;
+            (format!("provider:{provider}"), result)
+        });
+        let (store, providers) =
+            futures::future::join(store, futures::future::join_all(provider_checks)).await;
+
+        for (name, result) in std::iter::once(store).chain(providers) {
+            if let Some(error) = &result.error {
+                warn!(dependency = %name, error = %error, "Dependency check failed");
+            } else {
+                debug!(dependency = %name, latency_ms = result.latency_ms, "Dependency check passed");
+            }
+            self.history.record(&name, result);
+        }
+    }
+
+    async fn timed(
+        &self,
+        check: impl std::future::Future<Output = Result<(), String>>,
+    ) -> CheckResult {
+        let checked_at = Utc::now();
+        let started = Instant::now();
+        let outcome = match tokio::time::timeout(self.timeout, check).await {
+            Ok(outcome) => outcome,
+            Err(_) => Err(format!("timed out after {}s", self.timeout.as_secs())),
+        };
+        CheckResult {
+            checked_at,
+            latency_ms: started.elapsed().as_millis() as u64,
+            error: outcome.err(),
+        }
+    }
+
+    /// Write, read back, and remove a probe file in the workspace.
+    async fn check_store(&self) -> Result<(), String> {
+        let path = self.store_dir.join(".health-probe");
+        let token = ulid::Ulid::new().to_string();
+        tokio::fs::create_dir_all(&self.store_dir)
+            .await
+            .map_err(|e| format!("create {}: {e}", self.store_dir.display()))?;
+        tokio::fs::write(&path, &token)
+            .await
+            .map_err(|e| format!("write {}: {e}", path.display()))?;
+        let read = tokio::fs::read_to_string(&path)
+            .await
+            .map_err(|e| format!("read {}: {e}", path.display()))?;
+        let _ = tokio::fs::remove_file(&path).await;
+        if read != token {
+            return Err(format!("{} read back different contents", path.display()));
+        }
+        Ok(())
+    }
+
+    async fn check_provider(&self, provider: &Provider) -> Result<(), String> {
+        let client = self
+            .providers
+            .get(provider, None)
+            .await
+            .ok_or_else(|| "credentials unavailable".to_string())?;
+        client.health_check().await.map_err(|e| e.to_string())
+    }
+}
+
+/// Spawn the periodic check loop. The first round runs immediately.
+///
+/// Does nothing when `check_interval_seconds` is 0.
+pub fn spawn_health_checks(checker: HealthChecker, config: &HealthConfig) {
+    if config.check_interval_seconds == 0 {
+        return;
+    }
+    let interval = Duration::from_secs(config.check_interval_seconds);
+
+    tokio::spawn(async move {
+        let mut interval = tokio::time::interval(interval);
+        interval.set_missed_tick_behavior(tokio::time::MissedTickBehavior::Delay);
+        loop {
+            interval.tick().await;
+            checker.check_all().await;
+        }
+    });
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use tempfile::TempDir;
+
+    fn result(minute: u32, error: Option<&str>) -> CheckResult {
+        CheckResult {
+            checked_at: format!("2026-01-15T10:{minute:02}:00Z").parse().unwrap(),
+            latency_ms: 5,
+            error: error.map(String::from),
+        }
+    }
+
+    fn history(capacity: usize) -> HealthHistory {
+        HealthHistory::new(&HealthConfig {
+            history_size: capacity,
+            slo_target: 0.9,
+            ..HealthConfig::default()
+        })
+    }
+
+    #[test]
+    fn summaries_track_incidents_and_transitions() {
+        let history = history(10);
+        for (minute, error) in [
+            (0, None),
+            (1, Some("502")),
+            (2, Some("timed out")),
+            (3, None),
+            (4, None),
+            (5, Some("502")),
+        ] {
+            history.record("provider:openai", result(minute, error));
+        }
+
+        let summaries = history.summaries();
+        assert_eq!(summaries.len(), 1);
+        let health = &summaries[0];
+        assert_eq!(health.status, DependencyStatus::Down);
+        assert_eq!(health.checks, 6);
+        assert_eq!(health.failures, 3);
+        assert_eq!(health.availability, 0.5);
+        assert_eq!(health.transitions, 3);
+        assert_eq!(health.incidents.len(), 2);
+        assert_eq!(health.incidents[0].started_at, result(1, None).checked_at);
+        assert_eq!(
+            health.incidents[0].ended_at,
+            Some(result(3, None).checked_at)
+        );
+        assert_eq!(health.incidents[0].failed_checks, 2);
+        assert_eq!(health.incidents[0].last_error.as_deref(), Some("timed out"));
+        assert_eq!(health.incidents[1].ended_at, None);
+    }
+
+    #[test]
+    fn history_keeps_most_recent_results() {
+        let history = history(3);
+        for minute in 0..5 {
+            history.record(STORE_DEPENDENCY, result(minute, None));
+        }
+        let health = &history.summaries()[0];
+        assert_eq!(health.checks, 3);
+        assert_eq!(health.history[0], result(2, None));
+        assert_eq!(health.status, DependencyStatus::Up);
+    }
+
+    #[test]
+    fn uptime_only_counts_checks_in_window() {
+        let history = history(10);
+        history.record(STORE_DEPENDENCY, result(0, Some("down")));
+        history.record(STORE_DEPENDENCY, result(1, None));
+        history.record(STORE_DEPENDENCY, result(2, Some("down")));
+        let health = &history.summaries()[0];
+        let since = |minute| result(minute, None).checked_at;
+        assert_eq!(health.uptime_since(since(1)), Some(0.5));
+        assert_eq!(health.uptime_since(since(3)), None);
+    }
+
+    #[test]
+    fn error_budget_is_relative_to_allowed_failures() {
+        assert_eq!(error_budget_remaining(0, 0, 0.99), 1.0);
+        assert!((error_budget_remaining(100, 0, 0.9) - 1.0).abs() < 1e-9);
+        assert!((error_budget_remaining(100, 5, 0.9) - 0.5).abs() < 1e-9);
+        assert!((error_budget_remaining(100, 20, 0.9) + 1.0).abs() < 1e-9);
+        assert_eq!(error_budget_remaining(100, 1, 1.0), 0.0);
+    }
+
+    #[tokio::test]
+    async fn check_all_probes_store() {
+        let tmp = TempDir::new().unwrap();
+        let history = HealthHistory::default();
+        let checker = HealthChecker::new(
+            history.clone(),
+            ProviderRegistry::new(),
+            tmp.path().to_path_buf(),
+            &HealthConfig::default(),
+        );
+
+        checker.check_all().await;
+
+        let summaries = history.summaries();
+        assert_eq!(summaries.len(), 1);
+        assert_eq!(summaries[0].name, STORE_DEPENDENCY);
+        assert_eq!(summaries[0].status, DependencyStatus::Up);
+        assert!(!tmp.path().join(".health-probe").exists());
+    }
+}
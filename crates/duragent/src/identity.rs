@@ -0,0 +1,216 @@
+//! Provisioned users and groups, and the access they map to.
+//!
+//! Users and groups are created and updated by an identity provider through
+//! the SCIM endpoints (`/scim/v2`). Access comes from group membership: each
+//! `scim.group_roles` entry grants a role, in some or all projects, to the
+//! members of one group. Deactivated users have no access. Roles are enforced
+//! on API requests that name a user through `authorization.user_header`.
+
+use chrono::{DateTime, Utc};
+use serde::{Deserialize, Serialize};
+
+use crate::config::{AccessRole, GroupRoleConfig};
+
+/// ID prefix for provisioned users.
+pub const USER_ID_PREFIX: &str = "user_";
+/// ID prefix for provisioned groups.
+pub const GROUP_ID_PREFIX: &str = "group_";
+
+// ============================================================================
+// Types
+// ============================================================================
+
+/// A user provisioned by the identity provider.
+#[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
+pub struct User {
+    pub id: String,
+    /// Unique login name (case-insensitive).
+    pub user_name: String,
+    /// ID of the user in the identity provider.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub external_id: Option<String>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub display_name: Option<String>,
+    #[serde(default, skip_serializing_if = "Vec::is_empty")]
+    pub emails: Vec<String>,
+    pub active: bool,
+    pub created_at: DateTime<Utc>,
+    pub updated_at: DateTime<Utc>,
+}
+
+impl User {
+    pub fn new(user_name: String) -> Self {
+        let now = Utc::now();
+        Self {
+            id: format!("{USER_ID_PREFIX}{}", ulid::Ulid::new()),
+            user_name,
+            external_id: None,
+            display_name: None,
+            emails: Vec::new(),
+            active: true,
+            created_at: now,
+            updated_at: now,
+        }
+    }
+}
+
+/// A group provisioned by the identity provider.
+#[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
+pub struct Group {
+    pub id: String,
+    /// Unique group name; matched against `scim.group_roles[].group`.
+    pub display_name: String,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub external_id: Option<String>,
+    /// IDs of member users.
+    #[serde(default)]
+    pub members: Vec<String>,
+    pub created_at: DateTime<Utc>,
+    pub updated_at: DateTime<Utc>,
+}
+
+impl Group {
+    pub fn new(display_name: String) -> Self {
+        let now = Utc::now();
+        Self {
+            id: format!("{GROUP_ID_PREFIX}{}", ulid::Ulid::new()),
+            display_name,
+            external_id: None,
+            members: Vec::new(),
+            created_at: now,
+            updated_at: now,
+        }
+    }
+
+    /// Add a member, ignoring duplicates.
+    pub fn add_member(&mut self, user_id: &str) {
+        if !self.members.iter().any(|m| m == user_id) {
+            self.members.push(user_id.to_string());
+        }
+    }
+
+    pub fn remove_member(&mut self, user_id: &str) {
+        self.members.retain(|m| m != user_id);
+    }
+}
+
+/// A role held in one project, or in every project when `project` is `None`.
+#[derive(Debug, Clone, PartialEq, Eq, PartialOrd, Ord, Serialize)]
+pub struct Grant {
+    pub role: AccessRole,
+    pub project: Option<String>,
+}
+
+// ============================================================================
+// Access
+// ============================================================================
+
+/// Access `user` holds through its memberships in `groups`.
+///
+/// Sorted and deduplicated. Empty for deactivated users.
+pub fn grants_for(user: &User, groups: &[Group], mappings: &[GroupRoleConfig]) -> Vec<Grant> {
+    if !user.active {
+        return Vec::new();
+    }
+
+    let mut grants: Vec<Grant> = groups
+        .iter()
+        .filter(|g| g.members.iter().any(|m| *m == user.id))
+        .flat_map(|g| mappings.iter().filter(|m| m.group == g.display_name))
+        .flat_map(|m| {
+            let projects: Vec<Option<String>> = if m.projects.is_empty() {
+                vec![None]
+            } else {
+                m.projects.iter().cloned().map(Some).collect()
+            };
+            projects.into_iter().map(|project| Grant {
+                role: m.role,
+                project,
+            })
+        })
+        .collect();
+    grants.sort();
+    grants.dedup();
+    grants
+}
+
+/// Highest role `grants` give in `project`.
+///
+/// `None` stands for cross-project targets, which only grants for every
+/// project cover.
+pub fn role_in(grants: &[Grant], project: Option<&str>) -> Option<AccessRole> {
+    grants
+        .iter()
+        .filter(|g| match (&g.project, project) {
+            (None, _) => true,
+            (Some(granted), Some(project)) => granted == project,
+            (Some(_), None) => false,
+        })
+        .map(|g| g.role)
+        .max()
+}
+
+/// Whether `role` permits `verb` on an API `resource` (see
+/// [`crate::service_accounts::RESOURCES`]).
+pub fn role_permits(role: AccessRole, resource: &str, verb: &str) -> bool {
+    match role {
+        AccessRole::Admin => true,
+        AccessRole::Operator if matches!((resource, verb), ("sessions" | "runs", "create")) => true,
+        AccessRole::Operator | AccessRole::Viewer => {
+            verb == "read" && matches!(resource, "agents" | "sessions" | "runs" | "usage")
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn mapping(group: &str, role: AccessRole, projects: &[&str]) -> GroupRoleConfig {
+        GroupRoleConfig {
+            group: group.to_string(),
+            role,
+            projects: projects.iter().map(|p| p.to_string()).collect(),
+        }
+    }
+
+    #[test]
+    fn grants_follow_group_membership() {
+        let mut user = User::new("ada@example.com".to_string());
+        let mut admins = Group::new("platform-admins".to_string());
+        let mut support = Group::new("support".to_string());
+        let other = Group::new("other".to_string());
+        admins.add_member(&user.id);
+        support.add_member(&user.id);
+        support.add_member(&user.id);
+        assert_eq!(support.members.len(), 1);
+
+        let mappings = vec![
+            mapping("platform-admins", AccessRole::Admin, &["billing"]),
+            mapping("support", AccessRole::Operator, &[]),
+            mapping("other", AccessRole::Admin, &[]),
+        ];
+        let groups = vec![admins, support, other];
+        let grants = grants_for(&user, &groups, &mappings);
+        assert_eq!(grants.len(), 2);
+        assert_eq!(role_in(&grants, Some("billing")), Some(AccessRole::Admin));
+        assert_eq!(
+            role_in(&grants, Some("marketing")),
+            Some(AccessRole::Operator)
+        );
+        assert_eq!(role_in(&grants, None), Some(AccessRole::Operator));
+
+        user.active = false;
+        assert!(grants_for(&user, &groups, &mappings).is_empty());
+    }
+
+    #[test]
+    fn roles_widen_from_viewer_to_admin() {
+        assert!(role_permits(AccessRole::Viewer, "sessions", "read"));
+        assert!(!role_permits(AccessRole::Viewer, "sessions", "create"));
+        assert!(role_permits(AccessRole::Operator, "runs", "create"));
+        assert!(!role_permits(AccessRole::Operator, "agents", "update"));
+        assert!(!role_permits(AccessRole::Operator, "admin", "read"));
+        assert!(role_permits(AccessRole::Admin, "agents", "delete"));
+    }
+}
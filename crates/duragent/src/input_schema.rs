@@ -0,0 +1,431 @@
+//! Structured invoke input.
+//!
+//! An agent can declare a JSON Schema for what callers send it:
+//!
+//! ```yaml
+//! input_schema:
+//!   type: object
+//!   required: [ticket_id]
+//!   properties:
+//!     ticket_id: { type: string }
+//!     priority: { enum: [low, normal, high] }
+//! ```
+//!
+//! Messages to such an agent must carry a JSON value that validates against
+//! the schema. Only a subset of JSON Schema is supported; [`check_schema`]
+//! rejects anything outside it when the agent loads, so a schema never
+//! silently accepts input it was meant to refuse.
+
+use serde_json::{Map, Value};
+
+/// Keywords that describe a value without constraining it.
+const ANNOTATIONS: &[&str] = &[
+    "title",
+    "description",
+    "default",
+    "examples",
+    "$schema",
+    "$id",
+    "$comment",
+    "format",
+    "deprecated",
+    "readOnly",
+    "writeOnly",
+];
+
+const TYPES: &[&str] = &[
+    "null", "boolean", "object", "array", "number", "integer", "string",
+];
+
+/// A value that doesn't match the schema.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct SchemaError {
+    /// JSON Pointer to the offending value (empty for the root).
+    pub pointer: String,
+    pub message: String,
+}
+
+// ============================================================================
+// Schema checking
+// ============================================================================
+
+/// Check that `schema` only uses supported keywords, with well-formed values.
+pub fn check_schema(schema: &Value) -> Result<(), String> {
+    check_at(schema, "")
+}
+
+fn check_at(schema: &Value, path: &str) -> Result<(), String> {
+    let Value::Object(schema) = schema else {
+        return Err(format!("{} must be an object", display(path)));
+    };
+
+    for (keyword, value) in schema {
+        let at = format!("{path}/{keyword}");
+        match keyword.as_str() {
+            "type" => {
+                let valid = match value {
+                    Value::String(name) => TYPES.contains(&name.as_str()),
+                    Value::Array(names) => {
+                        !names.is_empty()
+                            && names
+                                .iter()
+                                .all(|name| name.as_str().is_some_and(|n| TYPES.contains(&n)))
+                    }
+                    _ => false,
+                };
+                if !valid {
+                    return Err(format!(
+                        "{at} must be one of {} or a list of them",
+                        TYPES.join(", ")
+                    ));
+                }
+            }
+            "enum" => {
+                if value.as_array().is_none_or(|values| values.is_empty()) {
+                    return Err(format!("{at} must be a non-empty list"));
+                }
+            }
+            "const" => {}
+            "properties" => {
+                let Value::Object(properties) = value else {
+                    return Err(format!("{at} must be an object"));
+                };
+                for (name, property) in properties {
+                    check_at(property, &format!("{at}/{}", escape(name)))?;
+                }
+            }
+            "required" => {
+                let valid = value
+                    .as_array()
+                    .is_some_and(|names| names.iter().all(Value::is_string));
+                if !valid {
+                    return Err(format!("{at} must be a list of property names"));
+                }
+            }
+            "additionalProperties" => {
+                if !value.is_boolean() {
+                    check_at(value, &at)?;
+                }
+            }
+            "items" => check_at(value, &at)?,
+            "minLength" | "maxLength" | "minItems" | "maxItems" => {
+                if value.as_u64().is_none() {
+                    return Err(format!("{at} must be a non-negative integer"));
+                }
+            }
+            "minimum" | "maximum" | "exclusiveMinimum" | "exclusiveMaximum" => {
+                if !value.is_number() {
+                    return Err(format!("{at} must be a number"));
+                }
+            }
+            keyword if ANNOTATIONS.contains(&keyword) => {}
+            keyword => return Err(format!("{at}: unsupported keyword '{keyword}'")),
+        }
+    }
+    Ok(())
+}
+
+fn display(path: &str) -> &str {
+    if path.is_empty() { "schema" } else { path }
+}
+
+// ============================================================================
+// Validation
+// ============================================================================
+
+/// Validate `value` against `schema`, which must have passed
+/// [`check_schema`]. Returns every mismatch found; empty means valid.
+pub fn validate(schema: &Value, value: &Value) -> Vec<SchemaError> {
+    let mut errors = Vec::new();
+    if let Value::Object(schema) = schema {
+        validate_at(schema, value, "", &mut errors);
+    }
+    errors
+}
+
+fn validate_at(
+    schema: &Map<String, Value>,
+    value: &Value,
+    pointer: &str,
+    errors: &mut Vec<SchemaError>,
+) {
+    if let Some(types) = schema.get("type") {
+        let types: Vec<&str> = match types {
+            Value::String(name) => vec![name.as_str()],
+            Value::Array(names) => names.iter().filter_map(Value::as_str).collect(),
+            _ => Vec::new(),
+        };
+        if !types.iter().any(|name| has_type(value, name)) {
+            fail(
+                errors,
+                pointer,
+                format!("must be of type {}", types.join(" or ")),
+            );
+            return;
+        }
+    }
+    if let Some(Value::Array(allowed)) = schema.get("enum")
+        && !allowed.contains(value)
+    {
+        let allowed: Vec<String> = allowed.iter().map(Value::to_string).collect();
+        fail(
+            errors,
+            pointer,
+            format!("must be one of {}", allowed.join(", ")),
+        );
+    }
+    if let Some(expected) = schema.get("const")
+        && expected != value
+    {
+        fail(errors, pointer, format!("must be {expected}"));
+    }
+
+    match value {
+        Value::String(s) => {
+            let len = s.chars().count() as u64;
+            if let Some(min) = schema.get("minLength").and_then(Value::as_u64)
+                && len < min
+            {
+                fail(
+                    errors,
+                    pointer,
+                    format!("must be at least {min} characters long"),
+                );
+            }
+            if let Some(max) = schema.get("maxLength").and_then(Value::as_u64)
+                && len > max
+            {
+                fail(
+                    errors,
+                    pointer,
+                    format!("must be at most {max} characters long"),
+                );
+            }
+        }
+        Value::Number(n) => {
+            let n = n.as_f64().unwrap_or(f64::NAN);
+            let bound = |keyword| schema.get(keyword).and_then(Value::as_f64);
+            if let Some(min) = bound("minimum")
+                && n < min
+            {
+                fail(errors, pointer, format!("must be at least {min}"));
+            }
+            if let Some(max) = bound("maximum")
+                && n > max
+            {
+                fail(errors, pointer, format!("must be at most {max}"));
+            }
+            if let Some(min) = bound("exclusiveMinimum")
+                && n <= min
+            {
+                fail(errors, pointer, format!("must be greater than {min}"));
+            }
+            if let Some(max) = bound("exclusiveMaximum")
+                && n >= max
+            {
+                fail(errors, pointer, format!("must be less than {max}"));
+            }
+        }
+        Value::Array(items) => {
+            let len = items.len() as u64;
+            if let Some(min) = schema.get("minItems").and_then(Value::as_u64)
+                && len < min
+            {
+                fail(errors, pointer, format!("must have at least {min} items"));
+            }
+            if let Some(max) = schema.get("maxItems").and_then(Value::as_u64)
+                && len > max
+            {
+                fail(errors, pointer, format!("must have at most {max} items"));
+            }
+            if let Some(Value::Object(item_schema)) = schema.get("items") {
+                for (i, item) in items.iter().enumerate() {
+                    validate_at(item_schema, item, &format!("{pointer}/{i}"), errors);
+                }
+            }
+        }
+        Value::Object(object) => {
+            if let Some(Value::Array(required)) = schema.get("required") {
+                for name in required.iter().filter_map(Value::as_str) {
+                    if !object.contains_key(name) {
+                        fail(
+                            errors,
+                            &format!("{pointer}/{}", escape(name)),
+                            "is required".into(),
+                        );
+                    }
+                }
+            }
+            let properties = schema.get("properties").and_then(Value::as_object);
+            for (name, property) in object {
+                let at = format!("{pointer}/{}", escape(name));
+                match (
+                    properties.and_then(|p| p.get(name)),
+                    schema.get("additionalProperties"),
+                ) {
+                    (Some(Value::Object(property_schema)), _) => {
+                        validate_at(property_schema, property, &at, errors)
+                    }
+                    (Some(_), _) => {}
+                    (None, Some(Value::Bool(false))) => fail(errors, &at, "is not allowed".into()),
+                    (None, Some(Value::Object(extra_schema))) => {
+                        validate_at(extra_schema, property, &at, errors)
+                    }
+                    (None, _) => {}
+                }
+            }
+        }
+        Value::Null | Value::Bool(_) => {}
+    }
+}
+
+fn fail(errors: &mut Vec<SchemaError>, pointer: &str, message: String) {
+    errors.push(SchemaError {
+        pointer: pointer.to_string(),
+        message,
+    });
+}
+
+fn has_type(value: &Value, name: &str) -> bool {
+    match name {
+        "null" => value.is_null(),
+        "boolean" => value.is_boolean(),
+        "object" => value.is_object(),
+        "array" => value.is_array(),
+        "number" => value.is_number(),
+        "integer" => match value {
+            Value::Number(n) => {
+                n.is_i64() || n.is_u64() || n.as_f64().is_some_and(|f| f.fract() == 0.0)
+            }
+            _ => false,
+        },
+        "string" => value.is_string(),
+        _ => false,
+    }
+}
+
+/// Escape a property name for use in a JSON Pointer.
+fn escape(name: &str) -> String {
+    name.replace('~', "~0").replace('/', "~1")
+}
+
+#[cfg(test)]
+mod tests {
+    use serde_json::json;
+
+    use super::*;
+
+    fn ticket_schema() -> Value {
+        json!({
+            "type": "object",
+            "required": ["ticket_id"],
+            "additionalProperties": false,
+            "properties": {
+                "ticket_id": { "type": "string", "minLength": 1, "description": "Ticket key" },
+                "priority": { "enum": ["low", "normal", "high"] },
+                "tags": { "type": "array", "items": { "type": "string" }, "maxItems": 2 },
+                "estimate": { "type": ["integer", "null"], "minimum": 0 }
+            }
+        })
+    }
+
+    /// Errors as `(pointer, message)`, sorted by pointer.
+    fn errors(schema: &Value, value: Value) -> Vec<(String, String)> {
+        let mut errors: Vec<_> = validate(schema, &value)
+            .into_iter()
+            .map(|e| (e.pointer, e.message))
+            .collect();
+        errors.sort();
+        errors
+    }
+
+    #[test]
+    fn check_accepts_supported_keywords() {
+        check_schema(&ticket_schema()).unwrap();
+    }
+
+    #[test]
+    fn check_rejects_unsupported_keywords() {
+        let err = check_schema(&json!({
+            "type": "object",
+            "properties": { "id": { "oneOf": [{ "type": "string" }] } }
+        }))
+        .unwrap_err();
+        assert_eq!(err, "/properties/id/oneOf: unsupported keyword 'oneOf'");
+
+        assert!(check_schema(&json!({ "type": "text" })).is_err());
+        assert!(check_schema(&json!({ "minLength": -1 })).is_err());
+        assert!(check_schema(&json!("string")).is_err());
+    }
+
+    #[test]
+    fn valid_input_has_no_errors() {
+        let value = json!({
+            "ticket_id": "T-1",
+            "priority": "high",
+            "tags": ["billing"],
+            "estimate": null
+        });
+        assert!(errors(&ticket_schema(), value).is_empty());
+    }
+
+    #[test]
+    fn reports_every_mismatch_with_its_pointer() {
+        let value = json!({
+            "priority": "urgent",
+            "tags": ["a", 2, "c"],
+            "estimate": 1.5,
+            "owner": "sam"
+        });
+        assert_eq!(
+            errors(&ticket_schema(), value),
+            vec![
+                ("/estimate".into(), "must be of type integer or null".into()),
+                ("/owner".into(), "is not allowed".into()),
+                (
+                    "/priority".into(),
+                    r#"must be one of "low", "normal", "high""#.into()
+                ),
+                ("/tags".into(), "must have at most 2 items".into()),
+                ("/tags/1".into(), "must be of type string".into()),
+                ("/ticket_id".into(), "is required".into()),
+            ]
+        );
+    }
+
+    #[test]
+    fn root_type_mismatch_stops_validation() {
+        assert_eq!(
+            errors(&ticket_schema(), json!("T-1")),
+            vec![("".into(), "must be of type object".into())]
+        );
+    }
+
+    #[test]
+    fn numeric_and_length_bounds() {
+        let schema = json!({ "type": "number", "exclusiveMinimum": 0, "maximum": 10 });
+        assert_eq!(
+            errors(&schema, json!(0)),
+            vec![("".into(), "must be greater than 0".into())]
+        );
+        assert_eq!(
+            errors(&schema, json!(11)),
+            vec![("".into(), "must be at most 10".into())]
+        );
+
+        let schema = json!({ "type": "string", "minLength": 2 });
+        assert_eq!(
+            errors(&schema, json!("é")),
+            vec![("".into(), "must be at least 2 characters long".into())]
+        );
+    }
+
+    #[test]
+    fn pointers_escape_property_names() {
+        let schema = json!({ "required": ["a/b"] });
+        assert_eq!(
+            errors(&schema, json!({})),
+            vec![("/a~1b".into(), "is required".into())]
+        );
+    }
+}
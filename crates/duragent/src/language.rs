@@ -0,0 +1,273 @@
+//! Input language detection and reply localization.
+//!
+//! A lightweight guess at the language a message is written in, returned as
+//! an ISO 639-1 code. Non-Latin scripts are recognized by their characters;
+//! Latin-script languages by common function words. Short or mixed messages
+//! may have no clear answer, in which case nothing is returned.
+//!
+//! Agents with a `language` section can be told which language to reply in:
+//!
+//! ```yaml
+//! language:
+//!   reply: input     # or a fixed language, e.g. fr
+//!   fallback: en     # when the input's language can't be detected
+//! ```
+
+use crate::agent::{AgentLanguageConfig, REPLY_IN_INPUT_LANGUAGE};
+use crate::context::{BlockSource, SystemBlock, priority};
+
+/// Function words for Latin-script languages. A word may appear under more
+/// than one language.
+const STOPWORDS: &[(&str, &[&str])] = &[
+    (
+        "en",
+        &[
+            "the", "and", "is", "are", "you", "what", "how", "this", "that", "with", "have", "for",
+            "not", "it", "my", "do", "can", "please", "of", "to", "hello", "thanks",
+        ],
+    ),
+    (
+        "es",
+        &[
+            "el", "la", "los", "las", "que", "de", "y", "es", "por", "para", "con", "una", "un",
+            "cómo", "qué", "está", "hola", "gracias", "pero", "mi",
+        ],
+    ),
+    (
+        "fr",
+        &[
+            "le", "la", "les", "et", "est", "une", "des", "pour", "avec", "vous", "je", "pas",
+            "que", "bonjour", "merci", "dans", "ce", "mon", "sur",
+        ],
+    ),
+    (
+        "de",
+        &[
+            "der", "die", "das", "und", "ist", "nicht", "ich", "sie", "mit", "ein", "eine", "für",
+            "auf", "wie", "was", "hallo", "danke", "bitte", "mein",
+        ],
+    ),
+    (
+        "it",
+        &[
+            "il", "lo", "gli", "che", "di", "e", "è", "per", "con", "non", "sono", "una", "ciao",
+            "grazie", "come", "cosa", "mio",
+        ],
+    ),
+    (
+        "pt",
+        &[
+            "o", "os", "as", "que", "de", "e", "é", "não", "para", "com", "uma", "um", "você",
+            "olá", "obrigado", "como", "meu",
+        ],
+    ),
+    (
+        "nl",
+        &[
+            "de", "het", "een", "en", "niet", "ik", "je", "van", "met", "voor", "hallo", "dank",
+            "wat", "hoe", "mijn",
+        ],
+    ),
+    (
+        "id",
+        &[
+            "yang",
+            "dan",
+            "di",
+            "ini",
+            "itu",
+            "saya",
+            "anda",
+            "tidak",
+            "dengan",
+            "untuk",
+            "apa",
+            "bagaimana",
+            "terima",
+            "kasih",
+            "halo",
+        ],
+    ),
+];
+
+/// English names for the codes [`detect`] returns, used in reply instructions.
+const NAMES: &[(&str, &str)] = &[
+    ("ar", "Arabic"),
+    ("de", "German"),
+    ("el", "Greek"),
+    ("en", "English"),
+    ("es", "Spanish"),
+    ("fr", "French"),
+    ("he", "Hebrew"),
+    ("hi", "Hindi"),
+    ("id", "Indonesian"),
+    ("it", "Italian"),
+    ("ja", "Japanese"),
+    ("ko", "Korean"),
+    ("nl", "Dutch"),
+    ("pt", "Portuguese"),
+    ("ru", "Russian"),
+    ("th", "Thai"),
+    ("zh", "Chinese"),
+];
+
+/// The languages an agent's `language` config picks for one message.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct Localization {
+    /// Language the message was detected as.
+    pub detected: Option<&'static str>,
+    /// Language the agent is told to reply in.
+    pub reply: Option<String>,
+}
+
+/// Detect the language of `input` and pick the reply language.
+pub fn localize(config: &AgentLanguageConfig, input: &str) -> Localization {
+    let detected = detect(input);
+    let reply = match config.reply.as_deref() {
+        Some(REPLY_IN_INPUT_LANGUAGE) => detected
+            .map(str::to_string)
+            .or_else(|| config.fallback.clone()),
+        Some(code) => Some(code.to_string()),
+        None => None,
+    };
+    Localization { detected, reply }
+}
+
+/// System block telling the agent to reply in `language`.
+pub fn reply_block(language: &str) -> SystemBlock {
+    let name = NAMES
+        .iter()
+        .find(|(code, _)| *code == language)
+        .map_or(language, |(_, name)| name);
+    SystemBlock {
+        content: format!(
+            "Always reply in {name}, whatever language the conversation or your instructions are in."
+        ),
+        label: "language".to_string(),
+        source: BlockSource::Runtime,
+        priority: priority::LANGUAGE,
+    }
+}
+
+/// Guess the language of `text`, as an ISO 639-1 code.
+pub fn detect(text: &str) -> Option<&'static str> {
+    detect_script(text).or_else(|| detect_latin(text))
+}
+
+/// Languages identified by their script, when most letters are in it.
+fn detect_script(text: &str) -> Option<&'static str> {
+    let mut letters = 0;
+    let mut counts: Vec<(&'static str, usize)> = Vec::new();
+    for c in text.chars().filter(|c| c.is_alphabetic()) {
+        letters += 1;
+        if let Some(language) = script_language(c) {
+            match counts.iter_mut().find(|(l, _)| *l == language) {
+                Some((_, count)) => *count += 1,
+                None => counts.push((language, 1)),
+            }
+        }
+    }
+    // Kana marks Japanese even among mostly Han characters.
+    if counts.iter().any(|(l, _)| *l == "ja") {
+        return Some("ja");
+    }
+    counts
+        .into_iter()
+        .max_by_key(|(_, count)| *count)
+        .filter(|(_, count)| count * 2 > letters)
+        .map(|(language, _)| language)
+}
+
+fn script_language(c: char) -> Option<&'static str> {
+    Some(match c {
+        '\u{3040}'..='\u{30FF}' => "ja",
+        '\u{AC00}'..='\u{D7AF}' | '\u{1100}'..='\u{11FF}' => "ko",
+        '\u{4E00}'..='\u{9FFF}' | '\u{3400}'..='\u{4DBF}' => "zh",
+        '\u{0400}'..='\u{04FF}' => "ru",
+        '\u{0600}'..='\u{06FF}' => "ar",
+        '\u{0590}'..='\u{05FF}' => "he",
+        '\u{0370}'..='\u{03FF}' => "el",
+        '\u{0E00}'..='\u{0E7F}' => "th",
+        '\u{0900}'..='\u{097F}' => "hi",
+        _ => return None,
+    })
+}
+
+/// Latin-script languages by function-word hits; ties have no answer.
+fn detect_latin(text: &str) -> Option<&'static str> {
+    let lowercase = text.to_lowercase();
+    let words: Vec<&str> = lowercase
+        .split(|c: char| !c.is_alphabetic())
+        .filter(|w| !w.is_empty())
+        .collect();
+    let mut scores: Vec<(&'static str, usize)> = STOPWORDS
+        .iter()
+        .map(|(language, stopwords)| {
+            let hits = words.iter().filter(|w| stopwords.contains(w)).count();
+            (*language, hits)
+        })
+        .filter(|(_, hits)| *hits > 0)
+        .collect();
+    scores.sort_by(|a, b| b.1.cmp(&a.1));
+    match scores.as_slice() {
+        [(language, _)] => Some(*language),
+        [(language, best), (_, next), ..] if best > next => Some(*language),
+        _ => None,
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn detects_latin_languages_by_function_words() {
+        assert_eq!(detect("What is the weather like today?"), Some("en"));
+        assert_eq!(detect("¿Cómo está el tiempo para mañana?"), Some("es"));
+        assert_eq!(detect("Ich habe das nicht verstanden"), Some("de"));
+        assert_eq!(detect("Saya tidak tahu apa itu"), Some("id"));
+    }
+
+    #[test]
+    fn detects_languages_by_script() {
+        assert_eq!(detect("今日はいい天気ですね"), Some("ja"));
+        assert_eq!(detect("今天天气很好"), Some("zh"));
+        assert_eq!(detect("안녕하세요"), Some("ko"));
+        assert_eq!(detect("Привет, как дела?"), Some("ru"));
+    }
+
+    #[test]
+    fn reply_language_follows_input_or_config() {
+        let follow = AgentLanguageConfig {
+            reply: Some("input".to_string()),
+            fallback: Some("en".to_string()),
+        };
+        assert_eq!(
+            localize(&follow, "Ich habe das nicht verstanden"),
+            Localization {
+                detected: Some("de"),
+                reply: Some("de".to_string()),
+            }
+        );
+        assert_eq!(localize(&follow, "12345").reply.as_deref(), Some("en"));
+
+        let fixed = AgentLanguageConfig {
+            reply: Some("fr".to_string()),
+            fallback: None,
+        };
+        assert_eq!(
+            localize(&fixed, "What is this?").reply.as_deref(),
+            Some("fr")
+        );
+        assert!(reply_block("fr").content.contains("French"));
+
+        let detect_only = AgentLanguageConfig::default();
+        assert_eq!(localize(&detect_only, "What is this?").reply, None);
+    }
+
+    #[test]
+    fn unclear_input_has_no_language() {
+        assert_eq!(detect("12345"), None);
+        assert_eq!(detect("Kubernetes"), None);
+    }
+}
@@ -81,7 +81,7 @@ pub async fn ensure_server_running(opts: LaunchOptions<'_>) -> Result<AgentClien
 
     // Build local server URL (always 127.0.0.1 for security)
     let port = opts.config.server.port;
-    let local_url = format!("http://127.0.0.1:{}", port);
+    let local_url = opts.config.server.local_url(port);
     let client = AgentClient::new(&local_url);
 
     // Check if server is already running and serves the right workspace
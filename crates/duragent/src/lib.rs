@@ -21,28 +21,92 @@ pub mod llm;
 #[cfg(feature = "server")]
 pub mod agent;
 #[cfg(feature = "server")]
+pub mod agent_routing;
+#[cfg(feature = "server")]
+pub mod artifacts;
+#[cfg(feature = "server")]
+pub mod audit;
+#[cfg(feature = "server")]
 pub mod background;
 #[cfg(feature = "server")]
+pub mod best_of;
+#[cfg(feature = "server")]
+pub mod callbacks;
+#[cfg(feature = "server")]
 pub mod context;
 #[cfg(feature = "server")]
+pub mod encryption;
+#[cfg(feature = "server")]
+pub mod ensemble;
+#[cfg(feature = "server")]
+pub mod examples;
+#[cfg(feature = "server")]
+pub mod faults;
+#[cfg(feature = "server")]
+pub mod features;
+#[cfg(feature = "server")]
 pub mod gateway;
 #[cfg(feature = "server")]
 pub mod handlers;
 #[cfg(feature = "server")]
+pub mod health;
+#[cfg(feature = "server")]
+pub mod identity;
+#[cfg(feature = "server")]
+pub mod input_schema;
+#[cfg(feature = "server")]
+pub mod language;
+#[cfg(feature = "server")]
 pub mod memory;
 #[cfg(feature = "server")]
+pub mod metrics;
+#[cfg(feature = "server")]
+pub mod model_routing;
+#[cfg(feature = "server")]
+pub mod models;
+#[cfg(feature = "server")]
+pub mod policy;
+#[cfg(feature = "server")]
+pub mod postprocess;
+#[cfg(feature = "server")]
 pub mod process;
 #[cfg(feature = "server")]
+pub mod prompts;
+#[cfg(feature = "server")]
+pub mod provider_stats;
+#[cfg(feature = "server")]
+pub mod public_agents;
+#[cfg(feature = "server")]
+pub mod runs;
+#[cfg(feature = "server")]
 pub mod sandbox;
 #[cfg(feature = "server")]
 pub mod scheduler;
 #[cfg(feature = "server")]
 pub mod server;
 #[cfg(feature = "server")]
+pub mod service_accounts;
+#[cfg(feature = "server")]
 pub mod session;
 #[cfg(feature = "server")]
+pub mod shares;
+#[cfg(feature = "server")]
+pub mod signing;
+#[cfg(feature = "server")]
+pub mod slo;
+#[cfg(feature = "server")]
 pub mod store;
 #[cfg(feature = "server")]
+pub mod stream_processors;
+#[cfg(feature = "server")]
 pub mod sync;
 #[cfg(feature = "server")]
+pub mod throttle;
+#[cfg(feature = "server")]
 pub mod tools;
+#[cfg(feature = "server")]
+pub mod upgrade;
+#[cfg(feature = "server")]
+pub mod usage;
+#[cfg(feature = "server")]
+pub mod user_memory;
@@ -11,6 +11,7 @@ use super::{
     ChatRequest, ChatResponse, ChatStream, Choice, FunctionCall, LLMError, LLMProvider, Message,
     Role, StreamEvent, ToolCall, ToolDefinition, Usage, check_response_error,
 };
+use crate::provider_stats::RateLimitObserver;
 use crate::sse_parser::SseEventStream;
 
 /// Authentication mode for the Anthropic provider.
@@ -27,6 +28,7 @@ pub struct AnthropicProvider {
     base_url: String,
     auth: AnthropicAuth,
     api_version: String,
+    rate_limits: Option<RateLimitObserver>,
 }
 
 impl AnthropicProvider {
@@ -39,18 +41,37 @@ impl AnthropicProvider {
             base_url,
             auth,
             api_version: Self::DEFAULT_API_VERSION.to_string(),
+            rate_limits: None,
+        }
+    }
+
+    /// Report the rate-limit headers of chat responses to `observer`.
+    #[must_use]
+    pub fn with_rate_limits(mut self, observer: RateLimitObserver) -> Self {
+        self.rate_limits = Some(observer);
+        self
+    }
+
+    fn observe(&self, response: &reqwest::Response) {
+        if let Some(observer) = &self.rate_limits {
+            observer.observe(response.headers());
         }
     }
 
     /// Build a POST request with appropriate auth headers.
     fn build_request(&self, url: &str, body: &Request) -> reqwest::RequestBuilder {
-        let mut builder = self
+        let builder = self
             .client
             .post(url)
-            .header("Content-Type", "application/json")
-            .header("anthropic-version", &self.api_version);
+            .header("Content-Type", "application/json");
+
+        self.with_auth(builder).json(body)
+    }
 
-        builder = builder
+    /// Add the API version and auth headers.
+    fn with_auth(&self, builder: reqwest::RequestBuilder) -> reqwest::RequestBuilder {
+        let mut builder = builder
+            .header("anthropic-version", &self.api_version)
             .header("accept", "application/json")
             .header("anthropic-dangerous-direct-browser-access", "true");
 
@@ -80,7 +101,7 @@ impl AnthropicProvider {
             }
         }
 
-        builder.json(body)
+        builder
     }
 
     fn is_oauth(&self) -> bool {
@@ -95,6 +116,7 @@ impl LLMProvider for AnthropicProvider {
         let anthropic_request = to_request(&request, None, self.is_oauth());
 
         let response = self.build_request(&url, &anthropic_request).send().await?;
+        self.observe(&response);
 
         if let Some(err) = check_response_error(&response) {
             return Err(err);
@@ -114,6 +136,7 @@ impl LLMProvider for AnthropicProvider {
         let anthropic_request = to_request(&request, Some(true), self.is_oauth());
 
         let response = self.build_request(&url, &anthropic_request).send().await?;
+        self.observe(&response);
 
         if let Some(err) = check_response_error(&response) {
             return Err(err);
@@ -130,6 +153,21 @@ impl LLMProvider for AnthropicProvider {
 
         Ok(Box::pin(event_stream))
     }
+
+    async fn health_check(&self) -> Result<(), LLMError> {
+        let url = format!("{}/v1/models", self.base_url);
+        let response = self.with_auth(self.client.get(&url)).send().await?;
+
+        if let Some(err) = check_response_error(&response) {
+            return Err(err);
+        }
+        if !response.status().is_success() {
+            let status = response.status().as_u16();
+            let message = response.text().await.unwrap_or_default();
+            return Err(LLMError::Api { status, message });
+        }
+        Ok(())
+    }
 }
 
 // ============================================================================
@@ -211,6 +249,13 @@ enum ResponseContent {
         name: String,
         input: serde_json::Value,
     },
+    /// Extended thinking, kept apart from the answer.
+    Thinking {
+        thinking: String,
+    },
+    /// Redacted thinking and block types added later.
+    #[serde(other)]
+    Other,
 }
 
 #[derive(serde::Deserialize)]
@@ -480,11 +525,14 @@ fn merge_into(target: &mut RequestMessage, source: RequestMessage, insert_separa
 
 fn from_response(response: Response) -> ChatResponse {
     let mut text_parts = Vec::new();
+    let mut thinking_parts = Vec::new();
     let mut tool_calls = Vec::new();
 
     for block in response.content {
         match block {
             ResponseContent::Text { text } => text_parts.push(text),
+            ResponseContent::Thinking { thinking } => thinking_parts.push(thinking),
+            ResponseContent::Other => {}
             ResponseContent::ToolUse { id, name, input } => {
                 tool_calls.push(ToolCall {
                     id,
@@ -503,6 +551,9 @@ fn from_response(response: Response) -> ChatResponse {
     if !tool_calls.is_empty() {
         message.tool_calls = Some(tool_calls);
     }
+    if !thinking_parts.is_empty() {
+        message.reasoning = Some(thinking_parts.join("\n\n"));
+    }
 
     ChatResponse {
         id: response.id,
@@ -624,6 +675,12 @@ where
                                 {
                                     return Poll::Ready(Some(Ok(StreamEvent::Token(text))));
                                 }
+                                // Handle thinking delta
+                                if let Some(thinking) = delta.thinking
+                                    && !thinking.is_empty()
+                                {
+                                    return Poll::Ready(Some(Ok(StreamEvent::Reasoning(thinking))));
+                                }
                                 // Handle tool input delta
                                 if let Some(partial_json) = delta.partial_json {
                                     let idx = index.unwrap_or_else(|| {
@@ -761,6 +818,8 @@ struct Delta {
     text: Option<String>,
     /// Partial JSON input (for tool_use blocks).
     partial_json: Option<String>,
+    /// Thinking content (for thinking blocks).
+    thinking: Option<String>,
 }
 
 #[derive(serde::Deserialize)]
@@ -885,4 +944,24 @@ mod tests {
         assert_eq!(content.len(), 1);
         assert_eq!(content[0]["text"], "real answer");
     }
+
+    #[test]
+    fn thinking_is_kept_apart_from_answer() {
+        let response: Response = serde_json::from_str(
+            r#"{
+                "id": "msg_1",
+                "content": [
+                    {"type": "thinking", "thinking": "Two plus two.", "signature": "sig"},
+                    {"type": "redacted_thinking", "data": "abc"},
+                    {"type": "text", "text": "4"}
+                ],
+                "stop_reason": "end_turn"
+            }"#,
+        )
+        .unwrap();
+        let response = from_response(response);
+        let message = &response.choices[0].message;
+        assert_eq!(message.content.as_deref(), Some("4"));
+        assert_eq!(message.reasoning.as_deref(), Some("Two plus two."));
+    }
 }
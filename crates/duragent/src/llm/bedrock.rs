@@ -0,0 +1,714 @@
+//! AWS Bedrock LLM provider using the Converse API.
+//!
+//! Requests are signed with AWS Signature Version 4. Converse streams in
+//! AWS's binary event-stream format rather than SSE, so streamed runs get
+//! the whole response as one chunk.
+
+use async_trait::async_trait;
+use chrono::{DateTime, Utc};
+use reqwest::{Client, Method};
+use sha2::{Digest, Sha256};
+use url::Url;
+
+use super::{
+    ChatRequest, ChatResponse, Choice, FunctionCall, LLMError, LLMProvider, Message, Role,
+    ToolCall, ToolDefinition, Usage, check_response_error,
+};
+use crate::signing::{hex, hmac_sha256};
+
+/// Region used when neither `AWS_REGION` nor `AWS_DEFAULT_REGION` is set.
+pub const DEFAULT_REGION: &str = "us-east-1";
+
+/// AWS credentials for signing Bedrock requests.
+#[derive(Clone)]
+pub struct AwsCredentials {
+    pub access_key_id: String,
+    pub secret_access_key: String,
+    /// Present for temporary credentials.
+    pub session_token: Option<String>,
+}
+
+impl AwsCredentials {
+    /// Read `AWS_ACCESS_KEY_ID`, `AWS_SECRET_ACCESS_KEY`, and the optional
+    /// `AWS_SESSION_TOKEN`.
+    pub fn from_env() -> Option<Self> {
+        Some(Self {
+            access_key_id: std::env::var("AWS_ACCESS_KEY_ID").ok()?,
+            secret_access_key: std::env::var("AWS_SECRET_ACCESS_KEY").ok()?,
+            session_token: std::env::var("AWS_SESSION_TOKEN")
+                .ok()
+                .filter(|t| !t.is_empty()),
+        })
+    }
+}
+
+/// Region from `AWS_REGION` or `AWS_DEFAULT_REGION`.
+pub fn region_from_env() -> Option<String> {
+    std::env::var("AWS_REGION")
+        .or_else(|_| std::env::var("AWS_DEFAULT_REGION"))
+        .ok()
+        .filter(|r| !r.is_empty())
+}
+
+/// AWS Bedrock provider. Model names are Bedrock model or inference
+/// profile IDs, e.g. `anthropic.claude-3-5-sonnet-20240620-v1:0`.
+pub struct BedrockProvider {
+    client: Client,
+    base_url: Url,
+    region: String,
+    credentials: AwsCredentials,
+}
+
+impl BedrockProvider {
+    /// Provider for `region`. `base_url` replaces the regional runtime
+    /// endpoint, e.g. for a VPC endpoint.
+    pub fn new(
+        client: Client,
+        credentials: AwsCredentials,
+        region: String,
+        base_url: Option<&str>,
+    ) -> Result<Self, url::ParseError> {
+        let base_url = match base_url {
+            Some(url) => Url::parse(url)?,
+            None => Url::parse(&format!("https://bedrock-runtime.{region}.amazonaws.com"))?,
+        };
+        Ok(Self {
+            client,
+            base_url,
+            region,
+            credentials,
+        })
+    }
+
+    fn converse_url(&self, model: &str) -> Url {
+        let mut url = self.base_url.clone();
+        let base = url.path().trim_end_matches('/').to_string();
+        url.set_path(&format!("{base}/model/{}/converse", uri_encode(model)));
+        url
+    }
+
+    /// A request signed for `service`, with `body` attached.
+    fn signed(
+        &self,
+        method: Method,
+        url: &Url,
+        service: &str,
+        body: Vec<u8>,
+    ) -> reqwest::RequestBuilder {
+        let headers = sign(
+            &self.credentials,
+            &self.region,
+            service,
+            method.as_str(),
+            url,
+            &body,
+            Utc::now(),
+        );
+        let mut builder = self
+            .client
+            .request(method, url.clone())
+            .header("accept", "application/json");
+        for (name, value) in headers {
+            builder = builder.header(name, value);
+        }
+        if body.is_empty() {
+            builder
+        } else {
+            builder
+                .header("content-type", "application/json")
+                .body(body)
+        }
+    }
+}
+
+#[async_trait]
+impl LLMProvider for BedrockProvider {
+    async fn chat(&self, request: ChatRequest) -> Result<ChatResponse, LLMError> {
+        let url = self.converse_url(&request.model);
+        let body =
+            serde_json::to_vec(&to_request(&request)).expect("converse request serializes to JSON");
+
+        let response = self
+            .signed(Method::POST, &url, "bedrock", body)
+            .send()
+            .await?;
+
+        if let Some(err) = check_response_error(&response) {
+            return Err(err);
+        }
+        if !response.status().is_success() {
+            let status = response.status().as_u16();
+            let message = response.text().await.unwrap_or_default();
+            return Err(LLMError::Api { status, message });
+        }
+
+        let id = response
+            .headers()
+            .get("x-amzn-requestid")
+            .and_then(|v| v.to_str().ok())
+            .map(str::to_string)
+            .unwrap_or_default();
+        let converse_response: ConverseResponse = response.json().await?;
+        Ok(from_response(converse_response, id))
+    }
+
+    async fn health_check(&self) -> Result<(), LLMError> {
+        // The model list is on the control plane, not the runtime endpoint.
+        let url = Url::parse(&format!(
+            "https://bedrock.{}.amazonaws.com/foundation-models",
+            self.region
+        ))
+        .map_err(|e| LLMError::Api {
+            status: 0,
+            message: format!("invalid region '{}': {e}", self.region),
+        })?;
+        let response = self
+            .signed(Method::GET, &url, "bedrock", Vec::new())
+            .send()
+            .await?;
+
+        if let Some(err) = check_response_error(&response) {
+            return Err(err);
+        }
+        if !response.status().is_success() {
+            let status = response.status().as_u16();
+            let message = response.text().await.unwrap_or_default();
+            return Err(LLMError::Api { status, message });
+        }
+        Ok(())
+    }
+}
+
+// ============================================================================
+// Signing
+// ============================================================================
+
+/// AWS Signature Version 4 headers for a request: `x-amz-date`,
+/// `x-amz-security-token` for temporary credentials, and `authorization`.
+fn sign(
+    credentials: &AwsCredentials,
+    region: &str,
+    service: &str,
+    method: &str,
+    url: &Url,
+    body: &[u8],
+    now: DateTime<Utc>,
+) -> Vec<(&'static str, String)> {
+    let amz_date = now.format("%Y%m%dT%H%M%SZ").to_string();
+    let host = match url.port() {
+        Some(port) => format!("{}:{port}", url.host_str().unwrap_or_default()),
+        None => url.host_str().unwrap_or_default().to_string(),
+    };
+
+    // Paths are encoded again on top of the URL's own encoding.
+    let path = url
+        .path()
+        .split('/')
+        .map(uri_encode)
+        .collect::<Vec<_>>()
+        .join("/");
+    let mut query: Vec<(String, String)> = url
+        .query_pairs()
+        .map(|(k, v)| (uri_encode(&k), uri_encode(&v)))
+        .collect();
+    query.sort();
+    let query = query
+        .iter()
+        .map(|(k, v)| format!("{k}={v}"))
+        .collect::<Vec<_>>()
+        .join("&");
+
+    let mut canonical_headers = format!("host:{host}\nx-amz-date:{amz_date}\n");
+    let mut signed_headers = "host;x-amz-date".to_string();
+    if let Some(token) = &credentials.session_token {
+        canonical_headers.push_str(&format!("x-amz-security-token:{token}\n"));
+        signed_headers.push_str(";x-amz-security-token");
+    }
+    let canonical_request = format!(
+        "{method}\n{path}\n{query}\n{canonical_headers}\n{signed_headers}\n{:x}",
+        Sha256::digest(body)
+    );
+
+    let date = &amz_date[..8];
+    let scope = format!("{date}/{region}/{service}/aws4_request");
+    let string_to_sign = format!(
+        "AWS4-HMAC-SHA256\n{amz_date}\n{scope}\n{:x}",
+        Sha256::digest(canonical_request.as_bytes())
+    );
+
+    let key = hmac_sha256(
+        format!("AWS4{}", credentials.secret_access_key).as_bytes(),
+        date.as_bytes(),
+    );
+    let key = hmac_sha256(&key, region.as_bytes());
+    let key = hmac_sha256(&key, service.as_bytes());
+    let key = hmac_sha256(&key, b"aws4_request");
+    let signature = hex(&hmac_sha256(&key, string_to_sign.as_bytes()));
+
+    let mut headers = vec![("x-amz-date", amz_date)];
+    if let Some(token) = &credentials.session_token {
+        headers.push(("x-amz-security-token", token.clone()));
+    }
+    headers.push((
+        "authorization",
+        format!(
+            "AWS4-HMAC-SHA256 Credential={}/{scope}, SignedHeaders={signed_headers}, Signature={signature}",
+            credentials.access_key_id
+        ),
+    ));
+    headers
+}
+
+/// Percent-encode everything except unreserved characters.
+fn uri_encode(value: &str) -> String {
+    let mut encoded = String::with_capacity(value.len());
+    for byte in value.bytes() {
+        match byte {
+            b'A'..=b'Z' | b'a'..=b'z' | b'0'..=b'9' | b'-' | b'_' | b'.' | b'~' => {
+                encoded.push(byte as char)
+            }
+            _ => encoded.push_str(&format!("%{byte:02X}")),
+        }
+    }
+    encoded
+}
+
+// ============================================================================
+// Request/Response Types
+// ============================================================================
+
+#[derive(serde::Serialize)]
+#[serde(rename_all = "camelCase")]
+struct ConverseRequest {
+    messages: Vec<ConverseMessage>,
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    system: Vec<SystemBlock>,
+    inference_config: InferenceConfig,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    tool_config: Option<ToolConfig>,
+}
+
+#[derive(serde::Serialize)]
+struct SystemBlock {
+    text: String,
+}
+
+#[derive(serde::Serialize)]
+#[serde(rename_all = "camelCase")]
+struct InferenceConfig {
+    #[serde(skip_serializing_if = "Option::is_none")]
+    max_tokens: Option<u32>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    temperature: Option<f32>,
+}
+
+#[derive(serde::Serialize)]
+struct ToolConfig {
+    tools: Vec<Tool>,
+}
+
+#[derive(serde::Serialize)]
+#[serde(rename_all = "camelCase")]
+struct Tool {
+    tool_spec: ToolSpec,
+}
+
+#[derive(serde::Serialize)]
+#[serde(rename_all = "camelCase")]
+struct ToolSpec {
+    name: String,
+    #[serde(skip_serializing_if = "String::is_empty")]
+    description: String,
+    input_schema: InputSchema,
+}
+
+#[derive(serde::Serialize)]
+struct InputSchema {
+    json: serde_json::Value,
+}
+
+#[derive(serde::Serialize)]
+struct ConverseMessage {
+    role: &'static str,
+    content: Vec<ContentBlock>,
+}
+
+#[derive(serde::Serialize)]
+#[serde(rename_all = "camelCase")]
+enum ContentBlock {
+    Text(String),
+    ToolUse(ToolUse),
+    ToolResult(ToolResult),
+}
+
+#[derive(serde::Serialize)]
+#[serde(rename_all = "camelCase")]
+struct ToolUse {
+    tool_use_id: String,
+    name: String,
+    input: serde_json::Value,
+}
+
+#[derive(serde::Serialize)]
+#[serde(rename_all = "camelCase")]
+struct ToolResult {
+    tool_use_id: String,
+    content: Vec<SystemBlock>,
+}
+
+#[derive(serde::Deserialize)]
+#[serde(rename_all = "camelCase")]
+struct ConverseResponse {
+    output: Output,
+    stop_reason: Option<String>,
+    usage: Option<ResponseUsage>,
+}
+
+#[derive(serde::Deserialize)]
+struct Output {
+    message: Option<OutputMessage>,
+}
+
+#[derive(serde::Deserialize)]
+struct OutputMessage {
+    #[serde(default)]
+    content: Vec<ResponseBlock>,
+}
+
+/// One content block; block types added later have none of these set.
+#[derive(serde::Deserialize)]
+#[serde(rename_all = "camelCase")]
+struct ResponseBlock {
+    text: Option<String>,
+    tool_use: Option<ResponseToolUse>,
+    reasoning_content: Option<ReasoningContent>,
+}
+
+#[derive(serde::Deserialize)]
+#[serde(rename_all = "camelCase")]
+struct ResponseToolUse {
+    tool_use_id: String,
+    name: String,
+    #[serde(default)]
+    input: serde_json::Value,
+}
+
+#[derive(serde::Deserialize)]
+#[serde(rename_all = "camelCase")]
+struct ReasoningContent {
+    reasoning_text: Option<ReasoningText>,
+}
+
+#[derive(serde::Deserialize)]
+struct ReasoningText {
+    text: String,
+}
+
+#[derive(serde::Deserialize)]
+#[serde(rename_all = "camelCase")]
+struct ResponseUsage {
+    input_tokens: u32,
+    output_tokens: u32,
+}
+
+// ============================================================================
+// Conversions
+// ============================================================================
+
+fn convert_tools(tools: Option<&Vec<ToolDefinition>>) -> Option<ToolConfig> {
+    let tools = tools.filter(|ts| !ts.is_empty())?;
+    Some(ToolConfig {
+        tools: tools
+            .iter()
+            .map(|t| Tool {
+                tool_spec: ToolSpec {
+                    name: t.function.name.clone(),
+                    description: t.function.description.clone(),
+                    input_schema: InputSchema {
+                        json: t.function.parameters.clone().unwrap_or_else(
+                            || serde_json::json!({"type": "object", "properties": {}}),
+                        ),
+                    },
+                },
+            })
+            .collect(),
+    })
+}
+
+fn to_request(request: &ChatRequest) -> ConverseRequest {
+    let mut system = Vec::new();
+    let mut messages: Vec<ConverseMessage> = Vec::new();
+
+    for msg in &request.messages {
+        // Converse rejects blank text blocks.
+        let text = msg
+            .content
+            .clone()
+            .filter(|c| !c.trim().is_empty())
+            .map(ContentBlock::Text);
+        let (role, blocks) = match msg.role {
+            Role::System => {
+                if let Some(ContentBlock::Text(text)) = text {
+                    system.push(SystemBlock { text });
+                }
+                continue;
+            }
+            Role::User | Role::Steering => ("user", text.into_iter().collect::<Vec<_>>()),
+            Role::Assistant => {
+                let mut blocks: Vec<ContentBlock> = text.into_iter().collect();
+                for tc in msg.tool_calls.iter().flatten() {
+                    blocks.push(ContentBlock::ToolUse(ToolUse {
+                        tool_use_id: tc.id.clone(),
+                        name: tc.function.name.clone(),
+                        input: parse_arguments(tc),
+                    }));
+                }
+                ("assistant", blocks)
+            }
+            Role::Tool => {
+                let Some(tool_call_id) = &msg.tool_call_id else {
+                    continue;
+                };
+                let output = msg.content.clone().unwrap_or_default();
+                let output = if output.trim().is_empty() {
+                    "(no output)".to_string()
+                } else {
+                    output
+                };
+                (
+                    "user",
+                    vec![ContentBlock::ToolResult(ToolResult {
+                        tool_use_id: tool_call_id.clone(),
+                        content: vec![SystemBlock { text: output }],
+                    })],
+                )
+            }
+        };
+        if blocks.is_empty() {
+            continue;
+        }
+
+        // Converse requires alternating roles, so adjacent messages from
+        // event replay are merged.
+        match messages.last_mut() {
+            Some(last) if last.role == role => last.content.extend(blocks),
+            _ => messages.push(ConverseMessage {
+                role,
+                content: blocks,
+            }),
+        }
+    }
+
+    ConverseRequest {
+        messages,
+        system,
+        inference_config: InferenceConfig {
+            max_tokens: request.max_tokens,
+            temperature: request.temperature,
+        },
+        tool_config: convert_tools(request.tools.as_ref()),
+    }
+}
+
+fn parse_arguments(tc: &ToolCall) -> serde_json::Value {
+    if tc.function.arguments.trim().is_empty() {
+        return serde_json::Value::Object(Default::default());
+    }
+    serde_json::from_str(&tc.function.arguments).unwrap_or_else(|e| {
+        tracing::warn!(
+            tool_call_id = %tc.id,
+            tool_name = %tc.function.name,
+            error = %e,
+            "Failed to parse tool call arguments, using empty object"
+        );
+        serde_json::Value::Object(Default::default())
+    })
+}
+
+fn from_response(response: ConverseResponse, id: String) -> ChatResponse {
+    let mut text_parts = Vec::new();
+    let mut reasoning_parts = Vec::new();
+    let mut tool_calls = Vec::new();
+
+    let blocks = response
+        .output
+        .message
+        .map(|m| m.content)
+        .unwrap_or_default();
+    for block in blocks {
+        if let Some(text) = block.text {
+            text_parts.push(text);
+        }
+        if let Some(reasoning) = block.reasoning_content.and_then(|r| r.reasoning_text) {
+            reasoning_parts.push(reasoning.text);
+        }
+        if let Some(tool_use) = block.tool_use {
+            tool_calls.push(ToolCall {
+                id: tool_use.tool_use_id,
+                tool_type: "function".to_string(),
+                function: FunctionCall {
+                    name: tool_use.name,
+                    arguments: tool_use.input.to_string(),
+                },
+            });
+        }
+    }
+
+    let mut message = Message::text(Role::Assistant, text_parts.join(""));
+    if !tool_calls.is_empty() {
+        message.tool_calls = Some(tool_calls);
+    }
+    if !reasoning_parts.is_empty() {
+        message.reasoning = Some(reasoning_parts.join("\n\n"));
+    }
+
+    ChatResponse {
+        id,
+        choices: vec![Choice {
+            index: 0,
+            message,
+            finish_reason: response.stop_reason,
+        }],
+        usage: response.usage.map(|u| Usage {
+            prompt_tokens: u.input_tokens,
+            completion_tokens: u.output_tokens,
+            total_tokens: u.input_tokens + u.output_tokens,
+        }),
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn credentials() -> AwsCredentials {
+        AwsCredentials {
+            access_key_id: "AKIDEXAMPLE".to_string(),
+            secret_access_key: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY".to_string(),
+            session_token: None,
+        }
+    }
+
+    #[test]
+    fn sign_matches_aws_test_suite() {
+        // AWS Signature Version 4 test suite, "get-vanilla"
+        let url = Url::parse("https://example.amazonaws.com/").unwrap();
+        let now = "2015-08-30T12:36:00Z".parse().unwrap();
+        let headers = sign(
+            &credentials(),
+            "us-east-1",
+            "service",
+            "GET",
+            &url,
+            b"",
+            now,
+        );
+        assert_eq!(headers[0], ("x-amz-date", "20150830T123600Z".to_string()));
+        assert_eq!(
+            headers[1].1,
+            "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, \
+             SignedHeaders=host;x-amz-date, \
+             Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+        );
+    }
+
+    #[test]
+    fn session_token_is_signed() {
+        let mut credentials = credentials();
+        credentials.session_token = Some("token".to_string());
+        let url = Url::parse("https://example.amazonaws.com/").unwrap();
+        let headers = sign(
+            &credentials,
+            "us-east-1",
+            "bedrock",
+            "GET",
+            &url,
+            b"",
+            Utc::now(),
+        );
+        assert_eq!(headers[1], ("x-amz-security-token", "token".to_string()));
+        assert!(
+            headers[2]
+                .1
+                .contains("SignedHeaders=host;x-amz-date;x-amz-security-token")
+        );
+    }
+
+    #[test]
+    fn converse_url_encodes_model_id() {
+        let provider =
+            BedrockProvider::new(Client::new(), credentials(), "eu-west-1".to_string(), None)
+                .unwrap();
+        assert_eq!(
+            provider
+                .converse_url("anthropic.claude-3-5-sonnet-20240620-v1:0")
+                .as_str(),
+            "https://bedrock-runtime.eu-west-1.amazonaws.com/model/anthropic.claude-3-5-sonnet-20240620-v1%3A0/converse"
+        );
+    }
+
+    #[test]
+    fn converts_tool_round_trip() {
+        let call = ToolCall {
+            id: "call_1".to_string(),
+            tool_type: "function".to_string(),
+            function: FunctionCall {
+                name: "weather".to_string(),
+                arguments: r#"{"city":"Oslo"}"#.to_string(),
+            },
+        };
+        let request = ChatRequest::new(
+            "anthropic.claude-3-haiku-20240307-v1:0",
+            vec![
+                Message::text(Role::System, "Be brief."),
+                Message::text(Role::User, "Weather?"),
+                Message::assistant_tool_calls(vec![call]),
+                Message::tool_result("call_1", "Sunny"),
+                Message::steering("In Celsius."),
+            ],
+            None,
+            Some(256),
+        );
+        let json = serde_json::to_value(to_request(&request)).unwrap();
+
+        assert_eq!(json["system"][0]["text"], "Be brief.");
+        assert_eq!(json["inferenceConfig"]["maxTokens"], 256);
+        let messages = json["messages"].as_array().unwrap();
+        assert_eq!(messages.len(), 3);
+        assert_eq!(messages[1]["role"], "assistant");
+        assert_eq!(
+            messages[1]["content"][0]["toolUse"]["input"]["city"],
+            "Oslo"
+        );
+        // The tool result and the steering message share one user turn.
+        assert_eq!(messages[2]["role"], "user");
+        assert_eq!(
+            messages[2]["content"][0]["toolResult"]["toolUseId"],
+            "call_1"
+        );
+        assert_eq!(messages[2]["content"][1]["text"], "In Celsius.");
+    }
+
+    #[test]
+    fn parses_converse_response() {
+        let response: ConverseResponse = serde_json::from_value(serde_json::json!({
+            "output": {"message": {"role": "assistant", "content": [
+                {"reasoningContent": {"reasoningText": {"text": "Check the tool.", "signature": "x"}}},
+                {"text": "Let me look."},
+                {"toolUse": {"toolUseId": "t1", "name": "weather", "input": {"city": "Oslo"}}}
+            ]}},
+            "stopReason": "tool_use",
+            "usage": {"inputTokens": 10, "outputTokens": 5, "totalTokens": 15}
+        }))
+        .unwrap();
+        let response = from_response(response, "req-1".to_string());
+
+        let message = &response.choices[0].message;
+        assert_eq!(message.content.as_deref(), Some("Let me look."));
+        assert_eq!(message.reasoning.as_deref(), Some("Check the tool."));
+        let calls = message.tool_calls.as_ref().unwrap();
+        assert_eq!(calls[0].id, "t1");
+        assert_eq!(calls[0].function.arguments, r#"{"city":"Oslo"}"#);
+        assert_eq!(response.usage.unwrap().total_tokens, 15);
+    }
+}
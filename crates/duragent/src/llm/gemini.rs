@@ -0,0 +1,865 @@
+//! Google Gemini LLM provider with native API format.
+//!
+//! Images, audio, video, and PDFs can be sent inline as base64 data URIs
+//! (`data:image/png;base64,...`) in user messages; they're passed to the
+//! model as media parts. Responses Gemini blocks for safety fail with a
+//! `content_filter` error, so they're classified like other providers'
+//! content-filter refusals.
+
+use std::collections::{HashMap, VecDeque};
+use std::pin::Pin;
+use std::task::{Context, Poll};
+
+use async_trait::async_trait;
+use futures::Stream;
+use reqwest::Client;
+
+use super::{
+    ChatRequest, ChatResponse, ChatStream, Choice, FunctionCall, LLMError, LLMProvider, Message,
+    Role, StreamEvent, ToolCall, ToolDefinition, Usage, check_response_error,
+};
+use crate::sse_parser::SseEventStream;
+
+/// Harm categories that safety thresholds apply to.
+const HARM_CATEGORIES: &[&str] = &[
+    "HARM_CATEGORY_HARASSMENT",
+    "HARM_CATEGORY_HATE_SPEECH",
+    "HARM_CATEGORY_SEXUALLY_EXPLICIT",
+    "HARM_CATEGORY_DANGEROUS_CONTENT",
+];
+
+/// Safety thresholds Gemini accepts.
+pub const SAFETY_THRESHOLDS: &[&str] = &[
+    "BLOCK_NONE",
+    "BLOCK_ONLY_HIGH",
+    "BLOCK_MEDIUM_AND_ABOVE",
+    "BLOCK_LOW_AND_ABOVE",
+    "OFF",
+];
+
+/// Finish and block reasons that mean Gemini's safety filters intervened.
+const SAFETY_REASONS: &[&str] = &[
+    "SAFETY",
+    "BLOCKLIST",
+    "PROHIBITED_CONTENT",
+    "SPII",
+    "IMAGE_SAFETY",
+];
+
+/// Media types sent to the model as inline data.
+const INLINE_MEDIA_PREFIXES: &[&str] = &["image/", "audio/", "video/", "application/pdf"];
+
+/// Gemini provider with native API format.
+pub struct GeminiProvider {
+    client: Client,
+    base_url: String,
+    api_key: String,
+    /// Threshold for every harm category; Gemini's defaults when `None`.
+    safety_threshold: Option<String>,
+}
+
+impl GeminiProvider {
+    #[must_use]
+    pub fn new(client: Client, base_url: String, api_key: String) -> Self {
+        Self {
+            client,
+            base_url: base_url.trim_end_matches('/').to_string(),
+            api_key,
+            safety_threshold: None,
+        }
+    }
+
+    /// Apply `threshold`, one of [`SAFETY_THRESHOLDS`], to every harm
+    /// category.
+    #[must_use]
+    pub fn with_safety_threshold(mut self, threshold: String) -> Self {
+        self.safety_threshold = Some(threshold);
+        self
+    }
+
+    /// URL of a method on `model`, e.g. `generateContent`.
+    fn url(&self, model: &str, method: &str) -> String {
+        let model = model.strip_prefix("models/").unwrap_or(model);
+        format!("{}/models/{model}:{method}", self.base_url)
+    }
+
+    fn post(&self, url: &str) -> reqwest::RequestBuilder {
+        self.client
+            .post(url)
+            .header("Content-Type", "application/json")
+            .header("x-goog-api-key", &self.api_key)
+    }
+}
+
+#[async_trait]
+impl LLMProvider for GeminiProvider {
+    async fn chat(&self, request: ChatRequest) -> Result<ChatResponse, LLMError> {
+        let url = self.url(&request.model, "generateContent");
+        let body = to_request(&request, self.safety_threshold.as_deref());
+
+        let response = self.post(&url).json(&body).send().await?;
+
+        if let Some(err) = check_response_error(&response) {
+            return Err(err);
+        }
+        if !response.status().is_success() {
+            let status = response.status().as_u16();
+            let message = response.text().await.unwrap_or_default();
+            return Err(LLMError::Api { status, message });
+        }
+
+        let gemini_response: Response = response.json().await?;
+        from_response(gemini_response)
+    }
+
+    async fn chat_stream(&self, request: ChatRequest) -> Result<ChatStream, LLMError> {
+        let url = format!(
+            "{}?alt=sse",
+            self.url(&request.model, "streamGenerateContent")
+        );
+        let body = to_request(&request, self.safety_threshold.as_deref());
+
+        let response = self.post(&url).json(&body).send().await?;
+
+        if let Some(err) = check_response_error(&response) {
+            return Err(err);
+        }
+        if !response.status().is_success() {
+            let status = response.status().as_u16();
+            let message = response.text().await.unwrap_or_default();
+            return Err(LLMError::Api { status, message });
+        }
+
+        let byte_stream = response.bytes_stream();
+        let sse_stream = SseEventStream::new(byte_stream);
+        let event_stream = GeminiStreamAdapter::new(sse_stream);
+
+        Ok(Box::pin(event_stream))
+    }
+
+    async fn health_check(&self) -> Result<(), LLMError> {
+        let url = format!("{}/models", self.base_url);
+        let response = self
+            .client
+            .get(&url)
+            .header("x-goog-api-key", &self.api_key)
+            .send()
+            .await?;
+
+        if let Some(err) = check_response_error(&response) {
+            return Err(err);
+        }
+        if !response.status().is_success() {
+            let status = response.status().as_u16();
+            let message = response.text().await.unwrap_or_default();
+            return Err(LLMError::Api { status, message });
+        }
+        Ok(())
+    }
+
+    async fn embed(&self, model: &str, inputs: Vec<String>) -> Result<Vec<Vec<f32>>, LLMError> {
+        let url = self.url(model, "batchEmbedContents");
+        let model_name = format!("models/{}", model.strip_prefix("models/").unwrap_or(model));
+        let body = EmbedRequest {
+            requests: inputs
+                .into_iter()
+                .map(|text| EmbedContentRequest {
+                    model: model_name.clone(),
+                    content: Content {
+                        role: None,
+                        parts: vec![Part::text(text)],
+                    },
+                })
+                .collect(),
+        };
+
+        let response = self.post(&url).json(&body).send().await?;
+
+        if let Some(err) = check_response_error(&response) {
+            return Err(err);
+        }
+        if !response.status().is_success() {
+            let status = response.status().as_u16();
+            let message = response.text().await.unwrap_or_default();
+            return Err(LLMError::Api { status, message });
+        }
+
+        let body: EmbedResponse = response.json().await?;
+        Ok(body.embeddings.into_iter().map(|e| e.values).collect())
+    }
+}
+
+// ============================================================================
+// Request/Response Types
+// ============================================================================
+
+#[derive(serde::Serialize)]
+#[serde(rename_all = "camelCase")]
+struct Request {
+    contents: Vec<Content>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    system_instruction: Option<Content>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    tools: Option<Vec<Tools>>,
+    generation_config: GenerationConfig,
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    safety_settings: Vec<SafetySetting>,
+}
+
+#[derive(serde::Serialize, serde::Deserialize)]
+struct Content {
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    role: Option<String>,
+    #[serde(default)]
+    parts: Vec<Part>,
+}
+
+/// One part of a message; exactly one field is set.
+#[derive(Default, serde::Serialize, serde::Deserialize)]
+#[serde(rename_all = "camelCase")]
+struct Part {
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    text: Option<String>,
+    /// Set on text parts that are the model's reasoning.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    thought: Option<bool>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    inline_data: Option<InlineData>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    function_call: Option<GeminiFunctionCall>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    function_response: Option<FunctionResponse>,
+}
+
+impl Part {
+    fn text(text: impl Into<String>) -> Self {
+        Self {
+            text: Some(text.into()),
+            ..Default::default()
+        }
+    }
+}
+
+#[derive(serde::Serialize, serde::Deserialize)]
+#[serde(rename_all = "camelCase")]
+struct InlineData {
+    mime_type: String,
+    data: String,
+}
+
+#[derive(serde::Serialize, serde::Deserialize)]
+struct GeminiFunctionCall {
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    id: Option<String>,
+    name: String,
+    #[serde(default)]
+    args: serde_json::Value,
+}
+
+#[derive(serde::Serialize, serde::Deserialize)]
+struct FunctionResponse {
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    id: Option<String>,
+    name: String,
+    response: serde_json::Value,
+}
+
+#[derive(serde::Serialize)]
+#[serde(rename_all = "camelCase")]
+struct Tools {
+    function_declarations: Vec<FunctionDeclaration>,
+}
+
+#[derive(serde::Serialize)]
+#[serde(rename_all = "camelCase")]
+struct FunctionDeclaration {
+    name: String,
+    description: String,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    parameters_json_schema: Option<serde_json::Value>,
+}
+
+#[derive(serde::Serialize)]
+#[serde(rename_all = "camelCase")]
+struct GenerationConfig {
+    #[serde(skip_serializing_if = "Option::is_none")]
+    temperature: Option<f32>,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    max_output_tokens: Option<u32>,
+}
+
+#[derive(serde::Serialize)]
+struct SafetySetting {
+    category: &'static str,
+    threshold: String,
+}
+
+#[derive(serde::Deserialize)]
+#[serde(rename_all = "camelCase")]
+struct Response {
+    #[serde(default)]
+    candidates: Vec<Candidate>,
+    #[serde(default)]
+    prompt_feedback: Option<PromptFeedback>,
+    #[serde(default)]
+    usage_metadata: Option<UsageMetadata>,
+    #[serde(default)]
+    response_id: Option<String>,
+}
+
+#[derive(serde::Deserialize)]
+#[serde(rename_all = "camelCase")]
+struct Candidate {
+    #[serde(default)]
+    content: Option<Content>,
+    #[serde(default)]
+    finish_reason: Option<String>,
+}
+
+#[derive(serde::Deserialize)]
+#[serde(rename_all = "camelCase")]
+struct PromptFeedback {
+    #[serde(default)]
+    block_reason: Option<String>,
+}
+
+#[derive(serde::Deserialize)]
+#[serde(rename_all = "camelCase")]
+struct UsageMetadata {
+    #[serde(default)]
+    prompt_token_count: u32,
+    #[serde(default)]
+    candidates_token_count: u32,
+    #[serde(default)]
+    thoughts_token_count: u32,
+}
+
+impl From<UsageMetadata> for Usage {
+    fn from(u: UsageMetadata) -> Self {
+        let completion_tokens = u.candidates_token_count + u.thoughts_token_count;
+        Usage {
+            prompt_tokens: u.prompt_token_count,
+            completion_tokens,
+            total_tokens: u.prompt_token_count + completion_tokens,
+        }
+    }
+}
+
+#[derive(serde::Serialize)]
+struct EmbedRequest {
+    requests: Vec<EmbedContentRequest>,
+}
+
+#[derive(serde::Serialize)]
+struct EmbedContentRequest {
+    model: String,
+    content: Content,
+}
+
+#[derive(serde::Deserialize)]
+struct EmbedResponse {
+    embeddings: Vec<Embedding>,
+}
+
+#[derive(serde::Deserialize)]
+struct Embedding {
+    values: Vec<f32>,
+}
+
+// ============================================================================
+// Conversions
+// ============================================================================
+
+fn to_request(request: &ChatRequest, safety_threshold: Option<&str>) -> Request {
+    let mut system_parts = Vec::new();
+    let mut contents: Vec<Content> = Vec::new();
+    // Function responses name the function, but tool results only carry the
+    // call ID.
+    let mut call_names: HashMap<&str, &str> = HashMap::new();
+
+    for msg in &request.messages {
+        let text = msg.content.as_deref().filter(|c| !c.is_empty());
+        let (role, parts) = match msg.role {
+            Role::System => {
+                if let Some(text) = text {
+                    system_parts.push(Part::text(text));
+                }
+                continue;
+            }
+            Role::User | Role::Steering => ("user", text.map(user_parts).unwrap_or_default()),
+            Role::Assistant => {
+                let mut parts: Vec<Part> = text.map(Part::text).into_iter().collect();
+                for tc in msg.tool_calls.iter().flatten() {
+                    call_names.insert(&tc.id, &tc.function.name);
+                    parts.push(Part {
+                        function_call: Some(GeminiFunctionCall {
+                            id: None,
+                            name: tc.function.name.clone(),
+                            args: parse_arguments(tc),
+                        }),
+                        ..Default::default()
+                    });
+                }
+                ("model", parts)
+            }
+            Role::Tool => {
+                let Some(tool_call_id) = msg.tool_call_id.as_deref() else {
+                    continue;
+                };
+                let Some(name) = call_names.get(tool_call_id) else {
+                    tracing::warn!(
+                        tool_call_id,
+                        "Dropped tool result without a matching tool call"
+                    );
+                    continue;
+                };
+                let output = msg.content.clone().unwrap_or_default();
+                (
+                    "user",
+                    vec![Part {
+                        function_response: Some(FunctionResponse {
+                            id: None,
+                            name: (*name).to_string(),
+                            response: serde_json::json!({ "content": output }),
+                        }),
+                        ..Default::default()
+                    }],
+                )
+            }
+        };
+        if parts.is_empty() {
+            continue;
+        }
+
+        // Parallel function responses must share one turn, so adjacent
+        // messages with the same role are merged.
+        match contents.last_mut() {
+            Some(last) if last.role.as_deref() == Some(role) => last.parts.extend(parts),
+            _ => contents.push(Content {
+                role: Some(role.to_string()),
+                parts,
+            }),
+        }
+    }
+
+    let tools = request.tools.as_ref().filter(|t| !t.is_empty()).map(|t| {
+        vec![Tools {
+            function_declarations: t.iter().map(convert_tool).collect(),
+        }]
+    });
+
+    Request {
+        contents,
+        system_instruction: (!system_parts.is_empty()).then_some(Content {
+            role: None,
+            parts: system_parts,
+        }),
+        tools,
+        generation_config: GenerationConfig {
+            temperature: request.temperature,
+            max_output_tokens: request.max_tokens,
+        },
+        safety_settings: safety_threshold
+            .map(|threshold| {
+                HARM_CATEGORIES
+                    .iter()
+                    .map(|category| SafetySetting {
+                        category,
+                        threshold: threshold.to_string(),
+                    })
+                    .collect()
+            })
+            .unwrap_or_default(),
+    }
+}
+
+fn convert_tool(tool: &ToolDefinition) -> FunctionDeclaration {
+    FunctionDeclaration {
+        name: tool.function.name.clone(),
+        description: tool.function.description.clone(),
+        parameters_json_schema: tool.function.parameters.clone(),
+    }
+}
+
+fn parse_arguments(tc: &ToolCall) -> serde_json::Value {
+    if tc.function.arguments.trim().is_empty() {
+        return serde_json::Value::Object(Default::default());
+    }
+    serde_json::from_str(&tc.function.arguments).unwrap_or_else(|e| {
+        tracing::warn!(
+            tool_call_id = %tc.id,
+            tool_name = %tc.function.name,
+            error = %e,
+            "Failed to parse tool call arguments, using empty object"
+        );
+        serde_json::Value::Object(Default::default())
+    })
+}
+
+/// Split user text into text parts and inline media from base64 data URIs.
+fn user_parts(text: &str) -> Vec<Part> {
+    let mut parts = Vec::new();
+    let mut rest = text;
+    while let Some(start) = rest.find("data:") {
+        let Some((mime_type, data, len)) = parse_data_uri(&rest[start..]) else {
+            // Not inline media: keep it as text and look further on.
+            let end = start + "data:".len();
+            push_text(&mut parts, &rest[..end]);
+            rest = &rest[end..];
+            continue;
+        };
+        push_text(&mut parts, &rest[..start]);
+        parts.push(Part {
+            inline_data: Some(InlineData {
+                mime_type: mime_type.to_string(),
+                data: data.to_string(),
+            }),
+            ..Default::default()
+        });
+        rest = &rest[start + len..];
+    }
+    push_text(&mut parts, rest);
+    parts
+}
+
+/// Append `text`, extending the previous text part if there is one.
+fn push_text(parts: &mut Vec<Part>, text: &str) {
+    if text.is_empty() {
+        return;
+    }
+    match parts.last_mut() {
+        Some(Part {
+            text: Some(last), ..
+        }) => last.push_str(text),
+        _ => parts.push(Part::text(text)),
+    }
+}
+
+/// Parse a `data:{mime};base64,{data}` URI at the start of `s` with an
+/// inline media type. Returns the type, the data, and the URI's length.
+fn parse_data_uri(s: &str) -> Option<(&str, &str, usize)> {
+    let after_scheme = s.strip_prefix("data:")?;
+    let mime_len = after_scheme
+        .find(|c: char| !(c.is_ascii_alphanumeric() || matches!(c, '/' | '+' | '.' | '-')))
+        .unwrap_or(after_scheme.len());
+    let mime_type = &after_scheme[..mime_len];
+    if !INLINE_MEDIA_PREFIXES
+        .iter()
+        .any(|prefix| mime_type.starts_with(prefix))
+    {
+        return None;
+    }
+    let encoded = after_scheme[mime_len..].strip_prefix(";base64,")?;
+    let data_len = encoded
+        .find(|c: char| !(c.is_ascii_alphanumeric() || matches!(c, '+' | '/' | '=')))
+        .unwrap_or(encoded.len());
+    if data_len == 0 {
+        return None;
+    }
+    let len = "data:".len() + mime_len + ";base64,".len() + data_len;
+    Some((mime_type, &encoded[..data_len], len))
+}
+
+/// Error for a prompt or response Gemini's safety filters blocked.
+fn safety_error(reason: &str) -> LLMError {
+    LLMError::Api {
+        status: 400,
+        message: format!("content_filter: blocked by Gemini safety filters ({reason})"),
+    }
+}
+
+fn is_safety_reason(reason: &str) -> bool {
+    SAFETY_REASONS.contains(&reason)
+}
+
+/// A new ID for a function call Gemini didn't give one.
+fn call_id(id: Option<String>) -> String {
+    id.unwrap_or_else(|| format!("call_{}", ulid::Ulid::new()))
+}
+
+fn from_response(response: Response) -> Result<ChatResponse, LLMError> {
+    if let Some(reason) = response.prompt_feedback.and_then(|f| f.block_reason) {
+        return Err(safety_error(&reason));
+    }
+    let candidate = response.candidates.into_iter().next();
+    let finish_reason = candidate.as_ref().and_then(|c| c.finish_reason.clone());
+    if let Some(reason) = finish_reason.as_deref()
+        && is_safety_reason(reason)
+    {
+        return Err(safety_error(reason));
+    }
+
+    let mut text_parts = Vec::new();
+    let mut thought_parts = Vec::new();
+    let mut tool_calls = Vec::new();
+    let parts = candidate
+        .and_then(|c| c.content)
+        .map(|c| c.parts)
+        .unwrap_or_default();
+    for part in parts {
+        if let Some(call) = part.function_call {
+            tool_calls.push(ToolCall {
+                id: call_id(call.id),
+                tool_type: "function".to_string(),
+                function: FunctionCall {
+                    name: call.name,
+                    arguments: call.args.to_string(),
+                },
+            });
+        } else if let Some(text) = part.text {
+            if part.thought == Some(true) {
+                thought_parts.push(text);
+            } else {
+                text_parts.push(text);
+            }
+        }
+    }
+
+    let mut message = Message::text(Role::Assistant, text_parts.join(""));
+    if !tool_calls.is_empty() {
+        message.tool_calls = Some(tool_calls);
+    }
+    if !thought_parts.is_empty() {
+        message.reasoning = Some(thought_parts.join("\n\n"));
+    }
+
+    Ok(ChatResponse {
+        id: response.response_id.unwrap_or_default(),
+        choices: vec![Choice {
+            index: 0,
+            message,
+            finish_reason,
+        }],
+        usage: response.usage_metadata.map(Usage::from),
+    })
+}
+
+// ============================================================================
+// Streaming
+// ============================================================================
+
+/// Adapter that converts Gemini SSE chunks into StreamEvents.
+///
+/// Each chunk is a partial response. Function calls arrive whole and are
+/// emitted together before `Done`.
+struct GeminiStreamAdapter<S> {
+    inner: SseEventStream<S>,
+    done: bool,
+    /// Events from the last chunk not yet returned.
+    pending: VecDeque<StreamEvent>,
+    tool_calls: Vec<ToolCall>,
+    usage: Option<Usage>,
+}
+
+impl<S> GeminiStreamAdapter<S> {
+    fn new(inner: SseEventStream<S>) -> Self {
+        Self {
+            inner,
+            done: false,
+            pending: VecDeque::new(),
+            tool_calls: Vec::new(),
+            usage: None,
+        }
+    }
+
+    /// Queue the events in one chunk.
+    fn push_chunk(&mut self, chunk: Response) -> Result<(), LLMError> {
+        if let Some(reason) = chunk.prompt_feedback.and_then(|f| f.block_reason) {
+            return Err(safety_error(&reason));
+        }
+        if let Some(usage) = chunk.usage_metadata {
+            self.usage = Some(usage.into());
+        }
+        let Some(candidate) = chunk.candidates.into_iter().next() else {
+            return Ok(());
+        };
+        for part in candidate.content.map(|c| c.parts).unwrap_or_default() {
+            if let Some(call) = part.function_call {
+                self.tool_calls.push(ToolCall {
+                    id: call_id(call.id),
+                    tool_type: "function".to_string(),
+                    function: FunctionCall {
+                        name: call.name,
+                        arguments: call.args.to_string(),
+                    },
+                });
+            } else if let Some(text) = part.text.filter(|t| !t.is_empty()) {
+                self.pending.push_back(if part.thought == Some(true) {
+                    StreamEvent::Reasoning(text)
+                } else {
+                    StreamEvent::Token(text)
+                });
+            }
+        }
+        if let Some(reason) = candidate.finish_reason.as_deref()
+            && is_safety_reason(reason)
+        {
+            return Err(safety_error(reason));
+        }
+        Ok(())
+    }
+
+    /// Queue the pending tool calls and `Done`.
+    fn finish(&mut self) {
+        self.done = true;
+        if !self.tool_calls.is_empty() {
+            let tool_calls = std::mem::take(&mut self.tool_calls);
+            self.pending.push_back(StreamEvent::ToolCalls(tool_calls));
+        }
+        self.pending.push_back(StreamEvent::Done {
+            usage: self.usage.take(),
+        });
+    }
+}
+
+impl<S> Stream for GeminiStreamAdapter<S>
+where
+    S: Stream<Item = Result<bytes::Bytes, reqwest::Error>> + Unpin,
+{
+    type Item = Result<StreamEvent, LLMError>;
+
+    fn poll_next(mut self: Pin<&mut Self>, cx: &mut Context<'_>) -> Poll<Option<Self::Item>> {
+        loop {
+            if let Some(event) = self.pending.pop_front() {
+                return Poll::Ready(Some(Ok(event)));
+            }
+            if self.done {
+                return Poll::Ready(None);
+            }
+
+            match Pin::new(&mut self.inner).poll_next(cx) {
+                Poll::Ready(Some(Ok(event))) => {
+                    if event.data.is_empty() {
+                        continue;
+                    }
+                    match serde_json::from_str::<Response>(&event.data) {
+                        Ok(chunk) => {
+                            if let Err(e) = self.push_chunk(chunk) {
+                                self.done = true;
+                                self.pending.clear();
+                                return Poll::Ready(Some(Err(e)));
+                            }
+                        }
+                        Err(e) => {
+                            tracing::debug!(data = %event.data, error = %e, "failed to parse SSE chunk");
+                        }
+                    }
+                }
+                Poll::Ready(Some(Err(e))) => {
+                    return Poll::Ready(Some(Err(LLMError::Request(e))));
+                }
+                Poll::Ready(None) => self.finish(),
+                Poll::Pending => return Poll::Pending,
+            }
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn converts_tool_round_trip() {
+        let call = ToolCall {
+            id: "call_1".to_string(),
+            tool_type: "function".to_string(),
+            function: FunctionCall {
+                name: "weather".to_string(),
+                arguments: r#"{"city":"Oslo"}"#.to_string(),
+            },
+        };
+        let request = ChatRequest::new(
+            "gemini-2.5-flash",
+            vec![
+                Message::text(Role::System, "Be brief."),
+                Message::text(Role::User, "Weather?"),
+                Message::assistant_tool_calls(vec![call]),
+                Message::tool_result("call_1", "Sunny"),
+            ],
+            Some(0.2),
+            Some(256),
+        );
+        let json = serde_json::to_value(to_request(&request, Some("BLOCK_ONLY_HIGH"))).unwrap();
+
+        assert_eq!(json["systemInstruction"]["parts"][0]["text"], "Be brief.");
+        assert_eq!(json["generationConfig"]["maxOutputTokens"], 256);
+        assert_eq!(json["safetySettings"].as_array().unwrap().len(), 4);
+        assert_eq!(json["safetySettings"][0]["threshold"], "BLOCK_ONLY_HIGH");
+        let contents = json["contents"].as_array().unwrap();
+        assert_eq!(contents.len(), 3);
+        assert_eq!(contents[1]["role"], "model");
+        assert_eq!(
+            contents[1]["parts"][0]["functionCall"]["args"]["city"],
+            "Oslo"
+        );
+        let response = &contents[2]["parts"][0]["functionResponse"];
+        assert_eq!(response["name"], "weather");
+        assert_eq!(response["response"]["content"], "Sunny");
+    }
+
+    #[test]
+    fn data_uris_become_inline_media() {
+        let parts = user_parts("What is this? data:image/png;base64,iVBORw0KGgo= Thanks");
+        assert_eq!(parts.len(), 3);
+        assert_eq!(parts[0].text.as_deref(), Some("What is this? "));
+        let media = parts[1].inline_data.as_ref().unwrap();
+        assert_eq!(media.mime_type, "image/png");
+        assert_eq!(media.data, "iVBORw0KGgo=");
+        assert_eq!(parts[2].text.as_deref(), Some(" Thanks"));
+
+        // Other data URIs stay text.
+        let parts = user_parts("See data:text/plain;base64,aGk= here");
+        assert_eq!(parts.len(), 1);
+        assert_eq!(
+            parts[0].text.as_deref(),
+            Some("See data:text/plain;base64,aGk= here")
+        );
+    }
+
+    #[test]
+    fn parses_response_with_thoughts_and_calls() {
+        let response: Response = serde_json::from_value(serde_json::json!({
+            "candidates": [{
+                "content": {"role": "model", "parts": [
+                    {"text": "Check the tool.", "thought": true},
+                    {"text": "Let me look."},
+                    {"functionCall": {"name": "weather", "args": {"city": "Oslo"}}}
+                ]},
+                "finishReason": "STOP"
+            }],
+            "usageMetadata": {"promptTokenCount": 10, "candidatesTokenCount": 5, "totalTokenCount": 15},
+            "responseId": "r1"
+        }))
+        .unwrap();
+        let response = from_response(response).unwrap();
+
+        assert_eq!(response.id, "r1");
+        let message = &response.choices[0].message;
+        assert_eq!(message.content.as_deref(), Some("Let me look."));
+        assert_eq!(message.reasoning.as_deref(), Some("Check the tool."));
+        let calls = message.tool_calls.as_ref().unwrap();
+        assert!(calls[0].id.starts_with("call_"));
+        assert_eq!(calls[0].function.arguments, r#"{"city":"Oslo"}"#);
+        assert_eq!(response.usage.unwrap().total_tokens, 15);
+    }
+
+    #[test]
+    fn safety_blocks_are_content_filtered() {
+        let response: Response = serde_json::from_value(serde_json::json!({
+            "promptFeedback": {"blockReason": "SAFETY"}
+        }))
+        .unwrap();
+        let err = from_response(response).unwrap_err();
+        assert_eq!(
+            err.class(),
+            Some(crate::llm::ProviderErrorClass::ContentFiltered)
+        );
+
+        let response: Response = serde_json::from_value(serde_json::json!({
+            "candidates": [{"finishReason": "PROHIBITED_CONTENT"}]
+        }))
+        .unwrap();
+        assert!(from_response(response).is_err());
+    }
+}
@@ -0,0 +1,146 @@
+//! Outbound HTTP client construction for LLM providers.
+//!
+//! Applies `outbound` config (timeouts, connection pool, proxy, custom CA
+//! bundle) to provider clients. Without a configured proxy, reqwest honors the
+//! standard `HTTP(S)_PROXY` / `NO_PROXY` environment variables.
+
+use std::path::{Path, PathBuf};
+use std::time::Duration;
+
+use reqwest::{Certificate, Client, ClientBuilder, NoProxy, Proxy};
+use thiserror::Error;
+
+use crate::config::OutboundConfig;
+
+#[derive(Debug, Error)]
+pub enum HttpClientError {
+    #[error("failed to read CA bundle {}: {source}", path.display())]
+    ReadCaBundle {
+        path: PathBuf,
+        source: std::io::Error,
+    },
+
+    #[error("invalid CA bundle {}: {source}", path.display())]
+    InvalidCaBundle {
+        path: PathBuf,
+        source: reqwest::Error,
+    },
+
+    #[error("invalid proxy URL '{url}': {source}")]
+    InvalidProxy { url: String, source: reqwest::Error },
+
+    #[error("failed to build HTTP client: {0}")]
+    Build(#[from] reqwest::Error),
+}
+
+/// Build a provider HTTP client.
+///
+/// `proxy_override` replaces `outbound.proxy` (used for per-provider
+/// overrides); an empty override disables proxying entirely.
+pub fn build_client(
+    outbound: &OutboundConfig,
+    proxy_override: Option<&str>,
+) -> Result<Client, HttpClientError> {
+    Ok(client_builder(outbound, proxy_override)?.build()?)
+}
+
+/// Like [`build_client`], but returns the builder so callers can add their
+/// own settings (e.g. a redirect policy) before building.
+pub fn client_builder(
+    outbound: &OutboundConfig,
+    proxy_override: Option<&str>,
+) -> Result<ClientBuilder, HttpClientError> {
+    let mut builder = Client::builder()
+        .connect_timeout(Duration::from_secs(outbound.connect_timeout_seconds))
+        .timeout(Duration::from_secs(outbound.request_timeout_seconds))
+        .pool_idle_timeout(Duration::from_secs(outbound.pool_idle_timeout_seconds))
+        .tcp_keepalive(outbound.tcp_keepalive_seconds.map(Duration::from_secs));
+    if let Some(max_idle) = outbound.pool_max_idle_per_host {
+        builder = builder.pool_max_idle_per_host(max_idle);
+    }
+
+    match proxy_override.or(outbound.proxy.as_deref()) {
+        Some("") => builder = builder.no_proxy(),
+        Some(url) => {
+            let proxy = Proxy::all(url).map_err(|source| HttpClientError::InvalidProxy {
+                url: url.to_string(),
+                source,
+            })?;
+            let no_proxy = outbound.no_proxy.as_deref().and_then(NoProxy::from_string);
+            builder = builder.proxy(proxy.no_proxy(no_proxy));
+        }
+        // Fall back to HTTP(S)_PROXY / NO_PROXY from the environment
+        None => {}
+    }
+
+    if let Some(path) = &outbound.ca_bundle {
+        for cert in load_ca_bundle(path)? {
+            builder = builder.add_root_certificate(cert);
+        }
+    }
+
+    Ok(builder)
+}
+
+fn load_ca_bundle(path: &Path) -> Result<Vec<Certificate>, HttpClientError> {
+    let pem = std::fs::read(path).map_err(|source| HttpClientError::ReadCaBundle {
+        path: path.to_path_buf(),
+        source,
+    })?;
+    Certificate::from_pem_bundle(&pem).map_err(|source| HttpClientError::InvalidCaBundle {
+        path: path.to_path_buf(),
+        source,
+    })
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn default_config_builds() {
+        assert!(build_client(&OutboundConfig::default(), None).is_ok());
+    }
+
+    #[test]
+    fn pool_tuning_builds() {
+        let outbound = OutboundConfig {
+            pool_max_idle_per_host: Some(8),
+            pool_idle_timeout_seconds: 30,
+            tcp_keepalive_seconds: Some(15),
+            ..Default::default()
+        };
+        assert!(build_client(&outbound, None).is_ok());
+    }
+
+    #[test]
+    fn proxy_and_direct_override_build() {
+        let outbound = OutboundConfig {
+            proxy: Some("http://proxy.corp:3128".to_string()),
+            no_proxy: Some("localhost,.internal".to_string()),
+            ..Default::default()
+        };
+        assert!(build_client(&outbound, None).is_ok());
+        assert!(build_client(&outbound, Some("")).is_ok());
+    }
+
+    #[test]
+    fn invalid_proxy_is_rejected() {
+        let outbound = OutboundConfig {
+            proxy: Some("not a url".to_string()),
+            ..Default::default()
+        };
+        let err = build_client(&outbound, None).unwrap_err();
+        assert!(matches!(err, HttpClientError::InvalidProxy { .. }));
+    }
+
+    #[test]
+    fn missing_ca_bundle_is_rejected() {
+        let outbound = OutboundConfig {
+            ca_bundle: Some(PathBuf::from("/nonexistent/ca.pem")),
+            ..Default::default()
+        };
+        let err = build_client(&outbound, None).unwrap_err();
+        assert!(matches!(err, HttpClientError::ReadCaBundle { .. }));
+    }
+}
@@ -0,0 +1,193 @@
+//! Mock LLM provider.
+//!
+//! Selected with `provider: mock`. It needs no credentials and makes no network
+//! calls: every request is answered by echoing the last user message back,
+//! streamed word by word, and embeddings are hashed bags of words. Models
+//! named `*-reasoning` (e.g. `echo-reasoning`) also return a line of
+//! reasoning before the reply. This keeps `duragent bench` runs and local
+//! tests free of provider cost and latency, so they measure the server itself.
+
+use async_trait::async_trait;
+use futures::stream;
+
+use super::{
+    ChatRequest, ChatResponse, ChatStream, Choice, LLMError, LLMProvider, Message, Role,
+    StreamEvent, Usage,
+};
+
+/// Offline provider that echoes the last user message.
+pub struct MockProvider;
+
+#[async_trait]
+impl LLMProvider for MockProvider {
+    async fn chat(&self, request: ChatRequest) -> Result<ChatResponse, LLMError> {
+        let content = reply(&request);
+        let usage = usage(&request, &content);
+        let mut message = Message::text(Role::Assistant, content);
+        message.reasoning = reasoning(&request);
+        Ok(ChatResponse {
+            id: format!("mock-{}", ulid::Ulid::new()),
+            choices: vec![Choice {
+                index: 0,
+                message,
+                finish_reason: Some("stop".to_string()),
+            }],
+            usage: Some(usage),
+        })
+    }
+
+    async fn chat_stream(&self, request: ChatRequest) -> Result<ChatStream, LLMError> {
+        let content = reply(&request);
+        let usage = usage(&request, &content);
+
+        let mut events: Vec<Result<StreamEvent, LLMError>> = reasoning(&request)
+            .map(|r| Ok(StreamEvent::Reasoning(r)))
+            .into_iter()
+            .chain(
+                content
+                    .split_inclusive(' ')
+                    .map(|word| Ok(StreamEvent::Token(word.to_string()))),
+            )
+            .collect();
+        events.push(Ok(StreamEvent::Done { usage: Some(usage) }));
+        Ok(Box::pin(stream::iter(events)))
+    }
+
+    /// Hashed bag of words, so texts sharing words come out similar.
+    async fn embed(&self, _model: &str, inputs: Vec<String>) -> Result<Vec<Vec<f32>>, LLMError> {
+        Ok(inputs.iter().map(|input| embedding(input)).collect())
+    }
+}
+
+const EMBEDDING_DIMS: usize = 64;
+
+fn embedding(input: &str) -> Vec<f32> {
+    let mut vector = vec![0.0f32; EMBEDDING_DIMS];
+    for word in input.split(|c: char| !c.is_alphanumeric()) {
+        if word.is_empty() {
+            continue;
+        }
+        // FNV-1a, stable across runs unlike the std hasher.
+        let hash = word
+            .to_lowercase()
+            .bytes()
+            .fold(0xcbf2_9ce4_8422_2325u64, |h, b| {
+                (h ^ u64::from(b)).wrapping_mul(0x0100_0000_01b3)
+            });
+        vector[(hash % EMBEDDING_DIMS as u64) as usize] += 1.0;
+    }
+    let norm = vector.iter().map(|v| v * v).sum::<f32>().sqrt();
+    if norm > 0.0 {
+        vector.iter_mut().for_each(|v| *v /= norm);
+    }
+    vector
+}
+
+fn reply(request: &ChatRequest) -> String {
+    request
+        .messages
+        .iter()
+        .rev()
+        .find(|m| m.role == Role::User)
+        .and_then(|m| m.content.clone())
+        .unwrap_or_default()
+}
+
+fn reasoning(request: &ChatRequest) -> Option<String> {
+    request
+        .model
+        .ends_with("-reasoning")
+        .then(|| "Echoing the last user message.".to_string())
+}
+
+/// Rough token counts (4 characters per token).
+fn usage(request: &ChatRequest, content: &str) -> Usage {
+    let prompt_chars: usize = request
+        .messages
+        .iter()
+        .filter_map(|m| m.content.as_deref())
+        .map(str::len)
+        .sum();
+    let prompt_tokens = prompt_chars.div_ceil(4) as u32;
+    let completion_tokens = content.len().div_ceil(4) as u32;
+    Usage {
+        prompt_tokens,
+        completion_tokens,
+        total_tokens: prompt_tokens + completion_tokens,
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use futures::StreamExt;
+
+    fn request(messages: Vec<Message>) -> ChatRequest {
+        ChatRequest {
+            model: "echo".to_string(),
+            messages,
+            temperature: None,
+            max_tokens: None,
+            tools: None,
+        }
+    }
+
+    #[tokio::test]
+    async fn chat_echoes_last_user_message() {
+        let req = request(vec![
+            Message::text(Role::System, "be brief"),
+            Message::text(Role::User, "first"),
+            Message::text(Role::Assistant, "first"),
+            Message::text(Role::User, "second message"),
+        ]);
+
+        let response = MockProvider.chat(req).await.unwrap();
+        assert_eq!(
+            response.choices[0].message.content.as_deref(),
+            Some("second message")
+        );
+        assert_eq!(response.usage.unwrap().completion_tokens, 4);
+    }
+
+    #[tokio::test]
+    async fn stream_splits_reply_into_words() {
+        let req = request(vec![Message::text(Role::User, "hello there world")]);
+
+        let events: Vec<_> = MockProvider
+            .chat_stream(req)
+            .await
+            .unwrap()
+            .map(Result::unwrap)
+            .collect()
+            .await;
+
+        let tokens: Vec<_> = events
+            .iter()
+            .filter_map(|e| match e {
+                StreamEvent::Token(t) => Some(t.as_str()),
+                _ => None,
+            })
+            .collect();
+        assert_eq!(tokens, ["hello ", "there ", "world"]);
+        assert!(matches!(events.last(), Some(StreamEvent::Done { .. })));
+        assert!(
+            !events
+                .iter()
+                .any(|e| matches!(e, StreamEvent::Reasoning(_)))
+        );
+    }
+
+    #[tokio::test]
+    async fn reasoning_models_return_reasoning() {
+        let mut req = request(vec![Message::text(Role::User, "hi")]);
+        req.model = "echo-reasoning".to_string();
+
+        let response = MockProvider.chat(req.clone()).await.unwrap();
+        assert!(response.choices[0].message.reasoning.is_some());
+        assert_eq!(response.choices[0].message.content.as_deref(), Some("hi"));
+
+        let mut events = MockProvider.chat_stream(req).await.unwrap();
+        let first = events.next().await.unwrap().unwrap();
+        assert!(matches!(first, StreamEvent::Reasoning(_)));
+    }
+}
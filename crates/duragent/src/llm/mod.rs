@@ -6,6 +6,14 @@ pub use duragent_client::llm::*;
 #[cfg(feature = "server")]
 mod anthropic;
 #[cfg(feature = "server")]
+mod bedrock;
+#[cfg(feature = "server")]
+mod gemini;
+#[cfg(feature = "server")]
+pub mod http;
+#[cfg(feature = "server")]
+mod mock;
+#[cfg(feature = "server")]
 mod openai;
 #[cfg(feature = "server")]
 mod provider;
@@ -15,6 +23,12 @@ mod registry;
 #[cfg(feature = "server")]
 pub use anthropic::{AnthropicAuth, AnthropicProvider};
 #[cfg(feature = "server")]
+pub use bedrock::{AwsCredentials, BedrockProvider};
+#[cfg(feature = "server")]
+pub use gemini::GeminiProvider;
+#[cfg(feature = "server")]
+pub use mock::MockProvider;
+#[cfg(feature = "server")]
 pub use openai::OpenAICompatibleProvider;
 #[cfg(feature = "server")]
 pub use provider::LLMProvider;
@@ -1,6 +1,7 @@
 //! OpenAI-compatible LLM provider.
 //!
-//! Works with OpenAI, OpenRouter, Ollama, and other compatible APIs.
+//! Works with OpenAI, OpenRouter, Ollama, Azure OpenAI, and other compatible
+//! APIs.
 
 use std::pin::Pin;
 use std::task::{Context, Poll};
@@ -10,25 +11,88 @@ use futures::Stream;
 use reqwest::Client;
 
 use super::{
-    ChatRequest, ChatResponse, ChatStream, FunctionCall, LLMError, LLMProvider, Message, Role,
-    StreamEvent, ToolCall, ToolDefinition, Usage, check_response_error,
+    ChatRequest, ChatResponse, ChatStream, Choice, FunctionCall, LLMError, LLMProvider, Message,
+    Role, StreamEvent, ToolCall, ToolDefinition, Usage, check_response_error,
 };
+use crate::provider_stats::RateLimitObserver;
 use crate::sse_parser::SseEventStream;
 
-/// OpenAI-compatible provider (works for OpenAI, OpenRouter, Ollama).
+/// OpenAI-compatible provider (works for OpenAI, OpenRouter, Ollama, Azure).
 pub struct OpenAICompatibleProvider {
     client: Client,
     base_url: String,
     api_key: Option<String>,
+    /// Azure OpenAI API version; `None` for OpenAI-style endpoints.
+    azure_api_version: Option<String>,
+    rate_limits: Option<RateLimitObserver>,
 }
 
 impl OpenAICompatibleProvider {
+    /// Azure OpenAI API version used when none is configured.
+    pub const DEFAULT_AZURE_API_VERSION: &'static str = "2024-10-21";
+
     #[must_use]
     pub fn new(client: Client, base_url: String, api_key: Option<String>) -> Self {
         Self {
             client,
             base_url,
             api_key,
+            azure_api_version: None,
+            rate_limits: None,
+        }
+    }
+
+    /// Azure OpenAI resource at `endpoint`. Requests go to the deployment
+    /// named by the request's model, with `api-key` authentication.
+    #[must_use]
+    pub fn azure(client: Client, endpoint: String, api_key: String, api_version: String) -> Self {
+        Self {
+            client,
+            base_url: endpoint.trim_end_matches('/').to_string(),
+            api_key: Some(api_key),
+            azure_api_version: Some(api_version),
+            rate_limits: None,
+        }
+    }
+
+    /// Report the rate-limit headers of chat responses to `observer`.
+    #[must_use]
+    pub fn with_rate_limits(mut self, observer: RateLimitObserver) -> Self {
+        self.rate_limits = Some(observer);
+        self
+    }
+
+    fn observe(&self, response: &reqwest::Response) {
+        if let Some(observer) = &self.rate_limits {
+            observer.observe(response.headers());
+        }
+    }
+
+    /// URL of an operation on `model`, e.g. `chat/completions`.
+    fn url(&self, operation: &str, model: &str) -> String {
+        match &self.azure_api_version {
+            Some(version) => format!(
+                "{}/openai/deployments/{model}/{operation}?api-version={version}",
+                self.base_url
+            ),
+            None => format!("{}/{operation}", self.base_url),
+        }
+    }
+
+    /// URL listing the available models, used for health checks.
+    fn models_url(&self) -> String {
+        match &self.azure_api_version {
+            Some(version) => format!("{}/openai/models?api-version={version}", self.base_url),
+            None => format!("{}/models", self.base_url),
+        }
+    }
+
+    /// Add the API key header, if any.
+    fn with_auth(&self, builder: reqwest::RequestBuilder) -> reqwest::RequestBuilder {
+        match (&self.api_key, &self.azure_api_version) {
+            (Some(key), Some(_)) => builder.header("api-key", key),
+            (Some(key), None) => builder.header("Authorization", format!("Bearer {}", key)),
+            (None, _) => builder,
         }
     }
 }
@@ -36,19 +100,16 @@ impl OpenAICompatibleProvider {
 #[async_trait]
 impl LLMProvider for OpenAICompatibleProvider {
     async fn chat(&self, request: ChatRequest) -> Result<ChatResponse, LLMError> {
-        let url = format!("{}/chat/completions", self.base_url);
+        let url = self.url("chat/completions", &request.model);
         let request = normalize_request(request);
 
-        let mut req = self
+        let req = self
             .client
             .post(&url)
             .header("Content-Type", "application/json");
 
-        if let Some(ref key) = self.api_key {
-            req = req.header("Authorization", format!("Bearer {}", key));
-        }
-
-        let response = req.json(&request).send().await?;
+        let response = self.with_auth(req).json(&request).send().await?;
+        self.observe(&response);
 
         if let Some(err) = check_response_error(&response) {
             return Err(err);
@@ -59,11 +120,12 @@ impl LLMProvider for OpenAICompatibleProvider {
             return Err(LLMError::Api { status, message });
         }
 
-        Ok(response.json().await?)
+        let response: CompletionResponse = response.json().await?;
+        Ok(response.into())
     }
 
     async fn chat_stream(&self, request: ChatRequest) -> Result<ChatStream, LLMError> {
-        let url = format!("{}/chat/completions", self.base_url);
+        let url = self.url("chat/completions", &request.model);
         let request = normalize_request(request);
 
         let stream_request = StreamRequest {
@@ -78,16 +140,13 @@ impl LLMProvider for OpenAICompatibleProvider {
             },
         };
 
-        let mut req = self
+        let req = self
             .client
             .post(&url)
             .header("Content-Type", "application/json");
 
-        if let Some(ref key) = self.api_key {
-            req = req.header("Authorization", format!("Bearer {}", key));
-        }
-
-        let response = req.json(&stream_request).send().await?;
+        let response = self.with_auth(req).json(&stream_request).send().await?;
+        self.observe(&response);
 
         if let Some(err) = check_response_error(&response) {
             return Err(err);
@@ -104,6 +163,162 @@ impl LLMProvider for OpenAICompatibleProvider {
 
         Ok(Box::pin(event_stream))
     }
+
+    async fn health_check(&self) -> Result<(), LLMError> {
+        let url = self.models_url();
+        let response = self.with_auth(self.client.get(&url)).send().await?;
+        if let Some(err) = check_response_error(&response) {
+            return Err(err);
+        }
+        if !response.status().is_success() {
+            let status = response.status().as_u16();
+            let message = response.text().await.unwrap_or_default();
+            return Err(LLMError::Api { status, message });
+        }
+        Ok(())
+    }
+
+    async fn embed(&self, model: &str, inputs: Vec<String>) -> Result<Vec<Vec<f32>>, LLMError> {
+        let url = self.url("embeddings", model);
+
+        let req = self
+            .client
+            .post(&url)
+            .header("Content-Type", "application/json");
+
+        let response = self
+            .with_auth(req)
+            .json(&EmbeddingRequest {
+                model,
+                input: inputs,
+            })
+            .send()
+            .await?;
+
+        if let Some(err) = check_response_error(&response) {
+            return Err(err);
+        }
+        if !response.status().is_success() {
+            let status = response.status().as_u16();
+            let message = response.text().await.unwrap_or_default();
+            return Err(LLMError::Api { status, message });
+        }
+
+        let mut body: EmbeddingResponse = response.json().await?;
+        body.data.sort_by_key(|d| d.index);
+        Ok(body.data.into_iter().map(|d| d.embedding).collect())
+    }
+
+    async fn list_models(&self) -> Result<Vec<String>, LLMError> {
+        let url = self.models_url();
+        let response = self.with_auth(self.client.get(&url)).send().await?;
+
+        if let Some(err) = check_response_error(&response) {
+            return Err(err);
+        }
+        if !response.status().is_success() {
+            let status = response.status().as_u16();
+            let message = response.text().await.unwrap_or_default();
+            return Err(LLMError::Api { status, message });
+        }
+
+        let body: ModelListResponse = response.json().await?;
+        Ok(body.data.into_iter().map(|m| m.id).collect())
+    }
+}
+
+// ============================================================================
+// Model List Types
+// ============================================================================
+
+#[derive(serde::Deserialize)]
+struct ModelListResponse {
+    data: Vec<ModelListEntry>,
+}
+
+#[derive(serde::Deserialize)]
+struct ModelListEntry {
+    id: String,
+}
+
+// ============================================================================
+// Embedding Types
+// ============================================================================
+
+#[derive(serde::Serialize)]
+struct EmbeddingRequest<'a> {
+    model: &'a str,
+    input: Vec<String>,
+}
+
+#[derive(serde::Deserialize)]
+struct EmbeddingResponse {
+    data: Vec<EmbeddingData>,
+}
+
+#[derive(serde::Deserialize)]
+struct EmbeddingData {
+    index: usize,
+    embedding: Vec<f32>,
+}
+
+// ============================================================================
+// Completion Types
+// ============================================================================
+
+/// Chat completion response, read with the reasoning fields some
+/// compatible APIs add next to the answer.
+#[derive(serde::Deserialize)]
+struct CompletionResponse {
+    id: String,
+    choices: Vec<CompletionChoice>,
+    usage: Option<Usage>,
+}
+
+#[derive(serde::Deserialize)]
+struct CompletionChoice {
+    index: u32,
+    message: CompletionMessage,
+    finish_reason: Option<String>,
+}
+
+#[derive(serde::Deserialize)]
+struct CompletionMessage {
+    #[serde(flatten)]
+    message: Message,
+    /// DeepSeek, vLLM, and others.
+    #[serde(default)]
+    reasoning_content: Option<String>,
+    /// OpenRouter.
+    #[serde(default)]
+    reasoning: Option<String>,
+}
+
+impl From<CompletionResponse> for ChatResponse {
+    fn from(response: CompletionResponse) -> Self {
+        let choices = response
+            .choices
+            .into_iter()
+            .map(|choice| {
+                let mut message = choice.message.message;
+                message.reasoning = choice
+                    .message
+                    .reasoning_content
+                    .or(choice.message.reasoning)
+                    .filter(|r| !r.is_empty());
+                Choice {
+                    index: choice.index,
+                    message,
+                    finish_reason: choice.finish_reason,
+                }
+            })
+            .collect();
+        ChatResponse {
+            id: response.id,
+            choices,
+            usage: response.usage,
+        }
+    }
 }
 
 fn normalize_request(mut request: ChatRequest) -> ChatRequest {
@@ -244,6 +459,19 @@ where
                                     ))));
                                 }
 
+                                // Handle reasoning tokens
+                                if let Some(reasoning) = choice
+                                    .delta
+                                    .reasoning_content
+                                    .as_ref()
+                                    .or(choice.delta.reasoning.as_ref())
+                                    && !reasoning.is_empty()
+                                {
+                                    return Poll::Ready(Some(Ok(StreamEvent::Reasoning(
+                                        reasoning.clone(),
+                                    ))));
+                                }
+
                                 // Handle tool calls (accumulated across chunks)
                                 if let Some(ref tool_calls) = choice.delta.tool_calls {
                                     for tc in tool_calls {
@@ -330,6 +558,10 @@ struct StreamChoice {
 #[derive(serde::Deserialize)]
 struct StreamDelta {
     content: Option<String>,
+    #[serde(default)]
+    reasoning_content: Option<String>,
+    #[serde(default)]
+    reasoning: Option<String>,
     tool_calls: Option<Vec<StreamToolCall>>,
 }
 
@@ -347,3 +579,36 @@ struct StreamFunctionCall {
     name: Option<String>,
     arguments: Option<String>,
 }
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn azure_routes_to_deployment() {
+        let provider = OpenAICompatibleProvider::azure(
+            Client::new(),
+            "https://acme.openai.azure.com/".to_string(),
+            "key".to_string(),
+            "2024-10-21".to_string(),
+        );
+        assert_eq!(
+            provider.url("chat/completions", "gpt-4o-prod"),
+            "https://acme.openai.azure.com/openai/deployments/gpt-4o-prod/chat/completions?api-version=2024-10-21"
+        );
+        assert_eq!(
+            provider.models_url(),
+            "https://acme.openai.azure.com/openai/models?api-version=2024-10-21"
+        );
+
+        let provider = OpenAICompatibleProvider::new(
+            Client::new(),
+            "https://api.openai.com/v1".to_string(),
+            None,
+        );
+        assert_eq!(
+            provider.url("chat/completions", "gpt-4o"),
+            "https://api.openai.com/v1/chat/completions"
+        );
+    }
+}
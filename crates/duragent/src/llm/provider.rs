@@ -18,16 +18,43 @@ pub trait LLMProvider: Send + Sync {
     /// as a single chunk. Override this for native token-by-token streaming.
     async fn chat_stream(&self, request: ChatRequest) -> Result<ChatStream, LLMError> {
         let response = self.chat(request).await?;
-        let content = response
+        let (content, reasoning) = response
             .choices
-            .first()
-            .and_then(|c| c.message.content.clone())
+            .into_iter()
+            .next()
+            .map(|c| (c.message.content, c.message.reasoning))
             .unwrap_or_default();
         let usage = response.usage;
 
-        Ok(Box::pin(stream::iter(vec![
-            Ok(StreamEvent::Token(content)),
-            Ok(StreamEvent::Done { usage }),
-        ])))
+        let events = reasoning
+            .map(StreamEvent::Reasoning)
+            .into_iter()
+            .chain([
+                StreamEvent::Token(content.unwrap_or_default()),
+                StreamEvent::Done { usage },
+            ])
+            .map(Ok);
+        Ok(Box::pin(stream::iter(events.collect::<Vec<_>>())))
+    }
+
+    /// Check that the provider is reachable and accepts its credentials.
+    ///
+    /// Default implementation reports healthy without making a request.
+    async fn health_check(&self) -> Result<(), LLMError> {
+        Ok(())
+    }
+
+    /// Embed each input with `model`, returning vectors in input order.
+    ///
+    /// Default implementation reports that the provider has no embeddings API.
+    async fn embed(&self, _model: &str, _inputs: Vec<String>) -> Result<Vec<Vec<f32>>, LLMError> {
+        Err(LLMError::Unsupported("embeddings"))
+    }
+
+    /// IDs of the models the provider serves, as it reports them.
+    ///
+    /// Default implementation reports that the provider can't list models.
+    async fn list_models(&self) -> Result<Vec<String>, LLMError> {
+        Err(LLMError::Unsupported("model listing"))
     }
 }
@@ -8,23 +8,50 @@ use tokio::sync::Mutex;
 use tracing::{debug, info, warn};
 
 use super::anthropic::{AnthropicAuth, AnthropicProvider};
+use super::bedrock::{self, AwsCredentials, BedrockProvider};
+use super::gemini::{self, GeminiProvider};
+use super::http::{self, HttpClientError};
+use super::mock::MockProvider;
 use super::openai::OpenAICompatibleProvider;
 use super::provider::LLMProvider;
 use crate::auth::anthropic_oauth;
 use crate::auth::credentials::{AuthCredential, AuthStorage};
+use crate::config::OutboundConfig;
+use crate::faults::FaultInjector;
 use crate::llm::Provider;
-
-/// TCP connect timeout for LLM HTTP requests.
-const CONNECT_TIMEOUT: std::time::Duration = std::time::Duration::from_secs(30);
-/// Total request timeout for LLM HTTP requests (connect + send + receive).
-const REQUEST_TIMEOUT: std::time::Duration = std::time::Duration::from_secs(300);
+use crate::provider_stats::{ProviderStats, TrackedProvider};
+use crate::slo::{ObservedProvider, ProviderSlos};
+use crate::throttle::ProviderThrottle;
 
 /// Default base URLs for each provider.
 pub mod defaults {
     pub const ANTHROPIC: &str = "https://api.anthropic.com";
+    pub const GEMINI: &str = "https://generativelanguage.googleapis.com/v1beta";
+    pub const LLAMA_CPP: &str = "http://localhost:8080/v1";
+    pub const LM_STUDIO: &str = "http://localhost:1234/v1";
     pub const OLLAMA: &str = "http://localhost:11434/v1";
     pub const OPENAI: &str = "https://api.openai.com/v1";
     pub const OPENROUTER: &str = "https://openrouter.ai/api/v1";
+    pub const VLLM: &str = "http://localhost:8000/v1";
+}
+
+/// Self-hosted OpenAI-compatible server presets: the environment variable
+/// prefix and default base URL. `{PREFIX}_BASE_URL` and `{PREFIX}_API_KEY`
+/// configure each; without an API key, requests are unauthenticated.
+fn self_hosted_preset(provider: &Provider) -> Option<(&'static str, &'static str)> {
+    match provider {
+        Provider::LlamaCpp => Some(("LLAMACPP", defaults::LLAMA_CPP)),
+        Provider::LmStudio => Some(("LMSTUDIO", defaults::LM_STUDIO)),
+        Provider::Vllm => Some(("VLLM", defaults::VLLM)),
+        _ => None,
+    }
+}
+
+/// Base URL and API key of a configured self-hosted server.
+#[derive(Clone, Default)]
+struct SelfHostedServer {
+    base_url: Option<String>,
+    api_key: Option<String>,
 }
 
 /// Registry of LLM provider credentials.
@@ -33,26 +60,49 @@ pub mod defaults {
 /// on-demand with optional base_url overrides from agent configuration.
 ///
 /// The registry holds a shared `reqwest::Client` that is passed to all providers,
-/// enabling connection pooling across requests.
+/// enabling connection pooling across requests. Providers with an outbound
+/// proxy override get their own client.
 #[derive(Clone)]
 pub struct ProviderRegistry {
     api_keys: HashMap<Provider, String>,
     client: Client,
+    provider_clients: HashMap<Provider, Client>,
     auth_storage: Arc<Mutex<AuthStorage>>,
+    slos: ProviderSlos,
+    faults: FaultInjector,
+    throttle: ProviderThrottle,
+    stats: ProviderStats,
+    /// Azure OpenAI resource endpoint, unless agents set `base_url`.
+    azure_endpoint: Option<String>,
+    azure_api_version: String,
+    aws_credentials: Option<AwsCredentials>,
+    aws_region: String,
+    /// Gemini safety threshold for every harm category.
+    gemini_safety_threshold: Option<String>,
+    /// Self-hosted servers configured in the environment.
+    self_hosted: HashMap<Provider, SelfHostedServer>,
 }
 
 impl Default for ProviderRegistry {
     fn default() -> Self {
-        let client = Client::builder()
-            .connect_timeout(CONNECT_TIMEOUT)
-            .timeout(REQUEST_TIMEOUT)
-            .build()
+        let client = http::build_client(&OutboundConfig::default(), None)
             .expect("failed to build HTTP client");
 
         Self {
             api_keys: HashMap::new(),
             client,
+            provider_clients: HashMap::new(),
             auth_storage: Arc::new(Mutex::new(AuthStorage::default())),
+            slos: ProviderSlos::default(),
+            faults: FaultInjector::default(),
+            throttle: ProviderThrottle::default(),
+            stats: ProviderStats::default(),
+            azure_endpoint: None,
+            azure_api_version: OpenAICompatibleProvider::DEFAULT_AZURE_API_VERSION.to_string(),
+            aws_credentials: None,
+            aws_region: bedrock::DEFAULT_REGION.to_string(),
+            gemini_safety_threshold: None,
+            self_hosted: HashMap::new(),
         }
     }
 }
@@ -63,6 +113,55 @@ impl ProviderRegistry {
         Self::default()
     }
 
+    /// Rebuild HTTP clients from outbound config (proxy, CA bundle, and
+    /// per-provider proxy overrides).
+    pub fn with_outbound(mut self, outbound: &OutboundConfig) -> Result<Self, HttpClientError> {
+        self.client = http::build_client(outbound, None)?;
+        self.provider_clients.clear();
+        for (name, overrides) in &outbound.providers {
+            if let Some(proxy) = overrides.proxy.as_deref() {
+                let client = http::build_client(outbound, Some(proxy))?;
+                self.provider_clients
+                    .insert(Provider::from(name.clone()), client);
+            }
+        }
+        Ok(self)
+    }
+
+    /// Record requests to providers with objectives in `slos`.
+    #[must_use]
+    pub fn with_slos(mut self, slos: ProviderSlos) -> Self {
+        self.slos = slos;
+        self
+    }
+
+    /// Inject the configured provider faults into LLM requests.
+    #[must_use]
+    pub fn with_faults(mut self, faults: FaultInjector) -> Self {
+        self.faults = faults;
+        self
+    }
+
+    /// Queue requests to providers with rate limit budgets in `throttle`.
+    #[must_use]
+    pub fn with_throttle(mut self, throttle: ProviderThrottle) -> Self {
+        self.throttle = throttle;
+        self
+    }
+
+    /// Recent latency and rate-limit headroom of every provider.
+    pub fn stats(&self) -> &ProviderStats {
+        &self.stats
+    }
+
+    /// HTTP client for a provider, honoring per-provider overrides.
+    fn client_for(&self, provider: &Provider) -> Client {
+        self.provider_clients
+            .get(provider)
+            .unwrap_or(&self.client)
+            .clone()
+    }
+
     /// Initialize registry with API keys from environment variables.
     pub fn from_env() -> Self {
         let mut registry = Self::new();
@@ -86,6 +185,9 @@ impl ProviderRegistry {
             info!("Found OpenRouter API key");
         }
 
+        registry.read_cloud_env();
+        registry.read_self_hosted_env();
+
         // Load OAuth credentials from disk
         let auth_path = AuthStorage::default_path();
         match AuthStorage::load(&auth_path) {
@@ -137,6 +239,9 @@ impl ProviderRegistry {
             info!("Found OpenRouter API key");
         }
 
+        registry.read_cloud_env();
+        registry.read_self_hosted_env();
+
         // Load OAuth credentials from disk
         let auth_path = AuthStorage::default_path();
         match AuthStorage::load_async(auth_path).await {
@@ -165,11 +270,91 @@ impl ProviderRegistry {
         registry
     }
 
+    /// Read Azure OpenAI, AWS Bedrock, and Gemini settings from the
+    /// environment.
+    fn read_cloud_env(&mut self) {
+        if let Ok(api_key) = std::env::var("AZURE_OPENAI_API_KEY") {
+            self.api_keys.insert(Provider::Azure, api_key);
+            info!("Found Azure OpenAI API key");
+        }
+        self.azure_endpoint = std::env::var("AZURE_OPENAI_ENDPOINT").ok();
+        if let Ok(version) = std::env::var("AZURE_OPENAI_API_VERSION") {
+            self.azure_api_version = version;
+        }
+
+        if let Some(credentials) = AwsCredentials::from_env() {
+            self.aws_credentials = Some(credentials);
+            info!("Found AWS credentials for Bedrock");
+        }
+        if let Some(region) = bedrock::region_from_env() {
+            self.aws_region = region;
+        }
+
+        if let Ok(api_key) =
+            std::env::var("GEMINI_API_KEY").or_else(|_| std::env::var("GOOGLE_API_KEY"))
+        {
+            self.api_keys.insert(Provider::Gemini, api_key);
+            info!("Found Gemini API key");
+        }
+        if let Ok(threshold) = std::env::var("GEMINI_SAFETY_THRESHOLD") {
+            let threshold = threshold.to_uppercase();
+            if gemini::SAFETY_THRESHOLDS.contains(&threshold.as_str()) {
+                self.gemini_safety_threshold = Some(threshold);
+            } else {
+                warn!(
+                    threshold = %threshold,
+                    "Ignoring unknown GEMINI_SAFETY_THRESHOLD; expected one of {}",
+                    gemini::SAFETY_THRESHOLDS.join(", ")
+                );
+            }
+        }
+    }
+
+    /// Read self-hosted server presets from the environment. A preset is
+    /// configured once its base URL or API key is set.
+    fn read_self_hosted_env(&mut self) {
+        for provider in [Provider::LlamaCpp, Provider::LmStudio, Provider::Vllm] {
+            let Some((prefix, _)) = self_hosted_preset(&provider) else {
+                continue;
+            };
+            let server = SelfHostedServer {
+                base_url: std::env::var(format!("{prefix}_BASE_URL")).ok(),
+                api_key: std::env::var(format!("{prefix}_API_KEY")).ok(),
+            };
+            if server.base_url.is_some() || server.api_key.is_some() {
+                info!(provider = %provider, "Found self-hosted server configuration");
+                self.self_hosted.insert(provider, server);
+            }
+        }
+    }
+
+    /// Names of the providers that can be used, sorted.
+    ///
+    /// A provider is available once its credentials are configured, and a
+    /// self-hosted server once its base URL or API key is; `mock` needs none
+    /// and is always available.
+    pub fn available(&self) -> Vec<String> {
+        let mut names: Vec<String> = self.api_keys.keys().map(Provider::to_string).collect();
+        names.extend(self.self_hosted.keys().map(Provider::to_string));
+        if self.has_oauth_credentials() && !self.api_keys.contains_key(&Provider::Anthropic) {
+            names.push(Provider::Anthropic.to_string());
+        }
+        if self.aws_credentials.is_some() {
+            names.push(Provider::Bedrock.to_string());
+        }
+        names.push(Provider::Mock.to_string());
+        names.sort();
+        names
+    }
+
     /// Check if any cloud provider is configured.
     fn has_cloud_provider(&self) -> bool {
         self.api_keys.contains_key(&Provider::Anthropic)
             || self.api_keys.contains_key(&Provider::OpenAI)
             || self.api_keys.contains_key(&Provider::OpenRouter)
+            || self.api_keys.contains_key(&Provider::Azure)
+            || self.api_keys.contains_key(&Provider::Gemini)
+            || self.aws_credentials.is_some()
             || self.has_oauth_credentials()
     }
 
@@ -186,61 +371,155 @@ impl ProviderRegistry {
     /// The base_url comes from the agent's model configuration. If not specified,
     /// the default URL for that provider is used.
     ///
-    /// All providers share the registry's `reqwest::Client` for connection pooling.
+    /// All providers share the registry's `reqwest::Client` for connection pooling,
+    /// unless they have an outbound proxy override.
     pub async fn get(
         &self,
         provider: &Provider,
         base_url: Option<&str>,
+    ) -> Option<Arc<dyn LLMProvider>> {
+        let instance = self.create(provider, base_url).await?;
+        // Faults sit inside the SLO and stats wrappers so injected failures count
+        let instance = self.faults.wrap_provider(provider.as_str(), instance);
+        let instance: Arc<dyn LLMProvider> = Arc::new(TrackedProvider::new(
+            instance,
+            provider.to_string(),
+            self.stats.clone(),
+        ));
+        let instance: Arc<dyn LLMProvider> = if self.slos.tracks(provider.as_str()) {
+            Arc::new(ObservedProvider::new(
+                instance,
+                provider.to_string(),
+                self.slos.clone(),
+            ))
+        } else {
+            instance
+        };
+        // Time spent queued for budget isn't provider latency
+        Some(self.throttle.wrap_provider(provider.as_str(), instance))
+    }
+
+    async fn create(
+        &self,
+        provider: &Provider,
+        base_url: Option<&str>,
     ) -> Option<Arc<dyn LLMProvider>> {
         match provider {
             Provider::Anthropic => {
                 // OAuth takes precedence over API key
                 if let Some(auth) = self.get_anthropic_oauth_auth().await {
                     let url = base_url.unwrap_or(defaults::ANTHROPIC);
-                    return Some(Arc::new(AnthropicProvider::new(
-                        self.client.clone(),
-                        auth,
-                        url.to_string(),
-                    )));
+                    return Some(Arc::new(
+                        AnthropicProvider::new(self.client_for(provider), auth, url.to_string())
+                            .with_rate_limits(self.stats.rate_limit_observer(provider.as_str())),
+                    ));
                 }
 
                 // Fall back to API key
                 let api_key = self.api_keys.get(provider)?;
                 let url = base_url.unwrap_or(defaults::ANTHROPIC);
-                Some(Arc::new(AnthropicProvider::new(
-                    self.client.clone(),
-                    AnthropicAuth::ApiKey(api_key.clone()),
+                Some(Arc::new(
+                    AnthropicProvider::new(
+                        self.client_for(provider),
+                        AnthropicAuth::ApiKey(api_key.clone()),
+                        url.to_string(),
+                    )
+                    .with_rate_limits(self.stats.rate_limit_observer(provider.as_str())),
+                ))
+            }
+            Provider::Azure => {
+                let api_key = self.api_keys.get(provider)?;
+                let Some(endpoint) = base_url.or(self.azure_endpoint.as_deref()) else {
+                    warn!("Azure OpenAI needs AZURE_OPENAI_ENDPOINT or a model base_url");
+                    return None;
+                };
+                Some(Arc::new(
+                    OpenAICompatibleProvider::azure(
+                        self.client_for(provider),
+                        endpoint.to_string(),
+                        api_key.clone(),
+                        self.azure_api_version.clone(),
+                    )
+                    .with_rate_limits(self.stats.rate_limit_observer(provider.as_str())),
+                ))
+            }
+            Provider::Bedrock => {
+                let credentials = self.aws_credentials.clone()?;
+                match BedrockProvider::new(
+                    self.client_for(provider),
+                    credentials,
+                    self.aws_region.clone(),
+                    base_url,
+                ) {
+                    Ok(instance) => Some(Arc::new(instance)),
+                    Err(e) => {
+                        warn!(base_url = ?base_url, error = %e, "Invalid Bedrock base_url");
+                        None
+                    }
+                }
+            }
+            Provider::Gemini => {
+                let api_key = self.api_keys.get(provider)?;
+                let url = base_url.unwrap_or(defaults::GEMINI);
+                let instance = GeminiProvider::new(
+                    self.client_for(provider),
                     url.to_string(),
-                )))
+                    api_key.clone(),
+                );
+                Some(Arc::new(match &self.gemini_safety_threshold {
+                    Some(threshold) => instance.with_safety_threshold(threshold.clone()),
+                    None => instance,
+                }))
+            }
+            Provider::LlamaCpp | Provider::LmStudio | Provider::Vllm => {
+                let (_, default_url) = self_hosted_preset(provider)?;
+                let server = self.self_hosted.get(provider).cloned().unwrap_or_default();
+                let url = base_url
+                    .or(server.base_url.as_deref())
+                    .unwrap_or(default_url);
+                Some(Arc::new(
+                    OpenAICompatibleProvider::new(
+                        self.client_for(provider),
+                        url.to_string(),
+                        server.api_key,
+                    )
+                    .with_rate_limits(self.stats.rate_limit_observer(provider.as_str())),
+                ))
             }
+            Provider::Mock => Some(Arc::new(MockProvider)),
             Provider::Ollama => {
                 if !self.api_keys.contains_key(provider) {
                     return None;
                 }
                 let url = base_url.unwrap_or(defaults::OLLAMA);
-                Some(Arc::new(OpenAICompatibleProvider::new(
-                    self.client.clone(),
-                    url.to_string(),
-                    None,
-                )))
+                Some(Arc::new(
+                    OpenAICompatibleProvider::new(self.client_for(provider), url.to_string(), None)
+                        .with_rate_limits(self.stats.rate_limit_observer(provider.as_str())),
+                ))
             }
             Provider::OpenAI => {
                 let api_key = self.api_keys.get(provider)?;
                 let url = base_url.unwrap_or(defaults::OPENAI);
-                Some(Arc::new(OpenAICompatibleProvider::new(
-                    self.client.clone(),
-                    url.to_string(),
-                    Some(api_key.clone()),
-                )))
+                Some(Arc::new(
+                    OpenAICompatibleProvider::new(
+                        self.client_for(provider),
+                        url.to_string(),
+                        Some(api_key.clone()),
+                    )
+                    .with_rate_limits(self.stats.rate_limit_observer(provider.as_str())),
+                ))
             }
             Provider::OpenRouter => {
                 let api_key = self.api_keys.get(provider)?;
                 let url = base_url.unwrap_or(defaults::OPENROUTER);
-                Some(Arc::new(OpenAICompatibleProvider::new(
-                    self.client.clone(),
-                    url.to_string(),
-                    Some(api_key.clone()),
-                )))
+                Some(Arc::new(
+                    OpenAICompatibleProvider::new(
+                        self.client_for(provider),
+                        url.to_string(),
+                        Some(api_key.clone()),
+                    )
+                    .with_rate_limits(self.stats.rate_limit_observer(provider.as_str())),
+                ))
             }
             Provider::Other(name) => {
                 warn!(provider = %name, "Unknown provider");
@@ -274,7 +553,12 @@ impl ProviderRegistry {
                 let refresh_token = refresh.clone();
 
                 debug!("Anthropic OAuth token expired, refreshing");
-                match anthropic_oauth::refresh_token(&self.client, &refresh_token).await {
+                match anthropic_oauth::refresh_token(
+                    &self.client_for(&Provider::Anthropic),
+                    &refresh_token,
+                )
+                .await
+                {
                     Ok(tokens) => {
                         storage.set_anthropic(AuthCredential::OAuth {
                             access: tokens.access_token.clone(),
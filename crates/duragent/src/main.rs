@@ -68,6 +68,45 @@ enum Commands {
         server: Option<String>,
     },
 
+    /// Drive synthetic load against a server and report latency percentiles
+    Bench {
+        /// Agent to send messages to (use one with `provider: mock`)
+        #[arg(short, long)]
+        agent: String,
+
+        /// Concurrent workers, each with its own session
+        #[arg(long, default_value_t = 8)]
+        concurrency: usize,
+
+        /// Total messages to send
+        #[arg(short = 'n', long, default_value_t = 200)]
+        requests: usize,
+
+        /// Size of each message in bytes
+        #[arg(long, default_value_t = 256)]
+        payload_bytes: usize,
+
+        /// Use the SSE streaming endpoint and report time to first token
+        #[arg(long)]
+        stream: bool,
+
+        /// Output format (text or json)
+        #[arg(long, default_value = "text")]
+        format: String,
+
+        /// Path to configuration file
+        #[arg(short, long, default_value = "duragent.yaml")]
+        config: String,
+
+        /// Agents directory (overrides config file)
+        #[arg(long)]
+        agents_dir: Option<PathBuf>,
+
+        /// Connect to a specific server URL instead of auto-starting
+        #[arg(short, long)]
+        server: Option<String>,
+    },
+
     /// Start an interactive chat session with an agent
     #[cfg(feature = "cli")]
     Chat {
@@ -88,6 +127,12 @@ enum Commands {
         server: Option<String>,
     },
 
+    /// Export Grafana dashboards and Prometheus alert rules
+    Dashboards {
+        #[command(subcommand)]
+        action: DashboardsAction,
+    },
+
     /// Diagnose installation and configuration issues
     Doctor {
         /// Path to configuration file
@@ -129,6 +174,20 @@ enum Commands {
         provider: String,
     },
 
+    /// Apply or revert store migrations
+    Migrate {
+        #[command(subcommand)]
+        action: MigrateAction,
+
+        /// Path to configuration file
+        #[arg(short, long, default_value = "duragent.yaml", global = true)]
+        config: String,
+
+        /// Output format (text or json)
+        #[arg(long, default_value = "text", global = true)]
+        format: String,
+    },
+
     /// Manage sessions
     Session {
         #[command(subcommand)]
@@ -237,6 +296,105 @@ enum AgentAction {
         #[arg(short, long)]
         server: Option<String>,
     },
+
+    /// Export an agent as a portable bundle
+    Export {
+        /// Name of the agent to export
+        name: String,
+
+        /// Output path (default: <name>.agent.tar.gz)
+        #[arg(short, long)]
+        output: Option<PathBuf>,
+
+        /// Sign the bundle with this secret key (from `duragent agent keygen`)
+        #[arg(long)]
+        sign_key: Option<PathBuf>,
+
+        /// Path to configuration file
+        #[arg(short, long, default_value = "duragent.yaml")]
+        config: String,
+
+        /// Agents directory (overrides config file)
+        #[arg(long)]
+        agents_dir: Option<PathBuf>,
+    },
+
+    /// Import an agent from a bundle
+    Import {
+        /// Path to the bundle file
+        bundle: PathBuf,
+
+        /// Import under a different agent name
+        #[arg(long)]
+        name: Option<String>,
+
+        /// Replace an existing agent with the same name
+        #[arg(long)]
+        force: bool,
+
+        /// Public key trusted for signature verification (repeatable)
+        #[arg(long = "trusted-key")]
+        trusted_keys: Vec<PathBuf>,
+
+        /// Reject bundles not signed by a trusted key
+        #[arg(long)]
+        require_signature: bool,
+
+        /// Path to configuration file
+        #[arg(short, long, default_value = "duragent.yaml")]
+        config: String,
+
+        /// Agents directory (overrides config file)
+        #[arg(long)]
+        agents_dir: Option<PathBuf>,
+    },
+
+    /// Generate a key pair for signing agent bundles
+    Keygen {
+        /// Output path prefix; writes <prefix>.key and <prefix>.pub
+        #[arg(short, long, default_value = "duragent")]
+        output: PathBuf,
+
+        /// Overwrite existing key files
+        #[arg(long)]
+        force: bool,
+    },
+}
+
+#[derive(Subcommand, Debug)]
+enum DashboardsAction {
+    /// Write a Grafana dashboard and Prometheus alert rules for `/metrics`
+    Export {
+        /// Directory to write the files to
+        #[arg(short, long, default_value = ".")]
+        output: PathBuf,
+
+        /// Prometheus job name the server is scraped under
+        #[arg(long, default_value = "duragent")]
+        job: String,
+
+        /// Overwrite existing files
+        #[arg(long)]
+        force: bool,
+    },
+}
+
+#[derive(Subcommand, Debug)]
+enum MigrateAction {
+    /// Apply pending migrations
+    Up {
+        /// Stop at this version instead of the latest
+        #[arg(long)]
+        to: Option<u32>,
+    },
+    /// Revert the newest applied migration
+    Down {
+        /// Revert every migration above this version instead
+        #[arg(long)]
+        to: Option<u32>,
+    },
+    /// Show which migrations are applied
+    Status,
 }
 
 #[derive(Subcommand, Debug)]
@@ -327,6 +485,43 @@ async fn run(cli: &Cli) -> Result<()> {
                 agents_dir,
                 server,
             } => commands::agent::list(config, agents_dir.as_deref(), server.as_deref()).await,
+            AgentAction::Export {
+                name,
+                output,
+                sign_key,
+                config,
+                agents_dir,
+            } => {
+                commands::agent::export(
+                    config,
+                    name,
+                    agents_dir.as_deref(),
+                    output.as_deref(),
+                    sign_key.as_deref(),
+                )
+                .await
+            }
+            AgentAction::Import {
+                bundle,
+                name,
+                force,
+                trusted_keys,
+                require_signature,
+                config,
+                agents_dir,
+            } => {
+                commands::agent::import(
+                    config,
+                    bundle,
+                    name.as_deref(),
+                    agents_dir.as_deref(),
+                    *force,
+                    trusted_keys,
+                    *require_signature,
+                )
+                .await
+            }
+            AgentAction::Keygen { output, force } => commands::agent::keygen(output, *force).await,
         },
         Commands::Completions { shell } => {
             clap_complete::generate(
@@ -350,6 +545,30 @@ async fn run(cli: &Cli) -> Result<()> {
             }
             _ => commands::attach::list(config, agents_dir.as_deref(), server.as_deref()).await,
         },
+        Commands::Bench {
+            agent,
+            concurrency,
+            requests,
+            payload_bytes,
+            stream,
+            format,
+            config,
+            agents_dir,
+            server,
+        } => {
+            commands::bench::run(commands::bench::BenchOpts {
+                agent,
+                config_path: config,
+                agents_dir: agents_dir.as_deref(),
+                server_url: server.as_deref(),
+                concurrency: *concurrency,
+                requests: *requests,
+                payload_bytes: *payload_bytes,
+                stream: *stream,
+                format,
+            })
+            .await
+        }
         #[cfg(feature = "cli")]
         Commands::Chat {
             agent,
@@ -357,6 +576,11 @@ async fn run(cli: &Cli) -> Result<()> {
             agents_dir,
             server,
         } => commands::chat::run(agent, config, agents_dir.as_deref(), server.as_deref()).await,
+        Commands::Dashboards { action } => match action {
+            DashboardsAction::Export { output, job, force } => {
+                commands::dashboards::export(output, job, *force).await
+            }
+        },
         Commands::Doctor { config, format } => commands::doctor::run(config, format).await,
         Commands::Init {
             path,
@@ -375,6 +599,15 @@ async fn run(cli: &Cli) -> Result<()> {
             .await
         }
         Commands::Login { provider } => commands::login::run(provider).await,
+        Commands::Migrate {
+            action,
+            config,
+            format,
+        } => match action {
+            MigrateAction::Up { to } => commands::migrate::up(config, *to, format).await,
+            MigrateAction::Down { to } => commands::migrate::down(config, *to, format).await,
+            MigrateAction::Status => commands::migrate::status(config, format).await,
+        },
         Commands::Session { action } => match action {
             SessionAction::List {
                 config,
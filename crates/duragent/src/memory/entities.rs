@@ -0,0 +1,408 @@
+//! Structured memory: entities and relations extracted from conversations.
+//!
+//! With `memory.entities` set, each exchange is read by an extraction model
+//! in the background, and the relations it finds ("Apollo — will use —
+//! Postgres") are appended to `{agent_memory_dir}/entities.jsonl` along with
+//! the session and time they came up. The `entities` tool queries them by
+//! entity, relation, and age, so an agent can answer "what did we decide
+//! last week?".
+
+use std::path::{Path, PathBuf};
+use std::sync::Arc;
+
+use anyhow::Result;
+use chrono::{DateTime, Utc};
+use serde::{Deserialize, Serialize};
+use tracing::{debug, warn};
+
+use crate::agent::AgentSpec;
+use crate::llm::{ChatRequest, LLMProvider, Message, Role};
+use crate::postprocess::extract_json;
+
+/// Relation log, relative to the agent's memory directory.
+pub const ENTITIES_FILE: &str = "entities.jsonl";
+
+/// Instructions for the extraction request.
+const EXTRACTION_PROMPT: &str = "You maintain a knowledge graph of a conversation. From the exchange below, extract relations worth remembering between named entities: people, projects, organizations, products, places, dates, and decisions. Use short, consistent entity names and a short verb phrase for the relation, such as {\"subject\": \"Apollo\", \"subject_type\": \"project\", \"relation\": \"will use\", \"object\": \"Postgres\", \"object_type\": \"technology\"}. Skip small talk and anything hypothetical. Reply with only a JSON array of such objects, or [] if there is nothing to record.";
+
+// ============================================================================
+// Types
+// ============================================================================
+
+/// A relation between two entities, as mentioned in a conversation.
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct Relation {
+    pub subject: String,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub subject_type: Option<String>,
+    pub relation: String,
+    pub object: String,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub object_type: Option<String>,
+    /// Session the relation was mentioned in.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub session_id: Option<String>,
+    pub recorded_at: DateTime<Utc>,
+}
+
+impl Relation {
+    /// Whether `self` and `other` state the same thing, ignoring case and
+    /// when it was mentioned.
+    fn same_as(&self, other: &Relation) -> bool {
+        self.subject.eq_ignore_ascii_case(&other.subject)
+            && self.relation.eq_ignore_ascii_case(&other.relation)
+            && self.object.eq_ignore_ascii_case(&other.object)
+    }
+
+    fn mentions(&self, entity: &str) -> bool {
+        let entity = entity.to_lowercase();
+        self.subject.to_lowercase().contains(&entity)
+            || self.object.to_lowercase().contains(&entity)
+    }
+}
+
+/// Filters for [`EntityStore::query`]. Empty filters match everything.
+#[derive(Debug, Clone, Default)]
+pub struct EntityQuery {
+    /// Part of the subject or object name, ignoring case.
+    pub entity: Option<String>,
+    /// Part of the relation, ignoring case.
+    pub relation: Option<String>,
+    /// Only relations recorded at or after this time.
+    pub since: Option<DateTime<Utc>>,
+    /// Most relations to return.
+    pub limit: usize,
+}
+
+// ============================================================================
+// EntityStore
+// ============================================================================
+
+/// An agent's relation log.
+#[derive(Debug, Clone)]
+pub struct EntityStore {
+    path: PathBuf,
+}
+
+impl EntityStore {
+    /// Store for the agent whose memory directory is `agent_memory_dir`.
+    pub fn new(agent_memory_dir: &Path) -> Self {
+        Self {
+            path: agent_memory_dir.join(ENTITIES_FILE),
+        }
+    }
+
+    /// Every recorded relation, oldest first. Malformed lines are skipped.
+    pub fn load(&self) -> Result<Vec<Relation>> {
+        let content = match std::fs::read_to_string(&self.path) {
+            Ok(content) => content,
+            Err(e) if e.kind() == std::io::ErrorKind::NotFound => return Ok(Vec::new()),
+            Err(e) => return Err(e.into()),
+        };
+        Ok(content
+            .lines()
+            .filter(|line| !line.trim().is_empty())
+            .filter_map(|line| match serde_json::from_str(line) {
+                Ok(relation) => Some(relation),
+                Err(e) => {
+                    warn!(path = %self.path.display(), error = %e, "Skipping malformed relation");
+                    None
+                }
+            })
+            .collect())
+    }
+
+    /// Append relations that aren't recorded yet. Returns how many were added.
+    pub fn record(&self, relations: Vec<Relation>) -> Result<usize> {
+        use std::io::Write;
+
+        let mut known = self.load()?;
+        let mut lines = String::new();
+        let mut added = 0;
+        for relation in relations {
+            if known.iter().any(|r| r.same_as(&relation)) {
+                continue;
+            }
+            lines.push_str(&serde_json::to_string(&relation)?);
+            lines.push('\n');
+            known.push(relation);
+            added += 1;
+        }
+        if added == 0 {
+            return Ok(0);
+        }
+
+        if let Some(parent) = self.path.parent() {
+            std::fs::create_dir_all(parent)?;
+        }
+        let mut file = std::fs::OpenOptions::new()
+            .create(true)
+            .append(true)
+            .open(&self.path)?;
+        file.write_all(lines.as_bytes())?;
+        Ok(added)
+    }
+
+    /// Relations matching `query`, newest first.
+    pub fn query(&self, query: &EntityQuery) -> Result<Vec<Relation>> {
+        let mut relations: Vec<Relation> = self
+            .load()?
+            .into_iter()
+            .filter(|r| query.entity.as_deref().is_none_or(|e| r.mentions(e)))
+            .filter(|r| {
+                query
+                    .relation
+                    .as_deref()
+                    .is_none_or(|rel| r.relation.to_lowercase().contains(&rel.to_lowercase()))
+            })
+            .filter(|r| query.since.is_none_or(|since| r.recorded_at >= since))
+            .collect();
+        relations.reverse();
+        relations.truncate(query.limit);
+        Ok(relations)
+    }
+}
+
+// ============================================================================
+// Extraction
+// ============================================================================
+
+/// Extract relations from the conversation's latest exchange in the
+/// background. Does nothing when the agent has no entity memory.
+///
+/// The exchange is the latest user message and the assistant reply before
+/// it, so decisions the user confirms are captured with what they confirm.
+pub fn learn(
+    agent: &AgentSpec,
+    provider: Arc<dyn LLMProvider>,
+    session_id: &str,
+    messages: &[Message],
+) {
+    let Some(config) = agent.memory.as_ref().and_then(|m| m.entities.as_ref()) else {
+        return;
+    };
+    let Some(exchange) = latest_exchange(messages) else {
+        return;
+    };
+    let store = EntityStore::new(&agent.agent_dir.join("memory"));
+    let agent_name = agent.metadata.name.clone();
+    let model = config
+        .extraction_model
+        .clone()
+        .unwrap_or_else(|| agent.model.name.clone());
+    let session_id = session_id.to_string();
+    tokio::spawn(async move {
+        let relations = match extract(provider.as_ref(), &model, &exchange, &session_id).await {
+            Ok(relations) if relations.is_empty() => return,
+            Ok(relations) => relations,
+            Err(e) => {
+                warn!(agent = %agent_name, error = %e, "Failed to extract entities");
+                return;
+            }
+        };
+        match tokio::task::spawn_blocking(move || store.record(relations)).await {
+            Ok(Ok(added)) => debug!(agent = %agent_name, added, "Recorded entity relations"),
+            Ok(Err(e)) => warn!(agent = %agent_name, error = %e, "Failed to record entities"),
+            Err(e) => warn!(agent = %agent_name, error = %e, "Entity recording task failed"),
+        }
+    });
+}
+
+/// Ask `model` for the relations in `exchange`.
+async fn extract(
+    provider: &dyn LLMProvider,
+    model: &str,
+    exchange: &str,
+    session_id: &str,
+) -> Result<Vec<Relation>> {
+    let request = ChatRequest::new(
+        model,
+        vec![
+            Message::text(Role::System, EXTRACTION_PROMPT),
+            Message::text(Role::User, exchange),
+        ],
+        Some(0.0),
+        Some(1024),
+    );
+    let response = provider.chat(request).await?;
+    let reply = response
+        .choices
+        .first()
+        .and_then(|c| c.message.content.as_deref())
+        .unwrap_or_default();
+    let Some(extracted) = extract_json(reply)
+        .and_then(|json| serde_json::from_str::<Vec<ExtractedRelation>>(json).ok())
+    else {
+        return Ok(Vec::new());
+    };
+
+    let now = Utc::now();
+    Ok(extracted
+        .into_iter()
+        .filter_map(|r| {
+            let blank = |s: &str| s.trim().is_empty();
+            if blank(&r.subject) || blank(&r.relation) || blank(&r.object) {
+                return None;
+            }
+            Some(Relation {
+                subject: r.subject.trim().to_string(),
+                subject_type: r.subject_type.filter(|t| !blank(t)),
+                relation: r.relation.trim().to_string(),
+                object: r.object.trim().to_string(),
+                object_type: r.object_type.filter(|t| !blank(t)),
+                session_id: Some(session_id.to_string()),
+                recorded_at: now,
+            })
+        })
+        .collect())
+}
+
+/// The latest user message, preceded by the assistant message before it.
+fn latest_exchange(messages: &[Message]) -> Option<String> {
+    let user_index = messages.iter().rposition(|m| m.role == Role::User)?;
+    let user = messages[user_index].content.as_deref()?;
+    let assistant = messages[..user_index]
+        .iter()
+        .rev()
+        .find(|m| m.role == Role::Assistant)
+        .and_then(|m| m.content.as_deref());
+    Some(match assistant {
+        Some(assistant) => format!("Assistant: {assistant}\n\nUser: {user}"),
+        None => format!("User: {user}"),
+    })
+}
+
+// ============================================================================
+// Private Types
+// ============================================================================
+
+/// A relation as the extraction model returns it.
+#[derive(Debug, Deserialize)]
+struct ExtractedRelation {
+    subject: String,
+    #[serde(default)]
+    subject_type: Option<String>,
+    relation: String,
+    object: String,
+    #[serde(default)]
+    object_type: Option<String>,
+}
+
+// ============================================================================
+// Tests
+// ============================================================================
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::llm::MockProvider;
+    use tempfile::TempDir;
+
+    fn relation(subject: &str, relation: &str, object: &str, days_ago: i64) -> Relation {
+        Relation {
+            subject: subject.to_string(),
+            subject_type: None,
+            relation: relation.to_string(),
+            object: object.to_string(),
+            object_type: None,
+            session_id: None,
+            recorded_at: Utc::now() - chrono::Duration::days(days_ago),
+        }
+    }
+
+    #[test]
+    fn record_skips_known_relations() {
+        let temp = TempDir::new().unwrap();
+        let store = EntityStore::new(temp.path());
+
+        let added = store
+            .record(vec![relation("Apollo", "will use", "Postgres", 0)])
+            .unwrap();
+        assert_eq!(added, 1);
+        let added = store
+            .record(vec![
+                relation("apollo", "Will use", "postgres", 0),
+                relation("Apollo", "launches on", "2026-11-01", 0),
+            ])
+            .unwrap();
+        assert_eq!(added, 1);
+        assert_eq!(store.load().unwrap().len(), 2);
+    }
+
+    #[test]
+    fn query_filters_newest_first() {
+        let temp = TempDir::new().unwrap();
+        let store = EntityStore::new(temp.path());
+        store
+            .record(vec![
+                relation("Apollo", "will use", "MySQL", 30),
+                relation("Apollo", "will use", "Postgres", 5),
+                relation("Alice", "owns", "Apollo", 2),
+                relation("Bob", "owns", "Zeus", 1),
+            ])
+            .unwrap();
+
+        let found = store
+            .query(&EntityQuery {
+                entity: Some("apollo".to_string()),
+                limit: 10,
+                ..Default::default()
+            })
+            .unwrap();
+        let objects: Vec<_> = found.iter().map(|r| r.object.as_str()).collect();
+        assert_eq!(objects, ["Apollo", "Postgres", "MySQL"]);
+
+        let found = store
+            .query(&EntityQuery {
+                relation: Some("use".to_string()),
+                since: Some(Utc::now() - chrono::Duration::days(7)),
+                limit: 10,
+                ..Default::default()
+            })
+            .unwrap();
+        assert_eq!(found.len(), 1);
+        assert_eq!(found[0].object, "Postgres");
+
+        let found = store
+            .query(&EntityQuery {
+                limit: 1,
+                ..Default::default()
+            })
+            .unwrap();
+        assert_eq!(found[0].subject, "Bob");
+    }
+
+    #[tokio::test]
+    async fn extract_parses_relations() {
+        // The mock provider echoes the exchange, so it "extracts" these.
+        let exchange = r#"[{"subject": "Apollo", "subject_type": "project", "relation": "will use", "object": "Postgres"}, {"subject": " ", "relation": "x", "object": "y"}]"#;
+        let relations = extract(&MockProvider, "echo", exchange, "session_1")
+            .await
+            .unwrap();
+        assert_eq!(relations.len(), 1);
+        assert_eq!(relations[0].subject_type.as_deref(), Some("project"));
+        assert_eq!(relations[0].session_id.as_deref(), Some("session_1"));
+
+        let relations = extract(&MockProvider, "echo", "hello", "session_1")
+            .await
+            .unwrap();
+        assert!(relations.is_empty());
+    }
+
+    #[test]
+    fn latest_exchange_includes_previous_reply() {
+        let messages = vec![
+            Message::text(Role::User, "Postgres or MySQL?"),
+            Message::text(Role::Assistant, "I'd pick Postgres."),
+            Message::text(Role::User, "Agreed, let's go with it."),
+        ];
+        assert_eq!(
+            latest_exchange(&messages).unwrap(),
+            "Assistant: I'd pick Postgres.\n\nUser: Agreed, let's go with it."
+        );
+        assert_eq!(
+            latest_exchange(&messages[..1]).unwrap(),
+            "User: Postgres or MySQL?"
+        );
+    }
+}
@@ -4,6 +4,9 @@
 //! - `{world_dir}/*.md` — Shared facts (all agents see)
 //! - `{agent_memory_dir}/MEMORY.md` — Agent's curated long-term memory
 //! - `{agent_memory_dir}/daily/*.md` — Agent's daily experiences (append-only)
+//! - `{agent_memory_dir}/entities.jsonl` — Extracted entity relations (see [`entities`])
+
+pub mod entities;
 
 use std::path::PathBuf;
 
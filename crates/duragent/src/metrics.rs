@@ -0,0 +1,264 @@
+//! Prometheus metrics.
+//!
+//! HTTP requests are counted as they complete. Everything else is read from
+//! server state when `/metrics` is scraped. Metric names are constants here
+//! so the dashboards and alert rules from `duragent dashboards export` stay
+//! in step with what the server exposes.
+
+use std::collections::BTreeMap;
+use std::fmt::Write;
+use std::sync::atomic::{AtomicU64, Ordering};
+use std::sync::{Arc, Mutex};
+use std::time::Duration;
+
+// ============================================================================
+// Metric Names
+// ============================================================================
+
+pub const BUILD_INFO: &str = "duragent_build_info";
+pub const HTTP_REQUESTS_TOTAL: &str = "duragent_http_requests_total";
+pub const HTTP_REQUEST_DURATION_SECONDS: &str = "duragent_http_request_duration_seconds";
+pub const HTTP_REQUESTS_IN_FLIGHT: &str = "duragent_http_requests_in_flight";
+pub const AGENTS_LOADED: &str = "duragent_agents_loaded";
+pub const SESSIONS_ACTIVE: &str = "duragent_sessions_active";
+pub const RUNS_RUNNING: &str = "duragent_runs_running";
+pub const RUNS_QUEUED: &str = "duragent_runs_queued";
+pub const RUN_ERRORS_TOTAL: &str = "duragent_run_errors_total";
+pub const DEPENDENCY_UP: &str = "duragent_dependency_up";
+pub const DEPENDENCY_CHECK_DURATION_SECONDS: &str = "duragent_dependency_check_duration_seconds";
+pub const DEPENDENCY_AVAILABILITY: &str = "duragent_dependency_availability";
+pub const DEPENDENCY_ERROR_BUDGET_REMAINING: &str = "duragent_dependency_error_budget_remaining";
+pub const SLO_BURN_RATE: &str = "duragent_slo_burn_rate";
+pub const SLO_BURN_RATE_THRESHOLD: &str = "duragent_slo_burn_rate_threshold";
+pub const SLO_ALERTING: &str = "duragent_slo_alerting";
+
+/// Upper bounds of the HTTP request duration histogram, in seconds.
+pub const DURATION_BUCKETS: &[f64] = &[
+    0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1.0, 2.5, 5.0, 10.0, 30.0, 60.0,
+];
+
+// ============================================================================
+// HttpMetrics
+// ============================================================================
+
+#[derive(Default)]
+struct RequestStats {
+    /// Per bucket in `DURATION_BUCKETS`, not cumulative.
+    buckets: [u64; DURATION_BUCKETS.len()],
+    count: u64,
+    sum_seconds: f64,
+}
+
+/// Request counts and durations by method, route template, and status, and
+/// the number of requests being handled.
+#[derive(Clone, Default)]
+pub struct HttpMetrics {
+    requests: Arc<Mutex<BTreeMap<(String, String, u16), RequestStats>>>,
+    in_flight: Arc<AtomicU64>,
+}
+
+/// A request being handled; counted in flight until dropped, so requests
+/// abandoned by the client are not counted forever.
+pub struct InFlight(Arc<AtomicU64>);
+
+impl Drop for InFlight {
+    fn drop(&mut self) {
+        self.0.fetch_sub(1, Ordering::Relaxed);
+    }
+}
+
+impl HttpMetrics {
+    #[must_use]
+    pub fn new() -> Self {
+        Self::default()
+    }
+
+    /// Count one request as in flight until the returned guard is dropped.
+    pub fn start(&self) -> InFlight {
+        self.in_flight.fetch_add(1, Ordering::Relaxed);
+        InFlight(self.in_flight.clone())
+    }
+
+    /// Count one finished request. `route` should be a route template, not
+    /// a raw path, to keep label cardinality bounded.
+    pub fn record(&self, method: &str, route: &str, status: u16, duration: Duration) {
+        let seconds = duration.as_secs_f64();
+        let mut requests = self.requests.lock().expect("mutex poisoned");
+        let stats = requests
+            .entry((method.to_string(), route.to_string(), status))
+            .or_default();
+        if let Some(i) = DURATION_BUCKETS.iter().position(|&le| seconds <= le) {
+            stats.buckets[i] += 1;
+        }
+        stats.count += 1;
+        stats.sum_seconds += seconds;
+    }
+
+    /// Write the request counter, duration histogram, and in-flight gauge.
+    pub fn render(&self, out: &mut Exposition) {
+        out.gauge(
+            HTTP_REQUESTS_IN_FLIGHT,
+            "HTTP requests being handled.",
+            self.in_flight.load(Ordering::Relaxed) as f64,
+        );
+
+        let requests = self.requests.lock().expect("mutex poisoned");
+
+        out.header(
+            HTTP_REQUESTS_TOTAL,
+            "counter",
+            "HTTP requests by method, route, and status.",
+        );
+        for ((method, route, status), stats) in requests.iter() {
+            let status = status.to_string();
+            let labels = [
+                ("method", method.as_str()),
+                ("route", route.as_str()),
+                ("status", status.as_str()),
+            ];
+            out.sample(HTTP_REQUESTS_TOTAL, &labels, stats.count as f64);
+        }
+
+        out.header(
+            HTTP_REQUEST_DURATION_SECONDS,
+            "histogram",
+            "HTTP request duration by method and route.",
+        );
+        let mut by_route: BTreeMap<(&str, &str), RequestStats> = BTreeMap::new();
+        for ((method, route, _), stats) in requests.iter() {
+            let merged = by_route
+                .entry((method.as_str(), route.as_str()))
+                .or_default();
+            for (total, count) in merged.buckets.iter_mut().zip(stats.buckets) {
+                *total += count;
+            }
+            merged.count += stats.count;
+            merged.sum_seconds += stats.sum_seconds;
+        }
+        let bucket = format!("{HTTP_REQUEST_DURATION_SECONDS}_bucket");
+        for ((method, route), stats) in &by_route {
+            let mut cumulative = 0;
+            for (le, count) in DURATION_BUCKETS.iter().zip(stats.buckets) {
+                cumulative += count;
+                let le = le.to_string();
+                let labels = [("method", *method), ("route", *route), ("le", le.as_str())];
+                out.sample(&bucket, &labels, cumulative as f64);
+            }
+            let labels = [("method", *method), ("route", *route), ("le", "+Inf")];
+            out.sample(&bucket, &labels, stats.count as f64);
+            let labels = [("method", *method), ("route", *route)];
+            out.sample(
+                &format!("{HTTP_REQUEST_DURATION_SECONDS}_sum"),
+                &labels,
+                stats.sum_seconds,
+            );
+            out.sample(
+                &format!("{HTTP_REQUEST_DURATION_SECONDS}_count"),
+                &labels,
+                stats.count as f64,
+            );
+        }
+    }
+}
+
+// ============================================================================
+// Exposition
+// ============================================================================
+
+/// Builder for the Prometheus text exposition format.
+#[derive(Default)]
+pub struct Exposition {
+    out: String,
+}
+
+impl Exposition {
+    #[must_use]
+    pub fn new() -> Self {
+        Self::default()
+    }
+
+    /// Write the `# HELP` and `# TYPE` lines for a metric.
+    pub fn header(&mut self, name: &str, kind: &str, help: &str) {
+        let _ = writeln!(self.out, "# HELP {name} {help}");
+        let _ = writeln!(self.out, "# TYPE {name} {kind}");
+    }
+
+    /// Write one sample line.
+    pub fn sample(&mut self, name: &str, labels: &[(&str, &str)], value: f64) {
+        self.out.push_str(name);
+        if !labels.is_empty() {
+            self.out.push('{');
+            for (i, (key, value)) in labels.iter().enumerate() {
+                if i > 0 {
+                    self.out.push(',');
+                }
+                let _ = write!(self.out, "{key}=\"{}\"", escape_label(value));
+            }
+            self.out.push('}');
+        }
+        let _ = writeln!(self.out, " {value}");
+    }
+
+    /// Write a metric with a single unlabeled sample.
+    pub fn gauge(&mut self, name: &str, help: &str, value: f64) {
+        self.header(name, "gauge", help);
+        self.sample(name, &[], value);
+    }
+
+    #[must_use]
+    pub fn finish(self) -> String {
+        self.out
+    }
+}
+
+fn escape_label(value: &str) -> String {
+    value
+        .replace('\\', "\\\\")
+        .replace('"', "\\\"")
+        .replace('\n', "\\n")
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn histogram_buckets_are_cumulative() {
+        let metrics = HttpMetrics::new();
+        let route = "/api/v1/sessions";
+        metrics.record("GET", route, 200, Duration::from_millis(3));
+        metrics.record("GET", route, 200, Duration::from_millis(80));
+        metrics.record("GET", route, 500, Duration::from_secs(120));
+
+        let mut out = Exposition::new();
+        metrics.render(&mut out);
+        let text = out.finish();
+
+        assert!(text.contains(
+            r#"duragent_http_requests_total{method="GET",route="/api/v1/sessions",status="200"} 2"#
+        ));
+        assert!(text.contains(
+            r#"duragent_http_requests_total{method="GET",route="/api/v1/sessions",status="500"} 1"#
+        ));
+        assert!(text.contains(
+            r#"duragent_http_request_duration_seconds_bucket{method="GET",route="/api/v1/sessions",le="0.005"} 1"#
+        ));
+        assert!(text.contains(
+            r#"duragent_http_request_duration_seconds_bucket{method="GET",route="/api/v1/sessions",le="0.1"} 2"#
+        ));
+        assert!(text.contains(
+            r#"duragent_http_request_duration_seconds_bucket{method="GET",route="/api/v1/sessions",le="60"} 2"#
+        ));
+        assert!(text.contains(
+            r#"duragent_http_request_duration_seconds_bucket{method="GET",route="/api/v1/sessions",le="+Inf"} 3"#
+        ));
+        assert!(text.contains("# TYPE duragent_http_request_duration_seconds histogram"));
+    }
+
+    #[test]
+    fn label_values_are_escaped() {
+        let mut out = Exposition::new();
+        out.sample("m", &[("l", "a\"b\\c")], 1.0);
+        assert_eq!(out.finish(), "m{l=\"a\\\"b\\\\c\"} 1\n");
+    }
+}
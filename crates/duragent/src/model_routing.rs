@@ -0,0 +1,196 @@
+//! Per-message model routing.
+//!
+//! An agent's manifest can send each message to a different model, picked
+//! by the first rule whose conditions all hold, to keep cheap messages on a
+//! cheap model:
+//!
+//! ```yaml
+//! model_routing:
+//!   classifier:
+//!     labels: [simple, complex]
+//!     instructions: complex means multi-step reasoning or code
+//!   rules:
+//!     - when: { max_input_chars: 200, classifier: [simple] }
+//!       model: { provider: openai, name: gpt-4o-mini }
+//!     - when: { languages: [ja, zh] }
+//!       model: { provider: anthropic, name: claude-sonnet-4-5 }
+//! ```
+//!
+//! Messages no rule matches use the agent's `model`. The classifier only
+//! runs when a rule up to the first match needs its label; a classifier
+//! failure leaves the label unset, so those rules don't match. Rules whose
+//! model the registry says can't serve the agent, such as one without tool
+//! calling for an agent with tools, are skipped.
+
+use std::sync::Arc;
+
+use tracing::{debug, warn};
+
+use crate::agent::{AgentSpec, RouteConditions, RoutingClassifierConfig};
+use crate::language;
+use crate::llm::{ChatRequest, Message, ProviderRegistry, Role};
+use crate::models::ModelRegistry;
+
+/// The agent to run `input` with: `agent` itself, or a copy with the model
+/// picked by its routing rules.
+pub async fn route(
+    agent: Arc<AgentSpec>,
+    providers: &ProviderRegistry,
+    models: &ModelRegistry,
+    input: &str,
+) -> Arc<AgentSpec> {
+    let Some(routing) = &agent.model_routing else {
+        return agent;
+    };
+
+    let chars = input.chars().count();
+    let detected = language::detect(input);
+    // Computed on first use; `None` inside means the classifier had no answer.
+    let mut label: Option<Option<String>> = None;
+
+    for (i, rule) in routing.rules.iter().enumerate() {
+        if !matches_input(&rule.when, chars, detected) {
+            continue;
+        }
+        if !models.supports(&agent, &rule.model.name) {
+            debug!(
+                agent = %agent.metadata.name,
+                rule = i,
+                model = %rule.model.name,
+                "Skipped routing rule whose model lacks required capabilities"
+            );
+            continue;
+        }
+        if !rule.when.classifier.is_empty() {
+            if label.is_none() {
+                label = Some(match &routing.classifier {
+                    Some(classifier) => classify(&agent, classifier, providers, input).await,
+                    None => None,
+                });
+            }
+            let Some(Some(label)) = &label else {
+                continue;
+            };
+            if !rule.when.classifier.contains(label) {
+                continue;
+            }
+        }
+
+        debug!(
+            agent = %agent.metadata.name,
+            rule = i,
+            model = %rule.model.name,
+            "Routed message to model"
+        );
+        let mut routed = (*agent).clone();
+        routed.model = rule.model.clone();
+        return Arc::new(routed);
+    }
+    agent
+}
+
+/// Length and language conditions.
+pub(crate) fn matches_input(when: &RouteConditions, chars: usize, language: Option<&str>) -> bool {
+    when.min_input_chars.is_none_or(|min| chars >= min)
+        && when.max_input_chars.is_none_or(|max| chars <= max)
+        && (when.languages.is_empty()
+            || language.is_some_and(|l| when.languages.iter().any(|w| w == l)))
+}
+
+/// Ask the classifier model for one of its labels.
+pub(crate) async fn classify(
+    agent: &AgentSpec,
+    classifier: &RoutingClassifierConfig,
+    providers: &ProviderRegistry,
+    input: &str,
+) -> Option<String> {
+    let Some(provider) = providers
+        .get(&agent.model.provider, agent.model.base_url.as_deref())
+        .await
+    else {
+        warn!(agent = %agent.metadata.name, "Routing classifier provider not configured");
+        return None;
+    };
+    let model = classifier.model.as_deref().unwrap_or(&agent.model.name);
+
+    let mut prompt = format!(
+        "Classify the user's message as exactly one of: {}. Reply with the label only.",
+        classifier.labels.join(", ")
+    );
+    if let Some(instructions) = &classifier.instructions {
+        prompt.push_str("\n\n");
+        prompt.push_str(instructions);
+    }
+    let request = ChatRequest::new(
+        model,
+        vec![
+            Message::text(Role::System, prompt),
+            Message::text(Role::User, input),
+        ],
+        Some(0.0),
+        Some(16),
+    );
+
+    let reply = match provider.chat(request).await {
+        Ok(response) => response
+            .choices
+            .into_iter()
+            .next()
+            .and_then(|c| c.message.content)
+            .unwrap_or_default(),
+        Err(e) => {
+            warn!(agent = %agent.metadata.name, error = %e, "Routing classifier failed");
+            return None;
+        }
+    };
+    parse_label(&reply, &classifier.labels)
+}
+
+/// The label the reply names, ignoring case, punctuation, and extra words.
+/// Prefers an exact match, then the longest label the reply contains.
+fn parse_label(reply: &str, labels: &[String]) -> Option<String> {
+    let reply = reply
+        .trim()
+        .trim_matches(|c: char| !c.is_alphanumeric())
+        .to_lowercase();
+    labels
+        .iter()
+        .find(|l| l.to_lowercase() == reply)
+        .or_else(|| {
+            labels
+                .iter()
+                .filter(|l| reply.contains(&l.to_lowercase()))
+                .max_by_key(|l| l.len())
+        })
+        .cloned()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn input_conditions_must_all_hold() {
+        let short_english = RouteConditions {
+            max_input_chars: Some(20),
+            languages: vec!["en".to_string()],
+            ..Default::default()
+        };
+        assert!(matches_input(&short_english, 12, Some("en")));
+        assert!(!matches_input(&short_english, 21, Some("en")));
+        assert!(!matches_input(&short_english, 12, Some("de")));
+        assert!(!matches_input(&short_english, 12, None));
+        assert!(matches_input(&RouteConditions::default(), 0, None));
+    }
+
+    #[test]
+    fn labels_are_parsed_leniently() {
+        let labels = vec!["simple".to_string(), "very complex".to_string()];
+        assert_eq!(parse_label(" Simple.", &labels).as_deref(), Some("simple"));
+        assert_eq!(
+            parse_label("Label: very complex", &labels).as_deref(),
+            Some("very complex")
+        );
+        assert_eq!(parse_label("unsure", &labels), None);
+    }
+}
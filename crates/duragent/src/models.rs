@@ -0,0 +1,586 @@
+//! Model capability registry.
+//!
+//! Knows, per model, the context window, whether it takes images, calls
+//! tools, or has a JSON mode, and what it costs. Models are matched by name
+//! substring, case-insensitively, so `claude` covers every Claude model and
+//! `anthropic/claude-sonnet-4` on OpenRouter alike; the longest matching
+//! pattern wins.
+//!
+//! A built-in table covers well-known model families. Overrides from
+//! `models.yaml` in the workspace, or `PUT /api/v1/models/{name}`, are
+//! layered on top field by field, so an override can add pricing without
+//! restating the context window:
+//!
+//! ```yaml
+//! models:
+//!   claude-sonnet-4-5:
+//!     pricing: { input_per_mtok: 3, output_per_mtok: 15 }
+//!   my-local-llama:
+//!     context_window: 32768
+//!     tool_calling: false
+//! ```
+//!
+//! Agents are checked against the registry when loaded, model routing skips
+//! models that can't serve the agent, and usage reports estimate cost from
+//! the pricing.
+
+use std::collections::BTreeMap;
+use std::sync::{Arc, RwLock};
+
+use serde::{Deserialize, Serialize};
+use tracing::warn;
+
+use crate::agent::{AgentSpec, AgentStore, ModelConfig};
+use crate::api::{ModelCapabilities, ModelPricing};
+use crate::store::{ModelStore, StorageResult};
+
+/// Context window assumed for models the registry doesn't know.
+pub const DEFAULT_CONTEXT_WINDOW: u32 = 128_000;
+
+// ============================================================================
+// Types
+// ============================================================================
+
+/// Capabilities set for model names containing a pattern. Unset fields fall
+/// through to less specific entries.
+#[derive(Debug, Clone, Default, PartialEq, Serialize, Deserialize)]
+pub struct ModelOverride {
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub context_window: Option<u32>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub max_output_tokens: Option<u32>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub vision: Option<bool>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub tool_calling: Option<bool>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub json_mode: Option<bool>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub pricing: Option<ModelPricing>,
+}
+
+impl ModelOverride {
+    fn apply(&self, capabilities: &mut ModelCapabilities) {
+        if let Some(v) = self.context_window {
+            capabilities.context_window = v;
+        }
+        if let Some(v) = self.max_output_tokens {
+            capabilities.max_output_tokens = Some(v);
+        }
+        if let Some(v) = self.vision {
+            capabilities.vision = v;
+        }
+        if let Some(v) = self.tool_calling {
+            capabilities.tool_calling = v;
+        }
+        if let Some(v) = self.json_mode {
+            capabilities.json_mode = v;
+        }
+        if let Some(v) = self.pricing {
+            capabilities.pricing = Some(v);
+        }
+    }
+}
+
+/// Where a registry entry comes from.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum ModelSource {
+    Builtin,
+    Override,
+}
+
+impl ModelSource {
+    pub fn as_str(self) -> &'static str {
+        match self {
+            Self::Builtin => "builtin",
+            Self::Override => "override",
+        }
+    }
+}
+
+/// Capabilities a model name resolves to.
+#[derive(Debug, Clone, PartialEq)]
+pub struct ResolvedModel {
+    pub capabilities: ModelCapabilities,
+    /// Patterns that matched, most specific first. Empty for unknown models.
+    pub matched: Vec<String>,
+}
+
+// ============================================================================
+// Built-in Table
+// ============================================================================
+
+struct Builtin {
+    pattern: &'static str,
+    context_window: u32,
+    vision: bool,
+    json_mode: bool,
+    /// Input and output price per million tokens.
+    pricing: Option<(f64, f64)>,
+}
+
+const fn builtin(
+    pattern: &'static str,
+    context_window: u32,
+    vision: bool,
+    json_mode: bool,
+    pricing: Option<(f64, f64)>,
+) -> Builtin {
+    Builtin {
+        pattern,
+        context_window,
+        vision,
+        json_mode,
+        pricing,
+    }
+}
+
+/// Well-known model families. Every one of them calls tools.
+///
+/// Context windows sourced from OpenRouter model pages (Feb 2026).
+const BUILTINS: &[Builtin] = &[
+    builtin("claude", 200_000, true, false, None),
+    builtin("claude-opus-4", 200_000, true, false, Some((15.0, 75.0))),
+    builtin("claude-sonnet-4", 200_000, true, false, Some((3.0, 15.0))),
+    builtin("claude-haiku-4", 200_000, true, false, Some((1.0, 5.0))),
+    builtin("claude-3-5-haiku", 200_000, true, false, Some((0.8, 4.0))),
+    builtin("gpt-5", 400_000, true, true, Some((1.25, 10.0))),
+    builtin("gpt-5-mini", 400_000, true, true, Some((0.25, 2.0))),
+    builtin("gpt-5-nano", 400_000, true, true, Some((0.05, 0.4))),
+    builtin("gpt-4.1", 1_000_000, true, true, Some((2.0, 8.0))),
+    builtin("gpt-4.1-mini", 1_000_000, true, true, Some((0.4, 1.6))),
+    builtin("gpt-4.1-nano", 1_000_000, true, true, Some((0.1, 0.4))),
+    builtin("gpt-4o", 128_000, true, true, Some((2.5, 10.0))),
+    builtin("gpt-4o-mini", 128_000, true, true, Some((0.15, 0.6))),
+    builtin("gpt-4-turbo", 128_000, true, true, None),
+    builtin("gpt-4", 128_000, false, true, None),
+    builtin("gemini", 1_000_000, true, true, None),
+    // Grok 4.x (2M); Grok 3.x and older (131K)
+    builtin("grok-4", 2_000_000, true, true, None),
+    builtin("grok", 131_072, false, true, None),
+    // DeepSeek V3.x (163K); older (128K)
+    builtin("deepseek-v3", 163_840, false, true, None),
+    builtin("deepseek-chat-v3", 163_840, false, true, None),
+    builtin("deepseek", 128_000, false, true, None),
+    builtin("qwen3", 131_072, false, true, None),
+    builtin("qwen", 128_000, false, true, None),
+    // Llama 4 (327K — conservative; maverick is 1M, scout is 327K)
+    builtin("llama-4", 327_680, true, false, None),
+    builtin("llama", 128_000, false, false, None),
+    builtin("mistral-large", 262_144, false, true, None),
+    builtin("mistral", 128_000, false, true, None),
+    builtin("mixtral", 128_000, false, true, None),
+];
+
+impl Builtin {
+    fn capabilities(&self) -> ModelCapabilities {
+        ModelCapabilities {
+            context_window: self.context_window,
+            max_output_tokens: None,
+            vision: self.vision,
+            tool_calling: true,
+            json_mode: self.json_mode,
+            pricing: self.pricing.map(|(input, output)| ModelPricing {
+                input_per_mtok: input,
+                output_per_mtok: output,
+            }),
+        }
+    }
+}
+
+/// Capabilities assumed for models nothing matches.
+fn default_capabilities() -> ModelCapabilities {
+    ModelCapabilities {
+        context_window: DEFAULT_CONTEXT_WINDOW,
+        max_output_tokens: None,
+        vision: false,
+        tool_calling: true,
+        json_mode: false,
+        pricing: None,
+    }
+}
+
+/// The most specific built-in entry for `model`.
+fn builtin_for(model: &str) -> Option<&'static Builtin> {
+    let name = model.to_lowercase();
+    BUILTINS
+        .iter()
+        .filter(|b| name.contains(b.pattern))
+        .max_by_key(|b| b.pattern.len())
+}
+
+/// Built-in capabilities for `model`, ignoring overrides.
+pub fn builtin_capabilities(model: &str) -> Option<ModelCapabilities> {
+    builtin_for(model).map(Builtin::capabilities)
+}
+
+// ============================================================================
+// ModelRegistry
+// ============================================================================
+
+/// Built-in model capabilities plus overrides, kept in memory.
+#[derive(Clone)]
+pub struct ModelRegistry {
+    store: Arc<dyn ModelStore>,
+    overrides: Arc<RwLock<BTreeMap<String, ModelOverride>>>,
+}
+
+impl ModelRegistry {
+    /// Load overrides from `store`. Patterns are matched lowercase.
+    pub async fn load(store: Arc<dyn ModelStore>) -> StorageResult<Self> {
+        let overrides = store
+            .load()
+            .await?
+            .into_iter()
+            .map(|(name, o)| (name.to_lowercase(), o))
+            .collect();
+        Ok(Self {
+            store,
+            overrides: Arc::new(RwLock::new(overrides)),
+        })
+    }
+
+    /// Capabilities for `model`: the most specific built-in entry, then
+    /// matching overrides from least to most specific.
+    pub fn resolve(&self, model: &str) -> ResolvedModel {
+        let name = model.to_lowercase();
+        let builtin = builtin_for(&name);
+        let mut capabilities = builtin
+            .map(Builtin::capabilities)
+            .unwrap_or_else(default_capabilities);
+
+        let overrides = self.overrides.read().unwrap();
+        let mut matching: Vec<(&String, &ModelOverride)> = overrides
+            .iter()
+            .filter(|(pattern, _)| name.contains(pattern.as_str()))
+            .collect();
+        matching.sort_by_key(|(pattern, _)| pattern.len());
+        for (_, o) in &matching {
+            o.apply(&mut capabilities);
+        }
+
+        let mut matched: Vec<String> = matching.iter().map(|(p, _)| (*p).clone()).collect();
+        if let Some(b) = builtin
+            && !matched.iter().any(|p| p == b.pattern)
+        {
+            matched.push(b.pattern.to_string());
+        }
+        matched.sort_by_key(|p| std::cmp::Reverse(p.len()));
+        ResolvedModel {
+            capabilities,
+            matched,
+        }
+    }
+
+    pub fn capabilities(&self, model: &str) -> ModelCapabilities {
+        self.resolve(model).capabilities
+    }
+
+    /// Built-in entries and overrides, sorted by name. Each shows what its
+    /// own name resolves to.
+    pub fn list(&self) -> Vec<(String, ModelSource, ModelCapabilities)> {
+        let overrides: Vec<String> = self.overrides.read().unwrap().keys().cloned().collect();
+        let mut entries: Vec<_> = BUILTINS
+            .iter()
+            .filter(|b| !overrides.iter().any(|o| o == b.pattern))
+            .map(|b| (b.pattern.to_string(), ModelSource::Builtin))
+            .chain(overrides.into_iter().map(|o| (o, ModelSource::Override)))
+            .map(|(name, source)| {
+                let capabilities = self.capabilities(&name);
+                (name, source, capabilities)
+            })
+            .collect();
+        entries.sort_by(|a, b| a.0.cmp(&b.0));
+        entries
+    }
+
+    pub fn get_override(&self, pattern: &str) -> Option<ModelOverride> {
+        self.overrides
+            .read()
+            .unwrap()
+            .get(&pattern.to_lowercase())
+            .cloned()
+    }
+
+    /// Create or replace the override for `pattern`. Returns whether it is
+    /// new.
+    pub async fn put(&self, pattern: &str, entry: ModelOverride) -> StorageResult<bool> {
+        let mut overrides = self.overrides.read().unwrap().clone();
+        let created = overrides.insert(pattern.to_lowercase(), entry).is_none();
+        self.store.save(&overrides).await?;
+        *self.overrides.write().unwrap() = overrides;
+        Ok(created)
+    }
+
+    /// Remove the override for `pattern`. Returns whether it existed.
+    pub async fn delete(&self, pattern: &str) -> StorageResult<bool> {
+        let mut overrides = self.overrides.read().unwrap().clone();
+        if overrides.remove(&pattern.to_lowercase()).is_none() {
+            return Ok(false);
+        }
+        self.store.save(&overrides).await?;
+        *self.overrides.write().unwrap() = overrides;
+        Ok(true)
+    }
+
+    /// Estimated cost in US dollars of `prompt_tokens` in and
+    /// `completion_tokens` out, or `None` without pricing for `model`.
+    pub fn estimate_cost(
+        &self,
+        model: &str,
+        prompt_tokens: u64,
+        completion_tokens: u64,
+    ) -> Option<f64> {
+        let pricing = self.capabilities(model).pricing?;
+        Some(
+            (prompt_tokens as f64 * pricing.input_per_mtok
+                + completion_tokens as f64 * pricing.output_per_mtok)
+                / 1_000_000.0,
+        )
+    }
+
+    /// Whether `model` can serve `agent`: it must call tools when the agent
+    /// has any, or plans its runs.
+    pub fn supports(&self, agent: &AgentSpec, model: &str) -> bool {
+        (agent.tools.is_empty() && agent.planning.is_none())
+            || self.capabilities(model).tool_calling
+    }
+
+    /// Problems with the models `agent` is configured to use.
+    pub fn check(&self, agent: &AgentSpec) -> Vec<String> {
+        let mut models = vec![("model".to_string(), &agent.model)];
+        if let Some(routing) = &agent.model_routing {
+            models.extend(
+                routing
+                    .rules
+                    .iter()
+                    .enumerate()
+                    .map(|(i, rule)| (format!("model_routing.rules[{i}].model"), &rule.model)),
+            );
+        }
+        if let Some(best_of) = &agent.best_of {
+            models.extend(
+                best_of
+                    .models
+                    .iter()
+                    .enumerate()
+                    .map(|(i, model)| (format!("best_of.models[{i}]"), model)),
+            );
+        }
+        if let Some(ensemble) = &agent.ensemble {
+            models.extend(
+                ensemble
+                    .members
+                    .iter()
+                    .enumerate()
+                    .filter_map(|(i, member)| {
+                        let model = member.model.as_ref()?;
+                        Some((format!("ensemble.members[{i}].model"), model))
+                    }),
+            );
+            if let Some(synthesizer) = &ensemble.synthesizer {
+                models.push(("ensemble.synthesizer".to_string(), synthesizer));
+            }
+        }
+        models
+            .into_iter()
+            .flat_map(|(field, model)| self.check_model(agent, &field, model))
+            .collect()
+    }
+
+    fn check_model(&self, agent: &AgentSpec, field: &str, model: &ModelConfig) -> Vec<String> {
+        let capabilities = self.capabilities(&model.name);
+        let mut issues = Vec::new();
+        if !agent.tools.is_empty() && !capabilities.tool_calling {
+            issues.push(format!(
+                "{field} '{}' does not support tool calling, but the agent has tools",
+                model.name
+            ));
+        } else if agent.planning.is_some() && !capabilities.tool_calling {
+            issues.push(format!(
+                "{field} '{}' does not support tool calling, which planning needs",
+                model.name
+            ));
+        }
+        if let Some(max) = model.max_input_tokens
+            && max > capabilities.context_window
+        {
+            issues.push(format!(
+                "{field}.max_input_tokens {max} exceeds the {}-token context window of '{}'",
+                capabilities.context_window, model.name
+            ));
+        }
+        if let (Some(max), Some(limit)) = (model.max_output_tokens, capabilities.max_output_tokens)
+            && max > limit
+        {
+            issues.push(format!(
+                "{field}.max_output_tokens {max} exceeds the {limit}-token output limit of '{}'",
+                model.name
+            ));
+        }
+        issues
+    }
+
+    /// Log the problems [`check`](Self::check) finds in `agent`.
+    pub fn warn_issues(&self, agent: &AgentSpec) {
+        for issue in self.check(agent) {
+            warn!(agent = %agent.metadata.name, issue = %issue, "Agent model does not match its capabilities");
+        }
+    }
+
+    /// Log the problems [`check`](Self::check) finds in every loaded agent.
+    pub fn warn_agent_issues(&self, agents: &AgentStore) {
+        for (_, agent) in agents.snapshot() {
+            self.warn_issues(&agent);
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::store::file::FileModelStore;
+
+    async fn registry(dir: &tempfile::TempDir) -> ModelRegistry {
+        ModelRegistry::load(Arc::new(FileModelStore::new(
+            dir.path().join("models.yaml"),
+        )))
+        .await
+        .unwrap()
+    }
+
+    fn agent(yaml_model: &str, tools: bool) -> AgentSpec {
+        let tools = if tools {
+            "  tools:\n    - type: builtin\n      name: bash\n"
+        } else {
+            ""
+        };
+        let yaml = format!(
+            "apiVersion: duragent/v1alpha1\nkind: Agent\nmetadata:\n  name: a\nspec:\n  model:\n{yaml_model}{tools}"
+        );
+        crate::agent::parse_agent_yaml(
+            &yaml,
+            Default::default(),
+            Vec::new(),
+            Default::default(),
+            std::path::PathBuf::from("/tmp/a"),
+            None,
+        )
+        .unwrap()
+    }
+
+    #[test]
+    fn builtin_prefers_longest_pattern() {
+        let caps = builtin_capabilities("openai/gpt-4.1-mini").unwrap();
+        assert_eq!(caps.context_window, 1_000_000);
+        assert_eq!(caps.pricing.unwrap().input_per_mtok, 0.4);
+        assert_eq!(
+            builtin_capabilities("gpt-4o-mini").unwrap().context_window,
+            128_000
+        );
+        assert!(builtin_capabilities("my-finetune").is_none());
+    }
+
+    #[tokio::test]
+    async fn overrides_layer_over_builtins() {
+        let tmp = tempfile::TempDir::new().unwrap();
+        let models = registry(&tmp).await;
+        models
+            .put(
+                "Claude",
+                ModelOverride {
+                    max_output_tokens: Some(64_000),
+                    ..Default::default()
+                },
+            )
+            .await
+            .unwrap();
+        models
+            .put(
+                "claude-sonnet-4-5",
+                ModelOverride {
+                    context_window: Some(1_000_000),
+                    ..Default::default()
+                },
+            )
+            .await
+            .unwrap();
+
+        let resolved = models.resolve("anthropic/claude-sonnet-4-5");
+        assert_eq!(resolved.capabilities.context_window, 1_000_000);
+        assert_eq!(resolved.capabilities.max_output_tokens, Some(64_000));
+        // Pricing still comes from the built-in table.
+        assert_eq!(resolved.capabilities.pricing.unwrap().output_per_mtok, 15.0);
+        assert_eq!(
+            resolved.matched,
+            vec!["claude-sonnet-4-5", "claude-sonnet-4", "claude"]
+        );
+
+        // Overrides survive a reload.
+        let reloaded = registry(&tmp).await;
+        assert_eq!(
+            reloaded.capabilities("claude-sonnet-4-5").context_window,
+            1_000_000
+        );
+        assert!(reloaded.delete("claude-sonnet-4-5").await.unwrap());
+        assert!(!reloaded.delete("claude-sonnet-4-5").await.unwrap());
+        assert_eq!(
+            reloaded.capabilities("claude-sonnet-4-5").context_window,
+            200_000
+        );
+    }
+
+    #[tokio::test]
+    async fn unknown_models_get_defaults() {
+        let tmp = tempfile::TempDir::new().unwrap();
+        let resolved = registry(&tmp).await.resolve("my-finetune");
+        assert_eq!(resolved.capabilities, default_capabilities());
+        assert!(resolved.matched.is_empty());
+    }
+
+    #[tokio::test]
+    async fn estimates_cost_from_pricing() {
+        let tmp = tempfile::TempDir::new().unwrap();
+        let models = registry(&tmp).await;
+        let cost = models.estimate_cost("gpt-4o", 1_000_000, 100_000).unwrap();
+        assert!((cost - 3.5).abs() < 1e-9);
+        assert!(models.estimate_cost("my-finetune", 1, 1).is_none());
+    }
+
+    #[tokio::test]
+    async fn check_flags_capability_mismatches() {
+        let tmp = tempfile::TempDir::new().unwrap();
+        let models = registry(&tmp).await;
+        models
+            .put(
+                "my-llama",
+                ModelOverride {
+                    context_window: Some(8_192),
+                    tool_calling: Some(false),
+                    ..Default::default()
+                },
+            )
+            .await
+            .unwrap();
+
+        let ok = agent(
+            "    provider: openai\n    name: gpt-4o\n    max_input_tokens: 100000\n",
+            true,
+        );
+        assert!(models.check(&ok).is_empty());
+
+        let bad = agent(
+            "    provider: openai\n    name: my-llama\n    max_input_tokens: 16000\n",
+            true,
+        );
+        let issues = models.check(&bad);
+        assert_eq!(issues.len(), 2, "{issues:?}");
+        assert!(issues[0].contains("tool calling"));
+        assert!(issues[1].contains("8192-token context window"));
+        assert!(!models.supports(&bad, "my-llama"));
+        assert!(models.supports(
+            &agent("    provider: openai\n    name: my-llama\n", false),
+            "my-llama"
+        ));
+    }
+}
@@ -0,0 +1,644 @@
+//! Authorization policies.
+//!
+//! Operators write rules in the `authorization.policies` config section as
+//! small boolean expressions, checked on every API request after
+//! authentication:
+//!
+//! ```yaml
+//! authorization:
+//!   policies:
+//!     - name: gpu-agents
+//!       when: 'action == "runs:create" && "gpu" in agent.tags'
+//!       allow: '"ml" in principal.groups'
+//! ```
+//!
+//! A request that a rule's `when` matches is denied unless its `allow`
+//! expression holds. Requests no rule denies are allowed.
+//!
+//! Expressions support string, boolean, and `null` literals, string lists
+//! (`["a", "b"]`), the attributes in [`Attr`], `==`, `!=`, `in` (list
+//! membership or substring), `&&`, `||`, `!`, and parentheses. Non-boolean
+//! values count as false.
+
+use std::fmt;
+use std::sync::Arc;
+
+use thiserror::Error;
+
+use crate::config::{AuthorizationConfig, PolicyConfig};
+
+/// An expression that failed to parse.
+#[derive(Debug, Error, PartialEq, Eq)]
+#[error("{message} at offset {offset}")]
+pub struct ParseError {
+    pub message: String,
+    pub offset: usize,
+}
+
+impl ParseError {
+    fn new(message: impl Into<String>, offset: usize) -> Self {
+        Self {
+            message: message.into(),
+            offset,
+        }
+    }
+}
+
+/// A policy that failed to compile.
+#[derive(Debug, Error)]
+#[error("policy '{name}': invalid `{field}` expression: {source}")]
+pub struct PolicyError {
+    pub name: String,
+    pub field: &'static str,
+    #[source]
+    pub source: ParseError,
+}
+
+// ============================================================================
+// Context
+// ============================================================================
+
+/// Who made a request.
+#[derive(Debug, Clone, Default, PartialEq, Eq)]
+pub struct Principal {
+    /// `token`, `local`, `service_account`, or `user`.
+    pub kind: &'static str,
+    pub name: String,
+    /// Provisioned groups (`user` principals only).
+    pub groups: Vec<String>,
+}
+
+impl fmt::Display for Principal {
+    /// How the principal appears in audit records.
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        match self.kind {
+            "service_account" => write!(f, "service-account:{}", self.name),
+            "user" => write!(f, "user:{}", self.name),
+            _ => f.write_str(&self.name),
+        }
+    }
+}
+
+/// The agent a request acts on.
+#[derive(Debug, Clone, Default, PartialEq, Eq)]
+pub struct AgentTarget {
+    pub name: String,
+    pub tags: Vec<String>,
+    pub project: Option<String>,
+}
+
+/// Everything a policy expression can see about a request.
+#[derive(Debug, Clone, Default)]
+pub struct RequestContext {
+    pub principal: Principal,
+    pub method: String,
+    /// Path relative to `/api/v1`.
+    pub path: String,
+    /// Resource and verb, as in service account scopes. `None` for
+    /// discovery routes.
+    pub resource: Option<&'static str>,
+    pub verb: Option<&'static str>,
+    pub agent: Option<AgentTarget>,
+}
+
+/// An attribute of [`RequestContext`] that expressions can reference.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum Attr {
+    /// `principal.kind`
+    PrincipalKind,
+    /// `principal.name`
+    PrincipalName,
+    /// `principal.groups`
+    PrincipalGroups,
+    /// `request.method`
+    RequestMethod,
+    /// `request.path`
+    RequestPath,
+    /// `request.resource`
+    RequestResource,
+    /// `request.verb`
+    RequestVerb,
+    /// `action`: `resource:verb`, e.g. `runs:create`
+    Action,
+    /// `agent.name`
+    AgentName,
+    /// `agent.tags`
+    AgentTags,
+    /// `agent.project`
+    AgentProject,
+}
+
+impl Attr {
+    const ALL: &[(&str, Attr)] = &[
+        ("principal.kind", Attr::PrincipalKind),
+        ("principal.name", Attr::PrincipalName),
+        ("principal.groups", Attr::PrincipalGroups),
+        ("request.method", Attr::RequestMethod),
+        ("request.path", Attr::RequestPath),
+        ("request.resource", Attr::RequestResource),
+        ("request.verb", Attr::RequestVerb),
+        ("action", Attr::Action),
+        ("agent.name", Attr::AgentName),
+        ("agent.tags", Attr::AgentTags),
+        ("agent.project", Attr::AgentProject),
+    ];
+
+    fn parse(path: &str) -> Option<Self> {
+        Self::ALL.iter().find(|(p, _)| *p == path).map(|(_, a)| *a)
+    }
+}
+
+impl RequestContext {
+    fn get(&self, attr: Attr) -> Value {
+        let text = |s: &str| Value::Str(s.to_string());
+        let opt = |s: Option<&str>| s.map_or(Value::Null, text);
+        let list = |items: &[String]| Value::List(items.iter().map(|s| text(s)).collect());
+        match attr {
+            Attr::PrincipalKind => text(self.principal.kind),
+            Attr::PrincipalName => text(&self.principal.name),
+            Attr::PrincipalGroups => list(&self.principal.groups),
+            Attr::RequestMethod => text(&self.method),
+            Attr::RequestPath => text(&self.path),
+            Attr::RequestResource => opt(self.resource),
+            Attr::RequestVerb => opt(self.verb),
+            Attr::Action => match (self.resource, self.verb) {
+                (Some(resource), Some(verb)) => Value::Str(format!("{resource}:{verb}")),
+                _ => Value::Null,
+            },
+            Attr::AgentName => opt(self.agent.as_ref().map(|a| a.name.as_str())),
+            Attr::AgentTags => self
+                .agent
+                .as_ref()
+                .map_or(Value::List(Vec::new()), |a| list(&a.tags)),
+            Attr::AgentProject => opt(self.agent.as_ref().and_then(|a| a.project.as_deref())),
+        }
+    }
+}
+
+// ============================================================================
+// Expressions
+// ============================================================================
+
+#[derive(Debug, Clone, PartialEq, Eq)]
+enum Value {
+    Null,
+    Bool(bool),
+    Str(String),
+    List(Vec<Value>),
+}
+
+impl Value {
+    fn truthy(&self) -> bool {
+        matches!(self, Value::Bool(true))
+    }
+}
+
+#[derive(Debug, Clone, PartialEq, Eq)]
+enum Expr {
+    Literal(Value),
+    List(Vec<Expr>),
+    Attr(Attr),
+    Not(Box<Expr>),
+    And(Box<Expr>, Box<Expr>),
+    Or(Box<Expr>, Box<Expr>),
+    Eq(Box<Expr>, Box<Expr>),
+    Ne(Box<Expr>, Box<Expr>),
+    In(Box<Expr>, Box<Expr>),
+}
+
+impl Expr {
+    fn eval(&self, ctx: &RequestContext) -> Value {
+        match self {
+            Expr::Literal(v) => v.clone(),
+            Expr::List(items) => Value::List(items.iter().map(|e| e.eval(ctx)).collect()),
+            Expr::Attr(attr) => ctx.get(*attr),
+            Expr::Not(e) => Value::Bool(!e.eval(ctx).truthy()),
+            Expr::And(a, b) => Value::Bool(a.eval(ctx).truthy() && b.eval(ctx).truthy()),
+            Expr::Or(a, b) => Value::Bool(a.eval(ctx).truthy() || b.eval(ctx).truthy()),
+            Expr::Eq(a, b) => Value::Bool(a.eval(ctx) == b.eval(ctx)),
+            Expr::Ne(a, b) => Value::Bool(a.eval(ctx) != b.eval(ctx)),
+            Expr::In(a, b) => Value::Bool(match (a.eval(ctx), b.eval(ctx)) {
+                (item, Value::List(items)) => items.contains(&item),
+                (Value::Str(needle), Value::Str(haystack)) => haystack.contains(&needle),
+                _ => false,
+            }),
+        }
+    }
+}
+
+/// A compiled policy expression.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct Expression(Expr);
+
+impl Expression {
+    /// Whether the expression holds for `ctx`.
+    pub fn matches(&self, ctx: &RequestContext) -> bool {
+        self.0.eval(ctx).truthy()
+    }
+}
+
+impl std::str::FromStr for Expression {
+    type Err = ParseError;
+
+    fn from_str(s: &str) -> Result<Self, Self::Err> {
+        let tokens = tokenize(s)?;
+        let mut parser = Parser {
+            tokens,
+            pos: 0,
+            end: s.len(),
+        };
+        let expr = parser.or()?;
+        match parser.peek() {
+            None => Ok(Self(expr)),
+            Some((tok, offset)) => Err(ParseError::new(format!("unexpected {tok}"), offset)),
+        }
+    }
+}
+
+#[derive(Debug, Clone, PartialEq, Eq)]
+enum Token {
+    Str(String),
+    Ident(String),
+    EqEq,
+    NotEq,
+    AndAnd,
+    OrOr,
+    Bang,
+    LParen,
+    RParen,
+    LBracket,
+    RBracket,
+    Comma,
+}
+
+impl fmt::Display for Token {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        match self {
+            Token::Str(s) => write!(f, "string {s:?}"),
+            Token::Ident(s) => write!(f, "'{s}'"),
+            Token::EqEq => f.write_str("'=='"),
+            Token::NotEq => f.write_str("'!='"),
+            Token::AndAnd => f.write_str("'&&'"),
+            Token::OrOr => f.write_str("'||'"),
+            Token::Bang => f.write_str("'!'"),
+            Token::LParen => f.write_str("'('"),
+            Token::RParen => f.write_str("')'"),
+            Token::LBracket => f.write_str("'['"),
+            Token::RBracket => f.write_str("']'"),
+            Token::Comma => f.write_str("','"),
+        }
+    }
+}
+
+fn tokenize(s: &str) -> Result<Vec<(Token, usize)>, ParseError> {
+    let mut tokens = Vec::new();
+    let mut chars = s.char_indices().peekable();
+    while let Some((offset, c)) = chars.next() {
+        let token = match c {
+            c if c.is_whitespace() => continue,
+            '"' | '\'' => {
+                let mut text = String::new();
+                loop {
+                    match chars.next() {
+                        Some((_, ch)) if ch == c => break,
+                        Some((_, '\\')) => match chars.next() {
+                            Some((_, escaped)) => text.push(escaped),
+                            None => return Err(ParseError::new("unterminated string", offset)),
+                        },
+                        Some((_, ch)) => text.push(ch),
+                        None => return Err(ParseError::new("unterminated string", offset)),
+                    }
+                }
+                Token::Str(text)
+            }
+            '=' | '!' | '&' | '|' => {
+                let doubled = match (c, chars.peek().map(|(_, n)| *n)) {
+                    ('=', Some('=')) => Some(Token::EqEq),
+                    ('!', Some('=')) => Some(Token::NotEq),
+                    ('&', Some('&')) => Some(Token::AndAnd),
+                    ('|', Some('|')) => Some(Token::OrOr),
+                    _ => None,
+                };
+                match doubled {
+                    Some(token) => {
+                        chars.next();
+                        token
+                    }
+                    None if c == '!' => Token::Bang,
+                    None => return Err(ParseError::new(format!("unexpected '{c}'"), offset)),
+                }
+            }
+            '(' => Token::LParen,
+            ')' => Token::RParen,
+            '[' => Token::LBracket,
+            ']' => Token::RBracket,
+            ',' => Token::Comma,
+            c if c.is_ascii_alphabetic() || c == '_' => {
+                let mut ident = String::from(c);
+                while let Some(&(_, n)) = chars.peek() {
+                    if n.is_ascii_alphanumeric() || n == '_' || n == '.' {
+                        ident.push(n);
+                        chars.next();
+                    } else {
+                        break;
+                    }
+                }
+                Token::Ident(ident)
+            }
+            _ => return Err(ParseError::new(format!("unexpected '{c}'"), offset)),
+        };
+        tokens.push((token, offset));
+    }
+    Ok(tokens)
+}
+
+struct Parser {
+    tokens: Vec<(Token, usize)>,
+    pos: usize,
+    /// Offset reported for errors at end of input.
+    end: usize,
+}
+
+impl Parser {
+    fn peek(&self) -> Option<(&Token, usize)> {
+        self.tokens.get(self.pos).map(|(t, o)| (t, *o))
+    }
+
+    fn eat(&mut self, token: &Token) -> bool {
+        if self.peek().is_some_and(|(t, _)| t == token) {
+            self.pos += 1;
+            true
+        } else {
+            false
+        }
+    }
+
+    fn expect(&mut self, token: &Token) -> Result<(), ParseError> {
+        if self.eat(token) {
+            return Ok(());
+        }
+        Err(match self.peek() {
+            Some((t, offset)) => ParseError::new(format!("expected {token}, found {t}"), offset),
+            None => ParseError::new(format!("expected {token}"), self.end),
+        })
+    }
+
+    fn or(&mut self) -> Result<Expr, ParseError> {
+        let mut left = self.and()?;
+        while self.eat(&Token::OrOr) {
+            left = Expr::Or(Box::new(left), Box::new(self.and()?));
+        }
+        Ok(left)
+    }
+
+    fn and(&mut self) -> Result<Expr, ParseError> {
+        let mut left = self.unary()?;
+        while self.eat(&Token::AndAnd) {
+            left = Expr::And(Box::new(left), Box::new(self.unary()?));
+        }
+        Ok(left)
+    }
+
+    fn unary(&mut self) -> Result<Expr, ParseError> {
+        if self.eat(&Token::Bang) {
+            return Ok(Expr::Not(Box::new(self.unary()?)));
+        }
+        self.comparison()
+    }
+
+    fn comparison(&mut self) -> Result<Expr, ParseError> {
+        let left = self.operand()?;
+        let op: fn(Box<Expr>, Box<Expr>) -> Expr = if self.eat(&Token::EqEq) {
+            Expr::Eq
+        } else if self.eat(&Token::NotEq) {
+            Expr::Ne
+        } else if self.eat(&Token::Ident("in".to_string())) {
+            Expr::In
+        } else {
+            return Ok(left);
+        };
+        Ok(op(Box::new(left), Box::new(self.operand()?)))
+    }
+
+    fn operand(&mut self) -> Result<Expr, ParseError> {
+        let Some((token, offset)) = self.peek() else {
+            return Err(ParseError::new("unexpected end of expression", self.end));
+        };
+        let token = token.clone();
+        self.pos += 1;
+        match token {
+            Token::Str(s) => Ok(Expr::Literal(Value::Str(s))),
+            Token::LParen => {
+                let expr = self.or()?;
+                self.expect(&Token::RParen)?;
+                Ok(expr)
+            }
+            Token::LBracket => {
+                let mut items = Vec::new();
+                if !self.eat(&Token::RBracket) {
+                    loop {
+                        items.push(self.operand()?);
+                        if self.eat(&Token::RBracket) {
+                            break;
+                        }
+                        self.expect(&Token::Comma)?;
+                    }
+                }
+                Ok(Expr::List(items))
+            }
+            Token::Ident(ident) => match ident.as_str() {
+                "true" => Ok(Expr::Literal(Value::Bool(true))),
+                "false" => Ok(Expr::Literal(Value::Bool(false))),
+                "null" => Ok(Expr::Literal(Value::Null)),
+                path => Attr::parse(path)
+                    .map(Expr::Attr)
+                    .ok_or_else(|| ParseError::new(format!("unknown attribute '{path}'"), offset)),
+            },
+            other => Err(ParseError::new(format!("unexpected {other}"), offset)),
+        }
+    }
+}
+
+// ============================================================================
+// Policies
+// ============================================================================
+
+/// A compiled `authorization.policies` rule.
+#[derive(Debug)]
+struct Policy {
+    name: String,
+    description: Option<String>,
+    when: Option<Expression>,
+    allow: Expression,
+}
+
+/// A request a policy turned down.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct Denial {
+    pub policy: String,
+    pub description: Option<String>,
+}
+
+/// Compiled authorization policies. Cheap to clone.
+#[derive(Debug, Clone, Default)]
+pub struct Policies {
+    policies: Arc<Vec<Policy>>,
+    user_header: Option<String>,
+}
+
+impl Policies {
+    /// Compile the `authorization` config section.
+    pub fn from_config(config: &AuthorizationConfig) -> Result<Self, PolicyError> {
+        let policies = config
+            .policies
+            .iter()
+            .map(compile)
+            .collect::<Result<_, _>>()?;
+        Ok(Self {
+            policies: Arc::new(policies),
+            user_header: config.user_header.clone(),
+        })
+    }
+
+    pub fn is_empty(&self) -> bool {
+        self.policies.is_empty()
+    }
+
+    /// Header naming the end user, if configured.
+    pub fn user_header(&self) -> Option<&str> {
+        self.user_header.as_deref()
+    }
+
+    /// The first policy that denies `ctx`, if any.
+    pub fn check(&self, ctx: &RequestContext) -> Result<(), Denial> {
+        for policy in self.policies.iter() {
+            let applies = policy.when.as_ref().is_none_or(|w| w.matches(ctx));
+            if applies && !policy.allow.matches(ctx) {
+                return Err(Denial {
+                    policy: policy.name.clone(),
+                    description: policy.description.clone(),
+                });
+            }
+        }
+        Ok(())
+    }
+}
+
+fn compile(config: &PolicyConfig) -> Result<Policy, PolicyError> {
+    let parse = |field: &'static str, source: &str| -> Result<Expression, PolicyError> {
+        source.parse().map_err(|source| PolicyError {
+            name: config.name.clone(),
+            field,
+            source,
+        })
+    };
+    Ok(Policy {
+        name: config.name.clone(),
+        description: config.description.clone(),
+        when: config
+            .when
+            .as_deref()
+            .map(|w| parse("when", w))
+            .transpose()?,
+        allow: parse("allow", &config.allow)?,
+    })
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn ctx(groups: &[&str], tags: &[&str]) -> RequestContext {
+        RequestContext {
+            principal: Principal {
+                kind: "user",
+                name: "ada".to_string(),
+                groups: groups.iter().map(|g| g.to_string()).collect(),
+            },
+            method: "POST".to_string(),
+            path: "/sessions/s1/messages".to_string(),
+            resource: Some("runs"),
+            verb: Some("create"),
+            agent: Some(AgentTarget {
+                name: "trainer".to_string(),
+                tags: tags.iter().map(|t| t.to_string()).collect(),
+                project: None,
+            }),
+        }
+    }
+
+    fn expr(s: &str) -> Expression {
+        s.parse().unwrap()
+    }
+
+    #[test]
+    fn evaluates_operators() {
+        let c = ctx(&["ml"], &["gpu"]);
+        assert!(expr(r#"action == "runs:create""#).matches(&c));
+        assert!(expr(r#""gpu" in agent.tags && !("ops" in principal.groups)"#).matches(&c));
+        assert!(expr(r#"principal.kind != 'user' || "sessions" in request.path"#).matches(&c));
+        assert!(expr(r#"request.method in ["GET", "POST"]"#).matches(&c));
+        assert!(expr("agent.project == null").matches(&c));
+        assert!(!expr("agent.name").matches(&c));
+        assert!(!expr(r#"principal.groups == "ml""#).matches(&c));
+    }
+
+    #[test]
+    fn reports_parse_errors() {
+        let err = "agent.colour == 'red'".parse::<Expression>().unwrap_err();
+        assert_eq!(err.message, "unknown attribute 'agent.colour'");
+        assert_eq!(err.offset, 0);
+
+        let err = "(action == 'x'".parse::<Expression>().unwrap_err();
+        assert_eq!(err.message, "expected ')'");
+
+        assert!("action = 'x'".parse::<Expression>().is_err());
+        assert!("'open".parse::<Expression>().is_err());
+        assert!("action == 'x' action".parse::<Expression>().is_err());
+    }
+
+    #[test]
+    fn first_failing_rule_denies() {
+        let config = AuthorizationConfig {
+            user_header: None,
+            policies: vec![
+                PolicyConfig {
+                    name: "gpu-agents".to_string(),
+                    description: Some("Only ML may run GPU agents".to_string()),
+                    when: Some(r#"action == "runs:create" && "gpu" in agent.tags"#.to_string()),
+                    allow: r#""ml" in principal.groups"#.to_string(),
+                },
+                PolicyConfig {
+                    name: "no-contractors".to_string(),
+                    description: None,
+                    when: None,
+                    allow: r#"!("contractors" in principal.groups)"#.to_string(),
+                },
+            ],
+        };
+        let policies = Policies::from_config(&config).unwrap();
+
+        assert!(policies.check(&ctx(&["ml"], &["gpu"])).is_ok());
+        assert!(policies.check(&ctx(&[], &["cpu"])).is_ok());
+        assert_eq!(
+            policies
+                .check(&ctx(&["support"], &["gpu"]))
+                .unwrap_err()
+                .policy,
+            "gpu-agents"
+        );
+        assert_eq!(
+            policies
+                .check(&ctx(&["ml", "contractors"], &[]))
+                .unwrap_err()
+                .policy,
+            "no-contractors"
+        );
+
+        let mut bad = config.clone();
+        bad.policies[0].allow = "principal.team == 'ml'".to_string();
+        let err = Policies::from_config(&bad).unwrap_err();
+        assert_eq!(err.name, "gpu-agents");
+        assert_eq!(err.field, "allow");
+    }
+}
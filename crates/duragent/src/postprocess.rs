@@ -0,0 +1,250 @@
+//! Output post-processors.
+//!
+//! An agent's manifest can list steps that rewrite its final output before
+//! it is stored and returned, applied in order:
+//!
+//! ```yaml
+//! post_processors:
+//!   - type: profanity_filter
+//!   - type: citations
+//!   - type: markdown_html
+//! ```
+//!
+//! Steps never fail a run: a step that finds nothing to do leaves the output
+//! as it was.
+
+use std::collections::HashSet;
+use std::ops::Range;
+
+use pulldown_cmark::{Event, LinkType, Options, Parser, Tag, html};
+use tracing::warn;
+
+use crate::agent::PostProcessor;
+
+/// Words masked by `profanity_filter`, in addition to the manifest's own.
+const DEFAULT_PROFANITY: &[&str] = &[
+    "arse",
+    "arsehole",
+    "asshole",
+    "bastard",
+    "bitch",
+    "bollocks",
+    "bullshit",
+    "crap",
+    "cunt",
+    "damn",
+    "dick",
+    "fuck",
+    "fucked",
+    "fucking",
+    "motherfucker",
+    "piss",
+    "prick",
+    "shit",
+    "shitty",
+    "twat",
+    "wanker",
+];
+
+/// Run `content` through `processors`, in order.
+pub fn apply(processors: &[PostProcessor], content: String) -> String {
+    processors
+        .iter()
+        .fold(content, |content, processor| apply_one(processor, content))
+}
+
+fn apply_one(processor: &PostProcessor, content: String) -> String {
+    match processor {
+        PostProcessor::MarkdownHtml => markdown_to_html(&content),
+        PostProcessor::JsonExtract => match extract_json(&content) {
+            Some(json) => json.to_string(),
+            None => {
+                warn!("No JSON found in agent output; leaving it unchanged");
+                content
+            }
+        },
+        PostProcessor::Citations { heading } => format_citations(&content, heading),
+        PostProcessor::ProfanityFilter { words, replacement } => {
+            filter_profanity(&content, words, replacement.as_deref())
+        }
+    }
+}
+
+// ============================================================================
+// Processors
+// ============================================================================
+
+fn markdown_to_html(markdown: &str) -> String {
+    let options = Options::ENABLE_STRIKETHROUGH | Options::ENABLE_TABLES;
+    let mut output = String::with_capacity(markdown.len() * 3 / 2);
+    html::push_html(&mut output, Parser::new_ext(markdown, options));
+    output
+}
+
+/// The first JSON object or array in `content`, as written. Finds JSON in
+/// fenced code blocks and in surrounding prose alike.
+pub(crate) fn extract_json(content: &str) -> Option<&str> {
+    content
+        .char_indices()
+        .filter(|(_, c)| matches!(c, '{' | '['))
+        .find_map(|(start, _)| {
+            let rest = &content[start..];
+            let mut values =
+                serde_json::Deserializer::from_str(rest).into_iter::<serde_json::Value>();
+            match values.next() {
+                Some(Ok(_)) => Some(&rest[..values.byte_offset()]),
+                _ => None,
+            }
+        })
+}
+
+/// Replace inline links with their text and a `[n]` marker, and list the
+/// URLs under `heading`. Repeated URLs share a number.
+fn format_citations(content: &str, heading: &str) -> String {
+    let mut links: Vec<(Range<usize>, String)> = Vec::new();
+    for (event, range) in Parser::new(content).into_offset_iter() {
+        if let Event::Start(Tag::Link {
+            link_type: LinkType::Inline,
+            dest_url,
+            ..
+        }) = event
+        {
+            links.push((range, dest_url.into_string()));
+        }
+    }
+    if links.is_empty() {
+        return content.to_string();
+    }
+
+    let mut sources: Vec<String> = Vec::new();
+    let mut output = String::with_capacity(content.len());
+    let mut last = 0;
+    for (range, url) in links {
+        // Nested links are not allowed in Markdown, but be safe.
+        if range.start < last {
+            continue;
+        }
+        let source = &content[range.clone()];
+        let text = source
+            .rfind("](")
+            .map_or(source, |end| &source[1..end])
+            .trim();
+        let number = match sources.iter().position(|s| *s == url) {
+            Some(i) => i + 1,
+            None => {
+                sources.push(url);
+                sources.len()
+            }
+        };
+        output.push_str(&content[last..range.start]);
+        output.push_str(&format!("{text} [{number}]"));
+        last = range.end;
+    }
+    output.push_str(&content[last..]);
+
+    output.push_str(&format!("\n\n{heading}:\n\n"));
+    for (i, url) in sources.iter().enumerate() {
+        output.push_str(&format!("{}. <{url}>\n", i + 1));
+    }
+    output.truncate(output.trim_end().len());
+    output
+}
+
+/// Mask whole words found in the built-in list or `extra`, ignoring case.
+fn filter_profanity(content: &str, extra: &[String], replacement: Option<&str>) -> String {
+    let blocked: HashSet<String> = DEFAULT_PROFANITY
+        .iter()
+        .map(|w| w.to_string())
+        .chain(extra.iter().map(|w| w.trim().to_lowercase()))
+        .collect();
+
+    let mut output = String::with_capacity(content.len());
+    let mut word_start = None;
+    for (i, c) in content.char_indices().chain([(content.len(), ' ')]) {
+        let in_word = c.is_alphanumeric() && i < content.len();
+        match (word_start, in_word) {
+            (None, true) => word_start = Some(i),
+            (Some(start), false) => {
+                let word = &content[start..i];
+                if blocked.contains(&word.to_lowercase()) {
+                    match replacement {
+                        Some(r) => output.push_str(r),
+                        None => output.push_str(&"*".repeat(word.chars().count())),
+                    }
+                } else {
+                    output.push_str(word);
+                }
+                word_start = None;
+            }
+            _ => {}
+        }
+        if !in_word && i < content.len() {
+            output.push(c);
+        }
+    }
+    output
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn markdown_is_rendered_as_html() {
+        assert_eq!(
+            markdown_to_html("**Hi** there"),
+            "<p><strong>Hi</strong> there</p>\n"
+        );
+    }
+
+    #[test]
+    fn json_is_extracted_from_prose_and_fences() {
+        assert_eq!(
+            extract_json("Here you go:\n```json\n{\"a\": [1, 2]}\n```\nDone."),
+            Some("{\"a\": [1, 2]}")
+        );
+        assert_eq!(extract_json("See [note] then [1, 2] ok"), Some("[1, 2]"));
+        assert_eq!(extract_json("no json here {"), None);
+    }
+
+    #[test]
+    fn citations_are_numbered_and_deduplicated() {
+        let output = format_citations(
+            "See [the docs](https://a.example) and [more](https://b.example), again [docs](https://a.example).",
+            "Sources",
+        );
+        assert_eq!(
+            output,
+            "See the docs [1] and more [2], again docs [1].\n\nSources:\n\n1. <https://a.example>\n2. <https://b.example>"
+        );
+        assert_eq!(format_citations("No links.", "Sources"), "No links.");
+    }
+
+    #[test]
+    fn profanity_is_masked_as_whole_words() {
+        let words = vec!["Heck".to_string()];
+        assert_eq!(
+            filter_profanity("Shit, what the heck? Scunthorpe.", &words, None),
+            "****, what the ****? Scunthorpe."
+        );
+        assert_eq!(
+            filter_profanity("damn it", &[], Some("[redacted]")),
+            "[redacted] it"
+        );
+    }
+
+    #[test]
+    fn processors_run_in_order() {
+        let processors = [
+            PostProcessor::ProfanityFilter {
+                words: Vec::new(),
+                replacement: Some("[redacted]".to_string()),
+            },
+            PostProcessor::MarkdownHtml,
+        ];
+        assert_eq!(
+            apply(&processors, "*damn*".to_string()),
+            "<p><em>[redacted]</em></p>\n"
+        );
+    }
+}
@@ -20,8 +20,8 @@ use crate::context::{ContextBuilder, TokenBudget, load_all_directives_async};
 use crate::gateway::{GatewaySender, build_approval_keyboard};
 use crate::server::RuntimeServices;
 use crate::session::{
-    AGENTIC_LOOP_LOCK_TIMEOUT, AgenticResult, STEERING_CHANNEL_CAPACITY, SteeringMessage,
-    run_agentic_loop,
+    AGENTIC_LOOP_LOCK_TIMEOUT, AgenticResult, RunPriority, STEERING_CHANNEL_CAPACITY,
+    SteeringMessage, run_agentic_loop,
 };
 use crate::tools::{ReloadDeps, ToolDependencies, build_executor_async};
 
@@ -589,6 +589,9 @@ impl ProcessRegistryHandle {
             .agents
             .get(&meta.agent)
             .ok_or_else(|| anyhow::anyhow!("Agent not found: {}", meta.agent))?;
+        if !agent.enabled {
+            anyhow::bail!("Agent disabled: {}", meta.agent);
+        }
 
         // Get provider
         let provider = self
@@ -676,7 +679,8 @@ impl ProcessRegistryHandle {
             agent_dir: agent.agent_dir.clone(),
             workspace_tools_dir: Some(self.services.workspace_tools_path.clone()),
             agent_tool_configs: agent.tools.clone(),
-        });
+        })
+        .with_mocks(self.services.tool_mocks.clone());
 
         // Create steering channel so user messages can be injected mid-loop
         let (steering_tx, steering_rx) = mpsc::channel(STEERING_CHANNEL_CAPACITY);
@@ -697,8 +701,13 @@ impl ProcessRegistryHandle {
             max_output_tokens: agent.model.max_output_tokens.unwrap_or(4096),
             max_history_tokens: agent.session.context.max_history_tokens,
         };
+        let blocks = self
+            .services
+            .context_blocks(&agent, &provider, &handle, &history)
+            .await;
         let messages = ContextBuilder::new()
             .from_agent_spec(&agent)
+            .with_blocks(blocks)
             .with_messages(history)
             .with_directives(directives)
             .build()
@@ -711,6 +720,9 @@ impl ProcessRegistryHandle {
             )
             .messages;
 
+        let _permit = self.services.run_pool.acquire(RunPriority::Low).await;
+        let provider = self.services.run_provider(&agent, provider, &handle).await;
+
         // Run agentic loop
         let result = run_agentic_loop(
             provider,
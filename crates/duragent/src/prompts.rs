@@ -0,0 +1,260 @@
+//! Prompt library: shared, versioned prompt fragments.
+//!
+//! Common policies and personas live in one place and are included by
+//! agents that list them under `prompts` in their manifest:
+//!
+//! ```yaml
+//! prompts:
+//!   - safety-policy        # latest version
+//!   - friendly-persona@2   # pinned to version 2
+//! ```
+//!
+//! Every change to a prompt's content adds a version; old versions are kept
+//! so pinned agents are unaffected. Included prompts are added to the context
+//! after the system prompt, with the same template variables.
+
+use std::collections::HashMap;
+use std::sync::{Arc, RwLock};
+
+use chrono::{DateTime, Utc};
+use serde::{Deserialize, Serialize};
+use tracing::warn;
+
+use crate::agent::{AgentSpec, PromptRef};
+use crate::context::{BlockSource, SystemBlock, interpolate_template_vars, priority};
+use crate::store::{PromptStore, StorageResult};
+
+// ============================================================================
+// Types
+// ============================================================================
+
+/// A shared prompt with its version history.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct Prompt {
+    pub name: String,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub description: Option<String>,
+    /// Versions, oldest first. Never empty.
+    pub versions: Vec<PromptVersion>,
+    pub created_at: DateTime<Utc>,
+    pub updated_at: DateTime<Utc>,
+}
+
+impl Prompt {
+    pub fn latest(&self) -> &PromptVersion {
+        self.versions
+            .last()
+            .expect("prompt has at least one version")
+    }
+
+    /// A specific version, or the latest for `None`.
+    pub fn version(&self, version: Option<u32>) -> Option<&PromptVersion> {
+        match version {
+            Some(v) => self.versions.iter().find(|pv| pv.version == v),
+            None => self.versions.last(),
+        }
+    }
+}
+
+/// One version of a prompt's content.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct PromptVersion {
+    /// Starts at 1 and increases with every content change.
+    pub version: u32,
+    pub content: String,
+    pub created_at: DateTime<Utc>,
+}
+
+/// What a [`PromptLibrary::put`] did.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum PutOutcome {
+    Created,
+    /// The content changed, so a version was added.
+    NewVersion,
+    /// The content matched the latest version; only metadata was saved.
+    Unchanged,
+}
+
+// ============================================================================
+// PromptLibrary
+// ============================================================================
+
+/// Loaded prompts, kept in memory so contexts can be built without I/O.
+#[derive(Clone)]
+pub struct PromptLibrary {
+    store: Arc<dyn PromptStore>,
+    prompts: Arc<RwLock<HashMap<String, Prompt>>>,
+}
+
+impl PromptLibrary {
+    /// Load all prompts from `store`.
+    pub async fn load(store: Arc<dyn PromptStore>) -> StorageResult<Self> {
+        let prompts = store
+            .list()
+            .await?
+            .into_iter()
+            .filter(|p| !p.versions.is_empty())
+            .map(|p| (p.name.clone(), p))
+            .collect();
+        Ok(Self {
+            store,
+            prompts: Arc::new(RwLock::new(prompts)),
+        })
+    }
+
+    /// All prompts, sorted by name.
+    pub fn list(&self) -> Vec<Prompt> {
+        let mut prompts: Vec<_> = self.prompts.read().unwrap().values().cloned().collect();
+        prompts.sort_by(|a, b| a.name.cmp(&b.name));
+        prompts
+    }
+
+    pub fn get(&self, name: &str) -> Option<Prompt> {
+        self.prompts.read().unwrap().get(name).cloned()
+    }
+
+    /// Create a prompt, or add a version when `content` differs from the
+    /// latest one.
+    pub async fn put(
+        &self,
+        name: &str,
+        description: Option<String>,
+        content: String,
+    ) -> StorageResult<(Prompt, PutOutcome)> {
+        let now = Utc::now();
+        let (prompt, outcome) = match self.get(name) {
+            None => {
+                let prompt = Prompt {
+                    name: name.to_string(),
+                    description,
+                    versions: vec![PromptVersion {
+                        version: 1,
+                        content,
+                        created_at: now,
+                    }],
+                    created_at: now,
+                    updated_at: now,
+                };
+                (prompt, PutOutcome::Created)
+            }
+            Some(mut prompt) => {
+                let outcome = if prompt.latest().content == content {
+                    PutOutcome::Unchanged
+                } else {
+                    let version = prompt.latest().version + 1;
+                    prompt.versions.push(PromptVersion {
+                        version,
+                        content,
+                        created_at: now,
+                    });
+                    PutOutcome::NewVersion
+                };
+                prompt.description = description;
+                prompt.updated_at = now;
+                (prompt, outcome)
+            }
+        };
+        self.store.save(&prompt).await?;
+        self.prompts
+            .write()
+            .unwrap()
+            .insert(prompt.name.clone(), prompt.clone());
+        Ok((prompt, outcome))
+    }
+
+    /// Delete a prompt and its versions. Returns whether it existed.
+    pub async fn delete(&self, name: &str) -> StorageResult<bool> {
+        if self.get(name).is_none() {
+            return Ok(false);
+        }
+        self.store.delete(name).await?;
+        self.prompts.write().unwrap().remove(name);
+        Ok(true)
+    }
+
+    /// Content a reference resolves to, or `None` if the prompt or version
+    /// doesn't exist.
+    pub fn resolve(&self, reference: &PromptRef) -> Option<String> {
+        let prompts = self.prompts.read().unwrap();
+        let prompt = prompts.get(&reference.name)?;
+        prompt.version(reference.version).map(|v| v.content.clone())
+    }
+
+    /// System blocks for the prompts an agent includes. Missing prompts are
+    /// skipped with a warning.
+    pub fn blocks_for(&self, agent: &AgentSpec) -> Vec<SystemBlock> {
+        agent
+            .prompts
+            .iter()
+            .filter_map(|reference| {
+                let Some(content) = self.resolve(reference) else {
+                    warn!(agent = %agent.metadata.name, prompt = %reference, "Included prompt not found");
+                    return None;
+                };
+                Some(SystemBlock {
+                    content: interpolate_template_vars(&content, agent),
+                    label: format!("prompt:{}", reference.name),
+                    source: BlockSource::AgentSpec,
+                    priority: priority::PROMPTS,
+                })
+            })
+            .collect()
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use tempfile::TempDir;
+
+    use super::*;
+    use crate::store::file::FilePromptStore;
+
+    async fn library(tmp: &TempDir) -> PromptLibrary {
+        PromptLibrary::load(Arc::new(FilePromptStore::new(tmp.path())))
+            .await
+            .unwrap()
+    }
+
+    fn reference(s: &str) -> PromptRef {
+        s.parse().unwrap()
+    }
+
+    #[tokio::test]
+    async fn put_adds_versions_only_when_content_changes() {
+        let tmp = TempDir::new().unwrap();
+        let library = library(&tmp).await;
+
+        let (_, outcome) = library.put("policy", None, "v1".into()).await.unwrap();
+        assert_eq!(outcome, PutOutcome::Created);
+        let (_, outcome) = library.put("policy", None, "v1".into()).await.unwrap();
+        assert_eq!(outcome, PutOutcome::Unchanged);
+        let (prompt, outcome) = library
+            .put("policy", Some("Safety".into()), "v2".into())
+            .await
+            .unwrap();
+        assert_eq!(outcome, PutOutcome::NewVersion);
+        assert_eq!(prompt.latest().version, 2);
+
+        assert_eq!(library.resolve(&reference("policy")).as_deref(), Some("v2"));
+        assert_eq!(
+            library.resolve(&reference("policy@1")).as_deref(),
+            Some("v1")
+        );
+        assert_eq!(library.resolve(&reference("policy@3")), None);
+
+        // Versions survive a reload
+        let reloaded = self::library(&tmp).await;
+        assert_eq!(reloaded.get("policy").unwrap().versions.len(), 2);
+    }
+
+    #[tokio::test]
+    async fn delete_removes_prompt() {
+        let tmp = TempDir::new().unwrap();
+        let library = library(&tmp).await;
+        library.put("policy", None, "v1".into()).await.unwrap();
+
+        assert!(library.delete("policy").await.unwrap());
+        assert!(!library.delete("policy").await.unwrap());
+        assert!(library.list().is_empty());
+    }
+}
@@ -0,0 +1,306 @@
+//! Recent LLM provider latency and rate-limit headroom.
+//!
+//! Every request to a provider is recorded with its latency and outcome,
+//! keeping the most recent [`RECENT_REQUESTS`] per provider. Providers also
+//! report the rate-limit headers of their responses, so the last known
+//! headroom can be shown next to each provider when choosing models.
+
+use std::collections::{HashMap, VecDeque};
+use std::sync::{Arc, Mutex};
+use std::time::{Duration, Instant};
+
+use async_trait::async_trait;
+use chrono::{DateTime, Utc};
+use reqwest::header::HeaderMap;
+
+use crate::llm::{ChatRequest, ChatResponse, ChatStream, LLMError, LLMProvider};
+
+/// Requests kept per provider.
+pub const RECENT_REQUESTS: usize = 100;
+
+// ============================================================================
+// Types
+// ============================================================================
+
+/// Remaining requests and tokens, as reported by a provider's response
+/// headers. Fields the provider didn't report are `None`.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct RateLimitHeadroom {
+    pub requests_limit: Option<u64>,
+    pub requests_remaining: Option<u64>,
+    pub tokens_limit: Option<u64>,
+    pub tokens_remaining: Option<u64>,
+    pub observed_at: DateTime<Utc>,
+}
+
+impl RateLimitHeadroom {
+    /// Parse OpenAI-style (`x-ratelimit-*`, also used by OpenRouter and
+    /// most compatible servers) and Anthropic-style
+    /// (`anthropic-ratelimit-*`) headers. `None` without any of them.
+    pub fn from_headers(headers: &HeaderMap) -> Option<Self> {
+        let number = |names: &[&str]| {
+            names.iter().find_map(|name| {
+                headers
+                    .get(*name)?
+                    .to_str()
+                    .ok()?
+                    .trim()
+                    .parse::<u64>()
+                    .ok()
+            })
+        };
+        let headroom = Self {
+            requests_limit: number(&[
+                "x-ratelimit-limit-requests",
+                "anthropic-ratelimit-requests-limit",
+                "x-ratelimit-limit",
+            ]),
+            requests_remaining: number(&[
+                "x-ratelimit-remaining-requests",
+                "anthropic-ratelimit-requests-remaining",
+                "x-ratelimit-remaining",
+            ]),
+            tokens_limit: number(&[
+                "x-ratelimit-limit-tokens",
+                "anthropic-ratelimit-tokens-limit",
+            ]),
+            tokens_remaining: number(&[
+                "x-ratelimit-remaining-tokens",
+                "anthropic-ratelimit-tokens-remaining",
+            ]),
+            observed_at: Utc::now(),
+        };
+        let any = headroom.requests_limit.is_some()
+            || headroom.requests_remaining.is_some()
+            || headroom.tokens_limit.is_some()
+            || headroom.tokens_remaining.is_some();
+        any.then_some(headroom)
+    }
+}
+
+#[derive(Debug, Clone, Copy)]
+struct Sample {
+    latency_ms: u64,
+    ok: bool,
+}
+
+#[derive(Debug, Default)]
+struct Entry {
+    samples: VecDeque<Sample>,
+    last_request_at: Option<DateTime<Utc>>,
+    rate_limit: Option<RateLimitHeadroom>,
+}
+
+/// Latency and outcomes of a provider's recent requests.
+#[derive(Debug, Clone, Default, PartialEq)]
+pub struct ProviderActivity {
+    /// Requests counted, at most [`RECENT_REQUESTS`].
+    pub requests: usize,
+    pub errors: usize,
+    /// Median latency of the successful requests.
+    pub p50_latency_ms: Option<u64>,
+    pub p95_latency_ms: Option<u64>,
+    pub last_request_at: Option<DateTime<Utc>>,
+    pub rate_limit: Option<RateLimitHeadroom>,
+}
+
+// ============================================================================
+// ProviderStats
+// ============================================================================
+
+/// Recent requests and rate-limit headroom per provider.
+#[derive(Clone, Default)]
+pub struct ProviderStats {
+    entries: Arc<Mutex<HashMap<String, Entry>>>,
+}
+
+impl ProviderStats {
+    /// Record one request to `provider`.
+    pub fn record(&self, provider: &str, latency: Duration, ok: bool) {
+        let mut entries = self.entries.lock().expect("mutex poisoned");
+        let entry = entries.entry(provider.to_string()).or_default();
+        if entry.samples.len() == RECENT_REQUESTS {
+            entry.samples.pop_front();
+        }
+        entry.samples.push_back(Sample {
+            latency_ms: latency.as_millis() as u64,
+            ok,
+        });
+        entry.last_request_at = Some(Utc::now());
+    }
+
+    /// Record the headroom reported by a response from `provider`.
+    pub fn record_rate_limit(&self, provider: &str, headroom: RateLimitHeadroom) {
+        let mut entries = self.entries.lock().expect("mutex poisoned");
+        entries.entry(provider.to_string()).or_default().rate_limit = Some(headroom);
+    }
+
+    /// Recent activity of `provider`; empty if it hasn't been used.
+    pub fn activity(&self, provider: &str) -> ProviderActivity {
+        let entries = self.entries.lock().expect("mutex poisoned");
+        let Some(entry) = entries.get(provider) else {
+            return ProviderActivity::default();
+        };
+        let mut latencies: Vec<u64> = entry
+            .samples
+            .iter()
+            .filter(|s| s.ok)
+            .map(|s| s.latency_ms)
+            .collect();
+        latencies.sort_unstable();
+        ProviderActivity {
+            requests: entry.samples.len(),
+            errors: entry.samples.iter().filter(|s| !s.ok).count(),
+            p50_latency_ms: percentile(&latencies, 0.5),
+            p95_latency_ms: percentile(&latencies, 0.95),
+            last_request_at: entry.last_request_at,
+            rate_limit: entry.rate_limit.clone(),
+        }
+    }
+
+    /// Observer for a provider's response headers.
+    pub fn rate_limit_observer(&self, provider: &str) -> RateLimitObserver {
+        RateLimitObserver {
+            stats: self.clone(),
+            provider: provider.to_string(),
+        }
+    }
+}
+
+/// Nearest-rank percentile of sorted values.
+fn percentile(sorted: &[u64], p: f64) -> Option<u64> {
+    if sorted.is_empty() {
+        return None;
+    }
+    let rank = (p * sorted.len() as f64).ceil() as usize;
+    Some(sorted[rank.clamp(1, sorted.len()) - 1])
+}
+
+/// Records the rate-limit headers of one provider's responses.
+#[derive(Clone)]
+pub struct RateLimitObserver {
+    stats: ProviderStats,
+    provider: String,
+}
+
+impl RateLimitObserver {
+    pub fn observe(&self, headers: &HeaderMap) {
+        if let Some(headroom) = RateLimitHeadroom::from_headers(headers) {
+            self.stats.record_rate_limit(&self.provider, headroom);
+        }
+    }
+}
+
+// ============================================================================
+// TrackedProvider
+// ============================================================================
+
+/// Records the latency and outcome of each request in [`ProviderStats`].
+///
+/// Streams are timed to the start of the response.
+pub struct TrackedProvider {
+    inner: Arc<dyn LLMProvider>,
+    provider: String,
+    stats: ProviderStats,
+}
+
+impl TrackedProvider {
+    #[must_use]
+    pub fn new(inner: Arc<dyn LLMProvider>, provider: String, stats: ProviderStats) -> Self {
+        Self {
+            inner,
+            provider,
+            stats,
+        }
+    }
+}
+
+#[async_trait]
+impl LLMProvider for TrackedProvider {
+    async fn chat(&self, request: ChatRequest) -> Result<ChatResponse, LLMError> {
+        let started = Instant::now();
+        let result = self.inner.chat(request).await;
+        self.stats
+            .record(&self.provider, started.elapsed(), result.is_ok());
+        result
+    }
+
+    async fn chat_stream(&self, request: ChatRequest) -> Result<ChatStream, LLMError> {
+        let started = Instant::now();
+        let result = self.inner.chat_stream(request).await;
+        self.stats
+            .record(&self.provider, started.elapsed(), result.is_ok());
+        result
+    }
+
+    async fn health_check(&self) -> Result<(), LLMError> {
+        self.inner.health_check().await
+    }
+
+    async fn embed(&self, model: &str, inputs: Vec<String>) -> Result<Vec<Vec<f32>>, LLMError> {
+        self.inner.embed(model, inputs).await
+    }
+
+    async fn list_models(&self) -> Result<Vec<String>, LLMError> {
+        self.inner.list_models().await
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn parses_openai_and_anthropic_headers() {
+        let mut headers = HeaderMap::new();
+        headers.insert("x-ratelimit-limit-requests", "500".parse().unwrap());
+        headers.insert("x-ratelimit-remaining-requests", "499".parse().unwrap());
+        headers.insert("x-ratelimit-remaining-tokens", "29000".parse().unwrap());
+        let headroom = RateLimitHeadroom::from_headers(&headers).unwrap();
+        assert_eq!(headroom.requests_limit, Some(500));
+        assert_eq!(headroom.requests_remaining, Some(499));
+        assert_eq!(headroom.tokens_limit, None);
+        assert_eq!(headroom.tokens_remaining, Some(29_000));
+
+        let mut headers = HeaderMap::new();
+        headers.insert(
+            "anthropic-ratelimit-tokens-remaining",
+            "80000".parse().unwrap(),
+        );
+        let headroom = RateLimitHeadroom::from_headers(&headers).unwrap();
+        assert_eq!(headroom.tokens_remaining, Some(80_000));
+
+        assert!(RateLimitHeadroom::from_headers(&HeaderMap::new()).is_none());
+    }
+
+    #[test]
+    fn activity_keeps_recent_requests() {
+        let stats = ProviderStats::default();
+        for ms in 1..=RECENT_REQUESTS as u64 + 10 {
+            stats.record("openai", Duration::from_millis(ms), true);
+        }
+        stats.record("openai", Duration::from_millis(5), false);
+
+        let activity = stats.activity("openai");
+        assert_eq!(activity.requests, RECENT_REQUESTS);
+        assert_eq!(activity.errors, 1);
+        // The oldest 11 samples were dropped: latencies are 12..=110.
+        assert_eq!(activity.p50_latency_ms, Some(61));
+        assert_eq!(activity.p95_latency_ms, Some(106));
+        assert!(activity.last_request_at.is_some());
+        assert_eq!(stats.activity("anthropic"), ProviderActivity::default());
+    }
+
+    #[test]
+    fn observer_records_headroom() {
+        let stats = ProviderStats::default();
+        let mut headers = HeaderMap::new();
+        headers.insert("x-ratelimit-remaining", "7".parse().unwrap());
+        stats.rate_limit_observer("openrouter").observe(&headers);
+        stats
+            .rate_limit_observer("openrouter")
+            .observe(&HeaderMap::new());
+        let headroom = stats.activity("openrouter").rate_limit.unwrap();
+        assert_eq!(headroom.requests_remaining, Some(7));
+    }
+}
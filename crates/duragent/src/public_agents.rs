@@ -0,0 +1,267 @@
+//! Anonymous access to public agents.
+//!
+//! Agents with `access.public` can be invoked over `/public/v1` without
+//! credentials, e.g. from a chat widget on a website. Every request counts
+//! against a per-client-IP budget of `requests_per_minute` for the agent,
+//! and agents with `captcha: true` need a token verified by the CAPTCHA
+//! provider under `public_agents.captcha` to start a conversation.
+
+use std::collections::VecDeque;
+use std::net::{IpAddr, SocketAddr};
+use std::sync::Arc;
+use std::time::{Duration, Instant};
+
+use axum::http::{HeaderMap, HeaderName};
+use dashmap::DashMap;
+use serde::Deserialize;
+use thiserror::Error;
+use tracing::warn;
+
+use crate::config::{CaptchaConfig, PublicAgentsConfig};
+
+/// The window `requests_per_minute` applies to.
+const WINDOW: Duration = Duration::from_secs(60);
+
+/// Tracked clients above which idle ones are dropped.
+const PRUNE_THRESHOLD: usize = 10_000;
+
+// ============================================================================
+// Types
+// ============================================================================
+
+#[derive(Debug, Error)]
+pub enum PublicAgentsError {
+    #[error("public_agents.client_ip_header: '{0}' is not a valid header name")]
+    InvalidHeader(String),
+
+    #[error("public_agents.captcha.{0} must not be empty")]
+    EmptyCaptchaField(&'static str),
+}
+
+/// Rate limits and CAPTCHA verification for public agents; cheap to clone.
+#[derive(Clone, Default)]
+pub struct PublicAgents {
+    inner: Arc<Inner>,
+}
+
+#[derive(Default)]
+struct Inner {
+    client_ip_header: Option<HeaderName>,
+    captcha: Option<Captcha>,
+    /// Recent requests per agent and client IP.
+    requests: DashMap<(String, IpAddr), VecDeque<Instant>>,
+}
+
+struct Captcha {
+    client: reqwest::Client,
+    verify_url: String,
+    secret: String,
+}
+
+/// The parts of a siteverify response we read.
+#[derive(Deserialize)]
+struct VerifyResponse {
+    success: bool,
+}
+
+// ============================================================================
+// PublicAgents
+// ============================================================================
+
+impl PublicAgents {
+    /// Verify CAPTCHA tokens with `client`, if a provider is configured.
+    pub fn new(
+        config: &PublicAgentsConfig,
+        client: reqwest::Client,
+    ) -> Result<Self, PublicAgentsError> {
+        let client_ip_header = config
+            .client_ip_header
+            .as_deref()
+            .map(|name| {
+                HeaderName::try_from(name)
+                    .map_err(|_| PublicAgentsError::InvalidHeader(name.to_string()))
+            })
+            .transpose()?;
+        let captcha = match &config.captcha {
+            Some(captcha) => Some(Captcha::new(captcha, client)?),
+            None => None,
+        };
+        Ok(Self {
+            inner: Arc::new(Inner {
+                client_ip_header,
+                captcha,
+                requests: DashMap::new(),
+            }),
+        })
+    }
+
+    /// Address a request came from: the last address in
+    /// `client_ip_header` when configured and valid, else the peer's.
+    pub fn client_ip(&self, peer: SocketAddr, headers: &HeaderMap) -> IpAddr {
+        self.inner
+            .client_ip_header
+            .as_ref()
+            .and_then(|name| headers.get(name))
+            .and_then(|value| value.to_str().ok())
+            .and_then(|value| value.rsplit(',').next())
+            .and_then(|ip| ip.trim().parse().ok())
+            .unwrap_or(peer.ip())
+    }
+
+    /// Count a request from `ip` to `agent`, or return how long until the
+    /// client is back under `requests_per_minute`.
+    pub fn admit(&self, agent: &str, ip: IpAddr, requests_per_minute: u32) -> Result<(), Duration> {
+        self.admit_at(Instant::now(), agent, ip, requests_per_minute)
+    }
+
+    fn admit_at(
+        &self,
+        now: Instant,
+        agent: &str,
+        ip: IpAddr,
+        requests_per_minute: u32,
+    ) -> Result<(), Duration> {
+        let requests = &self.inner.requests;
+        if requests.len() > PRUNE_THRESHOLD {
+            requests.retain(|_, times| {
+                times
+                    .back()
+                    .is_some_and(|t| now.duration_since(*t) < WINDOW)
+            });
+        }
+
+        let mut times = requests.entry((agent.to_string(), ip)).or_default();
+        while times
+            .front()
+            .is_some_and(|t| now.duration_since(*t) >= WINDOW)
+        {
+            times.pop_front();
+        }
+        if times.len() >= requests_per_minute as usize {
+            let oldest = times[times.len() - requests_per_minute as usize];
+            return Err((oldest + WINDOW).saturating_duration_since(now));
+        }
+        times.push_back(now);
+        Ok(())
+    }
+
+    pub fn captcha_configured(&self) -> bool {
+        self.inner.captcha.is_some()
+    }
+
+    /// Whether the CAPTCHA provider accepts `token` from `ip`. Fails closed:
+    /// without a provider, or when it can't be reached, no token passes.
+    pub async fn verify_captcha(&self, token: &str, ip: IpAddr) -> bool {
+        let Some(captcha) = &self.inner.captcha else {
+            return false;
+        };
+        match captcha.verify(token, ip).await {
+            Ok(success) => success,
+            Err(e) => {
+                warn!(error = %e, "CAPTCHA verification failed");
+                false
+            }
+        }
+    }
+}
+
+impl Captcha {
+    fn new(config: &CaptchaConfig, client: reqwest::Client) -> Result<Self, PublicAgentsError> {
+        if config.verify_url.trim().is_empty() {
+            return Err(PublicAgentsError::EmptyCaptchaField("verify_url"));
+        }
+        if config.secret.trim().is_empty() {
+            return Err(PublicAgentsError::EmptyCaptchaField("secret"));
+        }
+        Ok(Self {
+            client,
+            verify_url: config.verify_url.clone(),
+            secret: config.secret.clone(),
+        })
+    }
+
+    /// POST the token to the siteverify endpoint, as Turnstile, hCaptcha,
+    /// and reCAPTCHA all expect.
+    async fn verify(&self, token: &str, ip: IpAddr) -> Result<bool, reqwest::Error> {
+        let body = url::form_urlencoded::Serializer::new(String::new())
+            .append_pair("secret", &self.secret)
+            .append_pair("response", token)
+            .append_pair("remoteip", &ip.to_string())
+            .finish();
+        let response: VerifyResponse = self
+            .client
+            .post(&self.verify_url)
+            .header(
+                reqwest::header::CONTENT_TYPE,
+                "application/x-www-form-urlencoded",
+            )
+            .body(body)
+            .send()
+            .await?
+            .error_for_status()?
+            .json()
+            .await?;
+        Ok(response.success)
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn ip(last: u8) -> IpAddr {
+        IpAddr::from([203, 0, 113, last])
+    }
+
+    #[test]
+    fn requests_over_the_limit_wait_for_the_window() {
+        let public = PublicAgents::default();
+        let start = Instant::now();
+
+        assert!(public.admit_at(start, "widget", ip(1), 2).is_ok());
+        assert!(
+            public
+                .admit_at(start + Duration::from_secs(10), "widget", ip(1), 2)
+                .is_ok()
+        );
+        let wait = public
+            .admit_at(start + Duration::from_secs(20), "widget", ip(1), 2)
+            .unwrap_err();
+        assert_eq!(wait, Duration::from_secs(40));
+
+        // Other clients and agents have their own budgets
+        assert!(public.admit_at(start, "widget", ip(2), 2).is_ok());
+        assert!(public.admit_at(start, "other", ip(1), 2).is_ok());
+
+        // Once the oldest request leaves the window, there is room again
+        assert!(
+            public
+                .admit_at(start + Duration::from_secs(60), "widget", ip(1), 2)
+                .is_ok()
+        );
+    }
+
+    #[test]
+    fn client_ip_comes_from_the_configured_header() {
+        let peer: SocketAddr = ([10, 0, 0, 5], 443).into();
+        let mut headers = HeaderMap::new();
+        headers.insert(
+            "x-forwarded-for",
+            "198.51.100.7, 203.0.113.9".parse().unwrap(),
+        );
+
+        let direct = PublicAgents::default();
+        assert_eq!(direct.client_ip(peer, &headers), peer.ip());
+
+        let proxied = PublicAgents::new(
+            &PublicAgentsConfig {
+                client_ip_header: Some("X-Forwarded-For".to_string()),
+                captcha: None,
+            },
+            reqwest::Client::new(),
+        )
+        .unwrap();
+        assert_eq!(proxied.client_ip(peer, &headers), ip(9));
+        assert_eq!(proxied.client_ip(peer, &HeaderMap::new()), peer.ip());
+    }
+}
@@ -0,0 +1,567 @@
+//! Message runs.
+//!
+//! Every message sent through `POST /api/v1/sessions/{session_id}/messages`
+//! is recorded here as a run, with the labels the client sent, so runs can
+//! be listed and filtered by label. Messages sent with `background: true`
+//! or a `callback_url`, and runs started with `POST /api/v1/agents/{name}/runs`,
+//! run after the request returns; their outcome is kept so clients can fetch
+//! it with `GET /api/v1/runs/{id}`, optionally waiting for it to finish, or
+//! follow it with `GET /api/v1/runs/{id}/events`.
+//!
+//! Runs that fail on a provider request record the failure's
+//! [`ProviderErrorClass`], and failures are counted by class for
+//! `/metrics`.
+//!
+//! Finished runs are forgotten after `runs.retention_hours` or once more
+//! than [`MAX_FINISHED_RUNS`] have finished. With a [`RunStore`], every
+//! change is written behind, in order, so run history survives a restart;
+//! runs left unfinished by the restart are recorded as interrupted.
+//! Background runs are stopped after `runs.timeout_seconds` and when the
+//! server shuts down.
+
+// std::sync::Mutex is correct here—lock is never held across .await points.
+use std::collections::{BTreeMap, HashMap};
+use std::future::Future;
+use std::sync::{Arc, Mutex};
+use std::time::Duration;
+
+use axum::body;
+use axum::http::StatusCode;
+use axum::response::{IntoResponse, Response};
+use chrono::{DateTime, Utc};
+use serde::{Deserialize, Serialize};
+use tokio::sync::{mpsc, oneshot, watch};
+use tokio_util::sync::CancellationToken;
+use tracing::{error, warn};
+
+use crate::agent::LabelSelector;
+use crate::api::{ProviderErrorClass, RunStatus};
+use crate::config::RunsConfig;
+use crate::handlers::problem_details;
+use crate::store::{RunStore, StorageResult};
+
+/// Most finished runs kept; the oldest are forgotten first.
+pub const MAX_FINISHED_RUNS: usize = 10_000;
+
+/// A message run and, once finished, its outcome.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct Run {
+    pub run_id: String,
+    pub session_id: String,
+    /// Client-supplied labels, e.g. `order_id: A-1042`.
+    #[serde(default)]
+    pub labels: HashMap<String, String>,
+    pub status: RunStatus,
+    /// Status code the synchronous request would have returned.
+    pub http_status: Option<u16>,
+    /// Why the provider request failed, if the run failed on one.
+    pub error_class: Option<ProviderErrorClass>,
+    /// Body the synchronous request would have returned (background runs only).
+    pub result: Option<serde_json::Value>,
+    pub created_at: DateTime<Utc>,
+    pub finished_at: Option<DateTime<Utc>>,
+}
+
+impl Run {
+    pub fn is_finished(&self) -> bool {
+        self.finished_at.is_some()
+    }
+}
+
+/// A change for the run store's writer.
+enum Persist {
+    Save(Run),
+    Delete(String),
+    Flush(oneshot::Sender<()>),
+}
+
+/// Registry of message runs; cheap to clone.
+#[derive(Clone)]
+pub struct Runs {
+    runs: Arc<Mutex<HashMap<String, watch::Sender<Run>>>>,
+    /// Failed runs by error class, since startup.
+    errors: Arc<Mutex<BTreeMap<ProviderErrorClass, u64>>>,
+    /// How long a finished run can still be fetched.
+    retention: Duration,
+    /// Longest a background run may take.
+    timeout: Option<Duration>,
+    /// Writer that persists changes, in order, if runs are persisted.
+    writer: Option<mpsc::UnboundedSender<Persist>>,
+    /// Cancelled on shutdown to stop background runs.
+    shutdown: CancellationToken,
+}
+
+impl Default for Runs {
+    fn default() -> Self {
+        Self::with_config(&RunsConfig::default())
+    }
+}
+
+impl Runs {
+    /// In-memory runs with the default settings.
+    pub fn new() -> Self {
+        Self::default()
+    }
+
+    /// In-memory runs.
+    pub fn with_config(config: &RunsConfig) -> Self {
+        Self {
+            runs: Arc::default(),
+            errors: Arc::default(),
+            retention: Duration::from_secs(config.retention_hours * 3600),
+            timeout: (config.timeout_seconds > 0)
+                .then(|| Duration::from_secs(config.timeout_seconds)),
+            writer: None,
+            shutdown: CancellationToken::new(),
+        }
+    }
+
+    /// Runs persisted in `store`, starting with the runs kept before a
+    /// restart. Runs the restart left unfinished are recorded as failed
+    /// with a 503.
+    pub async fn load(config: &RunsConfig, store: Arc<dyn RunStore>) -> StorageResult<Self> {
+        let mut runs = Self::with_config(config);
+        let now = Utc::now();
+        let mut restored = HashMap::new();
+        let mut interrupted = Vec::new();
+        for mut run in store.list().await? {
+            if !run.is_finished() {
+                run.status = RunStatus::Failed;
+                run.http_status = Some(StatusCode::SERVICE_UNAVAILABLE.as_u16());
+                run.finished_at = Some(now);
+                interrupted.push(run.clone());
+            }
+            restored.insert(run.run_id.clone(), watch::channel(run).0);
+        }
+        for run in &interrupted {
+            store.save(run).await?;
+        }
+        if !interrupted.is_empty() {
+            warn!(
+                count = interrupted.len(),
+                "Recorded runs left unfinished by a restart as failed"
+            );
+        }
+        let forgotten = prune(&mut restored, now, runs.retention);
+        for run_id in &forgotten {
+            store.delete(run_id).await?;
+        }
+
+        *runs.runs.lock().expect("mutex poisoned") = restored;
+        runs.writer = Some(spawn_writer(store));
+        Ok(runs)
+    }
+
+    /// Record a run as started.
+    pub fn start(&self, run_id: &str, session_id: &str, labels: HashMap<String, String>) {
+        let run = Run {
+            run_id: run_id.to_string(),
+            session_id: session_id.to_string(),
+            labels,
+            status: RunStatus::Running,
+            http_status: None,
+            error_class: None,
+            result: None,
+            created_at: Utc::now(),
+            finished_at: None,
+        };
+        let forgotten = {
+            let mut runs = self.runs.lock().expect("mutex poisoned");
+            let forgotten = prune(&mut runs, Utc::now(), self.retention);
+            runs.insert(run_id.to_string(), watch::channel(run.clone()).0);
+            forgotten
+        };
+        for run_id in forgotten {
+            self.persist(Persist::Delete(run_id));
+        }
+        self.persist(Persist::Save(run));
+    }
+
+    /// Record a run's outcome from the status its request would have
+    /// returned, and wake anyone waiting on it.
+    pub fn finish(
+        &self,
+        run_id: &str,
+        http_status: StatusCode,
+        error_class: Option<ProviderErrorClass>,
+        result: Option<serde_json::Value>,
+    ) -> Option<Run> {
+        if let Some(class) = error_class {
+            *self
+                .errors
+                .lock()
+                .expect("mutex poisoned")
+                .entry(class)
+                .or_default() += 1;
+        }
+        let run = {
+            let runs = self.runs.lock().expect("mutex poisoned");
+            let tx = runs.get(run_id)?;
+            tx.send_modify(|run| {
+                run.status = run_status(http_status);
+                run.http_status = Some(http_status.as_u16());
+                run.error_class = error_class;
+                run.result = result;
+                run.finished_at = Some(Utc::now());
+            });
+            tx.borrow().clone()
+        };
+        self.persist(Persist::Save(run.clone()));
+        Some(run)
+    }
+
+    pub fn get(&self, run_id: &str) -> Option<Run> {
+        let runs = self.runs.lock().expect("mutex poisoned");
+        runs.get(run_id).map(|tx| tx.borrow().clone())
+    }
+
+    /// Follow a run: the receiver sees it as it is now and on every change.
+    pub fn subscribe(&self, run_id: &str) -> Option<watch::Receiver<Run>> {
+        let runs = self.runs.lock().expect("mutex poisoned");
+        runs.get(run_id).map(watch::Sender::subscribe)
+    }
+
+    /// Runs matching `selector`, newest first.
+    pub fn list(&self, selector: &LabelSelector) -> Vec<Run> {
+        let runs = self.runs.lock().expect("mutex poisoned");
+        let mut matching: Vec<Run> = runs
+            .values()
+            .map(|tx| tx.borrow().clone())
+            .filter(|run| selector.matches(&run.labels))
+            .collect();
+        matching.sort_by(|a, b| (b.created_at, &b.run_id).cmp(&(a.created_at, &a.run_id)));
+        matching
+    }
+
+    /// Failed runs by error class since startup, for `/metrics`.
+    pub fn error_counts(&self) -> Vec<(ProviderErrorClass, u64)> {
+        let errors = self.errors.lock().expect("mutex poisoned");
+        errors
+            .iter()
+            .map(|(class, count)| (*class, *count))
+            .collect()
+    }
+
+    /// The run once it finishes, or as it is after `timeout`.
+    pub async fn wait(&self, run_id: &str, timeout: Duration) -> Option<Run> {
+        let mut rx = self.subscribe(run_id)?;
+        let _ = tokio::time::timeout(timeout, rx.wait_for(Run::is_finished)).await;
+        let run = rx.borrow().clone();
+        Some(run)
+    }
+
+    /// Do a background run's work, stopping it once it takes longer than
+    /// the run timeout or when the server shuts down.
+    pub async fn bounded(&self, work: impl Future<Output = Response>) -> Response {
+        let work = async {
+            let Some(timeout) = self.timeout else {
+                return work.await;
+            };
+            match tokio::time::timeout(timeout, work).await {
+                Ok(response) => response,
+                Err(_) => problem_details::run_budget_exceeded(format!(
+                    "run exceeded the {}s run timeout",
+                    timeout.as_secs()
+                ))
+                .into_response(),
+            }
+        };
+        tokio::select! {
+            response = work => response,
+            () = self.shutdown.cancelled() => {
+                problem_details::run_interrupted("run stopped by server shutdown").into_response()
+            }
+        }
+    }
+
+    /// Stop every background run in progress; see [`Runs::bounded`].
+    pub fn shutdown(&self) {
+        self.shutdown.cancel();
+    }
+
+    /// Resolves once [`Runs::shutdown`] is called, for other background work
+    /// that should stop with the runs.
+    pub async fn shutting_down(&self) {
+        self.shutdown.cancelled().await;
+    }
+
+    /// Wait until every change so far is persisted.
+    pub async fn flush(&self) {
+        let Some(writer) = &self.writer else {
+            return;
+        };
+        let (tx, rx) = oneshot::channel();
+        if writer.send(Persist::Flush(tx)).is_ok() {
+            let _ = rx.await;
+        }
+    }
+
+    fn persist(&self, change: Persist) {
+        if let Some(writer) = &self.writer
+            && writer.send(change).is_err()
+        {
+            error!("Run store writer stopped; run changes are not persisted");
+        }
+    }
+}
+
+/// Spawn the task that applies changes to `store` in the order they're sent.
+fn spawn_writer(store: Arc<dyn RunStore>) -> mpsc::UnboundedSender<Persist> {
+    let (tx, mut rx) = mpsc::unbounded_channel();
+    tokio::spawn(async move {
+        while let Some(change) = rx.recv().await {
+            let (run_id, result) = match change {
+                Persist::Save(run) => (run.run_id.clone(), store.save(&run).await),
+                Persist::Delete(run_id) => {
+                    let result = store.delete(&run_id).await;
+                    (run_id, result)
+                }
+                Persist::Flush(done) => {
+                    let _ = done.send(());
+                    continue;
+                }
+            };
+            if let Err(e) = result {
+                error!(run_id = %run_id, error = %e, "Failed to persist run");
+            }
+        }
+    });
+    tx
+}
+
+/// The provider error class a run's response was built with, if any.
+pub fn error_class(response: &Response) -> Option<ProviderErrorClass> {
+    response.extensions().get().copied()
+}
+
+/// Read the JSON body of a finished run's response, with its status.
+pub async fn read_result(response: Response) -> (StatusCode, serde_json::Value) {
+    let status = response.status();
+    let result = match body::to_bytes(response.into_body(), usize::MAX).await {
+        Ok(bytes) => serde_json::from_slice(&bytes).unwrap_or(serde_json::Value::Null),
+        Err(e) => {
+            error!(error = %e, "Failed to read run response");
+            serde_json::Value::Null
+        }
+    };
+    (status, result)
+}
+
+fn run_status(status: StatusCode) -> RunStatus {
+    match status {
+        StatusCode::OK => RunStatus::Completed,
+        StatusCode::ACCEPTED => RunStatus::AwaitingApproval,
+        _ => RunStatus::Failed,
+    }
+}
+
+/// Forget runs that finished more than `retention` ago, then the oldest
+/// finished runs beyond [`MAX_FINISHED_RUNS`]. Returns the forgotten run IDs.
+fn prune(
+    runs: &mut HashMap<String, watch::Sender<Run>>,
+    now: DateTime<Utc>,
+    retention: Duration,
+) -> Vec<String> {
+    let retention = chrono::Duration::from_std(retention).unwrap_or(chrono::Duration::MAX);
+    let mut finished: Vec<(DateTime<Utc>, String)> = runs
+        .iter()
+        .filter_map(|(id, tx)| Some((tx.borrow().finished_at?, id.clone())))
+        .collect();
+    finished.sort();
+
+    let expired = finished
+        .iter()
+        .take_while(|(finished_at, _)| now - *finished_at >= retention)
+        .count();
+    let excess = finished.len().saturating_sub(MAX_FINISHED_RUNS);
+    let forgotten: Vec<String> = finished
+        .drain(..expired.max(excess))
+        .map(|(_, id)| id)
+        .collect();
+    for id in &forgotten {
+        runs.remove(id);
+    }
+    forgotten
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn complete(runs: &Runs, run_id: &str) {
+        runs.finish(
+            run_id,
+            StatusCode::OK,
+            None,
+            Some(serde_json::json!({ "content": "done" })),
+        );
+    }
+
+    fn labels(pairs: &[(&str, &str)]) -> HashMap<String, String> {
+        pairs
+            .iter()
+            .map(|(k, v)| (k.to_string(), v.to_string()))
+            .collect()
+    }
+
+    #[tokio::test]
+    async fn wait_returns_when_the_run_finishes() {
+        let runs = Runs::new();
+        runs.start("run_1", "session_1", HashMap::new());
+
+        let waiter = {
+            let runs = runs.clone();
+            tokio::spawn(async move { runs.wait("run_1", Duration::from_secs(10)).await })
+        };
+        tokio::task::yield_now().await;
+        complete(&runs, "run_1");
+
+        let run = waiter.await.unwrap().unwrap();
+        assert_eq!(run.status, RunStatus::Completed);
+        assert_eq!(run.http_status, Some(200));
+        assert_eq!(run.result.unwrap()["content"], "done");
+    }
+
+    #[tokio::test]
+    async fn wait_times_out_with_the_run_in_progress() {
+        let runs = Runs::new();
+        runs.start("run_1", "session_1", HashMap::new());
+
+        let run = runs.wait("run_1", Duration::from_millis(10)).await.unwrap();
+        assert_eq!(run.status, RunStatus::Running);
+        assert!(run.result.is_none());
+        assert!(runs.wait("run_2", Duration::ZERO).await.is_none());
+    }
+
+    #[test]
+    fn finished_runs_expire() {
+        let runs = Runs::new();
+        runs.start("run_1", "session_1", HashMap::new());
+        runs.start("run_2", "session_1", HashMap::new());
+        complete(&runs, "run_1");
+
+        let later = Utc::now() + chrono::Duration::hours(2);
+        let mut map = runs.runs.lock().unwrap();
+        let forgotten = prune(&mut map, later, Duration::from_secs(3600));
+        assert_eq!(forgotten, ["run_1"]);
+        assert!(!map.contains_key("run_1"));
+        assert!(map.contains_key("run_2"));
+    }
+
+    #[test]
+    fn list_filters_by_label_newest_first() {
+        let runs = Runs::new();
+        runs.start("run_1", "session_1", labels(&[("order_id", "A-1")]));
+        runs.start("run_2", "session_1", labels(&[("order_id", "A-2")]));
+        runs.start("run_3", "session_2", labels(&[("order_id", "A-1")]));
+
+        let selector: LabelSelector = "order_id=A-1".parse().unwrap();
+        let ids: Vec<_> = runs
+            .list(&selector)
+            .into_iter()
+            .map(|run| run.run_id)
+            .collect();
+        assert_eq!(ids, ["run_3", "run_1"]);
+        assert_eq!(runs.list(&LabelSelector::default()).len(), 3);
+    }
+
+    #[tokio::test]
+    async fn read_result_parses_json_bodies() {
+        use axum::response::IntoResponse;
+
+        let response =
+            crate::handlers::problem_details::provider_error("llm request failed").into_response();
+        let (status, result) = read_result(response).await;
+        assert_eq!(status, StatusCode::BAD_GATEWAY);
+        assert_eq!(result["detail"], "llm request failed");
+
+        let (_, result) = read_result((StatusCode::OK, "not json").into_response()).await;
+        assert!(result.is_null());
+    }
+
+    #[test]
+    fn failed_runs_record_and_count_their_error_class() {
+        use axum::response::IntoResponse;
+
+        let runs = Runs::new();
+        runs.start("run_1", "session_1", HashMap::new());
+        runs.start("run_2", "session_1", HashMap::new());
+
+        let response = crate::handlers::problem_details::provider_error("llm request failed")
+            .with_error_class(Some(ProviderErrorClass::RateLimited))
+            .into_response();
+        let class = error_class(&response);
+        assert_eq!(class, Some(ProviderErrorClass::RateLimited));
+
+        let run = runs
+            .finish("run_1", response.status(), class, None)
+            .unwrap();
+        assert_eq!(run.error_class, Some(ProviderErrorClass::RateLimited));
+        runs.finish("run_2", StatusCode::BAD_GATEWAY, class, None);
+        assert_eq!(runs.error_counts(), [(ProviderErrorClass::RateLimited, 2)]);
+    }
+
+    #[test]
+    fn finish_maps_the_response_status() {
+        let runs = Runs::new();
+        runs.start("run_1", "session_1", HashMap::new());
+        runs.start("run_2", "session_1", HashMap::new());
+
+        let run = runs
+            .finish("run_1", StatusCode::ACCEPTED, None, None)
+            .unwrap();
+        assert_eq!(run.status, RunStatus::AwaitingApproval);
+        let run = runs
+            .finish("run_2", StatusCode::BAD_GATEWAY, None, None)
+            .unwrap();
+        assert_eq!(run.status, RunStatus::Failed);
+        assert_eq!(run.http_status, Some(502));
+        assert!(runs.finish("run_3", StatusCode::OK, None, None).is_none());
+    }
+
+    #[tokio::test]
+    async fn load_restores_runs_and_interrupts_unfinished_ones() {
+        use crate::store::file::FileRunStore;
+
+        let temp_dir = tempfile::TempDir::new().unwrap();
+        let store = Arc::new(FileRunStore::new(temp_dir.path()));
+        let config = RunsConfig::default();
+
+        let runs = Runs::load(&config, store.clone()).await.unwrap();
+        runs.start("run_1", "session_1", labels(&[("order_id", "A-1")]));
+        runs.start("run_2", "session_1", HashMap::new());
+        complete(&runs, "run_1");
+        runs.flush().await;
+
+        // As after a restart
+        let runs = Runs::load(&config, store.clone()).await.unwrap();
+        let run = runs.get("run_1").unwrap();
+        assert_eq!(run.status, RunStatus::Completed);
+        assert_eq!(run.labels["order_id"], "A-1");
+        let run = runs.get("run_2").unwrap();
+        assert_eq!(run.status, RunStatus::Failed);
+        assert_eq!(run.http_status, Some(503));
+        assert!(run.is_finished());
+        assert_eq!(store.list().await.unwrap()[1].status, RunStatus::Failed);
+    }
+
+    #[tokio::test]
+    async fn bounded_stops_runs_on_timeout_and_shutdown() {
+        let slow = || async {
+            tokio::time::sleep(Duration::from_secs(60)).await;
+            StatusCode::OK.into_response()
+        };
+
+        let runs = Runs::with_config(&RunsConfig {
+            timeout_seconds: 1,
+            ..Default::default()
+        });
+        let response = runs.bounded(slow()).await;
+        assert_eq!(response.status(), StatusCode::UNPROCESSABLE_ENTITY);
+
+        let runs = Runs::new();
+        runs.shutdown();
+        let response = runs.bounded(slow()).await;
+        assert_eq!(response.status(), StatusCode::SERVICE_UNAVAILABLE);
+    }
+}
@@ -0,0 +1,179 @@
+//! Dead letter classification and selection.
+//!
+//! Dead letters only keep their last error as text, so bulk recovery after
+//! an outage works from that text: [`ErrorClass::classify`] sorts errors
+//! into coarse classes, and a [`DeadLetterFilter`] picks the dead letters
+//! that failed in a time window with a given class.
+
+use chrono::{DateTime, Utc};
+
+use crate::scheduler::DeadLetter;
+
+/// Coarse cause of a dead-lettered run's last failure.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum ErrorClass {
+    /// The LLM provider rate limited the run.
+    RateLimit,
+    /// The LLM provider failed or timed out.
+    Provider,
+    /// The destination gateway was unavailable.
+    Gateway,
+    /// The agent was missing or disabled.
+    Agent,
+    /// Anything else, e.g. tool failures or storage errors.
+    Other,
+}
+
+impl ErrorClass {
+    pub const ALL: [ErrorClass; 5] = [
+        Self::RateLimit,
+        Self::Provider,
+        Self::Gateway,
+        Self::Agent,
+        Self::Other,
+    ];
+
+    pub fn as_str(self) -> &'static str {
+        match self {
+            Self::RateLimit => "rate_limit",
+            Self::Provider => "provider",
+            Self::Gateway => "gateway",
+            Self::Agent => "agent",
+            Self::Other => "other",
+        }
+    }
+
+    pub fn parse(value: &str) -> Option<Self> {
+        Self::ALL.into_iter().find(|class| class.as_str() == value)
+    }
+
+    /// Classify a dead letter's error message.
+    pub fn classify(error: &str) -> Self {
+        if error.contains("rate limited") {
+            Self::RateLimit
+        } else if error.contains("llm error")
+            || error.contains("llm call timed out")
+            || error.contains("http request failed")
+            || error.contains("api error (status")
+        {
+            Self::Provider
+        } else if error.starts_with("gateway not available") {
+            Self::Gateway
+        } else if error.starts_with("agent not found") || error.starts_with("agent disabled") {
+            Self::Agent
+        } else {
+            Self::Other
+        }
+    }
+}
+
+/// Selects dead letters for bulk requeueing. Unset fields match everything.
+#[derive(Debug, Clone, Default)]
+pub struct DeadLetterFilter {
+    /// Failed at or after this time.
+    pub since: Option<DateTime<Utc>>,
+    /// Failed before this time.
+    pub until: Option<DateTime<Utc>>,
+    pub error_class: Option<ErrorClass>,
+    pub agent: Option<String>,
+}
+
+impl DeadLetterFilter {
+    pub fn matches(&self, dead_letter: &DeadLetter) -> bool {
+        self.since
+            .is_none_or(|since| dead_letter.failed_at >= since)
+            && self.until.is_none_or(|until| dead_letter.failed_at < until)
+            && self
+                .error_class
+                .is_none_or(|class| ErrorClass::classify(&dead_letter.error) == class)
+            && self
+                .agent
+                .as_ref()
+                .is_none_or(|agent| &dead_letter.agent == agent)
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::scheduler::{ScheduleDestination, SchedulePayload};
+
+    fn dead_letter(agent: &str, error: &str, minutes_ago: i64) -> DeadLetter {
+        let failed_at = Utc::now() - chrono::Duration::minutes(minutes_ago);
+        DeadLetter {
+            id: "dl_1".to_string(),
+            schedule_id: "sched_1".to_string(),
+            agent: agent.to_string(),
+            created_by_session: "session_123".to_string(),
+            destination: ScheduleDestination {
+                gateway: "telegram".to_string(),
+                chat_id: "12345".to_string(),
+            },
+            payload: SchedulePayload::Task {
+                task: "Check status".to_string(),
+            },
+            retry: None,
+            attempts: 4,
+            error: error.to_string(),
+            started_at: failed_at,
+            failed_at,
+        }
+    }
+
+    #[test]
+    fn classifies_scheduler_errors() {
+        let cases = [
+            (
+                "execution failed: llm error: rate limited (retry after Some(30)s)",
+                ErrorClass::RateLimit,
+            ),
+            (
+                "execution failed: llm error: api error (status 503): overloaded",
+                ErrorClass::Provider,
+            ),
+            (
+                "execution failed: llm call timed out after 120 seconds",
+                ErrorClass::Provider,
+            ),
+            ("gateway not available: telegram", ErrorClass::Gateway),
+            ("agent disabled: helper", ErrorClass::Agent),
+            (
+                "execution failed: tool error: command failed",
+                ErrorClass::Other,
+            ),
+        ];
+        for (error, class) in cases {
+            assert_eq!(ErrorClass::classify(error), class, "{error}");
+        }
+    }
+
+    #[test]
+    fn parses_class_names() {
+        for class in ErrorClass::ALL {
+            assert_eq!(ErrorClass::parse(class.as_str()), Some(class));
+        }
+        assert_eq!(ErrorClass::parse("timeout"), None);
+    }
+
+    #[test]
+    fn filter_selects_by_window_class_and_agent() {
+        let rate_limited = "execution failed: llm error: rate limited (retry after None s)";
+        let filter = DeadLetterFilter {
+            since: Some(Utc::now() - chrono::Duration::hours(1)),
+            until: Some(Utc::now() - chrono::Duration::minutes(10)),
+            error_class: Some(ErrorClass::RateLimit),
+            agent: None,
+        };
+        assert!(filter.matches(&dead_letter("a", rate_limited, 30)));
+        assert!(!filter.matches(&dead_letter("a", rate_limited, 5)));
+        assert!(!filter.matches(&dead_letter("a", rate_limited, 90)));
+        assert!(!filter.matches(&dead_letter("a", "agent disabled: a", 30)));
+
+        let filter = DeadLetterFilter {
+            agent: Some("b".to_string()),
+            ..Default::default()
+        };
+        assert!(filter.matches(&dead_letter("b", "anything", 30)));
+        assert!(!filter.matches(&dead_letter("a", "anything", 30)));
+    }
+}
@@ -37,6 +37,14 @@ pub enum SchedulerError {
     #[error("agent not found: {0}")]
     AgentNotFound(String),
 
+    /// Agent is disabled.
+    #[error("agent disabled: {0}")]
+    AgentDisabled(String),
+
+    /// Dead letter not found.
+    #[error("dead letter not found: {0}")]
+    DeadLetterNotFound(String),
+
     /// Not authorized to modify this schedule.
     #[error("not authorized: schedule belongs to agent '{0}'")]
     NotAuthorized(String),
@@ -9,13 +9,15 @@
 // Re-export scheduler domain types from duragent-types
 pub use duragent_types::scheduler::*;
 
+pub mod dead_letter;
 pub mod error;
 pub mod schedule_cache;
 mod schedule_eval;
 pub mod service;
 
-pub use schedule_eval::{RetryConfigEval, generate_schedule_id};
+pub use schedule_eval::{RetryConfigEval, generate_dead_letter_id, generate_schedule_id};
 
+pub use dead_letter::{DeadLetterFilter, ErrorClass};
 pub use error::{Result, SchedulerError};
 pub use schedule_cache::{LoadResult, ScheduleCache};
 pub use service::{SchedulerConfig, SchedulerHandle, SchedulerService};
@@ -1,6 +1,6 @@
 //! Evaluation methods for scheduler types.
 //!
-//! Extends `RetryConfig` with backoff logic and provides `generate_schedule_id`.
+//! Extends `RetryConfig` with backoff logic and provides ID generators.
 //! The data definitions live in `duragent-types`; evaluation lives here.
 
 use std::time::Duration;
@@ -12,6 +12,11 @@ pub fn generate_schedule_id() -> ScheduleId {
     format!("sched_{}", ulid::Ulid::new())
 }
 
+/// Generate a new unique dead letter ID.
+pub fn generate_dead_letter_id() -> String {
+    format!("dl_{}", ulid::Ulid::new())
+}
+
 /// Extension trait for `RetryConfig` evaluation logic.
 pub trait RetryConfigEval {
     /// Calculate the delay for a given attempt using exponential backoff with jitter.
@@ -43,6 +48,11 @@ mod tests {
         assert!(id1.starts_with("sched_"));
     }
 
+    #[test]
+    fn dead_letter_id_has_prefix() {
+        assert!(generate_dead_letter_id().starts_with("dl_"));
+    }
+
     #[test]
     fn retry_config_delay_exponential_backoff() {
         let config = RetryConfig {
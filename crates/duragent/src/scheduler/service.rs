@@ -12,22 +12,24 @@ use tokio::sync::{RwLock, Semaphore, mpsc, oneshot};
 use tokio::time::Instant;
 use tracing::{debug, error, info, warn};
 
-use crate::agent::ModelConfigEval;
+use crate::agent::{ChangeSource, ModelConfigEval, RunOrigin};
 use crate::context::{ContextBuilder, TokenBudget, load_all_directives_async};
 use crate::gateway::GatewaySender;
 use crate::process::ProcessRegistryHandle;
 use crate::server::RuntimeServices;
-use crate::session::{AgenticResult, ChatSessionCache, SessionHandle, run_agentic_loop};
-use crate::store::{RunLogStore, ScheduleStore as ScheduleStoreTrait};
+use crate::session::{
+    AgenticResult, ChatSessionCache, RunPriority, SessionHandle, run_agentic_loop,
+};
+use crate::store::{DeadLetterStore, RunLogStore, ScheduleStore as ScheduleStoreTrait};
 use crate::tools::{ReloadDeps, ToolDependencies, build_executor_async};
 
-use super::RetryConfigEval;
 use super::error::{Result, SchedulerError};
 use super::schedule_cache::ScheduleCache;
 use super::{
-    RunLogEntry, RunStatus, Schedule, ScheduleId, SchedulePayload, ScheduleState, ScheduleStatus,
-    ScheduleTiming,
+    DeadLetter, RunLogEntry, RunStatus, Schedule, ScheduleId, SchedulePayload, ScheduleState,
+    ScheduleStatus, ScheduleTiming,
 };
+use super::{RetryConfigEval, generate_dead_letter_id, generate_schedule_id};
 
 use std::str::FromStr;
 
@@ -42,6 +44,9 @@ const STUCK_RUN_TIMEOUT_SECS: i64 = 2 * 60 * 60;
 /// Type alias for the run log store trait object.
 type RunLogStoreRef = Arc<dyn RunLogStore>;
 
+/// Type alias for the dead letter store trait object.
+type DeadLetterStoreRef = Arc<dyn DeadLetterStore>;
+
 // ============================================================================
 // Public API
 // ============================================================================
@@ -51,6 +56,7 @@ type RunLogStoreRef = Arc<dyn RunLogStore>;
 pub struct SchedulerHandle {
     command_tx: mpsc::Sender<SchedulerCommand>,
     cache: ScheduleCache,
+    dead_letters: DeadLetterStoreRef,
 }
 
 impl SchedulerHandle {
@@ -138,6 +144,55 @@ impl SchedulerHandle {
         }
     }
 
+    /// List runs that failed after exhausting their retries, oldest first.
+    pub async fn list_dead_letters(&self) -> Result<Vec<DeadLetter>> {
+        self.dead_letters
+            .list()
+            .await
+            .map_err(|e| SchedulerError::Storage(e.to_string()))
+    }
+
+    /// Requeue a dead-lettered run.
+    ///
+    /// Creates a one-shot schedule that fires immediately with the original
+    /// payload, destination, and retry configuration, then drops the dead
+    /// letter. Returns the new schedule's ID.
+    pub async fn requeue_dead_letter(&self, id: &str) -> Result<ScheduleId> {
+        if !is_valid_dead_letter_id(id) {
+            return Err(SchedulerError::DeadLetterNotFound(id.to_string()));
+        }
+        let dead_letter = self
+            .dead_letters
+            .load(id)
+            .await
+            .map_err(|e| SchedulerError::Storage(e.to_string()))?
+            .ok_or_else(|| SchedulerError::DeadLetterNotFound(id.to_string()))?;
+
+        let schedule = Schedule {
+            id: generate_schedule_id(),
+            agent: dead_letter.agent,
+            created_by_session: dead_letter.created_by_session,
+            destination: dead_letter.destination,
+            // One-shot timings must be in the future; fire on the next tick
+            timing: ScheduleTiming::At {
+                at: Utc::now() + chrono::Duration::seconds(1),
+            },
+            payload: dead_letter.payload,
+            created_at: Utc::now(),
+            status: ScheduleStatus::Active,
+            retry: dead_letter.retry,
+            process_handle: None,
+        };
+        let schedule_id = self.create_schedule(schedule).await?;
+
+        if let Err(e) = self.dead_letters.delete(id).await {
+            warn!(dead_letter_id = %id, error = %e, "Failed to remove requeued dead letter");
+        }
+        info!(dead_letter_id = %id, schedule_id = %schedule_id, "Requeued dead letter");
+
+        Ok(schedule_id)
+    }
+
     /// Shutdown the scheduler.
     pub async fn shutdown(&self) {
         if self
@@ -159,6 +214,8 @@ pub struct SchedulerConfig {
     pub schedule_store: Arc<dyn ScheduleStoreTrait>,
     /// Storage backend for run log persistence.
     pub run_log_store: RunLogStoreRef,
+    /// Storage backend for runs that failed after exhausting retries.
+    pub dead_letter_store: DeadLetterStoreRef,
     pub chat_session_cache: ChatSessionCache,
     /// Process registry, set after scheduler starts via OnceLock to break circular dependency.
     pub process_registry: Arc<OnceLock<ProcessRegistryHandle>>,
@@ -198,6 +255,7 @@ impl SchedulerService {
         let handle = SchedulerHandle {
             command_tx,
             cache: self.cache.clone(),
+            dead_letters: self.config.dead_letter_store.clone(),
         };
 
         // Load existing schedules
@@ -298,6 +356,7 @@ impl SchedulerService {
             gateway_sender: self.config.gateway_sender.clone(),
             chat_session_cache: self.config.chat_session_cache.clone(),
             process_registry: self.config.process_registry.clone(),
+            dead_letters: self.config.dead_letter_store.clone(),
         };
 
         tokio::spawn(async move {
@@ -384,6 +443,7 @@ struct SchedulerConfigRef {
     gateway_sender: GatewaySender,
     chat_session_cache: ChatSessionCache,
     process_registry: Arc<OnceLock<ProcessRegistryHandle>>,
+    dead_letters: DeadLetterStoreRef,
 }
 
 /// Execute a schedule.
@@ -539,6 +599,11 @@ fn execute_schedule(
         };
         let _ = run_log.append(&schedule_id, &entry).await;
 
+        // Keep the failed run for inspection and requeue
+        if let Err(e) = &result {
+            dead_letter(&config, &schedule, attempts_made, e, start).await;
+        }
+
         // Handle completion or rescheduling
         if schedule.is_one_shot() {
             // Mark as completed
@@ -611,6 +676,50 @@ fn execute_schedule(
     })
 }
 
+/// Record a run that failed after exhausting its retries.
+async fn dead_letter(
+    config: &SchedulerConfigRef,
+    schedule: &Schedule,
+    attempts: u8,
+    error: &SchedulerError,
+    started_at: DateTime<Utc>,
+) {
+    let dead_letter = DeadLetter {
+        id: generate_dead_letter_id(),
+        schedule_id: schedule.id.clone(),
+        agent: schedule.agent.clone(),
+        created_by_session: schedule.created_by_session.clone(),
+        destination: schedule.destination.clone(),
+        payload: schedule.payload.clone(),
+        retry: schedule.retry.clone(),
+        attempts,
+        error: error.to_string(),
+        started_at,
+        failed_at: Utc::now(),
+    };
+
+    match config.dead_letters.save(&dead_letter).await {
+        Ok(()) => warn!(
+            schedule_id = %schedule.id,
+            dead_letter_id = %dead_letter.id,
+            attempts,
+            "Scheduled run moved to dead letter queue"
+        ),
+        Err(e) => error!(
+            schedule_id = %schedule.id,
+            error = %e,
+            "Failed to record dead letter"
+        ),
+    }
+}
+
+/// Dead letter IDs are generated by `generate_dead_letter_id`; reject anything
+/// else before it reaches the store as a path.
+fn is_valid_dead_letter_id(id: &str) -> bool {
+    id.strip_prefix("dl_")
+        .is_some_and(|rest| !rest.is_empty() && rest.chars().all(|c| c.is_ascii_alphanumeric()))
+}
+
 /// Execute a message payload (simple send, no LLM).
 async fn execute_message_payload(
     config: &SchedulerConfigRef,
@@ -643,6 +752,11 @@ async fn execute_task_payload(
         .agents
         .get(&schedule.agent)
         .ok_or_else(|| SchedulerError::AgentNotFound(schedule.agent.clone()))?;
+    if !agent.enabled {
+        return Err(SchedulerError::AgentDisabled(schedule.agent.clone()).into());
+    }
+    let agent = config.services.route_agent(agent, task).await;
+    let agent = config.services.route_model(agent, task).await;
 
     // Get provider
     let provider = config
@@ -683,7 +797,11 @@ async fn execute_task_payload(
 
     // Create tool executor (without execution context - schedules don't create nested schedules)
     // Load policy dynamically so AllowAlways approvals take effect immediately
-    let policy = config.services.policy_store.load(&schedule.agent).await;
+    let policy = config
+        .services
+        .policy_store
+        .load(&agent.metadata.name)
+        .await;
     let deps = ToolDependencies {
         sandbox: config.services.sandbox.clone(),
         agent_dir: agent.agent_dir.clone(),
@@ -692,12 +810,12 @@ async fn execute_task_payload(
         workspace_tools_dir: Some(config.services.workspace_tools_path.clone()),
         process_registry: config.process_registry.get().cloned(),
         session_id: Some(handle.id().to_string()),
-        agent_name: Some(schedule.agent.clone()),
+        agent_name: Some(agent.metadata.name.clone()),
         session_registry: Some(config.services.session_registry.clone()),
     };
     let mut executor = build_executor_async(
         agent.clone(),
-        schedule.agent.clone(),
+        agent.metadata.name.clone(),
         handle.id().to_string(),
         policy,
         deps,
@@ -710,7 +828,8 @@ async fn execute_task_payload(
         agent_dir: agent.agent_dir.clone(),
         workspace_tools_dir: Some(config.services.workspace_tools_path.clone()),
         agent_tool_configs: agent.tools.clone(),
-    });
+    })
+    .with_mocks(config.services.tool_mocks.clone());
 
     // Build messages using StructuredContext
     let history = handle.get_messages().await.unwrap_or_default();
@@ -725,8 +844,13 @@ async fn execute_task_payload(
         max_output_tokens: agent.model.max_output_tokens.unwrap_or(4096),
         max_history_tokens: agent.session.context.max_history_tokens,
     };
+    let blocks = config
+        .services
+        .context_blocks(&agent, &provider, &handle, &history)
+        .await;
     let messages = ContextBuilder::new()
         .from_agent_spec(&agent)
+        .with_blocks(blocks)
         .with_messages(history)
         .with_directives(directives)
         .build()
@@ -742,6 +866,12 @@ async fn execute_task_payload(
     // Acquire per-session agentic loop lock (wait for active session to finish)
     let loop_lock = config.services.agentic_loop_locks.get(handle.id());
     let _loop_guard = loop_lock.lock().await;
+    // Scheduled work yields to interactive runs when the pool is saturated
+    let _permit = config.services.run_pool.acquire(RunPriority::Low).await;
+    let provider = config
+        .services
+        .run_provider(&agent, provider, &handle)
+        .await;
 
     // Run agentic loop with SessionHandle
     let result = run_agentic_loop(
@@ -836,6 +966,11 @@ async fn get_or_create_session(
                                 silent_buffer_cap: crate::session::DEFAULT_SILENT_BUFFER_CAP,
                                 actor_message_limit: crate::session::DEFAULT_ACTOR_MESSAGE_LIMIT,
                                 compaction_override,
+                                origin: Some(RunOrigin {
+                                    source: ChangeSource::Scheduler,
+                                    created_by: None,
+                                    user: None,
+                                }),
                             },
                         )
                         .await?;
@@ -1044,4 +1179,12 @@ mod tests {
         let next = calculate_next_run(&timing, None).unwrap();
         assert!(next > Utc::now());
     }
+
+    #[test]
+    fn dead_letter_id_validation() {
+        assert!(is_valid_dead_letter_id(&generate_dead_letter_id()));
+        assert!(!is_valid_dead_letter_id("dl_"));
+        assert!(!is_valid_dead_letter_id("dl_../../etc/passwd"));
+        assert!(!is_valid_dead_letter_id("sched_01ABC"));
+    }
 }
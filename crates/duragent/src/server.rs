@@ -1,27 +1,63 @@
 use std::path::PathBuf;
 use std::sync::Arc;
-use std::time::Duration;
 
-use axum::Router;
 use axum::extract::DefaultBodyLimit;
-use axum::http::StatusCode;
-use axum::routing::{get, post};
+use axum::routing::{delete, get, post, put};
+use axum::{Extension, Router};
 use tokio::sync::{Mutex, oneshot};
 use tower::limit::ConcurrencyLimitLayer;
-use tower_http::timeout::TimeoutLayer;
+use tracing::warn;
 
 use dashmap::DashMap;
 
-use crate::agent::{AgentStore, PolicyLocks};
+use crate::agent::{AgentSpec, AgentStore, PolicyLocks};
+use crate::agent_routing;
+use crate::artifacts::Artifacts;
+use crate::audit::AuditLog;
 use crate::background::BackgroundTasks;
+use crate::best_of;
+use crate::callbacks::Callbacks;
+use crate::config::{ScimConfig, StatusPageConfig};
+use crate::context::SystemBlock;
+use crate::ensemble;
+use crate::examples::ExampleLibrary;
+use crate::faults::FaultInjector;
+use crate::features::FeatureFlags;
 use crate::handlers;
-use crate::llm::ProviderRegistry;
+pub use crate::handlers::access_log::AccessLog;
+pub use crate::handlers::debug_capture::DebugCapture;
+use crate::handlers::request_schemas::RequestSchemas;
+pub use crate::handlers::timeouts::RouteTimeouts;
+use crate::health::HealthHistory;
+use crate::language;
+use crate::llm::{LLMProvider, Message, ProviderRegistry, Role};
+use crate::memory::entities;
+use crate::metrics::HttpMetrics;
+use crate::model_routing;
+use crate::models::ModelRegistry;
+use crate::policy::Policies;
 use crate::process::ProcessRegistryHandle;
+use crate::prompts::PromptLibrary;
+use crate::public_agents::PublicAgents;
+use crate::runs::Runs;
 use crate::sandbox::Sandbox;
 use crate::scheduler::SchedulerHandle;
-use crate::session::{ChatSessionCache, SessionRegistry, SteeringSender};
-use crate::store::PolicyStore;
+use crate::service_accounts::ServiceAccounts;
+use crate::session::{
+    ChatSessionCache, RunPool, SessionArchiver, SessionHandle, SessionRegistry, SteeringSender,
+    StreamBuffers,
+};
+use crate::shares::Shares;
+use crate::slo::ProviderSlos;
+use crate::store::{IdentityStore, PolicyStore};
 use crate::sync::KeyedLocks;
+use crate::tools::ToolMocks;
+use crate::upgrade::UpgradeTrigger;
+use crate::usage::UsageRollups;
+use crate::user_memory::UserMemory;
+
+/// Maximum request body size for `/api/v1` routes.
+pub const MAX_REQUEST_BODY_BYTES: usize = 2 * 1024 * 1024;
 
 // ============================================================================
 // Runtime Services
@@ -42,6 +78,135 @@ pub struct RuntimeServices {
     pub agentic_loop_locks: KeyedLocks,
     /// Per-session steering channels for injecting messages into running loops.
     pub steering_channels: Arc<DashMap<String, SteeringSender>>,
+    /// Shared admission pool limiting concurrent LLM runs.
+    pub run_pool: RunPool,
+    /// Few-shot example pools.
+    pub examples: ExampleLibrary,
+    /// Shared prompts included by agent manifests.
+    pub prompts: PromptLibrary,
+    /// Long-term memory of end users.
+    pub user_memory: UserMemory,
+    /// Model capabilities and pricing.
+    pub models: ModelRegistry,
+    /// Tools answered with canned data instead of being run.
+    pub tool_mocks: ToolMocks,
+}
+
+impl RuntimeServices {
+    /// Blocks added to an agent's manifest prompts for a run: included
+    /// shared prompts, few-shot examples for the latest user message, and
+    /// facts remembered about the session's end user.
+    ///
+    /// Also starts learning new facts about that user, and the entities and
+    /// relations mentioned, from the latest message. For agents with
+    /// `language`, adds the reply language and records the detected one.
+    pub async fn context_blocks(
+        &self,
+        agent: &AgentSpec,
+        provider: &Arc<dyn LLMProvider>,
+        session: &SessionHandle,
+        history: &[Message],
+    ) -> Vec<SystemBlock> {
+        let user = session
+            .get_metadata()
+            .await
+            .ok()
+            .and_then(|m| m.origin)
+            .and_then(|o| o.user);
+        let user = user.as_deref();
+        self.user_memory
+            .learn(agent, provider.clone(), user, history);
+        entities::learn(agent, provider.clone(), session.id(), history);
+        let mut blocks = self.prompts.blocks_for(agent);
+        blocks.extend(
+            self.examples
+                .block_for(agent, provider.as_ref(), history)
+                .await,
+        );
+        blocks.extend(
+            self.user_memory
+                .block_for(agent, provider.as_ref(), user, history)
+                .await,
+        );
+        blocks.extend(localize(agent, session, history).await);
+        blocks
+    }
+
+    /// The provider for the model turns of a run: answered by ensemble for
+    /// agents with `ensemble`, sampled best-of for agents with `best_of`.
+    pub async fn run_provider(
+        &self,
+        agent: &AgentSpec,
+        provider: Arc<dyn LLMProvider>,
+        session: &SessionHandle,
+    ) -> Arc<dyn LLMProvider> {
+        let provider =
+            ensemble::wrap(agent, provider, &self.agents, &self.providers, session).await;
+        best_of::wrap(agent, provider, &self.providers, session).await
+    }
+
+    /// The agent to answer `input`: the downstream agent picked by a router
+    /// agent's `router` rules, or `agent` itself.
+    pub async fn route_agent(&self, agent: Arc<AgentSpec>, input: &str) -> Arc<AgentSpec> {
+        agent_routing::route(agent, &self.agents, &self.providers, input).await
+    }
+
+    /// The agent to run `input` with, after its `model_routing` rules.
+    pub async fn route_model(&self, agent: Arc<AgentSpec>, input: &str) -> Arc<AgentSpec> {
+        model_routing::route(agent, &self.providers, &self.models, input).await
+    }
+}
+
+/// Reply-language block for the latest user message. The detection is
+/// recorded only for a new message, not when a run resumes.
+async fn localize(
+    agent: &AgentSpec,
+    session: &SessionHandle,
+    history: &[Message],
+) -> Option<SystemBlock> {
+    let config = agent.language.as_ref()?;
+    let latest = history.iter().rposition(|m| m.role == Role::User)?;
+    let localization = language::localize(config, history[latest].content.as_deref()?);
+
+    if latest == history.len() - 1
+        && let Err(e) = session
+            .record_language(
+                localization.detected.map(str::to_string),
+                localization.reply.clone(),
+            )
+            .await
+    {
+        warn!(session_id = %session.id(), error = %e, "Failed to record detected language");
+    }
+    localization.reply.as_deref().map(language::reply_block)
+}
+
+// ============================================================================
+// External URLs
+// ============================================================================
+
+/// Builds links handed out to clients (problem instances, `Location` headers).
+///
+/// Uses `server.external_url` when configured so links stay valid behind a
+/// reverse proxy; otherwise falls back to root-relative paths under `base_path`.
+#[derive(Clone, Debug, Default)]
+pub struct ExternalUrl {
+    base: String,
+}
+
+impl ExternalUrl {
+    pub fn new(base_path: &str, external_url: Option<&str>) -> Self {
+        let base = match external_url.map(str::trim).filter(|u| !u.is_empty()) {
+            Some(url) => url.trim_end_matches('/').to_string(),
+            None => base_path.to_string(),
+        };
+        Self { base }
+    }
+
+    /// Link to `path`, an API path such as `/api/v1/sessions/{id}`.
+    pub fn url_for(&self, path: &str) -> String {
+        format!("{}{}", self.base, path)
+    }
 }
 
 // ============================================================================
@@ -60,12 +225,73 @@ pub struct AppState {
     pub idle_timeout_seconds: u64,
     pub keep_alive_interval_seconds: u64,
     pub max_connections: usize,
+    /// Check JSON request bodies against the OpenAPI request schemas
+    /// (`server.request_validation`).
+    pub request_validation: bool,
+    /// Refuse everything but list and get requests (`server.read_only`).
+    pub read_only: bool,
+    /// Normalized `server.base_path` all routes are nested under.
+    pub base_path: String,
+    pub external_url: ExternalUrl,
+    /// Sampled access logging (`server.access_log`).
+    pub access_log: AccessLog,
+    /// Per-route request timeout overrides (`server.route_timeouts`).
+    pub route_timeouts: RouteTimeouts,
+    /// Capture of 4xx requests for debugging (`server.debug_capture`).
+    pub debug_capture: DebugCapture,
     pub background_tasks: BackgroundTasks,
     pub shutdown_tx: Arc<Mutex<Option<oneshot::Sender<()>>>>,
     pub workspace_hash: String,
     pub chat_session_cache: ChatSessionCache,
+    /// Replay buffers for resuming SSE streams with `Last-Event-ID`.
+    pub stream_buffers: StreamBuffers,
     pub agents_dir: PathBuf,
     pub workspace_dir: Option<PathBuf>,
+    /// Hours deleted agents stay in the trash (0 = until purged).
+    pub agent_trash_retention_hours: u64,
+    /// Return model reasoning to admin callers (`sessions.expose_reasoning`).
+    pub expose_reasoning: bool,
+    /// Feature flags for experimental subsystems.
+    pub features: FeatureFlags,
+    /// Starts a graceful binary upgrade (socket handoff).
+    pub upgrade: UpgradeTrigger,
+    /// Hourly and daily token usage rollups.
+    pub usage: UsageRollups,
+    /// Reads sessions that are no longer live, including archived ones.
+    pub session_archive: SessionArchiver,
+    /// Files attached to session messages.
+    pub artifacts: Artifacts,
+    /// Users and groups provisioned over SCIM.
+    pub identity: Arc<dyn IdentityStore>,
+    /// SCIM token and group-to-role mappings (`scim`).
+    pub scim: ScimConfig,
+    /// Serializes SCIM writes so uniqueness checks and membership updates
+    /// never interleave.
+    pub scim_writes: Arc<Mutex<()>>,
+    /// Scoped API credentials for automation, managed via the admin API.
+    pub service_accounts: ServiceAccounts,
+    /// Read-only session transcript links served at `/shared/{token}`.
+    pub shares: Shares,
+    /// Authorization policies checked on API requests (`authorization`).
+    pub policies: Policies,
+    /// Security audit log (`audit`).
+    pub audit: AuditLog,
+    /// Delivers background run results to callback URLs (`callbacks`).
+    pub callbacks: Callbacks,
+    /// Message runs and their outcomes, for `GET /api/v1/runs`.
+    pub runs: Runs,
+    /// Recent dependency check results (`health`).
+    pub health: HealthHistory,
+    /// Provider latency and error objectives (`slo`).
+    pub slos: ProviderSlos,
+    /// HTTP request counts and durations for `/metrics`.
+    pub http_metrics: HttpMetrics,
+    /// Status page served at `/status` (`health.status_page`).
+    pub status_page: StatusPageConfig,
+    /// Route fault rules for resilience testing (`faults`).
+    pub faults: FaultInjector,
+    /// Rate limits and CAPTCHA checks for public agents (`public_agents`).
+    pub public_agents: PublicAgents,
 }
 
 // ============================================================================
@@ -81,19 +307,126 @@ pub fn shutdown_channel() -> (oneshot::Sender<()>, oneshot::Receiver<()>) {
 
 pub fn build_app(state: AppState, request_timeout_seconds: u64) -> Router {
     let max_connections = state.max_connections;
+    let base_path = state.base_path.clone();
+    let access_log = state.access_log.clone();
+    let debug_capture = state.debug_capture.clone();
+    let http_metrics = state.http_metrics.clone();
+    let faults = state.faults.clone();
+    let route_timeouts = state
+        .route_timeouts
+        .clone()
+        .with_default(request_timeout_seconds);
+    let request_schemas = if state.request_validation {
+        RequestSchemas::from_openapi(&base_path)
+    } else {
+        RequestSchemas::default()
+    };
 
     // SSE streaming routes - no request timeout (uses idle timeout internally)
     let streaming_routes = Router::new()
         .route(
             "/sessions/{session_id}/stream",
-            post(handlers::v1::stream_session),
+            post(handlers::v1::stream_session).get(handlers::v1::resume_stream),
         )
+        .route("/runs/{id}/events", get(handlers::v1::run_events))
         .with_state(state.clone());
 
     // Regular API routes - with request timeout
     let api_routes = Router::new()
-        .route("/agents", get(handlers::v1::list_agents))
-        .route("/agents/{name}", get(handlers::v1::get_agent))
+        .route(
+            "/agents",
+            get(handlers::v1::list_agents).post(handlers::v1::create_agent),
+        )
+        .route("/agents/bulk", post(handlers::v1::bulk_agents))
+        .route(
+            "/agents/{name}",
+            get(handlers::v1::get_agent).delete(handlers::v1::delete_agent),
+        )
+        .route("/agents/{name}/disable", post(handlers::v1::disable_agent))
+        .route(
+            "/agents/{name}/examples",
+            get(handlers::v1::list_examples).post(handlers::v1::create_example),
+        )
+        .route(
+            "/agents/{name}/examples/select",
+            post(handlers::v1::select_examples),
+        )
+        .route(
+            "/agents/{name}/examples/{id}",
+            get(handlers::v1::get_example)
+                .put(handlers::v1::update_example)
+                .delete(handlers::v1::delete_example),
+        )
+        .route("/agents/{name}/enable", post(handlers::v1::enable_agent))
+        .route("/agents/{name}/runs", post(handlers::v1::start_agent_run))
+        .route("/trash/agents", get(handlers::v1::list_trashed_agents))
+        .route("/trash/agents/{name}", delete(handlers::v1::purge_agent))
+        .route(
+            "/trash/agents/{name}/restore",
+            post(handlers::v1::restore_agent),
+        )
+        .route("/admin/drift", get(handlers::v1::get_drift))
+        .route(
+            "/admin/drift/reconcile",
+            post(handlers::v1::reconcile_drift),
+        )
+        .route(
+            "/admin/health-history",
+            get(handlers::v1::get_health_history),
+        )
+        .route("/admin/slos", get(handlers::v1::get_slos))
+        .route("/meta", get(handlers::v1::meta))
+        .route("/models", get(handlers::v1::list_models))
+        .route(
+            "/models/{*name}",
+            get(handlers::v1::get_model)
+                .put(handlers::v1::put_model)
+                .delete(handlers::v1::delete_model),
+        )
+        .route("/problems", get(handlers::v1::list_problems))
+        .route("/projects", get(handlers::v1::list_projects))
+        .route(
+            "/projects/{name}",
+            get(handlers::v1::get_project)
+                .put(handlers::v1::put_project)
+                .delete(handlers::v1::delete_project),
+        )
+        .route("/prompts", get(handlers::v1::list_prompts))
+        .route(
+            "/prompts/{name}",
+            get(handlers::v1::get_prompt)
+                .put(handlers::v1::put_prompt)
+                .delete(handlers::v1::delete_prompt),
+        )
+        .route(
+            "/prompts/{name}/versions",
+            get(handlers::v1::list_prompt_versions),
+        )
+        .route(
+            "/prompts/{name}/versions/{version}",
+            get(handlers::v1::get_prompt_version),
+        )
+        .route("/providers", get(handlers::v1::list_providers))
+        .route(
+            "/providers/{name}/models",
+            get(handlers::v1::list_provider_models),
+        )
+        .route("/runs", get(handlers::v1::list_runs))
+        .route("/runs/{id}", get(handlers::v1::get_run))
+        .route("/runs/dead-letter", get(handlers::v1::list_dead_letters))
+        .route(
+            "/runs/dead-letter/requeue",
+            post(handlers::v1::bulk_requeue_dead_letters),
+        )
+        .route(
+            "/runs/dead-letter/{id}/requeue",
+            post(handlers::v1::requeue_dead_letter),
+        )
+        .route(
+            "/schemas/agent-manifest.json",
+            get(handlers::v1::agent_manifest_schema),
+        )
+        .route("/schemas/openapi.json", get(handlers::v1::openapi_document))
         .route(
             "/sessions",
             get(handlers::v1::list_sessions).post(handlers::v1::create_session),
@@ -102,41 +435,255 @@ pub fn build_app(state: AppState, request_timeout_seconds: u64) -> Router {
             "/sessions/{session_id}",
             get(handlers::v1::get_session).delete(handlers::v1::delete_session),
         )
+        .route(
+            "/sessions/{session_id}/artifacts",
+            get(handlers::v1::list_artifacts),
+        )
+        .route(
+            "/sessions/{session_id}/artifacts/{id}",
+            get(handlers::v1::get_artifact),
+        )
         .route(
             "/sessions/{session_id}/messages",
             get(handlers::v1::get_messages).post(handlers::v1::send_message),
         )
+        .route(
+            "/sessions/{session_id}/export",
+            get(handlers::v1::export_session),
+        )
+        .route(
+            "/sessions/{session_id}/shares",
+            get(handlers::v1::list_shares).post(handlers::v1::create_share),
+        )
+        .route(
+            "/sessions/{session_id}/shares/{id}",
+            delete(handlers::v1::delete_share),
+        )
         .route(
             "/sessions/{session_id}/approve",
             post(handlers::v1::approve_command),
         )
+        .route("/templates/render", post(handlers::v1::render_template))
+        .route("/usage", get(handlers::v1::get_usage))
+        .route(
+            "/users/{user}/facts",
+            get(handlers::v1::list_user_facts)
+                .post(handlers::v1::create_user_fact)
+                .delete(handlers::v1::forget_user),
+        )
+        .route(
+            "/users/{user}/facts/{id}",
+            get(handlers::v1::get_user_fact)
+                .put(handlers::v1::update_user_fact)
+                .delete(handlers::v1::delete_user_fact),
+        )
         .with_state(state.clone())
-        .layer(TimeoutLayer::with_status_code(
-            StatusCode::REQUEST_TIMEOUT,
-            Duration::from_secs(request_timeout_seconds),
+        .layer(axum::middleware::from_fn_with_state(
+            route_timeouts.clone(),
+            handlers::timeouts::enforce_timeouts,
         ));
 
+    // Read-only checks run after authentication, and before the routes are
+    // handed to the RPC endpoint so calls made over it are checked too
     let api_v1 = Router::new()
         .merge(streaming_routes)
         .merge(api_routes)
-        .layer(DefaultBodyLimit::max(2 * 1024 * 1024)) // 2 MB
+        .layer(axum::middleware::from_fn_with_state(
+            request_schemas.clone(),
+            handlers::request_schemas::validate_bodies,
+        ))
+        .layer(DefaultBodyLimit::max(MAX_REQUEST_BODY_BYTES))
+        .layer(axum::middleware::from_fn_with_state(
+            state.clone(),
+            handlers::read_only::reject_writes,
+        ))
+        .layer(axum::middleware::from_fn_with_state(
+            state.clone(),
+            handlers::api_auth::require_api_token,
+        ));
+
+    // JSON-RPC over WebSocket - calls are dispatched through the routes above
+    let rpc_routes = Router::new()
+        .route("/rpc", get(handlers::v1::rpc))
+        .layer(Extension(handlers::v1::RpcRoutes(api_v1.clone())))
         .layer(axum::middleware::from_fn_with_state(
             state.clone(),
             handlers::api_auth::require_api_token,
         ))
+        .with_state(state.clone());
+
+    let api_v1 = api_v1
+        .merge(rpc_routes)
         .layer(ConcurrencyLimitLayer::new(max_connections));
 
     // Admin routes (no timeout, state required for shutdown)
     let admin_routes = Router::new()
-        .route("/shutdown", post(handlers::shutdown))
         .route("/reload-agents", post(handlers::reload_agents))
+        .route("/features", get(handlers::list_features))
+        .route("/features/{name}", put(handlers::set_feature))
+        .route(
+            "/service-accounts",
+            get(handlers::list_service_accounts).post(handlers::create_service_account),
+        )
+        .route(
+            "/service-accounts/{id}",
+            get(handlers::get_service_account)
+                .put(handlers::update_service_account)
+                .delete(handlers::delete_service_account),
+        )
+        .route(
+            "/service-accounts/{id}/rotate",
+            post(handlers::rotate_service_account),
+        )
+        .route("/debug/requests", get(handlers::list_captured_requests))
+        .route(
+            "/debug/requests/{request_id}",
+            get(handlers::get_captured_request),
+        )
+        .layer(axum::middleware::from_fn_with_state(
+            state.clone(),
+            handlers::read_only::reject_writes,
+        ))
+        // Added after the read-only layer: operating the process isn't a
+        // mutation, so a read-only replica can still be stopped and upgraded
+        .route("/shutdown", post(handlers::shutdown))
+        .route("/upgrade", post(handlers::upgrade))
+        .with_state(state.clone());
+
+    // Public agent routes (no credentials, per-client rate limits)
+    let public_routes = Router::new()
+        .route(
+            "/agents/{name}/sessions",
+            post(handlers::v1::create_public_session),
+        )
+        .route(
+            "/agents/{name}/sessions/{session_id}/messages",
+            post(handlers::v1::send_public_message),
+        )
+        .layer(axum::middleware::from_fn_with_state(
+            route_timeouts,
+            handlers::timeouts::enforce_timeouts,
+        ))
+        .layer(axum::middleware::from_fn_with_state(
+            request_schemas,
+            handlers::request_schemas::validate_bodies,
+        ))
+        .layer(DefaultBodyLimit::max(MAX_REQUEST_BODY_BYTES))
+        .layer(axum::middleware::from_fn_with_state(
+            state.clone(),
+            handlers::read_only::reject_writes,
+        ))
         .with_state(state.clone());
 
-    Router::new()
+    // SCIM provisioning routes (own token, SCIM error format)
+    let scim_routes = Router::new()
+        .route(
+            "/Users",
+            get(handlers::scim::list_users).post(handlers::scim::create_user),
+        )
+        .route(
+            "/Users/{id}",
+            get(handlers::scim::get_user)
+                .put(handlers::scim::replace_user)
+                .patch(handlers::scim::patch_user)
+                .delete(handlers::scim::delete_user),
+        )
+        .route(
+            "/Groups",
+            get(handlers::scim::list_groups).post(handlers::scim::create_group),
+        )
+        .route(
+            "/Groups/{id}",
+            get(handlers::scim::get_group)
+                .put(handlers::scim::replace_group)
+                .patch(handlers::scim::patch_group)
+                .delete(handlers::scim::delete_group),
+        )
+        .route(
+            "/ServiceProviderConfig",
+            get(handlers::scim::service_provider_config),
+        )
+        .layer(DefaultBodyLimit::max(MAX_REQUEST_BODY_BYTES))
+        .layer(axum::middleware::from_fn_with_state(
+            state.clone(),
+            handlers::read_only::reject_writes,
+        ))
+        .layer(axum::middleware::from_fn_with_state(
+            state.clone(),
+            handlers::scim::require_scim_token,
+        ))
+        .with_state(state.clone());
+
+    let mut app = Router::new()
         .route("/livez", get(handlers::livez))
         .route("/readyz", get(handlers::readyz))
         .route("/version", get(handlers::version))
+        .route("/metrics", get(handlers::metrics))
+        .route("/status", get(handlers::status_page))
+        .route("/shared/{token}", get(handlers::v1::get_shared_transcript))
         .with_state(state)
         .nest("/api/v1", api_v1)
         .nest("/api/admin/v1", admin_routes)
+        .nest("/public/v1", public_routes)
+        .nest("/scim/v2", scim_routes);
+
+    // Inside the access log and metrics layers, so injected faults are
+    // logged and counted like real ones
+    if !faults.is_empty() {
+        app = app.layer(axum::middleware::from_fn_with_state(
+            faults,
+            handlers::faults::inject_faults,
+        ));
+    }
+
+    let app = app
+        .layer(axum::middleware::from_fn_with_state(
+            debug_capture,
+            handlers::debug_capture::capture_requests,
+        ))
+        .layer(axum::middleware::from_fn_with_state(
+            access_log,
+            handlers::access_log::log_requests,
+        ))
+        .layer(axum::middleware::from_fn_with_state(
+            http_metrics,
+            handlers::metrics::record_requests,
+        ))
+        // Outermost, so every layer and log line sees the request ID
+        .layer(axum::middleware::from_fn(
+            handlers::request_id::assign_request_id,
+        ));
+
+    // Mount under the proxy sub-path, if any
+    if base_path.is_empty() {
+        app
+    } else {
+        Router::new().nest(&base_path, app)
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn external_url_prefers_configured_url() {
+        let urls = ExternalUrl::new("/duragent", Some("https://example.com/duragent/"));
+        assert_eq!(
+            urls.url_for("/api/v1/sessions/abc"),
+            "https://example.com/duragent/api/v1/sessions/abc"
+        );
+    }
+
+    #[test]
+    fn external_url_falls_back_to_base_path() {
+        let urls = ExternalUrl::new("/duragent", None);
+        assert_eq!(
+            urls.url_for("/api/v1/agents/a"),
+            "/duragent/api/v1/agents/a"
+        );
+
+        let urls = ExternalUrl::new("", Some("  "));
+        assert_eq!(urls.url_for("/api/v1/agents/a"), "/api/v1/agents/a");
+    }
 }
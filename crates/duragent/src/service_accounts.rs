@@ -0,0 +1,455 @@
+//! Service accounts: machine credentials with scoped access.
+//!
+//! Unlike the shared `server.api_token`, each service account has its own
+//! bearer token and a list of scopes. A scope is a `resource:verb` pair,
+//! optionally limited to one project with `@project` (agents without a
+//! project are in `default`). `*` matches any resource or verb:
+//!
+//! ```text
+//! agents:read            read any agent
+//! runs:create@helpdesk   run agents in the helpdesk project
+//! *:*                    everything the API token can do
+//! runs:reasoning         see model reasoning (`sessions.expose_reasoning`)
+//! ```
+//!
+//! `runs:reasoning` must be granted by name; `*` verbs don't include it.
+//!
+//! Only a SHA-256 hash of each token is stored. Tokens are shown once, when
+//! an account is created or its token rotated.
+
+use std::collections::HashMap;
+use std::fmt;
+use std::str::FromStr;
+use std::sync::{Arc, RwLock};
+
+use base64::Engine;
+use base64::engine::general_purpose::URL_SAFE_NO_PAD;
+use chrono::{DateTime, TimeDelta, Utc};
+use serde::{Deserialize, Deserializer, Serialize, Serializer};
+use sha2::{Digest, Sha256};
+
+use crate::store::{ServiceAccountStore, StorageResult};
+
+/// Prefix of every service account token, so leaked tokens are recognizable.
+pub const TOKEN_PREFIX: &str = "dsa_";
+
+/// ID prefix for service accounts.
+pub const SERVICE_ACCOUNT_ID_PREFIX: &str = "sa_";
+
+/// Resources a scope can name.
+pub const RESOURCES: &[&str] = &[
+    "agents", "sessions", "runs", "projects", "prompts", "models", "usage", "users", "admin",
+];
+
+/// Verbs a scope can name.
+pub const VERBS: &[&str] = &["read", "create", "update", "delete", REASONING_VERB];
+
+/// Verb of the `runs:reasoning` scope, which lets an account see model
+/// reasoning. Only valid on `runs`.
+pub const REASONING_VERB: &str = "reasoning";
+
+// ============================================================================
+// Scope
+// ============================================================================
+
+/// A `resource:verb` permission, optionally limited to one project.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct Scope {
+    pub resource: String,
+    pub verb: String,
+    pub project: Option<String>,
+}
+
+impl Scope {
+    /// Whether this scope permits `verb` on `resource` in `project`.
+    ///
+    /// `project` is `None` for requests that span projects (e.g. listing
+    /// all agents); only unlimited scopes permit those.
+    pub fn permits(&self, resource: &str, verb: &str, project: Option<&str>) -> bool {
+        let resource_ok = self.resource == "*" || self.resource == resource;
+        let verb_ok = self.verb == "*" || self.verb == verb;
+        let project_ok = match (&self.project, project) {
+            (None, _) => true,
+            (Some(scoped), Some(project)) => scoped == project,
+            (Some(_), None) => false,
+        };
+        resource_ok && verb_ok && project_ok
+    }
+}
+
+impl fmt::Display for Scope {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        write!(f, "{}:{}", self.resource, self.verb)?;
+        if let Some(project) = &self.project {
+            write!(f, "@{project}")?;
+        }
+        Ok(())
+    }
+}
+
+impl FromStr for Scope {
+    type Err = String;
+
+    fn from_str(s: &str) -> Result<Self, Self::Err> {
+        let (permission, project) = match s.split_once('@') {
+            Some((permission, project)) if !project.is_empty() => {
+                (permission, Some(project.to_string()))
+            }
+            Some(_) => return Err(format!("scope '{s}' has an empty project")),
+            None => (s, None),
+        };
+        let Some((resource, verb)) = permission.split_once(':') else {
+            return Err(format!("scope '{s}' must be 'resource:verb'"));
+        };
+        if resource != "*" && !RESOURCES.contains(&resource) {
+            return Err(format!("unknown resource '{resource}' in scope '{s}'"));
+        }
+        if verb != "*" && !VERBS.contains(&verb) {
+            return Err(format!("unknown verb '{verb}' in scope '{s}'"));
+        }
+        if verb == REASONING_VERB && resource != "runs" {
+            return Err(format!("verb '{verb}' only applies to runs in scope '{s}'"));
+        }
+        Ok(Self {
+            resource: resource.to_string(),
+            verb: verb.to_string(),
+            project,
+        })
+    }
+}
+
+impl Serialize for Scope {
+    fn serialize<S: Serializer>(&self, serializer: S) -> Result<S::Ok, S::Error> {
+        serializer.collect_str(self)
+    }
+}
+
+impl<'de> Deserialize<'de> for Scope {
+    fn deserialize<D: Deserializer<'de>>(deserializer: D) -> Result<Self, D::Error> {
+        let s = String::deserialize(deserializer)?;
+        s.parse().map_err(serde::de::Error::custom)
+    }
+}
+
+// ============================================================================
+// ServiceAccount
+// ============================================================================
+
+/// A stored service account.
+#[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
+pub struct ServiceAccount {
+    pub id: String,
+    pub name: String,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub description: Option<String>,
+    pub scopes: Vec<Scope>,
+    /// SHA-256 of the current token.
+    pub token_hash: String,
+    /// SHA-256 of the token replaced by the last rotation, accepted until
+    /// `previous_token_expires_at`.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub previous_token_hash: Option<String>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub previous_token_expires_at: Option<DateTime<Utc>>,
+    pub created_at: DateTime<Utc>,
+    pub rotated_at: DateTime<Utc>,
+}
+
+impl ServiceAccount {
+    /// Whether any scope permits `verb` on `resource` in `project`.
+    pub fn permits(&self, resource: &str, verb: &str, project: Option<&str>) -> bool {
+        self.scopes
+            .iter()
+            .any(|s| s.permits(resource, verb, project))
+    }
+
+    /// Whether a write scope names the `admin` resource itself, across
+    /// projects: `admin:*`, `admin:create`, `admin:update`, or
+    /// `admin:delete`. `admin:read` and wildcard resources don't count: `*:*`
+    /// covers every route's scope but never grants admin access.
+    pub fn is_admin(&self) -> bool {
+        self.scopes.iter().any(|s| {
+            s.resource == "admin"
+                && s.project.is_none()
+                && matches!(s.verb.as_str(), "*" | "create" | "update" | "delete")
+        })
+    }
+
+    /// Whether a `runs:reasoning` scope covers `project`.
+    pub fn sees_reasoning(&self, project: &str) -> bool {
+        self.scopes.iter().any(|s| {
+            s.resource == "runs"
+                && s.verb == REASONING_VERB
+                && s.project.as_deref().is_none_or(|p| p == project)
+        })
+    }
+
+    fn accepts(&self, hash: &str, now: DateTime<Utc>) -> bool {
+        self.token_hash == hash
+            || (self.previous_token_hash.as_deref() == Some(hash)
+                && self.previous_token_expires_at.is_some_and(|at| now < at))
+    }
+}
+
+/// A new random bearer token starting with `prefix`.
+pub(crate) fn generate_token(prefix: &str) -> String {
+    use rand::Rng;
+
+    let mut bytes = [0u8; 32];
+    rand::rng().fill(&mut bytes);
+    format!("{prefix}{}", URL_SAFE_NO_PAD.encode(bytes))
+}
+
+/// The hash of a token that is stored in its place.
+pub(crate) fn hash_token(token: &str) -> String {
+    URL_SAFE_NO_PAD.encode(Sha256::digest(token.as_bytes()))
+}
+
+// ============================================================================
+// ServiceAccounts
+// ============================================================================
+
+/// Service accounts, cached in memory for token lookups.
+///
+/// Uses `std::sync::RwLock` because the lock is never held across await
+/// points; the store is the source of truth and is written first.
+#[derive(Clone)]
+pub struct ServiceAccounts {
+    store: Arc<dyn ServiceAccountStore>,
+    accounts: Arc<RwLock<HashMap<String, ServiceAccount>>>,
+}
+
+impl ServiceAccounts {
+    /// Load all accounts from `store`.
+    pub async fn load(store: Arc<dyn ServiceAccountStore>) -> StorageResult<Self> {
+        let accounts = store
+            .list()
+            .await?
+            .into_iter()
+            .map(|a| (a.id.clone(), a))
+            .collect();
+        Ok(Self {
+            store,
+            accounts: Arc::new(RwLock::new(accounts)),
+        })
+    }
+
+    /// All accounts, sorted by name.
+    pub fn list(&self) -> Vec<ServiceAccount> {
+        let mut accounts: Vec<_> = self.accounts.read().unwrap().values().cloned().collect();
+        accounts.sort_by(|a, b| a.name.cmp(&b.name));
+        accounts
+    }
+
+    pub fn get(&self, id: &str) -> Option<ServiceAccount> {
+        self.accounts.read().unwrap().get(id).cloned()
+    }
+
+    /// Create an account. Returns it with its token.
+    pub async fn create(
+        &self,
+        name: String,
+        description: Option<String>,
+        scopes: Vec<Scope>,
+    ) -> StorageResult<(ServiceAccount, String)> {
+        let token = generate_token(TOKEN_PREFIX);
+        let now = Utc::now();
+        let account = ServiceAccount {
+            id: format!("{SERVICE_ACCOUNT_ID_PREFIX}{}", ulid::Ulid::new()),
+            name,
+            description,
+            scopes,
+            token_hash: hash_token(&token),
+            previous_token_hash: None,
+            previous_token_expires_at: None,
+            created_at: now,
+            rotated_at: now,
+        };
+        self.save(account.clone()).await?;
+        Ok((account, token))
+    }
+
+    /// Replace an account's description and scopes.
+    ///
+    /// Returns `Ok(None)` if the account doesn't exist.
+    pub async fn update(
+        &self,
+        id: &str,
+        description: Option<String>,
+        scopes: Vec<Scope>,
+    ) -> StorageResult<Option<ServiceAccount>> {
+        let Some(mut account) = self.get(id) else {
+            return Ok(None);
+        };
+        account.description = description;
+        account.scopes = scopes;
+        self.save(account.clone()).await?;
+        Ok(Some(account))
+    }
+
+    /// Issue a new token. The old one keeps working for `grace` so clients
+    /// can switch over.
+    ///
+    /// Returns `Ok(None)` if the account doesn't exist.
+    pub async fn rotate(
+        &self,
+        id: &str,
+        grace: TimeDelta,
+    ) -> StorageResult<Option<(ServiceAccount, String)>> {
+        let Some(mut account) = self.get(id) else {
+            return Ok(None);
+        };
+        let token = generate_token(TOKEN_PREFIX);
+        let now = Utc::now();
+        if grace > TimeDelta::zero() {
+            account.previous_token_hash = Some(account.token_hash.clone());
+            account.previous_token_expires_at = Some(now + grace);
+        } else {
+            account.previous_token_hash = None;
+            account.previous_token_expires_at = None;
+        }
+        account.token_hash = hash_token(&token);
+        account.rotated_at = now;
+        self.save(account.clone()).await?;
+        Ok(Some((account, token)))
+    }
+
+    /// Delete an account. Returns whether it existed.
+    pub async fn delete(&self, id: &str) -> StorageResult<bool> {
+        if self.get(id).is_none() {
+            return Ok(false);
+        }
+        self.store.delete(id).await?;
+        self.accounts.write().unwrap().remove(id);
+        Ok(true)
+    }
+
+    /// The account a bearer token belongs to, if any.
+    pub fn authenticate(&self, token: &str) -> Option<ServiceAccount> {
+        if !token.starts_with(TOKEN_PREFIX) {
+            return None;
+        }
+        let hash = hash_token(token);
+        let now = Utc::now();
+        self.accounts
+            .read()
+            .unwrap()
+            .values()
+            .find(|a| a.accepts(&hash, now))
+            .cloned()
+    }
+
+    async fn save(&self, account: ServiceAccount) -> StorageResult<()> {
+        self.store.save(&account).await?;
+        self.accounts
+            .write()
+            .unwrap()
+            .insert(account.id.clone(), account);
+        Ok(())
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::store::file::FileServiceAccountStore;
+    use tempfile::TempDir;
+
+    fn scope(s: &str) -> Scope {
+        s.parse().unwrap()
+    }
+
+    fn account(scopes: &[&str]) -> ServiceAccount {
+        ServiceAccount {
+            id: "sa_1".to_string(),
+            name: "ci".to_string(),
+            description: None,
+            scopes: scopes.iter().map(|s| scope(s)).collect(),
+            token_hash: String::new(),
+            previous_token_hash: None,
+            previous_token_expires_at: None,
+            created_at: Utc::now(),
+            rotated_at: Utc::now(),
+        }
+    }
+
+    #[test]
+    fn parses_and_matches_scopes() {
+        assert_eq!(
+            scope("runs:create@helpdesk").to_string(),
+            "runs:create@helpdesk"
+        );
+        assert!("agents".parse::<Scope>().is_err());
+        assert!("agents:fly".parse::<Scope>().is_err());
+        assert!("widgets:read".parse::<Scope>().is_err());
+        assert!("runs:create@".parse::<Scope>().is_err());
+
+        let scoped = scope("runs:create@helpdesk");
+        assert!(scoped.permits("runs", "create", Some("helpdesk")));
+        assert!(!scoped.permits("runs", "create", Some("billing")));
+        assert!(!scoped.permits("runs", "create", None));
+        assert!(!scoped.permits("runs", "read", Some("helpdesk")));
+
+        assert!(scope("*:*").permits("agents", "delete", None));
+        assert!(scope("agents:*").permits("agents", "update", Some("x")));
+    }
+
+    #[test]
+    fn only_explicit_admin_scopes_grant_admin() {
+        assert!(account(&["admin:update"]).is_admin());
+        assert!(account(&["runs:create", "admin:*"]).is_admin());
+        assert!(!account(&["admin:read"]).is_admin());
+        assert!(!account(&["*:*"]).is_admin());
+        assert!(!account(&["admin:read@helpdesk"]).is_admin());
+        assert!(!account(&["agents:*"]).is_admin());
+    }
+
+    #[test]
+    fn reasoning_scope_is_granted_by_name() {
+        assert!("agents:reasoning".parse::<Scope>().is_err());
+        assert!("*:reasoning".parse::<Scope>().is_err());
+        assert!(!scope("runs:reasoning").permits("runs", "create", None));
+
+        assert!(account(&["runs:reasoning"]).sees_reasoning("default"));
+        assert!(account(&["runs:reasoning@helpdesk"]).sees_reasoning("helpdesk"));
+        assert!(!account(&["runs:reasoning@helpdesk"]).sees_reasoning("billing"));
+        assert!(!account(&["runs:*", "*:*"]).sees_reasoning("default"));
+    }
+
+    #[tokio::test]
+    async fn rotation_keeps_old_token_for_grace_period() {
+        let tmp = TempDir::new().unwrap();
+        let accounts = ServiceAccounts::load(Arc::new(FileServiceAccountStore::new(tmp.path())))
+            .await
+            .unwrap();
+
+        let (account, old) = accounts
+            .create("ci".to_string(), None, vec![scope("agents:read")])
+            .await
+            .unwrap();
+        assert_eq!(accounts.authenticate(&old).unwrap().id, account.id);
+        assert!(accounts.authenticate("dsa_wrong").is_none());
+
+        let (_, new) = accounts
+            .rotate(&account.id, TimeDelta::minutes(5))
+            .await
+            .unwrap()
+            .unwrap();
+        assert!(accounts.authenticate(&new).is_some());
+        assert!(accounts.authenticate(&old).is_some());
+
+        accounts
+            .rotate(&account.id, TimeDelta::zero())
+            .await
+            .unwrap();
+        assert!(accounts.authenticate(&new).is_none());
+
+        // Reloading from the store sees the same accounts
+        let reloaded = ServiceAccounts::load(Arc::new(FileServiceAccountStore::new(tmp.path())))
+            .await
+            .unwrap();
+        assert_eq!(reloaded.list().len(), 1);
+        assert!(accounts.delete(&account.id).await.unwrap());
+        assert!(accounts.get(&account.id).is_none());
+    }
+}
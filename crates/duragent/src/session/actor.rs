@@ -12,25 +12,29 @@ use std::collections::VecDeque;
 use std::sync::Arc;
 
 use chrono::{DateTime, Utc};
-use tokio::sync::{mpsc, watch};
+use tokio::sync::{broadcast, mpsc, watch};
 use tokio::time::{Instant, interval_at};
 use tracing::{debug, warn};
 
-use crate::agent::OnDisconnect;
+use crate::agent::{OnDisconnect, RunOrigin};
 use crate::api::SessionStatus;
-use crate::config::CompactionMode;
+use crate::best_of::SampleSelection;
+use crate::config::{CompactionMode, EventBatchConfig};
+use crate::context::ContextRecovery;
+use crate::ensemble::Consensus;
 use crate::llm::{Message, Role, Usage};
 use crate::store::SessionStore;
+use crate::usage::UsageRollups;
 
 use super::actor_types::{
-    ActorConfig, ActorError, BATCH_SIZE, CHANNEL_CAPACITY, CHECKPOINT_THRESHOLD, FLUSH_INTERVAL,
-    RecoverConfig, SNAPSHOT_INTERVAL, SessionCommand, SessionMetadata, SilentMessageEntry,
+    ActorConfig, ActorError, CHANNEL_CAPACITY, CHECKPOINT_THRESHOLD, RecoverConfig,
+    SNAPSHOT_INTERVAL, SUBSCRIBER_CAPACITY, SessionCommand, SessionMetadata, SilentMessageEntry,
 };
 #[cfg(test)]
 use super::actor_types::{DEFAULT_ACTOR_MESSAGE_LIMIT, DEFAULT_SILENT_BUFFER_CAP};
 use super::events_eval::assistant_response_to_message;
 use super::{
-    CheckpointState, EventToolCall, PendingApproval, SessionConfig, SessionEvent,
+    CheckpointState, EventToolCall, PendingApproval, PlanStep, SessionConfig, SessionEvent,
     SessionEventPayload, SessionSnapshot, ToolResultData,
 };
 
@@ -73,13 +77,19 @@ pub struct SessionActor {
     on_disconnect: OnDisconnect,
     gateway: Option<String>,
     gateway_chat_id: Option<String>,
+    origin: Option<RunOrigin>,
     actor_message_limit: usize,
     compaction_mode: CompactionMode,
+    event_batch: EventBatchConfig,
+    usage_rollups: Option<UsageRollups>,
 
     // Persistence
     store: Arc<dyn SessionStore>,
     pending_events: VecDeque<SessionEvent>,
 
+    // Live subscribers to flushed events (created on first subscribe)
+    subscribers: Option<broadcast::Sender<SessionEvent>>,
+
     // Communication
     command_rx: mpsc::Receiver<SessionCommand>,
     shutdown_rx: watch::Receiver<bool>,
@@ -116,10 +126,14 @@ impl SessionActor {
             on_disconnect: config.on_disconnect,
             gateway: config.gateway,
             gateway_chat_id: config.gateway_chat_id,
+            origin: config.origin,
             actor_message_limit: config.actor_message_limit,
             compaction_mode: config.compaction_mode,
+            event_batch: config.event_batch,
+            usage_rollups: config.usage,
             store: config.store,
             pending_events: VecDeque::new(),
+            subscribers: None,
             command_rx: rx,
             shutdown_rx,
         };
@@ -160,10 +174,14 @@ impl SessionActor {
             on_disconnect: snapshot.config.on_disconnect,
             gateway: snapshot.config.gateway,
             gateway_chat_id: snapshot.config.gateway_chat_id,
+            origin: snapshot.config.origin,
             actor_message_limit: config.actor_message_limit,
             compaction_mode: config.compaction_mode,
+            event_batch: config.event_batch,
+            usage_rollups: config.usage,
             store: config.store,
             pending_events: VecDeque::new(),
+            subscribers: None,
             command_rx: rx,
             shutdown_rx,
         };
@@ -224,7 +242,8 @@ impl SessionActor {
 
     /// Main command processing loop.
     async fn command_loop(&mut self) {
-        let mut flush_timer = interval_at(Instant::now() + FLUSH_INTERVAL, FLUSH_INTERVAL);
+        let flush_interval = self.event_batch.flush_interval();
+        let mut flush_timer = interval_at(Instant::now() + flush_interval, flush_interval);
 
         loop {
             tokio::select! {
@@ -246,7 +265,7 @@ impl SessionActor {
                             self.handle_command(command).await;
 
                             // Check if batch is full
-                            if self.pending_events.len() >= BATCH_SIZE {
+                            if self.pending_events.len() >= self.event_batch.max_events {
                                 let _ = self.flush_events().await;
                             }
                         }
@@ -382,6 +401,54 @@ impl SessionActor {
                 let result = self.record_error(code, message).await;
                 let _ = reply.send(result);
             }
+            SessionCommand::RecordReasoning { content, reply } => {
+                let result = self.record_reasoning(content);
+                let _ = reply.send(result);
+            }
+            SessionCommand::RecordContextRecovery { recovery, reply } => {
+                let result = self.record_context_recovery(recovery);
+                let _ = reply.send(result);
+            }
+            SessionCommand::RecordSamples { selection, reply } => {
+                let result = self.record_samples(selection);
+                let _ = reply.send(result);
+            }
+            SessionCommand::RecordConsensus { consensus, reply } => {
+                let result = self.record_consensus(consensus);
+                let _ = reply.send(result);
+            }
+            SessionCommand::RecordPlan {
+                call_id,
+                steps,
+                requires_approval,
+                reply,
+            } => {
+                let result = self.record_plan(call_id, steps, requires_approval);
+                let _ = reply.send(result);
+            }
+            SessionCommand::RecordPlanStep {
+                call_id,
+                step,
+                content,
+                reply,
+            } => {
+                let result = self.record_plan_step(call_id, step, content);
+                let _ = reply.send(result);
+            }
+            SessionCommand::RecordLanguage {
+                language,
+                reply_language,
+                reply,
+            } => {
+                let result = self.record_language(language, reply_language);
+                let _ = reply.send(result);
+            }
+            SessionCommand::RecordPartial {
+                message_id,
+                content,
+            } => {
+                self.record_partial(message_id, content);
+            }
             SessionCommand::GetMessages { reply } => {
                 let _ = reply.send(Ok(self.all_messages()));
             }
@@ -396,9 +463,16 @@ impl SessionActor {
                     on_disconnect: self.on_disconnect,
                     gateway: self.gateway.clone(),
                     gateway_chat_id: self.gateway_chat_id.clone(),
+                    origin: self.origin.clone(),
                 };
                 let _ = reply.send(Ok(metadata));
             }
+            SessionCommand::Subscribe { reply } => {
+                let subscribers = self
+                    .subscribers
+                    .get_or_insert_with(|| broadcast::channel(SUBSCRIBER_CAPACITY).0);
+                let _ = reply.send(Ok(subscribers.subscribe()));
+            }
             SessionCommand::GetPendingApproval { reply } => {
                 let _ = reply.send(Ok(self.pending_approval.clone()));
             }
@@ -485,6 +559,10 @@ impl SessionActor {
         self.pending_messages
             .push(assistant_response_to_message(&content, &tool_calls));
 
+        if let (Some(usage), Some(rollups)) = (&usage, &self.usage_rollups) {
+            rollups.record(&self.agent, usage, self.updated_at);
+        }
+
         // Queue event
         self.pending_events.push_back(SessionEvent::new(
             seq,
@@ -713,6 +791,154 @@ impl SessionActor {
         Ok(seq)
     }
 
+    /// Record model reasoning for the run trace, outside conversation history.
+    fn record_reasoning(&mut self, content: String) -> Result<u64, ActorError> {
+        self.updated_at = Utc::now();
+        let seq = self.next_seq();
+
+        self.pending_events.push_back(SessionEvent::new(
+            seq,
+            SessionEventPayload::Reasoning {
+                agent: self.agent.clone(),
+                content,
+            },
+        ));
+
+        Ok(seq)
+    }
+
+    /// Record a context-too-long recovery for the run trace.
+    fn record_context_recovery(&mut self, recovery: ContextRecovery) -> Result<u64, ActorError> {
+        self.updated_at = Utc::now();
+        let seq = self.next_seq();
+
+        self.pending_events.push_back(SessionEvent::new(
+            seq,
+            SessionEventPayload::ContextRecovered {
+                agent: self.agent.clone(),
+                dropped_messages: recovery.dropped_messages,
+                tokens_before: recovery.tokens_before,
+                tokens_after: recovery.tokens_after,
+            },
+        ));
+
+        Ok(seq)
+    }
+
+    /// Record a best-of sampled turn for the run trace.
+    fn record_samples(&mut self, selection: SampleSelection) -> Result<u64, ActorError> {
+        self.updated_at = Utc::now();
+        let seq = self.next_seq();
+
+        self.pending_events.push_back(SessionEvent::new(
+            seq,
+            SessionEventPayload::SamplesSelected {
+                agent: self.agent.clone(),
+                select: selection.select.as_str().to_string(),
+                winner: selection.winner,
+                candidates: selection.candidates,
+                judge_error: selection.judge_error,
+            },
+        ));
+
+        Ok(seq)
+    }
+
+    /// Record an ensemble consensus for the run trace.
+    fn record_consensus(&mut self, consensus: Consensus) -> Result<u64, ActorError> {
+        self.updated_at = Utc::now();
+        let seq = self.next_seq();
+
+        self.pending_events.push_back(SessionEvent::new(
+            seq,
+            SessionEventPayload::ConsensusReached {
+                agent: self.agent.clone(),
+                members: consensus.members,
+                disagreements: consensus.disagreements,
+                synthesis_error: consensus.synthesis_error,
+            },
+        ));
+
+        Ok(seq)
+    }
+
+    /// Record a run's plan for the run trace.
+    fn record_plan(
+        &mut self,
+        call_id: String,
+        steps: Vec<PlanStep>,
+        requires_approval: bool,
+    ) -> Result<u64, ActorError> {
+        self.updated_at = Utc::now();
+        let seq = self.next_seq();
+
+        self.pending_events.push_back(SessionEvent::new(
+            seq,
+            SessionEventPayload::PlanCreated {
+                call_id,
+                steps,
+                requires_approval,
+            },
+        ));
+
+        Ok(seq)
+    }
+
+    /// Record a finished plan step for the run trace.
+    fn record_plan_step(
+        &mut self,
+        call_id: String,
+        step: usize,
+        content: String,
+    ) -> Result<u64, ActorError> {
+        self.updated_at = Utc::now();
+        let seq = self.next_seq();
+
+        self.pending_events.push_back(SessionEvent::new(
+            seq,
+            SessionEventPayload::PlanStepCompleted {
+                call_id,
+                step,
+                content,
+            },
+        ));
+
+        Ok(seq)
+    }
+
+    /// Record the detected input language and the reply language.
+    fn record_language(
+        &mut self,
+        language: Option<String>,
+        reply_language: Option<String>,
+    ) -> Result<u64, ActorError> {
+        self.updated_at = Utc::now();
+        let seq = self.next_seq();
+
+        self.pending_events.push_back(SessionEvent::new(
+            seq,
+            SessionEventPayload::LanguageDetected {
+                language,
+                reply_language,
+            },
+        ));
+
+        Ok(seq)
+    }
+
+    /// Record a streamed-content checkpoint without touching conversation
+    /// history. Flushed on the regular flush interval.
+    fn record_partial(&mut self, message_id: String, content: String) {
+        let seq = self.next_seq();
+        self.pending_events.push_back(SessionEvent::new(
+            seq,
+            SessionEventPayload::AssistantPartial {
+                message_id,
+                content,
+            },
+        ));
+    }
+
     async fn finalize_stream(
         &mut self,
         content: String,
@@ -821,6 +1047,13 @@ impl SessionActor {
 
         self.last_flushed_seq = last_seq;
 
+        if let Some(subscribers) = &self.subscribers {
+            for event in events {
+                // No receivers is fine; they may all have unsubscribed.
+                let _ = subscribers.send(event);
+            }
+        }
+
         // Check if snapshot is needed
         if self.last_flushed_seq - self.last_snapshot_seq >= SNAPSHOT_INTERVAL {
             self.write_snapshot().await?;
@@ -848,6 +1081,7 @@ impl SessionActor {
                 on_disconnect: self.on_disconnect,
                 gateway: self.gateway.clone(),
                 gateway_chat_id: self.gateway_chat_id.clone(),
+                origin: self.origin.clone(),
                 pending_approval: self.pending_approval.clone(),
                 silent_buffer_cap: Some(self.silent_buffer_cap),
                 actor_message_limit: Some(self.actor_message_limit),
@@ -922,6 +1156,9 @@ mod tests {
             silent_buffer_cap: DEFAULT_SILENT_BUFFER_CAP,
             actor_message_limit: DEFAULT_ACTOR_MESSAGE_LIMIT,
             compaction_mode: CompactionMode::Disabled,
+            event_batch: EventBatchConfig::default(),
+            usage: None,
+            origin: None,
         };
         let (tx, task_handle) = SessionActor::spawn(config, shutdown_rx);
         (tx, shutdown_tx, task_handle)
@@ -1029,6 +1266,48 @@ mod tests {
         shutdown_tx.send(true).unwrap();
     }
 
+    #[tokio::test]
+    async fn subscribers_receive_flushed_events() {
+        let temp_dir = TempDir::new().unwrap();
+        let (tx, shutdown_tx, _task_handle) = setup_test_actor(&temp_dir);
+
+        let (reply_tx, reply_rx) = oneshot::channel();
+        tx.send(SessionCommand::Subscribe { reply: reply_tx })
+            .await
+            .unwrap();
+        let mut events = reply_rx.await.unwrap().unwrap();
+
+        let (reply_tx, reply_rx) = oneshot::channel();
+        tx.send(SessionCommand::AddUserMessage {
+            content: "Watched message".to_string(),
+            sender_id: None,
+            sender_name: None,
+            reply: reply_tx,
+        })
+        .await
+        .unwrap();
+        let seq = reply_rx.await.unwrap().unwrap();
+
+        let (reply_tx, reply_rx) = oneshot::channel();
+        tx.send(SessionCommand::ForceFlush { reply: reply_tx })
+            .await
+            .unwrap();
+        reply_rx.await.unwrap().unwrap();
+
+        loop {
+            let event = events.recv().await.unwrap();
+            if event.seq == seq {
+                assert!(matches!(
+                    event.payload,
+                    SessionEventPayload::UserMessage { ref content, .. } if content == "Watched message"
+                ));
+                break;
+            }
+        }
+
+        shutdown_tx.send(true).unwrap();
+    }
+
     #[tokio::test]
     async fn shutdown_flushes_and_snapshots() {
         let temp_dir = TempDir::new().unwrap();
@@ -1142,6 +1421,68 @@ mod tests {
         }
     }
 
+    #[tokio::test]
+    async fn events_are_appended_in_batches() {
+        let temp_dir = TempDir::new().unwrap();
+        let (_shutdown_tx, shutdown_rx) = watch::channel(false);
+        let store = Arc::new(FailingStore::new(FileSessionStore::new(temp_dir.path()), 0));
+
+        let config = ActorConfig {
+            id: "sess_batched".to_string(),
+            agent: "test-agent".to_string(),
+            store: store.clone(),
+            on_disconnect: OnDisconnect::Pause,
+            gateway: None,
+            gateway_chat_id: None,
+            silent_buffer_cap: DEFAULT_SILENT_BUFFER_CAP,
+            actor_message_limit: DEFAULT_ACTOR_MESSAGE_LIMIT,
+            compaction_mode: CompactionMode::Disabled,
+            // Long interval so only the size limit triggers a flush
+            event_batch: EventBatchConfig {
+                max_events: 3,
+                flush_interval_ms: 60_000,
+            },
+            usage: None,
+            origin: None,
+        };
+        let (tx, _task_handle) = SessionActor::spawn(config, shutdown_rx);
+
+        async fn add_message(tx: &mpsc::Sender<SessionCommand>) {
+            let (reply_tx, reply_rx) = oneshot::channel();
+            tx.send(SessionCommand::AddUserMessage {
+                content: "hi".to_string(),
+                sender_id: None,
+                sender_name: None,
+                reply: reply_tx,
+            })
+            .await
+            .unwrap();
+            reply_rx.await.unwrap().unwrap();
+        }
+
+        // Round-trips through the actor, so earlier batch flushes are done
+        async fn barrier(tx: &mpsc::Sender<SessionCommand>) {
+            let (reply_tx, reply_rx) = oneshot::channel();
+            tx.send(SessionCommand::GetMetadata { reply: reply_tx })
+                .await
+                .unwrap();
+            reply_rx.await.unwrap().unwrap();
+        }
+
+        // SessionStart is flushed on its own
+        barrier(&tx).await;
+        assert_eq!(store.append_calls(), 1);
+
+        add_message(&tx).await;
+        add_message(&tx).await;
+        barrier(&tx).await;
+        assert_eq!(store.append_calls(), 1);
+
+        add_message(&tx).await;
+        barrier(&tx).await;
+        assert_eq!(store.append_calls(), 2);
+    }
+
     #[tokio::test]
     async fn flush_failure_requeues_events() {
         let temp_dir = TempDir::new().unwrap();
@@ -1163,6 +1504,9 @@ mod tests {
             silent_buffer_cap: DEFAULT_SILENT_BUFFER_CAP,
             actor_message_limit: DEFAULT_ACTOR_MESSAGE_LIMIT,
             compaction_mode: CompactionMode::Disabled,
+            event_batch: EventBatchConfig::default(),
+            usage: None,
+            origin: None,
         };
 
         let (tx, _task_handle) = SessionActor::spawn(config, shutdown_rx);
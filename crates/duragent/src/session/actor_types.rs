@@ -4,20 +4,23 @@
 //! along with configuration and error types.
 
 use std::sync::Arc;
-use std::time::Duration;
 
 use chrono::{DateTime, Utc};
 use thiserror::Error;
-use tokio::sync::oneshot;
+use tokio::sync::{broadcast, oneshot};
 
-use crate::agent::OnDisconnect;
+use crate::agent::{OnDisconnect, RunOrigin};
 use crate::api::SessionStatus;
-use crate::config::CompactionMode;
+use crate::best_of::SampleSelection;
+use crate::config::{CompactionMode, EventBatchConfig};
+use crate::context::ContextRecovery;
+use crate::ensemble::Consensus;
 use crate::llm::{Message, Usage};
 use crate::session::EventToolCall;
 use crate::store::SessionStore;
+use crate::usage::UsageRollups;
 
-use super::{ApprovalDecisionType, PendingApproval};
+use super::{ApprovalDecisionType, PendingApproval, PlanStep, SessionEvent};
 
 // ============================================================================
 // Session Command
@@ -92,6 +95,41 @@ pub enum SessionCommand {
         message: String,
         reply: oneshot::Sender<Result<u64, ActorError>>,
     },
+    RecordReasoning {
+        content: String,
+        reply: oneshot::Sender<Result<u64, ActorError>>,
+    },
+    RecordContextRecovery {
+        recovery: ContextRecovery,
+        reply: oneshot::Sender<Result<u64, ActorError>>,
+    },
+    RecordSamples {
+        selection: SampleSelection,
+        reply: oneshot::Sender<Result<u64, ActorError>>,
+    },
+    RecordConsensus {
+        consensus: Consensus,
+        reply: oneshot::Sender<Result<u64, ActorError>>,
+    },
+    RecordPlan {
+        call_id: String,
+        steps: Vec<PlanStep>,
+        requires_approval: bool,
+        reply: oneshot::Sender<Result<u64, ActorError>>,
+    },
+    RecordPlanStep {
+        call_id: String,
+        step: usize,
+        content: String,
+        reply: oneshot::Sender<Result<u64, ActorError>>,
+    },
+    RecordLanguage {
+        language: Option<String>,
+        reply_language: Option<String>,
+        reply: oneshot::Sender<Result<u64, ActorError>>,
+    },
+    /// Best-effort checkpoint of streamed content; no reply.
+    RecordPartial { message_id: String, content: String },
 
     // Read operations
     GetMessages {
@@ -108,6 +146,10 @@ pub enum SessionCommand {
     GetPendingApproval {
         reply: oneshot::Sender<Result<Option<PendingApproval>, ActorError>>,
     },
+    /// Receive events as they are flushed to the log.
+    Subscribe {
+        reply: oneshot::Sender<Result<broadcast::Receiver<SessionEvent>, ActorError>>,
+    },
 
     // Stream/Flush
     FinalizeStream {
@@ -170,6 +212,7 @@ pub struct SessionMetadata {
     pub on_disconnect: OnDisconnect,
     pub gateway: Option<String>,
     pub gateway_chat_id: Option<String>,
+    pub origin: Option<RunOrigin>,
 }
 
 // ============================================================================
@@ -193,6 +236,12 @@ pub struct ActorConfig {
     pub actor_message_limit: usize,
     /// Event log compaction mode.
     pub compaction_mode: CompactionMode,
+    /// Event log write batching.
+    pub event_batch: EventBatchConfig,
+    /// Where to count token usage, if anywhere.
+    pub usage: Option<UsageRollups>,
+    /// Who started the session.
+    pub origin: Option<RunOrigin>,
 }
 
 /// Configuration for recovering an actor from a snapshot.
@@ -207,6 +256,10 @@ pub struct RecoverConfig {
     pub actor_message_limit: usize,
     /// Event log compaction mode.
     pub compaction_mode: CompactionMode,
+    /// Event log write batching.
+    pub event_batch: EventBatchConfig,
+    /// Where to count token usage, if anywhere.
+    pub usage: Option<UsageRollups>,
 }
 
 /// Derive actor_message_limit from max_input_tokens.
@@ -224,12 +277,6 @@ pub const DEFAULT_ACTOR_MESSAGE_LIMIT: usize = 1000;
 // Constants
 // ============================================================================
 
-/// Maximum events to batch before forcing a flush.
-pub const BATCH_SIZE: usize = 10;
-
-/// Interval at which pending events are flushed to disk.
-pub const FLUSH_INTERVAL: Duration = Duration::from_millis(100);
-
 /// Number of events between snapshots.
 pub const SNAPSHOT_INTERVAL: u64 = 50;
 
@@ -244,3 +291,9 @@ pub const CHECKPOINT_THRESHOLD: usize = 50;
 /// Sized to handle burst traffic during agentic loops with many tool calls.
 /// If this fills up, callers will block on send(), causing backpressure.
 pub const CHANNEL_CAPACITY: usize = 256;
+
+/// Buffered events per live subscriber.
+///
+/// A subscriber that falls further behind than this misses events and is
+/// told how many it skipped.
+pub const SUBSCRIBER_CAPACITY: usize = 256;
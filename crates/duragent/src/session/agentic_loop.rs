@@ -13,7 +13,7 @@
 
 use std::collections::HashSet;
 use std::sync::Arc;
-use std::time::Duration;
+use std::time::{Duration, Instant};
 
 use futures::StreamExt;
 use tokio::sync::mpsc;
@@ -22,13 +22,22 @@ use tracing::{debug, warn};
 use thiserror::Error;
 
 use super::PendingApprovalEval;
+use super::planning::{PlanRun, render_plan};
+use super::run_stats::{RunMeter, RunStats};
 use super::{EventToolCall, PendingApproval};
-use crate::agent::{AgentSpec, ContextConfig, HooksConfig, ModelConfigEval};
-use crate::context::{drop_oldest_iterations, mask_tool_results, truncate_tool_result};
-use crate::llm::{ChatRequest, LLMError, LLMProvider, Message, Role, StreamEvent, ToolCall, Usage};
+use crate::agent::{AgentSpec, ContextConfig, HooksConfig, ModelConfigEval, ToolType};
+use crate::context::{
+    drop_oldest_iterations, mask_tool_results, shrink_context, truncate_tool_result,
+};
+use crate::llm::{
+    ChatRequest, LLMError, LLMProvider, Message, ProviderErrorClass, Role, StreamEvent, ToolCall,
+    Usage,
+};
+use crate::postprocess;
 use crate::session::handle::SessionHandle;
+use crate::stream_processors;
 use crate::tools::hooks::{GuardVerdict, HookContext, run_after_tool, run_before_tool};
-use crate::tools::{ToolError, ToolExecutor, ToolResult, extract_action};
+use crate::tools::{PLAN_ACCEPTED, PLAN_TOOL, ToolError, ToolExecutor, ToolResult, extract_action};
 
 // ============================================================================
 // Types
@@ -63,6 +72,8 @@ pub enum AgenticResult {
         iterations: u32,
         /// Tool calls made during the loop.
         tool_calls_made: u32,
+        /// Time and memory used by the run.
+        stats: RunStats,
     },
     /// The loop is paused waiting for approval.
     AwaitingApproval {
@@ -76,6 +87,8 @@ pub enum AgenticResult {
         iterations: u32,
         /// Tool calls made so far.
         tool_calls_made: u32,
+        /// Time and memory used so far.
+        stats: RunStats,
     },
 }
 
@@ -93,6 +106,46 @@ pub enum AgenticError {
 
     #[error("llm call timed out after {0} seconds")]
     LlmTimeout(u64),
+
+    #[error("run exceeded its wall time budget of {0} seconds")]
+    WallTimeExceeded(u64),
+
+    #[error("run exceeded its tool time budget of {0} seconds")]
+    ToolTimeExceeded(u64),
+
+    #[error("run exceeded its limit of {0} tool calls")]
+    ToolCallsExceeded(u32),
+
+    #[error("run exceeded its limit of {max} calls to tool '{tool}'")]
+    ToolCallLimitExceeded { tool: String, max: u32 },
+}
+
+impl AgenticError {
+    /// Whether the run was aborted for exceeding a configured budget.
+    pub fn is_budget_exceeded(&self) -> bool {
+        self.budget().is_some()
+    }
+
+    /// The `session` setting of the budget the run exceeded, if it was
+    /// aborted for exceeding one.
+    pub fn budget(&self) -> Option<&'static str> {
+        match self {
+            Self::WallTimeExceeded(_) => Some("max_wall_time_seconds"),
+            Self::ToolTimeExceeded(_) => Some("max_tool_time_seconds"),
+            Self::ToolCallsExceeded(_) => Some("max_tool_calls"),
+            Self::ToolCallLimitExceeded { .. } => Some("max_tool_calls_per_tool"),
+            _ => None,
+        }
+    }
+
+    /// Why the provider request failed, if the run failed on one.
+    pub fn provider_error_class(&self) -> Option<ProviderErrorClass> {
+        match self {
+            Self::Llm(e) => e.class(),
+            Self::LlmTimeout(_) => Some(ProviderErrorClass::Network),
+            _ => None,
+        }
+    }
 }
 
 /// Context for resuming an agentic loop after a tool approval.
@@ -130,6 +183,17 @@ enum ToolCallOutcome {
 /// - Continues until the LLM returns a final response or max iterations is reached
 ///
 /// If `tool_filter` is provided, only those tools will be visible to the LLM.
+///
+/// Runs are metered (see `RunStats`) and aborted with `WallTimeExceeded`,
+/// `ToolTimeExceeded`, `ToolCallsExceeded`, or `ToolCallLimitExceeded` once
+/// the agent's run budgets are spent.
+///
+/// A request the provider rejects as too long for the model's context is
+/// retried once with a shortened conversation (see `shrink_context`), and
+/// the recovery is recorded in the session's event log.
+///
+/// Agents with `planning` plan the run first and then carry out the plan
+/// one step at a time (see `planning`).
 pub async fn run_agentic_loop(
     provider: Arc<dyn LLMProvider>,
     executor: &mut ToolExecutor,
@@ -145,10 +209,16 @@ pub async fn run_agentic_loop(
 
     let mut steering_rx = steering_rx;
     let mut messages = initial_messages;
-    let conversation_end_idx = messages.len();
+    let mut conversation_end_idx = messages.len();
     let mut total_usage: Option<Usage> = None;
     let mut iterations = 0u32;
+    let mut context_recovered = false;
     let mut tool_calls_made = 0u32;
+    let mut meter = RunMeter::new(&agent_spec.session);
+    let mut plan = agent_spec
+        .planning
+        .as_ref()
+        .and_then(|config| PlanRun::resume(config, &messages));
 
     // Compute loop budget for iteration group dropping (Layer 3c)
     let max_input = agent_spec.model.effective_max_input_tokens();
@@ -162,6 +232,10 @@ pub async fn run_agentic_loop(
         if iterations > max_iterations {
             return Err(AgenticError::MaxIterationsExceeded(max_iterations));
         }
+        if let Err(e) = meter.check() {
+            log_budget_exceeded(&e, &meter);
+            return Err(e);
+        }
 
         // Drain steering messages (skip first iteration — we just started)
         if iterations > 1
@@ -173,6 +247,11 @@ pub async fn run_agentic_loop(
             }
         }
 
+        // Ask for a plan, or announce the plan step to carry out
+        if let Some(announcement) = plan.as_mut().and_then(PlanRun::announcement) {
+            messages.push(announcement);
+        }
+
         // Layer 3b: Mask old tool results (after first iteration)
         if iterations > 1 {
             mask_tool_results(
@@ -187,7 +266,10 @@ pub async fn run_agentic_loop(
         drop_oldest_iterations(&mut messages, conversation_end_idx, loop_token_budget);
 
         // Refresh tool definitions each iteration (picks up reload_tools changes)
-        let tool_definitions = executor.tool_definitions(tool_filter);
+        let tool_definitions = match &plan {
+            Some(plan) => plan.tool_definitions(executor, tool_filter),
+            None => executor.tool_definitions(tool_filter),
+        };
 
         debug!(
             iteration = iterations,
@@ -207,8 +289,12 @@ pub async fn run_agentic_loop(
 
         // Call LLM with streaming (retry on rate limit) + consume stream,
         // all under a single timeout covering the full LLM round-trip.
+        // The timeout is shortened to whatever is left of the wall time budget.
         let llm_timeout_secs = agent_spec.session.llm_timeout_seconds;
-        let (content, tool_calls, usage) = tokio::time::timeout(llm_timeout, async {
+        let remaining_wall_time = meter.remaining_wall_time();
+        let call_timeout = remaining_wall_time.map_or(llm_timeout, |r| r.min(llm_timeout));
+        let llm_started = Instant::now();
+        let llm_result = tokio::time::timeout(call_timeout, async {
             let mut stream = {
                 const MAX_RETRIES: u32 = 3;
                 let mut attempt = 0;
@@ -227,6 +313,7 @@ pub async fn run_agentic_loop(
             };
 
             let mut content = String::new();
+            let mut reasoning = String::new();
             let mut tool_calls: Vec<ToolCall> = Vec::new();
             let mut usage: Option<Usage> = None;
 
@@ -235,6 +322,9 @@ pub async fn run_agentic_loop(
                     StreamEvent::Token(token) => {
                         content.push_str(&token);
                     }
+                    StreamEvent::Reasoning(chunk) => {
+                        reasoning.push_str(&chunk);
+                    }
                     StreamEvent::ToolCalls(calls) => {
                         tool_calls = calls;
                     }
@@ -247,10 +337,52 @@ pub async fn run_agentic_loop(
                 }
             }
 
-            Ok((content, tool_calls, usage))
+            Ok((content, reasoning, tool_calls, usage))
         })
-        .await
-        .map_err(|_| AgenticError::LlmTimeout(llm_timeout_secs))??;
+        .await;
+        meter.record_provider(llm_started.elapsed());
+        let (content, reasoning, tool_calls, usage) = match llm_result {
+            // Too long for the model's context: shorten the conversation and
+            // retry once, without counting it as an iteration.
+            Ok(Err(e))
+                if !context_recovered
+                    && e.provider_error_class() == Some(ProviderErrorClass::ContextTooLong) =>
+            {
+                context_recovered = true;
+                let Some(recovery) = shrink_context(&mut messages, context_config) else {
+                    return Err(e);
+                };
+                conversation_end_idx =
+                    conversation_end_idx.saturating_sub(recovery.dropped_messages as usize);
+                warn!(
+                    dropped_messages = recovery.dropped_messages,
+                    tokens_before = recovery.tokens_before,
+                    tokens_after = recovery.tokens_after,
+                    "Context too long, retrying with a shortened conversation"
+                );
+                if let Err(e) = handle.record_context_recovery(recovery).await {
+                    warn!(error = %e, "Failed to record context recovery");
+                }
+                iterations -= 1;
+                continue;
+            }
+            Ok(result) => result?,
+            Err(_) if remaining_wall_time.is_some_and(|r| r < llm_timeout) => {
+                let e = meter
+                    .check()
+                    .err()
+                    .unwrap_or(AgenticError::LlmTimeout(llm_timeout_secs));
+                log_budget_exceeded(&e, &meter);
+                return Err(e);
+            }
+            Err(_) => return Err(AgenticError::LlmTimeout(llm_timeout_secs)),
+        };
+
+        if !reasoning.is_empty()
+            && let Err(e) = handle.record_reasoning(reasoning).await
+        {
+            warn!(error = %e, "Failed to record reasoning");
+        }
 
         let response_usage = usage.clone();
         // Accumulate usage
@@ -258,17 +390,43 @@ pub async fn run_agentic_loop(
 
         // If no tool calls, persist final response and we're done
         if tool_calls.is_empty() {
+            let content = stream_processors::apply(&agent_spec.stream_processors, content);
+            let content = postprocess::apply(&agent_spec.post_processors, content);
+
+            // A plan step is done: record it, and move on unless it was the last
+            if let Some(finished) = plan.as_mut().and_then(PlanRun::finish_step) {
+                if let Err(e) = handle
+                    .record_plan_step(finished.call_id, finished.step, content.clone())
+                    .await
+                {
+                    warn!(error = %e, "Failed to record plan step");
+                }
+                if !finished.last {
+                    if let Err(e) = handle
+                        .enqueue_assistant_response(content.clone(), vec![], response_usage)
+                        .await
+                    {
+                        warn!(error = %e, "Failed to enqueue plan step response");
+                    }
+                    messages.push(Message::text(Role::Assistant, &content));
+                    continue;
+                }
+            }
+
             if let Err(e) = handle
                 .enqueue_assistant_response(content.clone(), vec![], response_usage)
                 .await
             {
                 warn!(error = %e, "Failed to enqueue final assistant response");
             }
+            let stats = meter.stats();
+            log_run_stats("complete", &stats);
             return Ok(AgenticResult::Complete {
                 content,
                 usage: total_usage,
                 iterations,
                 tool_calls_made,
+                stats,
             });
         }
 
@@ -307,15 +465,30 @@ pub async fn run_agentic_loop(
                 reload_requested = true;
             }
 
-            let outcome = execute_tool_call(
-                executor,
-                handle,
-                tool_call,
-                &messages,
-                context_config,
-                &agent_spec.hooks,
-            )
-            .await;
+            // Abort before a call over the tool call limits, closing it out too
+            if let Err(e) = meter.admit_tool_call(&tool_call.function.name) {
+                return Err(abort_over_budget(handle, &tool_calls[i..], e, &meter).await);
+            }
+
+            let tool_started = Instant::now();
+            let outcome = match plan.as_mut() {
+                Some(plan) if tool_call.function.name == PLAN_TOOL => {
+                    propose_plan(plan, handle, tool_call, &messages).await
+                }
+                _ => {
+                    execute_tool_call(
+                        executor,
+                        handle,
+                        tool_call,
+                        &messages,
+                        context_config,
+                        &agent_spec.hooks,
+                        tool_filter,
+                    )
+                    .await
+                }
+            };
+            meter.record_tool(tool_started.elapsed());
 
             match outcome {
                 ToolCallOutcome::Executed {
@@ -342,16 +515,32 @@ pub async fn run_agentic_loop(
                             warn!(error = %e, "Failed to enqueue tools skipped event");
                         }
                     }
+                    if agent_spec.metadata.name != handle.agent() {
+                        pending.agent = Some(agent_spec.metadata.name.clone());
+                    }
+                    if let Some(filter) = tool_filter {
+                        let mut tools: Vec<String> = filter.iter().cloned().collect();
+                        tools.sort();
+                        pending.tools = Some(tools);
+                    }
+                    let stats = meter.stats();
+                    log_run_stats("awaiting_approval", &stats);
                     return Ok(AgenticResult::AwaitingApproval {
                         pending,
                         partial_content: content,
                         usage: total_usage,
                         iterations,
                         tool_calls_made,
+                        stats,
                     });
                 }
             }
 
+            // Abort once a budget is spent, closing out the remaining calls
+            if let Err(e) = meter.check() {
+                return Err(abort_over_budget(handle, &tool_calls[i + 1..], e, &meter).await);
+            }
+
             // Check for steering between tool calls
             if let Some(ref mut rx) = steering_rx {
                 let steered = drain_steering(rx);
@@ -432,6 +621,7 @@ pub async fn resume_agentic_loop(
 /// Execute a single tool call and return the outcome.
 ///
 /// This handles:
+/// - Refusing tools outside `tool_filter`
 /// - Running before-tool hooks (guards)
 /// - Executing the tool
 /// - Handling approval requirements (pausing the loop)
@@ -448,7 +638,19 @@ async fn execute_tool_call(
     messages: &[Message],
     context_config: &ContextConfig,
     hooks: &HooksConfig,
+    tool_filter: Option<&HashSet<String>>,
 ) -> ToolCallOutcome {
+    // The model only sees filtered tools, but may still name others
+    if let Some(filter) = tool_filter
+        && !filter.contains(&tool_call.function.name)
+    {
+        let reason = format!(
+            "Tool '{}' is not available in this run.",
+            tool_call.function.name
+        );
+        return reject_tool_call(handle, tool_call, reason).await;
+    }
+
     // Parse arguments (empty string is valid — means no arguments)
     let raw_args = &tool_call.function.arguments;
     let arguments = parse_tool_arguments(&tool_call.function.name, raw_args);
@@ -464,23 +666,7 @@ async fn execute_tool_call(
 
     // Run before-tool hooks (guards)
     if let GuardVerdict::Reject(reason) = run_before_tool(hooks, &hook_ctx) {
-        let result = ToolResult {
-            success: false,
-            content: reason,
-        };
-
-        // Record rejected result event (tool call already in composite event)
-        if let Err(e) = handle
-            .enqueue_tool_result(tool_call.id.clone(), false, result.content.clone())
-            .await
-        {
-            warn!(error = %e, "Failed to enqueue tool result event");
-        }
-
-        return ToolCallOutcome::Executed {
-            tool_result_msg: Message::tool_result(&tool_call.id, result.content),
-            steering_msg: None,
-        };
+        return reject_tool_call(handle, tool_call, reason).await;
     }
 
     // Execute the tool
@@ -548,6 +734,26 @@ async fn execute_tool_call(
     }
 }
 
+/// Answer a tool call with `reason` instead of running it.
+async fn reject_tool_call(
+    handle: &SessionHandle,
+    tool_call: &ToolCall,
+    reason: String,
+) -> ToolCallOutcome {
+    // Record rejected result event (tool call already in composite event)
+    if let Err(e) = handle
+        .enqueue_tool_result(tool_call.id.clone(), false, reason.clone())
+        .await
+    {
+        warn!(error = %e, "Failed to enqueue tool result event");
+    }
+
+    ToolCallOutcome::Executed {
+        tool_result_msg: Message::tool_result(&tool_call.id, reason),
+        steering_msg: None,
+    }
+}
+
 /// Handle a tool that requires approval.
 ///
 /// Records events and returns the pending approval state.
@@ -569,6 +775,59 @@ async fn handle_approval_required(
     ToolCallOutcome::AwaitingApproval(pending)
 }
 
+/// Handle a `plan` tool call: record the plan and start carrying it out, or
+/// pause for its approval. An invalid plan is returned to the model to fix.
+async fn propose_plan(
+    plan: &mut PlanRun,
+    handle: &SessionHandle,
+    tool_call: &ToolCall,
+    messages: &[Message],
+) -> ToolCallOutcome {
+    let result = match plan.propose(tool_call) {
+        Ok(steps) => {
+            if let Err(e) = handle
+                .record_plan(tool_call.id.clone(), steps.clone(), plan.require_approval())
+                .await
+            {
+                warn!(error = %e, "Failed to record plan");
+            }
+            if plan.require_approval() {
+                let pending = PendingApproval::new(
+                    tool_call.id.clone(),
+                    PLAN_TOOL.to_string(),
+                    parse_tool_arguments(PLAN_TOOL, &tool_call.function.arguments),
+                    render_plan(&steps),
+                    ToolType::Builtin,
+                    messages.to_vec(),
+                );
+                return handle_approval_required(handle, pending).await;
+            }
+            debug!(steps = steps.len(), "Plan accepted");
+            plan.start(tool_call.id.clone(), steps);
+            ToolResult {
+                success: true,
+                content: PLAN_ACCEPTED.to_string(),
+            }
+        }
+        Err(content) => ToolResult {
+            success: false,
+            content,
+        },
+    };
+
+    if let Err(e) = handle
+        .enqueue_tool_result(tool_call.id.clone(), result.success, result.content.clone())
+        .await
+    {
+        warn!(error = %e, "Failed to enqueue tool result event");
+    }
+
+    ToolCallOutcome::Executed {
+        tool_result_msg: Message::tool_result(&tool_call.id, result.content),
+        steering_msg: None,
+    }
+}
+
 /// Build the assistant message for a response with tool calls.
 fn build_assistant_message(content: &str, tool_calls: &[ToolCall]) -> Message {
     if content.is_empty() {
@@ -579,6 +838,7 @@ fn build_assistant_message(content: &str, tool_calls: &[ToolCall]) -> Message {
             content: Some(content.to_string()),
             tool_calls: Some(tool_calls.to_vec()),
             tool_call_id: None,
+            reasoning: None,
         }
     }
 }
@@ -635,6 +895,48 @@ fn parse_tool_arguments(tool_name: &str, raw_args: &str) -> serde_json::Value {
     }
 }
 
+fn log_run_stats(outcome: &str, stats: &RunStats) {
+    debug!(
+        outcome,
+        wall_time_ms = stats.wall_time.as_millis() as u64,
+        provider_time_ms = stats.provider_time.as_millis() as u64,
+        tool_time_ms = stats.tool_time.as_millis() as u64,
+        peak_memory_bytes = stats.peak_memory_bytes,
+        "Agentic run finished"
+    );
+}
+
+/// Record the tool calls a run over budget leaves unrun as skipped, and
+/// return the run's error.
+async fn abort_over_budget(
+    handle: &SessionHandle,
+    unrun: &[ToolCall],
+    error: AgenticError,
+    meter: &RunMeter,
+) -> AgenticError {
+    let remaining_ids: Vec<String> = unrun.iter().map(|tc| tc.id.clone()).collect();
+    if !remaining_ids.is_empty() {
+        let reason = "run budget exceeded".to_string();
+        if let Err(e) = handle.enqueue_tools_skipped(remaining_ids, reason).await {
+            warn!(error = %e, "Failed to enqueue tools skipped event");
+        }
+    }
+    log_budget_exceeded(&error, meter);
+    error
+}
+
+fn log_budget_exceeded(error: &AgenticError, meter: &RunMeter) {
+    let stats = meter.stats();
+    warn!(
+        error = %error,
+        wall_time_ms = stats.wall_time.as_millis() as u64,
+        provider_time_ms = stats.provider_time.as_millis() as u64,
+        tool_time_ms = stats.tool_time.as_millis() as u64,
+        peak_memory_bytes = stats.peak_memory_bytes,
+        "Agentic run aborted"
+    );
+}
+
 /// Accumulate token usage across iterations.
 fn accumulate_usage(existing: Option<Usage>, new: Option<Usage>) -> Option<Usage> {
     match (existing, new) {
@@ -656,7 +958,6 @@ fn accumulate_usage(existing: Option<Usage>, new: Option<Usage>) -> Option<Usage
 #[cfg(test)]
 mod tests {
     use super::*;
-    use crate::agent::ToolType;
 
     #[test]
     fn agentic_result_complete_debug() {
@@ -665,6 +966,7 @@ mod tests {
             usage: None,
             iterations: 1,
             tool_calls_made: 0,
+            stats: RunStats::default(),
         };
         assert!(format!("{:?}", result).contains("Hello"));
         assert!(format!("{:?}", result).contains("Complete"));
@@ -686,6 +988,7 @@ mod tests {
             usage: None,
             iterations: 1,
             tool_calls_made: 1,
+            stats: RunStats::default(),
         };
         assert!(format!("{:?}", result).contains("AwaitingApproval"));
         assert!(format!("{:?}", result).contains("call_123"));
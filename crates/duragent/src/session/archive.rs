@@ -0,0 +1,316 @@
+//! Moving old sessions to the cold archive and reading them back.
+//!
+//! An archived session is a gzip-compressed JSONL file: the snapshot on the
+//! first line, then every event still in the session's event log. With
+//! tenant encryption enabled, the compressed file is sealed as a whole with
+//! the session's tenant data key.
+
+use std::io::{Read, Write};
+use std::sync::Arc;
+use std::time::Duration;
+
+use chrono::{DateTime, TimeDelta, Utc};
+use flate2::Compression;
+use flate2::read::GzDecoder;
+use flate2::write::GzEncoder;
+use tracing::{debug, info, warn};
+
+use crate::api::SessionStatus;
+use crate::encryption::TenantKeys;
+use crate::llm::Message;
+use crate::store::{SessionArchive, SessionStore, StorageError, StorageResult};
+
+use super::{
+    SessionEvent, SessionEventEval, SessionRegistry, SessionSnapshot, SessionSnapshotEval,
+};
+
+/// How often to look for sessions to archive.
+const ARCHIVE_SWEEP_INTERVAL: Duration = Duration::from_secs(3600);
+
+// ============================================================================
+// Stored Session
+// ============================================================================
+
+/// A session that is no longer live, read from the hot store or the archive.
+#[derive(Debug, Clone)]
+pub struct StoredSession {
+    pub snapshot: SessionSnapshot,
+    pub events: Vec<SessionEvent>,
+}
+
+impl StoredSession {
+    /// The conversation, as the session actor would rebuild it.
+    pub fn messages(&self) -> Vec<Message> {
+        let replay_from = self.snapshot.replay_from_seq();
+        let mut messages = self.snapshot.conversation.clone();
+        messages.extend(
+            self.events
+                .iter()
+                .filter(|e| e.seq > replay_from)
+                .filter_map(|e| e.to_message()),
+        );
+        messages
+    }
+
+    fn encode(&self) -> StorageResult<Vec<u8>> {
+        let mut encoder = GzEncoder::new(Vec::new(), Compression::default());
+        let mut write_line = |value: String| {
+            encoder
+                .write_all(value.as_bytes())
+                .and_then(|()| encoder.write_all(b"\n"))
+                .map_err(|e| StorageError::serialization(e.to_string()))
+        };
+
+        write_line(
+            serde_json::to_string(&self.snapshot)
+                .map_err(|e| StorageError::serialization(e.to_string()))?,
+        )?;
+        for event in &self.events {
+            write_line(
+                serde_json::to_string(event)
+                    .map_err(|e| StorageError::serialization(e.to_string()))?,
+            )?;
+        }
+        encoder
+            .finish()
+            .map_err(|e| StorageError::serialization(e.to_string()))
+    }
+
+    fn decode(session_id: &str, data: &[u8]) -> StorageResult<Self> {
+        let corrupt = |message: String| {
+            StorageError::file_deserialization(format!("{session_id}.jsonl.gz"), message)
+        };
+
+        let mut content = String::new();
+        GzDecoder::new(data)
+            .read_to_string(&mut content)
+            .map_err(|e| corrupt(e.to_string()))?;
+
+        let mut lines = content.lines().filter(|line| !line.trim().is_empty());
+        let snapshot = lines
+            .next()
+            .ok_or_else(|| corrupt("archive is empty".to_string()))?;
+        let snapshot = serde_json::from_str(snapshot).map_err(|e| corrupt(e.to_string()))?;
+        let events = lines
+            .map(|line| serde_json::from_str(line).map_err(|e| corrupt(e.to_string())))
+            .collect::<StorageResult<_>>()?;
+
+        Ok(Self { snapshot, events })
+    }
+}
+
+// ============================================================================
+// Session Archiver
+// ============================================================================
+
+/// Moves completed sessions from the hot store to the archive.
+#[derive(Clone)]
+pub struct SessionArchiver {
+    store: Arc<dyn SessionStore>,
+    archive: Arc<dyn SessionArchive>,
+    encryption: Option<TenantKeys>,
+}
+
+impl SessionArchiver {
+    pub fn new(store: Arc<dyn SessionStore>, archive: Arc<dyn SessionArchive>) -> Self {
+        Self {
+            store,
+            archive,
+            encryption: None,
+        }
+    }
+
+    /// Seal archived sessions with per-tenant data keys.
+    pub fn with_encryption(mut self, keys: TenantKeys) -> Self {
+        self.encryption = Some(keys);
+        self
+    }
+
+    /// Load a session that is no longer live, from the hot store first and
+    /// the archive second.
+    ///
+    /// Returns `Ok(None)` if neither has it.
+    pub async fn load(&self, session_id: &str) -> StorageResult<Option<StoredSession>> {
+        if let Some(snapshot) = self.store.load_snapshot(session_id).await? {
+            let events = self.store.load_events(session_id, 0).await?;
+            return Ok(Some(StoredSession { snapshot, events }));
+        }
+
+        match self.archive.load(session_id).await? {
+            Some(data) => {
+                let data = self.open(session_id, data)?;
+                StoredSession::decode(session_id, &data).map(Some)
+            }
+            None => Ok(None),
+        }
+    }
+
+    /// Archive completed sessions last snapshotted before `cutoff`.
+    ///
+    /// Sessions still in `registry` are skipped. Each session is deleted from
+    /// the hot store only after its archive copy is saved. Returns the
+    /// number of sessions archived.
+    pub async fn archive_completed_before(
+        &self,
+        registry: &SessionRegistry,
+        cutoff: DateTime<Utc>,
+    ) -> StorageResult<usize> {
+        let mut archived = 0;
+        for session_id in self.store.list().await? {
+            if registry.get(&session_id).is_some() {
+                continue;
+            }
+            match self.archive_one(&session_id, cutoff).await {
+                Ok(true) => archived += 1,
+                Ok(false) => {}
+                Err(e) => warn!(session_id = %session_id, error = %e, "Failed to archive session"),
+            }
+        }
+        Ok(archived)
+    }
+
+    async fn archive_one(&self, session_id: &str, cutoff: DateTime<Utc>) -> StorageResult<bool> {
+        let Some(snapshot) = self.store.load_snapshot(session_id).await? else {
+            return Ok(false);
+        };
+        if snapshot.status != SessionStatus::Completed || snapshot.snapshot_at >= cutoff {
+            return Ok(false);
+        }
+
+        let events = self.store.load_events(session_id, 0).await?;
+        let data = self.seal(StoredSession { snapshot, events })?;
+        self.archive.save(session_id, &data).await?;
+        self.store.delete(session_id).await?;
+        debug!(session_id = %session_id, bytes = data.len(), "Archived session");
+        Ok(true)
+    }
+
+    fn seal(&self, session: StoredSession) -> StorageResult<Vec<u8>> {
+        let data = session.encode()?;
+        let Some(keys) = &self.encryption else {
+            return Ok(data);
+        };
+        let namespace = keys.namespace_for_agent(&session.snapshot.agent);
+        keys.seal(&namespace, &session.snapshot.session_id, &data)
+            .map(String::into_bytes)
+            .map_err(|e| StorageError::serialization(e.to_string()))
+    }
+
+    /// Unseal an archived session. Gzip data is never valid UTF-8, so
+    /// archives written before encryption was enabled pass through.
+    fn open(&self, session_id: &str, data: Vec<u8>) -> StorageResult<Vec<u8>> {
+        let Some(keys) = &self.encryption else {
+            return Ok(data);
+        };
+        let Ok(record) = std::str::from_utf8(&data) else {
+            return Ok(data);
+        };
+        match keys.open(session_id, record) {
+            Ok(Some((_, plaintext))) => Ok(plaintext),
+            Ok(None) => Ok(data),
+            Err(e) => Err(StorageError::file_deserialization(
+                format!("{session_id}.jsonl.gz"),
+                e.to_string(),
+            )),
+        }
+    }
+}
+
+/// Spawn the periodic sweep that archives sessions completed more than
+/// `after_days` ago.
+pub fn spawn_archive_job(archiver: SessionArchiver, registry: SessionRegistry, after_days: u64) {
+    let after = TimeDelta::days(after_days as i64);
+
+    tokio::spawn(async move {
+        let mut interval = tokio::time::interval(ARCHIVE_SWEEP_INTERVAL);
+        loop {
+            interval.tick().await;
+            match archiver
+                .archive_completed_before(&registry, Utc::now() - after)
+                .await
+            {
+                Ok(0) => {}
+                Ok(archived) => info!(archived, "Archived completed sessions"),
+                Err(e) => warn!(error = %e, "Failed to list sessions for archiving"),
+            }
+        }
+    });
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::config::CompactionMode;
+    use crate::llm::Role;
+    use crate::session::{CheckpointState, SessionConfig, SessionEventPayload};
+    use crate::store::file::{FileSessionArchive, FileSessionStore};
+    use tempfile::TempDir;
+
+    #[tokio::test]
+    async fn archives_old_completed_sessions_and_reads_them_back() {
+        let tmp = TempDir::new().unwrap();
+        let store: Arc<dyn SessionStore> = Arc::new(FileSessionStore::new(tmp.path().join("hot")));
+        let archiver = SessionArchiver::new(
+            store.clone(),
+            Arc::new(FileSessionArchive::new(tmp.path().join("cold"))),
+        );
+        let registry = SessionRegistry::new(store.clone(), CompactionMode::Disabled);
+
+        for (id, status) in [
+            ("session_done", SessionStatus::Completed),
+            ("session_paused", SessionStatus::Paused),
+        ] {
+            let snapshot = SessionSnapshot::new(
+                id.to_string(),
+                "test-agent".to_string(),
+                status,
+                Utc::now(),
+                CheckpointState {
+                    last_event_seq: 1,
+                    checkpoint_seq: 1,
+                    conversation: vec![Message::text(Role::User, "Hello")],
+                },
+                SessionConfig::default(),
+            );
+            store.save_snapshot(id, &snapshot).await.unwrap();
+            store
+                .append_events(
+                    id,
+                    &[SessionEvent::new(
+                        2,
+                        SessionEventPayload::AssistantMessage {
+                            agent: "test-agent".to_string(),
+                            content: "Hi!".to_string(),
+                            usage: None,
+                        },
+                    )],
+                )
+                .await
+                .unwrap();
+        }
+
+        // Not old enough yet
+        let cutoff = Utc::now() - TimeDelta::days(1);
+        let archived = archiver
+            .archive_completed_before(&registry, cutoff)
+            .await
+            .unwrap();
+        assert_eq!(archived, 0);
+
+        let cutoff = Utc::now() + TimeDelta::seconds(1);
+        let archived = archiver
+            .archive_completed_before(&registry, cutoff)
+            .await
+            .unwrap();
+        assert_eq!(archived, 1);
+        assert_eq!(store.list().await.unwrap(), vec!["session_paused"]);
+
+        let session = archiver.load("session_done").await.unwrap().unwrap();
+        assert_eq!(session.snapshot.status, SessionStatus::Completed);
+        let messages = session.messages();
+        assert_eq!(messages.len(), 2);
+        assert_eq!(messages[1].content.as_deref(), Some("Hi!"));
+
+        assert!(archiver.load("session_missing").await.unwrap().is_none());
+    }
+}
@@ -102,6 +102,7 @@ pub(crate) fn assistant_response_to_message(
         content: content_opt,
         tool_calls: tool_calls_opt,
         tool_call_id: None,
+        reasoning: None,
     }
 }
 
@@ -0,0 +1,578 @@
+//! Transcript export as Markdown, HTML, or PDF.
+//!
+//! Exports are meant for people rather than tools: user messages and
+//! assistant replies are shown in full, while tool calls and their results
+//! are collapsed. Markdown and HTML use `<details>` blocks; the PDF lists
+//! the tools called on a single line. System and steering messages are left
+//! out.
+
+use std::fmt::Write;
+
+use chrono::{DateTime, Utc};
+
+use crate::llm::{Message, Role};
+
+// ============================================================================
+// Format
+// ============================================================================
+
+/// An export format, as named in `?format=`.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum ExportFormat {
+    Markdown,
+    Html,
+    Pdf,
+}
+
+impl ExportFormat {
+    /// Parse `md` (or `markdown`), `html`, or `pdf`.
+    pub fn parse(value: &str) -> Option<Self> {
+        match value {
+            "md" | "markdown" => Some(Self::Markdown),
+            "html" => Some(Self::Html),
+            "pdf" => Some(Self::Pdf),
+            _ => None,
+        }
+    }
+
+    pub fn content_type(self) -> &'static str {
+        match self {
+            Self::Markdown => "text/markdown; charset=utf-8",
+            Self::Html => "text/html; charset=utf-8",
+            Self::Pdf => "application/pdf",
+        }
+    }
+
+    pub fn extension(self) -> &'static str {
+        match self {
+            Self::Markdown => "md",
+            Self::Html => "html",
+            Self::Pdf => "pdf",
+        }
+    }
+
+    pub fn render(self, transcript: &Transcript) -> Vec<u8> {
+        match self {
+            Self::Markdown => transcript.to_markdown().into_bytes(),
+            Self::Html => transcript.to_html().into_bytes(),
+            Self::Pdf => transcript.to_pdf(),
+        }
+    }
+}
+
+// ============================================================================
+// Transcript
+// ============================================================================
+
+/// A session's conversation, arranged for export.
+#[derive(Debug, Clone)]
+pub struct Transcript {
+    pub session_id: String,
+    pub agent: String,
+    pub created_at: DateTime<Utc>,
+    entries: Vec<Entry>,
+}
+
+#[derive(Debug, Clone, PartialEq, Eq)]
+enum Entry {
+    Message {
+        role: &'static str,
+        content: String,
+    },
+    /// One assistant turn's tool calls, with their results.
+    ToolCalls(Vec<ToolUse>),
+}
+
+#[derive(Debug, Clone, PartialEq, Eq)]
+struct ToolUse {
+    call_id: Option<String>,
+    name: String,
+    arguments: String,
+    result: Option<String>,
+}
+
+impl Transcript {
+    pub fn new(
+        session_id: impl Into<String>,
+        agent: impl Into<String>,
+        created_at: DateTime<Utc>,
+        messages: &[Message],
+    ) -> Self {
+        let mut entries = Vec::new();
+        for message in messages {
+            match message.role {
+                Role::User => entries.push(Entry::Message {
+                    role: "User",
+                    content: message.content_str().to_string(),
+                }),
+                Role::Assistant => {
+                    if !message.content_str().is_empty() {
+                        entries.push(Entry::Message {
+                            role: "Assistant",
+                            content: message.content_str().to_string(),
+                        });
+                    }
+                    if let Some(calls) = &message.tool_calls {
+                        let uses = calls
+                            .iter()
+                            .map(|call| ToolUse {
+                                call_id: Some(call.id.clone()),
+                                name: call.function.name.clone(),
+                                arguments: call.function.arguments.clone(),
+                                result: None,
+                            })
+                            .collect();
+                        entries.push(Entry::ToolCalls(uses));
+                    }
+                }
+                Role::Tool => attach_result(&mut entries, message),
+                Role::System | Role::Steering => {}
+            }
+        }
+        Self {
+            session_id: session_id.into(),
+            agent: agent.into(),
+            created_at,
+            entries,
+        }
+    }
+
+    pub fn to_markdown(&self) -> String {
+        let mut out = String::new();
+        let _ = write!(
+            out,
+            "# Session {}\n\nAgent: {} · Started {}\n",
+            self.session_id,
+            self.agent,
+            self.started()
+        );
+        for entry in &self.entries {
+            match entry {
+                Entry::Message { role, content } => {
+                    let _ = write!(out, "\n**{role}**\n\n{}\n", content.trim_end());
+                }
+                Entry::ToolCalls(uses) => {
+                    let _ = write!(
+                        out,
+                        "\n<details>\n<summary>{}</summary>\n",
+                        escape_html(&tool_summary(uses))
+                    );
+                    for tool in uses {
+                        let _ = write!(
+                            out,
+                            "\n`{}`\n\n{}",
+                            tool.name,
+                            fenced("json", &tool.arguments)
+                        );
+                        if let Some(result) = &tool.result {
+                            let _ = write!(out, "\nResult:\n\n{}", fenced("", result));
+                        }
+                    }
+                    out.push_str("\n</details>\n");
+                }
+            }
+        }
+        out
+    }
+
+    pub fn to_html(&self) -> String {
+        let title = escape_html(&format!("Session {}", self.session_id));
+        let mut out = String::new();
+        let _ = write!(
+            out,
+            "<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n<meta charset=\"utf-8\">\n\
+             <meta name=\"viewport\" content=\"width=device-width, initial-scale=1\">\n\
+             <title>{title}</title>\n<style>\n\
+             body{{font-family:system-ui,sans-serif;max-width:48rem;margin:2rem auto;padding:0 1rem;color:#1f2328}}\n\
+             .meta{{color:#656d76}}\n\
+             .message{{margin:1rem 0;padding:.75rem 1rem;border-radius:6px;background:#f6f8fa;white-space:pre-wrap}}\n\
+             .user{{background:#ddf4ff}}\n\
+             .role{{display:block;font-weight:600;margin-bottom:.25rem;white-space:normal}}\n\
+             details{{margin:1rem 0;color:#656d76}}\n\
+             pre{{background:#f6f8fa;padding:.5rem;border-radius:6px;overflow-x:auto;white-space:pre-wrap}}\n\
+             </style>\n</head>\n<body>\n<h1>{title}</h1>\n<p class=\"meta\">Agent: {} · Started {}</p>\n",
+            escape_html(&self.agent),
+            escape_html(&self.started()),
+        );
+        for entry in &self.entries {
+            match entry {
+                Entry::Message { role, content } => {
+                    let _ = writeln!(
+                        out,
+                        "<div class=\"message {}\"><span class=\"role\">{role}</span>{}</div>",
+                        role.to_lowercase(),
+                        escape_html(content.trim_end())
+                    );
+                }
+                Entry::ToolCalls(uses) => {
+                    let _ = writeln!(
+                        out,
+                        "<details>\n<summary>{}</summary>",
+                        escape_html(&tool_summary(uses))
+                    );
+                    for tool in uses {
+                        let _ = writeln!(
+                            out,
+                            "<p><code>{}</code></p>\n<pre>{}</pre>",
+                            escape_html(&tool.name),
+                            escape_html(&tool.arguments)
+                        );
+                        if let Some(result) = &tool.result {
+                            let _ =
+                                writeln!(out, "<p>Result:</p>\n<pre>{}</pre>", escape_html(result));
+                        }
+                    }
+                    out.push_str("</details>\n");
+                }
+            }
+        }
+        out.push_str("</body>\n</html>\n");
+        out
+    }
+
+    pub fn to_pdf(&self) -> Vec<u8> {
+        let mut lines = vec![
+            PdfLine::new(PdfFont::Bold, format!("Session {}", self.session_id)),
+            PdfLine::new(
+                PdfFont::Regular,
+                format!("Agent: {} · Started {}", self.agent, self.started()),
+            ),
+        ];
+        for entry in &self.entries {
+            lines.push(PdfLine::new(PdfFont::Regular, String::new()));
+            match entry {
+                Entry::Message { role, content } => {
+                    lines.push(PdfLine::new(PdfFont::Bold, (*role).to_string()));
+                    for line in wrap(content.trim_end(), PDF_WRAP_CHARS) {
+                        lines.push(PdfLine::new(PdfFont::Regular, line));
+                    }
+                }
+                Entry::ToolCalls(uses) => {
+                    let summary = format!("[{}]", tool_summary(uses));
+                    for line in wrap(&summary, PDF_WRAP_CHARS) {
+                        lines.push(PdfLine::new(PdfFont::Italic, line));
+                    }
+                }
+            }
+        }
+        write_pdf(&lines)
+    }
+
+    fn started(&self) -> String {
+        self.created_at.format("%Y-%m-%d %H:%M UTC").to_string()
+    }
+}
+
+/// Attach a tool result to the call it answers, or show it on its own.
+fn attach_result(entries: &mut Vec<Entry>, message: &Message) {
+    let result = message.content_str().to_string();
+    if let Some(Entry::ToolCalls(uses)) = entries.last_mut()
+        && let Some(tool) = uses
+            .iter_mut()
+            .find(|t| t.result.is_none() && t.call_id == message.tool_call_id)
+    {
+        tool.result = Some(result);
+        return;
+    }
+    entries.push(Entry::ToolCalls(vec![ToolUse {
+        call_id: message.tool_call_id.clone(),
+        name: "tool result".to_string(),
+        arguments: String::new(),
+        result: Some(result),
+    }]));
+}
+
+/// E.g. `Tool calls: bash, read_file`.
+fn tool_summary(uses: &[ToolUse]) -> String {
+    let names: Vec<&str> = uses.iter().map(|t| t.name.as_str()).collect();
+    let label = if uses.len() == 1 {
+        "Tool call"
+    } else {
+        "Tool calls"
+    };
+    format!("{label}: {}", names.join(", "))
+}
+
+/// A fenced code block longer than any backtick run in `text`.
+fn fenced(info: &str, text: &str) -> String {
+    let longest = text.split(|c| c != '`').map(str::len).max().unwrap_or(0);
+    let fence = "`".repeat(longest.max(2) + 1);
+    format!("{fence}{info}\n{}\n{fence}\n", text.trim_end())
+}
+
+fn escape_html(s: &str) -> String {
+    s.replace('&', "&amp;")
+        .replace('<', "&lt;")
+        .replace('>', "&gt;")
+        .replace('"', "&quot;")
+}
+
+/// Split `text` into lines of at most `width` characters, at spaces where
+/// possible.
+fn wrap(text: &str, width: usize) -> Vec<String> {
+    let mut lines = Vec::new();
+    for paragraph in text.split('\n') {
+        let mut line = String::new();
+        for word in paragraph.split(' ') {
+            let mut word = word.replace('\t', "    ");
+            while word.chars().count() > width {
+                if !line.is_empty() {
+                    lines.push(std::mem::take(&mut line));
+                }
+                let split = word
+                    .char_indices()
+                    .nth(width)
+                    .map_or(word.len(), |(i, _)| i);
+                lines.push(word[..split].to_string());
+                word = word[split..].to_string();
+            }
+            let needed =
+                line.chars().count() + usize::from(!line.is_empty()) + word.chars().count();
+            if needed > width && !line.is_empty() {
+                lines.push(std::mem::take(&mut line));
+            }
+            if !line.is_empty() {
+                line.push(' ');
+            }
+            line.push_str(&word);
+        }
+        lines.push(line);
+    }
+    lines
+}
+
+// ============================================================================
+// PDF
+// ============================================================================
+
+/// Characters per line; Helvetica at 10pt averages about five points per
+/// character across the 504pt text width.
+const PDF_WRAP_CHARS: usize = 95;
+/// US Letter, in points.
+const PDF_PAGE_WIDTH: u32 = 612;
+const PDF_PAGE_HEIGHT: u32 = 792;
+const PDF_MARGIN: u32 = 54;
+const PDF_FONT_SIZE: u32 = 10;
+const PDF_LEADING: u32 = 14;
+
+#[derive(Debug, Clone, Copy)]
+enum PdfFont {
+    Regular,
+    Bold,
+    Italic,
+}
+
+impl PdfFont {
+    fn resource(self) -> &'static str {
+        match self {
+            Self::Regular => "/F1",
+            Self::Bold => "/F2",
+            Self::Italic => "/F3",
+        }
+    }
+}
+
+struct PdfLine {
+    font: PdfFont,
+    text: String,
+}
+
+impl PdfLine {
+    fn new(font: PdfFont, text: String) -> Self {
+        Self { font, text }
+    }
+}
+
+/// Write a text-only PDF using the standard Helvetica fonts, so no fonts
+/// need to be embedded.
+fn write_pdf(lines: &[PdfLine]) -> Vec<u8> {
+    let per_page = ((PDF_PAGE_HEIGHT - 2 * PDF_MARGIN) / PDF_LEADING) as usize;
+    let pages: Vec<&[PdfLine]> = if lines.is_empty() {
+        vec![lines]
+    } else {
+        lines.chunks(per_page).collect()
+    };
+
+    // 1: catalog, 2: page tree, 3-5: fonts, then a page and its content
+    // stream for each page
+    let page_ids: Vec<usize> = (0..pages.len()).map(|i| 6 + 2 * i).collect();
+    let kids: Vec<String> = page_ids.iter().map(|id| format!("{id} 0 R")).collect();
+    let mut objects: Vec<Vec<u8>> = vec![
+        b"<< /Type /Catalog /Pages 2 0 R >>".to_vec(),
+        format!(
+            "<< /Type /Pages /Kids [{}] /Count {} >>",
+            kids.join(" "),
+            pages.len()
+        )
+        .into_bytes(),
+    ];
+    for base in ["Helvetica", "Helvetica-Bold", "Helvetica-Oblique"] {
+        objects.push(
+            format!(
+                "<< /Type /Font /Subtype /Type1 /BaseFont /{base} /Encoding /WinAnsiEncoding >>"
+            )
+            .into_bytes(),
+        );
+    }
+    for (page, id) in pages.iter().zip(&page_ids) {
+        objects.push(
+            format!(
+                "<< /Type /Page /Parent 2 0 R /MediaBox [0 0 {PDF_PAGE_WIDTH} {PDF_PAGE_HEIGHT}] \
+                 /Resources << /Font << /F1 3 0 R /F2 4 0 R /F3 5 0 R >> >> /Contents {} 0 R >>",
+                id + 1
+            )
+            .into_bytes(),
+        );
+        let content = page_content(page);
+        let mut stream = format!("<< /Length {} >>\nstream\n", content.len()).into_bytes();
+        stream.extend_from_slice(&content);
+        stream.extend_from_slice(b"\nendstream");
+        objects.push(stream);
+    }
+
+    let mut out = b"%PDF-1.4\n%\xE2\xE3\xCF\xD3\n".to_vec();
+    let mut offsets = Vec::with_capacity(objects.len());
+    for (i, object) in objects.iter().enumerate() {
+        offsets.push(out.len());
+        out.extend_from_slice(format!("{} 0 obj\n", i + 1).as_bytes());
+        out.extend_from_slice(object);
+        out.extend_from_slice(b"\nendobj\n");
+    }
+    let xref = out.len();
+    let mut table = format!("xref\n0 {}\n0000000000 65535 f \n", objects.len() + 1);
+    for offset in offsets {
+        let _ = write!(table, "{offset:010} 00000 n \n");
+    }
+    let _ = write!(
+        table,
+        "trailer\n<< /Size {} /Root 1 0 R >>\nstartxref\n{xref}\n%%EOF\n",
+        objects.len() + 1
+    );
+    out.extend_from_slice(table.as_bytes());
+    out
+}
+
+fn page_content(lines: &[PdfLine]) -> Vec<u8> {
+    let top = PDF_PAGE_HEIGHT - PDF_MARGIN;
+    let mut out = format!("BT\n{PDF_LEADING} TL\n{PDF_MARGIN} {top} Td\n").into_bytes();
+    for line in lines {
+        out.extend_from_slice(format!("{} {PDF_FONT_SIZE} Tf (", line.font.resource()).as_bytes());
+        out.extend_from_slice(&pdf_string(&line.text));
+        out.extend_from_slice(b") Tj T*\n");
+    }
+    out.extend_from_slice(b"ET");
+    out
+}
+
+/// Encode text for a PDF string in WinAnsiEncoding, escaping delimiters.
+/// Characters the encoding lacks become `?`.
+fn pdf_string(text: &str) -> Vec<u8> {
+    let mut out = Vec::with_capacity(text.len());
+    for c in text.chars() {
+        let byte = match c {
+            '(' | ')' | '\\' => {
+                out.push(b'\\');
+                c as u8
+            }
+            ' '..='~' | '\u{a0}'..='\u{ff}' => c as u32 as u8,
+            '€' => 0x80,
+            '…' => 0x85,
+            '‘' => 0x91,
+            '’' => 0x92,
+            '“' => 0x93,
+            '”' => 0x94,
+            '•' => 0x95,
+            '–' => 0x96,
+            '—' => 0x97,
+            _ => b'?',
+        };
+        out.push(byte);
+    }
+    out
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::llm::{FunctionCall, ToolCall};
+
+    fn transcript() -> Transcript {
+        let call = ToolCall {
+            id: "call_1".to_string(),
+            tool_type: "function".to_string(),
+            function: FunctionCall {
+                name: "bash".to_string(),
+                arguments: r#"{"command":"ls"}"#.to_string(),
+            },
+        };
+        let messages = vec![
+            Message::text(Role::System, "You are helpful."),
+            Message::text(Role::User, "What's in <here>?"),
+            Message::assistant_tool_calls(vec![call]),
+            Message::tool_result("call_1", "notes.txt"),
+            Message::text(Role::Assistant, "One file: notes.txt"),
+        ];
+        Transcript::new("session_1", "helper", Utc::now(), &messages)
+    }
+
+    #[test]
+    fn collapses_tool_calls_with_their_results() {
+        let transcript = transcript();
+        assert_eq!(transcript.entries.len(), 3);
+        let Entry::ToolCalls(uses) = &transcript.entries[1] else {
+            panic!("expected tool calls");
+        };
+        assert_eq!(uses[0].result.as_deref(), Some("notes.txt"));
+
+        let markdown = transcript.to_markdown();
+        assert!(markdown.starts_with("# Session session_1\n"));
+        assert!(markdown.contains("<summary>Tool call: bash</summary>"));
+        assert!(markdown.contains("```json\n{\"command\":\"ls\"}\n```"));
+        assert!(!markdown.contains("You are helpful."));
+
+        let html = transcript.to_html();
+        assert!(html.contains("What's in &lt;here&gt;?"));
+        assert!(html.contains("<details>\n<summary>Tool call: bash</summary>"));
+    }
+
+    #[test]
+    fn writes_a_well_formed_pdf() {
+        let pdf = transcript().to_pdf();
+        assert!(pdf.starts_with(b"%PDF-1.4\n"));
+        assert!(pdf.ends_with(b"%%EOF\n"));
+
+        let text = String::from_utf8_lossy(&pdf);
+        assert!(text.contains("(What's in <here>?) Tj"));
+        assert!(text.contains("([Tool call: bash]) Tj"));
+
+        // The xref table points at each object
+        let start = pdf.windows(10).rposition(|w| w == b"startxref\n").unwrap() + 10;
+        let xref: usize = std::str::from_utf8(&pdf[start..])
+            .unwrap()
+            .lines()
+            .next()
+            .unwrap()
+            .parse()
+            .unwrap();
+        let table = std::str::from_utf8(&pdf[xref..]).unwrap();
+        assert!(table.starts_with("xref\n"));
+        let first = table.lines().nth(3).unwrap();
+        let offset: usize = first[..10].parse().unwrap();
+        assert!(pdf[offset..].starts_with(b"1 0 obj\n"));
+    }
+
+    #[test]
+    fn wraps_long_lines_and_escapes_pdf_strings() {
+        assert_eq!(wrap("aa bb cc", 5), ["aa bb", "cc"]);
+        assert_eq!(wrap("abcdefg", 3), ["abc", "def", "g"]);
+        assert_eq!(wrap("a\n\nb", 10), ["a", "", "b"]);
+        assert_eq!(pdf_string("(a\\b) “ok” 日"), b"\\(a\\\\b\\) \x93ok\x94 ?");
+    }
+
+    #[test]
+    fn fences_outlast_backticks_in_the_text() {
+        assert_eq!(fenced("", "a ```b```"), "````\na ```b```\n````\n");
+        assert_eq!(ExportFormat::parse("md"), Some(ExportFormat::Markdown));
+        assert_eq!(ExportFormat::parse("docx"), None);
+    }
+}
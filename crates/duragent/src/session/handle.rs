@@ -5,14 +5,17 @@
 
 use std::time::Duration;
 
-use tokio::sync::{mpsc, oneshot};
+use tokio::sync::{broadcast, mpsc, oneshot};
 
 use crate::api::SessionStatus;
+use crate::best_of::SampleSelection;
+use crate::context::ContextRecovery;
+use crate::ensemble::Consensus;
 use crate::llm::{Message, Usage};
 use crate::session::EventToolCall;
 
 use super::actor_types::{ActorError, SessionCommand, SessionMetadata, SilentMessageEntry};
-use super::{ApprovalDecisionType, PendingApproval};
+use super::{ApprovalDecisionType, PendingApproval, PlanStep, SessionEvent};
 
 /// Defensive timeout for actor request-reply (30 seconds).
 const ACTOR_REPLY_TIMEOUT: Duration = Duration::from_secs(30);
@@ -412,6 +415,163 @@ impl SessionHandle {
         Ok(seq)
     }
 
+    /// Record reasoning the model returned apart from its answer.
+    ///
+    /// Kept in the event log for the run trace; never part of the
+    /// conversation. Returns the event sequence number on success.
+    pub async fn record_reasoning(&self, content: String) -> Result<u64, ActorError> {
+        let (reply_tx, reply_rx) = oneshot::channel();
+        self.tx
+            .send(SessionCommand::RecordReasoning {
+                content,
+                reply: reply_tx,
+            })
+            .await
+            .map_err(|_| ActorError::ActorShutdown)?;
+
+        self.await_reply(reply_rx).await?
+    }
+
+    /// Record that a request rejected as too long for the model's context
+    /// was shortened and retried.
+    ///
+    /// Kept in the event log for the run trace. Returns the event sequence
+    /// number on success.
+    pub async fn record_context_recovery(
+        &self,
+        recovery: ContextRecovery,
+    ) -> Result<u64, ActorError> {
+        let (reply_tx, reply_rx) = oneshot::channel();
+        self.tx
+            .send(SessionCommand::RecordContextRecovery {
+                recovery,
+                reply: reply_tx,
+            })
+            .await
+            .map_err(|_| ActorError::ActorShutdown)?;
+
+        self.await_reply(reply_rx).await?
+    }
+
+    /// Record the candidates of a best-of sampled model turn and the one
+    /// selected.
+    ///
+    /// Kept in the event log for the run trace. Returns the event sequence
+    /// number on success.
+    pub async fn record_samples(&self, selection: SampleSelection) -> Result<u64, ActorError> {
+        let (reply_tx, reply_rx) = oneshot::channel();
+        self.tx
+            .send(SessionCommand::RecordSamples {
+                selection,
+                reply: reply_tx,
+            })
+            .await
+            .map_err(|_| ActorError::ActorShutdown)?;
+
+        self.await_reply(reply_rx).await?
+    }
+
+    /// Record an ensemble's member answers and the consensus drawn from them.
+    ///
+    /// Kept in the event log for the run trace. Returns the event sequence
+    /// number on success.
+    pub async fn record_consensus(&self, consensus: Consensus) -> Result<u64, ActorError> {
+        let (reply_tx, reply_rx) = oneshot::channel();
+        self.tx
+            .send(SessionCommand::RecordConsensus {
+                consensus,
+                reply: reply_tx,
+            })
+            .await
+            .map_err(|_| ActorError::ActorShutdown)?;
+
+        self.await_reply(reply_rx).await?
+    }
+
+    /// Record the plan produced by a run's planning step.
+    ///
+    /// Kept in the event log for the run trace. Returns the event sequence
+    /// number on success.
+    pub async fn record_plan(
+        &self,
+        call_id: String,
+        steps: Vec<PlanStep>,
+        requires_approval: bool,
+    ) -> Result<u64, ActorError> {
+        let (reply_tx, reply_rx) = oneshot::channel();
+        self.tx
+            .send(SessionCommand::RecordPlan {
+                call_id,
+                steps,
+                requires_approval,
+                reply: reply_tx,
+            })
+            .await
+            .map_err(|_| ActorError::ActorShutdown)?;
+
+        self.await_reply(reply_rx).await?
+    }
+
+    /// Record the model's account of a finished plan step.
+    ///
+    /// Kept in the event log for the run trace. Returns the event sequence
+    /// number on success.
+    pub async fn record_plan_step(
+        &self,
+        call_id: String,
+        step: usize,
+        content: String,
+    ) -> Result<u64, ActorError> {
+        let (reply_tx, reply_rx) = oneshot::channel();
+        self.tx
+            .send(SessionCommand::RecordPlanStep {
+                call_id,
+                step,
+                content,
+                reply: reply_tx,
+            })
+            .await
+            .map_err(|_| ActorError::ActorShutdown)?;
+
+        self.await_reply(reply_rx).await?
+    }
+
+    /// Record the language detected for the latest user message and the
+    /// language the agent was told to reply in.
+    ///
+    /// Returns the event sequence number on success.
+    pub async fn record_language(
+        &self,
+        language: Option<String>,
+        reply_language: Option<String>,
+    ) -> Result<u64, ActorError> {
+        let (reply_tx, reply_rx) = oneshot::channel();
+        self.tx
+            .send(SessionCommand::RecordLanguage {
+                language,
+                reply_language,
+                reply: reply_tx,
+            })
+            .await
+            .map_err(|_| ActorError::ActorShutdown)?;
+
+        self.await_reply(reply_rx).await?
+    }
+
+    /// Checkpoint streamed assistant content (cumulative) for crash recovery.
+    ///
+    /// Non-blocking: the checkpoint is skipped if the actor's queue is full.
+    /// Because it enqueues synchronously, it is ordered before any command
+    /// sent afterwards (e.g. `finalize_stream`). Returns whether it was queued.
+    pub fn checkpoint_partial(&self, message_id: &str, content: &str) -> bool {
+        self.tx
+            .try_send(SessionCommand::RecordPartial {
+                message_id: message_id.to_string(),
+                content: content.to_string(),
+            })
+            .is_ok()
+    }
+
     // ------------------------------------------------------------------------
     // Read Operations
     // ------------------------------------------------------------------------
@@ -471,6 +631,20 @@ impl SessionHandle {
         self.await_reply(reply_rx).await?
     }
 
+    /// Subscribe to events as they are flushed to the session log.
+    ///
+    /// Events recorded before the call are not replayed; read history with
+    /// the event store instead.
+    pub async fn subscribe_events(&self) -> Result<broadcast::Receiver<SessionEvent>, ActorError> {
+        let (reply_tx, reply_rx) = oneshot::channel();
+        self.tx
+            .send(SessionCommand::Subscribe { reply: reply_tx })
+            .await
+            .map_err(|_| ActorError::ActorShutdown)?;
+
+        self.await_reply(reply_rx).await?
+    }
+
     // ------------------------------------------------------------------------
     // Stream/Flush Operations
     // ------------------------------------------------------------------------
@@ -563,6 +737,9 @@ mod tests {
             silent_buffer_cap: DEFAULT_SILENT_BUFFER_CAP,
             actor_message_limit: DEFAULT_ACTOR_MESSAGE_LIMIT,
             compaction_mode: crate::config::CompactionMode::Disabled,
+            event_batch: crate::config::EventBatchConfig::default(),
+            usage: None,
+            origin: None,
         };
         let (tx, task_handle) = SessionActor::spawn(config, shutdown_rx);
         let handle = SessionHandle::new(tx, "session_test".to_string(), "test-agent".to_string());
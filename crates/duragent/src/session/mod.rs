@@ -35,26 +35,36 @@ pub use duragent_types::session::*;
 mod actor;
 mod actor_types;
 mod agentic_loop;
+mod archive;
 mod chat_session_cache;
 mod events_eval;
+pub mod export;
 mod handle;
+mod planning;
 mod registry;
+mod run_pool;
+mod run_stats;
 mod snapshot_eval;
 mod sse_stream;
+pub mod stream_buffer;
 
 // Types and errors
 pub use actor_types::{
     ActorError, DEFAULT_ACTOR_MESSAGE_LIMIT, DEFAULT_SILENT_BUFFER_CAP, SessionMetadata,
     SilentMessageEntry, actor_message_limit,
 };
+pub use archive::{SessionArchiver, StoredSession, spawn_archive_job};
 pub use chat_session_cache::ChatSessionCache;
 pub use events_eval::{PendingApprovalEval, SessionEventEval};
 pub use handle::SessionHandle;
 pub use registry::{CreateSessionOpts, RecoveryResult, SessionRegistry};
+pub use run_pool::{RunPermit, RunPool, RunPriority};
+pub use run_stats::RunStats;
 pub use snapshot_eval::SessionSnapshotEval;
 
 // Streaming
-pub use sse_stream::{AccumulatingStream, StreamConfig};
+pub use sse_stream::{AccumulatingStream, CLIENT_DISCONNECTED, StreamConfig};
+pub use stream_buffer::{StreamBuffer, StreamBuffers};
 
 // Agentic loop
 pub use agentic_loop::{
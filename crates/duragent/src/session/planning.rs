@@ -0,0 +1,346 @@
+//! Planner-executor runs.
+//!
+//! For agents with `planning`, a run starts with a planning step: the model
+//! is offered only the `plan` tool and asked to record a plan with it. Once
+//! the plan is accepted — right away, or when approved if `require_approval`
+//! is set — the run works through it one step at a time. Each step is
+//! announced to the model, which is offered only the tools the step names,
+//! and ends when the model replies without calling tools. A plan that is
+//! invalid or denied sends the run back to planning.
+//!
+//! A run's progress is read back from its messages, so a run paused for
+//! approval picks up where it left off.
+
+use std::collections::HashSet;
+
+use crate::agent::PlanningConfig;
+use crate::llm::{Message, Role, ToolCall, ToolDefinition};
+use crate::session::PlanStep;
+use crate::tools::{PLAN_ACCEPTED, PLAN_TOOL, ToolExecutor, parse_plan};
+
+/// Prefix of the messages announcing each plan step.
+const STEP_PREFIX: &str = "[plan] Step ";
+
+/// Where a planned run is.
+#[derive(Debug)]
+enum PlanState {
+    /// Waiting for the model to record a plan.
+    Planning { prompted: bool },
+    /// Carrying out `steps[step]` of the plan recorded by `call_id`.
+    Executing {
+        call_id: String,
+        steps: Vec<PlanStep>,
+        step: usize,
+        announced: bool,
+    },
+}
+
+/// A plan step the model finished.
+#[derive(Debug, PartialEq, Eq)]
+pub(super) struct FinishedStep {
+    pub call_id: String,
+    pub step: usize,
+    /// Whether it was the plan's last step.
+    pub last: bool,
+}
+
+/// A planned run's progress.
+#[derive(Debug)]
+pub(super) struct PlanRun {
+    config: PlanningConfig,
+    state: PlanState,
+}
+
+impl PlanRun {
+    /// The progress of a run starting or resuming with `messages`.
+    ///
+    /// A run is planned when it starts on a user message, or when a plan was
+    /// recorded since the latest one; otherwise it runs as usual.
+    pub fn resume(config: &PlanningConfig, messages: &[Message]) -> Option<Self> {
+        let start = messages.iter().rposition(|m| m.role == Role::User)?;
+        let run = &messages[start + 1..];
+        let latest_plan = run.iter().enumerate().rev().find_map(|(i, m)| {
+            let call = m
+                .tool_calls
+                .as_ref()?
+                .iter()
+                .find(|c| c.function.name == PLAN_TOOL)?;
+            Some((i, call))
+        });
+
+        let state = match latest_plan {
+            None if run.is_empty() => PlanState::Planning { prompted: false },
+            None => return None,
+            Some((i, call)) => {
+                let accepted = run[i + 1..].iter().any(|m| {
+                    m.role == Role::Tool
+                        && m.tool_call_id.as_deref() == Some(call.id.as_str())
+                        && m.content.as_deref() == Some(PLAN_ACCEPTED)
+                });
+                if accepted {
+                    let steps = parse_plan(&call.function.arguments, config.max_steps).ok()?;
+                    let announced = run[i + 1..].iter().filter(|m| is_step_message(m)).count();
+                    PlanState::Executing {
+                        call_id: call.id.clone(),
+                        steps,
+                        step: announced.saturating_sub(1),
+                        announced: announced > 0,
+                    }
+                } else {
+                    PlanState::Planning { prompted: true }
+                }
+            }
+        };
+        Some(Self {
+            config: config.clone(),
+            state,
+        })
+    }
+
+    pub fn require_approval(&self) -> bool {
+        self.config.require_approval
+    }
+
+    /// The tools offered for the next model turn: only `plan` while
+    /// planning, then the current step's tools.
+    pub fn tool_definitions(
+        &self,
+        executor: &ToolExecutor,
+        tool_filter: Option<&HashSet<String>>,
+    ) -> Vec<ToolDefinition> {
+        match &self.state {
+            PlanState::Planning { .. } => {
+                executor.tool_definitions(Some(&HashSet::from([PLAN_TOOL.to_string()])))
+            }
+            PlanState::Executing { steps, step, .. } => {
+                let tools = &steps[*step].tools;
+                executor
+                    .tool_definitions(tool_filter)
+                    .into_iter()
+                    .filter(|d| {
+                        d.function.name != PLAN_TOOL
+                            && (tools.is_empty() || tools.contains(&d.function.name))
+                    })
+                    .collect()
+            }
+        }
+    }
+
+    /// The message telling the model what to do next, once per phase: the
+    /// planning prompt, then each step as it starts.
+    pub fn announcement(&mut self) -> Option<Message> {
+        match &mut self.state {
+            PlanState::Planning { prompted } if !*prompted => {
+                *prompted = true;
+                Some(Message::steering(planning_prompt(&self.config)))
+            }
+            PlanState::Executing {
+                steps,
+                step,
+                announced,
+                ..
+            } if !*announced => {
+                *announced = true;
+                Some(Message::steering(step_message(steps, *step)))
+            }
+            _ => None,
+        }
+    }
+
+    /// The plan in a `plan` tool call, or why it can't be accepted.
+    pub fn propose(&self, tool_call: &ToolCall) -> Result<Vec<PlanStep>, String> {
+        match self.state {
+            PlanState::Planning { .. } => {
+                parse_plan(&tool_call.function.arguments, self.config.max_steps)
+            }
+            PlanState::Executing { .. } => Err(
+                "A plan is already being carried out; continue with the current step.".to_string(),
+            ),
+        }
+    }
+
+    /// Start carrying out an accepted plan.
+    pub fn start(&mut self, call_id: String, steps: Vec<PlanStep>) {
+        self.state = PlanState::Executing {
+            call_id,
+            steps,
+            step: 0,
+            announced: false,
+        };
+    }
+
+    /// Finish the current step, moving on to the next one. `None` while
+    /// planning.
+    pub fn finish_step(&mut self) -> Option<FinishedStep> {
+        let PlanState::Executing {
+            call_id,
+            steps,
+            step,
+            announced,
+        } = &mut self.state
+        else {
+            return None;
+        };
+        let finished = FinishedStep {
+            call_id: call_id.clone(),
+            step: *step,
+            last: *step + 1 == steps.len(),
+        };
+        if !finished.last {
+            *step += 1;
+            *announced = false;
+        }
+        Some(finished)
+    }
+}
+
+/// The plan as text, for approval prompts.
+pub(super) fn render_plan(steps: &[PlanStep]) -> String {
+    steps
+        .iter()
+        .enumerate()
+        .map(|(i, step)| {
+            if step.tools.is_empty() {
+                format!("{}. {}", i + 1, step.description)
+            } else {
+                format!(
+                    "{}. {} [{}]",
+                    i + 1,
+                    step.description,
+                    step.tools.join(", ")
+                )
+            }
+        })
+        .collect::<Vec<_>>()
+        .join("\n")
+}
+
+fn planning_prompt(config: &PlanningConfig) -> String {
+    let mut prompt = format!(
+        "[plan] Before doing anything, call the `{PLAN_TOOL}` tool with a plan for this \
+         request: at most {} steps, each naming the tools it needs. End with a step that \
+         answers the user. If the request needs no work, answer it directly instead.",
+        config.max_steps
+    );
+    if let Some(instructions) = &config.instructions {
+        prompt.push(' ');
+        prompt.push_str(instructions);
+    }
+    prompt
+}
+
+fn step_message(steps: &[PlanStep], step: usize) -> String {
+    format!(
+        "{STEP_PREFIX}{} of {}: {}\nDo only this step, then reply with what you did.",
+        step + 1,
+        steps.len(),
+        steps[step].description
+    )
+}
+
+fn is_step_message(message: &Message) -> bool {
+    message.role == Role::Steering
+        && message
+            .content
+            .as_deref()
+            .is_some_and(|c| c.starts_with(STEP_PREFIX))
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::llm::FunctionCall;
+
+    fn config() -> PlanningConfig {
+        PlanningConfig {
+            max_steps: 4,
+            require_approval: false,
+            instructions: None,
+        }
+    }
+
+    fn plan_call() -> ToolCall {
+        ToolCall {
+            id: "call_plan".to_string(),
+            tool_type: "function".to_string(),
+            function: FunctionCall {
+                name: PLAN_TOOL.to_string(),
+                arguments: r#"{"steps": [{"description": "look", "tools": ["bash"]}, {"description": "answer"}]}"#
+                    .to_string(),
+            },
+        }
+    }
+
+    #[test]
+    fn fresh_runs_start_by_planning() {
+        let messages = vec![Message::text(Role::User, "tidy the repo")];
+        let mut run = PlanRun::resume(&config(), &messages).unwrap();
+        assert!(matches!(run.state, PlanState::Planning { .. }));
+        let prompt = run.announcement().unwrap();
+        assert_eq!(prompt.role, Role::Steering);
+        assert!(run.announcement().is_none());
+
+        // Mid-run without a plan: run as usual
+        let messages = vec![
+            Message::text(Role::User, "tidy the repo"),
+            Message::text(Role::Assistant, "done"),
+        ];
+        assert!(PlanRun::resume(&config(), &messages).is_none());
+    }
+
+    #[test]
+    fn steps_advance_until_the_last() {
+        let messages = vec![Message::text(Role::User, "tidy the repo")];
+        let mut run = PlanRun::resume(&config(), &messages).unwrap();
+        let steps = run.propose(&plan_call()).unwrap();
+        run.start("call_plan".to_string(), steps);
+        assert!(run.propose(&plan_call()).is_err());
+
+        let announcement = run.announcement().unwrap();
+        assert!(is_step_message(&announcement));
+        let finished = run.finish_step().unwrap();
+        assert_eq!(finished.step, 0);
+        assert!(!finished.last);
+        assert!(run.announcement().is_some());
+        assert!(run.finish_step().unwrap().last);
+    }
+
+    #[test]
+    fn resumes_from_messages() {
+        let mut messages = vec![
+            Message::text(Role::User, "tidy the repo"),
+            Message::assistant_tool_calls(vec![plan_call()]),
+            Message::tool_result("call_plan", PLAN_ACCEPTED),
+        ];
+
+        // Approved, not yet announced
+        let mut run = PlanRun::resume(&config(), &messages).unwrap();
+        assert!(matches!(run.state, PlanState::Executing { .. }));
+        assert!(run.announcement().is_some());
+
+        // Second step announced
+        let steps = parse_plan(&plan_call().function.arguments, 4).unwrap();
+        messages.push(Message::steering(step_message(&steps, 0)));
+        messages.push(Message::text(Role::Assistant, "looked"));
+        messages.push(Message::steering(step_message(&steps, 1)));
+        let mut run = PlanRun::resume(&config(), &messages).unwrap();
+        assert!(run.announcement().is_none());
+        assert!(run.finish_step().unwrap().last);
+
+        // Denied: plan again
+        let messages = vec![
+            Message::text(Role::User, "tidy the repo"),
+            Message::assistant_tool_calls(vec![plan_call()]),
+            Message::tool_result("call_plan", "denied by the user"),
+        ];
+        let mut run = PlanRun::resume(&config(), &messages).unwrap();
+        assert!(matches!(run.state, PlanState::Planning { .. }));
+        assert!(run.announcement().is_none());
+    }
+
+    #[test]
+    fn render_plan_lists_steps() {
+        let steps = parse_plan(&plan_call().function.arguments, 4).unwrap();
+        assert_eq!(render_plan(&steps), "1. look [bash]\n2. answer");
+    }
+}
@@ -15,10 +15,11 @@ use tokio::task::JoinHandle;
 use tracing::{debug, info, warn};
 use ulid::Ulid;
 
-use crate::agent::OnDisconnect;
+use crate::agent::{OnDisconnect, RunOrigin};
 use crate::api::{SESSION_ID_PREFIX, SessionStatus};
-use crate::config::CompactionMode;
+use crate::config::{CompactionMode, EventBatchConfig};
 use crate::store::SessionStore;
+use crate::usage::UsageRollups;
 
 use super::SessionSnapshotEval;
 use super::actor::SessionActor;
@@ -47,6 +48,10 @@ pub struct SessionRegistry {
     store: Arc<dyn SessionStore>,
     /// Event log compaction mode.
     compaction_mode: CompactionMode,
+    /// Event log write batching.
+    event_batch: EventBatchConfig,
+    /// Token usage rollups, if enabled.
+    usage: Option<UsageRollups>,
     /// Shutdown signal sender.
     shutdown_tx: Arc<watch::Sender<bool>>,
     /// Shutdown signal receiver (cloned for each actor).
@@ -61,6 +66,8 @@ pub struct CreateSessionOpts {
     pub silent_buffer_cap: usize,
     pub actor_message_limit: usize,
     pub compaction_override: Option<CompactionMode>,
+    /// Who started the session, recorded for its runs.
+    pub origin: Option<RunOrigin>,
 }
 
 /// Result of session recovery on startup.
@@ -99,11 +106,27 @@ impl SessionRegistry {
             task_handles: Arc::new(Mutex::new(Vec::new())),
             store,
             compaction_mode,
+            event_batch: EventBatchConfig::default(),
+            usage: None,
             shutdown_tx: Arc::new(shutdown_tx),
             shutdown_rx,
         }
     }
 
+    /// Set how session events are batched into event log writes.
+    #[must_use]
+    pub fn with_event_batch(mut self, event_batch: EventBatchConfig) -> Self {
+        self.event_batch = event_batch;
+        self
+    }
+
+    /// Count token usage from every session into `usage`.
+    #[must_use]
+    pub fn with_usage(mut self, usage: UsageRollups) -> Self {
+        self.usage = Some(usage);
+        self
+    }
+
     /// Gracefully shutdown all session actors.
     ///
     /// Sends shutdown signal and waits for all actors to complete.
@@ -158,6 +181,9 @@ impl SessionRegistry {
             silent_buffer_cap: opts.silent_buffer_cap,
             actor_message_limit: opts.actor_message_limit,
             compaction_mode: opts.compaction_override.unwrap_or(self.compaction_mode),
+            event_batch: self.event_batch,
+            usage: self.usage.clone(),
+            origin: opts.origin,
         };
 
         let (tx, task_handle) = SessionActor::spawn(config, self.shutdown_rx.clone());
@@ -335,11 +361,36 @@ impl SessionRegistry {
         let mut last_seq = snapshot.last_event_seq;
         let mut status = snapshot.status;
         let mut outstanding_call_ids: Vec<String> = Vec::new();
+        // Latest streamed-content checkpoint not yet superseded by a final message.
+        let mut partial: Option<String> = None;
 
         for event in events {
             last_seq = event.seq;
 
+            if let Some(content) = partial.take() {
+                match &event.payload {
+                    // Superseded by a newer checkpoint or the final message
+                    super::events::SessionEventPayload::AssistantPartial { .. }
+                    | super::events::SessionEventPayload::AssistantMessage { .. }
+                    | super::events::SessionEventPayload::AssistantResponse { .. } => {}
+                    // Conversation moved on without a final message: keep the partial
+                    super::events::SessionEventPayload::UserMessage { .. }
+                    | super::events::SessionEventPayload::ToolCall { .. }
+                    | super::events::SessionEventPayload::ToolResult { .. }
+                    | super::events::SessionEventPayload::ToolsSkipped { .. } => {
+                        pending_messages.push(crate::llm::Message::text(
+                            crate::llm::Role::Assistant,
+                            &content,
+                        ));
+                    }
+                    _ => partial = Some(content),
+                }
+            }
+
             match &event.payload {
+                super::events::SessionEventPayload::AssistantPartial { content, .. } => {
+                    partial = Some(content.clone());
+                }
                 super::events::SessionEventPayload::UserMessage { content, .. } => {
                     pending_messages
                         .push(crate::llm::Message::text(crate::llm::Role::User, content));
@@ -420,6 +471,19 @@ impl SessionRegistry {
             }
         }
 
+        // Crash recovery: keep content streamed before the server died mid-response.
+        if let Some(content) = partial {
+            debug!(
+                session_id = %session_id,
+                content_len = content.len(),
+                "Recovering partial assistant output from stream checkpoint"
+            );
+            pending_messages.push(crate::llm::Message::text(
+                crate::llm::Role::Assistant,
+                &content,
+            ));
+        }
+
         // Crash recovery: synthesize error results for tool calls that never
         // got a ToolResult/ToolsSkipped event (server crashed mid-iteration).
         if let Some(pending) = &snapshot.config.pending_approval {
@@ -503,6 +567,8 @@ impl SessionRegistry {
             silent_buffer_cap: recover_silent_buffer_cap,
             actor_message_limit: recover_actor_message_limit,
             compaction_mode: self.compaction_mode,
+            event_batch: self.event_batch,
+            usage: self.usage.clone(),
         };
 
         let (tx, task_handle) = SessionActor::spawn_recovered(config, self.shutdown_rx.clone());
@@ -606,7 +672,9 @@ impl SessionRegistry {
 mod tests {
     use super::*;
     use crate::llm::{Message, Role};
-    use crate::session::{CheckpointState, SessionConfig, SessionSnapshot};
+    use crate::session::{
+        CheckpointState, SessionConfig, SessionEvent, SessionEventPayload, SessionSnapshot,
+    };
     use crate::store::file::FileSessionStore;
     use chrono::Utc;
     use tempfile::TempDir;
@@ -659,6 +727,7 @@ mod tests {
                     silent_buffer_cap: DEFAULT_SILENT_BUFFER_CAP,
                     actor_message_limit: DEFAULT_ACTOR_MESSAGE_LIMIT,
                     compaction_override: None,
+                    origin: None,
                 },
             )
             .await
@@ -698,6 +767,7 @@ mod tests {
                     silent_buffer_cap: DEFAULT_SILENT_BUFFER_CAP,
                     actor_message_limit: DEFAULT_ACTOR_MESSAGE_LIMIT,
                     compaction_override: None,
+                    origin: None,
                 },
             )
             .await
@@ -712,6 +782,7 @@ mod tests {
                     silent_buffer_cap: DEFAULT_SILENT_BUFFER_CAP,
                     actor_message_limit: DEFAULT_ACTOR_MESSAGE_LIMIT,
                     compaction_override: None,
+                    origin: None,
                 },
             )
             .await
@@ -741,6 +812,7 @@ mod tests {
                     silent_buffer_cap: DEFAULT_SILENT_BUFFER_CAP,
                     actor_message_limit: DEFAULT_ACTOR_MESSAGE_LIMIT,
                     compaction_override: None,
+                    origin: None,
                 },
             )
             .await
@@ -767,6 +839,7 @@ mod tests {
                     silent_buffer_cap: DEFAULT_SILENT_BUFFER_CAP,
                     actor_message_limit: DEFAULT_ACTOR_MESSAGE_LIMIT,
                     compaction_override: None,
+                    origin: None,
                 },
             )
             .await
@@ -793,6 +866,7 @@ mod tests {
                     silent_buffer_cap: DEFAULT_SILENT_BUFFER_CAP,
                     actor_message_limit: DEFAULT_ACTOR_MESSAGE_LIMIT,
                     compaction_override: None,
+                    origin: None,
                 },
             )
             .await
@@ -900,6 +974,97 @@ mod tests {
         registry.shutdown().await;
     }
 
+    fn partial(seq: u64, content: &str) -> SessionEvent {
+        SessionEvent::new(
+            seq,
+            SessionEventPayload::AssistantPartial {
+                message_id: "msg_test".to_string(),
+                content: content.to_string(),
+            },
+        )
+    }
+
+    fn user_message(seq: u64, content: &str) -> SessionEvent {
+        SessionEvent::new(
+            seq,
+            SessionEventPayload::UserMessage {
+                content: content.to_string(),
+                sender_id: None,
+                sender_name: None,
+            },
+        )
+    }
+
+    #[tokio::test]
+    async fn recover_partial_stream_output() {
+        let temp_dir = TempDir::new().unwrap();
+        let (registry, store) = create_test_registry(&temp_dir);
+
+        write_test_snapshot(
+            &store,
+            "session_partial",
+            SessionStatus::Active,
+            OnDisconnect::Pause,
+        )
+        .await;
+        // Server died mid-stream: only checkpoints, no final message
+        let events = vec![
+            user_message(2, "Tell me a story"),
+            partial(3, "Once upon"),
+            partial(4, "Once upon a time"),
+        ];
+        store
+            .append_events("session_partial", &events)
+            .await
+            .unwrap();
+
+        registry.recover().await.unwrap();
+
+        let handle = registry.get("session_partial").unwrap();
+        let messages = handle.get_messages().await.unwrap();
+        assert_eq!(messages.len(), 4);
+        assert_eq!(messages[3].role, Role::Assistant);
+        assert_eq!(messages[3].content_str(), "Once upon a time");
+
+        registry.shutdown().await;
+    }
+
+    #[tokio::test]
+    async fn recover_ignores_superseded_partial_output() {
+        let temp_dir = TempDir::new().unwrap();
+        let (registry, store) = create_test_registry(&temp_dir);
+
+        write_test_snapshot(
+            &store,
+            "session_final",
+            SessionStatus::Active,
+            OnDisconnect::Pause,
+        )
+        .await;
+        let events = vec![
+            user_message(2, "Tell me a story"),
+            partial(3, "Once upon"),
+            SessionEvent::new(
+                4,
+                SessionEventPayload::AssistantMessage {
+                    agent: "test-agent".to_string(),
+                    content: "Once upon a time, the end.".to_string(),
+                    usage: None,
+                },
+            ),
+        ];
+        store.append_events("session_final", &events).await.unwrap();
+
+        registry.recover().await.unwrap();
+
+        let handle = registry.get("session_final").unwrap();
+        let messages = handle.get_messages().await.unwrap();
+        assert_eq!(messages.len(), 4);
+        assert_eq!(messages[3].content_str(), "Once upon a time, the end.");
+
+        registry.shutdown().await;
+    }
+
     #[tokio::test]
     async fn shutdown_flushes_all_sessions() {
         let temp_dir = TempDir::new().unwrap();
@@ -915,6 +1080,7 @@ mod tests {
                     silent_buffer_cap: DEFAULT_SILENT_BUFFER_CAP,
                     actor_message_limit: DEFAULT_ACTOR_MESSAGE_LIMIT,
                     compaction_override: None,
+                    origin: None,
                 },
             )
             .await
@@ -0,0 +1,400 @@
+//! Admission control for LLM runs.
+//!
+//! Every run (HTTP message, gateway message, scheduled task, background
+//! process) acquires a permit from the shared `RunPool` before calling the
+//! provider. When `sessions.max_concurrent_runs` is reached, runs wait in one
+//! queue per priority. Freed permits are handed out by weighted round-robin,
+//! so queued high-priority runs jump ahead of a backlog of batch work without
+//! starving it entirely.
+//!
+//! The pool also tracks recent run durations. When the queue or p95 duration
+//! passes the `sessions.load_shedding` thresholds, `should_shed` tells HTTP
+//! handlers to turn away low-priority work instead of queueing it.
+
+// std::sync::Mutex is correct here—lock is never held across .await points.
+use std::collections::VecDeque;
+use std::sync::{Arc, Mutex};
+use std::time::{Duration, Instant};
+
+use tokio::sync::oneshot;
+
+pub use crate::api::RunPriority;
+use crate::config::LoadSheddingConfig;
+
+/// Permits handed to each priority per round: high, normal, low.
+const PRIORITY_WEIGHTS: [u32; 3] = [4, 2, 1];
+
+/// Most recent run durations kept for the p95 estimate.
+const LATENCY_SAMPLES: usize = 256;
+
+/// Samples older than this are ignored by the p95 estimate.
+const LATENCY_WINDOW: Duration = Duration::from_secs(60);
+
+// ============================================================================
+// Public API
+// ============================================================================
+
+/// Shared pool limiting concurrent runs.
+#[derive(Clone)]
+pub struct RunPool {
+    inner: Arc<Mutex<PoolState>>,
+}
+
+impl RunPool {
+    /// Create a pool admitting at most `max_concurrent` runs (0 = unlimited).
+    #[must_use]
+    pub fn new(max_concurrent: usize) -> Self {
+        Self {
+            inner: Arc::new(Mutex::new(PoolState {
+                limit: max_concurrent,
+                running: 0,
+                queues: Default::default(),
+                credits: PRIORITY_WEIGHTS,
+                latencies: VecDeque::new(),
+                shedding: LoadSheddingConfig::default(),
+            })),
+        }
+    }
+
+    /// Enable load shedding with the given thresholds.
+    #[must_use]
+    pub fn with_load_shedding(self, config: LoadSheddingConfig) -> Self {
+        self.inner.lock().expect("mutex poisoned").shedding = config;
+        self
+    }
+
+    /// Wait for a run slot.
+    ///
+    /// Dropping the returned future while queued gives up the place in line.
+    pub async fn acquire(&self, priority: RunPriority) -> RunPermit {
+        let rx = {
+            let mut state = self.inner.lock().expect("mutex poisoned");
+            let queued = state.queues.iter().any(|q| !q.is_empty());
+            if state.limit == 0 || (state.running < state.limit && !queued) {
+                state.running += 1;
+                return self.permit();
+            }
+            let (tx, rx) = oneshot::channel();
+            state.queues[queue_index(priority)].push_back(tx);
+            rx
+        };
+
+        // The sender is only dropped by `release` after a successful send,
+        // and the pool outlives every waiter.
+        rx.await.expect("run pool dropped a queued waiter")
+    }
+
+    /// Maximum concurrent runs (0 = unlimited).
+    pub fn limit(&self) -> usize {
+        self.inner.lock().expect("mutex poisoned").limit
+    }
+
+    /// Number of runs currently holding a permit.
+    pub fn running(&self) -> usize {
+        self.inner.lock().expect("mutex poisoned").running
+    }
+
+    /// Number of runs waiting for a permit.
+    pub fn queued(&self) -> usize {
+        let state = self.inner.lock().expect("mutex poisoned");
+        state.queues.iter().map(VecDeque::len).sum()
+    }
+
+    /// p95 duration of runs that finished within the last minute.
+    pub fn p95_latency(&self) -> Option<Duration> {
+        self.inner.lock().expect("mutex poisoned").p95_latency()
+    }
+
+    /// Check whether a new run should be rejected instead of queued.
+    ///
+    /// Only low-priority runs are shed. Returns the delay clients should wait
+    /// before retrying.
+    pub fn should_shed(&self, priority: RunPriority) -> Option<Duration> {
+        if priority != RunPriority::Low {
+            return None;
+        }
+        let state = self.inner.lock().expect("mutex poisoned");
+        let config = &state.shedding;
+        let queued: usize = state.queues.iter().map(VecDeque::len).sum();
+        let queue_full = config.max_queued_runs > 0 && queued >= config.max_queued_runs;
+        let too_slow = config.max_p95_latency_ms > 0
+            && state
+                .p95_latency()
+                .is_some_and(|p95| p95 >= Duration::from_millis(config.max_p95_latency_ms));
+        (queue_full || too_slow).then(|| Duration::from_secs(config.retry_after_seconds))
+    }
+
+    fn permit(&self) -> RunPermit {
+        RunPermit {
+            pool: Some(self.clone()),
+            started: Instant::now(),
+        }
+    }
+
+    fn release(&self, elapsed: Duration) {
+        let mut state = self.inner.lock().expect("mutex poisoned");
+        state.running = state.running.saturating_sub(1);
+        state.record_latency(elapsed);
+        while state.limit == 0 || state.running < state.limit {
+            let Some(tx) = state.next_waiter() else {
+                break;
+            };
+            // Count the permit before sending so a receiver dropped right
+            // after the send releases it normally.
+            state.running += 1;
+            if let Err(mut permit) = tx.send(self.permit()) {
+                // Waiter gave up; this permit was never handed out.
+                permit.pool = None;
+                state.running -= 1;
+            }
+        }
+    }
+}
+
+/// A run slot; released when dropped.
+pub struct RunPermit {
+    pool: Option<RunPool>,
+    started: Instant,
+}
+
+impl Drop for RunPermit {
+    fn drop(&mut self) {
+        if let Some(pool) = self.pool.take() {
+            pool.release(self.started.elapsed());
+        }
+    }
+}
+
+// ============================================================================
+// Internal Types
+// ============================================================================
+
+struct PoolState {
+    limit: usize,
+    running: usize,
+    /// Waiters per priority, indexed by `queue_index`.
+    queues: [VecDeque<oneshot::Sender<RunPermit>>; 3],
+    /// Remaining permits each priority may take this round.
+    credits: [u32; 3],
+    /// Finish time and duration of recent runs, oldest first.
+    latencies: VecDeque<(Instant, Duration)>,
+    shedding: LoadSheddingConfig,
+}
+
+impl PoolState {
+    fn record_latency(&mut self, elapsed: Duration) {
+        if self.latencies.len() == LATENCY_SAMPLES {
+            self.latencies.pop_front();
+        }
+        self.latencies.push_back((Instant::now(), elapsed));
+    }
+
+    fn p95_latency(&self) -> Option<Duration> {
+        let mut recent: Vec<Duration> = self
+            .latencies
+            .iter()
+            .filter(|(at, _)| at.elapsed() <= LATENCY_WINDOW)
+            .map(|&(_, elapsed)| elapsed)
+            .collect();
+        if recent.is_empty() {
+            return None;
+        }
+        recent.sort_unstable();
+        Some(recent[(recent.len() * 95).div_ceil(100) - 1])
+    }
+
+    /// Pop the next waiter by weighted round-robin.
+    fn next_waiter(&mut self) -> Option<oneshot::Sender<RunPermit>> {
+        loop {
+            if self.queues.iter().all(VecDeque::is_empty) {
+                return None;
+            }
+            let next =
+                (0..self.queues.len()).find(|&i| self.credits[i] > 0 && !self.queues[i].is_empty());
+            match next {
+                Some(i) => {
+                    self.credits[i] -= 1;
+                    return self.queues[i].pop_front();
+                }
+                // Every waiting priority spent its share; start a new round.
+                None => self.credits = PRIORITY_WEIGHTS,
+            }
+        }
+    }
+}
+
+fn queue_index(priority: RunPriority) -> usize {
+    match priority {
+        RunPriority::High => 0,
+        RunPriority::Normal => 1,
+        RunPriority::Low => 2,
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[tokio::test]
+    async fn unlimited_pool_never_queues() {
+        let pool = RunPool::new(0);
+        let _a = pool.acquire(RunPriority::Low).await;
+        let _b = pool.acquire(RunPriority::Low).await;
+        assert_eq!(pool.running(), 2);
+        assert_eq!(pool.queued(), 0);
+    }
+
+    #[tokio::test]
+    async fn release_admits_waiters_by_weight() {
+        let pool = RunPool::new(1);
+        let held = pool.acquire(RunPriority::Normal).await;
+
+        let (order_tx, mut order_rx) = tokio::sync::mpsc::unbounded_channel();
+        let mut waiters = Vec::new();
+        // Queue low-priority work first, then high-priority work behind it.
+        for (label, priority) in [
+            ("low-1", RunPriority::Low),
+            ("low-2", RunPriority::Low),
+            ("high-1", RunPriority::High),
+            ("high-2", RunPriority::High),
+        ] {
+            let pool = pool.clone();
+            let order_tx = order_tx.clone();
+            waiters.push(tokio::spawn(async move {
+                let _permit = pool.acquire(priority).await;
+                order_tx.send(label).unwrap();
+            }));
+            tokio::task::yield_now().await;
+        }
+        while pool.queued() < 4 {
+            tokio::task::yield_now().await;
+        }
+
+        drop(held);
+        for waiter in waiters {
+            waiter.await.unwrap();
+        }
+        drop(order_tx);
+
+        let mut order = Vec::new();
+        while let Some(label) = order_rx.recv().await {
+            order.push(label);
+        }
+        assert_eq!(order, ["high-1", "high-2", "low-1", "low-2"]);
+        assert_eq!(pool.running(), 0);
+    }
+
+    #[test]
+    fn low_priority_still_progresses() {
+        let pool = RunPool::new(1);
+        let mut state = pool.inner.lock().unwrap();
+        for _ in 0..10 {
+            state.queues[queue_index(RunPriority::High)].push_back(oneshot::channel().0);
+        }
+        state.queues[queue_index(RunPriority::Low)].push_back(oneshot::channel().0);
+
+        // One round is four high permits, then the low waiter gets its turn.
+        for _ in 0..4 {
+            state.next_waiter().unwrap();
+        }
+        state.next_waiter().unwrap();
+        assert!(state.queues[queue_index(RunPriority::Low)].is_empty());
+    }
+
+    #[test]
+    fn p95_ignores_the_slowest_tail() {
+        let pool = RunPool::new(0);
+        {
+            let mut state = pool.inner.lock().unwrap();
+            for ms in 1..=100 {
+                state.record_latency(Duration::from_millis(ms));
+            }
+        }
+        assert_eq!(pool.p95_latency(), Some(Duration::from_millis(95)));
+        assert_eq!(RunPool::new(0).p95_latency(), None);
+    }
+
+    #[tokio::test]
+    async fn sheds_low_priority_when_queue_is_full() {
+        let pool = RunPool::new(1).with_load_shedding(LoadSheddingConfig {
+            max_queued_runs: 1,
+            retry_after_seconds: 7,
+            ..Default::default()
+        });
+        let held = pool.acquire(RunPriority::Normal).await;
+        assert_eq!(pool.should_shed(RunPriority::Low), None);
+
+        let waiter = {
+            let pool = pool.clone();
+            tokio::spawn(async move {
+                let _permit = pool.acquire(RunPriority::Normal).await;
+            })
+        };
+        while pool.queued() < 1 {
+            tokio::task::yield_now().await;
+        }
+
+        assert_eq!(
+            pool.should_shed(RunPriority::Low),
+            Some(Duration::from_secs(7))
+        );
+        assert_eq!(pool.should_shed(RunPriority::Normal), None);
+        assert_eq!(pool.should_shed(RunPriority::High), None);
+
+        drop(held);
+        waiter.await.unwrap();
+        assert_eq!(pool.should_shed(RunPriority::Low), None);
+    }
+
+    #[test]
+    fn sheds_low_priority_when_runs_are_slow() {
+        let pool = RunPool::new(0).with_load_shedding(LoadSheddingConfig {
+            max_p95_latency_ms: 1000,
+            ..Default::default()
+        });
+        pool.inner
+            .lock()
+            .unwrap()
+            .record_latency(Duration::from_millis(500));
+        assert_eq!(pool.should_shed(RunPriority::Low), None);
+
+        pool.inner
+            .lock()
+            .unwrap()
+            .record_latency(Duration::from_secs(5));
+        assert!(pool.should_shed(RunPriority::Low).is_some());
+    }
+
+    #[test]
+    fn shedding_disabled_by_default() {
+        let pool = RunPool::new(0);
+        pool.inner
+            .lock()
+            .unwrap()
+            .record_latency(Duration::from_secs(600));
+        assert_eq!(pool.should_shed(RunPriority::Low), None);
+    }
+
+    #[tokio::test]
+    async fn cancelled_waiter_does_not_leak_permit() {
+        let pool = RunPool::new(1);
+        let held = pool.acquire(RunPriority::Normal).await;
+
+        let waiter = {
+            let pool = pool.clone();
+            tokio::spawn(async move {
+                let _permit = pool.acquire(RunPriority::High).await;
+            })
+        };
+        while pool.queued() < 1 {
+            tokio::task::yield_now().await;
+        }
+        waiter.abort();
+        let _ = waiter.await;
+
+        drop(held);
+        assert_eq!(pool.running(), 0);
+        let _next = pool.acquire(RunPriority::Low).await;
+        assert_eq!(pool.running(), 1);
+    }
+}
@@ -0,0 +1,229 @@
+//! Resource accounting and budgets for agentic runs.
+//!
+//! A `RunMeter` tracks wall time, time spent waiting on the provider, time
+//! spent in tools, and the process's peak resident memory while a run is in
+//! progress. Agents can cap wall time and tool time with
+//! `session.max_wall_time_seconds` and `session.max_tool_time_seconds`; the
+//! loop aborts with `AgenticError::WallTimeExceeded` / `ToolTimeExceeded` once
+//! a budget is spent. Tool calls can be capped per run with
+//! `session.max_tool_calls` and per tool with `session.max_tool_calls_per_tool`;
+//! the call over a limit is not run, and the loop aborts with
+//! `ToolCallsExceeded` / `ToolCallLimitExceeded`.
+
+use std::collections::HashMap;
+use std::time::{Duration, Instant};
+
+use super::AgenticError;
+use crate::agent::AgentSessionConfig;
+
+/// Resource usage of one agentic run.
+#[derive(Debug, Clone, Default)]
+pub struct RunStats {
+    /// Time from loop start to completion or pause.
+    pub wall_time: Duration,
+    /// Time spent in LLM calls, including rate-limit backoff.
+    pub provider_time: Duration,
+    /// Time spent executing tools.
+    pub tool_time: Duration,
+    /// Highest process resident memory observed during the run, in bytes.
+    ///
+    /// `None` on platforms where it cannot be read.
+    pub peak_memory_bytes: Option<u64>,
+}
+
+/// Accumulates `RunStats` and enforces the agent's run budgets.
+pub(crate) struct RunMeter {
+    started: Instant,
+    max_wall_time: Option<Duration>,
+    max_tool_time: Option<Duration>,
+    max_tool_calls: Option<u32>,
+    max_tool_calls_per_tool: HashMap<String, u32>,
+    tool_calls: u32,
+    tool_calls_per_tool: HashMap<String, u32>,
+    stats: RunStats,
+}
+
+impl RunMeter {
+    pub(crate) fn new(config: &AgentSessionConfig) -> Self {
+        let mut meter = Self {
+            started: Instant::now(),
+            max_wall_time: config.max_wall_time_seconds.map(Duration::from_secs),
+            max_tool_time: config.max_tool_time_seconds.map(Duration::from_secs),
+            max_tool_calls: config.max_tool_calls,
+            max_tool_calls_per_tool: config.max_tool_calls_per_tool.clone(),
+            tool_calls: 0,
+            tool_calls_per_tool: HashMap::new(),
+            stats: RunStats::default(),
+        };
+        meter.sample_memory();
+        meter
+    }
+
+    /// Wall time left before the budget is spent, if one is set.
+    pub(crate) fn remaining_wall_time(&self) -> Option<Duration> {
+        self.max_wall_time
+            .map(|max| max.saturating_sub(self.started.elapsed()))
+    }
+
+    pub(crate) fn record_provider(&mut self, elapsed: Duration) {
+        self.stats.provider_time += elapsed;
+        self.sample_memory();
+    }
+
+    pub(crate) fn record_tool(&mut self, elapsed: Duration) {
+        self.stats.tool_time += elapsed;
+        self.sample_memory();
+    }
+
+    /// Count a call of tool `name`, failing instead if it would go over the
+    /// run's tool call limits.
+    pub(crate) fn admit_tool_call(&mut self, name: &str) -> Result<(), AgenticError> {
+        if let Some(max) = self.max_tool_calls
+            && self.tool_calls >= max
+        {
+            return Err(AgenticError::ToolCallsExceeded(max));
+        }
+        let calls = self
+            .tool_calls_per_tool
+            .entry(name.to_string())
+            .or_default();
+        if let Some(&max) = self.max_tool_calls_per_tool.get(name)
+            && *calls >= max
+        {
+            return Err(AgenticError::ToolCallLimitExceeded {
+                tool: name.to_string(),
+                max,
+            });
+        }
+        *calls += 1;
+        self.tool_calls += 1;
+        Ok(())
+    }
+
+    /// Fail if the run has spent its wall time or tool time budget.
+    pub(crate) fn check(&self) -> Result<(), AgenticError> {
+        if let Some(max) = self.max_wall_time
+            && self.started.elapsed() >= max
+        {
+            return Err(AgenticError::WallTimeExceeded(max.as_secs()));
+        }
+        if let Some(max) = self.max_tool_time
+            && self.stats.tool_time >= max
+        {
+            return Err(AgenticError::ToolTimeExceeded(max.as_secs()));
+        }
+        Ok(())
+    }
+
+    /// Snapshot the stats so far.
+    pub(crate) fn stats(&self) -> RunStats {
+        RunStats {
+            wall_time: self.started.elapsed(),
+            ..self.stats.clone()
+        }
+    }
+
+    fn sample_memory(&mut self) {
+        if let Some(rss) = resident_memory_bytes() {
+            let peak = self.stats.peak_memory_bytes.get_or_insert(0);
+            *peak = (*peak).max(rss);
+        }
+    }
+}
+
+/// Current resident set size of this process.
+#[cfg(target_os = "linux")]
+fn resident_memory_bytes() -> Option<u64> {
+    let status = std::fs::read_to_string("/proc/self/status").ok()?;
+    let kb = status
+        .lines()
+        .find_map(|line| line.strip_prefix("VmRSS:"))?
+        .trim()
+        .strip_suffix("kB")?
+        .trim()
+        .parse::<u64>()
+        .ok()?;
+    Some(kb * 1024)
+}
+
+#[cfg(not(target_os = "linux"))]
+fn resident_memory_bytes() -> Option<u64> {
+    None
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn session_config(wall: Option<u64>, tool: Option<u64>) -> AgentSessionConfig {
+        AgentSessionConfig {
+            max_wall_time_seconds: wall,
+            max_tool_time_seconds: tool,
+            ..Default::default()
+        }
+    }
+
+    #[test]
+    fn no_budget_never_fails() {
+        let mut meter = RunMeter::new(&session_config(None, None));
+        meter.record_tool(Duration::from_secs(3600));
+        assert!(meter.check().is_ok());
+        assert!(meter.remaining_wall_time().is_none());
+    }
+
+    #[test]
+    fn tool_budget_is_enforced() {
+        let mut meter = RunMeter::new(&session_config(None, Some(10)));
+        meter.record_tool(Duration::from_secs(4));
+        assert!(meter.check().is_ok());
+        meter.record_tool(Duration::from_secs(6));
+        assert!(matches!(
+            meter.check(),
+            Err(AgenticError::ToolTimeExceeded(10))
+        ));
+    }
+
+    #[test]
+    fn tool_call_limits_are_enforced() {
+        let mut meter = RunMeter::new(&AgentSessionConfig {
+            max_tool_calls: Some(3),
+            max_tool_calls_per_tool: HashMap::from([("bash".to_string(), 1)]),
+            ..Default::default()
+        });
+        assert!(meter.admit_tool_call("bash").is_ok());
+        assert!(matches!(
+            meter.admit_tool_call("bash"),
+            Err(AgenticError::ToolCallLimitExceeded { ref tool, max: 1 }) if tool == "bash"
+        ));
+        assert!(meter.admit_tool_call("web").is_ok());
+        assert!(meter.admit_tool_call("web").is_ok());
+        assert!(matches!(
+            meter.admit_tool_call("web"),
+            Err(AgenticError::ToolCallsExceeded(3))
+        ));
+    }
+
+    #[test]
+    fn zero_wall_budget_is_spent_immediately() {
+        let meter = RunMeter::new(&session_config(Some(0), None));
+        assert_eq!(meter.remaining_wall_time(), Some(Duration::ZERO));
+        assert!(matches!(
+            meter.check(),
+            Err(AgenticError::WallTimeExceeded(0))
+        ));
+    }
+
+    #[test]
+    fn stats_accumulate() {
+        let mut meter = RunMeter::new(&session_config(None, None));
+        meter.record_provider(Duration::from_millis(300));
+        meter.record_provider(Duration::from_millis(200));
+        meter.record_tool(Duration::from_millis(50));
+
+        let stats = meter.stats();
+        assert_eq!(stats.provider_time, Duration::from_millis(500));
+        assert_eq!(stats.tool_time, Duration::from_millis(50));
+        #[cfg(target_os = "linux")]
+        assert!(stats.peak_memory_bytes.is_some_and(|b| b > 0));
+    }
+}
@@ -5,9 +5,13 @@
 //! - Disconnect handling (pause/continue modes)
 //! - Background continuation when client disconnects
 //! - Automatic persistence of messages and snapshots
+//! - Periodic checkpoints of partial output for crash recovery
+//! - Recording events into a replay buffer for `Last-Event-ID` resumption
+//! - Running tokens through the agent's stream processors
 
 use std::convert::Infallible;
-use std::time::Duration;
+use std::sync::Arc;
+use std::time::{Duration, Instant};
 
 use axum::response::sse::Event;
 use serde::Serialize;
@@ -20,13 +24,22 @@ use crate::agent::OnDisconnect;
 use crate::api::{SessionStatus, sse as sse_events};
 use crate::background::BackgroundTasks;
 use crate::llm::{ChatStream, StreamEvent, Usage};
+use crate::stream_processors::{StreamTransform, StreamTransformer};
 
 use super::handle::SessionHandle;
+use super::stream_buffer::StreamBuffer;
 
 // ============================================================================
 // Public API
 // ============================================================================
 
+/// Minimum time between partial-output checkpoints while streaming.
+const PARTIAL_CHECKPOINT_INTERVAL: Duration = Duration::from_secs(1);
+
+/// Error event code recorded when the client disconnects mid-stream and the
+/// provider stream is cancelled (`on_disconnect: pause`).
+pub const CLIENT_DISCONNECTED: &str = "client_disconnected";
+
 /// Configuration for an accumulating SSE stream.
 pub struct StreamConfig {
     /// Session handle for persisting messages.
@@ -43,6 +56,13 @@ pub struct StreamConfig {
     pub on_disconnect: OnDisconnect,
     /// Registry for background tasks.
     pub background_tasks: BackgroundTasks,
+    /// Replay buffer for resumable streams.
+    pub replay: Arc<StreamBuffer>,
+    /// Stream the model's reasoning to this client as `reasoning` events.
+    /// Reasoning is recorded in the event log either way.
+    pub expose_reasoning: bool,
+    /// Stream processors applied to tokens before they are sent or stored.
+    pub transform: StreamTransform,
 }
 
 /// A stream wrapper that accumulates token content and stores the assistant message when done.
@@ -50,7 +70,8 @@ pub struct StreamConfig {
 /// Features:
 /// - Idle timeout via `tokio_stream::StreamExt::timeout()`
 /// - Continue mode: spawns background task to complete LLM when client disconnects
-/// - Pause mode: cancels LLM request and writes snapshot when client disconnects
+/// - Pause mode: cancels LLM request, records a `client_disconnected` error event,
+///   and writes snapshot when client disconnects
 /// - Drop safety: handles partial messages based on on_disconnect mode
 /// - Emits `start` event before streaming, `done` event with message ID when complete
 pub struct AccumulatingStream {
@@ -58,6 +79,8 @@ pub struct AccumulatingStream {
     inner: Option<FlattenedLLMStream>,
     message_id: String,
     accumulated: String,
+    reasoning: String,
+    expose_reasoning: bool,
     last_usage: Option<Usage>,
     handle: SessionHandle,
     session_id: String,
@@ -67,6 +90,11 @@ pub struct AccumulatingStream {
     on_disconnect: OnDisconnect,
     background_tasks: BackgroundTasks,
     disconnect_tx: Option<oneshot::Sender<DisconnectPayload>>,
+    last_checkpoint: Instant,
+    replay: Arc<StreamBuffer>,
+    transform: StreamTransform,
+    /// Event to send on the next poll, after one that had to go first.
+    queued: Option<Event>,
 }
 
 impl AccumulatingStream {
@@ -81,6 +109,9 @@ impl AccumulatingStream {
             cancel_token,
             on_disconnect,
             background_tasks,
+            replay,
+            expose_reasoning,
+            transform,
         } = config;
 
         // Clone the token for the stream wrapper
@@ -109,6 +140,7 @@ impl AccumulatingStream {
             session_id: session_id.clone(),
             message_id: message_id.clone(),
             on_disconnect,
+            replay: replay.clone(),
         };
         let handler_tasks = background_tasks.clone();
         handler_tasks.spawn(async move {
@@ -121,6 +153,8 @@ impl AccumulatingStream {
             inner: Some(Box::pin(flattened)),
             message_id,
             accumulated: String::new(),
+            reasoning: String::new(),
+            expose_reasoning,
             last_usage: None,
             handle,
             session_id,
@@ -130,6 +164,59 @@ impl AccumulatingStream {
             on_disconnect,
             background_tasks,
             disconnect_tx: Some(disconnect_tx),
+            last_checkpoint: Instant::now(),
+            replay,
+            transform,
+            queued: None,
+        }
+    }
+
+    /// Record an event in the replay buffer and build it with its SSE `id`.
+    fn emit(&self, event: &'static str, data: String) -> Event {
+        // `Event::data` copies the payload, so the replay buffer can take ours.
+        let sse = Event::default().event(event).data(&data);
+        let id = self.replay.push(event, data);
+        sse.id(id)
+    }
+
+    /// Emit a terminal event and close the replay buffer.
+    fn emit_final(&mut self, event: &'static str, data: String) -> Event {
+        self.finished = true;
+        self.save_accumulated();
+        let event = self.emit(event, data);
+        self.replay.finish();
+        event
+    }
+
+    /// Accumulate processed token text and emit it.
+    fn emit_token(&mut self, content: String) -> Event {
+        self.accumulated.push_str(&content);
+        self.maybe_checkpoint();
+        self.emit(sse_events::TOKEN, json_data(&TokenData { content }))
+    }
+
+    /// Emit the `done` event, preceded by `token` when there is one.
+    fn emit_done(&mut self, token: Option<Event>, usage: Option<Usage>) -> Event {
+        let data = json_data(&DoneData {
+            message_id: self.message_id.clone(),
+            usage,
+        });
+        let done = self.emit_final(sse_events::DONE, data);
+        match token {
+            Some(token) => {
+                self.queued = Some(done);
+                token
+            }
+            None => done,
+        }
+    }
+
+    /// Checkpoint accumulated content if the checkpoint interval has elapsed.
+    fn maybe_checkpoint(&mut self) {
+        if self.last_checkpoint.elapsed() >= PARTIAL_CHECKPOINT_INTERVAL {
+            self.last_checkpoint = Instant::now();
+            self.handle
+                .checkpoint_partial(&self.message_id, &self.accumulated);
         }
     }
 
@@ -137,6 +224,16 @@ impl AccumulatingStream {
     ///
     /// Uses `finalize_stream` to force a snapshot after stream completion.
     fn save_accumulated(&mut self) {
+        if !self.reasoning.is_empty() {
+            let handle = self.handle.clone();
+            let session_id = self.session_id.clone();
+            let reasoning = std::mem::take(&mut self.reasoning);
+            // Spawned ahead of the answer's task; the log order between the
+            // two doesn't matter since reasoning never replays.
+            self.background_tasks.spawn(async move {
+                record_reasoning(&handle, &session_id, reasoning).await;
+            });
+        }
         if !self.accumulated.is_empty() {
             let handle = self.handle.clone();
             let session_id = self.session_id.clone();
@@ -168,6 +265,10 @@ impl futures::Stream for AccumulatingStream {
     ) -> std::task::Poll<Option<Self::Item>> {
         use std::task::Poll;
 
+        if let Some(event) = self.queued.take() {
+            return Poll::Ready(Some(Ok(event)));
+        }
+
         if self.finished {
             return Poll::Ready(None);
         }
@@ -175,7 +276,7 @@ impl futures::Stream for AccumulatingStream {
         // Emit start event on first poll
         if !self.started {
             self.started = true;
-            let event = Event::default().event(sse_events::START).data("{}");
+            let event = self.emit(sse_events::START, "{}".to_string());
             return Poll::Ready(Some(Ok(event)));
         }
 
@@ -187,63 +288,63 @@ impl futures::Stream for AccumulatingStream {
 
         match futures::Stream::poll_next(inner.as_mut(), cx) {
             Poll::Ready(Some(Ok(StreamEvent::Token(content)))) => {
-                self.accumulated.push_str(&content);
-                let event = Event::default()
-                    .event(sse_events::TOKEN)
-                    .json_data(TokenData { content })
-                    .unwrap_or_else(|_| Event::default().event(sse_events::TOKEN).data("{}"));
-                Poll::Ready(Some(Ok(event)))
+                let out = self.transform.push(&content);
+                let token = (!out.text.is_empty()).then(|| self.emit_token(out.text));
+                if out.stop {
+                    // A stream processor ended the response; stop reading
+                    // from the provider.
+                    drop(self.inner.take());
+                    let event = self.emit_done(token, None);
+                    return Poll::Ready(Some(Ok(event)));
+                }
+                match token {
+                    Some(event) => Poll::Ready(Some(Ok(event))),
+                    None => {
+                        // Held back by a stream processor; poll for more.
+                        cx.waker().wake_by_ref();
+                        Poll::Pending
+                    }
+                }
+            }
+
+            Poll::Ready(Some(Ok(StreamEvent::Reasoning(content)))) => {
+                self.reasoning.push_str(&content);
+                if self.expose_reasoning {
+                    // Kept out of the replay buffer: a resumed stream may
+                    // belong to a caller who can't see reasoning.
+                    let event = Event::default()
+                        .event(sse_events::REASONING)
+                        .data(json_data(&TokenData { content }));
+                    return Poll::Ready(Some(Ok(event)));
+                }
+                cx.waker().wake_by_ref();
+                Poll::Pending
             }
 
             Poll::Ready(Some(Ok(StreamEvent::Done { usage }))) => {
-                self.finished = true;
                 self.last_usage = usage.clone();
-                self.save_accumulated();
-                let event = Event::default()
-                    .event(sse_events::DONE)
-                    .json_data(DoneData {
-                        message_id: self.message_id.clone(),
-                        usage,
-                    })
-                    .unwrap_or_else(|_| Event::default().event(sse_events::DONE).data("{}"));
-                Poll::Ready(Some(Ok(event)))
+                let tail = self.transform.finish();
+                let token = (!tail.is_empty()).then(|| self.emit_token(tail));
+                Poll::Ready(Some(Ok(self.emit_done(token, usage))))
             }
 
             Poll::Ready(Some(Err(StreamError::Timeout))) => {
-                self.finished = true;
-                self.save_accumulated();
-                let event = Event::default()
-                    .event(sse_events::ERROR)
-                    .json_data(ErrorData {
-                        message: "Stream idle timeout".to_string(),
-                    })
-                    .unwrap_or_else(|_| Event::default().event(sse_events::ERROR).data("{}"));
-                Poll::Ready(Some(Ok(event)))
+                let data = json_data(&ErrorData {
+                    message: "Stream idle timeout".to_string(),
+                });
+                Poll::Ready(Some(Ok(self.emit_final(sse_events::ERROR, data))))
             }
 
             Poll::Ready(Some(Err(StreamError::Llm(e)))) => {
-                self.finished = true;
-                self.save_accumulated();
-                let event = Event::default()
-                    .event(sse_events::ERROR)
-                    .json_data(ErrorData {
-                        message: e.to_string(),
-                    })
-                    .unwrap_or_else(|_| Event::default().event(sse_events::ERROR).data("{}"));
-                Poll::Ready(Some(Ok(event)))
+                let data = json_data(&ErrorData {
+                    message: e.to_string(),
+                });
+                Poll::Ready(Some(Ok(self.emit_final(sse_events::ERROR, data))))
             }
 
-            Poll::Ready(Some(Err(StreamError::Cancelled))) => {
-                self.finished = true;
-                self.save_accumulated();
-                let event = Event::default().event(sse_events::CANCELLED).data("{}");
-                Poll::Ready(Some(Ok(event)))
-            }
-
-            Poll::Ready(Some(Ok(StreamEvent::Cancelled))) => {
-                self.finished = true;
-                self.save_accumulated();
-                let event = Event::default().event(sse_events::CANCELLED).data("{}");
+            Poll::Ready(Some(Err(StreamError::Cancelled)))
+            | Poll::Ready(Some(Ok(StreamEvent::Cancelled))) => {
+                let event = self.emit_final(sse_events::CANCELLED, "{}".to_string());
                 Poll::Ready(Some(Ok(event)))
             }
 
@@ -258,6 +359,7 @@ impl futures::Stream for AccumulatingStream {
             Poll::Ready(None) => {
                 self.finished = true;
                 self.save_accumulated();
+                self.replay.finish();
                 Poll::Ready(None)
             }
 
@@ -285,6 +387,8 @@ impl Drop for AccumulatingStream {
                     DisconnectPayload {
                         inner: self.inner.take(),
                         accumulated: std::mem::take(&mut self.accumulated),
+                        reasoning: std::mem::take(&mut self.reasoning),
+                        transform: std::mem::take(&mut self.transform),
                     }
                 }
                 OnDisconnect::Pause => {
@@ -294,11 +398,15 @@ impl Drop for AccumulatingStream {
                         accumulated_len = self.accumulated.len(),
                         "SSE stream dropped before completion with on_disconnect: pause"
                     );
-                    // Cancel the LLM request
+                    // Cancel the LLM request and drop the provider stream now so the
+                    // upstream connection closes without waiting for the next token
                     self.cancel_token.cancel();
+                    drop(self.inner.take());
                     DisconnectPayload {
                         inner: None,
                         accumulated: std::mem::take(&mut self.accumulated),
+                        reasoning: std::mem::take(&mut self.reasoning),
+                        transform: StreamTransform::default(),
                     }
                 }
             };
@@ -325,15 +433,22 @@ struct StreamContext {
     session_id: String,
     message_id: String,
     on_disconnect: OnDisconnect,
+    replay: Arc<StreamBuffer>,
 }
 
 /// Payload sent when the SSE stream is dropped unexpectedly.
 struct DisconnectPayload {
     inner: Option<FlattenedLLMStream>,
     accumulated: String,
+    reasoning: String,
+    transform: StreamTransform,
 }
 
 async fn handle_disconnect(ctx: StreamContext, payload: DisconnectPayload) {
+    if !payload.reasoning.is_empty() {
+        record_reasoning(&ctx.handle, &ctx.session_id, payload.reasoning).await;
+    }
+
     match ctx.on_disconnect {
         OnDisconnect::Continue => {
             let Some(inner) = payload.inner else {
@@ -342,6 +457,7 @@ async fn handle_disconnect(ctx: StreamContext, payload: DisconnectPayload) {
                     message_id = %ctx.message_id,
                     "Missing stream for background continuation"
                 );
+                ctx.replay.finish();
                 if !payload.accumulated.is_empty()
                     && let Err(e) = ctx
                         .handle
@@ -369,7 +485,7 @@ async fn handle_disconnect(ctx: StreamContext, payload: DisconnectPayload) {
                 "Client disconnected with on_disconnect: continue, starting background task"
             );
 
-            continue_stream_in_background(inner, ctx, payload.accumulated).await;
+            continue_stream_in_background(inner, ctx, payload.accumulated, payload.transform).await;
         }
         OnDisconnect::Pause => {
             info!(
@@ -387,6 +503,25 @@ async fn handle_disconnect(ctx: StreamContext, payload: DisconnectPayload) {
                 return;
             }
 
+            ctx.replay.push(sse_events::CANCELLED, "{}".to_string());
+            ctx.replay.finish();
+
+            // Record the outcome distinctly from provider errors and timeouts
+            if let Err(e) = ctx
+                .handle
+                .record_error(
+                    CLIENT_DISCONNECTED.to_string(),
+                    format!("client disconnected during message {}", ctx.message_id),
+                )
+                .await
+            {
+                warn!(
+                    session_id = %ctx.session_id,
+                    error = %e,
+                    "Failed to write client disconnected event"
+                );
+            }
+
             if let Err(e) = ctx.handle.set_status(SessionStatus::Paused).await {
                 warn!(
                     session_id = %ctx.session_id,
@@ -418,6 +553,7 @@ async fn continue_stream_in_background(
     mut stream: FlattenedLLMStream,
     ctx: StreamContext,
     accumulated: String,
+    transform: StreamTransform,
 ) {
     if let Err(e) = ctx.handle.set_status(SessionStatus::Running).await {
         warn!(
@@ -447,14 +583,12 @@ async fn continue_stream_in_background(
         "Background continue task started"
     );
 
-    let result = consume_stream_to_completion(
-        &mut stream,
-        &ctx.handle,
-        &ctx.session_id,
-        &ctx.message_id,
-        accumulated,
-    )
-    .await;
+    let result = consume_stream_to_completion(&mut stream, &ctx, accumulated, transform).await;
+    ctx.replay.finish();
+
+    if !result.reasoning.is_empty() {
+        record_reasoning(&ctx.handle, &ctx.session_id, result.reasoning).await;
+    }
 
     // Finalize the stream: save accumulated message and force snapshot (crash safety)
     if !result.accumulated.is_empty()
@@ -489,6 +623,8 @@ async fn continue_stream_in_background(
 struct ConsumeResult {
     /// Accumulated content from the stream.
     accumulated: String,
+    /// Reasoning streamed apart from the content.
+    reasoning: String,
     /// Token usage if available.
     usage: Option<Usage>,
 }
@@ -498,25 +634,71 @@ struct ConsumeResult {
 /// Returns the accumulated content and usage when the stream completes.
 async fn consume_stream_to_completion(
     stream: &mut FlattenedLLMStream,
-    handle: &SessionHandle,
-    session_id: &str,
-    message_id: &str,
+    ctx: &StreamContext,
     mut accumulated: String,
+    mut transform: StreamTransform,
 ) -> ConsumeResult {
+    let handle = &ctx.handle;
+    let session_id = ctx.session_id.as_str();
+    let message_id = ctx.message_id.as_str();
+    let mut reasoning = String::new();
     let mut last_usage: Option<Usage> = None;
+    let mut last_checkpoint = Instant::now();
 
     while let Some(result) = stream.next().await {
         match result {
             Ok(StreamEvent::Token(content)) => {
-                accumulated.push_str(&content);
+                let out = transform.push(&content);
+                if !out.text.is_empty() {
+                    accumulated.push_str(&out.text);
+                    ctx.replay.push(
+                        sse_events::TOKEN,
+                        json_data(&TokenData { content: out.text }),
+                    );
+                    if last_checkpoint.elapsed() >= PARTIAL_CHECKPOINT_INTERVAL {
+                        last_checkpoint = Instant::now();
+                        handle.checkpoint_partial(message_id, &accumulated);
+                    }
+                }
+                if out.stop {
+                    debug!(
+                        session_id = %session_id,
+                        message_id = %message_id,
+                        "Background stream ended by a stream processor"
+                    );
+                    ctx.replay.push(
+                        sse_events::DONE,
+                        json_data(&DoneData {
+                            message_id: message_id.to_string(),
+                            usage: None,
+                        }),
+                    );
+                    break;
+                }
+            }
+            Ok(StreamEvent::Reasoning(content)) => {
+                reasoning.push_str(&content);
             }
             Ok(StreamEvent::Done { usage }) => {
+                let tail = transform.finish();
+                if !tail.is_empty() {
+                    accumulated.push_str(&tail);
+                    ctx.replay
+                        .push(sse_events::TOKEN, json_data(&TokenData { content: tail }));
+                }
                 debug!(
                     session_id = %session_id,
                     message_id = %message_id,
                     accumulated_len = accumulated.len(),
                     "Background stream completed"
                 );
+                ctx.replay.push(
+                    sse_events::DONE,
+                    json_data(&DoneData {
+                        message_id: message_id.to_string(),
+                        usage: usage.clone(),
+                    }),
+                );
                 last_usage = usage;
                 break;
             }
@@ -534,6 +716,12 @@ async fn consume_stream_to_completion(
                     message_id = %message_id,
                     "Background stream timed out"
                 );
+                ctx.replay.push(
+                    sse_events::ERROR,
+                    json_data(&ErrorData {
+                        message: "Stream idle timeout".to_string(),
+                    }),
+                );
                 if let Err(e) = handle
                     .record_error(
                         "timeout".to_string(),
@@ -556,6 +744,12 @@ async fn consume_stream_to_completion(
                     error = %e,
                     "Background stream LLM error"
                 );
+                ctx.replay.push(
+                    sse_events::ERROR,
+                    json_data(&ErrorData {
+                        message: e.to_string(),
+                    }),
+                );
                 if let Err(err) = handle
                     .record_error("llm_error".to_string(), e.to_string())
                     .await
@@ -585,10 +779,18 @@ async fn consume_stream_to_completion(
 
     ConsumeResult {
         accumulated,
+        reasoning,
         usage: last_usage,
     }
 }
 
+/// Record streamed reasoning in the event log, logging failures.
+async fn record_reasoning(handle: &SessionHandle, session_id: &str, reasoning: String) {
+    if let Err(e) = handle.record_reasoning(reasoning).await {
+        warn!(session_id = %session_id, error = %e, "Failed to record reasoning");
+    }
+}
+
 // ============================================================================
 // Internal Types
 // ============================================================================
@@ -604,6 +806,11 @@ enum StreamError {
 type FlattenedLLMStream =
     std::pin::Pin<Box<dyn futures::Stream<Item = Result<StreamEvent, StreamError>> + Send>>;
 
+/// Serialize SSE event data, falling back to an empty object.
+fn json_data<T: Serialize>(data: &T) -> String {
+    serde_json::to_string(data).unwrap_or_else(|_| "{}".to_string())
+}
+
 #[derive(Serialize)]
 struct TokenData {
     content: String,
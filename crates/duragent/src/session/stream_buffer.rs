@@ -0,0 +1,280 @@
+//! Replay buffers for resumable SSE streams.
+//!
+//! Every event emitted on a session stream is recorded in a per-message
+//! buffer and tagged with an SSE `id` of the form `{message_id}:{seq}`.
+//! A client that drops mid-stream reconnects with `Last-Event-ID` and
+//! receives the events it missed, then follows the stream live until the
+//! run finishes. Finished buffers are kept for `REPLAY_RETENTION`.
+
+use std::collections::VecDeque;
+use std::convert::Infallible;
+use std::sync::{Arc, Mutex};
+use std::time::{Duration, Instant};
+
+use axum::response::sse::Event;
+use dashmap::DashMap;
+use tokio::sync::watch;
+
+/// How long a finished stream stays available for resumption.
+pub const REPLAY_RETENTION: Duration = Duration::from_secs(5 * 60);
+
+// ============================================================================
+// Public API
+// ============================================================================
+
+/// A recorded SSE event.
+#[derive(Debug, Clone)]
+pub struct BufferedEvent {
+    pub seq: u64,
+    pub event: &'static str,
+    pub data: String,
+}
+
+/// Events recorded for one streamed assistant message.
+pub struct StreamBuffer {
+    session_id: String,
+    message_id: String,
+    state: Mutex<BufferState>,
+    version: watch::Sender<u64>,
+}
+
+struct BufferState {
+    events: Vec<BufferedEvent>,
+    finished_at: Option<Instant>,
+}
+
+impl StreamBuffer {
+    fn new(session_id: String, message_id: String) -> Self {
+        let (version, _) = watch::channel(0);
+        Self {
+            session_id,
+            message_id,
+            state: Mutex::new(BufferState {
+                events: Vec::new(),
+                finished_at: None,
+            }),
+            version,
+        }
+    }
+
+    pub fn session_id(&self) -> &str {
+        &self.session_id
+    }
+
+    pub fn message_id(&self) -> &str {
+        &self.message_id
+    }
+
+    /// Record an event and return its SSE `id`.
+    ///
+    /// Events pushed after the buffer is finished are ignored.
+    pub fn push(&self, event: &'static str, data: String) -> String {
+        let seq = {
+            let mut state = self.state.lock().unwrap();
+            let seq = state.events.len() as u64 + 1;
+            if state.finished_at.is_none() {
+                state.events.push(BufferedEvent { seq, event, data });
+            }
+            seq
+        };
+        self.version.send_replace(seq);
+        event_id(&self.message_id, seq)
+    }
+
+    /// Mark the stream as complete; no further events will be recorded.
+    pub fn finish(&self) {
+        let mut state = self.state.lock().unwrap();
+        if state.finished_at.is_none() {
+            state.finished_at = Some(Instant::now());
+            drop(state);
+            self.version.send_modify(|v| *v += 1);
+        }
+    }
+
+    /// Events after `seq` (all events if `None`), and whether the stream is finished.
+    pub fn events_after(&self, seq: Option<u64>) -> (Vec<BufferedEvent>, bool) {
+        let state = self.state.lock().unwrap();
+        let skip = seq.unwrap_or(0) as usize;
+        let events = state.events.iter().skip(skip).cloned().collect();
+        (events, state.finished_at.is_some())
+    }
+
+    fn expired(&self, retention: Duration) -> bool {
+        self.state
+            .lock()
+            .unwrap()
+            .finished_at
+            .is_some_and(|t| t.elapsed() >= retention)
+    }
+}
+
+/// Registry of stream buffers keyed by message ID.
+#[derive(Clone, Default)]
+pub struct StreamBuffers {
+    inner: Arc<DashMap<String, Arc<StreamBuffer>>>,
+}
+
+impl StreamBuffers {
+    #[must_use]
+    pub fn new() -> Self {
+        Self::default()
+    }
+
+    /// Create a buffer for a new stream, pruning expired ones.
+    pub fn create(&self, session_id: &str, message_id: &str) -> Arc<StreamBuffer> {
+        self.inner.retain(|_, b| !b.expired(REPLAY_RETENTION));
+        let buffer = Arc::new(StreamBuffer::new(
+            session_id.to_string(),
+            message_id.to_string(),
+        ));
+        self.inner.insert(message_id.to_string(), buffer.clone());
+        buffer
+    }
+
+    /// Look up a buffer that has not expired.
+    pub fn get(&self, message_id: &str) -> Option<Arc<StreamBuffer>> {
+        self.inner
+            .get(message_id)
+            .map(|b| b.clone())
+            .filter(|b| !b.expired(REPLAY_RETENTION))
+    }
+}
+
+/// Build an SSE event ID.
+pub fn event_id(message_id: &str, seq: u64) -> String {
+    format!("{message_id}:{seq}")
+}
+
+/// Parse a `Last-Event-ID` value into `(message_id, seq)`.
+pub fn parse_event_id(id: &str) -> Option<(&str, u64)> {
+    let (message_id, seq) = id.trim().rsplit_once(':')?;
+    if message_id.is_empty() {
+        return None;
+    }
+    Some((message_id, seq.parse().ok()?))
+}
+
+/// Replay events after `after`, then follow the buffer until it finishes.
+pub fn replay(
+    buffer: Arc<StreamBuffer>,
+    after: Option<u64>,
+) -> impl futures::Stream<Item = Result<Event, Infallible>> + Send {
+    let rx = buffer.version.subscribe();
+    let state = ReplayState {
+        buffer,
+        rx,
+        last: after,
+        queue: VecDeque::new(),
+        done: false,
+    };
+
+    futures::stream::unfold(state, |mut st| async move {
+        loop {
+            if let Some(ev) = st.queue.pop_front() {
+                st.last = Some(ev.seq);
+                let event = Event::default()
+                    .id(event_id(st.buffer.message_id(), ev.seq))
+                    .event(ev.event)
+                    .data(ev.data);
+                return Some((Ok(event), st));
+            }
+            if st.done {
+                return None;
+            }
+
+            // Mark the current version seen before reading, so a push that
+            // races with this read still wakes `changed()` below.
+            st.rx.borrow_and_update();
+            let (events, finished) = st.buffer.events_after(st.last);
+            st.queue.extend(events);
+            if finished {
+                st.done = true;
+                continue;
+            }
+            if st.queue.is_empty() && st.rx.changed().await.is_err() {
+                st.done = true;
+            }
+        }
+    })
+}
+
+// ============================================================================
+// Internal Types
+// ============================================================================
+
+struct ReplayState {
+    buffer: Arc<StreamBuffer>,
+    rx: watch::Receiver<u64>,
+    last: Option<u64>,
+    queue: VecDeque<BufferedEvent>,
+    done: bool,
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use futures::StreamExt;
+
+    #[test]
+    fn event_id_roundtrip() {
+        let id = event_id("msg_01ABC", 7);
+        assert_eq!(id, "msg_01ABC:7");
+        assert_eq!(parse_event_id(&id), Some(("msg_01ABC", 7)));
+    }
+
+    #[test]
+    fn parse_event_id_rejects_malformed() {
+        assert_eq!(parse_event_id("msg_01ABC"), None);
+        assert_eq!(parse_event_id(":3"), None);
+        assert_eq!(parse_event_id("msg_01ABC:x"), None);
+    }
+
+    #[test]
+    fn events_after_skips_seen_events() {
+        let buffers = StreamBuffers::new();
+        let buffer = buffers.create("session_1", "msg_1");
+        buffer.push("start", "{}".to_string());
+        buffer.push("token", r#"{"content":"a"}"#.to_string());
+        buffer.push("token", r#"{"content":"b"}"#.to_string());
+
+        let (events, finished) = buffer.events_after(Some(1));
+        assert_eq!(events.len(), 2);
+        assert_eq!(events[0].seq, 2);
+        assert!(!finished);
+    }
+
+    #[test]
+    fn push_after_finish_is_ignored() {
+        let buffers = StreamBuffers::new();
+        let buffer = buffers.create("session_1", "msg_1");
+        buffer.push("done", "{}".to_string());
+        buffer.finish();
+        buffer.push("token", "{}".to_string());
+
+        let (events, finished) = buffer.events_after(None);
+        assert_eq!(events.len(), 1);
+        assert!(finished);
+    }
+
+    #[tokio::test]
+    async fn replay_follows_live_events_until_finished() {
+        let buffers = StreamBuffers::new();
+        let buffer = buffers.create("session_1", "msg_1");
+        buffer.push("start", "{}".to_string());
+        buffer.push("token", r#"{"content":"a"}"#.to_string());
+
+        let stream = replay(buffers.get("msg_1").unwrap(), Some(1));
+
+        let writer = buffer.clone();
+        tokio::spawn(async move {
+            tokio::time::sleep(Duration::from_millis(10)).await;
+            writer.push("token", r#"{"content":"b"}"#.to_string());
+            writer.push("done", "{}".to_string());
+            writer.finish();
+        });
+
+        let events: Vec<_> = stream.collect().await;
+        // token a, token b, done
+        assert_eq!(events.len(), 3);
+    }
+}
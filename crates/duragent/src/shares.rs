@@ -0,0 +1,207 @@
+//! Read-only share links for session transcripts.
+//!
+//! A share is a bearer token for one session's transcript, served at
+//! `GET /shared/{token}` without API credentials, so conversations can be
+//! shown to people who have no access to the API. Shares may expire and can
+//! be revoked at any time; deleting the session revokes its links too.
+//!
+//! Only a SHA-256 hash of each token is stored. Tokens are shown once, when
+//! a share is created.
+
+use std::collections::HashMap;
+use std::sync::{Arc, RwLock};
+
+use chrono::{DateTime, Utc};
+use serde::{Deserialize, Serialize};
+
+use crate::service_accounts::{generate_token, hash_token};
+use crate::store::{ShareStore, StorageResult};
+
+/// Prefix of every share token, so leaked links are recognizable.
+pub const TOKEN_PREFIX: &str = "dsh_";
+
+/// ID prefix for shares.
+pub const SHARE_ID_PREFIX: &str = "shr_";
+
+/// A stored share link.
+#[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
+pub struct Share {
+    pub id: String,
+    pub session_id: String,
+    /// SHA-256 of the token.
+    pub token_hash: String,
+    pub created_at: DateTime<Utc>,
+    /// When the link stops working; `None` until revoked.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub expires_at: Option<DateTime<Utc>>,
+}
+
+impl Share {
+    pub fn is_expired(&self, now: DateTime<Utc>) -> bool {
+        self.expires_at.is_some_and(|at| at <= now)
+    }
+}
+
+/// Share links, cached in memory for token lookups.
+///
+/// Uses `std::sync::RwLock` because the lock is never held across await
+/// points; the store is the source of truth and is written first.
+#[derive(Clone)]
+pub struct Shares {
+    store: Arc<dyn ShareStore>,
+    shares: Arc<RwLock<HashMap<String, Share>>>,
+}
+
+impl Shares {
+    /// Load all shares from `store`.
+    pub async fn load(store: Arc<dyn ShareStore>) -> StorageResult<Self> {
+        let shares = store
+            .list()
+            .await?
+            .into_iter()
+            .map(|s| (s.id.clone(), s))
+            .collect();
+        Ok(Self {
+            store,
+            shares: Arc::new(RwLock::new(shares)),
+        })
+    }
+
+    /// A session's shares, oldest first, including expired ones.
+    pub fn list(&self, session_id: &str) -> Vec<Share> {
+        let mut shares: Vec<_> = self
+            .shares
+            .read()
+            .unwrap()
+            .values()
+            .filter(|s| s.session_id == session_id)
+            .cloned()
+            .collect();
+        // IDs are ULIDs, so they sort by creation time.
+        shares.sort_by(|a, b| a.id.cmp(&b.id));
+        shares
+    }
+
+    /// Share a session's transcript. Returns the share with its token.
+    pub async fn create(
+        &self,
+        session_id: &str,
+        expires_at: Option<DateTime<Utc>>,
+    ) -> StorageResult<(Share, String)> {
+        let token = generate_token(TOKEN_PREFIX);
+        let share = Share {
+            id: format!("{SHARE_ID_PREFIX}{}", ulid::Ulid::new()),
+            session_id: session_id.to_string(),
+            token_hash: hash_token(&token),
+            created_at: Utc::now(),
+            expires_at,
+        };
+        self.store.save(&share).await?;
+        self.shares
+            .write()
+            .unwrap()
+            .insert(share.id.clone(), share.clone());
+        Ok((share, token))
+    }
+
+    /// Revoke one of a session's shares. Returns whether it existed.
+    pub async fn revoke(&self, session_id: &str, id: &str) -> StorageResult<bool> {
+        let exists = self
+            .shares
+            .read()
+            .unwrap()
+            .get(id)
+            .is_some_and(|s| s.session_id == session_id);
+        if !exists {
+            return Ok(false);
+        }
+        self.store.delete(id).await?;
+        self.shares.write().unwrap().remove(id);
+        Ok(true)
+    }
+
+    /// Revoke every share of a session, e.g. when it is deleted. Returns how
+    /// many there were.
+    pub async fn revoke_session(&self, session_id: &str) -> StorageResult<usize> {
+        let shares = self.list(session_id);
+        for share in &shares {
+            self.store.delete(&share.id).await?;
+            self.shares.write().unwrap().remove(&share.id);
+        }
+        Ok(shares.len())
+    }
+
+    /// The unexpired share a token belongs to, if any.
+    pub fn resolve(&self, token: &str) -> Option<Share> {
+        if !token.starts_with(TOKEN_PREFIX) {
+            return None;
+        }
+        let hash = hash_token(token);
+        let now = Utc::now();
+        self.shares
+            .read()
+            .unwrap()
+            .values()
+            .find(|s| s.token_hash == hash && !s.is_expired(now))
+            .cloned()
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::store::file::FileShareStore;
+    use chrono::TimeDelta;
+    use tempfile::TempDir;
+
+    #[tokio::test]
+    async fn tokens_resolve_until_revoked_or_expired() {
+        let tmp = TempDir::new().unwrap();
+        let shares = Shares::load(Arc::new(FileShareStore::new(tmp.path())))
+            .await
+            .unwrap();
+
+        let (share, token) = shares.create("session_1", None).await.unwrap();
+        assert_eq!(shares.resolve(&token).unwrap().id, share.id);
+        assert!(shares.resolve("dsh_wrong").is_none());
+
+        let (_, expired) = shares
+            .create("session_1", Some(Utc::now() - TimeDelta::minutes(1)))
+            .await
+            .unwrap();
+        assert!(shares.resolve(&expired).is_none());
+        assert_eq!(shares.list("session_1").len(), 2);
+        assert!(shares.list("session_2").is_empty());
+
+        // Reloading from the store sees the same shares
+        let reloaded = Shares::load(Arc::new(FileShareStore::new(tmp.path())))
+            .await
+            .unwrap();
+        assert_eq!(reloaded.resolve(&token).unwrap().id, share.id);
+
+        assert!(!shares.revoke("session_2", &share.id).await.unwrap());
+        assert!(shares.revoke("session_1", &share.id).await.unwrap());
+        assert!(shares.resolve(&token).is_none());
+    }
+
+    #[tokio::test]
+    async fn revoking_a_session_drops_only_its_shares() {
+        let tmp = TempDir::new().unwrap();
+        let shares = Shares::load(Arc::new(FileShareStore::new(tmp.path())))
+            .await
+            .unwrap();
+        let (_, token) = shares.create("session_1", None).await.unwrap();
+        shares.create("session_1", None).await.unwrap();
+        let (_, other) = shares.create("session_2", None).await.unwrap();
+
+        assert_eq!(shares.revoke_session("session_1").await.unwrap(), 2);
+        assert!(shares.resolve(&token).is_none());
+        assert!(shares.resolve(&other).is_some());
+
+        let reloaded = Shares::load(Arc::new(FileShareStore::new(tmp.path())))
+            .await
+            .unwrap();
+        assert!(reloaded.list("session_1").is_empty());
+        assert_eq!(reloaded.list("session_2").len(), 1);
+    }
+}
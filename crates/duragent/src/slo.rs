@@ -0,0 +1,515 @@
+//! Provider latency and error objectives.
+//!
+//! Every LLM request to a provider with an objective in `slo.providers` is
+//! recorded with its latency and outcome. A background job computes each
+//! objective's burn rate — how many times faster than allowed its error
+//! budget is being spent over the window — and alerts when it crosses the
+//! threshold, and again when it recovers. Alerts are logged and POSTed to the
+//! configured webhooks.
+
+use std::collections::{HashMap, HashSet, VecDeque};
+use std::sync::{Arc, Mutex};
+use std::time::{Duration, Instant};
+
+use async_trait::async_trait;
+use chrono::{DateTime, Utc};
+use serde::Serialize;
+use thiserror::Error;
+use tracing::{info, warn};
+
+use crate::config::{SloConfig, SloWebhookConfig};
+use crate::llm::{ChatRequest, ChatResponse, ChatStream, LLMError, LLMProvider};
+
+// ============================================================================
+// Types
+// ============================================================================
+
+#[derive(Debug, Error)]
+pub enum SloError {
+    #[error("slo for provider '{provider}': {field} must be between 0 and 1, got {value}")]
+    InvalidTarget {
+        provider: String,
+        field: &'static str,
+        value: f64,
+    },
+
+    #[error("slo for provider '{0}' sets neither latency_ms nor error_target")]
+    NoObjective(String),
+}
+
+/// What an objective measures.
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Hash, Serialize)]
+#[serde(rename_all = "snake_case")]
+pub enum ObjectiveKind {
+    /// Successful requests completing within a threshold.
+    Latency,
+    /// Requests that succeed.
+    Errors,
+}
+
+#[derive(Debug, Clone)]
+struct Objective {
+    provider: String,
+    kind: ObjectiveKind,
+    target: f64,
+    latency_ms: Option<u64>,
+    window: Duration,
+    burn_rate_threshold: f64,
+    min_requests: usize,
+}
+
+#[derive(Debug, Clone, Copy)]
+struct Sample {
+    at: Instant,
+    latency_ms: u64,
+    ok: bool,
+}
+
+/// Burn rate of one objective over its window.
+#[derive(Debug, Clone, Serialize)]
+pub struct SloStatus {
+    pub provider: String,
+    pub objective: ObjectiveKind,
+    pub target: f64,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub latency_ms: Option<u64>,
+    pub window_minutes: u64,
+    /// Requests counted by the objective within the window.
+    pub requests: usize,
+    /// Requests that missed the objective.
+    pub bad: usize,
+    /// Error budget spend relative to the objective; 1.0 spends it exactly.
+    pub burn_rate: f64,
+    pub burn_rate_threshold: f64,
+    pub alerting: bool,
+}
+
+/// Change in an objective's alert state.
+#[derive(Debug, Clone, Serialize)]
+pub struct SloAlert {
+    /// `slo.burning` or `slo.recovered`.
+    pub event: &'static str,
+    pub at: DateTime<Utc>,
+    #[serde(flatten)]
+    pub status: SloStatus,
+}
+
+// ============================================================================
+// ProviderSlos
+// ============================================================================
+
+/// Request samples and alert state for every configured objective.
+#[derive(Clone, Default)]
+pub struct ProviderSlos {
+    inner: Arc<Inner>,
+}
+
+#[derive(Default)]
+struct Inner {
+    objectives: Vec<Objective>,
+    /// Longest window per provider; older samples are dropped.
+    retention: HashMap<String, Duration>,
+    samples: Mutex<HashMap<String, VecDeque<Sample>>>,
+    alerting: Mutex<HashSet<(String, ObjectiveKind)>>,
+}
+
+impl ProviderSlos {
+    pub fn from_config(config: &SloConfig) -> Result<Self, SloError> {
+        let mut objectives = Vec::new();
+        for slo in &config.providers {
+            let window = Duration::from_secs(slo.window_minutes.max(1) * 60);
+            let objective = |kind, target: f64, field| {
+                if !(target > 0.0 && target < 1.0) {
+                    return Err(SloError::InvalidTarget {
+                        provider: slo.provider.clone(),
+                        field,
+                        value: target,
+                    });
+                }
+                Ok(Objective {
+                    provider: slo.provider.clone(),
+                    kind,
+                    target,
+                    latency_ms: slo.latency_ms.filter(|_| kind == ObjectiveKind::Latency),
+                    window,
+                    burn_rate_threshold: slo.burn_rate_threshold,
+                    min_requests: slo.min_requests,
+                })
+            };
+            if slo.latency_ms.is_none() && slo.error_target.is_none() {
+                return Err(SloError::NoObjective(slo.provider.clone()));
+            }
+            if slo.latency_ms.is_some() {
+                objectives.push(objective(
+                    ObjectiveKind::Latency,
+                    slo.latency_target,
+                    "latency_target",
+                )?);
+            }
+            if let Some(target) = slo.error_target {
+                objectives.push(objective(ObjectiveKind::Errors, target, "error_target")?);
+            }
+        }
+
+        let mut retention: HashMap<String, Duration> = HashMap::new();
+        for o in &objectives {
+            let longest = retention.entry(o.provider.clone()).or_default();
+            *longest = (*longest).max(o.window);
+        }
+
+        Ok(Self {
+            inner: Arc::new(Inner {
+                objectives,
+                retention,
+                ..Inner::default()
+            }),
+        })
+    }
+
+    pub fn is_empty(&self) -> bool {
+        self.inner.objectives.is_empty()
+    }
+
+    /// Whether requests to `provider` are tracked.
+    pub fn tracks(&self, provider: &str) -> bool {
+        self.inner.retention.contains_key(provider)
+    }
+
+    /// Record one request to `provider`.
+    pub fn record(&self, provider: &str, latency: Duration, ok: bool) {
+        self.record_at(provider, Instant::now(), latency, ok);
+    }
+
+    fn record_at(&self, provider: &str, at: Instant, latency: Duration, ok: bool) {
+        let Some(retention) = self.inner.retention.get(provider) else {
+            return;
+        };
+        let mut samples = self.inner.samples.lock().expect("mutex poisoned");
+        let samples = samples.entry(provider.to_string()).or_default();
+        while samples
+            .front()
+            .is_some_and(|s| at.saturating_duration_since(s.at) > *retention)
+        {
+            samples.pop_front();
+        }
+        samples.push_back(Sample {
+            at,
+            latency_ms: latency.as_millis() as u64,
+            ok,
+        });
+    }
+
+    /// Burn rate of every objective, in config order.
+    pub fn statuses(&self) -> Vec<SloStatus> {
+        self.statuses_at(Instant::now())
+    }
+
+    fn statuses_at(&self, now: Instant) -> Vec<SloStatus> {
+        let samples = self.inner.samples.lock().expect("mutex poisoned");
+        let alerting = self.inner.alerting.lock().expect("mutex poisoned");
+        self.inner
+            .objectives
+            .iter()
+            .map(|o| {
+                let in_window = samples.get(&o.provider).into_iter().flatten().filter(|s| {
+                    now.saturating_duration_since(s.at) <= o.window
+                        && (o.kind == ObjectiveKind::Errors || s.ok)
+                });
+                let (requests, bad) = in_window.fold((0, 0), |(requests, bad), s| {
+                    let missed = match o.kind {
+                        ObjectiveKind::Errors => !s.ok,
+                        ObjectiveKind::Latency => s.latency_ms > o.latency_ms.unwrap_or(u64::MAX),
+                    };
+                    (requests + 1, bad + usize::from(missed))
+                });
+                let burn_rate = if requests == 0 {
+                    0.0
+                } else {
+                    (bad as f64 / requests as f64) / (1.0 - o.target)
+                };
+                SloStatus {
+                    provider: o.provider.clone(),
+                    objective: o.kind,
+                    target: o.target,
+                    latency_ms: o.latency_ms,
+                    window_minutes: o.window.as_secs() / 60,
+                    requests,
+                    bad,
+                    burn_rate,
+                    burn_rate_threshold: o.burn_rate_threshold,
+                    alerting: alerting.contains(&(o.provider.clone(), o.kind)),
+                }
+            })
+            .collect()
+    }
+
+    /// Evaluate every objective and return the ones whose alert state changed.
+    pub fn evaluate(&self) -> Vec<SloAlert> {
+        self.evaluate_at(Instant::now())
+    }
+
+    fn evaluate_at(&self, now: Instant) -> Vec<SloAlert> {
+        let statuses = self.statuses_at(now);
+        let mut alerting = self.inner.alerting.lock().expect("mutex poisoned");
+        let min_requests: HashMap<_, _> = self
+            .inner
+            .objectives
+            .iter()
+            .map(|o| ((o.provider.as_str(), o.kind), o.min_requests))
+            .collect();
+
+        let at = Utc::now();
+        let mut alerts = Vec::new();
+        for mut status in statuses {
+            let key = (status.provider.clone(), status.objective);
+            let min = min_requests[&(status.provider.as_str(), status.objective)];
+            let burning =
+                status.requests >= min.max(1) && status.burn_rate >= status.burn_rate_threshold;
+            let event = match (burning, alerting.contains(&key)) {
+                (true, false) => {
+                    alerting.insert(key);
+                    "slo.burning"
+                }
+                (false, true) => {
+                    alerting.remove(&key);
+                    "slo.recovered"
+                }
+                _ => continue,
+            };
+            status.alerting = burning;
+            alerts.push(SloAlert { event, at, status });
+        }
+        alerts
+    }
+}
+
+// ============================================================================
+// ObservedProvider
+// ============================================================================
+
+/// Records the latency and outcome of each request for its provider's SLOs.
+///
+/// Streams are timed to the start of the response; errors later in the
+/// stream are not counted.
+pub struct ObservedProvider {
+    inner: Arc<dyn LLMProvider>,
+    provider: String,
+    slos: ProviderSlos,
+}
+
+impl ObservedProvider {
+    #[must_use]
+    pub fn new(inner: Arc<dyn LLMProvider>, provider: String, slos: ProviderSlos) -> Self {
+        Self {
+            inner,
+            provider,
+            slos,
+        }
+    }
+}
+
+#[async_trait]
+impl LLMProvider for ObservedProvider {
+    async fn chat(&self, request: ChatRequest) -> Result<ChatResponse, LLMError> {
+        let started = Instant::now();
+        let result = self.inner.chat(request).await;
+        self.slos
+            .record(&self.provider, started.elapsed(), result.is_ok());
+        result
+    }
+
+    async fn chat_stream(&self, request: ChatRequest) -> Result<ChatStream, LLMError> {
+        let started = Instant::now();
+        let result = self.inner.chat_stream(request).await;
+        self.slos
+            .record(&self.provider, started.elapsed(), result.is_ok());
+        result
+    }
+
+    async fn health_check(&self) -> Result<(), LLMError> {
+        self.inner.health_check().await
+    }
+
+    async fn embed(&self, model: &str, inputs: Vec<String>) -> Result<Vec<Vec<f32>>, LLMError> {
+        self.inner.embed(model, inputs).await
+    }
+
+    async fn list_models(&self) -> Result<Vec<String>, LLMError> {
+        self.inner.list_models().await
+    }
+}
+
+// ============================================================================
+// Alerts
+// ============================================================================
+
+/// Spawn the periodic burn rate evaluation. Does nothing without objectives.
+pub fn spawn_slo_alerts(slos: ProviderSlos, config: &SloConfig, client: reqwest::Client) {
+    if slos.is_empty() {
+        return;
+    }
+    let interval = Duration::from_secs(config.evaluation_interval_seconds.max(1));
+    let webhooks = config.webhooks.clone();
+
+    tokio::spawn(async move {
+        let mut interval = tokio::time::interval(interval);
+        interval.tick().await; // skip immediate tick
+        loop {
+            interval.tick().await;
+            for alert in slos.evaluate() {
+                log_alert(&alert);
+                for webhook in &webhooks {
+                    if let Err(e) = send_alert(&client, webhook, &alert).await {
+                        warn!(url = %webhook.url, error = %e, "Failed to deliver SLO alert");
+                    }
+                }
+            }
+        }
+    });
+}
+
+fn log_alert(alert: &SloAlert) {
+    let s = &alert.status;
+    if alert.event == "slo.burning" {
+        warn!(
+            provider = %s.provider,
+            objective = ?s.objective,
+            burn_rate = s.burn_rate,
+            threshold = s.burn_rate_threshold,
+            requests = s.requests,
+            bad = s.bad,
+            "Provider SLO error budget burning"
+        );
+    } else {
+        info!(
+            provider = %s.provider,
+            objective = ?s.objective,
+            burn_rate = s.burn_rate,
+            "Provider SLO recovered"
+        );
+    }
+}
+
+async fn send_alert(
+    client: &reqwest::Client,
+    webhook: &SloWebhookConfig,
+    alert: &SloAlert,
+) -> Result<(), reqwest::Error> {
+    let mut request = client.post(&webhook.url).json(alert);
+    for (name, value) in &webhook.headers {
+        request = request.header(name, value);
+    }
+    request.send().await?.error_for_status()?;
+    Ok(())
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::config::ProviderSloConfig;
+
+    fn slos(latency_ms: Option<u64>, error_target: Option<f64>) -> ProviderSlos {
+        ProviderSlos::from_config(&SloConfig {
+            providers: vec![ProviderSloConfig {
+                provider: "openai".to_string(),
+                latency_ms,
+                latency_target: 0.9,
+                error_target,
+                window_minutes: 60,
+                burn_rate_threshold: 2.0,
+                min_requests: 10,
+            }],
+            ..SloConfig::default()
+        })
+        .unwrap()
+    }
+
+    fn record(slos: &ProviderSlos, at: Instant, count: usize, latency_ms: u64, ok: bool) {
+        for _ in 0..count {
+            slos.record_at("openai", at, Duration::from_millis(latency_ms), ok);
+        }
+    }
+
+    #[test]
+    fn burn_rate_alerts_once_and_recovers() {
+        let slos = slos(None, Some(0.9));
+        let start = Instant::now();
+        record(&slos, start, 6, 100, true);
+        record(&slos, start, 4, 100, false);
+
+        // 40% errors against a 10% budget burns at 4x
+        let alerts = slos.evaluate_at(start);
+        assert_eq!(alerts.len(), 1);
+        assert_eq!(alerts[0].event, "slo.burning");
+        assert!((alerts[0].status.burn_rate - 4.0).abs() < 1e-9);
+        assert!(slos.evaluate_at(start).is_empty());
+        assert!(slos.statuses_at(start)[0].alerting);
+
+        record(&slos, start, 30, 100, true);
+        let alerts = slos.evaluate_at(start);
+        assert_eq!(alerts.len(), 1);
+        assert_eq!(alerts[0].event, "slo.recovered");
+    }
+
+    #[test]
+    fn latency_objective_counts_successful_requests_only() {
+        let slos = slos(Some(1000), None);
+        let start = Instant::now();
+        record(&slos, start, 8, 200, true);
+        record(&slos, start, 2, 5000, true);
+        record(&slos, start, 5, 10, false);
+
+        let status = &slos.statuses_at(start)[0];
+        assert_eq!(status.objective, ObjectiveKind::Latency);
+        assert_eq!(status.requests, 10);
+        assert_eq!(status.bad, 2);
+        assert_eq!(slos.evaluate_at(start)[0].event, "slo.burning");
+    }
+
+    #[test]
+    fn samples_outside_window_are_ignored() {
+        let slos = slos(None, Some(0.9));
+        let start = Instant::now();
+        record(&slos, start, 10, 100, false);
+        let later = start + Duration::from_secs(61 * 60);
+        record(&slos, later, 10, 100, true);
+
+        let status = &slos.statuses_at(later)[0];
+        assert_eq!(status.requests, 10);
+        assert_eq!(status.bad, 0);
+    }
+
+    #[test]
+    fn min_requests_holds_back_alerts() {
+        let slos = slos(None, Some(0.9));
+        let start = Instant::now();
+        record(&slos, start, 5, 100, false);
+        assert!(slos.evaluate_at(start).is_empty());
+        slos.record("anthropic", Duration::from_millis(1), false);
+        assert!(!slos.tracks("anthropic"));
+    }
+
+    #[test]
+    fn invalid_targets_are_rejected() {
+        let config = |error_target| SloConfig {
+            providers: vec![ProviderSloConfig {
+                provider: "openai".to_string(),
+                latency_ms: None,
+                latency_target: 0.95,
+                error_target,
+                window_minutes: 60,
+                burn_rate_threshold: 2.0,
+                min_requests: 10,
+            }],
+            ..SloConfig::default()
+        };
+        assert!(matches!(
+            ProviderSlos::from_config(&config(Some(1.0))),
+            Err(SloError::InvalidTarget { .. })
+        ));
+        assert!(matches!(
+            ProviderSlos::from_config(&config(None)),
+            Err(SloError::NoObjective(_))
+        ));
+    }
+}
@@ -0,0 +1,22 @@
+//! Session archive storage trait.
+//!
+//! Defines the interface for the cold tier that old sessions are moved to.
+//! Archived sessions are opaque compressed blobs, one per session.
+
+use async_trait::async_trait;
+
+use super::error::StorageResult;
+
+/// Storage interface for archived sessions.
+#[async_trait]
+pub trait SessionArchive: Send + Sync {
+    /// Load an archived session.
+    ///
+    /// Returns `Ok(None)` if the session was never archived.
+    async fn load(&self, session_id: &str) -> StorageResult<Option<Vec<u8>>>;
+
+    /// Store an archived session, replacing any previous copy.
+    ///
+    /// Must be durable before returning; the hot copy is deleted afterwards.
+    async fn save(&self, session_id: &str, data: &[u8]) -> StorageResult<()>;
+}
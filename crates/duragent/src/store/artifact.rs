@@ -0,0 +1,26 @@
+//! Artifact storage trait.
+//!
+//! Defines the interface for persisting files attached to session messages.
+
+use async_trait::async_trait;
+
+use crate::artifacts::Artifact;
+
+use super::error::StorageResult;
+
+/// Storage interface for session artifacts.
+#[async_trait]
+pub trait ArtifactStore: Send + Sync {
+    /// List a session's artifacts, oldest first.
+    ///
+    /// Returns an empty list for sessions with no artifacts.
+    async fn list(&self, session_id: &str) -> StorageResult<Vec<Artifact>>;
+
+    /// Load an artifact and its content, or `None` if it doesn't exist.
+    async fn load(&self, session_id: &str, id: &str) -> StorageResult<Option<(Artifact, Vec<u8>)>>;
+
+    /// Store an artifact with its content.
+    ///
+    /// Must be atomic - either fully succeeds or has no effect.
+    async fn save(&self, artifact: &Artifact, content: &[u8]) -> StorageResult<()>;
+}
@@ -0,0 +1,32 @@
+//! Dead letter storage trait.
+//!
+//! Defines the interface for persisting scheduled runs that failed after
+//! exhausting their retries.
+
+use async_trait::async_trait;
+
+use crate::scheduler::DeadLetter;
+
+use super::error::StorageResult;
+
+/// Storage interface for dead-lettered runs.
+#[async_trait]
+pub trait DeadLetterStore: Send + Sync {
+    /// List all dead letters, oldest failure first.
+    async fn list(&self) -> StorageResult<Vec<DeadLetter>>;
+
+    /// Load a dead letter by ID.
+    ///
+    /// Returns `Ok(None)` if the dead letter doesn't exist.
+    async fn load(&self, id: &str) -> StorageResult<Option<DeadLetter>>;
+
+    /// Create or update a dead letter (upsert semantics).
+    ///
+    /// Must be atomic - either fully succeeds or has no effect.
+    async fn save(&self, dead_letter: &DeadLetter) -> StorageResult<()>;
+
+    /// Delete a dead letter.
+    ///
+    /// No-op if the dead letter doesn't exist.
+    async fn delete(&self, id: &str) -> StorageResult<()>;
+}
@@ -29,6 +29,13 @@ pub enum StorageError {
         found: String,
     },
 
+    // ========================================================================
+    // Remote backend errors
+    // ========================================================================
+    /// Request to a remote object store failed.
+    #[error("remote storage error at {location}: {message}")]
+    Remote { location: String, message: String },
+
     // ========================================================================
     // Generic errors (any backend)
     // ========================================================================
@@ -77,6 +84,17 @@ impl StorageError {
         }
     }
 
+    // ========================================================================
+    // Remote backend helpers
+    // ========================================================================
+    /// Create a remote storage error with location context (e.g. an object URL).
+    pub fn remote(location: impl Into<String>, message: impl Into<String>) -> Self {
+        Self::Remote {
+            location: location.into(),
+            message: message.into(),
+        }
+    }
+
     // ========================================================================
     // Generic helpers (any backend)
     // ========================================================================
@@ -0,0 +1,31 @@
+//! Few-shot example storage trait.
+//!
+//! Defines the interface for persisting example pools.
+
+use async_trait::async_trait;
+
+use crate::examples::Example;
+
+use super::error::StorageResult;
+
+/// Storage interface for few-shot example pools.
+#[async_trait]
+pub trait ExampleStore: Send + Sync {
+    /// List the examples in a pool, oldest first.
+    ///
+    /// Returns an empty list for pools that don't exist.
+    async fn list(&self, pool: &str) -> StorageResult<Vec<Example>>;
+
+    /// Load one example, or `None` if it doesn't exist.
+    async fn load(&self, pool: &str, id: &str) -> StorageResult<Option<Example>>;
+
+    /// Create or update an example (upsert semantics).
+    ///
+    /// Must be atomic - either fully succeeds or has no effect.
+    async fn save(&self, pool: &str, example: &Example) -> StorageResult<()>;
+
+    /// Delete an example.
+    ///
+    /// No-op if the example doesn't exist.
+    async fn delete(&self, pool: &str, id: &str) -> StorageResult<()>;
+}
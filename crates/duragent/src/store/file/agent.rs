@@ -5,17 +5,21 @@
 use std::path::{Path, PathBuf};
 
 use async_trait::async_trait;
+use chrono::{DateTime, Utc};
+use sha2::{Digest, Sha256};
 use tokio::fs;
 
 use super::policy::FilePolicyStore;
+use super::project::FileProjectStore;
 use crate::agent::SkillMetadata;
 use crate::agent::skill::parse_skill_frontmatter;
 use crate::agent::{
-    AgentLoadWarning, AgentSpec, LoadedAgentFiles, parse_agent_file_refs, parse_agent_yaml,
+    AgentLoadWarning, AgentSpec, ChangeSource, LoadedAgentFiles, Provenance, parse_agent_file_refs,
+    parse_agent_yaml,
 };
-use crate::store::PolicyStore;
 use crate::store::agent::{AgentCatalog, AgentScanResult, ScanWarning};
 use crate::store::error::{StorageError, StorageResult};
+use crate::store::{PolicyStore, ProjectStore};
 
 /// File-based implementation of `AgentCatalog`.
 ///
@@ -26,6 +30,48 @@ pub struct FileAgentCatalog {
     workspace_dir: Option<PathBuf>,
 }
 
+/// Trash directory inside the agents directory.
+const TRASH_DIR: &str = ".trash";
+
+/// Marker file recording when a trashed agent was deleted.
+const DELETED_AT_FILE: &str = ".deleted_at";
+
+/// Marker file present while an agent is disabled.
+pub const DISABLED_FILE: &str = ".disabled";
+
+/// Provenance record: who created and last changed the agent.
+pub const PROVENANCE_FILE: &str = ".provenance.yaml";
+
+/// An agent in the trash.
+#[derive(Debug, Clone)]
+pub struct TrashedAgent {
+    pub name: String,
+    pub deleted_at: DateTime<Utc>,
+}
+
+/// Who is making a change, recorded in the agent's provenance.
+#[derive(Debug, Clone)]
+pub struct ChangeAuthor {
+    pub source: ChangeSource,
+    pub principal: String,
+}
+
+/// A change to an agent directory, applied by [`FileAgentCatalog::apply_changes`].
+#[derive(Debug, Clone)]
+pub enum AgentChange {
+    /// Write files into the agent directory, creating it if needed.
+    ///
+    /// Paths are relative to the agent directory. Existing files not listed
+    /// are left untouched. With an `author`, the provenance record is updated.
+    Write {
+        name: String,
+        files: Vec<(PathBuf, String)>,
+        author: Option<ChangeAuthor>,
+    },
+    /// Move the agent directory to the trash.
+    Delete { name: String },
+}
+
 impl FileAgentCatalog {
     /// Create a new file agent catalog.
     ///
@@ -37,6 +83,287 @@ impl FileAgentCatalog {
             workspace_dir,
         }
     }
+
+    /// Project definitions member agents resolve `metadata.project` against.
+    ///
+    /// Stored in `{workspace}/projects`; without a workspace, next to the
+    /// agents directory.
+    pub fn projects(&self) -> FileProjectStore {
+        let root = self
+            .workspace_dir
+            .as_deref()
+            .or_else(|| self.agents_dir.parent())
+            .unwrap_or_else(|| Path::new("."));
+        FileProjectStore::new(root.join(crate::config::DEFAULT_PROJECTS_DIR))
+    }
+
+    /// Check whether an agent directory with an `agent.yaml` exists.
+    pub async fn exists(&self, name: &str) -> bool {
+        fs::metadata(self.agents_dir.join(name).join("agent.yaml"))
+            .await
+            .is_ok()
+    }
+
+    /// Apply a batch of changes, all or nothing.
+    ///
+    /// Deleted agents are moved to the trash (replacing any earlier trashed
+    /// copy) and overwritten files are kept in memory until the whole batch
+    /// succeeds; on the first failure every completed step is undone and the
+    /// error is returned.
+    pub async fn apply_changes(&self, changes: &[AgentChange]) -> StorageResult<()> {
+        fs::create_dir_all(&self.agents_dir)
+            .await
+            .map_err(|e| StorageError::file_io(&self.agents_dir, e))?;
+
+        let staging_dir = self.agents_dir.join(format!(".bulk-{}", ulid::Ulid::new()));
+        let mut undo = Vec::new();
+
+        let mut result = Ok(());
+        for change in changes {
+            result = self.apply_change(change, &staging_dir, &mut undo).await;
+            if result.is_err() {
+                break;
+            }
+        }
+
+        if result.is_err() {
+            for step in undo.into_iter().rev() {
+                step.revert().await;
+            }
+        }
+        let _ = fs::remove_dir_all(&staging_dir).await;
+        result
+    }
+
+    async fn apply_change(
+        &self,
+        change: &AgentChange,
+        staging_dir: &Path,
+        undo: &mut Vec<UndoStep>,
+    ) -> StorageResult<()> {
+        match change {
+            AgentChange::Write {
+                name,
+                files,
+                author,
+            } => {
+                let agent_dir = self.agents_dir.join(name);
+                if fs::metadata(&agent_dir).await.is_err() {
+                    fs::create_dir_all(&agent_dir)
+                        .await
+                        .map_err(|e| StorageError::file_io(&agent_dir, e))?;
+                    undo.push(UndoStep::RemoveDir(agent_dir.clone()));
+                }
+
+                for (rel, contents) in files {
+                    let path = agent_dir.join(rel);
+                    let previous = fs::read(&path).await.ok();
+                    if let Some(parent) = path.parent() {
+                        fs::create_dir_all(parent)
+                            .await
+                            .map_err(|e| StorageError::file_io(parent, e))?;
+                    }
+                    undo.push(UndoStep::RestoreFile(path.clone(), previous));
+                    super::atomic_write_file(&path, contents.as_bytes()).await?;
+                }
+
+                if let Some(author) = author {
+                    let path = agent_dir.join(PROVENANCE_FILE);
+                    let previous = fs::read(&path).await.ok();
+                    undo.push(UndoStep::RestoreFile(path, previous));
+                    write_provenance(&agent_dir, author).await?;
+                }
+            }
+            AgentChange::Delete { name } => {
+                let agent_dir = self.agents_dir.join(name);
+                let trashed = self.trash_dir().join(name);
+                fs::create_dir_all(staging_dir)
+                    .await
+                    .map_err(|e| StorageError::file_io(staging_dir, e))?;
+                fs::create_dir_all(self.trash_dir())
+                    .await
+                    .map_err(|e| StorageError::file_io(self.trash_dir(), e))?;
+
+                // An earlier trashed copy is superseded; it is discarded with
+                // the staging directory once the batch succeeds.
+                if fs::metadata(&trashed).await.is_ok() {
+                    let superseded = staging_dir.join(format!("trash-{name}"));
+                    fs::rename(&trashed, &superseded)
+                        .await
+                        .map_err(|e| StorageError::file_io(&trashed, e))?;
+                    undo.push(UndoStep::RestoreDir {
+                        from: superseded,
+                        to: trashed.clone(),
+                    });
+                }
+
+                fs::rename(&agent_dir, &trashed)
+                    .await
+                    .map_err(|e| StorageError::file_io(&agent_dir, e))?;
+                undo.push(UndoStep::RestoreDir {
+                    from: trashed.clone(),
+                    to: agent_dir,
+                });
+
+                let marker = trashed.join(DELETED_AT_FILE);
+                undo.push(UndoStep::RestoreFile(marker.clone(), None));
+                super::atomic_write_file(&marker, Utc::now().to_rfc3339().as_bytes()).await?;
+            }
+        }
+        Ok(())
+    }
+
+    /// Persist an agent's enabled state.
+    pub async fn set_enabled(&self, name: &str, enabled: bool) -> StorageResult<()> {
+        if !self.exists(name).await {
+            return Err(StorageError::not_found("agent", name));
+        }
+        let marker = self.agents_dir.join(name).join(DISABLED_FILE);
+        if enabled {
+            match fs::remove_file(&marker).await {
+                Err(e) if e.kind() != std::io::ErrorKind::NotFound => {
+                    Err(StorageError::file_io(&marker, e))
+                }
+                _ => Ok(()),
+            }
+        } else {
+            super::atomic_write_file(&marker, Utc::now().to_rfc3339().as_bytes()).await
+        }
+    }
+
+    /// Record a change made outside [`apply_changes`](Self::apply_changes),
+    /// e.g. by the CLI writing agent files directly.
+    pub async fn record_change(&self, name: &str, author: &ChangeAuthor) -> StorageResult<()> {
+        if !self.exists(name).await {
+            return Err(StorageError::not_found("agent", name));
+        }
+        write_provenance(&self.agents_dir.join(name), author).await
+    }
+
+    // ------------------------------------------------------------------------
+    // Trash
+    // ------------------------------------------------------------------------
+
+    fn trash_dir(&self) -> PathBuf {
+        self.agents_dir.join(TRASH_DIR)
+    }
+
+    /// List trashed agents, oldest deletion first.
+    pub async fn list_trash(&self) -> StorageResult<Vec<TrashedAgent>> {
+        let trash_dir = self.trash_dir();
+        let mut entries = match fs::read_dir(&trash_dir).await {
+            Ok(e) => e,
+            Err(e) if e.kind() == std::io::ErrorKind::NotFound => return Ok(Vec::new()),
+            Err(e) => return Err(StorageError::file_io(&trash_dir, e)),
+        };
+
+        let mut trashed = Vec::new();
+        while let Some(entry) = entries
+            .next_entry()
+            .await
+            .map_err(|e| StorageError::file_io(&trash_dir, e))?
+        {
+            let marker = entry.path().join(DELETED_AT_FILE);
+            let Ok(contents) = fs::read_to_string(&marker).await else {
+                continue;
+            };
+            let Ok(deleted_at) = DateTime::parse_from_rfc3339(contents.trim()) else {
+                continue;
+            };
+            trashed.push(TrashedAgent {
+                name: entry.file_name().to_string_lossy().to_string(),
+                deleted_at: deleted_at.with_timezone(&Utc),
+            });
+        }
+        trashed.sort_by_key(|t| t.deleted_at);
+        Ok(trashed)
+    }
+
+    /// Move a trashed agent back into the agents directory.
+    ///
+    /// Fails with `NotFound` if the agent is not in the trash, or with a file
+    /// error if an agent with the same name exists.
+    pub async fn restore(&self, name: &str) -> StorageResult<()> {
+        let trashed = self.trash_dir().join(name);
+        if fs::metadata(trashed.join(DELETED_AT_FILE)).await.is_err() {
+            return Err(StorageError::not_found("trashed agent", name));
+        }
+
+        let agent_dir = self.agents_dir.join(name);
+        if fs::metadata(&agent_dir).await.is_ok() {
+            return Err(StorageError::file_io(
+                &agent_dir,
+                std::io::Error::from(std::io::ErrorKind::AlreadyExists),
+            ));
+        }
+
+        fs::rename(&trashed, &agent_dir)
+            .await
+            .map_err(|e| StorageError::file_io(&trashed, e))?;
+        let marker = agent_dir.join(DELETED_AT_FILE);
+        fs::remove_file(&marker)
+            .await
+            .map_err(|e| StorageError::file_io(&marker, e))?;
+        Ok(())
+    }
+
+    /// Permanently delete a trashed agent.
+    pub async fn purge(&self, name: &str) -> StorageResult<()> {
+        let trashed = self.trash_dir().join(name);
+        if fs::metadata(trashed.join(DELETED_AT_FILE)).await.is_err() {
+            return Err(StorageError::not_found("trashed agent", name));
+        }
+        fs::remove_dir_all(&trashed)
+            .await
+            .map_err(|e| StorageError::file_io(&trashed, e))
+    }
+
+    /// Purge trashed agents deleted more than `retention` ago.
+    ///
+    /// Returns the names of purged agents.
+    pub async fn purge_expired(&self, retention: chrono::Duration) -> StorageResult<Vec<String>> {
+        let cutoff = Utc::now() - retention;
+        let mut purged = Vec::new();
+        for trashed in self.list_trash().await? {
+            if trashed.deleted_at <= cutoff {
+                self.purge(&trashed.name).await?;
+                purged.push(trashed.name);
+            }
+        }
+        Ok(purged)
+    }
+}
+
+/// Whether `name` is usable as an agent directory name.
+///
+/// Rejects path separators, `.`/`..`, and hidden names (reserved for
+/// internal use such as the trash and bulk staging).
+pub fn is_valid_agent_name(name: &str) -> bool {
+    !name.is_empty() && !name.starts_with('.') && !name.contains(['/', '\\'])
+}
+
+/// A reversible step taken by [`FileAgentCatalog::apply_changes`].
+enum UndoStep {
+    RemoveDir(PathBuf),
+    RestoreFile(PathBuf, Option<Vec<u8>>),
+    RestoreDir { from: PathBuf, to: PathBuf },
+}
+
+impl UndoStep {
+    async fn revert(self) {
+        let result = match &self {
+            Self::RemoveDir(dir) => fs::remove_dir_all(dir).await,
+            Self::RestoreFile(path, Some(previous)) => fs::write(path, previous).await,
+            Self::RestoreFile(path, None) => match fs::remove_file(path).await {
+                Err(e) if e.kind() == std::io::ErrorKind::NotFound => Ok(()),
+                other => other,
+            },
+            Self::RestoreDir { from, to } => fs::rename(from, to).await,
+        };
+        if let Err(e) = result {
+            tracing::warn!(error = %e, "Failed to roll back bulk agent change");
+        }
+    }
 }
 
 #[async_trait]
@@ -54,6 +381,7 @@ impl AgentCatalog for FileAgentCatalog {
         }
 
         let policy_store = FilePolicyStore::new(&self.agents_dir, self.workspace_dir.clone());
+        let projects = self.projects();
 
         let mut entries = match fs::read_dir(&self.agents_dir).await {
             Ok(e) => e,
@@ -92,7 +420,7 @@ impl AgentCatalog for FileAgentCatalog {
             let policy = policy_store.load(&agent_name).await;
 
             // Try to load the agent
-            match load_agent_from_dir(&path, &agent_name, policy).await {
+            match load_agent_from_dir(&path, &agent_name, policy, &projects).await {
                 Ok((agent, agent_warnings)) => {
                     agents.push(agent);
 
@@ -150,7 +478,7 @@ impl AgentCatalog for FileAgentCatalog {
         let policy_store = FilePolicyStore::new(&self.agents_dir, self.workspace_dir.clone());
         let policy = policy_store.load(name).await;
 
-        let (agent, _warnings) = load_agent_from_dir(&agent_dir, name, policy)
+        let (agent, _warnings) = load_agent_from_dir(&agent_dir, name, policy, &self.projects())
             .await
             .map_err(|e| StorageError::file_deserialization(&agent_dir, e.to_string()))?;
 
@@ -169,6 +497,7 @@ async fn load_agent_from_dir(
     agent_dir: &Path,
     agent_name: &str,
     policy: crate::agent::ToolPolicy,
+    projects: &FileProjectStore,
 ) -> Result<(AgentSpec, Vec<AgentLoadWarning>), crate::agent::AgentLoadError> {
     let yaml_path = agent_dir.join("agent.yaml");
 
@@ -210,13 +539,26 @@ async fn load_agent_from_dir(
     let skills_path = agent_dir.join(skills_dir);
     let skills = load_skills_from_dir(&skills_path, agent_name, &mut warnings).await;
 
-    let agent = parse_agent_yaml(
+    // Load the project whose defaults apply to this agent
+    let project = match &refs.project {
+        Some(project) => projects
+            .load(project)
+            .await
+            .map_err(|e| crate::agent::AgentLoadError::Validation(e.to_string()))?,
+        None => None,
+    };
+
+    let mut agent = parse_agent_yaml(
         &yaml_content,
         files,
         skills,
         policy,
         agent_dir.to_path_buf(),
+        project.as_ref(),
     )?;
+    agent.enabled = fs::metadata(agent_dir.join(DISABLED_FILE)).await.is_err();
+    agent.provenance = read_provenance(agent_dir).await.unwrap_or_default();
+    agent.digest = digest_agent_dir(agent_dir).await?;
 
     warnings.extend(crate::agent::validate_builtin_tools(
         agent_name,
@@ -226,6 +568,70 @@ async fn load_agent_from_dir(
     Ok((agent, warnings))
 }
 
+/// SHA-256 over every file in an agent directory.
+///
+/// The disabled marker and provenance record are left out: the server
+/// maintains them itself, and the enabled state is compared separately.
+async fn digest_agent_dir(agent_dir: &Path) -> std::io::Result<String> {
+    let mut files = Vec::new();
+    let mut dirs = vec![agent_dir.to_path_buf()];
+    while let Some(dir) = dirs.pop() {
+        let mut entries = fs::read_dir(&dir).await?;
+        while let Some(entry) = entries.next_entry().await? {
+            let file_type = entry.file_type().await?;
+            if file_type.is_dir() {
+                dirs.push(entry.path());
+            } else if file_type.is_file() {
+                files.push(entry.path());
+            }
+        }
+    }
+    files.sort();
+
+    let mut hasher = Sha256::new();
+    for path in files {
+        let rel = path.strip_prefix(agent_dir).unwrap_or(&path);
+        if rel == Path::new(DISABLED_FILE) || rel == Path::new(PROVENANCE_FILE) {
+            continue;
+        }
+        let data = fs::read(&path).await?;
+        hasher.update(rel.to_string_lossy().as_bytes());
+        hasher.update([0]);
+        hasher.update((data.len() as u64).to_le_bytes());
+        hasher.update(&data);
+    }
+    Ok(format!("{:x}", hasher.finalize()))
+}
+
+/// Read an agent's provenance record, if it has one.
+async fn read_provenance(agent_dir: &Path) -> Option<Provenance> {
+    let content = fs::read_to_string(agent_dir.join(PROVENANCE_FILE))
+        .await
+        .ok()?;
+    serde_saphyr::from_str(&content).ok()
+}
+
+/// Update an agent's provenance record for a change by `author`.
+///
+/// The creator is kept from the existing record; an agent without one is
+/// treated as created by this change.
+async fn write_provenance(agent_dir: &Path, author: &ChangeAuthor) -> StorageResult<()> {
+    let now = Utc::now();
+    let mut provenance = read_provenance(agent_dir).await.unwrap_or_default();
+    if provenance.created_at.is_none() {
+        provenance.created_by = Some(author.principal.clone());
+        provenance.created_at = Some(now);
+    }
+    provenance.source = author.source;
+    provenance.updated_by = Some(author.principal.clone());
+    provenance.updated_at = Some(now);
+
+    let path = agent_dir.join(PROVENANCE_FILE);
+    let yaml = serde_saphyr::to_string(&provenance)
+        .map_err(|e| StorageError::serialization(e.to_string()))?;
+    super::atomic_write_file(&path, yaml.as_bytes()).await
+}
+
 /// Load an optional file referenced in agent.yaml.
 ///
 /// If the file path is None or the file doesn't exist, returns None.
@@ -559,4 +965,189 @@ spec:
         assert_eq!(agent.skills.len(), 1);
         assert_eq!(agent.skills[0].name, "my-skill");
     }
+
+    // ==========================================================================
+    // apply_changes
+    // ==========================================================================
+
+    fn minimal_manifest(name: &str) -> String {
+        format!(
+            "apiVersion: {API_VERSION_V1ALPHA1}\nkind: {KIND_AGENT}\nmetadata:\n  name: {name}\nspec:\n  model:\n    provider: openrouter\n    name: anthropic/claude-sonnet-4\n"
+        )
+    }
+
+    #[tokio::test]
+    async fn apply_changes_writes_and_deletes() {
+        let tmp = TempDir::new().unwrap();
+        let agents_dir = tmp.path().join("agents");
+        std::fs::create_dir(&agents_dir).unwrap();
+        let old_dir = agents_dir.join("old-agent");
+        std::fs::create_dir(&old_dir).unwrap();
+        create_minimal_agent(&old_dir, "old-agent");
+
+        let catalog = FileAgentCatalog::new(&agents_dir, None);
+        catalog
+            .apply_changes(&[
+                AgentChange::Write {
+                    name: "new-agent".to_string(),
+                    files: vec![
+                        (PathBuf::from("agent.yaml"), minimal_manifest("new-agent")),
+                        (PathBuf::from("SOUL.md"), "Be kind.".to_string()),
+                    ],
+                },
+                AgentChange::Delete {
+                    name: "old-agent".to_string(),
+                },
+            ])
+            .await
+            .unwrap();
+
+        assert!(catalog.exists("new-agent").await);
+        assert!(!catalog.exists("old-agent").await);
+        assert!(agents_dir.join("new-agent/SOUL.md").exists());
+        // Deleted agent is in the trash; staging directory is cleaned up
+        let trashed = catalog.list_trash().await.unwrap();
+        assert_eq!(trashed.len(), 1);
+        assert_eq!(trashed[0].name, "old-agent");
+        let mut entries: Vec<_> = std::fs::read_dir(&agents_dir)
+            .unwrap()
+            .map(|e| e.unwrap().file_name().to_string_lossy().to_string())
+            .collect();
+        entries.sort();
+        assert_eq!(entries, vec![".trash", "new-agent"]);
+    }
+
+    #[tokio::test]
+    async fn trash_restore_and_purge() {
+        let tmp = TempDir::new().unwrap();
+        let agents_dir = tmp.path().join("agents");
+        std::fs::create_dir(&agents_dir).unwrap();
+        for name in ["keep", "drop"] {
+            let dir = agents_dir.join(name);
+            std::fs::create_dir(&dir).unwrap();
+            create_minimal_agent(&dir, name);
+        }
+
+        let catalog = FileAgentCatalog::new(&agents_dir, None);
+        for name in ["keep", "drop"] {
+            catalog
+                .apply_changes(&[AgentChange::Delete {
+                    name: name.to_string(),
+                }])
+                .await
+                .unwrap();
+        }
+        // Trashed agents are not loaded
+        assert!(catalog.load_all().await.unwrap().agents.is_empty());
+
+        catalog.restore("keep").await.unwrap();
+        assert!(catalog.exists("keep").await);
+        assert!(!agents_dir.join("keep").join(DELETED_AT_FILE).exists());
+
+        catalog.purge("drop").await.unwrap();
+        assert!(catalog.list_trash().await.unwrap().is_empty());
+        assert!(matches!(
+            catalog.restore("drop").await,
+            Err(StorageError::NotFound { .. })
+        ));
+    }
+
+    #[tokio::test]
+    async fn purge_expired_respects_retention() {
+        let tmp = TempDir::new().unwrap();
+        let agents_dir = tmp.path().join("agents");
+        let agent_dir = agents_dir.join("old");
+        std::fs::create_dir_all(&agent_dir).unwrap();
+        create_minimal_agent(&agent_dir, "old");
+
+        let catalog = FileAgentCatalog::new(&agents_dir, None);
+        catalog
+            .apply_changes(&[AgentChange::Delete {
+                name: "old".to_string(),
+            }])
+            .await
+            .unwrap();
+
+        let purged = catalog
+            .purge_expired(chrono::Duration::hours(1))
+            .await
+            .unwrap();
+        assert!(purged.is_empty());
+
+        let purged = catalog
+            .purge_expired(chrono::Duration::zero())
+            .await
+            .unwrap();
+        assert_eq!(purged, vec!["old".to_string()]);
+    }
+
+    #[tokio::test]
+    async fn apply_changes_rolls_back_on_failure() {
+        let tmp = TempDir::new().unwrap();
+        let agents_dir = tmp.path().join("agents");
+        std::fs::create_dir(&agents_dir).unwrap();
+        let existing_dir = agents_dir.join("existing");
+        std::fs::create_dir(&existing_dir).unwrap();
+        create_minimal_agent(&existing_dir, "existing");
+        let original = std::fs::read_to_string(existing_dir.join("agent.yaml")).unwrap();
+
+        let catalog = FileAgentCatalog::new(&agents_dir, None);
+        let result = catalog
+            .apply_changes(&[
+                AgentChange::Write {
+                    name: "existing".to_string(),
+                    files: vec![(PathBuf::from("agent.yaml"), "changed".to_string())],
+                },
+                AgentChange::Write {
+                    name: "created".to_string(),
+                    files: vec![(PathBuf::from("agent.yaml"), minimal_manifest("created"))],
+                },
+                // Fails: nothing to delete
+                AgentChange::Delete {
+                    name: "missing".to_string(),
+                },
+            ])
+            .await;
+
+        assert!(result.is_err());
+        assert_eq!(
+            std::fs::read_to_string(existing_dir.join("agent.yaml")).unwrap(),
+            original
+        );
+        assert!(!agents_dir.join("created").exists());
+    }
+
+    #[tokio::test]
+    async fn set_enabled_persists_across_loads() {
+        let tmp = TempDir::new().unwrap();
+        let agents_dir = tmp.path().join("agents");
+        let agent_dir = agents_dir.join("toggled");
+        std::fs::create_dir_all(&agent_dir).unwrap();
+        create_minimal_agent(&agent_dir, "toggled");
+
+        let catalog = FileAgentCatalog::new(&agents_dir, None);
+        assert!(catalog.load("toggled").await.unwrap().enabled);
+
+        catalog.set_enabled("toggled", false).await.unwrap();
+        assert!(!catalog.load("toggled").await.unwrap().enabled);
+
+        catalog.set_enabled("toggled", true).await.unwrap();
+        catalog.set_enabled("toggled", true).await.unwrap();
+        assert!(catalog.load("toggled").await.unwrap().enabled);
+
+        assert!(matches!(
+            catalog.set_enabled("missing", false).await,
+            Err(StorageError::NotFound { .. })
+        ));
+    }
+
+    #[test]
+    fn agent_name_validation() {
+        assert!(is_valid_agent_name("my-agent"));
+        assert!(!is_valid_agent_name(""));
+        assert!(!is_valid_agent_name(".."));
+        assert!(!is_valid_agent_name(".hidden"));
+        assert!(!is_valid_agent_name("a/b"));
+        assert!(!is_valid_agent_name("a\\b"));
+    }
 }
@@ -0,0 +1,49 @@
+//! File-based session archive implementation.
+//!
+//! Stores each archived session as `{archive_dir}/{session_id}.jsonl.gz`.
+
+use std::path::PathBuf;
+
+use async_trait::async_trait;
+use tokio::fs;
+
+use crate::store::archive::SessionArchive;
+use crate::store::error::{StorageError, StorageResult};
+
+/// File-based implementation of `SessionArchive`.
+#[derive(Debug, Clone)]
+pub struct FileSessionArchive {
+    archive_dir: PathBuf,
+}
+
+impl FileSessionArchive {
+    /// Create a new file session archive.
+    pub fn new(archive_dir: impl Into<PathBuf>) -> Self {
+        Self {
+            archive_dir: archive_dir.into(),
+        }
+    }
+
+    fn archive_path(&self, session_id: &str) -> PathBuf {
+        self.archive_dir.join(format!("{session_id}.jsonl.gz"))
+    }
+}
+
+#[async_trait]
+impl SessionArchive for FileSessionArchive {
+    async fn load(&self, session_id: &str) -> StorageResult<Option<Vec<u8>>> {
+        let path = self.archive_path(session_id);
+        match fs::read(&path).await {
+            Ok(data) => Ok(Some(data)),
+            Err(e) if e.kind() == std::io::ErrorKind::NotFound => Ok(None),
+            Err(e) => Err(StorageError::file_io(&path, e)),
+        }
+    }
+
+    async fn save(&self, session_id: &str, data: &[u8]) -> StorageResult<()> {
+        fs::create_dir_all(&self.archive_dir)
+            .await
+            .map_err(|e| StorageError::file_io(&self.archive_dir, e))?;
+        super::atomic_write_file(&self.archive_path(session_id), data).await
+    }
+}
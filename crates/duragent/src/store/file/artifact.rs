@@ -0,0 +1,172 @@
+//! File-based artifact storage implementation.
+//!
+//! Stores each artifact as `{artifacts_dir}/{session_id}/{id}.json`
+//! (metadata) and `{id}.bin` (content).
+
+use std::path::PathBuf;
+
+use async_trait::async_trait;
+use tokio::fs;
+
+use crate::artifacts::{ARTIFACT_ID_PREFIX, Artifact};
+use crate::store::artifact::ArtifactStore;
+use crate::store::error::{StorageError, StorageResult};
+
+/// File-based implementation of `ArtifactStore`.
+#[derive(Debug, Clone)]
+pub struct FileArtifactStore {
+    dir: PathBuf,
+}
+
+impl FileArtifactStore {
+    /// Create a new file artifact store.
+    pub fn new(dir: impl Into<PathBuf>) -> Self {
+        Self { dir: dir.into() }
+    }
+
+    /// Directory for a session, or `None` for IDs that would escape the store.
+    fn session_dir(&self, session_id: &str) -> Option<PathBuf> {
+        let valid = !session_id.is_empty()
+            && session_id
+                .chars()
+                .all(|c| c.is_ascii_alphanumeric() || c == '_' || c == '-');
+        valid.then(|| self.dir.join(session_id))
+    }
+
+    /// Metadata and content paths for an artifact, or `None` for IDs that
+    /// are not ours.
+    fn artifact_paths(&self, session_id: &str, id: &str) -> Option<(PathBuf, PathBuf)> {
+        let suffix = id.strip_prefix(ARTIFACT_ID_PREFIX)?;
+        let valid = !suffix.is_empty() && suffix.chars().all(|c| c.is_ascii_alphanumeric());
+        if !valid {
+            return None;
+        }
+        let dir = self.session_dir(session_id)?;
+        Some((
+            dir.join(format!("{id}.json")),
+            dir.join(format!("{id}.bin")),
+        ))
+    }
+}
+
+#[async_trait]
+impl ArtifactStore for FileArtifactStore {
+    async fn list(&self, session_id: &str) -> StorageResult<Vec<Artifact>> {
+        let Some(dir) = self.session_dir(session_id) else {
+            return Ok(Vec::new());
+        };
+        let mut entries = match fs::read_dir(&dir).await {
+            Ok(e) => e,
+            Err(e) if e.kind() == std::io::ErrorKind::NotFound => return Ok(Vec::new()),
+            Err(e) => return Err(StorageError::file_io(&dir, e)),
+        };
+
+        let mut artifacts = Vec::new();
+        while let Some(entry) = entries
+            .next_entry()
+            .await
+            .map_err(|e| StorageError::file_io(&dir, e))?
+        {
+            let path = entry.path();
+            if path.extension().is_none_or(|ext| ext != "json") {
+                continue;
+            }
+            let content = fs::read_to_string(&path)
+                .await
+                .map_err(|e| StorageError::file_io(&path, e))?;
+            let artifact: Artifact = serde_json::from_str(&content)
+                .map_err(|e| StorageError::file_deserialization(&path, e.to_string()))?;
+            artifacts.push(artifact);
+        }
+        // IDs are ULIDs, so they sort by creation time.
+        artifacts.sort_by(|a, b| a.id.cmp(&b.id));
+        Ok(artifacts)
+    }
+
+    async fn load(&self, session_id: &str, id: &str) -> StorageResult<Option<(Artifact, Vec<u8>)>> {
+        let Some((meta_path, content_path)) = self.artifact_paths(session_id, id) else {
+            return Ok(None);
+        };
+        let meta = match fs::read_to_string(&meta_path).await {
+            Ok(m) => m,
+            Err(e) if e.kind() == std::io::ErrorKind::NotFound => return Ok(None),
+            Err(e) => return Err(StorageError::file_io(&meta_path, e)),
+        };
+        let artifact: Artifact = serde_json::from_str(&meta)
+            .map_err(|e| StorageError::file_deserialization(&meta_path, e.to_string()))?;
+        let content = fs::read(&content_path)
+            .await
+            .map_err(|e| StorageError::file_io(&content_path, e))?;
+        Ok(Some((artifact, content)))
+    }
+
+    async fn save(&self, artifact: &Artifact, content: &[u8]) -> StorageResult<()> {
+        let (meta_path, content_path) = self
+            .artifact_paths(&artifact.session_id, &artifact.id)
+            .ok_or_else(|| {
+                StorageError::serialization(format!(
+                    "invalid artifact '{}' for session '{}'",
+                    artifact.id, artifact.session_id
+                ))
+            })?;
+        let dir = meta_path.parent().expect("artifact path has a parent");
+        fs::create_dir_all(dir)
+            .await
+            .map_err(|e| StorageError::file_io(dir, e))?;
+
+        // Content first: an artifact is only listed once its metadata exists.
+        super::atomic_write_file(&content_path, content).await?;
+        let meta = serde_json::to_string_pretty(artifact)
+            .map_err(|e| StorageError::serialization(e.to_string()))?;
+        super::atomic_write_file(&meta_path, meta.as_bytes()).await
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use tempfile::TempDir;
+
+    fn create_store(temp_dir: &TempDir) -> FileArtifactStore {
+        FileArtifactStore::new(temp_dir.path().join("artifacts"))
+    }
+
+    #[tokio::test]
+    async fn save_then_load_and_list() {
+        let temp_dir = TempDir::new().unwrap();
+        let store = create_store(&temp_dir);
+
+        let first = Artifact::new("session_1", "a.txt".into(), "text/plain".into(), 2);
+        let second = Artifact::new("session_1", "b.png".into(), "image/png".into(), 3);
+        store.save(&first, b"hi").await.unwrap();
+        store.save(&second, &[1, 2, 3]).await.unwrap();
+
+        let (artifact, content) = store.load("session_1", &second.id).await.unwrap().unwrap();
+        assert_eq!(artifact.name, "b.png");
+        assert_eq!(content, vec![1, 2, 3]);
+
+        let mut names: Vec<_> = store
+            .list("session_1")
+            .await
+            .unwrap()
+            .into_iter()
+            .map(|a| a.name)
+            .collect();
+        names.sort();
+        assert_eq!(names, ["a.txt", "b.png"]);
+        assert!(store.list("session_2").await.unwrap().is_empty());
+    }
+
+    #[tokio::test]
+    async fn rejects_ids_outside_the_store() {
+        let temp_dir = TempDir::new().unwrap();
+        let store = create_store(&temp_dir);
+
+        assert!(store.load("../etc", "art_1").await.unwrap().is_none());
+        assert!(store.load("session_1", "../art_1").await.unwrap().is_none());
+
+        let mut artifact = Artifact::new("session_1", "a.txt".into(), "text/plain".into(), 0);
+        artifact.session_id = "../escape".to_string();
+        assert!(store.save(&artifact, b"").await.is_err());
+    }
+}
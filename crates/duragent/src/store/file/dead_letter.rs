@@ -0,0 +1,188 @@
+//! File-based dead letter storage implementation.
+//!
+//! Stores dead letters as individual YAML files at `{dead_letter_dir}/{id}.yaml`.
+
+use std::path::PathBuf;
+
+use async_trait::async_trait;
+use tokio::fs;
+
+use crate::scheduler::DeadLetter;
+use crate::store::dead_letter::DeadLetterStore;
+use crate::store::error::{StorageError, StorageResult};
+
+/// File-based implementation of `DeadLetterStore`.
+#[derive(Debug, Clone)]
+pub struct FileDeadLetterStore {
+    dead_letter_dir: PathBuf,
+}
+
+impl FileDeadLetterStore {
+    /// Create a new file dead letter store.
+    pub fn new(dead_letter_dir: impl Into<PathBuf>) -> Self {
+        Self {
+            dead_letter_dir: dead_letter_dir.into(),
+        }
+    }
+
+    /// Get the file path for a dead letter.
+    fn dead_letter_path(&self, id: &str) -> PathBuf {
+        self.dead_letter_dir.join(format!("{}.yaml", id))
+    }
+
+    /// Ensure the dead letter directory exists.
+    async fn ensure_dir(&self) -> StorageResult<()> {
+        fs::create_dir_all(&self.dead_letter_dir)
+            .await
+            .map_err(|e| StorageError::file_io(&self.dead_letter_dir, e))
+    }
+}
+
+#[async_trait]
+impl DeadLetterStore for FileDeadLetterStore {
+    async fn list(&self) -> StorageResult<Vec<DeadLetter>> {
+        let mut dead_letters = Vec::new();
+
+        let mut entries = match fs::read_dir(&self.dead_letter_dir).await {
+            Ok(e) => e,
+            Err(e) if e.kind() == std::io::ErrorKind::NotFound => return Ok(Vec::new()),
+            Err(e) => return Err(StorageError::file_io(&self.dead_letter_dir, e)),
+        };
+
+        while let Some(entry) = entries
+            .next_entry()
+            .await
+            .map_err(|e| StorageError::file_io(&self.dead_letter_dir, e))?
+        {
+            let path = entry.path();
+            if path.extension().is_none_or(|ext| ext != "yaml") {
+                continue;
+            }
+
+            let content = match fs::read_to_string(&path).await {
+                Ok(c) => c,
+                Err(e) => {
+                    tracing::warn!(path = %path.display(), error = %e, "Failed to read dead letter");
+                    continue;
+                }
+            };
+
+            match serde_saphyr::from_str::<DeadLetter>(&content) {
+                Ok(dead_letter) => dead_letters.push(dead_letter),
+                Err(e) => {
+                    tracing::warn!(path = %path.display(), error = %e, "Failed to parse dead letter");
+                    continue;
+                }
+            }
+        }
+
+        dead_letters.sort_by_key(|d| d.failed_at);
+        Ok(dead_letters)
+    }
+
+    async fn load(&self, id: &str) -> StorageResult<Option<DeadLetter>> {
+        let path = self.dead_letter_path(id);
+
+        let content = match fs::read_to_string(&path).await {
+            Ok(c) => c,
+            Err(e) if e.kind() == std::io::ErrorKind::NotFound => return Ok(None),
+            Err(e) => return Err(StorageError::file_io(&path, e)),
+        };
+
+        let dead_letter: DeadLetter = serde_saphyr::from_str(&content)
+            .map_err(|e| StorageError::file_deserialization(&path, e.to_string()))?;
+
+        Ok(Some(dead_letter))
+    }
+
+    async fn save(&self, dead_letter: &DeadLetter) -> StorageResult<()> {
+        self.ensure_dir().await?;
+
+        let path = self.dead_letter_path(&dead_letter.id);
+
+        let content = serde_saphyr::to_string(dead_letter)
+            .map_err(|e| StorageError::serialization(e.to_string()))?;
+
+        super::atomic_write_file(&path, content.as_bytes()).await
+    }
+
+    async fn delete(&self, id: &str) -> StorageResult<()> {
+        let path = self.dead_letter_path(id);
+
+        match fs::remove_file(&path).await {
+            Ok(()) => Ok(()),
+            Err(e) if e.kind() == std::io::ErrorKind::NotFound => Ok(()),
+            Err(e) => Err(StorageError::file_io(&path, e)),
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::scheduler::{ScheduleDestination, SchedulePayload};
+    use chrono::Utc;
+    use tempfile::TempDir;
+
+    fn test_dead_letter(id: &str, minutes_ago: i64) -> DeadLetter {
+        let failed_at = Utc::now() - chrono::Duration::minutes(minutes_ago);
+        DeadLetter {
+            id: id.to_string(),
+            schedule_id: "sched_1".to_string(),
+            agent: "test-agent".to_string(),
+            created_by_session: "session_123".to_string(),
+            destination: ScheduleDestination {
+                gateway: "telegram".to_string(),
+                chat_id: "12345".to_string(),
+            },
+            payload: SchedulePayload::Task {
+                task: "Check status".to_string(),
+            },
+            retry: None,
+            attempts: 4,
+            error: "execution failed: provider unavailable".to_string(),
+            started_at: failed_at,
+            failed_at,
+        }
+    }
+
+    fn create_store(temp_dir: &TempDir) -> FileDeadLetterStore {
+        FileDeadLetterStore::new(temp_dir.path().join("dead-letter"))
+    }
+
+    #[tokio::test]
+    async fn list_orders_by_failure_time() {
+        let temp_dir = TempDir::new().unwrap();
+        let store = create_store(&temp_dir);
+
+        store.save(&test_dead_letter("dl_new", 1)).await.unwrap();
+        store.save(&test_dead_letter("dl_old", 30)).await.unwrap();
+
+        let dead_letters = store.list().await.unwrap();
+        let ids: Vec<_> = dead_letters.iter().map(|d| d.id.as_str()).collect();
+        assert_eq!(ids, ["dl_old", "dl_new"]);
+    }
+
+    #[tokio::test]
+    async fn list_empty() {
+        let temp_dir = TempDir::new().unwrap();
+        let store = create_store(&temp_dir);
+
+        assert!(store.list().await.unwrap().is_empty());
+    }
+
+    #[tokio::test]
+    async fn save_load_and_delete() {
+        let temp_dir = TempDir::new().unwrap();
+        let store = create_store(&temp_dir);
+
+        store.save(&test_dead_letter("dl_1", 5)).await.unwrap();
+        let loaded = store.load("dl_1").await.unwrap().unwrap();
+        assert_eq!(loaded.attempts, 4);
+        assert_eq!(loaded.schedule_id, "sched_1");
+
+        store.delete("dl_1").await.unwrap();
+        assert!(store.load("dl_1").await.unwrap().is_none());
+        store.delete("dl_1").await.unwrap();
+    }
+}
@@ -0,0 +1,118 @@
+//! File-based few-shot example storage implementation.
+//!
+//! Stores each example at `{examples_dir}/{pool}/{id}.json`.
+
+use std::path::PathBuf;
+
+use async_trait::async_trait;
+use tokio::fs;
+
+use super::is_valid_agent_name;
+use crate::examples::{EXAMPLE_ID_PREFIX, Example};
+use crate::store::error::{StorageError, StorageResult};
+use crate::store::example::ExampleStore;
+
+/// File-based implementation of `ExampleStore`.
+#[derive(Debug, Clone)]
+pub struct FileExampleStore {
+    dir: PathBuf,
+}
+
+impl FileExampleStore {
+    /// Create a new file example store.
+    pub fn new(dir: impl Into<PathBuf>) -> Self {
+        Self { dir: dir.into() }
+    }
+
+    /// Directory for a pool, or `None` for names that would escape the store.
+    fn pool_dir(&self, pool: &str) -> Option<PathBuf> {
+        is_valid_agent_name(pool).then(|| self.dir.join(pool))
+    }
+
+    /// Path for an example, or `None` for IDs that are not ours.
+    fn example_path(&self, pool: &str, id: &str) -> Option<PathBuf> {
+        let suffix = id.strip_prefix(EXAMPLE_ID_PREFIX)?;
+        let valid = !suffix.is_empty() && suffix.chars().all(|c| c.is_ascii_alphanumeric());
+        if !valid {
+            return None;
+        }
+        self.pool_dir(pool)
+            .map(|dir| dir.join(format!("{id}.json")))
+    }
+}
+
+#[async_trait]
+impl ExampleStore for FileExampleStore {
+    async fn list(&self, pool: &str) -> StorageResult<Vec<Example>> {
+        let Some(dir) = self.pool_dir(pool) else {
+            return Ok(Vec::new());
+        };
+        let mut entries = match fs::read_dir(&dir).await {
+            Ok(e) => e,
+            Err(e) if e.kind() == std::io::ErrorKind::NotFound => return Ok(Vec::new()),
+            Err(e) => return Err(StorageError::file_io(&dir, e)),
+        };
+
+        let mut examples = Vec::new();
+        while let Some(entry) = entries
+            .next_entry()
+            .await
+            .map_err(|e| StorageError::file_io(&dir, e))?
+        {
+            let path = entry.path();
+            if path.extension().is_none_or(|ext| ext != "json") {
+                continue;
+            }
+            let content = fs::read_to_string(&path)
+                .await
+                .map_err(|e| StorageError::file_io(&path, e))?;
+            let example: Example = serde_json::from_str(&content)
+                .map_err(|e| StorageError::file_deserialization(&path, e.to_string()))?;
+            examples.push(example);
+        }
+        // IDs are ULIDs, so they sort by creation time.
+        examples.sort_by(|a, b| a.id.cmp(&b.id));
+        Ok(examples)
+    }
+
+    async fn load(&self, pool: &str, id: &str) -> StorageResult<Option<Example>> {
+        let Some(path) = self.example_path(pool, id) else {
+            return Ok(None);
+        };
+        let content = match fs::read_to_string(&path).await {
+            Ok(c) => c,
+            Err(e) if e.kind() == std::io::ErrorKind::NotFound => return Ok(None),
+            Err(e) => return Err(StorageError::file_io(&path, e)),
+        };
+        serde_json::from_str(&content)
+            .map(Some)
+            .map_err(|e| StorageError::file_deserialization(&path, e.to_string()))
+    }
+
+    async fn save(&self, pool: &str, example: &Example) -> StorageResult<()> {
+        let path = self.example_path(pool, &example.id).ok_or_else(|| {
+            StorageError::serialization(format!(
+                "invalid example '{}' in pool '{pool}'",
+                example.id
+            ))
+        })?;
+        let dir = path.parent().expect("example path has a parent");
+        fs::create_dir_all(dir)
+            .await
+            .map_err(|e| StorageError::file_io(dir, e))?;
+        let content = serde_json::to_string_pretty(example)
+            .map_err(|e| StorageError::serialization(e.to_string()))?;
+        super::atomic_write_file(&path, content.as_bytes()).await
+    }
+
+    async fn delete(&self, pool: &str, id: &str) -> StorageResult<()> {
+        let Some(path) = self.example_path(pool, id) else {
+            return Ok(());
+        };
+        match fs::remove_file(&path).await {
+            Ok(()) => Ok(()),
+            Err(e) if e.kind() == std::io::ErrorKind::NotFound => Ok(()),
+            Err(e) => Err(StorageError::file_io(&path, e)),
+        }
+    }
+}
@@ -0,0 +1,180 @@
+//! File-based identity storage implementation.
+//!
+//! Stores each user at `{identity_dir}/users/{id}.json` and each group at
+//! `{identity_dir}/groups/{id}.json`.
+
+use std::path::{Path, PathBuf};
+
+use async_trait::async_trait;
+use serde::Serialize;
+use serde::de::DeserializeOwned;
+use tokio::fs;
+
+use crate::identity::{Group, User};
+use crate::store::error::{StorageError, StorageResult};
+use crate::store::identity::IdentityStore;
+
+/// File-based implementation of `IdentityStore`.
+#[derive(Debug, Clone)]
+pub struct FileIdentityStore {
+    identity_dir: PathBuf,
+}
+
+impl FileIdentityStore {
+    /// Create a new file identity store.
+    pub fn new(identity_dir: impl Into<PathBuf>) -> Self {
+        Self {
+            identity_dir: identity_dir.into(),
+        }
+    }
+
+    fn users_dir(&self) -> PathBuf {
+        self.identity_dir.join("users")
+    }
+
+    fn groups_dir(&self) -> PathBuf {
+        self.identity_dir.join("groups")
+    }
+}
+
+/// IDs are generated server-side, but reject anything that could escape the
+/// directory in case a client sends one in a URL.
+fn record_path(dir: &Path, id: &str) -> Option<PathBuf> {
+    let valid = !id.is_empty()
+        && id
+            .chars()
+            .all(|c| c.is_ascii_alphanumeric() || c == '_' || c == '-');
+    valid.then(|| dir.join(format!("{id}.json")))
+}
+
+async fn list_records<T: DeserializeOwned>(dir: &Path) -> StorageResult<Vec<T>> {
+    let mut entries = match fs::read_dir(dir).await {
+        Ok(e) => e,
+        Err(e) if e.kind() == std::io::ErrorKind::NotFound => return Ok(Vec::new()),
+        Err(e) => return Err(StorageError::file_io(dir, e)),
+    };
+
+    let mut records = Vec::new();
+    while let Some(entry) = entries
+        .next_entry()
+        .await
+        .map_err(|e| StorageError::file_io(dir, e))?
+    {
+        let path = entry.path();
+        if path.extension().is_none_or(|ext| ext != "json") {
+            continue;
+        }
+        match load_record(&path).await {
+            Ok(Some(record)) => records.push(record),
+            Ok(None) => {}
+            Err(e) => {
+                tracing::warn!(path = %path.display(), error = %e, "Failed to load identity record");
+            }
+        }
+    }
+    Ok(records)
+}
+
+async fn load_record<T: DeserializeOwned>(path: &Path) -> StorageResult<Option<T>> {
+    let content = match fs::read_to_string(path).await {
+        Ok(c) => c,
+        Err(e) if e.kind() == std::io::ErrorKind::NotFound => return Ok(None),
+        Err(e) => return Err(StorageError::file_io(path, e)),
+    };
+    serde_json::from_str(&content)
+        .map(Some)
+        .map_err(|e| StorageError::file_deserialization(path, e.to_string()))
+}
+
+async fn save_record<T: Serialize>(dir: &Path, id: &str, record: &T) -> StorageResult<()> {
+    let path = record_path(dir, id)
+        .ok_or_else(|| StorageError::serialization(format!("invalid record id '{id}'")))?;
+    fs::create_dir_all(dir)
+        .await
+        .map_err(|e| StorageError::file_io(dir, e))?;
+    let content = serde_json::to_string_pretty(record)
+        .map_err(|e| StorageError::serialization(e.to_string()))?;
+    super::atomic_write_file(&path, content.as_bytes()).await
+}
+
+async fn delete_record(dir: &Path, id: &str) -> StorageResult<()> {
+    let Some(path) = record_path(dir, id) else {
+        return Ok(());
+    };
+    match fs::remove_file(&path).await {
+        Ok(()) => Ok(()),
+        Err(e) if e.kind() == std::io::ErrorKind::NotFound => Ok(()),
+        Err(e) => Err(StorageError::file_io(&path, e)),
+    }
+}
+
+#[async_trait]
+impl IdentityStore for FileIdentityStore {
+    async fn list_users(&self) -> StorageResult<Vec<User>> {
+        let mut users: Vec<User> = list_records(&self.users_dir()).await?;
+        users.sort_by(|a, b| a.user_name.cmp(&b.user_name));
+        Ok(users)
+    }
+
+    async fn load_user(&self, id: &str) -> StorageResult<Option<User>> {
+        match record_path(&self.users_dir(), id) {
+            Some(path) => load_record(&path).await,
+            None => Ok(None),
+        }
+    }
+
+    async fn save_user(&self, user: &User) -> StorageResult<()> {
+        save_record(&self.users_dir(), &user.id, user).await
+    }
+
+    async fn delete_user(&self, id: &str) -> StorageResult<()> {
+        delete_record(&self.users_dir(), id).await
+    }
+
+    async fn list_groups(&self) -> StorageResult<Vec<Group>> {
+        let mut groups: Vec<Group> = list_records(&self.groups_dir()).await?;
+        groups.sort_by(|a, b| a.display_name.cmp(&b.display_name));
+        Ok(groups)
+    }
+
+    async fn load_group(&self, id: &str) -> StorageResult<Option<Group>> {
+        match record_path(&self.groups_dir(), id) {
+            Some(path) => load_record(&path).await,
+            None => Ok(None),
+        }
+    }
+
+    async fn save_group(&self, group: &Group) -> StorageResult<()> {
+        save_record(&self.groups_dir(), &group.id, group).await
+    }
+
+    async fn delete_group(&self, id: &str) -> StorageResult<()> {
+        delete_record(&self.groups_dir(), id).await
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use tempfile::TempDir;
+
+    #[tokio::test]
+    async fn save_list_and_delete() {
+        let temp_dir = TempDir::new().unwrap();
+        let store = FileIdentityStore::new(temp_dir.path());
+
+        let user = User::new("ada@example.com".to_string());
+        let mut group = Group::new("support".to_string());
+        group.add_member(&user.id);
+        store.save_user(&user).await.unwrap();
+        store.save_group(&group).await.unwrap();
+
+        assert_eq!(store.list_users().await.unwrap(), vec![user.clone()]);
+        assert_eq!(store.load_group(&group.id).await.unwrap(), Some(group));
+        assert!(store.load_user("../escape").await.unwrap().is_none());
+
+        store.delete_user(&user.id).await.unwrap();
+        assert!(store.load_user(&user.id).await.unwrap().is_none());
+        store.delete_user(&user.id).await.unwrap();
+    }
+}
@@ -0,0 +1,315 @@
+//! Embedded migrations for the on-disk store layout.
+//!
+//! Migrations are compiled into the binary and applied in version order.
+//! The workspace records the last applied version in `.schema-version`; a
+//! workspace without that file is at version 0.
+
+use std::path::{Path, PathBuf};
+
+use serde::Serialize;
+use tokio::fs;
+
+use super::atomic_write_file;
+use crate::store::error::{StorageError, StorageResult};
+
+/// File in the workspace holding the applied schema version.
+pub const SCHEMA_VERSION_FILE: &str = ".schema-version";
+
+/// Session snapshot file inside each session directory.
+const SNAPSHOT_FILE: &str = "state.json";
+
+// ============================================================================
+// Migration Catalog
+// ============================================================================
+
+#[derive(Debug, Clone, Copy)]
+enum Step {
+    /// Marks the layout that predates versioning. Nothing to change.
+    Baseline,
+    /// Session snapshots v1 -> v2 (`checkpoint_seq` replaces `last_event_seq`
+    /// as the replay start).
+    SessionSnapshotsV2,
+}
+
+struct Migration {
+    version: u32,
+    name: &'static str,
+    step: Step,
+}
+
+/// All migrations, in version order. Versions are contiguous from 1.
+const MIGRATIONS: &[Migration] = &[
+    Migration {
+        version: 1,
+        name: "baseline",
+        step: Step::Baseline,
+    },
+    Migration {
+        version: 2,
+        name: "session-snapshots-v2",
+        step: Step::SessionSnapshotsV2,
+    },
+];
+
+/// One migration and whether the workspace has it applied.
+#[derive(Debug, Clone, PartialEq, Eq, Serialize)]
+pub struct MigrationStatus {
+    pub version: u32,
+    pub name: &'static str,
+    pub applied: bool,
+}
+
+// ============================================================================
+// Migrator
+// ============================================================================
+
+/// Applies and reverts embedded migrations against a workspace.
+#[derive(Debug, Clone)]
+pub struct Migrator {
+    workspace: PathBuf,
+    sessions_dir: PathBuf,
+}
+
+impl Migrator {
+    pub fn new(workspace: impl Into<PathBuf>, sessions_dir: impl Into<PathBuf>) -> Self {
+        Self {
+            workspace: workspace.into(),
+            sessions_dir: sessions_dir.into(),
+        }
+    }
+
+    /// Newest version this binary knows about.
+    pub fn latest_version() -> u32 {
+        MIGRATIONS.last().map_or(0, |m| m.version)
+    }
+
+    /// Version currently applied to the workspace.
+    pub async fn current_version(&self) -> StorageResult<u32> {
+        let path = self.version_path();
+        match fs::read_to_string(&path).await {
+            Ok(contents) => contents.trim().parse().map_err(|_| {
+                StorageError::file_deserialization(&path, "expected a schema version number")
+            }),
+            Err(e) if e.kind() == std::io::ErrorKind::NotFound => Ok(0),
+            Err(e) => Err(StorageError::file_io(&path, e)),
+        }
+    }
+
+    /// Every known migration and whether it is applied.
+    pub async fn status(&self) -> StorageResult<Vec<MigrationStatus>> {
+        let current = self.current_version().await?;
+        Ok(MIGRATIONS
+            .iter()
+            .map(|m| MigrationStatus {
+                version: m.version,
+                name: m.name,
+                applied: m.version <= current,
+            })
+            .collect())
+    }
+
+    /// Apply pending migrations up to `target` (default: latest).
+    ///
+    /// Returns the migrations that were applied, in order.
+    pub async fn up(&self, target: Option<u32>) -> StorageResult<Vec<MigrationStatus>> {
+        let current = self.check_known(self.current_version().await?)?;
+        let target = target.unwrap_or_else(Self::latest_version);
+
+        let mut applied = Vec::new();
+        for m in MIGRATIONS
+            .iter()
+            .filter(|m| m.version > current && m.version <= target)
+        {
+            self.apply(m.step, Direction::Up).await?;
+            self.write_version(m.version).await?;
+            applied.push(MigrationStatus {
+                version: m.version,
+                name: m.name,
+                applied: true,
+            });
+        }
+        Ok(applied)
+    }
+
+    /// Revert applied migrations above `target` (default: the newest one).
+    ///
+    /// Returns the migrations that were reverted, newest first.
+    pub async fn down(&self, target: Option<u32>) -> StorageResult<Vec<MigrationStatus>> {
+        let current = self.check_known(self.current_version().await?)?;
+        let target = target.unwrap_or(current.saturating_sub(1));
+
+        let mut reverted = Vec::new();
+        for m in MIGRATIONS
+            .iter()
+            .rev()
+            .filter(|m| m.version <= current && m.version > target)
+        {
+            self.apply(m.step, Direction::Down).await?;
+            self.write_version(m.version - 1).await?;
+            reverted.push(MigrationStatus {
+                version: m.version,
+                name: m.name,
+                applied: false,
+            });
+        }
+        Ok(reverted)
+    }
+
+    /// Reject workspaces migrated by a newer binary.
+    fn check_known(&self, current: u32) -> StorageResult<u32> {
+        if current > Self::latest_version() {
+            return Err(StorageError::file_incompatible_schema(
+                self.version_path(),
+                Self::latest_version().to_string(),
+                current.to_string(),
+            ));
+        }
+        Ok(current)
+    }
+
+    fn version_path(&self) -> PathBuf {
+        self.workspace.join(SCHEMA_VERSION_FILE)
+    }
+
+    async fn write_version(&self, version: u32) -> StorageResult<()> {
+        fs::create_dir_all(&self.workspace)
+            .await
+            .map_err(|e| StorageError::file_io(&self.workspace, e))?;
+        atomic_write_file(&self.version_path(), format!("{version}\n").as_bytes()).await
+    }
+
+    async fn apply(&self, step: Step, direction: Direction) -> StorageResult<()> {
+        match step {
+            Step::Baseline => Ok(()),
+            Step::SessionSnapshotsV2 => {
+                for path in snapshot_paths(&self.sessions_dir).await? {
+                    rewrite_snapshot(&path, direction).await?;
+                }
+                Ok(())
+            }
+        }
+    }
+}
+
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+enum Direction {
+    Up,
+    Down,
+}
+
+// ============================================================================
+// Session Snapshots
+// ============================================================================
+
+async fn snapshot_paths(sessions_dir: &Path) -> StorageResult<Vec<PathBuf>> {
+    let mut entries = match fs::read_dir(sessions_dir).await {
+        Ok(entries) => entries,
+        Err(e) if e.kind() == std::io::ErrorKind::NotFound => return Ok(Vec::new()),
+        Err(e) => return Err(StorageError::file_io(sessions_dir, e)),
+    };
+
+    let mut paths = Vec::new();
+    while let Some(entry) = entries
+        .next_entry()
+        .await
+        .map_err(|e| StorageError::file_io(sessions_dir, e))?
+    {
+        let path = entry.path().join(SNAPSHOT_FILE);
+        if fs::try_exists(&path).await.unwrap_or(false) {
+            paths.push(path);
+        }
+    }
+    paths.sort();
+    Ok(paths)
+}
+
+/// Convert one snapshot between schema v1 and v2.
+///
+/// v1 replays events after `last_event_seq`; v2 replays after
+/// `checkpoint_seq`. Snapshots already at the target version are left alone.
+async fn rewrite_snapshot(path: &Path, direction: Direction) -> StorageResult<()> {
+    let contents = fs::read_to_string(path)
+        .await
+        .map_err(|e| StorageError::file_io(path, e))?;
+    let mut snapshot: serde_json::Value = serde_json::from_str(&contents)
+        .map_err(|e| StorageError::file_deserialization(path, e.to_string()))?;
+
+    let (from, to, seq_from, seq_to) = match direction {
+        Direction::Up => ("1", "2", "last_event_seq", "checkpoint_seq"),
+        Direction::Down => ("2", "1", "checkpoint_seq", "last_event_seq"),
+    };
+    if snapshot["schema_version"] != from {
+        return Ok(());
+    }
+
+    snapshot[seq_to] = snapshot[seq_from].clone();
+    snapshot["schema_version"] = to.into();
+
+    let json = serde_json::to_string_pretty(&snapshot)
+        .map_err(|e| StorageError::serialization(e.to_string()))?;
+    atomic_write_file(path, json.as_bytes()).await
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use tempfile::TempDir;
+
+    async fn write_snapshot(sessions_dir: &Path, id: &str, value: serde_json::Value) -> PathBuf {
+        let dir = sessions_dir.join(id);
+        fs::create_dir_all(&dir).await.unwrap();
+        let path = dir.join(SNAPSHOT_FILE);
+        fs::write(&path, value.to_string()).await.unwrap();
+        path
+    }
+
+    async fn read_snapshot(path: &Path) -> serde_json::Value {
+        serde_json::from_str(&fs::read_to_string(path).await.unwrap()).unwrap()
+    }
+
+    #[tokio::test]
+    async fn up_and_down_round_trip() {
+        let tmp = TempDir::new().unwrap();
+        let sessions = tmp.path().join("sessions");
+        let path = write_snapshot(
+            &sessions,
+            "session_a",
+            serde_json::json!({"schema_version": "1", "last_event_seq": 7, "checkpoint_seq": 0}),
+        )
+        .await;
+        let migrator = Migrator::new(tmp.path(), &sessions);
+
+        assert_eq!(migrator.current_version().await.unwrap(), 0);
+        let applied = migrator.up(None).await.unwrap();
+        assert_eq!(applied.len(), MIGRATIONS.len());
+        assert_eq!(
+            migrator.current_version().await.unwrap(),
+            Migrator::latest_version()
+        );
+
+        let snapshot = read_snapshot(&path).await;
+        assert_eq!(snapshot["schema_version"], "2");
+        assert_eq!(snapshot["checkpoint_seq"], 7);
+
+        // Nothing left to apply
+        assert!(migrator.up(None).await.unwrap().is_empty());
+
+        let reverted = migrator.down(None).await.unwrap();
+        assert_eq!(reverted[0].name, "session-snapshots-v2");
+        assert_eq!(migrator.current_version().await.unwrap(), 1);
+        assert_eq!(read_snapshot(&path).await["schema_version"], "1");
+    }
+
+    #[tokio::test]
+    async fn rejects_workspace_from_newer_binary() {
+        let tmp = TempDir::new().unwrap();
+        fs::write(tmp.path().join(SCHEMA_VERSION_FILE), "99\n")
+            .await
+            .unwrap();
+        let migrator = Migrator::new(tmp.path(), tmp.path().join("sessions"));
+
+        assert!(migrator.up(None).await.is_err());
+        assert!(migrator.down(None).await.is_err());
+        assert!(migrator.status().await.unwrap().iter().all(|m| m.applied));
+    }
+}
@@ -15,16 +15,47 @@ use tokio::io::AsyncWriteExt;
 use super::error::{StorageError, StorageResult};
 
 mod agent;
+mod archive;
+mod artifact;
+mod dead_letter;
+mod example;
+mod identity;
+mod migrations;
+mod model;
 mod policy;
+mod project;
+mod prompt;
+mod run;
 mod run_log;
 mod schedule;
+mod service_account;
 mod session;
+mod share;
+mod usage;
+mod user_fact;
 
-pub use agent::FileAgentCatalog;
+pub use agent::{
+    AgentChange, ChangeAuthor, DISABLED_FILE, FileAgentCatalog, PROVENANCE_FILE, TrashedAgent,
+    is_valid_agent_name,
+};
+pub use archive::FileSessionArchive;
+pub use artifact::FileArtifactStore;
+pub use dead_letter::FileDeadLetterStore;
+pub use example::FileExampleStore;
+pub use identity::FileIdentityStore;
+pub use migrations::{MigrationStatus, Migrator, SCHEMA_VERSION_FILE};
+pub use model::FileModelStore;
 pub use policy::FilePolicyStore;
+pub use project::FileProjectStore;
+pub use prompt::FilePromptStore;
+pub use run::FileRunStore;
 pub use run_log::FileRunLogStore;
 pub use schedule::FileScheduleStore;
+pub use service_account::FileServiceAccountStore;
 pub use session::FileSessionStore;
+pub use share::FileShareStore;
+pub use usage::FileUsageStore;
+pub use user_fact::FileUserFactStore;
 
 /// Write data to a temp file, fsync it, then atomically rename to the final path.
 ///
@@ -0,0 +1,125 @@
+//! File-based model registry storage implementation.
+//!
+//! Stores every override in one hand-editable YAML file, by default
+//! `{workspace}/models.yaml`:
+//!
+//! ```yaml
+//! models:
+//!   claude-sonnet-4-5:
+//!     pricing: { input_per_mtok: 3, output_per_mtok: 15 }
+//!   my-local-llama:
+//!     context_window: 32768
+//!     tool_calling: false
+//! ```
+
+use std::collections::BTreeMap;
+use std::path::PathBuf;
+
+use async_trait::async_trait;
+use serde::{Deserialize, Serialize};
+use tokio::fs;
+
+use crate::models::ModelOverride;
+use crate::store::error::{StorageError, StorageResult};
+use crate::store::model::ModelStore;
+
+#[derive(Default, Serialize, Deserialize)]
+struct ModelsFile {
+    #[serde(default)]
+    models: BTreeMap<String, ModelOverride>,
+}
+
+/// File-based implementation of `ModelStore`.
+#[derive(Debug, Clone)]
+pub struct FileModelStore {
+    path: PathBuf,
+}
+
+impl FileModelStore {
+    /// Create a new file model store.
+    pub fn new(path: impl Into<PathBuf>) -> Self {
+        Self { path: path.into() }
+    }
+}
+
+#[async_trait]
+impl ModelStore for FileModelStore {
+    async fn load(&self) -> StorageResult<BTreeMap<String, ModelOverride>> {
+        let content = match fs::read_to_string(&self.path).await {
+            Ok(c) => c,
+            Err(e) if e.kind() == std::io::ErrorKind::NotFound => return Ok(BTreeMap::new()),
+            Err(e) => return Err(StorageError::file_io(&self.path, e)),
+        };
+        if content.trim().is_empty() {
+            return Ok(BTreeMap::new());
+        }
+        let file: ModelsFile = serde_saphyr::from_str(&content)
+            .map_err(|e| StorageError::file_deserialization(&self.path, e.to_string()))?;
+        Ok(file.models)
+    }
+
+    async fn save(&self, overrides: &BTreeMap<String, ModelOverride>) -> StorageResult<()> {
+        if let Some(parent) = self.path.parent() {
+            fs::create_dir_all(parent)
+                .await
+                .map_err(|e| StorageError::file_io(parent, e))?;
+        }
+        let file = ModelsFile {
+            models: overrides.clone(),
+        };
+        let content = serde_saphyr::to_string(&file)
+            .map_err(|e| StorageError::serialization(e.to_string()))?;
+        super::atomic_write_file(&self.path, content.as_bytes()).await
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::api::ModelPricing;
+
+    #[tokio::test]
+    async fn missing_file_loads_empty() {
+        let tmp = tempfile::TempDir::new().unwrap();
+        let store = FileModelStore::new(tmp.path().join("models.yaml"));
+        assert!(store.load().await.unwrap().is_empty());
+    }
+
+    #[tokio::test]
+    async fn save_then_load_round_trips() {
+        let tmp = tempfile::TempDir::new().unwrap();
+        let store = FileModelStore::new(tmp.path().join("models.yaml"));
+        let mut overrides = BTreeMap::new();
+        overrides.insert(
+            "my-llama".to_string(),
+            ModelOverride {
+                context_window: Some(32_768),
+                tool_calling: Some(false),
+                pricing: Some(ModelPricing {
+                    input_per_mtok: 0.5,
+                    output_per_mtok: 1.5,
+                }),
+                ..Default::default()
+            },
+        );
+        store.save(&overrides).await.unwrap();
+        assert_eq!(store.load().await.unwrap(), overrides);
+    }
+
+    #[tokio::test]
+    async fn loads_hand_written_yaml() {
+        let tmp = tempfile::TempDir::new().unwrap();
+        let path = tmp.path().join("models.yaml");
+        fs::write(
+            &path,
+            "models:\n  gpt-4o:\n    pricing: { input_per_mtok: 2.5, output_per_mtok: 10 }\n",
+        )
+        .await
+        .unwrap();
+        let overrides = FileModelStore::new(path).load().await.unwrap();
+        assert_eq!(
+            overrides["gpt-4o"].pricing.map(|p| p.output_per_mtok),
+            Some(10.0)
+        );
+    }
+}
@@ -0,0 +1,177 @@
+//! File-based project storage implementation.
+//!
+//! Stores projects as individual YAML files at `{projects_dir}/{name}.yaml`.
+
+use std::path::PathBuf;
+
+use async_trait::async_trait;
+use tokio::fs;
+
+use crate::agent::Project;
+use crate::store::error::{StorageError, StorageResult};
+use crate::store::project::ProjectStore;
+
+/// File-based implementation of `ProjectStore`.
+#[derive(Debug, Clone)]
+pub struct FileProjectStore {
+    projects_dir: PathBuf,
+}
+
+impl FileProjectStore {
+    /// Create a new file project store.
+    pub fn new(projects_dir: impl Into<PathBuf>) -> Self {
+        Self {
+            projects_dir: projects_dir.into(),
+        }
+    }
+
+    /// Get the file path for a project.
+    fn project_path(&self, name: &str) -> PathBuf {
+        self.projects_dir.join(format!("{}.yaml", name))
+    }
+
+    /// Ensure the projects directory exists.
+    async fn ensure_dir(&self) -> StorageResult<()> {
+        fs::create_dir_all(&self.projects_dir)
+            .await
+            .map_err(|e| StorageError::file_io(&self.projects_dir, e))
+    }
+}
+
+#[async_trait]
+impl ProjectStore for FileProjectStore {
+    async fn list(&self) -> StorageResult<Vec<Project>> {
+        let mut projects = Vec::new();
+
+        let mut entries = match fs::read_dir(&self.projects_dir).await {
+            Ok(e) => e,
+            Err(e) if e.kind() == std::io::ErrorKind::NotFound => return Ok(Vec::new()),
+            Err(e) => return Err(StorageError::file_io(&self.projects_dir, e)),
+        };
+
+        while let Some(entry) = entries
+            .next_entry()
+            .await
+            .map_err(|e| StorageError::file_io(&self.projects_dir, e))?
+        {
+            let path = entry.path();
+            if path.extension().is_none_or(|ext| ext != "yaml") {
+                continue;
+            }
+            let Some(name) = path.file_stem().and_then(|s| s.to_str()) else {
+                continue;
+            };
+
+            match self.load(name).await {
+                Ok(Some(project)) => projects.push(project),
+                Ok(None) => {}
+                Err(e) => {
+                    tracing::warn!(path = %path.display(), error = %e, "Failed to load project");
+                }
+            }
+        }
+
+        projects.sort_by(|a, b| a.name.cmp(&b.name));
+        Ok(projects)
+    }
+
+    async fn load(&self, name: &str) -> StorageResult<Option<Project>> {
+        let path = self.project_path(name);
+
+        let content = match fs::read_to_string(&path).await {
+            Ok(c) => c,
+            Err(e) if e.kind() == std::io::ErrorKind::NotFound => return Ok(None),
+            Err(e) => return Err(StorageError::file_io(&path, e)),
+        };
+
+        let mut project: Project = serde_saphyr::from_str(&content)
+            .map_err(|e| StorageError::file_deserialization(&path, e.to_string()))?;
+        project.name = name.to_string();
+
+        Ok(Some(project))
+    }
+
+    async fn save(&self, project: &Project) -> StorageResult<()> {
+        self.ensure_dir().await?;
+
+        let path = self.project_path(&project.name);
+
+        let content = serde_saphyr::to_string(project)
+            .map_err(|e| StorageError::serialization(e.to_string()))?;
+
+        super::atomic_write_file(&path, content.as_bytes()).await
+    }
+
+    async fn delete(&self, name: &str) -> StorageResult<()> {
+        let path = self.project_path(name);
+
+        match fs::remove_file(&path).await {
+            Ok(()) => Ok(()),
+            Err(e) if e.kind() == std::io::ErrorKind::NotFound => Ok(()),
+            Err(e) => Err(StorageError::file_io(&path, e)),
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::agent::{ProjectBudget, ProjectModel};
+    use tempfile::TempDir;
+
+    fn test_project(name: &str) -> Project {
+        Project {
+            name: name.to_string(),
+            description: Some("Customer support agents".to_string()),
+            default_model: Some(ProjectModel {
+                provider: "anthropic".to_string(),
+                name: "claude-sonnet-4".to_string(),
+                temperature: None,
+                max_output_tokens: Some(2048),
+            }),
+            budget: ProjectBudget {
+                max_wall_time_seconds: Some(300),
+                max_tool_time_seconds: None,
+            },
+        }
+    }
+
+    fn create_store(temp_dir: &TempDir) -> FileProjectStore {
+        FileProjectStore::new(temp_dir.path().join("projects"))
+    }
+
+    #[tokio::test]
+    async fn save_load_and_delete() {
+        let temp_dir = TempDir::new().unwrap();
+        let store = create_store(&temp_dir);
+
+        store.save(&test_project("support")).await.unwrap();
+        let loaded = store.load("support").await.unwrap().unwrap();
+        assert_eq!(loaded.name, "support");
+        assert_eq!(loaded.default_model, test_project("support").default_model);
+        assert_eq!(loaded.budget.max_wall_time_seconds, Some(300));
+
+        store.delete("support").await.unwrap();
+        assert!(store.load("support").await.unwrap().is_none());
+        store.delete("support").await.unwrap();
+    }
+
+    #[tokio::test]
+    async fn list_sorted_by_name() {
+        let temp_dir = TempDir::new().unwrap();
+        let store = create_store(&temp_dir);
+        assert!(store.list().await.unwrap().is_empty());
+
+        store.save(&test_project("support")).await.unwrap();
+        store.save(&test_project("billing")).await.unwrap();
+
+        let names: Vec<_> = store
+            .list()
+            .await
+            .unwrap()
+            .into_iter()
+            .map(|p| p.name)
+            .collect();
+        assert_eq!(names, ["billing", "support"]);
+    }
+}
@@ -0,0 +1,84 @@
+//! File-based prompt library storage implementation.
+//!
+//! Stores each prompt, with every version, at `{prompts_dir}/{name}.json`.
+
+use std::path::PathBuf;
+
+use async_trait::async_trait;
+use tokio::fs;
+
+use super::is_valid_agent_name;
+use crate::prompts::Prompt;
+use crate::store::error::{StorageError, StorageResult};
+use crate::store::prompt::PromptStore;
+
+/// File-based implementation of `PromptStore`.
+#[derive(Debug, Clone)]
+pub struct FilePromptStore {
+    dir: PathBuf,
+}
+
+impl FilePromptStore {
+    /// Create a new file prompt store.
+    pub fn new(dir: impl Into<PathBuf>) -> Self {
+        Self { dir: dir.into() }
+    }
+
+    /// Path for a prompt, or `None` for names that would escape the store.
+    fn prompt_path(&self, name: &str) -> Option<PathBuf> {
+        is_valid_agent_name(name).then(|| self.dir.join(format!("{name}.json")))
+    }
+}
+
+#[async_trait]
+impl PromptStore for FilePromptStore {
+    async fn list(&self) -> StorageResult<Vec<Prompt>> {
+        let mut entries = match fs::read_dir(&self.dir).await {
+            Ok(e) => e,
+            Err(e) if e.kind() == std::io::ErrorKind::NotFound => return Ok(Vec::new()),
+            Err(e) => return Err(StorageError::file_io(&self.dir, e)),
+        };
+
+        let mut prompts = Vec::new();
+        while let Some(entry) = entries
+            .next_entry()
+            .await
+            .map_err(|e| StorageError::file_io(&self.dir, e))?
+        {
+            let path = entry.path();
+            if path.extension().is_none_or(|ext| ext != "json") {
+                continue;
+            }
+            let content = fs::read_to_string(&path)
+                .await
+                .map_err(|e| StorageError::file_io(&path, e))?;
+            let prompt: Prompt = serde_json::from_str(&content)
+                .map_err(|e| StorageError::file_deserialization(&path, e.to_string()))?;
+            prompts.push(prompt);
+        }
+        Ok(prompts)
+    }
+
+    async fn save(&self, prompt: &Prompt) -> StorageResult<()> {
+        let path = self.prompt_path(&prompt.name).ok_or_else(|| {
+            StorageError::serialization(format!("invalid prompt name '{}'", prompt.name))
+        })?;
+        fs::create_dir_all(&self.dir)
+            .await
+            .map_err(|e| StorageError::file_io(&self.dir, e))?;
+        let content = serde_json::to_string_pretty(prompt)
+            .map_err(|e| StorageError::serialization(e.to_string()))?;
+        super::atomic_write_file(&path, content.as_bytes()).await
+    }
+
+    async fn delete(&self, name: &str) -> StorageResult<()> {
+        let Some(path) = self.prompt_path(name) else {
+            return Ok(());
+        };
+        match fs::remove_file(&path).await {
+            Ok(()) => Ok(()),
+            Err(e) if e.kind() == std::io::ErrorKind::NotFound => Ok(()),
+            Err(e) => Err(StorageError::file_io(&path, e)),
+        }
+    }
+}
@@ -0,0 +1,164 @@
+//! File-based run storage implementation.
+//!
+//! Stores runs as individual JSON files at `{runs_dir}/{run_id}.json`.
+
+use std::path::PathBuf;
+
+use async_trait::async_trait;
+use tokio::fs;
+
+use crate::runs::Run;
+use crate::store::error::{StorageError, StorageResult};
+use crate::store::run::RunStore;
+
+/// File-based implementation of `RunStore`.
+#[derive(Debug, Clone)]
+pub struct FileRunStore {
+    runs_dir: PathBuf,
+}
+
+impl FileRunStore {
+    /// Create a new file run store.
+    pub fn new(runs_dir: impl Into<PathBuf>) -> Self {
+        Self {
+            runs_dir: runs_dir.into(),
+        }
+    }
+
+    /// Get the file path for a run.
+    fn run_path(&self, run_id: &str) -> PathBuf {
+        self.runs_dir.join(format!("{}.json", run_id))
+    }
+
+    /// Ensure the runs directory exists.
+    async fn ensure_dir(&self) -> StorageResult<()> {
+        fs::create_dir_all(&self.runs_dir)
+            .await
+            .map_err(|e| StorageError::file_io(&self.runs_dir, e))
+    }
+}
+
+#[async_trait]
+impl RunStore for FileRunStore {
+    async fn list(&self) -> StorageResult<Vec<Run>> {
+        let mut runs = Vec::new();
+
+        let mut entries = match fs::read_dir(&self.runs_dir).await {
+            Ok(e) => e,
+            Err(e) if e.kind() == std::io::ErrorKind::NotFound => return Ok(Vec::new()),
+            Err(e) => return Err(StorageError::file_io(&self.runs_dir, e)),
+        };
+
+        while let Some(entry) = entries
+            .next_entry()
+            .await
+            .map_err(|e| StorageError::file_io(&self.runs_dir, e))?
+        {
+            let path = entry.path();
+            if path.extension().is_none_or(|ext| ext != "json") {
+                continue;
+            }
+
+            let content = match fs::read(&path).await {
+                Ok(c) => c,
+                Err(e) => {
+                    tracing::warn!(path = %path.display(), error = %e, "Failed to read run");
+                    continue;
+                }
+            };
+
+            match serde_json::from_slice::<Run>(&content) {
+                Ok(run) => runs.push(run),
+                Err(e) => {
+                    tracing::warn!(path = %path.display(), error = %e, "Failed to parse run");
+                    continue;
+                }
+            }
+        }
+
+        runs.sort_by(|a, b| (a.created_at, &a.run_id).cmp(&(b.created_at, &b.run_id)));
+        Ok(runs)
+    }
+
+    async fn save(&self, run: &Run) -> StorageResult<()> {
+        self.ensure_dir().await?;
+
+        let path = self.run_path(&run.run_id);
+
+        let content =
+            serde_json::to_vec(run).map_err(|e| StorageError::serialization(e.to_string()))?;
+
+        super::atomic_write_file(&path, &content).await
+    }
+
+    async fn delete(&self, run_id: &str) -> StorageResult<()> {
+        let path = self.run_path(run_id);
+
+        match fs::remove_file(&path).await {
+            Ok(()) => Ok(()),
+            Err(e) if e.kind() == std::io::ErrorKind::NotFound => Ok(()),
+            Err(e) => Err(StorageError::file_io(&path, e)),
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::api::RunStatus;
+    use chrono::Utc;
+    use std::collections::HashMap;
+    use tempfile::TempDir;
+
+    fn test_run(run_id: &str, minutes_ago: i64) -> Run {
+        Run {
+            run_id: run_id.to_string(),
+            session_id: "session_1".to_string(),
+            labels: HashMap::from([("order_id".to_string(), "A-1".to_string())]),
+            status: RunStatus::Completed,
+            http_status: Some(200),
+            error_class: None,
+            result: Some(serde_json::json!({ "content": "done" })),
+            created_at: Utc::now() - chrono::Duration::minutes(minutes_ago),
+            finished_at: Some(Utc::now()),
+        }
+    }
+
+    fn create_store(temp_dir: &TempDir) -> FileRunStore {
+        FileRunStore::new(temp_dir.path().join("runs"))
+    }
+
+    #[tokio::test]
+    async fn list_orders_by_creation_time() {
+        let temp_dir = TempDir::new().unwrap();
+        let store = create_store(&temp_dir);
+        assert!(store.list().await.unwrap().is_empty());
+
+        store.save(&test_run("run_new", 1)).await.unwrap();
+        store.save(&test_run("run_old", 30)).await.unwrap();
+
+        let runs = store.list().await.unwrap();
+        let ids: Vec<_> = runs.iter().map(|r| r.run_id.as_str()).collect();
+        assert_eq!(ids, ["run_old", "run_new"]);
+        assert_eq!(runs[0].labels["order_id"], "A-1");
+        assert_eq!(runs[0].result.as_ref().unwrap()["content"], "done");
+    }
+
+    #[tokio::test]
+    async fn save_overwrites_and_delete_removes() {
+        let temp_dir = TempDir::new().unwrap();
+        let store = create_store(&temp_dir);
+
+        let mut run = test_run("run_1", 5);
+        store.save(&run).await.unwrap();
+        run.status = RunStatus::Failed;
+        store.save(&run).await.unwrap();
+        let runs = store.list().await.unwrap();
+        assert_eq!(runs.len(), 1);
+        assert_eq!(runs[0].status, RunStatus::Failed);
+
+        store.delete("run_1").await.unwrap();
+        assert!(store.list().await.unwrap().is_empty());
+        store.delete("run_1").await.unwrap();
+    }
+}
@@ -0,0 +1,86 @@
+//! File-based service account storage implementation.
+//!
+//! Stores each account at `{service_accounts_dir}/{id}.json`. Files hold token
+//! hashes, never tokens.
+
+use std::path::PathBuf;
+
+use async_trait::async_trait;
+use tokio::fs;
+
+use crate::service_accounts::{SERVICE_ACCOUNT_ID_PREFIX, ServiceAccount};
+use crate::store::error::{StorageError, StorageResult};
+use crate::store::service_account::ServiceAccountStore;
+
+/// File-based implementation of `ServiceAccountStore`.
+#[derive(Debug, Clone)]
+pub struct FileServiceAccountStore {
+    dir: PathBuf,
+}
+
+impl FileServiceAccountStore {
+    /// Create a new file service account store.
+    pub fn new(dir: impl Into<PathBuf>) -> Self {
+        Self { dir: dir.into() }
+    }
+
+    /// Path for an account, or `None` for IDs that are not ours.
+    fn account_path(&self, id: &str) -> Option<PathBuf> {
+        let suffix = id.strip_prefix(SERVICE_ACCOUNT_ID_PREFIX)?;
+        let valid = !suffix.is_empty() && suffix.chars().all(|c| c.is_ascii_alphanumeric());
+        valid.then(|| self.dir.join(format!("{id}.json")))
+    }
+}
+
+#[async_trait]
+impl ServiceAccountStore for FileServiceAccountStore {
+    async fn list(&self) -> StorageResult<Vec<ServiceAccount>> {
+        let mut entries = match fs::read_dir(&self.dir).await {
+            Ok(e) => e,
+            Err(e) if e.kind() == std::io::ErrorKind::NotFound => return Ok(Vec::new()),
+            Err(e) => return Err(StorageError::file_io(&self.dir, e)),
+        };
+
+        let mut accounts = Vec::new();
+        while let Some(entry) = entries
+            .next_entry()
+            .await
+            .map_err(|e| StorageError::file_io(&self.dir, e))?
+        {
+            let path = entry.path();
+            if path.extension().is_none_or(|ext| ext != "json") {
+                continue;
+            }
+            let content = fs::read_to_string(&path)
+                .await
+                .map_err(|e| StorageError::file_io(&path, e))?;
+            let account: ServiceAccount = serde_json::from_str(&content)
+                .map_err(|e| StorageError::file_deserialization(&path, e.to_string()))?;
+            accounts.push(account);
+        }
+        Ok(accounts)
+    }
+
+    async fn save(&self, account: &ServiceAccount) -> StorageResult<()> {
+        let path = self.account_path(&account.id).ok_or_else(|| {
+            StorageError::serialization(format!("invalid service account id '{}'", account.id))
+        })?;
+        fs::create_dir_all(&self.dir)
+            .await
+            .map_err(|e| StorageError::file_io(&self.dir, e))?;
+        let content = serde_json::to_string_pretty(account)
+            .map_err(|e| StorageError::serialization(e.to_string()))?;
+        super::atomic_write_file(&path, content.as_bytes()).await
+    }
+
+    async fn delete(&self, id: &str) -> StorageResult<()> {
+        let Some(path) = self.account_path(id) else {
+            return Ok(());
+        };
+        match fs::remove_file(&path).await {
+            Ok(()) => Ok(()),
+            Err(e) if e.kind() == std::io::ErrorKind::NotFound => Ok(()),
+            Err(e) => Err(StorageError::file_io(&path, e)),
+        }
+    }
+}
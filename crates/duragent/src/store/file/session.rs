@@ -9,15 +9,23 @@
 //!     events.jsonl       # Append-only event log
 //!     state.json         # Atomic snapshot
 //! ```
+//!
+//! With [`with_encryption`](FileSessionStore::with_encryption), every event
+//! line and the snapshot are sealed with the session's tenant data key (see
+//! [`crate::encryption`]). Unsealed records written before encryption was
+//! enabled are still read.
 
 use std::io::Write;
-use std::path::PathBuf;
+use std::path::{Path, PathBuf};
+use std::sync::Arc;
 
 use async_trait::async_trait;
+use dashmap::DashMap;
 use tokio::fs::{self, File};
 use tokio::io::{AsyncBufReadExt, BufReader};
 
-use crate::session::{SessionEvent, SessionSnapshot, SessionSnapshotEval};
+use crate::encryption::TenantKeys;
+use crate::session::{SessionEvent, SessionEventPayload, SessionSnapshot, SessionSnapshotEval};
 use crate::store::error::{StorageError, StorageResult};
 use crate::store::session::SessionStore;
 use crate::sync::KeyedLocks;
@@ -44,6 +52,9 @@ use crate::sync::KeyedLocks;
 pub struct FileSessionStore {
     sessions_dir: PathBuf,
     session_locks: KeyedLocks,
+    encryption: Option<TenantKeys>,
+    /// Tenant namespace per session, learned on first write.
+    namespaces: Arc<DashMap<String, String>>,
 }
 
 impl FileSessionStore {
@@ -54,8 +65,16 @@ impl FileSessionStore {
         Self {
             sessions_dir: sessions_dir.into(),
             session_locks: KeyedLocks::new(),
+            encryption: None,
+            namespaces: Arc::new(DashMap::new()),
         }
     }
+
+    /// Seal stored events and snapshots with per-tenant data keys.
+    pub fn with_encryption(mut self, keys: TenantKeys) -> Self {
+        self.encryption = Some(keys);
+        self
+    }
 }
 
 #[async_trait]
@@ -100,6 +119,7 @@ impl SessionStore for FileSessionStore {
 
     async fn delete(&self, session_id: &str) -> StorageResult<()> {
         let dir = self.session_dir(session_id);
+        self.namespaces.remove(session_id);
 
         match fs::remove_dir_all(&dir).await {
             Ok(()) => Ok(()),
@@ -138,9 +158,10 @@ impl SessionStore for FileSessionStore {
             if trimmed.is_empty() {
                 continue;
             }
+            let line = open_record(self.encryption.as_ref(), session_id, &path, trimmed)?;
 
             // Skip malformed lines (crash recovery)
-            let Ok(event) = serde_json::from_str::<SessionEvent>(trimmed) else {
+            let Ok(event) = serde_json::from_str::<SessionEvent>(&line) else {
                 continue;
             };
 
@@ -157,11 +178,17 @@ impl SessionStore for FileSessionStore {
             return Ok(());
         }
 
+        let namespace = match &self.encryption {
+            Some(keys) => Some(self.namespace(keys, session_id, events).await?),
+            None => None,
+        };
+
         // Serialize before acquiring the lock — no I/O, no contention
         let mut buffer = String::new();
         for event in events {
             let line = serde_json::to_string(event)
                 .map_err(|e| StorageError::serialization(e.to_string()))?;
+            let line = self.seal_record(namespace.as_deref(), session_id, line)?;
             buffer.push_str(&line);
             buffer.push('\n');
         }
@@ -203,6 +230,7 @@ impl SessionStore for FileSessionStore {
             Err(e) if e.kind() == std::io::ErrorKind::NotFound => return Ok(None),
             Err(e) => return Err(StorageError::file_io(&path, e)),
         };
+        let contents = open_record(self.encryption.as_ref(), session_id, &path, contents.trim())?;
 
         let snapshot: SessionSnapshot = serde_json::from_str(&contents)
             .map_err(|e| StorageError::file_deserialization(&path, e.to_string()))?;
@@ -229,6 +257,13 @@ impl SessionStore for FileSessionStore {
 
         let json = serde_json::to_string_pretty(snapshot)
             .map_err(|e| StorageError::serialization(e.to_string()))?;
+        let namespace = self.encryption.as_ref().map(|keys| {
+            self.namespaces
+                .entry(session_id.to_string())
+                .or_insert_with(|| keys.namespace_for_agent(&snapshot.agent))
+                .clone()
+        });
+        let json = self.seal_record(namespace.as_deref(), session_id, json)?;
 
         super::atomic_write_file(&final_path, json.as_bytes()).await
     }
@@ -251,6 +286,8 @@ impl SessionStore for FileSessionStore {
 
         // Single spawn_blocking for the entire read-modify-write cycle
         let path_clone = path.clone();
+        let encryption = self.encryption.clone();
+        let session_id = session_id.to_string();
         tokio::task::spawn_blocking(move || {
             let contents = match std::fs::read_to_string(&path_clone) {
                 Ok(c) => c,
@@ -266,8 +303,12 @@ impl SessionStore for FileSessionStore {
                 if trimmed.is_empty() {
                     continue;
                 }
-                match serde_json::from_str::<SessionEvent>(trimmed) {
-                    Ok(event) if event.seq <= up_to_seq => {
+                // Sealed lines are moved as-is, never re-encrypted
+                let event = open_record(encryption.as_ref(), &session_id, &path_clone, trimmed)
+                    .ok()
+                    .and_then(|json| serde_json::from_str::<SessionEvent>(&json).ok());
+                match event {
+                    Some(event) if event.seq <= up_to_seq => {
                         old_lines.push(line);
                     }
                     _ => {
@@ -338,6 +379,68 @@ impl FileSessionStore {
             .await
             .map_err(|e| StorageError::file_io(&dir, e))
     }
+
+    /// Tenant namespace of a session, from its start event or snapshot agent.
+    async fn namespace(
+        &self,
+        keys: &TenantKeys,
+        session_id: &str,
+        events: &[SessionEvent],
+    ) -> StorageResult<String> {
+        if let Some(namespace) = self.namespaces.get(session_id) {
+            return Ok(namespace.clone());
+        }
+
+        let agent = events.iter().find_map(|e| match &e.payload {
+            SessionEventPayload::SessionStart { agent, .. } => Some(agent.clone()),
+            _ => None,
+        });
+        let agent = match agent {
+            Some(agent) => agent,
+            None => match self.load_snapshot(session_id).await? {
+                Some(snapshot) => snapshot.agent,
+                None => String::new(),
+            },
+        };
+
+        let namespace = keys.namespace_for_agent(&agent);
+        self.namespaces
+            .insert(session_id.to_string(), namespace.clone());
+        Ok(namespace)
+    }
+
+    /// Seal a serialized record when encryption is enabled.
+    fn seal_record(
+        &self,
+        namespace: Option<&str>,
+        session_id: &str,
+        json: String,
+    ) -> StorageResult<String> {
+        match (&self.encryption, namespace) {
+            (Some(keys), Some(namespace)) => keys
+                .seal(namespace, session_id, json.as_bytes())
+                .map_err(|e| StorageError::serialization(e.to_string())),
+            _ => Ok(json),
+        }
+    }
+}
+
+/// Decrypt a stored record if it is sealed. Unsealed records pass through.
+fn open_record(
+    keys: Option<&TenantKeys>,
+    session_id: &str,
+    path: &Path,
+    record: &str,
+) -> StorageResult<String> {
+    let Some(keys) = keys else {
+        return Ok(record.to_string());
+    };
+    match keys.open(session_id, record) {
+        Ok(Some((_, plaintext))) => String::from_utf8(plaintext)
+            .map_err(|e| StorageError::file_deserialization(path, e.to_string())),
+        Ok(None) => Ok(record.to_string()),
+        Err(e) => Err(StorageError::file_deserialization(path, e.to_string())),
+    }
 }
 
 #[cfg(test)]
@@ -587,6 +690,60 @@ mod tests {
         assert_eq!(archive_lines.len(), 3);
     }
 
+    #[tokio::test]
+    async fn encrypted_sessions_are_sealed_on_disk() {
+        let temp_dir = TempDir::new().unwrap();
+        let keys = TenantKeys::new(
+            "AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8=",
+            temp_dir.path().join("keys"),
+            Arc::new(|_: &str| Some("acme".to_string())),
+        )
+        .unwrap();
+        let store = create_store(&temp_dir).with_encryption(keys);
+
+        let events = vec![
+            SessionEvent::new(
+                1,
+                SessionEventPayload::SessionStart {
+                    agent: "test-agent".to_string(),
+                    on_disconnect: OnDisconnect::Pause,
+                    gateway: None,
+                    gateway_chat_id: None,
+                },
+            ),
+            create_test_event(2, "top secret"),
+            create_test_event(3, "also secret"),
+        ];
+        store.append_events("session1", &events).await.unwrap();
+        store
+            .save_snapshot("session1", &create_test_snapshot("session1", 3))
+            .await
+            .unwrap();
+
+        let session_dir = temp_dir.path().join("sessions").join("session1");
+        let raw_events = std::fs::read_to_string(session_dir.join("events.jsonl")).unwrap();
+        let raw_snapshot = std::fs::read_to_string(session_dir.join("state.json")).unwrap();
+        assert!(!raw_events.contains("secret"));
+        assert!(!raw_snapshot.contains("Hello"));
+        assert!(temp_dir.path().join("keys").join("acme.json").exists());
+
+        let loaded = store.load_events("session1", 0).await.unwrap();
+        assert_eq!(loaded.len(), 3);
+        let snapshot = store.load_snapshot("session1").await.unwrap().unwrap();
+        assert_eq!(snapshot.last_event_seq, 3);
+
+        // Compaction keeps sealed lines readable
+        store.compact_events("session1", 2, true).await.unwrap();
+        let remaining = store.load_events("session1", 0).await.unwrap();
+        assert_eq!(remaining.len(), 1);
+        assert_eq!(remaining[0].seq, 3);
+
+        // Without the keys the records cannot be parsed
+        let plain = create_store(&temp_dir);
+        assert!(plain.load_events("session1", 0).await.unwrap().is_empty());
+        assert!(plain.load_snapshot("session1").await.is_err());
+    }
+
     #[tokio::test]
     async fn compact_events_nonexistent_session() {
         let temp_dir = TempDir::new().unwrap();
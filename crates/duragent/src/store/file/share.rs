@@ -0,0 +1,86 @@
+//! File-based share link storage implementation.
+//!
+//! Stores each share at `{shares_dir}/{id}.json`. Files hold token
+//! hashes, never tokens.
+
+use std::path::PathBuf;
+
+use async_trait::async_trait;
+use tokio::fs;
+
+use crate::shares::{SHARE_ID_PREFIX, Share};
+use crate::store::error::{StorageError, StorageResult};
+use crate::store::share::ShareStore;
+
+/// File-based implementation of `ShareStore`.
+#[derive(Debug, Clone)]
+pub struct FileShareStore {
+    dir: PathBuf,
+}
+
+impl FileShareStore {
+    /// Create a new file share store.
+    pub fn new(dir: impl Into<PathBuf>) -> Self {
+        Self { dir: dir.into() }
+    }
+
+    /// Path for a share, or `None` for IDs that are not ours.
+    fn share_path(&self, id: &str) -> Option<PathBuf> {
+        let suffix = id.strip_prefix(SHARE_ID_PREFIX)?;
+        let valid = !suffix.is_empty() && suffix.chars().all(|c| c.is_ascii_alphanumeric());
+        valid.then(|| self.dir.join(format!("{id}.json")))
+    }
+}
+
+#[async_trait]
+impl ShareStore for FileShareStore {
+    async fn list(&self) -> StorageResult<Vec<Share>> {
+        let mut entries = match fs::read_dir(&self.dir).await {
+            Ok(e) => e,
+            Err(e) if e.kind() == std::io::ErrorKind::NotFound => return Ok(Vec::new()),
+            Err(e) => return Err(StorageError::file_io(&self.dir, e)),
+        };
+
+        let mut shares = Vec::new();
+        while let Some(entry) = entries
+            .next_entry()
+            .await
+            .map_err(|e| StorageError::file_io(&self.dir, e))?
+        {
+            let path = entry.path();
+            if path.extension().is_none_or(|ext| ext != "json") {
+                continue;
+            }
+            let content = fs::read_to_string(&path)
+                .await
+                .map_err(|e| StorageError::file_io(&path, e))?;
+            let share: Share = serde_json::from_str(&content)
+                .map_err(|e| StorageError::file_deserialization(&path, e.to_string()))?;
+            shares.push(share);
+        }
+        Ok(shares)
+    }
+
+    async fn save(&self, share: &Share) -> StorageResult<()> {
+        let path = self.share_path(&share.id).ok_or_else(|| {
+            StorageError::serialization(format!("invalid share id '{}'", share.id))
+        })?;
+        fs::create_dir_all(&self.dir)
+            .await
+            .map_err(|e| StorageError::file_io(&self.dir, e))?;
+        let content = serde_json::to_string_pretty(share)
+            .map_err(|e| StorageError::serialization(e.to_string()))?;
+        super::atomic_write_file(&path, content.as_bytes()).await
+    }
+
+    async fn delete(&self, id: &str) -> StorageResult<()> {
+        let Some(path) = self.share_path(id) else {
+            return Ok(());
+        };
+        match fs::remove_file(&path).await {
+            Ok(()) => Ok(()),
+            Err(e) if e.kind() == std::io::ErrorKind::NotFound => Ok(()),
+            Err(e) => Err(StorageError::file_io(&path, e)),
+        }
+    }
+}
@@ -0,0 +1,231 @@
+//! File-based usage rollup storage implementation.
+//!
+//! Stores hourly rollups as one JSONL file per day at
+//! `{usage_dir}/hourly/{YYYY-MM-DD}.jsonl` and daily rollups as one file per
+//! month at `{usage_dir}/daily/{YYYY-MM}.jsonl`. Each line is one bucket.
+
+use std::path::{Path, PathBuf};
+use std::sync::Arc;
+
+use async_trait::async_trait;
+use chrono::{DateTime, TimeDelta, Utc};
+use tokio::fs;
+
+use crate::store::error::{StorageError, StorageResult};
+use crate::store::usage::UsageStore;
+use crate::sync::KeyedLocks;
+use crate::usage::{Granularity, UsageBucket, merge_buckets};
+
+/// File-based implementation of `UsageStore`.
+#[derive(Clone)]
+pub struct FileUsageStore {
+    usage_dir: PathBuf,
+    /// Per-file locks to serialize merges.
+    locks: Arc<KeyedLocks>,
+}
+
+impl FileUsageStore {
+    /// Create a new file usage store.
+    pub fn new(usage_dir: impl Into<PathBuf>) -> Self {
+        Self {
+            usage_dir: usage_dir.into(),
+            locks: Arc::new(KeyedLocks::new()),
+        }
+    }
+
+    fn granularity_dir(&self, granularity: Granularity) -> PathBuf {
+        self.usage_dir.join(match granularity {
+            Granularity::Hour => "hourly",
+            Granularity::Day => "daily",
+        })
+    }
+
+    fn partition_path(&self, granularity: Granularity, partition: &str) -> PathBuf {
+        self.granularity_dir(granularity)
+            .join(format!("{partition}.jsonl"))
+    }
+
+    /// List stored partitions, sorted.
+    async fn partitions(&self, granularity: Granularity) -> StorageResult<Vec<String>> {
+        let dir = self.granularity_dir(granularity);
+        let mut entries = match fs::read_dir(&dir).await {
+            Ok(e) => e,
+            Err(e) if e.kind() == std::io::ErrorKind::NotFound => return Ok(Vec::new()),
+            Err(e) => return Err(StorageError::file_io(&dir, e)),
+        };
+
+        let mut partitions = Vec::new();
+        while let Some(entry) = entries
+            .next_entry()
+            .await
+            .map_err(|e| StorageError::file_io(&dir, e))?
+        {
+            let path = entry.path();
+            if path.extension().is_some_and(|ext| ext == "jsonl")
+                && let Some(stem) = path.file_stem().and_then(|s| s.to_str())
+            {
+                partitions.push(stem.to_string());
+            }
+        }
+        partitions.sort();
+        Ok(partitions)
+    }
+}
+
+/// File a bucket starting at `start` belongs to. Sorts in time order.
+fn partition(granularity: Granularity, start: DateTime<Utc>) -> String {
+    match granularity {
+        Granularity::Hour => start.format("%Y-%m-%d").to_string(),
+        Granularity::Day => start.format("%Y-%m").to_string(),
+    }
+}
+
+async fn read_buckets(path: &Path) -> StorageResult<Vec<UsageBucket>> {
+    let content = match fs::read_to_string(path).await {
+        Ok(c) => c,
+        Err(e) if e.kind() == std::io::ErrorKind::NotFound => return Ok(Vec::new()),
+        Err(e) => return Err(StorageError::file_io(path, e)),
+    };
+
+    content
+        .lines()
+        .filter(|line| !line.trim().is_empty())
+        .map(|line| {
+            serde_json::from_str(line)
+                .map_err(|e| StorageError::file_deserialization(path, e.to_string()))
+        })
+        .collect()
+}
+
+#[async_trait]
+impl UsageStore for FileUsageStore {
+    async fn load(
+        &self,
+        granularity: Granularity,
+        from: DateTime<Utc>,
+        to: DateTime<Utc>,
+    ) -> StorageResult<Vec<UsageBucket>> {
+        if to <= from {
+            return Ok(Vec::new());
+        }
+        let first = partition(granularity, from);
+        let last = partition(granularity, to - TimeDelta::nanoseconds(1));
+
+        let mut buckets = Vec::new();
+        for name in self.partitions(granularity).await? {
+            if name < first || name > last {
+                continue;
+            }
+            let path = self.partition_path(granularity, &name);
+            buckets.extend(
+                read_buckets(&path)
+                    .await?
+                    .into_iter()
+                    .filter(|b| b.start >= from && b.start < to),
+            );
+        }
+        Ok(merge_buckets(buckets))
+    }
+
+    async fn merge(&self, granularity: Granularity, buckets: &[UsageBucket]) -> StorageResult<()> {
+        let dir = self.granularity_dir(granularity);
+        fs::create_dir_all(&dir)
+            .await
+            .map_err(|e| StorageError::file_io(&dir, e))?;
+
+        let mut by_partition: Vec<(String, Vec<UsageBucket>)> = Vec::new();
+        for bucket in buckets {
+            let name = partition(granularity, bucket.start);
+            match by_partition.iter_mut().find(|(p, _)| *p == name) {
+                Some((_, group)) => group.push(bucket.clone()),
+                None => by_partition.push((name, vec![bucket.clone()])),
+            }
+        }
+
+        for (name, group) in by_partition {
+            let path = self.partition_path(granularity, &name);
+            let lock = self.locks.get(&path.to_string_lossy());
+            let _guard = lock.lock().await;
+
+            let existing = read_buckets(&path).await?;
+            let mut content = String::new();
+            for bucket in merge_buckets(existing.into_iter().chain(group)) {
+                let line = serde_json::to_string(&bucket)
+                    .map_err(|e| StorageError::serialization(e.to_string()))?;
+                content.push_str(&line);
+                content.push('\n');
+            }
+            super::atomic_write_file(&path, content.as_bytes()).await?;
+        }
+        Ok(())
+    }
+
+    async fn prune(&self, granularity: Granularity, before: DateTime<Utc>) -> StorageResult<usize> {
+        let cutoff = partition(granularity, before);
+        let mut removed = 0;
+        for name in self.partitions(granularity).await? {
+            if name >= cutoff {
+                break;
+            }
+            let path = self.partition_path(granularity, &name);
+            match fs::remove_file(&path).await {
+                Ok(()) => removed += 1,
+                Err(e) if e.kind() == std::io::ErrorKind::NotFound => {}
+                Err(e) => return Err(StorageError::file_io(&path, e)),
+            }
+        }
+        Ok(removed)
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::usage::UsageTotals;
+    use tempfile::TempDir;
+
+    fn at(s: &str) -> DateTime<Utc> {
+        DateTime::parse_from_rfc3339(s).unwrap().with_timezone(&Utc)
+    }
+
+    fn bucket(start: &str, agent: &str, responses: u64) -> UsageBucket {
+        UsageBucket {
+            start: at(start),
+            agent: agent.to_string(),
+            totals: UsageTotals {
+                responses,
+                ..Default::default()
+            },
+        }
+    }
+
+    #[tokio::test]
+    async fn prune_removes_whole_days_before_cutoff() {
+        let tmp = TempDir::new().unwrap();
+        let store = FileUsageStore::new(tmp.path());
+        store
+            .merge(
+                Granularity::Hour,
+                &[
+                    bucket("2026-03-01T10:00:00Z", "a", 1),
+                    bucket("2026-03-02T10:00:00Z", "a", 1),
+                    bucket("2026-03-03T10:00:00Z", "a", 1),
+                ],
+            )
+            .await
+            .unwrap();
+
+        let cutoff = at("2026-03-02T12:00:00Z");
+        assert_eq!(store.prune(Granularity::Hour, cutoff).await.unwrap(), 1);
+
+        let left = store
+            .load(
+                Granularity::Hour,
+                at("2026-03-01T00:00:00Z"),
+                at("2026-03-04T00:00:00Z"),
+            )
+            .await
+            .unwrap();
+        assert_eq!(left.len(), 2);
+    }
+}
@@ -0,0 +1,126 @@
+//! File-based user memory storage implementation.
+//!
+//! Stores each fact at `{user_memory_dir}/{user}/{id}.json`.
+
+use std::path::PathBuf;
+
+use async_trait::async_trait;
+use tokio::fs;
+
+use super::is_valid_agent_name;
+use crate::store::error::{StorageError, StorageResult};
+use crate::store::user_fact::UserFactStore;
+use crate::user_memory::{FACT_ID_PREFIX, UserFact};
+
+/// File-based implementation of `UserFactStore`.
+#[derive(Debug, Clone)]
+pub struct FileUserFactStore {
+    dir: PathBuf,
+}
+
+impl FileUserFactStore {
+    /// Create a new file user fact store.
+    pub fn new(dir: impl Into<PathBuf>) -> Self {
+        Self { dir: dir.into() }
+    }
+
+    /// Directory for a user, or `None` for IDs that would escape the store.
+    fn user_dir(&self, user: &str) -> Option<PathBuf> {
+        is_valid_agent_name(user).then(|| self.dir.join(user))
+    }
+
+    /// Path for a fact, or `None` for IDs that are not ours.
+    fn fact_path(&self, user: &str, id: &str) -> Option<PathBuf> {
+        let suffix = id.strip_prefix(FACT_ID_PREFIX)?;
+        let valid = !suffix.is_empty() && suffix.chars().all(|c| c.is_ascii_alphanumeric());
+        if !valid {
+            return None;
+        }
+        self.user_dir(user)
+            .map(|dir| dir.join(format!("{id}.json")))
+    }
+}
+
+#[async_trait]
+impl UserFactStore for FileUserFactStore {
+    async fn list(&self, user: &str) -> StorageResult<Vec<UserFact>> {
+        let Some(dir) = self.user_dir(user) else {
+            return Ok(Vec::new());
+        };
+        let mut entries = match fs::read_dir(&dir).await {
+            Ok(e) => e,
+            Err(e) if e.kind() == std::io::ErrorKind::NotFound => return Ok(Vec::new()),
+            Err(e) => return Err(StorageError::file_io(&dir, e)),
+        };
+
+        let mut facts = Vec::new();
+        while let Some(entry) = entries
+            .next_entry()
+            .await
+            .map_err(|e| StorageError::file_io(&dir, e))?
+        {
+            let path = entry.path();
+            if path.extension().is_none_or(|ext| ext != "json") {
+                continue;
+            }
+            let content = fs::read_to_string(&path)
+                .await
+                .map_err(|e| StorageError::file_io(&path, e))?;
+            let fact: UserFact = serde_json::from_str(&content)
+                .map_err(|e| StorageError::file_deserialization(&path, e.to_string()))?;
+            facts.push(fact);
+        }
+        // IDs are ULIDs, so they sort by creation time.
+        facts.sort_by(|a, b| a.id.cmp(&b.id));
+        Ok(facts)
+    }
+
+    async fn load(&self, user: &str, id: &str) -> StorageResult<Option<UserFact>> {
+        let Some(path) = self.fact_path(user, id) else {
+            return Ok(None);
+        };
+        let content = match fs::read_to_string(&path).await {
+            Ok(c) => c,
+            Err(e) if e.kind() == std::io::ErrorKind::NotFound => return Ok(None),
+            Err(e) => return Err(StorageError::file_io(&path, e)),
+        };
+        serde_json::from_str(&content)
+            .map(Some)
+            .map_err(|e| StorageError::file_deserialization(&path, e.to_string()))
+    }
+
+    async fn save(&self, user: &str, fact: &UserFact) -> StorageResult<()> {
+        let path = self.fact_path(user, &fact.id).ok_or_else(|| {
+            StorageError::serialization(format!("invalid fact '{}' for user '{user}'", fact.id))
+        })?;
+        let dir = path.parent().expect("fact path has a parent");
+        fs::create_dir_all(dir)
+            .await
+            .map_err(|e| StorageError::file_io(dir, e))?;
+        let content = serde_json::to_string_pretty(fact)
+            .map_err(|e| StorageError::serialization(e.to_string()))?;
+        super::atomic_write_file(&path, content.as_bytes()).await
+    }
+
+    async fn delete(&self, user: &str, id: &str) -> StorageResult<()> {
+        let Some(path) = self.fact_path(user, id) else {
+            return Ok(());
+        };
+        match fs::remove_file(&path).await {
+            Ok(()) => Ok(()),
+            Err(e) if e.kind() == std::io::ErrorKind::NotFound => Ok(()),
+            Err(e) => Err(StorageError::file_io(&path, e)),
+        }
+    }
+
+    async fn delete_all(&self, user: &str) -> StorageResult<()> {
+        let Some(dir) = self.user_dir(user) else {
+            return Ok(());
+        };
+        match fs::remove_dir_all(&dir).await {
+            Ok(()) => Ok(()),
+            Err(e) if e.kind() == std::io::ErrorKind::NotFound => Ok(()),
+            Err(e) => Err(StorageError::file_io(&dir, e)),
+        }
+    }
+}
@@ -0,0 +1,41 @@
+//! Identity storage trait.
+//!
+//! Defines the interface for persisting provisioned users and groups.
+
+use async_trait::async_trait;
+
+use crate::identity::{Group, User};
+
+use super::error::StorageResult;
+
+/// Storage interface for provisioned users and groups.
+#[async_trait]
+pub trait IdentityStore: Send + Sync {
+    /// List all users, sorted by user name.
+    async fn list_users(&self) -> StorageResult<Vec<User>>;
+
+    /// Load a user by ID.
+    ///
+    /// Returns `Ok(None)` if the user doesn't exist.
+    async fn load_user(&self, id: &str) -> StorageResult<Option<User>>;
+
+    /// Create or update a user (upsert semantics).
+    async fn save_user(&self, user: &User) -> StorageResult<()>;
+
+    /// Delete a user. No-op if the user doesn't exist.
+    async fn delete_user(&self, id: &str) -> StorageResult<()>;
+
+    /// List all groups, sorted by display name.
+    async fn list_groups(&self) -> StorageResult<Vec<Group>>;
+
+    /// Load a group by ID.
+    ///
+    /// Returns `Ok(None)` if the group doesn't exist.
+    async fn load_group(&self, id: &str) -> StorageResult<Option<Group>>;
+
+    /// Create or update a group (upsert semantics).
+    async fn save_group(&self, group: &Group) -> StorageResult<()>;
+
+    /// Delete a group. No-op if the group doesn't exist.
+    async fn delete_group(&self, id: &str) -> StorageResult<()>;
+}
@@ -42,17 +42,44 @@
 pub mod error;
 
 mod agent;
+mod archive;
+mod artifact;
+mod dead_letter;
+mod example;
+mod identity;
+mod model;
 mod policy;
+mod project;
+mod prompt;
+mod run;
 mod run_log;
 mod schedule;
+mod service_account;
 mod session;
+mod share;
+mod usage;
+mod user_fact;
 
 pub mod file;
+pub mod s3;
 
 // Re-export traits
 pub use agent::{AgentCatalog, AgentScanResult, ScanWarning};
+pub use archive::SessionArchive;
+pub use artifact::ArtifactStore;
+pub use dead_letter::DeadLetterStore;
 pub use error::{StorageError, StorageResult};
+pub use example::ExampleStore;
+pub use identity::IdentityStore;
+pub use model::ModelStore;
 pub use policy::PolicyStore;
+pub use project::ProjectStore;
+pub use prompt::PromptStore;
+pub use run::RunStore;
 pub use run_log::RunLogStore;
 pub use schedule::ScheduleStore;
+pub use service_account::ServiceAccountStore;
 pub use session::SessionStore;
+pub use share::ShareStore;
+pub use usage::UsageStore;
+pub use user_fact::UserFactStore;
@@ -0,0 +1,23 @@
+//! Model registry storage trait.
+//!
+//! Defines the interface for persisting model capability overrides.
+
+use std::collections::BTreeMap;
+
+use async_trait::async_trait;
+
+use crate::models::ModelOverride;
+
+use super::error::StorageResult;
+
+/// Storage interface for model capability overrides, keyed by name pattern.
+#[async_trait]
+pub trait ModelStore: Send + Sync {
+    /// Load all overrides. Empty if none have been saved.
+    async fn load(&self) -> StorageResult<BTreeMap<String, ModelOverride>>;
+
+    /// Replace all overrides.
+    ///
+    /// Must be atomic - either fully succeeds or has no effect.
+    async fn save(&self, overrides: &BTreeMap<String, ModelOverride>) -> StorageResult<()>;
+}
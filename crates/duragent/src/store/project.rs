@@ -0,0 +1,31 @@
+//! Project storage trait.
+//!
+//! Defines the interface for persisting project definitions.
+
+use async_trait::async_trait;
+
+use crate::agent::Project;
+
+use super::error::StorageResult;
+
+/// Storage interface for projects.
+#[async_trait]
+pub trait ProjectStore: Send + Sync {
+    /// List all projects, sorted by name.
+    async fn list(&self) -> StorageResult<Vec<Project>>;
+
+    /// Load a project by name.
+    ///
+    /// Returns `Ok(None)` if the project doesn't exist.
+    async fn load(&self, name: &str) -> StorageResult<Option<Project>>;
+
+    /// Create or update a project (upsert semantics).
+    ///
+    /// Must be atomic - either fully succeeds or has no effect.
+    async fn save(&self, project: &Project) -> StorageResult<()>;
+
+    /// Delete a project.
+    ///
+    /// No-op if the project doesn't exist.
+    async fn delete(&self, name: &str) -> StorageResult<()>;
+}
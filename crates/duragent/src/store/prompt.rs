@@ -0,0 +1,26 @@
+//! Prompt library storage trait.
+//!
+//! Defines the interface for persisting shared prompts and their versions.
+
+use async_trait::async_trait;
+
+use crate::prompts::Prompt;
+
+use super::error::StorageResult;
+
+/// Storage interface for the prompt library.
+#[async_trait]
+pub trait PromptStore: Send + Sync {
+    /// List all prompts.
+    async fn list(&self) -> StorageResult<Vec<Prompt>>;
+
+    /// Create or update a prompt with all of its versions (upsert semantics).
+    ///
+    /// Must be atomic - either fully succeeds or has no effect.
+    async fn save(&self, prompt: &Prompt) -> StorageResult<()>;
+
+    /// Delete a prompt and its versions.
+    ///
+    /// No-op if the prompt doesn't exist.
+    async fn delete(&self, name: &str) -> StorageResult<()>;
+}
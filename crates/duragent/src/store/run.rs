@@ -0,0 +1,27 @@
+//! Run storage trait.
+//!
+//! Defines the interface for persisting message runs so they outlive a
+//! restart.
+
+use async_trait::async_trait;
+
+use crate::runs::Run;
+
+use super::error::StorageResult;
+
+/// Storage interface for message runs.
+#[async_trait]
+pub trait RunStore: Send + Sync {
+    /// List all runs, oldest first.
+    async fn list(&self) -> StorageResult<Vec<Run>>;
+
+    /// Create or update a run (upsert semantics).
+    ///
+    /// Must be atomic - either fully succeeds or has no effect.
+    async fn save(&self, run: &Run) -> StorageResult<()>;
+
+    /// Delete a run.
+    ///
+    /// No-op if the run doesn't exist.
+    async fn delete(&self, run_id: &str) -> StorageResult<()>;
+}
@@ -0,0 +1,43 @@
+//! S3 session archive implementation.
+//!
+//! Stores each archived session as `{prefix}{session_id}.jsonl.gz` in the
+//! configured bucket.
+
+use async_trait::async_trait;
+
+use super::S3Client;
+use crate::config::S3ArchiveConfig;
+use crate::store::archive::SessionArchive;
+use crate::store::error::StorageResult;
+
+/// S3 implementation of `SessionArchive`.
+#[derive(Debug, Clone)]
+pub struct S3SessionArchive {
+    client: S3Client,
+    prefix: String,
+}
+
+impl S3SessionArchive {
+    /// Create a new S3 session archive using `http` for requests.
+    pub fn new(http: reqwest::Client, config: &S3ArchiveConfig) -> StorageResult<Self> {
+        Ok(Self {
+            client: S3Client::new(http, config)?,
+            prefix: config.prefix.clone(),
+        })
+    }
+
+    fn key(&self, session_id: &str) -> String {
+        format!("{}{session_id}.jsonl.gz", self.prefix)
+    }
+}
+
+#[async_trait]
+impl SessionArchive for S3SessionArchive {
+    async fn load(&self, session_id: &str) -> StorageResult<Option<Vec<u8>>> {
+        self.client.get_object(&self.key(session_id)).await
+    }
+
+    async fn save(&self, session_id: &str, data: &[u8]) -> StorageResult<()> {
+        self.client.put_object(&self.key(session_id), data).await
+    }
+}
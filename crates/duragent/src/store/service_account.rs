@@ -0,0 +1,26 @@
+//! Service account storage trait.
+//!
+//! Defines the interface for persisting service accounts.
+
+use async_trait::async_trait;
+
+use crate::service_accounts::ServiceAccount;
+
+use super::error::StorageResult;
+
+/// Storage interface for service accounts.
+#[async_trait]
+pub trait ServiceAccountStore: Send + Sync {
+    /// List all service accounts.
+    async fn list(&self) -> StorageResult<Vec<ServiceAccount>>;
+
+    /// Create or update a service account (upsert semantics).
+    ///
+    /// Must be atomic - either fully succeeds or has no effect.
+    async fn save(&self, account: &ServiceAccount) -> StorageResult<()>;
+
+    /// Delete a service account.
+    ///
+    /// No-op if the account doesn't exist.
+    async fn delete(&self, id: &str) -> StorageResult<()>;
+}
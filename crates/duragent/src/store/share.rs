@@ -0,0 +1,26 @@
+//! Share link storage trait.
+//!
+//! Defines the interface for persisting session share links.
+
+use async_trait::async_trait;
+
+use crate::shares::Share;
+
+use super::error::StorageResult;
+
+/// Storage interface for session share links.
+#[async_trait]
+pub trait ShareStore: Send + Sync {
+    /// List all shares, including expired ones.
+    async fn list(&self) -> StorageResult<Vec<Share>>;
+
+    /// Create or update a share (upsert semantics).
+    ///
+    /// Must be atomic - either fully succeeds or has no effect.
+    async fn save(&self, share: &Share) -> StorageResult<()>;
+
+    /// Delete a share.
+    ///
+    /// No-op if the share doesn't exist.
+    async fn delete(&self, id: &str) -> StorageResult<()>;
+}
@@ -0,0 +1,33 @@
+//! Usage rollup storage trait.
+//!
+//! Defines the interface for persisting hourly and daily token usage
+//! rollups.
+
+use async_trait::async_trait;
+use chrono::{DateTime, Utc};
+
+use crate::usage::{Granularity, UsageBucket};
+
+use super::error::StorageResult;
+
+/// Storage interface for usage rollups.
+#[async_trait]
+pub trait UsageStore: Send + Sync {
+    /// Load buckets with `from <= start < to`, sorted by start then agent.
+    async fn load(
+        &self,
+        granularity: Granularity,
+        from: DateTime<Utc>,
+        to: DateTime<Utc>,
+    ) -> StorageResult<Vec<UsageBucket>>;
+
+    /// Add `buckets` to the stored totals, creating buckets as needed.
+    ///
+    /// Must be atomic per stored file.
+    async fn merge(&self, granularity: Granularity, buckets: &[UsageBucket]) -> StorageResult<()>;
+
+    /// Delete stored buckets that start before `before`.
+    ///
+    /// Returns the number of files removed.
+    async fn prune(&self, granularity: Granularity, before: DateTime<Utc>) -> StorageResult<usize>;
+}
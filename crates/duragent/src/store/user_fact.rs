@@ -0,0 +1,36 @@
+//! User memory storage trait.
+//!
+//! Defines the interface for persisting facts remembered about end users.
+
+use async_trait::async_trait;
+
+use crate::user_memory::UserFact;
+
+use super::error::StorageResult;
+
+/// Storage interface for per-user facts.
+#[async_trait]
+pub trait UserFactStore: Send + Sync {
+    /// List the facts about a user, oldest first.
+    ///
+    /// Returns an empty list for users with no facts.
+    async fn list(&self, user: &str) -> StorageResult<Vec<UserFact>>;
+
+    /// Load one fact, or `None` if it doesn't exist.
+    async fn load(&self, user: &str, id: &str) -> StorageResult<Option<UserFact>>;
+
+    /// Create or update a fact (upsert semantics).
+    ///
+    /// Must be atomic - either fully succeeds or has no effect.
+    async fn save(&self, user: &str, fact: &UserFact) -> StorageResult<()>;
+
+    /// Delete a fact.
+    ///
+    /// No-op if the fact doesn't exist.
+    async fn delete(&self, user: &str, id: &str) -> StorageResult<()>;
+
+    /// Delete every fact about a user.
+    ///
+    /// No-op if the user has no facts.
+    async fn delete_all(&self, user: &str) -> StorageResult<()>;
+}
@@ -0,0 +1,403 @@
+//! Streaming output processors.
+//!
+//! Unlike post-processors, which rewrite the finished output, these steps
+//! work on the token stream as it arrives, so a streamed reply is filtered
+//! before the client sees it without waiting for the whole response:
+//!
+//! ```yaml
+//! stream_processors:
+//!   - type: redact
+//!     terms: [sk-live-, internal.example.com]
+//!   - type: stop_sequences
+//!     sequences: ["\nUser:"]
+//!   - type: close_fences
+//! ```
+//!
+//! A step only holds back the few characters that might still turn into a
+//! match, and releases them when the next chunk rules the match out or the
+//! stream ends. Responses that aren't streamed go through the same steps in
+//! one piece, so both paths store the same text.
+
+use crate::agent::StreamProcessor;
+
+/// Replacement for redacted terms when the manifest doesn't set one.
+const DEFAULT_REDACTION: &str = "[redacted]";
+
+/// A step over the chunks of one response.
+pub trait StreamTransformer: Send {
+    /// Transform the next chunk. Text that later chunks could still change
+    /// is held back.
+    fn push(&mut self, chunk: &str) -> Transformed;
+
+    /// The stream ended: release whatever is held back.
+    fn finish(&mut self) -> String;
+}
+
+/// Output of one [`StreamTransformer::push`].
+#[derive(Debug, Default, PartialEq, Eq)]
+pub struct Transformed {
+    /// Text ready to send.
+    pub text: String,
+    /// The response must end after `text`.
+    pub stop: bool,
+}
+
+/// An agent's stream processors, chained in manifest order.
+pub struct StreamTransform {
+    stages: Vec<Box<dyn StreamTransformer>>,
+    stopped: bool,
+}
+
+impl StreamTransform {
+    pub fn new(processors: &[StreamProcessor]) -> Self {
+        let stages = processors
+            .iter()
+            .map(|processor| -> Box<dyn StreamTransformer> {
+                match processor {
+                    StreamProcessor::Redact { terms, replacement } => Box::new(Redact::new(
+                        terms,
+                        replacement.as_deref().unwrap_or(DEFAULT_REDACTION),
+                    )),
+                    StreamProcessor::StopSequences { sequences } => {
+                        Box::new(StopSequences::new(sequences))
+                    }
+                    StreamProcessor::CloseFences => Box::new(CloseFences::default()),
+                }
+            })
+            .collect();
+        Self {
+            stages,
+            stopped: false,
+        }
+    }
+}
+
+impl Default for StreamTransform {
+    /// No steps.
+    fn default() -> Self {
+        Self::new(&[])
+    }
+}
+
+impl StreamTransformer for StreamTransform {
+    fn push(&mut self, chunk: &str) -> Transformed {
+        if self.stopped {
+            return Transformed {
+                text: String::new(),
+                stop: true,
+            };
+        }
+        let mut text = chunk.to_string();
+        for i in 0..self.stages.len() {
+            let out = self.stages[i].push(&text);
+            text = out.text;
+            if out.stop {
+                // Later steps see the end of the stream right away.
+                self.stopped = true;
+                for stage in &mut self.stages[i + 1..] {
+                    let mut flushed = stage.push(&text).text;
+                    flushed.push_str(&stage.finish());
+                    text = flushed;
+                }
+                return Transformed { text, stop: true };
+            }
+        }
+        Transformed { text, stop: false }
+    }
+
+    fn finish(&mut self) -> String {
+        if self.stopped {
+            return String::new();
+        }
+        let mut text = String::new();
+        for stage in &mut self.stages {
+            let mut flushed = stage.push(&text).text;
+            flushed.push_str(&stage.finish());
+            text = flushed;
+        }
+        text
+    }
+}
+
+/// Run a complete response through `processors`.
+pub fn apply(processors: &[StreamProcessor], content: String) -> String {
+    if processors.is_empty() {
+        return content;
+    }
+    let mut transform = StreamTransform::new(processors);
+    let mut out = transform.push(&content);
+    if !out.stop {
+        out.text.push_str(&transform.finish());
+    }
+    out.text
+}
+
+// ============================================================================
+// Steps
+// ============================================================================
+
+/// Replaces terms, ignoring ASCII case.
+struct Redact {
+    /// Longest first, so a term never hides a longer one it starts.
+    terms: Vec<String>,
+    replacement: String,
+    pending: String,
+}
+
+impl Redact {
+    fn new(terms: &[String], replacement: &str) -> Self {
+        let mut terms: Vec<String> = terms.iter().filter(|t| !t.is_empty()).cloned().collect();
+        terms.sort_by_key(|t| std::cmp::Reverse(t.len()));
+        Self {
+            terms,
+            replacement: replacement.to_string(),
+            pending: String::new(),
+        }
+    }
+
+    /// Redact `pending`, keeping back a tail that could start a term unless
+    /// the stream is over.
+    fn drain(&mut self, last: bool) -> String {
+        let pending = std::mem::take(&mut self.pending);
+        let bytes = pending.as_bytes();
+        let mut output = String::with_capacity(pending.len());
+        let mut i = 0;
+        while i < pending.len() {
+            let rest = &bytes[i..];
+            if let Some(term) = self.terms.iter().find(|t| {
+                rest.len() >= t.len() && rest[..t.len()].eq_ignore_ascii_case(t.as_bytes())
+            }) {
+                output.push_str(&self.replacement);
+                i += term.len();
+                continue;
+            }
+            if !last
+                && self.terms.iter().any(|t| {
+                    t.len() > rest.len() && t.as_bytes()[..rest.len()].eq_ignore_ascii_case(rest)
+                })
+            {
+                break;
+            }
+            let c = pending[i..].chars().next().unwrap_or_default();
+            output.push(c);
+            i += c.len_utf8();
+        }
+        self.pending = pending[i..].to_string();
+        output
+    }
+}
+
+impl StreamTransformer for Redact {
+    fn push(&mut self, chunk: &str) -> Transformed {
+        self.pending.push_str(chunk);
+        Transformed {
+            text: self.drain(false),
+            stop: false,
+        }
+    }
+
+    fn finish(&mut self) -> String {
+        self.drain(true)
+    }
+}
+
+/// Ends the response before the first stop sequence.
+struct StopSequences {
+    sequences: Vec<String>,
+    pending: String,
+    stopped: bool,
+}
+
+impl StopSequences {
+    fn new(sequences: &[String]) -> Self {
+        Self {
+            sequences: sequences
+                .iter()
+                .filter(|s| !s.is_empty())
+                .cloned()
+                .collect(),
+            pending: String::new(),
+            stopped: false,
+        }
+    }
+}
+
+impl StreamTransformer for StopSequences {
+    fn push(&mut self, chunk: &str) -> Transformed {
+        if self.stopped {
+            return Transformed {
+                text: String::new(),
+                stop: true,
+            };
+        }
+        self.pending.push_str(chunk);
+
+        if let Some(end) = self
+            .sequences
+            .iter()
+            .filter_map(|s| self.pending.find(s.as_str()))
+            .min()
+        {
+            self.stopped = true;
+            let mut text = std::mem::take(&mut self.pending);
+            text.truncate(end);
+            return Transformed { text, stop: true };
+        }
+
+        // Hold back the longest tail that a sequence starts with.
+        let held = self
+            .sequences
+            .iter()
+            .flat_map(|s| {
+                (1..s.len())
+                    .filter(|&n| s.is_char_boundary(n) && self.pending.ends_with(&s[..n]))
+                    .max()
+            })
+            .max()
+            .unwrap_or(0);
+        let keep = self.pending.split_off(self.pending.len() - held);
+        Transformed {
+            text: std::mem::replace(&mut self.pending, keep),
+            stop: false,
+        }
+    }
+
+    fn finish(&mut self) -> String {
+        std::mem::take(&mut self.pending)
+    }
+}
+
+/// Appends a closing fence when a Markdown code block is left open.
+#[derive(Default)]
+struct CloseFences {
+    open: bool,
+    /// Backticks in a row at the end of the text so far.
+    ticks: usize,
+    ends_with_newline: bool,
+}
+
+impl StreamTransformer for CloseFences {
+    fn push(&mut self, chunk: &str) -> Transformed {
+        for c in chunk.chars() {
+            if c == '`' {
+                self.ticks += 1;
+                continue;
+            }
+            if self.ticks >= 3 {
+                self.open = !self.open;
+            }
+            self.ticks = 0;
+        }
+        if let Some(c) = chunk.chars().last() {
+            self.ends_with_newline = c == '\n';
+        }
+        Transformed {
+            text: chunk.to_string(),
+            stop: false,
+        }
+    }
+
+    fn finish(&mut self) -> String {
+        if self.ticks >= 3 {
+            self.open = !self.open;
+        }
+        self.ticks = 0;
+        if !self.open {
+            return String::new();
+        }
+        self.open = false;
+        if self.ends_with_newline {
+            "```".to_string()
+        } else {
+            "\n```".to_string()
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    /// Feed `chunks` one at a time and collect the output.
+    fn stream(processors: &[StreamProcessor], chunks: &[&str]) -> (String, bool) {
+        let mut transform = StreamTransform::new(processors);
+        let mut output = String::new();
+        for chunk in chunks {
+            let out = transform.push(chunk);
+            output.push_str(&out.text);
+            if out.stop {
+                return (output, true);
+            }
+        }
+        output.push_str(&transform.finish());
+        (output, false)
+    }
+
+    fn redact(terms: &[&str]) -> StreamProcessor {
+        StreamProcessor::Redact {
+            terms: terms.iter().map(|t| t.to_string()).collect(),
+            replacement: None,
+        }
+    }
+
+    #[test]
+    fn redacts_terms_split_across_chunks() {
+        let processors = [redact(&["sk-live-123"])];
+        assert_eq!(
+            stream(&processors, &["key: SK-li", "ve-1", "23 ok"]),
+            ("key: [redacted] ok".to_string(), false)
+        );
+
+        // Only the possible start of a term is held back.
+        let mut transform = StreamTransform::new(&processors);
+        assert_eq!(transform.push("say sk-").text, "say ");
+        assert_eq!(transform.push("lite").text, "sk-lite");
+        assert_eq!(transform.finish(), "");
+    }
+
+    #[test]
+    fn stops_before_a_sequence() {
+        let processors = [StreamProcessor::StopSequences {
+            sequences: vec!["\nUser:".to_string()],
+        }];
+        assert_eq!(
+            stream(&processors, &["Done.\nUs", "er: next", " turn"]),
+            ("Done.".to_string(), true)
+        );
+        assert_eq!(
+            stream(&processors, &["Done.\nUs", "ually fine"]),
+            ("Done.\nUsually fine".to_string(), false)
+        );
+    }
+
+    #[test]
+    fn closes_open_fences() {
+        let processors = [StreamProcessor::CloseFences];
+        assert_eq!(
+            stream(&processors, &["Run:\n``", "`sh\nls"]),
+            ("Run:\n```sh\nls\n```".to_string(), false)
+        );
+        assert_eq!(
+            stream(&processors, &["```\nls\n```\n"]),
+            ("```\nls\n```\n".to_string(), false)
+        );
+    }
+
+    #[test]
+    fn later_steps_see_the_end_when_an_earlier_one_stops() {
+        let processors = [
+            StreamProcessor::StopSequences {
+                sequences: vec!["END".to_string()],
+            },
+            StreamProcessor::CloseFences,
+        ];
+        assert_eq!(
+            stream(&processors, &["```\ncode END trailing"]),
+            ("```\ncode \n```".to_string(), true)
+        );
+        assert_eq!(
+            apply(&processors, "```\ncode END trailing".to_string()),
+            "```\ncode \n```"
+        );
+    }
+}
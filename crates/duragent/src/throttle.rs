@@ -0,0 +1,381 @@
+//! Client-side rate limiting of LLM provider requests.
+//!
+//! Providers with a `requests_per_minute` or `tokens_per_minute` budget under
+//! `outbound.providers` have their requests queued until they fit in the
+//! last minute's budget, instead of being sent only to come back as 429s.
+//! Tokens are estimated from the prompt and `max_tokens` when a request is
+//! admitted, then corrected to the usage the provider reports.
+
+use std::collections::{HashMap, VecDeque};
+use std::sync::{Arc, Mutex};
+use std::time::{Duration, Instant};
+
+use async_trait::async_trait;
+use futures::StreamExt;
+use thiserror::Error;
+use tracing::debug;
+
+use crate::config::OutboundConfig;
+use crate::context::{estimate_message_tokens, estimate_tool_definitions_tokens};
+use crate::llm::{
+    ChatRequest, ChatResponse, ChatStream, LLMError, LLMProvider, StreamEvent, Usage,
+};
+
+/// The window budgets apply to.
+const WINDOW: Duration = Duration::from_secs(60);
+
+/// How long a request waits for budget when `max_queue_seconds` is unset.
+pub const DEFAULT_MAX_QUEUE_SECONDS: u64 = 60;
+
+// ============================================================================
+// Types
+// ============================================================================
+
+#[derive(Debug, Error)]
+pub enum ThrottleError {
+    #[error("outbound.providers.{provider}: {field} must be greater than 0")]
+    ZeroBudget {
+        provider: String,
+        field: &'static str,
+    },
+}
+
+/// A request admitted in the current window.
+#[derive(Debug, Clone, Copy)]
+struct Admission {
+    id: u64,
+    at: Instant,
+    tokens: u64,
+}
+
+#[derive(Debug, Default)]
+struct Window {
+    next_id: u64,
+    admitted: VecDeque<Admission>,
+}
+
+/// One provider's budget and the requests counted against it.
+#[derive(Debug)]
+struct Budget {
+    provider: String,
+    requests_per_minute: Option<u32>,
+    tokens_per_minute: Option<u64>,
+    max_queue: Duration,
+    /// Held while waiting for budget, so requests are admitted in order.
+    queue: tokio::sync::Mutex<()>,
+    window: Mutex<Window>,
+}
+
+impl Budget {
+    /// Admit a request of `tokens` at `now`, or return how long until it fits.
+    fn try_admit_at(&self, now: Instant, tokens: u64) -> Result<u64, Duration> {
+        let mut window = self.window.lock().expect("mutex poisoned");
+        while window
+            .admitted
+            .front()
+            .is_some_and(|a| now.duration_since(a.at) >= WINDOW)
+        {
+            window.admitted.pop_front();
+        }
+
+        let expires = |a: &Admission| (a.at + WINDOW).saturating_duration_since(now);
+        let mut wait = Duration::ZERO;
+        if let Some(limit) = self.requests_per_minute {
+            let over = (window.admitted.len() + 1).saturating_sub(limit as usize);
+            if over > 0 {
+                wait = wait.max(expires(&window.admitted[over - 1]));
+            }
+        }
+        if let Some(limit) = self.tokens_per_minute {
+            // A request larger than the whole budget goes once the window is empty
+            let mut used: u64 = window.admitted.iter().map(|a| a.tokens).sum();
+            for admission in &window.admitted {
+                if used + tokens <= limit {
+                    break;
+                }
+                used -= admission.tokens;
+                wait = wait.max(expires(admission));
+            }
+        }
+        if !wait.is_zero() {
+            return Err(wait);
+        }
+
+        let id = window.next_id;
+        window.next_id += 1;
+        window.admitted.push_back(Admission {
+            id,
+            at: now,
+            tokens,
+        });
+        Ok(id)
+    }
+
+    /// Wait for budget, failing with a rate limit error if that would take
+    /// longer than `max_queue_seconds`.
+    async fn admit(&self, tokens: u64) -> Result<u64, LLMError> {
+        let _turn = self.queue.lock().await;
+        let deadline = Instant::now() + self.max_queue;
+        loop {
+            let now = Instant::now();
+            let wait = match self.try_admit_at(now, tokens) {
+                Ok(id) => return Ok(id),
+                Err(wait) => wait,
+            };
+            if now + wait > deadline {
+                return Err(LLMError::RateLimit {
+                    retry_after: Some(wait.as_secs().max(1)),
+                });
+            }
+            debug!(
+                provider = %self.provider,
+                wait_ms = wait.as_millis() as u64,
+                "Waiting for provider rate limit budget"
+            );
+            tokio::time::sleep(wait).await;
+        }
+    }
+
+    /// Replace an admitted request's estimate with the tokens it used.
+    fn settle(&self, id: u64, usage: Option<&Usage>) {
+        let Some(usage) = usage else {
+            return;
+        };
+        let mut window = self.window.lock().expect("mutex poisoned");
+        if let Some(admission) = window.admitted.iter_mut().find(|a| a.id == id) {
+            admission.tokens = u64::from(usage.total_tokens);
+        }
+    }
+}
+
+// ============================================================================
+// ProviderThrottle
+// ============================================================================
+
+/// Rate limit budgets per provider. Empty unless a budget is configured.
+#[derive(Debug, Clone, Default)]
+pub struct ProviderThrottle {
+    budgets: Arc<HashMap<String, Arc<Budget>>>,
+}
+
+impl ProviderThrottle {
+    pub fn from_config(config: &OutboundConfig) -> Result<Self, ThrottleError> {
+        let mut budgets = HashMap::new();
+        for (name, overrides) in &config.providers {
+            for (field, value) in [
+                (
+                    "requests_per_minute",
+                    overrides.requests_per_minute.map(u64::from),
+                ),
+                ("tokens_per_minute", overrides.tokens_per_minute),
+            ] {
+                if value == Some(0) {
+                    return Err(ThrottleError::ZeroBudget {
+                        provider: name.clone(),
+                        field,
+                    });
+                }
+            }
+            if overrides.requests_per_minute.is_none() && overrides.tokens_per_minute.is_none() {
+                continue;
+            }
+            budgets.insert(
+                name.clone(),
+                Arc::new(Budget {
+                    provider: name.clone(),
+                    requests_per_minute: overrides.requests_per_minute,
+                    tokens_per_minute: overrides.tokens_per_minute,
+                    max_queue: Duration::from_secs(
+                        overrides
+                            .max_queue_seconds
+                            .unwrap_or(DEFAULT_MAX_QUEUE_SECONDS),
+                    ),
+                    queue: tokio::sync::Mutex::new(()),
+                    window: Mutex::new(Window::default()),
+                }),
+            );
+        }
+        Ok(Self {
+            budgets: Arc::new(budgets),
+        })
+    }
+
+    pub fn is_empty(&self) -> bool {
+        self.budgets.is_empty()
+    }
+
+    /// Wrap a provider in its budget, if it has one.
+    pub fn wrap_provider(
+        &self,
+        provider: &str,
+        inner: Arc<dyn LLMProvider>,
+    ) -> Arc<dyn LLMProvider> {
+        match self.budgets.get(provider) {
+            Some(budget) => Arc::new(ThrottledProvider {
+                inner,
+                budget: budget.clone(),
+            }),
+            None => inner,
+        }
+    }
+}
+
+/// Estimated tokens a request counts against a budget.
+fn estimate_request_tokens(request: &ChatRequest) -> u64 {
+    let prompt: u32 = request.messages.iter().map(estimate_message_tokens).sum();
+    let tools = request
+        .tools
+        .as_deref()
+        .map_or(0, estimate_tool_definitions_tokens);
+    u64::from(prompt) + u64::from(tools) + u64::from(request.max_tokens.unwrap_or(0))
+}
+
+// ============================================================================
+// ThrottledProvider
+// ============================================================================
+
+/// Holds each chat request until it fits in its provider's budget.
+struct ThrottledProvider {
+    inner: Arc<dyn LLMProvider>,
+    budget: Arc<Budget>,
+}
+
+#[async_trait]
+impl LLMProvider for ThrottledProvider {
+    async fn chat(&self, request: ChatRequest) -> Result<ChatResponse, LLMError> {
+        let id = self.budget.admit(estimate_request_tokens(&request)).await?;
+        let result = self.inner.chat(request).await;
+        if let Ok(response) = &result {
+            self.budget.settle(id, response.usage.as_ref());
+        }
+        result
+    }
+
+    async fn chat_stream(&self, request: ChatRequest) -> Result<ChatStream, LLMError> {
+        let id = self.budget.admit(estimate_request_tokens(&request)).await?;
+        let stream = self.inner.chat_stream(request).await?;
+        let budget = self.budget.clone();
+        Ok(Box::pin(stream.inspect(move |event| {
+            if let Ok(StreamEvent::Done { usage }) = event {
+                budget.settle(id, usage.as_ref());
+            }
+        })))
+    }
+
+    async fn health_check(&self) -> Result<(), LLMError> {
+        self.inner.health_check().await
+    }
+
+    async fn embed(&self, model: &str, inputs: Vec<String>) -> Result<Vec<Vec<f32>>, LLMError> {
+        self.inner.embed(model, inputs).await
+    }
+
+    async fn list_models(&self) -> Result<Vec<String>, LLMError> {
+        self.inner.list_models().await
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::config::ProviderOutboundConfig;
+
+    fn budget(requests_per_minute: Option<u32>, tokens_per_minute: Option<u64>) -> Arc<Budget> {
+        let mut config = OutboundConfig::default();
+        config.providers.insert(
+            "openai".to_string(),
+            ProviderOutboundConfig {
+                requests_per_minute,
+                tokens_per_minute,
+                max_queue_seconds: Some(1),
+                ..ProviderOutboundConfig::default()
+            },
+        );
+        let throttle = ProviderThrottle::from_config(&config).unwrap();
+        throttle.budgets["openai"].clone()
+    }
+
+    #[test]
+    fn requests_wait_for_the_oldest_to_leave_the_window() {
+        let budget = budget(Some(2), None);
+        let start = Instant::now();
+        budget.try_admit_at(start, 0).unwrap();
+        budget
+            .try_admit_at(start + Duration::from_secs(10), 0)
+            .unwrap();
+
+        let wait = budget
+            .try_admit_at(start + Duration::from_secs(20), 0)
+            .unwrap_err();
+        assert_eq!(wait, Duration::from_secs(40));
+        budget
+            .try_admit_at(start + Duration::from_secs(60), 0)
+            .unwrap();
+    }
+
+    #[test]
+    fn tokens_wait_until_enough_have_expired() {
+        let budget = budget(None, Some(1000));
+        let start = Instant::now();
+        budget.try_admit_at(start, 600).unwrap();
+        budget
+            .try_admit_at(start + Duration::from_secs(30), 300)
+            .unwrap();
+
+        // 900 used: 500 more fits only once the first 600 expire
+        let wait = budget
+            .try_admit_at(start + Duration::from_secs(40), 500)
+            .unwrap_err();
+        assert_eq!(wait, Duration::from_secs(20));
+
+        // Oversized requests go once the window is empty
+        let wait = budget
+            .try_admit_at(start + Duration::from_secs(40), 5000)
+            .unwrap_err();
+        assert_eq!(wait, Duration::from_secs(50));
+    }
+
+    #[test]
+    fn settled_usage_replaces_the_estimate() {
+        let budget = budget(None, Some(1000));
+        let start = Instant::now();
+        let id = budget.try_admit_at(start, 900).unwrap();
+        budget.settle(
+            id,
+            Some(&Usage {
+                prompt_tokens: 100,
+                completion_tokens: 50,
+                total_tokens: 150,
+            }),
+        );
+        budget.try_admit_at(start, 800).unwrap();
+    }
+
+    #[test]
+    fn zero_budgets_are_rejected() {
+        let mut config = OutboundConfig::default();
+        config.providers.insert(
+            "openai".to_string(),
+            ProviderOutboundConfig {
+                tokens_per_minute: Some(0),
+                ..ProviderOutboundConfig::default()
+            },
+        );
+        assert!(matches!(
+            ProviderThrottle::from_config(&config),
+            Err(ThrottleError::ZeroBudget { .. })
+        ));
+    }
+
+    #[tokio::test]
+    async fn admit_fails_when_the_wait_exceeds_the_queue_limit() {
+        let budget = budget(Some(1), None);
+        budget.admit(0).await.unwrap();
+        assert!(matches!(
+            budget.admit(0).await,
+            Err(LLMError::RateLimit {
+                retry_after: Some(_)
+            })
+        ));
+    }
+}
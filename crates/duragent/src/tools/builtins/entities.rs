@@ -0,0 +1,186 @@
+//! Entity memory tool for agents.
+//!
+//! Queries the relations extracted from past conversations (see
+//! [`crate::memory::entities`]).
+
+use async_trait::async_trait;
+use chrono::{Duration, Utc};
+use serde::Deserialize;
+use serde_json::json;
+
+use crate::llm::{FunctionDefinition, ToolDefinition};
+use crate::memory::entities::{EntityQuery, EntityStore, Relation};
+use crate::tools::error::ToolError;
+use crate::tools::executor::ToolResult;
+use crate::tools::tool::Tool;
+
+/// Relations returned when the call doesn't set a limit.
+const DEFAULT_LIMIT: usize = 20;
+
+/// Most relations one call can return.
+const MAX_LIMIT: usize = 100;
+
+// ============================================================================
+// Tool struct
+// ============================================================================
+
+/// Query tool over an agent's extracted entities and relations.
+pub struct EntitiesTool {
+    store: EntityStore,
+}
+
+impl EntitiesTool {
+    pub fn new(store: EntityStore) -> Self {
+        Self { store }
+    }
+}
+
+// ============================================================================
+// Tool trait implementation
+// ============================================================================
+
+#[async_trait]
+impl Tool for EntitiesTool {
+    fn name(&self) -> &str {
+        "entities"
+    }
+
+    fn definition(&self) -> ToolDefinition {
+        ToolDefinition {
+            tool_type: "function".to_string(),
+            function: FunctionDefinition {
+                name: "entities".to_string(),
+                description: "Query relations between people, projects, decisions, and other entities mentioned in past conversations, newest first, with when they came up. Use it to answer questions like 'what did we decide about X last week?'. All filters are optional and match part of a name, ignoring case.".to_string(),
+                parameters: Some(json!({
+                    "type": "object",
+                    "properties": {
+                        "entity": {
+                            "type": "string",
+                            "description": "Entity that must be the subject or object, e.g. 'Apollo'"
+                        },
+                        "relation": {
+                            "type": "string",
+                            "description": "Relation to match, e.g. 'decided'"
+                        },
+                        "days": {
+                            "type": "integer",
+                            "description": "Only relations mentioned in the last N days"
+                        },
+                        "limit": {
+                            "type": "integer",
+                            "description": "Most relations to return (default: 20, max: 100)"
+                        }
+                    }
+                })),
+            },
+        }
+    }
+
+    async fn execute(&self, arguments: &str) -> Result<ToolResult, ToolError> {
+        let args: EntitiesArgs = serde_json::from_str(arguments)
+            .map_err(|e| ToolError::InvalidArguments(e.to_string()))?;
+
+        let query = EntityQuery {
+            entity: args.entity.filter(|e| !e.trim().is_empty()),
+            relation: args.relation.filter(|r| !r.trim().is_empty()),
+            since: args
+                .days
+                .map(|days| Utc::now() - Duration::days(days as i64)),
+            limit: args.limit.unwrap_or(DEFAULT_LIMIT).clamp(1, MAX_LIMIT),
+        };
+        let store = self.store.clone();
+        let relations = tokio::task::spawn_blocking(move || store.query(&query))
+            .await
+            .map_err(|e| ToolError::ExecutionFailed(e.to_string()))?
+            .map_err(|e| ToolError::ExecutionFailed(e.to_string()))?;
+
+        if relations.is_empty() {
+            return Ok(ToolResult {
+                success: true,
+                content: "No matching relations found".to_string(),
+            });
+        }
+        let content = relations
+            .iter()
+            .map(format_relation)
+            .collect::<Vec<_>>()
+            .join("\n");
+        Ok(ToolResult {
+            success: true,
+            content,
+        })
+    }
+}
+
+/// One line per relation: `- 2026-10-09: Apollo (project) will use Postgres`.
+fn format_relation(relation: &Relation) -> String {
+    let entity = |name: &str, kind: Option<&str>| match kind {
+        Some(kind) => format!("{name} ({kind})"),
+        None => name.to_string(),
+    };
+    format!(
+        "- {}: {} {} {}",
+        relation.recorded_at.format("%Y-%m-%d"),
+        entity(&relation.subject, relation.subject_type.as_deref()),
+        relation.relation,
+        entity(&relation.object, relation.object_type.as_deref()),
+    )
+}
+
+// ============================================================================
+// Private Types
+// ============================================================================
+
+#[derive(Debug, Deserialize)]
+struct EntitiesArgs {
+    #[serde(default)]
+    entity: Option<String>,
+    #[serde(default)]
+    relation: Option<String>,
+    #[serde(default)]
+    days: Option<u32>,
+    #[serde(default)]
+    limit: Option<usize>,
+}
+
+// ============================================================================
+// Tests
+// ============================================================================
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use tempfile::TempDir;
+
+    #[tokio::test]
+    async fn query_formats_matching_relations() {
+        let temp = TempDir::new().unwrap();
+        let store = EntityStore::new(temp.path());
+        store
+            .record(vec![Relation {
+                subject: "Apollo".to_string(),
+                subject_type: Some("project".to_string()),
+                relation: "will use".to_string(),
+                object: "Postgres".to_string(),
+                object_type: None,
+                session_id: None,
+                recorded_at: Utc::now(),
+            }])
+            .unwrap();
+        let tool = EntitiesTool::new(store);
+
+        let result = tool
+            .execute(r#"{"entity": "postgres", "days": 7}"#)
+            .await
+            .unwrap();
+        assert!(result.success);
+        assert!(
+            result
+                .content
+                .ends_with("Apollo (project) will use Postgres")
+        );
+
+        let result = tool.execute(r#"{"entity": "Zeus"}"#).await.unwrap();
+        assert_eq!(result.content, "No matching relations found");
+    }
+}
@@ -3,7 +3,9 @@
 pub(crate) mod background_process;
 pub(crate) mod bash;
 pub(crate) mod cli;
+pub(crate) mod entities;
 pub(crate) mod memory;
+pub(crate) mod plan;
 pub(crate) mod reload;
 pub mod schedule;
 pub(crate) mod session;
@@ -0,0 +1,164 @@
+//! Plan tool.
+//!
+//! The `plan` tool is how agents with `planning` record the plan for a run.
+//! It's registered automatically for those agents and offered alone during
+//! the planning step; the agentic loop reads the plan from its arguments and
+//! carries it out one step at a time. Executing the tool only validates the
+//! plan — it's what runs when a plan that required approval is approved.
+
+use async_trait::async_trait;
+use serde::Deserialize;
+
+use crate::llm::{FunctionDefinition, ToolDefinition};
+use crate::session::PlanStep;
+use crate::tools::error::ToolError;
+use crate::tools::executor::ToolResult;
+use crate::tools::tool::Tool;
+
+/// Name of the plan tool.
+pub const PLAN_TOOL: &str = "plan";
+
+/// The plan tool's result for a valid plan, which starts its execution.
+pub const PLAN_ACCEPTED: &str = "Plan accepted. Carry it out one step at a time.";
+
+#[derive(Debug, Deserialize)]
+struct PlanArgs {
+    steps: Vec<PlanStep>,
+}
+
+/// Parse a plan from the plan tool's arguments, or say what's wrong with it.
+pub fn parse_plan(arguments: &str, max_steps: u32) -> Result<Vec<PlanStep>, String> {
+    let args: PlanArgs =
+        serde_json::from_str(arguments).map_err(|e| format!("Invalid plan arguments: {e}"))?;
+    if args.steps.is_empty() {
+        return Err("A plan needs at least one step.".to_string());
+    }
+    if args.steps.len() > max_steps as usize {
+        return Err(format!(
+            "A plan can have at most {max_steps} steps; combine some steps."
+        ));
+    }
+    if args.steps.iter().any(|s| s.description.trim().is_empty()) {
+        return Err("Every plan step needs a description.".to_string());
+    }
+    Ok(args.steps)
+}
+
+// ============================================================================
+// PlanTool
+// ============================================================================
+
+/// Built-in tool that records a run's plan.
+pub struct PlanTool {
+    max_steps: u32,
+}
+
+impl PlanTool {
+    pub fn new(max_steps: u32) -> Self {
+        Self { max_steps }
+    }
+}
+
+#[async_trait]
+impl Tool for PlanTool {
+    fn name(&self) -> &str {
+        PLAN_TOOL
+    }
+
+    fn definition(&self) -> ToolDefinition {
+        ToolDefinition {
+            tool_type: "function".to_string(),
+            function: FunctionDefinition {
+                name: PLAN_TOOL.to_string(),
+                description: "Record the plan for this task before carrying it out. Each step \
+                    is one piece of work, with the tools it needs. Steps are carried out in \
+                    order, one at a time."
+                    .to_string(),
+                parameters: Some(serde_json::json!({
+                    "type": "object",
+                    "properties": {
+                        "steps": {
+                            "type": "array",
+                            "minItems": 1,
+                            "maxItems": self.max_steps,
+                            "items": {
+                                "type": "object",
+                                "properties": {
+                                    "description": {
+                                        "type": "string",
+                                        "description": "What the step does"
+                                    },
+                                    "tools": {
+                                        "type": "array",
+                                        "items": { "type": "string" },
+                                        "description": "Tools the step uses; omit for none in particular"
+                                    }
+                                },
+                                "required": ["description"]
+                            }
+                        }
+                    },
+                    "required": ["steps"]
+                })),
+            },
+        }
+    }
+
+    async fn execute(&self, arguments: &str) -> Result<ToolResult, ToolError> {
+        Ok(match parse_plan(arguments, self.max_steps) {
+            Ok(_) => ToolResult {
+                success: true,
+                content: PLAN_ACCEPTED.to_string(),
+            },
+            Err(content) => ToolResult {
+                success: false,
+                content,
+            },
+        })
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn parse_plan_reads_steps() {
+        let steps = parse_plan(
+            r#"{"steps": [{"description": "find the file", "tools": ["bash"]}, {"description": "summarize it"}]}"#,
+            8,
+        )
+        .unwrap();
+        assert_eq!(steps.len(), 2);
+        assert_eq!(steps[0].tools, vec!["bash".to_string()]);
+        assert!(steps[1].tools.is_empty());
+    }
+
+    #[test]
+    fn parse_plan_rejects_bad_plans() {
+        assert!(parse_plan("not json", 8).is_err());
+        assert!(parse_plan(r#"{"steps": []}"#, 8).is_err());
+        assert!(parse_plan(r#"{"steps": [{"description": " "}]}"#, 8).is_err());
+        assert!(
+            parse_plan(
+                r#"{"steps": [{"description": "a"}, {"description": "b"}]}"#,
+                1
+            )
+            .is_err()
+        );
+    }
+
+    #[tokio::test]
+    async fn execute_accepts_valid_plans() {
+        let tool = PlanTool::new(4);
+        let result = tool
+            .execute(r#"{"steps": [{"description": "a"}]}"#)
+            .await
+            .unwrap();
+        assert!(result.success);
+        assert_eq!(result.content, PLAN_ACCEPTED);
+
+        let result = tool.execute(r#"{"steps": []}"#).await.unwrap();
+        assert!(!result.success);
+    }
+}
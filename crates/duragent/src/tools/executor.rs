@@ -8,6 +8,7 @@ use tracing::{debug, warn};
 use super::discovery::discover_all_tools;
 use super::error::ToolError;
 use super::factory::{ReloadDeps, ToolDependencies, create_tools};
+use super::mocks::ToolMocks;
 use super::notify::send_notification;
 use super::tool::Tool;
 use crate::agent::{NotifyConfig, PolicyDecision, ToolPolicy, ToolPolicyEval, ToolType};
@@ -87,6 +88,8 @@ pub struct ToolExecutor {
     agent_name: String,
     /// Dependencies for rebuilding tools mid-session via `reload_tools`.
     reload_deps: Option<ReloadDeps>,
+    /// Tools answered with canned data instead of being run.
+    mocks: ToolMocks,
 }
 
 impl ToolExecutor {
@@ -103,6 +106,7 @@ impl ToolExecutor {
             session_id: None,
             agent_name,
             reload_deps: None,
+            mocks: ToolMocks::default(),
         }
     }
 
@@ -132,13 +136,25 @@ impl ToolExecutor {
         self
     }
 
+    /// Answer calls of the mocked tools with their canned data. Mocks
+    /// survive `reload_tools` rebuilds.
+    pub fn with_mocks(mut self, mocks: ToolMocks) -> Self {
+        self.mocks = mocks;
+        self
+    }
+
     /// Replace all tools, preserving memory tools and `reload_tools`.
     ///
     /// Used by the agentic loop after `reload_tools` to rebuild the executor
     /// with newly discovered tools while keeping session-bound tools intact.
     pub fn replace_tools(&mut self, new_tools: Vec<Arc<dyn Tool>>) {
-        const PRESERVED_TOOLS: &[&str] =
-            &["memory", "reload_tools", "background_process", "session"];
+        const PRESERVED_TOOLS: &[&str] = &[
+            "memory",
+            "entities",
+            "reload_tools",
+            "background_process",
+            "session",
+        ];
 
         // Extract preserved tools before clearing
         let preserved: Vec<Arc<dyn Tool>> = self
@@ -334,6 +350,16 @@ impl ToolExecutor {
             .collect()
     }
 
+    /// Names of the tools a run restricted by `allow` and `deny` may use:
+    /// those in `allow` (all when `None`) and not in `deny`.
+    pub fn allowed_tools(&self, allow: Option<&[String]>, deny: &[String]) -> HashSet<String> {
+        self.tools
+            .keys()
+            .filter(|name| allow.is_none_or(|a| a.contains(*name)) && !deny.contains(*name))
+            .cloned()
+            .collect()
+    }
+
     /// Check if any tools are configured.
     pub fn has_tools(&self) -> bool {
         !self.tools.is_empty()
@@ -401,7 +427,17 @@ impl ToolExecutor {
             arguments = %tool_call.function.arguments,
             "Executing tool"
         );
-        let result = tool.execute(&tool_call.function.arguments).await;
+        // Mocks are looked up by the resolved tool, not the name as called
+        let result = match self
+            .mocks
+            .respond(tool.name(), &tool_call.function.arguments)
+        {
+            Some(result) => {
+                debug!(tool = %tool.name(), "Answered tool call from mock");
+                Ok(result)
+            }
+            None => tool.execute(&tool_call.function.arguments).await,
+        };
 
         // Send notification if configured
         if self.policy.should_notify(tool_type, invocation) {
@@ -658,6 +694,37 @@ mod tests {
         assert_eq!(defs.len(), 2);
     }
 
+    #[test]
+    fn allowed_tools_applies_allow_then_deny() {
+        let executor = test_executor(vec![
+            ToolConfig::Builtin {
+                name: "bash".to_string(),
+            },
+            ToolConfig::Builtin {
+                name: "web".to_string(),
+            },
+        ]);
+        let names = |allowed: HashSet<String>| {
+            let mut names: Vec<_> = allowed.into_iter().collect();
+            names.sort();
+            names
+        };
+
+        assert_eq!(names(executor.allowed_tools(None, &[])), ["bash", "web"]);
+        assert_eq!(
+            names(executor.allowed_tools(None, &["bash".to_string()])),
+            ["web"]
+        );
+        // Names the agent doesn't have grant nothing
+        let allow = ["web".to_string(), "deploy".to_string()];
+        assert_eq!(names(executor.allowed_tools(Some(&allow), &[])), ["web"]);
+        assert!(
+            executor
+                .allowed_tools(Some(&allow), &["web".to_string()])
+                .is_empty()
+        );
+    }
+
     #[test]
     fn tool_definitions_for_unknown_builtin() {
         // Unknown builtins are now skipped by create_tools, so no tools are created
@@ -690,6 +757,39 @@ mod tests {
         assert!(result.content.contains("hello"));
     }
 
+    #[tokio::test]
+    async fn execute_answers_mocked_tools_without_running_them() {
+        let temp_dir = TempDir::new().unwrap();
+        std::fs::write(
+            temp_dir.path().join("bash.json"),
+            r#"{"content": "pretended to run it"}"#,
+        )
+        .unwrap();
+        let mocks = ToolMocks::from_config(
+            &crate::config::ToolMocksConfig {
+                enabled: true,
+                fixtures_dir: None,
+                tools: vec!["bash".to_string()],
+            },
+            temp_dir.path(),
+        )
+        .unwrap();
+        let executor = test_executor_with_dir(
+            vec![ToolConfig::Builtin {
+                name: "bash".to_string(),
+            }],
+            &temp_dir,
+        )
+        .with_mocks(mocks);
+
+        let tool_call = bash_tool_call("touch created");
+        let result = executor.execute(&tool_call).await.unwrap();
+
+        assert!(result.success);
+        assert_eq!(result.content, "pretended to run it");
+        assert!(!temp_dir.path().join("created").exists());
+    }
+
     #[tokio::test]
     async fn execute_returns_not_found_for_unknown_tool() {
         let executor = test_executor(vec![]);
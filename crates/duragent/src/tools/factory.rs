@@ -11,6 +11,7 @@ use anyhow::Result;
 use crate::agent::{AgentSpec, ToolConfig, ToolPolicy};
 use crate::config::DEFAULT_TOOLS_DIR;
 use crate::memory::Memory;
+use crate::memory::entities::EntityStore;
 use crate::process::ProcessRegistryHandle;
 use crate::sandbox::Sandbox;
 use crate::scheduler::SchedulerHandle;
@@ -19,7 +20,9 @@ use crate::session::SessionRegistry;
 use super::builtins::background_process::BackgroundProcessTool;
 use super::builtins::bash::BashTool;
 use super::builtins::cli::CliTool;
+use super::builtins::entities::EntitiesTool;
 use super::builtins::memory::MemoryTool;
+use super::builtins::plan::PlanTool;
 use super::builtins::reload::ReloadToolsTool;
 use super::builtins::schedule::{ScheduleTool, ToolExecutionContext};
 use super::builtins::session::SessionTool;
@@ -169,9 +172,14 @@ fn get_process_deps(deps: &ToolDependencies) -> Option<(ProcessRegistryHandle, S
 
 /// Create memory tools for an agent.
 ///
-/// Returns the single consolidated memory tool.
-fn create_memory_tools(memory: Arc<Memory>) -> Vec<SharedTool> {
-    vec![Arc::new(MemoryTool::new(memory)) as SharedTool]
+/// Returns the consolidated memory tool, plus the entities tool when entity
+/// memory is enabled.
+fn create_memory_tools(memory: Arc<Memory>, entities: Option<EntityStore>) -> Vec<SharedTool> {
+    let mut tools = vec![Arc::new(MemoryTool::new(memory)) as SharedTool];
+    if let Some(store) = entities {
+        tools.push(Arc::new(EntitiesTool::new(store)));
+    }
+    tools
 }
 
 /// Build a fully configured tool executor for an agent.
@@ -201,12 +209,21 @@ pub fn build_executor(
         .register_all(merged)
         .with_session_id(session_id.to_string());
 
-    if agent.memory.is_some() {
+    if let Some(config) = &agent.memory {
+        let agent_memory_dir = agent.agent_dir.join("memory");
+        let entities = config
+            .entities
+            .is_some()
+            .then(|| EntityStore::new(&agent_memory_dir));
         let memory = Arc::new(Memory::new(
             world_memory_path.to_path_buf(),
-            agent.agent_dir.join("memory"),
+            agent_memory_dir,
         ));
-        executor = executor.register_all(create_memory_tools(memory));
+        executor = executor.register_all(create_memory_tools(memory, entities));
+    }
+
+    if let Some(planning) = &agent.planning {
+        executor = executor.register(Arc::new(PlanTool::new(planning.max_steps)));
     }
 
     executor
@@ -385,9 +402,14 @@ mod tests {
         let agent_memory_dir = temp_dir.path().join("agent-memory");
         let memory = Arc::new(Memory::new(world_dir, agent_memory_dir));
 
-        let tools = create_memory_tools(memory);
+        let tools = create_memory_tools(memory.clone(), None);
 
         assert_eq!(tools.len(), 1);
         assert_eq!(tools[0].name(), "memory");
+
+        let entities = EntityStore::new(&temp_dir.path().join("agent-memory"));
+        let tools = create_memory_tools(memory, Some(entities));
+        let names: Vec<_> = tools.iter().map(|t| t.name().to_string()).collect();
+        assert_eq!(names, ["memory", "entities"]);
     }
 }
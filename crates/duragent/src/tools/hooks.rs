@@ -503,6 +503,7 @@ mod tests {
                     r#"{"action": "capture", "handle": "proc1"}"#,
                 )]),
                 tool_call_id: None,
+                reasoning: None,
             },
             Message::tool_result("call_background_process", "screen output here"),
         ];
@@ -543,6 +544,7 @@ mod tests {
                     r#"{"action": "capture", "handle": "proc2"}"#,
                 )]),
                 tool_call_id: None,
+                reasoning: None,
             },
             Message::tool_result("call_background_process", "screen output"),
         ];
@@ -587,6 +589,7 @@ mod tests {
                     r#"{"action": "recall", "days": 7}"#,
                 )]),
                 tool_call_id: None,
+                reasoning: None,
             },
             Message::tool_result("call_memory", "some memories"),
         ];
@@ -627,6 +630,7 @@ mod tests {
                     r#"{"action": "recall", "days": 7}"#,
                 )]),
                 tool_call_id: None,
+                reasoning: None,
             },
             Message::tool_result("call_memory", "some memories"),
         ];
@@ -667,6 +671,7 @@ mod tests {
                     r#"{"action": "recall", "days": 7}"#,
                 )]),
                 tool_call_id: None,
+                reasoning: None,
             },
             Message::tool_result("call_memory", "[result masked — ~100 tokens removed]"),
         ];
@@ -0,0 +1,216 @@
+//! Tool mocking.
+//!
+//! With `tool_mocks.enabled`, the listed tools are not run: their calls are
+//! answered with canned data from a fixture file, `{fixtures_dir}/{tool}.json`,
+//! so destructive tools can be exercised safely in development and staging.
+//! Policy still applies, so approvals can be exercised too.
+//!
+//! ```json
+//! {
+//!   "content": "Deployed build 1042 to staging",
+//!   "cases": [
+//!     { "arguments": { "env": "production" }, "success": false, "content": "Deploy frozen" }
+//!   ]
+//! }
+//! ```
+//!
+//! The first case whose `arguments` are all present, with the same values, in
+//! the call's arguments answers it; otherwise the top-level answer does.
+//! `content` that isn't a string is returned as JSON text.
+
+use std::collections::HashMap;
+use std::path::{Path, PathBuf};
+use std::sync::Arc;
+
+use serde::Deserialize;
+use serde_json::Value;
+use thiserror::Error;
+
+use super::executor::ToolResult;
+use crate::config::ToolMocksConfig;
+
+// ============================================================================
+// Types
+// ============================================================================
+
+#[derive(Debug, Error)]
+pub enum ToolMockError {
+    #[error("failed to read fixture for tool '{tool}' at {path}: {source}")]
+    Read {
+        tool: String,
+        path: PathBuf,
+        source: std::io::Error,
+    },
+
+    #[error("invalid fixture for tool '{tool}' at {path}: {source}")]
+    Parse {
+        tool: String,
+        path: PathBuf,
+        source: serde_json::Error,
+    },
+}
+
+/// Canned answers for one tool.
+#[derive(Debug, Clone, Deserialize)]
+struct Fixture {
+    #[serde(flatten)]
+    default: Answer,
+    #[serde(default)]
+    cases: Vec<Case>,
+}
+
+#[derive(Debug, Clone, Deserialize)]
+struct Case {
+    #[serde(default)]
+    arguments: serde_json::Map<String, Value>,
+    #[serde(flatten)]
+    answer: Answer,
+}
+
+#[derive(Debug, Clone, Deserialize)]
+struct Answer {
+    #[serde(default = "default_success")]
+    success: bool,
+    #[serde(default)]
+    content: Value,
+}
+
+fn default_success() -> bool {
+    true
+}
+
+impl Fixture {
+    fn respond(&self, arguments: &str) -> ToolResult {
+        let arguments: Value = serde_json::from_str(arguments).unwrap_or_default();
+        let answer = self
+            .cases
+            .iter()
+            .find(|case| {
+                case.arguments
+                    .iter()
+                    .all(|(key, value)| arguments.get(key) == Some(value))
+            })
+            .map_or(&self.default, |case| &case.answer);
+        let content = match &answer.content {
+            Value::String(text) => text.clone(),
+            Value::Null => String::new(),
+            other => other.to_string(),
+        };
+        ToolResult {
+            success: answer.success,
+            content,
+        }
+    }
+}
+
+// ============================================================================
+// ToolMocks
+// ============================================================================
+
+/// Fixtures of the mocked tools. Empty unless `tool_mocks.enabled` is set.
+#[derive(Debug, Clone, Default)]
+pub struct ToolMocks {
+    fixtures: Arc<HashMap<String, Fixture>>,
+}
+
+impl ToolMocks {
+    /// Load the fixture of every mocked tool from `fixtures_dir`.
+    pub fn from_config(
+        config: &ToolMocksConfig,
+        fixtures_dir: &Path,
+    ) -> Result<Self, ToolMockError> {
+        if !config.enabled {
+            return Ok(Self::default());
+        }
+        let mut fixtures = HashMap::new();
+        for tool in &config.tools {
+            let path = fixtures_dir.join(format!("{tool}.json"));
+            let content = std::fs::read(&path).map_err(|source| ToolMockError::Read {
+                tool: tool.clone(),
+                path: path.clone(),
+                source,
+            })?;
+            let fixture =
+                serde_json::from_slice(&content).map_err(|source| ToolMockError::Parse {
+                    tool: tool.clone(),
+                    path: path.clone(),
+                    source,
+                })?;
+            fixtures.insert(tool.clone(), fixture);
+        }
+        Ok(Self {
+            fixtures: Arc::new(fixtures),
+        })
+    }
+
+    pub fn is_empty(&self) -> bool {
+        self.fixtures.is_empty()
+    }
+
+    /// The canned answer to a call of `tool`, or `None` when it isn't mocked.
+    pub fn respond(&self, tool: &str, arguments: &str) -> Option<ToolResult> {
+        self.fixtures
+            .get(tool)
+            .map(|fixture| fixture.respond(arguments))
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use tempfile::TempDir;
+
+    fn config(tools: &[&str]) -> ToolMocksConfig {
+        ToolMocksConfig {
+            enabled: true,
+            fixtures_dir: None,
+            tools: tools.iter().map(|t| t.to_string()).collect(),
+        }
+    }
+
+    #[test]
+    fn mocked_tools_answer_from_fixtures() {
+        let tmp = TempDir::new().unwrap();
+        std::fs::write(
+            tmp.path().join("deploy.json"),
+            r#"{
+                "content": "Deployed build 1042 to staging",
+                "cases": [
+                    { "arguments": { "env": "production" }, "success": false, "content": "Deploy frozen" },
+                    { "arguments": { "env": "canary" }, "content": { "status": "ok" } }
+                ]
+            }"#,
+        )
+        .unwrap();
+        let mocks = ToolMocks::from_config(&config(&["deploy"]), tmp.path()).unwrap();
+
+        let result = mocks.respond("deploy", r#"{"env":"staging"}"#).unwrap();
+        assert!(result.success);
+        assert_eq!(result.content, "Deployed build 1042 to staging");
+
+        let result = mocks
+            .respond("deploy", r#"{"env":"production","force":true}"#)
+            .unwrap();
+        assert!(!result.success);
+        assert_eq!(result.content, "Deploy frozen");
+
+        let result = mocks.respond("deploy", r#"{"env":"canary"}"#).unwrap();
+        assert_eq!(result.content, r#"{"status":"ok"}"#);
+
+        assert!(mocks.respond("bash", "{}").is_none());
+    }
+
+    #[test]
+    fn missing_fixtures_fail_only_when_enabled() {
+        let tmp = TempDir::new().unwrap();
+        let mut config = config(&["deploy"]);
+        assert!(matches!(
+            ToolMocks::from_config(&config, tmp.path()),
+            Err(ToolMockError::Read { .. })
+        ));
+
+        config.enabled = false;
+        let mocks = ToolMocks::from_config(&config, tmp.path()).unwrap();
+        assert!(mocks.is_empty());
+    }
+}
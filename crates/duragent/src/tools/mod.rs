@@ -9,9 +9,11 @@ mod error;
 mod executor;
 mod factory;
 pub mod hooks;
+mod mocks;
 mod notify;
 mod tool;
 
+pub use builtins::plan::{PLAN_ACCEPTED, PLAN_TOOL, parse_plan};
 pub use builtins::schedule;
 pub use builtins::schedule::ToolExecutionContext;
 pub use error::ToolError;
@@ -21,5 +23,6 @@ pub use factory::{
     KNOWN_BUILTIN_TOOLS, ReloadDeps, ToolDependencies, build_executor, build_executor_async,
     create_tools,
 };
+pub use mocks::{ToolMockError, ToolMocks};
 pub use notify::send_notification;
 pub use tool::{SharedTool, Tool};
@@ -1,5 +1,7 @@
 //! Notification delivery for tool execution events.
 
+use std::sync::LazyLock;
+
 use serde::Serialize;
 use tracing::{debug, error, info, warn};
 
@@ -69,6 +71,12 @@ struct WebhookPayload<'a> {
     success: bool,
 }
 
+/// Shared webhook client, so notifications reuse pooled connections.
+fn webhook_client() -> &'static reqwest::Client {
+    static CLIENT: LazyLock<reqwest::Client> = LazyLock::new(reqwest::Client::new);
+    &CLIENT
+}
+
 /// Send a webhook notification (fire and forget).
 async fn send_webhook(url: &str, session_id: &str, agent: &str, command: &str, success: bool) {
     let payload = WebhookPayload {
@@ -79,8 +87,7 @@ async fn send_webhook(url: &str, session_id: &str, agent: &str, command: &str, s
         success,
     };
 
-    let client = reqwest::Client::new();
-    match client.post(url).json(&payload).send().await {
+    match webhook_client().post(url).json(&payload).send().await {
         Ok(response) => {
             if response.status().is_success() {
                 debug!(url = %url, "Webhook notification sent");
@@ -0,0 +1,230 @@
+//! Graceful in-place binary upgrades.
+//!
+//! Sending `SIGUSR2` to a running server, or calling the admin upgrade
+//! endpoint, starts the binary at the same path with the same arguments and
+//! hands it the listening socket. The new process loads its config and agents,
+//! then sends `SIGTERM` to the old one and waits for it to drain and flush its
+//! sessions before recovering them. Connections that arrive in between queue
+//! on the shared socket instead of being refused.
+//!
+//! If the new process fails before it signals, the old one keeps serving.
+
+use std::path::PathBuf;
+use std::sync::Arc;
+use std::sync::atomic::{AtomicBool, Ordering};
+use std::time::Duration;
+
+use anyhow::Result;
+use tokio::sync::Notify;
+use tracing::{info, warn};
+
+/// Environment variable carrying the inherited listening socket's fd.
+pub const LISTEN_FD_ENV: &str = "DURAGENT_LISTEN_FD";
+
+/// Environment variable carrying the pid of the process being replaced.
+pub const UPGRADE_FROM_ENV: &str = "DURAGENT_UPGRADE_FROM";
+
+/// How often to check whether the previous process has exited.
+const DRAIN_POLL_INTERVAL: Duration = Duration::from_millis(100);
+
+// ============================================================================
+// Successor Side
+// ============================================================================
+
+/// Take over the listening socket handed down by a previous process, if any.
+#[cfg(unix)]
+pub fn inherited_listener() -> Result<Option<std::net::TcpListener>> {
+    use std::os::fd::{FromRawFd, RawFd};
+
+    let Ok(raw) = std::env::var(LISTEN_FD_ENV) else {
+        return Ok(None);
+    };
+    let fd: RawFd = raw
+        .parse()
+        .map_err(|_| anyhow::anyhow!("invalid {LISTEN_FD_ENV}: {raw}"))?;
+
+    // SAFETY: the previous process passed this fd to us for exactly this
+    // purpose and nothing else in this process owns it.
+    let listener = unsafe { std::net::TcpListener::from_raw_fd(fd) };
+    // Keep the socket out of subprocesses we spawn (gateways, tools).
+    set_cloexec(fd, true)?;
+    listener.set_nonblocking(true)?;
+    Ok(Some(listener))
+}
+
+#[cfg(not(unix))]
+pub fn inherited_listener() -> Result<Option<std::net::TcpListener>> {
+    Ok(None)
+}
+
+/// Ask the process we are replacing to drain, then wait until it exits.
+///
+/// Does nothing unless this process was started by an upgrade. The previous
+/// process is our parent, so a changed parent pid means it is gone.
+#[cfg(unix)]
+pub async fn drain_previous() {
+    let Some(pid) = std::env::var(UPGRADE_FROM_ENV)
+        .ok()
+        .and_then(|v| v.parse::<libc::pid_t>().ok())
+    else {
+        return;
+    };
+
+    // SAFETY: getppid has no preconditions.
+    if unsafe { libc::getppid() } != pid {
+        warn!(pid, "Previous server is not our parent, not signalling it");
+        return;
+    }
+
+    info!(pid, "Draining previous server");
+    // SAFETY: pid is our live parent, checked above.
+    unsafe {
+        libc::kill(pid, libc::SIGTERM);
+    }
+
+    // SAFETY: getppid has no preconditions.
+    while unsafe { libc::getppid() } == pid {
+        tokio::time::sleep(DRAIN_POLL_INTERVAL).await;
+    }
+    info!(pid, "Previous server exited, taking over");
+}
+
+#[cfg(not(unix))]
+pub async fn drain_previous() {}
+
+// ============================================================================
+// Predecessor Side
+// ============================================================================
+
+/// Requests an upgrade from outside the signal handler, e.g. the admin API.
+#[derive(Debug, Clone, Default)]
+pub struct UpgradeTrigger {
+    inner: Arc<TriggerInner>,
+}
+
+#[derive(Debug, Default)]
+struct TriggerInner {
+    notify: Notify,
+    in_progress: AtomicBool,
+}
+
+impl UpgradeTrigger {
+    /// Whether this platform supports socket handoff.
+    pub fn is_supported(&self) -> bool {
+        cfg!(unix)
+    }
+
+    /// Start an upgrade. Returns `false` if one is already in progress.
+    pub fn trigger(&self) -> bool {
+        if self.inner.in_progress.swap(true, Ordering::SeqCst) {
+            return false;
+        }
+        self.inner.notify.notify_one();
+        true
+    }
+}
+
+/// Start a new server process on `SIGUSR2` or [`UpgradeTrigger::trigger`],
+/// handing it `listener`.
+///
+/// Only one upgrade runs at a time. If the new process exits before taking
+/// over, the error is logged and a later request can try again.
+#[cfg(unix)]
+pub fn spawn_upgrade_listener(listener: std::os::fd::RawFd, trigger: UpgradeTrigger) {
+    use tokio::signal::unix::{SignalKind, signal};
+
+    let mut sigusr2 = match signal(SignalKind::user_defined2()) {
+        Ok(sig) => Some(sig),
+        Err(e) => {
+            warn!(error = %e, "Failed to install SIGUSR2 handler");
+            None
+        }
+    };
+
+    // Not tracked in BackgroundTasks: waiting on the successor must not hold
+    // up our own shutdown, which the successor triggers.
+    tokio::spawn(async move {
+        loop {
+            tokio::select! {
+                Some(()) = async { sigusr2.as_mut()?.recv().await } => {
+                    if !trigger.trigger() {
+                        warn!("Received SIGUSR2, but an upgrade is already in progress");
+                    }
+                }
+                () = trigger.inner.notify.notified() => {
+                    info!("Starting upgraded server");
+                    run_successor(listener).await;
+                    trigger.inner.in_progress.store(false, Ordering::SeqCst);
+                }
+            }
+        }
+    });
+}
+
+/// Spawn the successor and wait for it. Returning means it failed, since a
+/// successful successor ends this process before exiting itself.
+#[cfg(unix)]
+async fn run_successor(listener: std::os::fd::RawFd) {
+    let mut child = match spawn_successor(listener) {
+        Ok(child) => child,
+        Err(e) => {
+            warn!(error = %e, "Failed to start upgraded server");
+            return;
+        }
+    };
+    match child.wait().await {
+        Ok(status) => warn!(%status, "Upgraded server exited before taking over"),
+        Err(e) => warn!(error = %e, "Failed to wait for upgraded server"),
+    }
+}
+
+#[cfg(unix)]
+fn spawn_successor(listener: std::os::fd::RawFd) -> std::io::Result<tokio::process::Child> {
+    let mut cmd = tokio::process::Command::new(current_binary()?);
+    cmd.args(std::env::args_os().skip(1))
+        .env(LISTEN_FD_ENV, listener.to_string())
+        .env(UPGRADE_FROM_ENV, std::process::id().to_string());
+
+    // SAFETY: pre_exec runs in the forked child before exec. fcntl is
+    // async-signal-safe, and clearing FD_CLOEXEC only in the child keeps the
+    // socket from leaking into other subprocesses.
+    unsafe {
+        cmd.pre_exec(move || set_cloexec(listener, false));
+    }
+
+    cmd.spawn()
+}
+
+/// Path of the running binary, as installed now.
+///
+/// `duragent upgrade` replaces the binary in place. Linux then reports the
+/// old inode as `<path> (deleted)`; the new binary lives at `<path>`.
+#[cfg(unix)]
+fn current_binary() -> std::io::Result<PathBuf> {
+    let exe = std::env::current_exe()?;
+    let path = exe.to_string_lossy();
+    Ok(match path.strip_suffix(" (deleted)") {
+        Some(installed) => PathBuf::from(installed),
+        None => exe,
+    })
+}
+
+#[cfg(unix)]
+fn set_cloexec(fd: std::os::fd::RawFd, on: bool) -> std::io::Result<()> {
+    // SAFETY: fcntl on an fd we own, with no pointers involved.
+    unsafe {
+        let flags = libc::fcntl(fd, libc::F_GETFD);
+        if flags == -1 {
+            return Err(std::io::Error::last_os_error());
+        }
+        let flags = if on {
+            flags | libc::FD_CLOEXEC
+        } else {
+            flags & !libc::FD_CLOEXEC
+        };
+        if libc::fcntl(fd, libc::F_SETFD, flags) == -1 {
+            return Err(std::io::Error::last_os_error());
+        }
+    }
+    Ok(())
+}
@@ -0,0 +1,319 @@
+//! Token usage rollups.
+//!
+//! Every assistant response with usage is counted into an in-memory hourly
+//! bucket per agent. A background job periodically merges those buckets into
+//! hourly and daily rollups in the [`UsageStore`], so usage queries read a few
+//! small files instead of scanning session event logs. Hourly rollups are
+//! pruned after a retention period; daily rollups are kept.
+
+use std::collections::HashMap;
+use std::sync::{Arc, Mutex};
+use std::time::Duration;
+
+use chrono::{DateTime, DurationRound, TimeDelta, Utc};
+use serde::{Deserialize, Serialize};
+use tracing::{debug, warn};
+
+use crate::config::UsageConfig;
+use crate::llm::Usage;
+use crate::store::UsageStore;
+
+// ============================================================================
+// Types
+// ============================================================================
+
+/// Width of a rollup bucket.
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Hash, Default, Serialize, Deserialize)]
+#[serde(rename_all = "snake_case")]
+pub enum Granularity {
+    Hour,
+    #[default]
+    Day,
+}
+
+impl Granularity {
+    fn width(self) -> TimeDelta {
+        match self {
+            Granularity::Hour => TimeDelta::hours(1),
+            Granularity::Day => TimeDelta::days(1),
+        }
+    }
+
+    /// Start of the bucket containing `at`.
+    pub fn truncate(self, at: DateTime<Utc>) -> DateTime<Utc> {
+        at.duration_trunc(self.width()).unwrap_or(at)
+    }
+}
+
+/// Summed usage over a set of responses.
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq, Serialize, Deserialize)]
+pub struct UsageTotals {
+    /// Assistant responses that reported usage.
+    pub responses: u64,
+    pub prompt_tokens: u64,
+    pub completion_tokens: u64,
+    pub total_tokens: u64,
+}
+
+impl UsageTotals {
+    fn from_usage(usage: &Usage) -> Self {
+        Self {
+            responses: 1,
+            prompt_tokens: u64::from(usage.prompt_tokens),
+            completion_tokens: u64::from(usage.completion_tokens),
+            total_tokens: u64::from(usage.total_tokens),
+        }
+    }
+
+    pub fn add(&mut self, other: &UsageTotals) {
+        self.responses += other.responses;
+        self.prompt_tokens += other.prompt_tokens;
+        self.completion_tokens += other.completion_tokens;
+        self.total_tokens += other.total_tokens;
+    }
+}
+
+/// Usage for one agent over one bucket.
+#[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
+pub struct UsageBucket {
+    /// Start of the bucket (UTC).
+    pub start: DateTime<Utc>,
+    pub agent: String,
+    #[serde(flatten)]
+    pub totals: UsageTotals,
+}
+
+type BucketKey = (DateTime<Utc>, String);
+
+/// Sum buckets that share a start and agent, sorted by start then agent.
+pub fn merge_buckets(buckets: impl IntoIterator<Item = UsageBucket>) -> Vec<UsageBucket> {
+    let mut merged: HashMap<BucketKey, UsageTotals> = HashMap::new();
+    for bucket in buckets {
+        merged
+            .entry((bucket.start, bucket.agent))
+            .or_default()
+            .add(&bucket.totals);
+    }
+    let mut buckets: Vec<UsageBucket> = merged
+        .into_iter()
+        .map(|((start, agent), totals)| UsageBucket {
+            start,
+            agent,
+            totals,
+        })
+        .collect();
+    buckets.sort_by(|a, b| (a.start, &a.agent).cmp(&(b.start, &b.agent)));
+    buckets
+}
+
+/// Re-bucket hourly buckets at `granularity`.
+fn rebucket(buckets: &[UsageBucket], granularity: Granularity) -> Vec<UsageBucket> {
+    merge_buckets(buckets.iter().map(|b| UsageBucket {
+        start: granularity.truncate(b.start),
+        agent: b.agent.clone(),
+        totals: b.totals,
+    }))
+}
+
+// ============================================================================
+// UsageRollups
+// ============================================================================
+
+/// Collects usage in memory and rolls it up into the store.
+///
+/// Uses `std::sync::Mutex` because the lock is never held across await points.
+#[derive(Clone)]
+pub struct UsageRollups {
+    pending: Arc<Mutex<HashMap<BucketKey, UsageTotals>>>,
+    store: Arc<dyn UsageStore>,
+}
+
+impl UsageRollups {
+    pub fn new(store: Arc<dyn UsageStore>) -> Self {
+        Self {
+            pending: Arc::new(Mutex::new(HashMap::new())),
+            store,
+        }
+    }
+
+    /// Count one response's usage for `agent`.
+    pub fn record(&self, agent: &str, usage: &Usage, at: DateTime<Utc>) {
+        let key = (Granularity::Hour.truncate(at), agent.to_string());
+        self.pending
+            .lock()
+            .unwrap()
+            .entry(key)
+            .or_default()
+            .add(&UsageTotals::from_usage(usage));
+    }
+
+    /// Usage not yet rolled up, as hourly buckets.
+    fn pending_buckets(&self) -> Vec<UsageBucket> {
+        let pending = self.pending.lock().unwrap();
+        merge_buckets(pending.iter().map(|((start, agent), totals)| UsageBucket {
+            start: *start,
+            agent: agent.clone(),
+            totals: *totals,
+        }))
+    }
+
+    /// Merge pending usage into the stored hourly and daily rollups.
+    ///
+    /// If the hourly merge fails, usage stays pending for the next flush.
+    pub async fn flush(&self) {
+        let drained: Vec<UsageBucket> = {
+            let mut pending = self.pending.lock().unwrap();
+            pending
+                .drain()
+                .map(|((start, agent), totals)| UsageBucket {
+                    start,
+                    agent,
+                    totals,
+                })
+                .collect()
+        };
+        if drained.is_empty() {
+            return;
+        }
+
+        if let Err(e) = self.store.merge(Granularity::Hour, &drained).await {
+            warn!(error = %e, "Failed to roll up hourly usage, will retry");
+            let mut pending = self.pending.lock().unwrap();
+            for bucket in drained {
+                pending
+                    .entry((bucket.start, bucket.agent))
+                    .or_default()
+                    .add(&bucket.totals);
+            }
+            return;
+        }
+
+        let daily = rebucket(&drained, Granularity::Day);
+        if let Err(e) = self.store.merge(Granularity::Day, &daily).await {
+            warn!(error = %e, "Failed to roll up daily usage");
+        }
+        debug!(buckets = drained.len(), "Rolled up usage");
+    }
+
+    /// Usage per bucket in `[from, to)`, including usage not yet rolled up.
+    pub async fn query(
+        &self,
+        granularity: Granularity,
+        from: DateTime<Utc>,
+        to: DateTime<Utc>,
+    ) -> crate::store::StorageResult<Vec<UsageBucket>> {
+        let from = granularity.truncate(from);
+        let stored = self.store.load(granularity, from, to).await?;
+        let pending = rebucket(&self.pending_buckets(), granularity)
+            .into_iter()
+            .filter(|b| b.start >= from && b.start < to);
+        Ok(merge_buckets(stored.into_iter().chain(pending)))
+    }
+
+    /// Delete hourly rollups older than `retention_days`.
+    async fn prune(&self, retention_days: u32) {
+        if retention_days == 0 {
+            return;
+        }
+        let cutoff = Utc::now() - TimeDelta::days(i64::from(retention_days));
+        match self.store.prune(Granularity::Hour, cutoff).await {
+            Ok(0) => {}
+            Ok(removed) => debug!(removed, "Pruned hourly usage rollups"),
+            Err(e) => warn!(error = %e, "Failed to prune hourly usage rollups"),
+        }
+    }
+}
+
+/// Spawn the periodic rollup and pruning loop.
+///
+/// Call [`UsageRollups::flush`] once more on shutdown; the loop itself is
+/// dropped with the runtime.
+pub fn spawn_rollup_job(rollups: UsageRollups, config: &UsageConfig) {
+    let interval = Duration::from_secs(config.rollup_interval_seconds.max(1));
+    let retention_days = config.hourly_retention_days;
+
+    tokio::spawn(async move {
+        let mut interval = tokio::time::interval(interval);
+        interval.tick().await; // skip immediate tick
+        loop {
+            interval.tick().await;
+            rollups.flush().await;
+            rollups.prune(retention_days).await;
+        }
+    });
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::store::file::FileUsageStore;
+    use tempfile::TempDir;
+
+    fn usage(prompt: u32, completion: u32) -> Usage {
+        Usage {
+            prompt_tokens: prompt,
+            completion_tokens: completion,
+            total_tokens: prompt + completion,
+        }
+    }
+
+    fn at(s: &str) -> DateTime<Utc> {
+        DateTime::parse_from_rfc3339(s).unwrap().with_timezone(&Utc)
+    }
+
+    #[tokio::test]
+    async fn flush_rolls_up_hourly_and_daily() {
+        let tmp = TempDir::new().unwrap();
+        let rollups = UsageRollups::new(Arc::new(FileUsageStore::new(tmp.path())));
+
+        rollups.record("a", &usage(10, 5), at("2026-03-01T10:15:00Z"));
+        rollups.record("a", &usage(20, 5), at("2026-03-01T10:45:00Z"));
+        rollups.record("a", &usage(1, 1), at("2026-03-01T13:00:00Z"));
+        rollups.record("b", &usage(7, 0), at("2026-03-02T00:30:00Z"));
+        rollups.flush().await;
+        // A second flush with the same hour adds to the stored bucket
+        rollups.record("a", &usage(100, 0), at("2026-03-01T10:50:00Z"));
+        rollups.flush().await;
+
+        let hourly = rollups
+            .query(
+                Granularity::Hour,
+                at("2026-03-01T00:00:00Z"),
+                at("2026-03-02T00:00:00Z"),
+            )
+            .await
+            .unwrap();
+        assert_eq!(hourly.len(), 2);
+        assert_eq!(hourly[0].start, at("2026-03-01T10:00:00Z"));
+        assert_eq!(hourly[0].totals.responses, 3);
+        assert_eq!(hourly[0].totals.prompt_tokens, 130);
+
+        let daily = rollups
+            .query(
+                Granularity::Day,
+                at("2026-03-01T00:00:00Z"),
+                at("2026-03-03T00:00:00Z"),
+            )
+            .await
+            .unwrap();
+        assert_eq!(daily.len(), 2);
+        assert_eq!(daily[0].agent, "a");
+        assert_eq!(daily[0].totals.responses, 4);
+        assert_eq!(daily[1].agent, "b");
+    }
+
+    #[tokio::test]
+    async fn query_includes_pending_usage() {
+        let tmp = TempDir::new().unwrap();
+        let rollups = UsageRollups::new(Arc::new(FileUsageStore::new(tmp.path())));
+        let now = Utc::now();
+
+        rollups.record("a", &usage(3, 4), now);
+        let daily = rollups
+            .query(Granularity::Day, now, now + TimeDelta::hours(1))
+            .await
+            .unwrap();
+        assert_eq!(daily.len(), 1);
+        assert_eq!(daily[0].totals.total_tokens, 7);
+    }
+}
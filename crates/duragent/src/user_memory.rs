@@ -0,0 +1,492 @@
+//! Long-term memory of end users.
+//!
+//! Agents that enable `memory.user` learn facts about the people they talk
+//! to and recall them in later sessions:
+//!
+//! ```yaml
+//! memory:
+//!   user:
+//!     recall: 5
+//!     embedding_model: text-embedding-3-small
+//! ```
+//!
+//! After each user message, the agent's provider extracts durable facts
+//! ("Prefers metric units") in the background. Before each request, the
+//! facts most similar to the message, or the most recent ones without an
+//! embedding model, are added to the context. Facts are keyed by the
+//! session's user: the `user` given when an API session is created, or the
+//! sender of a direct gateway chat. Facts can be listed, edited, and deleted
+//! through the API so users can see what is remembered about them.
+
+use std::sync::Arc;
+
+use chrono::{DateTime, Utc};
+use serde::{Deserialize, Serialize};
+use tracing::{debug, warn};
+
+use crate::agent::{AgentSpec, UserMemoryConfig};
+use crate::context::{BlockSource, SystemBlock, priority};
+use crate::examples::{Embedding, cosine_similarity};
+use crate::llm::{ChatRequest, LLMError, LLMProvider, Message, Role};
+use crate::postprocess::extract_json;
+use crate::store::{StorageResult, UserFactStore};
+
+/// ID prefix for user facts.
+pub const FACT_ID_PREFIX: &str = "fact_";
+
+/// Instructions for the fact extraction request.
+const EXTRACTION_PROMPT: &str = "You maintain long-term memory about a user. From the user's message, extract durable facts about them that would help in future conversations: preferences, details they chose to share, goals, and ongoing projects. Skip small talk, questions, and anything that only matters right now. Reply with only a JSON array of short statements in the third person, such as [\"Prefers metric units\"]. Reply with [] if there is nothing new to remember.";
+
+// ============================================================================
+// Types
+// ============================================================================
+
+/// A fact remembered about a user.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct UserFact {
+    pub id: String,
+    pub content: String,
+    /// Agent that learned the fact, or `None` when added through the API.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub agent: Option<String>,
+    pub created_at: DateTime<Utc>,
+    pub updated_at: DateTime<Utc>,
+    /// Cached embedding of `content`, for similarity recall.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub embedding: Option<Embedding>,
+}
+
+impl UserFact {
+    pub fn new(content: String, agent: Option<String>) -> Self {
+        let now = Utc::now();
+        Self {
+            id: format!("{FACT_ID_PREFIX}{}", ulid::Ulid::new()),
+            content,
+            agent,
+            created_at: now,
+            updated_at: now,
+            embedding: None,
+        }
+    }
+}
+
+// ============================================================================
+// UserMemory
+// ============================================================================
+
+/// Facts about end users, and their extraction and recall.
+#[derive(Clone)]
+pub struct UserMemory {
+    store: Arc<dyn UserFactStore>,
+}
+
+impl UserMemory {
+    pub fn new(store: Arc<dyn UserFactStore>) -> Self {
+        Self { store }
+    }
+
+    pub async fn list(&self, user: &str) -> StorageResult<Vec<UserFact>> {
+        self.store.list(user).await
+    }
+
+    pub async fn get(&self, user: &str, id: &str) -> StorageResult<Option<UserFact>> {
+        self.store.load(user, id).await
+    }
+
+    pub async fn create(
+        &self,
+        user: &str,
+        content: String,
+        agent: Option<String>,
+    ) -> StorageResult<UserFact> {
+        let fact = UserFact::new(content, agent);
+        self.store.save(user, &fact).await?;
+        Ok(fact)
+    }
+
+    /// Replace a fact's content. Returns `None` if it doesn't exist.
+    pub async fn update(
+        &self,
+        user: &str,
+        id: &str,
+        content: String,
+    ) -> StorageResult<Option<UserFact>> {
+        let Some(mut fact) = self.store.load(user, id).await? else {
+            return Ok(None);
+        };
+        if fact.content != content {
+            fact.embedding = None;
+        }
+        fact.content = content;
+        fact.updated_at = Utc::now();
+        self.store.save(user, &fact).await?;
+        Ok(Some(fact))
+    }
+
+    /// Delete a fact. Returns whether it existed.
+    pub async fn delete(&self, user: &str, id: &str) -> StorageResult<bool> {
+        if self.store.load(user, id).await?.is_none() {
+            return Ok(false);
+        }
+        self.store.delete(user, id).await?;
+        Ok(true)
+    }
+
+    /// Forget everything about a user. Returns how many facts were deleted.
+    pub async fn forget(&self, user: &str) -> StorageResult<usize> {
+        let count = self.store.list(user).await?.len();
+        self.store.delete_all(user).await?;
+        Ok(count)
+    }
+
+    /// Facts to show the agent for `input`.
+    ///
+    /// Never fails: unreadable facts are skipped, and similarity recall falls
+    /// back to the most recent facts when embedding fails.
+    pub async fn recall(
+        &self,
+        agent: &AgentSpec,
+        provider: &dyn LLMProvider,
+        user: &str,
+        input: &str,
+    ) -> Vec<UserFact> {
+        let Some(config) = user_config(agent) else {
+            return Vec::new();
+        };
+        let mut facts = match self.store.list(user).await {
+            Ok(facts) => facts,
+            Err(e) => {
+                warn!(agent = %agent.metadata.name, user, error = %e, "Failed to load user facts");
+                return Vec::new();
+            }
+        };
+        facts.reverse();
+
+        if let Some(model) = config.embedding_model.as_deref()
+            && let Err(e) = self
+                .rank_by_similarity(provider, model, user, input, &mut facts)
+                .await
+        {
+            warn!(agent = %agent.metadata.name, error = %e, "User fact similarity recall failed; using recent facts");
+        }
+
+        facts.truncate(config.recall);
+        facts
+    }
+
+    /// System block with the facts recalled for the conversation's latest
+    /// user message, or `None` without a user or facts.
+    pub async fn block_for(
+        &self,
+        agent: &AgentSpec,
+        provider: &dyn LLMProvider,
+        user: Option<&str>,
+        messages: &[Message],
+    ) -> Option<SystemBlock> {
+        user_config(agent)?;
+        let user = user?;
+        let input = latest_user_message(messages).unwrap_or_default();
+        user_memory_block(&self.recall(agent, provider, user, input).await)
+    }
+
+    /// Extract facts from the conversation's latest user message in the
+    /// background. Does nothing without a user or when the agent has no
+    /// user memory.
+    pub fn learn(
+        &self,
+        agent: &AgentSpec,
+        provider: Arc<dyn LLMProvider>,
+        user: Option<&str>,
+        messages: &[Message],
+    ) {
+        let (Some(config), Some(user), Some(message)) =
+            (user_config(agent), user, latest_user_message(messages))
+        else {
+            return;
+        };
+        let memory = self.clone();
+        let agent_name = agent.metadata.name.clone();
+        let model = config
+            .extraction_model
+            .clone()
+            .unwrap_or_else(|| agent.model.name.clone());
+        let user = user.to_string();
+        let message = message.to_string();
+        tokio::spawn(async move {
+            match memory
+                .extract(provider.as_ref(), &model, &agent_name, &user, &message)
+                .await
+            {
+                Ok(0) => {}
+                Ok(count) => {
+                    debug!(agent = %agent_name, user = %user, count, "Remembered user facts")
+                }
+                Err(e) => {
+                    warn!(agent = %agent_name, user = %user, error = %e, "Failed to extract user facts")
+                }
+            }
+        });
+    }
+
+    /// Extract facts from `message` and save the new ones. Returns how many
+    /// were saved.
+    async fn extract(
+        &self,
+        provider: &dyn LLMProvider,
+        model: &str,
+        agent: &str,
+        user: &str,
+        message: &str,
+    ) -> anyhow::Result<usize> {
+        let known = self.store.list(user).await?;
+        let mut prompt = EXTRACTION_PROMPT.to_string();
+        if !known.is_empty() {
+            prompt.push_str("\n\nAlready known, do not repeat:\n");
+            for fact in &known {
+                prompt.push_str(&format!("- {}\n", fact.content));
+            }
+        }
+        let request = ChatRequest::new(
+            model,
+            vec![
+                Message::text(Role::System, prompt),
+                Message::text(Role::User, message),
+            ],
+            Some(0.0),
+            Some(512),
+        );
+        let response = provider.chat(request).await?;
+        let reply = response
+            .choices
+            .first()
+            .and_then(|c| c.message.content.as_deref())
+            .unwrap_or_default();
+        let Some(facts) =
+            extract_json(reply).and_then(|json| serde_json::from_str::<Vec<String>>(json).ok())
+        else {
+            return Ok(0);
+        };
+
+        let mut saved = 0;
+        for content in facts {
+            let content = content.trim();
+            let duplicate = known
+                .iter()
+                .any(|f| f.content.trim().eq_ignore_ascii_case(content));
+            if content.is_empty() || duplicate {
+                continue;
+            }
+            self.create(user, content.to_string(), Some(agent.to_string()))
+                .await?;
+            saved += 1;
+        }
+        Ok(saved)
+    }
+
+    /// Sort `facts` most similar to `input` first, embedding facts that have
+    /// no embedding for `model` yet.
+    async fn rank_by_similarity(
+        &self,
+        provider: &dyn LLMProvider,
+        model: &str,
+        user: &str,
+        input: &str,
+        facts: &mut [UserFact],
+    ) -> Result<(), LLMError> {
+        let stale: Vec<usize> = facts
+            .iter()
+            .enumerate()
+            .filter(|(_, f)| f.embedding.as_ref().is_none_or(|emb| emb.model != model))
+            .map(|(i, _)| i)
+            .collect();
+
+        // Embed the input and any stale facts in one request.
+        let mut texts = vec![input.to_string()];
+        texts.extend(stale.iter().map(|&i| facts[i].content.clone()));
+        let mut vectors = provider.embed(model, texts).await?.into_iter();
+        let query = vectors.next().unwrap_or_default();
+
+        for (&i, vector) in stale.iter().zip(vectors) {
+            let fact = &mut facts[i];
+            fact.embedding = Some(Embedding {
+                model: model.to_string(),
+                vector,
+            });
+            if let Err(e) = self.store.save(user, fact).await {
+                warn!(user, fact = %fact.id, error = %e, "Failed to cache user fact embedding");
+            }
+        }
+
+        let score = |fact: &UserFact| {
+            fact.embedding
+                .as_ref()
+                .map_or(0.0, |emb| cosine_similarity(&query, &emb.vector))
+        };
+        facts.sort_by(|a, b| score(b).total_cmp(&score(a)));
+        Ok(())
+    }
+}
+
+/// Render facts as a system block, or `None` when there are none.
+pub fn user_memory_block(facts: &[UserFact]) -> Option<SystemBlock> {
+    if facts.is_empty() {
+        return None;
+    }
+    let mut content = String::from(
+        "<user_memory>\nFacts remembered about this user from earlier conversations:\n",
+    );
+    for fact in facts {
+        content.push_str(&format!("- {}\n", fact.content));
+    }
+    content.push_str("</user_memory>");
+    Some(SystemBlock {
+        content,
+        label: "user_memory".to_string(),
+        source: BlockSource::Runtime,
+        priority: priority::USER_MEMORY,
+    })
+}
+
+fn user_config(agent: &AgentSpec) -> Option<&UserMemoryConfig> {
+    agent.memory.as_ref()?.user.as_ref()
+}
+
+fn latest_user_message(messages: &[Message]) -> Option<&str> {
+    messages
+        .iter()
+        .rev()
+        .find(|m| m.role == Role::User)
+        .and_then(|m| m.content.as_deref())
+}
+
+#[cfg(test)]
+mod tests {
+    use tempfile::TempDir;
+
+    use super::*;
+    use crate::agent::AgentMemoryConfig;
+    use crate::llm::MockProvider;
+    use crate::store::file::FileUserFactStore;
+
+    fn agent(embedding_model: Option<&str>) -> AgentSpec {
+        let yaml = r#"apiVersion: duragent/v1alpha1
+kind: Agent
+metadata:
+  name: helper
+spec:
+  model:
+    provider: mock
+    name: echo
+"#;
+        let mut spec = crate::agent::parse_agent_yaml(
+            yaml,
+            Default::default(),
+            Vec::new(),
+            Default::default(),
+            std::path::PathBuf::from("/tmp/helper"),
+            None,
+        )
+        .unwrap();
+        spec.memory = Some(AgentMemoryConfig {
+            backend: "filesystem".to_string(),
+            user: Some(UserMemoryConfig {
+                recall: 1,
+                embedding_model: embedding_model.map(str::to_string),
+                extraction_model: None,
+            }),
+            entities: None,
+        });
+        spec
+    }
+
+    fn memory(tmp: &TempDir) -> UserMemory {
+        UserMemory::new(Arc::new(FileUserFactStore::new(tmp.path())))
+    }
+
+    #[tokio::test]
+    async fn extract_saves_new_facts_only() {
+        let tmp = TempDir::new().unwrap();
+        let memory = memory(&tmp);
+        memory
+            .create("alice", "Prefers metric units".into(), None)
+            .await
+            .unwrap();
+
+        // The mock provider echoes the message, so it "extracts" these facts.
+        let message = r#"["prefers metric units", "Lives in Lisbon"]"#;
+        let saved = memory
+            .extract(&MockProvider, "echo", "helper", "alice", message)
+            .await
+            .unwrap();
+        assert_eq!(saved, 1);
+
+        let facts = memory.list("alice").await.unwrap();
+        assert_eq!(facts.len(), 2);
+        assert_eq!(facts[1].content, "Lives in Lisbon");
+        assert_eq!(facts[1].agent.as_deref(), Some("helper"));
+
+        let saved = memory
+            .extract(&MockProvider, "echo", "helper", "alice", "hello there")
+            .await
+            .unwrap();
+        assert_eq!(saved, 0);
+    }
+
+    #[tokio::test]
+    async fn recall_prefers_similar_or_recent_facts() {
+        let tmp = TempDir::new().unwrap();
+        let memory = memory(&tmp);
+        for content in ["Lives in Lisbon", "Has a dog named Rex"] {
+            memory.create("alice", content.into(), None).await.unwrap();
+        }
+
+        let recent = memory
+            .recall(&agent(None), &MockProvider, "alice", "where do I live")
+            .await;
+        assert_eq!(recent[0].content, "Has a dog named Rex");
+
+        let similar = memory
+            .recall(
+                &agent(Some("mock-embed")),
+                &MockProvider,
+                "alice",
+                "where is Lisbon",
+            )
+            .await;
+        assert_eq!(similar[0].content, "Lives in Lisbon");
+
+        assert!(
+            memory
+                .recall(&agent(None), &MockProvider, "bob", "hi")
+                .await
+                .is_empty()
+        );
+    }
+
+    #[tokio::test]
+    async fn forget_deletes_all_facts() {
+        let tmp = TempDir::new().unwrap();
+        let memory = memory(&tmp);
+        memory
+            .create("alice", "Likes tea".into(), None)
+            .await
+            .unwrap();
+        memory
+            .create("alice", "Likes jazz".into(), None)
+            .await
+            .unwrap();
+
+        assert_eq!(memory.forget("alice").await.unwrap(), 2);
+        assert!(memory.list("alice").await.unwrap().is_empty());
+        assert_eq!(memory.forget("alice").await.unwrap(), 0);
+    }
+
+    #[test]
+    fn block_lists_facts() {
+        let fact = UserFact::new("Likes tea".into(), None);
+        let block = user_memory_block(&[fact]).unwrap();
+        assert_eq!(block.priority, priority::USER_MEMORY);
+        assert!(block.content.contains("- Likes tea"));
+        assert!(user_memory_block(&[]).is_none());
+    }
+}
@@ -63,6 +63,125 @@ async fn test_version() {
     assert!(json.get("version").is_some());
 }
 
+#[tokio::test]
+async fn test_meta() {
+    let app = test_app().await;
+
+    let response = app
+        .oneshot(Request::get("/api/v1/meta").body(Body::empty()).unwrap())
+        .await
+        .unwrap();
+
+    assert_eq!(response.status(), StatusCode::OK);
+
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    let json: serde_json::Value = serde_json::from_slice(&body).unwrap();
+
+    assert!(json["version"].is_string());
+    assert_eq!(json["providers"], serde_json::json!(["mock"]));
+    assert_eq!(json["auth"]["api"], "loopback");
+    assert_eq!(json["auth"]["admin"], "loopback");
+    assert_eq!(json["features"]["scheduler"], false);
+    assert_eq!(json["limits"]["max_connections"], 1024);
+    assert!(json["limits"].get("max_concurrent_runs").is_none());
+}
+
+#[cfg(unix)]
+#[tokio::test]
+async fn test_upgrade_runs_one_at_a_time() {
+    let app = test_app().await;
+
+    let upgrade = || {
+        Request::post("/api/admin/v1/upgrade")
+            .body(Body::empty())
+            .unwrap()
+    };
+    let response = app.clone().oneshot(upgrade()).await.unwrap();
+    assert_eq!(response.status(), StatusCode::ACCEPTED);
+
+    // Nothing consumes the trigger in tests, so the first upgrade stays pending
+    let response = app.oneshot(upgrade()).await.unwrap();
+    assert_eq!(response.status(), StatusCode::CONFLICT);
+}
+
+#[tokio::test]
+async fn test_feature_flags() {
+    let app = test_app().await;
+
+    let response = app
+        .clone()
+        .oneshot(
+            Request::get("/api/admin/v1/features")
+                .body(Body::empty())
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    let json: serde_json::Value = serde_json::from_slice(&body).unwrap();
+    let features = json["features"].as_array().unwrap();
+    assert!(
+        features
+            .iter()
+            .any(|f| f["name"] == "workflows" && f["enabled"] == false)
+    );
+
+    let response = app
+        .clone()
+        .oneshot(
+            Request::put("/api/admin/v1/features/workflows")
+                .header("content-type", "application/json")
+                .body(Body::from(r#"{"enabled":true}"#))
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    let json: serde_json::Value = serde_json::from_slice(&body).unwrap();
+    assert_eq!(json["enabled"], true);
+
+    // Enabled flags show up in the meta endpoint
+    let response = app
+        .clone()
+        .oneshot(Request::get("/api/v1/meta").body(Body::empty()).unwrap())
+        .await
+        .unwrap();
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    let json: serde_json::Value = serde_json::from_slice(&body).unwrap();
+    assert_eq!(
+        json["features"]["experimental"],
+        serde_json::json!(["workflows"])
+    );
+
+    let response = app
+        .clone()
+        .oneshot(
+            Request::put("/api/admin/v1/features/teleport")
+                .header("content-type", "application/json")
+                .body(Body::from(r#"{"enabled":true}"#))
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::NOT_FOUND);
+
+    let response = app
+        .oneshot(
+            Request::put("/api/admin/v1/features/workflows")
+                .header("content-type", "application/json")
+                .body(Body::from(r#"{"enabled":"yes"}"#))
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::BAD_REQUEST);
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    let json: serde_json::Value = serde_json::from_slice(&body).unwrap();
+    assert_eq!(json["errors"][0]["pointer"], "/enabled");
+}
+
 // ============================================================================
 // Agents API
 // ============================================================================
@@ -84,6 +203,118 @@ async fn test_list_agents_empty() {
     assert_eq!(json["agents"], serde_json::json!([]));
 }
 
+#[tokio::test]
+async fn test_list_agents_format_negotiation() {
+    let app = test_app().await;
+
+    let response = app
+        .clone()
+        .oneshot(
+            Request::get("/api/v1/agents")
+                .header("accept", "application/x-ndjson")
+                .body(Body::empty())
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
+    assert_eq!(response.headers()["content-type"], "application/x-ndjson");
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    assert!(body.is_empty());
+
+    let response = app
+        .oneshot(
+            Request::get("/api/v1/agents")
+                .header("accept", "application/msgpack")
+                .body(Body::empty())
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
+    assert_eq!(response.headers()["content-type"], "application/msgpack");
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    let decoded: serde_json::Value = rmp_serde::from_slice(&body).unwrap();
+    assert_eq!(decoded["agents"], serde_json::json!([]));
+}
+
+#[tokio::test]
+async fn test_list_agents_search() {
+    let app = test_app().await;
+    let send = |req: Request<Body>| {
+        let app = app.clone();
+        async move { app.oneshot(req).await.unwrap() }
+    };
+    let manifest = |name: &str, description: &str, team: &str| {
+        format!(
+            "apiVersion: duragent/v1alpha1\nkind: Agent\nmetadata:\n  name: {name}\n  description: {description}\n  labels:\n    team: {team}\n  tags: [{team}-desk]\nspec:\n  model:\n    provider: openrouter\n    name: anthropic/claude-sonnet-4\n"
+        )
+    };
+
+    let create = serde_json::json!({
+        "operations": [
+            {"op": "create", "name": "billing-bot", "manifest": manifest("billing-bot", "Answers invoice questions", "finance")},
+            {"op": "create", "name": "helpdesk", "manifest": manifest("helpdesk", "Resets passwords", "support")},
+            {"op": "create", "name": "refunds", "manifest": manifest("refunds", "Handles refund requests", "support")},
+        ]
+    });
+    let response = send(
+        Request::post("/api/v1/agents/bulk")
+            .header("content-type", "application/json")
+            .body(Body::from(create.to_string()))
+            .unwrap(),
+    )
+    .await;
+    assert_eq!(response.status(), StatusCode::OK);
+
+    let names = |json: serde_json::Value| -> Vec<String> {
+        json["agents"]
+            .as_array()
+            .unwrap()
+            .iter()
+            .map(|a| a["name"].as_str().unwrap().to_string())
+            .collect()
+    };
+    for (uri, expected) in [
+        ("/api/v1/agents", vec!["billing-bot", "helpdesk", "refunds"]),
+        ("/api/v1/agents?q=INVOICE", vec!["billing-bot"]),
+        ("/api/v1/agents?q=support", vec!["helpdesk", "refunds"]),
+        ("/api/v1/agents?q=support%20refund", vec!["refunds"]),
+        ("/api/v1/agents?q=finance-desk", vec!["billing-bot"]),
+        (
+            "/api/v1/agents?label=team=support",
+            vec!["helpdesk", "refunds"],
+        ),
+        (
+            "/api/v1/agents?label=team=support&q=password",
+            vec!["helpdesk"],
+        ),
+        ("/api/v1/agents?label=team=legal", vec![]),
+    ] {
+        let response = send(Request::get(uri).body(Body::empty()).unwrap()).await;
+        assert_eq!(response.status(), StatusCode::OK, "{uri}");
+        let body = response.into_body().collect().await.unwrap().to_bytes();
+        let json: serde_json::Value = serde_json::from_slice(&body).unwrap();
+        assert_eq!(names(json), expected, "{uri}");
+    }
+
+    let response = send(
+        Request::get("/api/v1/agents?selector=team=a=b")
+            .body(Body::empty())
+            .unwrap(),
+    )
+    .await;
+    assert_eq!(response.status(), StatusCode::BAD_REQUEST);
+
+    let response = send(
+        Request::get("/api/v1/sessions?selector=team=support")
+            .body(Body::empty())
+            .unwrap(),
+    )
+    .await;
+    assert_eq!(response.status(), StatusCode::OK);
+}
+
 #[tokio::test]
 async fn test_get_agent_not_found() {
     let app = test_app().await;
@@ -106,104 +337,3631 @@ async fn test_get_agent_not_found() {
     assert!(json["detail"].as_str().unwrap().contains("not found"));
 }
 
-// ============================================================================
-// Sessions API
-// ============================================================================
+#[tokio::test]
+async fn test_create_agent() {
+    let app = test_app().await;
+    let send = |req: Request<Body>| {
+        let app = app.clone();
+        async move { app.oneshot(req).await.unwrap() }
+    };
+    let create = |body: serde_json::Value| {
+        Request::post("/api/v1/agents")
+            .header("content-type", "application/json")
+            .body(Body::from(body.to_string()))
+            .unwrap()
+    };
+    let manifest = "apiVersion: duragent/v1alpha1\nkind: Agent\nmetadata:\n  name: alpha\nspec:\n  model:\n    provider: openrouter\n    name: anthropic/claude-sonnet-4\n";
+
+    let response = send(create(serde_json::json!({
+        "name": "alpha",
+        "manifest": manifest,
+        "files": {"SOUL.md": "Be kind."},
+    })))
+    .await;
+    assert_eq!(response.status(), StatusCode::CREATED);
+    assert!(
+        response.headers()["location"]
+            .to_str()
+            .unwrap()
+            .ends_with("/api/v1/agents/alpha")
+    );
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    let json: serde_json::Value = serde_json::from_slice(&body).unwrap();
+    assert_eq!(json["metadata"]["name"], "alpha");
+    assert_eq!(json["provenance"]["source"], "api");
+
+    // Servable without a reload
+    let response = send(
+        Request::get("/api/v1/agents/alpha")
+            .body(Body::empty())
+            .unwrap(),
+    )
+    .await;
+    assert_eq!(response.status(), StatusCode::OK);
+
+    let response = send(create(
+        serde_json::json!({"name": "alpha", "manifest": manifest}),
+    ))
+    .await;
+    assert_eq!(response.status(), StatusCode::CONFLICT);
+
+    // metadata.name must match
+    let response = send(create(
+        serde_json::json!({"name": "beta", "manifest": manifest}),
+    ))
+    .await;
+    assert_eq!(response.status(), StatusCode::BAD_REQUEST);
+    let response = send(
+        Request::get("/api/v1/agents/beta")
+            .body(Body::empty())
+            .unwrap(),
+    )
+    .await;
+    assert_eq!(response.status(), StatusCode::NOT_FOUND);
+}
 
 #[tokio::test]
-async fn test_create_session_agent_not_found() {
+async fn test_bulk_agents() {
     let app = test_app().await;
+    let manifest = |name: &str| {
+        format!(
+            "apiVersion: duragent/v1alpha1\nkind: Agent\nmetadata:\n  name: {name}\nspec:\n  model:\n    provider: openrouter\n    name: anthropic/claude-sonnet-4\n"
+        )
+    };
 
+    let create = serde_json::json!({
+        "operations": [
+            {"op": "create", "name": "alpha", "manifest": manifest("alpha"), "files": {"SOUL.md": "Be kind."}},
+            {"op": "create", "name": "beta", "manifest": manifest("beta")},
+        ]
+    });
     let response = app
+        .clone()
         .oneshot(
-            Request::post("/api/v1/sessions")
+            Request::post("/api/v1/agents/bulk")
                 .header("content-type", "application/json")
-                .body(Body::from(r#"{"agent": "nonexistent"}"#))
+                .body(Body::from(create.to_string()))
                 .unwrap(),
         )
         .await
         .unwrap();
-
-    assert_eq!(response.status(), StatusCode::NOT_FOUND);
-
+    assert_eq!(response.status(), StatusCode::OK);
     let body = response.into_body().collect().await.unwrap().to_bytes();
     let json: serde_json::Value = serde_json::from_slice(&body).unwrap();
+    assert_eq!(json["applied"], true);
+    assert_eq!(json["results"][1]["status"], "created");
 
-    assert_eq!(json["status"], 404);
-    assert!(json["detail"].as_str().unwrap().contains("not found"));
-}
+    let response = app
+        .clone()
+        .oneshot(
+            Request::get("/api/v1/agents/alpha")
+                .body(Body::empty())
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    let json: serde_json::Value = serde_json::from_slice(&body).unwrap();
+    assert_eq!(json["provenance"]["source"], "api");
+    assert_eq!(json["provenance"]["created_by"], "local");
 
-#[tokio::test]
-async fn test_get_session_not_found() {
-    let app = test_app().await;
+    // One invalid operation rejects the whole batch
+    let mixed = serde_json::json!({
+        "operations": [
+            {"op": "delete", "name": "alpha"},
+            {"op": "update", "name": "missing", "manifest": manifest("missing")},
+        ]
+    });
+    let response = app
+        .clone()
+        .oneshot(
+            Request::post("/api/v1/agents/bulk")
+                .header("content-type", "application/json")
+                .body(Body::from(mixed.to_string()))
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::BAD_REQUEST);
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    let json: serde_json::Value = serde_json::from_slice(&body).unwrap();
+    assert_eq!(json["applied"], false);
+    assert_eq!(json["results"][0]["status"], "skipped");
+    assert_eq!(json["results"][1]["status"], "failed");
+    assert_eq!(json["results"][1]["error"], "agent not found");
 
     let response = app
         .oneshot(
-            Request::get("/api/v1/sessions/nonexistent")
+            Request::get("/api/v1/agents/alpha")
                 .body(Body::empty())
                 .unwrap(),
         )
         .await
         .unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
+}
 
+#[tokio::test]
+async fn test_agent_trash_restore_and_purge() {
+    let app = test_app().await;
+    let send = |req: Request<Body>| {
+        let app = app.clone();
+        async move { app.oneshot(req).await.unwrap() }
+    };
+
+    let create = serde_json::json!({
+        "operations": [{
+            "op": "create",
+            "name": "temp",
+            "manifest": "apiVersion: duragent/v1alpha1\nkind: Agent\nmetadata:\n  name: temp\nspec:\n  model:\n    provider: openrouter\n    name: anthropic/claude-sonnet-4\n",
+        }]
+    });
+    let response = send(
+        Request::post("/api/v1/agents/bulk")
+            .header("content-type", "application/json")
+            .body(Body::from(create.to_string()))
+            .unwrap(),
+    )
+    .await;
+    assert_eq!(response.status(), StatusCode::OK);
+
+    let response = send(
+        Request::delete("/api/v1/agents/temp")
+            .body(Body::empty())
+            .unwrap(),
+    )
+    .await;
+    assert_eq!(response.status(), StatusCode::NO_CONTENT);
+
+    let response = send(
+        Request::get("/api/v1/agents/temp")
+            .body(Body::empty())
+            .unwrap(),
+    )
+    .await;
     assert_eq!(response.status(), StatusCode::NOT_FOUND);
 
+    let response = send(
+        Request::get("/api/v1/trash/agents")
+            .body(Body::empty())
+            .unwrap(),
+    )
+    .await;
+    assert_eq!(response.status(), StatusCode::OK);
     let body = response.into_body().collect().await.unwrap().to_bytes();
     let json: serde_json::Value = serde_json::from_slice(&body).unwrap();
+    assert_eq!(json["agents"][0]["name"], "temp");
+    assert!(json["agents"][0]["purge_at"].is_string());
 
-    assert_eq!(json["status"], 404);
+    let response = send(
+        Request::post("/api/v1/trash/agents/temp/restore")
+            .body(Body::empty())
+            .unwrap(),
+    )
+    .await;
+    assert_eq!(response.status(), StatusCode::NO_CONTENT);
+
+    let response = send(
+        Request::get("/api/v1/agents/temp")
+            .body(Body::empty())
+            .unwrap(),
+    )
+    .await;
+    assert_eq!(response.status(), StatusCode::OK);
+
+    let response = send(
+        Request::delete("/api/v1/agents/temp")
+            .body(Body::empty())
+            .unwrap(),
+    )
+    .await;
+    assert_eq!(response.status(), StatusCode::NO_CONTENT);
+
+    let response = send(
+        Request::delete("/api/v1/trash/agents/temp")
+            .body(Body::empty())
+            .unwrap(),
+    )
+    .await;
+    assert_eq!(response.status(), StatusCode::NO_CONTENT);
+
+    let response = send(
+        Request::post("/api/v1/trash/agents/temp/restore")
+            .body(Body::empty())
+            .unwrap(),
+    )
+    .await;
+    assert_eq!(response.status(), StatusCode::NOT_FOUND);
 }
 
 #[tokio::test]
-async fn test_stream_session_not_found() {
+async fn test_agent_disable_and_enable() {
+    let app = test_app().await;
+    let send = |req: Request<Body>| {
+        let app = app.clone();
+        async move { app.oneshot(req).await.unwrap() }
+    };
+
+    let create = serde_json::json!({
+        "operations": [{
+            "op": "create",
+            "name": "paused",
+            "manifest": "apiVersion: duragent/v1alpha1\nkind: Agent\nmetadata:\n  name: paused\nspec:\n  model:\n    provider: openrouter\n    name: anthropic/claude-sonnet-4\n",
+        }]
+    });
+    let response = send(
+        Request::post("/api/v1/agents/bulk")
+            .header("content-type", "application/json")
+            .body(Body::from(create.to_string()))
+            .unwrap(),
+    )
+    .await;
+    assert_eq!(response.status(), StatusCode::OK);
+
+    let response = send(
+        Request::post("/api/v1/agents/paused/disable")
+            .body(Body::empty())
+            .unwrap(),
+    )
+    .await;
+    assert_eq!(response.status(), StatusCode::NO_CONTENT);
+
+    let response = send(
+        Request::get("/api/v1/agents/paused")
+            .body(Body::empty())
+            .unwrap(),
+    )
+    .await;
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    let json: serde_json::Value = serde_json::from_slice(&body).unwrap();
+    assert_eq!(json["enabled"], false);
+
+    let session = serde_json::json!({ "agent": "paused" }).to_string();
+    let response = send(
+        Request::post("/api/v1/sessions")
+            .header("content-type", "application/json")
+            .body(Body::from(session.clone()))
+            .unwrap(),
+    )
+    .await;
+    assert_eq!(response.status(), StatusCode::CONFLICT);
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    let json: serde_json::Value = serde_json::from_slice(&body).unwrap();
+    assert_eq!(json["code"], "agent-disabled");
+
+    let response = send(
+        Request::post("/api/v1/agents/paused/enable")
+            .body(Body::empty())
+            .unwrap(),
+    )
+    .await;
+    assert_eq!(response.status(), StatusCode::NO_CONTENT);
+
+    let response = send(
+        Request::post("/api/v1/sessions")
+            .header("content-type", "application/json")
+            .body(Body::from(session))
+            .unwrap(),
+    )
+    .await;
+    assert_eq!(response.status(), StatusCode::CREATED);
+
+    let response = send(
+        Request::post("/api/v1/agents/missing/disable")
+            .body(Body::empty())
+            .unwrap(),
+    )
+    .await;
+    assert_eq!(response.status(), StatusCode::NOT_FOUND);
+}
+
+#[tokio::test]
+async fn test_projects() {
     let app = test_app().await;
 
+    let project = serde_json::json!({
+        "description": "Support agents",
+        "default_model": {"provider": "openrouter", "name": "anthropic/claude-sonnet-4"},
+        "budget": {"max_wall_time_seconds": 600}
+    });
     let response = app
+        .clone()
         .oneshot(
-            Request::post("/api/v1/sessions/nonexistent/stream")
+            Request::put("/api/v1/projects/support")
                 .header("content-type", "application/json")
-                .body(Body::from(r#"{"content": "hello"}"#))
+                .body(Body::from(project.to_string()))
                 .unwrap(),
         )
         .await
         .unwrap();
+    assert_eq!(response.status(), StatusCode::CREATED);
+    assert!(response.headers().contains_key("location"));
 
-    assert_eq!(response.status(), StatusCode::NOT_FOUND);
+    // An agent in the project may omit spec.model
+    let create = serde_json::json!({
+        "operations": [{
+            "op": "create",
+            "name": "triage",
+            "manifest": "apiVersion: duragent/v1alpha1\nkind: Agent\nmetadata:\n  name: triage\n  project: support\nspec: {}\n",
+        }]
+    });
+    let response = app
+        .clone()
+        .oneshot(
+            Request::post("/api/v1/agents/bulk")
+                .header("content-type", "application/json")
+                .body(Body::from(create.to_string()))
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
 
+    let response = app
+        .clone()
+        .oneshot(
+            Request::get("/api/v1/agents?project=support")
+                .body(Body::empty())
+                .unwrap(),
+        )
+        .await
+        .unwrap();
     let body = response.into_body().collect().await.unwrap().to_bytes();
     let json: serde_json::Value = serde_json::from_slice(&body).unwrap();
+    assert_eq!(json["agents"][0]["name"], "triage");
+    assert_eq!(json["agents"][0]["project"], "support");
 
-    assert_eq!(json["status"], 404);
-}
-
-// ============================================================================
-// Error Responses
-// ============================================================================
-
-#[tokio::test]
-async fn test_problem_details_format() {
-    let app = test_app().await;
+    let response = app
+        .clone()
+        .oneshot(
+            Request::get("/api/v1/projects/support")
+                .body(Body::empty())
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    let json: serde_json::Value = serde_json::from_slice(&body).unwrap();
+    assert_eq!(json["agents"], serde_json::json!(["triage"]));
+    assert_eq!(json["budget"]["max_wall_time_seconds"], 600);
 
+    // A project with agents cannot be deleted
     let response = app
+        .clone()
         .oneshot(
-            Request::get("/api/v1/agents/nonexistent")
+            Request::delete("/api/v1/projects/support")
                 .body(Body::empty())
                 .unwrap(),
         )
         .await
         .unwrap();
+    assert_eq!(response.status(), StatusCode::CONFLICT);
 
+    let response = app
+        .oneshot(
+            Request::get("/api/v1/projects/missing")
+                .body(Body::empty())
+                .unwrap(),
+        )
+        .await
+        .unwrap();
     assert_eq!(response.status(), StatusCode::NOT_FOUND);
-    assert_eq!(
-        response.headers().get("content-type").unwrap(),
-        "application/problem+json"
-    );
+}
+
+#[tokio::test]
+async fn test_drift_detection_and_reconcile() {
+    use axum::extract::connect_info::MockConnectInfo;
+    use duragent::server;
+
+    let state = common::test_app_state().await;
+    let agents_dir = state.agents_dir.clone();
+    let loopback: std::net::SocketAddr = ([127, 0, 0, 1], 0).into();
+    let app = server::build_app(state, 300).layer(MockConnectInfo(loopback));
+
+    let manifest = "apiVersion: duragent/v1alpha1\nkind: Agent\nmetadata:\n  name: alpha\nspec:\n  model:\n    provider: openrouter\n    name: anthropic/claude-sonnet-4\n";
+    let create = serde_json::json!({
+        "operations": [{"op": "create", "name": "alpha", "manifest": manifest, "files": {"SOUL.md": "Be kind."}}]
+    });
+    let response = app
+        .clone()
+        .oneshot(
+            Request::post("/api/v1/agents/bulk")
+                .header("content-type", "application/json")
+                .body(Body::from(create.to_string()))
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
 
+    let get_drift = || {
+        Request::get("/api/v1/admin/drift")
+            .body(Body::empty())
+            .unwrap()
+    };
+    let response = app.clone().oneshot(get_drift()).await.unwrap();
     let body = response.into_body().collect().await.unwrap().to_bytes();
     let json: serde_json::Value = serde_json::from_slice(&body).unwrap();
+    assert_eq!(json["in_sync"], true);
 
-    // RFC 7807 required fields
+    // Edit one agent and add another behind the server's back
+    std::fs::write(agents_dir.join("alpha/SOUL.md"), "Be terse.").unwrap();
+    std::fs::create_dir(agents_dir.join("beta")).unwrap();
+    std::fs::write(
+        agents_dir.join("beta/agent.yaml"),
+        manifest.replace("alpha", "beta"),
+    )
+    .unwrap();
+
+    let response = app.clone().oneshot(get_drift()).await.unwrap();
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    let json: serde_json::Value = serde_json::from_slice(&body).unwrap();
+    assert_eq!(json["in_sync"], false);
+    assert_eq!(json["agents"][0]["name"], "alpha");
+    assert_eq!(json["agents"][0]["status"], "modified");
+    assert_eq!(json["agents"][1]["name"], "beta");
+    assert_eq!(json["agents"][1]["status"], "not_loaded");
+
+    let response = app
+        .clone()
+        .oneshot(
+            Request::post("/api/v1/admin/drift/reconcile")
+                .body(Body::empty())
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    let json: serde_json::Value = serde_json::from_slice(&body).unwrap();
+    assert_eq!(json["in_sync"], true);
+    assert_eq!(json["reconciled"], serde_json::json!(["alpha", "beta"]));
+
+    let response = app.oneshot(get_drift()).await.unwrap();
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    let json: serde_json::Value = serde_json::from_slice(&body).unwrap();
+    assert_eq!(json["in_sync"], true);
+}
+
+#[tokio::test]
+async fn test_agent_manifest_schema() {
+    let app = test_app().await;
+
+    let response = app
+        .oneshot(
+            Request::get("/api/v1/schemas/agent-manifest.json")
+                .body(Body::empty())
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+
+    assert_eq!(response.status(), StatusCode::OK);
+    assert_eq!(
+        response.headers().get("content-type").unwrap(),
+        "application/schema+json"
+    );
+
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    let json: serde_json::Value = serde_json::from_slice(&body).unwrap();
+    assert_eq!(json["properties"]["kind"]["const"], "Agent");
+}
+
+#[tokio::test]
+async fn test_openapi_document() {
+    let app = test_app().await;
+
+    let response = app
+        .oneshot(
+            Request::get("/api/v1/schemas/openapi.json")
+                .body(Body::empty())
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+
+    assert_eq!(response.status(), StatusCode::OK);
+    assert_eq!(
+        response.headers().get("content-type").unwrap(),
+        "application/json"
+    );
+
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    let json: serde_json::Value = serde_json::from_slice(&body).unwrap();
+    assert_eq!(json["openapi"], "3.1.0");
+    assert!(json["paths"]["/api/v1/sessions/{session_id}/stream"]["post"].is_object());
+}
+
+// ============================================================================
+// Sessions API
+// ============================================================================
+
+#[tokio::test]
+async fn test_create_session_agent_not_found() {
+    let app = test_app().await;
+
+    let response = app
+        .oneshot(
+            Request::post("/api/v1/sessions")
+                .header("content-type", "application/json")
+                .body(Body::from(r#"{"agent": "nonexistent"}"#))
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+
+    assert_eq!(response.status(), StatusCode::NOT_FOUND);
+
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    let json: serde_json::Value = serde_json::from_slice(&body).unwrap();
+
+    assert_eq!(json["status"], 404);
+    assert!(json["detail"].as_str().unwrap().contains("not found"));
+}
+
+#[tokio::test]
+async fn test_get_session_not_found() {
+    let app = test_app().await;
+
+    let response = app
+        .oneshot(
+            Request::get("/api/v1/sessions/nonexistent")
+                .body(Body::empty())
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+
+    assert_eq!(response.status(), StatusCode::NOT_FOUND);
+
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    let json: serde_json::Value = serde_json::from_slice(&body).unwrap();
+
+    assert_eq!(json["status"], 404);
+}
+
+#[tokio::test]
+async fn test_stream_session_not_found() {
+    let app = test_app().await;
+
+    let response = app
+        .oneshot(
+            Request::post("/api/v1/sessions/nonexistent/stream")
+                .header("content-type", "application/json")
+                .body(Body::from(r#"{"content": "hello"}"#))
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+
+    assert_eq!(response.status(), StatusCode::NOT_FOUND);
+
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    let json: serde_json::Value = serde_json::from_slice(&body).unwrap();
+
+    assert_eq!(json["status"], 404);
+}
+
+#[tokio::test]
+async fn test_archived_session_is_still_readable() {
+    use axum::extract::connect_info::MockConnectInfo;
+    use duragent::api::SessionStatus;
+    use duragent::llm::{Message, Role};
+    use duragent::server;
+    use duragent::session::{CheckpointState, SessionConfig, SessionSnapshot};
+
+    let state = common::test_app_state().await;
+    let registry = state.services.session_registry.clone();
+    let snapshot = SessionSnapshot::new(
+        "session_old".to_string(),
+        "test-agent".to_string(),
+        SessionStatus::Completed,
+        chrono::Utc::now(),
+        CheckpointState {
+            last_event_seq: 2,
+            checkpoint_seq: 2,
+            conversation: vec![
+                Message::text(Role::User, "Hello"),
+                Message::text(Role::Assistant, "Hi there!"),
+            ],
+        },
+        SessionConfig::default(),
+    );
+    registry
+        .store()
+        .save_snapshot("session_old", &snapshot)
+        .await
+        .unwrap();
+    let archived = state
+        .session_archive
+        .archive_completed_before(
+            &registry,
+            chrono::Utc::now() + chrono::TimeDelta::seconds(1),
+        )
+        .await
+        .unwrap();
+    assert_eq!(archived, 1);
+    assert!(registry.store().list().await.unwrap().is_empty());
+
+    let loopback: std::net::SocketAddr = ([127, 0, 0, 1], 0).into();
+    let app = server::build_app(state, 300).layer(MockConnectInfo(loopback));
+
+    let response = app
+        .clone()
+        .oneshot(
+            Request::get("/api/v1/sessions/session_old")
+                .body(Body::empty())
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    let json: serde_json::Value = serde_json::from_slice(&body).unwrap();
+    assert_eq!(json["status"], "completed");
+
+    let response = app
+        .oneshot(
+            Request::get("/api/v1/sessions/session_old/messages")
+                .body(Body::empty())
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    let json: serde_json::Value = serde_json::from_slice(&body).unwrap();
+    assert_eq!(json["messages"][1]["content"], "Hi there!");
+}
+
+#[tokio::test]
+async fn test_export_session_transcript() {
+    use axum::extract::connect_info::MockConnectInfo;
+    use duragent::api::SessionStatus;
+    use duragent::llm::{Message, Role};
+    use duragent::server;
+    use duragent::session::{CheckpointState, SessionConfig, SessionSnapshot};
+
+    let state = common::test_app_state().await;
+    let snapshot = SessionSnapshot::new(
+        "session_old".to_string(),
+        "test-agent".to_string(),
+        SessionStatus::Completed,
+        chrono::Utc::now(),
+        CheckpointState {
+            last_event_seq: 2,
+            checkpoint_seq: 2,
+            conversation: vec![
+                Message::text(Role::User, "Hello"),
+                Message::text(Role::Assistant, "Hi there!"),
+            ],
+        },
+        SessionConfig::default(),
+    );
+    state
+        .services
+        .session_registry
+        .store()
+        .save_snapshot("session_old", &snapshot)
+        .await
+        .unwrap();
+    let loopback: std::net::SocketAddr = ([127, 0, 0, 1], 0).into();
+    let app = server::build_app(state, 300).layer(MockConnectInfo(loopback));
+    let export = |format: &str| {
+        Request::get(format!(
+            "/api/v1/sessions/session_old/export?format={format}"
+        ))
+        .body(Body::empty())
+        .unwrap()
+    };
+
+    let response = app.clone().oneshot(export("md")).await.unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
+    assert_eq!(
+        response.headers()["content-disposition"],
+        "attachment; filename=\"session_old.md\""
+    );
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    let markdown = String::from_utf8(body.to_vec()).unwrap();
+    assert!(markdown.contains("**Assistant**\n\nHi there!"));
+
+    let response = app.clone().oneshot(export("pdf")).await.unwrap();
+    assert_eq!(response.headers()["content-type"], "application/pdf");
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    assert!(body.starts_with(b"%PDF-"));
+
+    let response = app.oneshot(export("docx")).await.unwrap();
+    assert_eq!(response.status(), StatusCode::BAD_REQUEST);
+}
+
+#[tokio::test]
+async fn test_shared_transcript_link() {
+    use axum::extract::connect_info::MockConnectInfo;
+    use duragent::api::SessionStatus;
+    use duragent::llm::{Message, Role};
+    use duragent::server;
+    use duragent::session::{CheckpointState, SessionConfig, SessionSnapshot};
+
+    async fn json(response: axum::response::Response) -> serde_json::Value {
+        let body = response.into_body().collect().await.unwrap().to_bytes();
+        serde_json::from_slice(&body).unwrap()
+    }
+    let get = |uri: &str| Request::get(uri).body(Body::empty()).unwrap();
+    let create = |body: &str| {
+        Request::post("/api/v1/sessions/session_old/shares")
+            .header("content-type", "application/json")
+            .body(Body::from(body.to_string()))
+            .unwrap()
+    };
+
+    let state = common::test_app_state().await;
+    let snapshot = SessionSnapshot::new(
+        "session_old".to_string(),
+        "test-agent".to_string(),
+        SessionStatus::Completed,
+        chrono::Utc::now(),
+        CheckpointState {
+            last_event_seq: 2,
+            checkpoint_seq: 2,
+            conversation: vec![
+                Message::text(Role::User, "Hello"),
+                Message::text(Role::Assistant, "Hi there!"),
+            ],
+        },
+        SessionConfig::default(),
+    );
+    state
+        .services
+        .session_registry
+        .store()
+        .save_snapshot("session_old", &snapshot)
+        .await
+        .unwrap();
+    let loopback: std::net::SocketAddr = ([127, 0, 0, 1], 0).into();
+    let app = server::build_app(state, 300).layer(MockConnectInfo(loopback));
+
+    let response = app.clone().oneshot(create("{}")).await.unwrap();
+    assert_eq!(response.status(), StatusCode::CREATED);
+    let created = json(response).await;
+    let token = created["token"].as_str().unwrap().to_string();
+    let share_id = created["share"]["id"].as_str().unwrap().to_string();
+    assert!(
+        created["url"]
+            .as_str()
+            .unwrap()
+            .ends_with(&format!("/shared/{token}"))
+    );
+
+    let response = app
+        .clone()
+        .oneshot(get(&format!("/shared/{token}")))
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
+    let transcript = json(response).await;
+    assert_eq!(transcript["agent"], "test-agent");
+    assert_eq!(transcript["messages"][1]["content"], "Hi there!");
+
+    let response = app
+        .clone()
+        .oneshot(create(r#"{"expires_in_seconds": 0}"#))
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::BAD_REQUEST);
+
+    let response = app
+        .clone()
+        .oneshot(
+            Request::delete(format!("/api/v1/sessions/session_old/shares/{share_id}"))
+                .body(Body::empty())
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::NO_CONTENT);
+
+    let response = app
+        .clone()
+        .oneshot(get(&format!("/shared/{token}")))
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::NOT_FOUND);
+
+    let response = app
+        .oneshot(get("/api/v1/sessions/session_missing/shares"))
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::NOT_FOUND);
+}
+
+#[tokio::test]
+async fn test_deleting_a_session_revokes_its_shares() {
+    use axum::extract::connect_info::MockConnectInfo;
+    use duragent::server;
+
+    async fn json(response: axum::response::Response) -> serde_json::Value {
+        let body = response.into_body().collect().await.unwrap().to_bytes();
+        serde_json::from_slice(&body).unwrap()
+    }
+    let post = |uri: String, body: serde_json::Value| {
+        Request::post(uri)
+            .header("content-type", "application/json")
+            .body(Body::from(body.to_string()))
+            .unwrap()
+    };
+
+    let state = common::test_app_state().await;
+    let loopback: std::net::SocketAddr = ([127, 0, 0, 1], 0).into();
+    let app = server::build_app(state.clone(), 300).layer(MockConnectInfo(loopback));
+
+    let manifest = "apiVersion: duragent/v1alpha1\nkind: Agent\nmetadata:\n  name: echo\nspec:\n  model:\n    provider: mock\n    name: echo\n";
+    let response = app
+        .clone()
+        .oneshot(post(
+            "/api/v1/agents/bulk".to_string(),
+            serde_json::json!({
+                "operations": [{"op": "create", "name": "echo", "manifest": manifest}],
+            }),
+        ))
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
+    let response = app
+        .clone()
+        .oneshot(post(
+            "/api/v1/sessions".to_string(),
+            serde_json::json!({"agent": "echo"}),
+        ))
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::CREATED);
+    let session_id = json(response).await["session_id"]
+        .as_str()
+        .unwrap()
+        .to_string();
+
+    let response = app
+        .clone()
+        .oneshot(post(
+            format!("/api/v1/sessions/{session_id}/shares"),
+            serde_json::json!({}),
+        ))
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::CREATED);
+    let token = json(response).await["token"].as_str().unwrap().to_string();
+    assert!(state.shares.resolve(&token).is_some());
+
+    let response = app
+        .clone()
+        .oneshot(
+            Request::delete(format!("/api/v1/sessions/{session_id}"))
+                .body(Body::empty())
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::NO_CONTENT);
+    assert!(state.shares.list(&session_id).is_empty());
+    assert!(state.shares.resolve(&token).is_none());
+
+    let response = app
+        .oneshot(
+            Request::get(format!("/shared/{token}"))
+                .body(Body::empty())
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::NOT_FOUND);
+}
+
+#[tokio::test]
+async fn test_agent_input_schema() {
+    let app = test_app().await;
+    let send = |req: Request<Body>| {
+        let app = app.clone();
+        async move { app.oneshot(req).await.unwrap() }
+    };
+    async fn json(response: axum::response::Response) -> serde_json::Value {
+        let body = response.into_body().collect().await.unwrap().to_bytes();
+        serde_json::from_slice(&body).unwrap()
+    }
+    let manifest = |input_schema: &str| {
+        format!(
+            "apiVersion: duragent/v1alpha1\nkind: Agent\nmetadata:\n  name: triage\nspec:\n  model:\n    provider: openrouter\n    name: anthropic/claude-sonnet-4\n  input_schema:\n{input_schema}"
+        )
+    };
+    let bulk = |manifest: String| {
+        let body = serde_json::json!({
+            "operations": [{"op": "create", "name": "triage", "manifest": manifest}]
+        });
+        Request::post("/api/v1/agents/bulk")
+            .header("content-type", "application/json")
+            .body(Body::from(body.to_string()))
+            .unwrap()
+    };
+
+    // Unsupported keywords are rejected when the agent is created
+    let response = send(bulk(manifest("    oneOf: [{type: string}]\n"))).await;
+    assert_eq!(response.status(), StatusCode::UNPROCESSABLE_ENTITY);
+    let error = json(response).await["results"][0]["error"].to_string();
+    assert!(error.contains("unsupported keyword 'oneOf'"), "{error}");
+
+    let schema = "    type: object\n    required: [ticket_id]\n    properties:\n      ticket_id: {type: string}\n";
+    let response = send(bulk(manifest(schema))).await;
+    assert_eq!(response.status(), StatusCode::OK);
+
+    let response = send(
+        Request::get("/api/v1/agents/triage")
+            .body(Body::empty())
+            .unwrap(),
+    )
+    .await;
+    assert_eq!(
+        json(response).await["spec"]["input_schema"]["required"],
+        serde_json::json!(["ticket_id"])
+    );
+
+    let response = send(
+        Request::get("/api/v1/schemas/openapi.json")
+            .body(Body::empty())
+            .unwrap(),
+    )
+    .await;
+    let openapi = json(response).await;
+    assert_eq!(
+        openapi["x-agent-inputs"]["triage"]["$ref"],
+        "#/components/schemas/AgentInput.triage"
+    );
+    assert_eq!(
+        openapi["components"]["schemas"]["AgentInput.triage"]["type"],
+        "object"
+    );
+
+    let response = send(
+        Request::post("/api/v1/sessions")
+            .header("content-type", "application/json")
+            .body(Body::from(r#"{"agent":"triage"}"#))
+            .unwrap(),
+    )
+    .await;
+    assert_eq!(response.status(), StatusCode::CREATED);
+    let session_id = json(response).await["session_id"]
+        .as_str()
+        .unwrap()
+        .to_string();
+
+    for (body, pointer) in [
+        (
+            serde_json::json!({"input": {"ticket_id": 42}}),
+            "/input/ticket_id",
+        ),
+        (serde_json::json!({"input": {}}), "/input/ticket_id"),
+        (serde_json::json!({"content": "help me"}), "/content"),
+        (serde_json::json!({"content": "{}"}), "/content/ticket_id"),
+    ] {
+        let response = send(
+            Request::post(format!("/api/v1/sessions/{session_id}/messages"))
+                .header("content-type", "application/json")
+                .body(Body::from(body.to_string()))
+                .unwrap(),
+        )
+        .await;
+        assert_eq!(response.status(), StatusCode::BAD_REQUEST, "{body}");
+        assert_eq!(
+            json(response).await["errors"][0]["pointer"],
+            pointer,
+            "{body}"
+        );
+    }
+}
+
+#[tokio::test]
+async fn test_send_message_with_attachments() {
+    let app = test_app().await;
+    let send = |req: Request<Body>| {
+        let app = app.clone();
+        async move { app.oneshot(req).await.unwrap() }
+    };
+    async fn json(response: axum::response::Response) -> serde_json::Value {
+        let body = response.into_body().collect().await.unwrap().to_bytes();
+        serde_json::from_slice(&body).unwrap()
+    }
+
+    let create = serde_json::json!({
+        "operations": [{
+            "op": "create",
+            "name": "reader",
+            "manifest": "apiVersion: duragent/v1alpha1\nkind: Agent\nmetadata:\n  name: reader\nspec:\n  model:\n    provider: openrouter\n    name: anthropic/claude-sonnet-4\n",
+        }]
+    });
+    let response = send(
+        Request::post("/api/v1/agents/bulk")
+            .header("content-type", "application/json")
+            .body(Body::from(create.to_string()))
+            .unwrap(),
+    )
+    .await;
+    assert_eq!(response.status(), StatusCode::OK);
+
+    let response = send(
+        Request::post("/api/v1/sessions")
+            .header("content-type", "application/json")
+            .body(Body::from(r#"{"agent":"reader"}"#))
+            .unwrap(),
+    )
+    .await;
+    let session_id = json(response).await["session_id"]
+        .as_str()
+        .unwrap()
+        .to_string();
+
+    let form = "--XYZ\r\n\
+        Content-Disposition: form-data; name=\"content\"\r\n\r\n\
+        Summarize this\r\n\
+        --XYZ\r\n\
+        Content-Disposition: form-data; name=\"files\"; filename=\"notes.txt\"\r\n\
+        Content-Type: text/plain\r\n\r\n\
+        Ship on Friday\r\n\
+        --XYZ--\r\n";
+    let response = send(
+        Request::post(format!("/api/v1/sessions/{session_id}/messages"))
+            .header("content-type", "multipart/form-data; boundary=XYZ")
+            .body(Body::from(form))
+            .unwrap(),
+    )
+    .await;
+    // No provider is configured, but the message and its attachment are kept
+    assert_eq!(json(response).await["code"], "provider-not-configured");
+
+    let response = send(
+        Request::get(format!("/api/v1/sessions/{session_id}/artifacts"))
+            .body(Body::empty())
+            .unwrap(),
+    )
+    .await;
+    assert_eq!(response.status(), StatusCode::OK);
+    let artifacts = json(response).await;
+    assert_eq!(artifacts["artifacts"][0]["name"], "notes.txt");
+    assert_eq!(artifacts["artifacts"][0]["size"], 14);
+    let id = artifacts["artifacts"][0]["id"]
+        .as_str()
+        .unwrap()
+        .to_string();
+
+    let response = send(
+        Request::get(format!("/api/v1/sessions/{session_id}/artifacts/{id}"))
+            .body(Body::empty())
+            .unwrap(),
+    )
+    .await;
+    assert_eq!(response.status(), StatusCode::OK);
+    assert_eq!(response.headers()["content-type"], "text/plain");
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    assert_eq!(&body[..], b"Ship on Friday");
+
+    let response = send(
+        Request::get(format!("/api/v1/sessions/{session_id}/messages"))
+            .body(Body::empty())
+            .unwrap(),
+    )
+    .await;
+    let content = json(response).await["messages"][0]["content"].to_string();
+    assert!(content.starts_with("\"Summarize this"), "{content}");
+    assert!(
+        content.contains(&format!("[Attachment {id}: notes.txt")),
+        "{content}"
+    );
+    assert!(content.contains("Ship on Friday"), "{content}");
+
+    // Form fields are validated like JSON bodies
+    let form = "--XYZ\r\nContent-Disposition: form-data; name=\"content\"\r\n\r\n \r\n--XYZ--\r\n";
+    let response = send(
+        Request::post(format!("/api/v1/sessions/{session_id}/messages"))
+            .header("content-type", "multipart/form-data; boundary=XYZ")
+            .body(Body::from(form))
+            .unwrap(),
+    )
+    .await;
+    assert_eq!(response.status(), StatusCode::BAD_REQUEST);
+    assert_eq!(json(response).await["errors"][0]["pointer"], "/content");
+}
+
+#[tokio::test]
+async fn test_send_message_callback_url() {
+    use axum::extract::connect_info::MockConnectInfo;
+    use duragent::callbacks::Callbacks;
+    use duragent::config::CallbacksConfig;
+    use duragent::server;
+
+    async fn json(response: axum::response::Response) -> serde_json::Value {
+        let body = response.into_body().collect().await.unwrap().to_bytes();
+        serde_json::from_slice(&body).unwrap()
+    }
+    let post = |uri: String, body: serde_json::Value| {
+        Request::post(uri)
+            .header("content-type", "application/json")
+            .body(Body::from(body.to_string()))
+            .unwrap()
+    };
+
+    let mut state = common::test_app_state().await;
+    let loopback: std::net::SocketAddr = ([127, 0, 0, 1], 0).into();
+    let disabled = server::build_app(state.clone(), 300).layer(MockConnectInfo(loopback));
+    state.callbacks = Callbacks::new(
+        &CallbacksConfig {
+            signing_secret: Some("s3cret".to_string()),
+            ..CallbacksConfig::default()
+        },
+        reqwest::Client::builder(),
+    )
+    .unwrap();
+    let app = server::build_app(state, 300).layer(MockConnectInfo(loopback));
+
+    let create = serde_json::json!({
+        "operations": [{
+            "op": "create",
+            "name": "notifier",
+            "manifest": "apiVersion: duragent/v1alpha1\nkind: Agent\nmetadata:\n  name: notifier\nspec:\n  model:\n    provider: openrouter\n    name: anthropic/claude-sonnet-4\n",
+        }]
+    });
+    let response = app
+        .clone()
+        .oneshot(post("/api/v1/agents/bulk".to_string(), create))
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
+    let response = app
+        .clone()
+        .oneshot(post(
+            "/api/v1/sessions".to_string(),
+            serde_json::json!({"agent": "notifier"}),
+        ))
+        .await
+        .unwrap();
+    let session_id = json(response).await["session_id"]
+        .as_str()
+        .unwrap()
+        .to_string();
+
+    let message = serde_json::json!({
+        "content": "hello",
+        "callback_url": "https://93.184.215.14/runs"
+    });
+
+    // Refused until a signing secret is configured
+    let response = disabled
+        .oneshot(post(
+            format!("/api/v1/sessions/{session_id}/messages"),
+            message.clone(),
+        ))
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::BAD_REQUEST);
+    assert_eq!(
+        json(response).await["errors"][0]["pointer"],
+        "/callback_url"
+    );
+
+    // Not available when streaming
+    let response = app
+        .clone()
+        .oneshot(post(
+            format!("/api/v1/sessions/{session_id}/stream"),
+            serde_json::json!({"content": "hello", "background": true}),
+        ))
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::BAD_REQUEST);
+    assert_eq!(json(response).await["errors"][0]["pointer"], "/background");
+
+    let response = app
+        .clone()
+        .oneshot(post(
+            format!("/api/v1/sessions/{session_id}/messages"),
+            serde_json::json!({"content": "hello", "callback_url": "ftp://example.com"}),
+        ))
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::BAD_REQUEST);
+
+    // Internal addresses are refused
+    let response = app
+        .clone()
+        .oneshot(post(
+            format!("/api/v1/sessions/{session_id}/messages"),
+            serde_json::json!({"content": "hello", "callback_url": "http://169.254.169.254/latest"}),
+        ))
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::BAD_REQUEST);
+    assert_eq!(
+        json(response).await["errors"][0]["pointer"],
+        "/callback_url"
+    );
+
+    // Failures before the run starts are reported directly, not to the callback
+    let response = app
+        .oneshot(post(
+            format!("/api/v1/sessions/{session_id}/messages"),
+            message,
+        ))
+        .await
+        .unwrap();
+    assert_eq!(json(response).await["code"], "provider-not-configured");
+}
+
+#[tokio::test]
+async fn test_get_run_waits_for_completion() {
+    use axum::extract::connect_info::MockConnectInfo;
+    use duragent::server;
+    use std::collections::HashMap;
+
+    async fn json(response: axum::response::Response) -> serde_json::Value {
+        let body = response.into_body().collect().await.unwrap().to_bytes();
+        serde_json::from_slice(&body).unwrap()
+    }
+    let get = |uri: &str| Request::get(uri).body(Body::empty()).unwrap();
+
+    let state = common::test_app_state().await;
+    let runs = state.runs.clone();
+    let loopback: std::net::SocketAddr = ([127, 0, 0, 1], 0).into();
+    let app = server::build_app(state, 300).layer(MockConnectInfo(loopback));
+
+    runs.start("run_1", "session_1", HashMap::new());
+    let response = app
+        .clone()
+        .oneshot(get("/api/v1/runs/run_1"))
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
+    let run = json(response).await;
+    assert_eq!(run["status"], "running");
+    assert!(run.get("result").is_none());
+
+    let finisher = tokio::spawn(async move {
+        tokio::time::sleep(std::time::Duration::from_millis(50)).await;
+        runs.finish(
+            "run_1",
+            StatusCode::OK,
+            None,
+            Some(serde_json::json!({"content": "done"})),
+        );
+    });
+    let response = app
+        .clone()
+        .oneshot(get("/api/v1/runs/run_1?wait=30s"))
+        .await
+        .unwrap();
+    finisher.await.unwrap();
+    let run = json(response).await;
+    assert_eq!(run["status"], "completed");
+    assert_eq!(run["http_status"], 200);
+    assert_eq!(run["result"]["content"], "done");
+    assert!(run["finished_at"].is_string());
+
+    let response = app
+        .clone()
+        .oneshot(get("/api/v1/runs/run_1?wait=soon"))
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::BAD_REQUEST);
+
+    let response = app.oneshot(get("/api/v1/runs/run_2")).await.unwrap();
+    assert_eq!(response.status(), StatusCode::NOT_FOUND);
+}
+
+#[tokio::test]
+async fn test_run_events_and_agent_runs() {
+    use axum::extract::connect_info::MockConnectInfo;
+    use duragent::server;
+    use std::collections::HashMap;
+
+    let state = common::test_app_state().await;
+    let runs = state.runs.clone();
+    let loopback: std::net::SocketAddr = ([127, 0, 0, 1], 0).into();
+    let app = server::build_app(state, 300).layer(MockConnectInfo(loopback));
+
+    runs.start("run_1", "session_1", HashMap::new());
+    let response = app
+        .clone()
+        .oneshot(
+            Request::get("/api/v1/runs/run_1/events")
+                .body(Body::empty())
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
+    assert_eq!(response.headers()["content-type"], "text/event-stream");
+    runs.finish(
+        "run_1",
+        StatusCode::OK,
+        None,
+        Some(serde_json::json!({"content": "done"})),
+    );
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    let body = String::from_utf8(body.to_vec()).unwrap();
+    assert!(body.contains("event: done"));
+    assert!(body.contains(r#""content":"done""#));
+
+    let response = app
+        .clone()
+        .oneshot(
+            Request::get("/api/v1/runs/run_2/events")
+                .body(Body::empty())
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::NOT_FOUND);
+
+    let response = app
+        .oneshot(
+            Request::post("/api/v1/agents/nonexistent/runs")
+                .header("content-type", "application/json")
+                .body(Body::from(r#"{"content": "hello"}"#))
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::NOT_FOUND);
+}
+
+#[tokio::test]
+async fn test_agent_runs_need_runs_create_scope() {
+    use axum::extract::connect_info::MockConnectInfo;
+    use duragent::server;
+
+    let state = common::test_app_state().await;
+    let loopback: std::net::SocketAddr = ([127, 0, 0, 1], 0).into();
+    let app = server::build_app(state, 300).layer(MockConnectInfo(loopback));
+
+    let post = |uri: &str, token: Option<&str>, body: serde_json::Value| {
+        let mut builder = Request::post(uri).header("content-type", "application/json");
+        if let Some(token) = token {
+            builder = builder.header("authorization", format!("Bearer {token}"));
+        }
+        builder.body(Body::from(body.to_string())).unwrap()
+    };
+    async fn json(response: axum::response::Response) -> serde_json::Value {
+        let body = response.into_body().collect().await.unwrap().to_bytes();
+        serde_json::from_slice(&body).unwrap()
+    }
+
+    let manifest = "apiVersion: duragent/v1alpha1\nkind: Agent\nmetadata:\n  name: echo\nspec:\n  model:\n    provider: mock\n    name: echo\n";
+    let response = app
+        .clone()
+        .oneshot(post(
+            "/api/v1/agents/bulk",
+            None,
+            serde_json::json!({
+                "operations": [{"op": "create", "name": "echo", "manifest": manifest}],
+            }),
+        ))
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
+
+    let mut tokens = Vec::new();
+    for (name, scope) in [("runner", "runs:create"), ("editor", "agents:update")] {
+        let response = app
+            .clone()
+            .oneshot(post(
+                "/api/admin/v1/service-accounts",
+                None,
+                serde_json::json!({"name": name, "scopes": [scope]}),
+            ))
+            .await
+            .unwrap();
+        assert_eq!(response.status(), StatusCode::CREATED);
+        tokens.push(json(response).await["token"].as_str().unwrap().to_string());
+    }
+
+    let run = serde_json::json!({"content": "hello"});
+    let response = app
+        .clone()
+        .oneshot(post(
+            "/api/v1/agents/echo/runs",
+            Some(&tokens[0]),
+            run.clone(),
+        ))
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::ACCEPTED);
+
+    let response = app
+        .oneshot(post("/api/v1/agents/echo/runs", Some(&tokens[1]), run))
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::FORBIDDEN);
+    assert_eq!(
+        json(response).await["detail"],
+        "service account 'editor' lacks scope runs:create in project 'default'"
+    );
+}
+
+#[tokio::test]
+async fn test_list_runs_by_label() {
+    use axum::extract::connect_info::MockConnectInfo;
+    use duragent::server;
+    use std::collections::HashMap;
+
+    async fn run_ids(response: axum::response::Response) -> Vec<String> {
+        let body = response.into_body().collect().await.unwrap().to_bytes();
+        let body: serde_json::Value = serde_json::from_slice(&body).unwrap();
+        body["runs"]
+            .as_array()
+            .unwrap()
+            .iter()
+            .map(|run| run["run_id"].as_str().unwrap().to_string())
+            .collect()
+    }
+    let get = |uri: &str| Request::get(uri).body(Body::empty()).unwrap();
+    let order = |id: &str| HashMap::from([("order_id".to_string(), id.to_string())]);
+
+    let state = common::test_app_state().await;
+    state.runs.start("run_1", "session_1", order("A-1"));
+    state.runs.start("run_2", "session_1", order("A-2"));
+    state.runs.start("run_3", "session_2", order("A-1"));
+    let loopback: std::net::SocketAddr = ([127, 0, 0, 1], 0).into();
+    let app = server::build_app(state, 300).layer(MockConnectInfo(loopback));
+
+    let response = app
+        .clone()
+        .oneshot(get("/api/v1/runs?selector=order_id%3DA-1"))
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
+    let mut ids = run_ids(response).await;
+    ids.sort();
+    assert_eq!(ids, ["run_1", "run_3"]);
+
+    let response = app
+        .clone()
+        .oneshot(get(
+            "/api/v1/runs?selector=order_id%3DA-1&session_id=session_2",
+        ))
+        .await
+        .unwrap();
+    assert_eq!(run_ids(response).await, ["run_3"]);
+
+    let response = app
+        .oneshot(get("/api/v1/runs?selector=%3Dbad"))
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::BAD_REQUEST);
+}
+
+// ============================================================================
+// Usage API
+// ============================================================================
+
+#[tokio::test]
+async fn test_usage_rollups() {
+    use axum::extract::connect_info::MockConnectInfo;
+    use duragent::llm::Usage;
+    use duragent::server;
+
+    let state = common::test_app_state().await;
+    let usage = Usage {
+        prompt_tokens: 10,
+        completion_tokens: 5,
+        total_tokens: 15,
+    };
+    let at = chrono::DateTime::parse_from_rfc3339("2026-03-01T10:30:00Z")
+        .unwrap()
+        .with_timezone(&chrono::Utc);
+    state.usage.record("alpha", &usage, at);
+    state.usage.record("alpha", &usage, at);
+    state.usage.record("beta", &usage, at);
+    state.usage.flush().await;
+    let loopback: std::net::SocketAddr = ([127, 0, 0, 1], 0).into();
+    let app = server::build_app(state, 300).layer(MockConnectInfo(loopback));
+
+    let response = app
+        .clone()
+        .oneshot(
+            Request::get(
+                "/api/v1/usage?granularity=hour&agent=alpha&from=2026-03-01T00:00:00Z&to=2026-03-02T00:00:00Z",
+            )
+            .body(Body::empty())
+            .unwrap(),
+        )
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    let json: serde_json::Value = serde_json::from_slice(&body).unwrap();
+    assert_eq!(json["granularity"], "hour");
+    let buckets = json["buckets"].as_array().unwrap();
+    assert_eq!(buckets.len(), 1);
+    assert_eq!(buckets[0]["start"], "2026-03-01T10:00:00+00:00");
+    assert_eq!(buckets[0]["responses"], 2);
+    assert_eq!(json["totals"]["total_tokens"], 30);
+
+    let response = app
+        .oneshot(
+            Request::get("/api/v1/usage?granularity=minute")
+                .body(Body::empty())
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::BAD_REQUEST);
+}
+
+// ============================================================================
+// Error Responses
+// ============================================================================
+
+#[tokio::test]
+async fn test_problem_details_format() {
+    let app = test_app().await;
+
+    let response = app
+        .oneshot(
+            Request::get("/api/v1/agents/nonexistent")
+                .body(Body::empty())
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+
+    assert_eq!(response.status(), StatusCode::NOT_FOUND);
+    assert_eq!(
+        response.headers().get("content-type").unwrap(),
+        "application/problem+json"
+    );
+
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    let json: serde_json::Value = serde_json::from_slice(&body).unwrap();
+
+    // RFC 7807 required fields
     assert!(json.get("type").is_some());
     assert!(json.get("title").is_some());
     assert!(json.get("status").is_some());
+
+    // Catalog extension members
+    assert_eq!(json["code"], "agent-not-found");
+    assert_eq!(json["retryable"], false);
+}
+
+#[tokio::test]
+async fn test_list_problems() {
+    let app = test_app().await;
+
+    let response = app
+        .oneshot(
+            Request::get("/api/v1/problems")
+                .body(Body::empty())
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+
+    assert_eq!(response.status(), StatusCode::OK);
+
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    let json: serde_json::Value = serde_json::from_slice(&body).unwrap();
+    let problems = json["problems"].as_array().unwrap();
+
+    let session = problems
+        .iter()
+        .find(|p| p["code"] == "session-not-found")
+        .expect("catalog should include session-not-found");
+    assert_eq!(session["type"], "urn:duragent:problem:session-not-found");
+    assert_eq!(session["status"], 404);
+    assert_eq!(session["retryable"], false);
+    assert!(
+        session["docs_url"]
+            .as_str()
+            .unwrap()
+            .ends_with("#session-not-found")
+    );
+}
+
+#[tokio::test]
+async fn test_request_bodies_checked_against_openapi_schemas() {
+    use axum::extract::connect_info::MockConnectInfo;
+    use duragent::server;
+
+    async fn pointers(app: axum::Router, body: &str) -> Vec<String> {
+        let response = app
+            .oneshot(
+                Request::post("/api/v1/sessions")
+                    .header("content-type", "application/json")
+                    .body(Body::from(body.to_string()))
+                    .unwrap(),
+            )
+            .await
+            .unwrap();
+        assert_eq!(response.status(), StatusCode::BAD_REQUEST);
+        let body = response.into_body().collect().await.unwrap().to_bytes();
+        let json: serde_json::Value = serde_json::from_slice(&body).unwrap();
+        assert_eq!(json["code"], "validation-failed");
+        json["errors"]
+            .as_array()
+            .unwrap()
+            .iter()
+            .map(|e| e["pointer"].as_str().unwrap().to_string())
+            .collect()
+    }
+
+    // The schema check reports every mismatch at once
+    let mut found = pointers(test_app().await, r#"{"agent": 1, "user": 2}"#).await;
+    found.sort();
+    assert_eq!(found, ["/agent", "/user"]);
+
+    // Without it, the handler's own checks still run
+    let mut state = common::test_app_state().await;
+    state.request_validation = false;
+    let loopback: std::net::SocketAddr = ([127, 0, 0, 1], 0).into();
+    let app = server::build_app(state, 300).layer(MockConnectInfo(loopback));
+    assert_eq!(
+        pointers(app.clone(), r#"{"agent": 1, "user": 2}"#)
+            .await
+            .len(),
+        1
+    );
+    assert_eq!(pointers(app, r#"{"agent": "  "}"#).await, ["/agent"]);
+}
+
+// ============================================================================
+// Reverse Proxy
+// ============================================================================
+
+#[tokio::test]
+async fn test_base_path_and_external_url() {
+    use axum::extract::connect_info::MockConnectInfo;
+    use duragent::server::{self, ExternalUrl};
+
+    let mut state = common::test_app_state().await;
+    state.base_path = "/duragent".to_string();
+    state.external_url = ExternalUrl::new("/duragent", Some("https://example.com/duragent"));
+    let loopback: std::net::SocketAddr = ([127, 0, 0, 1], 0).into();
+    let app = server::build_app(state, 300).layer(MockConnectInfo(loopback));
+
+    // Routes are only served under the base path
+    let response = app
+        .clone()
+        .oneshot(Request::get("/livez").body(Body::empty()).unwrap())
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::NOT_FOUND);
+
+    let response = app
+        .oneshot(
+            Request::get("/duragent/api/v1/sessions/nonexistent")
+                .body(Body::empty())
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::NOT_FOUND);
+
+    // Problem instances link through the external URL
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    let json: serde_json::Value = serde_json::from_slice(&body).unwrap();
+    assert_eq!(
+        json["instance"],
+        "https://example.com/duragent/api/v1/sessions/nonexistent"
+    );
+}
+
+// ============================================================================
+// SCIM Provisioning
+// ============================================================================
+
+#[tokio::test]
+async fn test_scim_provisioning_maps_groups_to_roles() {
+    use axum::extract::connect_info::MockConnectInfo;
+    use duragent::config::{AccessRole, GroupRoleConfig};
+    use duragent::server;
+
+    let mut state = common::test_app_state().await;
+    state.scim.token = Some("scim-secret".to_string());
+    state.scim.group_roles = vec![GroupRoleConfig {
+        group: "support".to_string(),
+        role: AccessRole::Operator,
+        projects: vec!["helpdesk".to_string()],
+    }];
+    let loopback: std::net::SocketAddr = ([127, 0, 0, 1], 0).into();
+    let app = server::build_app(state, 300).layer(MockConnectInfo(loopback));
+
+    let scim = |method: &str, uri: &str, body: serde_json::Value| {
+        Request::builder()
+            .method(method)
+            .uri(uri)
+            .header("authorization", "Bearer scim-secret")
+            .header("content-type", "application/scim+json")
+            .body(Body::from(body.to_string()))
+            .unwrap()
+    };
+    async fn json(response: axum::response::Response) -> serde_json::Value {
+        let body = response.into_body().collect().await.unwrap().to_bytes();
+        serde_json::from_slice(&body).unwrap()
+    }
+
+    // The SCIM token is required even from localhost
+    let response = app
+        .clone()
+        .oneshot(Request::get("/scim/v2/Users").body(Body::empty()).unwrap())
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::UNAUTHORIZED);
+
+    let response = app
+        .clone()
+        .oneshot(scim(
+            "POST",
+            "/scim/v2/Users",
+            serde_json::json!({"userName": "ada@example.com", "emails": [{"value": "ada@example.com"}]}),
+        ))
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::CREATED);
+    let user_id = json(response).await["id"].as_str().unwrap().to_string();
+
+    // userName is unique, case-insensitively
+    let response = app
+        .clone()
+        .oneshot(scim(
+            "POST",
+            "/scim/v2/Users",
+            serde_json::json!({"userName": "ADA@example.com"}),
+        ))
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::CONFLICT);
+    assert_eq!(json(response).await["scimType"], "uniqueness");
+
+    // Malformed bodies get a SCIM error, not a plain-text rejection
+    let response = app
+        .clone()
+        .oneshot(
+            Request::post("/scim/v2/Users")
+                .header("authorization", "Bearer scim-secret")
+                .header("content-type", "application/scim+json")
+                .body(Body::from(r#"{"userName": "#))
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::BAD_REQUEST);
+    let error = json(response).await;
+    assert_eq!(error["scimType"], "invalidSyntax");
+    assert_eq!(
+        error["schemas"],
+        serde_json::json!(["urn:ietf:params:scim:api:messages:2.0:Error"])
+    );
+
+    let response = app
+        .clone()
+        .oneshot(scim(
+            "POST",
+            "/scim/v2/Groups",
+            serde_json::json!({"displayName": "support", "members": [{"value": user_id}]}),
+        ))
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::CREATED);
+
+    let response = app
+        .clone()
+        .oneshot(scim(
+            "GET",
+            "/scim/v2/Users?filter=userName%20eq%20%22ada%40example.com%22",
+            serde_json::Value::Null,
+        ))
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
+    let list = json(response).await;
+    assert_eq!(list["totalResults"], 1);
+    let user = &list["Resources"][0];
+    assert_eq!(user["groups"][0]["display"], "support");
+    let grants = &user["urn:duragent:params:scim:schemas:extension:access:1.0:User"]["grants"];
+    assert_eq!(
+        grants,
+        &serde_json::json!([{"role": "operator", "project": "helpdesk"}])
+    );
+
+    // Deactivating a user drops its access
+    let response = app
+        .clone()
+        .oneshot(scim(
+            "PATCH",
+            &format!("/scim/v2/Users/{user_id}"),
+            serde_json::json!({
+                "schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+                "Operations": [{"op": "replace", "path": "active", "value": false}],
+            }),
+        ))
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
+    let user = json(response).await;
+    assert_eq!(user["active"], false);
+    assert_eq!(
+        user["urn:duragent:params:scim:schemas:extension:access:1.0:User"]["grants"],
+        serde_json::json!([])
+    );
+
+    let response = app
+        .clone()
+        .oneshot(scim(
+            "DELETE",
+            &format!("/scim/v2/Users/{user_id}"),
+            serde_json::Value::Null,
+        ))
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::NO_CONTENT);
+
+    let response = app
+        .oneshot(scim("GET", "/scim/v2/Groups", serde_json::Value::Null))
+        .await
+        .unwrap();
+    let groups = json(response).await;
+    assert_eq!(groups["Resources"][0]["members"], serde_json::json!([]));
+}
+
+// ============================================================================
+// Service Accounts
+// ============================================================================
+
+#[tokio::test]
+async fn test_service_account_scopes_and_rotation() {
+    use axum::extract::connect_info::MockConnectInfo;
+    use duragent::server;
+
+    let mut state = common::test_app_state().await;
+    state.api_token = Some("api-secret".to_string());
+    let loopback: std::net::SocketAddr = ([127, 0, 0, 1], 0).into();
+    let app = server::build_app(state, 300).layer(MockConnectInfo(loopback));
+
+    let request = |method: &str, uri: &str, token: Option<&str>, body: serde_json::Value| {
+        let mut builder = Request::builder()
+            .method(method)
+            .uri(uri)
+            .header("content-type", "application/json");
+        if let Some(token) = token {
+            builder = builder.header("authorization", format!("Bearer {token}"));
+        }
+        let body = if body.is_null() {
+            Body::empty()
+        } else {
+            Body::from(body.to_string())
+        };
+        builder.body(body).unwrap()
+    };
+    async fn json(response: axum::response::Response) -> serde_json::Value {
+        let body = response.into_body().collect().await.unwrap().to_bytes();
+        serde_json::from_slice(&body).unwrap()
+    }
+
+    // Scopes are validated
+    let response = app
+        .clone()
+        .oneshot(request(
+            "POST",
+            "/api/admin/v1/service-accounts",
+            None,
+            serde_json::json!({"name": "ci", "scopes": ["agents:fly"]}),
+        ))
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::BAD_REQUEST);
+    assert_eq!(json(response).await["errors"][0]["pointer"], "/scopes/0");
+
+    let response = app
+        .clone()
+        .oneshot(request(
+            "POST",
+            "/api/admin/v1/service-accounts",
+            None,
+            serde_json::json!({
+                "name": "ci",
+                "scopes": ["usage:read", "projects:read@helpdesk"],
+            }),
+        ))
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::CREATED);
+    let created = json(response).await;
+    let id = created["service_account"]["id"]
+        .as_str()
+        .unwrap()
+        .to_string();
+    let token = created["token"].as_str().unwrap().to_string();
+    assert!(token.starts_with("dsa_"));
+
+    // Allowed: usage:read
+    let response = app
+        .clone()
+        .oneshot(request(
+            "GET",
+            "/api/v1/usage",
+            Some(&token),
+            serde_json::Value::Null,
+        ))
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
+
+    // Allowed in the scoped project only
+    let response = app
+        .clone()
+        .oneshot(request(
+            "GET",
+            "/api/v1/projects/helpdesk",
+            Some(&token),
+            serde_json::Value::Null,
+        ))
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::NOT_FOUND);
+
+    for uri in ["/api/v1/projects/billing", "/api/v1/sessions"] {
+        let response = app
+            .clone()
+            .oneshot(request("GET", uri, Some(&token), serde_json::Value::Null))
+            .await
+            .unwrap();
+        assert_eq!(response.status(), StatusCode::FORBIDDEN, "{uri}");
+    }
+
+    // Rotation without grace revokes the old token immediately
+    let response = app
+        .clone()
+        .oneshot(request(
+            "POST",
+            &format!("/api/admin/v1/service-accounts/{id}/rotate"),
+            None,
+            serde_json::Value::Null,
+        ))
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
+    let new_token = json(response).await["token"].as_str().unwrap().to_string();
+    assert_ne!(new_token, token);
+
+    let response = app
+        .clone()
+        .oneshot(request(
+            "GET",
+            "/api/v1/usage",
+            Some(&token),
+            serde_json::Value::Null,
+        ))
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::UNAUTHORIZED);
+
+    let response = app
+        .clone()
+        .oneshot(request(
+            "GET",
+            "/api/v1/usage",
+            Some(&new_token),
+            serde_json::Value::Null,
+        ))
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
+
+    let response = app
+        .clone()
+        .oneshot(request(
+            "DELETE",
+            &format!("/api/admin/v1/service-accounts/{id}"),
+            None,
+            serde_json::Value::Null,
+        ))
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::NO_CONTENT);
+
+    let response = app
+        .oneshot(request(
+            "GET",
+            "/api/v1/usage",
+            Some(&new_token),
+            serde_json::Value::Null,
+        ))
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::UNAUTHORIZED);
+}
+
+#[tokio::test]
+async fn test_agent_changes_attributed_to_service_account() {
+    use axum::extract::connect_info::MockConnectInfo;
+    use duragent::server;
+
+    let state = common::test_app_state().await;
+    let loopback: std::net::SocketAddr = ([127, 0, 0, 1], 0).into();
+    let app = server::build_app(state, 300).layer(MockConnectInfo(loopback));
+
+    let request = |method: &str, uri: &str, token: Option<&str>, body: serde_json::Value| {
+        let mut builder = Request::builder()
+            .method(method)
+            .uri(uri)
+            .header("content-type", "application/json");
+        if let Some(token) = token {
+            builder = builder.header("authorization", format!("Bearer {token}"));
+        }
+        let body = if body.is_null() {
+            Body::empty()
+        } else {
+            Body::from(body.to_string())
+        };
+        builder.body(body).unwrap()
+    };
+    async fn json(response: axum::response::Response) -> serde_json::Value {
+        let body = response.into_body().collect().await.unwrap().to_bytes();
+        serde_json::from_slice(&body).unwrap()
+    }
+
+    let response = app
+        .clone()
+        .oneshot(request(
+            "POST",
+            "/api/admin/v1/service-accounts",
+            None,
+            serde_json::json!({"name": "deployer", "scopes": ["agents:*"]}),
+        ))
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::CREATED);
+    let token = json(response).await["token"].as_str().unwrap().to_string();
+
+    let manifest = "apiVersion: duragent/v1alpha1\nkind: Agent\nmetadata:\n  name: alpha\nspec:\n  model:\n    provider: openrouter\n    name: anthropic/claude-sonnet-4\n";
+    let response = app
+        .clone()
+        .oneshot(request(
+            "POST",
+            "/api/v1/agents/bulk",
+            Some(&token),
+            serde_json::json!({
+                "operations": [{"op": "create", "name": "alpha", "manifest": manifest}],
+            }),
+        ))
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
+
+    let response = app
+        .clone()
+        .oneshot(request(
+            "GET",
+            "/api/v1/agents/alpha",
+            Some(&token),
+            serde_json::Value::Null,
+        ))
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
+    assert_eq!(
+        json(response).await["provenance"]["created_by"],
+        "service-account:deployer"
+    );
+
+    let manifest = manifest.replace("alpha", "beta");
+    let response = app
+        .clone()
+        .oneshot(request(
+            "POST",
+            "/api/v1/agents",
+            Some(&token),
+            serde_json::json!({"name": "beta", "manifest": manifest}),
+        ))
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::CREATED);
+    assert_eq!(
+        json(response).await["provenance"]["created_by"],
+        "service-account:deployer"
+    );
+}
+
+#[tokio::test]
+async fn test_reasoning_needs_admin_or_reasoning_scope() {
+    use axum::extract::connect_info::MockConnectInfo;
+    use duragent::server;
+
+    let mut state = common::test_app_state().await;
+    state.admin_token = Some("admin-secret".to_string());
+    state.expose_reasoning = true;
+    let loopback: std::net::SocketAddr = ([127, 0, 0, 1], 0).into();
+    let app = server::build_app(state, 300).layer(MockConnectInfo(loopback));
+
+    let request = |method: &str, uri: &str, token: &str, body: serde_json::Value| {
+        Request::builder()
+            .method(method)
+            .uri(uri)
+            .header("content-type", "application/json")
+            .header("authorization", format!("Bearer {token}"))
+            .body(Body::from(body.to_string()))
+            .unwrap()
+    };
+    async fn json(response: axum::response::Response) -> serde_json::Value {
+        let body = response.into_body().collect().await.unwrap().to_bytes();
+        serde_json::from_slice(&body).unwrap()
+    }
+
+    let manifest = "apiVersion: duragent/v1alpha1\nkind: Agent\nmetadata:\n  name: thinker\nspec:\n  model:\n    provider: mock\n    name: echo-reasoning\n";
+    let response = app
+        .clone()
+        .oneshot(request(
+            "POST",
+            "/api/v1/agents/bulk",
+            "admin-secret",
+            serde_json::json!({
+                "operations": [{"op": "create", "name": "thinker", "manifest": manifest}],
+            }),
+        ))
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
+
+    // Reasoning shown to each caller, if any
+    let mut tokens = vec![("admin", "admin-secret".to_string())];
+    for (name, scopes) in [
+        ("runner", serde_json::json!(["sessions:*", "runs:create"])),
+        ("everything", serde_json::json!(["*:*"])),
+        (
+            "auditor",
+            serde_json::json!(["sessions:*", "runs:create", "admin:read"]),
+        ),
+        (
+            "operator",
+            serde_json::json!(["sessions:*", "runs:create", "admin:update"]),
+        ),
+        (
+            "reviewer",
+            serde_json::json!(["sessions:*", "runs:create", "runs:reasoning"]),
+        ),
+    ] {
+        let response = app
+            .clone()
+            .oneshot(request(
+                "POST",
+                "/api/admin/v1/service-accounts",
+                "admin-secret",
+                serde_json::json!({"name": name, "scopes": scopes}),
+            ))
+            .await
+            .unwrap();
+        assert_eq!(response.status(), StatusCode::CREATED);
+        let token = json(response).await["token"].as_str().unwrap().to_string();
+        tokens.push((name, token));
+    }
+
+    for (name, token) in &tokens {
+        let response = app
+            .clone()
+            .oneshot(request(
+                "POST",
+                "/api/v1/sessions",
+                token,
+                serde_json::json!({"agent": "thinker"}),
+            ))
+            .await
+            .unwrap();
+        assert_eq!(response.status(), StatusCode::CREATED, "{name}");
+        let session_id = json(response).await["session_id"]
+            .as_str()
+            .unwrap()
+            .to_string();
+
+        let response = app
+            .clone()
+            .oneshot(request(
+                "POST",
+                &format!("/api/v1/sessions/{session_id}/messages"),
+                token,
+                serde_json::json!({"content": "hello"}),
+            ))
+            .await
+            .unwrap();
+        assert_eq!(response.status(), StatusCode::OK, "{name}");
+        let message = json(response).await;
+        assert_eq!(message["content"], "hello", "{name}");
+        let shown = !message["reasoning"].is_null();
+        assert_eq!(
+            shown,
+            matches!(*name, "admin" | "operator" | "reviewer"),
+            "{name}"
+        );
+    }
+}
+
+// ============================================================================
+// Authorization Policies
+// ============================================================================
+
+#[tokio::test]
+async fn test_authorization_policy_restricts_tagged_agents_to_group() {
+    use axum::extract::connect_info::MockConnectInfo;
+    use duragent::config::{AuthorizationConfig, PolicyConfig};
+    use duragent::identity::{Group, User};
+    use duragent::policy::Policies;
+    use duragent::server;
+
+    let mut state = common::test_app_state().await;
+    state.policies = Policies::from_config(&AuthorizationConfig {
+        user_header: Some("x-forwarded-user".to_string()),
+        policies: vec![PolicyConfig {
+            name: "gpu-agents".to_string(),
+            description: Some("GPU agents are reserved for the ML team".to_string()),
+            when: Some(r#""gpu" in agent.tags"#.to_string()),
+            allow: r#""ml" in principal.groups"#.to_string(),
+        }],
+    })
+    .unwrap();
+    let ada = User::new("ada@example.com".to_string());
+    let mut ml = Group::new("ml".to_string());
+    ml.add_member(&ada.id);
+    state.identity.save_user(&ada).await.unwrap();
+    state
+        .identity
+        .save_user(&User::new("bob@example.com".to_string()))
+        .await
+        .unwrap();
+    state.identity.save_group(&ml).await.unwrap();
+    let loopback: std::net::SocketAddr = ([127, 0, 0, 1], 0).into();
+    let app = server::build_app(state, 300).layer(MockConnectInfo(loopback));
+
+    let manifest = |name: &str, tags: &str| {
+        format!(
+            "apiVersion: duragent/v1alpha1\nkind: Agent\nmetadata:\n  name: {name}\n  tags: [{tags}]\nspec:\n  model:\n    provider: openrouter\n    name: anthropic/claude-sonnet-4\n"
+        )
+    };
+    let create = serde_json::json!({
+        "operations": [
+            {"op": "create", "name": "trainer", "manifest": manifest("trainer", "gpu")},
+            {"op": "create", "name": "helper", "manifest": manifest("helper", "")},
+        ]
+    });
+    let response = app
+        .clone()
+        .oneshot(
+            Request::post("/api/v1/agents/bulk")
+                .header("content-type", "application/json")
+                .body(Body::from(create.to_string()))
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
+
+    let get_agent = |name: &str, user: &str| {
+        Request::get(format!("/api/v1/agents/{name}"))
+            .header("x-forwarded-user", user)
+            .body(Body::empty())
+            .unwrap()
+    };
+
+    let response = app
+        .clone()
+        .oneshot(get_agent("trainer", "ADA@example.com"))
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
+
+    let response = app
+        .clone()
+        .oneshot(get_agent("trainer", "bob@example.com"))
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::FORBIDDEN);
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    let json: serde_json::Value = serde_json::from_slice(&body).unwrap();
+    assert_eq!(
+        json["detail"],
+        "denied by policy 'gpu-agents': GPU agents are reserved for the ML team"
+    );
+
+    let response = app
+        .oneshot(get_agent("helper", "bob@example.com"))
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
+}
+
+#[tokio::test]
+async fn test_group_roles_limit_named_users() {
+    use axum::extract::connect_info::MockConnectInfo;
+    use duragent::config::{AccessRole, AuthorizationConfig, GroupRoleConfig};
+    use duragent::identity::{Group, User};
+    use duragent::policy::Policies;
+    use duragent::server;
+
+    let mut state = common::test_app_state().await;
+    state.policies = Policies::from_config(&AuthorizationConfig {
+        user_header: Some("x-forwarded-user".to_string()),
+        policies: Vec::new(),
+    })
+    .unwrap();
+    state.scim.group_roles = vec![
+        GroupRoleConfig {
+            group: "support".to_string(),
+            role: AccessRole::Viewer,
+            projects: Vec::new(),
+        },
+        GroupRoleConfig {
+            group: "ops".to_string(),
+            role: AccessRole::Operator,
+            projects: vec!["default".to_string()],
+        },
+    ];
+    let ada = User::new("ada@example.com".to_string());
+    let bob = User::new("bob@example.com".to_string());
+    let mut support = Group::new("support".to_string());
+    let mut ops = Group::new("ops".to_string());
+    support.add_member(&ada.id);
+    ops.add_member(&bob.id);
+    state.identity.save_user(&ada).await.unwrap();
+    state.identity.save_user(&bob).await.unwrap();
+    state.identity.save_group(&support).await.unwrap();
+    state.identity.save_group(&ops).await.unwrap();
+    let loopback: std::net::SocketAddr = ([127, 0, 0, 1], 0).into();
+    let app = server::build_app(state, 300).layer(MockConnectInfo(loopback));
+
+    let list_sessions = |user: &str| {
+        Request::get("/api/v1/sessions")
+            .header("x-forwarded-user", user)
+            .body(Body::empty())
+            .unwrap()
+    };
+    let create_session = |user: &str| {
+        Request::post("/api/v1/sessions")
+            .header("x-forwarded-user", user)
+            .header("content-type", "application/json")
+            .body(Body::from(r#"{"agent":"helper"}"#))
+            .unwrap()
+    };
+
+    // Requests that name no user are not limited by roles
+    let response = app
+        .clone()
+        .oneshot(
+            Request::get("/api/v1/sessions")
+                .body(Body::empty())
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
+
+    // Viewers read everywhere but cannot create sessions
+    let response = app
+        .clone()
+        .oneshot(list_sessions("ada@example.com"))
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
+    let response = app
+        .clone()
+        .oneshot(create_session("ada@example.com"))
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::FORBIDDEN);
+
+    // A project-scoped operator creates sessions there but cannot list
+    // sessions across projects
+    let response = app
+        .clone()
+        .oneshot(create_session("bob@example.com"))
+        .await
+        .unwrap();
+    assert_ne!(response.status(), StatusCode::FORBIDDEN);
+    let response = app
+        .clone()
+        .oneshot(list_sessions("bob@example.com"))
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::FORBIDDEN);
+
+    // Unknown users have no role
+    let response = app.oneshot(list_sessions("eve@example.com")).await.unwrap();
+    assert_eq!(response.status(), StatusCode::FORBIDDEN);
+}
+
+// ============================================================================
+// Audit Log
+// ============================================================================
+
+#[tokio::test]
+async fn test_audit_log_records_auth_failures_and_admin_actions() {
+    use axum::extract::connect_info::MockConnectInfo;
+    use duragent::audit::AuditLog;
+    use duragent::config::{AuditConfig, AuditSinkConfig};
+    use duragent::server;
+
+    let tmp = tempfile::TempDir::new().unwrap();
+    let path = tmp.path().join("audit.jsonl");
+    let mut state = common::test_app_state().await;
+    state.admin_token = Some("secret".to_string());
+    state.audit = AuditLog::start(
+        &AuditConfig {
+            sinks: vec![AuditSinkConfig::File { path: None }],
+            flush_interval_ms: 10,
+            ..AuditConfig::default()
+        },
+        path.clone(),
+        reqwest::Client::new(),
+    );
+    let remote: std::net::SocketAddr = ([10, 0, 0, 7], 4000).into();
+    let app = server::build_app(state, 300).layer(MockConnectInfo(remote));
+
+    let response = app
+        .clone()
+        .oneshot(
+            Request::get("/api/admin/v1/features")
+                .body(Body::empty())
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::FORBIDDEN);
+
+    let response = app
+        .oneshot(
+            Request::post("/api/admin/v1/reload-agents")
+                .header("authorization", "Bearer secret")
+                .body(Body::empty())
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
+
+    let mut events = Vec::new();
+    for _ in 0..100 {
+        tokio::time::sleep(std::time::Duration::from_millis(20)).await;
+        events = std::fs::read_to_string(&path)
+            .unwrap_or_default()
+            .lines()
+            .map(|line| serde_json::from_str::<serde_json::Value>(line).unwrap())
+            .collect();
+        if events.len() >= 2 {
+            break;
+        }
+    }
+    assert_eq!(events.len(), 2);
+    assert_eq!(events[0]["action"], "auth.failed");
+    assert_eq!(events[0]["outcome"], "denied");
+    assert_eq!(events[0]["source_ip"], "10.0.0.7");
+    assert_eq!(events[1]["action"], "agents.reloaded");
+    assert_eq!(events[1]["outcome"], "success");
+    assert_eq!(events[1]["principal"], "admin");
+}
+
+// ============================================================================
+// Health History
+// ============================================================================
+
+#[tokio::test]
+async fn test_health_history_reports_incidents_and_error_budget() {
+    use axum::extract::connect_info::MockConnectInfo;
+    use duragent::health::CheckResult;
+    use duragent::server;
+
+    let state = common::test_app_state().await;
+    let at = |minute: u32| -> chrono::DateTime<chrono::Utc> {
+        format!("2026-01-15T10:{minute:02}:00Z").parse().unwrap()
+    };
+    for (minute, error) in [(0, None), (1, Some("api error (status 502)")), (2, None)] {
+        state.health.record(
+            "provider:openai",
+            CheckResult {
+                checked_at: at(minute),
+                latency_ms: 120,
+                error: error.map(String::from),
+            },
+        );
+    }
+    state.health.record(
+        "store",
+        CheckResult {
+            checked_at: at(2),
+            latency_ms: 1,
+            error: None,
+        },
+    );
+    let loopback: std::net::SocketAddr = ([127, 0, 0, 1], 0).into();
+    let app = server::build_app(state, 300).layer(MockConnectInfo(loopback));
+
+    let response = app
+        .clone()
+        .oneshot(
+            Request::get("/api/v1/admin/health-history?limit=2")
+                .body(Body::empty())
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    let json: serde_json::Value = serde_json::from_slice(&body).unwrap();
+    assert_eq!(json["slo_target"], 0.99);
+    let openai = &json["dependencies"][0];
+    assert_eq!(openai["name"], "provider:openai");
+    assert_eq!(openai["status"], "up");
+    assert_eq!(openai["checks"], 3);
+    assert_eq!(openai["failures"], 1);
+    assert_eq!(openai["transitions"], 2);
+    assert!(openai["error_budget_remaining"].as_f64().unwrap() < 0.0);
+    assert_eq!(openai["history"].as_array().unwrap().len(), 2);
+    assert_eq!(openai["incidents"][0]["failed_checks"], 1);
+    assert_eq!(
+        openai["incidents"][0]["last_error"],
+        "api error (status 502)"
+    );
+    assert!(openai["incidents"][0]["ended_at"].is_string());
+    assert_eq!(json["dependencies"][1]["name"], "store");
+
+    let response = app
+        .oneshot(
+            Request::get("/api/v1/admin/health-history?dependency=store")
+                .body(Body::empty())
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    let json: serde_json::Value = serde_json::from_slice(&body).unwrap();
+    assert_eq!(json["dependencies"].as_array().unwrap().len(), 1);
+}
+
+// ============================================================================
+// Provider SLOs
+// ============================================================================
+
+#[tokio::test]
+async fn test_slos_report_burn_rate_of_mock_provider_requests() {
+    use axum::extract::connect_info::MockConnectInfo;
+    use duragent::config::{ProviderSloConfig, SloConfig};
+    use duragent::llm::{ChatRequest, Message, Provider, ProviderRegistry, Role};
+    use duragent::server;
+    use duragent::slo::ProviderSlos;
+
+    let slos = ProviderSlos::from_config(&SloConfig {
+        providers: vec![ProviderSloConfig {
+            provider: "mock".to_string(),
+            latency_ms: Some(60_000),
+            latency_target: 0.95,
+            error_target: Some(0.99),
+            window_minutes: 60,
+            burn_rate_threshold: 2.0,
+            min_requests: 1,
+        }],
+        ..SloConfig::default()
+    })
+    .unwrap();
+    let registry = ProviderRegistry::new().with_slos(slos.clone());
+    let provider = registry.get(&Provider::Mock, None).await.unwrap();
+    provider
+        .chat(ChatRequest::new(
+            "echo",
+            vec![Message::text(Role::User, "hello")],
+            None,
+            None,
+        ))
+        .await
+        .unwrap();
+
+    let mut state = common::test_app_state().await;
+    state.slos = slos;
+    let loopback: std::net::SocketAddr = ([127, 0, 0, 1], 0).into();
+    let app = server::build_app(state, 300).layer(MockConnectInfo(loopback));
+
+    let response = app
+        .oneshot(
+            Request::get("/api/v1/admin/slos")
+                .body(Body::empty())
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    let json: serde_json::Value = serde_json::from_slice(&body).unwrap();
+    let objectives = json["objectives"].as_array().unwrap();
+    assert_eq!(objectives.len(), 2);
+    assert_eq!(objectives[0]["objective"], "latency");
+    assert_eq!(objectives[1]["objective"], "errors");
+    for objective in objectives {
+        assert_eq!(objective["provider"], "mock");
+        assert_eq!(objective["requests"], 1);
+        assert_eq!(objective["bad"], 0);
+        assert_eq!(objective["alerting"], false);
+    }
+}
+
+// ============================================================================
+// Metrics
+// ============================================================================
+
+#[tokio::test]
+async fn test_metrics_expose_request_counts_and_gauges() {
+    use axum::extract::connect_info::MockConnectInfo;
+    use duragent::health::CheckResult;
+    use duragent::server;
+
+    let state = common::test_app_state().await;
+    state.health.record(
+        "store",
+        CheckResult {
+            checked_at: chrono::Utc::now(),
+            latency_ms: 4,
+            error: None,
+        },
+    );
+    let loopback: std::net::SocketAddr = ([127, 0, 0, 1], 0).into();
+    let app = server::build_app(state, 300).layer(MockConnectInfo(loopback));
+
+    let response = app
+        .clone()
+        .oneshot(Request::get("/api/v1/agents").body(Body::empty()).unwrap())
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
+
+    let response = app
+        .oneshot(Request::get("/metrics").body(Body::empty()).unwrap())
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
+    assert!(
+        response.headers()["content-type"]
+            .to_str()
+            .unwrap()
+            .starts_with("text/plain")
+    );
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    let text = String::from_utf8(body.to_vec()).unwrap();
+    assert!(text.lines().any(|line| {
+        line.starts_with(r#"duragent_http_requests_total{method="GET""#)
+            && line.ends_with(r#"status="200"} 1"#)
+    }));
+    assert!(text.contains("duragent_agents_loaded 0"));
+    assert!(text.contains(r#"duragent_dependency_up{dependency="store"} 1"#));
+    assert!(text.contains("# TYPE duragent_slo_burn_rate gauge"));
+    // The scrape itself
+    assert!(text.contains("duragent_http_requests_in_flight 1"));
+}
+
+// ============================================================================
+// Request IDs
+// ============================================================================
+
+#[tokio::test]
+async fn test_request_ids_tag_responses_and_problems() {
+    use axum::extract::connect_info::MockConnectInfo;
+    use duragent::server;
+
+    let state = common::test_app_state().await;
+    let loopback: std::net::SocketAddr = ([127, 0, 0, 1], 0).into();
+    let app = server::build_app(state, 300).layer(MockConnectInfo(loopback));
+
+    let response = app
+        .clone()
+        .oneshot(
+            Request::get("/api/v1/agents/missing")
+                .header("x-request-id", "client-req-2")
+                .body(Body::empty())
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::NOT_FOUND);
+    assert_eq!(response.headers()["x-request-id"], "client-req-2");
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    let json: serde_json::Value = serde_json::from_slice(&body).unwrap();
+    assert_eq!(json["code"], "agent-not-found");
+    assert_eq!(json["request_id"], "client-req-2");
+
+    // Invalid IDs are replaced
+    let response = app
+        .oneshot(
+            Request::get("/api/v1/agents/missing")
+                .header("x-request-id", "not valid")
+                .body(Body::empty())
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    let request_id = response.headers()["x-request-id"]
+        .to_str()
+        .unwrap()
+        .to_string();
+    assert!(request_id.starts_with("req_"));
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    let json: serde_json::Value = serde_json::from_slice(&body).unwrap();
+    assert_eq!(json["request_id"], request_id);
+}
+
+// ============================================================================
+// Status Page
+// ============================================================================
+
+#[tokio::test]
+async fn test_status_page_reports_components_and_incidents() {
+    use axum::extract::connect_info::MockConnectInfo;
+    use duragent::health::CheckResult;
+    use duragent::server;
+
+    let mut state = common::test_app_state().await;
+    state.api_token = Some("secret".to_string());
+    let now = chrono::Utc::now();
+    let check = |minutes_ago: i64, error: Option<&str>| CheckResult {
+        checked_at: now - chrono::Duration::minutes(minutes_ago),
+        latency_ms: 5,
+        error: error.map(str::to_string),
+    };
+    state.health.record("store", check(2, None));
+    state.health.record("store", check(1, None));
+    state.health.record("provider:openai", check(2, None));
+    state
+        .health
+        .record("provider:openai", check(1, Some("timeout")));
+
+    let remote: std::net::SocketAddr = ([203, 0, 113, 7], 0).into();
+    let app = server::build_app(state.clone(), 300).layer(MockConnectInfo(remote));
+    let response = app
+        .oneshot(Request::get("/status").body(Body::empty()).unwrap())
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::UNAUTHORIZED);
+
+    state.status_page.public = true;
+    let app = server::build_app(state, 300).layer(MockConnectInfo(remote));
+    let response = app
+        .clone()
+        .oneshot(
+            Request::get("/status")
+                .header("accept", "application/json")
+                .body(Body::empty())
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    let json: serde_json::Value = serde_json::from_slice(&body).unwrap();
+    assert_eq!(json["status"], "degraded");
+    let components = json["components"].as_array().unwrap();
+    let openai = components
+        .iter()
+        .find(|c| c["name"] == "provider:openai")
+        .unwrap();
+    assert_eq!(openai["status"], "down");
+    assert_eq!(openai["uptime_1h"], 0.5);
+    assert_eq!(json["active_incidents"][0]["component"], "provider:openai");
+    assert!(!body.windows(7).any(|w| w == b"timeout"));
+
+    let response = app
+        .oneshot(Request::get("/status").body(Body::empty()).unwrap())
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
+    assert!(
+        response.headers()["content-type"]
+            .to_str()
+            .unwrap()
+            .starts_with("text/html")
+    );
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    let html = String::from_utf8(body.to_vec()).unwrap();
+    assert!(html.contains("Some systems degraded"));
+    assert!(html.contains("provider:openai"));
+}
+
+// ============================================================================
+// Fault Injection
+// ============================================================================
+
+#[tokio::test]
+async fn test_fault_injection_fails_matching_routes() {
+    use duragent::config::{FaultSpec, FaultsConfig, RouteFaultConfig};
+    use duragent::faults::FaultInjector;
+    use duragent::server;
+
+    let mut state = common::test_app_state().await;
+    state.faults = FaultInjector::from_config(&FaultsConfig {
+        enabled: true,
+        routes: vec![RouteFaultConfig {
+            path: "/api/v1/agents".to_string(),
+            methods: vec!["GET".to_string()],
+            faults: FaultSpec {
+                error_rate: 1.0,
+                error_status: 502,
+                ..FaultSpec::default()
+            },
+        }],
+        providers: Vec::new(),
+    })
+    .unwrap();
+    let app = server::build_app(state, 300);
+
+    let response = app
+        .clone()
+        .oneshot(Request::get("/api/v1/agents").body(Body::empty()).unwrap())
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::BAD_GATEWAY);
+    assert_eq!(response.headers()["x-duragent-fault"], "error");
+
+    let response = app
+        .oneshot(Request::get("/livez").body(Body::empty()).unwrap())
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
+    assert!(response.headers().get("x-duragent-fault").is_none());
+}
+
+// ============================================================================
+// Public Agents
+// ============================================================================
+
+#[tokio::test]
+async fn test_public_agent_sessions_are_rate_limited() {
+    let app = test_app().await;
+    let send = |req: Request<Body>| {
+        let app = app.clone();
+        async move { app.oneshot(req).await.unwrap() }
+    };
+    async fn json(response: axum::response::Response) -> serde_json::Value {
+        let body = response.into_body().collect().await.unwrap().to_bytes();
+        serde_json::from_slice(&body).unwrap()
+    }
+    let post = |uri: String, body: &str| {
+        Request::post(uri)
+            .header("content-type", "application/json")
+            .body(Body::from(body.to_string()))
+            .unwrap()
+    };
+
+    let create = serde_json::json!({
+        "operations": [
+            {
+                "op": "create",
+                "name": "widget",
+                "manifest": "apiVersion: duragent/v1alpha1\nkind: Agent\nmetadata:\n  name: widget\nspec:\n  model:\n    provider: mock\n    name: echo\n  access:\n    public:\n      requests_per_minute: 3\n",
+            },
+            {
+                "op": "create",
+                "name": "internal",
+                "manifest": "apiVersion: duragent/v1alpha1\nkind: Agent\nmetadata:\n  name: internal\nspec:\n  model:\n    provider: mock\n    name: echo\n",
+            }
+        ]
+    });
+    let response = send(post("/api/v1/agents/bulk".to_string(), &create.to_string())).await;
+    assert_eq!(response.status(), StatusCode::OK);
+
+    // Agents that aren't public don't exist as far as anonymous callers know
+    let response = send(post(
+        "/public/v1/agents/internal/sessions".to_string(),
+        "{}",
+    ))
+    .await;
+    assert_eq!(response.status(), StatusCode::NOT_FOUND);
+
+    let response = send(post("/public/v1/agents/widget/sessions".to_string(), "{}")).await;
+    assert_eq!(response.status(), StatusCode::CREATED);
+    let session = json(response).await;
+    assert_eq!(session["agent"], "widget");
+    let session_id = session["session_id"].as_str().unwrap().to_string();
+
+    let messages = format!("/public/v1/agents/widget/sessions/{session_id}/messages");
+    for _ in 0..2 {
+        let response = send(post(messages.clone(), r#"{"content":"hello"}"#)).await;
+        assert_eq!(response.status(), StatusCode::OK);
+    }
+
+    let response = send(post(messages.clone(), r#"{"content":"hello"}"#)).await;
+    assert_eq!(response.status(), StatusCode::TOO_MANY_REQUESTS);
+    assert!(response.headers().contains_key("retry-after"));
+    assert_eq!(json(response).await["code"], "rate-limited");
+
+    // The session is an ordinary one to authenticated callers
+    let response = send(
+        Request::get(format!("/api/v1/sessions/{session_id}"))
+            .body(Body::empty())
+            .unwrap(),
+    )
+    .await;
+    assert_eq!(response.status(), StatusCode::OK);
+}
+
+// ============================================================================
+// Read-Only Mode
+// ============================================================================
+
+#[tokio::test]
+async fn test_read_only_mode_rejects_mutations() {
+    use axum::extract::connect_info::MockConnectInfo;
+    use duragent::server;
+
+    let mut state = common::test_app_state().await;
+    state.read_only = true;
+    let loopback: std::net::SocketAddr = ([127, 0, 0, 1], 0).into();
+    let app = server::build_app(state, 300).layer(MockConnectInfo(loopback));
+
+    let response = app
+        .clone()
+        .oneshot(
+            Request::get("/api/v1/sessions")
+                .body(Body::empty())
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
+
+    let body = r#"{"agent": "test-agent"}"#;
+    let response = app
+        .clone()
+        .oneshot(
+            Request::post("/api/v1/sessions")
+                .header("content-type", "application/json")
+                .body(Body::from(body))
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::FORBIDDEN);
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    let json: serde_json::Value = serde_json::from_slice(&body).unwrap();
+    assert_eq!(json["code"], "read-only");
+
+    let response = app
+        .clone()
+        .oneshot(
+            Request::post("/api/admin/v1/reload-agents")
+                .body(Body::empty())
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::FORBIDDEN);
+
+    // SCIM provisioning is a mutation too
+    let response = app
+        .clone()
+        .oneshot(
+            Request::post("/scim/v2/Users")
+                .header("content-type", "application/scim+json")
+                .body(Body::from(r#"{"userName": "ada@example.com"}"#))
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::FORBIDDEN);
+    let response = app
+        .clone()
+        .oneshot(Request::get("/scim/v2/Users").body(Body::empty()).unwrap())
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
+
+    // Operating the process still works
+    let response = app
+        .oneshot(
+            Request::post("/api/admin/v1/shutdown")
+                .body(Body::empty())
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
+}
+
+// ============================================================================
+// Debug Capture
+// ============================================================================
+
+#[tokio::test]
+async fn test_debug_capture_records_client_errors() {
+    use axum::extract::connect_info::MockConnectInfo;
+    use duragent::config::DebugCaptureConfig;
+    use duragent::server;
+
+    let mut state = common::test_app_state().await;
+    state.debug_capture = server::DebugCapture::new(&DebugCaptureConfig {
+        enabled: true,
+        ..DebugCaptureConfig::default()
+    });
+    let loopback: std::net::SocketAddr = ([127, 0, 0, 1], 0).into();
+    let app = server::build_app(state, 300).layer(MockConnectInfo(loopback));
+
+    let body = r#"{"agent": "test-agent", "api_key": "sk-123""#;
+    let response = app
+        .clone()
+        .oneshot(
+            Request::post("/api/v1/sessions")
+                .header("content-type", "application/json")
+                .header("content-length", body.len())
+                .header("x-request-id", "client-req-1")
+                .body(Body::from(body))
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::BAD_REQUEST);
+    assert_eq!(response.headers()["x-request-id"], "client-req-1");
+
+    let response = app
+        .clone()
+        .oneshot(
+            Request::get("/api/admin/v1/debug/requests/client-req-1")
+                .body(Body::empty())
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    let json: serde_json::Value = serde_json::from_slice(&body).unwrap();
+    assert_eq!(json["status"], 400);
+    assert_eq!(json["path"], "/api/v1/sessions");
+    assert_eq!(
+        json["body_text"],
+        r#"{"agent": "test-agent", "api_key": "[REDACTED]""#
+    );
+    assert_eq!(json["response"]["status"], 400);
+
+    // Successful requests get an ID but are not captured
+    let response = app
+        .clone()
+        .oneshot(Request::get("/api/v1/agents").body(Body::empty()).unwrap())
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
+    let request_id = response.headers()["x-request-id"].to_str().unwrap();
+    assert!(request_id.starts_with("req_"));
+
+    let response = app
+        .oneshot(
+            Request::get(format!("/api/admin/v1/debug/requests/{request_id}"))
+                .body(Body::empty())
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::NOT_FOUND);
+}
+
+// ============================================================================
+// Templates
+// ============================================================================
+
+#[tokio::test]
+async fn test_render_template_reports_output_and_warnings() {
+    use axum::extract::connect_info::MockConnectInfo;
+    use duragent::server;
+
+    let state = common::test_app_state().await;
+    let loopback: std::net::SocketAddr = ([127, 0, 0, 1], 0).into();
+    let app = server::build_app(state, 300).layer(MockConnectInfo(loopback));
+
+    let body = serde_json::json!({
+        "template": "{{#if user}}Hi {{user | upper}}{{else}}Hi{{/if}}. {{json tags}} {{nope}}",
+        "vars": {"user": "ada", "tags": ["a", "b"]},
+    });
+    let response = app
+        .clone()
+        .oneshot(
+            Request::post("/api/v1/templates/render")
+                .header("content-type", "application/json")
+                .body(Body::from(body.to_string()))
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    let json: serde_json::Value = serde_json::from_slice(&body).unwrap();
+    assert_eq!(json["rendered"], r#"Hi ADA. ["a","b"] {{nope}}"#);
+    assert_eq!(json["warnings"][0], "{{nope}}: unknown variable 'nope'");
+    assert!(json["estimated_tokens"].as_u64().unwrap() > 0);
+
+    let body = serde_json::json!({"template": "{{agent.name}}", "agent": "missing"});
+    let response = app
+        .oneshot(
+            Request::post("/api/v1/templates/render")
+                .header("content-type", "application/json")
+                .body(Body::from(body.to_string()))
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::NOT_FOUND);
+}
+
+// ============================================================================
+// Examples
+// ============================================================================
+
+#[tokio::test]
+async fn test_example_pool_crud_and_similarity_selection() {
+    use axum::extract::connect_info::MockConnectInfo;
+    use duragent::server;
+
+    let state = common::test_app_state().await;
+    let loopback: std::net::SocketAddr = ([127, 0, 0, 1], 0).into();
+    let app = server::build_app(state, 300).layer(MockConnectInfo(loopback));
+
+    let manifest = "apiVersion: duragent/v1alpha1\nkind: Agent\nmetadata:\n  name: helper\nspec:\n  model:\n    provider: mock\n    name: echo\n  examples:\n    count: 1\n    selection: similarity\n    embedding_model: mock-embed\n";
+    let create = serde_json::json!({
+        "operations": [{"op": "create", "name": "helper", "manifest": manifest}]
+    });
+    let response = app
+        .clone()
+        .oneshot(
+            Request::post("/api/v1/agents/bulk")
+                .header("content-type", "application/json")
+                .body(Body::from(create.to_string()))
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
+
+    let post_json = |uri: &str, body: serde_json::Value| {
+        Request::post(uri)
+            .header("content-type", "application/json")
+            .body(Body::from(body.to_string()))
+            .unwrap()
+    };
+
+    let mut ids = Vec::new();
+    for (input, output) in [
+        ("how do I reset my password", "Use the reset link."),
+        ("what are your opening hours", "9 to 5."),
+    ] {
+        let response = app
+            .clone()
+            .oneshot(post_json(
+                "/api/v1/agents/helper/examples",
+                serde_json::json!({"input": input, "output": output}),
+            ))
+            .await
+            .unwrap();
+        assert_eq!(response.status(), StatusCode::CREATED);
+        let body = response.into_body().collect().await.unwrap().to_bytes();
+        let json: serde_json::Value = serde_json::from_slice(&body).unwrap();
+        ids.push(json["id"].as_str().unwrap().to_string());
+    }
+
+    let response = app
+        .clone()
+        .oneshot(post_json(
+            "/api/v1/agents/helper/examples",
+            serde_json::json!({"input": " ", "output": "x"}),
+        ))
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::BAD_REQUEST);
+
+    let response = app
+        .clone()
+        .oneshot(post_json(
+            "/api/v1/agents/helper/examples/select",
+            serde_json::json!({"input": "when are the opening hours"}),
+        ))
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    let json: serde_json::Value = serde_json::from_slice(&body).unwrap();
+    assert_eq!(json["examples"].as_array().unwrap().len(), 1);
+    assert_eq!(json["examples"][0]["id"], ids[1].as_str());
+    assert_eq!(json["examples"][0]["embedding_model"], "mock-embed");
+
+    let response = app
+        .clone()
+        .oneshot(
+            Request::put(format!("/api/v1/agents/helper/examples/{}", ids[0]))
+                .header("content-type", "application/json")
+                .body(Body::from(
+                    serde_json::json!({"input": "forgot password", "output": "Reset it.", "tags": ["account"]})
+                        .to_string(),
+                ))
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    let json: serde_json::Value = serde_json::from_slice(&body).unwrap();
+    assert_eq!(json["tags"], serde_json::json!(["account"]));
+    assert!(json.get("embedding_model").is_none());
+
+    let response = app
+        .clone()
+        .oneshot(
+            Request::delete(format!("/api/v1/agents/helper/examples/{}", ids[1]))
+                .body(Body::empty())
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::NO_CONTENT);
+
+    let response = app
+        .clone()
+        .oneshot(
+            Request::get("/api/v1/agents/helper/examples")
+                .body(Body::empty())
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    let json: serde_json::Value = serde_json::from_slice(&body).unwrap();
+    assert_eq!(json["examples"].as_array().unwrap().len(), 1);
+    assert_eq!(json["examples"][0]["input"], "forgot password");
+
+    let response = app
+        .oneshot(
+            Request::get("/api/v1/agents/missing/examples")
+                .body(Body::empty())
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::NOT_FOUND);
+}
+
+// ============================================================================
+// Prompts
+// ============================================================================
+
+#[tokio::test]
+async fn test_prompt_versions_and_agent_includes() {
+    use axum::extract::connect_info::MockConnectInfo;
+    use duragent::server;
+
+    let state = common::test_app_state().await;
+    let loopback: std::net::SocketAddr = ([127, 0, 0, 1], 0).into();
+    let app = server::build_app(state, 300).layer(MockConnectInfo(loopback));
+
+    let put_json = |uri: &str, body: serde_json::Value| {
+        Request::put(uri)
+            .header("content-type", "application/json")
+            .body(Body::from(body.to_string()))
+            .unwrap()
+    };
+    let get = |uri: &str| Request::get(uri).body(Body::empty()).unwrap();
+
+    let response = app
+        .clone()
+        .oneshot(put_json(
+            "/api/v1/prompts/policy",
+            serde_json::json!({"content": "Be careful.", "description": "Safety"}),
+        ))
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::CREATED);
+    assert!(response.headers().contains_key("location"));
+
+    let response = app
+        .clone()
+        .oneshot(put_json(
+            "/api/v1/prompts/policy",
+            serde_json::json!({"content": "Be very careful."}),
+        ))
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    let json: serde_json::Value = serde_json::from_slice(&body).unwrap();
+    assert_eq!(json["version"], 2);
+    assert_eq!(json["content"], "Be very careful.");
+
+    let response = app
+        .clone()
+        .oneshot(get("/api/v1/prompts/policy/versions/1"))
+        .await
+        .unwrap();
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    let json: serde_json::Value = serde_json::from_slice(&body).unwrap();
+    assert_eq!(json["content"], "Be careful.");
+
+    let manifest = "apiVersion: duragent/v1alpha1\nkind: Agent\nmetadata:\n  name: helper\nspec:\n  model:\n    provider: mock\n    name: echo\n  prompts:\n    - policy@1\n";
+    let create = serde_json::json!({
+        "operations": [{"op": "create", "name": "helper", "manifest": manifest}]
+    });
+    let response = app
+        .clone()
+        .oneshot(
+            Request::post("/api/v1/agents/bulk")
+                .header("content-type", "application/json")
+                .body(Body::from(create.to_string()))
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
+
+    let response = app
+        .clone()
+        .oneshot(get("/api/v1/prompts/policy"))
+        .await
+        .unwrap();
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    let json: serde_json::Value = serde_json::from_slice(&body).unwrap();
+    assert_eq!(json["used_by"], serde_json::json!(["helper"]));
+
+    let response = app
+        .clone()
+        .oneshot(
+            Request::delete("/api/v1/prompts/policy")
+                .body(Body::empty())
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::CONFLICT);
+
+    let response = app
+        .clone()
+        .oneshot(get("/api/v1/prompts/policy/versions"))
+        .await
+        .unwrap();
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    let json: serde_json::Value = serde_json::from_slice(&body).unwrap();
+    assert_eq!(json["versions"].as_array().unwrap().len(), 2);
+
+    let response = app.oneshot(get("/api/v1/prompts/missing")).await.unwrap();
+    assert_eq!(response.status(), StatusCode::NOT_FOUND);
+}
+
+// ============================================================================
+// Models
+// ============================================================================
+
+#[tokio::test]
+async fn test_model_registry_overrides() {
+    use axum::extract::connect_info::MockConnectInfo;
+    use duragent::server;
+
+    let state = common::test_app_state().await;
+    let loopback: std::net::SocketAddr = ([127, 0, 0, 1], 0).into();
+    let app = server::build_app(state, 300).layer(MockConnectInfo(loopback));
+
+    let get = |uri: &str| Request::get(uri).body(Body::empty()).unwrap();
+    let get_json = |app: axum::Router, uri: String| async move {
+        let response = app
+            .oneshot(Request::get(&uri).body(Body::empty()).unwrap())
+            .await
+            .unwrap();
+        assert_eq!(response.status(), StatusCode::OK);
+        let body = response.into_body().collect().await.unwrap().to_bytes();
+        serde_json::from_slice::<serde_json::Value>(&body).unwrap()
+    };
+
+    let json = get_json(
+        app.clone(),
+        "/api/v1/models/anthropic/claude-sonnet-4".to_string(),
+    )
+    .await;
+    assert_eq!(json["context_window"], 200_000);
+    assert_eq!(json["tool_calling"], true);
+    assert_eq!(json["matched"][0], "claude-sonnet-4");
+
+    let response = app
+        .clone()
+        .oneshot(
+            Request::put("/api/v1/models/my-llama")
+                .header("content-type", "application/json")
+                .body(Body::from(
+                    serde_json::json!({"context_window": 8192, "tool_calling": false}).to_string(),
+                ))
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::CREATED);
+
+    let json = get_json(app.clone(), "/api/v1/models/acme/my-llama-3b".to_string()).await;
+    assert_eq!(json["context_window"], 8192);
+    assert_eq!(json["tool_calling"], false);
+
+    let json = get_json(app.clone(), "/api/v1/models".to_string()).await;
+    let models = json["models"].as_array().unwrap();
+    assert!(
+        models
+            .iter()
+            .any(|m| m["name"] == "my-llama" && m["source"] == "override")
+    );
+
+    let response = app
+        .clone()
+        .oneshot(
+            Request::delete("/api/v1/models/my-llama")
+                .body(Body::empty())
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::NO_CONTENT);
+    let response = app
+        .clone()
+        .oneshot(
+            Request::delete("/api/v1/models/claude")
+                .body(Body::empty())
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::NOT_FOUND);
+    let response = app.oneshot(get("/api/v1/models/my-llama")).await.unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
+}
+
+#[tokio::test]
+async fn test_list_providers() {
+    use axum::extract::connect_info::MockConnectInfo;
+    use duragent::server;
+
+    let state = common::test_app_state().await;
+    let loopback: std::net::SocketAddr = ([127, 0, 0, 1], 0).into();
+    let app = server::build_app(state, 300).layer(MockConnectInfo(loopback));
+
+    let response = app
+        .oneshot(
+            Request::get("/api/v1/providers")
+                .body(Body::empty())
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    let json: serde_json::Value = serde_json::from_slice(&body).unwrap();
+
+    let mock = json["providers"]
+        .as_array()
+        .unwrap()
+        .iter()
+        .find(|p| p["name"] == "mock")
+        .expect("mock provider listed");
+    assert_eq!(mock["health"], "unknown");
+    assert_eq!(mock["latency"]["requests"], 0);
+    assert!(mock.get("rate_limit").is_none());
+    assert_eq!(mock["models"], serde_json::json!([]));
+}
+
+#[tokio::test]
+async fn test_list_provider_models_unsupported() {
+    use axum::extract::connect_info::MockConnectInfo;
+    use duragent::server;
+
+    let state = common::test_app_state().await;
+    let loopback: std::net::SocketAddr = ([127, 0, 0, 1], 0).into();
+    let app = server::build_app(state, 300).layer(MockConnectInfo(loopback));
+
+    // The mock provider has no model list to pass through
+    let response = app
+        .clone()
+        .oneshot(
+            Request::get("/api/v1/providers/mock/models")
+                .body(Body::empty())
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::NOT_FOUND);
+
+    let response = app
+        .oneshot(
+            Request::get("/api/v1/providers/nope/models")
+                .body(Body::empty())
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::INTERNAL_SERVER_ERROR);
+}
+
+// ============================================================================
+// User Memory
+// ============================================================================
+
+#[tokio::test]
+async fn test_user_facts_crud_and_forget() {
+    use axum::extract::connect_info::MockConnectInfo;
+    use duragent::server;
+
+    let state = common::test_app_state().await;
+    let loopback: std::net::SocketAddr = ([127, 0, 0, 1], 0).into();
+    let app = server::build_app(state, 300).layer(MockConnectInfo(loopback));
+
+    let json_request = |method: &str, uri: &str, body: serde_json::Value| {
+        Request::builder()
+            .method(method)
+            .uri(uri)
+            .header("content-type", "application/json")
+            .body(Body::from(body.to_string()))
+            .unwrap()
+    };
+    let request = |method: &str, uri: &str| {
+        Request::builder()
+            .method(method)
+            .uri(uri)
+            .body(Body::empty())
+            .unwrap()
+    };
+
+    let response = app
+        .clone()
+        .oneshot(json_request(
+            "POST",
+            "/api/v1/users/customer-42/facts",
+            serde_json::json!({"content": "Is vegetarian."}),
+        ))
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::CREATED);
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    let json: serde_json::Value = serde_json::from_slice(&body).unwrap();
+    let id = json["id"].as_str().unwrap().to_string();
+    assert!(json.get("agent").is_none());
+
+    let response = app
+        .clone()
+        .oneshot(json_request(
+            "PUT",
+            &format!("/api/v1/users/customer-42/facts/{id}"),
+            serde_json::json!({"content": "Is vegan."}),
+        ))
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::OK);
+
+    let response = app
+        .clone()
+        .oneshot(request("GET", "/api/v1/users/customer-42/facts"))
+        .await
+        .unwrap();
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    let json: serde_json::Value = serde_json::from_slice(&body).unwrap();
+    assert_eq!(json["facts"].as_array().unwrap().len(), 1);
+    assert_eq!(json["facts"][0]["content"], "Is vegan.");
+
+    let response = app
+        .clone()
+        .oneshot(request("DELETE", "/api/v1/users/customer-42/facts"))
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::NO_CONTENT);
+
+    let response = app
+        .clone()
+        .oneshot(request(
+            "GET",
+            &format!("/api/v1/users/customer-42/facts/{id}"),
+        ))
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::NOT_FOUND);
+
+    let response = app
+        .oneshot(json_request(
+            "POST",
+            "/api/v1/sessions",
+            serde_json::json!({"agent": "test-agent", "user": "../escape"}),
+        ))
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::BAD_REQUEST);
 }
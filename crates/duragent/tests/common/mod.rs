@@ -9,13 +9,39 @@ use axum::extract::connect_info::MockConnectInfo;
 use tokio::sync::Mutex;
 
 use duragent::agent::AgentStore;
+use duragent::artifacts::Artifacts;
+use duragent::audit::AuditLog;
 use duragent::background::BackgroundTasks;
-use duragent::config::CompactionMode;
+use duragent::callbacks::Callbacks;
+use duragent::config::{CompactionMode, ScimConfig, StatusPageConfig};
+use duragent::examples::ExampleLibrary;
+use duragent::faults::FaultInjector;
+use duragent::features::FeatureFlags;
+use duragent::health::HealthHistory;
 use duragent::llm::ProviderRegistry;
+use duragent::metrics::HttpMetrics;
+use duragent::models::ModelRegistry;
+use duragent::policy::Policies;
+use duragent::prompts::PromptLibrary;
+use duragent::public_agents::PublicAgents;
+use duragent::runs::Runs;
 use duragent::sandbox::TrustSandbox;
 use duragent::server::{self, AppState, RuntimeServices};
-use duragent::session::{ChatSessionCache, SessionRegistry};
-use duragent::store::file::{FileAgentCatalog, FilePolicyStore, FileSessionStore};
+use duragent::service_accounts::ServiceAccounts;
+use duragent::session::{
+    ChatSessionCache, RunPool, SessionArchiver, SessionRegistry, StreamBuffers,
+};
+use duragent::shares::Shares;
+use duragent::slo::ProviderSlos;
+use duragent::store::file::{
+    FileAgentCatalog, FileArtifactStore, FileExampleStore, FileIdentityStore, FileModelStore,
+    FilePolicyStore, FilePromptStore, FileServiceAccountStore, FileSessionArchive,
+    FileSessionStore, FileShareStore, FileUsageStore, FileUserFactStore,
+};
+use duragent::tools::ToolMocks;
+use duragent::upgrade::UpgradeTrigger;
+use duragent::usage::UsageRollups;
+use duragent::user_memory::UserMemory;
 
 /// Create a test `AppState` with sensible defaults.
 pub async fn test_app_state() -> AppState {
@@ -30,15 +56,21 @@ pub async fn test_app_state() -> AppState {
     let sessions_path = tmp.path().join("sessions");
 
     let session_store = Arc::new(FileSessionStore::new(&sessions_path));
+    let session_archive = SessionArchiver::new(
+        session_store.clone(),
+        Arc::new(FileSessionArchive::new(tmp.path().join("archive/sessions"))),
+    );
     let agents_dir = tmp.path().join("agents");
     let policy_store: Arc<dyn duragent::store::PolicyStore> =
         Arc::new(FilePolicyStore::new(&agents_dir, None));
+    let usage = UsageRollups::new(Arc::new(FileUsageStore::new(tmp.path().join("usage"))));
     let (shutdown_tx, _shutdown_rx) = server::shutdown_channel();
     AppState {
         services: RuntimeServices {
             agents: empty_agent_store().await,
             providers: ProviderRegistry::new(),
-            session_registry: SessionRegistry::new(session_store, CompactionMode::Disabled),
+            session_registry: SessionRegistry::new(session_store, CompactionMode::Disabled)
+                .with_usage(usage.clone()),
             sandbox: Arc::new(TrustSandbox::new()),
             policy_store,
             world_memory_path: tmp.path().join("memory/world"),
@@ -46,6 +78,24 @@ pub async fn test_app_state() -> AppState {
             workspace_tools_path: tmp.path().join("tools"),
             agentic_loop_locks: duragent::sync::KeyedLocks::new(),
             steering_channels: Arc::new(dashmap::DashMap::new()),
+            run_pool: RunPool::new(0),
+            examples: ExampleLibrary::new(Arc::new(FileExampleStore::new(
+                tmp.path().join("examples"),
+            ))),
+            prompts: PromptLibrary::load(Arc::new(FilePromptStore::new(
+                tmp.path().join("prompts"),
+            )))
+            .await
+            .unwrap(),
+            user_memory: UserMemory::new(Arc::new(FileUserFactStore::new(
+                tmp.path().join("user-memory"),
+            ))),
+            models: ModelRegistry::load(Arc::new(FileModelStore::new(
+                tmp.path().join("models.yaml"),
+            )))
+            .await
+            .unwrap(),
+            tool_mocks: ToolMocks::default(),
         },
         scheduler: None,
         process_registry: None,
@@ -55,12 +105,50 @@ pub async fn test_app_state() -> AppState {
         idle_timeout_seconds: 60,
         keep_alive_interval_seconds: 15,
         max_connections: 1024,
+        request_validation: true,
+        read_only: false,
+        base_path: String::new(),
+        external_url: server::ExternalUrl::default(),
+        access_log: server::AccessLog::default(),
+        route_timeouts: server::RouteTimeouts::default(),
+        debug_capture: server::DebugCapture::default(),
         background_tasks: BackgroundTasks::new(),
         shutdown_tx: Arc::new(Mutex::new(Some(shutdown_tx))),
         workspace_hash: "test".to_string(),
         chat_session_cache: ChatSessionCache::new(),
+        stream_buffers: StreamBuffers::new(),
         agents_dir,
         workspace_dir: None,
+        agent_trash_retention_hours: 168,
+        expose_reasoning: false,
+        features: FeatureFlags::default(),
+        upgrade: UpgradeTrigger::default(),
+        usage,
+        session_archive,
+        artifacts: Artifacts::new(Arc::new(FileArtifactStore::new(
+            tmp.path().join("artifacts"),
+        ))),
+        identity: Arc::new(FileIdentityStore::new(tmp.path().join("identity"))),
+        scim: ScimConfig::default(),
+        scim_writes: Arc::new(Mutex::new(())),
+        service_accounts: ServiceAccounts::load(Arc::new(FileServiceAccountStore::new(
+            tmp.path().join("service-accounts"),
+        )))
+        .await
+        .unwrap(),
+        shares: Shares::load(Arc::new(FileShareStore::new(tmp.path().join("shares"))))
+            .await
+            .unwrap(),
+        policies: Policies::default(),
+        audit: AuditLog::default(),
+        callbacks: Callbacks::default(),
+        runs: Runs::new(),
+        health: HealthHistory::default(),
+        slos: ProviderSlos::default(),
+        http_metrics: HttpMetrics::new(),
+        status_page: StatusPageConfig::default(),
+        faults: FaultInjector::default(),
+        public_agents: PublicAgents::default(),
     }
 }
 
@@ -0,0 +1,143 @@
+#![cfg(feature = "server")]
+//! Allocation benchmarks for response encoding.
+//!
+//! Compare allocator calls of the pooled encoders in `handlers::format`
+//! against encoding each body (or NDJSON line) into a fresh buffer. The
+//! counting allocator lives in this test binary so it doesn't replace the
+//! allocator of the crate's unit tests; counts are per thread.
+
+use std::alloc::{GlobalAlloc, Layout, System};
+use std::cell::Cell;
+
+use axum::Json;
+use axum::http::StatusCode;
+use axum::response::IntoResponse;
+use bytes::Bytes;
+use http_body_util::BodyExt;
+use serde::Serialize;
+
+use duragent::handlers::format::ResponseFormat;
+
+// ============================================================================
+// Counting allocator
+// ============================================================================
+
+struct CountingAlloc;
+
+thread_local! {
+    static ALLOCATIONS: Cell<usize> = const { Cell::new(0) };
+}
+
+fn count_allocation() {
+    let _ = ALLOCATIONS.try_with(|n| n.set(n.get() + 1));
+}
+
+unsafe impl GlobalAlloc for CountingAlloc {
+    unsafe fn alloc(&self, layout: Layout) -> *mut u8 {
+        count_allocation();
+        unsafe { System.alloc(layout) }
+    }
+
+    unsafe fn dealloc(&self, ptr: *mut u8, layout: Layout) {
+        unsafe { System.dealloc(ptr, layout) }
+    }
+
+    unsafe fn realloc(&self, ptr: *mut u8, layout: Layout, new_size: usize) -> *mut u8 {
+        count_allocation();
+        unsafe { System.realloc(ptr, layout, new_size) }
+    }
+}
+
+#[global_allocator]
+static GLOBAL: CountingAlloc = CountingAlloc;
+
+fn allocations(f: impl FnOnce()) -> usize {
+    let before = ALLOCATIONS.with(Cell::get);
+    f();
+    ALLOCATIONS.with(Cell::get) - before
+}
+
+// ============================================================================
+// Fixtures
+// ============================================================================
+
+#[derive(Serialize)]
+struct Envelope {
+    items: Vec<u32>,
+}
+
+#[derive(Clone, Serialize)]
+struct Summary {
+    session_id: &'static str,
+    agent: &'static str,
+    status: &'static str,
+    created_at: &'static str,
+}
+
+fn summaries(n: usize) -> Vec<Summary> {
+    vec![
+        Summary {
+            session_id: "session_01JMABCD1234567890ABCDEFGH",
+            agent: "my-assistant",
+            status: "active",
+            created_at: "2026-01-01T00:00:00+00:00",
+        };
+        n
+    ]
+}
+
+// ============================================================================
+// Benchmarks
+// ============================================================================
+
+#[test]
+fn bench_json_list_allocations() {
+    const ROUNDS: usize = 100;
+    let envelope = Envelope {
+        items: (0..20_000).collect(),
+    };
+    // Warm up this thread's pool
+    drop(ResponseFormat::Json.respond(StatusCode::OK, &envelope));
+
+    let fresh = allocations(|| {
+        for _ in 0..ROUNDS {
+            drop((StatusCode::OK, Json(&envelope)).into_response());
+        }
+    });
+    let pooled = allocations(|| {
+        for _ in 0..ROUNDS {
+            drop(ResponseFormat::Json.respond(StatusCode::OK, &envelope));
+        }
+    });
+
+    println!("json list x{ROUNDS}: fresh={fresh} pooled={pooled} allocations");
+    assert!(pooled * 2 < fresh, "fresh={fresh} pooled={pooled}");
+}
+
+#[test]
+fn bench_ndjson_list_allocations() {
+    const ITEMS: usize = 5_000;
+
+    let per_line = allocations(|| {
+        for item in summaries(ITEMS) {
+            let mut line = serde_json::to_vec(&item).unwrap();
+            line.push(b'\n');
+            drop(Bytes::from(line));
+        }
+    });
+
+    // The body is encoded as it's polled, so collect it within the count
+    let mut body = None;
+    let chunked = allocations(|| {
+        let response = ResponseFormat::NdJson.respond_list(summaries(ITEMS), |items| items.len());
+        body = Some(futures::executor::block_on(response.into_body().collect()));
+    });
+    let body = body.unwrap().unwrap().to_bytes();
+    assert_eq!(body.iter().filter(|&&b| b == b'\n').count(), ITEMS);
+
+    println!("ndjson x{ITEMS}: per_line={per_line} chunked={chunked} allocations");
+    assert!(
+        chunked * 10 < per_line,
+        "per_line={per_line} chunked={chunked}"
+    );
+}
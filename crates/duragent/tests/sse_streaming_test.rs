@@ -9,7 +9,7 @@
 //! can be verified with the existing test infrastructure.
 
 use axum::body::Body;
-use axum::http::Request;
+use axum::http::{Request, StatusCode};
 use http_body_util::BodyExt;
 use tower::ServiceExt;
 
@@ -112,8 +112,11 @@ async fn stream_session_invalid_json() {
         .await
         .unwrap();
 
-    // axum returns 400 for JSON parse errors
-    assert!(response.status().is_client_error());
+    assert_eq!(response.status(), StatusCode::BAD_REQUEST);
+    assert_eq!(
+        response.headers().get("content-type").unwrap(),
+        "application/problem+json"
+    );
 }
 
 /// Test that SSE endpoint returns 400 for missing content field.
@@ -131,8 +134,148 @@ async fn stream_session_missing_content() {
         .await
         .unwrap();
 
-    // axum returns 422 for missing required fields
-    assert!(response.status().is_client_error());
+    assert_eq!(response.status(), StatusCode::BAD_REQUEST);
+
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    let json: serde_json::Value = serde_json::from_slice(&body).unwrap();
+    assert_eq!(json["code"], "validation-failed");
+    assert_eq!(json["errors"][0]["pointer"], "/content");
+}
+
+/// Test that SSE endpoint rejects blank content before touching the session.
+#[tokio::test]
+async fn stream_session_blank_content() {
+    let app = test_app().await;
+
+    let response = app
+        .oneshot(
+            Request::post("/api/v1/sessions/some-session/stream")
+                .header("content-type", "application/json")
+                .body(Body::from(r#"{"content": "   "}"#))
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+
+    assert_eq!(response.status(), StatusCode::BAD_REQUEST);
+
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    let json: serde_json::Value = serde_json::from_slice(&body).unwrap();
+    assert_eq!(
+        json["errors"],
+        serde_json::json!([{"pointer": "/content", "message": "must not be empty"}])
+    );
+}
+
+/// Test that the handler's own checks still run when request validation is disabled.
+#[tokio::test]
+async fn stream_session_blank_content_with_validation_disabled() {
+    use axum::extract::connect_info::MockConnectInfo;
+
+    let mut state = common::test_app_state().await;
+    state.request_validation = false;
+    let loopback: std::net::SocketAddr = ([127, 0, 0, 1], 0).into();
+    let app = server::build_app(state, 300).layer(MockConnectInfo(loopback));
+
+    let response = app
+        .oneshot(
+            Request::post("/api/v1/sessions/some-session/stream")
+                .header("content-type", "application/json")
+                .body(Body::from(r#"{"content": ""}"#))
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+
+    assert_eq!(response.status(), StatusCode::BAD_REQUEST);
+
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    let json: serde_json::Value = serde_json::from_slice(&body).unwrap();
+    assert_eq!(json["errors"][0]["pointer"], "/content");
+}
+
+/// Test that resuming without Last-Event-ID is rejected.
+#[tokio::test]
+async fn resume_stream_missing_last_event_id() {
+    let app = test_app().await;
+
+    let response = app
+        .oneshot(
+            Request::get("/api/v1/sessions/some-session/stream")
+                .body(Body::empty())
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+
+    assert_eq!(response.status(), StatusCode::BAD_REQUEST);
+}
+
+/// Test that resuming an unknown stream returns 404.
+#[tokio::test]
+async fn resume_stream_unknown_event_id() {
+    let app = test_app().await;
+
+    let response = app
+        .oneshot(
+            Request::get("/api/v1/sessions/some-session/stream")
+                .header("last-event-id", "msg_unknown:3")
+                .body(Body::empty())
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+
+    assert_eq!(response.status(), StatusCode::NOT_FOUND);
+}
+
+/// Test that resuming replays only the events after Last-Event-ID.
+#[tokio::test]
+async fn resume_stream_replays_missed_events() {
+    use axum::extract::connect_info::MockConnectInfo;
+
+    let state = common::test_app_state().await;
+    let buffer = state.stream_buffers.create("session_1", "msg_1");
+    buffer.push("start", "{}".to_string());
+    buffer.push("token", r#"{"content":"Hello"}"#.to_string());
+    buffer.push("done", r#"{"message_id":"msg_1"}"#.to_string());
+    buffer.finish();
+
+    let loopback: std::net::SocketAddr = ([127, 0, 0, 1], 0).into();
+    let app = server::build_app(state.clone(), 300).layer(MockConnectInfo(loopback));
+
+    let response = app
+        .oneshot(
+            Request::get("/api/v1/sessions/session_1/stream")
+                .header("last-event-id", "msg_1:1")
+                .body(Body::empty())
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+
+    assert_eq!(response.status(), StatusCode::OK);
+
+    let body = response.into_body().collect().await.unwrap().to_bytes();
+    let body = String::from_utf8(body.to_vec()).unwrap();
+    assert!(!body.contains("id: msg_1:1\n"));
+    assert!(body.contains("id: msg_1:2"));
+    assert!(body.contains("event: token"));
+    assert!(body.contains("id: msg_1:3"));
+    assert!(body.contains("event: done"));
+
+    // The stream belongs to a different session
+    let app = server::build_app(state, 300).layer(MockConnectInfo(loopback));
+    let response = app
+        .oneshot(
+            Request::get("/api/v1/sessions/other-session/stream")
+                .header("last-event-id", "msg_1:1")
+                .body(Body::empty())
+                .unwrap(),
+        )
+        .await
+        .unwrap();
+    assert_eq!(response.status(), StatusCode::NOT_FOUND);
 }
 
 // ============================================================================
@@ -0,0 +1,453 @@
+#!/usr/bin/env python3
+"""Generate the Go and TypeScript clients from the OpenAPI document.
+
+Reads crates/duragent/schemas/openapi.json and writes the generated halves
+of both SDKs: models and one method per operation. The HTTP plumbing and
+the SSE parsers are hand-written next to them.
+
+Usage:
+    python3 sdk/generate.py           # regenerate
+    python3 sdk/generate.py --check   # fail if the checked-in files are stale
+"""
+
+import json
+import re
+import subprocess
+import sys
+from pathlib import Path
+
+ROOT = Path(__file__).resolve().parent.parent
+SPEC = ROOT / "crates" / "duragent" / "schemas" / "openapi.json"
+GO_DIR = ROOT / "sdk" / "go"
+TS_DIR = ROOT / "sdk" / "typescript" / "src"
+
+HEADER = "Code generated by sdk/generate.py from crates/duragent/schemas/openapi.json. DO NOT EDIT."
+
+# Words Go spells in capitals.
+GO_INITIALISMS = {"id", "url", "api", "ms", "http", "json"}
+
+METHODS = ["get", "post", "put", "patch", "delete"]
+
+
+# ============================================================================
+# Spec helpers
+# ============================================================================
+
+
+def ref_name(ref):
+    return ref.rsplit("/", 1)[-1]
+
+
+def deref(spec, node):
+    """Follow `$ref`s to what they point at."""
+    while isinstance(node, dict) and "$ref" in node:
+        target = spec
+        for part in node["$ref"].lstrip("#/").split("/"):
+            target = target[part]
+        node = target
+    return node
+
+
+def operations(spec):
+    """Operations in document order, with parameters resolved."""
+    ops = []
+    for path, item in spec["paths"].items():
+        for method in METHODS:
+            op = item.get(method)
+            if op is None:
+                continue
+            params = [deref(spec, p) for p in op.get("parameters", [])]
+            body = None
+            if "requestBody" in op:
+                schema = op["requestBody"]["content"]["application/json"]["schema"]
+                body = ref_name(schema["$ref"])
+            ops.append(
+                {
+                    "id": op["operationId"],
+                    "summary": op.get("summary", ""),
+                    "method": method.upper(),
+                    "path": path,
+                    "path_params": [p for p in params if p["in"] == "path"],
+                    "header_params": [p for p in params if p["in"] == "header"],
+                    "query_params": [p for p in params if p["in"] == "query"],
+                    "body": body,
+                    "result": result_of(spec, op),
+                }
+            )
+    return ops
+
+
+def result_of(spec, op):
+    """`("json", Model)`, `("stream", None)`, or `("empty", None)`."""
+    for status, response in op["responses"].items():
+        if not status.startswith("2"):
+            continue
+        if response.get("$ref", "").endswith("/EventStream"):
+            return ("stream", None)
+        content = deref(spec, response).get("content", {})
+        if "application/json" in content:
+            return ("json", ref_name(content["application/json"]["schema"]["$ref"]))
+        return ("empty", None)
+    raise SystemExit(f"operation {op['operationId']} has no success response")
+
+
+def stream_events(spec):
+    response = spec["components"]["responses"]["EventStream"]
+    events = response["content"]["text/event-stream"]["x-events"]
+    return [(name, ref_name(schema["$ref"])) for name, schema in events.items()]
+
+
+def words(name):
+    """Split snake_case, kebab-case, and camelCase names into lowercase words."""
+    name = re.sub(r"([a-z0-9])([A-Z])", r"\1_\2", name)
+    return [w.lower() for w in re.split(r"[_\-\s]+", name) if w]
+
+
+def camel(name):
+    parts = words(name)
+    return parts[0] + "".join(w.capitalize() for w in parts[1:])
+
+
+# ============================================================================
+# Go
+# ============================================================================
+
+
+def gofmt(source):
+    """Format Go source the way `gofmt` would, so output is stable."""
+    result = subprocess.run(["gofmt"], input=source, capture_output=True, text=True)
+    if result.returncode != 0:
+        raise SystemExit(f"gofmt failed:\n{result.stderr}")
+    return result.stdout
+
+
+def go_name(name):
+    return "".join(w.upper() if w in GO_INITIALISMS else w.capitalize() for w in words(name))
+
+
+def go_arg(name):
+    parts = words(name)
+    return parts[0] + "".join(
+        w.upper() if w in GO_INITIALISMS else w.capitalize() for w in parts[1:]
+    )
+
+
+def go_type(schema):
+    if "$ref" in schema:
+        return ref_name(schema["$ref"])
+    kind = schema.get("type")
+    if kind is None:
+        return "any"
+    if kind == "string":
+        return "time.Time" if schema.get("format") == "date-time" else "string"
+    if kind == "integer":
+        return "int64"
+    if kind == "number":
+        return "float64"
+    if kind == "boolean":
+        return "bool"
+    if kind == "array":
+        return "[]" + go_type(schema["items"])
+    if kind == "object" and "additionalProperties" in schema:
+        return "map[string]" + go_type(schema["additionalProperties"])
+    raise SystemExit(f"unsupported schema for Go: {schema}")
+
+
+def go_comment(text, indent=""):
+    text = text.replace("`", "")
+    return [f"{indent}// {line}" for line in text.splitlines()]
+
+
+def go_models(spec):
+    out = [f"// {HEADER}", "", "package duragent", "", 'import "time"', ""]
+    schemas = spec["components"]["schemas"]
+    for name, schema in schemas.items():
+        if "description" in schema:
+            out += go_comment(f"{name}: {schema['description']}")
+        if "enum" in schema:
+            out.append(f"type {name} string")
+            out.append("")
+            out.append("const (")
+            for value in schema["enum"]:
+                out.append(f'\t{name}{go_name(value)} {name} = "{value}"')
+            out.append(")")
+            out.append("")
+            continue
+
+        required = set(schema.get("required", []))
+        out.append(f"type {name} struct {{")
+        for field, prop in schema.get("properties", {}).items():
+            typ = go_type(prop)
+            optional = field not in required
+            tag = field + (",omitempty" if optional else "")
+            # Optional scalars and structs are pointers so zero values stay
+            # distinguishable from absent ones; untyped values are already
+            # nil when absent.
+            if optional and typ != "any" and not typ.startswith(("[]", "map[")):
+                typ = "*" + typ
+            if "description" in prop:
+                out += go_comment(prop["description"], "\t")
+            out.append(f'\t{go_name(field)} {typ} `json:"{tag}"`')
+        out.append("}")
+        out.append("")
+
+    out.append("// Stream event names.")
+    out.append("const (")
+    for event, _ in stream_events(spec):
+        out.append(f'\tEvent{go_name(event)} = "{event}"')
+    out.append(")")
+    out.append("")
+    out.append("// newEventData returns a pointer to the model for an event's data.")
+    out.append("func newEventData(event string) any {")
+    out.append("\tswitch event {")
+    for event, model in stream_events(spec):
+        out.append(f"\tcase Event{go_name(event)}:")
+        out.append(f"\t\treturn new({model})")
+    out.append("\tdefault:")
+    out.append("\t\treturn nil")
+    out.append("\t}")
+    out.append("}")
+    return "\n".join(out) + "\n"
+
+
+def go_client(spec):
+    ops = operations(spec)
+    imports = {"context", "net/http"}
+    out = []
+    for op in ops:
+        params_type = None
+        if op["query_params"]:
+            imports.add("net/url")
+            params_type = go_name(op["id"]) + "Params"
+            out.append(f"// {params_type} holds the optional query parameters of {go_name(op['id'])}.")
+            out.append(f"type {params_type} struct {{")
+            for p in op["query_params"]:
+                if "description" in p:
+                    out += go_comment(p["description"], "\t")
+                out.append(f"\t{go_name(p['name'])} *{go_type(p['schema'])}")
+            out.append("}")
+            out.append("")
+
+        args = ["ctx context.Context"]
+        args += [f"{go_arg(p['name'])} string" for p in op["path_params"]]
+        args += [f"{go_arg(p['name'])} string" for p in op["header_params"]]
+        if op["body"]:
+            args.append(f"body *{op['body']}")
+        if params_type:
+            args.append(f"params *{params_type}")
+
+        kind, model = op["result"]
+        returns = {"json": f"(*{model}, error)", "stream": "(*Stream, error)", "empty": "error"}[
+            kind
+        ]
+
+        path = op["path"]
+        if op["path_params"]:
+            path_expr = '"' + re.sub(r"\{(\w+)\}", r'" + url.PathEscape(\1) + "', path) + '"'
+            for p in op["path_params"]:
+                path_expr = path_expr.replace(f"({p['name']})", f"({go_arg(p['name'])})")
+            path_expr = path_expr.replace(' + ""', "")
+            imports.add("net/url")
+        else:
+            path_expr = f'"{path}"'
+
+        out.append(f"// {go_name(op['id'])} sends {op['method']} {op['path']}.")
+        out.append("//")
+        out.append(f"// {op['summary']}.")
+        out.append(f"func (c *Client) {go_name(op['id'])}({', '.join(args)}) {returns} {{")
+        out.append("\treq := request{")
+        out.append(f"\t\tmethod: http.Method{op['method'].capitalize()},")
+        out.append(f"\t\tpath:   {path_expr},")
+        if op["body"]:
+            out.append("\t\tbody:   body,")
+        out.append("\t}")
+        if op["header_params"]:
+            out.append("\treq.header = http.Header{}")
+            for p in op["header_params"]:
+                out.append(f'\treq.header.Set("{p["name"]}", {go_arg(p["name"])})')
+        if params_type:
+            imports.add("fmt")
+            out.append("\tif params != nil {")
+            out.append("\t\treq.query = url.Values{}")
+            for p in op["query_params"]:
+                field = go_name(p["name"])
+                out.append(f"\t\tif params.{field} != nil {{")
+                out.append(f'\t\t\treq.query.Set("{p["name"]}", fmt.Sprint(*params.{field}))')
+                out.append("\t\t}")
+            out.append("\t}")
+        if kind == "json":
+            out.append(f"\tvar out {model}")
+            out.append("\tif err := c.do(ctx, req, &out); err != nil {")
+            out.append("\t\treturn nil, err")
+            out.append("\t}")
+            out.append("\treturn &out, nil")
+        elif kind == "stream":
+            out.append("\treturn c.stream(ctx, req)")
+        else:
+            out.append("\treturn c.do(ctx, req, nil)")
+        out.append("}")
+        out.append("")
+
+    head = [f"// {HEADER}", "", "package duragent", "", "import ("]
+    head += [f'\t"{i}"' for i in sorted(imports)]
+    head += [")", ""]
+    return "\n".join(head + out).rstrip("\n") + "\n"
+
+
+# ============================================================================
+# TypeScript
+# ============================================================================
+
+
+def ts_type(schema):
+    if "$ref" in schema:
+        return ref_name(schema["$ref"])
+    kind = schema.get("type")
+    if kind is None:
+        return "unknown"
+    if kind == "string":
+        return "string"
+    if kind in ("integer", "number"):
+        return "number"
+    if kind == "boolean":
+        return "boolean"
+    if kind == "array":
+        return ts_type(schema["items"]) + "[]"
+    if kind == "object" and "additionalProperties" in schema:
+        return f"Record<string, {ts_type(schema['additionalProperties'])}>"
+    raise SystemExit(f"unsupported schema for TypeScript: {schema}")
+
+
+def ts_doc(text, indent=""):
+    return [f"{indent}/** {text} */"]
+
+
+def ts_models(spec):
+    out = [f"// {HEADER}", ""]
+    for name, schema in spec["components"]["schemas"].items():
+        if "description" in schema:
+            out += ts_doc(schema["description"])
+        if "enum" in schema:
+            values = " | ".join(json.dumps(v) for v in schema["enum"])
+            out.append(f"export type {name} = {values};")
+            out.append("")
+            continue
+        props = schema.get("properties", {})
+        if not props:
+            out.append(f"export type {name} = Record<string, never>;")
+            out.append("")
+            continue
+        required = set(schema.get("required", []))
+        out.append(f"export interface {name} {{")
+        for field, prop in props.items():
+            if "description" in prop:
+                out += ts_doc(prop["description"], "  ")
+            optional = "" if field in required else "?"
+            out.append(f"  {field}{optional}: {ts_type(prop)};")
+        out.append("}")
+        out.append("")
+
+    out.append("/** Data of each stream event, by event name. */")
+    out.append("export interface StreamEventMap {")
+    for event, model in stream_events(spec):
+        out.append(f"  {event}: {model};")
+    out.append("}")
+    return "\n".join(out) + "\n"
+
+
+def ts_client(spec):
+    ops = operations(spec)
+    models = set()
+    out = []
+    for op in ops:
+        args = [f"{camel(p['name'])}: string" for p in op["path_params"]]
+        args += [f"{camel(p['name'])}: string" for p in op["header_params"]]
+        if op["body"]:
+            models.add(op["body"])
+            args.append(f"body: {op['body']}")
+        if op["query_params"]:
+            fields = "; ".join(
+                f"{camel(p['name'])}?: {ts_type(p['schema'])}" for p in op["query_params"]
+            )
+            args.append(f"params: {{ {fields} }} = {{}}")
+        args.append("options: RequestOptions = {}")
+
+        kind, model = op["result"]
+        if model:
+            models.add(model)
+        returns = {
+            "json": f"Promise<{model}>",
+            "stream": "Promise<AsyncGenerator<StreamEvent>>",
+            "empty": "Promise<void>",
+        }[kind]
+
+        path = re.sub(
+            r"\{(\w+)\}", lambda m: "${encodeURIComponent(" + camel(m.group(1)) + ")}", op["path"]
+        )
+        fields = [f"method: {json.dumps(op['method'])}", f"path: `{path}`"]
+        if op["body"]:
+            fields.append("body")
+        if op["query_params"]:
+            query = ", ".join(f"{p['name']}: params.{camel(p['name'])}" for p in op["query_params"])
+            fields.append(f"query: {{ {query} }}")
+        if op["header_params"]:
+            headers = ", ".join(
+                f"{json.dumps(p['name'])}: {camel(p['name'])}" for p in op["header_params"]
+            )
+            fields.append(f"headers: {{ {headers} }}")
+        fields.append("...options")
+
+        call = {"json": f"this.request<{model}>", "stream": "this.stream", "empty": "this.send"}[
+            kind
+        ]
+        out += ts_doc(f"{op['summary']}.", "  ")
+        out.append(f"  async {camel(op['id'])}({', '.join(args)}): {returns} {{")
+        out.append(f"    return {call}({{ {', '.join(fields)} }});")
+        out.append("  }")
+        out.append("")
+
+    head = [
+        f"// {HEADER}",
+        "",
+        f"import type {{ {', '.join(sorted(models))} }} from \"./models.gen.js\";",
+        'import { BaseClient, type RequestOptions } from "./runtime.js";',
+        'import type { StreamEvent } from "./stream.js";',
+        "",
+        "/** Client for the Duragent HTTP API. */",
+        "export class DuragentClient extends BaseClient {",
+    ]
+    return "\n".join(head + out).rstrip("\n") + "\n}\n"
+
+
+# ============================================================================
+# Main
+# ============================================================================
+
+
+def main():
+    check = "--check" in sys.argv[1:]
+    spec = json.loads(SPEC.read_text())
+    outputs = {
+        GO_DIR / "models_gen.go": gofmt(go_models(spec)),
+        GO_DIR / "client_gen.go": gofmt(go_client(spec)),
+        TS_DIR / "models.gen.ts": ts_models(spec),
+        TS_DIR / "client.gen.ts": ts_client(spec),
+    }
+
+    stale = []
+    for path, content in outputs.items():
+        current = path.read_text() if path.exists() else None
+        if current == content:
+            continue
+        if check:
+            stale.append(path.relative_to(ROOT))
+        else:
+            path.write_text(content)
+            print(f"wrote {path.relative_to(ROOT)}")
+    if stale:
+        names = ", ".join(str(p) for p in stale)
+        raise SystemExit(f"stale generated files: {names}; run `make sdk`")
+
+
+if __name__ == "__main__":
+    main()
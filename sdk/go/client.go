@@ -0,0 +1,163 @@
+// Package duragent is a client for the Duragent HTTP API.
+//
+// Models and one method per operation are generated from the server's
+// OpenAPI document by sdk/generate.py; this file and stream.go hold the
+// hand-written transport.
+//
+//	client := duragent.NewClient("http://localhost:8080", duragent.WithToken(token))
+//	session, err := client.CreateSession(ctx, &duragent.CreateSessionRequest{Agent: "my-assistant"})
+package duragent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Client calls a Duragent server. It is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithToken sends token as a Bearer token on every request.
+func WithToken(token string) Option {
+	return func(c *Client) { c.token = token }
+}
+
+// WithHTTPClient uses httpClient instead of http.DefaultClient. Streams stay
+// open for a whole response, so avoid a client-wide Timeout; use contexts.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) { c.httpClient = httpClient }
+}
+
+// NewClient returns a client for the server at baseURL, e.g.
+// "http://localhost:8080".
+func NewClient(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// APIError is a non-2xx response, decoded from its RFC 7807 problem body
+// when there is one.
+type APIError struct {
+	StatusCode int
+	Problem    *Problem
+}
+
+func (e *APIError) Error() string {
+	if e.Problem == nil {
+		return fmt.Sprintf("duragent: HTTP %d", e.StatusCode)
+	}
+	if e.Problem.Detail != nil {
+		return fmt.Sprintf("duragent: HTTP %d: %s", e.StatusCode, *e.Problem.Detail)
+	}
+	return fmt.Sprintf("duragent: HTTP %d: %s", e.StatusCode, e.Problem.Title)
+}
+
+// Code is the problem's catalog code, e.g. "agent-not-found", if any.
+func (e *APIError) Code() string {
+	if e.Problem == nil || e.Problem.Code == nil {
+		return ""
+	}
+	return *e.Problem.Code
+}
+
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	header http.Header
+	body   any
+}
+
+func (c *Client) send(ctx context.Context, req request, accept string) (*http.Response, error) {
+	target := c.baseURL + req.path
+	if len(req.query) > 0 {
+		target += "?" + req.query.Encode()
+	}
+
+	var body io.Reader
+	if req.body != nil {
+		data, err := json.Marshal(req.body)
+		if err != nil {
+			return nil, fmt.Errorf("duragent: encode request: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range req.header {
+		httpReq.Header[name] = values
+	}
+	httpReq.Header.Set("Accept", accept)
+	if req.body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		return nil, decodeError(resp)
+	}
+	return resp, nil
+}
+
+// do sends req and decodes a JSON response into out, unless out is nil.
+func (c *Client) do(ctx context.Context, req request, out any) error {
+	resp, err := c.send(ctx, req, "application/json")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		_, err = io.Copy(io.Discard, resp.Body)
+		return err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("duragent: decode response: %w", err)
+	}
+	return nil
+}
+
+// stream sends req and returns its server-sent events.
+func (c *Client) stream(ctx context.Context, req request) (*Stream, error) {
+	resp, err := c.send(ctx, req, "text/event-stream")
+	if err != nil {
+		return nil, err
+	}
+	return newStream(resp.Body), nil
+}
+
+func decodeError(resp *http.Response) error {
+	apiErr := &APIError{StatusCode: resp.StatusCode}
+	var problem Problem
+	if err := json.NewDecoder(resp.Body).Decode(&problem); err == nil && problem.Title != "" {
+		apiErr.Problem = &problem
+	}
+	return apiErr
+}
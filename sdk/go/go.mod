@@ -0,0 +1,3 @@
+module github.com/giosakti/duragent/sdk/go
+
+go 1.22
@@ -0,0 +1,145 @@
+package duragent
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrStreamEnded is returned when a stream closes before a terminal event
+// (done, cancelled, or error).
+var ErrStreamEnded = errors.New("duragent: stream ended before a terminal event")
+
+// StreamEvent is one server-sent event.
+type StreamEvent struct {
+	// ID resumes the stream after this event; see ResumeStream.
+	ID string
+	// Name is one of the Event* constants.
+	Name string
+	// Data is the decoded payload: *TokenEvent for EventToken, *DoneEvent
+	// for EventDone, and so on. Nil for events this client doesn't know.
+	Data any
+	// Raw is the undecoded JSON payload.
+	Raw json.RawMessage
+}
+
+// Terminal reports whether the response ends with this event.
+func (e *StreamEvent) Terminal() bool {
+	switch e.Name {
+	case EventDone, EventCancelled, EventError:
+		return true
+	}
+	return false
+}
+
+// Stream reads server-sent events from a streaming response.
+//
+//	for stream.Next() {
+//		if token, ok := stream.Event().Data.(*duragent.TokenEvent); ok {
+//			fmt.Print(token.Content)
+//		}
+//	}
+//	if err := stream.Err(); err != nil { ... }
+type Stream struct {
+	body     io.ReadCloser
+	reader   *bufio.Reader
+	event    *StreamEvent
+	err      error
+	finished bool
+}
+
+func newStream(body io.ReadCloser) *Stream {
+	return &Stream{body: body, reader: bufio.NewReader(body)}
+}
+
+// Next advances to the next event. It returns false after a terminal event
+// or an error; check Err.
+func (s *Stream) Next() bool {
+	if s.finished || s.err != nil {
+		return false
+	}
+	event, err := s.read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			err = ErrStreamEnded
+		}
+		s.err = err
+		s.body.Close()
+		return false
+	}
+	s.event = event
+	if event.Terminal() {
+		s.finished = true
+		s.body.Close()
+	}
+	return true
+}
+
+// Event returns the event Next advanced to.
+func (s *Stream) Event() *StreamEvent {
+	return s.event
+}
+
+// Err returns the error that stopped the stream, if any.
+func (s *Stream) Err() error {
+	return s.err
+}
+
+// Close stops reading. With on_disconnect: pause the server cancels the
+// response.
+func (s *Stream) Close() error {
+	s.finished = true
+	return s.body.Close()
+}
+
+// read parses lines up to the next dispatched event, skipping comments
+// such as keep-alives.
+func (s *Stream) read() (*StreamEvent, error) {
+	var event StreamEvent
+	var data strings.Builder
+	hasData := false
+	for {
+		line, err := s.reader.ReadString('\n')
+		if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		if line == "" {
+			if !hasData {
+				continue
+			}
+			return s.decode(event, data.String())
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event.Name = value
+		case "id":
+			event.ID = value
+		case "data":
+			if hasData {
+				data.WriteByte('\n')
+			}
+			data.WriteString(value)
+			hasData = true
+		}
+	}
+}
+
+func (s *Stream) decode(event StreamEvent, data string) (*StreamEvent, error) {
+	event.Raw = json.RawMessage(data)
+	if event.Data = newEventData(event.Name); event.Data != nil {
+		if err := json.Unmarshal(event.Raw, event.Data); err != nil {
+			return nil, fmt.Errorf("duragent: decode %s event: %w", event.Name, err)
+		}
+	}
+	return &event, nil
+}
@@ -0,0 +1,80 @@
+package duragent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestStreamDecodesEvents(t *testing.T) {
+	body := ": keep-alive\n\n" +
+		"id: msg_1:0\nevent: start\ndata: {}\n\n" +
+		"id: msg_1:1\nevent: token\ndata: {\"content\":\"Hel\"}\n\n" +
+		"id: msg_1:2\nevent: token\ndata: {\"content\":\"lo\"}\n\n" +
+		"id: msg_1:3\nevent: done\ndata: {\"message_id\":\"msg_1\",\"usage\":{\"prompt_tokens\":1,\"completion_tokens\":2,\"total_tokens\":3}}\n\n"
+	stream := newStream(io.NopCloser(strings.NewReader(body)))
+
+	var text strings.Builder
+	var done *DoneEvent
+	var lastID string
+	for stream.Next() {
+		event := stream.Event()
+		lastID = event.ID
+		switch data := event.Data.(type) {
+		case *TokenEvent:
+			text.WriteString(data.Content)
+		case *DoneEvent:
+			done = data
+		}
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if text.String() != "Hello" {
+		t.Errorf("text = %q, want %q", text.String(), "Hello")
+	}
+	if done == nil || done.MessageID != "msg_1" || done.Usage.TotalTokens != 3 {
+		t.Errorf("done = %+v", done)
+	}
+	if lastID != "msg_1:3" {
+		t.Errorf("last id = %q", lastID)
+	}
+}
+
+func TestStreamWithoutTerminalEventFails(t *testing.T) {
+	body := "event: token\ndata: {\"content\":\"Hi\"}\n\n"
+	stream := newStream(io.NopCloser(strings.NewReader(body)))
+	for stream.Next() {
+	}
+	if !errors.Is(stream.Err(), ErrStreamEnded) {
+		t.Fatalf("err = %v, want ErrStreamEnded", stream.Err())
+	}
+}
+
+func TestProblemResponsesBecomeAPIErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("Authorization = %q", got)
+		}
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"type":"about:blank","title":"Not Found","status":404,"detail":"Agent not found: x","code":"agent-not-found"}`)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL+"/", WithToken("secret"))
+	_, err := client.GetAgent(context.Background(), "x")
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("err = %v, want *APIError", err)
+	}
+	if apiErr.StatusCode != http.StatusNotFound || apiErr.Code() != "agent-not-found" {
+		t.Errorf("apiErr = %+v", apiErr)
+	}
+}
@@ -0,0 +1,4 @@
+export { DuragentClient } from "./client.gen.js";
+export * from "./models.gen.js";
+export { DuragentError, type ClientOptions, type RequestOptions } from "./runtime.js";
+export { parseEventStream, StreamEndedError, type StreamEvent } from "./stream.js";
@@ -0,0 +1,109 @@
+// HTTP transport for the generated client.
+
+import type { Problem } from "./models.gen.js";
+import { parseEventStream, type StreamEvent } from "./stream.js";
+
+export interface ClientOptions {
+  /** Server URL, e.g. `http://localhost:8080`. */
+  baseUrl: string;
+  /** Sent as a Bearer token when set. */
+  token?: string;
+  /** `fetch` implementation; defaults to the global one. */
+  fetch?: typeof fetch;
+}
+
+/** Per-call options. */
+export interface RequestOptions {
+  /** Aborts the request, or an open stream. */
+  signal?: AbortSignal;
+}
+
+interface Request extends RequestOptions {
+  method: string;
+  path: string;
+  query?: Record<string, string | number | boolean | undefined>;
+  headers?: Record<string, string>;
+  body?: unknown;
+}
+
+/** A non-2xx response, with its RFC 7807 problem body when there is one. */
+export class DuragentError extends Error {
+  readonly status: number;
+  readonly problem?: Problem;
+
+  constructor(status: number, problem?: Problem) {
+    super(`duragent: HTTP ${status}${problem ? `: ${problem.detail ?? problem.title}` : ""}`);
+    this.name = "DuragentError";
+    this.status = status;
+    this.problem = problem;
+  }
+
+  /** The problem's catalog code, e.g. `agent-not-found`. */
+  get code(): string | undefined {
+    return this.problem?.code;
+  }
+}
+
+export class BaseClient {
+  private readonly baseUrl: string;
+  private readonly token?: string;
+  private readonly fetchImpl: typeof fetch;
+
+  constructor(options: ClientOptions) {
+    this.baseUrl = options.baseUrl.replace(/\/+$/, "");
+    this.token = options.token;
+    this.fetchImpl = options.fetch ?? globalThis.fetch.bind(globalThis);
+  }
+
+  protected async request<T>(request: Request): Promise<T> {
+    const response = await this.fetch(request, "application/json");
+    return (await response.json()) as T;
+  }
+
+  protected async send(request: Request): Promise<void> {
+    const response = await this.fetch(request, "application/json");
+    await response.body?.cancel();
+  }
+
+  protected async stream(request: Request): Promise<AsyncGenerator<StreamEvent>> {
+    const response = await this.fetch(request, "text/event-stream");
+    if (!response.body) {
+      throw new Error("duragent: stream response has no body");
+    }
+    return parseEventStream(response.body);
+  }
+
+  private async fetch(request: Request, accept: string): Promise<Response> {
+    const url = new URL(this.baseUrl + request.path);
+    for (const [name, value] of Object.entries(request.query ?? {})) {
+      if (value !== undefined) {
+        url.searchParams.set(name, String(value));
+      }
+    }
+
+    const headers: Record<string, string> = { Accept: accept, ...request.headers };
+    if (request.body !== undefined) {
+      headers["Content-Type"] = "application/json";
+    }
+    if (this.token) {
+      headers.Authorization = `Bearer ${this.token}`;
+    }
+
+    const response = await this.fetchImpl(url, {
+      method: request.method,
+      headers,
+      body: request.body === undefined ? undefined : JSON.stringify(request.body),
+      signal: request.signal,
+    });
+    if (!response.ok) {
+      let problem: Problem | undefined;
+      try {
+        problem = (await response.json()) as Problem;
+      } catch {
+        problem = undefined;
+      }
+      throw new DuragentError(response.status, problem?.title ? problem : undefined);
+    }
+    return response;
+  }
+}
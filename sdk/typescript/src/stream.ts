@@ -0,0 +1,88 @@
+// Server-sent event parsing for streaming responses.
+
+import type { StreamEventMap } from "./models.gen.js";
+
+/** One server-sent event, with its JSON data decoded. */
+export type StreamEvent = {
+  [Name in keyof StreamEventMap]: {
+    /** Resumes the stream after this event; see `resumeStream`. */
+    id?: string;
+    event: Name;
+    data: StreamEventMap[Name];
+  };
+}[keyof StreamEventMap];
+
+const TERMINAL_EVENTS: ReadonlySet<string> = new Set(["done", "cancelled", "error"]);
+
+/** Thrown when a stream closes before a `done`, `cancelled`, or `error` event. */
+export class StreamEndedError extends Error {
+  constructor() {
+    super("duragent: stream ended before a terminal event");
+    this.name = "StreamEndedError";
+  }
+}
+
+/**
+ * Parse a `text/event-stream` body into events, ending after the terminal
+ * one. Comments such as keep-alives are skipped.
+ *
+ * ```ts
+ * for await (const event of await client.streamMessage(sessionId, { content: "Hi" })) {
+ *   if (event.event === "token") process.stdout.write(event.data.content);
+ * }
+ * ```
+ */
+export async function* parseEventStream(
+  body: ReadableStream<Uint8Array>,
+): AsyncGenerator<StreamEvent> {
+  const reader = body.pipeThrough(new TextDecoderStream()).getReader();
+  let buffer = "";
+  let id: string | undefined;
+  let name = "message";
+  let data: string[] = [];
+
+  try {
+    for (;;) {
+      const { value, done } = await reader.read();
+      if (done) {
+        throw new StreamEndedError();
+      }
+      buffer += value;
+
+      let newline: number;
+      while ((newline = buffer.search(/\r?\n/)) >= 0) {
+        const line = buffer.slice(0, newline);
+        buffer = buffer.slice(newline + (buffer[newline] === "\r" ? 2 : 1));
+
+        if (line === "") {
+          if (data.length > 0) {
+            const event = { id, event: name, data: JSON.parse(data.join("\n")) } as StreamEvent;
+            yield event;
+            if (TERMINAL_EVENTS.has(name)) {
+              return;
+            }
+          }
+          id = undefined;
+          name = "message";
+          data = [];
+          continue;
+        }
+        if (line.startsWith(":")) {
+          continue;
+        }
+        const colon = line.indexOf(":");
+        const field = colon < 0 ? line : line.slice(0, colon);
+        const fieldValue = colon < 0 ? "" : line.slice(colon + 1).replace(/^ /, "");
+        if (field === "event") {
+          name = fieldValue;
+        } else if (field === "id") {
+          id = fieldValue;
+        } else if (field === "data") {
+          data.push(fieldValue);
+        }
+      }
+    }
+  } finally {
+    await reader.cancel().catch(() => undefined);
+  }
+}